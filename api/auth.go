@@ -0,0 +1,182 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	gopath "path"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// API authentication modes
+const (
+	// AuthModeOff serves the API without authentication
+	AuthModeOff = "off"
+	// AuthModeWrites requires a token for requests that change state;
+	// GET and HEAD requests stay open
+	AuthModeWrites = "writes"
+	// AuthModeAll requires a token for every request; read tokens and viewers
+	// may only send GET and HEAD requests
+	AuthModeAll = "all"
+)
+
+// adminRoutes manage who may use the API or return secrets, and take an
+// admin role even to read: the profiler and traffic captures can expose
+// request headers and credentials
+var adminRoutes = []string{"/api/tokens", "/api/users", "/api/system/pprof", "/api/captures"}
+
+// adminRoutePatterns are admin routes with path parameters, matched with
+// path.Match; a client's PKCS#12 file holds its private key
+var adminRoutePatterns = []string{"/api/mtls/clients/*/download"}
+
+// editorRoutes are the resource, middleware and service routes editors may
+// change; every other write takes an admin role
+var editorRoutes = []string{"/api/resources", "/api/middlewares", "/api/services", "/api/service-overrides"}
+
+// routeIn reports whether path is one of the prefixes or below it
+func routeIn(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// routeMatches reports whether path matches one of the patterns
+func routeMatches(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := gopath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredRole returns the least role allowed to send a request
+func requiredRole(method, path string) string {
+	read := method == http.MethodGet || method == http.MethodHead
+	switch {
+	case routeIn(path, adminRoutes) || routeMatches(path, adminRoutePatterns):
+		return models.RoleAdmin
+	case read:
+		return models.RoleViewer
+	case routeIn(path, editorRoutes):
+		return models.RoleEditor
+	default:
+		return models.RoleAdmin
+	}
+}
+
+// apiTokenContextKey is the gin context key the authenticated token is stored under
+const apiTokenContextKey = "api_token"
+
+// authExemptPaths carry their own credentials and are never checked against
+// API tokens: Traefik calls forward-auth with the end user's Authorization
+// header
+var authExemptPaths = map[string]bool{
+	"/api/forward-auth/verify": true,
+}
+
+// ValidAuthMode reports whether mode is one of the API authentication modes
+func ValidAuthMode(mode string) bool {
+	return mode == AuthModeOff || mode == AuthModeWrites || mode == AuthModeAll
+}
+
+// apiAuth checks bearer tokens on /api requests
+type apiAuth struct {
+	mu     sync.RWMutex
+	mode   string
+	tokens *services.APITokenService
+}
+
+// setMode switches the authentication mode; empty means off
+func (a *apiAuth) setMode(mode string) {
+	if mode == "" {
+		mode = AuthModeOff
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.mode = mode
+}
+
+func (a *apiAuth) currentMode() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.mode
+}
+
+// logMode logs the authentication mode and warns when no token can be used
+func (a *apiAuth) logMode() {
+	mode := a.currentMode()
+	if mode == AuthModeOff {
+		return
+	}
+	log.Printf("API authentication enabled (mode %s)", mode)
+	if !a.tokens.HasAdminToken() {
+		if tokens, err := a.tokens.List(); err == nil && len(tokens) == 0 {
+			log.Printf("Warning: no API tokens exist and API_ADMIN_TOKEN is not set; set it to create the first token")
+		}
+	}
+}
+
+// Middleware returns a Gin middleware enforcing the authentication mode and
+// the role of the token. Managing tokens and users, and the routes that
+// return secrets, always take an admin role once authentication is on, so
+// open reads cannot reach them.
+func (a *apiAuth) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		mode := a.currentMode()
+		if mode == AuthModeOff || c.Request.Method == http.MethodOptions ||
+			!strings.HasPrefix(path, "/api/") || authExemptPaths[path] {
+			c.Next()
+			return
+		}
+
+		required := requiredRole(c.Request.Method, path)
+		if required == models.RoleViewer && mode == AuthModeWrites {
+			c.Next()
+			return
+		}
+
+		auth := c.GetHeader("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			c.Header("WWW-Authenticate", `Bearer realm="middleware-manager"`)
+			apierrors.Unauthorized(c, "An API token is required")
+			c.Abort()
+			return
+		}
+
+		token, err := a.tokens.Verify(strings.TrimPrefix(auth, "Bearer "))
+		if err != nil {
+			log.Printf("Error verifying API token: %v", err)
+			apierrors.ServerError(c, "Failed to verify API token", nil)
+			c.Abort()
+			return
+		}
+		if token == nil {
+			c.Header("WWW-Authenticate", `Bearer realm="middleware-manager", error="invalid_token"`)
+			apierrors.Unauthorized(c, "Invalid or expired API token")
+			c.Abort()
+			return
+		}
+		if !models.RoleAllows(token.Role, required) {
+			if token.Scope == models.APITokenScopeRead {
+				apierrors.Forbidden(c, "This API token is read-only")
+			} else {
+				apierrors.Forbidden(c, "The "+token.Role+" role does not allow this request")
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set(apiTokenContextKey, token)
+		c.Next()
+	}
+}
@@ -5,37 +5,150 @@ import (
   "net/http"
   
   "github.com/gin-gonic/gin"
+  "github.com/hhftechnology/middleware-manager/api/i18n"
+)
+
+// Code is a stable, machine-readable error code. Clients should branch on the
+// code rather than the message, which may be reworded or translated.
+type Code string
+
+// Error codes returned in APIError.Code
+const (
+  CodeInvalidRequest   Code = "invalid_request"
+  CodeValidationFailed Code = "validation_failed"
+  CodePayloadTooLarge  Code = "payload_too_large"
+  CodeMissingField     Code = "missing_field"
+  CodeNotFound         Code = "not_found"
+  CodeConflict         Code = "conflict"
+  CodeVersionConflict  Code = "version_conflict"
+  CodeVersionRequired  Code = "version_required"
+  CodeResourceDisabled Code = "resource_disabled"
+  CodeNotConfigured    Code = "not_configured"
+  CodePreflightFailed  Code = "preflight_failed"
+  CodeUnauthorized     Code = "unauthorized"
+  CodeForbidden        Code = "forbidden"
+  CodeDatabaseError    Code = "database_error"
+  CodeInternal         Code = "internal_error"
+  CodeUnavailable      Code = "service_unavailable"
 )
 
 // APIError represents a standardized error response
 type APIError struct {
-  Code    int    `json:"code"`
+  Status  int    `json:"status"`
+  Code    Code   `json:"code"`
   Message string `json:"message"`
+  Field   string `json:"field,omitempty"`   // Request field the error refers to
+  Hint    string `json:"hint,omitempty"`    // Suggested fix for the operator
   Details string `json:"details,omitempty"` // Optional detailed error info
+
+  Errors  []FieldError `json:"errors,omitempty"`  // Per-field validation errors
+  Current interface{}  `json:"current,omitempty"` // Current state of the entity on a conflict
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+  Field   string `json:"field"`
+  Code    string `json:"code"`
+  Message string `json:"message"`
+}
+
+// New creates an API error
+func New(status int, code Code, message string) *APIError {
+  return &APIError{Status: status, Code: code, Message: message}
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+  return string(e.Code) + ": " + e.Message
+}
+
+// WithField returns a copy of the error pointing at a request field
+func (e *APIError) WithField(field string) *APIError {
+  copy := *e
+  copy.Field = field
+  return &copy
+}
+
+// WithHint returns a copy of the error with a suggested fix
+func (e *APIError) WithHint(hint string) *APIError {
+  copy := *e
+  copy.Hint = hint
+  return &copy
 }
 
-// HandleAPIError logs and returns a standardized error response
+// WithErrors returns a copy of the error listing the rejected fields
+func (e *APIError) WithErrors(errs []FieldError) *APIError {
+  copy := *e
+  copy.Errors = errs
+  return &copy
+}
+
+// WithCurrent returns a copy of the error carrying the entity's current state
+func (e *APIError) WithCurrent(current interface{}) *APIError {
+  copy := *e
+  copy.Current = current
+  return &copy
+}
+
+// CodeForStatus returns the default error code for an HTTP status
+func CodeForStatus(status int) Code {
+  switch status {
+  case http.StatusBadRequest:
+    return CodeInvalidRequest
+  case http.StatusUnprocessableEntity:
+    return CodeValidationFailed
+  case http.StatusRequestEntityTooLarge:
+    return CodePayloadTooLarge
+  case http.StatusNotFound:
+    return CodeNotFound
+  case http.StatusConflict:
+    return CodeConflict
+  case http.StatusPreconditionRequired:
+    return CodeVersionRequired
+  case http.StatusUnauthorized:
+    return CodeUnauthorized
+  case http.StatusForbidden:
+    return CodeForbidden
+  case http.StatusServiceUnavailable:
+    return CodeUnavailable
+  default:
+    return CodeInternal
+  }
+}
+
+// Respond logs and sends a typed error response
+func Respond(c *gin.Context, e *APIError) {
+  if e.Details != "" {
+    log.Printf("API Error [%s]: %s - %s", e.Code, e.Message, e.Details)
+  } else {
+    log.Printf("API Error [%s]: %s", e.Code, e.Message)
+  }
+
+  resp := *e
+  resp.Message = localize(c, e.Message)
+  c.JSON(e.Status, resp)
+}
+
+// HandleAPIError logs and returns a standardized error response, using the
+// default code for the status. The message is translated per the request's
+// Accept-Language; logs stay in English.
 func HandleAPIError(c *gin.Context, statusCode int, message string, err error) {
-  // Log the error with details
+  apiErr := New(statusCode, CodeForStatus(statusCode), message)
   if err != nil {
-    log.Printf("API Error: %s - %v", message, err)
-    
-    // Respond to the client with error details
-    c.JSON(statusCode, APIError{
-      Code:    statusCode,
-      Message: message,
-      Details: err.Error(),
-    })
-  } else {
-    // Log just the message if no specific error is provided
-    log.Printf("API Error: %s", message)
-    
-    // Respond to the client without error details
-    c.JSON(statusCode, APIError{
-      Code:    statusCode,
-      Message: message,
-    })
+    apiErr.Details = err.Error()
+  }
+  Respond(c, apiErr)
+}
+
+// localize translates message into the language negotiated for the request
+func localize(c *gin.Context, message string) string {
+  if c.Request == nil {
+    return message
   }
+  lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+  c.Header("Content-Language", lang)
+  c.Header("Vary", "Accept-Language")
+  return i18n.Translate(lang, message)
 }
 
 // NotFound handles not found errors (404)
@@ -38,8 +38,11 @@ func TestHandleAPIError_WithError(t *testing.T) {
 	}
 
 	resp := parseResponse(t, w)
-	if resp.Code != http.StatusBadRequest {
-		t.Errorf("resp.Code = %d, want %d", resp.Code, http.StatusBadRequest)
+	if resp.Status != http.StatusBadRequest {
+		t.Errorf("resp.Status = %d, want %d", resp.Status, http.StatusBadRequest)
+	}
+	if resp.Code != CodeInvalidRequest {
+		t.Errorf("resp.Code = %q, want %q", resp.Code, CodeInvalidRequest)
 	}
 	if resp.Message != "bad input" {
 		t.Errorf("resp.Message = %q, want %q", resp.Message, "bad input")
@@ -182,3 +185,47 @@ func TestServiceUnavailable(t *testing.T) {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
 	}
 }
+
+func TestHandleAPIError_Localized(t *testing.T) {
+	c, w := newTestContext()
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	NotFound(c, "Resource", "")
+
+	resp := parseResponse(t, w)
+	if resp.Message != "Ressource nicht gefunden" {
+		t.Errorf("message = %q", resp.Message)
+	}
+	if got := w.Header().Get("Content-Language"); got != "de" {
+		t.Errorf("Content-Language = %q, want de", got)
+	}
+}
+
+func TestRespond_TypedError(t *testing.T) {
+	c, w := newTestContext()
+	Respond(c, New(http.StatusBadRequest, CodeMissingField, "Resource ID is required").
+		WithField("id").WithHint("Pass the resource ID in the URL"))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	resp := parseResponse(t, w)
+	if resp.Code != CodeMissingField || resp.Field != "id" || resp.Hint == "" {
+		t.Errorf("unexpected envelope: %+v", resp)
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]Code{
+		http.StatusBadRequest:          CodeInvalidRequest,
+		http.StatusNotFound:            CodeNotFound,
+		http.StatusConflict:            CodeConflict,
+		http.StatusInternalServerError: CodeInternal,
+		http.StatusBadGateway:          CodeInternal,
+	}
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
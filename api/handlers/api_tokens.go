@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// APITokenHandler manages the bearer tokens of the management API
+type APITokenHandler struct {
+	Tokens *services.APITokenService
+}
+
+// NewAPITokenHandler creates a new API token handler
+func NewAPITokenHandler(tokens *services.APITokenService) *APITokenHandler {
+	return &APITokenHandler{Tokens: tokens}
+}
+
+// GetTokens lists the stored tokens without their secrets
+func (h *APITokenHandler) GetTokens(c *gin.Context) {
+	tokens, err := h.Tokens.List()
+	if err != nil {
+		log.Printf("Error fetching API tokens: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch API tokens")
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken issues a token; its secret is only returned in this response
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	var req models.APITokenRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid API token: %v", err))
+		return
+	}
+
+	token, err := h.Tokens.Create(req)
+	if err == services.ErrUnknownUser {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid API token: user %s not found", req.UserID))
+		return
+	} else if err != nil {
+		log.Printf("Error creating API token: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to create API token")
+		return
+	}
+	log.Printf("Created %s API token %s (%s) with role %s", token.Scope, token.Prefix, token.Name, token.Role)
+	c.JSON(http.StatusCreated, token)
+}
+
+// DeleteToken revokes a token; requests using it fail right away
+func (h *APITokenHandler) DeleteToken(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.Tokens.Revoke(id); err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "API token not found")
+		return
+	} else if err != nil {
+		log.Printf("Error revoking API token: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Revoked API token %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
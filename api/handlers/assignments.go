@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// AssignmentHandler handles temporary middleware assignment requests
+type AssignmentHandler struct {
+	DB      *sql.DB
+	Expirer *services.AssignmentExpirer
+}
+
+// NewAssignmentHandler creates a new assignment handler
+func NewAssignmentHandler(db *sql.DB, expirer *services.AssignmentExpirer) *AssignmentHandler {
+	return &AssignmentHandler{DB: db, Expirer: expirer}
+}
+
+// GetExpirations returns the most recently expired assignments together with
+// the owner that should be told about them
+func (h *AssignmentHandler) GetExpirations(c *gin.Context) {
+	expirations, err := h.Expirer.ListExpirations(100)
+	if err != nil {
+		log.Printf("Error fetching assignment expirations: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch assignment expirations")
+		return
+	}
+	c.JSON(http.StatusOK, expirations)
+}
+
+// ExpireNow removes expired assignments immediately instead of waiting for the
+// next scheduler run
+func (h *AssignmentHandler) ExpireNow(c *gin.Context) {
+	expired, err := h.Expirer.ExpireAssignments()
+	if err != nil {
+		log.Printf("Error expiring middleware assignments: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to expire middleware assignments")
+		return
+	}
+	if expired == nil {
+		expired = []models.AssignmentExpiration{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"expired": len(expired),
+		"items":   expired,
+	})
+}
+
+// assignmentExpiry validates an optional assignment expiry and normalizes it
+// to UTC so it compares correctly against the scheduler's clock. A past
+// expiry is rejected with 422.
+func assignmentExpiry(c *gin.Context, field string, expiresAt *time.Time) (*time.Time, bool) {
+	if expiresAt == nil {
+		return nil, true
+	}
+	if !expiresAt.After(time.Now()) {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			"Request validation failed").WithErrors([]apierrors.FieldError{
+			{Field: field, Code: "in_past", Message: "Must be in the future"},
+		}))
+		return nil, false
+	}
+	utc := expiresAt.UTC()
+	return &utc, true
+}
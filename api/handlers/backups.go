@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// BackupHandler lists, runs and restores backups in the local backup
+// directory and in object storage
+type BackupHandler struct {
+	Local *services.Backups
+	S3    *services.Backups
+}
+
+// NewBackupHandler creates a new backup handler. A nil local or s3 answers
+// the endpoints of that target with 503.
+func NewBackupHandler(local, s3 *services.Backups) *BackupHandler {
+	return &BackupHandler{Local: local, S3: s3}
+}
+
+// localAvailable reports whether local backups are enabled, responding with
+// 503 when not
+func (h *BackupHandler) localAvailable(c *gin.Context) bool {
+	if h.Local == nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusServiceUnavailable, apierrors.CodeNotConfigured,
+			"Local backups are not available").WithHint("Local backups need a SQLite database; back PostgreSQL up with pg_dump."))
+		return false
+	}
+	return true
+}
+
+// GetBackups lists the database snapshots and config exports in the backup
+// directory along with the state of the last run
+// GET /api/backups
+func (h *BackupHandler) GetBackups(c *gin.Context) {
+	if !h.localAvailable(c) {
+		return
+	}
+	objects, err := h.Local.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing backups: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to list backups: "+err.Error())
+		return
+	}
+	if objects == nil {
+		objects = []services.BackupObject{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  h.Local.Status(),
+		"objects": objects,
+	})
+}
+
+// CreateBackup writes a database snapshot and a config export to the backup
+// directory now, rotating out the oldest beyond the retention
+// POST /api/backup
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	if !h.localAvailable(c) {
+		return
+	}
+	objects, err := h.Local.Run(c.Request.Context())
+	if err != nil {
+		log.Printf("Backup failed: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Backup failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"objects": objects})
+}
+
+// RestoreBackup replaces the database with a snapshot from the backup
+// directory. Encrypted snapshots use the passphrase of the optional body or
+// the configured one.
+// POST /api/backups/:id/restore {"passphrase": "..."}
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	if !h.localAvailable(c) {
+		return
+	}
+	id := c.Param("id")
+	var input struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if c.Request.ContentLength > 0 && !bindRequest(c, &input) {
+		return
+	}
+
+	if err := h.Local.RestoreID(c.Request.Context(), id, input.Passphrase); err != nil {
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			ResponseWithError(c, http.StatusNotFound, "Backup not found")
+		case errors.Is(err, services.ErrNotSnapshot):
+			ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed, err.Error()))
+		case errors.Is(err, services.ErrBundleDecrypt) || errors.Is(err, services.ErrBundlePassphrase):
+			ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+				err.Error()).WithField("passphrase"))
+		default:
+			log.Printf("Restoring backup %s failed: %v", id, err)
+			ResponseWithError(c, http.StatusInternalServerError, "Restore failed: "+err.Error())
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup restored", "id": id})
+}
+
+// s3Available reports whether an S3 target is configured, responding with 503
+// when not
+func (h *BackupHandler) s3Available(c *gin.Context) bool {
+	if h.S3 == nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusServiceUnavailable, apierrors.CodeNotConfigured,
+			"S3 backups are not configured").WithHint("Set BACKUP_S3_ENDPOINT, BACKUP_S3_BUCKET and the BACKUP_S3_* credentials."))
+		return false
+	}
+	return true
+}
+
+// GetS3Backups lists the database snapshots and config exports in the bucket
+// along with the state of the last run
+// GET /api/backups/s3
+func (h *BackupHandler) GetS3Backups(c *gin.Context) {
+	if !h.s3Available(c) {
+		return
+	}
+	objects, err := h.S3.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing S3 backups: %v", err)
+		ResponseWithError(c, http.StatusBadGateway, "Failed to list S3 backups: "+err.Error())
+		return
+	}
+	if objects == nil {
+		objects = []services.BackupObject{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  h.S3.Status(),
+		"objects": objects,
+	})
+}
+
+// RunS3Backup uploads a database snapshot and a config export now
+// POST /api/backups/s3/run
+func (h *BackupHandler) RunS3Backup(c *gin.Context) {
+	if !h.s3Available(c) {
+		return
+	}
+	objects, err := h.S3.Run(c.Request.Context())
+	if err != nil {
+		log.Printf("S3 backup failed: %v", err)
+		ResponseWithError(c, http.StatusBadGateway, "S3 backup failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"objects": objects})
+}
+
+// RestoreS3Backup replaces the database with a snapshot from the bucket.
+// Encrypted snapshots use the passphrase of the request or the configured one.
+// POST /api/backups/s3/restore {"key": "db/...", "passphrase": "..."}
+func (h *BackupHandler) RestoreS3Backup(c *gin.Context) {
+	if !h.s3Available(c) {
+		return
+	}
+	var input struct {
+		Key        string `json:"key"`
+		Passphrase string `json:"passphrase"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if input.Key == "" {
+		ResponseWithAPIError(c, missingFieldError("key", "Backup key is required"))
+		return
+	}
+
+	if err := h.S3.Restore(c.Request.Context(), input.Key, input.Passphrase); err != nil {
+		if errors.Is(err, services.ErrNotSnapshot) {
+			ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+				err.Error()).WithField("key"))
+			return
+		}
+		if errors.Is(err, services.ErrBundleDecrypt) || errors.Is(err, services.ErrBundlePassphrase) {
+			ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+				err.Error()).WithField("passphrase"))
+			return
+		}
+		log.Printf("Restoring S3 backup %s failed: %v", input.Key, err)
+		ResponseWithError(c, http.StatusInternalServerError, "Restore failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup restored", "key": input.Key})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// memoryBackupTarget keeps backup objects in memory
+type memoryBackupTarget map[string][]byte
+
+func (m memoryBackupTarget) Name() string { return "memory://" }
+
+func (m memoryBackupTarget) Put(_ context.Context, key string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func (m memoryBackupTarget) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("no such key %s", key)
+	}
+	return data, nil
+}
+
+func (m memoryBackupTarget) List(_ context.Context, prefix string) ([]services.BackupObject, error) {
+	var objects []services.BackupObject
+	for key, data := range m {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, services.BackupObject{Key: key, Size: int64(len(data))})
+		}
+	}
+	return objects, nil
+}
+
+func TestBackupHandler_S3(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/backups/s3", nil)
+	NewBackupHandler(nil, nil).GetS3Backups(c)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a target, got %d", rec.Code)
+	}
+
+	db := testutil.NewTempDB(t)
+	handler := NewBackupHandler(nil, services.NewBackups(db, memoryBackupTarget{}, nil, nil))
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/backups/s3/run", nil)
+	handler.RunS3Backup(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/backups/s3", nil)
+	handler.GetS3Backups(c)
+	var resp struct {
+		Objects []services.BackupObject `json:"objects"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Objects) != 1 || !strings.HasPrefix(resp.Objects[0].Key, "db/") {
+		t.Fatalf("unexpected objects %+v", resp.Objects)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/backups/s3/restore", strings.NewReader(`{"key":"config/x.json"}`))
+	handler.RestoreS3Backup(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a config export, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/backups/s3/restore",
+		strings.NewReader(fmt.Sprintf(`{"key":%q}`, resp.Objects[0].Key)))
+	handler.RestoreS3Backup(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBackupHandler_Local(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/backups", nil)
+	NewBackupHandler(nil, nil).GetBackups(c)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without local backups, got %d", rec.Code)
+	}
+
+	db := testutil.NewTempDB(t)
+	target, err := services.NewLocalTarget(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewBackupHandler(services.NewBackups(db, target, nil, nil), nil)
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/backup", nil)
+	handler.CreateBackup(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/backups", nil)
+	handler.GetBackups(c)
+	var resp struct {
+		Objects []services.BackupObject `json:"objects"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Objects) != 1 || resp.Objects[0].ID == "" {
+		t.Fatalf("unexpected objects %+v", resp.Objects)
+	}
+
+	restore := func(id string) int {
+		c, rec := testutil.NewContext(t, http.MethodPost, "/api/backups/"+id+"/restore", nil)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handler.RestoreBackup(c)
+		return rec.Code
+	}
+	if code := restore("missing.db"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown backup, got %d", code)
+	}
+	if code := restore(resp.Objects[0].ID); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+}
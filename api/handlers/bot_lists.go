@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// BotListHandler manages user-agent block lists and the resources they protect
+type BotListHandler struct {
+	DB      *sql.DB
+	Updater *services.BotListUpdater
+}
+
+// NewBotListHandler creates a new bot list handler
+func NewBotListHandler(db *sql.DB, updater *services.BotListUpdater) *BotListHandler {
+	return &BotListHandler{DB: db, Updater: updater}
+}
+
+const botListColumns = `id, name, category, patterns, source_url, update_interval,
+		       last_updated_at, last_error, created_at, updated_at`
+
+func scanBotList(row corsScanner) (models.BotList, error) {
+	var list models.BotList
+	var patterns string
+	var lastUpdated sql.NullTime
+	err := row.Scan(&list.ID, &list.Name, &list.Category, &patterns, &list.SourceURL, &list.UpdateInterval,
+		&lastUpdated, &list.LastError, &list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		return list, err
+	}
+	_ = json.Unmarshal([]byte(patterns), &list.Patterns)
+	if list.Patterns == nil {
+		list.Patterns = []string{}
+	}
+	if lastUpdated.Valid {
+		list.LastUpdatedAt = &lastUpdated.Time
+	}
+	return list, nil
+}
+
+// GetBotLists returns all bot lists with the resources each one is applied to
+func (h *BotListHandler) GetBotLists(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT " + botListColumns + " FROM bot_lists ORDER BY name")
+	if err != nil {
+		log.Printf("Error fetching bot lists: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch bot lists")
+		return
+	}
+	defer rows.Close()
+
+	var lists []models.BotList
+	for rows.Next() {
+		list, err := scanBotList(rows)
+		if err != nil {
+			log.Printf("Error scanning bot list row: %v", err)
+			continue
+		}
+		lists = append(lists, list)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating bot list rows: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Database error while fetching bot lists")
+		return
+	}
+	rows.Close()
+
+	response := []gin.H{}
+	for _, list := range lists {
+		response = append(response, gin.H{"list": list, "resource_ids": h.listResources(list.ID)})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBotList returns a single bot list, its resources and the generated user-agent regex
+func (h *BotListHandler) GetBotList(c *gin.Context) {
+	id := c.Param("id")
+	list, err := scanBotList(h.DB.QueryRow("SELECT "+botListColumns+" FROM bot_lists WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Bot list not found")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching bot list: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"list":         list,
+		"resource_ids": h.listResources(id),
+		"regex":        models.UserAgentRegex(list.Patterns),
+	})
+}
+
+// CreateBotList validates and stores a new bot list
+func (h *BotListHandler) CreateBotList(c *gin.Context) {
+	var list models.BotList
+	if !bindRequest(c, &list) {
+		return
+	}
+
+	list.Normalize()
+	if err := list.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid bot list: %v", err))
+		return
+	}
+
+	id, err := generateID()
+	if err != nil {
+		log.Printf("Error generating ID: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to generate ID")
+		return
+	}
+	list.ID = id
+
+	patterns, _ := json.Marshal(list.Patterns)
+	_, err = h.DB.Exec(`
+		INSERT INTO bot_lists (id, name, category, patterns, source_url, update_interval)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, list.Name, list.Category, string(patterns), list.SourceURL, list.UpdateInterval)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			ResponseWithError(c, http.StatusConflict, fmt.Sprintf("Bot list %q already exists", list.Name))
+			return
+		}
+		log.Printf("Error inserting bot list: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save bot list")
+		return
+	}
+
+	log.Printf("Created bot list %s (%s)", list.Name, id)
+	c.JSON(http.StatusCreated, list)
+}
+
+// UpdateBotList validates and replaces an existing bot list
+func (h *BotListHandler) UpdateBotList(c *gin.Context) {
+	id := c.Param("id")
+	var list models.BotList
+	if !bindRequest(c, &list) {
+		return
+	}
+
+	list.Normalize()
+	if err := list.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid bot list: %v", err))
+		return
+	}
+
+	patterns, _ := json.Marshal(list.Patterns)
+	result, err := h.DB.Exec(`
+		UPDATE bot_lists SET name = ?, category = ?, patterns = ?, source_url = ?, update_interval = ?, updated_at = ?
+		WHERE id = ?
+	`, list.Name, list.Category, string(patterns), list.SourceURL, list.UpdateInterval, time.Now(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			ResponseWithError(c, http.StatusConflict, fmt.Sprintf("Bot list %q already exists", list.Name))
+			return
+		}
+		log.Printf("Error updating bot list: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update bot list")
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		ResponseWithError(c, http.StatusNotFound, "Bot list not found")
+		return
+	}
+
+	list.ID = id
+	log.Printf("Updated bot list %s (%s)", list.Name, id)
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteBotList removes a bot list and unblocks it on every resource
+func (h *BotListHandler) DeleteBotList(c *gin.Context) {
+	id := c.Param("id")
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		log.Printf("Error beginning transaction: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM resource_bot_lists WHERE list_id = ?", id); err != nil {
+		log.Printf("Error removing bot list assignments: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete bot list")
+		return
+	}
+	result, err := tx.Exec("DELETE FROM bot_lists WHERE id = ?", id)
+	if err != nil {
+		log.Printf("Error deleting bot list: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete bot list")
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		ResponseWithError(c, http.StatusNotFound, "Bot list not found")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing bot list deletion: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete bot list")
+		return
+	}
+
+	log.Printf("Deleted bot list %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Bot list deleted successfully"})
+}
+
+// RefreshBotList downloads the list from its source URL now
+func (h *BotListHandler) RefreshBotList(c *gin.Context) {
+	id := c.Param("id")
+	count, err := h.Updater.Refresh(c.Request.Context(), id)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Bot list not found")
+		return
+	} else if err != nil {
+		ResponseWithError(c, http.StatusBadGateway, fmt.Sprintf("Failed to refresh bot list: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "patterns": count})
+}
+
+// SetBotListResources replaces the set of resources a bot list is applied to
+func (h *BotListHandler) SetBotListResources(c *gin.Context) {
+	id := c.Param("id")
+	var input struct {
+		ResourceIDs []string `json:"resource_ids"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	var exists int
+	if err := h.DB.QueryRow("SELECT 1 FROM bot_lists WHERE id = ?", id).Scan(&exists); err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Bot list not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking bot list existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		log.Printf("Error beginning transaction: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM resource_bot_lists WHERE list_id = ?", id); err != nil {
+		log.Printf("Error clearing bot list assignments: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update bot list resources")
+		return
+	}
+	for _, resourceID := range input.ResourceIDs {
+		var found int
+		if err := tx.QueryRow("SELECT 1 FROM resources WHERE id = ?", resourceID).Scan(&found); err == sql.ErrNoRows {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Resource %s not found", resourceID))
+			return
+		} else if err != nil {
+			log.Printf("Error checking resource existence: %v", err)
+			ResponseWithAPIError(c, errDatabase)
+			return
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO resource_bot_lists (resource_id, list_id) VALUES (?, ?) ON CONFLICT DO NOTHING", resourceID, id,
+		); err != nil {
+			log.Printf("Error assigning bot list: %v", err)
+			ResponseWithError(c, http.StatusInternalServerError, "Failed to update bot list resources")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing bot list assignments: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update bot list resources")
+		return
+	}
+
+	log.Printf("Applied bot list %s to %d resource(s)", id, len(input.ResourceIDs))
+	c.JSON(http.StatusOK, gin.H{"id": id, "resource_ids": h.listResources(id)})
+}
+
+// listResources returns the IDs of the resources a bot list is applied to
+func (h *BotListHandler) listResources(listID string) []string {
+	ids := []string{}
+	rows, err := h.DB.Query("SELECT resource_id FROM resource_bot_lists WHERE list_id = ? ORDER BY resource_id", listID)
+	if err != nil {
+		log.Printf("Error fetching bot list resources: %v", err)
+		return ids
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
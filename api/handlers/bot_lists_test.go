@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestBotListHandler_Lifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewBotListHandler(db.DB, services.NewBotListUpdater(db))
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	// The built-in lists are seeded
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/bot-lists", nil)
+	handler.GetBotLists(c)
+	var lists []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &lists); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(lists) != 3 {
+		t.Fatalf("expected 3 built-in lists, got %d", len(lists))
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/bot-lists",
+		bytes.NewBufferString(`{"name":"curl","patterns":["curl/"," curl/ ","Wget"]}`))
+	handler.CreateBotList(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	id := created["id"].(string)
+	if created["category"] != "custom" || len(created["patterns"].([]interface{})) != 2 {
+		t.Fatalf("unexpected list: %v", created)
+	}
+
+	setResources := func(body string) int {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/bot-lists/"+id+"/resources", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handler.SetBotListResources(c)
+		return rec.Code
+	}
+	if code := setResources(`{"resource_ids":["missing"]}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown resource, got %d", code)
+	}
+	if code := setResources(`{"resource_ids":["res-1"]}`); code != http.StatusOK {
+		t.Fatalf("expected 200 applying list, got %d", code)
+	}
+	var assigned int
+	if err := db.QueryRow("SELECT COUNT(*) FROM resource_bot_lists WHERE list_id = ?", id).Scan(&assigned); err != nil || assigned != 1 {
+		t.Fatalf("expected 1 assignment, got %d (%v)", assigned, err)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/bot-lists/"+id, nil)
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	handler.DeleteBotList(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 deleting list, got %d", rec.Code)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM resource_bot_lists").Scan(&assigned); err != nil || assigned != 0 {
+		t.Fatalf("assignments left after delete: %d (%v)", assigned, err)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// Traffic capture limits
+const (
+	defaultCaptureLines = 20
+	maxCaptureLines     = 500
+	defaultCaptureTTL   = 5 * time.Minute
+	maxCaptureTTL       = time.Hour
+)
+
+// CaptureHandler starts, reads and stops temporary traffic captures
+type CaptureHandler struct {
+	DB          *sql.DB
+	Capturer    *services.TrafficCapturer
+	ConfigProxy *services.ConfigProxy
+}
+
+// NewCaptureHandler creates a new traffic capture handler
+func NewCaptureHandler(db *sql.DB, capturer *services.TrafficCapturer, configProxy *services.ConfigProxy) *CaptureHandler {
+	return &CaptureHandler{DB: db, Capturer: capturer, ConfigProxy: configProxy}
+}
+
+// StartCapture marks a resource's requests and collects the next N matching
+// access-log lines. The marker is removed once the lines are collected or the
+// capture expires.
+func (h *CaptureHandler) StartCapture(c *gin.Context) {
+	id := c.Param("id")
+	if !h.Capturer.Enabled() {
+		ResponseWithAPIError(c, apierrors.New(http.StatusServiceUnavailable, apierrors.CodeNotConfigured,
+			"Traffic capture is not configured").WithHint("Set TRAEFIK_ACCESS_LOG_PATH to Traefik's JSON access log."))
+		return
+	}
+
+	var input struct {
+		Lines      int `json:"lines"`
+		TTLSeconds int `json:"ttl_seconds"`
+	}
+	if c.Request.ContentLength > 0 {
+		if !bindRequest(c, &input) {
+			return
+		}
+	}
+	if input.Lines == 0 {
+		input.Lines = defaultCaptureLines
+	}
+	if input.Lines < 1 || input.Lines > maxCaptureLines {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("lines must be between 1 and %d", maxCaptureLines))
+		return
+	}
+	ttl := defaultCaptureTTL
+	if input.TTLSeconds != 0 {
+		ttl = time.Duration(input.TTLSeconds) * time.Second
+	}
+	if ttl <= 0 || ttl > maxCaptureTTL {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("ttl_seconds must be between 1 and %d", int(maxCaptureTTL.Seconds())))
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot capture traffic for a disabled resource"))
+		return
+	}
+
+	var running int
+	if err := h.DB.QueryRow(
+		"SELECT COUNT(*) FROM traffic_captures WHERE resource_id = ? AND status = ?", id, models.CaptureActive,
+	).Scan(&running); err != nil {
+		log.Printf("Error checking running captures: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if running > 0 {
+		ResponseWithError(c, http.StatusConflict, "A capture is already running for this resource")
+		return
+	}
+
+	capture, err := h.Capturer.StartCapture(id, input.Lines, ttl)
+	if err != nil {
+		log.Printf("Error starting traffic capture: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to start traffic capture")
+		return
+	}
+	if h.ConfigProxy != nil {
+		h.ConfigProxy.InvalidateCache()
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"capture": capture,
+		"note": fmt.Sprintf("Requests are marked with the %s header. Traefik's access log must use the JSON format and keep this request header.",
+			models.CaptureHeader),
+	})
+}
+
+// GetCaptures returns the most recent captures without their lines
+func (h *CaptureHandler) GetCaptures(c *gin.Context) {
+	captures, err := h.Capturer.ListCaptures(50)
+	if err != nil {
+		log.Printf("Error fetching traffic captures: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch traffic captures")
+		return
+	}
+	c.JSON(http.StatusOK, captures)
+}
+
+// GetCapture returns a capture and the access-log lines collected so far
+func (h *CaptureHandler) GetCapture(c *gin.Context) {
+	capture, err := h.Capturer.GetCapture(c.Param("captureId"))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Capture not found")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching traffic capture: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch traffic capture")
+		return
+	}
+	c.JSON(http.StatusOK, capture)
+}
+
+// StopCapture ends a capture early and detaches its marker
+func (h *CaptureHandler) StopCapture(c *gin.Context) {
+	captureID := c.Param("captureId")
+	if err := h.Capturer.StopCapture(captureID); err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "No active capture with this ID")
+		return
+	} else if err != nil {
+		log.Printf("Error stopping traffic capture: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to stop traffic capture")
+		return
+	}
+	if h.ConfigProxy != nil {
+		h.ConfigProxy.InvalidateCache()
+	}
+
+	log.Printf("Stopped traffic capture %s", captureID)
+	c.JSON(http.StatusOK, gin.H{"message": "Capture stopped"})
+}
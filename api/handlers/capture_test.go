@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestCaptureHandler_StartCapture(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	capturer := services.NewTrafficCapturer(db, filepath.Join(t.TempDir(), "access.log"))
+	handler := NewCaptureHandler(db.DB, capturer, nil)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	start := func(id, body string) int {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPost, "/api/resources/"+id+"/capture", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handler.StartCapture(c)
+		return rec.Code
+	}
+
+	if code := start("res-1", `{"lines":1000}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for too many lines, got %d", code)
+	}
+	if code := start("missing", `{}`); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing resource, got %d", code)
+	}
+	if code := start("res-1", `{"lines":5,"ttl_seconds":60}`); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if code := start("res-1", `{}`); code != http.StatusConflict {
+		t.Fatalf("expected 409 for a second capture, got %d", code)
+	}
+
+	disabled := NewCaptureHandler(db.DB, services.NewTrafficCapturer(db, ""), nil)
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/resources/res-1/capture", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	disabled.StartCapture(c)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without an access log, got %d", rec.Code)
+	}
+	var resp apierrors.APIError
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+	if resp.Code != apierrors.CodeNotConfigured || resp.Hint == "" {
+		t.Errorf("unexpected error envelope: %+v", resp)
+	}
+}
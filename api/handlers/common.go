@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,29 @@ func ResponseWithError(c *gin.Context, statusCode int, message string) {
 	apierrors.HandleAPIError(c, statusCode, message, nil)
 }
 
+// ResponseWithAPIError sends a typed error response carrying a machine-readable code
+func ResponseWithAPIError(c *gin.Context, err *apierrors.APIError) {
+	apierrors.Respond(c, err)
+}
+
+// Errors shared by many handlers
+var (
+	errDatabase = apierrors.New(http.StatusInternalServerError, apierrors.CodeDatabaseError, "Database error")
+
+	errStaticConfigPathNotSet = apierrors.New(http.StatusInternalServerError, apierrors.CodeNotConfigured,
+		"Traefik static configuration file path is not configured").WithHint("Set it in settings.")
+)
+
+// missingFieldError reports a required request field or path parameter that was empty
+func missingFieldError(field, message string) *apierrors.APIError {
+	return apierrors.New(http.StatusBadRequest, apierrors.CodeMissingField, message).WithField(field)
+}
+
+// disabledResourceError reports an attempt to change a disabled resource
+func disabledResourceError(message string) *apierrors.APIError {
+	return apierrors.New(http.StatusBadRequest, apierrors.CodeResourceDisabled, message)
+}
+
 // generateID generates a random 16-character hex string
 func generateID() (string, error) {
 	bytes := make([]byte, 8)
@@ -33,6 +57,7 @@ func isValidMiddlewareType(typ string) bool {
 		"digestAuth":        true,
 		"forwardAuth":       true,
 		"ipAllowList":       true,
+		"ipWhiteList":       true,
 		"rateLimit":         true,
 		"headers":           true,
 		"stripPrefix":       true,
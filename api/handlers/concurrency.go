@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+)
+
+// versionETag renders an entity version as an ETag value
+func versionETag(version int64) string {
+	return fmt.Sprintf("%q", strconv.FormatInt(version, 10))
+}
+
+// requestedVersion returns the version the client last saw, from the request
+// body or an If-Match header holding the ETag of a previous GET. found is false
+// when the client sent neither; ok is false when a bad If-Match was answered.
+func requestedVersion(c *gin.Context, bodyVersion *int64) (version int64, found bool, ok bool) {
+	if bodyVersion != nil {
+		return *bodyVersion, true, true
+	}
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeInvalidRequest,
+			"If-Match must be the version returned by a previous GET").WithField("If-Match"))
+		return 0, false, false
+	}
+	return version, true, true
+}
+
+// versionConflictError reports an edit based on an outdated version
+func versionConflictError(entity string, current interface{}) *apierrors.APIError {
+	return apierrors.New(http.StatusConflict, apierrors.CodeVersionConflict,
+		fmt.Sprintf("%s was changed by someone else", entity)).
+		WithHint("Reload it, reapply your changes and send the new version.").
+		WithCurrent(current)
+}
+
+// resourceVersion returns the version a resource edit is based on, from an
+// If-Match header holding the ETag of a previous GET. A missing version is
+// answered with 428 so edits cannot overwrite each other unnoticed. It
+// returns false when a response was written.
+func resourceVersion(c *gin.Context) (int64, bool) {
+	version, found, ok := requestedVersion(c, nil)
+	if !ok {
+		return 0, false
+	}
+	if !found {
+		ResponseWithAPIError(c, apierrors.New(http.StatusPreconditionRequired, apierrors.CodeVersionRequired,
+			"Resource version is required").WithField("If-Match").
+			WithHint("Send the ETag from the last GET of the resource as If-Match."))
+		return 0, false
+	}
+	return version, true
+}
+
+// resourceVersionConflict answers a versioned resource UPDATE that changed no
+// rows: 404 when the resource is gone, otherwise 409 with the current resource
+func resourceVersionConflict(c *gin.Context, db *sql.DB, id string) {
+	resource, err := loadResource(db, id)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching resource after version conflict: %v", err)
+		resource = map[string]interface{}{"id": id}
+	}
+	ResponseWithAPIError(c, apierrors.New(http.StatusConflict, apierrors.CodeVersionConflict,
+		"Resource was changed by someone else").
+		WithHint("Reload it, reapply your changes and send the new version.").
+		WithCurrent(resource))
+}
+
+// bumpResourceVersion moves a resource on to its next version within tx, for
+// edits stored outside the resources table. It returns false when the resource
+// is not at version, so the caller can roll back and report the conflict.
+func bumpResourceVersion(tx *sql.Tx, id string, version int64) (bool, error) {
+	result, err := tx.Exec(
+		"UPDATE resources SET updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		time.Now(), id, version,
+	)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestMiddlewareHandler_UpdateMiddleware_VersionConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMiddlewareHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'headers', 'headers', '{}')`)
+
+	update := func(body, ifMatch string) (int, map[string]interface{}) {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/middlewares/mw-1", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "mw-1"}}
+		if ifMatch != "" {
+			c.Request.Header.Set("If-Match", ifMatch)
+		}
+		handler.UpdateMiddleware(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	if code, _ := update(`{"name":"a","type":"headers","config":{}}`, ""); code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 without a version, got %d", code)
+	}
+
+	// First admin saves version 1
+	code, resp := update(`{"name":"first","type":"headers","config":{},"version":1}`, "")
+	if code != http.StatusOK || resp["version"] != float64(2) {
+		t.Fatalf("expected 200 with version 2, got %d: %v", code, resp)
+	}
+
+	// Second admin still holds version 1
+	code, resp = update(`{"name":"second","type":"headers","config":{}}`, `"1"`)
+	if code != http.StatusConflict || resp["code"] != "version_conflict" {
+		t.Fatalf("expected 409 version_conflict, got %d: %v", code, resp)
+	}
+	current, _ := resp["current"].(map[string]interface{})
+	if current["name"] != "first" || current["version"] != float64(2) {
+		t.Errorf("conflict should carry the current middleware, got %v", current)
+	}
+
+	if code, _ := update(`{"name":"second","type":"headers","config":{}}`, `"2"`); code != http.StatusOK {
+		t.Errorf("expected 200 with the current version, got %d", code)
+	}
+}
+
+func TestConfigHandler_ResourceVersionCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewConfigHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	setPriority := func(ifMatch string) int {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/priority",
+			bytes.NewBufferString(`{"router_priority": 300}`))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		if ifMatch != "" {
+			c.Request.Header.Set("If-Match", ifMatch)
+		}
+		handler.UpdateRouterPriority(c)
+		return rec.Code
+	}
+
+	if code := setPriority(""); code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 without If-Match, got %d", code)
+	}
+	if code := setPriority(`"1"`); code != http.StatusOK {
+		t.Fatalf("expected 200 with the current version, got %d", code)
+	}
+	if code := setPriority(`"1"`); code != http.StatusConflict {
+		t.Fatalf("expected 409 with a stale version, got %d", code)
+	}
+	if code := setPriority(`"2"`); code != http.StatusOK {
+		t.Fatalf("expected 200 with the new version, got %d", code)
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT version FROM resources WHERE id = 'res-1'").Scan(&version); err != nil || version != 3 {
+		t.Errorf("expected version 3 after two edits, got %d (%v)", version, err)
+	}
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/missing/config/priority",
+		bytes.NewBufferString(`{"router_priority": 300}`))
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+	c.Request.Header.Set("If-Match", `"1"`)
+	handler.UpdateRouterPriority(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing resource, got %d", rec.Code)
+	}
+}
+
+func TestResourceSettingsVersionCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	environments := NewEnvironmentHandler(db.DB, services.NewEnvironmentService(db), nil)
+	edits := []struct {
+		name    string
+		body    string
+		handler gin.HandlerFunc
+		table   string
+	}{
+		{"waf", `{"enabled":true,"paranoia_level":1}`, NewWAFHandler(db.DB, "").UpdateResourceWAF, "resource_waf"},
+		{"mirror", `{"target_url":"http://staging:8080","percent":10}`, NewMirrorHandler(db.DB).SetResourceMirror, "resource_mirrors"},
+		{"failover", `{"fallback_url":"http://backup:8080"}`, NewFailoverHandler(db.DB).SetResourceFailover, "resource_failovers"},
+		{"environments", `{"environments":[]}`, environments.SetResourceEnvironments, ""},
+	}
+
+	for _, edit := range edits {
+		send := func(ifMatch string) *httptest.ResponseRecorder {
+			t.Helper()
+			c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/"+edit.name, bytes.NewBufferString(edit.body))
+			c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+			if ifMatch != "" {
+				c.Request.Header.Set("If-Match", ifMatch)
+			}
+			edit.handler(c)
+			return rec
+		}
+
+		if rec := send(""); rec.Code != http.StatusPreconditionRequired {
+			t.Fatalf("%s: expected 428 without If-Match, got %d", edit.name, rec.Code)
+		}
+		rec := send(`"999"`)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusConflict || resp["code"] != "version_conflict" || resp["current"] == nil {
+			t.Fatalf("%s: expected 409 with the current resource for a stale version, got %d: %v", edit.name, rec.Code, resp)
+		}
+		if edit.table != "" {
+			var stored int
+			if err := db.QueryRow("SELECT COUNT(*) FROM " + edit.table).Scan(&stored); err != nil || stored != 0 {
+				t.Fatalf("%s: a stale edit must not be stored, found %d rows (%v)", edit.name, stored, err)
+			}
+		}
+
+		var version int64
+		if err := db.QueryRow("SELECT version FROM resources WHERE id = 'res-1'").Scan(&version); err != nil {
+			t.Fatalf("failed to read the resource version: %v", err)
+		}
+		rec = send(versionETag(version))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200 with the current version, got %d: %s", edit.name, rec.Code, rec.Body.String())
+		}
+		if etag := rec.Header().Get("ETag"); etag != versionETag(version+1) {
+			t.Errorf("%s: expected ETag %s, got %q", edit.name, versionETag(version+1), etag)
+		}
+	}
+}
+
+// setResourceVersion sends the current version of a resource as If-Match;
+// nothing is sent for a missing resource
+func setResourceVersion(t *testing.T, db *database.DB, c *gin.Context, id string) {
+	t.Helper()
+	var version int64
+	err := db.QueryRow("SELECT version FROM resources WHERE id = ?", id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return
+	} else if err != nil {
+		t.Fatalf("failed to read the version of %s: %v", id, err)
+	}
+	c.Request.Header.Set("If-Match", versionETag(version))
+}
@@ -9,11 +9,15 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
 )
 
 // ConfigHandler handles configuration-related requests
 type ConfigHandler struct {
-	DB *sql.DB
+	DB                      *sql.DB
+	TraefikStaticConfigPath string
 }
 
 // NewConfigHandler creates a new config handler
@@ -21,11 +25,16 @@ func NewConfigHandler(db *sql.DB) *ConfigHandler {
 	return &ConfigHandler{DB: db}
 }
 
+// SetTraefikConfigPath sets the Traefik static config path used by the mTLS pre-flight checks
+func (h *ConfigHandler) SetTraefikConfigPath(path string) {
+	h.TraefikStaticConfigPath = path
+}
+
 // UpdateRouterPriority updates the router priority for a resource
 func (h *ConfigHandler) UpdateRouterPriority(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -33,8 +42,7 @@ func (h *ConfigHandler) UpdateRouterPriority(c *gin.Context) {
 		RouterPriority int `json:"router_priority" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -47,13 +55,17 @@ func (h *ConfigHandler) UpdateRouterPriority(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow updating disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot update a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
 		return
 	}
 
@@ -61,7 +73,7 @@ func (h *ConfigHandler) UpdateRouterPriority(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -78,8 +90,8 @@ func (h *ConfigHandler) UpdateRouterPriority(c *gin.Context) {
 	// Set router_priority_manual = 1 to indicate this was set by the user
 	// This prevents Pangolin sync from overwriting user-configured priorities
 	result, txErr := tx.Exec(
-		"UPDATE resources SET router_priority = ?, router_priority_manual = 1, updated_at = ? WHERE id = ?",
-		input.RouterPriority, time.Now(), id,
+		"UPDATE resources SET router_priority = ?, router_priority_manual = 1, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		input.RouterPriority, time.Now(), id, version,
 	)
 
 	if txErr != nil {
@@ -88,19 +100,21 @@ func (h *ConfigHandler) UpdateRouterPriority(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err == nil {
-		log.Printf("Update affected %d rows", rowsAffected)
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
 	}
 
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully updated router priority for resource %s", id)
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
 		"id":              id,
 		"router_priority": input.RouterPriority,
@@ -111,7 +125,7 @@ func (h *ConfigHandler) UpdateRouterPriority(c *gin.Context) {
 func (h *ConfigHandler) UpdateHTTPConfig(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -119,8 +133,7 @@ func (h *ConfigHandler) UpdateHTTPConfig(c *gin.Context) {
 		Entrypoints string `json:"entrypoints"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -133,13 +146,17 @@ func (h *ConfigHandler) UpdateHTTPConfig(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow updating disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot update a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
 		return
 	}
 
@@ -152,7 +169,7 @@ func (h *ConfigHandler) UpdateHTTPConfig(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -167,8 +184,8 @@ func (h *ConfigHandler) UpdateHTTPConfig(c *gin.Context) {
 	log.Printf("Updating HTTP entrypoints for resource %s: %s", id, input.Entrypoints)
 
 	result, txErr := tx.Exec(
-		"UPDATE resources SET entrypoints = ?, updated_at = ? WHERE id = ?",
-		input.Entrypoints, time.Now(), id,
+		"UPDATE resources SET entrypoints = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		input.Entrypoints, time.Now(), id, version,
 	)
 
 	if txErr != nil {
@@ -177,22 +194,21 @@ func (h *ConfigHandler) UpdateHTTPConfig(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err == nil {
-		log.Printf("Update affected %d rows", rowsAffected)
-		if rowsAffected == 0 {
-			log.Printf("Warning: Update query succeeded but no rows were affected")
-		}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
 	}
 
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully updated HTTP entrypoints for resource %s", id)
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
 		"id":          id,
 		"entrypoints": input.Entrypoints,
@@ -203,7 +219,7 @@ func (h *ConfigHandler) UpdateHTTPConfig(c *gin.Context) {
 func (h *ConfigHandler) UpdateTLSConfig(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -211,10 +227,10 @@ func (h *ConfigHandler) UpdateTLSConfig(c *gin.Context) {
 		TLSDomains string `json:"tls_domains"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
+	input.TLSDomains = util.NormalizeHostList(input.TLSDomains)
 
 	// Verify resource exists and is active
 	var exists int
@@ -225,13 +241,17 @@ func (h *ConfigHandler) UpdateTLSConfig(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow updating disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot update a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
 		return
 	}
 
@@ -239,7 +259,7 @@ func (h *ConfigHandler) UpdateTLSConfig(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -254,8 +274,8 @@ func (h *ConfigHandler) UpdateTLSConfig(c *gin.Context) {
 	log.Printf("Updating TLS domains for resource %s: %s", id, input.TLSDomains)
 
 	result, txErr := tx.Exec(
-		"UPDATE resources SET tls_domains = ?, updated_at = ? WHERE id = ?",
-		input.TLSDomains, time.Now(), id,
+		"UPDATE resources SET tls_domains = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		input.TLSDomains, time.Now(), id, version,
 	)
 
 	if txErr != nil {
@@ -264,22 +284,21 @@ func (h *ConfigHandler) UpdateTLSConfig(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err == nil {
-		log.Printf("Update affected %d rows", rowsAffected)
-		if rowsAffected == 0 {
-			log.Printf("Warning: Update query succeeded but no rows were affected")
-		}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
 	}
 
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully updated TLS domains for resource %s", id)
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
 		"id":          id,
 		"tls_domains": input.TLSDomains,
@@ -290,7 +309,7 @@ func (h *ConfigHandler) UpdateTLSConfig(c *gin.Context) {
 func (h *ConfigHandler) UpdateTCPConfig(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -300,8 +319,7 @@ func (h *ConfigHandler) UpdateTCPConfig(c *gin.Context) {
 		TCPSNIRule     string `json:"tcp_sni_rule"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -314,13 +332,17 @@ func (h *ConfigHandler) UpdateTCPConfig(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow updating disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot update a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
 		return
 	}
 
@@ -339,7 +361,7 @@ func (h *ConfigHandler) UpdateTCPConfig(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -355,8 +377,8 @@ func (h *ConfigHandler) UpdateTCPConfig(c *gin.Context) {
 		id, input.TCPEnabled, input.TCPEntrypoints)
 
 	result, txErr := tx.Exec(
-		"UPDATE resources SET tcp_enabled = ?, tcp_entrypoints = ?, tcp_sni_rule = ?, updated_at = ? WHERE id = ?",
-		tcpEnabled, input.TCPEntrypoints, input.TCPSNIRule, time.Now(), id,
+		"UPDATE resources SET tcp_enabled = ?, tcp_entrypoints = ?, tcp_sni_rule = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		tcpEnabled, input.TCPEntrypoints, input.TCPSNIRule, time.Now(), id, version,
 	)
 
 	if txErr != nil {
@@ -365,22 +387,21 @@ func (h *ConfigHandler) UpdateTCPConfig(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err == nil {
-		log.Printf("Update affected %d rows", rowsAffected)
-		if rowsAffected == 0 {
-			log.Printf("Warning: Update query succeeded but no rows were affected")
-		}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
 	}
 
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully updated TCP configuration for resource %s", id)
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
 		"id":              id,
 		"tcp_enabled":     input.TCPEnabled,
@@ -393,7 +414,7 @@ func (h *ConfigHandler) UpdateTCPConfig(c *gin.Context) {
 func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -401,8 +422,7 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 		MTLSEnabled bool `json:"mtls_enabled"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -415,13 +435,17 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow updating disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot update a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
 		return
 	}
 
@@ -438,6 +462,18 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 			ResponseWithError(c, http.StatusBadRequest, "Cannot enable mTLS on resource: global mTLS is not enabled")
 			return
 		}
+
+		// Catch a missing plugin, CA file or TLS router now rather than as a failed handshake later
+		preflight, err := runMTLSPreflight(h.DB, h.TraefikStaticConfigPath, id)
+		if err != nil {
+			log.Printf("Error running mTLS pre-flight checks: %v", err)
+			ResponseWithError(c, http.StatusInternalServerError, "Failed to run mTLS pre-flight checks")
+			return
+		}
+		if !preflight.Ready {
+			ResponseWithAPIError(c, preflightError(preflight))
+			return
+		}
 	}
 
 	// Convert boolean to integer for SQLite
@@ -450,7 +486,7 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -468,13 +504,13 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 	var result sql.Result
 	if input.MTLSEnabled {
 		result, txErr = tx.Exec(
-			"UPDATE resources SET mtls_enabled = ?, tls_hardening_enabled = 0, updated_at = ? WHERE id = ?",
-			mtlsEnabled, time.Now(), id,
+			"UPDATE resources SET mtls_enabled = ?, tls_hardening_enabled = 0, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+			mtlsEnabled, time.Now(), id, version,
 		)
 	} else {
 		result, txErr = tx.Exec(
-			"UPDATE resources SET mtls_enabled = ?, updated_at = ? WHERE id = ?",
-			mtlsEnabled, time.Now(), id,
+			"UPDATE resources SET mtls_enabled = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+			mtlsEnabled, time.Now(), id, version,
 		)
 	}
 
@@ -484,18 +520,16 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err == nil {
-		log.Printf("Update affected %d rows", rowsAffected)
-		if rowsAffected == 0 {
-			log.Printf("Warning: Update query succeeded but no rows were affected")
-		}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
 	}
 
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -509,6 +543,7 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 		response["tls_hardening_enabled"] = false
 		response["message"] = "mTLS enabled. TLS hardening automatically disabled (mTLS includes TLS hardening)."
 	}
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -516,7 +551,7 @@ func (h *ConfigHandler) UpdateMTLSConfig(c *gin.Context) {
 func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -529,8 +564,7 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 		ExternalData    map[string]interface{} `json:"external_data"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -542,12 +576,12 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot update a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
 		return
 	}
 
@@ -587,11 +621,16 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 		rejectCode = *input.RejectCode
 	}
 
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
 	// Update the resource within a transaction
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -603,7 +642,7 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 		}
 	}()
 
-	_, txErr = tx.Exec(
+	result, txErr := tx.Exec(
 		`UPDATE resources 
 		 SET mtls_rules = ?, 
 		     mtls_request_headers = ?, 
@@ -611,8 +650,9 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 		     mtls_reject_code = ?, 
 		     mtls_refresh_interval = ?, 
 		     mtls_external_data = ?, 
-		     updated_at = ?
-		 WHERE id = ?`,
+		     updated_at = ?,
+		     version = version + 1
+		 WHERE id = ? AND version = ?`,
 		rulesJSON,
 		requestHeadersJSON,
 		input.RejectMessage,
@@ -621,6 +661,7 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 		externalDataJSON,
 		time.Now(),
 		id,
+		version,
 	)
 
 	if txErr != nil {
@@ -628,13 +669,19 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 		ResponseWithError(c, http.StatusInternalServerError, "Failed to update mTLS whitelist configuration")
 		return
 	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
 
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
 		"id":                    id,
 		"mtls_rules":            rulesJSON,
@@ -646,11 +693,227 @@ func (h *ConfigHandler) UpdateMTLSWhitelistConfig(c *gin.Context) {
 	})
 }
 
+// UpdateMTLSExemptions replaces the path prefixes of a resource that are
+// routed without the mTLS middleware (e.g. webhooks that cannot present a certificate)
+func (h *ConfigHandler) UpdateMTLSExemptions(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+
+	var input models.UpdateMTLSExemptionsRequest
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	paths, err := models.NormalizeMTLSExemptPaths(input.Paths)
+	if err != nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			err.Error()).WithField("paths"))
+		return
+	}
+
+	var status string
+	err = h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	pathsJSON, err := json.Marshal(paths)
+	if err != nil {
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to encode exempt paths")
+		return
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE resources SET mtls_exempt_paths = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		string(pathsJSON), time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating mTLS exempt paths: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update mTLS exempt paths")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Updated mTLS exempt paths for resource %s: %v", id, paths)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"id":                id,
+		"mtls_exempt_paths": paths,
+	})
+}
+
+// UpdateMiddlewareOrder sets where MM's middlewares go in the resource's router
+// chain relative to the upstream ones: before them, after them, or at
+// explicit positions (e.g. forward auth after Pangolin's badger)
+func (h *ConfigHandler) UpdateMiddlewareOrder(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+
+	var order models.MiddlewareOrder
+	if !bindRequest(c, &order) {
+		return
+	}
+	order.Normalize()
+	if err := order.Validate(); err != nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			err.Error()).WithField("policy"))
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	positions := order.Positions
+	if positions == nil {
+		positions = map[string]int{}
+	}
+	positionsJSON, err := json.Marshal(positions)
+	if err != nil {
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to encode middleware positions")
+		return
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE resources SET middleware_order = ?, middleware_positions = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		order.Policy, string(positionsJSON), time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating middleware order: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update middleware order")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Set middleware order of resource %s to %s", id, order.Policy)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"id":               id,
+		"middleware_order": order,
+	})
+}
+
+// UpdateUpstreamMiddlewares sets which middlewares the upstream attached to the
+// resource's router are removed or replaced during the merge. The response
+// warns when a removal takes authentication off the resource.
+func (h *ConfigHandler) UpdateUpstreamMiddlewares(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+
+	var input models.UpdateUpstreamMiddlewaresRequest
+	if !bindRequest(c, &input) {
+		return
+	}
+	removals, err := models.NormalizeUpstreamMiddlewareRemovals(input.Removals)
+	if err != nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			err.Error()).WithField("removals"))
+		return
+	}
+
+	var status string
+	err = h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	removalsJSON, err := json.Marshal(removals)
+	if err != nil {
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to encode upstream middleware removals")
+		return
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE resources SET upstream_middleware_removals = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		string(removalsJSON), time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating upstream middleware removals: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update upstream middlewares")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	warnings := models.UpstreamRemovalWarnings(removals)
+	for _, warning := range warnings {
+		log.Printf("Warning: resource %s: %s", id, warning)
+	}
+	log.Printf("Set %d upstream middleware removals for resource %s", len(removals), id)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"id":                           id,
+		"upstream_middleware_removals": removals,
+		"warnings":                     warnings,
+	})
+}
+
 // UpdateHeadersConfig updates the custom headers configuration
 func (h *ConfigHandler) UpdateHeadersConfig(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -658,8 +921,7 @@ func (h *ConfigHandler) UpdateHeadersConfig(c *gin.Context) {
 		CustomHeaders map[string]string `json:"custom_headers" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -672,13 +934,17 @@ func (h *ConfigHandler) UpdateHeadersConfig(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow updating disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot update a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
 		return
 	}
 
@@ -694,7 +960,7 @@ func (h *ConfigHandler) UpdateHeadersConfig(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -710,8 +976,8 @@ func (h *ConfigHandler) UpdateHeadersConfig(c *gin.Context) {
 		id, len(input.CustomHeaders))
 
 	result, txErr := tx.Exec(
-		"UPDATE resources SET custom_headers = ?, updated_at = ? WHERE id = ?",
-		string(headersJSON), time.Now(), id,
+		"UPDATE resources SET custom_headers = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		string(headersJSON), time.Now(), id, version,
 	)
 
 	if txErr != nil {
@@ -720,18 +986,16 @@ func (h *ConfigHandler) UpdateHeadersConfig(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err == nil {
-		log.Printf("Update affected %d rows", rowsAffected)
-		if rowsAffected == 0 {
-			log.Printf("Warning: Update query succeeded but no rows were affected")
-		}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
 	}
 
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -747,6 +1011,7 @@ func (h *ConfigHandler) UpdateHeadersConfig(c *gin.Context) {
 	}
 
 	log.Printf("Successfully updated custom headers for resource %s", id)
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
 		"id":             id,
 		"custom_headers": input.CustomHeaders,
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -37,6 +39,7 @@ func TestConfigHandler_UpdateRouterPriority(t *testing.T) {
 	body := bytes.NewBufferString(`{"router_priority": 500}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/test-res/priority", body)
 	c.Params = gin.Params{{Key: "id", Value: "test-res"}}
+	setResourceVersion(t, db, c, "test-res")
 	handler.UpdateRouterPriority(c)
 
 	if rec.Code != http.StatusOK {
@@ -70,6 +73,7 @@ func TestConfigHandler_UpdateRouterPriority_NotFound(t *testing.T) {
 	body := bytes.NewBufferString(`{"router_priority": 100}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/nonexistent/priority", body)
 	c.Params = gin.Params{{Key: "id", Value: "nonexistent"}}
+	setResourceVersion(t, db, c, "nonexistent")
 	handler.UpdateRouterPriority(c)
 
 	if rec.Code != http.StatusNotFound {
@@ -90,6 +94,7 @@ func TestConfigHandler_UpdateRouterPriority_DisabledResource(t *testing.T) {
 	body := bytes.NewBufferString(`{"router_priority": 200}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/disabled-res/priority", body)
 	c.Params = gin.Params{{Key: "id", Value: "disabled-res"}}
+	setResourceVersion(t, db, c, "disabled-res")
 	handler.UpdateRouterPriority(c)
 
 	if rec.Code != http.StatusBadRequest {
@@ -105,6 +110,7 @@ func TestConfigHandler_UpdateRouterPriority_EmptyID(t *testing.T) {
 	body := bytes.NewBufferString(`{"router_priority": 100}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources//priority", body)
 	c.Params = gin.Params{{Key: "id", Value: ""}}
+	setResourceVersion(t, db, c, "")
 	handler.UpdateRouterPriority(c)
 
 	if rec.Code != http.StatusBadRequest {
@@ -125,6 +131,7 @@ func TestConfigHandler_UpdateRouterPriority_InvalidJSON(t *testing.T) {
 	body := bytes.NewBufferString(`{invalid}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/test-res/priority", body)
 	c.Params = gin.Params{{Key: "id", Value: "test-res"}}
+	setResourceVersion(t, db, c, "test-res")
 	handler.UpdateRouterPriority(c)
 
 	if rec.Code != http.StatusBadRequest {
@@ -145,10 +152,11 @@ func TestConfigHandler_UpdateRouterPriority_MissingPriority(t *testing.T) {
 	body := bytes.NewBufferString(`{}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/test-res/priority", body)
 	c.Params = gin.Params{{Key: "id", Value: "test-res"}}
+	setResourceVersion(t, db, c, "test-res")
 	handler.UpdateRouterPriority(c)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", rec.Code)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", rec.Code)
 	}
 }
 
@@ -165,6 +173,7 @@ func TestConfigHandler_UpdateHTTPConfig(t *testing.T) {
 	body := bytes.NewBufferString(`{"entrypoints": "web,websecure"}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/test-res/http", body)
 	c.Params = gin.Params{{Key: "id", Value: "test-res"}}
+	setResourceVersion(t, db, c, "test-res")
 	handler.UpdateHTTPConfig(c)
 
 	if rec.Code != http.StatusOK {
@@ -194,6 +203,7 @@ func TestConfigHandler_UpdateHTTPConfig_DefaultEntrypoints(t *testing.T) {
 	body := bytes.NewBufferString(`{"entrypoints": ""}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/test-res/http", body)
 	c.Params = gin.Params{{Key: "id", Value: "test-res"}}
+	setResourceVersion(t, db, c, "test-res")
 	handler.UpdateHTTPConfig(c)
 
 	if rec.Code != http.StatusOK {
@@ -216,6 +226,7 @@ func TestConfigHandler_UpdateHTTPConfig_NotFound(t *testing.T) {
 	body := bytes.NewBufferString(`{"entrypoints": "web"}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/nonexistent/http", body)
 	c.Params = gin.Params{{Key: "id", Value: "nonexistent"}}
+	setResourceVersion(t, db, c, "nonexistent")
 	handler.UpdateHTTPConfig(c)
 
 	if rec.Code != http.StatusNotFound {
@@ -236,9 +247,121 @@ func TestConfigHandler_UpdateHTTPConfig_DisabledResource(t *testing.T) {
 	body := bytes.NewBufferString(`{"entrypoints": "web"}`)
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/config/resources/disabled-res/http", body)
 	c.Params = gin.Params{{Key: "id", Value: "disabled-res"}}
+	setResourceVersion(t, db, c, "disabled-res")
 	handler.UpdateHTTPConfig(c)
 
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("expected 400, got %d", rec.Code)
 	}
 }
+
+// TestConfigHandler_UpdateMTLSExemptions tests storing and validating exempt paths
+func TestConfigHandler_UpdateMTLSExemptions(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')
+	`)
+	handler := NewConfigHandler(db.DB)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/mtls/exemptions",
+		strings.NewReader(`{"paths": ["/api/webhook", " /api/webhook "]}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateMTLSExemptions(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	resource, err := loadResource(db.DB, "res-1")
+	if err != nil {
+		t.Fatalf("loadResource: %v", err)
+	}
+	if paths, _ := resource["mtls_exempt_paths"].([]string); len(paths) != 1 || paths[0] != "/api/webhook" {
+		t.Errorf("expected stored paths [/api/webhook], got %v", resource["mtls_exempt_paths"])
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/mtls/exemptions",
+		strings.NewReader(`{"paths": ["webhook"]}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateMTLSExemptions(c)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a relative path, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestConfigHandler_UpdateMiddlewareOrder tests setting and validating a resource's middleware order
+func TestConfigHandler_UpdateMiddlewareOrder(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewConfigHandler(db.DB)
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/middleware-order", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.UpdateMiddlewareOrder(c)
+		return rec
+	}
+
+	if rec := put(`{"policy": "interleave", "positions": {"res-1-forwardauth": 1}}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var policy, positions string
+	if err := db.QueryRow("SELECT middleware_order, middleware_positions FROM resources WHERE id = 'res-1'").Scan(&policy, &positions); err != nil {
+		t.Fatalf("query resource: %v", err)
+	}
+	if policy != "interleave" || positions != `{"res-1-forwardauth":1}` {
+		t.Errorf("stored order = %s %s", policy, positions)
+	}
+
+	if rec := put(`{"policy": "last"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid policy status = %d, want 422", rec.Code)
+	}
+}
+
+// TestConfigHandler_UpdateUpstreamMiddlewares tests storing upstream middleware removals and their warnings
+func TestConfigHandler_UpdateUpstreamMiddlewares(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewConfigHandler(db.DB)
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/upstream-middlewares", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.UpdateUpstreamMiddlewares(c)
+		return rec
+	}
+
+	rec := put(`{"removals": [{"name": " badger@http "}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Errorf("warnings = %v, want one for badger", resp.Warnings)
+	}
+	var stored string
+	if err := db.QueryRow("SELECT upstream_middleware_removals FROM resources WHERE id = 'res-1'").Scan(&stored); err != nil {
+		t.Fatalf("query resource: %v", err)
+	}
+	if stored != `[{"name":"badger@http"}]` {
+		t.Errorf("stored removals = %s", stored)
+	}
+
+	if rec := put(`{"removals": [{"name": "a"}, {"name": "a"}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("duplicate removal status = %d, want 422", rec.Code)
+	}
+}
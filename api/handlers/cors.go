@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// CORSHandler handles CORS policy requests
+type CORSHandler struct {
+	DB *sql.DB
+}
+
+// NewCORSHandler creates a new CORS policy handler
+func NewCORSHandler(db *sql.DB) *CORSHandler {
+	return &CORSHandler{DB: db}
+}
+
+const corsPolicyColumns = `id, name, allow_origins, allow_methods, allow_headers, expose_headers,
+		       allow_credentials, max_age, created_at, updated_at`
+
+type corsScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCORSPolicy(row corsScanner) (models.CORSPolicy, error) {
+	var policy models.CORSPolicy
+	var origins, methods, headers, expose string
+	var credentials int
+	err := row.Scan(&policy.ID, &policy.Name, &origins, &methods, &headers, &expose,
+		&credentials, &policy.MaxAge, &policy.CreatedAt, &policy.UpdatedAt)
+	if err != nil {
+		return policy, err
+	}
+	_ = json.Unmarshal([]byte(origins), &policy.AllowOrigins)
+	_ = json.Unmarshal([]byte(methods), &policy.AllowMethods)
+	_ = json.Unmarshal([]byte(headers), &policy.AllowHeaders)
+	_ = json.Unmarshal([]byte(expose), &policy.ExposeHeaders)
+	policy.AllowCredentials = credentials == 1
+	return policy, nil
+}
+
+// GetCORSPolicies returns all CORS policies
+func (h *CORSHandler) GetCORSPolicies(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT " + corsPolicyColumns + " FROM cors_policies ORDER BY name")
+	if err != nil {
+		log.Printf("Error fetching CORS policies: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch CORS policies")
+		return
+	}
+	defer rows.Close()
+
+	policies := []models.CORSPolicy{}
+	for rows.Next() {
+		policy, err := scanCORSPolicy(rows)
+		if err != nil {
+			log.Printf("Error scanning CORS policy row: %v", err)
+			continue
+		}
+		policies = append(policies, policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating CORS policy rows: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Database error while fetching CORS policies")
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetCORSPolicy returns a single CORS policy along with the headers it generates
+func (h *CORSHandler) GetCORSPolicy(c *gin.Context) {
+	id := c.Param("id")
+	policy, err := scanCORSPolicy(h.DB.QueryRow("SELECT "+corsPolicyColumns+" FROM cors_policies WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "CORS policy not found")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching CORS policy: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policy":  policy,
+		"headers": policy.HeadersConfig(),
+	})
+}
+
+// CreateCORSPolicy validates and stores a new CORS policy
+func (h *CORSHandler) CreateCORSPolicy(c *gin.Context) {
+	var policy models.CORSPolicy
+	if !bindRequest(c, &policy) {
+		return
+	}
+
+	policy.Normalize()
+	if err := policy.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid CORS policy: %v", err))
+		return
+	}
+
+	id, err := generateID()
+	if err != nil {
+		log.Printf("Error generating ID: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to generate ID")
+		return
+	}
+	policy.ID = id
+
+	args := corsPolicyArgs(&policy)
+	_, err = h.DB.Exec(`
+		INSERT INTO cors_policies (name, allow_origins, allow_methods, allow_headers, expose_headers,
+		                           allow_credentials, max_age, id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, append(args, id)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			ResponseWithError(c, http.StatusConflict, fmt.Sprintf("CORS policy %q already exists", policy.Name))
+			return
+		}
+		log.Printf("Error inserting CORS policy: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save CORS policy")
+		return
+	}
+
+	log.Printf("Created CORS policy %s (%s)", policy.Name, id)
+	c.JSON(http.StatusCreated, policy)
+}
+
+// UpdateCORSPolicy validates and replaces an existing CORS policy
+func (h *CORSHandler) UpdateCORSPolicy(c *gin.Context) {
+	id := c.Param("id")
+	var policy models.CORSPolicy
+	if !bindRequest(c, &policy) {
+		return
+	}
+
+	policy.Normalize()
+	if err := policy.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid CORS policy: %v", err))
+		return
+	}
+	policy.ID = id
+
+	args := corsPolicyArgs(&policy)
+	result, err := h.DB.Exec(`
+		UPDATE cors_policies SET name = ?, allow_origins = ?, allow_methods = ?, allow_headers = ?,
+		       expose_headers = ?, allow_credentials = ?, max_age = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, append(args, id)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			ResponseWithError(c, http.StatusConflict, fmt.Sprintf("CORS policy %q already exists", policy.Name))
+			return
+		}
+		log.Printf("Error updating CORS policy: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update CORS policy")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		ResponseWithError(c, http.StatusNotFound, "CORS policy not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteCORSPolicy deletes a CORS policy that is not attached to any resource
+func (h *CORSHandler) DeleteCORSPolicy(c *gin.Context) {
+	id := c.Param("id")
+
+	var inUse int
+	if err := h.DB.QueryRow("SELECT COUNT(*) FROM resources WHERE cors_policy_id = ?", id).Scan(&inUse); err != nil {
+		log.Printf("Error checking CORS policy usage: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if inUse > 0 {
+		ResponseWithError(c, http.StatusConflict, fmt.Sprintf("CORS policy is attached to %d resource(s)", inUse))
+		return
+	}
+
+	result, err := h.DB.Exec("DELETE FROM cors_policies WHERE id = ?", id)
+	if err != nil {
+		log.Printf("Error deleting CORS policy: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete CORS policy")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		ResponseWithError(c, http.StatusNotFound, "CORS policy not found")
+		return
+	}
+
+	log.Printf("Deleted CORS policy %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "CORS policy deleted successfully"})
+}
+
+// UpdateResourceCORS attaches a CORS policy to a resource; an empty ID detaches it
+func (h *CORSHandler) UpdateResourceCORS(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+
+	var input struct {
+		CORSPolicyID string `json:"cors_policy_id"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	var exists int
+	var status string
+	err := h.DB.QueryRow("SELECT 1, status FROM resources WHERE id = ?", id).Scan(&exists, &status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	if input.CORSPolicyID != "" {
+		err := h.DB.QueryRow("SELECT 1 FROM cors_policies WHERE id = ?", input.CORSPolicyID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			ResponseWithError(c, http.StatusNotFound, "CORS policy not found")
+			return
+		} else if err != nil {
+			log.Printf("Error checking CORS policy existence: %v", err)
+			ResponseWithAPIError(c, errDatabase)
+			return
+		}
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE resources SET cors_policy_id = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		input.CORSPolicyID, time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating resource CORS policy: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update CORS policy")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Set CORS policy for resource %s to %q", id, input.CORSPolicyID)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"id":             id,
+		"cors_policy_id": input.CORSPolicyID,
+	})
+}
+
+// corsPolicyArgs returns the column values shared by insert and update, in column order
+func corsPolicyArgs(policy *models.CORSPolicy) []interface{} {
+	encode := func(values []string) string {
+		if values == nil {
+			values = []string{}
+		}
+		b, _ := json.Marshal(values)
+		return string(b)
+	}
+	credentials := 0
+	if policy.AllowCredentials {
+		credentials = 1
+	}
+	return []interface{}{
+		policy.Name,
+		encode(policy.AllowOrigins),
+		encode(policy.AllowMethods),
+		encode(policy.AllowHeaders),
+		encode(policy.ExposeHeaders),
+		credentials,
+		policy.MaxAge,
+	}
+}
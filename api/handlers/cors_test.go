@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestCORSHandler_RejectsWildcardWithCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewCORSHandler(db.DB)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/cors-policies",
+		bytes.NewBufferString(`{"name":"bad","allow_origins":["*"],"allow_credentials":true}`))
+	handler.CreateCORSPolicy(c)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCORSHandler_CreateAttachAndDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewCORSHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/cors-policies",
+		bytes.NewBufferString(`{"name":"spa","allow_origins":["https://spa.example.com"],"allow_methods":["get","post"],"allow_credentials":true,"max_age":600}`))
+	handler.CreateCORSPolicy(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	policyID := created["id"].(string)
+	if methods := created["allow_methods"].([]interface{}); methods[0] != "GET" {
+		t.Errorf("expected methods to be normalized, got %v", methods)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/cors",
+		bytes.NewBufferString(`{"cors_policy_id":"`+policyID+`"}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateResourceCORS(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 attaching policy, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Attached policies cannot be deleted
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/cors-policies/"+policyID, nil)
+	c.Params = gin.Params{{Key: "id", Value: policyID}}
+	handler.DeleteCORSPolicy(c)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 deleting attached policy, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/cors",
+		bytes.NewBufferString(`{"cors_policy_id":""}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateResourceCORS(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 detaching policy, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/cors-policies/"+policyID, nil)
+	c.Params = gin.Params{{Key: "id", Value: policyID}}
+	handler.DeleteCORSPolicy(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 deleting detached policy, got %d", rec.Code)
+	}
+}
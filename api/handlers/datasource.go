@@ -61,8 +61,7 @@ func (h *DataSourceHandler) SetActiveDataSource(c *gin.Context) {
         Name string `json:"name" binding:"required"`
     }
     
-    if err := c.ShouldBindJSON(&request); err != nil {
-        ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+    if !bindRequest(c, &request) {
         return
     }
     
@@ -81,17 +80,38 @@ func (h *DataSourceHandler) SetActiveDataSource(c *gin.Context) {
     })
 }
 
+// GetFailover returns the failover settings and which data source resource
+// discovery is currently reading from
+func (h *DataSourceHandler) GetFailover(c *gin.Context) {
+    c.JSON(http.StatusOK, h.ConfigManager.FailoverStatus())
+}
+
+// SetFailover configures falling back to a secondary data source while the
+// active one is unreachable
+func (h *DataSourceHandler) SetFailover(c *gin.Context) {
+    var config models.DataSourceFailover
+    if !bindRequest(c, &config) {
+        return
+    }
+    
+    if err := h.ConfigManager.SetFailoverConfig(config); err != nil {
+        ResponseWithError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    c.JSON(http.StatusOK, h.ConfigManager.FailoverStatus())
+}
+
 // UpdateDataSource updates a data source configuration
 func (h *DataSourceHandler) UpdateDataSource(c *gin.Context) {
     name := c.Param("name")
     if name == "" {
-        ResponseWithError(c, http.StatusBadRequest, "Data source name is required")
+        ResponseWithAPIError(c, missingFieldError("name", "Data source name is required"))
         return
     }
     
     var config models.DataSourceConfig
-    if err := c.ShouldBindJSON(&config); err != nil {
-        ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+    if !bindRequest(c, &config) {
         return
     }
     
@@ -111,7 +131,7 @@ func (h *DataSourceHandler) UpdateDataSource(c *gin.Context) {
 func (h *DataSourceHandler) TestDataSourceConnection(c *gin.Context) {
     name := c.Param("name")
     if name == "" {
-        ResponseWithError(c, http.StatusBadRequest, "Data source name is required")
+        ResponseWithAPIError(c, missingFieldError("name", "Data source name is required"))
         return
     }
     
@@ -153,8 +173,9 @@ func (h *DataSourceHandler) TestDataSourceConnection(c *gin.Context) {
 // testDataSourceConnection tests the connection to a data source using different endpoints
 // based on the data source type
 func testDataSourceConnection(ctx context.Context, config models.DataSourceConfig) error {
-    client := &http.Client{
-        Timeout: 5 * time.Second,
+    client, err := services.DataSourceHTTPClient(services.HTTPClientWithTimeout(5*time.Second), config)
+    if err != nil {
+        return fmt.Errorf("connection failed: %w", err)
     }
     
     var url string
@@ -174,10 +195,8 @@ func testDataSourceConnection(ctx context.Context, config models.DataSourceConfi
         return fmt.Errorf("failed to create request: %w", err)
     }
     
-    // Add basic auth if configured
-    if config.BasicAuth.Username != "" {
-        req.SetBasicAuth(config.BasicAuth.Username, config.BasicAuth.Password)
-    }
+    // Add basic auth and custom headers if configured
+    services.AuthorizeDataSourceRequest(req, config)
     
     resp, err := client.Do(req)
     if err != nil {
@@ -100,7 +100,7 @@ func TestDataSourceHandler_SetActiveDataSource(t *testing.T) {
 		{
 			name:       "missing name",
 			body:       `{}`,
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:       "invalid JSON",
@@ -232,3 +232,53 @@ func TestDataSourceHandler_TestDataSourceConnection_NotFound(t *testing.T) {
 		t.Errorf("expected 404, got %d", rec.Code)
 	}
 }
+
+// TestDataSourceHandler_SetFailover tests failover validation and status
+func TestDataSourceHandler_SetFailover(t *testing.T) {
+	cm := testutil.NewTestConfigManager(t)
+	handler := NewDataSourceHandler(cm)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"traefik as secondary", `{"enabled": true, "secondary": "traefik", "after_minutes": 3}`, http.StatusOK},
+		{"secondary is the active source", `{"enabled": true, "secondary": "pangolin"}`, http.StatusBadRequest},
+		{"unknown secondary", `{"enabled": true, "secondary": "nope"}`, http.StatusBadRequest},
+		{"missing secondary", `{"enabled": true}`, http.StatusBadRequest},
+		{"disabled", `{"enabled": false}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := testutil.NewContext(t, http.MethodPut, "/api/datasource/failover", bytes.NewBufferString(tt.body))
+			handler.SetFailover(c)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	c, _ := testutil.NewContext(t, http.MethodPut, "/api/datasource/failover",
+		bytes.NewBufferString(`{"enabled": true, "secondary": "traefik"}`))
+	handler.SetFailover(c)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/datasource/failover", nil)
+	handler.GetFailover(c)
+
+	var status struct {
+		Config struct {
+			Enabled   bool   `json:"enabled"`
+			Secondary string `json:"secondary"`
+		} `json:"config"`
+		Primary string `json:"primary"`
+		Serving string `json:"serving"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !status.Config.Enabled || status.Config.Secondary != "traefik" || status.Primary != "pangolin" || status.Serving != "pangolin" {
+		t.Errorf("unexpected failover status: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// DefaultChainHandler manages the global default middleware chain and the
+// resources excluded from it
+type DefaultChainHandler struct {
+	DB *sql.DB
+}
+
+// NewDefaultChainHandler creates a new default chain handler
+func NewDefaultChainHandler(db *sql.DB) *DefaultChainHandler {
+	return &DefaultChainHandler{DB: db}
+}
+
+// GetDefaultChain returns the chain's middlewares, highest priority first,
+// and the IDs of the resources excluded from it
+func (h *DefaultChainHandler) GetDefaultChain(c *gin.Context) {
+	chain, err := h.loadChain()
+	if err != nil {
+		log.Printf("Error fetching default chain: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch default chain")
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT id FROM resources WHERE default_chain_excluded = 1 ORDER BY id")
+	if err != nil {
+		log.Printf("Error fetching excluded resources: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	defer rows.Close()
+
+	excluded := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("Error scanning excluded resource: %v", err)
+			continue
+		}
+		excluded = append(excluded, id)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"middlewares": chain, "excluded_resources": excluded})
+}
+
+// UpdateDefaultChain replaces the chain's middlewares; an empty list turns
+// the chain off
+func (h *DefaultChainHandler) UpdateDefaultChain(c *gin.Context) {
+	var input struct {
+		Middlewares []models.DefaultChainMiddleware `json:"middlewares"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i, mw := range input.Middlewares {
+		if mw.MiddlewareID == "" {
+			ResponseWithAPIError(c, missingFieldError("middleware_id", "Middleware ID is required"))
+			return
+		}
+		if seen[mw.MiddlewareID] {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Middleware %s is listed twice", mw.MiddlewareID))
+			return
+		}
+		seen[mw.MiddlewareID] = true
+
+		// Default priority is 200 if not specified, as for resources
+		if mw.Priority <= 0 {
+			input.Middlewares[i].Priority = 200
+		}
+
+		var exists int
+		err := h.DB.QueryRow("SELECT 1 FROM middlewares WHERE id = ?", mw.MiddlewareID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			ResponseWithError(c, http.StatusNotFound, fmt.Sprintf("Middleware %s not found", mw.MiddlewareID))
+			return
+		} else if err != nil {
+			log.Printf("Error checking middleware existence: %v", err)
+			ResponseWithAPIError(c, errDatabase)
+			return
+		}
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		log.Printf("Error beginning transaction: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	var txErr error
+	defer func() {
+		if txErr != nil {
+			tx.Rollback()
+			log.Printf("Transaction rolled back due to error: %v", txErr)
+		}
+	}()
+
+	if _, txErr = tx.Exec("DELETE FROM default_chain_middlewares"); txErr != nil {
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update default chain")
+		return
+	}
+	now := time.Now()
+	for _, mw := range input.Middlewares {
+		if _, txErr = tx.Exec(
+			"INSERT INTO default_chain_middlewares (middleware_id, priority, created_at) VALUES (?, ?, ?)",
+			mw.MiddlewareID, mw.Priority, now,
+		); txErr != nil {
+			ResponseWithError(c, http.StatusInternalServerError, "Failed to update default chain")
+			return
+		}
+	}
+	if txErr = tx.Commit(); txErr != nil {
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	chain, err := h.loadChain()
+	if err != nil {
+		log.Printf("Error fetching default chain: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Default middleware chain set to %d middlewares", len(chain))
+	c.JSON(http.StatusOK, gin.H{"middlewares": chain})
+}
+
+// UpdateResourceDefaultChain excludes a resource's router from the default
+// chain, or includes it again
+func (h *DefaultChainHandler) UpdateResourceDefaultChain(c *gin.Context) {
+	id := c.Param("id")
+	var input struct {
+		Excluded bool `json:"excluded"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	excluded := 0
+	if input.Excluded {
+		excluded = 1
+	}
+	result, err := h.DB.Exec(
+		"UPDATE resources SET default_chain_excluded = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		excluded, time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating default chain exclusion: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update default chain exclusion")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Set default chain exclusion for resource %s to %v", id, input.Excluded)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"id":                     id,
+		"default_chain_excluded": input.Excluded,
+	})
+}
+
+// loadChain returns the chain's middlewares, highest priority first
+func (h *DefaultChainHandler) loadChain() ([]models.DefaultChainMiddleware, error) {
+	rows, err := h.DB.Query(`
+		SELECT dc.middleware_id, m.name, dc.priority
+		FROM default_chain_middlewares dc
+		JOIN middlewares m ON m.id = dc.middleware_id
+		ORDER BY dc.priority DESC, m.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chain := []models.DefaultChainMiddleware{}
+	for rows.Next() {
+		var mw models.DefaultChainMiddleware
+		if err := rows.Scan(&mw.MiddlewareID, &mw.MiddlewareName, &mw.Priority); err != nil {
+			return nil, err
+		}
+		chain = append(chain, mw)
+	}
+	return chain, rows.Err()
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// TestDefaultChainHandler_UpdateDefaultChain tests replacing and reading the default chain
+func TestDefaultChainHandler_UpdateDefaultChain(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewDefaultChainHandler(db.DB)
+	testutil.MustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES
+		('mw-1', 'secure-headers', 'headers', '{}'),
+		('mw-2', 'gzip', 'compress', '{}')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/default-chain",
+		strings.NewReader(`{"middlewares": [{"middleware_id": "missing"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.UpdateDefaultChain(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown middleware status = %d, want 404", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/default-chain",
+		strings.NewReader(`{"middlewares": [{"middleware_id": "mw-2", "priority": 100}, {"middleware_id": "mw-1"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.UpdateDefaultChain(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/default-chain", nil)
+	handler.GetDefaultChain(c)
+	var response struct {
+		Middlewares []models.DefaultChainMiddleware `json:"middlewares"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(response.Middlewares) != 2 || response.Middlewares[0].MiddlewareName != "secure-headers" ||
+		response.Middlewares[0].Priority != 200 || response.Middlewares[1].MiddlewareID != "mw-2" {
+		t.Errorf("unexpected default chain %+v", response.Middlewares)
+	}
+
+	// Middlewares in the default chain cannot be deleted
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/middlewares/mw-1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "mw-1"}}
+	NewMiddlewareHandler(db.DB).DeleteMiddleware(c)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("deleting a default chain middleware status = %d, want 409", rec.Code)
+	}
+}
+
+// TestDefaultChainHandler_UpdateResourceDefaultChain tests excluding a resource from the default chain
+func TestDefaultChainHandler_UpdateResourceDefaultChain(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewDefaultChainHandler(db.DB)
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/default-chain",
+		strings.NewReader(`{"excluded": true}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateResourceDefaultChain(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var excluded, version int
+	if err := db.QueryRow("SELECT default_chain_excluded, version FROM resources WHERE id = 'res-1'").Scan(&excluded, &version); err != nil {
+		t.Fatalf("query resource: %v", err)
+	}
+	if excluded != 1 || version != 2 {
+		t.Errorf("default_chain_excluded = %d, version = %d, want 1 and 2", excluded, version)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/missing/config/default-chain",
+		strings.NewReader(`{"excluded": true}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+	setResourceVersion(t, db, c, "missing")
+	handler.UpdateResourceDefaultChain(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown resource status = %d, want 404", rec.Code)
+	}
+}
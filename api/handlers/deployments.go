@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// DeploymentHandler drives blue/green deployments of resources
+type DeploymentHandler struct {
+	DB          *sql.DB
+	Deployer    *services.BlueGreenDeployer
+	ConfigProxy *services.ConfigProxy
+}
+
+// NewDeploymentHandler creates a new deployment handler
+func NewDeploymentHandler(db *sql.DB, deployer *services.BlueGreenDeployer, configProxy *services.ConfigProxy) *DeploymentHandler {
+	return &DeploymentHandler{DB: db, Deployer: deployer, ConfigProxy: configProxy}
+}
+
+// GetDeployments returns every blue/green deployment
+func (h *DeploymentHandler) GetDeployments(c *gin.Context) {
+	deployments, err := h.Deployer.List()
+	if err != nil {
+		log.Printf("Error fetching deployments: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch deployments")
+		return
+	}
+	c.JSON(http.StatusOK, deployments)
+}
+
+// GetResourceDeployment returns the deployment of a resource and the services it generates
+func (h *DeploymentHandler) GetResourceDeployment(c *gin.Context) {
+	id := c.Param("id")
+	deployment, err := h.Deployer.Get(id)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "No deployment for this resource")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching deployment: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	h.respondWithDeployment(c, http.StatusOK, deployment)
+}
+
+// StartDeployment stages a new green backend for a resource, optionally
+// mirroring a share of the requests to it
+func (h *DeploymentHandler) StartDeployment(c *gin.Context) {
+	id := c.Param("id")
+	var deployment models.ResourceDeployment
+	if !bindRequest(c, &deployment) {
+		return
+	}
+
+	deployment.Normalize()
+	if err := deployment.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid deployment: %v", err))
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot deploy a disabled resource"))
+		return
+	}
+
+	deployment.ResourceID = id
+	staged, err := h.Deployer.Begin(deployment)
+	if h.respondWithStageError(c, err, "Failed to start deployment") {
+		return
+	}
+	h.invalidate()
+	h.respondWithDeployment(c, http.StatusCreated, staged)
+}
+
+// SwitchDeployment points the resource's router at green in one config
+// change and starts the soak period
+func (h *DeploymentHandler) SwitchDeployment(c *gin.Context) {
+	deployment, err := h.Deployer.Switch(c.Param("id"))
+	if h.respondWithStageError(c, err, "Failed to switch deployment") {
+		return
+	}
+	h.invalidate()
+	h.respondWithDeployment(c, http.StatusOK, deployment)
+}
+
+// RollbackDeployment returns the resource's router to blue
+func (h *DeploymentHandler) RollbackDeployment(c *gin.Context) {
+	id := c.Param("id")
+	if h.respondWithStageError(c, h.Deployer.Rollback(id), "Failed to roll back deployment") {
+		return
+	}
+	h.invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Deployment rolled back"})
+}
+
+// DeleteDeployment forgets a resource's deployment in any stage; the router
+// uses its upstream service again
+func (h *DeploymentHandler) DeleteDeployment(c *gin.Context) {
+	if h.respondWithStageError(c, h.Deployer.Remove(c.Param("id")), "Failed to delete deployment") {
+		return
+	}
+	h.invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Deployment removed successfully"})
+}
+
+// respondWithStageError writes the response for a failed deployment step and
+// reports whether err was non-nil
+func (h *DeploymentHandler) respondWithStageError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, sql.ErrNoRows):
+		ResponseWithError(c, http.StatusNotFound, "No deployment for this resource")
+	case errors.Is(err, services.ErrDeploymentStage):
+		ResponseWithError(c, http.StatusConflict, err.Error())
+	default:
+		log.Printf("%s: %v", message, err)
+		ResponseWithError(c, http.StatusInternalServerError, message)
+	}
+	return true
+}
+
+func (h *DeploymentHandler) respondWithDeployment(c *gin.Context, status int, deployment *models.ResourceDeployment) {
+	greenService, blueService, mirroringService := models.DeploymentServiceNames(deployment.ResourceID)
+	response := gin.H{
+		"deployment":    deployment,
+		"green_service": greenService,
+	}
+	if deployment.BlueURL != "" && deployment.Stage == models.DeploymentStaged {
+		response["blue_service"] = blueService
+	}
+	if deployment.MirrorPercent > 0 && deployment.Stage == models.DeploymentStaged {
+		response["mirroring_service"] = mirroringService
+	}
+	c.JSON(status, response)
+}
+
+// invalidate makes the next config fetch serve the new stage right away
+func (h *DeploymentHandler) invalidate() {
+	if h.ConfigProxy != nil {
+		h.ConfigProxy.InvalidateCache()
+	}
+}
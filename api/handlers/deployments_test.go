@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestDeploymentHandler_SwitchAndRollback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewDeploymentHandler(db.DB, services.NewBlueGreenDeployer(db), nil)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	call := func(method, path string, fn gin.HandlerFunc, body string) int {
+		t.Helper()
+		c, rec := testutil.NewContext(t, method, path, bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		fn(c)
+		return rec.Code
+	}
+
+	if code := call(http.MethodPost, "/api/resources/res-1/deployment", handler.StartDeployment, `{"green_url":"green:80"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid green_url, got %d", code)
+	}
+	if code := call(http.MethodPost, "/api/resources/res-1/deployment/switch", handler.SwitchDeployment, ""); code != http.StatusNotFound {
+		t.Fatalf("expected 404 switching without a deployment, got %d", code)
+	}
+	if code := call(http.MethodPost, "/api/resources/res-1/deployment", handler.StartDeployment,
+		`{"green_url":"http://green:80","mirror_percent":10}`); code != http.StatusCreated {
+		t.Fatalf("expected 201 starting a deployment, got %d", code)
+	}
+	if code := call(http.MethodPost, "/api/resources/res-1/deployment", handler.StartDeployment,
+		`{"green_url":"http://other:80"}`); code != http.StatusConflict {
+		t.Fatalf("expected 409 while a deployment is staged, got %d", code)
+	}
+	if code := call(http.MethodPost, "/api/resources/res-1/deployment/switch", handler.SwitchDeployment, ""); code != http.StatusOK {
+		t.Fatalf("expected 200 switching, got %d", code)
+	}
+	if code := call(http.MethodPost, "/api/resources/res-1/deployment/rollback", handler.RollbackDeployment, ""); code != http.StatusOK {
+		t.Fatalf("expected 200 rolling back, got %d", code)
+	}
+	if code := call(http.MethodGet, "/api/resources/res-1/deployment", handler.GetResourceDeployment, ""); code != http.StatusNotFound {
+		t.Errorf("expected the rolled back deployment to be gone, got %d", code)
+	}
+}
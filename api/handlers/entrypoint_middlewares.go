@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// EntrypointMiddlewareHandler manages middlewares attached to whole entrypoints
+type EntrypointMiddlewareHandler struct {
+	DB *sql.DB
+}
+
+// NewEntrypointMiddlewareHandler creates a new entrypoint middleware handler
+func NewEntrypointMiddlewareHandler(db *sql.DB) *EntrypointMiddlewareHandler {
+	return &EntrypointMiddlewareHandler{DB: db}
+}
+
+// GetEntrypointMiddlewares lists the middlewares attached to entrypoints,
+// highest priority first within each entrypoint
+func (h *EntrypointMiddlewareHandler) GetEntrypointMiddlewares(c *gin.Context) {
+	rows, err := h.DB.Query(`
+		SELECT em.entrypoint, em.middleware_id, m.name, em.priority, em.created_at
+		FROM entrypoint_middlewares em
+		JOIN middlewares m ON m.id = em.middleware_id
+		ORDER BY em.entrypoint, em.priority DESC, m.name
+	`)
+	if err != nil {
+		log.Printf("Error fetching entrypoint middlewares: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch entrypoint middlewares")
+		return
+	}
+	defer rows.Close()
+
+	attached := []models.EntrypointMiddleware{}
+	for rows.Next() {
+		var em models.EntrypointMiddleware
+		if err := rows.Scan(&em.EntryPoint, &em.MiddlewareID, &em.MiddlewareName, &em.Priority, &em.CreatedAt); err != nil {
+			log.Printf("Error scanning entrypoint middleware row: %v", err)
+			continue
+		}
+		attached = append(attached, em)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating entrypoint middleware rows: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Database error while fetching entrypoint middlewares")
+		return
+	}
+
+	c.JSON(http.StatusOK, attached)
+}
+
+// AssignEntrypointMiddleware attaches a middleware to every HTTP router on an
+// entrypoint, or changes its priority if already attached
+func (h *EntrypointMiddlewareHandler) AssignEntrypointMiddleware(c *gin.Context) {
+	entryPoint := c.Param("entrypoint")
+	if entryPoint == "" {
+		ResponseWithAPIError(c, missingFieldError("entrypoint", "Entrypoint is required"))
+		return
+	}
+
+	var input struct {
+		MiddlewareID string `json:"middleware_id" binding:"required"`
+		Priority     int    `json:"priority"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	// Default priority is 200 if not specified, as for resources
+	if input.Priority <= 0 {
+		input.Priority = 200
+	}
+
+	var name string
+	err := h.DB.QueryRow("SELECT name FROM middlewares WHERE id = ?", input.MiddlewareID).Scan(&name)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Middleware not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking middleware existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	now := time.Now()
+	if _, err := h.DB.Exec(`
+		INSERT INTO entrypoint_middlewares (entrypoint, middleware_id, priority, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(entrypoint, middleware_id) DO UPDATE SET priority = excluded.priority
+	`, entryPoint, input.MiddlewareID, input.Priority, now); err != nil {
+		log.Printf("Error attaching middleware to entrypoint: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to attach middleware to entrypoint")
+		return
+	}
+
+	log.Printf("Attached middleware %s to entrypoint %s with priority %d", input.MiddlewareID, entryPoint, input.Priority)
+	c.JSON(http.StatusOK, models.EntrypointMiddleware{
+		EntryPoint:     entryPoint,
+		MiddlewareID:   input.MiddlewareID,
+		MiddlewareName: name,
+		Priority:       input.Priority,
+		CreatedAt:      now,
+	})
+}
+
+// RemoveEntrypointMiddleware detaches a middleware from an entrypoint
+func (h *EntrypointMiddlewareHandler) RemoveEntrypointMiddleware(c *gin.Context) {
+	entryPoint := c.Param("entrypoint")
+	middlewareID := c.Param("middlewareId")
+
+	result, err := h.DB.Exec(
+		"DELETE FROM entrypoint_middlewares WHERE entrypoint = ? AND middleware_id = ?",
+		entryPoint, middlewareID,
+	)
+	if err != nil {
+		log.Printf("Error detaching middleware from entrypoint: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to detach middleware from entrypoint")
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		ResponseWithError(c, http.StatusNotFound, "Middleware is not attached to this entrypoint")
+		return
+	}
+
+	log.Printf("Detached middleware %s from entrypoint %s", middlewareID, entryPoint)
+	c.JSON(http.StatusOK, gin.H{"message": "Middleware detached from entrypoint successfully"})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// TestEntrypointMiddlewareHandler_Lifecycle tests attaching, listing and detaching an entrypoint middleware
+func TestEntrypointMiddlewareHandler_Lifecycle(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewEntrypointMiddlewareHandler(db.DB)
+	testutil.MustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'secure-headers', 'headers', '{}')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/entrypoints/websecure/middlewares",
+		strings.NewReader(`{"middleware_id": "missing"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "entrypoint", Value: "websecure"}}
+	handler.AssignEntrypointMiddleware(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown middleware status = %d, want 404", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/entrypoints/websecure/middlewares",
+		strings.NewReader(`{"middleware_id": "mw-1"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "entrypoint", Value: "websecure"}}
+	handler.AssignEntrypointMiddleware(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/entrypoints/middlewares", nil)
+	handler.GetEntrypointMiddlewares(c)
+	var attached []models.EntrypointMiddleware
+	if err := json.Unmarshal(rec.Body.Bytes(), &attached); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(attached) != 1 || attached[0].MiddlewareName != "secure-headers" || attached[0].Priority != 200 {
+		t.Errorf("unexpected entrypoint middlewares %+v", attached)
+	}
+
+	// Attached middlewares cannot be deleted
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/middlewares/mw-1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "mw-1"}}
+	NewMiddlewareHandler(db.DB).DeleteMiddleware(c)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("deleting an attached middleware status = %d, want 409", rec.Code)
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		c, rec = testutil.NewContext(t, http.MethodDelete, "/api/entrypoints/websecure/middlewares/mw-1", nil)
+		c.Params = gin.Params{{Key: "entrypoint", Value: "websecure"}, {Key: "middlewareId", Value: "mw-1"}}
+		handler.RemoveEntrypointMiddleware(c)
+		if rec.Code != want {
+			t.Errorf("RemoveEntrypointMiddleware() status = %d, want %d", rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// EnvironmentHandler manages environments and the environment tags of
+// resources and middlewares
+type EnvironmentHandler struct {
+	DB           *sql.DB
+	Environments *services.EnvironmentService
+	ConfigProxy  *services.ConfigProxy
+}
+
+// NewEnvironmentHandler creates a new environment handler
+func NewEnvironmentHandler(db *sql.DB, environments *services.EnvironmentService, configProxy *services.ConfigProxy) *EnvironmentHandler {
+	return &EnvironmentHandler{DB: db, Environments: environments, ConfigProxy: configProxy}
+}
+
+// GetEnvironments lists the environments with the number of tagged
+// resources and middlewares
+func (h *EnvironmentHandler) GetEnvironments(c *gin.Context) {
+	environments, err := h.Environments.List()
+	if err != nil {
+		log.Printf("Error fetching environments: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch environments")
+		return
+	}
+	c.JSON(http.StatusOK, environments)
+}
+
+// CreateEnvironment adds an environment
+func (h *EnvironmentHandler) CreateEnvironment(c *gin.Context) {
+	var input struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		IsDefault   bool   `json:"is_default"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	env := models.Environment{Name: input.Name, Description: input.Description, IsDefault: input.IsDefault}
+	env.Normalize()
+	if err := env.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid environment: %v", err))
+		return
+	}
+
+	created, err := h.Environments.Create(env)
+	if errors.Is(err, services.ErrEnvironmentExists) {
+		ResponseWithError(c, http.StatusConflict, fmt.Sprintf("Environment %s already exists", env.Name))
+		return
+	} else if err != nil {
+		log.Printf("Error creating environment: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Created environment %s", created.Name)
+	h.invalidate()
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateEnvironment replaces the description and default flag of an environment
+func (h *EnvironmentHandler) UpdateEnvironment(c *gin.Context) {
+	var input struct {
+		Description string `json:"description"`
+		IsDefault   bool   `json:"is_default"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	env := models.Environment{Description: input.Description, IsDefault: input.IsDefault}
+	env.Normalize()
+	updated, err := h.Environments.Update(models.NormalizeEnvironmentName(c.Param("name")), env)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Environment not found")
+		return
+	} else if err != nil {
+		log.Printf("Error updating environment: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Updated environment %s", updated.Name)
+	h.invalidate()
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteEnvironment removes an environment and its tags
+func (h *EnvironmentHandler) DeleteEnvironment(c *gin.Context) {
+	name := models.NormalizeEnvironmentName(c.Param("name"))
+	if err := h.Environments.Delete(name); err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Environment not found")
+		return
+	} else if err != nil {
+		log.Printf("Error deleting environment: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Deleted environment %s", name)
+	h.invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Environment deleted"})
+}
+
+// GetEnvironmentTags lists the tags of every tagged resource and middleware
+func (h *EnvironmentHandler) GetEnvironmentTags(c *gin.Context) {
+	tags, err := h.Environments.AllTags()
+	if err != nil {
+		log.Printf("Error fetching environment tags: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch environment tags")
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetResourceEnvironments returns the environments a resource is limited to
+func (h *EnvironmentHandler) GetResourceEnvironments(c *gin.Context) {
+	h.getTags(c, models.EnvironmentTargetResource, "Resource not found")
+}
+
+// SetResourceEnvironments limits a resource to environments
+func (h *EnvironmentHandler) SetResourceEnvironments(c *gin.Context) {
+	id := c.Param("id")
+	var input struct {
+		Environments []string `json:"environments"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	tags, err := h.Environments.SetResourceTags(id, version, input.Environments)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if errors.Is(err, services.ErrResourceVersionConflict) {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	} else if errors.Is(err, services.ErrUnknownEnvironment) {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid environments: %v", err))
+		return
+	} else if err != nil {
+		log.Printf("Error setting resource environments: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Limited resource %s to environments %v", id, tags.Environments)
+	h.invalidate()
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetMiddlewareEnvironments returns the environments a middleware is limited to
+func (h *EnvironmentHandler) GetMiddlewareEnvironments(c *gin.Context) {
+	h.getTags(c, models.EnvironmentTargetMiddleware, "Middleware not found")
+}
+
+// SetMiddlewareEnvironments limits a middleware to environments
+func (h *EnvironmentHandler) SetMiddlewareEnvironments(c *gin.Context) {
+	h.setTags(c, models.EnvironmentTargetMiddleware, "Middleware not found")
+}
+
+func (h *EnvironmentHandler) getTags(c *gin.Context, targetType, notFound string) {
+	tags, err := h.Environments.Tags(targetType, c.Param("id"))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, notFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching %s environments: %v", targetType, err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+func (h *EnvironmentHandler) setTags(c *gin.Context, targetType, notFound string) {
+	var input struct {
+		Environments []string `json:"environments"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	tags, err := h.Environments.SetTags(targetType, c.Param("id"), input.Environments)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, notFound)
+		return
+	} else if errors.Is(err, services.ErrUnknownEnvironment) {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid environments: %v", err))
+		return
+	} else if err != nil {
+		log.Printf("Error setting %s environments: %v", targetType, err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Limited %s %s to environments %v", targetType, tags.TargetID, tags.Environments)
+	h.invalidate()
+	c.JSON(http.StatusOK, tags)
+}
+
+// invalidate makes the next polls pick up changed environments and tags
+func (h *EnvironmentHandler) invalidate() {
+	if h.ConfigProxy != nil {
+		h.ConfigProxy.InvalidateCache()
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// FailoverHandler manages per-resource failover services
+type FailoverHandler struct {
+	DB *sql.DB
+}
+
+// NewFailoverHandler creates a new failover handler
+func NewFailoverHandler(db *sql.DB) *FailoverHandler {
+	return &FailoverHandler{DB: db}
+}
+
+// GetFailovers returns every configured failover
+func (h *FailoverHandler) GetFailovers(c *gin.Context) {
+	rows, err := h.DB.Query(`
+		SELECT resource_id, fallback_url, fallback_service, health_check_path, health_check_interval, health_check_timeout,
+		       created_at, updated_at
+		FROM resource_failovers ORDER BY resource_id
+	`)
+	if err != nil {
+		log.Printf("Error fetching failovers: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch failovers")
+		return
+	}
+	defer rows.Close()
+
+	failovers := []models.ResourceFailover{}
+	for rows.Next() {
+		failover, err := scanFailover(rows)
+		if err != nil {
+			log.Printf("Error scanning failover row: %v", err)
+			continue
+		}
+		failovers = append(failovers, failover)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating failover rows: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Database error while fetching failovers")
+		return
+	}
+
+	c.JSON(http.StatusOK, failovers)
+}
+
+// GetResourceFailover returns the failover configured for a resource and the services it generates
+func (h *FailoverHandler) GetResourceFailover(c *gin.Context) {
+	id := c.Param("id")
+	failover, err := scanFailover(h.DB.QueryRow(`
+		SELECT resource_id, fallback_url, fallback_service, health_check_path, health_check_interval, health_check_timeout,
+		       created_at, updated_at
+		FROM resource_failovers WHERE resource_id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "No failover configured for this resource")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching failover: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	failoverService, primaryService, fallbackService := models.FailoverServiceNames(id)
+	if failover.FallbackURL == "" {
+		fallbackService = failover.FallbackService
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"failover":         failover,
+		"failover_service": failoverService,
+		"primary_service":  primaryService,
+		"fallback_service": fallbackService,
+	})
+}
+
+// SetResourceFailover creates or replaces the failover of a resource. The
+// router's service is wrapped in a failover service on the next config merge.
+func (h *FailoverHandler) SetResourceFailover(c *gin.Context) {
+	id := c.Param("id")
+	var failover models.ResourceFailover
+	if !bindRequest(c, &failover) {
+		return
+	}
+
+	failover.Normalize()
+	if err := failover.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid failover: %v", err))
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	failover.ResourceID = id
+	failover.UpdatedAt = time.Now()
+	tx, err := h.DB.Begin()
+	if err != nil {
+		log.Printf("Error beginning transaction: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	defer tx.Rollback()
+
+	if bumped, err := bumpResourceVersion(tx, id, version); err != nil {
+		log.Printf("Error updating resource version: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save failover")
+		return
+	} else if !bumped {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO resource_failovers (resource_id, fallback_url, fallback_service, health_check_path,
+			health_check_interval, health_check_timeout, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(resource_id) DO UPDATE SET fallback_url = excluded.fallback_url,
+			fallback_service = excluded.fallback_service, health_check_path = excluded.health_check_path,
+			health_check_interval = excluded.health_check_interval, health_check_timeout = excluded.health_check_timeout,
+			updated_at = excluded.updated_at
+	`, id, failover.FallbackURL, failover.FallbackService, failover.HealthCheckPath,
+		failover.HealthCheckInterval, failover.HealthCheckTimeout, failover.UpdatedAt); err != nil {
+		log.Printf("Error saving failover: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save failover")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing failover: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	fallback := failover.FallbackService
+	if failover.FallbackURL != "" {
+		fallback = failover.FallbackURL
+	}
+	log.Printf("Resource %s fails over to %s", id, fallback)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, failover)
+}
+
+// DeleteResourceFailover removes a resource's failover; its router gets the original service back
+func (h *FailoverHandler) DeleteResourceFailover(c *gin.Context) {
+	id := c.Param("id")
+	result, err := h.DB.Exec("DELETE FROM resource_failovers WHERE resource_id = ?", id)
+	if err != nil {
+		log.Printf("Error deleting failover: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete failover")
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		ResponseWithError(c, http.StatusNotFound, "No failover configured for this resource")
+		return
+	}
+
+	log.Printf("Removed failover for resource %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Failover removed successfully"})
+}
+
+func scanFailover(row corsScanner) (models.ResourceFailover, error) {
+	var failover models.ResourceFailover
+	err := row.Scan(&failover.ResourceID, &failover.FallbackURL, &failover.FallbackService, &failover.HealthCheckPath,
+		&failover.HealthCheckInterval, &failover.HealthCheckTimeout, &failover.CreatedAt, &failover.UpdatedAt)
+	return failover, err
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestFailoverHandler_SetAndRemove(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewFailoverHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	set := func(body string) (int, map[string]interface{}) {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/failover", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.SetResourceFailover(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	if code, _ := set(`{}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a fallback, got %d", code)
+	}
+	code, resp := set(`{"fallback_service":"maintenance@file"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, resp)
+	}
+	if resp["health_check_path"] != "/" {
+		t.Fatalf("expected default health check path, got %v", resp)
+	}
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/resources/res-1/failover", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.GetResourceFailover(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var got map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &got)
+	if got["failover_service"] != "res-1-failover" || got["fallback_service"] != "maintenance@file" {
+		t.Errorf("unexpected generated names: %v", got)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/resources/res-1/failover", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.DeleteResourceFailover(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 removing failover, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/resources/res-1/failover", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.DeleteResourceFailover(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 removing a missing failover, got %d", rec.Code)
+	}
+}
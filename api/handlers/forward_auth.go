@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// ForwardAuthHandler manages per-resource tokens and serves the built-in forwardAuth endpoint
+type ForwardAuthHandler struct {
+	DB      *sql.DB
+	Service *services.ForwardAuthService
+}
+
+// NewForwardAuthHandler creates a new forward-auth handler
+func NewForwardAuthHandler(db *sql.DB, service *services.ForwardAuthService) *ForwardAuthHandler {
+	return &ForwardAuthHandler{DB: db, Service: service}
+}
+
+// Verify is called by Traefik's forwardAuth middleware.
+// GET /api/forward-auth/verify?resource=<id>
+// Accepts "Authorization: Bearer <token-or-totp-code>" or an X-Auth-Token header.
+// An accepted TOTP code sets a session cookie that is accepted instead of a
+// code until it expires.
+func (h *ForwardAuthHandler) Verify(c *gin.Context) {
+	resourceID := c.Query("resource")
+
+	if session, err := c.Cookie(services.ForwardAuthSessionCookie); err == nil && session != "" {
+		ok, err := h.Service.VerifySession(resourceID, session)
+		if err != nil {
+			log.Printf("Forward auth session error for resource %s: %v", resourceID, err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if ok {
+			c.AbortWithStatus(http.StatusOK)
+			return
+		}
+	}
+
+	credential := c.GetHeader("X-Auth-Token")
+	if auth := c.GetHeader("Authorization"); credential == "" && strings.HasPrefix(auth, "Bearer ") {
+		credential = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	result, err := h.Service.Verify(resourceID, credential, forwardedClientIP(c))
+	if errors.Is(err, services.ErrForwardAuthThrottled) {
+		c.Header("Retry-After", "300")
+		c.AbortWithStatus(http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		log.Printf("Forward auth verification error for resource %s: %v", resourceID, err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !result.OK {
+		c.Header("WWW-Authenticate", `Bearer realm="middleware-manager"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if result.Session != "" {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     services.ForwardAuthSessionCookie,
+			Value:    result.Session,
+			Path:     "/",
+			Expires:  result.SessionExpires,
+			Secure:   c.GetHeader("X-Forwarded-Proto") == "https",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// forwardedClientIP returns the address of the client Traefik is checking.
+// The forwardAuth middleware does not trust forwarded headers, so the last
+// X-Forwarded-For entry is the one Traefik added.
+func forwardedClientIP(c *gin.Context) string {
+	if forwarded := c.GetHeader("X-Forwarded-For"); forwarded != "" {
+		entries := strings.Split(forwarded, ",")
+		return strings.TrimSpace(entries[len(entries)-1])
+	}
+	return c.RemoteIP()
+}
+
+// GetTokens lists forward-auth tokens for a resource (secrets are never returned)
+func (h *ForwardAuthHandler) GetTokens(c *gin.Context) {
+	id := c.Param("id")
+	if _, _, ok := h.lookupResource(c, id); !ok {
+		return
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT id, name, type, created_at, last_used_at
+		FROM forward_auth_tokens WHERE resource_id = ? ORDER BY created_at
+	`, id)
+	if err != nil {
+		log.Printf("Error fetching forward-auth tokens: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch tokens")
+		return
+	}
+	defer rows.Close()
+
+	tokens := []gin.H{}
+	for rows.Next() {
+		var tokenID, name, typ string
+		var createdAt time.Time
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&tokenID, &name, &typ, &createdAt, &lastUsed); err != nil {
+			log.Printf("Error scanning forward-auth token row: %v", err)
+			continue
+		}
+		token := gin.H{
+			"id":         tokenID,
+			"name":       name,
+			"type":       typ,
+			"created_at": createdAt,
+		}
+		if lastUsed.Valid {
+			token["last_used_at"] = lastUsed.Time
+		}
+		tokens = append(tokens, token)
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates a bearer token or TOTP secret for a resource.
+// The secret is only returned in this response.
+func (h *ForwardAuthHandler) CreateToken(c *gin.Context) {
+	id := c.Param("id")
+	var input struct {
+		Name string `json:"name" binding:"required"`
+		Type string `json:"type"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if input.Type == "" {
+		input.Type = services.ForwardAuthTokenBearer
+	}
+	if input.Type != services.ForwardAuthTokenBearer && input.Type != services.ForwardAuthTokenTOTP {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid token type: %s", input.Type))
+		return
+	}
+
+	host, status, ok := h.lookupResource(c, id)
+	if !ok {
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+
+	tokenID, err := generateID()
+	if err != nil {
+		log.Printf("Error generating ID: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to generate ID")
+		return
+	}
+
+	cred, err := h.Service.CreateToken(tokenID, id, host, input.Name, input.Type)
+	if err != nil {
+		log.Printf("Error creating forward-auth token: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to create token")
+		return
+	}
+
+	log.Printf("Created %s forward-auth token %s for resource %s", input.Type, tokenID, id)
+	c.JSON(http.StatusCreated, cred)
+}
+
+// RotateToken issues a new secret for an existing token. With a positive
+// grace_period_seconds the previous secret is accepted until the period ends.
+func (h *ForwardAuthHandler) RotateToken(c *gin.Context) {
+	id := c.Param("id")
+	tokenID := c.Param("tokenId")
+	var input struct {
+		GracePeriodSeconds int `json:"grace_period_seconds"`
+	}
+	// Body is optional; an empty body rotates without a grace period
+	if c.Request.ContentLength > 0 {
+		if !bindRequest(c, &input) {
+			return
+		}
+	}
+	if input.GracePeriodSeconds < 0 {
+		ResponseWithError(c, http.StatusBadRequest, "grace_period_seconds cannot be negative")
+		return
+	}
+
+	host, _, ok := h.lookupResource(c, id)
+	if !ok {
+		return
+	}
+
+	grace := time.Duration(input.GracePeriodSeconds) * time.Second
+	cred, err := h.Service.RotateToken(id, tokenID, host, grace)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Token not found")
+		return
+	} else if err != nil {
+		log.Printf("Error rotating forward-auth token: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to rotate token")
+		return
+	}
+
+	log.Printf("Rotated forward-auth token %s for resource %s (grace %s)", tokenID, id, grace)
+	response := gin.H{"token": cred, "grace_applied": grace > 0}
+	if grace > 0 {
+		response["grace_expires_at"] = time.Now().Add(grace)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteToken revokes a forward-auth token
+func (h *ForwardAuthHandler) DeleteToken(c *gin.Context) {
+	id := c.Param("id")
+	tokenID := c.Param("tokenId")
+
+	result, err := h.DB.Exec("DELETE FROM forward_auth_tokens WHERE id = ? AND resource_id = ?", tokenID, id)
+	if err != nil {
+		log.Printf("Error deleting forward-auth token: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete token")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		ResponseWithError(c, http.StatusNotFound, "Token not found")
+		return
+	}
+
+	log.Printf("Deleted forward-auth token %s for resource %s", tokenID, id)
+	c.JSON(http.StatusOK, gin.H{"message": "Token deleted successfully"})
+}
+
+// UpdateResourceForwardAuth enables or disables the built-in forward auth for a resource
+func (h *ForwardAuthHandler) UpdateResourceForwardAuth(c *gin.Context) {
+	id := c.Param("id")
+	var input struct {
+		Enabled bool `json:"enabled"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	_, status, ok := h.lookupResource(c, id)
+	if !ok {
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	enabled := 0
+	if input.Enabled {
+		enabled = 1
+	}
+	result, err := h.DB.Exec(
+		"UPDATE resources SET forward_auth_enabled = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		enabled, time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating forward auth: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update forward auth")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Set forward auth for resource %s to %v", id, input.Enabled)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"id":                   id,
+		"forward_auth_enabled": input.Enabled,
+	})
+}
+
+func (h *ForwardAuthHandler) lookupResource(c *gin.Context, id string) (string, string, bool) {
+	var host, status string
+	err := h.DB.QueryRow("SELECT host, status FROM resources WHERE id = ?", id).Scan(&host, &status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return "", "", false
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return "", "", false
+	}
+	return host, status, true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestForwardAuthHandler_TokenLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewForwardAuthHandler(db.DB, services.NewForwardAuthService(db))
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'tool.example.com', 'svc', 'org', 'site', 'active')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/resources/res-1/forward-auth/tokens",
+		bytes.NewBufferString(`{"name":"ci"}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.CreateToken(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var cred map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &cred); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	token, _ := cred["token"].(string)
+	if token == "" {
+		t.Fatalf("expected bearer token in response, got %v", cred)
+	}
+
+	verify := func() int {
+		c, rec := testutil.NewContext(t, http.MethodGet, "/api/forward-auth/verify?resource=res-1", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+		handler.Verify(c)
+		return rec.Code
+	}
+
+	// Tokens are rejected until forward auth is enabled on the resource
+	if code := verify(); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 before enabling, got %d", code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/forward-auth",
+		bytes.NewBufferString(`{"enabled":true}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateResourceForwardAuth(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 enabling forward auth, got %d", rec.Code)
+	}
+
+	if code := verify(); code != http.StatusOK {
+		t.Fatalf("expected 200 with valid token, got %d", code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/resources/res-1/forward-auth/tokens", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.GetTokens(c)
+	if bytes.Contains(rec.Body.Bytes(), []byte(token)) {
+		t.Fatal("token listing must not expose secrets")
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/resources/res-1/forward-auth/tokens/"+cred["id"].(string), nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}, {Key: "tokenId", Value: cred["id"].(string)}}
+	handler.DeleteToken(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 deleting token, got %d", rec.Code)
+	}
+
+	if code := verify(); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after deleting token, got %d", code)
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestHeaderPolicyHandler_CreateValidatesType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewHeaderPolicyHandler(db.DB)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/header-policies",
+		bytes.NewBufferString(`{"name":"bad","type":"bogus","config":{"customResponseHeaders":{"X-A":"1"}}}`))
+	handler.CreateHeaderPolicy(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid type, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/header-policies",
+		bytes.NewBufferString(`{"name":"empty","config":{}}`))
+	handler.CreateHeaderPolicy(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty config, got %d", rec.Code)
+	}
+}
+
+func TestHeaderPolicyHandler_AssignToResourceAndGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewHeaderPolicyHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/header-policies",
+		bytes.NewBufferString(`{"name":"caching","type":"caching","config":{"customResponseHeaders":{"Cache-Control":"no-store"}}}`))
+	handler.CreateHeaderPolicy(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var policy map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	policyID := policy["id"].(string)
+
+	// Duplicate names are rejected
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/header-policies",
+		bytes.NewBufferString(`{"name":"caching","config":{"customResponseHeaders":{"X-A":"1"}}}`))
+	handler.CreateHeaderPolicy(c)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate name, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resources/res-1/header-policies",
+		bytes.NewBufferString(`{"policy_id":"`+policyID+`","priority":50}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.AssignResourcePolicy(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 assigning to resource, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/resources/res-1/header-policies", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.GetResourcePolicies(c)
+	var assigned []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &assigned); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(assigned) != 1 || assigned[0]["priority"] != float64(50) {
+		t.Fatalf("unexpected resource policies: %v", assigned)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resource-groups",
+		bytes.NewBufferString(`{"name":"apps"}`))
+	handler.CreateResourceGroup(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating group, got %d", rec.Code)
+	}
+	var group map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &group)
+	groupID := group["id"].(string)
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resource-groups/"+groupID+"/resources",
+		bytes.NewBufferString(`{"resource_id":"missing"}`))
+	c.Params = gin.Params{{Key: "id", Value: groupID}}
+	handler.AddGroupResource(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown resource, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resource-groups/"+groupID+"/header-policies",
+		bytes.NewBufferString(`{"policy_id":"`+policyID+`"}`))
+	c.Params = gin.Params{{Key: "id", Value: groupID}}
+	handler.AssignGroupPolicy(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 assigning to group, got %d", rec.Code)
+	}
+
+	// Deleting the policy removes every assignment
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/header-policies/"+policyID, nil)
+	c.Params = gin.Params{{Key: "id", Value: policyID}}
+	handler.DeleteHeaderPolicy(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 deleting policy, got %d", rec.Code)
+	}
+	var remaining int
+	if err := db.QueryRow("SELECT COUNT(*) FROM header_policy_assignments").Scan(&remaining); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatalf("expected assignments to be removed, %d remain", remaining)
+	}
+}
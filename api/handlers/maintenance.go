@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/database"
+)
+
+// MaintenanceHandler runs database maintenance tasks and serves their reports
+type MaintenanceHandler struct {
+	DB      *database.DB
+	Cleanup *database.CleanupManager
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(db *database.DB) *MaintenanceHandler {
+	return &MaintenanceHandler{DB: db, Cleanup: database.NewCleanupManager(db)}
+}
+
+// RunCleanup runs the full cleanup and returns a report of every change and why.
+// Runs are dry-run unless the body sets "dry_run": false, so destructive cleanup
+// can be reviewed first.
+func (h *MaintenanceHandler) RunCleanup(c *gin.Context) {
+	input := struct {
+		DryRun           *bool `json:"dry_run"`
+		ReapDisabled     bool  `json:"reap_disabled"`
+		RecoverCorrupted *bool `json:"recover_corrupted"`
+	}{}
+	// Body is optional; an empty body means a default dry run
+	if c.Request.ContentLength > 0 {
+		if !bindRequest(c, &input) {
+			return
+		}
+	}
+
+	opts := database.DefaultCleanupOptions()
+	opts.DryRun = true
+	if input.DryRun != nil {
+		opts.DryRun = *input.DryRun
+	}
+	opts.ReapDisabled = input.ReapDisabled
+	if input.RecoverCorrupted != nil {
+		opts.RecoverCorrupted = *input.RecoverCorrupted
+	}
+	opts.Report = database.NewCleanupReport(opts)
+
+	runErr := h.Cleanup.PerformFullCleanup(opts)
+	opts.Report.Finish(runErr)
+	if err := h.DB.SaveCleanupReport(opts.Report); err != nil {
+		log.Printf("Error saving cleanup report: %v", err)
+	}
+
+	if runErr != nil {
+		log.Printf("Cleanup run %s failed: %v", opts.Report.RunID, runErr)
+		c.JSON(http.StatusInternalServerError, opts.Report)
+		return
+	}
+
+	log.Printf("Cleanup run %s finished (dry run: %v, %d actions)", opts.Report.RunID, opts.DryRun, len(opts.Report.Actions))
+	c.JSON(http.StatusOK, opts.Report)
+}
+
+// MigrateResourceIDs gives resources still on the legacy ID scheme (ID equal
+// to the Pangolin router ID) internal UUIDs and returns the old-to-new
+// mapping. Like cleanup it is a dry run unless the body sets "dry_run": false.
+func (h *MaintenanceHandler) MigrateResourceIDs(c *gin.Context) {
+	input := struct {
+		DryRun *bool `json:"dry_run"`
+	}{}
+	if c.Request.ContentLength > 0 {
+		if !bindRequest(c, &input) {
+			return
+		}
+	}
+	dryRun := input.DryRun == nil || *input.DryRun
+
+	report, err := h.DB.MigrateLegacyResourceIDs(dryRun)
+	if errors.Is(err, database.ErrSQLiteOnly) {
+		ResponseWithAPIError(c, apierrors.New(http.StatusConflict, apierrors.CodeConflict, err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("Error migrating legacy resource IDs: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to migrate legacy resource IDs")
+		return
+	}
+	log.Printf("Legacy resource ID migration (dry run: %v): %d resources", dryRun, len(report.Mappings))
+	c.JSON(http.StatusOK, report)
+}
+
+// MigrateToPostgres copies the SQLite database into the PostgreSQL database
+// at the body's dsn and verifies the copy. It is a dry run, which only
+// creates the schema and counts rows, unless the body sets "dry_run": false.
+// Cutting over is refused here: this process keeps writing to SQLite, so it
+// is only offered by -migrate-cutover with MM stopped.
+func (h *MaintenanceHandler) MigrateToPostgres(c *gin.Context) {
+	input := struct {
+		DSN       string `json:"dsn"`
+		DryRun    *bool  `json:"dry_run"`
+		Overwrite bool   `json:"overwrite"`
+		Cutover   bool   `json:"cutover"`
+	}{}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if strings.TrimSpace(input.DSN) == "" {
+		ResponseWithAPIError(c, missingFieldError("dsn", "dsn is required"))
+		return
+	}
+	if input.Cutover {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			"cutover is not available from a running MM").WithField("cutover").
+			WithHint("Stop MM and run middleware-manager -migrate-postgres <dsn> -migrate-cutover"))
+		return
+	}
+	opts := database.PostgresMigrationOptions{
+		DryRun:    input.DryRun == nil || *input.DryRun,
+		Overwrite: input.Overwrite,
+	}
+
+	report, err := h.DB.MigrateToPostgres(c.Request.Context(), input.DSN, opts)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, report)
+	case errors.Is(err, database.ErrSQLiteOnly):
+		ResponseWithAPIError(c, apierrors.New(http.StatusConflict, apierrors.CodeConflict, "MM already runs on PostgreSQL"))
+	case errors.Is(err, database.ErrTargetNotEmpty):
+		ResponseWithAPIError(c, apierrors.New(http.StatusConflict, apierrors.CodeConflict, err.Error()).
+			WithHint(`Set "overwrite": true to replace its data`))
+	case errors.Is(err, database.ErrVerificationFailed):
+		log.Printf("PostgreSQL migration to %s failed verification", report.Target)
+		c.JSON(http.StatusInternalServerError, report)
+	default:
+		log.Printf("PostgreSQL migration failed: %v", err)
+		ResponseWithError(c, http.StatusBadRequest, "Migration failed: "+err.Error())
+	}
+}
+
+// GetCleanupRuns lists stored cleanup reports, newest first
+func (h *MaintenanceHandler) GetCleanupRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	reports, err := h.DB.ListCleanupReports(limit)
+	if err != nil {
+		log.Printf("Error fetching cleanup runs: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch cleanup runs")
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetCleanupRun returns one cleanup report; ?download=true serves it as a file
+func (h *MaintenanceHandler) GetCleanupRun(c *gin.Context) {
+	runID := c.Param("runId")
+	report, err := h.DB.GetCleanupReport(runID)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Cleanup run not found")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching cleanup run %s: %v", runID, err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch cleanup run")
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=cleanup-%s.json", runID))
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// UndoCleanupRun restores the rows a cleanup run deleted or changed, within the undo window
+func (h *MaintenanceHandler) UndoCleanupRun(c *gin.Context) {
+	runID := c.Param("runId")
+	restored, err := h.DB.UndoCleanupRun(runID)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Nothing to undo for this run (unknown, dry run, already undone or expired)")
+		return
+	} else if err != nil {
+		log.Printf("Error undoing cleanup run %s: %v", runID, err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to undo cleanup run")
+		return
+	}
+
+	log.Printf("Undid cleanup run %s, restored %d rows", runID, restored)
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Cleanup run undone successfully",
+		"run_id":        runID,
+		"rows_restored": restored,
+	})
+}
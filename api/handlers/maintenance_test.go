@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestMaintenanceHandler_CleanupDefaultsToDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMaintenanceHandler(db)
+
+	testutil.MustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES ('svc@file', 'svc', 'loadBalancer', '{}')`)
+	testutil.MustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES ('svc', 'svc', 'loadBalancer', '{}')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/maintenance/cleanup", nil)
+	handler.RunCleanup(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report database.CleanupReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	if !report.DryRun || report.Summary["service_delete"] != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM services").Scan(&count); err != nil {
+		t.Fatalf("count services: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("dry run must not delete services, %d remain", count)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/maintenance/cleanup/runs/"+report.RunID+"?download=true", nil)
+	c.Params = gin.Params{{Key: "runId", Value: report.RunID}}
+	handler.GetCleanupRun(c)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Disposition") == "" {
+		t.Fatalf("expected downloadable report, got %d (%q)", rec.Code, rec.Header().Get("Content-Disposition"))
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/maintenance/cleanup",
+		bytes.NewBufferString(`{"dry_run":false}`))
+	handler.RunCleanup(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM services").Scan(&count); err != nil {
+		t.Fatalf("count services: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected duplicate removed, %d remain", count)
+	}
+
+	var applied database.CleanupReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &applied); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/maintenance/undo/"+applied.RunID, nil)
+	c.Params = gin.Params{{Key: "runId", Value: applied.RunID}}
+	handler.UndoCleanupRun(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 undoing run, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM services").Scan(&count); err != nil {
+		t.Fatalf("count services: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected undo to restore the deleted service, %d present", count)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/maintenance/undo/"+report.RunID, nil)
+	c.Params = gin.Params{{Key: "runId", Value: report.RunID}}
+	handler.UndoCleanupRun(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 undoing a dry run, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/maintenance/cleanup/runs", nil)
+	handler.GetCleanupRuns(c)
+	var runs []database.CleanupReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &runs); err != nil || len(runs) != 2 {
+		t.Fatalf("expected 2 stored runs, got %d (%v)", len(runs), err)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/maintenance/cleanup/runs/missing", nil)
+	c.Params = gin.Params{{Key: "runId", Value: "missing"}}
+	handler.GetCleanupRun(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestMaintenanceHandler_MigrateToPostgresValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMaintenanceHandler(db)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/maintenance/migrate-postgres", bytes.NewBufferString(`{}`))
+	handler.MigrateToPostgres(c)
+	if rec.Code != http.StatusBadRequest || !bytes.Contains(rec.Body.Bytes(), []byte(`"dsn"`)) {
+		t.Fatalf("expected 400 for a missing dsn, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/maintenance/migrate-postgres",
+		bytes.NewBufferString(`{"dsn":"postgres://mm@127.0.0.1:1/mm","dry_run":false,"cutover":true}`))
+	handler.MigrateToPostgres(c)
+	if rec.Code != http.StatusBadRequest || !bytes.Contains(rec.Body.Bytes(), []byte(`"cutover"`)) {
+		t.Fatalf("expected 400 for a cutover from the API, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The default build has no PostgreSQL driver, and the address is unreachable
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/maintenance/migrate-postgres",
+		bytes.NewBufferString(`{"dsn":"postgres://mm@127.0.0.1:1/mm?connect_timeout=1"}`))
+	handler.MigrateToPostgres(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unusable dsn, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// maxMetadataSearchResults caps each result list of a metadata search
+const maxMetadataSearchResults = 100
+
+// MetadataHandler manages notes and ownership metadata on resources and middlewares
+type MetadataHandler struct {
+	DB *sql.DB
+}
+
+// NewMetadataHandler creates a new metadata handler
+func NewMetadataHandler(db *sql.DB) *MetadataHandler {
+	return &MetadataHandler{DB: db}
+}
+
+// metadataResult is one resource or middleware matched by a metadata search
+type metadataResult struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	models.OwnershipMetadata
+}
+
+// UpdateResourceMetadata replaces the notes, owner and contact of a resource.
+// Disabled resources can still be annotated.
+func (h *MetadataHandler) UpdateResourceMetadata(c *gin.Context) {
+	id := c.Param("id")
+	meta, ok := h.bindMetadata(c)
+	if !ok {
+		return
+	}
+
+	var exists int
+	err := h.DB.QueryRow("SELECT 1 FROM resources WHERE id = ?", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE resources SET notes = ?, owner = ?, contact = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		meta.Notes, meta.Owner, meta.Contact, time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating resource metadata: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update resource metadata")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Updated metadata for resource %s (owner %q)", id, meta.Owner)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{"id": id, "notes": meta.Notes, "owner": meta.Owner, "contact": meta.Contact})
+}
+
+// UpdateMiddlewareMetadata replaces the notes, owner and contact of a middleware.
+// An If-Match header is honoured like on middleware edits but not required.
+func (h *MetadataHandler) UpdateMiddlewareMetadata(c *gin.Context) {
+	id := c.Param("id")
+	meta, ok := h.bindMetadata(c)
+	if !ok {
+		return
+	}
+	expectedVersion, found, ok := requestedVersion(c, nil)
+	if !ok {
+		return
+	}
+
+	var current int64
+	err := h.DB.QueryRow("SELECT version FROM middlewares WHERE id = ?", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Middleware not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking middleware existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if !found {
+		expectedVersion = current
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE middlewares SET notes = ?, owner = ?, contact = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		meta.Notes, meta.Owner, meta.Contact, time.Now(), id, expectedVersion,
+	)
+	if err != nil {
+		log.Printf("Error updating middleware metadata: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update middleware metadata")
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		latest, err := (&MiddlewareHandler{DB: h.DB}).loadMiddleware(id)
+		if err != nil {
+			log.Printf("Error fetching middleware after version conflict: %v", err)
+		}
+		ResponseWithAPIError(c, versionConflictError("Middleware", latest))
+		return
+	}
+
+	log.Printf("Updated metadata for middleware %s (owner %q)", id, meta.Owner)
+	c.JSON(http.StatusOK, gin.H{"id": id, "notes": meta.Notes, "owner": meta.Owner, "contact": meta.Contact})
+}
+
+// SearchMetadata finds resources and middlewares whose notes, owner or contact
+// contain q, optionally limited to an owner
+func (h *MetadataHandler) SearchMetadata(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	owner := strings.TrimSpace(c.Query("owner"))
+	if q == "" && owner == "" {
+		ResponseWithAPIError(c, missingFieldError("q", "Search text or owner is required"))
+		return
+	}
+
+	where := []string{}
+	var args []interface{}
+	if q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		where = append(where, `(notes LIKE ? ESCAPE '\' OR owner LIKE ? ESCAPE '\' OR contact LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern)
+	}
+	if owner != "" {
+		where = append(where, "owner = ? COLLATE NOCASE")
+		args = append(args, owner)
+	}
+	filter := strings.Join(where, " AND ")
+
+	resources, err := h.searchTable("SELECT id, host, notes, owner, contact FROM resources WHERE "+filter+" ORDER BY host", args)
+	if err != nil {
+		log.Printf("Error searching resource metadata: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	middlewares, err := h.searchTable("SELECT id, name, notes, owner, contact FROM middlewares WHERE "+filter+" ORDER BY name", args)
+	if err != nil {
+		log.Printf("Error searching middleware metadata: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resources":   resources,
+		"middlewares": middlewares,
+	})
+}
+
+func (h *MetadataHandler) searchTable(query string, args []interface{}) ([]metadataResult, error) {
+	rows, err := h.DB.Query(query+fmt.Sprintf(" LIMIT %d", maxMetadataSearchResults), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []metadataResult{}
+	for rows.Next() {
+		var r metadataResult
+		if err := rows.Scan(&r.ID, &r.Name, &r.Notes, &r.Owner, &r.Contact); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// bindMetadata reads and validates a metadata request body
+func (h *MetadataHandler) bindMetadata(c *gin.Context) (models.OwnershipMetadata, bool) {
+	var meta models.OwnershipMetadata
+	if !bindRequest(c, &meta) {
+		return meta, false
+	}
+	meta.Normalize()
+	if err := meta.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid metadata: %v", err))
+		return meta, false
+	}
+	return meta, true
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestMetadataHandler_UpdateAndSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMetadataHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'billing.example.com', 'svc', 'org', 'site', 'disabled')`)
+	testutil.MustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'allow-webhooks', 'ipAllowList', '{}')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/metadata",
+		bytes.NewBufferString(`{"notes":"Stripe webhooks bypass auth","owner":"Payments","contact":"#payments"}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateResourceMetadata(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for resource metadata, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/middlewares/mw-1/metadata",
+		bytes.NewBufferString(`{"notes":"100% needed for webhooks","owner":"payments"}`))
+	c.Params = gin.Params{{Key: "id", Value: "mw-1"}}
+	handler.UpdateMiddlewareMetadata(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for middleware metadata, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/middlewares/missing/metadata", bytes.NewBufferString(`{}`))
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+	handler.UpdateMiddlewareMetadata(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing middleware, got %d", rec.Code)
+	}
+
+	search := func(query string) map[string][]map[string]interface{} {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodGet, "/api/metadata/search?"+query, nil)
+		handler.SearchMetadata(c)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %q: expected 200, got %d", query, rec.Code)
+		}
+		var resp map[string][]map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	resp := search("owner=payments")
+	if len(resp["resources"]) != 1 || len(resp["middlewares"]) != 1 {
+		t.Fatalf("owner search should match both, got %v", resp)
+	}
+	if resp["resources"][0]["name"] != "billing.example.com" {
+		t.Errorf("unexpected resource result: %v", resp["resources"][0])
+	}
+
+	resp = search("q=100%25")
+	if len(resp["resources"]) != 0 || len(resp["middlewares"]) != 1 {
+		t.Errorf("%% must match literally, got %v", resp)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/metadata/search", nil)
+	handler.SearchMetadata(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a query, got %d", rec.Code)
+	}
+}
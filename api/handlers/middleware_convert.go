@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// ConvertMiddlewares converts stored middleware configs between Traefik v2 and
+// v3 naming. It previews the changes unless dry_run is false.
+func (h *MiddlewareHandler) ConvertMiddlewares(c *gin.Context) {
+	var input struct {
+		Target string   `json:"target" binding:"required"`
+		IDs    []string `json:"ids"`
+		DryRun *bool    `json:"dry_run"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	major := services.ParseTraefikMajor(input.Target)
+	if major != 2 && major != 3 {
+		ResponseWithError(c, http.StatusBadRequest, "target must be a Traefik v2 or v3 version")
+		return
+	}
+	dryRun := true
+	if input.DryRun != nil {
+		dryRun = *input.DryRun
+	}
+
+	conversions, err := services.ConvertStoredMiddlewares(h.DB, major, input.IDs, !dryRun)
+	if err != nil {
+		log.Printf("Error converting middlewares to Traefik v%d: %v", major, err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to convert middlewares")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"target_major": major,
+		"dry_run":      dryRun,
+		"count":        len(conversions),
+		"middlewares":  conversions,
+	})
+}
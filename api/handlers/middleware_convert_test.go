@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestMiddlewareHandler_ConvertMiddlewares(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewMiddlewareHandler(db.DB)
+
+	testutil.MustExec(t, db, `
+		INSERT INTO middlewares (id, name, type, config)
+		VALUES ('mw-1', 'office-only', 'ipWhiteList', '{"sourceRange":["10.0.0.0/8"]}')
+	`)
+	testutil.MustExec(t, db, `
+		INSERT INTO middlewares (id, name, type, config)
+		VALUES ('mw-2', 'secure', 'headers', '{"featurePolicy":"camera ''none''","sslRedirect":true,"frameDeny":true}')
+	`)
+	testutil.MustExec(t, db, `
+		INSERT INTO middlewares (id, name, type, config)
+		VALUES ('mw-3', 'limit', 'rateLimit', '{"average":100}')
+	`)
+
+	convert := func(body string) map[string]interface{} {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPost, "/api/middlewares/convert", bytes.NewBufferString(body))
+		handler.ConvertMiddlewares(c)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return resp
+	}
+	storedType := func(id string) (string, string) {
+		t.Helper()
+		var typ, config string
+		if err := db.QueryRow("SELECT type, config FROM middlewares WHERE id = ?", id).Scan(&typ, &config); err != nil {
+			t.Fatalf("query middleware %s: %v", id, err)
+		}
+		return typ, config
+	}
+
+	// Preview by default
+	resp := convert(`{"target":"v3"}`)
+	if resp["dry_run"] != true || resp["count"] != float64(2) {
+		t.Fatalf("unexpected preview: %v", resp)
+	}
+	if typ, _ := storedType("mw-1"); typ != "ipWhiteList" {
+		t.Fatalf("dry run changed the stored type to %s", typ)
+	}
+
+	resp = convert(`{"target":"3","dry_run":false}`)
+	if resp["count"] != float64(2) {
+		t.Fatalf("unexpected conversion: %v", resp)
+	}
+	if typ, _ := storedType("mw-1"); typ != "ipAllowList" {
+		t.Errorf("mw-1 type = %s, want ipAllowList", typ)
+	}
+	if _, config := storedType("mw-2"); config != `{"frameDeny":true,"permissionsPolicy":"camera 'none'"}` {
+		t.Errorf("mw-2 config = %s", config)
+	}
+
+	// Rendering back to v2 only touches the selected middleware
+	resp = convert(`{"target":"v2.11","ids":["mw-1"],"dry_run":false}`)
+	if resp["count"] != float64(1) {
+		t.Fatalf("unexpected v2 conversion: %v", resp)
+	}
+	if typ, _ := storedType("mw-1"); typ != "ipWhiteList" {
+		t.Errorf("mw-1 type = %s, want ipWhiteList", typ)
+	}
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/middlewares/convert", bytes.NewBufferString(`{"target":"v1"}`))
+	handler.ConvertMiddlewares(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported target, got %d", rec.Code)
+	}
+}
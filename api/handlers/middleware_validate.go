@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// ValidateMiddleware checks a middleware config against the Traefik schema of
+// its type without saving it
+func (h *MiddlewareHandler) ValidateMiddleware(c *gin.Context) {
+	var input struct {
+		Type   string                 `json:"type" binding:"required"`
+		Config map[string]interface{} `json:"config"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if !checkConfigSize(c, "config", input.Config, maxMiddlewareConfigSize) {
+		return
+	}
+	if !isValidMiddlewareType(input.Type) {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid middleware type: %s", input.Type))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ValidateMiddlewareConfig(input.Type, input.Config))
+}
+
+// checkMiddlewareSchema rejects a config that does not match the Traefik
+// schema of its type with 422, unless the request has ?force=true
+func checkMiddlewareSchema(c *gin.Context, name, typ string, config map[string]interface{}) bool {
+	result := models.ValidateMiddlewareConfig(typ, config)
+	if result.Valid {
+		return true
+	}
+	if c.Query("force") == "true" {
+		log.Printf("Warning: saving middleware %s despite %d schema errors (force=true)", name, len(result.Errors))
+		return true
+	}
+
+	errs := make([]apierrors.FieldError, 0, len(result.Errors))
+	for _, issue := range result.Errors {
+		field := "config"
+		if issue.Field != "" {
+			field += "." + issue.Field
+		}
+		errs = append(errs, apierrors.FieldError{Field: field, Code: issue.Code, Message: issue.Message})
+	}
+	ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+		fmt.Sprintf("Middleware config does not match the Traefik %s schema", typ)).WithErrors(errs).
+		WithHint("Fix the listed fields, or retry with ?force=true to save the config as is."))
+	return false
+}
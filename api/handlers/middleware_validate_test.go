@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestMiddlewareHandler_ValidateMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMiddlewareHandler(db.DB)
+
+	body := `{"type":"buffering","config":{"maxRequestBodyByte":1000}}`
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/middlewares/validate", bytes.NewBufferString(body))
+	handler.ValidateMiddleware(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result models.MiddlewareValidation
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Valid || len(result.Errors) != 1 || result.Errors[0].Field != "maxRequestBodyByte" {
+		t.Fatalf("expected one error on maxRequestBodyByte, got %+v", result)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/middlewares/validate",
+		bytes.NewBufferString(`{"type":"bogus","config":{}}`))
+	handler.ValidateMiddleware(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown type, got %d", rec.Code)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM middlewares WHERE type = 'buffering'").Scan(&count); err != nil || count != 0 {
+		t.Fatalf("validate must not save anything, found %d (%v)", count, err)
+	}
+}
+
+func TestMiddlewareHandler_CreateMiddleware_SchemaErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMiddlewareHandler(db.DB)
+
+	body := `{"name":"buf","type":"buffering","config":{"maxRequestBodyByte":1000}}`
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/middlewares", bytes.NewBufferString(body))
+	handler.CreateMiddleware(c)
+	if rec.Code != http.StatusUnprocessableEntity ||
+		!strings.Contains(rec.Body.String(), `"field":"config.maxRequestBodyByte"`) ||
+		!strings.Contains(rec.Body.String(), "maxRequestBodyBytes") {
+		t.Fatalf("expected 422 naming the field, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/middlewares?force=true", bytes.NewBufferString(body))
+	handler.CreateMiddleware(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected force=true to save the config, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMiddlewareHandler_UpdateMiddleware_SchemaErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMiddlewareHandler(db.DB)
+
+	testutil.MustExec(t, db, `
+		INSERT INTO middlewares (id, name, type, config)
+		VALUES ('rl', 'limit', 'rateLimit', '{"average":100}')
+	`)
+
+	body := `{"name":"limit","type":"rateLimit","config":{"average":"lots"},"version":1}`
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/middlewares/rl", bytes.NewBufferString(body))
+	c.Params = gin.Params{{Key: "id", Value: "rl"}}
+	handler.UpdateMiddleware(c)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"config.average"`) {
+		t.Fatalf("expected 422 on config.average, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var config string
+	if err := db.QueryRow("SELECT config FROM middlewares WHERE id = 'rl'").Scan(&config); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if config != `{"average":100}` {
+		t.Fatalf("rejected update changed the config to %s", config)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
 )
 
 // MiddlewareHandler handles middleware-related requests
@@ -37,7 +38,7 @@ func (h *MiddlewareHandler) GetMiddlewares(c *gin.Context) {
 		}
 	}
 
-	query := "SELECT id, name, type, config FROM middlewares ORDER BY name"
+	query := "SELECT id, name, type, config, version, notes, owner, contact FROM middlewares ORDER BY name"
 	var rows *sql.Rows
 	var err error
 
@@ -57,8 +58,9 @@ func (h *MiddlewareHandler) GetMiddlewares(c *gin.Context) {
 
 	middlewares := []map[string]interface{}{}
 	for rows.Next() {
-		var id, name, typ, configStr string
-		if err := rows.Scan(&id, &name, &typ, &configStr); err != nil {
+		var id, name, typ, configStr, notes, owner, contact string
+		var version int64
+		if err := rows.Scan(&id, &name, &typ, &configStr, &version, &notes, &owner, &contact); err != nil {
 			log.Printf("Error scanning middleware row: %v", err)
 			continue
 		}
@@ -70,10 +72,14 @@ func (h *MiddlewareHandler) GetMiddlewares(c *gin.Context) {
 		}
 
 		middlewares = append(middlewares, map[string]interface{}{
-			"id":     id,
-			"name":   name,
-			"type":   typ,
-			"config": config,
+			"id":      id,
+			"name":    name,
+			"type":    typ,
+			"config":  config,
+			"version": version,
+			"notes":   notes,
+			"owner":   owner,
+			"contact": contact,
 		})
 	}
 
@@ -98,8 +104,10 @@ func (h *MiddlewareHandler) CreateMiddleware(c *gin.Context) {
 		Config map[string]interface{} `json:"config" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&middleware); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &middleware) {
+		return
+	}
+	if !checkConfigSize(c, "config", middleware.Config, maxMiddlewareConfigSize) {
 		return
 	}
 
@@ -108,6 +116,9 @@ func (h *MiddlewareHandler) CreateMiddleware(c *gin.Context) {
 		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid middleware type: %s", middleware.Type))
 		return
 	}
+	if !checkMiddlewareSchema(c, middleware.Name, middleware.Type, middleware.Config) {
+		return
+	}
 
 	// Generate a unique ID
 	id, err := generateID()
@@ -129,7 +140,7 @@ func (h *MiddlewareHandler) CreateMiddleware(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 	
@@ -167,16 +178,18 @@ func (h *MiddlewareHandler) CreateMiddleware(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully created middleware %s (%s)", middleware.Name, id)
+	c.Header("ETag", versionETag(1))
 	c.JSON(http.StatusCreated, gin.H{
-		"id":     id,
-		"name":   middleware.Name,
-		"type":   middleware.Type,
-		"config": middleware.Config,
+		"id":      id,
+		"name":    middleware.Name,
+		"type":    middleware.Type,
+		"config":  middleware.Config,
+		"version": 1,
 	})
 }
 
@@ -184,12 +197,11 @@ func (h *MiddlewareHandler) CreateMiddleware(c *gin.Context) {
 func (h *MiddlewareHandler) GetMiddleware(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Middleware ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Middleware ID is required"))
 		return
 	}
 
-	var name, typ, configStr string
-	err := h.DB.QueryRow("SELECT name, type, config FROM middlewares WHERE id = ?", id).Scan(&name, &typ, &configStr)
+	middleware, err := h.loadMiddleware(id)
 	if err == sql.ErrNoRows {
 		ResponseWithError(c, http.StatusNotFound, "Middleware not found")
 		return
@@ -199,36 +211,69 @@ func (h *MiddlewareHandler) GetMiddleware(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", versionETag(middleware["version"].(int64)))
+	c.JSON(http.StatusOK, middleware)
+}
+
+// loadMiddleware returns a middleware as served by GetMiddleware
+func (h *MiddlewareHandler) loadMiddleware(id string) (gin.H, error) {
+	var name, typ, configStr, notes, owner, contact string
+	var version int64
+	err := h.DB.QueryRow("SELECT name, type, config, version, notes, owner, contact FROM middlewares WHERE id = ?", id).
+		Scan(&name, &typ, &configStr, &version, &notes, &owner, &contact)
+	if err != nil {
+		return nil, err
+	}
+
 	var config map[string]interface{}
 	if err := json.Unmarshal([]byte(configStr), &config); err != nil {
 		log.Printf("Error parsing middleware config: %v", err)
 		config = map[string]interface{}{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":     id,
-		"name":   name,
-		"type":   typ,
-		"config": config,
-	})
+	return gin.H{
+		"id":      id,
+		"name":    name,
+		"type":    typ,
+		"config":  config,
+		"version": version,
+		"notes":   notes,
+		"owner":   owner,
+		"contact": contact,
+	}, nil
 }
 
 // UpdateMiddleware updates a middleware configuration
 func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Middleware ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Middleware ID is required"))
 		return
 	}
 
 	var middleware struct {
-		Name   string                 `json:"name" binding:"required"`
-		Type   string                 `json:"type" binding:"required"`
-		Config map[string]interface{} `json:"config" binding:"required"`
+		Name    string                 `json:"name" binding:"required"`
+		Type    string                 `json:"type" binding:"required"`
+		Config  map[string]interface{} `json:"config" binding:"required"`
+		Version *int64                 `json:"version"`
+	}
+
+	if !bindRequest(c, &middleware) {
+		return
+	}
+	if !checkConfigSize(c, "config", middleware.Config, maxMiddlewareConfigSize) {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&middleware); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	// The client must say which version it edited so concurrent edits are not lost
+	expectedVersion, found, ok := requestedVersion(c, middleware.Version)
+	if !ok {
+		return
+	}
+	if !found {
+		ResponseWithAPIError(c, apierrors.New(http.StatusPreconditionRequired, apierrors.CodeVersionRequired,
+			"Middleware version is required").WithField("version").
+			WithHint("Send the version from the last GET in the body or as If-Match."))
 		return
 	}
 
@@ -237,6 +282,9 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid middleware type: %s", middleware.Type))
 		return
 	}
+	if !checkMiddlewareSchema(c, middleware.Name, middleware.Type, middleware.Config) {
+		return
+	}
 
 	// Check if middleware exists
 	var exists int
@@ -246,7 +294,7 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking middleware existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -262,7 +310,7 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 	
@@ -278,9 +326,10 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 	log.Printf("Attempting to update middleware %s with name=%s, type=%s", 
 		id, middleware.Name, middleware.Type)
 	
+	// Only update the version the client saw; anything else is a concurrent edit
 	result, txErr := tx.Exec(
-		"UPDATE middlewares SET name = ?, type = ?, config = ?, updated_at = ? WHERE id = ?",
-		middleware.Name, middleware.Type, string(configJSON), time.Now(), id,
+		"UPDATE middlewares SET name = ?, type = ?, config = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		middleware.Name, middleware.Type, string(configJSON), time.Now(), id, expectedVersion,
 	)
 	
 	if txErr != nil {
@@ -293,14 +342,20 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 	if err == nil {
 		log.Printf("Update affected %d rows", rowsAffected)
 		if rowsAffected == 0 {
-			log.Printf("Warning: Update query succeeded but no rows were affected")
+			tx.Rollback()
+			current, err := h.loadMiddleware(id)
+			if err != nil {
+				log.Printf("Error fetching middleware after version conflict: %v", err)
+			}
+			ResponseWithAPIError(c, versionConflictError("Middleware", current))
+			return
 		}
 	}
 	
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -316,11 +371,13 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 	}
 
 	// Return the updated middleware
+	c.Header("ETag", versionETag(expectedVersion+1))
 	c.JSON(http.StatusOK, gin.H{
-		"id":     id,
-		"name":   middleware.Name,
-		"type":   middleware.Type,
-		"config": middleware.Config,
+		"id":      id,
+		"name":    middleware.Name,
+		"type":    middleware.Type,
+		"config":  middleware.Config,
+		"version": expectedVersion + 1,
 	})
 }
 
@@ -328,7 +385,7 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 func (h *MiddlewareHandler) DeleteMiddleware(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Middleware ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Middleware ID is required"))
 		return
 	}
 
@@ -337,7 +394,7 @@ func (h *MiddlewareHandler) DeleteMiddleware(c *gin.Context) {
 	err := h.DB.QueryRow("SELECT COUNT(*) FROM resource_middlewares WHERE middleware_id = ?", id).Scan(&count)
 	if err != nil {
 		log.Printf("Error checking middleware dependencies: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -346,11 +403,35 @@ func (h *MiddlewareHandler) DeleteMiddleware(c *gin.Context) {
 		return
 	}
 
+	err = h.DB.QueryRow("SELECT COUNT(*) FROM entrypoint_middlewares WHERE middleware_id = ?", id).Scan(&count)
+	if err != nil {
+		log.Printf("Error checking middleware dependencies: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	if count > 0 {
+		ResponseWithError(c, http.StatusConflict, fmt.Sprintf("Cannot delete middleware because it is attached to %d entrypoints", count))
+		return
+	}
+
+	err = h.DB.QueryRow("SELECT COUNT(*) FROM default_chain_middlewares WHERE middleware_id = ?", id).Scan(&count)
+	if err != nil {
+		log.Printf("Error checking middleware dependencies: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	if count > 0 {
+		ResponseWithError(c, http.StatusConflict, "Cannot delete middleware because it is part of the default chain")
+		return
+	}
+
 	// Delete from database using a transaction
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 	
@@ -375,7 +456,7 @@ func (h *MiddlewareHandler) DeleteMiddleware(c *gin.Context) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Error getting rows affected: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -384,8 +465,15 @@ func (h *MiddlewareHandler) DeleteMiddleware(c *gin.Context) {
 		return
 	}
 
+	_, txErr = tx.Exec("DELETE FROM environment_tags WHERE target_type = 'middleware' AND target_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing middleware environment tags: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete middleware")
+		return
+	}
+
 	// Track deletion to prevent template from being re-created on restart
-	_, txErr = tx.Exec("INSERT OR REPLACE INTO deleted_templates (id, type) VALUES (?, 'middleware')", id)
+	_, txErr = tx.Exec("INSERT INTO deleted_templates (id, type) VALUES (?, 'middleware') ON CONFLICT(id, type) DO UPDATE SET deleted_at = CURRENT_TIMESTAMP", id)
 	if txErr != nil {
 		log.Printf("Warning: Failed to track deleted template: %v", txErr)
 		// Continue anyway - this is not critical
@@ -396,7 +484,7 @@ func (h *MiddlewareHandler) DeleteMiddleware(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -214,8 +214,8 @@ func TestMiddlewareHandler_CreateMiddleware_ValidationError(t *testing.T) {
 	c, rec := testutil.NewContext(t, http.MethodPost, "/api/middlewares", body)
 	handler.CreateMiddleware(c)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", rec.Code)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", rec.Code)
 	}
 }
 
@@ -233,7 +233,8 @@ func TestMiddlewareHandler_UpdateMiddleware(t *testing.T) {
 	body := bytes.NewBufferString(`{
 		"name": "updated-name",
 		"type": "headers",
-		"config": {"customRequestHeaders": {"X-Updated": "true"}}
+		"config": {"customRequestHeaders": {"X-Updated": "true"}},
+		"version": 1
 	}`)
 
 	c, rec := testutil.NewContext(t, http.MethodPut, "/api/middlewares/update-test", body)
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// MirrorHandler manages per-resource request mirroring
+type MirrorHandler struct {
+	DB *sql.DB
+}
+
+// NewMirrorHandler creates a new mirror handler
+func NewMirrorHandler(db *sql.DB) *MirrorHandler {
+	return &MirrorHandler{DB: db}
+}
+
+// GetMirrors returns every configured request mirror
+func (h *MirrorHandler) GetMirrors(c *gin.Context) {
+	rows, err := h.DB.Query(`
+		SELECT resource_id, target_url, percent, mirror_body, max_body_size, created_at, updated_at
+		FROM resource_mirrors ORDER BY resource_id
+	`)
+	if err != nil {
+		log.Printf("Error fetching mirrors: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch mirrors")
+		return
+	}
+	defer rows.Close()
+
+	mirrors := []models.ResourceMirror{}
+	for rows.Next() {
+		mirror, err := scanMirror(rows)
+		if err != nil {
+			log.Printf("Error scanning mirror row: %v", err)
+			continue
+		}
+		mirrors = append(mirrors, mirror)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating mirror rows: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Database error while fetching mirrors")
+		return
+	}
+
+	c.JSON(http.StatusOK, mirrors)
+}
+
+// GetResourceMirror returns the mirror configured for a resource and the services it generates
+func (h *MirrorHandler) GetResourceMirror(c *gin.Context) {
+	id := c.Param("id")
+	mirror, err := scanMirror(h.DB.QueryRow(`
+		SELECT resource_id, target_url, percent, mirror_body, max_body_size, created_at, updated_at
+		FROM resource_mirrors WHERE resource_id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "No mirror configured for this resource")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching mirror: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	mirroringService, targetService := models.MirrorServiceNames(id)
+	c.JSON(http.StatusOK, gin.H{
+		"mirror":            mirror,
+		"mirroring_service": mirroringService,
+		"target_service":    targetService,
+	})
+}
+
+// SetResourceMirror creates or replaces the mirror for a resource. The router's
+// service is wrapped in a mirroring service on the next config merge.
+func (h *MirrorHandler) SetResourceMirror(c *gin.Context) {
+	id := c.Param("id")
+	var mirror models.ResourceMirror
+	// Mirror request bodies unless told otherwise, matching Traefik's default
+	mirror.MirrorBody = true
+	if !bindRequest(c, &mirror) {
+		return
+	}
+
+	mirror.Normalize()
+	if err := mirror.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid mirror: %v", err))
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	mirrorBody := 0
+	if mirror.MirrorBody {
+		mirrorBody = 1
+	}
+	mirror.ResourceID = id
+	mirror.UpdatedAt = time.Now()
+	tx, err := h.DB.Begin()
+	if err != nil {
+		log.Printf("Error beginning transaction: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	defer tx.Rollback()
+
+	if bumped, err := bumpResourceVersion(tx, id, version); err != nil {
+		log.Printf("Error updating resource version: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save mirror")
+		return
+	} else if !bumped {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO resource_mirrors (resource_id, target_url, percent, mirror_body, max_body_size, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(resource_id) DO UPDATE SET target_url = excluded.target_url, percent = excluded.percent,
+			mirror_body = excluded.mirror_body, max_body_size = excluded.max_body_size, updated_at = excluded.updated_at
+	`, id, mirror.TargetURL, mirror.Percent, mirrorBody, mirror.MaxBodySize, mirror.UpdatedAt); err != nil {
+		log.Printf("Error saving mirror: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save mirror")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing mirror: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	log.Printf("Mirroring %d%% of resource %s requests to %s", mirror.Percent, id, mirror.TargetURL)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, mirror)
+}
+
+// DeleteResourceMirror stops mirroring a resource; its router gets the original service back
+func (h *MirrorHandler) DeleteResourceMirror(c *gin.Context) {
+	id := c.Param("id")
+	result, err := h.DB.Exec("DELETE FROM resource_mirrors WHERE resource_id = ?", id)
+	if err != nil {
+		log.Printf("Error deleting mirror: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete mirror")
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		ResponseWithError(c, http.StatusNotFound, "No mirror configured for this resource")
+		return
+	}
+
+	log.Printf("Removed mirror for resource %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Mirror removed successfully"})
+}
+
+func scanMirror(row corsScanner) (models.ResourceMirror, error) {
+	var mirror models.ResourceMirror
+	var mirrorBody int
+	err := row.Scan(&mirror.ResourceID, &mirror.TargetURL, &mirror.Percent, &mirrorBody, &mirror.MaxBodySize,
+		&mirror.CreatedAt, &mirror.UpdatedAt)
+	mirror.MirrorBody = mirrorBody == 1
+	return mirror, err
+}
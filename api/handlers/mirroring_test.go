@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestMirrorHandler_SetAndTearDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMirrorHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	set := func(body string) (int, map[string]interface{}) {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/mirror", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.SetResourceMirror(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	if code, _ := set(`{"target_url":"staging:8080","percent":10}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a URL without scheme, got %d", code)
+	}
+	if code, _ := set(`{"target_url":"http://staging:8080","percent":0}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for 0 percent, got %d", code)
+	}
+	code, resp := set(`{"target_url":" http://staging:8080/ ","percent":10}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, resp)
+	}
+	if resp["target_url"] != "http://staging:8080" || resp["mirror_body"] != true {
+		t.Fatalf("unexpected mirror: %v", resp)
+	}
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/resources/res-1/mirror", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.GetResourceMirror(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/resources/res-1/mirror", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.DeleteResourceMirror(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 removing mirror, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/resources/res-1/mirror", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.DeleteResourceMirror(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 removing a missing mirror, got %d", rec.Code)
+	}
+}
+
+func TestMirrorHandler_DisabledResourceErrorCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMirrorHandler(db.DB)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'disabled')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/mirror",
+		bytes.NewBufferString(`{"target_url":"http://staging:8080","percent":10}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.SetResourceMirror(c)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusBadRequest || resp["code"] != "resource_disabled" {
+		t.Fatalf("expected 400 resource_disabled, got %d: %v", rec.Code, resp)
+	}
+}
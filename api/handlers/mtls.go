@@ -2,16 +2,13 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hhftechnology/middleware-manager/models"
 	"github.com/hhftechnology/middleware-manager/services"
-	"gopkg.in/yaml.v3"
 )
 
 // MTLSHandler handles mTLS-related requests
@@ -92,8 +89,7 @@ func (h *MTLSHandler) DisableMTLS(c *gin.Context) {
 // CreateCA creates a new Certificate Authority
 func (h *MTLSHandler) CreateCA(c *gin.Context) {
 	var req models.CreateCARequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindRequest(c, &req) {
 		return
 	}
 
@@ -160,12 +156,15 @@ func (h *MTLSHandler) GetClients(c *gin.Context) {
 // CreateClient creates a new client certificate
 func (h *MTLSHandler) CreateClient(c *gin.Context) {
 	var req models.CreateClientRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindRequest(c, &req) {
 		return
 	}
 
 	client, err := h.CertGenerator.GenerateClientCert(req)
+	if errors.Is(err, services.ErrCANotConfigured) {
+		ResponseWithError(c, http.StatusBadRequest, "Failed to create client certificate: "+err.Error())
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating client certificate: %v", err)
 		ResponseWithError(c, http.StatusInternalServerError, "Failed to create client certificate: "+err.Error())
@@ -182,7 +181,7 @@ func (h *MTLSHandler) CreateClient(c *gin.Context) {
 func (h *MTLSHandler) GetClient(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Client ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Client ID is required"))
 		return
 	}
 
@@ -200,7 +199,7 @@ func (h *MTLSHandler) GetClient(c *gin.Context) {
 func (h *MTLSHandler) DownloadClientP12(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Client ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Client ID is required"))
 		return
 	}
 
@@ -210,6 +209,11 @@ func (h *MTLSHandler) DownloadClientP12(c *gin.Context) {
 		ResponseWithError(c, http.StatusNotFound, "Client not found")
 		return
 	}
+	if len(p12Data) == 0 {
+		// Imported without a private key or PKCS#12 password
+		ResponseWithError(c, http.StatusNotFound, "No PKCS#12 file is stored for this client")
+		return
+	}
 
 	filename := name + ".p12"
 	c.Header("Content-Disposition", "attachment; filename="+filename)
@@ -221,7 +225,7 @@ func (h *MTLSHandler) DownloadClientP12(c *gin.Context) {
 func (h *MTLSHandler) RevokeClient(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Client ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Client ID is required"))
 		return
 	}
 
@@ -241,7 +245,7 @@ func (h *MTLSHandler) RevokeClient(c *gin.Context) {
 func (h *MTLSHandler) DeleteClient(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Client ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Client ID is required"))
 		return
 	}
 
@@ -263,8 +267,7 @@ func (h *MTLSHandler) UpdateCertsBasePath(c *gin.Context) {
 		CertsBasePath string `json:"certs_base_path" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -282,11 +285,11 @@ func (h *MTLSHandler) UpdateCertsBasePath(c *gin.Context) {
 
 // CheckPlugin checks if the mtlswhitelist plugin is installed
 func (h *MTLSHandler) CheckPlugin(c *gin.Context) {
-	installed, version := h.isPluginInstalled("mtlswhitelist")
+	installed, version := h.isPluginInstalled(mtlsPluginName)
 
 	c.JSON(http.StatusOK, gin.H{
 		"installed":   installed,
-		"plugin_name": "mtlswhitelist",
+		"plugin_name": mtlsPluginName,
 		"version":     version,
 	})
 }
@@ -297,38 +300,13 @@ func (h *MTLSHandler) isPluginInstalled(pluginName string) (bool, string) {
 		return false, ""
 	}
 
-	cleanPath := filepath.Clean(h.TraefikStaticConfigPath)
-	yamlFile, err := os.ReadFile(cleanPath)
+	config, err := readStaticConfig(h.TraefikStaticConfigPath)
 	if err != nil {
 		log.Printf("Warning: Could not read Traefik config for plugin check: %v", err)
 		return false, ""
 	}
 
-	var config map[string]interface{}
-	if err := yaml.Unmarshal(yamlFile, &config); err != nil {
-		log.Printf("Warning: Could not parse Traefik config for plugin check: %v", err)
-		return false, ""
-	}
-
-	// Check experimental.plugins section
-	if experimentalSection, ok := config["experimental"].(map[string]interface{}); ok {
-		if pluginsConfig, ok := experimentalSection["plugins"].(map[string]interface{}); ok {
-			// Check for the plugin by name (lowercase comparison)
-			for key, pluginData := range pluginsConfig {
-				if strings.EqualFold(key, pluginName) {
-					version := ""
-					if pluginEntry, ok := pluginData.(map[string]interface{}); ok {
-						if v, ok := pluginEntry["version"].(string); ok {
-							version = v
-						}
-					}
-					return true, version
-				}
-			}
-		}
-	}
-
-	return false, ""
+	return staticConfigPlugin(config, pluginName)
 }
 
 // GetMiddlewareConfig returns the mTLS middleware configuration
@@ -346,8 +324,7 @@ func (h *MTLSHandler) GetMiddlewareConfig(c *gin.Context) {
 // UpdateMiddlewareConfig updates the mTLS middleware configuration
 func (h *MTLSHandler) UpdateMiddlewareConfig(c *gin.Context) {
 	var input models.MTLSMiddlewareConfig
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -361,3 +338,49 @@ func (h *MTLSHandler) UpdateMiddlewareConfig(c *gin.Context) {
 		"message": "Middleware configuration updated successfully",
 	})
 }
+
+// ExportConfig returns a JSON manifest with the CA bundle, CRL and per-resource
+// verification policy, for use by other proxies (nginx, HAProxy, ...)
+func (h *MTLSHandler) ExportConfig(c *gin.Context) {
+	export, err := h.CertGenerator.ExportConfig()
+	if err != nil {
+		log.Printf("Error exporting mTLS config: %v", err)
+		ResponseWithError(c, http.StatusBadRequest, "Failed to export mTLS configuration: "+err.Error())
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", "attachment; filename=mtls-manifest.json")
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+// ExportPEMBundle downloads the CA certificate followed by the current CRL as one PEM file
+func (h *MTLSHandler) ExportPEMBundle(c *gin.Context) {
+	bundle, err := h.CertGenerator.ExportPEMBundle()
+	if err != nil {
+		log.Printf("Error exporting mTLS PEM bundle: %v", err)
+		ResponseWithError(c, http.StatusBadRequest, "Failed to export PEM bundle: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=mtls-bundle.pem")
+	c.Data(http.StatusOK, "application/x-pem-file", bundle)
+}
+
+// ExportCRL downloads the current certificate revocation list
+func (h *MTLSHandler) ExportCRL(c *gin.Context) {
+	crlPEM, _, err := h.CertGenerator.GenerateCRL()
+	if errors.Is(err, services.ErrExternalCA) {
+		ResponseWithError(c, http.StatusConflict, "The CRL is published by the external CA that issues client certificates")
+		return
+	}
+	if err != nil {
+		log.Printf("Error generating CRL: %v", err)
+		ResponseWithError(c, http.StatusBadRequest, "Failed to generate CRL: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=crl.pem")
+	c.Data(http.StatusOK, "application/x-pem-file", crlPEM)
+}
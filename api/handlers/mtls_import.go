@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// ImportClients imports existing client certificates, e.g. from easy-rsa or
+// step-ca, reporting the outcome of each one
+func (h *MTLSHandler) ImportClients(c *gin.Context) {
+	var req models.ImportClientsRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	if len(req.Clients) == 0 {
+		ResponseWithAPIError(c, missingFieldError("clients", "At least one client certificate is required"))
+		return
+	}
+	if len(req.Clients) > services.MaxClientImport {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("At most %d client certificates can be imported at once", services.MaxClientImport))
+		return
+	}
+
+	response, err := h.CertGenerator.ImportClients(req.Clients)
+	if err != nil {
+		log.Printf("Error importing client certificates: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to import client certificates")
+		return
+	}
+	log.Printf("Imported %d client certificates (%d skipped, %d failed)", response.Imported, response.Skipped, response.Failed)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportClients returns the certificates of every client that is not revoked,
+// as JSON or, with ?format=pem, as one PEM file
+func (h *MTLSHandler) ExportClients(c *gin.Context) {
+	if c.Query("format") == "pem" {
+		bundle, err := h.CertGenerator.ExportClientCertsPEM()
+		if err != nil {
+			log.Printf("Error exporting client certificates: %v", err)
+			ResponseWithError(c, http.StatusInternalServerError, "Failed to export client certificates")
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename=mtls-clients.pem")
+		c.Data(http.StatusOK, "application/x-pem-file", bundle)
+		return
+	}
+
+	certs, err := h.CertGenerator.ExportClientCerts()
+	if err != nil {
+		log.Printf("Error exporting client certificates: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to export client certificates")
+		return
+	}
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", "attachment; filename=mtls-clients.json")
+	}
+	c.JSON(http.StatusOK, certs)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
+	"gopkg.in/yaml.v3"
+)
+
+// mtlsPluginName is the Traefik plugin that verifies client certificates
+const mtlsPluginName = "mtlswhitelist"
+
+// Preflight reports whether mTLS can be enabled on a resource without
+// breaking its TLS handshake
+func (h *MTLSHandler) Preflight(c *gin.Context) {
+	resourceID := c.Param("id")
+	if resourceID == "" {
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+
+	result, err := runMTLSPreflight(h.DB, h.TraefikStaticConfigPath, resourceID)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error running mTLS pre-flight checks: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to run mTLS pre-flight checks")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// runMTLSPreflight checks that the mtlswhitelist plugin is installed, that the
+// CA certificate exists where the generated middleware points Traefik, and
+// that the resource's router terminates TLS
+func runMTLSPreflight(db *sql.DB, staticConfigPath, resourceID string) (*models.MTLSPreflightResult, error) {
+	var entrypoints, tlsDomains string
+	err := db.QueryRow(
+		"SELECT COALESCE(entrypoints, ''), COALESCE(tls_domains, '') FROM resources WHERE id = ?", resourceID,
+	).Scan(&entrypoints, &tlsDomains)
+	if err != nil {
+		return nil, err
+	}
+
+	var caCertPath string
+	err = db.QueryRow("SELECT COALESCE(ca_cert_path, '') FROM mtls_config WHERE id = 1").Scan(&caCertPath)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// The static config is optional; without it the plugin and entrypoint
+	// checks fall back to warnings
+	var staticConfig map[string]interface{}
+	var staticErr error
+	if staticConfigPath == "" {
+		staticErr = fmt.Errorf("Traefik static config path is not set")
+	} else {
+		staticConfig, staticErr = readStaticConfig(staticConfigPath)
+	}
+
+	checks := []models.MTLSPreflightCheck{
+		checkMTLSPlugin(staticConfig, staticErr),
+		checkCACertFile(caCertPath),
+		checkRouterTLS(staticConfig, entrypoints, tlsDomains),
+	}
+
+	result := &models.MTLSPreflightResult{ResourceID: resourceID, Ready: true, Checks: checks}
+	for _, check := range checks {
+		if check.Status == models.PreflightFail {
+			result.Ready = false
+		}
+	}
+	return result, nil
+}
+
+// preflightError turns the failed checks into a 409 listing each problem
+func preflightError(result *models.MTLSPreflightResult) *apierrors.APIError {
+	var errs []apierrors.FieldError
+	hint := ""
+	for _, check := range result.Checks {
+		if check.Status != models.PreflightFail {
+			continue
+		}
+		message := check.Message
+		if check.Hint != "" {
+			message += ". " + check.Hint
+			if hint == "" {
+				hint = check.Hint
+			}
+		}
+		errs = append(errs, apierrors.FieldError{Field: check.Name, Code: models.PreflightFail, Message: message})
+	}
+	return apierrors.New(http.StatusConflict, apierrors.CodePreflightFailed,
+		"Cannot enable mTLS on resource: pre-flight checks failed").WithErrors(errs).WithHint(hint)
+}
+
+// readStaticConfig parses the Traefik static config file
+func readStaticConfig(path string) (map[string]interface{}, error) {
+	data, err := util.OS.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// staticConfigPlugin looks up a plugin under experimental.plugins and returns
+// its version
+func staticConfigPlugin(config map[string]interface{}, pluginName string) (bool, string) {
+	experimentalSection, ok := config["experimental"].(map[string]interface{})
+	if !ok {
+		return false, ""
+	}
+	pluginsConfig, ok := experimentalSection["plugins"].(map[string]interface{})
+	if !ok {
+		return false, ""
+	}
+	for key, pluginData := range pluginsConfig {
+		if strings.EqualFold(key, pluginName) {
+			version := ""
+			if pluginEntry, ok := pluginData.(map[string]interface{}); ok {
+				if v, ok := pluginEntry["version"].(string); ok {
+					version = v
+				}
+			}
+			return true, version
+		}
+	}
+	return false, ""
+}
+
+func checkMTLSPlugin(staticConfig map[string]interface{}, staticErr error) models.MTLSPreflightCheck {
+	check := models.MTLSPreflightCheck{Name: "plugin"}
+	if staticErr != nil {
+		check.Status = models.PreflightWarn
+		check.Message = fmt.Sprintf("Could not verify the %s plugin: %v", mtlsPluginName, staticErr)
+		check.Hint = "Set TRAEFIK_STATIC_CONFIG_PATH so MM can read the Traefik static config"
+		return check
+	}
+	if installed, version := staticConfigPlugin(staticConfig, mtlsPluginName); installed {
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("%s plugin is installed", mtlsPluginName)
+		if version != "" {
+			check.Message += " (" + version + ")"
+		}
+		return check
+	}
+	check.Status = models.PreflightFail
+	check.Message = fmt.Sprintf("The %s plugin is not installed in the Traefik static config", mtlsPluginName)
+	check.Hint = "Install it from the Plugins page (or add it under experimental.plugins) and restart Traefik"
+	return check
+}
+
+func checkCACertFile(caCertPath string) models.MTLSPreflightCheck {
+	check := models.MTLSPreflightCheck{Name: "ca_cert"}
+	if caCertPath == "" {
+		check.Status = models.PreflightFail
+		check.Message = "No CA certificate has been created"
+		check.Hint = "Create a CA on the mTLS page first"
+		return check
+	}
+
+	data, err := util.OS.ReadFile(caCertPath)
+	if err != nil {
+		check.Status = models.PreflightFail
+		check.Message = fmt.Sprintf("CA certificate is not readable at %s: %v", caCertPath, err)
+		check.Hint = "Point the certificates base path at a volume shared with Traefik, then recreate the CA"
+		return check
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		check.Status = models.PreflightFail
+		check.Message = fmt.Sprintf("%s does not contain a PEM certificate", caCertPath)
+		check.Hint = "Recreate the CA so the certificate file is rewritten"
+		return check
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		check.Status = models.PreflightFail
+		check.Message = fmt.Sprintf("%s contains an invalid certificate: %v", caCertPath, err)
+		check.Hint = "Recreate the CA so the certificate file is rewritten"
+		return check
+	}
+
+	check.Status = models.PreflightPass
+	check.Message = fmt.Sprintf("CA certificate found at %s", caCertPath)
+	return check
+}
+
+// Entrypoint TLS states used by checkRouterTLS
+const (
+	entrypointTLSUnknown = iota
+	entrypointTLSYes
+	entrypointTLSNo
+)
+
+func checkRouterTLS(staticConfig map[string]interface{}, entrypoints, tlsDomains string) models.MTLSPreflightCheck {
+	check := models.MTLSPreflightCheck{Name: "router_tls"}
+	if strings.TrimSpace(tlsDomains) != "" {
+		check.Status = models.PreflightPass
+		check.Message = "Router has TLS domains configured"
+		return check
+	}
+
+	names := splitEntrypoints(entrypoints)
+	if len(names) == 0 {
+		names = []string{"websecure"}
+	}
+
+	allPlain := true
+	for _, name := range names {
+		switch entrypointTLS(staticConfig, name) {
+		case entrypointTLSYes:
+			check.Status = models.PreflightPass
+			check.Message = fmt.Sprintf("Router uses TLS entrypoint %s", name)
+			return check
+		case entrypointTLSUnknown:
+			allPlain = false
+		}
+	}
+
+	if allPlain {
+		check.Status = models.PreflightFail
+		check.Message = fmt.Sprintf("Router entrypoints (%s) do not terminate TLS, so no client certificate can be requested",
+			strings.Join(names, ", "))
+		check.Hint = "Route the resource through a TLS entrypoint such as websecure"
+		return check
+	}
+	check.Status = models.PreflightWarn
+	check.Message = fmt.Sprintf("Could not determine whether entrypoints (%s) terminate TLS", strings.Join(names, ", "))
+	check.Hint = "Make sure the router has a tls section or uses a TLS entrypoint"
+	return check
+}
+
+// entrypointTLS decides from the static config (or, failing that, the
+// conventional names) whether an entrypoint terminates TLS
+func entrypointTLS(staticConfig map[string]interface{}, name string) int {
+	if eps, ok := staticConfig["entryPoints"].(map[string]interface{}); ok {
+		for key, raw := range eps {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			ep, _ := raw.(map[string]interface{})
+			if httpSection, ok := ep["http"].(map[string]interface{}); ok {
+				if _, ok := httpSection["tls"]; ok {
+					return entrypointTLSYes
+				}
+			}
+			// A router can still carry its own tls section, so only the
+			// well-known ports are conclusive
+			if address, ok := ep["address"].(string); ok {
+				if _, port, err := net.SplitHostPort(address); err == nil {
+					switch strings.SplitN(port, "/", 2)[0] {
+					case "443":
+						return entrypointTLSYes
+					case "80":
+						return entrypointTLSNo
+					}
+				}
+			}
+			return entrypointTLSUnknown
+		}
+	}
+
+	switch strings.ToLower(name) {
+	case "websecure", "https":
+		return entrypointTLSYes
+	case "web", "http":
+		return entrypointTLSNo
+	}
+	return entrypointTLSUnknown
+}
+
+// splitEntrypoints splits a comma-separated entrypoint list, dropping blanks
+func splitEntrypoints(entrypoints string) []string {
+	var names []string
+	for _, name := range strings.Split(entrypoints, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
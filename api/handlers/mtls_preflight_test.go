@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+const preflightStaticConfig = `
+entryPoints:
+  web:
+    address: ":80"
+  websecure:
+    address: ":443"
+experimental:
+  plugins:
+    mtlswhitelist:
+      moduleName: github.com/smerschjohann/mtlswhitelist
+      version: v0.3.0
+`
+
+// setupMTLSPreflight creates a CA on disk, enables global mTLS and writes a
+// static config, returning its path
+func setupMTLSPreflight(t *testing.T, db *sql.DB, staticConfig string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	cg := services.NewCertGenerator(db)
+	config, err := cg.GenerateCA(models.CreateCARequest{CommonName: "Test CA"}, filepath.Join(dir, "certs"))
+	if err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+	if err := cg.WriteCACertToFilesystem(config.CertsBasePath, []byte(config.CACert)); err != nil {
+		t.Fatalf("WriteCACertToFilesystem: %v", err)
+	}
+	if err := cg.EnableMTLS(); err != nil {
+		t.Fatalf("EnableMTLS: %v", err)
+	}
+
+	staticPath := filepath.Join(dir, "traefik.yml")
+	if err := os.WriteFile(staticPath, []byte(staticConfig), 0644); err != nil {
+		t.Fatalf("write static config: %v", err)
+	}
+	return staticPath
+}
+
+// TestRunMTLSPreflight_Ready tests a resource that passes every check
+func TestRunMTLSPreflight_Ready(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	staticPath := setupMTLSPreflight(t, db.DB, preflightStaticConfig)
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status, entrypoints)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active', 'websecure')
+	`)
+
+	result, err := runMTLSPreflight(db.DB, staticPath, "res-1")
+	if err != nil {
+		t.Fatalf("runMTLSPreflight: %v", err)
+	}
+	if !result.Ready {
+		t.Fatalf("expected resource to be ready, got %+v", result.Checks)
+	}
+	for _, check := range result.Checks {
+		if check.Status != models.PreflightPass {
+			t.Errorf("check %s: expected pass, got %s (%s)", check.Name, check.Status, check.Message)
+		}
+	}
+}
+
+// TestRunMTLSPreflight_Failures tests that each broken prerequisite is reported
+func TestRunMTLSPreflight_Failures(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	staticPath := setupMTLSPreflight(t, db.DB, "entryPoints:\n  web:\n    address: \":80\"\n")
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status, entrypoints)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active', 'web')
+	`)
+	var caPath string
+	db.DB.QueryRow("SELECT ca_cert_path FROM mtls_config WHERE id = 1").Scan(&caPath)
+	os.Remove(caPath)
+
+	result, err := runMTLSPreflight(db.DB, staticPath, "res-1")
+	if err != nil {
+		t.Fatalf("runMTLSPreflight: %v", err)
+	}
+	if result.Ready {
+		t.Fatal("expected resource not to be ready")
+	}
+	for _, check := range result.Checks {
+		if check.Status != models.PreflightFail {
+			t.Errorf("check %s: expected fail, got %s (%s)", check.Name, check.Status, check.Message)
+		}
+		if check.Hint == "" {
+			t.Errorf("check %s: expected an actionable hint", check.Name)
+		}
+	}
+}
+
+// TestRunMTLSPreflight_NoStaticConfig tests that unverifiable checks only warn
+func TestRunMTLSPreflight_NoStaticConfig(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	setupMTLSPreflight(t, db.DB, "")
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status, entrypoints)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active', 'internal')
+	`)
+
+	result, err := runMTLSPreflight(db.DB, "", "res-1")
+	if err != nil {
+		t.Fatalf("runMTLSPreflight: %v", err)
+	}
+	if !result.Ready {
+		t.Fatalf("expected warnings not to block, got %+v", result.Checks)
+	}
+	statuses := map[string]string{}
+	for _, check := range result.Checks {
+		statuses[check.Name] = check.Status
+	}
+	if statuses["plugin"] != models.PreflightWarn || statuses["router_tls"] != models.PreflightWarn {
+		t.Errorf("expected plugin and router_tls to warn, got %v", statuses)
+	}
+}
+
+// TestConfigHandler_UpdateMTLSConfig_PreflightFailed tests that enabling mTLS
+// is refused with the failing checks listed
+func TestConfigHandler_UpdateMTLSConfig_PreflightFailed(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	staticPath := setupMTLSPreflight(t, db.DB, "entryPoints:\n  websecure:\n    address: \":443\"\n")
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status, entrypoints)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active', 'websecure')
+	`)
+
+	handler := NewConfigHandler(db.DB)
+	handler.SetTraefikConfigPath(staticPath)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/mtls", strings.NewReader(`{"mtls_enabled": true}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateMTLSConfig(c)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Code   string `json:"code"`
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Code != "preflight_failed" {
+		t.Errorf("expected code preflight_failed, got %q", resp.Code)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Field != "plugin" {
+		t.Errorf("expected only the plugin check to fail, got %+v", resp.Errors)
+	}
+
+	var enabled int
+	db.DB.QueryRow("SELECT mtls_enabled FROM resources WHERE id = 'res-1'").Scan(&enabled)
+	if enabled != 0 {
+		t.Error("mTLS should not have been enabled")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -156,7 +157,7 @@ func TestMTLSHandler_CreateClient_NoCA(t *testing.T) {
 	db := testutil.NewTempDB(t)
 	handler := NewMTLSHandler(db.DB)
 
-	body := bytes.NewBufferString(`{"name": "test-client"}`)
+	body := bytes.NewBufferString(`{"name": "test-client", "p12_password": "s3cret-pass"}`)
 	c, rec := testutil.NewContext(t, http.MethodPost, "/api/mtls/clients", body)
 	handler.CreateClient(c)
 
@@ -164,6 +165,9 @@ func TestMTLSHandler_CreateClient_NoCA(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("expected 400 (no CA), got %d: %s", rec.Code, rec.Body.String())
 	}
+	if !strings.Contains(rec.Body.String(), "CA not configured") {
+		t.Errorf("expected a no-CA error, got %s", rec.Body.String())
+	}
 }
 
 // TestMTLSHandler_CreateClient_InvalidRequest tests invalid client creation
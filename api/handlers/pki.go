@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// UpdatePublicPKI enables or disables the unauthenticated /pki/ endpoints
+func (h *MTLSHandler) UpdatePublicPKI(c *gin.Context) {
+	var input struct {
+		Enabled bool `json:"enabled"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	if err := h.CertGenerator.SetPublicPKI(input.Enabled); err != nil {
+		log.Printf("Error updating public PKI setting: %v", err)
+		ResponseWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Public PKI endpoints enabled: %v", input.Enabled)
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Public PKI setting updated successfully",
+		"public_pki_enabled": input.Enabled,
+	})
+}
+
+// publishedCA returns the CA config when public PKI is enabled; otherwise it
+// responds 404 so the endpoints are indistinguishable from unknown paths
+func (h *MTLSHandler) publishedCA(c *gin.Context) (*models.MTLSConfig, bool) {
+	config, err := h.CertGenerator.GetConfig()
+	if err != nil {
+		log.Printf("Error getting mTLS config: %v", err)
+		c.String(http.StatusInternalServerError, "Internal error")
+		return nil, false
+	}
+	if !config.PublicPKI || !config.HasCA {
+		c.String(http.StatusNotFound, "Not found")
+		return nil, false
+	}
+	return config, true
+}
+
+// PublicCACert serves the CA certificate. Unauthenticated; only active when public PKI is enabled.
+// GET /pki/ca.crt (PEM by default, DER with ?format=der)
+func (h *MTLSHandler) PublicCACert(c *gin.Context) {
+	config, ok := h.publishedCA(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("format") == "der" {
+		block, _ := pem.Decode([]byte(config.CACert))
+		if block == nil {
+			c.String(http.StatusInternalServerError, "Internal error")
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename=ca.der")
+		c.Data(http.StatusOK, "application/pkix-cert", block.Bytes)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=ca.crt")
+	c.Data(http.StatusOK, "application/x-x509-ca-cert", []byte(config.CACert))
+}
+
+// PublicInstructions serves OS-specific steps for trusting the CA and installing a client certificate.
+// GET /pki/instructions
+func (h *MTLSHandler) PublicInstructions(c *gin.Context) {
+	config, ok := h.publishedCA(c)
+	if !ok {
+		return
+	}
+
+	fingerprint := ""
+	if block, _ := pem.Decode([]byte(config.CACert)); block != nil {
+		sum := sha256.Sum256(block.Bytes)
+		pairs := make([]string, len(sum))
+		for i, b := range sum {
+			pairs[i] = fmt.Sprintf("%02X", b)
+		}
+		fingerprint = strings.Join(pairs, ":")
+	}
+
+	data := gin.H{
+		"Subject":     config.CASubject,
+		"Expiry":      config.CAExpiry,
+		"Fingerprint": fingerprint,
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := pkiInstructionsTemplate.Execute(c.Writer, data); err != nil {
+		log.Printf("Error rendering PKI instructions: %v", err)
+	}
+}
+
+var pkiInstructionsTemplate = template.Must(template.New("pki").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Certificate installation</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+code, pre { background: #f4f4f5; padding: 0.1rem 0.3rem; border-radius: 4px; }
+pre { padding: 0.75rem; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Certificate installation</h1>
+<p>Services on this network require a client certificate issued by the CA below.
+Install the CA certificate so your device trusts it, then import the <code>.p12</code>
+client certificate your administrator gave you.</p>
+
+<p><a href="ca.crt">Download CA certificate (PEM)</a> &middot; <a href="ca.crt?format=der">DER</a></p>
+<ul>
+<li>Subject: <code>{{.Subject}}</code></li>
+{{if .Expiry}}<li>Expires: {{.Expiry.Format "2006-01-02"}}</li>{{end}}
+<li>SHA-256 fingerprint: <code>{{.Fingerprint}}</code></li>
+</ul>
+<p>Check that the fingerprint matches what your administrator published before trusting the certificate.</p>
+
+<h2>Windows</h2>
+<ol>
+<li>Open <code>ca.crt</code>, choose <em>Install Certificate</em>, select <em>Local Machine</em>.</li>
+<li>Place it in <em>Trusted Root Certification Authorities</em>.</li>
+<li>Double-click your <code>.p12</code> file and follow the import wizard (store: <em>Personal</em>).</li>
+</ol>
+<pre>certutil -addstore -f Root ca.crt</pre>
+
+<h2>macOS</h2>
+<ol>
+<li>Open <code>ca.crt</code> in Keychain Access, add it to the <em>System</em> keychain.</li>
+<li>Open the certificate, expand <em>Trust</em> and set <em>When using this certificate</em> to <em>Always Trust</em>.</li>
+<li>Double-click your <code>.p12</code> file to import it into the <em>login</em> keychain.</li>
+</ol>
+<pre>sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain ca.crt</pre>
+
+<h2>Linux</h2>
+<p>Debian / Ubuntu:</p>
+<pre>sudo cp ca.crt /usr/local/share/ca-certificates/middleware-manager-ca.crt
+sudo update-ca-certificates</pre>
+<p>Fedora / RHEL:</p>
+<pre>sudo cp ca.crt /etc/pki/ca-trust/source/anchors/middleware-manager-ca.crt
+sudo update-ca-trust</pre>
+<p>Browsers keep their own client certificate store: import the <code>.p12</code> under
+<em>Settings &rarr; Privacy and security &rarr; Certificates</em>.</p>
+
+<h2>iOS / iPadOS</h2>
+<ol>
+<li>Open this page in Safari and download <code>ca.crt</code>; install the profile under <em>Settings &rarr; Profile Downloaded</em>.</li>
+<li>Enable full trust under <em>Settings &rarr; General &rarr; About &rarr; Certificate Trust Settings</em>.</li>
+<li>Install your <code>.p12</code> the same way (ask for a legacy-encrypted file if iOS rejects the password).</li>
+</ol>
+
+<h2>Android</h2>
+<ol>
+<li>Download <code>ca.crt</code>, then go to <em>Settings &rarr; Security &rarr; Encryption &amp; credentials &rarr; Install a certificate &rarr; CA certificate</em>.</li>
+<li>Install your <code>.p12</code> under <em>VPN &amp; app user certificate</em>.</li>
+</ol>
+
+<h2>Firefox (all platforms)</h2>
+<p>Firefox uses its own store: <em>Settings &rarr; Privacy &amp; Security &rarr; View Certificates</em>.
+Import <code>ca.crt</code> under <em>Authorities</em> and your <code>.p12</code> under <em>Your Certificates</em>.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestMTLSHandler_PublicPKI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMTLSHandler(db.DB)
+
+	get := func(handle func(*gin.Context), path string) (int, string) {
+		c, rec := testutil.NewContext(t, http.MethodGet, path, nil)
+		handle(c)
+		return rec.Code, rec.Body.String()
+	}
+	setPublic := func(enabled string) int {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/mtls/public-pki",
+			bytes.NewBufferString(`{"enabled":`+enabled+`}`))
+		handler.UpdatePublicPKI(c)
+		return rec.Code
+	}
+
+	// Enabling without a CA is rejected
+	if code := setPublic("true"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 enabling without CA, got %d", code)
+	}
+
+	if _, err := handler.CertGenerator.GenerateCA(models.CreateCARequest{CommonName: "Public CA"}, t.TempDir()); err != nil {
+		t.Fatalf("GenerateCA() error = %v", err)
+	}
+
+	// Disabled by default: endpoints look like unknown paths
+	if code, _ := get(handler.PublicCACert, "/pki/ca.crt"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 while disabled, got %d", code)
+	}
+
+	if code := setPublic("true"); code != http.StatusOK {
+		t.Fatalf("expected 200 enabling public PKI, got %d", code)
+	}
+
+	code, body := get(handler.PublicCACert, "/pki/ca.crt")
+	if code != http.StatusOK || !strings.Contains(body, "BEGIN CERTIFICATE") {
+		t.Fatalf("expected CA PEM, got %d: %s", code, body)
+	}
+	code, body = get(handler.PublicInstructions, "/pki/instructions")
+	if code != http.StatusOK || !strings.Contains(body, "CN=Public CA") || !strings.Contains(body, "update-ca-certificates") {
+		t.Fatalf("expected instructions page, got %d: %s", code, body)
+	}
+	if strings.Contains(body, "PRIVATE KEY") {
+		t.Fatal("instructions must not include key material")
+	}
+
+	if code := setPublic("false"); code != http.StatusOK {
+		t.Fatalf("expected 200 disabling public PKI, got %d", code)
+	}
+	if code, _ := get(handler.PublicInstructions, "/pki/instructions"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 after disabling, got %d", code)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -15,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/hhftechnology/middleware-manager/models"
 	"github.com/hhftechnology/middleware-manager/services"
+	"github.com/hhftechnology/middleware-manager/util"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,6 +24,7 @@ type PluginHandler struct {
 	TraefikStaticConfigPath string
 	ConfigManager           *services.ConfigManager
 	pluginFetcher           *services.PluginFetcher
+	sandbox                 *services.PluginSandbox
 }
 
 // NewPluginHandler creates a new plugin handler
@@ -32,6 +33,7 @@ func NewPluginHandler(db *sql.DB, traefikStaticConfigPath string, configManager
 		DB:                      db,
 		TraefikStaticConfigPath: traefikStaticConfigPath,
 		ConfigManager:           configManager,
+		sandbox:                 services.NewPluginSandbox(),
 	}
 
 	// Initialize plugin fetcher with data source config
@@ -207,7 +209,7 @@ func (h *PluginHandler) getLocalInstalledPlugins() (map[string]map[string]interf
 
 	cleanPath := filepath.Clean(h.TraefikStaticConfigPath)
 
-	config, err := h.readTraefikStaticConfig(cleanPath)
+	config, err := readTraefikStaticConfig(cleanPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return make(map[string]map[string]interface{}), nil
@@ -238,19 +240,18 @@ type InstallPluginBody struct {
 // InstallPlugin adds a plugin to the Traefik static configuration
 func (h *PluginHandler) InstallPlugin(c *gin.Context) {
 	var body InstallPluginBody
-	if err := c.ShouldBindJSON(&body); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+	if !bindRequest(c, &body) {
 		return
 	}
 
 	if h.TraefikStaticConfigPath == "" {
-		ResponseWithError(c, http.StatusInternalServerError, "Traefik static configuration file path is not configured. Please set it in settings.")
+		ResponseWithAPIError(c, errStaticConfigPathNotSet)
 		return
 	}
 
 	cleanPath := filepath.Clean(h.TraefikStaticConfigPath)
 
-	traefikStaticConfig, err := h.readTraefikStaticConfig(cleanPath)
+	traefikStaticConfig, err := readTraefikStaticConfig(cleanPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			traefikStaticConfig = make(map[string]interface{})
@@ -296,7 +297,7 @@ func (h *PluginHandler) InstallPlugin(c *gin.Context) {
 	}
 	pluginsConfig[pluginKey] = pluginEntry
 
-	if err := h.writeTraefikStaticConfig(cleanPath, traefikStaticConfig); err != nil {
+	if err := writeTraefikStaticConfig(cleanPath, traefikStaticConfig); err != nil {
 		LogError("writing traefik static config", err)
 		ResponseWithError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -324,19 +325,18 @@ type RemovePluginBody struct {
 // RemovePlugin removes a plugin from the Traefik static configuration
 func (h *PluginHandler) RemovePlugin(c *gin.Context) {
 	var body RemovePluginBody
-	if err := c.ShouldBindJSON(&body); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+	if !bindRequest(c, &body) {
 		return
 	}
 
 	if h.TraefikStaticConfigPath == "" {
-		ResponseWithError(c, http.StatusInternalServerError, "Traefik static configuration file path is not configured.")
+		ResponseWithAPIError(c, errStaticConfigPathNotSet)
 		return
 	}
 
 	cleanPath := filepath.Clean(h.TraefikStaticConfigPath)
 
-	traefikStaticConfig, err := h.readTraefikStaticConfig(cleanPath)
+	traefikStaticConfig, err := readTraefikStaticConfig(cleanPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			ResponseWithError(c, http.StatusNotFound, fmt.Sprintf("Traefik static configuration file not found at: %s", cleanPath))
@@ -372,7 +372,7 @@ func (h *PluginHandler) RemovePlugin(c *gin.Context) {
 		return
 	}
 
-	if err := h.writeTraefikStaticConfig(cleanPath, traefikStaticConfig); err != nil {
+	if err := writeTraefikStaticConfig(cleanPath, traefikStaticConfig); err != nil {
 		LogError("writing traefik static config after removal", err)
 		ResponseWithError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -411,13 +411,12 @@ type UpdatePathBody struct {
 // UpdateTraefikStaticConfigPath updates the Traefik static config path
 func (h *PluginHandler) UpdateTraefikStaticConfigPath(c *gin.Context) {
 	var body UpdatePathBody
-	if err := c.ShouldBindJSON(&body); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+	if !bindRequest(c, &body) {
 		return
 	}
 
 	cleanPath := filepath.Clean(body.Path)
-	if cleanPath == "" || cleanPath == "." || cleanPath == "/" || strings.HasSuffix(cleanPath, "/") {
+	if cleanPath == "" || cleanPath == "." || util.IsRootPath(cleanPath) {
 		ResponseWithError(c, http.StatusBadRequest, "Invalid configuration path provided.")
 		return
 	}
@@ -436,7 +435,7 @@ func (h *PluginHandler) UpdateTraefikStaticConfigPath(c *gin.Context) {
 func (h *PluginHandler) GetPluginUsage(c *gin.Context) {
 	pluginName := c.Param("name")
 	if pluginName == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Plugin name is required")
+		ResponseWithAPIError(c, missingFieldError("name", "Plugin name is required"))
 		return
 	}
 
@@ -473,8 +472,8 @@ func (h *PluginHandler) GetPluginUsage(c *gin.Context) {
 
 // Helper functions
 
-func (h *PluginHandler) readTraefikStaticConfig(filePath string) (map[string]interface{}, error) {
-	yamlFile, err := os.ReadFile(filePath)
+func readTraefikStaticConfig(filePath string) (map[string]interface{}, error) {
+	yamlFile, err := util.OS.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -486,7 +485,7 @@ func (h *PluginHandler) readTraefikStaticConfig(filePath string) (map[string]int
 	return config, nil
 }
 
-func (h *PluginHandler) writeTraefikStaticConfig(filePath string, config map[string]interface{}) error {
+func writeTraefikStaticConfig(filePath string, config map[string]interface{}) error {
 	updatedYaml, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to prepare updated Traefik configuration: %w", err)
@@ -500,18 +499,11 @@ func (h *PluginHandler) writeTraefikStaticConfig(filePath string, config map[str
 		LogInfo(fmt.Sprintf("Created backup at %s", backupPath))
 	}
 
-	// Write to temp file first
-	tempFile := filePath + ".tmp"
-	if err := os.WriteFile(tempFile, updatedYaml, 0644); err != nil {
-		_ = os.Remove(tempFile)
+	// Replace the file atomically
+	if err := util.OS.WriteFile(filePath, updatedYaml, 0644); err != nil {
 		return fmt.Errorf("failed to write configuration: %w", err)
 	}
 
-	// Atomic rename
-	if err := os.Rename(tempFile, filePath); err != nil {
-		return fmt.Errorf("failed to finalize configuration: %w", err)
-	}
-
 	return nil
 }
 
@@ -534,23 +526,14 @@ func getPluginKey(moduleName string) string {
 }
 
 func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("could not open source file %s: %w", src, err)
-	}
-	defer sourceFile.Close()
-
-	destinationFile, err := os.Create(dst)
+	data, err := util.OS.ReadFile(src)
 	if err != nil {
-		return fmt.Errorf("could not create destination file %s: %w", dst, err)
+		return fmt.Errorf("could not read source file %s: %w", src, err)
 	}
-	defer destinationFile.Close()
-
-	_, err = io.Copy(destinationFile, sourceFile)
-	if err != nil {
-		return fmt.Errorf("could not copy content: %w", err)
+	if err := util.OS.WriteFile(dst, data, 0644); err != nil {
+		return fmt.Errorf("could not write destination file %s: %w", dst, err)
 	}
-	return destinationFile.Sync()
+	return nil
 }
 
 func LogInfo(message string) {
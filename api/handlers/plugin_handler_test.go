@@ -160,8 +160,8 @@ func TestPluginHandler_InstallPlugin_MissingFields(t *testing.T) {
 	c, rec := testutil.NewContext(t, http.MethodPost, "/api/plugins/install", body)
 	handler.InstallPlugin(c)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", rec.Code)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", rec.Code)
 	}
 }
 
@@ -198,3 +198,60 @@ func TestPluginHandler_GetPluginUsage(t *testing.T) {
 		t.Fatalf("unexpected status code %d", rec.Code)
 	}
 }
+
+// TestPluginHandler_LintStaticConfig tests linting the configured static config file
+func TestPluginHandler_LintStaticConfig(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	configPath := filepath.Join(t.TempDir(), "traefik.yml")
+	if err := os.WriteFile(configPath, []byte("api:\n  insecure: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewPluginHandler(db.DB, configPath, nil)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/traefik/static-config/lint", nil)
+	handler.LintStaticConfig(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("LintStaticConfig() status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var report struct {
+		Path   string `json:"path"`
+		Errors int    `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Path != configPath || report.Errors != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	handler.TraefikStaticConfigPath = filepath.Join(t.TempDir(), "missing.yml")
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/traefik/static-config/lint", nil)
+	handler.LintStaticConfig(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing file status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestPluginHandler_LintStaticConfigContent tests linting unsaved editor content
+func TestPluginHandler_LintStaticConfigContent(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewPluginHandler(db.DB, "", nil)
+
+	body := `{"content": "experimental:\n  plugins:\n    demo:\n      moduleName: github.com/example/demo\n"}`
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/traefik/static-config/lint", bytes.NewBufferString(body))
+	handler.LintStaticConfigContent(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("LintStaticConfigContent() status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte(`"plugin-version"`)) {
+		t.Errorf("expected an unpinned plugin finding, got %s", rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/traefik/static-config/lint", bytes.NewBufferString(`{}`))
+	handler.LintStaticConfigContent(c)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("empty body status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+}
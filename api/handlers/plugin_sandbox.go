@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// TestPluginSandbox loads a middleware plugin into Yaegi with the given config
+// and runs a synthetic request through it. Plugin failures are part of the
+// 200 response so the UI can show which stage broke; nothing is written to the
+// Traefik static configuration.
+func (h *PluginHandler) TestPluginSandbox(c *gin.Context) {
+	var req models.PluginSandboxRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	req.ModuleName = strings.TrimSpace(req.ModuleName)
+	if strings.Contains(req.ModuleName, "@") {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			"moduleName must not include a version").
+			WithField("moduleName").
+			WithHint("Pass the version in the version field"))
+		return
+	}
+	if req.Request != nil && req.Request.Method != "" && !isSandboxMethod(req.Request.Method) {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			fmt.Sprintf("unsupported request method %q", req.Request.Method)).
+			WithField("request.method"))
+		return
+	}
+
+	result, err := h.sandbox.Run(c.Request.Context(), req)
+	if err != nil {
+		LogError("running plugin sandbox", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to run the plugin sandbox")
+		return
+	}
+
+	if result.OK {
+		log.Printf("Plugin sandbox passed for %s@%s", result.ModuleName, result.Version)
+	} else {
+		log.Printf("Plugin sandbox failed for %s@%s at stage %s", result.ModuleName, result.Version, result.FailedStage)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func isSandboxMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/database"
+)
+
+// ProtectedHandler manages middleware and service names that must never be
+// overridden or de-duplicated
+type ProtectedHandler struct {
+	DB *sql.DB
+}
+
+// NewProtectedHandler creates a new protected names handler
+func NewProtectedHandler(db *sql.DB) *ProtectedHandler {
+	return &ProtectedHandler{DB: db}
+}
+
+// GetProtectedNames lists protected names, optionally filtered by ?kind=
+func (h *ProtectedHandler) GetProtectedNames(c *gin.Context) {
+	query := "SELECT kind, name, reason, created_at FROM protected_names"
+	var args []interface{}
+	if kind := c.Query("kind"); kind != "" {
+		if !isProtectedKind(kind) {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid kind: %s", kind))
+			return
+		}
+		query += " WHERE kind = ?"
+		args = append(args, kind)
+	}
+	query += " ORDER BY kind, name"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching protected names: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch protected names")
+		return
+	}
+	defer rows.Close()
+
+	entries := []gin.H{}
+	for rows.Next() {
+		var kind, name, reason string
+		var createdAt time.Time
+		if err := rows.Scan(&kind, &name, &reason, &createdAt); err != nil {
+			log.Printf("Error scanning protected name row: %v", err)
+			continue
+		}
+		entries = append(entries, gin.H{
+			"kind":       kind,
+			"name":       name,
+			"reason":     reason,
+			"created_at": createdAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// AddProtectedName marks a middleware or service name as protected.
+// A name without a provider suffix protects it under every provider.
+func (h *ProtectedHandler) AddProtectedName(c *gin.Context) {
+	var input struct {
+		Kind   string `json:"kind" binding:"required"`
+		Name   string `json:"name" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if !isProtectedKind(input.Kind) {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid kind: %s", input.Kind))
+		return
+	}
+	if input.Name == "" {
+		ResponseWithAPIError(c, missingFieldError("name", "Name is required"))
+		return
+	}
+
+	_, err := h.DB.Exec(
+		"INSERT INTO protected_names (kind, name, reason) VALUES (?, ?, ?)",
+		input.Kind, input.Name, input.Reason,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			ResponseWithError(c, http.StatusConflict, fmt.Sprintf("%s %q is already protected", input.Kind, input.Name))
+			return
+		}
+		log.Printf("Error adding protected name: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to add protected name")
+		return
+	}
+
+	log.Printf("Protected %s %s", input.Kind, input.Name)
+	c.JSON(http.StatusCreated, gin.H{
+		"kind":   input.Kind,
+		"name":   input.Name,
+		"reason": input.Reason,
+	})
+}
+
+// RemoveProtectedName removes a name from the protected list
+func (h *ProtectedHandler) RemoveProtectedName(c *gin.Context) {
+	kind := c.Param("kind")
+	name := c.Param("name")
+
+	result, err := h.DB.Exec("DELETE FROM protected_names WHERE kind = ? AND name = ?", kind, name)
+	if err != nil {
+		log.Printf("Error removing protected name: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to remove protected name")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		ResponseWithError(c, http.StatusNotFound, "Protected name not found")
+		return
+	}
+
+	log.Printf("Unprotected %s %s", kind, name)
+	c.JSON(http.StatusOK, gin.H{"message": "Protected name removed successfully"})
+}
+
+func isProtectedKind(kind string) bool {
+	return kind == database.ProtectedKindMiddleware || kind == database.ProtectedKindService
+}
@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
 	"github.com/hhftechnology/middleware-manager/services"
 )
 
@@ -22,19 +30,248 @@ func NewProxyHandler(configProxy *services.ConfigProxy) *ProxyHandler {
 // GetTraefikConfig returns merged Pangolin + MW-manager configuration
 // This endpoint is designed to be used by Traefik's HTTP provider
 // GET /api/traefik-config
+// GET /api/traefik-config/env/:environment
 func (h *ProxyHandler) GetTraefikConfig(c *gin.Context) {
-	config, err := h.ConfigProxy.GetMergedConfig()
+	// Traefik instances select an environment by path or header; neither
+	// serves the default environment
+	environment := c.Param("environment")
+	if environment == "" {
+		environment = c.GetHeader(services.EnvironmentHeader)
+	}
+
+	// Faults injected in chaos mode (PROXY_CHAOS) to rehearse provider failures
+	chaos := h.ConfigProxy.PlanChaos()
+	if chaos != nil {
+		c.Header(services.ChaosHeader, chaos.Describe())
+		select {
+		case <-time.After(chaos.Delay):
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+
+	var config *services.ProxiedTraefikConfig
+	var err error
+	switch {
+	case chaos != nil && chaos.Config != nil && environment == "":
+		config = chaos.Config
+	case environment != "":
+		config, err = h.ConfigProxy.GetEnvironmentConfig(environment)
+	default:
+		config, err = h.ConfigProxy.GetMergedConfig()
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get Traefik configuration",
-			"details": err.Error(),
-		})
+		h.ConfigProxy.RecordProviderPoll(c.ClientIP(), c.Request.UserAgent(), c.FullPath(), err)
+		if errors.Is(err, services.ErrUnknownEnvironment) {
+			ResponseWithAPIError(c, apierrors.New(http.StatusNotFound, apierrors.CodeNotFound, err.Error()).
+				WithHint("Create the environment under /api/environments first"))
+			return
+		}
+		apierrors.HandleAPIError(c, http.StatusInternalServerError, "Failed to get Traefik configuration", err)
 		return
 	}
 
 	// Return the merged configuration
-	// Traefik expects a JSON response with http, tcp, udp, tls sections
-	c.JSON(http.StatusOK, config)
+	// Traefik expects a JSON response with http, tcp, udp, tls sections.
+	// Stream it rather than marshalling the whole document; large configs
+	// would otherwise be held in memory twice.
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+	if chaos != nil {
+		err = chaos.Write(c.Writer, config)
+	} else {
+		err = config.WriteJSON(c.Writer)
+	}
+	if err != nil {
+		log.Printf("Error streaming Traefik configuration: %v", err)
+	} else if environment == "" && (chaos == nil || !chaos.Truncate) {
+		h.ConfigProxy.MarkServed(config)
+	}
+	h.ConfigProxy.RecordProviderPoll(c.ClientIP(), c.Request.UserAgent(), c.FullPath(), err)
+}
+
+// GetProviderHealth reports whether Traefik is fetching the merged config:
+// 200 when a client got it within max_age seconds (default 60), 503 otherwise,
+// so it can back a container health check
+// GET /api/traefik-config/health?max_age=<seconds>
+func (h *ProxyHandler) GetProviderHealth(c *gin.Context) {
+	maxAge, ok := secondsQuery(c, "max_age", services.DefaultProviderPollMaxAge)
+	if !ok {
+		return
+	}
+
+	health := h.ConfigProxy.ProviderHealth(maxAge)
+	status := http.StatusOK
+	if !health.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
+// GetProviderConsumers lists the clients that polled the config endpoint,
+// which path each used and how often, with warnings when none or several
+// polled within window seconds (default 300)
+// GET /api/traefik-config/consumers?window=<seconds>
+func (h *ProxyHandler) GetProviderConsumers(c *gin.Context) {
+	window, ok := secondsQuery(c, "window", services.DefaultConsumerWindow)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.ConfigProxy.ProviderConsumers(window))
+}
+
+// secondsQuery reads a positive number of seconds from the query string,
+// answering 400 when it is malformed
+func secondsQuery(c *gin.Context, name string, fallback time.Duration) (time.Duration, bool) {
+	raw := strings.TrimSpace(c.Query(name))
+	if raw == "" {
+		return fallback, true
+	}
+	seconds, err := strconv.Atoi(raw)
+	if err != nil || seconds <= 0 {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			name+" must be a positive number of seconds").
+			WithField(name).
+			WithHint("Use a few times Traefik's providers.http.pollInterval"))
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// GetConfigChanges returns only the routers and middlewares added, modified
+// or removed since a revision, for external sync tools that mirror the config.
+// A revision that is unknown or too old answers with reset=true and the full set.
+// GET /api/traefik-config/changes?since=<revision>
+func (h *ProxyHandler) GetConfigChanges(c *gin.Context) {
+	var since uint64
+	if raw := strings.TrimSpace(c.Query("since")); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+				"since must be a revision number").
+				WithField("since").
+				WithHint("Use the revision returned by a previous call, or omit it for the full config"))
+			return
+		}
+		since = parsed
+	}
+
+	// Refresh first so the delta reflects the config Traefik would get now
+	if _, err := h.ConfigProxy.GetMergedConfig(); err != nil {
+		apierrors.HandleAPIError(c, http.StatusInternalServerError, "Failed to get Traefik configuration", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.ConfigProxy.ConfigChanges(since))
+}
+
+// GetTransformLog returns what the latest merge changed in the upstream
+// config: items MM added or removed, middlewares attached to routers and
+// fields it rewrote. Recording is off unless PROXY_TRANSFORM_LOG is set.
+// GET /api/traefik-config/transforms
+func (h *ProxyHandler) GetTransformLog(c *gin.Context) {
+	if _, enabled := h.ConfigProxy.TransformLog(); !enabled {
+		ResponseWithAPIError(c, apierrors.New(http.StatusServiceUnavailable, apierrors.CodeNotConfigured,
+			"Transform logging is off").
+			WithHint("Set PROXY_TRANSFORM_LOG=true to record what each merge changes"))
+		return
+	}
+
+	// Refresh first so the log matches the config Traefik would get now
+	if _, err := h.ConfigProxy.GetMergedConfig(); err != nil {
+		apierrors.HandleAPIError(c, http.StatusInternalServerError, "Failed to get Traefik configuration", err)
+		return
+	}
+
+	transforms, _ := h.ConfigProxy.TransformLog()
+	if transforms == nil {
+		ResponseWithError(c, http.StatusNotFound, "No merge has been recorded yet")
+		return
+	}
+	c.JSON(http.StatusOK, transforms)
+}
+
+// GetChaos reports whether chaos mode is on and the faults injected into
+// the config endpoint
+// GET /api/traefik-config/chaos
+func (h *ProxyHandler) GetChaos(c *gin.Context) {
+	c.JSON(http.StatusOK, h.ConfigProxy.ChaosStatus())
+}
+
+// SetChaos injects faults into the config endpoint until they expire or are
+// cleared: a delay, a stale config or a truncated body. Only allowed when
+// PROXY_CHAOS is set.
+// PUT /api/traefik-config/chaos
+func (h *ProxyHandler) SetChaos(c *gin.Context) {
+	var faults services.ChaosFaults
+	if !bindRequest(c, &faults) {
+		return
+	}
+
+	if err := faults.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid faults: %v", err))
+		return
+	}
+
+	set, err := h.ConfigProxy.SetChaosFaults(faults)
+	if errors.Is(err, services.ErrChaosDisabled) {
+		ResponseWithAPIError(c, apierrors.New(http.StatusServiceUnavailable, apierrors.CodeNotConfigured,
+			"Chaos mode is off").
+			WithHint("Set PROXY_CHAOS=true on a test instance to inject faults into the config endpoint"))
+		return
+	} else if err != nil {
+		apierrors.HandleAPIError(c, http.StatusInternalServerError, "Failed to set chaos faults", err)
+		return
+	}
+	c.JSON(http.StatusOK, set)
+}
+
+// ClearChaos stops injecting faults into the config endpoint
+// DELETE /api/traefik-config/chaos
+func (h *ProxyHandler) ClearChaos(c *gin.Context) {
+	h.ConfigProxy.ClearChaosFaults()
+	c.JSON(http.StatusOK, gin.H{"message": "Chaos faults cleared"})
+}
+
+// GetConfigDiff merges the config the proxy would serve now, without serving
+// it, and diffs it against the config Traefik last got. The response lists
+// structured changes and a unified diff; ?format=unified returns the diff
+// as text.
+// GET /api/traefik-config/diff
+func (h *ProxyHandler) GetConfigDiff(c *gin.Context) {
+	h.previewDiff(c, nil)
+}
+
+// DiffConfigSnapshot is GetConfigDiff against a config in the request body,
+// such as a saved response of the config endpoint
+// POST /api/traefik-config/diff
+func (h *ProxyHandler) DiffConfigSnapshot(c *gin.Context) {
+	var snapshot services.ProxiedTraefikConfig
+	if !bindRequest(c, &snapshot) {
+		return
+	}
+	h.previewDiff(c, &snapshot)
+}
+
+func (h *ProxyHandler) previewDiff(c *gin.Context, base *services.ProxiedTraefikConfig) {
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "json")))
+	if format != "json" && format != "unified" {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			"format must be json or unified").
+			WithField("format"))
+		return
+	}
+
+	diff, err := h.ConfigProxy.PreviewConfigDiff(base)
+	if err != nil {
+		apierrors.HandleAPIError(c, http.StatusInternalServerError, "Failed to preview Traefik configuration", err)
+		return
+	}
+	if format == "unified" {
+		c.Data(http.StatusOK, "text/x-diff; charset=utf-8", []byte(diff.Unified))
+		return
+	}
+	c.JSON(http.StatusOK, diff)
 }
 
 // InvalidateCache forces the proxy to fetch fresh configuration
@@ -59,9 +296,26 @@ func (h *ProxyHandler) GetProxyStatus(c *gin.Context) {
 		errorMsg = err.Error()
 	}
 
+	validation := h.ConfigProxy.ValidationStatus()
+	breaker := h.ConfigProxy.PangolinBreakerStatus()
+	if err == nil && (validation.ServingFallback || breaker.State != services.BreakerClosed) {
+		status = "degraded"
+	}
+
 	response := gin.H{
-		"status":  status,
-		"message": "Config proxy is operational",
+		"status":            status,
+		"message":           "Config proxy is operational",
+		"validation":        validation,
+		"traefik":           h.ConfigProxy.TraefikCompatibility(),
+		"pangolin_cache":    h.ConfigProxy.PangolinCacheStats(),
+		"pangolin_breaker":  breaker,
+		"pangolin_pool":     h.ConfigProxy.PangolinPoolStats(),
+		"merge_sections":    h.ConfigProxy.MergeSectionsStatus(),
+		"upstream_removals": h.ConfigProxy.UpstreamRemovalStatus(),
+		"service_overrides": h.ConfigProxy.ServiceOverrideStatus(),
+		"dns_discovery":     h.ConfigProxy.DNSDiscoveryStatus(),
+		"revision":          h.ConfigProxy.ConfigRevision(),
+		"webhook":           h.ConfigProxy.ConfigWebhookStatus(),
 	}
 
 	if errorMsg != "" {
@@ -70,3 +324,74 @@ func (h *ProxyHandler) GetProxyStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// SimulateRoute reports which merged router Traefik would pick for a URL,
+// with its service and middleware chain, by evaluating the router rules
+// inside Middleware Manager. Headers are given as repeated "Name: value"
+// header parameters.
+// GET /api/tools/route?url=https://host/path&method=GET&header=X-Env:%20prod&client_ip=10.0.0.1&entrypoint=websecure
+func (h *ProxyHandler) SimulateRoute(c *gin.Context) {
+	rawURL := strings.TrimSpace(c.Query("url"))
+	if rawURL == "" {
+		ResponseWithAPIError(c, missingFieldError("url", "url is required"))
+		return
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" || (target.Scheme != "http" && target.Scheme != "https") {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			"url must be an absolute http or https URL").
+			WithField("url").
+			WithHint("For example https://app.example.com/path"))
+		return
+	}
+
+	headers := http.Header{}
+	for _, raw := range c.QueryArray("header") {
+		name, value, ok := strings.Cut(raw, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+				"header must be \"Name: value\"").WithField("header"))
+			return
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	simulation, err := h.ConfigProxy.SimulateRoute(services.RouteRequest{
+		URL:        target,
+		Method:     c.Query("method"),
+		Headers:    headers,
+		ClientIP:   c.Query("client_ip"),
+		EntryPoint: c.Query("entrypoint"),
+	})
+	if err != nil {
+		apierrors.HandleAPIError(c, http.StatusInternalServerError, "Failed to get Traefik configuration", err)
+		return
+	}
+	c.JSON(http.StatusOK, simulation)
+}
+
+// GetDeprecations lists stored middlewares and services using types or options
+// the target Traefik version renamed, removed or deprecated
+// GET /api/traefik/deprecations
+func (h *ProxyHandler) GetDeprecations(c *gin.Context) {
+	scan, err := h.ConfigProxy.ScanDeprecations()
+	if err != nil {
+		log.Printf("Error scanning for deprecated Traefik options: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	c.JSON(http.StatusOK, scan)
+}
+
+// FixDeprecations rewrites the fixable findings for the target Traefik
+// version and returns the findings left
+// POST /api/traefik/deprecations/fix
+func (h *ProxyHandler) FixDeprecations(c *gin.Context) {
+	scan, err := h.ConfigProxy.FixDeprecations()
+	if err != nil {
+		log.Printf("Error fixing deprecated Traefik options: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	c.JSON(http.StatusOK, scan)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/hhftechnology/middleware-manager/internal/testutil"
 	"github.com/hhftechnology/middleware-manager/services"
 )
@@ -87,3 +88,106 @@ func TestProxyHandler_GetTraefikConfig(t *testing.T) {
 		t.Fatalf("expected 200 or 500, got %d", rec.Code)
 	}
 }
+
+// TestProxyHandler_GetConfigChanges_InvalidSince rejects non-numeric revisions
+func TestProxyHandler_GetConfigChanges_InvalidSince(t *testing.T) {
+	handler := NewProxyHandler(newTestConfigProxy(t))
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/traefik-config/changes?since=abc", nil)
+	handler.GetConfigChanges(c)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestProxyHandler_GetProviderHealth reports 503 until Traefik gets the config
+func TestProxyHandler_GetProviderHealth(t *testing.T) {
+	handler := NewProxyHandler(newTestConfigProxy(t))
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/v1/traefik-config/health", nil)
+	handler.GetProviderHealth(c)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before any poll, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	handler.ConfigProxy.RecordProviderPoll("172.18.0.5", "Go-http-client/1.1", "/api/v1/traefik-config", nil)
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/v1/traefik-config/health?max_age=30", nil)
+	handler.GetProviderHealth(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after a poll, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var health services.ProviderHealth
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !health.Healthy || health.MaxAgeSeconds != 30 || len(health.Clients) != 1 {
+		t.Errorf("unexpected health: %+v", health)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/v1/traefik-config/health?max_age=soon", nil)
+	handler.GetProviderHealth(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad max_age, got %d", rec.Code)
+	}
+}
+
+// TestProxyHandler_GetProviderConsumers lists pollers and validates window
+func TestProxyHandler_GetProviderConsumers(t *testing.T) {
+	handler := NewProxyHandler(newTestConfigProxy(t))
+	handler.ConfigProxy.RecordProviderPoll("172.18.0.5", "Go-http-client/1.1", "/api/v1/traefik-config", nil)
+	handler.ConfigProxy.RecordProviderPoll("172.18.0.7", "Go-http-client/1.1", "/api/traefik-config", nil)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/traefik-config/consumers", nil)
+	handler.GetProviderConsumers(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var consumers services.ProviderConsumers
+	if err := json.Unmarshal(rec.Body.Bytes(), &consumers); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if consumers.Active != 2 || len(consumers.Warnings) != 1 {
+		t.Errorf("expected two active consumers and a warning, got %+v", consumers)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/traefik-config/consumers?window=-1", nil)
+	handler.GetProviderConsumers(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad window, got %d", rec.Code)
+	}
+}
+
+// TestProxyHandler_SimulateRoute_Validation tests the URL checks of the route simulation
+func TestProxyHandler_SimulateRoute_Validation(t *testing.T) {
+	handler := NewProxyHandler(newTestConfigProxy(t))
+
+	for _, query := range []string{"", "?url=app.example.com/path", "?url=ftp://app.example.com", "?url=https://app.example.com&header=NoColon"} {
+		c, rec := testutil.NewContext(t, http.MethodGet, "/api/tools/route"+query, nil)
+		handler.SimulateRoute(c)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+// TestProxyHandler_GetTraefikConfig_UnknownEnvironment answers 404 for an
+// environment selected by path or header that was not created
+func TestProxyHandler_GetTraefikConfig_UnknownEnvironment(t *testing.T) {
+	handler := NewProxyHandler(newTestConfigProxy(t))
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/traefik-config/env/qa", nil)
+	c.Params = gin.Params{{Key: "environment", Value: "qa"}}
+	handler.GetTraefikConfig(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown environment path, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/traefik-config", nil)
+	c.Request.Header.Set(services.EnvironmentHeader, "qa")
+	handler.GetTraefikConfig(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown environment header, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
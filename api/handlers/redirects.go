@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// RedirectHandler manages HTTP→HTTPS redirects globally, per resource and on
+// Traefik entrypoints
+type RedirectHandler struct {
+	DB                      *sql.DB
+	ConfigProxy             *services.ConfigProxy
+	TraefikStaticConfigPath string
+}
+
+// NewRedirectHandler creates a new redirect handler
+func NewRedirectHandler(db *sql.DB, configProxy *services.ConfigProxy, traefikStaticConfigPath string) *RedirectHandler {
+	return &RedirectHandler{DB: db, ConfigProxy: configProxy, TraefikStaticConfigPath: traefikStaticConfigPath}
+}
+
+// GetRedirects returns the global redirect settings, any entrypoint redirection
+// in the static config and how each resource's redirect was resolved
+func (h *RedirectHandler) GetRedirects(c *gin.Context) {
+	var enabled, permanent int
+	var entryPoint string
+	err := h.DB.QueryRow(
+		"SELECT enabled, COALESCE(entrypoint, 'web'), permanent FROM redirect_config WHERE id = 1",
+	).Scan(&enabled, &entryPoint, &permanent)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error fetching redirect config: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch redirect config")
+		return
+	}
+	if entryPoint == "" {
+		entryPoint = "web"
+	}
+
+	// Merge once so the per-resource states are current
+	if _, err := h.ConfigProxy.GetMergedConfig(); err != nil {
+		log.Printf("Warning: could not refresh merged config for redirect status: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":    enabled == 1,
+		"entrypoint": entryPoint,
+		"permanent":  permanent == 1,
+		"entrypoint_redirect": gin.H{
+			"to": services.EntrypointRedirectTarget(h.TraefikStaticConfigPath, entryPoint),
+		},
+		"resources": h.ConfigProxy.RedirectStatus(),
+	})
+}
+
+// UpdateRedirects updates the global redirect settings inherited by resources
+func (h *RedirectHandler) UpdateRedirects(c *gin.Context) {
+	var input struct {
+		Enabled    bool   `json:"enabled"`
+		EntryPoint string `json:"entrypoint"`
+		Permanent  *bool  `json:"permanent"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if input.EntryPoint == "" {
+		input.EntryPoint = "web"
+	}
+	permanent := true
+	if input.Permanent != nil {
+		permanent = *input.Permanent
+	}
+	enabledVal, permanentVal := 0, 0
+	if input.Enabled {
+		enabledVal = 1
+	}
+	if permanent {
+		permanentVal = 1
+	}
+
+	if _, err := h.DB.Exec(`
+		INSERT INTO redirect_config (id, enabled, entrypoint, permanent, updated_at) VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET enabled = excluded.enabled, entrypoint = excluded.entrypoint,
+			permanent = excluded.permanent, updated_at = excluded.updated_at
+	`, enabledVal, input.EntryPoint, permanentVal, time.Now()); err != nil {
+		log.Printf("Error updating redirect config: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update redirect config")
+		return
+	}
+
+	log.Printf("Set global HTTPS redirect to %v on entrypoint %s", input.Enabled, input.EntryPoint)
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":    input.Enabled,
+		"entrypoint": input.EntryPoint,
+		"permanent":  permanent,
+	})
+}
+
+// UpdateResourceRedirect sets a resource's redirect mode: inherit, enabled or disabled
+func (h *RedirectHandler) UpdateResourceRedirect(c *gin.Context) {
+	id := c.Param("id")
+	var input struct {
+		Mode string `json:"mode" binding:"required"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if !services.IsValidHTTPSRedirectMode(input.Mode) {
+		ResponseWithError(c, http.StatusBadRequest, "mode must be one of inherit, enabled or disabled")
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE resources SET https_redirect = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		input.Mode, time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating HTTPS redirect: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update HTTPS redirect")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Set HTTPS redirect for resource %s to %s", id, input.Mode)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"id":             id,
+		"https_redirect": input.Mode,
+	})
+}
+
+// UpdateEntrypointRedirect adds or removes an entrypoint-level redirection in
+// the Traefik static config. Traefik must be restarted to apply it.
+func (h *RedirectHandler) UpdateEntrypointRedirect(c *gin.Context) {
+	var input struct {
+		Enabled    bool   `json:"enabled"`
+		EntryPoint string `json:"entrypoint"`
+		To         string `json:"to"`
+		Permanent  *bool  `json:"permanent"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if input.EntryPoint == "" {
+		input.EntryPoint = "web"
+	}
+	if input.To == "" {
+		input.To = "websecure"
+	}
+	if input.EntryPoint == input.To {
+		ResponseWithError(c, http.StatusBadRequest, "An entrypoint cannot redirect to itself")
+		return
+	}
+
+	if h.TraefikStaticConfigPath == "" {
+		ResponseWithAPIError(c, errStaticConfigPathNotSet)
+		return
+	}
+	cleanPath := filepath.Clean(h.TraefikStaticConfigPath)
+
+	staticConfig, err := readTraefikStaticConfig(cleanPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			ResponseWithError(c, http.StatusNotFound, "Traefik static configuration file not found")
+			return
+		}
+		log.Printf("Error reading Traefik static config %s: %v", cleanPath, err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to read Traefik static configuration file.")
+		return
+	}
+
+	entryPoints, _ := staticConfig["entryPoints"].(map[string]interface{})
+	entryPoint, _ := entryPoints[input.EntryPoint].(map[string]interface{})
+	if entryPoint == nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Entrypoint %s is not defined in the Traefik static configuration", input.EntryPoint))
+		return
+	}
+	if _, ok := entryPoints[input.To]; !ok {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Entrypoint %s is not defined in the Traefik static configuration", input.To))
+		return
+	}
+
+	httpSection, _ := entryPoint["http"].(map[string]interface{})
+	if input.Enabled {
+		if httpSection == nil {
+			httpSection = make(map[string]interface{})
+			entryPoint["http"] = httpSection
+		}
+		redirect := map[string]interface{}{"to": input.To, "scheme": "https"}
+		if input.Permanent != nil {
+			redirect["permanent"] = *input.Permanent
+		}
+		httpSection["redirections"] = map[string]interface{}{"entryPoint": redirect}
+	} else if httpSection != nil {
+		delete(httpSection, "redirections")
+		if len(httpSection) == 0 {
+			delete(entryPoint, "http")
+		}
+	}
+
+	if err := writeTraefikStaticConfig(cleanPath, staticConfig); err != nil {
+		log.Printf("Error writing Traefik static config %s: %v", cleanPath, err)
+		ResponseWithError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("Set entrypoint redirection %s -> %s to %v in %s", input.EntryPoint, input.To, input.Enabled, cleanPath)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Entrypoint redirection updated. A Traefik restart is required to apply it.",
+		"entrypoint": input.EntryPoint,
+		"to":         input.To,
+		"enabled":    input.Enabled,
+	})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// ReportHandler produces reports for security and access reviews
+type ReportHandler struct {
+	DB *sql.DB
+	// staticConfigPath returns the current Traefik static config path
+	staticConfigPath func() string
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(db *sql.DB, staticConfigPath func() string) *ReportHandler {
+	return &ReportHandler{DB: db, staticConfigPath: staticConfigPath}
+}
+
+// GetAccessMatrix returns every exposed host with its auth mechanism, IP
+// restrictions and TLS posture, as JSON or as a CSV download
+// GET /api/reports/access-matrix?format=json|csv
+func (h *ReportHandler) GetAccessMatrix(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			"format must be json or csv").WithField("format"))
+		return
+	}
+
+	entries, err := services.BuildAccessMatrix(h.DB)
+	if err != nil {
+		log.Printf("Error building access matrix: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"generated_at": time.Now().UTC(),
+			"hosts":        entries,
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("access-matrix-%s.csv", time.Now().UTC().Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	if err := services.WriteAccessMatrixCSV(c.Writer, entries); err != nil {
+		log.Printf("Error writing access matrix CSV: %v", err)
+	}
+}
+
+// GetInventory lists the plugins of the Traefik static config with their
+// versions and every middleware type in use with counts, flagging types
+// Traefik deprecated
+// GET /api/reports/inventory
+func (h *ReportHandler) GetInventory(c *gin.Context) {
+	var staticConfig []byte
+	staticConfigError := ""
+	path := ""
+	if h.staticConfigPath != nil {
+		path = h.staticConfigPath()
+	}
+	if path == "" {
+		staticConfigError = "Traefik static configuration path is not set"
+	} else {
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			LogError(fmt.Sprintf("reading traefik static config file %s for inventory", path), err)
+			staticConfigError = "Failed to read Traefik static configuration file"
+		}
+		staticConfig = data
+	}
+
+	inventory, err := services.BuildInventory(h.DB, staticConfig)
+	if err != nil {
+		log.Printf("Error building inventory: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if staticConfigError != "" {
+		inventory.StaticConfigError = staticConfigError
+	}
+
+	response := gin.H{
+		"generated_at":       time.Now().UTC(),
+		"static_config_path": path,
+		"plugins":            inventory.Plugins,
+		"middleware_types":   inventory.MiddlewareTypes,
+	}
+	if inventory.StaticConfigError != "" {
+		response["static_config_error"] = inventory.StaticConfigError
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+// TestReportHandler_GetAccessMatrix tests the JSON and CSV access matrix
+func TestReportHandler_GetAccessMatrix(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewReportHandler(db.DB, nil)
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status, entrypoints)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active', 'websecure')`)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/reports/access-matrix", nil)
+	handler.GetAccessMatrix(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Hosts []struct {
+			Host string   `json:"host"`
+			Auth []string `json:"auth"`
+		} `json:"hosts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(response.Hosts) != 1 || response.Hosts[0].Host != "app.example.com" || response.Hosts[0].Auth[0] != "none" {
+		t.Errorf("unexpected hosts %+v", response.Hosts)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/reports/access-matrix?format=csv", nil)
+	handler.GetAccessMatrix(c)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "access-matrix-") ||
+		!strings.Contains(rec.Body.String(), "app.example.com,res-1,websecure,none") {
+		t.Errorf("unexpected csv %q", rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/reports/access-matrix?format=xml", nil)
+	handler.GetAccessMatrix(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format status = %d, want 400", rec.Code)
+	}
+}
+
+// TestReportHandler_GetInventory tests the inventory with and without a
+// readable static config
+func TestReportHandler_GetInventory(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	staticPath := filepath.Join(t.TempDir(), "traefik.yml")
+	if err := os.WriteFile(staticPath, []byte("experimental:\n  plugins:\n    geoblock:\n      moduleName: github.com/PascalMinder/geoblock\n      version: v0.3.2\n"), 0644); err != nil {
+		t.Fatalf("write static config: %v", err)
+	}
+	path := staticPath
+	handler := NewReportHandler(db.DB, func() string { return path })
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/reports/inventory", nil)
+	handler.GetInventory(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Plugins []struct {
+			Key     string `json:"key"`
+			Version string `json:"version"`
+		} `json:"plugins"`
+		StaticConfigError string `json:"static_config_error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(response.Plugins) != 1 || response.Plugins[0].Key != "geoblock" || response.Plugins[0].Version != "v0.3.2" {
+		t.Errorf("unexpected plugins %+v", response.Plugins)
+	}
+	if response.StaticConfigError != "" {
+		t.Errorf("unexpected static config error %q", response.StaticConfigError)
+	}
+
+	path = filepath.Join(t.TempDir(), "missing.yml")
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/reports/inventory", nil)
+	handler.GetInventory(c)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "static_config_error") {
+		t.Errorf("missing static config: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
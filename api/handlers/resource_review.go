@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// ResourceReviewHandler serves the queue of resource changes the watcher
+// holds back in review mode
+type ResourceReviewHandler struct {
+	Review        *services.ResourceReview
+	ConfigManager *services.ConfigManager
+}
+
+// NewResourceReviewHandler creates a new resource review handler
+func NewResourceReviewHandler(review *services.ResourceReview, configManager *services.ConfigManager) *ResourceReviewHandler {
+	return &ResourceReviewHandler{Review: review, ConfigManager: configManager}
+}
+
+// GetPendingChanges lists queued creations, disables and service changes
+// with the review settings
+func (h *ResourceReviewHandler) GetPendingChanges(c *gin.Context) {
+	changes, err := h.Review.Pending()
+	if err != nil {
+		log.Printf("Error fetching pending changes: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch pending changes")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"review":  h.ConfigManager.GetResourceReviewConfig(),
+		"changes": changes,
+	})
+}
+
+// ApprovePendingChanges applies the queued changes listed in "ids", or all
+// of them when the body is empty
+func (h *ResourceReviewHandler) ApprovePendingChanges(c *gin.Context) {
+	input := struct {
+		IDs []string `json:"ids"`
+	}{}
+	if c.Request.ContentLength > 0 {
+		if !bindRequest(c, &input) {
+			return
+		}
+	}
+
+	applied, err := h.Review.Approve(input.IDs)
+	if err != nil {
+		log.Printf("Error approving pending changes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"applied": applied, "error": err.Error()})
+		return
+	}
+	log.Printf("Approved %d pending resource changes", applied)
+	c.JSON(http.StatusOK, gin.H{"applied": applied})
+}
+
+// UpdateReviewConfig turns review mode on or off and sets the auto-approve delay
+func (h *ResourceReviewHandler) UpdateReviewConfig(c *gin.Context) {
+	var config models.ResourceReviewConfig
+	if !bindRequest(c, &config) {
+		return
+	}
+	if err := h.ConfigManager.SetResourceReviewConfig(config); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestResourceReviewHandler_ApprovePendingChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	handler := NewResourceReviewHandler(services.NewResourceReview(db, cm), cm)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+	testutil.MustExec(t, db, `INSERT INTO pending_changes (id, change_key, kind, resource_id, host)
+		VALUES ('chg-1', 'disable:res-1', 'disable', 'res-1', 'app.example.com')`)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/resources/pending", nil)
+	handler.GetPendingChanges(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var listed struct {
+		Changes []services.PendingChange `json:"changes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(listed.Changes) != 1 || listed.Changes[0].Kind != services.PendingDisable {
+		t.Fatalf("expected one pending disable, got %+v", listed.Changes)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resources/pending/approve", nil)
+	handler.ApprovePendingChanges(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result struct {
+		Applied int `json:"applied"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if result.Applied != 1 {
+		t.Errorf("expected 1 applied change, got %d", result.Applied)
+	}
+
+	var status string
+	if err := db.QueryRow("SELECT status FROM resources WHERE id = 'res-1'").Scan(&status); err != nil {
+		t.Fatalf("failed to query resource: %v", err)
+	}
+	if status != "disabled" {
+		t.Errorf("expected resource disabled after approval, got %q", status)
+	}
+}
+
+func TestResourceReviewHandler_UpdateReviewConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	handler := NewResourceReviewHandler(services.NewResourceReview(db, cm), cm)
+
+	tests := []struct {
+		body       string
+		wantStatus int
+	}{
+		{`{"enabled": true, "auto_approve_minutes": 15}`, http.StatusOK},
+		{`{"enabled": true, "auto_approve_minutes": -1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/review", bytes.NewBufferString(tt.body))
+		handler.UpdateReviewConfig(c)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: expected %d, got %d: %s", tt.body, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+	}
+
+	if cfg := cm.GetResourceReviewConfig(); !cfg.Enabled || cfg.AutoApproveMinutes != 15 {
+		t.Errorf("expected review enabled with 15 minute auto-approve, got %+v", cfg)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// ResourceSyncHandler runs the resource watcher on demand
+type ResourceSyncHandler struct {
+	Watcher *services.ResourceWatcher
+}
+
+// NewResourceSyncHandler creates a new resource sync handler. A nil watcher
+// answers every request with 503.
+func NewResourceSyncHandler(watcher *services.ResourceWatcher) *ResourceSyncHandler {
+	return &ResourceSyncHandler{Watcher: watcher}
+}
+
+// available reports whether a watcher is attached, responding with 503 when not
+func (h *ResourceSyncHandler) available(c *gin.Context) bool {
+	if h.Watcher == nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusServiceUnavailable, apierrors.CodeNotConfigured,
+			"Resource watcher is not running"))
+		return false
+	}
+	return true
+}
+
+// SyncResources runs a full resource check now instead of waiting for the
+// next poll and returns the run summary with the changes it made
+func (h *ResourceSyncHandler) SyncResources(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	run, err := h.Watcher.Sync()
+	if err != nil {
+		log.Printf("Manual resource sync failed: %v", err)
+		ResponseWithError(c, http.StatusBadGateway, "Resource sync failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// ResyncResource refreshes one resource from the data source and returns
+// the run summary with the changes made to it
+func (h *ResourceSyncHandler) ResyncResource(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+	if !h.available(c) {
+		return
+	}
+
+	run, err := h.Watcher.ResyncResource(id)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Resync of resource %s failed: %v", id, err)
+		ResponseWithError(c, http.StatusBadGateway, "Resource resync failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
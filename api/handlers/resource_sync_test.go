@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestResourceSyncHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/resources/sync", nil)
+	NewResourceSyncHandler(nil).SyncResources(c)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a watcher, got %d", rec.Code)
+	}
+
+	pangolin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cfg models.PangolinTraefikConfig
+		cfg.HTTP.Routers = map[string]models.PangolinRouter{
+			"app-router": {Rule: "Host(`app.example.com`)", Service: "app-service"},
+		}
+		json.NewEncoder(w).Encode(cfg)
+	}))
+	defer pangolin.Close()
+
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	if err := cm.UpdateDataSource("pangolin", models.DataSourceConfig{Type: models.PangolinAPI, URL: pangolin.URL}); err != nil {
+		t.Fatalf("failed to update data source: %v", err)
+	}
+	if err := cm.SetActiveDataSource("pangolin"); err != nil {
+		t.Fatalf("failed to set active data source: %v", err)
+	}
+	watcher, err := services.NewResourceWatcher(db, cm)
+	if err != nil {
+		t.Fatalf("NewResourceWatcher() error = %v", err)
+	}
+	handler := NewResourceSyncHandler(watcher)
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resources/sync", nil)
+	handler.SyncResources(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Changes []services.ResourceChange `json:"changes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Changes) != 1 || resp.Changes[0].Action != services.ResourceCreated || resp.Changes[0].Host != "app.example.com" {
+		t.Fatalf("unexpected changes %+v", resp.Changes)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resources/"+resp.Changes[0].ResourceID+"/resync", nil)
+	c.Params = gin.Params{{Key: "id", Value: resp.Changes[0].ResourceID}}
+	handler.ResyncResource(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for resync, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/resources/missing/resync", nil)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+	handler.ResyncResource(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing resource, got %d", rec.Code)
+	}
+}
@@ -2,17 +2,25 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
 )
 
 // ResourceHandler handles resource-related requests
 type ResourceHandler struct {
 	DB *sql.DB
+	// ConfigProxy supplies the router chains of GetResource (nil leaves them out)
+	ConfigProxy *services.ConfigProxy
+	// Webhooks is told about assigned and removed middlewares (nil drops them)
+	Webhooks *services.WebhookService
 }
 
 // NewResourceHandler creates a new resource handler
@@ -20,6 +28,17 @@ func NewResourceHandler(db *sql.DB) *ResourceHandler {
 	return &ResourceHandler{DB: db}
 }
 
+// SetConfigProxy lets GetResource report the middlewares Pangolin attached to
+// the resource's router next to the merged chain
+func (h *ResourceHandler) SetConfigProxy(cp *services.ConfigProxy) {
+	h.ConfigProxy = cp
+}
+
+// SetWebhooks sets the service told about assigned and removed middlewares
+func (h *ResourceHandler) SetWebhooks(webhooks *services.WebhookService) {
+	h.Webhooks = webhooks
+}
+
 // GetResources returns all resources and their assigned middlewares
 // Supports pagination via ?page=N&page_size=M query parameters
 // Supports filtering by source_type via ?source_type=pangolin|traefik
@@ -70,7 +89,9 @@ func (h *ResourceHandler) GetResources(c *gin.Context) {
 		       r.custom_headers, r.mtls_enabled, r.router_priority, r.source_type,
 		       r.mtls_rules, r.mtls_request_headers, r.mtls_reject_message, r.mtls_reject_code,
 		       r.mtls_refresh_interval, r.mtls_external_data,
-		       COALESCE(r.tls_hardening_enabled, 0), COALESCE(r.secure_headers_enabled, 0),
+		       COALESCE(r.tls_hardening_enabled, 0), COALESCE(r.secure_headers_enabled, 0), r.version,
+		       r.notes, r.owner, r.contact, r.pinned, COALESCE(r.default_chain_excluded, 0),
+		       (SELECT COUNT(*) FROM resources o WHERE o.host = r.host AND o.id != r.id AND o.status = 'active'),
 		       GROUP_CONCAT(m.id || ':' || m.name || ':' || rm.priority, ',') as middlewares
 		FROM resources r
 		LEFT JOIN resource_middlewares rm ON r.id = rm.resource_id
@@ -108,13 +129,17 @@ func (h *ResourceHandler) GetResources(c *gin.Context) {
 		var middlewares sql.NullString
 		var mtlsRules, mtlsRequestHeaders, mtlsRejectMessage, mtlsRefreshInterval, mtlsExternalData sql.NullString
 		var mtlsRejectCode sql.NullInt64
+		var version int64
+		var notes, owner, contact string
+		var pinned, defaultChainExcluded, sharedHost int
 
 		if err := rows.Scan(&id, &pangolinRouterID, &host, &serviceID, &orgID, &siteID, &status,
 			&entrypoints, &tlsDomains, &tcpEnabled, &tcpEntrypoints, &tcpSNIRule,
 			&customHeaders, &mtlsEnabled, &routerPriority, &sourceType,
 			&mtlsRules, &mtlsRequestHeaders, &mtlsRejectMessage, &mtlsRejectCode,
 			&mtlsRefreshInterval, &mtlsExternalData,
-			&tlsHardeningEnabled, &secureHeadersEnabled,
+			&tlsHardeningEnabled, &secureHeadersEnabled, &version,
+			&notes, &owner, &contact, &pinned, &defaultChainExcluded, &sharedHost,
 			&middlewares); err != nil {
 			log.Printf("Error scanning resource row: %v", err)
 			continue
@@ -144,6 +169,13 @@ func (h *ResourceHandler) GetResources(c *gin.Context) {
 			"source_type":            sourceType,
 			"tls_hardening_enabled":  tlsHardeningEnabled > 0,
 			"secure_headers_enabled": secureHeadersEnabled > 0,
+			"version":                version,
+			"notes":                  notes,
+			"owner":                  owner,
+			"contact":                contact,
+			"pinned":                 pinned > 0,
+			"default_chain_excluded": defaultChainExcluded > 0,
+			"shared_host":            sharedHost > 0,
 		}
 
 		if mtlsRules.Valid {
@@ -220,26 +252,78 @@ func (h *ResourceHandler) GetResources(c *gin.Context) {
 func (h *ResourceHandler) GetResource(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+
+	resource, err := loadResource(h.DB, id)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, fmt.Sprintf("Resource not found: %s", id))
+		return
+	} else if err != nil {
+		log.Printf("Error fetching resource: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch resource")
+		return
+	}
+
+	if h.ConfigProxy != nil {
+		h.addRouterChain(resource)
+	}
+
+	c.Header("ETag", versionETag(resource["version"].(int64)))
+	c.JSON(http.StatusOK, resource)
+}
+
+// addRouterChain adds the upstream and effective middlewares of the
+// resource's router as router_chain. Without a reachable upstream the
+// resource is still served, with the reason as router_chain_error.
+func (h *ResourceHandler) addRouterChain(resource map[string]interface{}) {
+	routerID, _ := resource["pangolin_router_id"].(string)
+	host, _ := resource["host"].(string)
+	chain, err := h.ConfigProxy.ResourceRouterChain(routerID, host)
+	if err != nil {
+		resource["router_chain_error"] = err.Error()
 		return
 	}
+	if chain != nil {
+		resource["router_chain"] = chain
+	}
+}
 
+// loadResource returns a resource with its assigned middlewares as served by GetResource
+func loadResource(db *sql.DB, id string) (map[string]interface{}, error) {
 	var pangolinRouterID, host, serviceID, orgID, siteID, status, entrypoints, tlsDomains, tcpEntrypoints, tcpSNIRule, customHeaders, sourceType string
-	var tcpEnabled int
+	var corsPolicyID, httpsRedirect string
+	var tcpEnabled, forwardAuthEnabled int
 	var mtlsEnabled int
-	var tlsHardeningEnabled, secureHeadersEnabled int
+	var tlsHardeningEnabled, tlsHardeningOptOut, secureHeadersEnabled, secureHeadersReportOnly int
 	var routerPriority sql.NullInt64
 	var middlewares sql.NullString
 	var mtlsRules, mtlsRequestHeaders, mtlsRejectMessage, mtlsRefreshInterval, mtlsExternalData sql.NullString
 	var mtlsRejectCode sql.NullInt64
-
-	err := h.DB.QueryRow(`
+	var mtlsExemptPaths, tlsHardeningProfile, secureHeadersPreset string
+	var version int64
+	var notes, owner, contact string
+	var pinned, defaultChainExcluded, sharedHost int
+	var pinDivergence string
+	var middlewareOrder, middlewarePositions, upstreamRemovals string
+
+	err := db.QueryRow(`
         SELECT COALESCE(r.pangolin_router_id, r.id), r.host, r.service_id, r.org_id, r.site_id, r.status,
                r.entrypoints, r.tls_domains, r.tcp_enabled, r.tcp_entrypoints, r.tcp_sni_rule,
                r.custom_headers, r.mtls_enabled, r.router_priority, r.source_type,
                r.mtls_rules, r.mtls_request_headers, r.mtls_reject_message, r.mtls_reject_code,
-               r.mtls_refresh_interval, r.mtls_external_data,
-               COALESCE(r.tls_hardening_enabled, 0), COALESCE(r.secure_headers_enabled, 0),
+               r.mtls_refresh_interval, r.mtls_external_data, COALESCE(r.mtls_exempt_paths, '[]'),
+               COALESCE(r.tls_hardening_enabled, 0), COALESCE(r.tls_hardening_profile, 'hardened'),
+               COALESCE(r.tls_hardening_opt_out, 0),
+               COALESCE(r.secure_headers_enabled, 0), COALESCE(r.secure_headers_preset, ''),
+               COALESCE(r.secure_headers_report_only, 0),
+               COALESCE(r.cors_policy_id, ''), COALESCE(r.forward_auth_enabled, 0),
+               COALESCE(r.https_redirect, 'inherit'), r.version,
+               r.notes, r.owner, r.contact, r.pinned, r.pin_divergence, COALESCE(r.default_chain_excluded, 0),
+               COALESCE(r.middleware_order, 'prepend'), COALESCE(r.middleware_positions, '{}'),
+               COALESCE(r.upstream_middleware_removals, '[]'),
+               (SELECT COUNT(*) FROM resources o WHERE o.host = r.host AND o.id != r.id AND o.status = 'active'),
                GROUP_CONCAT(m.id || ':' || m.name || ':' || rm.priority, ',') as middlewares
         FROM resources r
         LEFT JOIN resource_middlewares rm ON r.id = rm.resource_id
@@ -250,17 +334,16 @@ func (h *ResourceHandler) GetResource(c *gin.Context) {
 		&entrypoints, &tlsDomains, &tcpEnabled, &tcpEntrypoints, &tcpSNIRule,
 		&customHeaders, &mtlsEnabled, &routerPriority, &sourceType,
 		&mtlsRules, &mtlsRequestHeaders, &mtlsRejectMessage, &mtlsRejectCode,
-		&mtlsRefreshInterval, &mtlsExternalData,
-		&tlsHardeningEnabled, &secureHeadersEnabled,
+		&mtlsRefreshInterval, &mtlsExternalData, &mtlsExemptPaths,
+		&tlsHardeningEnabled, &tlsHardeningProfile, &tlsHardeningOptOut, &secureHeadersEnabled, &secureHeadersPreset, &secureHeadersReportOnly,
+		&corsPolicyID, &forwardAuthEnabled,
+		&httpsRedirect, &version,
+		&notes, &owner, &contact, &pinned, &pinDivergence, &defaultChainExcluded,
+		&middlewareOrder, &middlewarePositions, &upstreamRemovals, &sharedHost,
 		&middlewares)
 
-	if err == sql.ErrNoRows {
-		ResponseWithError(c, http.StatusNotFound, fmt.Sprintf("Resource not found: %s", id))
-		return
-	} else if err != nil {
-		log.Printf("Error fetching resource: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch resource")
-		return
+	if err != nil {
+		return nil, err
 	}
 
 	// Use default priority if null
@@ -270,24 +353,38 @@ func (h *ResourceHandler) GetResource(c *gin.Context) {
 	}
 
 	resource := map[string]interface{}{
-		"id":                     id,
-		"pangolin_router_id":     pangolinRouterID,
-		"host":                   host,
-		"service_id":             serviceID,
-		"org_id":                 orgID,
-		"site_id":                siteID,
-		"status":                 status,
-		"entrypoints":            entrypoints,
-		"tls_domains":            tlsDomains,
-		"tcp_enabled":            tcpEnabled > 0,
-		"tcp_entrypoints":        tcpEntrypoints,
-		"tcp_sni_rule":           tcpSNIRule,
-		"custom_headers":         customHeaders,
-		"mtls_enabled":           mtlsEnabled > 0,
-		"router_priority":        priority,
-		"source_type":            sourceType,
-		"tls_hardening_enabled":  tlsHardeningEnabled > 0,
-		"secure_headers_enabled": secureHeadersEnabled > 0,
+		"id":                         id,
+		"pangolin_router_id":         pangolinRouterID,
+		"host":                       host,
+		"service_id":                 serviceID,
+		"org_id":                     orgID,
+		"site_id":                    siteID,
+		"status":                     status,
+		"entrypoints":                entrypoints,
+		"tls_domains":                tlsDomains,
+		"tcp_enabled":                tcpEnabled > 0,
+		"tcp_entrypoints":            tcpEntrypoints,
+		"tcp_sni_rule":               tcpSNIRule,
+		"custom_headers":             customHeaders,
+		"mtls_enabled":               mtlsEnabled > 0,
+		"router_priority":            priority,
+		"source_type":                sourceType,
+		"tls_hardening_enabled":      tlsHardeningEnabled > 0,
+		"tls_hardening_profile":      tlsHardeningProfile,
+		"tls_hardening_opt_out":      tlsHardeningOptOut > 0,
+		"secure_headers_enabled":     secureHeadersEnabled > 0,
+		"secure_headers_preset":      secureHeadersPreset,
+		"secure_headers_report_only": secureHeadersReportOnly > 0,
+		"cors_policy_id":             corsPolicyID,
+		"forward_auth_enabled":       forwardAuthEnabled > 0,
+		"https_redirect":             httpsRedirect,
+		"version":                    version,
+		"notes":                      notes,
+		"owner":                      owner,
+		"contact":                    contact,
+		"pinned":                     pinned > 0,
+		"default_chain_excluded":     defaultChainExcluded > 0,
+		"shared_host":                sharedHost > 0,
 	}
 
 	if mtlsRules.Valid {
@@ -308,6 +405,30 @@ func (h *ResourceHandler) GetResource(c *gin.Context) {
 	if mtlsExternalData.Valid {
 		resource["mtls_external_data"] = mtlsExternalData.String
 	}
+	exemptPaths := []string{}
+	if err := json.Unmarshal([]byte(mtlsExemptPaths), &exemptPaths); err != nil {
+		log.Printf("Warning: invalid mtls_exempt_paths for resource %s: %v", id, err)
+		exemptPaths = []string{}
+	}
+	resource["mtls_exempt_paths"] = exemptPaths
+	divergence := []models.PinDivergence{}
+	if err := json.Unmarshal([]byte(pinDivergence), &divergence); err != nil {
+		log.Printf("Warning: invalid pin_divergence for resource %s: %v", id, err)
+		divergence = []models.PinDivergence{}
+	}
+	resource["pin_divergence"] = divergence
+	order, err := models.ParseMiddlewareOrder(middlewareOrder, middlewarePositions)
+	if err != nil {
+		log.Printf("Warning: invalid middleware order for resource %s: %v", id, err)
+		order = models.MiddlewareOrder{Policy: models.MiddlewareOrderPrepend}
+	}
+	resource["middleware_order"] = order
+	removals := []models.UpstreamMiddlewareRemoval{}
+	if err := json.Unmarshal([]byte(upstreamRemovals), &removals); err != nil {
+		log.Printf("Warning: invalid upstream_middleware_removals for resource %s: %v", id, err)
+		removals = []models.UpstreamMiddlewareRemoval{}
+	}
+	resource["upstream_middleware_removals"] = removals
 
 	if middlewares.Valid {
 		resource["middlewares"] = middlewares.String
@@ -316,7 +437,7 @@ func (h *ResourceHandler) GetResource(c *gin.Context) {
 	}
 
 	// Fetch external (Traefik-native) middlewares assigned to this resource
-	extRows, err := h.DB.Query(
+	extRows, err := db.Query(
 		"SELECT middleware_name, priority, provider FROM resource_external_middlewares WHERE resource_id = ? ORDER BY priority DESC",
 		id,
 	)
@@ -338,14 +459,66 @@ func (h *ResourceHandler) GetResource(c *gin.Context) {
 		resource["external_middlewares"] = strings.Join(extParts, ",")
 	}
 
-	c.JSON(http.StatusOK, resource)
+	return resource, nil
+}
+
+// SetResourcePin pins or unpins a resource. The watcher leaves the host,
+// service, priority and status of a pinned resource alone and only records
+// where the data source disagrees; unpinning clears that record so the next
+// check applies the data source again.
+func (h *ResourceHandler) SetResourcePin(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
+		return
+	}
+
+	var input struct {
+		Pinned *bool `json:"pinned" binding:"required"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+
+	var exists int
+	err := h.DB.QueryRow("SELECT 1 FROM resources WHERE id = ?", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE resources SET pinned = ?, pin_divergence = '[]', updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+		*input.Pinned, time.Now(), id, version,
+	)
+	if err != nil {
+		log.Printf("Error updating resource pin: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update resource pin")
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	log.Printf("Set pinned=%v for resource %s", *input.Pinned, id)
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, gin.H{"id": id, "pinned": *input.Pinned})
 }
 
 // DeleteResource deletes a resource from the database
 func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -357,7 +530,7 @@ func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -371,7 +544,7 @@ func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -393,6 +566,62 @@ func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 		return
 	}
 
+	// Remove group memberships and header policy assignments
+	_, txErr = tx.Exec("DELETE FROM resource_group_members WHERE resource_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource group memberships: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM header_policy_assignments WHERE target_type = 'resource' AND target_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource header policies: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM forward_auth_tokens WHERE resource_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource forward-auth tokens: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM resource_waf WHERE resource_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource WAF settings: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM resource_bot_lists WHERE resource_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource bot lists: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM resource_mirrors WHERE resource_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource mirror: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM resource_failovers WHERE resource_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource failover: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM resource_deployments WHERE resource_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource deployment: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+	_, txErr = tx.Exec("DELETE FROM environment_tags WHERE target_type = 'resource' AND target_id = ?", id)
+	if txErr != nil {
+		log.Printf("Error removing resource environment tags: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resource")
+		return
+	}
+
 	// Then delete the resource
 	log.Printf("Deleting resource %s", id)
 	result, txErr := tx.Exec("DELETE FROM resources WHERE id = ?", id)
@@ -405,7 +634,7 @@ func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Error getting rows affected: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -419,7 +648,7 @@ func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -433,8 +662,11 @@ func (h *ResourceHandler) DeleteDisabledResources(c *gin.Context) {
 		IDs []string `json:"ids" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.IDs) == 0 {
-		ResponseWithError(c, http.StatusBadRequest, "IDs are required")
+	if !bindRequest(c, &payload) {
+		return
+	}
+	if len(payload.IDs) == 0 {
+		ResponseWithAPIError(c, missingFieldError("ids", "IDs are required"))
 		return
 	}
 
@@ -442,7 +674,7 @@ func (h *ResourceHandler) DeleteDisabledResources(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -468,7 +700,7 @@ func (h *ResourceHandler) DeleteDisabledResources(c *gin.Context) {
 	if err != nil {
 		txErr = err
 		log.Printf("Error checking resource statuses: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 	defer rows.Close()
@@ -479,7 +711,7 @@ func (h *ResourceHandler) DeleteDisabledResources(c *gin.Context) {
 		if err := rows.Scan(&rid, &status); err != nil {
 			txErr = err
 			log.Printf("Error scanning resource row: %v", err)
-			ResponseWithError(c, http.StatusInternalServerError, "Database error")
+			ResponseWithAPIError(c, errDatabase)
 			return
 		}
 		if status == "disabled" {
@@ -516,6 +748,13 @@ func (h *ResourceHandler) DeleteDisabledResources(c *gin.Context) {
 		return
 	}
 
+	etQuery := fmt.Sprintf("DELETE FROM environment_tags WHERE target_type = 'resource' AND target_id IN (%s)", dPlaceholders)
+	if _, txErr = tx.Exec(etQuery, dArgs...); txErr != nil {
+		log.Printf("Error deleting environment_tags: %v", txErr)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete resources")
+		return
+	}
+
 	// Delete resources
 	resQuery := fmt.Sprintf("DELETE FROM resources WHERE id IN (%s) AND status = 'disabled'", dPlaceholders)
 	result, txErr := tx.Exec(resQuery, dArgs...)
@@ -529,13 +768,13 @@ func (h *ResourceHandler) DeleteDisabledResources(c *gin.Context) {
 	if err != nil {
 		txErr = err
 		log.Printf("Error getting rows affected: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -549,17 +788,21 @@ func (h *ResourceHandler) DeleteDisabledResources(c *gin.Context) {
 func (h *ResourceHandler) AssignMiddleware(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
 	var input struct {
-		MiddlewareID string `json:"middleware_id" binding:"required"`
-		Priority     int    `json:"priority"`
+		MiddlewareID string     `json:"middleware_id" binding:"required"`
+		Priority     int        `json:"priority"`
+		ExpiresAt    *time.Time `json:"expires_at"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
+		return
+	}
+	expiresAt, ok := assignmentExpiry(c, "expires_at", input.ExpiresAt)
+	if !ok {
 		return
 	}
 
@@ -577,13 +820,13 @@ func (h *ResourceHandler) AssignMiddleware(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow attaching middlewares to disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot assign middleware to a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot assign middleware to a disabled resource"))
 		return
 	}
 
@@ -594,7 +837,7 @@ func (h *ResourceHandler) AssignMiddleware(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking middleware existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -602,7 +845,7 @@ func (h *ResourceHandler) AssignMiddleware(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -624,7 +867,7 @@ func (h *ResourceHandler) AssignMiddleware(c *gin.Context) {
 	)
 	if txErr != nil {
 		log.Printf("Error removing existing relationship: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -632,8 +875,8 @@ func (h *ResourceHandler) AssignMiddleware(c *gin.Context) {
 	log.Printf("Creating new middleware relationship: resource=%s, middleware=%s, priority=%d",
 		resourceID, input.MiddlewareID, input.Priority)
 	result, txErr := tx.Exec(
-		"INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES (?, ?, ?)",
-		resourceID, input.MiddlewareID, input.Priority,
+		"INSERT INTO resource_middlewares (resource_id, middleware_id, priority, expires_at) VALUES (?, ?, ?, ?)",
+		resourceID, input.MiddlewareID, input.Priority, expiresAt,
 	)
 	if txErr != nil {
 		log.Printf("Error assigning middleware: %v", txErr)
@@ -649,38 +892,49 @@ func (h *ResourceHandler) AssignMiddleware(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully assigned middleware %s to resource %s with priority %d",
 		input.MiddlewareID, resourceID, input.Priority)
-	c.JSON(http.StatusOK, gin.H{
+	assignment := gin.H{
 		"resource_id":   resourceID,
 		"middleware_id": input.MiddlewareID,
 		"priority":      input.Priority,
-	})
+		"expires_at":    expiresAt,
+	}
+	h.Webhooks.Emit(models.EventMiddlewareAssigned, assignment)
+	c.JSON(http.StatusOK, assignment)
 }
 
 // AssignMultipleMiddlewares assigns multiple middlewares to a resource in one operation
 func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
 	var input struct {
 		Middlewares []struct {
-			MiddlewareID string `json:"middleware_id" binding:"required"`
-			Priority     int    `json:"priority"`
+			MiddlewareID string     `json:"middleware_id" binding:"required"`
+			Priority     int        `json:"priority"`
+			ExpiresAt    *time.Time `json:"expires_at"`
 		} `json:"middlewares" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
+	expiries := make([]*time.Time, len(input.Middlewares))
+	for i, mw := range input.Middlewares {
+		expiresAt, ok := assignmentExpiry(c, fmt.Sprintf("middlewares[%d].expires_at", i), mw.ExpiresAt)
+		if !ok {
+			return
+		}
+		expiries[i] = expiresAt
+	}
 
 	// Verify resource exists and is active
 	var exists int
@@ -691,13 +945,13 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow attaching middlewares to disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot assign middlewares to a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot assign middlewares to a disabled resource"))
 		return
 	}
 
@@ -705,7 +959,7 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -722,7 +976,7 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 	successful := make([]map[string]interface{}, 0)
 	log.Printf("Assigning %d middlewares to resource %s", len(input.Middlewares), resourceID)
 
-	for _, mw := range input.Middlewares {
+	for i, mw := range input.Middlewares {
 		// Default priority is 200 if not specified
 		if mw.Priority <= 0 {
 			mw.Priority = 200
@@ -737,7 +991,7 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 			continue
 		} else if err != nil {
 			log.Printf("Error checking middleware existence: %v", err)
-			ResponseWithError(c, http.StatusInternalServerError, "Database error")
+			ResponseWithAPIError(c, errDatabase)
 			return
 		}
 
@@ -750,7 +1004,7 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 		)
 		if txErr != nil {
 			log.Printf("Error removing existing relationship: %v", txErr)
-			ResponseWithError(c, http.StatusInternalServerError, "Database error")
+			ResponseWithAPIError(c, errDatabase)
 			return
 		}
 
@@ -758,8 +1012,8 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 		log.Printf("Creating new relationship: resource=%s, middleware=%s, priority=%d",
 			resourceID, mw.MiddlewareID, mw.Priority)
 		result, txErr := tx.Exec(
-			"INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES (?, ?, ?)",
-			resourceID, mw.MiddlewareID, mw.Priority,
+			"INSERT INTO resource_middlewares (resource_id, middleware_id, priority, expires_at) VALUES (?, ?, ?, ?)",
+			resourceID, mw.MiddlewareID, mw.Priority, expiries[i],
 		)
 		if txErr != nil {
 			log.Printf("Error assigning middleware: %v", txErr)
@@ -774,6 +1028,7 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 			successful = append(successful, map[string]interface{}{
 				"middleware_id": mw.MiddlewareID,
 				"priority":      mw.Priority,
+				"expires_at":    expiries[i],
 			})
 		} else {
 			log.Printf("Warning: Insertion query succeeded but affected %d rows", rowsAffected)
@@ -783,11 +1038,19 @@ func (h *ResourceHandler) AssignMultipleMiddlewares(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully assigned %d middlewares to resource %s", len(successful), resourceID)
+	for _, assignment := range successful {
+		h.Webhooks.Emit(models.EventMiddlewareAssigned, gin.H{
+			"resource_id":   resourceID,
+			"middleware_id": assignment["middleware_id"],
+			"priority":      assignment["priority"],
+			"expires_at":    assignment["expires_at"],
+		})
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"resource_id": resourceID,
 		"middlewares": successful,
@@ -800,7 +1063,7 @@ func (h *ResourceHandler) RemoveMiddleware(c *gin.Context) {
 	middlewareID := c.Param("middlewareId")
 
 	if resourceID == "" || middlewareID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID and Middleware ID are required")
+		ResponseWithAPIError(c, missingFieldError("middlewareId", "Resource ID and Middleware ID are required"))
 		return
 	}
 
@@ -810,7 +1073,7 @@ func (h *ResourceHandler) RemoveMiddleware(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -837,7 +1100,7 @@ func (h *ResourceHandler) RemoveMiddleware(c *gin.Context) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Error getting rows affected: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -852,11 +1115,12 @@ func (h *ResourceHandler) RemoveMiddleware(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully removed middleware %s from resource %s", middlewareID, resourceID)
+	h.Webhooks.Emit(models.EventMiddlewareRemoved, gin.H{"resource_id": resourceID, "middleware_id": middlewareID})
 	c.JSON(http.StatusOK, gin.H{"message": "Middleware removed from resource successfully"})
 }
 
@@ -864,7 +1128,7 @@ func (h *ResourceHandler) RemoveMiddleware(c *gin.Context) {
 func (h *ResourceHandler) AssignExternalMiddleware(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -874,15 +1138,14 @@ func (h *ResourceHandler) AssignExternalMiddleware(c *gin.Context) {
 		Provider       string `json:"provider"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
 	// Validate middleware name is not empty after trimming
 	input.MiddlewareName = strings.TrimSpace(input.MiddlewareName)
 	if input.MiddlewareName == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Middleware name is required")
+		ResponseWithAPIError(c, missingFieldError("middleware_name", "Middleware name is required"))
 		return
 	}
 
@@ -900,12 +1163,12 @@ func (h *ResourceHandler) AssignExternalMiddleware(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot assign middleware to a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot assign middleware to a disabled resource"))
 		return
 	}
 
@@ -913,7 +1176,7 @@ func (h *ResourceHandler) AssignExternalMiddleware(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -932,7 +1195,7 @@ func (h *ResourceHandler) AssignExternalMiddleware(c *gin.Context) {
 	)
 	if txErr != nil {
 		log.Printf("Error removing existing external middleware: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -949,18 +1212,20 @@ func (h *ResourceHandler) AssignExternalMiddleware(c *gin.Context) {
 
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully assigned external middleware %s to resource %s with priority %d",
 		input.MiddlewareName, resourceID, input.Priority)
-	c.JSON(http.StatusOK, gin.H{
+	assignment := gin.H{
 		"resource_id":     resourceID,
 		"middleware_name": input.MiddlewareName,
 		"priority":        input.Priority,
 		"provider":        input.Provider,
-	})
+	}
+	h.Webhooks.Emit(models.EventMiddlewareAssigned, assignment)
+	c.JSON(http.StatusOK, assignment)
 }
 
 // RemoveExternalMiddleware removes a Traefik-native middleware from a resource
@@ -969,7 +1234,7 @@ func (h *ResourceHandler) RemoveExternalMiddleware(c *gin.Context) {
 	middlewareName := c.Param("name")
 
 	if resourceID == "" || middlewareName == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID and Middleware name are required")
+		ResponseWithAPIError(c, missingFieldError("name", "Resource ID and Middleware name are required"))
 		return
 	}
 
@@ -978,7 +1243,7 @@ func (h *ResourceHandler) RemoveExternalMiddleware(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -1003,7 +1268,7 @@ func (h *ResourceHandler) RemoveExternalMiddleware(c *gin.Context) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Error getting rows affected: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -1014,11 +1279,12 @@ func (h *ResourceHandler) RemoveExternalMiddleware(c *gin.Context) {
 
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	log.Printf("Successfully removed external middleware %s from resource %s", middlewareName, resourceID)
+	h.Webhooks.Emit(models.EventMiddlewareRemoved, gin.H{"resource_id": resourceID, "middleware_name": middlewareName})
 	c.JSON(http.StatusOK, gin.H{"message": "External middleware removed from resource successfully"})
 }
 
@@ -1026,7 +1292,7 @@ func (h *ResourceHandler) RemoveExternalMiddleware(c *gin.Context) {
 func (h *ResourceHandler) GetExternalMiddlewares(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -3,11 +3,15 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
 )
 
 func init() {
@@ -141,6 +145,36 @@ func TestResourceHandler_GetResources_SourceTypeFilter(t *testing.T) {
 	}
 }
 
+// TestResourceHandler_GetResources_SharedHost tests flagging resources whose host other routers serve
+func TestResourceHandler_GetResources_SharedHost(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewResourceHandler(db.DB)
+
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, source_type)
+		VALUES ('res-web', 'app-web', 'app.example.com', 'svc-1', 'org-1', 'site-1', 'active', 'pangolin'),
+		       ('res-api', 'app-api', 'app.example.com', 'svc-2', 'org-1', 'site-1', 'active', 'pangolin'),
+		       ('res-other', 'other', 'other.example.com', 'svc-3', 'org-1', 'site-1', 'active', 'pangolin')
+	`)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/resources", nil)
+	handler.GetResources(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resources []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resources); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	for _, r := range resources {
+		want := r["host"] == "app.example.com"
+		if r["shared_host"] != want {
+			t.Errorf("resource %v shared_host = %v, want %v", r["id"], r["shared_host"], want)
+		}
+	}
+}
+
 // TestResourceHandler_GetResources_Pagination tests paginated results
 func TestResourceHandler_GetResources_Pagination(t *testing.T) {
 	db := testutil.NewTempDB(t)
@@ -340,8 +374,8 @@ func TestResourceHandler_AssignExternalMiddleware_MissingName(t *testing.T) {
 	c.Params = gin.Params{{Key: "id", Value: "ext-res-2"}}
 	handler.AssignExternalMiddleware(c)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d: %s", rec.Code, rec.Body.String())
 	}
 }
 
@@ -516,3 +550,146 @@ func TestResourceHandler_GetResource_IncludesExternalMiddlewares(t *testing.T) {
 		t.Errorf("expected 'my-plugin@file:150:file', got '%s'", extMws)
 	}
 }
+
+// TestResourceHandler_AssignMiddleware_WithExpiry tests storing a temporary assignment
+func TestResourceHandler_AssignMiddleware_WithExpiry(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewResourceHandler(db.DB)
+
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-temp', 'temp.example.com', 'svc-1', 'org-1', 'site-1', 'active')
+	`)
+	testutil.MustExec(t, db, `
+		INSERT INTO middlewares (id, name, type, config)
+		VALUES ('mw-allow', 'incident-allowlist', 'ipAllowList', '{}')
+	`)
+
+	expiresAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	body := `{"middleware_id": "mw-allow", "expires_at": "` + expiresAt.Format(time.RFC3339) + `"}`
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/resources/res-temp/middlewares", strings.NewReader(body))
+	c.Params = gin.Params{{Key: "id", Value: "res-temp"}}
+	handler.AssignMiddleware(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var stored time.Time
+	if err := db.DB.QueryRow(
+		"SELECT expires_at FROM resource_middlewares WHERE resource_id = 'res-temp' AND middleware_id = 'mw-allow'",
+	).Scan(&stored); err != nil {
+		t.Fatalf("failed to read assignment: %v", err)
+	}
+	if !stored.Equal(expiresAt) {
+		t.Errorf("expected expires_at %v, got %v", expiresAt, stored)
+	}
+}
+
+// TestResourceHandler_AssignMiddleware_PastExpiry tests that an expiry in the past is rejected
+func TestResourceHandler_AssignMiddleware_PastExpiry(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewResourceHandler(db.DB)
+
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-temp', 'temp.example.com', 'svc-1', 'org-1', 'site-1', 'active')
+	`)
+
+	body := `{"middlewares": [{"middleware_id": "mw-allow", "expires_at": "2020-01-01T00:00:00Z"}]}`
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/resources/res-temp/middlewares/bulk", strings.NewReader(body))
+	c.Params = gin.Params{{Key: "id", Value: "res-temp"}}
+	handler.AssignMultipleMiddlewares(c)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "middlewares[0].expires_at") {
+		t.Errorf("expected field error for middlewares[0].expires_at, got %s", rec.Body.String())
+	}
+}
+
+// TestResourceHandler_SetResourcePin tests pinning and unpinning a resource
+func TestResourceHandler_SetResourcePin(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewResourceHandler(db.DB)
+
+	testutil.MustExec(t, db, `
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status, pin_divergence)
+		VALUES ('res-1', 'app.example.com', 'svc-1', 'org-1', 'site-1', 'active',
+		        '[{"field":"service_id","pinned":"svc-1","upstream":"svc-2"}]')
+	`)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/pin", strings.NewReader(`{"pinned":true}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.SetResourcePin(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	resource, err := loadResource(db.DB, "res-1")
+	if err != nil {
+		t.Fatalf("loadResource() error = %v", err)
+	}
+	if resource["pinned"] != true {
+		t.Errorf("expected pinned resource, got %v", resource["pinned"])
+	}
+	if divergence, ok := resource["pin_divergence"].([]models.PinDivergence); !ok || len(divergence) != 0 {
+		t.Errorf("expected pinning to reset the divergence, got %v", resource["pin_divergence"])
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/pin", strings.NewReader(`{}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.SetResourcePin(c)
+	if rec.Code == http.StatusOK {
+		t.Error("expected a missing pinned field to be rejected")
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/missing/pin", strings.NewReader(`{"pinned":false}`))
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+	setResourceVersion(t, db, c, "missing")
+	handler.SetResourcePin(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing resource, got %d", rec.Code)
+	}
+}
+
+// TestResourceHandler_GetResource_IncludesRouterChain tests reporting the upstream and merged middlewares
+func TestResourceHandler_GetResource_IncludesRouterChain(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewResourceHandler(db.DB)
+
+	pangolin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"http":{"routers":{"app-router":{"rule":"Host(` + "`app.example.com`" + `)","service":"app","middlewares":["badger"]}},"services":{"app":{}}}}`))
+	}))
+	defer pangolin.Close()
+	handler.SetConfigProxy(services.NewConfigProxy(db, testutil.NewTestConfigManager(t), pangolin.URL))
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'app', 'org', 'site', 'active')`)
+	testutil.MustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'strip', 'stripPrefix', '{"prefixes":["/api"]}')`)
+	testutil.MustExec(t, db, `INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES ('res-1', 'mw-1', 100)`)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/resources/res-1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.GetResource(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resource struct {
+		RouterChain *services.RouterChain `json:"router_chain"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resource); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	chain := resource.RouterChain
+	if chain == nil || chain.Router != "app-router" {
+		t.Fatalf("unexpected router chain %+v", chain)
+	}
+	if strings.Join(chain.Upstream, ",") != "badger" || strings.Join(chain.Effective, ",") != "strip,badger" {
+		t.Errorf("upstream = %v, effective = %v", chain.Upstream, chain.Effective)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// ScopeHandler manages the management scope (which resources MM may modify)
+type ScopeHandler struct {
+	DB *sql.DB
+}
+
+// NewScopeHandler creates a new management scope handler
+func NewScopeHandler(db *sql.DB) *ScopeHandler {
+	return &ScopeHandler{DB: db}
+}
+
+// GetScope returns the management scope and which resources currently fall inside it
+func (h *ScopeHandler) GetScope(c *gin.Context) {
+	scope, err := services.LoadManagementScope(h.DB)
+	if err != nil {
+		log.Printf("Error loading management scope: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to load management scope")
+		return
+	}
+
+	resources, err := h.scopePreview(scope)
+	if err != nil {
+		log.Printf("Error building scope preview: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch resources")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scope":     scope,
+		"resources": resources,
+	})
+}
+
+// UpdateScope replaces the include/exclude host patterns
+func (h *ScopeHandler) UpdateScope(c *gin.Context) {
+	var scope models.ManagementScope
+	if !bindRequest(c, &scope) {
+		return
+	}
+
+	scope.Normalize()
+	if err := scope.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid management scope: %v", err))
+		return
+	}
+
+	if err := services.SaveManagementScope(h.DB, &scope); err != nil {
+		log.Printf("Error saving management scope: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save management scope")
+		return
+	}
+
+	log.Printf("Updated management scope: include=%v exclude=%v", scope.Include, scope.Exclude)
+	c.JSON(http.StatusOK, scope)
+}
+
+func (h *ScopeHandler) scopePreview(scope *models.ManagementScope) ([]gin.H, error) {
+	rows, err := h.DB.Query("SELECT id, host, status FROM resources ORDER BY host")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resources := []gin.H{}
+	for rows.Next() {
+		var id, host, status string
+		if err := rows.Scan(&id, &host, &status); err != nil {
+			log.Printf("Error scanning resource row: %v", err)
+			continue
+		}
+		resources = append(resources, gin.H{
+			"id":       id,
+			"host":     host,
+			"status":   status,
+			"in_scope": scope.Matches(host),
+		})
+	}
+	return resources, rows.Err()
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// SecretsHandler handles secret rotation requests
+type SecretsHandler struct {
+	DB      *sql.DB
+	Rotator *services.SecretRotator
+}
+
+// NewSecretsHandler creates a new secrets handler
+func NewSecretsHandler(db *sql.DB, rotator *services.SecretRotator) *SecretsHandler {
+	return &SecretsHandler{DB: db, Rotator: rotator}
+}
+
+// RotateSecret rotates a secret stored in a middleware config and updates
+// every middleware that references it. The new value is only returned here.
+func (h *SecretsHandler) RotateSecret(c *gin.Context) {
+	var input struct {
+		MiddlewareID       string `json:"middleware_id" binding:"required"`
+		Path               string `json:"path"`
+		Username           string `json:"username"`
+		NewValue           string `json:"new_value"`
+		GracePeriodSeconds int    `json:"grace_period_seconds"`
+	}
+	if !bindRequest(c, &input) {
+		return
+	}
+	if input.GracePeriodSeconds < 0 {
+		ResponseWithError(c, http.StatusBadRequest, "grace_period_seconds cannot be negative")
+		return
+	}
+
+	var exists int
+	err := h.DB.QueryRow("SELECT 1 FROM middlewares WHERE id = ?", input.MiddlewareID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Middleware not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking middleware existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	result, err := h.Rotator.Rotate(services.RotateSecretRequest{
+		MiddlewareID: input.MiddlewareID,
+		Path:         input.Path,
+		Username:     input.Username,
+		NewValue:     input.NewValue,
+		GracePeriod:  time.Duration(input.GracePeriodSeconds) * time.Second,
+	})
+	if err != nil {
+		log.Printf("Error rotating secret for middleware %s: %v", input.MiddlewareID, err)
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Failed to rotate secret: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetRotations lists rotation history; old secret values are never returned
+func (h *SecretsHandler) GetRotations(c *gin.Context) {
+	rows, err := h.DB.Query(`
+		SELECT id, kind, middleware_ids, path, grace_applied, expires_at, completed_at, created_at
+		FROM secret_rotations ORDER BY created_at DESC
+	`)
+	if err != nil {
+		log.Printf("Error fetching secret rotations: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch rotations")
+		return
+	}
+	defer rows.Close()
+
+	rotations := []gin.H{}
+	for rows.Next() {
+		var id, kind, middlewareIDs, path string
+		var graceApplied int
+		var expiresAt, completedAt sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&id, &kind, &middlewareIDs, &path, &graceApplied, &expiresAt, &completedAt, &createdAt); err != nil {
+			log.Printf("Error scanning secret rotation row: %v", err)
+			continue
+		}
+		ids := []string{}
+		_ = json.Unmarshal([]byte(middlewareIDs), &ids)
+		rotation := gin.H{
+			"id":             id,
+			"kind":           kind,
+			"middleware_ids": ids,
+			"path":           path,
+			"grace_applied":  graceApplied == 1,
+			"created_at":     createdAt,
+		}
+		if expiresAt.Valid {
+			rotation["grace_expires_at"] = expiresAt.Time
+		}
+		if completedAt.Valid {
+			rotation["completed_at"] = completedAt.Time
+		}
+		rotations = append(rotations, rotation)
+	}
+
+	c.JSON(http.StatusOK, rotations)
+}
+
+// CompleteRotation ends a rotation's grace period and removes the old value now
+func (h *SecretsHandler) CompleteRotation(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.Rotator.CompleteRotation(id); err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "No pending rotation found")
+		return
+	} else if err != nil {
+		log.Printf("Error completing rotation %s: %v", id, err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to complete rotation")
+		return
+	}
+
+	log.Printf("Completed secret rotation %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Rotation completed successfully"})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func TestSecretsHandler_RotateAndComplete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewSecretsHandler(db.DB, services.NewSecretRotator(db))
+
+	testutil.MustExec(t, db, `INSERT INTO middlewares (id, name, type, config)
+		VALUES ('plug', 'plug', 'plugin', '{"auth":{"keys":["old"]}}')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/secrets/rotate",
+		bytes.NewBufferString(`{"middleware_id":"missing","path":"auth.keys"}`))
+	handler.RotateSecret(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown middleware, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/secrets/rotate",
+		bytes.NewBufferString(`{"middleware_id":"plug","path":"auth.keys","grace_period_seconds":3600}`))
+	handler.RotateSecret(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result services.RotateSecretResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if result.NewValue == "" || !result.GraceApplied {
+		t.Fatalf("unexpected rotation result: %+v", result)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/secrets/rotations", nil)
+	handler.GetRotations(c)
+	if rec.Code != http.StatusOK || bytes.Contains(rec.Body.Bytes(), []byte(`"old"`)) {
+		t.Fatalf("rotation listing must succeed without exposing secrets: %d %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/secrets/rotations/"+result.RotationID+"/complete", nil)
+	c.Params = gin.Params{{Key: "id", Value: result.RotationID}}
+	handler.CompleteRotation(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 completing rotation, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var config string
+	if err := db.QueryRow("SELECT config FROM middlewares WHERE id = 'plug'").Scan(&config); err != nil {
+		t.Fatalf("query middleware: %v", err)
+	}
+	if bytes.Contains([]byte(config), []byte(`"old"`)) {
+		t.Fatalf("old key should be removed after completion, got %s", config)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/secrets/rotations/"+result.RotationID+"/complete", nil)
+	c.Params = gin.Params{{Key: "id", Value: result.RotationID}}
+	handler.CompleteRotation(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 completing twice, got %d", rec.Code)
+	}
+}
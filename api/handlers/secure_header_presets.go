@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// lookupSecureHeadersPreset returns a built-in or custom preset, or sql.ErrNoRows
+func lookupSecureHeadersPreset(db *sql.DB, id string) (models.SecureHeadersPreset, error) {
+	if preset, ok := models.BuiltInSecureHeadersPreset(id); ok {
+		return preset, nil
+	}
+
+	var preset models.SecureHeadersPreset
+	var headersJSON string
+	err := db.QueryRow(
+		"SELECT id, name, description, headers, created_at, updated_at FROM secure_header_presets WHERE id = ?", id,
+	).Scan(&preset.ID, &preset.Name, &preset.Description, &headersJSON, &preset.CreatedAt, &preset.UpdatedAt)
+	if err != nil {
+		return preset, err
+	}
+	if err := json.Unmarshal([]byte(headersJSON), &preset.Headers); err != nil {
+		return preset, fmt.Errorf("invalid headers for preset %s: %w", id, err)
+	}
+	return preset, nil
+}
+
+// GetSecureHeadersPresets returns the built-in presets followed by custom ones
+func (h *SecurityHandler) GetSecureHeadersPresets(c *gin.Context) {
+	presets := models.BuiltInSecureHeadersPresets()
+
+	rows, err := h.DB.Query("SELECT id, name, description, headers, created_at, updated_at FROM secure_header_presets ORDER BY name")
+	if err != nil {
+		log.Printf("Error fetching secure headers presets: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch secure headers presets")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var preset models.SecureHeadersPreset
+		var headersJSON string
+		if err := rows.Scan(&preset.ID, &preset.Name, &preset.Description, &headersJSON, &preset.CreatedAt, &preset.UpdatedAt); err != nil {
+			log.Printf("Error scanning secure headers preset row: %v", err)
+			continue
+		}
+		if err := json.Unmarshal([]byte(headersJSON), &preset.Headers); err != nil {
+			log.Printf("Error parsing secure headers preset %s: %v", preset.ID, err)
+			continue
+		}
+		presets = append(presets, preset)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating secure headers preset rows: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Database error while fetching secure headers presets")
+		return
+	}
+
+	c.JSON(http.StatusOK, presets)
+}
+
+// CreateSecureHeadersPreset saves a custom preset
+func (h *SecurityHandler) CreateSecureHeadersPreset(c *gin.Context) {
+	var req models.SecureHeadersPresetRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	headersJSON, ok := validateSecureHeadersPreset(c, &req)
+	if !ok {
+		return
+	}
+
+	id, err := generateID()
+	if err != nil {
+		log.Printf("Error generating ID: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to generate ID")
+		return
+	}
+
+	_, err = h.DB.Exec(
+		"INSERT INTO secure_header_presets (id, name, description, headers) VALUES (?, ?, ?, ?)",
+		id, req.Name, req.Description, headersJSON,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			ResponseWithError(c, http.StatusConflict, fmt.Sprintf("Secure headers preset %q already exists", req.Name))
+			return
+		}
+		log.Printf("Error inserting secure headers preset: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save secure headers preset")
+		return
+	}
+
+	log.Printf("Created secure headers preset %s (%s)", req.Name, id)
+	c.JSON(http.StatusCreated, models.SecureHeadersPreset{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		Headers:     req.Headers,
+	})
+}
+
+// UpdateSecureHeadersPreset updates a custom preset; built-in presets are read-only
+func (h *SecurityHandler) UpdateSecureHeadersPreset(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := models.BuiltInSecureHeadersPreset(id); ok {
+		ResponseWithError(c, http.StatusBadRequest, "Built-in presets cannot be modified; save a custom preset instead")
+		return
+	}
+
+	var req models.SecureHeadersPresetRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	headersJSON, ok := validateSecureHeadersPreset(c, &req)
+	if !ok {
+		return
+	}
+
+	result, err := h.DB.Exec(
+		"UPDATE secure_header_presets SET name = ?, description = ?, headers = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		req.Name, req.Description, headersJSON, id,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			ResponseWithError(c, http.StatusConflict, fmt.Sprintf("Secure headers preset %q already exists", req.Name))
+			return
+		}
+		log.Printf("Error updating secure headers preset: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update secure headers preset")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		ResponseWithError(c, http.StatusNotFound, "Secure headers preset not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SecureHeadersPreset{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		Headers:     req.Headers,
+	})
+}
+
+// DeleteSecureHeadersPreset deletes a custom preset that no resource uses
+func (h *SecurityHandler) DeleteSecureHeadersPreset(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := models.BuiltInSecureHeadersPreset(id); ok {
+		ResponseWithError(c, http.StatusBadRequest, "Built-in presets cannot be deleted")
+		return
+	}
+
+	var inUse int
+	if err := h.DB.QueryRow("SELECT COUNT(*) FROM resources WHERE secure_headers_preset = ?", id).Scan(&inUse); err != nil {
+		log.Printf("Error checking secure headers preset usage: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if inUse > 0 {
+		ResponseWithError(c, http.StatusConflict, fmt.Sprintf("Secure headers preset is used by %d resource(s)", inUse))
+		return
+	}
+
+	result, err := h.DB.Exec("DELETE FROM secure_header_presets WHERE id = ?", id)
+	if err != nil {
+		log.Printf("Error deleting secure headers preset: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete secure headers preset")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		ResponseWithError(c, http.StatusNotFound, "Secure headers preset not found")
+		return
+	}
+
+	log.Printf("Deleted secure headers preset %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Secure headers preset deleted successfully"})
+}
+
+// ApplySecureHeadersPreset copies a preset's values into the global secure headers configuration
+func (h *SecurityHandler) ApplySecureHeadersPreset(c *gin.Context) {
+	preset, err := lookupSecureHeadersPreset(h.DB, c.Param("id"))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Secure headers preset not found")
+		return
+	} else if err != nil {
+		log.Printf("Error loading secure headers preset: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	headers := preset.Headers
+	directivesJSON, err := json.Marshal(headers.PermissionsPolicyDirectives)
+	if err != nil || headers.PermissionsPolicyDirectives == nil {
+		directivesJSON = []byte("[]")
+	}
+
+	_, err = h.DB.Exec(`
+		UPDATE security_config SET
+		       secure_headers_x_content_type_options = ?,
+		       secure_headers_x_frame_options = ?,
+		       secure_headers_x_xss_protection = ?,
+		       secure_headers_hsts = ?,
+		       secure_headers_referrer_policy = ?,
+		       secure_headers_csp = ?,
+		       secure_headers_permissions_policy = ?,
+		       secure_headers_permissions_policy_directives = ?,
+		       secure_headers_coop = ?,
+		       secure_headers_coep = ?,
+		       secure_headers_corp = ?,
+		       updated_at = CURRENT_TIMESTAMP
+		WHERE id = 1
+	`, headers.XContentTypeOptions, headers.XFrameOptions, headers.XXSSProtection,
+		headers.HSTS, headers.ReferrerPolicy, headers.CSP, headers.PermissionsPolicy, string(directivesJSON),
+		headers.CrossOriginOpenerPolicy, headers.CrossOriginEmbedderPolicy, headers.CrossOriginResourcePolicy)
+	if err != nil {
+		log.Printf("Error applying secure headers preset: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to apply secure headers preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        fmt.Sprintf("Secure headers preset %q applied", preset.Name),
+		"secure_headers": headers,
+	})
+}
+
+// validateSecureHeadersPreset normalizes a preset request and returns the encoded headers
+func validateSecureHeadersPreset(c *gin.Context, req *models.SecureHeadersPresetRequest) (string, bool) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		ResponseWithAPIError(c, missingFieldError("name", "Preset name is required"))
+		return "", false
+	}
+	if req.Headers.IsEmpty() {
+		ResponseWithError(c, http.StatusBadRequest, "Preset must set at least one header")
+		return "", false
+	}
+	if !validateSecureHeaders(c, "headers.", req.Headers) {
+		return "", false
+	}
+
+	headersJSON, err := json.Marshal(req.Headers)
+	if err != nil {
+		ResponseWithError(c, http.StatusBadRequest, "Failed to encode preset headers")
+		return "", false
+	}
+	return string(headersJSON), true
+}
+
+// validateSecureHeaders writes a 422 naming the invalid setting when headers
+// fail models.SecureHeadersConfig.Validate. prefix qualifies the field name
+// when the headers are nested in the request.
+func validateSecureHeaders(c *gin.Context, prefix string, headers models.SecureHeadersConfig) bool {
+	err := headers.Validate()
+	if err == nil {
+		return true
+	}
+
+	field, message := prefix+"secure_headers", err.Error()
+	var headersErr *models.SecureHeadersError
+	if errors.As(err, &headersErr) {
+		field, message = prefix+headersErr.Field, headersErr.Message
+	}
+	ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+		"Request validation failed").WithErrors([]apierrors.FieldError{{
+		Field: field, Code: "invalid_syntax", Message: message,
+	}}))
+	return false
+}
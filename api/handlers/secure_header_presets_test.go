@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestSecureHeadersPresets_CRUD(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewSecurityHandler(db.DB, testutil.NewTestConfigManager(t))
+
+	body := bytes.NewBufferString(`{"name": "kiosk", "headers": {"x_frame_options": "SAMEORIGIN", "csp": "frame-ancestors 'self'"}}`)
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/security/secure-headers/presets", body)
+	handler.CreateSecureHeadersPreset(c)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created models.SecureHeadersPreset
+	json.Unmarshal(rec.Body.Bytes(), &created)
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/security/secure-headers/presets", bytes.NewBufferString(`{"name": "empty"}`))
+	handler.CreateSecureHeadersPreset(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a preset without headers, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/security/secure-headers/presets", nil)
+	handler.GetSecureHeadersPresets(c)
+	var presets []models.SecureHeadersPreset
+	json.Unmarshal(rec.Body.Bytes(), &presets)
+	builtIn := len(models.BuiltInSecureHeadersPresets())
+	if len(presets) != builtIn+1 || presets[builtIn].ID != created.ID || presets[builtIn].Headers.CSP != "frame-ancestors 'self'" {
+		t.Fatalf("expected built-in presets followed by the custom one, got %+v", presets)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/security/secure-headers/presets/strict", bytes.NewBufferString(`{"name": "x", "headers": {"csp": "x"}}`))
+	c.Params = gin.Params{{Key: "id", Value: "strict"}}
+	handler.UpdateSecureHeadersPreset(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected built-in presets to be read-only, got %d", rec.Code)
+	}
+
+	// A preset in use cannot be deleted
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status, secure_headers_preset)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active', ?)`, created.ID)
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/security/secure-headers/presets/"+created.ID, nil)
+	c.Params = gin.Params{{Key: "id", Value: created.ID}}
+	handler.DeleteSecureHeadersPreset(c)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a preset in use, got %d", rec.Code)
+	}
+}
+
+func TestSecureHeadersPresets_Apply(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewSecurityHandler(db.DB, testutil.NewTestConfigManager(t))
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/security/secure-headers/presets/api/apply", nil)
+	c.Params = gin.Params{{Key: "id", Value: "api"}}
+	handler.ApplySecureHeadersPreset(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var csp, frame string
+	db.DB.QueryRow("SELECT secure_headers_csp, secure_headers_x_frame_options FROM security_config WHERE id = 1").Scan(&csp, &frame)
+	if csp != "default-src 'none'; frame-ancestors 'none'" || frame != "DENY" {
+		t.Errorf("global headers not replaced by the API preset: csp=%q frame=%q", csp, frame)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/security/secure-headers/presets/nope/apply", nil)
+	c.Params = gin.Params{{Key: "id", Value: "nope"}}
+	handler.ApplySecureHeadersPreset(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestSecurityHandler_UpdateResourceSecureHeaders_Preset(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewSecurityHandler(db.DB, testutil.NewTestConfigManager(t))
+
+	testutil.MustExec(t, db, `UPDATE security_config SET secure_headers_enabled = 1 WHERE id = 1`)
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	update := func(body string) int {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/secure-headers", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.UpdateResourceSecureHeaders(c)
+		return rec.Code
+	}
+
+	if code := update(`{"enabled": true, "preset": "embedded-app"}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := update(`{"enabled": true}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var preset string
+	db.DB.QueryRow("SELECT secure_headers_preset FROM resources WHERE id = 'res-1'").Scan(&preset)
+	if preset != "embedded-app" {
+		t.Errorf("expected the preset to be kept when omitted, got %q", preset)
+	}
+
+	if code := update(`{"enabled": true, "preset": "missing"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an unknown preset, got %d", code)
+	}
+
+	if code := update(`{"enabled": true, "preset": ""}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	db.DB.QueryRow("SELECT secure_headers_preset FROM resources WHERE id = 'res-1'").Scan(&preset)
+	if preset != "" {
+		t.Errorf("expected an empty preset to fall back to global values, got %q", preset)
+	}
+}
+
+func TestSecurityHandler_UpdateResourceSecureHeaders_ReportOnly(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewSecurityHandler(db.DB, testutil.NewTestConfigManager(t))
+
+	testutil.MustExec(t, db, `UPDATE security_config SET secure_headers_enabled = 1 WHERE id = 1`)
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	update := func(body string) map[string]interface{} {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/secure-headers", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.UpdateResourceSecureHeaders(c)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	if resp := update(`{"enabled": true, "report_only": true}`); resp["secure_headers_report_only"] != true {
+		t.Errorf("expected report-only on, got %v", resp["secure_headers_report_only"])
+	}
+	// Omitting report_only keeps the trial running
+	if resp := update(`{"enabled": true}`); resp["secure_headers_report_only"] != true {
+		t.Errorf("expected report-only to be kept, got %v", resp["secure_headers_report_only"])
+	}
+	if resp := update(`{"enabled": true, "report_only": false}`); resp["secure_headers_report_only"] != false {
+		t.Errorf("expected report-only off, got %v", resp["secure_headers_report_only"])
+	}
+}
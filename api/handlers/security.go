@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
 	"github.com/hhftechnology/middleware-manager/models"
 	"github.com/hhftechnology/middleware-manager/services"
 )
@@ -28,20 +30,26 @@ func NewSecurityHandler(db *sql.DB, configManager *services.ConfigManager) *Secu
 func (h *SecurityHandler) GetConfig(c *gin.Context) {
 	var config models.SecurityConfig
 	var tlsHardeningEnabled, secureHeadersEnabled int
+	var directivesJSON string
 
 	err := h.DB.QueryRow(`
-		SELECT id, tls_hardening_enabled, secure_headers_enabled,
+		SELECT id, tls_hardening_enabled, COALESCE(tls_hardening_mode, 'per_resource'), secure_headers_enabled,
 		       secure_headers_x_content_type_options, secure_headers_x_frame_options,
 		       secure_headers_x_xss_protection, secure_headers_hsts,
 		       secure_headers_referrer_policy, secure_headers_csp,
-		       secure_headers_permissions_policy, created_at, updated_at
+		       secure_headers_permissions_policy,
+		       COALESCE(secure_headers_permissions_policy_directives, '[]'),
+		       COALESCE(secure_headers_coop, ''), COALESCE(secure_headers_coep, ''),
+		       COALESCE(secure_headers_corp, ''), created_at, updated_at
 		FROM security_config WHERE id = 1
 	`).Scan(
-		&config.ID, &tlsHardeningEnabled, &secureHeadersEnabled,
+		&config.ID, &tlsHardeningEnabled, &config.TLSHardeningMode, &secureHeadersEnabled,
 		&config.SecureHeaders.XContentTypeOptions, &config.SecureHeaders.XFrameOptions,
 		&config.SecureHeaders.XXSSProtection, &config.SecureHeaders.HSTS,
 		&config.SecureHeaders.ReferrerPolicy, &config.SecureHeaders.CSP,
-		&config.SecureHeaders.PermissionsPolicy, &config.CreatedAt, &config.UpdatedAt,
+		&config.SecureHeaders.PermissionsPolicy, &directivesJSON,
+		&config.SecureHeaders.CrossOriginOpenerPolicy, &config.SecureHeaders.CrossOriginEmbedderPolicy,
+		&config.SecureHeaders.CrossOriginResourcePolicy, &config.CreatedAt, &config.UpdatedAt,
 	)
 
 	if err != nil {
@@ -50,6 +58,7 @@ func (h *SecurityHandler) GetConfig(c *gin.Context) {
 			config = models.SecurityConfig{
 				ID:                   1,
 				TLSHardeningEnabled:  false,
+				TLSHardeningMode:     models.TLSHardeningPerResource,
 				SecureHeadersEnabled: false,
 				SecureHeaders:        models.DefaultSecureHeaders(),
 			}
@@ -61,6 +70,9 @@ func (h *SecurityHandler) GetConfig(c *gin.Context) {
 	} else {
 		config.TLSHardeningEnabled = tlsHardeningEnabled == 1
 		config.SecureHeadersEnabled = secureHeadersEnabled == 1
+		if err := json.Unmarshal([]byte(directivesJSON), &config.SecureHeaders.PermissionsPolicyDirectives); err != nil {
+			log.Printf("Error parsing Permissions-Policy directives: %v", err)
+		}
 	}
 
 	c.JSON(http.StatusOK, config)
@@ -100,6 +112,36 @@ func (h *SecurityHandler) DisableTLSHardening(c *gin.Context) {
 	})
 }
 
+// UpdateTLSHardeningMode sets how the global TLS hardening setting combines
+// with the per-resource flags (force_on, default_on or per_resource)
+func (h *SecurityHandler) UpdateTLSHardeningMode(c *gin.Context) {
+	var input models.UpdateTLSHardeningModeRequest
+	if !bindRequest(c, &input) {
+		return
+	}
+	if !models.ValidTLSHardeningMode(input.Mode) {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			"Unknown TLS hardening mode").
+			WithField("mode").
+			WithHint("Use \"force_on\", \"default_on\" or \"per_resource\""))
+		return
+	}
+
+	_, err := h.DB.Exec(`
+		UPDATE security_config SET tls_hardening_mode = ?, updated_at = CURRENT_TIMESTAMP WHERE id = 1
+	`, input.Mode)
+	if err != nil {
+		log.Printf("Error updating TLS hardening mode: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update TLS hardening mode")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "TLS hardening mode updated",
+		"mode":    input.Mode,
+	})
+}
+
 // EnableSecureHeaders enables secure headers globally
 func (h *SecurityHandler) EnableSecureHeaders(c *gin.Context) {
 	_, err := h.DB.Exec(`
@@ -137,12 +179,19 @@ func (h *SecurityHandler) DisableSecureHeaders(c *gin.Context) {
 // UpdateSecureHeadersConfig updates the secure headers configuration
 func (h *SecurityHandler) UpdateSecureHeadersConfig(c *gin.Context) {
 	var input models.SecureHeadersConfig
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindRequest(c, &input) {
+		return
+	}
+	if !validateSecureHeaders(c, "", input) {
 		return
 	}
 
-	_, err := h.DB.Exec(`
+	directivesJSON, err := json.Marshal(input.PermissionsPolicyDirectives)
+	if err != nil || input.PermissionsPolicyDirectives == nil {
+		directivesJSON = []byte("[]")
+	}
+
+	_, err = h.DB.Exec(`
 		UPDATE security_config SET
 		       secure_headers_x_content_type_options = ?,
 		       secure_headers_x_frame_options = ?,
@@ -151,10 +200,15 @@ func (h *SecurityHandler) UpdateSecureHeadersConfig(c *gin.Context) {
 		       secure_headers_referrer_policy = ?,
 		       secure_headers_csp = ?,
 		       secure_headers_permissions_policy = ?,
+		       secure_headers_permissions_policy_directives = ?,
+		       secure_headers_coop = ?,
+		       secure_headers_coep = ?,
+		       secure_headers_corp = ?,
 		       updated_at = CURRENT_TIMESTAMP
 		WHERE id = 1
 	`, input.XContentTypeOptions, input.XFrameOptions, input.XXSSProtection,
-		input.HSTS, input.ReferrerPolicy, input.CSP, input.PermissionsPolicy)
+		input.HSTS, input.ReferrerPolicy, input.CSP, input.PermissionsPolicy, string(directivesJSON),
+		input.CrossOriginOpenerPolicy, input.CrossOriginEmbedderPolicy, input.CrossOriginResourcePolicy)
 
 	if err != nil {
 		log.Printf("Error updating secure headers config: %v", err)
@@ -170,8 +224,7 @@ func (h *SecurityHandler) UpdateSecureHeadersConfig(c *gin.Context) {
 // CheckMiddlewareDuplicates checks if a middleware name conflicts with existing Traefik middlewares
 func (h *SecurityHandler) CheckMiddlewareDuplicates(c *gin.Context) {
 	var req models.DuplicateCheckRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindRequest(c, &req) {
 		return
 	}
 
@@ -184,13 +237,19 @@ func (h *SecurityHandler) CheckMiddlewareDuplicates(c *gin.Context) {
 func (h *SecurityHandler) UpdateResourceTLSHardening(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
-	var input models.UpdateResourceSecurityRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	var input models.UpdateResourceTLSHardeningRequest
+	if !bindRequest(c, &input) {
+		return
+	}
+	if input.Profile != "" && !models.ValidTLSProfile(input.Profile) {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			"Unknown TLS hardening profile").
+			WithField("profile").
+			WithHint("Use \"hardened\" or \"compat\"; see /api/security/tls-hardening/compatibility"))
 		return
 	}
 
@@ -212,25 +271,62 @@ func (h *SecurityHandler) UpdateResourceTLSHardening(c *gin.Context) {
 		return
 	}
 
-	enabledVal := 0
+	// A resource cannot opt out while hardening is forced on globally
+	var mode string
+	err = h.DB.QueryRow("SELECT COALESCE(tls_hardening_mode, 'per_resource') FROM security_config WHERE id = 1").Scan(&mode)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error checking TLS hardening mode: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to check global configuration")
+		return
+	}
+	if mode == models.TLSHardeningForceOn && !input.Enabled {
+		ResponseWithAPIError(c, apierrors.New(http.StatusConflict, apierrors.CodeConflict,
+			"TLS hardening is forced on globally and cannot be disabled per resource").
+			WithHint("Switch the global TLS hardening mode to default_on or per_resource first"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	// Switching off also records the opt-out that default_on mode honours
+	enabledVal, optOutVal := 0, 1
 	if input.Enabled {
-		enabledVal = 1
+		enabledVal, optOutVal = 1, 0
 	}
 
-	_, err = h.DB.Exec(`
-		UPDATE resources SET tls_hardening_enabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
-	`, enabledVal, resourceID)
+	result, err := h.DB.Exec(`
+		UPDATE resources SET tls_hardening_enabled = ?, tls_hardening_opt_out = ?,
+		       tls_hardening_profile = COALESCE(NULLIF(?, ''), tls_hardening_profile),
+		       updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = ? AND version = ?
+	`, enabledVal, optOutVal, input.Profile, resourceID, version)
 
 	if err != nil {
 		log.Printf("Error updating resource TLS hardening: %v", err)
 		ResponseWithError(c, http.StatusInternalServerError, "Failed to update TLS hardening")
 		return
 	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, resourceID)
+		return
+	}
 
+	profile := input.Profile
+	if profile == "" {
+		if err := h.DB.QueryRow("SELECT tls_hardening_profile FROM resources WHERE id = ?", resourceID).Scan(&profile); err != nil {
+			log.Printf("Error reading resource TLS hardening profile: %v", err)
+			profile = models.TLSProfileHardened
+		}
+	}
+
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
 		"message":              "TLS hardening updated",
 		"resource_id":          resourceID,
 		"tls_hardening_enabled": input.Enabled,
+		"tls_hardening_profile": profile,
 	})
 }
 
@@ -238,15 +334,27 @@ func (h *SecurityHandler) UpdateResourceTLSHardening(c *gin.Context) {
 func (h *SecurityHandler) UpdateResourceSecureHeaders(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
-	var input models.UpdateResourceSecurityRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	var input models.UpdateResourceSecureHeadersRequest
+	if !bindRequest(c, &input) {
 		return
 	}
+	if input.Preset != nil && *input.Preset != "" {
+		if _, err := lookupSecureHeadersPreset(h.DB, *input.Preset); err == sql.ErrNoRows {
+			ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+				"Unknown secure headers preset").
+				WithField("preset").
+				WithHint("List presets with GET /api/security/secure-headers/presets"))
+			return
+		} else if err != nil {
+			log.Printf("Error loading secure headers preset: %v", err)
+			ResponseWithAPIError(c, errDatabase)
+			return
+		}
+	}
 
 	// Check if global secure headers is enabled
 	var globalEnabled int
@@ -261,25 +369,48 @@ func (h *SecurityHandler) UpdateResourceSecureHeaders(c *gin.Context) {
 		ResponseWithError(c, http.StatusBadRequest, "Enable secure headers globally first before enabling per-resource")
 		return
 	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
 
 	enabledVal := 0
 	if input.Enabled {
 		enabledVal = 1
 	}
 
-	_, err = h.DB.Exec(`
-		UPDATE resources SET secure_headers_enabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
-	`, enabledVal, resourceID)
+	result, err := h.DB.Exec(`
+		UPDATE resources SET secure_headers_enabled = ?,
+		       secure_headers_preset = COALESCE(?, secure_headers_preset),
+		       secure_headers_report_only = COALESCE(?, secure_headers_report_only),
+		       updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = ? AND version = ?
+	`, enabledVal, input.Preset, input.ReportOnly, resourceID, version)
 
 	if err != nil {
 		log.Printf("Error updating resource secure headers: %v", err)
 		ResponseWithError(c, http.StatusInternalServerError, "Failed to update secure headers")
 		return
 	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		resourceVersionConflict(c, h.DB, resourceID)
+		return
+	}
+
+	var preset string
+	var reportOnly bool
+	if err := h.DB.QueryRow(
+		"SELECT secure_headers_preset, secure_headers_report_only FROM resources WHERE id = ?", resourceID,
+	).Scan(&preset, &reportOnly); err != nil {
+		log.Printf("Error reading resource secure headers settings: %v", err)
+	}
 
+	c.Header("ETag", versionETag(version+1))
 	c.JSON(http.StatusOK, gin.H{
-		"message":                "Secure headers updated",
-		"resource_id":            resourceID,
-		"secure_headers_enabled": input.Enabled,
+		"message":                    "Secure headers updated",
+		"resource_id":                resourceID,
+		"secure_headers_enabled":     input.Enabled,
+		"secure_headers_preset":      preset,
+		"secure_headers_report_only": reportOnly,
 	})
 }
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/hhftechnology/middleware-manager/internal/testutil"
 )
 
@@ -238,3 +240,184 @@ func TestSecurityHandler_UpdateSecureHeadersConfig_InvalidJSON(t *testing.T) {
 		t.Errorf("expected 400, got %d", rec.Code)
 	}
 }
+
+// TestSecurityHandler_GetTLSCompatibility tests the client compatibility report
+func TestSecurityHandler_GetTLSCompatibility(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	handler := NewSecurityHandler(db.DB, cm)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/security/tls-hardening/compatibility?key_type=rsa", nil)
+	handler.GetTLSCompatibility(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &report)
+	if report["profile"] != "hardened" || report["options_name"] != "tls-hardened" {
+		t.Errorf("unexpected report header: %v", report)
+	}
+	if report["compat_profile"] == nil {
+		t.Error("expected the compat profile alongside a hardened report that breaks clients")
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/security/tls-hardening/compatibility?profile=legacy", nil)
+	handler.GetTLSCompatibility(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown profile, got %d", rec.Code)
+	}
+}
+
+// TestSecurityHandler_UpdateResourceTLSHardening_Profile tests choosing the compat profile
+func TestSecurityHandler_UpdateResourceTLSHardening_Profile(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	handler := NewSecurityHandler(db.DB, cm)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'tv.example.com', 'svc', 'org', 'site', 'active')`)
+
+	update := func(body string) *httptest.ResponseRecorder {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/tls-hardening", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.UpdateResourceTLSHardening(c)
+		return rec
+	}
+
+	if rec := update(`{"enabled": true, "profile": "compat"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	// Omitting the profile keeps the current one
+	rec := update(`{"enabled": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp["tls_hardening_profile"] != "compat" {
+		t.Errorf("expected profile compat in response, got %v", resp["tls_hardening_profile"])
+	}
+
+	var profile string
+	db.DB.QueryRow("SELECT tls_hardening_profile FROM resources WHERE id = 'res-1'").Scan(&profile)
+	if profile != "compat" {
+		t.Errorf("expected db profile compat, got %q", profile)
+	}
+
+	if rec := update(`{"enabled": true, "profile": "legacy"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an unknown profile, got %d", rec.Code)
+	}
+}
+
+// TestSecurityHandler_UpdateTLSHardeningMode tests the inheritance mode and force-on opt-out guard
+func TestSecurityHandler_UpdateTLSHardeningMode(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	handler := NewSecurityHandler(db.DB, cm)
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/security/tls-hardening/mode", bytes.NewBufferString(`{"mode": "sometimes"}`))
+	handler.UpdateTLSHardeningMode(c)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for an unknown mode, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/security/tls-hardening/mode", bytes.NewBufferString(`{"mode": "force_on"}`))
+	handler.UpdateTLSHardeningMode(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/security/config", nil)
+	handler.GetConfig(c)
+	var config map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &config)
+	if config["tls_hardening_mode"] != "force_on" {
+		t.Errorf("expected mode force_on in config, got %v", config["tls_hardening_mode"])
+	}
+
+	// Opting a resource out is rejected while hardening is forced on
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/tls-hardening", bytes.NewBufferString(`{"enabled": false}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateResourceTLSHardening(c)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	testutil.MustExec(t, db, `UPDATE security_config SET tls_hardening_mode = 'default_on' WHERE id = 1`)
+	c, rec = testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/tls-hardening", bytes.NewBufferString(`{"enabled": false}`))
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	setResourceVersion(t, db, c, "res-1")
+	handler.UpdateResourceTLSHardening(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var optOut int
+	db.DB.QueryRow("SELECT tls_hardening_opt_out FROM resources WHERE id = 'res-1'").Scan(&optOut)
+	if optOut != 1 {
+		t.Errorf("expected the resource to be recorded as opted out, got %d", optOut)
+	}
+}
+
+// TestSecurityHandler_UpdateSecureHeadersConfig_Structured tests Permissions-Policy directives and COOP/COEP/CORP
+func TestSecurityHandler_UpdateSecureHeadersConfig_Structured(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	handler := NewSecurityHandler(db.DB, cm)
+
+	update := func(body string) *httptest.ResponseRecorder {
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/security/secure-headers/config", bytes.NewBufferString(body))
+		handler.UpdateSecureHeadersConfig(c)
+		return rec
+	}
+
+	rec := update(`{"cross_origin_opener_policy": "sometimes"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an unknown COOP value, got %d", rec.Code)
+	}
+
+	rec = update(`{"permissions_policy_directives": [{"feature": "geolocation", "allowlist": ["maps.example.com"]}]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a bare host in the allowlist, got %d", rec.Code)
+	}
+	var apiErr struct {
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "permissions_policy_directives" {
+		t.Errorf("expected the directives field to be named, got %s", rec.Body.String())
+	}
+
+	rec = update(`{
+		"permissions_policy_directives": [{"feature": "camera", "allowlist": []}, {"feature": "geolocation", "allowlist": ["self"]}],
+		"cross_origin_opener_policy": "same-origin",
+		"cross_origin_embedder_policy": "require-corp",
+		"cross_origin_resource_policy": "same-site"
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/security/config", nil)
+	handler.GetConfig(c)
+	var config struct {
+		SecureHeaders struct {
+			Directives []map[string]interface{} `json:"permissions_policy_directives"`
+			COOP       string                   `json:"cross_origin_opener_policy"`
+			COEP       string                   `json:"cross_origin_embedder_policy"`
+			CORP       string                   `json:"cross_origin_resource_policy"`
+		} `json:"secure_headers"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &config)
+	if len(config.SecureHeaders.Directives) != 2 || config.SecureHeaders.COOP != "same-origin" ||
+		config.SecureHeaders.COEP != "require-corp" || config.SecureHeaders.CORP != "same-site" {
+		t.Errorf("structured headers not stored: %s", rec.Body.String())
+	}
+}
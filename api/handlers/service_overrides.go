@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// ServiceOverrideHandler manages deltas applied to services discovered upstream
+type ServiceOverrideHandler struct {
+	DB *sql.DB
+}
+
+// NewServiceOverrideHandler creates a new service override handler
+func NewServiceOverrideHandler(db *sql.DB) *ServiceOverrideHandler {
+	return &ServiceOverrideHandler{DB: db}
+}
+
+// GetServiceOverrides returns every service override
+func (h *ServiceOverrideHandler) GetServiceOverrides(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT service_name, data, created_at, updated_at FROM service_overrides ORDER BY service_name")
+	if err != nil {
+		log.Printf("Error fetching service overrides: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	defer rows.Close()
+
+	overrides := []models.ServiceOverride{}
+	for rows.Next() {
+		override, err := scanServiceOverride(rows)
+		if err != nil {
+			log.Printf("Error scanning service override: %v", err)
+			continue
+		}
+		overrides = append(overrides, override)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating service overrides: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+// GetServiceOverride returns the override of one service
+func (h *ServiceOverrideHandler) GetServiceOverride(c *gin.Context) {
+	override, err := scanServiceOverride(h.DB.QueryRow(
+		"SELECT service_name, data, created_at, updated_at FROM service_overrides WHERE service_name = ?", serviceOverrideName(c)))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "No override configured for this service")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching service override: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	c.JSON(http.StatusOK, override)
+}
+
+// SetServiceOverride creates or replaces the override of a service. The
+// service does not have to be present upstream yet; the proxy status reports
+// overrides that matched nothing.
+func (h *ServiceOverrideHandler) SetServiceOverride(c *gin.Context) {
+	var override models.ServiceOverride
+	if !bindRequest(c, &override) {
+		return
+	}
+	override.ServiceName = c.Param("name")
+	override.Normalize()
+	if err := override.Validate(); err != nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	override.UpdatedAt = time.Now()
+	data, err := json.Marshal(override)
+	if err != nil {
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to encode service override")
+		return
+	}
+	if _, err := h.DB.Exec(`
+		INSERT INTO service_overrides (service_name, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(service_name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at
+	`, override.ServiceName, string(data), override.UpdatedAt); err != nil {
+		log.Printf("Error saving service override: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save service override")
+		return
+	}
+
+	log.Printf("Saved override of service %s", override.ServiceName)
+	c.JSON(http.StatusOK, override)
+}
+
+// DeleteServiceOverride removes the override; the upstream service is served
+// unchanged again on the next merge
+func (h *ServiceOverrideHandler) DeleteServiceOverride(c *gin.Context) {
+	name := serviceOverrideName(c)
+	result, err := h.DB.Exec("DELETE FROM service_overrides WHERE service_name = ?", name)
+	if err != nil {
+		log.Printf("Error deleting service override: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to delete service override")
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		ResponseWithError(c, http.StatusNotFound, "No override configured for this service")
+		return
+	}
+
+	log.Printf("Removed override of service %s", name)
+	c.JSON(http.StatusOK, gin.H{"message": "Service override removed successfully"})
+}
+
+// serviceOverrideName returns the service name from the path, normalized the
+// way overrides are stored
+func serviceOverrideName(c *gin.Context) string {
+	override := models.ServiceOverride{ServiceName: c.Param("name")}
+	override.Normalize()
+	return override.ServiceName
+}
+
+func scanServiceOverride(row corsScanner) (models.ServiceOverride, error) {
+	var override models.ServiceOverride
+	var name, data string
+	var createdAt, updatedAt time.Time
+	if err := row.Scan(&name, &data, &createdAt, &updatedAt); err != nil {
+		return override, err
+	}
+	if err := json.Unmarshal([]byte(data), &override); err != nil {
+		return override, err
+	}
+	override.ServiceName = name
+	override.CreatedAt = createdAt
+	override.UpdatedAt = updatedAt
+	return override, nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestServiceOverrideHandler_SetGetDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewServiceOverrideHandler(db.DB)
+
+	set := func(name, body string) (int, map[string]interface{}) {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/service-overrides/"+name, bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "name", Value: name}}
+		handler.SetServiceOverride(c)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	if code, _ := set("app-service", `{}`); code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for an empty override, got %d", code)
+	}
+	if code, _ := set("app-service", `{"add_servers":[{"url":"10.0.0.7:8080"}]}`); code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a server without scheme, got %d", code)
+	}
+	code, resp := set("app-service@http", `{"server_weights":{"http://10.0.0.6:8080":3}}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, resp)
+	}
+	if resp["service_name"] != "app-service" {
+		t.Fatalf("expected the provider suffix to be dropped, got %v", resp["service_name"])
+	}
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/service-overrides/app-service", nil)
+	c.Params = gin.Params{{Key: "name", Value: "app-service"}}
+	handler.GetServiceOverride(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodDelete, "/api/service-overrides/app-service@http", nil)
+	c.Params = gin.Params{{Key: "name", Value: "app-service@http"}}
+	handler.DeleteServiceOverride(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 removing override, got %d", rec.Code)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/service-overrides/app-service", nil)
+	c.Params = gin.Params{{Key: "name", Value: "app-service"}}
+	handler.GetServiceOverride(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after removal, got %d", rec.Code)
+	}
+}
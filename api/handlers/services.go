@@ -114,8 +114,7 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 		Config map[string]interface{} `json:"config" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&service); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &service) {
 		return
 	}
 
@@ -124,6 +123,12 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid service type: %s", service.Type))
 		return
 	}
+	if service.Type == string(models.DNSDiscoveryType) {
+		if _, err := models.ParseDNSDiscoveryConfig(service.Config); err != nil {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid dnsDiscovery config: %v", err))
+			return
+		}
+	}
 
 	// Generate a unique ID
 	id, err := generateID()
@@ -148,7 +153,7 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -186,7 +191,7 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -203,7 +208,7 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 func (h *ServiceHandler) GetService(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Service ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Service ID is required"))
 		return
 	}
 
@@ -237,7 +242,7 @@ func (h *ServiceHandler) GetService(c *gin.Context) {
 func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Service ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Service ID is required"))
 		return
 	}
 
@@ -247,8 +252,7 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 		Config map[string]interface{} `json:"config" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&service); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &service) {
 		return
 	}
 
@@ -257,6 +261,12 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid service type: %s", service.Type))
 		return
 	}
+	if service.Type == string(models.DNSDiscoveryType) {
+		if _, err := models.ParseDNSDiscoveryConfig(service.Config); err != nil {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid dnsDiscovery config: %v", err))
+			return
+		}
+	}
 
 	rec, err := h.findServiceByID(id)
 	if err == sql.ErrNoRows {
@@ -264,7 +274,7 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking service existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -283,7 +293,7 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -321,7 +331,7 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -349,7 +359,7 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Service ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Service ID is required"))
 		return
 	}
 
@@ -359,7 +369,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error fetching service for delete: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -368,7 +378,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	err = h.DB.QueryRow("SELECT COUNT(*) FROM resource_services WHERE service_id = ?", rec.ID).Scan(&count)
 	if err != nil {
 		log.Printf("Error checking service dependencies: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -381,7 +391,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -406,7 +416,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Error getting rows affected: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -416,7 +426,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	}
 
 	// Track deletion to prevent template from being re-created on restart
-	_, txErr = tx.Exec("INSERT OR REPLACE INTO deleted_templates (id, type) VALUES (?, 'service')", rec.ID)
+	_, txErr = tx.Exec("INSERT INTO deleted_templates (id, type) VALUES (?, 'service') ON CONFLICT(id, type) DO UPDATE SET deleted_at = CURRENT_TIMESTAMP", rec.ID)
 	if txErr != nil {
 		log.Printf("Warning: Failed to track deleted template: %v", txErr)
 		// Continue anyway - this is not critical
@@ -427,7 +437,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -439,7 +449,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -447,8 +457,7 @@ func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 		ServiceID string `json:"service_id" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	if !bindRequest(c, &input) {
 		return
 	}
 
@@ -461,13 +470,13 @@ func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking resource existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
 	// Don't allow attaching services to disabled resources
 	if status == "disabled" {
-		ResponseWithError(c, http.StatusBadRequest, "Cannot assign service to a disabled resource")
+		ResponseWithAPIError(c, disabledResourceError("Cannot assign service to a disabled resource"))
 		return
 	}
 
@@ -478,7 +487,7 @@ func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 		return
 	} else if err != nil {
 		log.Printf("Error checking service existence: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -486,7 +495,7 @@ func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -507,7 +516,7 @@ func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 	)
 	if txErr != nil {
 		log.Printf("Error removing existing relationship: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -533,7 +542,7 @@ func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -549,7 +558,7 @@ func (h *ServiceHandler) AssignServiceToResource(c *gin.Context) {
 func (h *ServiceHandler) RemoveServiceFromResource(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
@@ -559,7 +568,7 @@ func (h *ServiceHandler) RemoveServiceFromResource(c *gin.Context) {
 	tx, err := h.DB.Begin()
 	if err != nil {
 		log.Printf("Error beginning transaction: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -586,7 +595,7 @@ func (h *ServiceHandler) RemoveServiceFromResource(c *gin.Context) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Error getting rows affected: %v", err)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -601,7 +610,7 @@ func (h *ServiceHandler) RemoveServiceFromResource(c *gin.Context) {
 	// Commit the transaction
 	if txErr = tx.Commit(); txErr != nil {
 		log.Printf("Error committing transaction: %v", txErr)
-		ResponseWithError(c, http.StatusInternalServerError, "Database error")
+		ResponseWithAPIError(c, errDatabase)
 		return
 	}
 
@@ -613,7 +622,7 @@ func (h *ServiceHandler) RemoveServiceFromResource(c *gin.Context) {
 func (h *ServiceHandler) GetResourceService(c *gin.Context) {
 	resourceID := c.Param("id")
 	if resourceID == "" {
-		ResponseWithError(c, http.StatusBadRequest, "Resource ID is required")
+		ResponseWithAPIError(c, missingFieldError("id", "Resource ID is required"))
 		return
 	}
 
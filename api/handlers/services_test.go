@@ -245,8 +245,8 @@ func TestServiceHandler_CreateService_ValidationError(t *testing.T) {
 	c, rec := testutil.NewContext(t, http.MethodPost, "/api/services", body)
 	handler.CreateService(c)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", rec.Code)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", rec.Code)
 	}
 }
 
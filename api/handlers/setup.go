@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// SetupHandler serves the first-run setup wizard
+type SetupHandler struct {
+	wizard *services.SetupWizard
+	// staticConfigPath returns the current Traefik static config path
+	staticConfigPath func() string
+}
+
+// NewSetupHandler creates a new setup handler
+func NewSetupHandler(wizard *services.SetupWizard, staticConfigPath func() string) *SetupHandler {
+	return &SetupHandler{wizard: wizard, staticConfigPath: staticConfigPath}
+}
+
+// GetSetupState returns the wizard progress and the next step to show
+func (h *SetupHandler) GetSetupState(c *gin.Context) {
+	c.JSON(http.StatusOK, h.wizard.State())
+}
+
+// DetectDeployment probes the Docker network for Pangolin and Traefik
+func (h *SetupHandler) DetectDeployment(c *gin.Context) {
+	var req models.SetupDetectRequest
+	// The body is optional; it only adds candidate URLs
+	if c.Request.ContentLength > 0 && !bindRequest(c, &req) {
+		return
+	}
+
+	detection, err := h.wizard.Detect(c.Request.Context(), req)
+	if err != nil {
+		LogError("detecting deployment", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save setup progress")
+		return
+	}
+	c.JSON(http.StatusOK, detection)
+}
+
+// ConfigureDataSource saves the data sources for the chosen deployment type
+func (h *SetupHandler) ConfigureDataSource(c *gin.Context) {
+	var req models.SetupDataSourceRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	err := h.wizard.ConfigureDataSource(c.Request.Context(), req)
+	var fieldErr *services.SetupFieldError
+	if errors.As(err, &fieldErr) {
+		apiErr := apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed, fieldErr.Message).
+			WithField(fieldErr.Field)
+		if fieldErr.Unreachable {
+			apiErr = apiErr.WithHint("Check the URL from inside the container, or send force=true to save it anyway")
+		}
+		ResponseWithAPIError(c, apiErr)
+		return
+	} else if err != nil {
+		LogError("configuring data source from setup", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save data source configuration")
+		return
+	}
+
+	log.Printf("Setup configured a %s deployment", req.DeploymentType)
+	c.JSON(http.StatusOK, h.wizard.State())
+}
+
+// CheckProvider verifies that Traefik loads the config served by this instance
+func (h *SetupHandler) CheckProvider(c *gin.Context) {
+	path := ""
+	if h.staticConfigPath != nil {
+		path = h.staticConfigPath()
+	}
+	result, err := h.wizard.CheckProvider(c.Request.Context(), path)
+	if err != nil {
+		LogError("checking Traefik provider from setup", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save setup progress")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CompleteSetup marks the wizard finished
+func (h *SetupHandler) CompleteSetup(c *gin.Context) {
+	if err := h.wizard.Complete(); err != nil {
+		LogError("completing setup", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to save setup progress")
+		return
+	}
+	c.JSON(http.StatusOK, h.wizard.State())
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func newTestSetupHandler(t *testing.T) *SetupHandler {
+	t.Helper()
+	return NewSetupHandler(services.NewSetupWizard(testutil.NewTestConfigManager(t)), nil)
+}
+
+// TestSetupHandler_GetSetupState tests the initial wizard state
+func TestSetupHandler_GetSetupState(t *testing.T) {
+	handler := newTestSetupHandler(t)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/setup", nil)
+	handler.GetSetupState(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetSetupState() status = %d", rec.Code)
+	}
+	var state models.SetupState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Completed || len(state.Steps) != 4 {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
+
+// TestSetupHandler_ConfigureDataSource_Validation tests request validation
+func TestSetupHandler_ConfigureDataSource_Validation(t *testing.T) {
+	handler := newTestSetupHandler(t)
+
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantField string
+	}{
+		{"unknown deployment type", `{"deployment_type": "kubernetes"}`, http.StatusUnprocessableEntity, ""},
+		{"missing url", `{"deployment_type": "pangolin"}`, http.StatusBadRequest, "pangolin_url"},
+		{"bad scheme", `{"deployment_type": "standalone", "traefik_url": "traefik:8080"}`, http.StatusBadRequest, "traefik_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := testutil.NewContext(t, http.MethodPut, "/api/setup/datasource", strings.NewReader(tt.body))
+			handler.ConfigureDataSource(c)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantField != "" && !strings.Contains(rec.Body.String(), `"field":"`+tt.wantField+`"`) {
+				t.Errorf("expected field %q in %s", tt.wantField, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestSetupHandler_CompleteSetup tests finishing the wizard
+func TestSetupHandler_CompleteSetup(t *testing.T) {
+	handler := newTestSetupHandler(t)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/setup/complete", nil)
+	handler.CompleteSetup(c)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"completed":true`) {
+		t.Fatalf("CompleteSetup() = %d %s", rec.Code, rec.Body.String())
+	}
+}
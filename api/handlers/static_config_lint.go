@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+	"github.com/hhftechnology/middleware-manager/util"
+)
+
+// LintStaticConfig lints the Traefik static config file at the configured path
+func (h *PluginHandler) LintStaticConfig(c *gin.Context) {
+	if h.TraefikStaticConfigPath == "" {
+		ResponseWithAPIError(c, errStaticConfigPathNotSet)
+		return
+	}
+
+	cleanPath := filepath.Clean(h.TraefikStaticConfigPath)
+	data, err := util.OS.ReadFile(cleanPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			ResponseWithError(c, http.StatusNotFound, fmt.Sprintf("Traefik static config file not found at %s", cleanPath))
+			return
+		}
+		LogError(fmt.Sprintf("reading traefik static config file %s", cleanPath), err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to read Traefik static configuration file.")
+		return
+	}
+
+	report := services.LintTraefikStaticConfig(data)
+	report.Path = cleanPath
+	c.JSON(http.StatusOK, report)
+}
+
+// LintStaticConfigContent lints static config content from the editor before
+// it is saved
+func (h *PluginHandler) LintStaticConfigContent(c *gin.Context) {
+	var req models.LintStaticConfigRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	c.JSON(http.StatusOK, services.LintTraefikStaticConfig([]byte(req.Content)))
+}
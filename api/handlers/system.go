@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// Limits for the resource watcher run list
+const (
+	defaultWatcherRuns = 50
+	maxWatcherRuns     = 500
+)
+
+// SystemHandler serves environment diagnostics and watcher run history
+type SystemHandler struct {
+	diagnostics  *services.Diagnostics
+	resourceRuns *services.ResourceRunLog
+	// runtimeStats collects the process figures of /api/system/runtime
+	runtimeStats func() services.RuntimeStats
+	// reload re-reads the settings; nil when reloading is not available
+	reload func(trigger string) (*services.ReloadReport, error)
+}
+
+// NewSystemHandler creates a new system handler
+func NewSystemHandler(diagnostics *services.Diagnostics, resourceRuns *services.ResourceRunLog) *SystemHandler {
+	return &SystemHandler{diagnostics: diagnostics, resourceRuns: resourceRuns}
+}
+
+// SetRuntimeStats sets the collector behind GetRuntime
+func (h *SystemHandler) SetRuntimeStats(collect func() services.RuntimeStats) {
+	h.runtimeStats = collect
+}
+
+// SetReload sets the function behind Reload
+func (h *SystemHandler) SetReload(reload func(trigger string) (*services.ReloadReport, error)) {
+	h.reload = reload
+}
+
+// Reload re-reads the environment file, config file and settings like
+// SIGHUP does, without dropping requests in flight. Settings that need a
+// restart are listed under restart_required.
+func (h *SystemHandler) Reload(c *gin.Context) {
+	if h.reload == nil {
+		ResponseWithAPIError(c, apierrors.New(http.StatusServiceUnavailable, apierrors.CodeNotConfigured,
+			"Reloading is not available"))
+		return
+	}
+	report, err := h.reload(services.ReloadAPI)
+	if err != nil {
+		log.Printf("Settings reload failed: %v", err)
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Reload failed, previous settings kept: %v", err))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetRuntime reports goroutines, heap and GC figures, the database size and
+// connection pool, cache sizes and open file descriptors, for diagnosing
+// memory growth on long-running instances
+func (h *SystemHandler) GetRuntime(c *gin.Context) {
+	if h.runtimeStats == nil {
+		c.JSON(http.StatusOK, services.CollectRuntimeStats(nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, h.runtimeStats())
+}
+
+// GetDiagnostics runs the environment self-checks. Failed checks are part of
+// the 200 response; ok is false when any of them failed.
+func (h *SystemHandler) GetDiagnostics(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	c.JSON(http.StatusOK, h.diagnostics.Run(ctx))
+}
+
+// GetResourceWatcherRuns lists the latest resource watcher runs, newest
+// first, with their counts, skipped resources and changes. ?limit=N caps the
+// list (default 50, at most 500).
+func (h *SystemHandler) GetResourceWatcherRuns(c *gin.Context) {
+	limit := defaultWatcherRuns
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxWatcherRuns {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxWatcherRuns))
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.resourceRuns.List(limit)
+	if err != nil {
+		log.Printf("Error fetching resource watcher runs: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// TestSystemHandler_GetDiagnostics tests that failed checks are reported in a 200 response
+func TestSystemHandler_GetDiagnostics(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	handler := NewSystemHandler(services.NewDiagnostics(db.DB, nil, services.DiagnosticsOptions{
+		StaticConfigPath: func() string { return "/nonexistent/traefik.yml" },
+	}), services.NewResourceRunLog(db))
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/system/diagnostics", nil)
+	handler.GetDiagnostics(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetDiagnostics() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var report models.DiagnosticsReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.OK {
+		t.Error("expected ok=false with an unreadable static config")
+	}
+	for _, check := range report.Checks {
+		if check.Name == "database_writable" && check.Status != models.DiagnosticPass {
+			t.Errorf("database check = %+v, want pass", check)
+		}
+	}
+}
+
+// TestSystemHandler_GetResourceWatcherRuns tests the run history listing and its limit
+func TestSystemHandler_GetResourceWatcherRuns(t *testing.T) {
+	db := testutil.NewTempDB(t)
+	runs := services.NewResourceRunLog(db)
+	for _, trigger := range []string{services.RunScheduled, services.RunManual} {
+		if err := runs.Save(&services.ResourceRun{Trigger: trigger, Created: 1}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	handler := NewSystemHandler(nil, runs)
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/system/watchers/resource/runs?limit=1", nil)
+	handler.GetResourceWatcherRuns(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetResourceWatcherRuns() status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Runs []services.ResourceRun `json:"runs"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Runs) != 1 || resp.Runs[0].Trigger != services.RunManual || resp.Runs[0].Created != 1 {
+		t.Errorf("expected only the newest run, got %+v", resp.Runs)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/system/watchers/resource/runs?limit=0", nil)
+	handler.GetResourceWatcherRuns(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0 status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// TestSystemHandler_Reload tests the reload endpoint with and without a reload function
+func TestSystemHandler_Reload(t *testing.T) {
+	handler := NewSystemHandler(nil, nil)
+
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/system/reload", nil)
+	handler.Reload(c)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Reload() without a reload function status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	var trigger string
+	handler.SetReload(func(t string) (*services.ReloadReport, error) {
+		trigger = t
+		return &services.ReloadReport{Trigger: t, Applied: []string{"config_file"}, RestartRequired: []string{"PORT"}}, nil
+	})
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/system/reload", nil)
+	handler.Reload(c)
+	if rec.Code != http.StatusOK || trigger != services.ReloadAPI {
+		t.Fatalf("Reload() status = %d, trigger = %q", rec.Code, trigger)
+	}
+	var report services.ReloadReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.RestartRequired) != 1 || report.RestartRequired[0] != "PORT" {
+		t.Errorf("restart_required = %v", report.RestartRequired)
+	}
+
+	handler.SetReload(func(string) (*services.ReloadReport, error) {
+		return nil, errors.New("invalid PROXY_DISABLED_SECTIONS")
+	})
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/system/reload", nil)
+	handler.Reload(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Reload() with a failing reload status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// GetTLSCompatibility reports which common clients would fail to connect to a
+// resource using a TLS hardening profile. Query parameters: profile
+// (hardened or compat, default hardened) and key_type (rsa or ecdsa, default rsa).
+func (h *SecurityHandler) GetTLSCompatibility(c *gin.Context) {
+	profile := strings.ToLower(strings.TrimSpace(c.DefaultQuery("profile", models.TLSProfileHardened)))
+	if !models.ValidTLSProfile(profile) {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			"Unknown TLS hardening profile").
+			WithField("profile").
+			WithHint("Use \"hardened\" or \"compat\""))
+		return
+	}
+
+	keyType := strings.ToLower(strings.TrimSpace(c.DefaultQuery("key_type", services.TLSKeyTypeRSA)))
+	if keyType != services.TLSKeyTypeRSA && keyType != services.TLSKeyTypeECDSA {
+		ResponseWithAPIError(c, apierrors.New(http.StatusBadRequest, apierrors.CodeValidationFailed,
+			"Unknown certificate key type").
+			WithField("key_type").
+			WithHint("Use \"rsa\" or \"ecdsa\" to match the certificate Traefik serves"))
+		return
+	}
+
+	c.JSON(http.StatusOK, services.AnalyzeTLSCompatibility(profile, keyType))
+}
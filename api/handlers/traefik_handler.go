@@ -43,7 +43,9 @@ func (h *TraefikHandler) getFetcher() (*services.TraefikFetcher, error) {
 		}
 	}
 
-	return services.NewTraefikFetcher(config), nil
+	fetcher := services.NewTraefikFetcher(config)
+	fetcher.SetURLPersister(h.ConfigManager.PersistTraefikURL)
+	return fetcher, nil
 }
 
 // GetOverview returns the Traefik overview
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// UserHandler manages the operators of the management API and their roles
+type UserHandler struct {
+	Users *services.UserService
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(users *services.UserService) *UserHandler {
+	return &UserHandler{Users: users}
+}
+
+// GetUsers lists the users
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	users, err := h.Users.List()
+	if err != nil {
+		log.Printf("Error fetching users: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateUser adds a user; issue them a token with POST /api/tokens
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var user models.User
+	if !bindRequest(c, &user) {
+		return
+	}
+
+	user.Normalize()
+	if err := user.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid user: %v", err))
+		return
+	}
+
+	created, err := h.Users.Create(user)
+	if database.IsUniqueViolation(err) {
+		ResponseWithError(c, http.StatusConflict, fmt.Sprintf("User %s already exists", user.Username))
+		return
+	} else if err != nil {
+		log.Printf("Error creating user: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Created user %s with role %s", created.Username, created.Role)
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateUser changes a user's role
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	if !models.ValidRole(role) {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid role %q: expected %s, %s or %s",
+			req.Role, models.RoleViewer, models.RoleEditor, models.RoleAdmin))
+		return
+	}
+
+	user, err := h.Users.UpdateRole(c.Param("id"), role)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "User not found")
+		return
+	} else if err != nil {
+		log.Printf("Error updating user: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Changed the role of user %s to %s", user.Username, user.Role)
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser removes a user and revokes their tokens
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.Users.Delete(id); err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "User not found")
+		return
+	} else if err != nil {
+		log.Printf("Error deleting user: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Deleted user %s and revoked their API tokens", id)
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+)
+
+// maxMiddlewareConfigSize caps the encoded size of a single middleware config
+const maxMiddlewareConfigSize = 64 << 10
+
+// bindRequest decodes a JSON request body into obj and runs its binding
+// validation. Unknown top-level keys are rejected so typos are not silently
+// dropped. On failure it writes the error response and returns false: 400 for
+// malformed JSON, 413 for oversized bodies and 422 with per-field errors for
+// bodies that parse but do not validate.
+func bindRequest(c *gin.Context, obj interface{}) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ResponseWithAPIError(c, apierrors.New(http.StatusRequestEntityTooLarge, apierrors.CodePayloadTooLarge,
+				fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)))
+			return false
+		}
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return false
+	}
+
+	var fieldErrs []apierrors.FieldError
+	if names := jsonFieldNames(obj); names != nil {
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(body, &keys); err == nil {
+			for key := range keys {
+				if !names[strings.ToLower(key)] {
+					fieldErrs = append(fieldErrs, apierrors.FieldError{
+						Field: key, Code: "unknown_field", Message: "Unknown field",
+					})
+				}
+			}
+		}
+	}
+
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(obj); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+			return false
+		}
+		fieldErrs = append(fieldErrs, apierrors.FieldError{
+			Field: typeErr.Field, Code: "invalid_type", Message: fmt.Sprintf("Must be of type %s", typeErr.Type),
+		})
+	} else if err := binding.Validator.ValidateStruct(obj); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+			return false
+		}
+		for _, fe := range validationErrs {
+			fieldErrs = append(fieldErrs, apierrors.FieldError{
+				Field: jsonFieldPath(obj, fe.StructNamespace()), Code: fe.Tag(), Message: validationMessage(fe),
+			})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		sort.SliceStable(fieldErrs, func(i, j int) bool { return fieldErrs[i].Field < fieldErrs[j].Field })
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			"Request validation failed").WithErrors(fieldErrs))
+		return false
+	}
+	return true
+}
+
+// checkConfigSize rejects a config whose JSON encoding exceeds limit bytes
+func checkConfigSize(c *gin.Context, field string, config interface{}, limit int) bool {
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid %s: %v", field, err))
+		return false
+	}
+	if len(encoded) > limit {
+		ResponseWithAPIError(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			"Request validation failed").WithErrors([]apierrors.FieldError{{
+			Field: field, Code: "max_size", Message: fmt.Sprintf("Must encode to at most %d bytes", limit),
+		}}))
+		return false
+	}
+	return true
+}
+
+// jsonFieldNames returns the lowercased top-level JSON keys a struct accepts, or nil when
+// obj does not point to a struct
+func jsonFieldNames(obj interface{}) map[string]bool {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	collectJSONFieldNames(t, names)
+	return names
+}
+
+func collectJSONFieldNames(t reflect.Type, names map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectJSONFieldNames(ft, names)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		// encoding/json matches keys case-insensitively
+		names[strings.ToLower(name)] = true
+	}
+}
+
+// jsonFieldPath converts a validator namespace such as "input.Config.Name" into
+// the JSON path the client sent, e.g. "config.name"
+func jsonFieldPath(obj interface{}, namespace string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+
+	t := reflect.TypeOf(obj)
+	path := make([]string, 0, len(parts))
+	for _, part := range parts {
+		for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+		// Drop slice/map indexes such as Items[0] before looking the field up
+		name, index, _ := strings.Cut(part, "[")
+		if index != "" {
+			index = "[" + index
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			path = append(path, part)
+			continue
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, part)
+			t = nil
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" || jsonName == "-" {
+			jsonName = name
+		}
+		path = append(path, jsonName+index)
+		t = field.Type
+	}
+	return strings.Join(path, ".")
+}
+
+// validationMessage turns a failed binding tag into a readable sentence
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "This field is required"
+	case "min":
+		return fmt.Sprintf("Must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("Must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("Must be one of: %s", fe.Param())
+	case "url":
+		return "Must be a valid URL"
+	default:
+		return fmt.Sprintf("Failed the %q check", fe.Tag())
+	}
+}
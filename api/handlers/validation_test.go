@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestBindRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type payload struct {
+		Name     string `json:"name" binding:"required"`
+		Priority int    `json:"priority" binding:"min=0,max=1000"`
+		Nested   struct {
+			Anything string `json:"anything"`
+		} `json:"nested"`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFields []string
+	}{
+		{"valid", `{"name":"a","priority":5,"nested":{"anything":"x"}}`, http.StatusOK, nil},
+		{"malformed", `{"name":`, http.StatusBadRequest, nil},
+		{"missing required", `{"priority":5}`, http.StatusUnprocessableEntity, []string{"name"}},
+		{"out of range", `{"name":"a","priority":5000}`, http.StatusUnprocessableEntity, []string{"priority"}},
+		{"unknown key", `{"name":"a","naem":"b"}`, http.StatusUnprocessableEntity, []string{"naem"}},
+		{"wrong type", `{"name":"a","priority":"high"}`, http.StatusUnprocessableEntity, []string{"priority"}},
+		{"key case ignored", `{"Name":"a"}`, http.StatusOK, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := testutil.NewContext(t, http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			var p payload
+			if bindRequest(c, &p) {
+				c.Status(http.StatusOK)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantFields == nil {
+				return
+			}
+			var resp apierrors.APIError
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Code != apierrors.CodeValidationFailed || len(resp.Errors) != len(tt.wantFields) {
+				t.Fatalf("unexpected envelope: %+v", resp)
+			}
+			for i, field := range tt.wantFields {
+				if resp.Errors[i].Field != field {
+					t.Errorf("errors[%d].field = %q, want %q", i, resp.Errors[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareHandler_CreateMiddleware_ConfigTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewMiddlewareHandler(db.DB)
+
+	body := `{"name":"big","type":"headers","config":{"customRequestHeaders":{"X-Big":"` +
+		strings.Repeat("a", maxMiddlewareConfigSize) + `"}}}`
+	c, rec := testutil.NewContext(t, http.MethodPost, "/api/middlewares", bytes.NewBufferString(body))
+	handler.CreateMiddleware(c)
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"max_size"`) {
+		t.Fatalf("expected 422 max_size, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
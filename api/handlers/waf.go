@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// WAFHandler manages the Coraza WAF plugin and per-resource WAF settings
+type WAFHandler struct {
+	DB                      *sql.DB
+	TraefikStaticConfigPath string
+}
+
+// NewWAFHandler creates a new WAF handler
+func NewWAFHandler(db *sql.DB, traefikStaticConfigPath string) *WAFHandler {
+	return &WAFHandler{DB: db, TraefikStaticConfigPath: traefikStaticConfigPath}
+}
+
+// GetWAFConfig returns the global WAF settings and whether the plugin is installed
+func (h *WAFHandler) GetWAFConfig(c *gin.Context) {
+	cfg, err := services.LoadWAFConfig(h.DB)
+	if err != nil {
+		log.Printf("Error fetching WAF config: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch WAF config")
+		return
+	}
+
+	var protectedResources int
+	if err := h.DB.QueryRow("SELECT COUNT(*) FROM resource_waf WHERE enabled = 1").Scan(&protectedResources); err != nil {
+		log.Printf("Error counting WAF resources: %v", err)
+	}
+
+	installedVersion := h.installedPluginVersion()
+	c.JSON(http.StatusOK, gin.H{
+		"config":              cfg,
+		"plugin_installed":    installedVersion != "",
+		"installed_version":   installedVersion,
+		"protected_resources": protectedResources,
+	})
+}
+
+// UpdateWAFConfig updates the plugin version, CRS version and default paranoia level
+func (h *WAFHandler) UpdateWAFConfig(c *gin.Context) {
+	var cfg models.WAFConfig
+	if !bindRequest(c, &cfg) {
+		return
+	}
+
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid WAF config: %v", err))
+		return
+	}
+
+	cfg.UpdatedAt = time.Now()
+	if _, err := h.DB.Exec(`
+		INSERT INTO waf_config (id, plugin_module, plugin_version, crs_version, default_paranoia, updated_at)
+		VALUES (1, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET plugin_module = excluded.plugin_module, plugin_version = excluded.plugin_version,
+			crs_version = excluded.crs_version, default_paranoia = excluded.default_paranoia, updated_at = excluded.updated_at
+	`, cfg.PluginModule, cfg.PluginVersion, cfg.CRSVersion, cfg.DefaultParanoia, cfg.UpdatedAt); err != nil {
+		log.Printf("Error updating WAF config: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update WAF config")
+		return
+	}
+
+	log.Printf("Updated WAF config: plugin %s@%s, CRS v%s, default paranoia %d",
+		cfg.PluginModule, cfg.PluginVersion, cfg.CRSVersion, cfg.DefaultParanoia)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// InstallWAFPlugin adds the configured Coraza plugin version to the Traefik static configuration
+func (h *WAFHandler) InstallWAFPlugin(c *gin.Context) {
+	cfg, err := services.LoadWAFConfig(h.DB)
+	if err != nil {
+		log.Printf("Error fetching WAF config: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch WAF config")
+		return
+	}
+
+	if h.TraefikStaticConfigPath == "" {
+		ResponseWithAPIError(c, errStaticConfigPathNotSet)
+		return
+	}
+	cleanPath := filepath.Clean(h.TraefikStaticConfigPath)
+
+	staticConfig, err := readTraefikStaticConfig(cleanPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading Traefik static config %s: %v", cleanPath, err)
+			ResponseWithError(c, http.StatusInternalServerError, "Failed to read Traefik static configuration file.")
+			return
+		}
+		staticConfig = make(map[string]interface{})
+	}
+
+	experimental, ok := staticConfig["experimental"].(map[string]interface{})
+	if !ok {
+		if staticConfig["experimental"] != nil {
+			ResponseWithError(c, http.StatusInternalServerError, "Traefik static configuration 'experimental' section has an unexpected format.")
+			return
+		}
+		experimental = make(map[string]interface{})
+		staticConfig["experimental"] = experimental
+	}
+	plugins, ok := experimental["plugins"].(map[string]interface{})
+	if !ok {
+		if experimental["plugins"] != nil {
+			ResponseWithError(c, http.StatusInternalServerError, "Traefik static configuration 'plugins' section has an unexpected format.")
+			return
+		}
+		plugins = make(map[string]interface{})
+		experimental["plugins"] = plugins
+	}
+	plugins[models.WAFPluginKey] = map[string]interface{}{
+		"moduleName": cfg.PluginModule,
+		"version":    cfg.PluginVersion,
+	}
+
+	if err := writeTraefikStaticConfig(cleanPath, staticConfig); err != nil {
+		log.Printf("Error writing Traefik static config %s: %v", cleanPath, err)
+		ResponseWithError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("Configured WAF plugin %s@%s (key: %s) in %s", cfg.PluginModule, cfg.PluginVersion, models.WAFPluginKey, cleanPath)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("WAF plugin %s configured. A Traefik restart is required to load the plugin.", cfg.PluginModule),
+		"pluginKey":  models.WAFPluginKey,
+		"moduleName": cfg.PluginModule,
+		"version":    cfg.PluginVersion,
+	})
+}
+
+// GetResourceWAF returns a resource's WAF settings and the directives they generate
+func (h *WAFHandler) GetResourceWAF(c *gin.Context) {
+	id := c.Param("id")
+
+	settings, err := h.loadResourceWAF(id)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error fetching WAF settings for resource %s: %v", id, err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch WAF settings")
+		return
+	}
+	cfg, err := services.LoadWAFConfig(h.DB)
+	if err != nil {
+		log.Printf("Error fetching WAF config: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch WAF config")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings":   settings,
+		"directives": settings.Directives(cfg),
+	})
+}
+
+// UpdateResourceWAF enables or disables the WAF for a resource and sets its
+// paranoia level, log-only mode and rule exclusions
+func (h *WAFHandler) UpdateResourceWAF(c *gin.Context) {
+	id := c.Param("id")
+	var settings models.ResourceWAF
+	if !bindRequest(c, &settings) {
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid WAF settings: %v", err))
+		return
+	}
+
+	var status string
+	err := h.DB.QueryRow("SELECT status FROM resources WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Resource not found")
+		return
+	} else if err != nil {
+		log.Printf("Error checking resource existence: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	if status == "disabled" {
+		ResponseWithAPIError(c, disabledResourceError("Cannot update a disabled resource"))
+		return
+	}
+	version, ok := resourceVersion(c)
+	if !ok {
+		return
+	}
+
+	if settings.Exclusions == nil {
+		settings.Exclusions = []models.WAFExclusion{}
+	}
+	exclusions, err := json.Marshal(settings.Exclusions)
+	if err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid exclusions: %v", err))
+		return
+	}
+	enabledVal, logOnlyVal := 0, 0
+	if settings.Enabled {
+		enabledVal = 1
+	}
+	if settings.LogOnly {
+		logOnlyVal = 1
+	}
+
+	settings.ResourceID = id
+	settings.UpdatedAt = time.Now()
+	tx, err := h.DB.Begin()
+	if err != nil {
+		log.Printf("Error beginning transaction: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	defer tx.Rollback()
+
+	if bumped, err := bumpResourceVersion(tx, id, version); err != nil {
+		log.Printf("Error updating resource version: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update WAF settings")
+		return
+	} else if !bumped {
+		tx.Rollback()
+		resourceVersionConflict(c, h.DB, id)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO resource_waf (resource_id, enabled, paranoia_level, log_only, exclusions, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(resource_id) DO UPDATE SET enabled = excluded.enabled, paranoia_level = excluded.paranoia_level,
+			log_only = excluded.log_only, exclusions = excluded.exclusions, updated_at = excluded.updated_at
+	`, id, enabledVal, settings.ParanoiaLevel, logOnlyVal, string(exclusions), settings.UpdatedAt); err != nil {
+		log.Printf("Error updating WAF settings: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to update WAF settings")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing WAF settings: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+
+	log.Printf("Updated WAF for resource %s: enabled=%v log_only=%v paranoia=%d exclusions=%d",
+		id, settings.Enabled, settings.LogOnly, settings.ParanoiaLevel, len(settings.Exclusions))
+	c.Header("ETag", versionETag(version+1))
+	c.JSON(http.StatusOK, settings)
+}
+
+// loadResourceWAF returns the stored WAF settings for a resource, or disabled
+// defaults when none are stored. sql.ErrNoRows means the resource does not exist.
+func (h *WAFHandler) loadResourceWAF(id string) (models.ResourceWAF, error) {
+	settings := models.ResourceWAF{ResourceID: id, Exclusions: []models.WAFExclusion{}}
+
+	var exists int
+	if err := h.DB.QueryRow("SELECT 1 FROM resources WHERE id = ?", id).Scan(&exists); err != nil {
+		return settings, err
+	}
+
+	var enabled, logOnly int
+	var exclusions string
+	var updatedAt sql.NullTime
+	err := h.DB.QueryRow(`
+		SELECT enabled, paranoia_level, log_only, exclusions, updated_at
+		FROM resource_waf WHERE resource_id = ?
+	`, id).Scan(&enabled, &settings.ParanoiaLevel, &logOnly, &exclusions, &updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	} else if err != nil {
+		return settings, err
+	}
+
+	settings.Enabled = enabled == 1
+	settings.LogOnly = logOnly == 1
+	if updatedAt.Valid {
+		settings.UpdatedAt = updatedAt.Time
+	}
+	if err := json.Unmarshal([]byte(exclusions), &settings.Exclusions); err != nil {
+		log.Printf("Invalid WAF exclusions stored for resource %s: %v", id, err)
+	}
+	return settings, nil
+}
+
+// installedPluginVersion returns the Coraza plugin version configured in the
+// static config, or "" when it is not installed or the file cannot be read
+func (h *WAFHandler) installedPluginVersion() string {
+	if h.TraefikStaticConfigPath == "" {
+		return ""
+	}
+	staticConfig, err := readTraefikStaticConfig(filepath.Clean(h.TraefikStaticConfigPath))
+	if err != nil {
+		return ""
+	}
+	experimental, _ := staticConfig["experimental"].(map[string]interface{})
+	plugins, _ := experimental["plugins"].(map[string]interface{})
+	plugin, _ := plugins[models.WAFPluginKey].(map[string]interface{})
+	if plugin == nil {
+		return ""
+	}
+	if version, ok := plugin["version"].(string); ok && version != "" {
+		return version
+	}
+	return "unknown"
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestWAFHandler_ResourceSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	handler := NewWAFHandler(db.DB, "")
+
+	testutil.MustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`)
+
+	update := func(body string) int {
+		t.Helper()
+		c, rec := testutil.NewContext(t, http.MethodPut, "/api/resources/res-1/config/waf", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+		setResourceVersion(t, db, c, "res-1")
+		handler.UpdateResourceWAF(c)
+		return rec.Code
+	}
+
+	if code := update(`{"enabled":true,"paranoia_level":9}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid paranoia level, got %d", code)
+	}
+	body := `{"enabled":true,"log_only":true,"paranoia_level":2,"exclusions":[{"rule_ids":[942100],"paths":["/api"]}]}`
+	if code := update(body); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	c, rec := testutil.NewContext(t, http.MethodGet, "/api/resources/res-1/waf", nil)
+	c.Params = gin.Params{{Key: "id", Value: "res-1"}}
+	handler.GetResourceWAF(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Settings struct {
+			Enabled    bool `json:"enabled"`
+			LogOnly    bool `json:"log_only"`
+			Exclusions []struct {
+				RuleIDs []int `json:"rule_ids"`
+			} `json:"exclusions"`
+		} `json:"settings"`
+		Directives []string `json:"directives"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if !resp.Settings.Enabled || !resp.Settings.LogOnly || len(resp.Settings.Exclusions) != 1 {
+		t.Fatalf("unexpected settings: %+v", resp.Settings)
+	}
+	if !strings.Contains(strings.Join(resp.Directives, "\n"), "SecRuleEngine DetectionOnly") {
+		t.Errorf("log-only mode not reflected in directives: %v", resp.Directives)
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/resources/missing/waf", nil)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+	handler.GetResourceWAF(c)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing resource, got %d", rec.Code)
+	}
+}
+
+func TestWAFHandler_InstallPlugin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewTempDB(t)
+	staticPath := filepath.Join(t.TempDir(), "traefik.yml")
+	if err := os.WriteFile(staticPath, []byte("entryPoints:\n  web:\n    address: \":80\"\n"), 0644); err != nil {
+		t.Fatalf("write static config: %v", err)
+	}
+	handler := NewWAFHandler(db.DB, staticPath)
+
+	c, rec := testutil.NewContext(t, http.MethodPut, "/api/waf",
+		bytes.NewBufferString(`{"plugin_module":"github.com/jcchavezs/coraza-http-wasm-traefik","plugin_version":"v0.2.2","crs_version":"4","default_paranoia":2}`))
+	handler.UpdateWAFConfig(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 updating config, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodPost, "/api/waf/install", nil)
+	handler.InstallWAFPlugin(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 installing plugin, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	c, rec = testutil.NewContext(t, http.MethodGet, "/api/waf", nil)
+	handler.GetWAFConfig(c)
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp["plugin_installed"] != true || resp["installed_version"] != "v0.2.2" {
+		t.Fatalf("unexpected WAF status: %v", resp)
+	}
+
+	data, err := os.ReadFile(staticPath)
+	if err != nil {
+		t.Fatalf("read static config: %v", err)
+	}
+	if !strings.Contains(string(data), "address: :80") && !strings.Contains(string(data), `address: ":80"`) {
+		t.Errorf("existing static config was not preserved:\n%s", data)
+	}
+}
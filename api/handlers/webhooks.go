@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+// WebhookHandler manages the webhooks events are posted to and their delivery log
+type WebhookHandler struct {
+	Webhooks *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhooks *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{Webhooks: webhooks}
+}
+
+// GetWebhooks lists the webhooks with their secrets masked
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.Webhooks.List()
+	if err != nil {
+		log.Printf("Error fetching webhooks: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].MaskSecret()
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "events": models.WebhookEvents})
+}
+
+// CreateWebhook adds a webhook; it is enabled unless the request says otherwise
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	webhook := models.Webhook{Enabled: true}
+	if !bindRequest(c, &webhook) {
+		return
+	}
+
+	webhook.Normalize()
+	if err := webhook.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid webhook: %v", err))
+		return
+	}
+
+	created, err := h.Webhooks.Create(webhook)
+	if err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Created webhook %s for %s", created.Name, created.URL)
+	created.MaskSecret()
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateWebhook replaces a webhook's settings
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var webhook models.Webhook
+	if !bindRequest(c, &webhook) {
+		return
+	}
+
+	webhook.Normalize()
+	if err := webhook.Validate(); err != nil {
+		ResponseWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid webhook: %v", err))
+		return
+	}
+
+	updated, err := h.Webhooks.Update(c.Param("id"), webhook)
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Webhook not found")
+		return
+	} else if err != nil {
+		log.Printf("Error updating webhook: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Updated webhook %s", updated.Name)
+	updated.MaskSecret()
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.Webhooks.Delete(id); err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Webhook not found")
+		return
+	} else if err != nil {
+		log.Printf("Error deleting webhook: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	log.Printf("Deleted webhook %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// TestWebhook queues a webhook.test delivery; follow it in the delivery log
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	delivery, err := h.Webhooks.Test(c.Param("id"))
+	if err == sql.ErrNoRows {
+		ResponseWithError(c, http.StatusNotFound, "Webhook not found")
+		return
+	} else if err != nil {
+		log.Printf("Error queuing webhook test: %v", err)
+		ResponseWithAPIError(c, errDatabase)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// GetDeliveries lists the latest deliveries, newest first, of one webhook
+// or, without an id, of every webhook; ?limit= caps the count (default 100)
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id := c.Param("id")
+	if id != "" {
+		if _, err := h.Webhooks.Get(id); err == sql.ErrNoRows {
+			ResponseWithError(c, http.StatusNotFound, "Webhook not found")
+			return
+		} else if err != nil {
+			log.Printf("Error fetching webhook: %v", err)
+			ResponseWithAPIError(c, errDatabase)
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	deliveries, err := h.Webhooks.Deliveries(id, limit)
+	if err != nil {
+		log.Printf("Error fetching webhook deliveries: %v", err)
+		ResponseWithError(c, http.StatusInternalServerError, "Failed to fetch webhook deliveries")
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
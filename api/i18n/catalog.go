@@ -0,0 +1,81 @@
+package i18n
+
+// catalogs maps a language to translations keyed by the English message.
+// Keys must match the strings handlers pass to ResponseWithError exactly.
+var catalogs = map[string]map[string]string{
+	"de": {
+		"Database error":                                  "Datenbankfehler",
+		"Invalid request":                                 "Ungültige Anfrage",
+		"Resource not found":                              "Ressource nicht gefunden",
+		"Resource ID is required":                         "Ressourcen-ID ist erforderlich",
+		"Cannot update a disabled resource":               "Eine deaktivierte Ressource kann nicht geändert werden",
+		"Cannot assign middleware to a disabled resource": "Einer deaktivierten Ressource kann keine Middleware zugewiesen werden",
+		"Only disabled resources can be deleted":          "Nur deaktivierte Ressourcen können gelöscht werden",
+		"Failed to delete resource":                       "Ressource konnte nicht gelöscht werden",
+		"Failed to fetch resources":                       "Ressourcen konnten nicht geladen werden",
+		"Failed to generate ID":                           "ID konnte nicht erzeugt werden",
+		"Middleware not found":                            "Middleware nicht gefunden",
+		"Middleware ID is required":                       "Middleware-ID ist erforderlich",
+		"Failed to fetch middlewares":                     "Middlewares konnten nicht geladen werden",
+		"Failed to assign middleware":                     "Middleware konnte nicht zugewiesen werden",
+		"Service not found":                               "Dienst nicht gefunden",
+		"Service ID is required":                          "Dienst-ID ist erforderlich",
+		"Failed to fetch services":                        "Dienste konnten nicht geladen werden",
+		"Plugin name is required":                         "Plugin-Name ist erforderlich",
+		"Data source name is required":                    "Name der Datenquelle ist erforderlich",
+		"Failed to get data source configuration":         "Konfiguration der Datenquelle konnte nicht gelesen werden",
+		"Traefik static configuration file not found":     "Statische Traefik-Konfigurationsdatei nicht gefunden",
+		"Unauthorized access":                             "Nicht autorisierter Zugriff",
+		"Access forbidden":                                "Zugriff verweigert",
+	},
+	"fr": {
+		"Database error":                                  "Erreur de base de données",
+		"Invalid request":                                 "Requête invalide",
+		"Resource not found":                              "Ressource introuvable",
+		"Resource ID is required":                         "L'identifiant de la ressource est requis",
+		"Cannot update a disabled resource":               "Impossible de modifier une ressource désactivée",
+		"Cannot assign middleware to a disabled resource": "Impossible d'assigner un middleware à une ressource désactivée",
+		"Only disabled resources can be deleted":          "Seules les ressources désactivées peuvent être supprimées",
+		"Failed to delete resource":                       "Échec de la suppression de la ressource",
+		"Failed to fetch resources":                       "Échec du chargement des ressources",
+		"Failed to generate ID":                           "Échec de la génération de l'identifiant",
+		"Middleware not found":                            "Middleware introuvable",
+		"Middleware ID is required":                       "L'identifiant du middleware est requis",
+		"Failed to fetch middlewares":                     "Échec du chargement des middlewares",
+		"Failed to assign middleware":                     "Échec de l'assignation du middleware",
+		"Service not found":                               "Service introuvable",
+		"Service ID is required":                          "L'identifiant du service est requis",
+		"Failed to fetch services":                        "Échec du chargement des services",
+		"Plugin name is required":                         "Le nom du plugin est requis",
+		"Data source name is required":                    "Le nom de la source de données est requis",
+		"Failed to get data source configuration":         "Impossible de lire la configuration de la source de données",
+		"Traefik static configuration file not found":     "Fichier de configuration statique Traefik introuvable",
+		"Unauthorized access":                             "Accès non autorisé",
+		"Access forbidden":                                "Accès interdit",
+	},
+	"es": {
+		"Database error":                                  "Error de base de datos",
+		"Invalid request":                                 "Solicitud no válida",
+		"Resource not found":                              "Recurso no encontrado",
+		"Resource ID is required":                         "El ID del recurso es obligatorio",
+		"Cannot update a disabled resource":               "No se puede modificar un recurso deshabilitado",
+		"Cannot assign middleware to a disabled resource": "No se puede asignar un middleware a un recurso deshabilitado",
+		"Only disabled resources can be deleted":          "Solo se pueden eliminar recursos deshabilitados",
+		"Failed to delete resource":                       "No se pudo eliminar el recurso",
+		"Failed to fetch resources":                       "No se pudieron cargar los recursos",
+		"Failed to generate ID":                           "No se pudo generar el ID",
+		"Middleware not found":                            "Middleware no encontrado",
+		"Middleware ID is required":                       "El ID del middleware es obligatorio",
+		"Failed to fetch middlewares":                     "No se pudieron cargar los middlewares",
+		"Failed to assign middleware":                     "No se pudo asignar el middleware",
+		"Service not found":                               "Servicio no encontrado",
+		"Service ID is required":                          "El ID del servicio es obligatorio",
+		"Failed to fetch services":                        "No se pudieron cargar los servicios",
+		"Plugin name is required":                         "El nombre del plugin es obligatorio",
+		"Data source name is required":                    "El nombre de la fuente de datos es obligatorio",
+		"Failed to get data source configuration":         "No se pudo leer la configuración de la fuente de datos",
+		"Traefik static configuration file not found":     "No se encontró el archivo de configuración estática de Traefik",
+		"Unauthorized access":                             "Acceso no autorizado",
+		"Access forbidden":                                "Acceso prohibido",
+	},
+}
@@ -0,0 +1,85 @@
+// Package i18n translates user-facing API error messages.
+//
+// Messages are looked up by their English text, so handlers keep passing plain
+// English strings and untranslated messages fall back to English unchanged.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language handlers write their messages in
+const DefaultLanguage = "en"
+
+// Languages returns the supported language tags, English first
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return append([]string{DefaultLanguage}, langs...)
+}
+
+// Negotiate picks the best supported language for an Accept-Language header
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{tag, q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		// Match "de-AT" against the "de" catalog
+		base := strings.SplitN(c.lang, "-", 2)[0]
+		if base == DefaultLanguage {
+			return DefaultLanguage
+		}
+		if _, ok := catalogs[base]; ok {
+			return base
+		}
+	}
+	return DefaultLanguage
+}
+
+// Translate returns message in the given language. Messages of the form
+// "<known prefix>: <details>" have only the prefix translated, since details
+// usually carry error text or identifiers.
+func Translate(lang, message string) string {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		return message
+	}
+	if translated, ok := catalog[message]; ok {
+		return translated
+	}
+	if prefix, rest, found := strings.Cut(message, ": "); found {
+		if translated, ok := catalog[prefix]; ok {
+			return translated + ": " + rest
+		}
+	}
+	return message
+}
@@ -0,0 +1,53 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-AT,de;q=0.9,en;q=0.8", "de"},
+		{"ja,fr;q=0.5", "fr"},
+		{"en-US,fr;q=0.9", "en"},
+		{"fr;q=0.2,es;q=0.8", "es"},
+		{"fr;q=0", "en"},
+		{"ja", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("de", "Resource not found"); got != "Ressource nicht gefunden" {
+		t.Errorf("exact match = %q", got)
+	}
+	if got := Translate("fr", "Invalid request: EOF"); got != "Requête invalide: EOF" {
+		t.Errorf("prefix match = %q", got)
+	}
+	if got := Translate("es", "Something unknown"); got != "Something unknown" {
+		t.Errorf("unknown message = %q", got)
+	}
+	if got := Translate("en", "Resource not found"); got != "Resource not found" {
+		t.Errorf("english = %q", got)
+	}
+}
+
+func TestCatalogsCoverSameMessages(t *testing.T) {
+	reference := catalogs["de"]
+	for lang, catalog := range catalogs {
+		if len(catalog) != len(reference) {
+			t.Errorf("catalog %s has %d messages, de has %d", lang, len(catalog), len(reference))
+		}
+		for key := range reference {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("catalog %s is missing %q", lang, key)
+			}
+		}
+	}
+}
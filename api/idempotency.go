@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
+	"github.com/hhftechnology/middleware-manager/cache"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make a POST safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the header so keys cannot bloat the cache
+const maxIdempotencyKeyLength = 255
+
+// idempotencyExemptRoutes return a secret once; their responses are never
+// stored, so a replay cannot hand the secret to anyone again
+var idempotencyExemptRoutes = []string{
+	"/api/tokens",
+	"/api/secrets/rotate",
+	"/api/resources/*/forward-auth/tokens",
+	"/api/resources/*/forward-auth/tokens/*/rotate",
+}
+
+// idempotentEntry is a reserved or completed request in the replay cache
+type idempotentEntry struct {
+	fingerprint string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+}
+
+// IdempotencyCache replays the response of a POST request retried with the
+// same Idempotency-Key, so retries after a lost response cannot create duplicates
+type IdempotencyCache struct {
+	entries *cache.Cache
+	ttl     time.Duration
+}
+
+// NewIdempotencyCache creates a replay cache keeping responses for ttl
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{entries: cache.New(), ttl: ttl}
+}
+
+// Len returns the number of stored responses
+func (ic *IdempotencyCache) Len() int {
+	return ic.entries.Len()
+}
+
+// Stop stops the cache's cleanup goroutine
+func (ic *IdempotencyCache) Stop() {
+	ic.entries.Stop()
+}
+
+// Middleware returns a Gin middleware handling Idempotency-Key on POST requests.
+// The first request with a key runs normally and its response is stored; a
+// retry with the same key and body gets the stored response back, a retry while
+// the first is still running gets 409, and reusing a key for a different
+// request gets 422. Server errors are not stored so the request can be retried.
+// Keys are scoped to the API token of the caller, so one caller cannot replay
+// another's response. Routes returning secrets ignore the key.
+func (ic *IdempotencyCache) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" || routeMatches(c.Request.URL.Path, idempotencyExemptRoutes) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			apierrors.HandleAPIError(c, http.StatusBadRequest, "Idempotency-Key is too long", nil)
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				status := http.StatusBadRequest
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					status = http.StatusRequestEntityTooLarge
+				}
+				apierrors.HandleAPIError(c, status, "Failed to read request body", err)
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		sum := sha256.Sum256(append([]byte(c.Request.URL.Path+"\n"), body...))
+		entry := &idempotentEntry{fingerprint: hex.EncodeToString(sum[:])}
+		cacheKey := idempotencyCaller(c) + "|" + c.Request.URL.Path + "|" + key
+
+		if !ic.entries.SetIfNotExists(cacheKey, entry, ic.ttl) {
+			ic.replay(c, cacheKey, entry.fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			ic.entries.Delete(cacheKey)
+			return
+		}
+		ic.entries.Set(cacheKey, &idempotentEntry{
+			fingerprint: entry.fingerprint,
+			done:        true,
+			status:      status,
+			contentType: recorder.Header().Get("Content-Type"),
+			body:        recorder.body.Bytes(),
+		}, ic.ttl)
+	}
+}
+
+// idempotencyCaller identifies the caller by the ID of its API token; requests
+// without one, when authentication is off, share one scope
+func idempotencyCaller(c *gin.Context) string {
+	if value, ok := c.Get(apiTokenContextKey); ok {
+		if token, ok := value.(*models.APIToken); ok && token != nil {
+			return token.ID
+		}
+	}
+	return ""
+}
+
+// replay answers a request whose key is already in the cache
+func (ic *IdempotencyCache) replay(c *gin.Context, cacheKey, fingerprint string) {
+	defer c.Abort()
+
+	value, ok := ic.entries.Get(cacheKey)
+	if !ok {
+		// Expired between the reservation attempt and now; treat as in progress
+		apierrors.Respond(c, apierrors.New(http.StatusConflict, apierrors.CodeConflict,
+			"A request with this Idempotency-Key is still in progress"))
+		return
+	}
+	stored := value.(*idempotentEntry)
+	if stored.fingerprint != fingerprint {
+		apierrors.Respond(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed,
+			"Idempotency-Key was already used for a different request").WithField(IdempotencyKeyHeader))
+		return
+	}
+	if !stored.done {
+		apierrors.Respond(c, apierrors.New(http.StatusConflict, apierrors.CodeConflict,
+			"A request with this Idempotency-Key is still in progress").WithHint("Retry once the first request finishes."))
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(stored.status, stored.contentType, stored.body)
+}
+
+// responseRecorder copies the response body while it is written to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ic := NewIdempotencyCache(time.Minute)
+	defer ic.Stop()
+
+	created := 0
+	failNext := false
+	router := gin.New()
+	router.Use(ic.Middleware())
+	router.POST("/items", func(c *gin.Context) {
+		if failNext {
+			failNext = false
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "boom"})
+			return
+		}
+		created++
+		c.JSON(http.StatusCreated, gin.H{"n": created})
+	})
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post("k1", `{"name":"a"}`)
+	retry := post("k1", `{"name":"a"}`)
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated {
+		t.Fatalf("expected 201 twice, got %d and %d", first.Code, retry.Code)
+	}
+	if created != 1 || retry.Body.String() != first.Body.String() {
+		t.Fatalf("retry must replay the first response: created=%d body=%s", created, retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replayed response should be marked")
+	}
+
+	if rec := post("k1", `{"name":"b"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reusing a key for another body: expected 422, got %d", rec.Code)
+	}
+
+	post("", `{"name":"a"}`)
+	post("", `{"name":"a"}`)
+	if created != 3 {
+		t.Errorf("requests without a key must not be deduplicated, created=%d", created)
+	}
+
+	failNext = true
+	if rec := post("k2", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if rec := post("k2", `{}`); rec.Code != http.StatusCreated {
+		t.Errorf("server errors must not be replayed, got %d", rec.Code)
+	}
+}
+
+func TestIdempotencyCacheScopesCallersAndSkipsSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ic := NewIdempotencyCache(time.Minute)
+	defer ic.Stop()
+
+	created := 0
+	router := gin.New()
+	// Stands in for apiAuth, which stores the verified token
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Token-ID"); id != "" {
+			c.Set(apiTokenContextKey, &models.APIToken{ID: id})
+		}
+	})
+	router.Use(ic.Middleware())
+	handler := func(c *gin.Context) {
+		created++
+		c.JSON(http.StatusCreated, gin.H{"n": created})
+	}
+	router.POST("/api/middlewares", handler)
+	router.POST("/api/tokens", handler)
+	router.POST("/api/resources/:id/forward-auth/tokens", handler)
+
+	post := func(path, tokenID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		req.Header.Set("X-Token-ID", tokenID)
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	post("/api/middlewares", "alice")
+	if rec := post("/api/middlewares", "bob"); rec.Header().Get("Idempotent-Replayed") != "" || created != 2 {
+		t.Errorf("another caller's key was replayed: created=%d body=%s", created, rec.Body.String())
+	}
+	if rec := post("/api/middlewares", "alice"); rec.Header().Get("Idempotent-Replayed") != "true" || created != 2 {
+		t.Errorf("same caller's retry was not replayed: created=%d", created)
+	}
+
+	for _, path := range []string{"/api/tokens", "/api/resources/r1/forward-auth/tokens"} {
+		before := created
+		post(path, "alice")
+		if rec := post(path, "alice"); rec.Header().Get("Idempotent-Replayed") != "" || created != before+2 {
+			t.Errorf("%s response was stored: created=%d", path, created-before)
+		}
+	}
+	if ic.Len() != 2 {
+		t.Errorf("expected only the middleware responses stored, got %d", ic.Len())
+	}
+}
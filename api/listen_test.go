@@ -0,0 +1,57 @@
+package api
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket permissions are not POSIX modes on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run", "mm.sock")
+
+	listener, err := listenUnix(path, 0600)
+	if err != nil {
+		t.Fatalf("listenUnix: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&fs.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected a 0600 socket, got %v, %v", info, err)
+	}
+
+	// A socket still accepting connections is not taken over
+	if _, err := listenUnix(path, 0600); err == nil {
+		t.Fatalf("expected a socket in use to be rejected")
+	}
+	listener.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected closing the listener to remove the socket, got %v", err)
+	}
+
+	// A socket left behind by a crashed process is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	listener, err = listenUnix(path, 0660)
+	if err != nil {
+		t.Fatalf("expected a stale socket to be replaced: %v", err)
+	}
+	listener.Close()
+
+	regular := filepath.Join(dir, "file")
+	if err := os.WriteFile(regular, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnix(regular, 0660); err == nil {
+		t.Fatalf("expected a regular file not to be replaced")
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerPprof serves the net/http/pprof profiles under group, e.g.
+// /api/system/pprof/heap. The index links to the profiles relatively, so it
+// works outside /debug/pprof.
+func registerPprof(group *gin.RouterGroup) {
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", limitProfileSeconds, gin.WrapF(pprof.Profile))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, profile := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		group.GET("/"+profile, gin.WrapH(pprof.Handler(profile)))
+	}
+}
+
+// limitProfileSeconds shortens a CPU profile, 30 seconds unless asked
+// otherwise, to end before the server's write timeout. pprof refuses longer
+// ones, and gin's writer does not let it move the write deadline.
+func limitProfileSeconds(c *gin.Context) {
+	srv, ok := c.Request.Context().Value(http.ServerContextKey).(*http.Server)
+	if !ok || srv.WriteTimeout <= 0 {
+		return
+	}
+	limit := int64(math.Ceil(srv.WriteTimeout.Seconds())) - 1
+	if limit < 1 {
+		limit = 1
+	}
+
+	seconds, err := strconv.ParseInt(c.Query("seconds"), 10, 64)
+	if err != nil || seconds <= 0 {
+		seconds = 30
+	}
+	if seconds > limit {
+		query := c.Request.URL.Query()
+		query.Set("seconds", strconv.FormatInt(limit, 10))
+		c.Request.URL.RawQuery = query.Encode()
+	}
+}
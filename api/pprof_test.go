@@ -0,0 +1,43 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hhftechnology/middleware-manager/internal/testutil"
+)
+
+func TestPprofProfileFitsTheWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	srv := NewServer(db, ServerConfig{Port: "0", Pprof: true}, cm, filepath.Join(t.TempDir(), "traefik.yml"))
+
+	ts := httptest.NewUnstartedServer(srv.router)
+	// Two seconds keeps the default profile of this test short
+	ts.Config.WriteTimeout = 2 * time.Second
+	ts.Start()
+	defer ts.Close()
+
+	for _, query := range []string{"?seconds=1", ""} {
+		start := time.Now()
+		resp, err := http.Get(ts.URL + "/api/system/pprof/profile" + query)
+		if err != nil {
+			t.Fatalf("profile%s: %v", query, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK || len(body) == 0 {
+			t.Fatalf("profile%s: expected a profile, got %d (%v): %s", query, resp.StatusCode, err, body)
+		}
+		if elapsed := time.Since(start); elapsed >= ts.Config.WriteTimeout {
+			t.Errorf("profile%s took %s, past the write timeout", query, elapsed)
+		}
+	}
+}
@@ -3,18 +3,23 @@ package api
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
+	apierrors "github.com/hhftechnology/middleware-manager/api/errors"
 	"github.com/hhftechnology/middleware-manager/api/handlers"
 	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
 	"github.com/hhftechnology/middleware-manager/services"
 )
 
@@ -32,20 +37,138 @@ type Server struct {
 	traefikHandler          *handlers.TraefikHandler
 	mtlsHandler             *handlers.MTLSHandler
 	securityHandler         *handlers.SecurityHandler
+	headerPolicyHandler     *handlers.HeaderPolicyHandler
+	corsHandler             *handlers.CORSHandler
+	forwardAuthHandler      *handlers.ForwardAuthHandler
+	secretsHandler          *handlers.SecretsHandler
+	scopeHandler            *handlers.ScopeHandler
+	protectedHandler        *handlers.ProtectedHandler
+	maintenanceHandler      *handlers.MaintenanceHandler
+	redirectHandler         *handlers.RedirectHandler
+	wafHandler              *handlers.WAFHandler
+	botListHandler          *handlers.BotListHandler
+	mirrorHandler           *handlers.MirrorHandler
+	failoverHandler         *handlers.FailoverHandler
+	deploymentHandler       *handlers.DeploymentHandler
+	serviceOverrideHandler  *handlers.ServiceOverrideHandler
+	entrypointMWHandler     *handlers.EntrypointMiddlewareHandler
+	defaultChainHandler     *handlers.DefaultChainHandler
+	reportHandler           *handlers.ReportHandler
+	captureHandler          *handlers.CaptureHandler
+	metadataHandler         *handlers.MetadataHandler
+	assignmentHandler       *handlers.AssignmentHandler
 	proxyHandler            *handlers.ProxyHandler
+	systemHandler           *handlers.SystemHandler
+	setupHandler            *handlers.SetupHandler
+	resourceReviewHandler   *handlers.ResourceReviewHandler
+	resourceSyncHandler     *handlers.ResourceSyncHandler
+	backupHandler           *handlers.BackupHandler
+	apiTokenHandler         *handlers.APITokenHandler
+	userHandler             *handlers.UserHandler
+	webhookHandler          *handlers.WebhookHandler
+	environmentHandler      *handlers.EnvironmentHandler
 	configManager           *services.ConfigManager
 	configProxy             *services.ConfigProxy
+	diagnostics             *services.Diagnostics
+	secretRotator           *services.SecretRotator
+	botListUpdater          *services.BotListUpdater
+	trafficCapturer         *services.TrafficCapturer
+	blueGreenDeployer       *services.BlueGreenDeployer
+	assignmentExpirer       *services.AssignmentExpirer
+	webhooks                *services.WebhookService
+	localBackups            *services.Backups
+	localBackupInterval     time.Duration
+	s3Backups               *services.Backups
+	s3BackupInterval        time.Duration
+	idempotency             *IdempotencyCache
+	auth                    *apiAuth
 	traefikStaticConfigPath string
+	socketPath              string
+	socketMode              os.FileMode
+	socketOnly              bool
 }
 
 // ServerConfig contains configuration options for the server
 type ServerConfig struct {
-	Port        string
-	UIPath      string
-	Debug       bool
-	AllowCORS   bool
-	CORSOrigin  string
-	PangolinURL string // URL for Pangolin API (for config proxy)
+	Port           string
+	UIPath         string
+	Debug          bool
+	AllowCORS      bool
+	CORSOrigin     string
+	PangolinURL    string // URL for Pangolin API (for config proxy)
+	ForwardAuthURL string // Base URL Traefik uses to reach this server (for built-in forwardAuth)
+	ErrorBudget    int    // Validation errors tolerated in the merged config before falling back to last-known-good
+	TraefikVersion string // Pins the Traefik version generated config targets (empty means detect)
+	AccessLogPath  string // Traefik JSON access log read by traffic captures (empty disables them)
+	TraefikConfDir string // Directory the file config generator writes to (checked by diagnostics)
+	FileConfig     bool   // Whether the file config generator is enabled
+
+	// ConfigLimits are the size and complexity limits the merged config is warned about
+	ConfigLimits services.ConfigLimits
+
+	// MergeSections turns off MM's changes to the TCP, UDP or TLS sections of the served config
+	MergeSections services.MergeSections
+
+	// TransformLog records what each merge changed in the upstream config
+	TransformLog bool
+
+	// Chaos allows injecting faults into the config endpoint; for test instances only
+	Chaos bool
+
+	// PangolinBreakerThreshold consecutive failed Pangolin fetches open the circuit breaker (0 disables it)
+	PangolinBreakerThreshold int
+	// PangolinBreakerCooldown is how long an open breaker waits before probing Pangolin again
+	PangolinBreakerCooldown time.Duration
+
+	// ConfigWebhook posts the served config to an external consumer when it changes (empty URL disables it)
+	ConfigWebhook services.ConfigWebhookSettings
+
+	// BackupDir is the directory local database snapshots are kept in (empty disables them)
+	BackupDir string
+	// BackupInterval schedules local backups (0 only backs up on demand)
+	BackupInterval time.Duration
+	// BackupKeep is how many local backups are kept (0 keeps all)
+	BackupKeep int
+
+	// S3Backup is the bucket database snapshots and config exports are backed up to (empty endpoint disables it)
+	S3Backup services.S3Settings
+	// S3BackupInterval schedules S3 backups (0 only backs up on demand)
+	S3BackupInterval time.Duration
+	// BackupPassphrase encrypts backups, which hold CA keys and secrets (empty stores them in the clear)
+	BackupPassphrase string
+
+	// Pprof serves the Go profiler under /api/system/pprof for diagnosing memory growth
+	Pprof bool
+
+	// DNSDiscoveryServer is the nameserver dnsDiscovery services are resolved with (empty uses /etc/resolv.conf)
+	DNSDiscoveryServer string
+
+	// AuthMode is off, writes (mutating requests need an admin token) or all
+	// (every request needs a token); empty means off
+	AuthMode string
+	// AdminToken is a fixed admin token accepted besides the stored ones (empty disables it)
+	AdminToken string
+
+	// SocketPath is a Unix socket the API is served on besides the TCP port (empty disables it)
+	SocketPath string
+	// SocketMode is the socket's permission bits (0 uses DefaultSocketMode)
+	SocketMode os.FileMode
+	// SocketOnly serves the API on SocketPath alone, without the TCP port
+	SocketOnly bool
+
+	// Reload re-reads the settings without a restart (nil disables POST /api/system/reload)
+	Reload func(trigger string) (*services.ReloadReport, error)
+
+	// ResourceWatcher runs manual resource syncs (nil disables them)
+	ResourceWatcher *services.ResourceWatcher
+	// ServiceWatcher is rebuilt after a backup restore (nil skips it)
+	ServiceWatcher *services.ServiceWatcher
+
+	// CertSigner issues mTLS client certificates from an external CA (nil uses the CA in the database)
+	CertSigner services.CertSigner
+
+	// Webhooks stores the webhooks and delivers their events (nil creates one)
+	Webhooks *services.WebhookService
 }
 
 // NewServer creates a new API server
@@ -81,18 +204,34 @@ func NewServer(dbWrapper *database.DB, config ServerConfig, configManager *servi
 		}
 
 		corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-		corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-		corsConfig.ExposeHeaders = []string{"Content-Length"}
+		corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", IdempotencyKeyHeader}
+		corsConfig.ExposeHeaders = []string{"Content-Length", "Idempotent-Replayed"}
 		corsConfig.AllowCredentials = true
 		corsConfig.MaxAge = 12 * time.Hour
 
 		router.Use(cors.New(corsConfig))
 	}
 
+	// Cap request bodies; handlers report oversized bodies as 413
+	router.Use(requestSizeLimit(maxRequestBodySize))
+
+	// Check API tokens before anything is replayed or handled
+	apiTokens := services.NewAPITokenService(dbWrapper)
+	apiTokens.SetAdminToken(config.AdminToken)
+	auth := &apiAuth{tokens: apiTokens}
+	auth.setMode(config.AuthMode)
+	auth.logMode()
+	router.Use(auth.Middleware())
+
+	// Replay responses of POST requests retried with the same Idempotency-Key
+	idempotency := NewIdempotencyCache(24 * time.Hour)
+	router.Use(idempotency.Middleware())
+
 	// Create request handlers
 	middlewareHandler := handlers.NewMiddlewareHandler(db)
 	resourceHandler := handlers.NewResourceHandler(db)
 	configHandler := handlers.NewConfigHandler(db)
+	configHandler.SetTraefikConfigPath(traefikStaticConfigPath)
 	dataSourceHandler := handlers.NewDataSourceHandler(configManager)
 	serviceHandler := handlers.NewServiceHandler(db)
 	// Initialize PluginHandler with ConfigManager for Traefik API access
@@ -102,14 +241,162 @@ func NewServer(dbWrapper *database.DB, config ServerConfig, configManager *servi
 	// Initialize MTLSHandler for mTLS certificate management
 	mtlsHandler := handlers.NewMTLSHandler(db)
 	mtlsHandler.SetTraefikConfigPath(traefikStaticConfigPath)
+	if config.CertSigner != nil {
+		mtlsHandler.CertGenerator.SetSigner(config.CertSigner)
+	}
 
 	// Initialize SecurityHandler for security features (TLS hardening, secure headers, duplicate detection)
 	securityHandler := handlers.NewSecurityHandler(db, configManager)
 
+	// Initialize header policy and CORS handlers (both render into the per-resource headers middleware)
+	headerPolicyHandler := handlers.NewHeaderPolicyHandler(db)
+	corsHandler := handlers.NewCORSHandler(db)
+
 	// Initialize ConfigProxy for Traefik config proxying
 	configProxy := services.NewConfigProxy(dbWrapper, configManager, config.PangolinURL)
+	configProxy.SetForwardAuthURL(config.ForwardAuthURL)
+	configProxy.SetErrorBudget(config.ErrorBudget)
+	configProxy.SetConfigLimits(config.ConfigLimits)
+	configProxy.SetMergeSections(config.MergeSections)
+	configProxy.SetTransformLog(config.TransformLog)
+	configProxy.SetChaosMode(config.Chaos)
+	configProxy.SetDNSDiscoveryServer(config.DNSDiscoveryServer)
+	configProxy.SetConfigWebhook(config.ConfigWebhook)
+	configProxy.SetPangolinCircuitBreaker(config.PangolinBreakerThreshold, config.PangolinBreakerCooldown)
+	configProxy.SetTraefikVersion(config.TraefikVersion)
+	configProxy.SetTraefikStaticConfigPath(traefikStaticConfigPath)
+	resourceHandler.SetConfigProxy(configProxy)
+
+	// Webhooks are told about middleware changes and failed merges; delivery runs with the server
+	webhooks := config.Webhooks
+	if webhooks == nil {
+		webhooks = services.NewWebhookService(dbWrapper)
+	}
+	resourceHandler.SetWebhooks(webhooks)
+	configProxy.SetWebhooks(webhooks)
 	proxyHandler := handlers.NewProxyHandler(configProxy)
 
+	// Initialize ForwardAuthHandler for the built-in token-based forwardAuth endpoint
+	forwardAuthHandler := handlers.NewForwardAuthHandler(db, services.NewForwardAuthService(dbWrapper))
+
+	// Initialize SecretRotator for middleware secret rotation (grace-period cleanup runs with the server)
+	secretRotator := services.NewSecretRotator(dbWrapper)
+	secretsHandler := handlers.NewSecretsHandler(db, secretRotator)
+
+	// Initialize ScopeHandler for limiting which resources are managed
+	scopeHandler := handlers.NewScopeHandler(db)
+
+	// Initialize ProtectedHandler for never-override/never-dedupe names
+	protectedHandler := handlers.NewProtectedHandler(db)
+
+	// Initialize ResourceReviewHandler for watcher changes held back in review mode
+	resourceReviewHandler := handlers.NewResourceReviewHandler(services.NewResourceReview(dbWrapper, configManager), configManager)
+
+	// Initialize ResourceSyncHandler for syncs that don't wait for the next poll
+	resourceSyncHandler := handlers.NewResourceSyncHandler(config.ResourceWatcher)
+
+	// Initialize MaintenanceHandler for cleanup runs and their reports
+	maintenanceHandler := handlers.NewMaintenanceHandler(dbWrapper)
+
+	// Initialize RedirectHandler for HTTP→HTTPS redirects (per resource, global and entrypoint-level)
+	redirectHandler := handlers.NewRedirectHandler(db, configProxy, traefikStaticConfigPath)
+
+	// Initialize WAFHandler for the Coraza plugin and per-resource WAF settings
+	wafHandler := handlers.NewWAFHandler(db, traefikStaticConfigPath)
+
+	// Initialize BotListUpdater and BotListHandler for user-agent block lists (lists with a source URL refresh in the background)
+	botListUpdater := services.NewBotListUpdater(dbWrapper)
+	botListHandler := handlers.NewBotListHandler(db, botListUpdater)
+
+	// Initialize MirrorHandler for per-resource request mirroring
+	mirrorHandler := handlers.NewMirrorHandler(db)
+
+	// Initialize FailoverHandler for per-resource failover services
+	failoverHandler := handlers.NewFailoverHandler(db)
+
+	// Initialize BlueGreenDeployer for blue/green deployments (soak completion runs with the server)
+	blueGreenDeployer := services.NewBlueGreenDeployer(dbWrapper)
+	deploymentHandler := handlers.NewDeploymentHandler(db, blueGreenDeployer, configProxy)
+
+	// Initialize ServiceOverrideHandler for deltas applied to discovered services
+	serviceOverrideHandler := handlers.NewServiceOverrideHandler(db)
+
+	// Initialize EntrypointMiddlewareHandler for middlewares attached to every router on an entrypoint
+	entrypointMWHandler := handlers.NewEntrypointMiddlewareHandler(db)
+	defaultChainHandler := handlers.NewDefaultChainHandler(db)
+	reportHandler := handlers.NewReportHandler(db,
+		func() string { return pluginHandler.TraefikStaticConfigPath })
+
+	// Initialize TrafficCapturer and CaptureHandler for temporary per-resource access-log captures
+	trafficCapturer := services.NewTrafficCapturer(dbWrapper, config.AccessLogPath)
+	captureHandler := handlers.NewCaptureHandler(db, trafficCapturer, configProxy)
+
+	// Initialize MetadataHandler for notes and ownership on resources and middlewares
+	metadataHandler := handlers.NewMetadataHandler(db)
+
+	// Initialize AssignmentExpirer for temporary middleware assignments (removal runs with the server)
+	assignmentExpirer := services.NewAssignmentExpirer(dbWrapper)
+	assignmentExpirer.SetWebhooks(webhooks)
+	assignmentHandler := handlers.NewAssignmentHandler(db, assignmentExpirer)
+
+	// Initialize Diagnostics for the startup self-check and /api/system/diagnostics
+	diagnostics := services.NewDiagnostics(db, configManager, services.DiagnosticsOptions{
+		TraefikConfDir:    config.TraefikConfDir,
+		FileConfigEnabled: config.FileConfig,
+		StaticConfigPath:  func() string { return pluginHandler.TraefikStaticConfigPath },
+	})
+	systemHandler := handlers.NewSystemHandler(diagnostics, services.NewResourceRunLog(dbWrapper))
+
+	// Initialize SetupHandler for the first-run wizard
+	setupHandler := handlers.NewSetupHandler(services.NewSetupWizard(configManager),
+		func() string { return pluginHandler.TraefikStaticConfigPath })
+
+	// Restores drop the cached config and rebuild the fetchers, which
+	// reconcile the restored resources and services with the data source
+	afterRestore := func() {
+		configProxy.InvalidateCache()
+		if config.ResourceWatcher != nil {
+			go func() {
+				if _, err := config.ResourceWatcher.Restored(); err != nil {
+					log.Printf("Resource check after restore failed: %v", err)
+				}
+			}()
+		}
+		if config.ServiceWatcher != nil {
+			config.ServiceWatcher.Reload()
+		}
+	}
+
+	// Initialize local backups of database snapshots and config exports
+	var localBackups *services.Backups
+	if config.BackupDir != "" && !database.IsPostgres() {
+		target, err := services.NewLocalTarget(config.BackupDir)
+		if err != nil {
+			log.Printf("Local backups disabled: %v", err)
+		} else {
+			localBackups = services.NewBackups(dbWrapper, target, configProxy.GetMergedConfig, afterRestore)
+			localBackups.SetPassphrase(config.BackupPassphrase)
+			localBackups.SetRetention(config.BackupKeep)
+		}
+	}
+
+	// Initialize S3 backups of database snapshots and config exports
+	var s3Backups *services.Backups
+	if config.S3Backup.Endpoint != "" {
+		target, err := services.NewS3Target(config.S3Backup)
+		if err != nil {
+			log.Printf("S3 backups disabled: %v", err)
+		} else {
+			s3Backups = services.NewBackups(dbWrapper, target, configProxy.GetMergedConfig, afterRestore)
+			s3Backups.SetPassphrase(config.BackupPassphrase)
+		}
+	}
+	backupHandler := handlers.NewBackupHandler(localBackups, s3Backups)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokens)
+	userHandler := handlers.NewUserHandler(services.NewUserService(dbWrapper))
+	webhookHandler := handlers.NewWebhookHandler(webhooks)
+	environmentHandler := handlers.NewEnvironmentHandler(db, services.NewEnvironmentService(dbWrapper), configProxy)
+
 	// Setup server with all handlers
 	server := &Server{
 		db:                      db,
@@ -123,10 +410,55 @@ func NewServer(dbWrapper *database.DB, config ServerConfig, configManager *servi
 		traefikHandler:          traefikHandler,
 		mtlsHandler:             mtlsHandler,
 		securityHandler:         securityHandler,
+		headerPolicyHandler:     headerPolicyHandler,
+		corsHandler:             corsHandler,
+		forwardAuthHandler:      forwardAuthHandler,
+		secretsHandler:          secretsHandler,
+		scopeHandler:            scopeHandler,
+		protectedHandler:        protectedHandler,
+		maintenanceHandler:      maintenanceHandler,
+		resourceReviewHandler:   resourceReviewHandler,
+		resourceSyncHandler:     resourceSyncHandler,
+		backupHandler:           backupHandler,
+		apiTokenHandler:         apiTokenHandler,
+		userHandler:             userHandler,
+		webhookHandler:          webhookHandler,
+		environmentHandler:      environmentHandler,
+		redirectHandler:         redirectHandler,
+		wafHandler:              wafHandler,
+		botListHandler:          botListHandler,
+		mirrorHandler:           mirrorHandler,
+		failoverHandler:         failoverHandler,
+		deploymentHandler:       deploymentHandler,
+		serviceOverrideHandler:  serviceOverrideHandler,
+		entrypointMWHandler:     entrypointMWHandler,
+		defaultChainHandler:     defaultChainHandler,
+		reportHandler:           reportHandler,
+		captureHandler:          captureHandler,
+		metadataHandler:         metadataHandler,
+		assignmentHandler:       assignmentHandler,
 		proxyHandler:            proxyHandler,
+		systemHandler:           systemHandler,
+		setupHandler:            setupHandler,
 		configManager:           configManager,
 		configProxy:             configProxy,
+		diagnostics:             diagnostics,
+		secretRotator:           secretRotator,
+		botListUpdater:          botListUpdater,
+		trafficCapturer:         trafficCapturer,
+		blueGreenDeployer:       blueGreenDeployer,
+		assignmentExpirer:       assignmentExpirer,
+		webhooks:                webhooks,
+		localBackups:            localBackups,
+		localBackupInterval:     config.BackupInterval,
+		s3Backups:               s3Backups,
+		s3BackupInterval:        config.S3BackupInterval,
+		idempotency:             idempotency,
+		auth:                    auth,
 		traefikStaticConfigPath: traefikStaticConfigPath,
+		socketPath:              config.SocketPath,
+		socketMode:              config.SocketMode,
+		socketOnly:              config.SocketOnly && config.SocketPath != "",
 		srv: &http.Server{
 			Addr:              ":" + config.Port,
 			Handler:           router,
@@ -137,8 +469,22 @@ func NewServer(dbWrapper *database.DB, config ServerConfig, configManager *servi
 		},
 	}
 
+	// Report process figures along with the cache sizes only the server knows
+	systemHandler.SetRuntimeStats(func() services.RuntimeStats {
+		caches := configProxy.CacheSizes()
+		caches["idempotency"] = idempotency.Len()
+		return services.CollectRuntimeStats(db, caches)
+	})
+
+	// Settings reloads (SIGHUP or POST /api/system/reload) go through main
+	systemHandler.SetReload(config.Reload)
+
 	// Configure routes
 	server.setupRoutes(config.UIPath)
+	if config.Pprof {
+		log.Printf("Go profiler enabled under /api/system/pprof")
+		registerPprof(router.Group("/api/system/pprof"))
+	}
 
 	return server
 }
@@ -158,8 +504,13 @@ func (s *Server) setupRoutes(uiPath string) {
 		{
 			middlewares.GET("", s.middlewareHandler.GetMiddlewares)
 			middlewares.POST("", s.middlewareHandler.CreateMiddleware)
+			middlewares.POST("/convert", s.middlewareHandler.ConvertMiddlewares)
+			middlewares.POST("/validate", s.middlewareHandler.ValidateMiddleware)
 			middlewares.GET("/:id", s.middlewareHandler.GetMiddleware)
 			middlewares.PUT("/:id", s.middlewareHandler.UpdateMiddleware)
+			middlewares.PUT("/:id/metadata", s.metadataHandler.UpdateMiddlewareMetadata)
+			middlewares.GET("/:id/environments", s.environmentHandler.GetMiddlewareEnvironments)
+			middlewares.PUT("/:id/environments", s.environmentHandler.SetMiddlewareEnvironments)
 			middlewares.DELETE("/:id", s.middlewareHandler.DeleteMiddleware)
 		}
 
@@ -173,6 +524,15 @@ func (s *Server) setupRoutes(uiPath string) {
 			services.DELETE("/:id", s.serviceHandler.DeleteService)
 		}
 
+		// Overrides of services discovered upstream
+		serviceOverrides := api.Group("/service-overrides")
+		{
+			serviceOverrides.GET("", s.serviceOverrideHandler.GetServiceOverrides)
+			serviceOverrides.GET("/:name", s.serviceOverrideHandler.GetServiceOverride)
+			serviceOverrides.PUT("/:name", s.serviceOverrideHandler.SetServiceOverride)
+			serviceOverrides.DELETE("/:name", s.serviceOverrideHandler.DeleteServiceOverride)
+		}
+
 		// Resource routes
 		resources := api.Group("/resources")
 		{
@@ -181,6 +541,15 @@ func (s *Server) setupRoutes(uiPath string) {
 			resources.DELETE("/:id", s.resourceHandler.DeleteResource)
 			resources.POST("/bulk-delete-disabled", s.resourceHandler.DeleteDisabledResources)
 
+			// Review mode - watcher changes waiting for approval
+			resources.GET("/pending", s.resourceReviewHandler.GetPendingChanges)
+			resources.POST("/pending/approve", s.resourceReviewHandler.ApprovePendingChanges)
+			resources.PUT("/review", s.resourceReviewHandler.UpdateReviewConfig)
+
+			// Manual sync with the data source
+			resources.POST("/sync", s.resourceSyncHandler.SyncResources)
+			resources.POST("/:id/resync", s.resourceSyncHandler.ResyncResource)
+
 			// Middleware assignments
 			resources.POST("/:id/middlewares", s.resourceHandler.AssignMiddleware)
 			resources.POST("/:id/middlewares/bulk", s.resourceHandler.AssignMultipleMiddlewares)
@@ -202,11 +571,78 @@ func (s *Server) setupRoutes(uiPath string) {
 			resources.PUT("/:id/config/tcp", s.configHandler.UpdateTCPConfig)
 			resources.PUT("/:id/config/headers", s.configHandler.UpdateHeadersConfig)
 			resources.PUT("/:id/config/priority", s.configHandler.UpdateRouterPriority)
+			resources.PUT("/:id/config/middleware-order", s.configHandler.UpdateMiddlewareOrder)
+			resources.PUT("/:id/config/upstream-middlewares", s.configHandler.UpdateUpstreamMiddlewares)
 			resources.PUT("/:id/config/mtls", s.configHandler.UpdateMTLSConfig)
 			resources.PUT("/:id/config/mtlswhitelist", s.configHandler.UpdateMTLSWhitelistConfig)
+			resources.PUT("/:id/config/mtls/exemptions", s.configHandler.UpdateMTLSExemptions)
 			// Per-resource security configuration
 			resources.PUT("/:id/config/tls-hardening", s.securityHandler.UpdateResourceTLSHardening)
 			resources.PUT("/:id/config/secure-headers", s.securityHandler.UpdateResourceSecureHeaders)
+			resources.PUT("/:id/config/cors", s.corsHandler.UpdateResourceCORS)
+			resources.PUT("/:id/config/forward-auth", s.forwardAuthHandler.UpdateResourceForwardAuth)
+			resources.PUT("/:id/config/default-chain", s.defaultChainHandler.UpdateResourceDefaultChain)
+			resources.PUT("/:id/config/https-redirect", s.redirectHandler.UpdateResourceRedirect)
+			resources.GET("/:id/waf", s.wafHandler.GetResourceWAF)
+			resources.PUT("/:id/config/waf", s.wafHandler.UpdateResourceWAF)
+
+			// Request mirroring to a test environment
+			resources.GET("/:id/mirror", s.mirrorHandler.GetResourceMirror)
+			resources.PUT("/:id/mirror", s.mirrorHandler.SetResourceMirror)
+			resources.DELETE("/:id/mirror", s.mirrorHandler.DeleteResourceMirror)
+
+			// Failover to a fallback while the service is unhealthy
+			resources.GET("/:id/failover", s.failoverHandler.GetResourceFailover)
+			resources.PUT("/:id/failover", s.failoverHandler.SetResourceFailover)
+			resources.DELETE("/:id/failover", s.failoverHandler.DeleteResourceFailover)
+
+			// Blue/green deployment to a new backend URL
+			resources.GET("/:id/deployment", s.deploymentHandler.GetResourceDeployment)
+			resources.POST("/:id/deployment", s.deploymentHandler.StartDeployment)
+			resources.POST("/:id/deployment/switch", s.deploymentHandler.SwitchDeployment)
+			resources.POST("/:id/deployment/rollback", s.deploymentHandler.RollbackDeployment)
+			resources.DELETE("/:id/deployment", s.deploymentHandler.DeleteDeployment)
+
+			// Temporary traffic capture from the access log
+			resources.POST("/:id/capture", s.captureHandler.StartCapture)
+
+			// Notes and ownership
+			resources.PUT("/:id/metadata", s.metadataHandler.UpdateResourceMetadata)
+			resources.GET("/:id/environments", s.environmentHandler.GetResourceEnvironments)
+			resources.PUT("/:id/environments", s.environmentHandler.SetResourceEnvironments)
+
+			// Pinning keeps the watcher from changing a resource
+			resources.PUT("/:id/pin", s.resourceHandler.SetResourcePin)
+
+			// Built-in forward auth tokens
+			resources.GET("/:id/forward-auth/tokens", s.forwardAuthHandler.GetTokens)
+			resources.POST("/:id/forward-auth/tokens", s.forwardAuthHandler.CreateToken)
+			resources.DELETE("/:id/forward-auth/tokens/:tokenId", s.forwardAuthHandler.DeleteToken)
+			resources.POST("/:id/forward-auth/tokens/:tokenId/rotate", s.forwardAuthHandler.RotateToken)
+
+			// Header policy assignments
+			resources.GET("/:id/header-policies", s.headerPolicyHandler.GetResourcePolicies)
+			resources.POST("/:id/header-policies", s.headerPolicyHandler.AssignResourcePolicy)
+			resources.DELETE("/:id/header-policies/:policyId", s.headerPolicyHandler.RemoveResourcePolicy)
+		}
+
+		// System routes - environment self-checks with remediation hints
+		system := api.Group("/system")
+		{
+			system.GET("/diagnostics", s.systemHandler.GetDiagnostics)
+			system.GET("/runtime", s.systemHandler.GetRuntime)
+			system.POST("/reload", s.systemHandler.Reload)
+			system.GET("/watchers/resource/runs", s.systemHandler.GetResourceWatcherRuns)
+		}
+
+		// Setup wizard routes - guide first-run configuration instead of hand-written env vars
+		setup := api.Group("/setup")
+		{
+			setup.GET("", s.setupHandler.GetSetupState)
+			setup.POST("/detect", s.setupHandler.DetectDeployment)
+			setup.PUT("/datasource", s.setupHandler.ConfigureDataSource)
+			setup.POST("/provider-check", s.setupHandler.CheckProvider)
+			setup.POST("/complete", s.setupHandler.CompleteSetup)
 		}
 
 		// Data source routes
@@ -215,6 +651,8 @@ func (s *Server) setupRoutes(uiPath string) {
 			datasource.GET("", s.dataSourceHandler.GetDataSources)
 			datasource.GET("/active", s.dataSourceHandler.GetActiveDataSource)
 			datasource.PUT("/active", s.dataSourceHandler.SetActiveDataSource)
+			datasource.GET("/failover", s.dataSourceHandler.GetFailover)
+			datasource.PUT("/failover", s.dataSourceHandler.SetFailover)
 			datasource.PUT("/:name", s.dataSourceHandler.UpdateDataSource)
 			datasource.POST("/:name/test", s.dataSourceHandler.TestDataSourceConnection)
 		}
@@ -225,6 +663,7 @@ func (s *Server) setupRoutes(uiPath string) {
 			pluginsGroup.GET("", s.pluginHandler.GetPlugins)
 			pluginsGroup.GET("/catalogue", s.pluginHandler.GetPluginCatalogue) // Fetch from plugins.traefik.io
 			pluginsGroup.GET("/:name/usage", s.pluginHandler.GetPluginUsage)
+			pluginsGroup.POST("/sandbox", s.pluginHandler.TestPluginSandbox) // Dry-run a plugin in Yaegi before installing
 			pluginsGroup.POST("/install", s.pluginHandler.InstallPlugin)
 			pluginsGroup.DELETE("/remove", s.pluginHandler.RemovePlugin)
 			pluginsGroup.GET("/configpath", s.pluginHandler.GetTraefikStaticConfigPath)
@@ -242,6 +681,35 @@ func (s *Server) setupRoutes(uiPath string) {
 			traefik.GET("/services", s.traefikHandler.GetServices)
 			traefik.GET("/middlewares", s.traefikHandler.GetMiddlewares)
 			traefik.GET("/data", s.traefikHandler.GetFullData)
+			// The static config lint reads the file the plugin hub manages
+			traefik.GET("/static-config/lint", s.pluginHandler.LintStaticConfig)
+			traefik.POST("/static-config/lint", s.pluginHandler.LintStaticConfigContent)
+			traefik.GET("/deprecations", s.proxyHandler.GetDeprecations)
+			traefik.POST("/deprecations/fix", s.proxyHandler.FixDeprecations)
+		}
+
+		// Tools - evaluations against the merged config that never touch Traefik
+		tools := api.Group("/tools")
+		{
+			tools.GET("/route", s.proxyHandler.SimulateRoute)
+		}
+
+		// Reports - artifacts for security and access reviews
+		reports := api.Group("/reports")
+		{
+			reports.GET("/access-matrix", s.reportHandler.GetAccessMatrix)
+			reports.GET("/inventory", s.reportHandler.GetInventory)
+		}
+
+		// Backups - database snapshots and config exports in the backup directory and object storage
+		api.POST("/backup", s.backupHandler.CreateBackup)
+		backups := api.Group("/backups")
+		{
+			backups.GET("", s.backupHandler.GetBackups)
+			backups.POST("/:id/restore", s.backupHandler.RestoreBackup)
+			backups.GET("/s3", s.backupHandler.GetS3Backups)
+			backups.POST("/s3/run", s.backupHandler.RunS3Backup)
+			backups.POST("/s3/restore", s.backupHandler.RestoreS3Backup)
 		}
 
 		// mTLS Routes - Certificate Authority and client certificate management
@@ -255,33 +723,243 @@ func (s *Server) setupRoutes(uiPath string) {
 			mtls.PUT("/config/path", s.mtlsHandler.UpdateCertsBasePath)
 			mtls.GET("/clients", s.mtlsHandler.GetClients)
 			mtls.POST("/clients", s.mtlsHandler.CreateClient)
+			mtls.POST("/clients/import", s.mtlsHandler.ImportClients)
+			mtls.GET("/clients/export", s.mtlsHandler.ExportClients)
 			mtls.GET("/clients/:id", s.mtlsHandler.GetClient)
 			mtls.GET("/clients/:id/download", s.mtlsHandler.DownloadClientP12)
 			mtls.PUT("/clients/:id/revoke", s.mtlsHandler.RevokeClient)
 			mtls.DELETE("/clients/:id", s.mtlsHandler.DeleteClient)
 			// Plugin detection and middleware configuration
 			mtls.GET("/plugin/check", s.mtlsHandler.CheckPlugin)
+			mtls.GET("/preflight/:id", s.mtlsHandler.Preflight)
 			mtls.GET("/middleware/config", s.mtlsHandler.GetMiddlewareConfig)
 			mtls.PUT("/middleware/config", s.mtlsHandler.UpdateMiddlewareConfig)
+			// Export for validating the same client certs on other proxies
+			mtls.GET("/export", s.mtlsHandler.ExportConfig)
+			mtls.GET("/export/bundle.pem", s.mtlsHandler.ExportPEMBundle)
+			mtls.GET("/export/crl.pem", s.mtlsHandler.ExportCRL)
+			mtls.PUT("/public-pki", s.mtlsHandler.UpdatePublicPKI)
 		}
 
 		// Security Routes - TLS hardening, secure headers, duplicate detection
 		security := api.Group("/security")
 		{
 			security.GET("/config", s.securityHandler.GetConfig)
+			security.GET("/tls-hardening/compatibility", s.securityHandler.GetTLSCompatibility)
 			security.PUT("/tls-hardening/enable", s.securityHandler.EnableTLSHardening)
 			security.PUT("/tls-hardening/disable", s.securityHandler.DisableTLSHardening)
+			security.PUT("/tls-hardening/mode", s.securityHandler.UpdateTLSHardeningMode)
 			security.PUT("/secure-headers/enable", s.securityHandler.EnableSecureHeaders)
 			security.PUT("/secure-headers/disable", s.securityHandler.DisableSecureHeaders)
 			security.PUT("/secure-headers/config", s.securityHandler.UpdateSecureHeadersConfig)
+			security.GET("/secure-headers/presets", s.securityHandler.GetSecureHeadersPresets)
+			security.POST("/secure-headers/presets", s.securityHandler.CreateSecureHeadersPreset)
+			security.PUT("/secure-headers/presets/:id", s.securityHandler.UpdateSecureHeadersPreset)
+			security.DELETE("/secure-headers/presets/:id", s.securityHandler.DeleteSecureHeadersPreset)
+			security.POST("/secure-headers/presets/:id/apply", s.securityHandler.ApplySecureHeadersPreset)
 			security.POST("/check-duplicates", s.securityHandler.CheckMiddlewareDuplicates)
 		}
 
+		// Header Policy Routes - reusable header policies merged into one headers middleware per resource
+		headerPolicies := api.Group("/header-policies")
+		{
+			headerPolicies.GET("", s.headerPolicyHandler.GetHeaderPolicies)
+			headerPolicies.POST("", s.headerPolicyHandler.CreateHeaderPolicy)
+			headerPolicies.GET("/:id", s.headerPolicyHandler.GetHeaderPolicy)
+			headerPolicies.PUT("/:id", s.headerPolicyHandler.UpdateHeaderPolicy)
+			headerPolicies.DELETE("/:id", s.headerPolicyHandler.DeleteHeaderPolicy)
+		}
+
+		// CORS Policy Routes - validated CORS settings rendered into the resource headers middleware
+		corsPolicies := api.Group("/cors-policies")
+		{
+			corsPolicies.GET("", s.corsHandler.GetCORSPolicies)
+			corsPolicies.POST("", s.corsHandler.CreateCORSPolicy)
+			corsPolicies.GET("/:id", s.corsHandler.GetCORSPolicy)
+			corsPolicies.PUT("/:id", s.corsHandler.UpdateCORSPolicy)
+			corsPolicies.DELETE("/:id", s.corsHandler.DeleteCORSPolicy)
+		}
+
+		// Resource Group Routes - groups share header policies across resources
+		resourceGroups := api.Group("/resource-groups")
+		{
+			resourceGroups.GET("", s.headerPolicyHandler.GetResourceGroups)
+			resourceGroups.POST("", s.headerPolicyHandler.CreateResourceGroup)
+			resourceGroups.GET("/:id", s.headerPolicyHandler.GetResourceGroup)
+			resourceGroups.DELETE("/:id", s.headerPolicyHandler.DeleteResourceGroup)
+			resourceGroups.POST("/:id/resources", s.headerPolicyHandler.AddGroupResource)
+			resourceGroups.DELETE("/:id/resources/:resourceId", s.headerPolicyHandler.RemoveGroupResource)
+			resourceGroups.POST("/:id/header-policies", s.headerPolicyHandler.AssignGroupPolicy)
+			resourceGroups.DELETE("/:id/header-policies/:policyId", s.headerPolicyHandler.RemoveGroupPolicy)
+		}
+
+		// Management scope routes - include/exclude host patterns for managed resources
+		scope := api.Group("/scope")
+		{
+			scope.GET("", s.scopeHandler.GetScope)
+			scope.PUT("", s.scopeHandler.UpdateScope)
+		}
+
+		// Protected names - middlewares/services MM must never override or de-duplicate
+		protected := api.Group("/protected")
+		{
+			protected.GET("", s.protectedHandler.GetProtectedNames)
+			protected.POST("", s.protectedHandler.AddProtectedName)
+			protected.DELETE("/:kind/:name", s.protectedHandler.RemoveProtectedName)
+		}
+
+		// Maintenance routes - cleanup runs default to dry-run, keep a structured report and can be undone
+		maintenance := api.Group("/maintenance")
+		{
+			maintenance.POST("/cleanup", s.maintenanceHandler.RunCleanup)
+			maintenance.GET("/cleanup/runs", s.maintenanceHandler.GetCleanupRuns)
+			maintenance.GET("/cleanup/runs/:runId", s.maintenanceHandler.GetCleanupRun)
+			maintenance.POST("/undo/:runId", s.maintenanceHandler.UndoCleanupRun)
+			maintenance.POST("/migrate-resource-ids", s.maintenanceHandler.MigrateResourceIDs)
+			maintenance.POST("/migrate-postgres", s.maintenanceHandler.MigrateToPostgres)
+		}
+
+		// HTTP→HTTPS redirect routes - entrypoint changes are written to the static config and need a Traefik restart
+		redirects := api.Group("/redirects")
+		{
+			redirects.GET("", s.redirectHandler.GetRedirects)
+			redirects.PUT("", s.redirectHandler.UpdateRedirects)
+			redirects.PUT("/entrypoint", s.redirectHandler.UpdateEntrypointRedirect)
+		}
+
+		// Entrypoint middleware routes - rendered in front of every HTTP router on the entrypoint
+		entrypoints := api.Group("/entrypoints")
+		{
+			entrypoints.GET("/middlewares", s.entrypointMWHandler.GetEntrypointMiddlewares)
+			entrypoints.POST("/:entrypoint/middlewares", s.entrypointMWHandler.AssignEntrypointMiddleware)
+			entrypoints.DELETE("/:entrypoint/middlewares/:middlewareId", s.entrypointMWHandler.RemoveEntrypointMiddleware)
+		}
+
+		// Default chain routes - rendered once and put in front of every HTTP router not excluded
+		defaultChain := api.Group("/default-chain")
+		{
+			defaultChain.GET("", s.defaultChainHandler.GetDefaultChain)
+			defaultChain.PUT("", s.defaultChainHandler.UpdateDefaultChain)
+		}
+
+		// WAF routes - installing the plugin writes the static config and needs a Traefik restart
+		waf := api.Group("/waf")
+		{
+			waf.GET("", s.wafHandler.GetWAFConfig)
+			waf.PUT("", s.wafHandler.UpdateWAFConfig)
+			waf.POST("/install", s.wafHandler.InstallWAFPlugin)
+		}
+
+		// Traffic capture routes
+		captures := api.Group("/captures")
+		{
+			captures.GET("", s.captureHandler.GetCaptures)
+			captures.GET("/:captureId", s.captureHandler.GetCapture)
+			captures.DELETE("/:captureId", s.captureHandler.StopCapture)
+		}
+
+		// Mirror overview
+		api.GET("/mirrors", s.mirrorHandler.GetMirrors)
+		api.GET("/failovers", s.failoverHandler.GetFailovers)
+
+		// Blue/green deployment overview
+		api.GET("/deployments", s.deploymentHandler.GetDeployments)
+
+		// Search notes, owners and contacts of resources and middlewares
+		api.GET("/metadata/search", s.metadataHandler.SearchMetadata)
+
+		// Temporary middleware assignment routes
+		assignments := api.Group("/assignments")
+		{
+			assignments.GET("/expirations", s.assignmentHandler.GetExpirations)
+			assignments.POST("/expire", s.assignmentHandler.ExpireNow)
+		}
+
+		// Bot list routes - lists block user agents on the resources they are applied to
+		botLists := api.Group("/bot-lists")
+		{
+			botLists.GET("", s.botListHandler.GetBotLists)
+			botLists.POST("", s.botListHandler.CreateBotList)
+			botLists.GET("/:id", s.botListHandler.GetBotList)
+			botLists.PUT("/:id", s.botListHandler.UpdateBotList)
+			botLists.DELETE("/:id", s.botListHandler.DeleteBotList)
+			botLists.POST("/:id/refresh", s.botListHandler.RefreshBotList)
+			botLists.PUT("/:id/resources", s.botListHandler.SetBotListResources)
+		}
+
+		// Secret rotation routes
+		secrets := api.Group("/secrets")
+		{
+			secrets.POST("/rotate", s.secretsHandler.RotateSecret)
+			secrets.GET("/rotations", s.secretsHandler.GetRotations)
+			secrets.POST("/rotations/:id/complete", s.secretsHandler.CompleteRotation)
+		}
+
+		// API tokens - bearer tokens for the management API (admin only once auth is on)
+		tokens := api.Group("/tokens")
+		{
+			tokens.GET("", s.apiTokenHandler.GetTokens)
+			tokens.POST("", s.apiTokenHandler.CreateToken)
+			tokens.DELETE("/:id", s.apiTokenHandler.DeleteToken)
+		}
+
+		// Users - operators whose tokens act with a viewer, editor or admin role
+		users := api.Group("/users")
+		{
+			users.GET("", s.userHandler.GetUsers)
+			users.POST("", s.userHandler.CreateUser)
+			users.PUT("/:id", s.userHandler.UpdateUser)
+			users.DELETE("/:id", s.userHandler.DeleteUser)
+		}
+
+		// Webhooks - event notifications posted to external URLs, with their delivery log
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.GET("", s.webhookHandler.GetWebhooks)
+			webhooks.POST("", s.webhookHandler.CreateWebhook)
+			webhooks.GET("/deliveries", s.webhookHandler.GetDeliveries)
+			webhooks.PUT("/:id", s.webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", s.webhookHandler.DeleteWebhook)
+			webhooks.POST("/:id/test", s.webhookHandler.TestWebhook)
+			webhooks.GET("/:id/deliveries", s.webhookHandler.GetDeliveries)
+		}
+
+		// Environments: one database serving different merged configs to
+		// the Traefik instances of each environment
+		environments := api.Group("/environments")
+		{
+			environments.GET("", s.environmentHandler.GetEnvironments)
+			environments.POST("", s.environmentHandler.CreateEnvironment)
+			environments.GET("/tags", s.environmentHandler.GetEnvironmentTags)
+			environments.PUT("/:name", s.environmentHandler.UpdateEnvironment)
+			environments.DELETE("/:name", s.environmentHandler.DeleteEnvironment)
+		}
+
+		// Built-in forwardAuth endpoint, called by Traefik for resources with forward auth enabled
+		api.GET("/forward-auth/verify", s.forwardAuthHandler.Verify)
+
 		// Config Proxy Routes - Proxies Pangolin config with MW-manager additions
 		// This endpoint is designed for Traefik's HTTP provider
 		api.GET("/traefik-config", s.proxyHandler.GetTraefikConfig)
+		api.GET("/traefik-config/env/:environment", s.proxyHandler.GetTraefikConfig)
 		api.POST("/traefik-config/invalidate", s.proxyHandler.InvalidateCache)
 		api.GET("/traefik-config/status", s.proxyHandler.GetProxyStatus)
+		api.GET("/traefik-config/changes", s.proxyHandler.GetConfigChanges)
+		api.GET("/traefik-config/transforms", s.proxyHandler.GetTransformLog)
+		api.GET("/traefik-config/diff", s.proxyHandler.GetConfigDiff)
+		api.POST("/traefik-config/diff", s.proxyHandler.DiffConfigSnapshot)
+		api.GET("/traefik-config/chaos", s.proxyHandler.GetChaos)
+		api.PUT("/traefik-config/chaos", s.proxyHandler.SetChaos)
+		api.DELETE("/traefik-config/chaos", s.proxyHandler.ClearChaos)
+		api.GET("/traefik-config/health", s.proxyHandler.GetProviderHealth)
+		api.GET("/traefik-config/consumers", s.proxyHandler.GetProviderConsumers)
+	}
+
+	// Public PKI routes - unauthenticated, served only when explicitly enabled in mTLS settings
+	pki := s.router.Group("/pki")
+	{
+		pki.GET("/ca.crt", s.mtlsHandler.PublicCACert)
+		pki.GET("/instructions", s.mtlsHandler.PublicInstructions)
 	}
 
 	// API v1 routes - for Traefik HTTP provider compatibility
@@ -290,8 +968,13 @@ func (s *Server) setupRoutes(uiPath string) {
 	{
 		// Config Proxy endpoint - replaces Pangolin's /api/v1/traefik-config
 		v1.GET("/traefik-config", s.proxyHandler.GetTraefikConfig)
+		v1.GET("/traefik-config/env/:environment", s.proxyHandler.GetTraefikConfig)
 		v1.POST("/traefik-config/invalidate", s.proxyHandler.InvalidateCache)
 		v1.GET("/traefik-config/status", s.proxyHandler.GetProxyStatus)
+		v1.GET("/traefik-config/changes", s.proxyHandler.GetConfigChanges)
+		v1.GET("/traefik-config/transforms", s.proxyHandler.GetTransformLog)
+		v1.GET("/traefik-config/health", s.proxyHandler.GetProviderHealth)
+		v1.GET("/traefik-config/consumers", s.proxyHandler.GetProviderConsumers)
 	}
 
 	// Serve the React app (Vite build output)
@@ -309,28 +992,103 @@ func (s *Server) setupRoutes(uiPath string) {
 		s.router.NoRoute(func(c *gin.Context) {
 			// API routes should 404 when not found
 			if len(c.Request.URL.Path) >= 4 && c.Request.URL.Path[:4] == "/api" {
-				c.JSON(http.StatusNotFound, gin.H{"error": "API endpoint not found"})
+				apierrors.HandleAPIError(c, http.StatusNotFound, "API endpoint not found", nil)
 				return
 			}
 
 			// Non-API routes serve the SPA
-			c.File(uiPathToUse + "/index.html")
+			c.File(filepath.Join(uiPathToUse, "index.html"))
 		})
 	} else {
 		log.Printf("Warning: UI path %s doesn't exist or is not a directory. Web UI will not be available.", uiPathToUse)
 	}
 }
 
+// Reload applies the settings of config that can change while the server
+// runs: API authentication and the config proxy's validation, limits,
+// sections, Traefik version and DNS server. Requests in flight are not
+// interrupted; the next config fetch is merged with the new settings.
+func (s *Server) Reload(config ServerConfig) {
+	s.auth.tokens.SetAdminToken(config.AdminToken)
+	s.auth.setMode(config.AuthMode)
+	s.auth.logMode()
+
+	s.configProxy.SetErrorBudget(config.ErrorBudget)
+	s.configProxy.SetConfigLimits(config.ConfigLimits)
+	s.configProxy.SetMergeSections(config.MergeSections)
+	s.configProxy.SetTransformLog(config.TransformLog)
+	s.configProxy.SetChaosMode(config.Chaos)
+	s.configProxy.SetTraefikVersion(config.TraefikVersion)
+	s.configProxy.SetDNSDiscoveryServer(config.DNSDiscoveryServer)
+	s.configProxy.InvalidateCache()
+}
+
 // Start starts the API server with graceful shutdown
 func (s *Server) Start() error {
-	// Channel to listen for errors coming from the listener.
-	serverErrors := make(chan error, 1)
+	// Channel to listen for errors coming from the listeners.
+	serverErrors := make(chan error, 2)
+
+	// Open the Unix socket first so a bad path fails the start
+	var socket net.Listener
+	if s.socketPath != "" {
+		mode := s.socketMode
+		if mode == 0 {
+			mode = DefaultSocketMode
+		}
+		var err error
+		if socket, err = listenUnix(s.socketPath, mode); err != nil {
+			return fmt.Errorf("failed to listen on unix socket: %w", err)
+		}
+	}
+
+	// Remove superseded secrets once their rotation grace period ends
+	go s.secretRotator.Start(time.Minute)
+
+	// Track the Traefik version so generated middleware options match it
+	go s.configProxy.StartVersionDetection(5 * time.Minute)
+
+	// Post served config changes to the configured webhook consumer
+	go s.configProxy.StartConfigWebhook()
+
+	// Refresh bot lists that are downloaded from a source URL
+	go s.botListUpdater.Start(time.Minute)
+
+	// Collect access-log lines for active traffic captures
+	go s.trafficCapturer.Start(2 * time.Second)
+
+	// Remove temporary middleware assignments once they expire
+	go s.assignmentExpirer.Start(time.Minute)
+
+	// Deliver queued webhook events and retry failed deliveries
+	go s.webhooks.Start(5 * time.Second)
+
+	// Archive blue once a switched deployment's soak period ends
+	go s.blueGreenDeployer.Start(30 * time.Second)
+
+	// Back up locally and to S3 on the configured schedules
+	if s.localBackups != nil && s.localBackupInterval > 0 {
+		go s.localBackups.Start(s.localBackupInterval)
+	}
+	if s.s3Backups != nil && s.s3BackupInterval > 0 {
+		go s.s3Backups.Start(s.s3BackupInterval)
+	}
+
+	// Log environment problems that would otherwise surface as confusing errors later
+	go s.logStartupDiagnostics()
 
-	// Start the server
-	go func() {
-		log.Printf("API server listening on %s", s.srv.Addr)
-		serverErrors <- s.srv.ListenAndServe()
-	}()
+	// Start the server; Shutdown closes every listener and removes the socket file
+	if !s.socketOnly {
+		go func() {
+			log.Printf("API server listening on %s", s.srv.Addr)
+			serverErrors <- s.srv.ListenAndServe()
+		}()
+	}
+	if socket != nil {
+		go func() {
+			log.Printf("API server listening on unix socket %s", s.socketPath)
+			serverErrors <- s.srv.Serve(socket)
+		}()
+	}
 
 	// Channel to listen for an interrupt or terminate signal from the OS.
 	shutdown := make(chan os.Signal, 1)
@@ -367,6 +1125,22 @@ func (s *Server) Start() error {
 
 // Stop gracefully stops the API server
 func (s *Server) Stop() {
+	s.secretRotator.Stop()
+	s.configProxy.StopVersionDetection()
+	s.configProxy.StopConfigWebhook()
+	s.botListUpdater.Stop()
+	s.trafficCapturer.Stop()
+	s.assignmentExpirer.Stop()
+	s.webhooks.Stop()
+	s.blueGreenDeployer.Stop()
+	if s.localBackups != nil {
+		s.localBackups.Stop()
+	}
+	if s.s3Backups != nil {
+		s.s3Backups.Stop()
+	}
+	s.idempotency.Stop()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -380,6 +1154,50 @@ func (s *Server) Stop() {
 	}
 }
 
+// logStartupDiagnostics runs the environment self-checks once and logs every
+// check that did not pass together with its remediation
+func (s *Server) logStartupDiagnostics() {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	report := s.diagnostics.Run(ctx)
+	for _, check := range report.Checks {
+		if check.Status != models.DiagnosticFail && check.Status != models.DiagnosticWarn {
+			continue
+		}
+		if check.Remediation != "" {
+			log.Printf("Self-check %s [%s]: %s (%s)", check.Name, check.Status, check.Message, check.Remediation)
+		} else {
+			log.Printf("Self-check %s [%s]: %s", check.Name, check.Status, check.Message)
+		}
+	}
+	if report.OK {
+		log.Println("Startup self-check passed")
+	} else {
+		log.Println("Startup self-check found problems; see /api/system/diagnostics")
+	}
+}
+
+// maxRequestBodySize caps the body of any API request
+const maxRequestBodySize = 1 << 20
+
+// requestSizeLimit returns a Gin middleware that limits request bodies to limit
+// bytes. Requests announcing a larger body are rejected before they are read.
+func requestSizeLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			apierrors.HandleAPIError(c, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Request body exceeds %d bytes", limit), nil)
+			c.Abort()
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
 // minimalLogger returns a Gin middleware for minimal request logging
 func minimalLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
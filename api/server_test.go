@@ -1,9 +1,11 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -37,3 +39,186 @@ func TestServerHealthAndDatasourceRoutes(t *testing.T) {
 		t.Fatalf("expected /api/datasource/active 200, got %d", rec2.Code)
 	}
 }
+
+func TestServerRejectsOversizedBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	srv := NewServer(db, ServerConfig{Port: "0"}, cm, filepath.Join(t.TempDir(), "traefik.yml"))
+
+	body := `{"name":"big","type":"headers","config":{"x":"` + strings.Repeat("a", maxRequestBodySize) + `"}}`
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/middlewares", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", rec.Code)
+	}
+}
+
+func TestServerRuntimeAndPprofRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	for _, enabled := range []bool{false, true} {
+		srv := NewServer(db, ServerConfig{Port: "0", Pprof: enabled}, cm, filepath.Join(t.TempDir(), "traefik.yml"))
+
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/system/runtime", nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"goroutines"`) ||
+			!strings.Contains(rec.Body.String(), `"idempotency"`) {
+			t.Fatalf("unexpected /api/system/runtime response %d: %s", rec.Code, rec.Body.String())
+		}
+
+		rec = httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/system/pprof/heap?debug=1", nil))
+		if enabled && rec.Code != http.StatusOK {
+			t.Errorf("expected the heap profile with pprof enabled, got %d", rec.Code)
+		}
+		if !enabled && rec.Code == http.StatusOK {
+			t.Errorf("pprof must not be served unless enabled")
+		}
+	}
+}
+
+func TestServerAPITokenAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	srv := NewServer(db, ServerConfig{Port: "0", AuthMode: AuthModeWrites, AdminToken: "bootstrap"}, cm,
+		filepath.Join(t.TempDir(), "traefik.yml"))
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, "/api/datasource/active", "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected open reads in writes mode, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/api/traefik-config/invalidate", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a write without a token, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/tokens", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected tokens to need a token even for reads, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/mtls/clients/c1/download", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected client key downloads to need a token even for reads, got %d", rec.Code)
+	}
+
+	rec := serve(http.MethodPost, "/api/tokens", "bootstrap", `{"name":"dashboard","scope":"read"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating a token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.Secret == "" {
+		t.Fatalf("expected the secret in the response: %v", err)
+	}
+
+	if rec := serve(http.MethodPost, "/api/traefik-config/invalidate", created.Secret, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a write with a read token, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/api/traefik-config/invalidate", "bootstrap", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected an admin token to write, got %d", rec.Code)
+	}
+}
+
+func TestServerUserRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	srv := NewServer(db, ServerConfig{Port: "0", AuthMode: AuthModeAll, AdminToken: "bootstrap"}, cm,
+		filepath.Join(t.TempDir(), "traefik.yml"))
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+	tokenFor := func(username, role string) string {
+		rec := serve(http.MethodPost, "/api/users", "bootstrap", `{"username":"`+username+`","role":"`+role+`"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201 creating user %s, got %d: %s", username, rec.Code, rec.Body.String())
+		}
+		var user struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &user)
+		rec = serve(http.MethodPost, "/api/tokens", "bootstrap", `{"name":"`+username+`","scope":"admin","user_id":"`+user.ID+`"}`)
+		var created struct {
+			Secret string `json:"secret"`
+			Role   string `json:"role"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.Role != role {
+			t.Fatalf("expected a %s token, got %d: %s", role, rec.Code, rec.Body.String())
+		}
+		return created.Secret
+	}
+	viewer := tokenFor("alice", "viewer")
+	editor := tokenFor("bob", "editor")
+
+	if rec := serve(http.MethodPost, "/api/users", "bootstrap", `{"username":"alice"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a taken username, got %d", rec.Code)
+	}
+
+	if rec := serve(http.MethodGet, "/api/middlewares", viewer, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected a viewer to read middlewares, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/api/resources/r1/middlewares/m1", viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a viewer removing a middleware, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/api/resources/r1/middlewares/m1", editor, ""); rec.Code == http.StatusForbidden {
+		t.Fatalf("expected an editor to pass the role check, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/api/traefik-config/invalidate", editor, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an editor writing outside resources, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/users", editor, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected users to need an admin role, got %d", rec.Code)
+	}
+	for _, path := range []string{"/api/mtls/clients/c1/download", "/api/system/pprof/heap", "/api/captures/cap1"} {
+		if rec := serve(http.MethodGet, path, viewer, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for a viewer reading %s, got %d", path, rec.Code)
+		}
+	}
+	if rec := serve(http.MethodGet, "/api/mtls/clients/c1", viewer, ""); rec.Code == http.StatusForbidden {
+		t.Errorf("expected a viewer to read a client's certificate details, got %d", rec.Code)
+	}
+}
+
+func TestServerAPITokenAuthAllMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTempDB(t)
+	cm := testutil.NewTestConfigManager(t)
+	srv := NewServer(db, ServerConfig{Port: "0", AuthMode: AuthModeAll, AdminToken: "bootstrap"}, cm,
+		filepath.Join(t.TempDir(), "traefik.yml"))
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/traefik-config/status", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected reads to need a token in all mode, got %d", rec.Code)
+	}
+
+	// forward-auth answers with its own bare 401, not the API token error
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/forward-auth/verify?resource=missing", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Body.Len() != 0 {
+		t.Fatalf("expected forward-auth verify to skip API tokens, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/hhftechnology/middleware-manager/internal/testutil"
+	"github.com/hhftechnology/middleware-manager/models"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSaveTemplateFileIdempotent(t *testing.T) {
@@ -80,3 +82,20 @@ func TestLoadDefaultServiceTemplatesInsertRecords(t *testing.T) {
 		t.Fatalf("expected default service to be inserted")
 	}
 }
+
+func TestDefaultTemplatesMatchMiddlewareSchema(t *testing.T) {
+	data, err := os.ReadFile("templates.yaml")
+	if err != nil {
+		t.Fatalf("read templates: %v", err)
+	}
+	var templates DefaultTemplates
+	if err := yaml.Unmarshal(data, &templates); err != nil {
+		t.Fatalf("parse templates: %v", err)
+	}
+	for _, mw := range templates.Middlewares {
+		result := models.ValidateMiddlewareConfig(mw.Type, mw.Config)
+		if !result.Valid || len(result.Warnings) > 0 {
+			t.Errorf("template %s: errors %+v, warnings %+v", mw.ID, result.Errors, result.Warnings)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/util"
 	"gopkg.in/yaml.v3"
 )
 
@@ -28,32 +29,14 @@ type DefaultTemplates struct {
 
 // LoadDefaultTemplates loads the default middleware templates
 func LoadDefaultTemplates(db *database.DB) error {
-	// Determine the path to the templates file
-	templatesFile := "config/templates.yaml"
-	
-	// Check if the file exists in the current directory
-	if _, err := os.Stat(templatesFile); os.IsNotExist(err) {
-		// Try to find it in different locations
-		possiblePaths := []string{
-			"/app/config/templates.yaml",  // Docker container path
-			"templates.yaml",              // Current directory
-		}
-		
-		found := false
-		for _, path := range possiblePaths {
-			if _, err := os.Stat(path); err == nil {
-				templatesFile = path
-				found = true
-				break
-			}
-		}
-		
-		if !found {
-			log.Printf("Warning: templates.yaml not found, skipping default templates")
-			return nil
-		}
+	// Find the templates file next to the working directory, the executable
+	// or in the Docker image
+	templatesFile := util.FindFile("config/templates.yaml", "/app/config/templates.yaml", "templates.yaml")
+	if templatesFile == "" {
+		log.Printf("Warning: templates.yaml not found, skipping default templates")
+		return nil
 	}
-	
+
 	// Read the templates file
 	data, err := os.ReadFile(templatesFile)
 	if err != nil {
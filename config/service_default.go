@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/util"
 	"gopkg.in/yaml.v3"
 )
 
@@ -26,32 +27,14 @@ type DefaultServiceTemplates struct {
 
 // LoadDefaultServiceTemplates loads the default service templates
 func LoadDefaultServiceTemplates(db *database.DB) error {
-	// Determine the path to the templates file
-	templatesFile := "config/templates_services.yaml"
-	
-	// Check if the file exists in the current directory
-	if _, err := os.Stat(templatesFile); os.IsNotExist(err) {
-		// Try to find it in different locations
-		possiblePaths := []string{
-			"/app/config/templates_services.yaml",  // Docker container path
-			"templates_services.yaml",              // Current directory
-		}
-		
-		found := false
-		for _, path := range possiblePaths {
-			if _, err := os.Stat(path); err == nil {
-				templatesFile = path
-				found = true
-				break
-			}
-		}
-		
-		if !found {
-			log.Printf("Warning: templates_services.yaml not found, skipping default service templates")
-			return nil
-		}
+	// Find the templates file next to the working directory, the executable
+	// or in the Docker image
+	templatesFile := util.FindFile("config/templates_services.yaml", "/app/config/templates_services.yaml", "templates_services.yaml")
+	if templatesFile == "" {
+		log.Printf("Warning: templates_services.yaml not found, skipping default service templates")
+		return nil
 	}
-	
+
 	// Read the templates file
 	data, err := os.ReadFile(templatesFile)
 	if err != nil {
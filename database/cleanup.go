@@ -19,6 +19,8 @@ type CleanupOptions struct {
     MaxDeleteBatch   int  // Maximum number of items to delete in one batch
     ReapDisabled     bool // If true, physically delete disabled resources
     RecoverCorrupted bool // If true, attempt to recover corrupted resources
+    Report           *CleanupReport // If set, every planned change and its reason is recorded here
+    UndoRetention    time.Duration  // How long changed rows can be restored (needs Report for a run ID)
 }
 
 // DefaultCleanupOptions returns the default cleanup options
@@ -29,6 +31,7 @@ func DefaultCleanupOptions() CleanupOptions {
         MaxDeleteBatch:   100,
         ReapDisabled:     false,
         RecoverCorrupted: true,
+        UndoRetention:    DefaultUndoRetention,
     }
 }
 
@@ -48,6 +51,12 @@ func (db *DB) CleanupDuplicateServices(opts CleanupOptions) error {
         log.Println("Starting cleanup of duplicate services...")
     }
     
+    // Protected services are never considered duplicates
+    protected, err := db.ProtectedNames(ProtectedKindService)
+    if err != nil {
+        return err
+    }
+
     // Get all services
     rows, err := db.Query("SELECT id, name, type, config FROM services")
     if err != nil {
@@ -70,6 +79,13 @@ func (db *DB) CleanupDuplicateServices(opts CleanupOptions) error {
             return fmt.Errorf("failed to scan service: %w", err)
         }
         
+        if protected.Contains(id) {
+            if opts.LogLevel >= 2 {
+                log.Printf("Skipping protected service %s", id)
+            }
+            continue
+        }
+
         // Get normalized ID
         normalizedID := normalizeID(id) // Use local function instead of util.NormalizeID
         
@@ -107,6 +123,12 @@ func (db *DB) CleanupDuplicateServices(opts CleanupOptions) error {
                 if opts.LogLevel >= 2 {
                     log.Printf("Duplicate found: keeping %s, will delete %s", id, existing.ID)
                 }
+                opts.Report.Add(CleanupAction{
+                    Target: "service",
+                    Action: CleanupActionDelete,
+                    ID:     existing.ID,
+                    Reason: fmt.Sprintf("duplicate of %s", id),
+                })
                 servicesToDelete = append(servicesToDelete, existing.ID)
                 uniqueServices[normalizedID] = serviceInfo{id, configStr}
             } else {
@@ -114,6 +136,12 @@ func (db *DB) CleanupDuplicateServices(opts CleanupOptions) error {
                 if opts.LogLevel >= 2 {
                     log.Printf("Duplicate found: keeping %s, will delete %s", existing.ID, id)
                 }
+                opts.Report.Add(CleanupAction{
+                    Target: "service",
+                    Action: CleanupActionDelete,
+                    ID:     id,
+                    Reason: fmt.Sprintf("duplicate of %s", existing.ID),
+                })
                 servicesToDelete = append(servicesToDelete, id)
             }
         } else {
@@ -160,6 +188,16 @@ func (db *DB) CleanupDuplicateServices(opts CleanupOptions) error {
             
             batch := servicesToDelete[i:end]
             
+            // Keep the rows so the run can be undone
+            for _, id := range batch {
+                if err := opts.stash(tx, "resource_services", "service_id", id); err != nil {
+                    return err
+                }
+                if err := opts.stash(tx, "services", "id", id); err != nil {
+                    return err
+                }
+            }
+            
             // Use batch DELETE with IN clause for better performance
             if len(batch) > 1 {
                 placeholders := strings.Repeat("?,", len(batch)-1) + "?"
@@ -238,6 +276,10 @@ func (cm *CleanupManager) PerformFullCleanup(opts CleanupOptions) error {
         log.Println("⚠️  Database cleanup starting - this may cause brief service interruptions")
     }
     
+    if err := cm.db.PurgeExpiredUndo(); err != nil {
+        log.Printf("Warning: %v", err)
+    }
+    
     // First clean up services
     if err := cm.db.CleanupDuplicateServices(opts); err != nil {
         return fmt.Errorf("service cleanup failed: %w", err)
@@ -303,6 +345,12 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
     // Find hosts with multiple resources
     var resourcesToDelete []string
     var resourcesToActivate []string
+
+    // Duplicates are disabled unless ReapDisabled asks for physical deletion
+    removeAction := CleanupActionDisable
+    if opts.ReapDisabled {
+        removeAction = CleanupActionDelete
+    }
     
     for host, resources := range hostMap {
         if len(resources) <= 1 {
@@ -361,6 +409,12 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
                     if opts.LogLevel >= 2 {
                         log.Printf("  - Will disable duplicate active resource: %s", res.ID)
                     }
+                    opts.Report.Add(CleanupAction{
+                        Target: "resource",
+                        Action: removeAction,
+                        ID:     res.ID,
+                        Reason: fmt.Sprintf("duplicate active resource for host %s; keeping %s", host, activeResources[bestIdx].ID),
+                    })
                     resourcesToDelete = append(resourcesToDelete, res.ID)
                 } else if opts.LogLevel >= 2 {
                     log.Printf("  - Keeping active resource: %s", res.ID)
@@ -388,6 +442,12 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
             if opts.LogLevel >= 2 {
                 log.Printf("  - Will activate resource: %s", disabledResources[bestIdx].ID)
             }
+            opts.Report.Add(CleanupAction{
+                Target: "resource",
+                Action: CleanupActionActivate,
+                ID:     disabledResources[bestIdx].ID,
+                Reason: fmt.Sprintf("no active resource for host %s", host),
+            })
             resourcesToActivate = append(resourcesToActivate, disabledResources[bestIdx].ID)
             
             // If reaping disabled resources, delete the rest
@@ -397,6 +457,12 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
                         if opts.LogLevel >= 2 {
                             log.Printf("  - Will delete disabled resource: %s", res.ID)
                         }
+                        opts.Report.Add(CleanupAction{
+                            Target: "resource",
+                            Action: CleanupActionDelete,
+                            ID:     res.ID,
+                            Reason: fmt.Sprintf("disabled duplicate for host %s; %s was reactivated", host, disabledResources[bestIdx].ID),
+                        })
                         resourcesToDelete = append(resourcesToDelete, res.ID)
                     }
                 }
@@ -407,6 +473,12 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
                 if opts.LogLevel >= 2 {
                     log.Printf("  - Will delete disabled resource: %s", res.ID)
                 }
+                opts.Report.Add(CleanupAction{
+                    Target: "resource",
+                    Action: CleanupActionDelete,
+                    ID:     res.ID,
+                    Reason: fmt.Sprintf("disabled duplicate for host %s", host),
+                })
                 resourcesToDelete = append(resourcesToDelete, res.ID)
             }
         }
@@ -433,6 +505,10 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
                 log.Printf("Activating resource: %s", id)
             }
             
+            if err := opts.stash(tx, "resources", "id", id); err != nil {
+                return err
+            }
+            
             _, err := tx.Exec(
                 "UPDATE resources SET status = 'active', updated_at = ? WHERE id = ?",
                 time.Now(), id,
@@ -445,7 +521,20 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
         
         // Delete or disable resources
         for _, id := range resourcesToDelete {
+            // Keep the rows so the run can be undone
+            if err := opts.stash(tx, "resources", "id", id); err != nil {
+                return err
+            }
+            
             if opts.ReapDisabled {
+                if err := opts.stash(tx, "resource_middlewares", "resource_id", id); err != nil {
+                    return err
+                }
+                if err := opts.stash(tx, "resource_services", "resource_id", id); err != nil {
+                    return err
+                }
+                
+
                 // Physically delete the resource
                 if opts.LogLevel >= 1 {
                     log.Printf("Deleting resource: %s", id)
@@ -492,6 +581,10 @@ func (db *DB) CleanupDuplicateResources(opts CleanupOptions) error {
 
 // PerformFullCleanup runs a comprehensive cleanup of the database
 func (db *DB) PerformFullCleanup(opts CleanupOptions) error {
+    if err := db.PurgeExpiredUndo(); err != nil {
+        log.Printf("Warning: %v", err)
+    }
+    
     // First clean up services
     if err := db.CleanupDuplicateServices(opts); err != nil {
         return fmt.Errorf("service cleanup failed: %w", err)
@@ -519,6 +612,8 @@ func (db *DB) CleanupOrphanedRelationships(opts CleanupOptions) error {
         {"orphaned resource_services by missing resource", "SELECT COUNT(*) FROM resource_services rs LEFT JOIN resources r ON rs.resource_id = r.id WHERE r.id IS NULL"},
         {"orphaned resource_middlewares by missing middleware", "SELECT COUNT(*) FROM resource_middlewares rm LEFT JOIN middlewares m ON rm.middleware_id = m.id WHERE m.id IS NULL"},
         {"orphaned resource_middlewares by missing resource", "SELECT COUNT(*) FROM resource_middlewares rm LEFT JOIN resources r ON rm.resource_id = r.id WHERE r.id IS NULL"},
+        {"orphaned resource_group_members by missing resource", "SELECT COUNT(*) FROM resource_group_members gm LEFT JOIN resources r ON gm.resource_id = r.id WHERE r.id IS NULL"},
+        {"orphaned header_policy_assignments by missing resource", "SELECT COUNT(*) FROM header_policy_assignments a LEFT JOIN resources r ON a.target_id = r.id WHERE a.target_type = 'resource' AND r.id IS NULL"},
     }
 
     // Dry run: just report counts
@@ -533,6 +628,14 @@ func (db *DB) CleanupOrphanedRelationships(opts CleanupOptions) error {
                 continue
             }
             log.Printf("DRY RUN: %s: %d", q.desc, count)
+            if count > 0 {
+                opts.Report.Add(CleanupAction{
+                    Target: "relationship",
+                    Action: CleanupActionDelete,
+                    Count:  count,
+                    Reason: q.desc,
+                })
+            }
         }
         return nil
     }
@@ -547,6 +650,8 @@ func (db *DB) CleanupOrphanedRelationships(opts CleanupOptions) error {
             {"delete resource_services with missing resource", "DELETE FROM resource_services WHERE resource_id NOT IN (SELECT id FROM resources)"},
             {"delete resource_middlewares with missing middleware", "DELETE FROM resource_middlewares WHERE middleware_id NOT IN (SELECT id FROM middlewares)"},
             {"delete resource_middlewares with missing resource", "DELETE FROM resource_middlewares WHERE resource_id NOT IN (SELECT id FROM resources)"},
+            {"delete resource_group_members with missing resource", "DELETE FROM resource_group_members WHERE resource_id NOT IN (SELECT id FROM resources)"},
+            {"delete header_policy_assignments with missing resource", "DELETE FROM header_policy_assignments WHERE target_type = 'resource' AND target_id NOT IN (SELECT id FROM resources)"},
         }
 
         for _, dq := range delQueries {
@@ -554,8 +659,17 @@ func (db *DB) CleanupOrphanedRelationships(opts CleanupOptions) error {
             if err != nil {
                 return fmt.Errorf("failed to %s: %w", dq.desc, err)
             }
+            n, countErr := res.RowsAffected()
+            if countErr == nil && n > 0 {
+                opts.Report.Add(CleanupAction{
+                    Target: "relationship",
+                    Action: CleanupActionDelete,
+                    Count:  n,
+                    Reason: dq.desc,
+                })
+            }
             if opts.LogLevel >= 1 {
-                if n, err := res.RowsAffected(); err == nil {
+                if countErr == nil {
                     log.Printf("Deleted %d rows: %s", n, dq.desc)
                 } else {
                     log.Printf("Deleted rows (unknown count): %s", dq.desc)
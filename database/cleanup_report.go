@@ -0,0 +1,130 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cleanup action kinds
+const (
+	CleanupActionDelete   = "delete"
+	CleanupActionDisable  = "disable"
+	CleanupActionActivate = "activate"
+)
+
+// CleanupAction records one change a cleanup run made, or would make in dry-run mode
+type CleanupAction struct {
+	Target string `json:"target"` // service, resource or relationship
+	Action string `json:"action"`
+	ID     string `json:"id,omitempty"`
+	Count  int64  `json:"count,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// CleanupReport is the structured result of a cleanup run
+type CleanupReport struct {
+	RunID            string          `json:"run_id"`
+	DryRun           bool            `json:"dry_run"`
+	ReapDisabled     bool            `json:"reap_disabled"`
+	RecoverCorrupted bool            `json:"recover_corrupted"`
+	StartedAt        time.Time       `json:"started_at"`
+	FinishedAt       time.Time       `json:"finished_at"`
+	Error            string          `json:"error,omitempty"`
+	Actions          []CleanupAction `json:"actions"`
+	Summary          map[string]int  `json:"summary"`
+}
+
+// NewCleanupReport starts a report for a run with the given options
+func NewCleanupReport(opts CleanupOptions) *CleanupReport {
+	return &CleanupReport{
+		RunID:            uuid.New().String(),
+		DryRun:           opts.DryRun,
+		ReapDisabled:     opts.ReapDisabled,
+		RecoverCorrupted: opts.RecoverCorrupted,
+		StartedAt:        time.Now(),
+		Actions:          []CleanupAction{},
+		Summary:          map[string]int{},
+	}
+}
+
+// Add records an action; it is a no-op on a nil report so callers need not check
+func (r *CleanupReport) Add(action CleanupAction) {
+	if r == nil {
+		return
+	}
+	r.Actions = append(r.Actions, action)
+	n := 1
+	if action.Count > 0 {
+		n = int(action.Count)
+	}
+	r.Summary[action.Target+"_"+action.Action] += n
+}
+
+// Finish stamps the end time and any error
+func (r *CleanupReport) Finish(err error) {
+	if r == nil {
+		return
+	}
+	r.FinishedAt = time.Now()
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// SaveCleanupReport stores a finished report so it can be retrieved later
+func (db *DB) SaveCleanupReport(report *CleanupReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode cleanup report: %w", err)
+	}
+	_, err = db.Exec(
+		"INSERT INTO cleanup_runs (id, dry_run, report, created_at) VALUES (?, ?, ?, ?)",
+		report.RunID, report.DryRun, string(data), report.StartedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save cleanup report: %w", err)
+	}
+	return nil
+}
+
+// GetCleanupReport loads a stored report by run ID
+func (db *DB) GetCleanupReport(runID string) (*CleanupReport, error) {
+	var data string
+	if err := db.QueryRow("SELECT report FROM cleanup_runs WHERE id = ?", runID).Scan(&data); err != nil {
+		return nil, err
+	}
+	var report CleanupReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("failed to decode cleanup report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListCleanupReports returns stored reports, newest first
+func (db *DB) ListCleanupReports(limit int) ([]*CleanupReport, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := db.Query("SELECT report FROM cleanup_runs ORDER BY created_at DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cleanup runs: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*CleanupReport{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan cleanup run: %w", err)
+		}
+		var report CleanupReport
+		if err := json.Unmarshal([]byte(data), &report); err != nil {
+			continue
+		}
+		reports = append(reports, &report)
+	}
+	return reports, rows.Err()
+}
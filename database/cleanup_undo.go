@@ -0,0 +1,229 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// DefaultUndoRetention is how long rows changed by a cleanup run can be restored
+const DefaultUndoRetention = 7 * 24 * time.Hour
+
+// undoTimeFormat matches how the sqlite driver stores time values, so restored
+// timestamps read back as time.Time
+const undoTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// stash copies the rows of table where column = value into cleanup_undo before
+// a cleanup run changes or deletes them. It is a no-op without a report, since
+// the report's run ID is what an undo refers to.
+func (opts CleanupOptions) stash(tx *sql.Tx, table, column string, value interface{}) error {
+	if opts.Report == nil {
+		return nil
+	}
+	retention := opts.UndoRetention
+	if retention <= 0 {
+		retention = DefaultUndoRetention
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", table, column), value)
+	if err != nil {
+		return fmt.Errorf("failed to read %s rows for undo: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to read %s columns for undo: %w", table, err)
+	}
+
+	var stashed []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("failed to scan %s row for undo: %w", table, err)
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			switch v := values[i].(type) {
+			case []byte:
+				row[col] = string(v)
+			case time.Time:
+				row[col] = v.Format(undoTimeFormat)
+			default:
+				row[col] = v
+			}
+		}
+		data, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s row for undo: %w", table, err)
+		}
+		stashed = append(stashed, string(data))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating %s rows for undo: %w", table, err)
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, data := range stashed {
+		if _, err := tx.Exec(
+			"INSERT INTO cleanup_undo (run_id, table_name, row_data, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+			opts.Report.RunID, table, data, now, now.Add(retention),
+		); err != nil {
+			return fmt.Errorf("failed to stash %s row for undo: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// UndoCleanupRun restores every row a cleanup run changed or deleted, as long as
+// its undo window has not expired. It returns the number of rows restored, or
+// sql.ErrNoRows when there is nothing left to undo for the run.
+func (db *DB) UndoCleanupRun(runID string) (int, error) {
+	restored := 0
+	err := db.WithTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(
+			"SELECT table_name, row_data FROM cleanup_undo WHERE run_id = ? AND expires_at > ? ORDER BY id",
+			runID, time.Now(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to query undo rows: %w", err)
+		}
+
+		type undoRow struct {
+			table string
+			data  string
+		}
+		var pending []undoRow
+		for rows.Next() {
+			var r undoRow
+			if err := rows.Scan(&r.table, &r.data); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan undo row: %w", err)
+			}
+			pending = append(pending, r)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return fmt.Errorf("error iterating undo rows: %w", err)
+		}
+		rows.Close()
+
+		if len(pending) == 0 {
+			return sql.ErrNoRows
+		}
+
+		for _, r := range pending {
+			if err := restoreRow(tx, r.table, r.data); err != nil {
+				return err
+			}
+			restored++
+		}
+
+		if _, err := tx.Exec("DELETE FROM cleanup_undo WHERE run_id = ?", runID); err != nil {
+			return fmt.Errorf("failed to clear undo rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+// restoreRow writes a stashed row back, replacing whatever now has the same key
+func restoreRow(tx *sql.Tx, table, data string) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	var row map[string]interface{}
+	if err := decoder.Decode(&row); err != nil {
+		return fmt.Errorf("failed to decode undo row for %s: %w", table, err)
+	}
+
+	columns := make([]string, 0, len(row))
+	args := make([]interface{}, 0, len(row))
+	for col, value := range row {
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				value = i
+			} else if f, err := n.Float64(); err == nil {
+				value = f
+			}
+		}
+		columns = append(columns, col)
+		args = append(args, value)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+	query := fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+	if IsPostgres() {
+		key, err := primaryKey(tx, table)
+		if err != nil {
+			return err
+		}
+		updates := make([]string, 0, len(columns))
+		for _, col := range columns {
+			updates = append(updates, col+" = excluded."+col)
+		}
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT(%s) DO UPDATE SET %s",
+			table, strings.Join(columns, ", "), placeholders, strings.Join(key, ", "), strings.Join(updates, ", "))
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to restore %s row: %w", table, err)
+	}
+	return nil
+}
+
+// primaryKey returns the primary key columns of a PostgreSQL table, which
+// its upserts name as the conflict target
+func primaryKey(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+		  ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+		WHERE tc.table_schema = current_schema() AND tc.table_name = ? AND tc.constraint_type = 'PRIMARY KEY'
+		ORDER BY kcu.ordinal_position
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the primary key of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var key []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		key = append(key, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%s has no primary key to restore rows by", table)
+	}
+	return key, nil
+}
+
+// PurgeExpiredUndo drops stashed rows whose undo window has passed
+func (db *DB) PurgeExpiredUndo() error {
+	result, err := db.Exec("DELETE FROM cleanup_undo WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to purge expired undo rows: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		log.Printf("Purged %d expired cleanup undo rows", n)
+	}
+	return nil
+}
@@ -125,3 +125,170 @@ func TestCleanupDuplicateServices(t *testing.T) {
 		t.Fatalf("expected relationships cleaned up, got %d", count)
 	}
 }
+
+func TestCleanupDuplicateServicesSkipsProtected(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	mustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES (?, ?, ?, ?)`,
+		"keep@file", "keep", "loadBalancer", "{}")
+	mustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES (?, ?, ?, ?)`,
+		"keep", "keep", "loadBalancer", "{}")
+	mustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES (?, ?, ?, ?)`,
+		"svc@file", "svc", "loadBalancer", "{}")
+	mustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES (?, ?, ?, ?)`,
+		"svc", "svc", "loadBalancer", "{}")
+	mustExec(t, db, `INSERT INTO protected_names (kind, name) VALUES (?, ?)`, ProtectedKindService, "keep")
+
+	if err := db.CleanupDuplicateServices(DefaultCleanupOptions()); err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM services WHERE id LIKE 'keep%'`).Scan(&count); err != nil {
+		t.Fatalf("count query failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected both protected services to remain, got %d", count)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM services WHERE id LIKE 'svc%'`).Scan(&count); err != nil {
+		t.Fatalf("count query failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected unprotected duplicates to be cleaned up, got %d", count)
+	}
+}
+
+func TestProtectedSetContains(t *testing.T) {
+	set := ProtectedSet{"auth": {}, "cache@docker": {}}
+	tests := map[string]bool{
+		"auth":         true,
+		"auth@file":    true,
+		"cache@docker": true,
+		"cache@file":   false,
+		"cache":        false,
+		"other":        false,
+	}
+	for name, want := range tests {
+		if got := set.Contains(name); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCleanupDryRunReport(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	mustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES (?, ?, ?, ?)`,
+		"svc@file", "svc", "loadBalancer", "{}")
+	mustExec(t, db, `INSERT INTO services (id, name, type, config) VALUES (?, ?, ?, ?)`,
+		"svc", "svc", "loadBalancer", "{}")
+	mustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status) VALUES (?, ?, ?, ?, ?, ?)`,
+		"res1", "example.com", "svc", "org", "site", "active")
+	mustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status) VALUES (?, ?, ?, ?, ?, ?)`,
+		"res1-router-auth", "example.com", "svc", "org", "site", "active")
+
+	opts := DefaultCleanupOptions()
+	opts.DryRun = true
+	opts.Report = NewCleanupReport(opts)
+	if err := NewCleanupManager(db).PerformFullCleanup(opts); err != nil {
+		t.Fatalf("dry-run cleanup failed: %v", err)
+	}
+	opts.Report.Finish(nil)
+
+	var serviceAction, resourceAction *CleanupAction
+	for i := range opts.Report.Actions {
+		action := &opts.Report.Actions[i]
+		switch action.Target {
+		case "service":
+			serviceAction = action
+		case "resource":
+			resourceAction = action
+		}
+	}
+	if serviceAction == nil || serviceAction.ID != "svc@file" || serviceAction.Action != CleanupActionDelete ||
+		!strings.Contains(serviceAction.Reason, "svc") {
+		t.Fatalf("unexpected service action: %+v", serviceAction)
+	}
+	if resourceAction == nil || resourceAction.ID != "res1" || resourceAction.Action != CleanupActionDisable {
+		t.Fatalf("unexpected resource action: %+v", resourceAction)
+	}
+
+	// Dry run must leave the data untouched
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM services`).Scan(&count); err != nil {
+		t.Fatalf("count query failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("dry run deleted services, %d remain", count)
+	}
+
+	if err := db.SaveCleanupReport(opts.Report); err != nil {
+		t.Fatalf("save report failed: %v", err)
+	}
+	stored, err := db.GetCleanupReport(opts.Report.RunID)
+	if err != nil {
+		t.Fatalf("get report failed: %v", err)
+	}
+	if !stored.DryRun || len(stored.Actions) != len(opts.Report.Actions) || stored.Summary["service_delete"] != 1 {
+		t.Fatalf("stored report does not match: %+v", stored)
+	}
+}
+
+func TestUndoCleanupRunRestoresReapedResources(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	mustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status) VALUES (?, ?, ?, ?, ?, ?)`,
+		"res1", "example.com", "svc", "org", "site", "active")
+	mustExec(t, db, `INSERT INTO resources (id, host, service_id, org_id, site_id, status) VALUES (?, ?, ?, ?, ?, ?)`,
+		"res2", "example.com", "svc", "org", "site", "disabled")
+	mustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES (?, ?, ?, ?)`,
+		"mw", "mw", "headers", "{}")
+	mustExec(t, db, `INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES (?, ?, ?)`,
+		"res2", "mw", 150)
+
+	opts := DefaultCleanupOptions()
+	opts.ReapDisabled = true
+	opts.Report = NewCleanupReport(opts)
+	if err := db.CleanupDuplicateResources(opts); err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM resources WHERE id = 'res2'`).Scan(&count); err != nil {
+		t.Fatalf("count query failed: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected res2 to be reaped")
+	}
+
+	restored, err := db.UndoCleanupRun(opts.Report.RunID)
+	if err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if restored != 2 {
+		t.Fatalf("expected 2 rows restored, got %d", restored)
+	}
+
+	var status string
+	var createdAt time.Time
+	if err := db.QueryRow(`SELECT status, created_at FROM resources WHERE id = 'res2'`).Scan(&status, &createdAt); err != nil {
+		t.Fatalf("restored resource missing: %v", err)
+	}
+	if status != "disabled" || createdAt.IsZero() {
+		t.Fatalf("unexpected restored resource: status=%s created_at=%v", status, createdAt)
+	}
+	var priority int
+	if err := db.QueryRow(`SELECT priority FROM resource_middlewares WHERE resource_id = 'res2'`).Scan(&priority); err != nil {
+		t.Fatalf("restored relationship missing: %v", err)
+	}
+	if priority != 150 {
+		t.Fatalf("expected priority 150, got %d", priority)
+	}
+
+	if _, err := db.UndoCleanupRun(opts.Report.RunID); err != sql.ErrNoRows {
+		t.Fatalf("expected second undo to find nothing, got %v", err)
+	}
+}
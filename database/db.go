@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/hhftechnology/middleware-manager/util"
 )
 
 // import "github.com/hhftechnology/middleware-manager/config"
@@ -56,7 +58,8 @@ type TraefikConfig struct {
 }
 
 func NewDB(dbPath string) (*DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	driverName, _ := openArgs(dbPath)
+	db, err := sql.Open(driverName, dbPath)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +88,7 @@ func InitDB(dbPath string) (*DB, error) {
 	}
 
 	// Open the database with pragmas for better reliability
-	db, err := sql.Open("sqlite3", dbPath+"?_journal=WAL&_busy_timeout=5000")
+	db, err := sql.Open(openArgs(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -101,7 +104,7 @@ func InitDB(dbPath string) (*DB, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(30 * time.Minute)
 
-	log.Printf("Connected to database at %s", dbPath)
+	log.Printf("Connected to database at %s (%s SQLite driver)", dbPath, DriverName())
 
 	// Run migrations
 	if err := runMigrations(db); err != nil {
@@ -646,47 +649,396 @@ func runPostMigrationUpdates(db *sql.DB) error {
 		log.Println("Successfully created resource_external_middlewares table")
 	}
 
+	// Check for cors_policy_id column in resources table
+	var hasCORSPolicyColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'cors_policy_id'
+	`).Scan(&hasCORSPolicyColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if cors_policy_id column exists: %w", err)
+	}
+	if !hasCORSPolicyColumn {
+		log.Println("Adding cors_policy_id column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN cors_policy_id TEXT DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add cors_policy_id column: %w", err)
+		}
+		log.Println("Successfully added cors_policy_id column")
+	}
+
+	// Check for forward_auth_enabled column in resources table
+	var hasForwardAuthColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'forward_auth_enabled'
+	`).Scan(&hasForwardAuthColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if forward_auth_enabled column exists: %w", err)
+	}
+	if !hasForwardAuthColumn {
+		log.Println("Adding forward_auth_enabled column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN forward_auth_enabled INTEGER DEFAULT 0"); err != nil {
+			return fmt.Errorf("failed to add forward_auth_enabled column: %w", err)
+		}
+		log.Println("Successfully added forward_auth_enabled column")
+	}
+
+	// Check for rotation grace-period columns in forward_auth_tokens table
+	var hasPreviousTokenColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('forward_auth_tokens')
+		WHERE name = 'previous_token_hash'
+	`).Scan(&hasPreviousTokenColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if previous_token_hash column exists: %w", err)
+	}
+	if !hasPreviousTokenColumn {
+		log.Println("Adding rotation columns to forward_auth_tokens table")
+		if _, err := db.Exec("ALTER TABLE forward_auth_tokens ADD COLUMN previous_token_hash TEXT DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add previous_token_hash column: %w", err)
+		}
+		if _, err := db.Exec("ALTER TABLE forward_auth_tokens ADD COLUMN previous_totp_secret TEXT DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add previous_totp_secret column: %w", err)
+		}
+		if _, err := db.Exec("ALTER TABLE forward_auth_tokens ADD COLUMN previous_expires_at TIMESTAMP"); err != nil {
+			return fmt.Errorf("failed to add previous_expires_at column: %w", err)
+		}
+		log.Println("Successfully added rotation columns to forward_auth_tokens table")
+	}
+
+	// Check for totp_last_step column in forward_auth_tokens table
+	var hasTOTPLastStepColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('forward_auth_tokens')
+		WHERE name = 'totp_last_step'
+	`).Scan(&hasTOTPLastStepColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if totp_last_step column exists: %w", err)
+	}
+	if !hasTOTPLastStepColumn {
+		log.Println("Adding totp_last_step column to forward_auth_tokens table")
+		if _, err := db.Exec("ALTER TABLE forward_auth_tokens ADD COLUMN totp_last_step INTEGER"); err != nil {
+			return fmt.Errorf("failed to add totp_last_step column: %w", err)
+		}
+		log.Println("Successfully added totp_last_step column")
+	}
+
+	// Check for public_pki_enabled column in mtls_config table
+	var hasPublicPKIColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('mtls_config')
+		WHERE name = 'public_pki_enabled'
+	`).Scan(&hasPublicPKIColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if public_pki_enabled column exists: %w", err)
+	}
+	if !hasPublicPKIColumn {
+		log.Println("Adding public_pki_enabled column to mtls_config table")
+		if _, err := db.Exec("ALTER TABLE mtls_config ADD COLUMN public_pki_enabled INTEGER DEFAULT 0"); err != nil {
+			return fmt.Errorf("failed to add public_pki_enabled column: %w", err)
+		}
+		log.Println("Successfully added public_pki_enabled column")
+	}
+
+	// Check for https_redirect column in resources table
+	var hasHTTPSRedirectColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'https_redirect'
+	`).Scan(&hasHTTPSRedirectColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if https_redirect column exists: %w", err)
+	}
+	if !hasHTTPSRedirectColumn {
+		log.Println("Adding https_redirect column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN https_redirect TEXT DEFAULT 'inherit'"); err != nil {
+			return fmt.Errorf("failed to add https_redirect column: %w", err)
+		}
+		log.Println("Successfully added https_redirect column")
+	}
+
+	// Check for version columns used for optimistic concurrency on edits
+	for _, table := range []string{"middlewares", "resources"} {
+		var hasVersionColumn bool
+		err = db.QueryRow(`
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info(?)
+			WHERE name = 'version'
+		`, table).Scan(&hasVersionColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check if version column exists in %s: %w", table, err)
+		}
+		if !hasVersionColumn {
+			log.Printf("Adding version column to %s table", table)
+			if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN version INTEGER NOT NULL DEFAULT 1"); err != nil {
+				return fmt.Errorf("failed to add version column to %s: %w", table, err)
+			}
+			log.Printf("Successfully added version column to %s", table)
+		}
+	}
+
+	// Check for notes and ownership columns on resources and middlewares
+	for _, table := range []string{"middlewares", "resources"} {
+		for _, column := range []string{"notes", "owner", "contact"} {
+			var hasColumn bool
+			err = db.QueryRow(`
+				SELECT COUNT(*) > 0
+				FROM pragma_table_info(?)
+				WHERE name = ?
+			`, table, column).Scan(&hasColumn)
+			if err != nil {
+				return fmt.Errorf("failed to check if %s column exists in %s: %w", column, table, err)
+			}
+			if !hasColumn {
+				log.Printf("Adding %s column to %s table", column, table)
+				if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " TEXT NOT NULL DEFAULT ''"); err != nil {
+					return fmt.Errorf("failed to add %s column to %s: %w", column, table, err)
+				}
+			}
+		}
+	}
+
+	// Check for expires_at column on middleware assignments (temporary assignments)
+	var hasAssignmentExpiryColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resource_middlewares')
+		WHERE name = 'expires_at'
+	`).Scan(&hasAssignmentExpiryColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if expires_at column exists in resource_middlewares: %w", err)
+	}
+	if !hasAssignmentExpiryColumn {
+		log.Println("Adding expires_at column to resource_middlewares table")
+		if _, err := db.Exec("ALTER TABLE resource_middlewares ADD COLUMN expires_at TIMESTAMP"); err != nil {
+			return fmt.Errorf("failed to add expires_at column to resource_middlewares: %w", err)
+		}
+		log.Println("Successfully added expires_at column to resource_middlewares")
+	}
+
+	// Check for mtls_exempt_paths column in resources table
+	var hasMTLSExemptPathsColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'mtls_exempt_paths'
+	`).Scan(&hasMTLSExemptPathsColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if mtls_exempt_paths column exists: %w", err)
+	}
+	if !hasMTLSExemptPathsColumn {
+		log.Println("Adding mtls_exempt_paths column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN mtls_exempt_paths TEXT NOT NULL DEFAULT '[]'"); err != nil {
+			return fmt.Errorf("failed to add mtls_exempt_paths column: %w", err)
+		}
+		log.Println("Successfully added mtls_exempt_paths column")
+	}
+
+	// Check for tls_hardening_profile column in resources table
+	var hasTLSHardeningProfileColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'tls_hardening_profile'
+	`).Scan(&hasTLSHardeningProfileColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if tls_hardening_profile column exists: %w", err)
+	}
+	if !hasTLSHardeningProfileColumn {
+		log.Println("Adding tls_hardening_profile column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN tls_hardening_profile TEXT NOT NULL DEFAULT 'hardened'"); err != nil {
+			return fmt.Errorf("failed to add tls_hardening_profile column: %w", err)
+		}
+		log.Println("Successfully added tls_hardening_profile column")
+	}
+
+	// Check for tls_hardening_mode column in security_config table
+	var hasTLSHardeningModeColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('security_config')
+		WHERE name = 'tls_hardening_mode'
+	`).Scan(&hasTLSHardeningModeColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if tls_hardening_mode column exists: %w", err)
+	}
+	if !hasTLSHardeningModeColumn {
+		log.Println("Adding tls_hardening_mode column to security_config table")
+		if _, err := db.Exec("ALTER TABLE security_config ADD COLUMN tls_hardening_mode TEXT NOT NULL DEFAULT 'per_resource'"); err != nil {
+			return fmt.Errorf("failed to add tls_hardening_mode column: %w", err)
+		}
+		log.Println("Successfully added tls_hardening_mode column")
+	}
+
+	// Check for tls_hardening_opt_out column in resources table
+	var hasTLSHardeningOptOutColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'tls_hardening_opt_out'
+	`).Scan(&hasTLSHardeningOptOutColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if tls_hardening_opt_out column exists: %w", err)
+	}
+	if !hasTLSHardeningOptOutColumn {
+		log.Println("Adding tls_hardening_opt_out column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN tls_hardening_opt_out INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return fmt.Errorf("failed to add tls_hardening_opt_out column: %w", err)
+		}
+		log.Println("Successfully added tls_hardening_opt_out column")
+	}
+
+	// Check for secure_headers_preset column in resources table
+	var hasSecureHeadersPresetColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'secure_headers_preset'
+	`).Scan(&hasSecureHeadersPresetColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if secure_headers_preset column exists: %w", err)
+	}
+	if !hasSecureHeadersPresetColumn {
+		log.Println("Adding secure_headers_preset column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN secure_headers_preset TEXT NOT NULL DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add secure_headers_preset column: %w", err)
+		}
+		log.Println("Successfully added secure_headers_preset column")
+	}
+
+	// Check for secure_headers_report_only column in resources table
+	var hasSecureHeadersReportOnlyColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('resources')
+		WHERE name = 'secure_headers_report_only'
+	`).Scan(&hasSecureHeadersReportOnlyColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if secure_headers_report_only column exists: %w", err)
+	}
+	if !hasSecureHeadersReportOnlyColumn {
+		log.Println("Adding secure_headers_report_only column to resources table")
+		if _, err := db.Exec("ALTER TABLE resources ADD COLUMN secure_headers_report_only INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return fmt.Errorf("failed to add secure_headers_report_only column: %w", err)
+		}
+		log.Println("Successfully added secure_headers_report_only column")
+	}
+
+	// Check for structured secure header columns in security_config table
+	for _, column := range []struct{ name, definition string }{
+		{"secure_headers_permissions_policy_directives", "TEXT NOT NULL DEFAULT '[]'"},
+		{"secure_headers_coop", "TEXT NOT NULL DEFAULT ''"},
+		{"secure_headers_coep", "TEXT NOT NULL DEFAULT ''"},
+		{"secure_headers_corp", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		var hasColumn bool
+		err = db.QueryRow(`
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info('security_config')
+			WHERE name = ?
+		`, column.name).Scan(&hasColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check if %s column exists: %w", column.name, err)
+		}
+		if !hasColumn {
+			log.Printf("Adding %s column to security_config table", column.name)
+			if _, err := db.Exec("ALTER TABLE security_config ADD COLUMN " + column.name + " " + column.definition); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", column.name, err)
+			}
+		}
+	}
+
+	// Check for pinning, default chain and middleware order columns in resources table
+	for _, column := range []struct{ name, definition string }{
+		{"pinned", "INTEGER NOT NULL DEFAULT 0"},
+		{"pin_divergence", "TEXT NOT NULL DEFAULT '[]'"},
+		{"default_chain_excluded", "INTEGER NOT NULL DEFAULT 0"},
+		{"middleware_order", "TEXT NOT NULL DEFAULT 'prepend'"},
+		{"middleware_positions", "TEXT NOT NULL DEFAULT '{}'"},
+		{"upstream_middleware_removals", "TEXT NOT NULL DEFAULT '[]'"},
+	} {
+		var hasColumn bool
+		err = db.QueryRow(`
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info('resources')
+			WHERE name = ?
+		`, column.name).Scan(&hasColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check if %s column exists: %w", column.name, err)
+		}
+		if !hasColumn {
+			log.Printf("Adding %s column to resources table", column.name)
+			if _, err := db.Exec("ALTER TABLE resources ADD COLUMN " + column.name + " " + column.definition); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", column.name, err)
+			}
+		}
+	}
+
+	// Check for user_id column in api_tokens table (tokens issued to users)
+	var hasTokenUserColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('api_tokens')
+		WHERE name = 'user_id'
+	`).Scan(&hasTokenUserColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if user_id column exists in api_tokens: %w", err)
+	}
+	if !hasTokenUserColumn {
+		log.Println("Adding user_id column to api_tokens table")
+		if _, err := db.Exec("ALTER TABLE api_tokens ADD COLUMN user_id TEXT NOT NULL DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add user_id column to api_tokens: %w", err)
+		}
+	}
+
+	// Check for signer column in mtls_clients table (certificates issued by an external CA)
+	var hasClientSignerColumn bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('mtls_clients')
+		WHERE name = 'signer'
+	`).Scan(&hasClientSignerColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if signer column exists in mtls_clients: %w", err)
+	}
+	if !hasClientSignerColumn {
+		log.Println("Adding signer column to mtls_clients table")
+		if _, err := db.Exec("ALTER TABLE mtls_clients ADD COLUMN signer TEXT NOT NULL DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add signer column to mtls_clients: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // findMigrationsFile tries to find the migrations file in different locations
 func findMigrationsFile() string {
-	possiblePaths := []string{
+	return util.FindFile(
 		"database/migrations.sql",
 		"migrations.sql",
 		"../database/migrations.sql",
 		"../../database/migrations.sql",
 		"/app/database/migrations.sql",
 		"/app/migrations.sql",
-	}
-
-	for _, path := range possiblePaths {
-		if _, err := os.Stat(path); err == nil {
-			return path
-		}
-	}
-
-	return ""
+	)
 }
 
 // findServiceMigrationsFile tries to find the service migrations file in different locations
 func findServiceMigrationsFile() string {
-	possiblePaths := []string{
+	return util.FindFile(
 		"database/migrations_service.sql",
 		"migrations_service.sql",
 		"../database/migrations_service.sql",
 		"../../database/migrations_service.sql",
 		"/app/database/migrations_service.sql",
 		"/app/migrations_service.sql",
-	}
-
-	for _, path := range possiblePaths {
-		if _, err := os.Stat(path); err == nil {
-			return path
-		}
-	}
-
-	return ""
+	)
 }
 
 // GetMiddlewares fetches all middleware definitions
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SQLite drivers MM can open the database with. The cgo driver
+// (mattn/go-sqlite3) is the default where it is compiled in. The pure-Go
+// driver (modernc.org/sqlite) needs no C toolchain or libc, so static
+// binaries run on Alpine, scratch and architectures without a cross
+// compiler; it is the only driver in CGO_ENABLED=0 or -tags purego builds.
+const (
+	DriverCGO    = "cgo"
+	DriverPureGo = "purego"
+)
+
+// SQLite result codes MM reacts to; extended codes carry the primary code
+// in their low byte
+const (
+	sqliteBusy             = 5
+	sqliteLocked           = 6
+	sqliteConstraintUnique = 2067
+	sqliteConstraintPK     = 1555
+)
+
+// PostgreSQL SQLSTATEs MM reacts to
+const (
+	postgresUniqueViolation      = "23505"
+	postgresSerializationFailure = "40001"
+	postgresDeadlock             = "40P01"
+	postgresLockNotAvailable     = "55P03"
+)
+
+// sqliteDriver describes one SQLite driver: the database/sql name it is
+// registered under, how it takes connection pragmas and how to read the
+// SQLite result code from its errors
+type sqliteDriver struct {
+	sqlName string
+	dsn     func(path string) string
+	code    func(err error) (int, bool)
+}
+
+var (
+	drivers      = map[string]sqliteDriver{}
+	activeDriver string
+)
+
+func registerDriver(name string, driver sqliteDriver) {
+	drivers[name] = driver
+	if activeDriver == "" || name == DriverCGO {
+		activeDriver = name
+	}
+}
+
+// UseDriver selects the SQLite driver InitDB opens the database with. An
+// empty name keeps the default.
+func UseDriver(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	if _, ok := drivers[name]; !ok {
+		return fmt.Errorf("SQLite driver %q is not available in this build (available: %s)",
+			name, strings.Join(AvailableDrivers(), ", "))
+	}
+	activeDriver = name
+	return nil
+}
+
+// DriverName returns the SQLite driver in use, or DriverPostgres
+func DriverName() string {
+	return activeDriver
+}
+
+// AvailableDrivers returns the SQLite drivers compiled into this build
+func AvailableDrivers() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openArgs returns the database/sql driver name and DSN for a database file
+func openArgs(path string) (string, string) {
+	driver := drivers[activeDriver]
+	return driver.sqlName, driver.dsn(path)
+}
+
+// errorCode returns the extended SQLite result code of err from whichever
+// driver produced it
+func errorCode(err error) (int, bool) {
+	for _, driver := range drivers {
+		if code, ok := driver.code(err); ok {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// IsBusy reports whether err means the database was locked by another
+// connection, so the operation can be retried
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code, ok := errorCode(err); ok {
+		return code&0xff == sqliteBusy || code&0xff == sqliteLocked
+	}
+	if state, ok := sqlState(err); ok {
+		return state == postgresSerializationFailure || state == postgresDeadlock || state == postgresLockNotAvailable
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "database is locked")
+}
+
+// IsUniqueViolation reports whether err is a UNIQUE or PRIMARY KEY
+// constraint failure
+func IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code, ok := errorCode(err); ok {
+		return code == sqliteConstraintUnique || code == sqliteConstraintPK
+	}
+	if state, ok := sqlState(err); ok {
+		return state == postgresUniqueViolation
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint")
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useDriver switches the SQLite driver for one test
+func useDriver(t *testing.T, name string) {
+	t.Helper()
+	previous := DriverName()
+	if err := UseDriver(name); err != nil {
+		t.Fatalf("UseDriver(%s): %v", name, err)
+	}
+	t.Cleanup(func() { activeDriver = previous })
+}
+
+func TestUseDriverRejectsUnknownDriver(t *testing.T) {
+	if err := UseDriver("postgres"); err == nil {
+		t.Fatal("expected an unknown driver to be rejected")
+	}
+	if err := UseDriver(""); err != nil {
+		t.Fatalf("empty driver should keep the default: %v", err)
+	}
+}
+
+func TestDriversClassifyErrors(t *testing.T) {
+	for _, name := range AvailableDrivers() {
+		t.Run(name, func(t *testing.T) {
+			useDriver(t, name)
+			db := newTestDB(t)
+			defer db.Close()
+
+			mustExec(t, db, `CREATE TABLE items (id TEXT PRIMARY KEY, name TEXT UNIQUE)`)
+			mustExec(t, db, `INSERT INTO items (id, name) VALUES ('a', 'one')`)
+
+			_, err := db.Exec(`INSERT INTO items (id, name) VALUES ('b', 'one')`)
+			if !IsUniqueViolation(err) {
+				t.Errorf("expected a UNIQUE violation, got %v", err)
+			}
+			_, err = db.Exec(`INSERT INTO items (id, name) VALUES ('a', 'two')`)
+			if !IsUniqueViolation(err) {
+				t.Errorf("expected a PRIMARY KEY violation, got %v", err)
+			}
+			if IsBusy(err) {
+				t.Errorf("a constraint failure is not a busy error")
+			}
+		})
+	}
+}
+
+// A database written by one driver must read the same with the other, since
+// SQLITE_DRIVER can change between restarts
+func TestDriversShareTimestamps(t *testing.T) {
+	drivers := AvailableDrivers()
+	if len(drivers) < 2 {
+		t.Skip("only one SQLite driver in this build")
+	}
+	path := filepath.Join(t.TempDir(), "shared.db")
+	stored := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	for i, name := range drivers {
+		useDriver(t, name)
+		db, err := InitDB(path)
+		if err != nil {
+			t.Fatalf("%s: InitDB: %v", name, err)
+		}
+		if i == 0 {
+			mustExec(t, db, `CREATE TABLE events (id INTEGER PRIMARY KEY, at TIMESTAMP)`)
+			mustExec(t, db, `INSERT INTO events (at) VALUES (?)`, stored)
+		}
+		var at time.Time
+		if err := db.QueryRow(`SELECT at FROM events WHERE at <= ?`, stored).Scan(&at); err != nil {
+			t.Fatalf("%s: read timestamp: %v", name, err)
+		}
+		if !at.Equal(stored) {
+			t.Errorf("%s: timestamp = %v, want %v", name, at, stored)
+		}
+		db.Close()
+	}
+}
@@ -132,11 +132,12 @@ CREATE TABLE IF NOT EXISTS mtls_clients (
     expiry TIMESTAMP,
     revoked INTEGER DEFAULT 0,
     revoked_at TIMESTAMP,
+    signer TEXT NOT NULL DEFAULT '',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Initialize mTLS config singleton row
-INSERT OR IGNORE INTO mtls_config (id) VALUES (1);
+INSERT INTO mtls_config (id) VALUES (1) ON CONFLICT DO NOTHING;
 
 -- Security Configuration (singleton table for TLS hardening and secure headers)
 CREATE TABLE IF NOT EXISTS security_config (
@@ -155,7 +156,7 @@ CREATE TABLE IF NOT EXISTS security_config (
 );
 
 -- Initialize security config singleton row
-INSERT OR IGNORE INTO security_config (id) VALUES (1);
+INSERT INTO security_config (id) VALUES (1) ON CONFLICT DO NOTHING;
 
 -- External middlewares table stores references to Traefik-native middlewares assigned to resources
 -- These are middlewares defined in Traefik dynamic config or plugins (not managed by MW-manager)
@@ -167,4 +168,424 @@ CREATE TABLE IF NOT EXISTS resource_external_middlewares (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (resource_id, middleware_name),
     FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+-- Header policies are reusable headers middleware configurations (security, CORS, caching)
+CREATE TABLE IF NOT EXISTS header_policies (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL DEFAULT 'custom',
+    description TEXT DEFAULT '',
+    config TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Resource groups let several resources share header policies
+CREATE TABLE IF NOT EXISTS resource_groups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS resource_group_members (
+    group_id TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, resource_id),
+    FOREIGN KEY (group_id) REFERENCES resource_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE
+);
+
+-- Header policy assignments reference a policy from a resource or a group
+-- target_type is 'resource' or 'group'; higher priority wins within a level
+CREATE TABLE IF NOT EXISTS header_policy_assignments (
+    policy_id TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 100,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (policy_id, target_type, target_id),
+    FOREIGN KEY (policy_id) REFERENCES header_policies(id) ON DELETE CASCADE
+);
+
+-- CORS policies render into the CORS options of a resource's headers middleware
+-- allow_* lists are stored as JSON arrays
+CREATE TABLE IF NOT EXISTS cors_policies (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    allow_origins TEXT NOT NULL DEFAULT '[]',
+    allow_methods TEXT NOT NULL DEFAULT '[]',
+    allow_headers TEXT NOT NULL DEFAULT '[]',
+    expose_headers TEXT NOT NULL DEFAULT '[]',
+    allow_credentials INTEGER DEFAULT 0,
+    max_age INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Forward-auth tokens for the built-in forwardAuth endpoint
+-- Bearer tokens are stored hashed (token_hash); TOTP secrets are stored base32-encoded
+-- totp_last_step is the last accepted TOTP time step, so a code is accepted once
+CREATE TABLE IF NOT EXISTS forward_auth_tokens (
+    id TEXT PRIMARY KEY,
+    resource_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL DEFAULT 'bearer',
+    token_hash TEXT DEFAULT '',
+    totp_secret TEXT DEFAULT '',
+    totp_last_step INTEGER,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_forward_auth_tokens_resource ON forward_auth_tokens(resource_id);
+
+-- Secret rotations track middleware secrets replaced via the rotation API
+-- old_value is kept only while a grace period is pending so it can be removed afterwards
+CREATE TABLE IF NOT EXISTS secret_rotations (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    middleware_ids TEXT NOT NULL DEFAULT '[]',
+    path TEXT DEFAULT '',
+    old_value TEXT DEFAULT '',
+    grace_applied INTEGER DEFAULT 0,
+    expires_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Management scope (singleton): include/exclude host patterns limiting which resources MM manages
+CREATE TABLE IF NOT EXISTS management_scope (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    include_patterns TEXT NOT NULL DEFAULT '[]',
+    exclude_patterns TEXT NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Initialize management scope singleton row
+INSERT INTO management_scope (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+-- Protected names: Pangolin/Traefik middlewares or services that MM must never override or de-duplicate
+CREATE TABLE IF NOT EXISTS protected_names (
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    reason TEXT DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (kind, name)
+);
+
+-- Cleanup runs: structured report of what a cleanup changed (or would change in dry-run mode) and why
+CREATE TABLE IF NOT EXISTS cleanup_runs (
+    id TEXT PRIMARY KEY,
+    dry_run INTEGER NOT NULL DEFAULT 0,
+    report TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Cleanup undo: rows changed or deleted by a cleanup run, restorable until expires_at
+CREATE TABLE IF NOT EXISTS cleanup_undo (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    row_data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cleanup_undo_run ON cleanup_undo(run_id);
+
+-- HTTP to HTTPS redirect defaults for resources that inherit the global setting
+CREATE TABLE IF NOT EXISTS redirect_config (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    enabled INTEGER DEFAULT 0,
+    entrypoint TEXT DEFAULT 'web',
+    permanent INTEGER DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO redirect_config (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+-- WAF (Coraza plugin) global settings: plugin version, OWASP CRS major version and default paranoia level
+CREATE TABLE IF NOT EXISTS waf_config (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    plugin_module TEXT DEFAULT 'github.com/jcchavezs/coraza-http-wasm-traefik',
+    plugin_version TEXT DEFAULT 'v0.3.0',
+    crs_version TEXT DEFAULT '4',
+    default_paranoia INTEGER DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO waf_config (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+-- Per-resource WAF settings; paranoia_level 0 inherits the default, exclusions are stored as JSON
+CREATE TABLE IF NOT EXISTS resource_waf (
+    resource_id TEXT PRIMARY KEY,
+    enabled INTEGER DEFAULT 0,
+    paranoia_level INTEGER DEFAULT 0,
+    log_only INTEGER DEFAULT 0,
+    exclusions TEXT NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE
+);
+
+-- Bot block lists: named lists of user-agent substrings (JSON array), optionally refreshed from source_url
+-- every update_interval minutes (0 = manual only)
+CREATE TABLE IF NOT EXISTS bot_lists (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    category TEXT NOT NULL DEFAULT 'custom',
+    patterns TEXT NOT NULL DEFAULT '[]',
+    source_url TEXT DEFAULT '',
+    update_interval INTEGER DEFAULT 0,
+    last_updated_at TIMESTAMP,
+    last_error TEXT DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO bot_lists (id, name, category, patterns) VALUES
+    ('builtin-bad-bots', 'Bad bots', 'bad_bots',
+     '["AhrefsBot","Barkrowler","BLEXBot","DataForSeoBot","DotBot","MegaIndex","MJ12bot","PetalBot","SemrushBot","serpstatbot"]'),
+    ('builtin-ai-crawlers', 'AI crawlers', 'ai_crawlers',
+     '["Amazonbot","anthropic-ai","Applebot-Extended","Bytespider","CCBot","ChatGPT-User","ClaudeBot","Claude-Web","cohere-ai","Diffbot","Google-Extended","GPTBot","ImagesiftBot","Meta-ExternalAgent","OAI-SearchBot","Omgilibot","PerplexityBot","YouBot"]'),
+    ('builtin-scanners', 'Vulnerability scanners', 'scanners',
+     '["Acunetix","CensysInspect","dirbuster","ffuf","gobuster","Havij","masscan","Nessus","Netsparker","Nikto","Nmap","Nuclei","OpenVAS","sqlmap","wfuzz","WPScan","zgrab","ZmEu"]')
+ON CONFLICT DO NOTHING;
+
+-- Bot lists blocked on each resource
+CREATE TABLE IF NOT EXISTS resource_bot_lists (
+    resource_id TEXT NOT NULL,
+    list_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (resource_id, list_id),
+    FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE,
+    FOREIGN KEY (list_id) REFERENCES bot_lists(id) ON DELETE CASCADE
+);
+
+-- Request mirroring: a percentage of a resource's requests is copied to target_url
+CREATE TABLE IF NOT EXISTS resource_mirrors (
+    resource_id TEXT PRIMARY KEY,
+    target_url TEXT NOT NULL,
+    percent INTEGER NOT NULL DEFAULT 10,
+    mirror_body INTEGER DEFAULT 1,
+    max_body_size INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE
+);
+
+-- Traffic captures: access-log lines collected for one resource while status is 'active'
+-- lines is a JSON array of raw access-log entries
+CREATE TABLE IF NOT EXISTS traffic_captures (
+    id TEXT PRIMARY KEY,
+    resource_id TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active',
+    max_lines INTEGER NOT NULL DEFAULT 20,
+    lines TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_traffic_captures_status ON traffic_captures(status);
+
+-- Assignment expirations: temporary middleware assignments removed by the scheduler,
+-- with the owner and contact to notify captured at removal time
+CREATE TABLE IF NOT EXISTS assignment_expirations (
+    id TEXT PRIMARY KEY,
+    resource_id TEXT NOT NULL,
+    resource_host TEXT NOT NULL DEFAULT '',
+    middleware_id TEXT NOT NULL,
+    middleware_name TEXT NOT NULL DEFAULT '',
+    owner TEXT NOT NULL DEFAULT '',
+    contact TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    removed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignment_expirations_removed_at ON assignment_expirations(removed_at);
+
+-- Custom secure headers presets; built-in presets live in code
+CREATE TABLE IF NOT EXISTS secure_header_presets (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT DEFAULT '',
+    headers TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Resource changes found by the watcher in review mode, waiting for approval.
+-- change_key is kind plus the resource (or Pangolin router for creations) so a
+-- change seen on every run keeps its detected_at; data is the fetched resource JSON
+CREATE TABLE IF NOT EXISTS pending_changes (
+    id TEXT PRIMARY KEY,
+    change_key TEXT NOT NULL UNIQUE,
+    kind TEXT NOT NULL,
+    resource_id TEXT NOT NULL DEFAULT '',
+    pangolin_router_id TEXT NOT NULL DEFAULT '',
+    host TEXT NOT NULL DEFAULT '',
+    old_service_id TEXT NOT NULL DEFAULT '',
+    new_service_id TEXT NOT NULL DEFAULT '',
+    data TEXT NOT NULL DEFAULT '{}',
+    detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Summaries of resource watcher runs (counts, skipped resources, changes) as JSON;
+-- only the latest runs are kept
+CREATE TABLE IF NOT EXISTS resource_watcher_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    trigger_type TEXT NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    data TEXT NOT NULL DEFAULT '{}'
+);
+
+-- Middlewares attached to every HTTP router on an entrypoint, ahead of the
+-- routers' own middlewares
+CREATE TABLE IF NOT EXISTS entrypoint_middlewares (
+    entrypoint TEXT NOT NULL,
+    middleware_id TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 200,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entrypoint, middleware_id),
+    FOREIGN KEY (middleware_id) REFERENCES middlewares(id) ON DELETE CASCADE
+);
+
+-- Global default middleware chain, rendered once as a chain middleware and
+-- referenced by every HTTP router except those of excluded resources
+CREATE TABLE IF NOT EXISTS default_chain_middlewares (
+    middleware_id TEXT PRIMARY KEY,
+    priority INTEGER NOT NULL DEFAULT 200,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (middleware_id) REFERENCES middlewares(id) ON DELETE CASCADE
+);
+
+-- Deltas applied to HTTP services discovered upstream during the merge;
+-- data is the models.ServiceOverride JSON
+CREATE TABLE IF NOT EXISTS service_overrides (
+    service_name TEXT PRIMARY KEY,
+    data TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Failover: a resource's service is wrapped in a failover service that uses
+-- fallback_url (a generated service) or fallback_service while it is unhealthy
+CREATE TABLE IF NOT EXISTS resource_failovers (
+    resource_id TEXT PRIMARY KEY,
+    fallback_url TEXT NOT NULL DEFAULT '',
+    fallback_service TEXT NOT NULL DEFAULT '',
+    health_check_path TEXT NOT NULL DEFAULT '/',
+    health_check_interval TEXT NOT NULL DEFAULT '10s',
+    health_check_timeout TEXT NOT NULL DEFAULT '3s',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE
+);
+
+-- Blue/green deployments: the router of a resource moves from blue (its upstream
+-- service, or blue_url) to the generated green_url service; blue is kept for
+-- rollback until soak_until, then archived
+CREATE TABLE IF NOT EXISTS resource_deployments (
+    resource_id TEXT PRIMARY KEY,
+    green_url TEXT NOT NULL,
+    blue_url TEXT NOT NULL DEFAULT '',
+    mirror_percent INTEGER NOT NULL DEFAULT 0,
+    soak_period TEXT NOT NULL DEFAULT '1h',
+    stage TEXT NOT NULL DEFAULT 'staged',
+    switched_at TIMESTAMP,
+    soak_until TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (resource_id) REFERENCES resources(id) ON DELETE CASCADE
+);
+
+-- API tokens for the management API; only the SHA-256 hash of the secret is
+-- stored, prefix identifies the token in lists and logs
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT 'read',
+    user_id TEXT NOT NULL DEFAULT '',
+    prefix TEXT NOT NULL DEFAULT '',
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Operators of the management API; API tokens issued to a user (api_tokens.user_id)
+-- act with the user's role: viewer, editor or admin
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL DEFAULT 'viewer',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhooks posted when resources or middleware assignments change; events is
+-- a comma-separated filter, empty for every event
+CREATE TABLE IF NOT EXISTS webhooks (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    events TEXT NOT NULL DEFAULT '',
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Deliveries of webhook events; pending ones are retried at next_attempt_at
+-- with backoff until they succeed or run out of attempts
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+
+-- Environments (e.g. dev, staging, prod) one database serves separate merged
+-- configs for; the default one is served at the plain config endpoint
+CREATE TABLE IF NOT EXISTS environments (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    is_default INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Resources and middlewares limited to some environments; untagged ones
+-- apply in every environment
+CREATE TABLE IF NOT EXISTS environment_tags (
+    environment TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    PRIMARY KEY (environment, target_type, target_id),
+    FOREIGN KEY (environment) REFERENCES environments(name) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_environment_tags_target ON environment_tags(target_type, target_id);
+
+-- Set on a SQLite database once it has been copied to PostgreSQL and cut
+-- over, so MM no longer starts on the stale file
+CREATE TABLE IF NOT EXISTS postgres_cutover (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    target TEXT NOT NULL,
+    migrated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
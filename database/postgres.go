@@ -0,0 +1,242 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DriverPostgres is reported by DriverName while MM runs on PostgreSQL
+// (DB_DSN) instead of a SQLite file. MM's queries are written for SQLite;
+// every statement passes through rewritePostgres on its way to the driver,
+// which covers the SQLite constructs MM uses.
+const DriverPostgres = "postgres"
+
+// postgresDriverNames are the database/sql names PostgreSQL drivers register
+// under: pgx (linked in unless built with -tags nopostgres) and lib/pq
+var postgresDriverNames = []string{"pgx", "postgres"}
+
+// ErrSQLiteOnly is returned by operations that only exist for SQLite files,
+// such as snapshots, when MM runs on PostgreSQL
+var ErrSQLiteOnly = errors.New("only supported on SQLite")
+
+var registerPostgres sync.Once
+
+// IsPostgres reports whether the database is PostgreSQL
+func IsPostgres() bool {
+	return activeDriver == DriverPostgres
+}
+
+// Open connects to PostgreSQL when dsn is set and to the SQLite file at
+// dbPath otherwise
+func Open(dbPath, dsn string) (*DB, error) {
+	if strings.TrimSpace(dsn) == "" {
+		return InitDB(dbPath)
+	}
+	return InitPostgres(dsn)
+}
+
+// InitPostgres connects to the PostgreSQL database at dsn, a URL such as
+// postgres://mm:secret@db:5432/mm?sslmode=disable, and migrates it
+func InitPostgres(dsn string) (*DB, error) {
+	db, err := openPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+	activeDriver = DriverPostgres
+	return &DB{db}, nil
+}
+
+// openPostgres connects to dsn and migrates its schema without making it
+// the active database, so a SQLite install can also open one as a
+// migration target
+func openPostgres(dsn string) (*sql.DB, error) {
+	sqlName, err := postgresSQLName()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(sqlName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	log.Printf("Connected to PostgreSQL database %s", redactDSN(dsn))
+
+	if err := runMigrations(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := runPostMigrationUpdates(db); err != nil {
+		log.Printf("Warning: Error running post-migration updates: %v", err)
+	}
+
+	return db, nil
+}
+
+// postgresSQLName registers the rewriting wrapper around the PostgreSQL
+// driver linked into this build and returns the name it is registered under
+func postgresSQLName() (string, error) {
+	var inner string
+	registered := sql.Drivers()
+	for _, name := range postgresDriverNames {
+		for _, have := range registered {
+			if have == name {
+				inner = name
+				break
+			}
+		}
+		if inner != "" {
+			break
+		}
+	}
+	if inner == "" {
+		return "", fmt.Errorf("PostgreSQL support is not compiled into this build; it was built with -tags nopostgres")
+	}
+
+	name := "mm-" + inner
+	var err error
+	registerPostgres.Do(func() {
+		var db *sql.DB
+		// sql.Open only looks the driver up; it does not connect
+		if db, err = sql.Open(inner, ""); err != nil {
+			return
+		}
+		defer db.Close()
+		sql.Register(name, rewriteDriver{Driver: db.Driver(), rewrite: rewritePostgres})
+	})
+	return name, err
+}
+
+// redactDSN hides the password of a URL-style DSN for logging
+func redactDSN(dsn string) string {
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return "(key/value DSN)"
+	}
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		if user, _, hasPassword := strings.Cut(rest[:at], ":"); hasPassword {
+			rest = user + ":***" + rest[at:]
+		}
+	}
+	return scheme + "://" + rest
+}
+
+// sqlState returns the SQLSTATE of a PostgreSQL error; pgx and lib/pq both
+// expose it
+func sqlState(err error) (string, bool) {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState(), true
+	}
+	return "", false
+}
+
+// rewriteDriver wraps a driver so every statement is rewritten before the
+// driver sees it and booleans are sent as the 0/1 integers MM's columns hold
+type rewriteDriver struct {
+	driver.Driver
+	rewrite func(string) string
+}
+
+func (d rewriteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &rewriteConn{Conn: conn, rewrite: d.rewrite}, nil
+}
+
+type rewriteConn struct {
+	driver.Conn
+	rewrite func(string) string
+}
+
+func (c *rewriteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(c.rewrite(query))
+}
+
+func (c *rewriteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, c.rewrite(query))
+	}
+	return c.Conn.Prepare(c.rewrite(query))
+}
+
+func (c *rewriteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, c.rewrite(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rewriteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, c.rewrite(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rewriteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *rewriteConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *rewriteConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *rewriteConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue turns booleans into 0/1, as SQLite stores them, before the
+// driver's own conversion
+func (c *rewriteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if valuer, ok := nv.Value.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		nv.Value = value
+	}
+	if b, ok := nv.Value.(bool); ok {
+		nv.Value = int64(0)
+		if b {
+			nv.Value = int64(1)
+		}
+	}
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	groupConcatPattern   = regexp.MustCompile(`(?i)\bGROUP_CONCAT\s*\(`)
+	autoincrementPattern = regexp.MustCompile(`(?i)\bINTEGER\s+PRIMARY\s+KEY\s+AUTOINCREMENT\b`)
+	blobPattern          = regexp.MustCompile(`(?i)\bBLOB\b`)
+	sqliteMasterPattern  = regexp.MustCompile(`(?i)(^|[^.\w])sqlite_master\b`)
+	tableInfoPattern     = regexp.MustCompile(`(?i)\bpragma_table_info\(\s*('[^']*'|[\w.?]+)\s*\)`)
+	nextWordPattern      = regexp.MustCompile(`^\s+(\w+)`)
+)
+
+// clauseKeywords follow a table reference that has no alias
+var clauseKeywords = map[string]bool{
+	"where": true, "order": true, "group": true, "join": true, "left": true, "right": true,
+	"inner": true, "cross": true, "limit": true, "union": true, "having": true, "on": true,
+}
+
+const (
+	postgresTables  = `(SELECT table_name AS name, 'table' AS type FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE')`
+	postgresColumns = `(SELECT column_name AS name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = %s)`
+)
+
+// rewritePostgres translates the SQLite dialect MM's queries use into
+// PostgreSQL: ? placeholders, GROUP_CONCAT, AUTOINCREMENT and BLOB columns,
+// and the sqlite_master and pragma_table_info lookups of the migrations.
+// Statements that are valid in both, such as ON CONFLICT upserts and
+// RETURNING, pass through unchanged.
+func rewritePostgres(query string) string {
+	query = groupConcatPattern.ReplaceAllString(query, "STRING_AGG(")
+	query = autoincrementPattern.ReplaceAllString(query, "BIGSERIAL PRIMARY KEY")
+	query = blobPattern.ReplaceAllString(query, "BYTEA")
+	query = replaceTableRef(query, sqliteMasterPattern, func(match []string) string {
+		return match[1] + postgresTables
+	}, "sqlite_master")
+	query = replaceTableRef(query, tableInfoPattern, func(match []string) string {
+		return strings.Replace(postgresColumns, "%s", match[1], 1)
+	}, "pragma_table_info")
+	return rebindPlaceholders(query)
+}
+
+// replaceTableRef replaces every table reference pattern matches with a
+// subquery, adding alias when the reference is not followed by one, as
+// PostgreSQL requires for subqueries in FROM
+func replaceTableRef(query string, pattern *regexp.Regexp, subquery func([]string) string, alias string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(query, -1) {
+		match := make([]string, len(loc)/2)
+		for i := range match {
+			if loc[2*i] >= 0 {
+				match[i] = query[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		b.WriteString(query[last:loc[0]])
+		b.WriteString(subquery(match))
+		next := nextWordPattern.FindStringSubmatch(query[loc[1]:])
+		if next == nil || clauseKeywords[strings.ToLower(next[1])] {
+			b.WriteString(" AS " + alias)
+		}
+		last = loc[1]
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// rebindPlaceholders numbers ? placeholders $1, $2, ... skipping string
+// literals, quoted identifiers and comments
+func rebindPlaceholders(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == ch {
+					// A doubled quote is an escaped quote
+					if end+1 < len(query) && query[end+1] == ch {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(query) {
+				end = len(query) - 1
+			}
+			b.WriteString(query[i : end+1])
+			i = end
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case ch == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 3
+		case ch == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
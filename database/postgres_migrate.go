@@ -0,0 +1,512 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTargetNotEmpty is returned when the PostgreSQL database already holds
+// rows and the migration was not asked to overwrite them
+var ErrTargetNotEmpty = errors.New("the PostgreSQL database already holds data")
+
+// ErrVerificationFailed is returned when the rows read back from PostgreSQL
+// do not match the ones copied
+var ErrVerificationFailed = errors.New("the copy in PostgreSQL does not match the SQLite database")
+
+// ErrSourceChanged is returned when SQLite was written to during a cutover
+// copy, so the copy is no longer complete
+var ErrSourceChanged = errors.New("the SQLite database changed during the copy")
+
+// cutoverTable marks a migrated SQLite database; it is never copied
+const cutoverTable = "postgres_cutover"
+
+// seedInsertPattern finds the tables migrations.sql seeds with default rows
+var seedInsertPattern = regexp.MustCompile(`(?i)\bINSERT\s+INTO\s+(\w+)`)
+
+// PostgresMigrationOptions controls a SQLite to PostgreSQL migration
+type PostgresMigrationOptions struct {
+	// DryRun creates the schema in PostgreSQL and counts the rows on both
+	// sides without copying
+	DryRun bool
+	// Overwrite replaces rows PostgreSQL already holds instead of refusing
+	Overwrite bool
+	// Cutover marks the SQLite database as migrated once the copy is
+	// verified, so MM no longer starts on it without DB_DSN. Only the
+	// command line offers it, with MM stopped.
+	Cutover bool
+}
+
+// TableMigration is the outcome for one table
+type TableMigration struct {
+	Table          string `json:"table"`
+	SourceRows     int64  `json:"source_rows"`
+	TargetRows     int64  `json:"target_rows"`
+	SourceChecksum string `json:"source_checksum,omitempty"`
+	TargetChecksum string `json:"target_checksum,omitempty"`
+	Verified       bool   `json:"verified"`
+}
+
+// PostgresMigration reports a SQLite to PostgreSQL migration
+type PostgresMigration struct {
+	// Target is the DSN with its password hidden
+	Target     string           `json:"target"`
+	DryRun     bool             `json:"dry_run"`
+	Tables     []TableMigration `json:"tables"`
+	Rows       int64            `json:"rows"`
+	Verified   bool             `json:"verified"`
+	CutOver    bool             `json:"cut_over"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// PostgresCutover records that a SQLite database moved to PostgreSQL
+type PostgresCutover struct {
+	Target     string    `json:"target"`
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// MigrateToPostgres copies every table of the SQLite database into the
+// PostgreSQL database at dsn, creating its schema first, and verifies the
+// copy with row counts and per-table checksums. MM keeps running on SQLite
+// meanwhile; the copy is of one point in time.
+func (db *DB) MigrateToPostgres(ctx context.Context, dsn string, opts PostgresMigrationOptions) (*PostgresMigration, error) {
+	if IsPostgres() {
+		return nil, fmt.Errorf("migrating to PostgreSQL is %w", ErrSQLiteOnly)
+	}
+	target, err := openPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer target.Close()
+	return db.copyTo(ctx, target, redactDSN(dsn), true, opts)
+}
+
+// PostgresCutover returns the cutover recorded in a SQLite database, or nil
+// when it has not been migrated
+func (db *DB) PostgresCutover() (*PostgresCutover, error) {
+	cutover := &PostgresCutover{}
+	err := db.QueryRow("SELECT target, migrated_at FROM postgres_cutover WHERE id = 1").Scan(&cutover.Target, &cutover.MigratedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cutover, nil
+}
+
+// copyTo copies the database into target, which already has MM's schema.
+// postgres enables the sequence updates only PostgreSQL needs.
+func (db *DB) copyTo(ctx context.Context, target *sql.DB, name string, postgres bool, opts PostgresMigrationOptions) (*PostgresMigration, error) {
+	report := &PostgresMigration{Target: name, DryRun: opts.DryRun, StartedAt: time.Now().UTC()}
+	cutover := opts.Cutover && !opts.DryRun
+
+	// Reading every table in one read transaction copies a single point in
+	// time without holding SQLite's write lock, so MM keeps accepting writes
+	src, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer src.Rollback()
+
+	tables, err := migrationOrder(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	targetTables, err := queryStrings(ctx, target, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PostgreSQL tables: %w", err)
+	}
+	inTarget := make(map[string]bool, len(targetTables))
+	for _, table := range targetTables {
+		inTarget[table] = true
+	}
+
+	// A freshly migrated database already holds the rows migrations.sql
+	// seeds; those are replaced by the copied ones rather than counted as data
+	seeded, err := seededTables()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string][]string, len(tables))
+	notEmpty := false
+	for _, table := range tables {
+		if !inTarget[table] {
+			return nil, fmt.Errorf("table %s does not exist in PostgreSQL", table)
+		}
+		if columns[table], err = migrationColumns(ctx, src, target, table); err != nil {
+			return nil, err
+		}
+		entry := TableMigration{Table: table}
+		if err := target.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %q", table)).Scan(&entry.TargetRows); err != nil {
+			return nil, fmt.Errorf("failed to count %s in PostgreSQL: %w", table, err)
+		}
+		if err := src.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %q", table)).Scan(&entry.SourceRows); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		notEmpty = notEmpty || (entry.TargetRows > 0 && !seeded[table])
+		report.Rows += entry.SourceRows
+		report.Tables = append(report.Tables, entry)
+	}
+
+	if opts.DryRun {
+		report.FinishedAt = time.Now().UTC()
+		return report, nil
+	}
+	if notEmpty && !opts.Overwrite {
+		return report, ErrTargetNotEmpty
+	}
+
+	tx, err := target.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Children first, so foreign keys never point at deleted rows
+	for i := len(tables) - 1; i >= 0; i-- {
+		if !opts.Overwrite && !seeded[tables[i]] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %q", tables[i])); err != nil {
+			return nil, fmt.Errorf("failed to clear %s in PostgreSQL: %w", tables[i], err)
+		}
+	}
+
+	for i, table := range tables {
+		checksum, rows, err := copyTable(ctx, src, tx, table, columns[table])
+		if err != nil {
+			return nil, err
+		}
+		if postgres {
+			if err := resetSequences(ctx, tx, table); err != nil {
+				return nil, err
+			}
+		}
+		report.Tables[i].SourceRows = rows
+		report.Tables[i].SourceChecksum = checksum
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit the copy: %w", err)
+	}
+
+	// Read everything back, so values PostgreSQL stored differently from
+	// what was sent fail the migration instead of surfacing later
+	report.Verified = true
+	report.Rows = 0
+	for i := range report.Tables {
+		entry := &report.Tables[i]
+		sum := newRowChecksum()
+		if err := scanTable(ctx, target, entry.Table, columns[entry.Table], func(values []interface{}) error {
+			sum.add(values)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %w", entry.Table, err)
+		}
+		entry.TargetRows = int64(sum.count())
+		entry.TargetChecksum = sum.sum()
+		entry.Verified = entry.TargetRows == entry.SourceRows && entry.TargetChecksum == entry.SourceChecksum
+		report.Verified = report.Verified && entry.Verified
+		report.Rows += entry.SourceRows
+	}
+	report.FinishedAt = time.Now().UTC()
+	if !report.Verified {
+		return report, ErrVerificationFailed
+	}
+
+	if cutover {
+		if err := db.recordCutover(ctx, name, report.Tables); err != nil {
+			return report, err
+		}
+		report.CutOver = true
+	}
+	log.Printf("Copied %d rows in %d tables to PostgreSQL %s (cutover: %v)", report.Rows, len(report.Tables), name, report.CutOver)
+	return report, nil
+}
+
+// recordCutover marks the database as migrated to name. The marker is
+// written first, taking SQLite's write lock, and the tables are checksummed
+// again under it: a write that landed after the copy was read fails the
+// cutover instead of being left behind in SQLite.
+func (db *DB) recordCutover(ctx context.Context, name string, tables []TableMigration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO postgres_cutover (id, target, migrated_at) VALUES (1, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET target = excluded.target, migrated_at = excluded.migrated_at
+	`, name, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record cutover: %w", err)
+	}
+
+	for _, entry := range tables {
+		columns, err := queryStrings(ctx, tx, "SELECT name FROM pragma_table_info(?) ORDER BY cid", entry.Table)
+		if err != nil {
+			return err
+		}
+		for i, column := range columns {
+			columns[i] = fmt.Sprintf("%q", column)
+		}
+		sum := newRowChecksum()
+		if err := scanTable(ctx, tx, entry.Table, columns, func(values []interface{}) error {
+			sum.add(values)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to recheck %s: %w", entry.Table, err)
+		}
+		if sum.sum() != entry.SourceChecksum {
+			return fmt.Errorf("%w: %s was written to, run the migration again with MM stopped", ErrSourceChanged, entry.Table)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to record cutover: %w", err)
+	}
+	return nil
+}
+
+// migrationOrder lists the tables to copy with every table after the ones
+// its foreign keys reference
+func migrationOrder(ctx context.Context, src *sql.Tx) ([]string, error) {
+	tables, err := queryStrings(ctx, src, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		if parents[table], err = queryStrings(ctx, src, `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`, table); err != nil {
+			return nil, err
+		}
+	}
+
+	var ordered []string
+	state := make(map[string]int) // 1 while visiting, 2 once placed
+	var visit func(string)
+	visit = func(table string) {
+		if state[table] != 0 {
+			return
+		}
+		state[table] = 1
+		for _, parent := range parents[table] {
+			if _, known := parents[parent]; known {
+				visit(parent)
+			}
+		}
+		state[table] = 2
+		if table != cutoverTable {
+			ordered = append(ordered, table)
+		}
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered, nil
+}
+
+// seededTables returns the tables migrations.sql inserts default rows into
+func seededTables() (map[string]bool, error) {
+	migrationsFile := findMigrationsFile()
+	if migrationsFile == "" {
+		return nil, fmt.Errorf("migrations file not found")
+	}
+	migrations, err := os.ReadFile(migrationsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations file: %w", err)
+	}
+	seeded := make(map[string]bool)
+	for _, match := range seedInsertPattern.FindAllStringSubmatch(string(migrations), -1) {
+		seeded[match[1]] = true
+	}
+	return seeded, nil
+}
+
+// migrationColumns returns the quoted columns of a table, failing if
+// PostgreSQL lacks one so no data is dropped silently
+func migrationColumns(ctx context.Context, src *sql.Tx, target *sql.DB, table string) ([]string, error) {
+	sourceColumns, err := queryStrings(ctx, src, "SELECT name FROM pragma_table_info(?) ORDER BY cid", table)
+	if err != nil {
+		return nil, err
+	}
+	targetColumns, err := queryStrings(ctx, target, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s in PostgreSQL: %w", table, err)
+	}
+	inTarget := make(map[string]bool, len(targetColumns))
+	for _, column := range targetColumns {
+		inTarget[column] = true
+	}
+
+	columns := make([]string, 0, len(sourceColumns))
+	for _, column := range sourceColumns {
+		if !inTarget[column] {
+			return nil, fmt.Errorf("column %s.%s does not exist in PostgreSQL", table, column)
+		}
+		columns = append(columns, fmt.Sprintf("%q", column))
+	}
+	return columns, nil
+}
+
+// copyTable inserts every row of a table into the target transaction and
+// returns the checksum and count of the rows read
+func copyTable(ctx context.Context, src *sql.Tx, tx *sql.Tx, table string, columns []string) (string, int64, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insert, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %q (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to prepare the copy of %s: %w", table, err)
+	}
+	defer insert.Close()
+
+	sum := newRowChecksum()
+	err = scanTable(ctx, src, table, columns, func(values []interface{}) error {
+		sum.add(values)
+		if _, err := insert.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("failed to copy a row of %s: %w", table, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return sum.sum(), int64(sum.count()), nil
+}
+
+// resetSequences moves the sequences of a table's serial columns past the
+// copied IDs, so the next insert does not collide with them
+func resetSequences(ctx context.Context, tx *sql.Tx, table string) error {
+	serials, err := queryStrings(ctx, tx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND column_default LIKE 'nextval(%'
+	`, table)
+	if err != nil {
+		return fmt.Errorf("failed to find the sequences of %s: %w", table, err)
+	}
+	for _, column := range serials {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%q), 0) + 1, false) FROM %q", column, table,
+		), table, column); err != nil {
+			return fmt.Errorf("failed to reset the sequence of %s.%s: %w", table, column, err)
+		}
+	}
+	return nil
+}
+
+// scanTable calls fn with the values of every row of a table
+func scanTable(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, table string, columns []string, fn func([]interface{}) error) error {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %q", strings.Join(columns, ", "), table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// queryStrings returns the first column of every row of a query
+func queryStrings(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// rowChecksum hashes a table independently of row order, which differs
+// between the databases, and of how each driver types a value
+type rowChecksum struct {
+	rows []string
+}
+
+func newRowChecksum() *rowChecksum {
+	return &rowChecksum{}
+}
+
+func (c *rowChecksum) add(values []interface{}) {
+	h := sha256.New()
+	for _, value := range values {
+		if value == nil {
+			h.Write([]byte("N;"))
+			continue
+		}
+		s := checksumValue(value)
+		fmt.Fprintf(h, "%d:%s;", len(s), s)
+	}
+	c.rows = append(c.rows, string(h.Sum(nil)))
+}
+
+func (c *rowChecksum) count() int {
+	return len(c.rows)
+}
+
+func (c *rowChecksum) sum() string {
+	sort.Strings(c.rows)
+	h := sha256.New()
+	for _, row := range c.rows {
+		h.Write([]byte(row))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checksumValue renders a value the same way whichever driver returned it:
+// SQLite's 0/1 and PostgreSQL's booleans, text as string or bytes, whole
+// floats as integers and timestamps at PostgreSQL's microsecond precision
+func checksumValue(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.UTC().Round(time.Microsecond).Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
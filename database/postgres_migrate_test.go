@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// The copy is exercised between two SQLite files; only the sequence updates
+// are PostgreSQL-specific
+func TestCopyTo(t *testing.T) {
+	ctx := context.Background()
+	source := newTestDB(t)
+	mustExec(t, source, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'auth', 'basicAuth', '{}')`)
+	mustExec(t, source, `INSERT INTO resources (id, host, service_id, org_id, site_id, tls_domains) VALUES ('res-1', 'a.example.com', 'svc', 'org', 'site', NULL)`)
+	mustExec(t, source, `INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES ('res-1', 'mw-1', 200)`)
+	mustExec(t, source, `INSERT INTO mtls_clients (id, name, cert, key, p12, subject) VALUES ('c-1', 'laptop', 'pem', 'key', X'00FF10', 'CN=laptop')`)
+
+	target := newTestDB(t)
+	report, err := source.copyTo(ctx, target.DB, "target", false, PostgresMigrationOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !report.DryRun || report.Rows < 4 || report.Verified {
+		t.Errorf("dry run report = %+v", report)
+	}
+	var count int
+	if err := target.QueryRow(`SELECT COUNT(*) FROM resource_middlewares`).Scan(&count); err != nil || count != 0 {
+		t.Errorf("dry run copied rows: %d, %v", count, err)
+	}
+
+	report, err = source.copyTo(ctx, target.DB, "target", false, PostgresMigrationOptions{})
+	if err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if !report.Verified || report.CutOver {
+		t.Errorf("copy report = %+v", report)
+	}
+	for _, table := range report.Tables {
+		if table.Table == cutoverTable {
+			t.Errorf("%s was copied", cutoverTable)
+		}
+		if !table.Verified || table.SourceChecksum == "" || table.SourceRows != table.TargetRows {
+			t.Errorf("table %+v not verified", table)
+		}
+	}
+	var p12 []byte
+	var tlsDomains *string
+	if err := target.QueryRow(`SELECT p12 FROM mtls_clients WHERE id = 'c-1'`).Scan(&p12); err != nil || string(p12) != "\x00\xff\x10" {
+		t.Errorf("p12 = %x, %v", p12, err)
+	}
+	if err := target.QueryRow(`SELECT tls_domains FROM resources WHERE id = 'res-1'`).Scan(&tlsDomains); err != nil || tlsDomains != nil {
+		t.Errorf("NULL not kept: %v, %v", tlsDomains, err)
+	}
+
+	if _, err := source.copyTo(ctx, target.DB, "target", false, PostgresMigrationOptions{}); !errors.Is(err, ErrTargetNotEmpty) {
+		t.Errorf("second copy error = %v, want ErrTargetNotEmpty", err)
+	}
+
+	mustExec(t, source, `UPDATE middlewares SET name = 'renamed'`)
+	report, err = source.copyTo(ctx, target.DB, "target", false, PostgresMigrationOptions{Overwrite: true, Cutover: true})
+	if err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if !report.CutOver {
+		t.Error("expected the cutover to be recorded")
+	}
+	var name string
+	if err := target.QueryRow(`SELECT name FROM middlewares WHERE id = 'mw-1'`).Scan(&name); err != nil || name != "renamed" {
+		t.Errorf("middleware after overwrite = %q, %v", name, err)
+	}
+	cutover, err := source.PostgresCutover()
+	if err != nil || cutover == nil || cutover.Target != "target" || time.Since(cutover.MigratedAt) > time.Minute {
+		t.Errorf("PostgresCutover() = %+v, %v", cutover, err)
+	}
+	if cutover, err := target.PostgresCutover(); err != nil || cutover != nil {
+		t.Errorf("target cutover = %+v, %v", cutover, err)
+	}
+}
+
+func TestCopyToFailsVerification(t *testing.T) {
+	ctx := context.Background()
+	source := newTestDB(t)
+	mustExec(t, source, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'auth', 'basicAuth', '{}')`)
+
+	// A target that stores something other than what it was sent
+	target := newTestDB(t)
+	mustExec(t, target, `CREATE TRIGGER tamper AFTER INSERT ON middlewares BEGIN UPDATE middlewares SET name = 'tampered' WHERE id = NEW.id; END`)
+
+	report, err := source.copyTo(ctx, target.DB, "target", false, PostgresMigrationOptions{Cutover: true})
+	if !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("copy error = %v, want ErrVerificationFailed", err)
+	}
+	if report.Verified || report.CutOver {
+		t.Errorf("report = %+v", report)
+	}
+	for _, table := range report.Tables {
+		if table.Table == "middlewares" && (table.Verified || table.SourceChecksum == table.TargetChecksum) {
+			t.Errorf("middlewares passed verification: %+v", table)
+		}
+	}
+	if cutover, err := source.PostgresCutover(); err != nil || cutover != nil {
+		t.Errorf("cutover recorded after a failed verification: %+v, %v", cutover, err)
+	}
+}
+
+func TestRecordCutoverRejectsChangedSource(t *testing.T) {
+	ctx := context.Background()
+	source := newTestDB(t)
+	mustExec(t, source, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'auth', 'basicAuth', '{}')`)
+
+	report, err := source.copyTo(ctx, newTestDB(t).DB, "target", false, PostgresMigrationOptions{})
+	if err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	// A write after the copy was read
+	mustExec(t, source, `UPDATE middlewares SET name = 'renamed'`)
+
+	if err := source.recordCutover(ctx, "target", report.Tables); !errors.Is(err, ErrSourceChanged) {
+		t.Fatalf("recordCutover error = %v, want ErrSourceChanged", err)
+	}
+	if cutover, err := source.PostgresCutover(); err != nil || cutover != nil {
+		t.Errorf("cutover recorded over a changed database: %+v, %v", cutover, err)
+	}
+	if err := source.recordCutover(ctx, "target", nil); err != nil {
+		t.Errorf("recordCutover with nothing to recheck: %v", err)
+	}
+}
+
+func TestChecksumValue(t *testing.T) {
+	same := [][]interface{}{
+		{int64(1), float64(1), true, "1", []byte("1")},
+		{int64(0), false},
+		{
+			time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.FixedZone("CET", 3600)),
+			time.Date(2024, 1, 2, 2, 4, 5, 123457000, time.UTC),
+		},
+	}
+	for _, values := range same {
+		for _, value := range values[1:] {
+			if checksumValue(value) != checksumValue(values[0]) {
+				t.Errorf("checksumValue(%#v) = %q, want %q", value, checksumValue(value), checksumValue(values[0]))
+			}
+		}
+	}
+
+	a, b := newRowChecksum(), newRowChecksum()
+	a.add([]interface{}{"x", nil})
+	a.add([]interface{}{"y", ""})
+	b.add([]interface{}{"y", ""})
+	b.add([]interface{}{"x", nil})
+	if a.sum() != b.sum() {
+		t.Error("checksum depends on row order")
+	}
+	c := newRowChecksum()
+	c.add([]interface{}{"x", ""})
+	c.add([]interface{}{"y", nil})
+	if c.sum() == a.sum() {
+		t.Error("checksum does not tell NULL from an empty string")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !nopostgres
+
+package database
+
+import (
+	// Registers the "pgx" database/sql driver InitPostgres wraps
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewritePostgres(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "placeholders skip literals and comments",
+			in:   "SELECT id FROM t WHERE a = ? AND b = '?' AND c = ? -- why?\nAND d = ?",
+			want: "SELECT id FROM t WHERE a = $1 AND b = '?' AND c = $2 -- why?\nAND d = $3",
+		},
+		{
+			name: "escaped quotes",
+			in:   "UPDATE t SET note = 'it''s ?' WHERE id = ?",
+			want: "UPDATE t SET note = 'it''s ?' WHERE id = $1",
+		},
+		{
+			name: "group_concat",
+			in:   "SELECT GROUP_CONCAT(m.id || ':' || m.name, ',') FROM m",
+			want: "SELECT STRING_AGG(m.id || ':' || m.name, ',') FROM m",
+		},
+		{
+			name: "ddl types",
+			in:   "CREATE TABLE x (id INTEGER PRIMARY KEY AUTOINCREMENT, p12 BLOB)",
+			want: "CREATE TABLE x (id BIGSERIAL PRIMARY KEY, p12 BYTEA)",
+		},
+		{
+			name: "column lookup",
+			in:   "SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?",
+			want: "SELECT COUNT(*) > 0 FROM " + strings.Replace(postgresColumns, "%s", "$1", 1) + " AS pragma_table_info WHERE name = $2",
+		},
+		{
+			name: "table lookup",
+			in:   "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type='table' AND name='services'",
+			want: "SELECT COUNT(*) > 0 FROM " + postgresTables + " AS sqlite_master WHERE type='table' AND name='services'",
+		},
+		{
+			name: "aliased lookups keep their alias",
+			in:   "SELECT m.name FROM sqlite_master m WHERE EXISTS (SELECT 1 FROM pragma_table_info(m.name) p WHERE p.name = 'resource_id')",
+			want: "SELECT m.name FROM " + postgresTables + " m WHERE EXISTS (SELECT 1 FROM " +
+				strings.Replace(postgresColumns, "%s", "m.name", 1) + " p WHERE p.name = 'resource_id')",
+		},
+		{
+			name: "portable statements pass through",
+			in:   "INSERT INTO t (id) VALUES (1) ON CONFLICT(id) DO UPDATE SET a = excluded.a RETURNING id",
+			want: "INSERT INTO t (id) VALUES (1) ON CONFLICT(id) DO UPDATE SET a = excluded.a RETURNING id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewritePostgres(tt.in); got != tt.want {
+				t.Errorf("rewritePostgres()\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// The wrapper is exercised over SQLite, which accepts $N placeholders too
+func TestRewriteDriver(t *testing.T) {
+	sqlName, _ := openArgs("")
+	inner, err := sql.Open(sqlName, ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	driverName := "mm-rewrite-test"
+	sql.Register(driverName, rewriteDriver{Driver: inner.Driver(), rewrite: rebindPlaceholders})
+	inner.Close()
+
+	db, err := sql.Open(driverName, filepath.Join(t.TempDir(), "rewrite.db"))
+	if err != nil {
+		t.Fatalf("open wrapped: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE flags (id TEXT PRIMARY KEY, enabled INTEGER, note TEXT)`); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO flags (id, enabled, note) VALUES (?, ?, ?)`, "a", true, "why?"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO flags (id, enabled, note) VALUES (?, ?, '?')`, "b", sql.NullBool{Bool: false, Valid: true}); err != nil {
+		t.Fatalf("insert in tx: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	var enabled int
+	var note string
+	if err := db.QueryRow(`SELECT enabled, note FROM flags WHERE id = ?`, "a").Scan(&enabled, &note); err != nil || enabled != 1 || note != "why?" {
+		t.Fatalf("row a = %d %q (%v), want 1 \"why?\"", enabled, note, err)
+	}
+	if err := db.QueryRow(`SELECT enabled, note FROM flags WHERE id = ?`, "b").Scan(&enabled, &note); err != nil || enabled != 0 || note != "?" {
+		t.Fatalf("row b = %d %q (%v), want 0 \"?\"", enabled, note, err)
+	}
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestPostgresErrorClassification(t *testing.T) {
+	unique := fmt.Errorf("insert: %w", sqlStateError(postgresUniqueViolation))
+	if !IsUniqueViolation(unique) || IsBusy(unique) {
+		t.Errorf("23505 should be a unique violation only")
+	}
+	if !IsBusy(sqlStateError(postgresLockNotAvailable)) || IsUniqueViolation(sqlStateError(postgresDeadlock)) {
+		t.Errorf("lock errors should be busy errors only")
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	if got := redactDSN("postgres://mm:s3cret@db:5432/mm?sslmode=disable"); got != "postgres://mm:***@db:5432/mm?sslmode=disable" {
+		t.Errorf("redactDSN() = %s", got)
+	}
+	if got := redactDSN("host=db password=s3cret"); strings.Contains(got, "s3cret") {
+		t.Errorf("redactDSN() leaked the password: %s", got)
+	}
+}
+
+func TestInitPostgresWithoutDriver(t *testing.T) {
+	for _, name := range sql.Drivers() {
+		if name == "pgx" || name == "postgres" {
+			t.Skip("a PostgreSQL driver is linked into this build")
+		}
+	}
+	if _, err := Open("", "postgres://mm@localhost/mm"); err == nil || !strings.Contains(err.Error(), "-tags nopostgres") {
+		t.Fatalf("expected a missing-driver error, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Protected name kinds
+const (
+	ProtectedKindMiddleware = "middleware"
+	ProtectedKindService    = "service"
+)
+
+// ProtectedSet holds middleware or service names that must never be
+// overridden or de-duplicated. An entry with a provider suffix ("name@file")
+// protects only that exact name; a bare entry protects the name under any provider.
+type ProtectedSet map[string]struct{}
+
+// Contains reports whether name is protected
+func (p ProtectedSet) Contains(name string) bool {
+	if len(p) == 0 {
+		return false
+	}
+	if _, ok := p[name]; ok {
+		return true
+	}
+	_, ok := p[normalizeID(name)]
+	return ok
+}
+
+// ProtectedNames loads the protected names for a kind
+func (db *DB) ProtectedNames(kind string) (ProtectedSet, error) {
+	rows, err := db.Query("SELECT name FROM protected_names WHERE kind = ?", kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query protected names: %w", err)
+	}
+	defer rows.Close()
+
+	set := make(ProtectedSet)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan protected name: %w", err)
+		}
+		set[strings.TrimSpace(name)] = struct{}{}
+	}
+	return set, rows.Err()
+}
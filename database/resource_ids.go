@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResourceIDMapping records the internal UUID a legacy resource was given
+type ResourceIDMapping struct {
+	OldID string `json:"old_id"`
+	NewID string `json:"new_id"`
+	Host  string `json:"host"`
+	// Rows counts the relationship rows that pointed at OldID, per table
+	Rows map[string]int64 `json:"rows"`
+}
+
+// ResourceIDMigration reports a run of MigrateLegacyResourceIDs
+type ResourceIDMigration struct {
+	DryRun   bool                `json:"dry_run"`
+	Tables   []string            `json:"tables"`
+	Mappings []ResourceIDMapping `json:"mappings"`
+	RanAt    time.Time           `json:"ran_at"`
+}
+
+// CountLegacyResources returns how many resources still use the legacy ID
+// scheme, where the ID is the Pangolin router ID
+func (db *DB) CountLegacyResources() (int, error) {
+	legacy, err := legacyResources(db.DB)
+	return len(legacy), err
+}
+
+// MigrateLegacyResourceIDs gives every legacy resource an internal UUID,
+// keeping its old ID as pangolin_router_id so the generated router names do
+// not change, and rewrites the resource_id column of every table that has
+// one. The run is one transaction; a dry run only reports the mapping.
+func (db *DB) MigrateLegacyResourceIDs(dryRun bool) (*ResourceIDMigration, error) {
+	if IsPostgres() && !dryRun {
+		// PostgreSQL cannot defer the foreign keys of parent and child rows
+		return nil, fmt.Errorf("migrating legacy resource IDs is %w; migrate them before moving to PostgreSQL", ErrSQLiteOnly)
+	}
+	report := &ResourceIDMigration{DryRun: dryRun, Mappings: []ResourceIDMapping{}, RanAt: time.Now().UTC()}
+
+	err := db.WithTransaction(func(tx *sql.Tx) error {
+		tables, err := resourceIDTables(tx)
+		if err != nil {
+			return err
+		}
+		report.Tables = tables
+
+		legacy, err := legacyResources(tx)
+		if err != nil {
+			return err
+		}
+		if len(legacy) == 0 {
+			return nil
+		}
+
+		// Parents and children are rewritten in the same transaction
+		if _, err := tx.Exec("PRAGMA defer_foreign_keys = ON"); err != nil {
+			return err
+		}
+
+		for _, mapping := range legacy {
+			mapping.NewID = uuid.New().String()
+			for _, table := range tables {
+				var n int64
+				if dryRun {
+					err = tx.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %q WHERE resource_id = ?", table), mapping.OldID).Scan(&n)
+				} else {
+					var result sql.Result
+					result, err = tx.Exec(fmt.Sprintf("UPDATE %q SET resource_id = ? WHERE resource_id = ?", table), mapping.NewID, mapping.OldID)
+					if err == nil {
+						n, err = result.RowsAffected()
+					}
+				}
+				if err != nil {
+					return fmt.Errorf("failed to rewrite %s for resource %s: %w", table, mapping.OldID, err)
+				}
+				if n > 0 {
+					mapping.Rows[table] = n
+				}
+			}
+
+			if !dryRun {
+				_, err = tx.Exec(`
+					UPDATE resources SET id = ?, pangolin_router_id = ?, updated_at = ?
+					WHERE id = ?
+				`, mapping.NewID, mapping.OldID, time.Now(), mapping.OldID)
+				if err != nil {
+					return fmt.Errorf("failed to migrate resource %s: %w", mapping.OldID, err)
+				}
+			}
+			report.Mappings = append(report.Mappings, mapping)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun && len(report.Mappings) > 0 {
+		log.Printf("Migrated %d legacy resources to internal UUIDs", len(report.Mappings))
+	}
+	return report, nil
+}
+
+// legacyResources returns the resources whose ID is their Pangolin router ID
+// or that predate pangolin_router_id. Resources that already have a UUID are
+// left alone.
+func legacyResources(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}) ([]ResourceIDMapping, error) {
+	rows, err := q.Query(`
+		SELECT id, host FROM resources
+		WHERE pangolin_router_id IS NULL OR pangolin_router_id = '' OR pangolin_router_id = id
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find legacy resources: %w", err)
+	}
+	defer rows.Close()
+
+	var legacy []ResourceIDMapping
+	for rows.Next() {
+		var id, host string
+		if err := rows.Scan(&id, &host); err != nil {
+			return nil, err
+		}
+		if _, err := uuid.Parse(id); err == nil {
+			continue
+		}
+		legacy = append(legacy, ResourceIDMapping{OldID: id, Host: host, Rows: map[string]int64{}})
+	}
+	return legacy, rows.Err()
+}
+
+// resourceIDTables lists the tables with a resource_id column, so tables
+// added later are rewritten without being listed here
+func resourceIDTables(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT m.name FROM sqlite_master m
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+		  AND EXISTS (SELECT 1 FROM pragma_table_info(m.name) p WHERE p.name = 'resource_id')
+		ORDER BY m.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
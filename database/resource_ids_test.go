@@ -0,0 +1,59 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMigrateLegacyResourceIDs(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	mustExec(t, db, `INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+		VALUES ('app-router@http', 'app-router@http', 'app.example.com', 'app-service', 'org', 'site', 'active')`)
+	current := uuid.New().String()
+	mustExec(t, db, `INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+		VALUES (?, 'other@http', 'other.example.com', 'other-service', 'org', 'site', 'active')`, current)
+	mustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw1', 'auth', 'basicAuth', '{}')`)
+	mustExec(t, db, `INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES ('app-router@http', 'mw1', 100)`)
+
+	if n, err := db.CountLegacyResources(); err != nil || n != 1 {
+		t.Fatalf("CountLegacyResources = %d, %v", n, err)
+	}
+
+	preview, err := db.MigrateLegacyResourceIDs(true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(preview.Mappings) != 1 || preview.Mappings[0].Rows["resource_middlewares"] != 1 {
+		t.Fatalf("unexpected dry run report: %+v", preview)
+	}
+	if n, _ := db.CountLegacyResources(); n != 1 {
+		t.Fatalf("expected a dry run to change nothing")
+	}
+
+	report, err := db.MigrateLegacyResourceIDs(false)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(report.Mappings) != 1 {
+		t.Fatalf("expected one mapping, got %+v", report.Mappings)
+	}
+	newID := report.Mappings[0].NewID
+
+	var routerID string
+	if err := db.QueryRow("SELECT pangolin_router_id FROM resources WHERE id = ?", newID).Scan(&routerID); err != nil || routerID != "app-router@http" {
+		t.Fatalf("expected the old ID kept as pangolin_router_id, got %q, %v", routerID, err)
+	}
+	var assigned string
+	if err := db.QueryRow("SELECT resource_id FROM resource_middlewares WHERE middleware_id = 'mw1'").Scan(&assigned); err != nil || assigned != newID {
+		t.Fatalf("expected the assignment to follow the new ID, got %q, %v", assigned, err)
+	}
+	if n, _ := db.CountLegacyResources(); n != 0 {
+		t.Fatalf("expected no legacy resources after migrating, got %d", n)
+	}
+	if again, err := db.MigrateLegacyResourceIDs(false); err != nil || len(again.Mappings) != 0 {
+		t.Fatalf("expected a second run to do nothing, got %+v, %v", again, err)
+	}
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Snapshot writes a consistent copy of the live database to path with
+// VACUUM INTO, which reads inside a transaction and does not block writers
+// for longer than a regular read. path must not exist.
+func (db *DB) Snapshot(ctx context.Context, path string) error {
+	if IsPostgres() {
+		return fmt.Errorf("snapshots are %w; back PostgreSQL up with pg_dump", ErrSQLiteOnly)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("snapshot target %s already exists", path)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
+// RestoreSnapshot replaces the contents of the live database with those of a
+// snapshot file in one transaction, so readers see either the old or the
+// restored data. The snapshot is integrity-checked first. Tables the snapshot
+// lacks are emptied, and only columns both schemas have are copied, so
+// snapshots of older releases restore into the current schema.
+func (db *DB) RestoreSnapshot(ctx context.Context, path string) error {
+	if IsPostgres() {
+		return fmt.Errorf("snapshot restores are %w; restore PostgreSQL with pg_restore", ErrSQLiteOnly)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+
+	// ATTACH and the foreign_keys pragma are per connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", path); err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "DETACH DATABASE snapshot"); err != nil {
+			log.Printf("Warning: failed to detach snapshot: %v", err)
+		}
+	}()
+
+	var integrity string
+	if err := conn.QueryRowContext(ctx, "PRAGMA snapshot.quick_check").Scan(&integrity); err != nil {
+		return fmt.Errorf("failed to check snapshot: %w", err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("snapshot failed its integrity check: %s", integrity)
+	}
+
+	var foreignKeys int
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), fmt.Sprintf("PRAGMA foreign_keys = %d", foreignKeys)); err != nil {
+			log.Printf("Warning: failed to restore foreign_keys pragma: %v", err)
+		}
+	}()
+
+	tables, err := snapshotTables(ctx, conn, "main")
+	if err != nil {
+		return err
+	}
+	snapshotTableSet, err := snapshotTables(ctx, conn, "snapshot")
+	if err != nil {
+		return err
+	}
+	inSnapshot := make(map[string]bool, len(snapshotTableSet))
+	for _, table := range snapshotTableSet {
+		inSnapshot[table] = true
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM main.%q", table)); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+		if !inSnapshot[table] {
+			continue
+		}
+		columns, err := sharedColumns(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		list := strings.Join(columns, ", ")
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%q (%s) SELECT %s FROM snapshot.%q", table, list, list, table)); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit restore: %w", err)
+	}
+	log.Printf("Restored database from snapshot %s (%d tables)", path, len(tables))
+	return nil
+}
+
+// snapshotTables lists the user tables of an attached schema
+func snapshotTables(ctx context.Context, conn *sql.Conn, schema string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(
+		"SELECT name FROM %s.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%%' ORDER BY name", schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// sharedColumns returns the quoted columns a table has in both the live
+// database and the snapshot
+func sharedColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT m.name FROM pragma_table_info(?, 'main') m
+		JOIN pragma_table_info(?, 'snapshot') s ON s.name = m.name
+		ORDER BY m.cid
+	`, table, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, fmt.Sprintf("%q", name))
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	mustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'auth', 'basicAuth', '{}')`)
+
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := db.Snapshot(ctx, path); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if err := db.Snapshot(ctx, path); err == nil {
+		t.Error("expected snapshotting over an existing file to fail")
+	}
+
+	mustExec(t, db, `DELETE FROM middlewares`)
+	mustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-2', 'later', 'compress', '{}')`)
+
+	if err := db.RestoreSnapshot(ctx, path); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	var ids []string
+	rows, err := db.Query(`SELECT id FROM middlewares ORDER BY id`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 1 || ids[0] != "mw-1" {
+		t.Errorf("middlewares after restore = %v, want [mw-1]", ids)
+	}
+}
+
+func TestRestoreSnapshotRejectsCorruptFile(t *testing.T) {
+	db := newTestDB(t)
+	mustExec(t, db, `INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'auth', 'basicAuth', '{}')`)
+
+	path := filepath.Join(t.TempDir(), "corrupt.db")
+	if err := os.WriteFile(path, []byte("not a database"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := db.RestoreSnapshot(context.Background(), path); err == nil {
+		t.Fatal("expected restoring a corrupt snapshot to fail")
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM middlewares`).Scan(&count); err != nil || count != 1 {
+		t.Errorf("live data changed after a failed restore: count = %d, err = %v", count, err)
+	}
+}
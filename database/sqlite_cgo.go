@@ -1,5 +1,25 @@
-//go:build cgo
+//go:build cgo && !purego
 
 package database
 
-import _ "github.com/mattn/go-sqlite3"
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	registerDriver(DriverCGO, sqliteDriver{
+		sqlName: "sqlite3",
+		dsn: func(path string) string {
+			return path + "?_journal=WAL&_busy_timeout=5000"
+		},
+		code: func(err error) (int, bool) {
+			var sqliteErr sqlite3.Error
+			if !errors.As(err, &sqliteErr) {
+				return 0, false
+			}
+			return int(sqliteErr.ExtendedCode), true
+		},
+	})
+}
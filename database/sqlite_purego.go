@@ -0,0 +1,26 @@
+package database
+
+import (
+	"errors"
+
+	"modernc.org/sqlite"
+)
+
+func init() {
+	registerDriver(DriverPureGo, sqliteDriver{
+		// modernc.org/sqlite registers itself under this name
+		sqlName: "sqlite",
+		// Pragmas are applied per connection; the sqlite time format stores
+		// timestamps the way the cgo driver does, so both read the same file
+		dsn: func(path string) string {
+			return path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+		},
+		code: func(err error) (int, bool) {
+			var sqliteErr *sqlite.Error
+			if !errors.As(err, &sqliteErr) {
+				return 0, false
+			}
+			return sqliteErr.Code(), true
+		},
+	})
+}
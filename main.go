@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -15,7 +18,9 @@ import (
 	"github.com/hhftechnology/middleware-manager/api"
 	"github.com/hhftechnology/middleware-manager/config"
 	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
 	"github.com/hhftechnology/middleware-manager/services"
+	"github.com/hhftechnology/middleware-manager/util"
 )
 
 // Configuration represents the application configuration
@@ -24,6 +29,8 @@ type Configuration struct {
 	TraefikAPIURL           string
 	TraefikConfDir          string
 	DBPath                  string
+	SQLiteDriver            string
+	DBDSN                   string
 	Port                    string
 	UIPath                  string
 	ConfigDir               string
@@ -35,20 +42,48 @@ type Configuration struct {
 	CORSOrigin              string
 	ActiveDataSource        string
 	TraefikStaticConfigPath string
+	ForwardAuthURL          string
+	ProxyErrorBudget        int
+	ProxyConfigLimits       services.ConfigLimits
+	ProxyMergeSections      services.MergeSections
+	ProxyTransformLog       bool
+	ProxyChaos              bool
+	ConfigWebhook           services.ConfigWebhookSettings
+	BreakerThreshold        int
+	BreakerCooldown         time.Duration
+	CertSigner              services.CertSignerSettings
+	BackupDir               string
+	BackupInterval          time.Duration
+	BackupKeep              int
+	S3Backup                services.S3Settings
+	S3BackupInterval        time.Duration
+	BackupPassphrase        string
+	Pprof                   bool
+	TraefikVersion          string
+	TraefikAccessLogPath    string
+	OutboundProxy           string
+	OutboundNoProxy         string
+	OutboundUserAgent       string
+	UpstreamRetry           services.RetryPolicy
+	ShrinkPercent           int
+	ShrinkConfirmations     int
+	DNSDiscoveryServer      string
+	APIAuthMode             string
+	APIAdminToken           string
+	SocketPath              string
+	SocketMode              os.FileMode
+	SocketOnly              bool
+	FileConfig              bool
+	LogFile                 string
+	MigrateResourceIDs      bool
 }
 
 // DiscoverTraefikAPI attempts to discover the Traefik API by trying common URLs
+// and the Traefik services that resolve on the Docker network
 func DiscoverTraefikAPI() (string, error) {
-	client := &http.Client{
-		Timeout: 2 * time.Second,
-	}
+	client := services.HTTPClientWithTimeout(2 * time.Second)
 
-	urls := []string{
-		"http://host.docker.internal:8080",
-		"http://localhost:8080",
-		"http://127.0.0.1:8080",
-		"http://traefik:8080",
-	}
+	urls := services.TraefikCandidateURLs(context.Background(), models.DataSourceConfig{})
 
 	for _, url := range urls {
 		testURL := url + "/api/version"
@@ -66,13 +101,50 @@ func DiscoverTraefikAPI() (string, error) {
 }
 
 func main() {
+	// The plugin sandbox runs each plugin in a copy of this binary
+	if services.IsPluginSandboxChild() {
+		os.Exit(services.RunPluginSandboxChild())
+	}
+
 	log.Println("Starting Middleware Manager...")
 
 	var debug bool
+	var migrateDSN string
+	var migrateOpts database.PostgresMigrationOptions
 	flag.BoolVar(&debug, "debug", false, "Enable debug mode")
+	flag.StringVar(&migrateDSN, "migrate-postgres", "", "Copy the SQLite database to this PostgreSQL DSN, verify it and exit")
+	flag.BoolVar(&migrateOpts.DryRun, "migrate-dry-run", false, "With -migrate-postgres, only create the schema and count rows")
+	flag.BoolVar(&migrateOpts.Overwrite, "migrate-overwrite", false, "With -migrate-postgres, replace data PostgreSQL already holds")
+	flag.BoolVar(&migrateOpts.Cutover, "migrate-cutover", false, "With -migrate-postgres, mark the SQLite database as migrated once verified; run it with MM stopped")
 	flag.Parse()
 
-	cfg := loadConfiguration(debug)
+	// ENV_FILE settings are applied before anything reads the environment
+	// and re-read on reload
+	var envFile *util.EnvFile
+	if path := os.Getenv("ENV_FILE"); path != "" {
+		envFile = util.NewEnvFile(path)
+		if err := envFile.Load(); err != nil {
+			log.Fatalf("Failed to load ENV_FILE: %v", err)
+		}
+	}
+
+	cfg, err := loadConfiguration(debug)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	logOutput := &util.LogOutput{}
+	if err := logOutput.Open(cfg.LogFile); err != nil {
+		log.Fatalf("Failed to open LOG_FILE: %v", err)
+	}
+	defer logOutput.Close()
+
+	if err := configureOutboundProxy(cfg, false); err != nil {
+		log.Fatalf("%v", err)
+	}
+	services.ConfigureUserAgent(cfg.OutboundUserAgent)
+	services.ConfigureRetry(cfg.UpstreamRetry)
+	log.Printf("Middleware Manager %s, outbound User-Agent: %s", services.AppVersion(), services.UserAgent())
 
 	if os.Getenv("TRAEFIK_API_URL") == "" {
 		if discoveredURL, err := DiscoverTraefikAPI(); err == nil && discoveredURL != "" {
@@ -81,12 +153,31 @@ func main() {
 		}
 	}
 
-	db, err := database.InitDB(cfg.DBPath)
+	if err := database.UseDriver(cfg.SQLiteDriver); err != nil {
+		log.Fatalf("Invalid SQLITE_DRIVER: %v", err)
+	}
+	db, err := database.Open(cfg.DBPath, cfg.DBDSN)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
+	if migrateDSN != "" {
+		code := migrateToPostgres(db, migrateDSN, migrateOpts)
+		db.Close()
+		os.Exit(code)
+	}
+	// A SQLite file that was cut over to PostgreSQL is stale; writing to it
+	// would split the data between the two
+	if !database.IsPostgres() {
+		if cutover, err := db.PostgresCutover(); err != nil {
+			log.Printf("Warning: Failed to check for a PostgreSQL cutover: %v", err)
+		} else if cutover != nil {
+			log.Fatalf("The database at %s was migrated to PostgreSQL %s on %s; set DB_DSN to it, or delete the row in postgres_cutover to keep using SQLite",
+				cfg.DBPath, cutover.Target, cutover.MigratedAt.Format(time.RFC3339))
+		}
+	}
+
 	configDir := cfg.ConfigDir
 	if err := config.EnsureConfigDirectory(configDir); err != nil {
 		log.Printf("Warning: Failed to create config directory: %v", err)
@@ -112,12 +203,31 @@ func main() {
 	log.Println("Performing full database cleanup...")
 	cleanupOpts := database.DefaultCleanupOptions()
 	cleanupOpts.LogLevel = 2 // More verbose logging during startup
+	cleanupOpts.Report = database.NewCleanupReport(cleanupOpts)
 
-	if err := db.PerformFullCleanup(cleanupOpts); err != nil {
-		log.Printf("Warning: Database cleanup encountered issues: %v", err)
+	cleanupErr := db.PerformFullCleanup(cleanupOpts)
+	if cleanupErr != nil {
+		log.Printf("Warning: Database cleanup encountered issues: %v", cleanupErr)
 	} else {
 		log.Println("Database cleanup completed successfully")
 	}
+	cleanupOpts.Report.Finish(cleanupErr)
+	if err := db.SaveCleanupReport(cleanupOpts.Report); err != nil {
+		log.Printf("Warning: Failed to save startup cleanup report: %v", err)
+	}
+
+	// Move resources still keyed by their Pangolin router ID to internal UUIDs
+	if cfg.MigrateResourceIDs {
+		if _, err := db.MigrateLegacyResourceIDs(false); err != nil {
+			log.Printf("Warning: Failed to migrate legacy resource IDs: %v", err)
+		}
+	} else if n, err := db.CountLegacyResources(); err == nil && n > 0 {
+		if database.IsPostgres() {
+			log.Printf("%d resources use legacy IDs; they can only be moved to internal UUIDs on SQLite", n)
+		} else {
+			log.Printf("%d resources use legacy IDs; set MIGRATE_LEGACY_RESOURCE_IDS=true or POST /api/maintenance/migrate-resource-ids to move them to internal UUIDs", n)
+		}
+	}
 
 	configManager, err := services.NewConfigManager(filepath.Join(configDir, "config.json"))
 	if err != nil {
@@ -128,29 +238,57 @@ func main() {
 
 	stopChan := make(chan struct{})
 
+	// Webhooks are delivered by the API server; the watchers only queue events
+	webhooks := services.NewWebhookService(db)
+
 	resourceWatcher, err := services.NewResourceWatcher(db, configManager)
 	if err != nil {
 		log.Fatalf("Failed to create resource watcher: %v", err)
 	}
+	resourceWatcher.SetShrinkGuard(cfg.ShrinkPercent, cfg.ShrinkConfirmations)
+	resourceWatcher.SetWebhooks(webhooks)
 	go resourceWatcher.Start(cfg.CheckInterval)
 
 	configGenerator := services.NewConfigGenerator(db, cfg.TraefikConfDir, configManager)
-	if strings.ToLower(os.Getenv("ENABLE_FILE_CONFIG")) == "true" {
+	configGenerator.SetWebhooks(webhooks)
+	if cfg.FileConfig {
 		go configGenerator.Start(cfg.GenerateInterval)
 	} else {
 		log.Println("File config generator disabled (ENABLE_FILE_CONFIG not true); relying on API proxy only")
 	}
 
-	serverConfig := api.ServerConfig{
-		Port:        cfg.Port,
-		UIPath:      cfg.UIPath,
-		Debug:       cfg.Debug,
-		AllowCORS:   cfg.AllowCORS,
-		CORSOrigin:  cfg.CORSOrigin,
-		PangolinURL: cfg.PangolinAPIURL,
+	serviceWatcher, err := services.NewServiceWatcher(db, configManager)
+	if err != nil {
+		log.Printf("Warning: Failed to create service watcher: %v", err)
+		serviceWatcher = nil
+	}
+
+	reloader := &reloader{
+		debug:           debug,
+		running:         cfg,
+		envFile:         envFile,
+		logOutput:       logOutput,
+		configManager:   configManager,
+		resourceWatcher: resourceWatcher,
+		serviceWatcher:  serviceWatcher,
 	}
 
+	// A misconfigured signer must not fall back to keeping a CA key in the database
+	certSigner, err := services.NewCertSigner(cfg.CertSigner)
+	if err != nil {
+		log.Fatalf("Invalid MTLS_SIGNER settings: %v", err)
+	}
+	if certSigner != nil {
+		log.Printf("mTLS client certificates are issued by %s", certSigner.Name())
+	}
+
+	serverConfig := newServerConfig(cfg, resourceWatcher)
+	serverConfig.ServiceWatcher = serviceWatcher
+	serverConfig.CertSigner = certSigner
+	serverConfig.Reload = reloader.Reload
+	serverConfig.Webhooks = webhooks
 	server := api.NewServer(db, serverConfig, configManager, cfg.TraefikStaticConfigPath)
+	reloader.server = server
 	go func() {
 		if err := server.Start(); err != nil {
 			log.Printf("Server error: %v", err)
@@ -161,19 +299,29 @@ func main() {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 
-	serviceWatcher, err := services.NewServiceWatcher(db, configManager)
-	if err != nil {
-		log.Printf("Warning: Failed to create service watcher: %v", err)
-		serviceWatcher = nil
-	} else {
+	// SIGHUP reloads the settings in place
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	if serviceWatcher != nil {
 		go serviceWatcher.Start(cfg.ServiceInterval)
 	}
 
-	select {
-	case <-signalChan:
-		log.Println("Received shutdown signal")
-	case <-stopChan:
-		log.Println("Received stop signal from server")
+wait:
+	for {
+		select {
+		case <-reloadChan:
+			log.Println("Received SIGHUP, reloading settings")
+			if _, err := reloader.Reload(services.ReloadSignal); err != nil {
+				log.Printf("Settings reload failed, previous settings kept: %v", err)
+			}
+		case <-signalChan:
+			log.Println("Received shutdown signal")
+			break wait
+		case <-stopChan:
+			log.Println("Received stop signal from server")
+			break wait
+		}
 	}
 
 	log.Println("Shutting down...")
@@ -186,7 +334,70 @@ func main() {
 	log.Println("Middleware Manager stopped")
 }
 
-func loadConfiguration(debug bool) Configuration {
+// newServerConfig returns the API server settings of cfg
+func newServerConfig(cfg Configuration, resourceWatcher *services.ResourceWatcher) api.ServerConfig {
+	return api.ServerConfig{
+		Port:           cfg.Port,
+		UIPath:         cfg.UIPath,
+		Debug:          cfg.Debug,
+		AllowCORS:      cfg.AllowCORS,
+		CORSOrigin:     cfg.CORSOrigin,
+		PangolinURL:    cfg.PangolinAPIURL,
+		ForwardAuthURL: cfg.ForwardAuthURL,
+		ErrorBudget:    cfg.ProxyErrorBudget,
+		ConfigLimits:   cfg.ProxyConfigLimits,
+		MergeSections:  cfg.ProxyMergeSections,
+		TransformLog:   cfg.ProxyTransformLog,
+		Chaos:          cfg.ProxyChaos,
+		ConfigWebhook:  cfg.ConfigWebhook,
+		TraefikVersion: cfg.TraefikVersion,
+		AccessLogPath:  cfg.TraefikAccessLogPath,
+		TraefikConfDir: cfg.TraefikConfDir,
+		FileConfig:     cfg.FileConfig,
+
+		PangolinBreakerThreshold: cfg.BreakerThreshold,
+		PangolinBreakerCooldown:  cfg.BreakerCooldown,
+
+		BackupDir:        cfg.BackupDir,
+		BackupInterval:   cfg.BackupInterval,
+		BackupKeep:       cfg.BackupKeep,
+		S3Backup:         cfg.S3Backup,
+		S3BackupInterval: cfg.S3BackupInterval,
+		BackupPassphrase: cfg.BackupPassphrase,
+		Pprof:            cfg.Pprof,
+
+		DNSDiscoveryServer: cfg.DNSDiscoveryServer,
+
+		AuthMode:   cfg.APIAuthMode,
+		AdminToken: cfg.APIAdminToken,
+
+		SocketPath: cfg.SocketPath,
+		SocketMode: cfg.SocketMode,
+		SocketOnly: cfg.SocketOnly,
+
+		ResourceWatcher: resourceWatcher,
+	}
+}
+
+// migrateToPostgres runs -migrate-postgres, printing the report as JSON, and
+// returns the exit code
+func migrateToPostgres(db *database.DB, dsn string, opts database.PostgresMigrationOptions) int {
+	report, err := db.MigrateToPostgres(context.Background(), dsn, opts)
+	if report != nil {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	}
+	if err != nil {
+		log.Printf("PostgreSQL migration failed: %v", err)
+		return 1
+	}
+	if report.CutOver {
+		log.Printf("Cut over to PostgreSQL; start MM with DB_DSN set to %s", report.Target)
+	}
+	return 0
+}
+
+func loadConfiguration(debug bool) (Configuration, error) {
 	checkInterval := 30 * time.Second
 	if intervalStr := getEnv("CHECK_INTERVAL_SECONDS", "30"); intervalStr != "" {
 		if interval, err := strconv.Atoi(intervalStr); err == nil && interval > 0 {
@@ -208,6 +419,168 @@ func loadConfiguration(debug bool) Configuration {
 		}
 	}
 
+	proxyErrorBudget := 0
+	if budgetStr := getEnv("PROXY_ERROR_BUDGET", "0"); budgetStr != "" {
+		if budget, err := strconv.Atoi(budgetStr); err == nil && budget >= 0 {
+			proxyErrorBudget = budget
+		}
+	}
+
+	proxyConfigLimits := services.DefaultConfigLimits
+	for env, limit := range map[string]*int{
+		"PROXY_MAX_MIDDLEWARES_PER_ROUTER": &proxyConfigLimits.MaxMiddlewaresPerRouter,
+		"PROXY_MAX_CONFIG_BYTES":           &proxyConfigLimits.MaxConfigBytes,
+		"PROXY_MAX_RULE_REGEX_LENGTH":      &proxyConfigLimits.MaxRuleRegexLength,
+	} {
+		if valueStr := getEnv(env, ""); valueStr != "" {
+			if value, err := strconv.Atoi(valueStr); err == nil && value >= 0 {
+				*limit = value
+			}
+		}
+	}
+
+	proxyMergeSections, err := services.ParseDisabledSections(getEnv("PROXY_DISABLED_SECTIONS", ""))
+	if err != nil {
+		return Configuration{}, fmt.Errorf("invalid PROXY_DISABLED_SECTIONS: %w", err)
+	}
+
+	configWebhook := services.ConfigWebhookSettings{
+		URL:    getEnv("CONFIG_WEBHOOK_URL", ""),
+		Secret: getEnv("CONFIG_WEBHOOK_SECRET", ""),
+		Mode:   strings.ToLower(getEnv("CONFIG_WEBHOOK_MODE", services.ConfigWebhookModeFull)),
+	}
+	if attemptsStr := getEnv("CONFIG_WEBHOOK_MAX_ATTEMPTS", ""); attemptsStr != "" {
+		if attempts, err := strconv.Atoi(attemptsStr); err == nil && attempts > 0 {
+			configWebhook.MaxAttempts = attempts
+		}
+	}
+
+	breakerThreshold := services.DefaultPangolinBreakerThreshold
+	if thresholdStr := getEnv("PANGOLIN_BREAKER_THRESHOLD", ""); thresholdStr != "" {
+		if threshold, err := strconv.Atoi(thresholdStr); err == nil && threshold >= 0 {
+			breakerThreshold = threshold
+		}
+	}
+	breakerCooldown := services.DefaultPangolinBreakerCooldown
+	if cooldownStr := getEnv("PANGOLIN_BREAKER_COOLDOWN_SECONDS", ""); cooldownStr != "" {
+		if seconds, err := strconv.Atoi(cooldownStr); err == nil && seconds > 0 {
+			breakerCooldown = time.Duration(seconds) * time.Second
+		}
+	}
+
+	// Zero values fall back to services.DefaultRetryPolicy
+	var upstreamRetry services.RetryPolicy
+	if attemptsStr := getEnv("UPSTREAM_RETRY_ATTEMPTS", ""); attemptsStr != "" {
+		if attempts, err := strconv.Atoi(attemptsStr); err == nil && attempts > 0 {
+			upstreamRetry.Attempts = attempts
+		}
+	}
+	if delayStr := getEnv("UPSTREAM_RETRY_BASE_DELAY_MS", ""); delayStr != "" {
+		if ms, err := strconv.Atoi(delayStr); err == nil && ms > 0 {
+			upstreamRetry.BaseDelay = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if delayStr := getEnv("UPSTREAM_RETRY_MAX_DELAY_MS", ""); delayStr != "" {
+		if ms, err := strconv.Atoi(delayStr); err == nil && ms > 0 {
+			upstreamRetry.MaxDelay = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	certSigner := services.CertSignerSettings{
+		Type: getEnv("MTLS_SIGNER", ""),
+		StepCA: services.StepCASettings{
+			URL:                getEnv("MTLS_STEPCA_URL", ""),
+			Provisioner:        getEnv("MTLS_STEPCA_PROVISIONER", ""),
+			ProvisionerKeyFile: getEnv("MTLS_STEPCA_PROVISIONER_KEY_FILE", ""),
+			RootFile:           getEnv("MTLS_STEPCA_ROOT_FILE", ""),
+		},
+		Vault: services.VaultPKISettings{
+			Addr:      getEnv("MTLS_VAULT_ADDR", getEnv("VAULT_ADDR", "")),
+			Token:     getEnv("MTLS_VAULT_TOKEN", getEnv("VAULT_TOKEN", "")),
+			Mount:     getEnv("MTLS_VAULT_PKI_MOUNT", ""),
+			Role:      getEnv("MTLS_VAULT_PKI_ROLE", ""),
+			Namespace: getEnv("MTLS_VAULT_NAMESPACE", getEnv("VAULT_NAMESPACE", "")),
+			CAFile:    getEnv("MTLS_VAULT_CA_FILE", ""),
+		},
+		AWSPCA: services.AWSPCASettings{
+			CAARN:            getEnv("MTLS_AWS_PCA_ARN", ""),
+			Region:           getEnv("MTLS_AWS_PCA_REGION", getEnv("AWS_REGION", "")),
+			Endpoint:         getEnv("MTLS_AWS_PCA_ENDPOINT", ""),
+			AccessKeyID:      getEnv("AWS_ACCESS_KEY_ID", ""),
+			SecretAccessKey:  getEnv("AWS_SECRET_ACCESS_KEY", ""),
+			SessionToken:     getEnv("AWS_SESSION_TOKEN", ""),
+			SigningAlgorithm: getEnv("MTLS_AWS_PCA_SIGNING_ALGORITHM", ""),
+			TemplateARN:      getEnv("MTLS_AWS_PCA_TEMPLATE_ARN", ""),
+		},
+	}
+
+	dbPath := getEnv("DB_PATH", "/data/middleware.db")
+	backupInterval := 24 * time.Hour
+	if hoursStr := getEnv("BACKUP_INTERVAL_HOURS", ""); hoursStr != "" {
+		if hours, err := strconv.Atoi(hoursStr); err == nil && hours >= 0 {
+			backupInterval = time.Duration(hours) * time.Hour
+		}
+	}
+	backupKeep := services.DefaultBackupKeep
+	if keepStr := getEnv("BACKUP_KEEP", ""); keepStr != "" {
+		if keep, err := strconv.Atoi(keepStr); err == nil && keep >= 0 {
+			backupKeep = keep
+		}
+	}
+
+	s3Backup := services.S3Settings{
+		Endpoint:        getEnv("BACKUP_S3_ENDPOINT", ""),
+		Region:          getEnv("BACKUP_S3_REGION", ""),
+		Bucket:          getEnv("BACKUP_S3_BUCKET", ""),
+		AccessKeyID:     getEnv("BACKUP_S3_ACCESS_KEY_ID", ""),
+		SecretAccessKey: getEnv("BACKUP_S3_SECRET_ACCESS_KEY", ""),
+		Prefix:          getEnv("BACKUP_S3_PREFIX", ""),
+		PathStyle:       strings.ToLower(getEnv("BACKUP_S3_PATH_STYLE", "false")) == "true",
+		SSE:             getEnv("BACKUP_S3_SSE", ""),
+		SSEKMSKeyID:     getEnv("BACKUP_S3_SSE_KMS_KEY_ID", ""),
+	}
+	var s3BackupInterval time.Duration
+	if hoursStr := getEnv("BACKUP_S3_INTERVAL_HOURS", ""); hoursStr != "" {
+		if hours, err := strconv.Atoi(hoursStr); err == nil && hours > 0 {
+			s3BackupInterval = time.Duration(hours) * time.Hour
+		}
+	}
+
+	shrinkPercent := services.DefaultShrinkPercent
+	if percentStr := getEnv("RESOURCE_SHRINK_PERCENT", ""); percentStr != "" {
+		if percent, err := strconv.Atoi(percentStr); err == nil && percent >= 0 && percent <= 100 {
+			shrinkPercent = percent
+		}
+	}
+
+	shrinkConfirmations := services.DefaultShrinkConfirmations
+	if confirmStr := getEnv("RESOURCE_SHRINK_CONFIRMATIONS", ""); confirmStr != "" {
+		if confirmations, err := strconv.Atoi(confirmStr); err == nil && confirmations >= 0 {
+			shrinkConfirmations = confirmations
+		}
+	}
+
+	apiAuthMode := strings.ToLower(strings.TrimSpace(getEnv("API_AUTH_MODE", api.AuthModeOff)))
+	if !api.ValidAuthMode(apiAuthMode) {
+		log.Printf("Invalid API_AUTH_MODE %q (expected off, writes or all); requiring a token for every request", apiAuthMode)
+		apiAuthMode = api.AuthModeAll
+	}
+
+	socketMode := api.DefaultSocketMode
+	if modeStr := getEnv("API_SOCKET_MODE", ""); modeStr != "" {
+		if mode, err := strconv.ParseUint(modeStr, 8, 32); err == nil && mode <= 0777 {
+			socketMode = os.FileMode(mode)
+		} else {
+			log.Printf("Invalid API_SOCKET_MODE %q (expected octal permissions such as 0660); using %04o", modeStr, socketMode)
+		}
+	}
+	socketPath := getEnv("API_SOCKET_PATH", "")
+	socketOnly := strings.ToLower(getEnv("API_SOCKET_ONLY", "false")) == "true"
+	if socketOnly && socketPath == "" {
+		log.Printf("API_SOCKET_ONLY is set without API_SOCKET_PATH; serving on the TCP port")
+		socketOnly = false
+	}
+
 	allowCORS := false
 	if corsStr := getEnv("ALLOW_CORS", "false"); corsStr != "" {
 		allowCORS = strings.ToLower(corsStr) == "true"
@@ -217,14 +590,28 @@ func loadConfiguration(debug bool) Configuration {
 		debug = strings.ToLower(debugStr) == "true"
 	}
 
+	// Snapshots, backups and the legacy resource ID migration work on the
+	// SQLite file. On PostgreSQL they would do nothing, so asking for them
+	// stops startup instead of leaving the install without backups.
+	dbDSN := getEnv("DB_DSN", "")
+	backupDir := getEnv("BACKUP_DIR", filepath.Join(filepath.Dir(dbPath), "backups"))
+	if strings.TrimSpace(dbDSN) != "" {
+		if err := checkPostgresSettings(s3Backup.Endpoint); err != nil {
+			return Configuration{}, err
+		}
+		backupDir = ""
+	}
+
 	return Configuration{
 		PangolinAPIURL: getEnv("PANGOLIN_API_URL", "http://pangolin:3001/api/v1"),
 		// Default to in-network Traefik service; host.docker.internal often fails inside containers
 		TraefikAPIURL:           getEnv("TRAEFIK_API_URL", "http://traefik:8080"),
 		TraefikConfDir:          getEnv("TRAEFIK_CONF_DIR", "/conf"),
-		DBPath:                  getEnv("DB_PATH", "/data/middleware.db"),
+		DBPath:                  dbPath,
+		SQLiteDriver:            getEnv("SQLITE_DRIVER", ""),
+		DBDSN:                   dbDSN,
 		Port:                    getEnv("PORT", "3456"),
-		UIPath:                  getEnv("UI_PATH", "/app/ui/dist"),
+		UIPath:                  getEnv("UI_PATH", defaultUIPath()),
 		ConfigDir:               getEnv("CONFIG_DIR", "/app/config"),
 		ActiveDataSource:        getEnv("ACTIVE_DATA_SOURCE", "pangolin"),
 		CheckInterval:           checkInterval,
@@ -234,7 +621,100 @@ func loadConfiguration(debug bool) Configuration {
 		AllowCORS:               allowCORS,
 		CORSOrigin:              getEnv("CORS_ORIGIN", ""),
 		TraefikStaticConfigPath: getEnv("TRAEFIK_STATIC_CONFIG_PATH", "/etc/traefik/traefik.yml"),
+		ForwardAuthURL:          getEnv("FORWARD_AUTH_URL", ""),
+		ProxyErrorBudget:        proxyErrorBudget,
+		ProxyConfigLimits:       proxyConfigLimits,
+		ProxyMergeSections:      proxyMergeSections,
+		ProxyTransformLog:       strings.ToLower(getEnv("PROXY_TRANSFORM_LOG", "false")) == "true",
+		ProxyChaos:              strings.ToLower(getEnv("PROXY_CHAOS", "false")) == "true",
+		ConfigWebhook:           configWebhook,
+		BreakerThreshold:        breakerThreshold,
+		BreakerCooldown:         breakerCooldown,
+		CertSigner:              certSigner,
+		BackupDir:               backupDir,
+		BackupInterval:          backupInterval,
+		BackupKeep:              backupKeep,
+		S3Backup:                s3Backup,
+		S3BackupInterval:        s3BackupInterval,
+		BackupPassphrase:        getEnv("BACKUP_ENCRYPTION_PASSPHRASE", ""),
+		Pprof:                   strings.ToLower(getEnv("ENABLE_PPROF", "false")) == "true",
+		MigrateResourceIDs:      strings.ToLower(getEnv("MIGRATE_LEGACY_RESOURCE_IDS", "false")) == "true",
+		TraefikVersion:          getEnv("TRAEFIK_VERSION", ""),
+		TraefikAccessLogPath:    getEnv("TRAEFIK_ACCESS_LOG_PATH", ""),
+		OutboundProxy:           getEnv("OUTBOUND_PROXY", ""),
+		OutboundNoProxy:         getEnv("OUTBOUND_NO_PROXY", ""),
+		OutboundUserAgent:       getEnv("OUTBOUND_USER_AGENT", ""),
+		UpstreamRetry:           upstreamRetry,
+		DNSDiscoveryServer:      getEnv("DNS_DISCOVERY_SERVER", ""),
+		ShrinkPercent:           shrinkPercent,
+		ShrinkConfirmations:     shrinkConfirmations,
+		APIAuthMode:             apiAuthMode,
+		APIAdminToken:           getEnv("API_ADMIN_TOKEN", ""),
+		SocketPath:              socketPath,
+		SocketMode:              socketMode,
+		SocketOnly:              socketOnly,
+		FileConfig:              strings.ToLower(getEnv("ENABLE_FILE_CONFIG", "false")) == "true",
+		LogFile:                 getEnv("LOG_FILE", ""),
+	}, nil
+}
+
+// checkPostgresSettings rejects the SQLite-only settings when DB_DSN is set
+func checkPostgresSettings(s3Endpoint string) error {
+	if strings.TrimSpace(getEnv("BACKUP_DIR", "")) != "" {
+		return fmt.Errorf("BACKUP_DIR cannot be used with DB_DSN: local backups snapshot the SQLite file; back PostgreSQL up with pg_dump")
+	}
+	if s3Endpoint != "" {
+		return fmt.Errorf("BACKUP_S3_ENDPOINT cannot be used with DB_DSN: S3 backups snapshot the SQLite file; back PostgreSQL up with pg_dump")
+	}
+	if strings.ToLower(getEnv("MIGRATE_LEGACY_RESOURCE_IDS", "false")) == "true" {
+		return fmt.Errorf("MIGRATE_LEGACY_RESOURCE_IDS cannot be used with DB_DSN: migrate legacy resource IDs on SQLite before moving to PostgreSQL")
+	}
+	return nil
+}
+
+// configureOutboundProxy applies OUTBOUND_PROXY and OUTBOUND_NO_PROXY. Each
+// falls back to the standard HTTP(S)_PROXY and NO_PROXY variables. Without
+// either, the proxy is only set when force is true, i.e. on a reload that
+// may have removed them.
+func configureOutboundProxy(cfg Configuration, force bool) error {
+	if cfg.OutboundProxy == "" && cfg.OutboundNoProxy == "" && !force {
+		return nil
+	}
+	proxyConfig := services.ProxyConfig{
+		HTTPProxy:  firstEnv("HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: firstEnv("HTTPS_PROXY", "https_proxy"),
+		NoProxy:    firstEnv("NO_PROXY", "no_proxy"),
+	}
+	if cfg.OutboundProxy != "" {
+		proxyConfig.HTTPProxy = cfg.OutboundProxy
+		proxyConfig.HTTPSProxy = cfg.OutboundProxy
+	}
+	if cfg.OutboundNoProxy != "" {
+		proxyConfig.NoProxy = cfg.OutboundNoProxy
+	}
+	if err := services.ConfigureOutboundProxy(proxyConfig); err != nil {
+		return fmt.Errorf("invalid OUTBOUND_PROXY: %w", err)
+	}
+	log.Printf("Outbound proxy configured (no proxy for: %s)", proxyConfig.NoProxy)
+	return nil
+}
+
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// defaultUIPath returns the UI build of the Docker image, or the ui/dist
+// directory next to the working directory or the executable of a release archive
+func defaultUIPath() string {
+	if path := util.FindFile("/app/ui/dist", "ui/dist"); path != "" {
+		return path
 	}
+	return "/app/ui/dist"
 }
 
 func getEnv(key, fallback string) string {
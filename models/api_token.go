@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API token scopes
+const (
+	// APITokenScopeRead allows GET and HEAD requests
+	APITokenScopeRead = "read"
+	// APITokenScopeAdmin allows every request the token's role allows
+	APITokenScopeAdmin = "admin"
+)
+
+// APIToken is a bearer token for the management API. Only a hash of the
+// secret is stored; Prefix identifies the token in lists and logs.
+type APIToken struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	// UserID is the user the token acts for; empty for service tokens
+	UserID string `json:"user_id,omitempty"`
+	// Role is what the token may do: its user's role, or admin for a service
+	// token, lowered to viewer by the read scope
+	Role       string     `json:"role"`
+	Prefix     string     `json:"prefix"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ResolveRole sets Role from the scope and the role of the token's user
+// (empty for service tokens)
+func (t *APIToken) ResolveRole(userRole string) {
+	switch {
+	case t.Scope != APITokenScopeAdmin:
+		t.Role = RoleViewer
+	case t.UserID != "":
+		t.Role = userRole
+	default:
+		t.Role = RoleAdmin
+	}
+}
+
+// APITokenRequest creates an API token
+type APITokenRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	// UserID issues the token to a user, whose role it then acts with
+	UserID string `json:"user_id,omitempty"`
+	// ExpiresIn is a duration such as 720h; empty never expires
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// Normalize trims the fields and fills in the read scope default
+func (r *APITokenRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Scope = strings.ToLower(strings.TrimSpace(r.Scope))
+	r.UserID = strings.TrimSpace(r.UserID)
+	r.ExpiresIn = strings.TrimSpace(r.ExpiresIn)
+	if r.Scope == "" {
+		r.Scope = APITokenScopeRead
+	}
+}
+
+// Validate checks the name, scope and expiry
+func (r *APITokenRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Scope != APITokenScopeRead && r.Scope != APITokenScopeAdmin {
+		return fmt.Errorf("invalid scope %q: expected %s or %s", r.Scope, APITokenScopeRead, APITokenScopeAdmin)
+	}
+	if r.ExpiresIn != "" {
+		if d, err := time.ParseDuration(r.ExpiresIn); err != nil || d <= 0 {
+			return fmt.Errorf("invalid expires_in %q: expected a duration such as 720h", r.ExpiresIn)
+		}
+	}
+	return nil
+}
+
+// CreatedAPIToken is returned once when a token is created; the secret
+// cannot be read again
+type CreatedAPIToken struct {
+	APIToken
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AssignmentExpiration records a temporary middleware assignment that the
+// scheduler removed once its expiry passed. Owner and Contact are copied from
+// the resource (or the middleware when the resource has none) so whoever
+// attached the middleware can be told it is gone.
+type AssignmentExpiration struct {
+	ID             string    `json:"id"`
+	ResourceID     string    `json:"resource_id"`
+	ResourceHost   string    `json:"resource_host"`
+	MiddlewareID   string    `json:"middleware_id"`
+	MiddlewareName string    `json:"middleware_name"`
+	Owner          string    `json:"owner"`
+	Contact        string    `json:"contact"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	RemovedAt      time.Time `json:"removed_at"`
+}
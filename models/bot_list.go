@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bot list categories
+const (
+	BotListBadBots    = "bad_bots"
+	BotListAICrawlers = "ai_crawlers"
+	BotListScanners   = "scanners"
+	BotListCustom     = "custom"
+)
+
+// BotList is a named list of user-agent substrings to block. Lists with a
+// source URL can be refreshed periodically (every UpdateInterval minutes).
+type BotList struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Category       string     `json:"category"`
+	Patterns       []string   `json:"patterns"`
+	SourceURL      string     `json:"source_url"`
+	UpdateInterval int        `json:"update_interval"`
+	LastUpdatedAt  *time.Time `json:"last_updated_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Normalize trims whitespace and drops empty or duplicate patterns
+func (l *BotList) Normalize() {
+	l.Name = strings.TrimSpace(l.Name)
+	l.Category = strings.TrimSpace(l.Category)
+	if l.Category == "" {
+		l.Category = BotListCustom
+	}
+	l.SourceURL = strings.TrimSpace(l.SourceURL)
+	l.Patterns = cleanBotPatterns(l.Patterns)
+}
+
+// Validate checks the list name, category, source URL and update interval
+func (l *BotList) Validate() error {
+	if l.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch l.Category {
+	case BotListBadBots, BotListAICrawlers, BotListScanners, BotListCustom:
+	default:
+		return fmt.Errorf("invalid category %q", l.Category)
+	}
+	if l.SourceURL != "" {
+		u, err := url.Parse(l.SourceURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid source_url %q", l.SourceURL)
+		}
+	}
+	if l.UpdateInterval < 0 {
+		return fmt.Errorf("update_interval must not be negative")
+	}
+	if l.UpdateInterval > 0 && l.SourceURL == "" {
+		return fmt.Errorf("update_interval requires a source_url")
+	}
+	if len(l.Patterns) == 0 && l.SourceURL == "" {
+		return fmt.Errorf("at least one pattern or a source_url is required")
+	}
+	return nil
+}
+
+// ParseBotPatterns parses a downloaded list: either a JSON array of strings or
+// plain text with one pattern per line and # comments
+func ParseBotPatterns(data []byte) []string {
+	var patterns []string
+	if err := json.Unmarshal(data, &patterns); err == nil {
+		return cleanBotPatterns(patterns)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		patterns = append(patterns, line)
+	}
+	return cleanBotPatterns(patterns)
+}
+
+// UserAgentRegex builds a case-insensitive regular expression matching any of
+// the patterns as a substring, or "" when there are none
+func UserAgentRegex(patterns []string) string {
+	patterns = cleanBotPatterns(patterns)
+	if len(patterns) == 0 {
+		return ""
+	}
+	sort.Strings(patterns)
+	quoted := make([]string, len(patterns))
+	for i, p := range patterns {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	return "(?i)(" + strings.Join(quoted, "|") + ")"
+}
+
+// cleanBotPatterns trims patterns and drops empty and case-insensitive duplicates.
+// Backticks are dropped as they would terminate the router rule string.
+func cleanBotPatterns(patterns []string) []string {
+	seen := make(map[string]bool, len(patterns))
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.TrimSpace(strings.ReplaceAll(p, "`", ""))
+		key := strings.ToLower(p)
+		if p == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, p)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseBotPatterns(t *testing.T) {
+	text := "# AI crawlers\nGPTBot\n  ClaudeBot  # Anthropic\n\ngptbot\nCCBot\n"
+	if got, want := ParseBotPatterns([]byte(text)), []string{"GPTBot", "ClaudeBot", "CCBot"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBotPatterns(text) = %v, want %v", got, want)
+	}
+	if got, want := ParseBotPatterns([]byte(`["sqlmap", " Nikto ", ""]`)), []string{"sqlmap", "Nikto"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBotPatterns(json) = %v, want %v", got, want)
+	}
+}
+
+func TestUserAgentRegex(t *testing.T) {
+	if got := UserAgentRegex(nil); got != "" {
+		t.Errorf("expected no regex for an empty list, got %q", got)
+	}
+
+	regex := UserAgentRegex([]string{"GPTBot", "Mozilla/5.0 (compatible; MJ12bot", "x`y"})
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		t.Fatalf("generated regex %q does not compile: %v", regex, err)
+	}
+	for ua, want := range map[string]bool{
+		"Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; gptbot/1.1)": true,
+		"Mozilla/5.0 (compatible; MJ12bot/v1.4.8; http://mj12bot.com/)":              true,
+		"xy": true,
+		"Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0": false,
+	} {
+		if got := re.MatchString(ua); got != want {
+			t.Errorf("match %q = %v, want %v", ua, got, want)
+		}
+	}
+}
+
+func TestBotListValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    BotList
+		wantErr bool
+	}{
+		{"patterns only", BotList{Name: "mine", Patterns: []string{"curl"}}, false},
+		{"source only", BotList{Name: "remote", SourceURL: "https://example.com/bots.txt", UpdateInterval: 60}, false},
+		{"missing name", BotList{Patterns: []string{"curl"}}, true},
+		{"empty", BotList{Name: "empty"}, true},
+		{"bad category", BotList{Name: "x", Category: "friends", Patterns: []string{"curl"}}, true},
+		{"bad url", BotList{Name: "x", SourceURL: "ftp://example.com/list"}, true},
+		{"interval without url", BotList{Name: "x", Patterns: []string{"curl"}, UpdateInterval: 5}, true},
+	}
+	for _, tt := range tests {
+		tt.list.Normalize()
+		if err := tt.list.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
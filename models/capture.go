@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Traffic capture states
+const (
+	CaptureActive   = "active"
+	CaptureComplete = "complete"
+	CaptureExpired  = "expired"
+	CaptureStopped  = "stopped"
+)
+
+// CaptureHeader is the request header MM adds to a captured resource's
+// requests so their access-log lines can be told apart
+const CaptureHeader = "X-MM-Capture"
+
+// TrafficCapture is a temporary capture of one resource's access-log lines.
+// It stops once MaxLines lines were collected or at ExpiresAt.
+type TrafficCapture struct {
+	ID          string            `json:"id"`
+	ResourceID  string            `json:"resource_id"`
+	Status      string            `json:"status"`
+	MaxLines    int               `json:"max_lines"`
+	Lines       []json.RawMessage `json:"lines"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CORSPolicy describes a cross-origin resource sharing policy that renders
+// into the CORS options of a Traefik headers middleware
+type CORSPolicy struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	AllowOrigins     []string  `json:"allow_origins"`
+	AllowMethods     []string  `json:"allow_methods"`
+	AllowHeaders     []string  `json:"allow_headers"`
+	ExposeHeaders    []string  `json:"expose_headers"`
+	AllowCredentials bool      `json:"allow_credentials"`
+	MaxAge           int       `json:"max_age"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+var validCORSMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// Normalize trims whitespace, upper-cases methods and drops empty entries
+func (p *CORSPolicy) Normalize() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.AllowOrigins = cleanList(p.AllowOrigins, false)
+	p.AllowMethods = cleanList(p.AllowMethods, true)
+	p.AllowHeaders = cleanList(p.AllowHeaders, false)
+	p.ExposeHeaders = cleanList(p.ExposeHeaders, false)
+}
+
+// Validate checks the policy for combinations browsers reject or that are unsafe
+func (p *CORSPolicy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(p.AllowOrigins) == 0 {
+		return fmt.Errorf("at least one allowed origin is required")
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative")
+	}
+
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				return fmt.Errorf("wildcard origin \"*\" cannot be combined with allow_credentials")
+			}
+			if len(p.AllowOrigins) > 1 {
+				return fmt.Errorf("wildcard origin \"*\" must be the only allowed origin")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid origin %q: expected scheme://host[:port]", origin)
+		}
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("invalid origin %q: origins must not include a path, query or fragment", origin)
+		}
+	}
+
+	for _, method := range p.AllowMethods {
+		if method == "*" {
+			if p.AllowCredentials {
+				return fmt.Errorf("wildcard method \"*\" cannot be combined with allow_credentials")
+			}
+			continue
+		}
+		if !validCORSMethods[method] {
+			return fmt.Errorf("invalid method %q", method)
+		}
+	}
+
+	if p.AllowCredentials {
+		for _, header := range append(append([]string{}, p.AllowHeaders...), p.ExposeHeaders...) {
+			if header == "*" {
+				return fmt.Errorf("wildcard header \"*\" cannot be combined with allow_credentials")
+			}
+		}
+	}
+
+	return nil
+}
+
+// HeadersConfig renders the policy as Traefik headers middleware options
+func (p *CORSPolicy) HeadersConfig() map[string]interface{} {
+	origins := make([]string, 0, len(p.AllowOrigins))
+	for _, origin := range p.AllowOrigins {
+		origins = append(origins, strings.TrimSuffix(origin, "/"))
+	}
+
+	config := map[string]interface{}{
+		"accessControlAllowOriginList": origins,
+	}
+	if len(p.AllowMethods) > 0 {
+		config["accessControlAllowMethods"] = p.AllowMethods
+	}
+	if len(p.AllowHeaders) > 0 {
+		config["accessControlAllowHeaders"] = p.AllowHeaders
+	}
+	if len(p.ExposeHeaders) > 0 {
+		config["accessControlExposeHeaders"] = p.ExposeHeaders
+	}
+	if p.AllowCredentials {
+		config["accessControlAllowCredentials"] = true
+	}
+	if p.MaxAge > 0 {
+		config["accessControlMaxAge"] = p.MaxAge
+	}
+	// Responses vary by Origin whenever a specific origin is echoed back
+	if !(len(origins) == 1 && origins[0] == "*") {
+		config["addVaryHeader"] = true
+	}
+	return config
+}
+
+func cleanList(values []string, upper bool) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if upper {
+			v = strings.ToUpper(v)
+		}
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCORSPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CORSPolicy
+		wantErr string
+	}{
+		{
+			name:   "valid explicit origins with credentials",
+			policy: CORSPolicy{Name: "app", AllowOrigins: []string{"https://app.example.com"}, AllowMethods: []string{"GET"}, AllowCredentials: true},
+		},
+		{
+			name:   "wildcard without credentials",
+			policy: CORSPolicy{Name: "public", AllowOrigins: []string{"*"}},
+		},
+		{
+			name:    "wildcard with credentials",
+			policy:  CORSPolicy{Name: "bad", AllowOrigins: []string{"*"}, AllowCredentials: true},
+			wantErr: "allow_credentials",
+		},
+		{
+			name:    "wildcard header with credentials",
+			policy:  CORSPolicy{Name: "bad", AllowOrigins: []string{"https://a.example.com"}, AllowHeaders: []string{"*"}, AllowCredentials: true},
+			wantErr: "wildcard header",
+		},
+		{
+			name:    "wildcard mixed with origins",
+			policy:  CORSPolicy{Name: "bad", AllowOrigins: []string{"*", "https://a.example.com"}},
+			wantErr: "only allowed origin",
+		},
+		{
+			name:    "origin with path",
+			policy:  CORSPolicy{Name: "bad", AllowOrigins: []string{"https://a.example.com/app"}},
+			wantErr: "path",
+		},
+		{
+			name:    "origin without scheme",
+			policy:  CORSPolicy{Name: "bad", AllowOrigins: []string{"a.example.com"}},
+			wantErr: "scheme://host",
+		},
+		{
+			name:    "unknown method",
+			policy:  CORSPolicy{Name: "bad", AllowOrigins: []string{"https://a.example.com"}, AllowMethods: []string{"FETCH"}},
+			wantErr: "invalid method",
+		},
+		{
+			name:    "no origins",
+			policy:  CORSPolicy{Name: "bad"},
+			wantErr: "origin",
+		},
+		{
+			name:    "negative max age",
+			policy:  CORSPolicy{Name: "bad", AllowOrigins: []string{"*"}, MaxAge: -1},
+			wantErr: "max_age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.Normalize()
+			err := tt.policy.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCORSPolicyHeadersConfig(t *testing.T) {
+	policy := CORSPolicy{
+		Name:             "app",
+		AllowOrigins:     []string{" https://app.example.com/ ", ""},
+		AllowMethods:     []string{"get", "post"},
+		AllowHeaders:     []string{"Authorization"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	policy.Normalize()
+	cfg := policy.HeadersConfig()
+
+	origins := cfg["accessControlAllowOriginList"].([]string)
+	if len(origins) != 1 || origins[0] != "https://app.example.com" {
+		t.Errorf("origins = %v", origins)
+	}
+	methods := cfg["accessControlAllowMethods"].([]string)
+	if len(methods) != 2 || methods[0] != "GET" {
+		t.Errorf("methods = %v", methods)
+	}
+	if cfg["accessControlAllowCredentials"] != true {
+		t.Error("expected accessControlAllowCredentials")
+	}
+	if cfg["accessControlMaxAge"] != 600 {
+		t.Errorf("accessControlMaxAge = %v", cfg["accessControlMaxAge"])
+	}
+	if cfg["addVaryHeader"] != true {
+		t.Error("expected addVaryHeader for explicit origins")
+	}
+
+	wildcard := CORSPolicy{Name: "public", AllowOrigins: []string{"*"}}
+	if _, ok := wildcard.HeadersConfig()["addVaryHeader"]; ok {
+		t.Error("did not expect addVaryHeader for wildcard origin")
+	}
+}
@@ -23,12 +23,58 @@ type DataSourceConfig struct {
         Username string `json:"username"`
         Password string `json:"password"`
     } `json:"basic_auth,omitempty"`
+    // FallbackURLs are tried in order when URL does not answer (Traefik
+    // only); empty means the built-in Docker and localhost guesses
+    FallbackURLs []string `json:"fallback_urls,omitempty"`
+    // DiscoveryHosts are Docker service names resolved on the container
+    // network and tried on port 8080 after the fallbacks
+    DiscoveryHosts []string `json:"discovery_hosts,omitempty"`
+    // DisableAutoPersist keeps URL unchanged when a fallback answers instead
+    DisableAutoPersist bool `json:"disable_auto_persist,omitempty"`
+    // Headers are sent with every request, e.g. CF-Access-Client-Id and
+    // CF-Access-Client-Secret for APIs behind a zero-trust tunnel
+    Headers map[string]string `json:"headers,omitempty"`
+    // CAFile, ClientCertFile and ClientKeyFile are PEM files inside the
+    // container; the CA is trusted in addition to the system roots
+    CAFile         string `json:"ca_file,omitempty"`
+    ClientCertFile string `json:"client_cert_file,omitempty"`
+    ClientKeyFile  string `json:"client_key_file,omitempty"`
+    // ProxyURL overrides the outbound proxy for this data source; "direct"
+    // connects without one
+    ProxyURL string `json:"proxy_url,omitempty"`
 }
 
+// MaskedSecret replaces secrets in API responses
+const MaskedSecret = "••••••••"
+
 // SystemConfig represents the overall system configuration
 type SystemConfig struct {
     ActiveDataSource string                     `json:"active_data_source"`
     DataSources      map[string]DataSourceConfig `json:"data_sources"`
+    Setup            *SetupProgress              `json:"setup,omitempty"`
+    Failover         *DataSourceFailover         `json:"failover,omitempty"`
+    ResourceReview   *ResourceReviewConfig       `json:"resource_review,omitempty"`
+}
+
+// ResourceReviewConfig makes the resource watcher queue creations, disables
+// and service changes for approval instead of applying them
+type ResourceReviewConfig struct {
+    Enabled bool `json:"enabled"`
+    // AutoApproveMinutes applies a change once it has been pending this
+    // long; zero waits for an approve call
+    AutoApproveMinutes int `json:"auto_approve_minutes,omitempty"`
+}
+
+// DataSourceFailover lets resource discovery fall back to a secondary data
+// source while the active one is unreachable
+type DataSourceFailover struct {
+    Enabled bool `json:"enabled"`
+    // Secondary names the data source used during an outage, usually
+    // "traefik" when Pangolin is active
+    Secondary string `json:"secondary"`
+    // AfterMinutes is how long the active source must keep failing before
+    // the watcher switches; zero means 5
+    AfterMinutes int `json:"after_minutes,omitempty"`
 }
 
 // TraefikRouter represents a router configuration from Traefik API
@@ -70,11 +116,22 @@ type ResourceCollection struct {
     Resources []Resource `json:"resources"`
 }
 
-// FormatBasicAuth formats the basic auth field to mask the password
+// FormatBasicAuth formats the basic auth field to mask the password. Custom
+// header values usually carry tokens, so they are masked as well.
 func (dc *DataSourceConfig) FormatBasicAuth() {
     // If the password is not empty, mask it for display
     if dc.BasicAuth.Password != "" {
-        dc.BasicAuth.Password = "••••••••" // Mask the password
+        dc.BasicAuth.Password = MaskedSecret // Mask the password
+    }
+    if len(dc.Headers) > 0 {
+        masked := make(map[string]string, len(dc.Headers))
+        for name, value := range dc.Headers {
+            if value != "" {
+                value = MaskedSecret
+            }
+            masked[name] = value
+        }
+        dc.Headers = masked
     }
 }
 
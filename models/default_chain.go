@@ -0,0 +1,9 @@
+package models
+
+// DefaultChainMiddleware is a middleware of the global default chain applied
+// to every HTTP router. Higher priorities run first.
+type DefaultChainMiddleware struct {
+	MiddlewareID   string `json:"middleware_id"`
+	MiddlewareName string `json:"middleware_name,omitempty"`
+	Priority       int    `json:"priority"`
+}
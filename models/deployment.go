@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Blue/green deployment stages
+const (
+	// DeploymentStaged: the green service exists and optionally receives a
+	// mirrored share of the requests; the router still uses blue
+	DeploymentStaged = "staged"
+	// DeploymentSwitched: the router uses green; blue is kept for rollback
+	// until SoakUntil
+	DeploymentSwitched = "switched"
+	// DeploymentCompleted: the soak period ended and blue was archived
+	DeploymentCompleted = "completed"
+)
+
+// ResourceDeployment moves a resource to a new backend URL (green). Blue is
+// the router's upstream service, or the green URL of the previous completed
+// deployment when BlueURL is set.
+type ResourceDeployment struct {
+	ResourceID string `json:"resource_id"`
+	GreenURL   string `json:"green_url"`
+	BlueURL    string `json:"blue_url,omitempty"`
+	// MirrorPercent of the requests is copied to green while staged; 0 disables mirroring
+	MirrorPercent int `json:"mirror_percent"`
+	// SoakPeriod is how long blue is kept for rollback after the switch
+	SoakPeriod  string     `json:"soak_period"`
+	Stage       string     `json:"stage"`
+	SwitchedAt  *time.Time `json:"switched_at,omitempty"`
+	SoakUntil   *time.Time `json:"soak_until,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Normalize trims the green URL and fills in the soak period default
+func (d *ResourceDeployment) Normalize() {
+	d.GreenURL = strings.TrimSuffix(strings.TrimSpace(d.GreenURL), "/")
+	d.SoakPeriod = strings.TrimSpace(d.SoakPeriod)
+	if d.SoakPeriod == "" {
+		d.SoakPeriod = "1h"
+	}
+}
+
+// Validate checks the green URL, mirror percentage and soak period
+func (d *ResourceDeployment) Validate() error {
+	u, err := url.Parse(d.GreenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid green_url %q: expected http(s)://host[:port]", d.GreenURL)
+	}
+	if d.MirrorPercent < 0 || d.MirrorPercent > 100 {
+		return fmt.Errorf("mirror_percent must be between 0 and 100")
+	}
+	if soak, err := time.ParseDuration(d.SoakPeriod); err != nil || soak < 0 {
+		return fmt.Errorf("invalid soak_period %q: expected a duration such as 30m", d.SoakPeriod)
+	}
+	return nil
+}
+
+// DeploymentServiceNames returns the names of the green, blue and mirroring
+// services generated for a resource. The blue service is only generated when
+// blue is the URL of a previous deployment.
+func DeploymentServiceNames(resourceID string) (green, blue, mirroring string) {
+	return resourceID + "-green", resourceID + "-blue", resourceID + "-green-mirroring"
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Diagnostic check statuses. Skip means the check does not apply to this
+// deployment, e.g. the Traefik config dir when file config is disabled.
+const (
+	DiagnosticPass = "pass"
+	DiagnosticWarn = "warn"
+	DiagnosticFail = "fail"
+	DiagnosticSkip = "skip"
+)
+
+// DiagnosticCheck is the outcome of one environment check
+type DiagnosticCheck struct {
+	Name        string  `json:"name"`
+	Status      string  `json:"status"`
+	Message     string  `json:"message"`
+	Remediation string  `json:"remediation,omitempty"`
+	DurationMs  float64 `json:"duration_ms"`
+}
+
+// DiagnosticsReport lists every environment check. OK is false when any
+// check failed; warnings do not affect it.
+type DiagnosticsReport struct {
+	OK        bool              `json:"ok"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    []DiagnosticCheck `json:"checks"`
+}
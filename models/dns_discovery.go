@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DNS record types a dnsDiscovery service can resolve
+const (
+	DNSRecordSRV = "SRV"
+	DNSRecordA   = "A"
+)
+
+// DNSDiscoveryConfig is the config of a dnsDiscovery service. MM resolves
+// Name periodically, honouring the record TTLs, and serves the result as a
+// loadBalancer service. SRV records carry the port and weight of each server;
+// A records use Port for every address.
+type DNSDiscoveryConfig struct {
+	// Name is the fully qualified record, e.g. _http._tcp.api.service.consul
+	Name string `json:"name"`
+	// RecordType is SRV (default) or A
+	RecordType string `json:"recordType,omitempty"`
+	// Scheme of the server URLs, http by default
+	Scheme         string             `json:"scheme,omitempty"`
+	Port           int                `json:"port,omitempty"`
+	PassHostHeader *bool              `json:"passHostHeader,omitempty"`
+	HealthCheck    *HealthCheckConfig `json:"healthCheck,omitempty"`
+	Sticky         *StickyConfig      `json:"sticky,omitempty"`
+}
+
+// Normalize trims the name and fills in the defaults
+func (c *DNSDiscoveryConfig) Normalize() {
+	c.Name = strings.TrimSuffix(strings.TrimSpace(c.Name), ".")
+	c.RecordType = strings.ToUpper(strings.TrimSpace(c.RecordType))
+	if c.RecordType == "" {
+		c.RecordType = DNSRecordSRV
+	}
+	c.Scheme = strings.ToLower(strings.TrimSpace(c.Scheme))
+	if c.Scheme == "" {
+		c.Scheme = "http"
+	}
+}
+
+// Validate checks the record, scheme and port
+func (c *DNSDiscoveryConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch c.RecordType {
+	case DNSRecordSRV:
+	case DNSRecordA:
+		if c.Port < 1 || c.Port > 65535 {
+			return fmt.Errorf("port must be between 1 and 65535 for A records")
+		}
+	default:
+		return fmt.Errorf("invalid recordType %q: expected SRV or A", c.RecordType)
+	}
+	switch c.Scheme {
+	case "http", "https", "h2c":
+	default:
+		return fmt.Errorf("invalid scheme %q: expected http, https or h2c", c.Scheme)
+	}
+	return nil
+}
+
+// ParseDNSDiscoveryConfig reads, normalizes and validates the stored config
+// of a dnsDiscovery service
+func ParseDNSDiscoveryConfig(config map[string]interface{}) (DNSDiscoveryConfig, error) {
+	var parsed DNSDiscoveryConfig
+	data, err := json.Marshal(config)
+	if err != nil {
+		return parsed, err
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return parsed, fmt.Errorf("invalid dnsDiscovery config: %w", err)
+	}
+	parsed.Normalize()
+	if err := parsed.Validate(); err != nil {
+		return parsed, err
+	}
+	return parsed, nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestParseDNSDiscoveryConfig(t *testing.T) {
+	cfg, err := ParseDNSDiscoveryConfig(map[string]interface{}{"name": " _http._tcp.api.internal. "})
+	if err != nil {
+		t.Fatalf("ParseDNSDiscoveryConfig: %v", err)
+	}
+	if cfg.Name != "_http._tcp.api.internal" || cfg.RecordType != DNSRecordSRV || cfg.Scheme != "http" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{},
+		{"name": "api.internal", "recordType": "A"},
+		{"name": "api.internal", "recordType": "MX"},
+		{"name": "api.internal", "scheme": "ftp"},
+	} {
+		if _, err := ParseDNSDiscoveryConfig(invalid); err == nil {
+			t.Errorf("expected %v to be rejected", invalid)
+		}
+	}
+}
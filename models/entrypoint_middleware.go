@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// EntrypointMiddleware attaches a middleware to every HTTP router on a
+// Traefik entrypoint. Higher priorities run first, as for resources.
+type EntrypointMiddleware struct {
+	EntryPoint     string    `json:"entrypoint"`
+	MiddlewareID   string    `json:"middleware_id"`
+	MiddlewareName string    `json:"middleware_name"`
+	Priority       int       `json:"priority"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Environment tag targets
+const (
+	EnvironmentTargetResource   = "resource"
+	EnvironmentTargetMiddleware = "middleware"
+)
+
+var environmentNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Environment is a profile such as dev, staging or prod that a Traefik
+// instance selects on the config endpoint. Resources and middlewares tagged
+// with environments only apply in those; untagged ones apply in all.
+type Environment struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// IsDefault serves the environment at the plain config endpoint
+	IsDefault bool `json:"is_default"`
+	// Resources and Middlewares count the tagged ones
+	Resources   int       `json:"resources"`
+	Middlewares int       `json:"middlewares"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Normalize lowercases the name and trims the description
+func (e *Environment) Normalize() {
+	e.Name = NormalizeEnvironmentName(e.Name)
+	e.Description = strings.TrimSpace(e.Description)
+}
+
+// Validate checks the name
+func (e *Environment) Validate() error {
+	return ValidateEnvironmentName(e.Name)
+}
+
+// NormalizeEnvironmentName trims and lowercases an environment name
+func NormalizeEnvironmentName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateEnvironmentName checks a name is usable in the config endpoint path
+func ValidateEnvironmentName(name string) error {
+	if !environmentNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment name %q: use up to 32 lowercase letters, digits, - or _", name)
+	}
+	return nil
+}
+
+// EnvironmentTags are the environments a resource or middleware is limited
+// to; empty means every environment
+type EnvironmentTags struct {
+	TargetType   string   `json:"target_type"`
+	TargetID     string   `json:"target_id"`
+	Environments []string `json:"environments"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ResourceFailover wraps a resource's service in a Traefik failover service
+// that sends requests to a fallback while the primary's health check fails.
+// The fallback is either a backend URL, for which MM generates a service, or
+// an existing service such as a static maintenance page.
+type ResourceFailover struct {
+	ResourceID      string `json:"resource_id"`
+	FallbackURL     string `json:"fallback_url,omitempty"`
+	FallbackService string `json:"fallback_service,omitempty"`
+	// Health check MM adds to its copy of the primary service; Traefik only
+	// fails over on failing health checks
+	HealthCheckPath     string    `json:"health_check_path"`
+	HealthCheckInterval string    `json:"health_check_interval"`
+	HealthCheckTimeout  string    `json:"health_check_timeout"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Normalize trims the fallback and fills in the health check defaults
+func (f *ResourceFailover) Normalize() {
+	f.FallbackURL = strings.TrimSuffix(strings.TrimSpace(f.FallbackURL), "/")
+	f.FallbackService = strings.TrimSpace(f.FallbackService)
+	f.HealthCheckPath = strings.TrimSpace(f.HealthCheckPath)
+	if f.HealthCheckPath == "" {
+		f.HealthCheckPath = "/"
+	}
+	if strings.TrimSpace(f.HealthCheckInterval) == "" {
+		f.HealthCheckInterval = "10s"
+	}
+	if strings.TrimSpace(f.HealthCheckTimeout) == "" {
+		f.HealthCheckTimeout = "3s"
+	}
+}
+
+// Validate checks that exactly one fallback is set and the health check
+// durations parse
+func (f *ResourceFailover) Validate() error {
+	if (f.FallbackURL == "") == (f.FallbackService == "") {
+		return fmt.Errorf("set either fallback_url or fallback_service")
+	}
+	if f.FallbackURL != "" {
+		u, err := url.Parse(f.FallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid fallback_url %q: expected http(s)://host[:port]", f.FallbackURL)
+		}
+	}
+	if !strings.HasPrefix(f.HealthCheckPath, "/") {
+		return fmt.Errorf("health_check_path must start with /")
+	}
+	for name, value := range map[string]string{"health_check_interval": f.HealthCheckInterval, "health_check_timeout": f.HealthCheckTimeout} {
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q: expected a duration such as 10s", name, value)
+		}
+	}
+	return nil
+}
+
+// FailoverServiceNames returns the names of the failover service, the health
+// checked copy of the primary service and the fallback service generated for
+// a resource. The fallback name is only used with a fallback URL.
+func FailoverServiceNames(resourceID string) (failover, primary, fallback string) {
+	return resourceID + "-failover", resourceID + "-failover-primary", resourceID + "-failover-fallback"
+}
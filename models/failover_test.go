@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestResourceFailover_NormalizeAndValidate(t *testing.T) {
+	failover := ResourceFailover{FallbackURL: " http://standby:8080/ "}
+	failover.Normalize()
+	if err := failover.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if failover.FallbackURL != "http://standby:8080" || failover.HealthCheckPath != "/" || failover.HealthCheckInterval != "10s" {
+		t.Errorf("unexpected failover %+v", failover)
+	}
+
+	for _, invalid := range []ResourceFailover{
+		{},
+		{FallbackURL: "http://standby", FallbackService: "maintenance@file"},
+		{FallbackURL: "standby:8080"},
+		{FallbackService: "maintenance@file", HealthCheckPath: "health"},
+		{FallbackService: "maintenance@file", HealthCheckInterval: "often"},
+	} {
+		invalid.Normalize()
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+)
+
+// Header policy types
+const (
+	HeaderPolicyTypeSecurity = "security"
+	HeaderPolicyTypeCORS     = "cors"
+	HeaderPolicyTypeCaching  = "caching"
+	HeaderPolicyTypeCustom   = "custom"
+)
+
+// Header policy assignment targets
+const (
+	HeaderPolicyTargetResource = "resource"
+	HeaderPolicyTargetGroup    = "group"
+)
+
+// HeaderPolicy is a reusable set of Traefik headers middleware options that
+// can be referenced by resources and resource groups.
+type HeaderPolicy struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	Config      map[string]interface{} `json:"config"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// ResourceGroup is a named collection of resources that share header policies
+type ResourceGroup struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ResourceIDs []string  `json:"resource_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// HeaderPolicyAssignment links a header policy to a resource or group
+type HeaderPolicyAssignment struct {
+	PolicyID   string `json:"policy_id"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Priority   int    `json:"priority"`
+}
+
+// IsValidHeaderPolicyType reports whether typ is a supported header policy type
+func IsValidHeaderPolicyType(typ string) bool {
+	switch typ {
+	case HeaderPolicyTypeSecurity, HeaderPolicyTypeCORS, HeaderPolicyTypeCaching, HeaderPolicyTypeCustom:
+		return true
+	}
+	return false
+}
+
+// MergeHeaderConfigs merges headers middleware configs in precedence order.
+// Later layers win. Header maps (customRequestHeaders, customResponseHeaders)
+// are merged key by key so a later layer only overrides the headers it sets;
+// every other option is replaced wholesale.
+func MergeHeaderConfigs(layers ...map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, layer := range layers {
+		for key, value := range layer {
+			incoming, isMap := toStringInterfaceMap(value)
+			if !isMap {
+				merged[key] = value
+				continue
+			}
+			existing, ok := toStringInterfaceMap(merged[key])
+			if !ok {
+				existing = make(map[string]interface{}, len(incoming))
+			}
+			for k, v := range incoming {
+				existing[k] = v
+			}
+			merged[key] = existing
+		}
+	}
+	return merged
+}
+
+// toStringInterfaceMap converts header maps decoded from JSON or built in Go
+// into a fresh map[string]interface{}.
+func toStringInterfaceMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[k] = val
+		}
+		return out, true
+	case map[string]string:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[k] = val
+		}
+		return out, true
+	}
+	return nil, false
+}
@@ -0,0 +1,61 @@
+package models
+
+import "testing"
+
+func TestIsValidHeaderPolicyType(t *testing.T) {
+	for _, typ := range []string{HeaderPolicyTypeSecurity, HeaderPolicyTypeCORS, HeaderPolicyTypeCaching, HeaderPolicyTypeCustom} {
+		if !IsValidHeaderPolicyType(typ) {
+			t.Errorf("IsValidHeaderPolicyType(%q) = false, want true", typ)
+		}
+	}
+	if IsValidHeaderPolicyType("bogus") {
+		t.Error("IsValidHeaderPolicyType(\"bogus\") = true, want false")
+	}
+}
+
+func TestMergeHeaderConfigs(t *testing.T) {
+	base := map[string]interface{}{
+		"customResponseHeaders": map[string]string{
+			"X-Frame-Options": "SAMEORIGIN",
+			"Cache-Control":   "no-store",
+		},
+		"accessControlAllowMethods": []interface{}{"GET"},
+	}
+	override := map[string]interface{}{
+		"customResponseHeaders": map[string]interface{}{
+			"X-Frame-Options": "DENY",
+		},
+		"accessControlAllowMethods": []interface{}{"GET", "POST"},
+		"stsSeconds":                float64(300),
+	}
+
+	merged := MergeHeaderConfigs(base, nil, override)
+
+	headers, ok := merged["customResponseHeaders"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("customResponseHeaders has type %T", merged["customResponseHeaders"])
+	}
+	if headers["X-Frame-Options"] != "DENY" {
+		t.Errorf("X-Frame-Options = %v, want DENY", headers["X-Frame-Options"])
+	}
+	if headers["Cache-Control"] != "no-store" {
+		t.Errorf("Cache-Control = %v, want inherited no-store", headers["Cache-Control"])
+	}
+	if methods, ok := merged["accessControlAllowMethods"].([]interface{}); !ok || len(methods) != 2 {
+		t.Errorf("accessControlAllowMethods = %v, want override list", merged["accessControlAllowMethods"])
+	}
+	if merged["stsSeconds"] != float64(300) {
+		t.Errorf("stsSeconds = %v, want 300", merged["stsSeconds"])
+	}
+
+	// Inputs must not be mutated
+	if base["customResponseHeaders"].(map[string]string)["X-Frame-Options"] != "SAMEORIGIN" {
+		t.Error("MergeHeaderConfigs mutated its input")
+	}
+}
+
+func TestMergeHeaderConfigsEmpty(t *testing.T) {
+	if merged := MergeHeaderConfigs(); len(merged) != 0 {
+		t.Errorf("MergeHeaderConfigs() = %v, want empty", merged)
+	}
+}
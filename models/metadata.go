@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Length limits for ownership metadata
+const (
+	MaxNotesLength   = 4000
+	MaxOwnerLength   = 200
+	MaxContactLength = 200
+)
+
+// OwnershipMetadata records why a resource or middleware exists and who to ask
+// before changing or removing it
+type OwnershipMetadata struct {
+	Notes   string `json:"notes"`
+	Owner   string `json:"owner"`
+	Contact string `json:"contact"`
+}
+
+// Normalize trims surrounding whitespace
+func (m *OwnershipMetadata) Normalize() {
+	m.Notes = strings.TrimSpace(m.Notes)
+	m.Owner = strings.TrimSpace(m.Owner)
+	m.Contact = strings.TrimSpace(m.Contact)
+}
+
+// Validate checks the field lengths
+func (m *OwnershipMetadata) Validate() error {
+	if utf8.RuneCountInString(m.Notes) > MaxNotesLength {
+		return fmt.Errorf("notes must be at most %d characters", MaxNotesLength)
+	}
+	if utf8.RuneCountInString(m.Owner) > MaxOwnerLength {
+		return fmt.Errorf("owner must be at most %d characters", MaxOwnerLength)
+	}
+	if utf8.RuneCountInString(m.Contact) > MaxContactLength {
+		return fmt.Errorf("contact must be at most %d characters", MaxContactLength)
+	}
+	if strings.ContainsAny(m.Owner+m.Contact, "\r\n") {
+		return fmt.Errorf("owner and contact must be single lines")
+	}
+	return nil
+}
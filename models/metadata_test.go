@@ -0,0 +1,28 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOwnershipMetadataValidate(t *testing.T) {
+	meta := OwnershipMetadata{Notes: "  bypass for the billing webhook  ", Owner: " payments ", Contact: "#payments-oncall"}
+	meta.Normalize()
+	if meta.Notes != "bypass for the billing webhook" || meta.Owner != "payments" {
+		t.Fatalf("Normalize did not trim: %+v", meta)
+	}
+	if err := meta.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []OwnershipMetadata{
+		{Notes: strings.Repeat("n", MaxNotesLength+1)},
+		{Owner: strings.Repeat("o", MaxOwnerLength+1)},
+		{Contact: "line one\nline two"},
+	}
+	for _, tt := range tests {
+		if err := tt.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", tt)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Middleware ordering policies decide where MM's middlewares go in a router
+// chain relative to the ones the upstream (e.g. Pangolin's badger) already set
+const (
+	// MiddlewareOrderPrepend runs MM's middlewares before the upstream ones
+	MiddlewareOrderPrepend = "prepend"
+	// MiddlewareOrderAppend runs MM's middlewares after the upstream ones
+	MiddlewareOrderAppend = "append"
+	// MiddlewareOrderInterleave inserts MM's middlewares at explicit positions
+	MiddlewareOrderInterleave = "interleave"
+)
+
+// MiddlewareOrder is a resource's ordering policy. With interleave,
+// Positions maps the name of an MM middleware in the router chain to the
+// index of the upstream middleware it runs before: 0 is first, an index past
+// the end runs it last. MM middlewares without a position run first.
+type MiddlewareOrder struct {
+	Policy    string         `json:"policy"`
+	Positions map[string]int `json:"positions,omitempty"`
+}
+
+// Normalize lower-cases the policy, defaulting to prepend, and drops
+// positions unless the policy uses them
+func (o *MiddlewareOrder) Normalize() {
+	o.Policy = strings.ToLower(strings.TrimSpace(o.Policy))
+	if o.Policy == "" {
+		o.Policy = MiddlewareOrderPrepend
+	}
+	if o.Policy != MiddlewareOrderInterleave {
+		o.Positions = nil
+		return
+	}
+	positions := make(map[string]int, len(o.Positions))
+	for name, index := range o.Positions {
+		positions[strings.TrimSpace(name)] = index
+	}
+	o.Positions = positions
+}
+
+// Validate checks the policy and positions
+func (o *MiddlewareOrder) Validate() error {
+	switch o.Policy {
+	case MiddlewareOrderPrepend, MiddlewareOrderAppend, MiddlewareOrderInterleave:
+	default:
+		return fmt.Errorf("invalid policy %q: expected prepend, append or interleave", o.Policy)
+	}
+	for name, index := range o.Positions {
+		if name == "" {
+			return fmt.Errorf("positions must name a middleware")
+		}
+		if index < 0 {
+			return fmt.Errorf("position of %s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// ParseMiddlewareOrder builds the policy stored in a resource's
+// middleware_order and middleware_positions columns
+func ParseMiddlewareOrder(policy, positions string) (MiddlewareOrder, error) {
+	order := MiddlewareOrder{Policy: policy}
+	if strings.TrimSpace(positions) != "" {
+		if err := json.Unmarshal([]byte(positions), &order.Positions); err != nil {
+			return MiddlewareOrder{}, fmt.Errorf("invalid middleware positions: %w", err)
+		}
+	}
+	order.Normalize()
+	if err := order.Validate(); err != nil {
+		return MiddlewareOrder{}, err
+	}
+	return order, nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestMiddlewareOrder_NormalizeAndValidate(t *testing.T) {
+	order := MiddlewareOrder{Policy: " Append ", Positions: map[string]int{"auth": 1}}
+	order.Normalize()
+	if order.Policy != MiddlewareOrderAppend || order.Positions != nil {
+		t.Errorf("unexpected order %+v", order)
+	}
+
+	order = MiddlewareOrder{}
+	order.Normalize()
+	if order.Policy != MiddlewareOrderPrepend {
+		t.Errorf("empty policy = %q, want prepend", order.Policy)
+	}
+
+	for _, invalid := range []MiddlewareOrder{
+		{Policy: "first"},
+		{Policy: MiddlewareOrderInterleave, Positions: map[string]int{"auth": -1}},
+		{Policy: MiddlewareOrderInterleave, Positions: map[string]int{" ": 0}},
+	} {
+		invalid.Normalize()
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestParseMiddlewareOrder(t *testing.T) {
+	order, err := ParseMiddlewareOrder("interleave", `{"auth": 2}`)
+	if err != nil || order.Positions["auth"] != 2 {
+		t.Fatalf("ParseMiddlewareOrder() = %+v, %v", order, err)
+	}
+	if _, err := ParseMiddlewareOrder("interleave", `[1]`); err == nil {
+		t.Error("expected malformed positions to be rejected")
+	}
+}
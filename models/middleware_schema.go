@@ -0,0 +1,599 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Middleware config issue codes; unknown_field, invalid_type and required
+// match the codes of request validation errors
+const (
+	IssueUnknownField = "unknown_field"
+	IssueInvalidType  = "invalid_type"
+	IssueInvalidValue = "invalid_value"
+	IssueRequired     = "required"
+	IssueDeprecated   = "deprecated"
+)
+
+// MiddlewareConfigIssue is one problem found in a middleware config
+type MiddlewareConfigIssue struct {
+	// Field is the dotted path of the field, e.g. sourceCriterion.ipStrategy.depth
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// MiddlewareValidation is the result of checking a middleware config against
+// the Traefik schema of its type. Errors are fields Traefik would reject or
+// ignore; warnings are missing or deprecated fields that still load.
+type MiddlewareValidation struct {
+	Valid    bool                    `json:"valid"`
+	Errors   []MiddlewareConfigIssue `json:"errors"`
+	Warnings []MiddlewareConfigIssue `json:"warnings"`
+}
+
+// fieldKind is the kind of value a config field takes
+type fieldKind int
+
+const (
+	kindAny fieldKind = iota
+	kindString
+	kindBool
+	kindInt
+	kindDuration
+	kindRegex
+	kindStringList
+	kindRegexList
+	kindIPList
+	kindStringMap
+	kindObject
+)
+
+// fieldSpec describes one config field; fields is the schema of an object
+type fieldSpec struct {
+	kind       fieldKind
+	fields     configSchema
+	deprecated string
+}
+
+type configSchema map[string]fieldSpec
+
+var (
+	str         = fieldSpec{kind: kindString}
+	boolean     = fieldSpec{kind: kindBool}
+	integer     = fieldSpec{kind: kindInt}
+	duration    = fieldSpec{kind: kindDuration}
+	pattern     = fieldSpec{kind: kindRegex}
+	strList     = fieldSpec{kind: kindStringList}
+	patterns    = fieldSpec{kind: kindRegexList}
+	ipList      = fieldSpec{kind: kindIPList}
+	strMap      = fieldSpec{kind: kindStringMap}
+	removedInV3 = "removed in Traefik v3; convert it with POST /api/middlewares/convert"
+)
+
+func object(fields configSchema) fieldSpec {
+	return fieldSpec{kind: kindObject, fields: fields}
+}
+
+func deprecated(spec fieldSpec, reason string) fieldSpec {
+	spec.deprecated = reason
+	return spec
+}
+
+var ipStrategySchema = object(configSchema{
+	"depth":       integer,
+	"excludedIPs": ipList,
+	"ipv6Subnet":  integer,
+})
+
+var sourceCriterionSchema = object(configSchema{
+	"ipStrategy":        ipStrategySchema,
+	"requestHeaderName": str,
+	"requestHost":       boolean,
+})
+
+var clientTLSSchema = object(configSchema{
+	"ca":                 str,
+	"cert":               str,
+	"key":                str,
+	"insecureSkipVerify": boolean,
+	"caOptional":         boolean,
+})
+
+var authSchema = configSchema{
+	"users":        strList,
+	"usersFile":    str,
+	"realm":        str,
+	"removeHeader": boolean,
+	"headerField":  str,
+}
+
+var certInfoSchema = configSchema{
+	"country":         boolean,
+	"province":        boolean,
+	"locality":        boolean,
+	"organization":    boolean,
+	"commonName":      boolean,
+	"serialNumber":    boolean,
+	"domainComponent": boolean,
+}
+
+// middlewareSchemas lists the fields Traefik accepts for each middleware
+// type. Plugin configs are checked separately since their fields belong to
+// the plugin.
+var middlewareSchemas = map[string]configSchema{
+	"addPrefix":  {"prefix": str},
+	"basicAuth":  authSchema,
+	"digestAuth": authSchema,
+	"buffering": {
+		"maxRequestBodyBytes":  integer,
+		"memRequestBodyBytes":  integer,
+		"maxResponseBodyBytes": integer,
+		"memResponseBodyBytes": integer,
+		"retryExpression":      str,
+	},
+	"chain": {"middlewares": strList},
+	"circuitBreaker": {
+		"expression":       str,
+		"checkPeriod":      duration,
+		"fallbackDuration": duration,
+		"recoveryDuration": duration,
+		"responseCode":     integer,
+	},
+	"compress": {
+		"excludedContentTypes": strList,
+		"includedContentTypes": strList,
+		"minResponseBodyBytes": integer,
+		"defaultEncoding":      str,
+		"encodings":            strList,
+	},
+	"contentType": {"autoDetect": boolean},
+	"errors": {
+		"status":         strList,
+		"statusRewrites": strMap,
+		"service":        str,
+		"query":          str,
+	},
+	"forwardAuth": {
+		"address":                  str,
+		"tls":                      clientTLSSchema,
+		"trustForwardHeader":       boolean,
+		"authResponseHeaders":      strList,
+		"authResponseHeadersRegex": pattern,
+		"authRequestHeaders":       strList,
+		"addAuthCookiesToResponse": strList,
+		"headerField":              str,
+		"forwardBody":              boolean,
+		"maxBodySize":              integer,
+		"maxResponseBodySize":      integer,
+		"preserveLocationHeader":   boolean,
+		"preserveRequestMethod":    boolean,
+	},
+	"grpcWeb": {"allowOrigins": strList},
+	"headers": {
+		"customRequestHeaders":              strMap,
+		"customResponseHeaders":             strMap,
+		"accessControlAllowCredentials":     boolean,
+		"accessControlAllowHeaders":         strList,
+		"accessControlAllowMethods":         strList,
+		"accessControlAllowOriginList":      strList,
+		"accessControlAllowOriginListRegex": patterns,
+		"accessControlExposeHeaders":        strList,
+		"accessControlMaxAge":               integer,
+		"addVaryHeader":                     boolean,
+		"allowedHosts":                      strList,
+		"hostsProxyHeaders":                 strList,
+		"sslProxyHeaders":                   strMap,
+		"stsSeconds":                        integer,
+		"stsIncludeSubdomains":              boolean,
+		"stsPreload":                        boolean,
+		"forceSTSHeader":                    boolean,
+		"frameDeny":                         boolean,
+		"customFrameOptionsValue":           str,
+		"contentTypeNosniff":                boolean,
+		"browserXssFilter":                  boolean,
+		"customBrowserXSSValue":             str,
+		"contentSecurityPolicy":             str,
+		"contentSecurityPolicyReportOnly":   str,
+		"publicKey":                         str,
+		"referrerPolicy":                    str,
+		"permissionsPolicy":                 str,
+		"isDevelopment":                     boolean,
+		"sslRedirect":                       deprecated(boolean, removedInV3),
+		"sslTemporaryRedirect":              deprecated(boolean, removedInV3),
+		"sslHost":                           deprecated(str, removedInV3),
+		"sslForceHost":                      deprecated(boolean, removedInV3),
+		"featurePolicy":                     deprecated(str, removedInV3),
+	},
+	"inFlightReq": {
+		"amount":          integer,
+		"sourceCriterion": sourceCriterionSchema,
+	},
+	"ipAllowList": {
+		"sourceRange":      ipList,
+		"ipStrategy":       ipStrategySchema,
+		"rejectStatusCode": integer,
+	},
+	"ipWhiteList": {
+		"sourceRange": ipList,
+		"ipStrategy":  ipStrategySchema,
+	},
+	"passTLSClientCert": {
+		"pem": boolean,
+		"info": object(configSchema{
+			"notAfter":     boolean,
+			"notBefore":    boolean,
+			"sans":         boolean,
+			"serialNumber": boolean,
+			"subject":      object(certInfoSchema),
+			"issuer":       object(certInfoSchema),
+		}),
+	},
+	"rateLimit": {
+		"average":         integer,
+		"period":          duration,
+		"burst":           integer,
+		"sourceCriterion": sourceCriterionSchema,
+		"redis": object(configSchema{
+			"endpoints":      strList,
+			"tls":            clientTLSSchema,
+			"username":       str,
+			"password":       str,
+			"db":             integer,
+			"poolSize":       integer,
+			"minIdleConns":   integer,
+			"maxActiveConns": integer,
+			"readTimeout":    duration,
+			"writeTimeout":   duration,
+			"dialTimeout":    duration,
+		}),
+	},
+	"redirectRegex": {
+		"regex":       pattern,
+		"replacement": str,
+		"permanent":   boolean,
+	},
+	"redirectScheme": {
+		"scheme":    str,
+		"port":      str,
+		"permanent": boolean,
+	},
+	"replacePath": {"path": str},
+	"replacePathRegex": {
+		"regex":       pattern,
+		"replacement": str,
+	},
+	"retry": {
+		"attempts":        integer,
+		"initialInterval": duration,
+	},
+	"stripPrefix": {
+		"prefixes":   strList,
+		"forceSlash": boolean,
+	},
+	"stripPrefixRegex": {"regex": patterns},
+}
+
+// middlewareRequired lists the fields each type needs to do anything;
+// alternatives are separated by |
+var middlewareRequired = map[string][]string{
+	"addPrefix":        {"prefix"},
+	"basicAuth":        {"users|usersFile"},
+	"digestAuth":       {"users|usersFile"},
+	"chain":            {"middlewares"},
+	"circuitBreaker":   {"expression"},
+	"errors":           {"status", "service"},
+	"forwardAuth":      {"address"},
+	"inFlightReq":      {"amount"},
+	"ipAllowList":      {"sourceRange"},
+	"ipWhiteList":      {"sourceRange"},
+	"redirectRegex":    {"regex", "replacement"},
+	"redirectScheme":   {"scheme"},
+	"replacePath":      {"path"},
+	"replacePathRegex": {"regex", "replacement"},
+	"retry":            {"attempts"},
+	"stripPrefix":      {"prefixes"},
+	"stripPrefixRegex": {"regex"},
+}
+
+// HasMiddlewareSchema reports whether configs of a middleware type can be validated
+func HasMiddlewareSchema(typ string) bool {
+	_, ok := middlewareSchemas[typ]
+	return ok || typ == "plugin"
+}
+
+// ValidateMiddlewareConfig checks a config against the Traefik schema of its
+// middleware type: unknown fields (with the closest known field as a
+// suggestion), values of the wrong type and invalid regexes or IP ranges are
+// errors; missing required and deprecated fields are warnings
+func ValidateMiddlewareConfig(typ string, config map[string]interface{}) MiddlewareValidation {
+	v := &schemaValidator{}
+	switch schema, ok := middlewareSchemas[typ]; {
+	case typ == "plugin":
+		v.plugin(config)
+	case !ok:
+		v.errorf("type", IssueInvalidValue, "Unknown middleware type %q", typ)
+	default:
+		v.object("", schema, config)
+		v.required(middlewareRequired[typ], config)
+	}
+	return v.result()
+}
+
+type schemaValidator struct {
+	errors   []MiddlewareConfigIssue
+	warnings []MiddlewareConfigIssue
+}
+
+func (v *schemaValidator) errorf(field, code, format string, args ...interface{}) {
+	v.errors = append(v.errors, MiddlewareConfigIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) warnf(field, code, format string, args ...interface{}) {
+	v.warnings = append(v.warnings, MiddlewareConfigIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) result() MiddlewareValidation {
+	sortIssues := func(issues []MiddlewareConfigIssue) []MiddlewareConfigIssue {
+		if issues == nil {
+			return []MiddlewareConfigIssue{}
+		}
+		sort.SliceStable(issues, func(i, j int) bool { return issues[i].Field < issues[j].Field })
+		return issues
+	}
+	return MiddlewareValidation{
+		Valid:    len(v.errors) == 0,
+		Errors:   sortIssues(v.errors),
+		Warnings: sortIssues(v.warnings),
+	}
+}
+
+// plugin checks a plugin config holds one plugin with an object config; the
+// plugin's own fields are not known here
+func (v *schemaValidator) plugin(config map[string]interface{}) {
+	switch len(config) {
+	case 0:
+		v.warnf("", IssueRequired, "No plugin configured; expected {\"<plugin name>\": {...}}")
+		return
+	case 1:
+	default:
+		v.errorf("", IssueInvalidValue, "A plugin middleware configures exactly one plugin, got %d", len(config))
+	}
+	for name, value := range config {
+		if _, ok := value.(map[string]interface{}); !ok && value != nil {
+			v.errorf(name, IssueInvalidType, "Must be an object with the config of plugin %s", name)
+		}
+	}
+}
+
+func (v *schemaValidator) object(prefix string, schema configSchema, config map[string]interface{}) {
+	for key, value := range config {
+		path := joinPath(prefix, key)
+		spec, ok := schema[key]
+		if !ok {
+			v.unknown(path, key, schema)
+			continue
+		}
+		if spec.deprecated != "" {
+			v.warnf(path, IssueDeprecated, "Deprecated: %s", spec.deprecated)
+		}
+		if value != nil {
+			v.value(path, spec, value)
+		}
+	}
+}
+
+// unknown reports a field the schema does not have. A field that only
+// differs in case still loads, so it is a warning.
+func (v *schemaValidator) unknown(path, key string, schema configSchema) {
+	for known := range schema {
+		if strings.EqualFold(known, key) {
+			v.warnf(path, IssueUnknownField, "Unknown field; Traefik spells it %s", known)
+			return
+		}
+	}
+	if suggestion := closestField(key, schema); suggestion != "" {
+		v.errorf(path, IssueUnknownField, "Unknown field; did you mean %s?", suggestion)
+		return
+	}
+	v.errorf(path, IssueUnknownField, "Unknown field")
+}
+
+func (v *schemaValidator) required(fields []string, config map[string]interface{}) {
+	for _, field := range fields {
+		alternatives := strings.Split(field, "|")
+		found := false
+		for _, name := range alternatives {
+			if value, ok := config[name]; ok && value != nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.warnf(alternatives[0], IssueRequired, "%s is required for this middleware to have an effect",
+				strings.Join(alternatives, " or "))
+		}
+	}
+}
+
+func (v *schemaValidator) value(path string, spec fieldSpec, value interface{}) {
+	switch spec.kind {
+	case kindString:
+		if !isScalar(value) {
+			v.errorf(path, IssueInvalidType, "Must be a string")
+		}
+	case kindBool:
+		if !isBool(value) {
+			v.errorf(path, IssueInvalidType, "Must be true or false")
+		}
+	case kindInt:
+		if _, ok := toInt(value); !ok {
+			v.errorf(path, IssueInvalidType, "Must be a whole number")
+		}
+	case kindDuration:
+		if !isDuration(value) {
+			v.errorf(path, IssueInvalidType, "Must be a duration such as 10s or a number of seconds")
+		}
+	case kindRegex:
+		v.regex(path, value)
+	case kindStringList, kindRegexList, kindIPList:
+		items, ok := value.([]interface{})
+		if !ok {
+			v.errorf(path, IssueInvalidType, "Must be a list")
+			return
+		}
+		for i, item := range items {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case !isScalar(item):
+				v.errorf(itemPath, IssueInvalidType, "Must be a string")
+			case spec.kind == kindRegexList:
+				v.regex(itemPath, item)
+			case spec.kind == kindIPList && !isIPRange(fmt.Sprint(item)):
+				v.errorf(itemPath, IssueInvalidValue, "Must be an IP address or CIDR range")
+			}
+		}
+	case kindStringMap:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			v.errorf(path, IssueInvalidType, "Must be an object of names to values")
+			return
+		}
+		for key, entry := range entries {
+			if entry != nil && !isScalar(entry) {
+				v.errorf(joinPath(path, key), IssueInvalidType, "Must be a string")
+			}
+		}
+	case kindObject:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			v.errorf(path, IssueInvalidType, "Must be an object")
+			return
+		}
+		v.object(path, spec.fields, fields)
+	}
+}
+
+func (v *schemaValidator) regex(path string, value interface{}) {
+	s, ok := value.(string)
+	if !ok {
+		v.errorf(path, IssueInvalidType, "Must be a regular expression")
+		return
+	}
+	if _, err := regexp.Compile(s); err != nil {
+		v.errorf(path, IssueInvalidValue, "Invalid regular expression: %v", err)
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// isScalar reports whether value decodes into a Traefik string field
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+func isBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return true
+	case string:
+		_, err := strconv.ParseBool(v)
+		return err == nil
+	}
+	return false
+}
+
+// toInt accepts whole numbers, as decoded from JSON or YAML, and their strings
+func toInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// isDuration accepts Go durations and numbers of seconds, like Traefik
+func isDuration(value interface{}) bool {
+	if s, ok := value.(string); ok {
+		if _, err := time.ParseDuration(s); err == nil {
+			return true
+		}
+	}
+	_, ok := toInt(value)
+	return ok
+}
+
+func isIPRange(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
+	}
+	return net.ParseIP(s) != nil
+}
+
+// closestField suggests the known field a typo most likely meant, or "" when
+// none is close enough
+func closestField(key string, schema configSchema) string {
+	lower := strings.ToLower(key)
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, bestDistance := "", max(1, len(key)/3)+1
+	for _, name := range names {
+		if d := editDistance(lower, strings.ToLower(name)); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeConfig(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &config); err != nil {
+		t.Fatalf("invalid test config %s: %v", s, err)
+	}
+	return config
+}
+
+func issueFields(issues []MiddlewareConfigIssue) string {
+	fields := make([]string, len(issues))
+	for i, issue := range issues {
+		fields[i] = issue.Field + ":" + issue.Code
+	}
+	return strings.Join(fields, ",")
+}
+
+func TestValidateMiddlewareConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		typ          string
+		config       string
+		wantErrors   string
+		wantWarnings string
+	}{
+		{"valid headers", "headers", `{"customRequestHeaders":{"X-A":"1"},"stsSeconds":31536000,"frameDeny":true}`, "", ""},
+		{"typo", "buffering", `{"maxRequestBodyByte":1000}`, "maxRequestBodyByte:unknown_field", ""},
+		{"unrelated field", "buffering", `{"color":"blue"}`, "color:unknown_field", ""},
+		{"case only", "headers", `{"FrameDeny":true}`, "", "FrameDeny:unknown_field"},
+		{"wrong types", "rateLimit", `{"average":"fast","burst":1.5,"period":"soon"}`,
+			"average:invalid_type,burst:invalid_type,period:invalid_type", ""},
+		{"numeric strings", "rateLimit", `{"average":"100","period":"1m","burst":50}`, "", ""},
+		{"nested", "inFlightReq", `{"amount":10,"sourceCriterion":{"ipStrategy":{"depht":1,"excludedIPs":["nope"]}}}`,
+			"sourceCriterion.ipStrategy.depht:unknown_field,sourceCriterion.ipStrategy.excludedIPs[0]:invalid_value", ""},
+		{"list expected", "ipAllowList", `{"sourceRange":"10.0.0.0/8"}`, "sourceRange:invalid_type", ""},
+		{"bad regex", "redirectRegex", `{"regex":"^(foo","replacement":"/"}`, "regex:invalid_value", ""},
+		{"missing required", "forwardAuth", `{"trustForwardHeader":true}`, "", "address:required"},
+		{"alternative required", "basicAuth", `{"usersFile":"/etc/users"}`, "", ""},
+		{"deprecated", "headers", `{"sslRedirect":true}`, "", "sslRedirect:deprecated"},
+		{"object expected", "forwardAuth", `{"address":"http://auth","tls":true}`, "tls:invalid_type", ""},
+		{"null ignored", "headers", `{"frameDeny":null}`, "", ""},
+		{"plugin", "plugin", `{"geoblock":{"anything":[1,2]}}`, "", ""},
+		{"empty plugin", "plugin", `{}`, "", ":required"},
+		{"two plugins", "plugin", `{"a":{},"b":{}}`, ":invalid_value", ""},
+		{"plugin scalar", "plugin", `{"a":"on"}`, "a:invalid_type", ""},
+		{"unknown type", "bogus", `{}`, "type:invalid_value", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ValidateMiddlewareConfig(tt.typ, decodeConfig(t, tt.config))
+			if got := issueFields(result.Errors); got != tt.wantErrors {
+				t.Errorf("errors = %q, want %q (%+v)", got, tt.wantErrors, result.Errors)
+			}
+			if got := issueFields(result.Warnings); got != tt.wantWarnings {
+				t.Errorf("warnings = %q, want %q (%+v)", got, tt.wantWarnings, result.Warnings)
+			}
+			if result.Valid != (tt.wantErrors == "") {
+				t.Errorf("valid = %v with errors %q", result.Valid, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestValidateMiddlewareConfigSuggestsField(t *testing.T) {
+	result := ValidateMiddlewareConfig("buffering", map[string]interface{}{"maxRequestBodyByte": 1000})
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, "maxRequestBodyBytes") {
+		t.Fatalf("expected a maxRequestBodyBytes suggestion, got %+v", result.Errors)
+	}
+
+	result = ValidateMiddlewareConfig("buffering", map[string]interface{}{"color": "blue"})
+	if len(result.Errors) != 1 || strings.Contains(result.Errors[0].Message, "did you mean") {
+		t.Fatalf("expected no suggestion for an unrelated field, got %+v", result.Errors)
+	}
+}
+
+func TestEveryMiddlewareTypeHasSchema(t *testing.T) {
+	for typ := range middlewareProcessors {
+		if !HasMiddlewareSchema(typ) {
+			t.Errorf("no schema for middleware type %s", typ)
+		}
+	}
+	for typ := range middlewareRequired {
+		if _, ok := middlewareSchemas[typ]; !ok {
+			t.Errorf("required fields listed for unknown type %s", typ)
+		}
+	}
+}
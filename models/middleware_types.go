@@ -52,6 +52,7 @@ var middlewareProcessors = map[string]MiddlewareProcessor{
 	"rateLimit":       &RateLimitProcessor{},
 	"inFlightReq":     &RateLimitProcessor{},
 	"ipAllowList":     &IPFilterProcessor{},
+	"ipWhiteList":     &IPFilterProcessor{},
 	"buffering":       &BufferingProcessor{}, // ADD THIS LINE
 	// Add more middleware types as needed
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ResourceMirror copies a percentage of a resource's requests to a test
+// environment. MM wraps the router's service in a Traefik mirroring service.
+type ResourceMirror struct {
+	ResourceID  string    `json:"resource_id"`
+	TargetURL   string    `json:"target_url"`
+	Percent     int       `json:"percent"`
+	MirrorBody  bool      `json:"mirror_body"`
+	MaxBodySize int64     `json:"max_body_size"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Normalize trims whitespace and a trailing slash from the target URL
+func (m *ResourceMirror) Normalize() {
+	m.TargetURL = strings.TrimSuffix(strings.TrimSpace(m.TargetURL), "/")
+}
+
+// Validate checks the target URL, percentage and body size limit
+func (m *ResourceMirror) Validate() error {
+	u, err := url.Parse(m.TargetURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid target_url %q: expected http(s)://host[:port]", m.TargetURL)
+	}
+	if m.Percent < 1 || m.Percent > 100 {
+		return fmt.Errorf("percent must be between 1 and 100")
+	}
+	if m.MaxBodySize < 0 {
+		return fmt.Errorf("max_body_size must not be negative")
+	}
+	return nil
+}
+
+// MirrorServiceNames returns the names of the mirroring service and the mirror
+// target service generated for a resource
+func MirrorServiceNames(resourceID string) (mirroring, target string) {
+	return resourceID + "-mirroring", resourceID + "-mirror-target"
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -15,6 +18,8 @@ type MTLSConfig struct {
 	CAExpiry      *time.Time `json:"ca_expiry,omitempty"`
 	CertsBasePath string     `json:"certs_base_path"`
 	HasCA         bool       `json:"has_ca"`
+	PublicPKI     bool       `json:"public_pki_enabled"` // Serve CA cert and install instructions at /pki/
+	Signer        string     `json:"signer,omitempty"`   // External CA client certificates are issued by (empty for the built-in CA)
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
@@ -31,6 +36,7 @@ type MTLSClient struct {
 	Expiry          *time.Time `json:"expiry,omitempty"`
 	Revoked         bool       `json:"revoked"`
 	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	Signer          string     `json:"signer,omitempty"` // External CA that issued the certificate (empty for the built-in CA or imports)
 	CreatedAt       time.Time  `json:"created_at"`
 }
 
@@ -72,3 +78,170 @@ type MTLSMiddlewareConfig struct {
 	// RefreshInterval in seconds for external data
 	RefreshInterval int `json:"refresh_interval"`
 }
+
+// MTLSExport is a proxy-neutral snapshot of the mTLS trust configuration
+// (CA bundle, CRL and per-resource verification policy)
+type MTLSExport struct {
+	Version     int                        `json:"version"`
+	GeneratedAt time.Time                  `json:"generated_at"`
+	CA          MTLSExportCA               `json:"ca"`
+	CRL         MTLSExportCRL              `json:"crl"`
+	Clients     []MTLSExportClient         `json:"clients"`
+	Resources   []MTLSExportResourcePolicy `json:"resources"`
+	CABundlePEM string                     `json:"ca_bundle_pem"`
+	CRLPEM      string                     `json:"crl_pem"`
+}
+
+// MTLSExportCA describes the exported CA certificate
+type MTLSExportCA struct {
+	Subject           string     `json:"subject"`
+	SerialNumber      string     `json:"serial_number"`
+	FingerprintSHA256 string     `json:"fingerprint_sha256"`
+	NotAfter          *time.Time `json:"not_after,omitempty"`
+}
+
+// MTLSExportCRL describes the exported certificate revocation list
+type MTLSExportCRL struct {
+	Number       string    `json:"number"`
+	ThisUpdate   time.Time `json:"this_update"`
+	NextUpdate   time.Time `json:"next_update"`
+	RevokedCount int       `json:"revoked_count"`
+}
+
+// MTLSExportClient lists an issued client certificate (no key material)
+type MTLSExportClient struct {
+	Name         string     `json:"name"`
+	Subject      string     `json:"subject"`
+	SerialNumber string     `json:"serial_number"`
+	NotAfter     *time.Time `json:"not_after,omitempty"`
+	Revoked      bool       `json:"revoked"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// MTLSExportResourcePolicy is the verification policy for one mTLS-protected resource
+type MTLSExportResourcePolicy struct {
+	ResourceID     string          `json:"resource_id"`
+	Host           string          `json:"host"`
+	VerifyClient   string          `json:"verify_client"`
+	Rules          json.RawMessage `json:"rules,omitempty"`
+	RequestHeaders json.RawMessage `json:"request_headers,omitempty"`
+	RejectMessage  string          `json:"reject_message,omitempty"`
+	RejectCode     int             `json:"reject_code"`
+}
+
+// mTLS pre-flight check outcomes
+const (
+	PreflightPass = "pass"
+	PreflightWarn = "warn"
+	PreflightFail = "fail"
+)
+
+// MTLSPreflightCheck is the outcome of one check run before mTLS is enabled on
+// a resource. Only failed checks block enabling; warnings mean MM could not
+// verify the condition itself.
+type MTLSPreflightCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// MTLSPreflightResult lists every pre-flight check for a resource
+type MTLSPreflightResult struct {
+	ResourceID string               `json:"resource_id"`
+	Ready      bool                 `json:"ready"`
+	Checks     []MTLSPreflightCheck `json:"checks"`
+}
+
+// MaxMTLSExemptPaths caps how many paths a resource can exempt from mTLS
+const MaxMTLSExemptPaths = 20
+
+// UpdateMTLSExemptionsRequest replaces the paths of a resource that are served
+// without requiring a client certificate
+type UpdateMTLSExemptionsRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// NormalizeMTLSExemptPaths trims, validates and de-duplicates exemption path
+// prefixes. Paths must start with "/" and may not contain characters that
+// would break out of a Traefik rule.
+func NormalizeMTLSExemptPaths(paths []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("path %q must start with /", path)
+		}
+		if strings.ContainsAny(path, "`\"' \t\r\n") {
+			return nil, fmt.Errorf("path %q contains characters not allowed in a rule", path)
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		normalized = append(normalized, path)
+	}
+	if len(normalized) > MaxMTLSExemptPaths {
+		return nil, fmt.Errorf("at most %d exempt paths are allowed", MaxMTLSExemptPaths)
+	}
+	return normalized, nil
+}
+
+// mTLS client import outcomes
+const (
+	ImportStatusImported = "imported"
+	ImportStatusSkipped  = "skipped"
+	ImportStatusFailed   = "failed"
+)
+
+// MTLSClientImport is an existing client certificate, e.g. issued by easy-rsa
+// or step-ca, to import without reissuing it
+type MTLSClientImport struct {
+	// Name defaults to the certificate's common name
+	Name string `json:"name"`
+	// Cert is the PEM certificate; a PEM private key may follow it instead of using Key
+	Cert string `json:"cert"`
+	// Key is the optional PEM private key, needed to build a PKCS#12 download
+	Key         string `json:"key"`
+	P12Password string `json:"p12_password"`
+	LegacyP12   bool   `json:"legacy_p12"`
+}
+
+// ImportClientsRequest is the request to import existing client certificates
+type ImportClientsRequest struct {
+	Clients []MTLSClientImport `json:"clients"`
+}
+
+// MTLSClientImportResult is the outcome of importing one certificate
+type MTLSClientImportResult struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// IssuedByCA is false for certificates Traefik only accepts while their
+	// issuing CA is trusted as well
+	IssuedByCA bool `json:"issued_by_ca"`
+}
+
+// MTLSClientImportResponse summarises an import
+type MTLSClientImportResponse struct {
+	Imported int                      `json:"imported"`
+	Skipped  int                      `json:"skipped"`
+	Failed   int                      `json:"failed"`
+	Results  []MTLSClientImportResult `json:"results"`
+}
+
+// MTLSClientCert is an exported client certificate (no key material)
+type MTLSClientCert struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Subject      string     `json:"subject"`
+	SerialNumber string     `json:"serial_number"`
+	NotAfter     *time.Time `json:"not_after,omitempty"`
+	Cert         string     `json:"cert"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeMTLSExemptPaths(t *testing.T) {
+	paths, err := NormalizeMTLSExemptPaths([]string{" /api/webhook ", "", "/healthz", "/api/webhook"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"/api/webhook", "/healthz"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	if paths, err := NormalizeMTLSExemptPaths(nil); err != nil || paths == nil || len(paths) != 0 {
+		t.Errorf("expected an empty list for nil input, got %v (err %v)", paths, err)
+	}
+
+	for _, bad := range []string{"api/webhook", "/a`) || Host(`x", "/with space"} {
+		if _, err := NormalizeMTLSExemptPaths([]string{bad}); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+
+	tooMany := make([]string, MaxMTLSExemptPaths+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("/p%d", i)
+	}
+	if _, err := NormalizeMTLSExemptPaths(tooMany); err == nil {
+		t.Error("expected too many paths to be rejected")
+	}
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// PermissionsPolicyDirective restricts one browser feature. An empty
+// Allowlist disables the feature everywhere; entries are "*", "self", "src"
+// or an origin such as https://maps.example.com.
+type PermissionsPolicyDirective struct {
+	Feature   string   `json:"feature" binding:"required"`
+	Allowlist []string `json:"allowlist"`
+}
+
+// SecureHeadersError reports which secure header setting is invalid
+type SecureHeadersError struct {
+	Field   string
+	Message string
+}
+
+func (e *SecureHeadersError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+var permissionsPolicyFeature = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// BuildPermissionsPolicy validates directives and renders the Permissions-Policy
+// header value, e.g. camera=(), geolocation=(self "https://maps.example.com")
+func BuildPermissionsPolicy(directives []PermissionsPolicyDirective) (string, error) {
+	seen := make(map[string]bool, len(directives))
+	parts := make([]string, 0, len(directives))
+	for _, directive := range directives {
+		feature := strings.TrimSpace(directive.Feature)
+		if !permissionsPolicyFeature.MatchString(feature) {
+			return "", fmt.Errorf("invalid feature name %q", directive.Feature)
+		}
+		if seen[feature] {
+			return "", fmt.Errorf("feature %s is listed more than once", feature)
+		}
+		seen[feature] = true
+
+		var members []string
+		for _, entry := range directive.Allowlist {
+			member, err := permissionsPolicyMember(strings.TrimSpace(entry))
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", feature, err)
+			}
+			members = append(members, member)
+		}
+
+		if len(members) == 1 && members[0] == "*" {
+			parts = append(parts, feature+"=*")
+			continue
+		}
+		for _, member := range members {
+			if member == "*" {
+				return "", fmt.Errorf("%s: * cannot be combined with other origins", feature)
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s=(%s)", feature, strings.Join(members, " ")))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// permissionsPolicyMember renders one allowlist entry in header syntax
+func permissionsPolicyMember(entry string) (string, error) {
+	switch strings.ToLower(strings.Trim(entry, "'")) {
+	case "*":
+		return "*", nil
+	case "self":
+		return "self", nil
+	case "src":
+		return "src", nil
+	}
+
+	origin := strings.Trim(entry, `"`)
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("allowlist entry %q must be *, self, src or an origin like https://example.com", entry)
+	}
+	return fmt.Sprintf("%q", u.Scheme+"://"+u.Host), nil
+}
+
+// ParsePermissionsPolicy parses a raw Permissions-Policy header value into
+// directives, rejecting values that browsers would ignore
+func ParsePermissionsPolicy(value string) ([]PermissionsPolicyDirective, error) {
+	var directives []PermissionsPolicyDirective
+	if strings.TrimSpace(value) == "" {
+		return directives, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		feature, list, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("directive %q must look like feature=(allowlist)", strings.TrimSpace(part))
+		}
+		directive := PermissionsPolicyDirective{Feature: strings.TrimSpace(feature), Allowlist: []string{}}
+
+		list = strings.TrimSpace(list)
+		switch {
+		case list == "*":
+			directive.Allowlist = append(directive.Allowlist, "*")
+		case strings.HasPrefix(list, "(") && strings.HasSuffix(list, ")"):
+			directive.Allowlist = append(directive.Allowlist, strings.Fields(list[1:len(list)-1])...)
+		default:
+			return nil, fmt.Errorf("allowlist for %s must be * or wrapped in parentheses", directive.Feature)
+		}
+		directives = append(directives, directive)
+	}
+
+	if _, err := BuildPermissionsPolicy(directives); err != nil {
+		return nil, err
+	}
+	return directives, nil
+}
+
+// Validate checks the settings that binding tags cannot: the syntax of the
+// raw and structured Permissions-Policy
+func (h SecureHeadersConfig) Validate() error {
+	if len(h.PermissionsPolicyDirectives) > 0 {
+		if _, err := BuildPermissionsPolicy(h.PermissionsPolicyDirectives); err != nil {
+			return &SecureHeadersError{Field: "permissions_policy_directives", Message: err.Error()}
+		}
+	}
+	if _, err := ParsePermissionsPolicy(h.PermissionsPolicy); err != nil {
+		return &SecureHeadersError{Field: "permissions_policy", Message: err.Error()}
+	}
+	return nil
+}
+
+// PermissionsPolicyValue returns the Permissions-Policy header value, preferring
+// the structured directives over the raw string
+func (h SecureHeadersConfig) PermissionsPolicyValue() string {
+	if len(h.PermissionsPolicyDirectives) > 0 {
+		if value, err := BuildPermissionsPolicy(h.PermissionsPolicyDirectives); err == nil {
+			return value
+		}
+	}
+	return h.PermissionsPolicy
+}
+
+// IsEmpty reports whether no secure header is set
+func (h SecureHeadersConfig) IsEmpty() bool {
+	return h.XContentTypeOptions == "" && h.XFrameOptions == "" && h.XXSSProtection == "" &&
+		h.HSTS == "" && h.ReferrerPolicy == "" && h.CSP == "" && h.PermissionsPolicyValue() == "" &&
+		h.CrossOriginOpenerPolicy == "" && h.CrossOriginEmbedderPolicy == "" && h.CrossOriginResourcePolicy == ""
+}
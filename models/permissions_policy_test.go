@@ -0,0 +1,78 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildPermissionsPolicy(t *testing.T) {
+	value, err := BuildPermissionsPolicy([]PermissionsPolicyDirective{
+		{Feature: "camera"},
+		{Feature: "geolocation", Allowlist: []string{"self", "https://maps.example.com/"}},
+		{Feature: "fullscreen", Allowlist: []string{"*"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `camera=(), geolocation=(self "https://maps.example.com"), fullscreen=*`
+	if value != want {
+		t.Errorf("value = %s, want %s", value, want)
+	}
+
+	bad := [][]PermissionsPolicyDirective{
+		{{Feature: "Camera"}},
+		{{Feature: "camera"}, {Feature: "camera"}},
+		{{Feature: "camera", Allowlist: []string{"maps.example.com"}}},
+		{{Feature: "camera", Allowlist: []string{"https://example.com/path"}}},
+		{{Feature: "camera", Allowlist: []string{"*", "self"}}},
+	}
+	for _, directives := range bad {
+		if _, err := BuildPermissionsPolicy(directives); err == nil {
+			t.Errorf("expected %+v to be rejected", directives)
+		}
+	}
+}
+
+func TestParsePermissionsPolicy(t *testing.T) {
+	directives, err := ParsePermissionsPolicy(`camera=(), geolocation=(self "https://maps.example.com")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []PermissionsPolicyDirective{
+		{Feature: "camera", Allowlist: []string{}},
+		{Feature: "geolocation", Allowlist: []string{"self", `"https://maps.example.com"`}},
+	}
+	if !reflect.DeepEqual(directives, want) {
+		t.Errorf("directives = %+v, want %+v", directives, want)
+	}
+
+	for _, bad := range []string{"camera", "camera=self", "geolocation=(maps.example.com)"} {
+		if _, err := ParsePermissionsPolicy(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSecureHeadersConfigValidate(t *testing.T) {
+	headers := DefaultSecureHeaders()
+	headers.PermissionsPolicy = "camera=self"
+	err := headers.Validate()
+	if e, ok := err.(*SecureHeadersError); !ok || e.Field != "permissions_policy" {
+		t.Fatalf("expected a permissions_policy error, got %v", err)
+	}
+
+	headers.PermissionsPolicy = "camera=()"
+	headers.PermissionsPolicyDirectives = []PermissionsPolicyDirective{{Feature: "microphone"}}
+	if err := headers.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := headers.PermissionsPolicyValue(); got != "microphone=()" {
+		t.Errorf("structured directives should win, got %s", got)
+	}
+
+	for _, preset := range BuiltInSecureHeadersPresets() {
+		if err := preset.Headers.Validate(); err != nil {
+			t.Errorf("built-in preset %s is invalid: %v", preset.ID, err)
+		}
+	}
+}
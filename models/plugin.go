@@ -73,3 +73,56 @@ type PluginInstallRequest struct {
 type PluginRemoveRequest struct {
 	ModuleName string `json:"moduleName" binding:"required"`
 }
+
+// PluginSandboxRequest asks for a plugin to be loaded and exercised in the sandbox
+// before it is added to the Traefik static config
+type PluginSandboxRequest struct {
+	ModuleName string `json:"moduleName" binding:"required"`
+	// Version defaults to the latest version known to the Go module proxy
+	Version string `json:"version,omitempty"`
+	// Config is the middleware config; the plugin's own testData is used when empty
+	Config  map[string]interface{}    `json:"config,omitempty"`
+	Request *PluginSandboxHTTPRequest `json:"request,omitempty"`
+}
+
+// PluginSandboxHTTPRequest is the synthetic request sent through the plugin
+type PluginSandboxHTTPRequest struct {
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// PluginSandboxStage is the outcome of one step of a sandbox run
+type PluginSandboxStage struct {
+	Name       string  `json:"name"`
+	OK         bool    `json:"ok"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// PluginSandboxResponse is what the plugin did with the synthetic request
+type PluginSandboxResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// CalledNext is true when the plugin passed the request on to the upstream
+	CalledNext bool `json:"calledNext"`
+	// UpstreamHeaders are the request headers the upstream received
+	UpstreamHeaders map[string]string `json:"upstreamHeaders,omitempty"`
+}
+
+// PluginSandboxResult reports whether a plugin loads and handles a request
+type PluginSandboxResult struct {
+	ModuleName string `json:"moduleName"`
+	Version    string `json:"version"`
+	Import     string `json:"import,omitempty"`
+	OK         bool   `json:"ok"`
+	// FailedStage names the first stage that failed
+	FailedStage  string                 `json:"failedStage,omitempty"`
+	Panic        string                 `json:"panic,omitempty"`
+	UsedTestData bool                   `json:"usedTestData"`
+	Config       map[string]interface{} `json:"config,omitempty"`
+	Stages       []PluginSandboxStage   `json:"stages"`
+	Response     *PluginSandboxResponse `json:"response,omitempty"`
+}
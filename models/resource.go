@@ -40,6 +40,9 @@ type Resource struct {
 	// TLS Hardening configuration (standalone, disabled when mTLS is active)
 	TLSHardeningEnabled bool `json:"tls_hardening_enabled"`
 
+	// Hardening profile applied when TLS hardening is enabled (hardened or compat)
+	TLSHardeningProfile string `json:"tls_hardening_profile"`
+
 	// Secure Headers configuration
 	SecureHeadersEnabled bool `json:"secure_headers_enabled"`
 
@@ -47,6 +50,14 @@ type Resource struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PinDivergence is a watcher-owned field on which the data source disagrees
+// with a pinned resource. Upstream is what the watcher would have set.
+type PinDivergence struct {
+	Field    string `json:"field"`
+	Pinned   string `json:"pinned"`
+	Upstream string `json:"upstream"`
+}
+
 // PangolinResource represents the format of a resource from Pangolin API
 type PangolinResource struct {
 	ID     string `json:"id"`
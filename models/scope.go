@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/util"
+)
+
+// ManagementScope limits which resources Middleware Manager touches.
+// Patterns are shell-style globs matched against the resource host
+// (e.g. "*.example.com"); an empty include list means every host.
+// Exclude patterns always win over include patterns.
+type ManagementScope struct {
+	Include   []string  `json:"include"`
+	Exclude   []string  `json:"exclude"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize trims, lowercases and de-duplicates the patterns
+func (s *ManagementScope) Normalize() {
+	s.Include = normalizePatterns(s.Include)
+	s.Exclude = normalizePatterns(s.Exclude)
+}
+
+// Validate checks that every pattern is a well-formed glob
+func (s *ManagementScope) Validate() error {
+	for _, list := range [][]string{s.Include, s.Exclude} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IsUnrestricted reports whether the scope covers every host
+func (s *ManagementScope) IsUnrestricted() bool {
+	return s == nil || (len(s.Include) == 0 && len(s.Exclude) == 0)
+}
+
+// Matches reports whether a resource host is inside the scope
+func (s *ManagementScope) Matches(host string) bool {
+	if s.IsUnrestricted() {
+		return true
+	}
+	host = util.NormalizeHost(host)
+
+	for _, pattern := range s.Exclude {
+		if matchHostPattern(pattern, host) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, pattern := range s.Include {
+		if matchHostPattern(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchHostPattern(pattern, host string) bool {
+	ok, err := path.Match(pattern, host)
+	return err == nil && ok
+}
+
+func normalizePatterns(patterns []string) []string {
+	seen := make(map[string]struct{}, len(patterns))
+	out := []string{}
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package models
+
+import "testing"
+
+func TestManagementScopeMatches(t *testing.T) {
+	scope := &ManagementScope{
+		Include: []string{" *.Example.com ", "app.other.org", "*.example.com"},
+		Exclude: []string{"legacy.example.com"},
+	}
+	scope.Normalize()
+	if len(scope.Include) != 2 {
+		t.Fatalf("Normalize() should trim, lowercase and dedupe, got %v", scope.Include)
+	}
+
+	tests := map[string]bool{
+		"api.example.com":    true,
+		"API.EXAMPLE.COM":    true,
+		"a.b.example.com":    true,
+		"legacy.example.com": false,
+		"app.other.org":      true,
+		"www.other.org":      false,
+		"example.com":        false,
+	}
+	for host, want := range tests {
+		if got := scope.Matches(host); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	excludeOnly := &ManagementScope{Exclude: []string{"*.internal"}}
+	if !excludeOnly.Matches("app.example.com") || excludeOnly.Matches("db.internal") {
+		t.Error("exclude-only scope should include everything except excluded hosts")
+	}
+
+	var unrestricted *ManagementScope
+	if !unrestricted.Matches("anything") {
+		t.Error("nil scope should match every host")
+	}
+
+	bad := &ManagementScope{Include: []string{"[bad"}}
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate() should reject malformed patterns")
+	}
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+)
+
+// Built-in secure headers preset IDs
+const (
+	SecureHeadersPresetBaseline    = "baseline"
+	SecureHeadersPresetStrict      = "strict"
+	SecureHeadersPresetAPI         = "api"
+	SecureHeadersPresetEmbeddedApp = "embedded-app"
+)
+
+// SecureHeadersPreset is a named set of the seven secure header values that
+// can be applied globally or selected per resource
+type SecureHeadersPreset struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	BuiltIn     bool                `json:"built_in"`
+	Headers     SecureHeadersConfig `json:"headers"`
+	CreatedAt   *time.Time          `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time          `json:"updated_at,omitempty"`
+}
+
+// SecureHeadersPresetRequest represents request to create or update a custom preset
+type SecureHeadersPresetRequest struct {
+	Name        string              `json:"name" binding:"required"`
+	Description string              `json:"description"`
+	Headers     SecureHeadersConfig `json:"headers"`
+}
+
+// UpdateResourceSecureHeadersRequest represents request to update per-resource
+// secure headers. A nil Preset keeps the current selection; an empty string
+// goes back to the global secure headers values. A nil ReportOnly keeps the
+// current mode.
+type UpdateResourceSecureHeadersRequest struct {
+	Enabled    bool    `json:"enabled"`
+	Preset     *string `json:"preset"`
+	ReportOnly *bool   `json:"report_only"`
+}
+
+// BuiltInSecureHeadersPresets returns the presets shipped with MM
+func BuiltInSecureHeadersPresets() []SecureHeadersPreset {
+	return []SecureHeadersPreset{
+		{
+			ID:          SecureHeadersPresetBaseline,
+			Name:        "Baseline",
+			Description: "Safe defaults for most web apps; same-origin framing and no CSP",
+			BuiltIn:     true,
+			Headers:     DefaultSecureHeaders(),
+		},
+		{
+			ID:          SecureHeadersPresetStrict,
+			Name:        "Strict",
+			Description: "Locked-down pages: no framing, same-origin content only, HSTS preload",
+			BuiltIn:     true,
+			Headers: SecureHeadersConfig{
+				XContentTypeOptions: "nosniff",
+				XFrameOptions:       "DENY",
+				XXSSProtection:      "0",
+				HSTS:                "max-age=63072000; includeSubDomains; preload",
+				ReferrerPolicy:      "no-referrer",
+				CSP:                 "default-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'",
+				PermissionsPolicy:   "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+
+				CrossOriginOpenerPolicy:   "same-origin",
+				CrossOriginResourcePolicy: "same-origin",
+			},
+		},
+		{
+			ID:          SecureHeadersPresetAPI,
+			Name:        "API",
+			Description: "JSON APIs: responses are never rendered or framed by a browser",
+			BuiltIn:     true,
+			Headers: SecureHeadersConfig{
+				XContentTypeOptions: "nosniff",
+				XFrameOptions:       "DENY",
+				XXSSProtection:      "0",
+				HSTS:                "max-age=31536000; includeSubDomains",
+				ReferrerPolicy:      "no-referrer",
+				CSP:                 "default-src 'none'; frame-ancestors 'none'",
+
+				CrossOriginResourcePolicy: "same-site",
+			},
+		},
+		{
+			ID:          SecureHeadersPresetEmbeddedApp,
+			Name:        "Embedded app",
+			Description: "Apps shown inside dashboards or iframes; framing is allowed from HTTPS origins",
+			BuiltIn:     true,
+			Headers: SecureHeadersConfig{
+				XContentTypeOptions: "nosniff",
+				XXSSProtection:      "0",
+				HSTS:                "max-age=31536000; includeSubDomains",
+				ReferrerPolicy:      "strict-origin-when-cross-origin",
+				CSP:                 "frame-ancestors 'self' https:",
+			},
+		},
+	}
+}
+
+// BuiltInSecureHeadersPreset returns the built-in preset with the given ID
+func BuiltInSecureHeadersPreset(id string) (SecureHeadersPreset, bool) {
+	for _, preset := range BuiltInSecureHeadersPresets() {
+		if preset.ID == id {
+			return preset, true
+		}
+	}
+	return SecureHeadersPreset{}, false
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -8,6 +11,7 @@ import (
 type SecurityConfig struct {
 	ID                   int       `json:"id"`
 	TLSHardeningEnabled  bool      `json:"tls_hardening_enabled"`
+	TLSHardeningMode     string    `json:"tls_hardening_mode"`
 	SecureHeadersEnabled bool      `json:"secure_headers_enabled"`
 	SecureHeaders        SecureHeadersConfig `json:"secure_headers"`
 	CreatedAt            time.Time `json:"created_at"`
@@ -23,6 +27,13 @@ type SecureHeadersConfig struct {
 	ReferrerPolicy      string `json:"referrer_policy"`
 	CSP                 string `json:"csp"`
 	PermissionsPolicy   string `json:"permissions_policy"`
+
+	// Structured Permissions-Policy; when set it replaces PermissionsPolicy
+	PermissionsPolicyDirectives []PermissionsPolicyDirective `json:"permissions_policy_directives,omitempty"`
+
+	CrossOriginOpenerPolicy   string `json:"cross_origin_opener_policy" binding:"omitempty,oneof=unsafe-none same-origin-allow-popups same-origin noopener-allow-popups"`
+	CrossOriginEmbedderPolicy string `json:"cross_origin_embedder_policy" binding:"omitempty,oneof=unsafe-none require-corp credentialless"`
+	CrossOriginResourcePolicy string `json:"cross_origin_resource_policy" binding:"omitempty,oneof=same-site same-origin cross-origin"`
 }
 
 // DefaultSecureHeaders returns the default security headers configuration
@@ -38,6 +49,36 @@ func DefaultSecureHeaders() SecureHeadersConfig {
 	}
 }
 
+// SoftHSTSMaxAge caps Strict-Transport-Security max-age while a resource's
+// secure headers are in report-only mode, so a bad trial expires in minutes
+const SoftHSTSMaxAge = 300
+
+// SoftHSTS weakens an HSTS value for report-only trials: max-age is capped at
+// SoftHSTSMaxAge and preload is dropped, since a preload submission cannot be
+// undone quickly. includeSubDomains is kept so the trial covers the same hosts.
+func SoftHSTS(hsts string) string {
+	if strings.TrimSpace(hsts) == "" {
+		return ""
+	}
+
+	directives := []string{fmt.Sprintf("max-age=%d", SoftHSTSMaxAge)}
+	for _, part := range strings.Split(hsts, ";") {
+		directive := strings.TrimSpace(part)
+		name := strings.ToLower(directive)
+		switch {
+		case directive == "", name == "preload":
+			continue
+		case strings.HasPrefix(name, "max-age="):
+			if age, err := strconv.Atoi(strings.TrimPrefix(name, "max-age=")); err == nil && age < SoftHSTSMaxAge {
+				directives[0] = fmt.Sprintf("max-age=%d", age)
+			}
+		default:
+			directives = append(directives, directive)
+		}
+	}
+	return strings.Join(directives, "; ")
+}
+
 // DuplicateCheckResult represents the result of middleware duplicate detection
 type DuplicateCheckResult struct {
 	HasDuplicates  bool       `json:"has_duplicates"`
@@ -66,11 +107,58 @@ type UpdateSecurityConfigRequest struct {
 	SecureHeaders        *SecureHeadersConfig `json:"secure_headers,omitempty"`
 }
 
+// TLS hardening inheritance modes decide how the global setting and the
+// per-resource flags combine. mTLS resources are never hardened separately
+// because mtls-verify already carries the hardened options.
+const (
+	// TLSHardeningForceOn hardens every resource; per-resource opt-outs are ignored
+	TLSHardeningForceOn = "force_on"
+	// TLSHardeningDefaultOn hardens every resource that has not opted out
+	TLSHardeningDefaultOn = "default_on"
+	// TLSHardeningPerResource hardens only resources that enabled it (the default)
+	TLSHardeningPerResource = "per_resource"
+)
+
+// ValidTLSHardeningMode reports whether mode is a known inheritance mode
+func ValidTLSHardeningMode(mode string) bool {
+	switch mode {
+	case TLSHardeningForceOn, TLSHardeningDefaultOn, TLSHardeningPerResource:
+		return true
+	}
+	return false
+}
+
+// EffectiveTLSHardening resolves whether a resource is hardened under mode.
+// enabled is the resource's own flag and optOut records that it was
+// explicitly switched off. Unknown modes fall back to per-resource.
+func EffectiveTLSHardening(mode string, enabled, optOut bool) bool {
+	switch mode {
+	case TLSHardeningForceOn:
+		return true
+	case TLSHardeningDefaultOn:
+		return !optOut
+	default:
+		return enabled
+	}
+}
+
+// UpdateTLSHardeningModeRequest represents request to change the TLS hardening inheritance mode
+type UpdateTLSHardeningModeRequest struct {
+	Mode string `json:"mode" binding:"required"`
+}
+
 // UpdateResourceSecurityRequest represents request to update per-resource security settings
 type UpdateResourceSecurityRequest struct {
 	Enabled bool `json:"enabled"`
 }
 
+// UpdateResourceTLSHardeningRequest represents request to update per-resource TLS hardening.
+// Profile is optional and keeps the current profile when empty.
+type UpdateResourceTLSHardeningRequest struct {
+	Enabled bool   `json:"enabled"`
+	Profile string `json:"profile"`
+}
+
 // TLSHardeningOptions returns the TLS options for hardened security
 func TLSHardeningOptions() map[string]interface{} {
 	return map[string]interface{}{
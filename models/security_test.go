@@ -68,3 +68,43 @@ func TestTLSHardeningOptions(t *testing.T) {
 		t.Errorf("len(curvePreferences) = %d, want 3", len(curves))
 	}
 }
+
+func TestEffectiveTLSHardening(t *testing.T) {
+	tests := []struct {
+		mode    string
+		enabled bool
+		optOut  bool
+		want    bool
+	}{
+		{TLSHardeningForceOn, false, true, true},
+		{TLSHardeningDefaultOn, false, false, true},
+		{TLSHardeningDefaultOn, false, true, false},
+		{TLSHardeningPerResource, false, false, false},
+		{TLSHardeningPerResource, true, false, true},
+		{"", true, false, true},
+		{"bogus", false, false, false},
+	}
+	for _, tt := range tests {
+		if got := EffectiveTLSHardening(tt.mode, tt.enabled, tt.optOut); got != tt.want {
+			t.Errorf("EffectiveTLSHardening(%q, %v, %v) = %v, want %v", tt.mode, tt.enabled, tt.optOut, got, tt.want)
+		}
+	}
+	if ValidTLSHardeningMode("bogus") || !ValidTLSHardeningMode(TLSHardeningDefaultOn) {
+		t.Error("ValidTLSHardeningMode misclassified a mode")
+	}
+}
+
+func TestSoftHSTS(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		"max-age=63072000; includeSubDomains; preload": "max-age=300; includeSubDomains",
+		"max-age=31536000":    "max-age=300",
+		"max-age=60; Preload": "max-age=60",
+		"includeSubDomains":   "max-age=300; includeSubDomains",
+	}
+	for in, want := range tests {
+		if got := SoftHSTS(in); got != want {
+			t.Errorf("SoftHSTS(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -23,6 +23,9 @@ const (
 	WeightedType     ServiceType = "weighted"
 	MirroringType    ServiceType = "mirroring"
 	FailoverType     ServiceType = "failover"
+	// DNSDiscoveryType services are served as loadBalancer services whose
+	// servers MM resolves from DNS records (see DNSDiscoveryConfig)
+	DNSDiscoveryType ServiceType = "dnsDiscovery"
 )
 
 // IsValidServiceType checks if a service type is valid
@@ -32,6 +35,7 @@ func IsValidServiceType(typ string) bool {
 		string(WeightedType):     true,
 		string(MirroringType):    true,
 		string(FailoverType):     true,
+		string(DNSDiscoveryType): true,
 	}
 	return validTypes[typ]
 }
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ServiceOverride is a delta MM applies to an HTTP service discovered upstream
+// (e.g. a Pangolin site) during the merge, so a backend can be tweaked without
+// copying the whole service into a custom MM service. Fields left empty keep
+// the upstream value.
+type ServiceOverride struct {
+	ServiceName string `json:"service_name"`
+	// loadBalancer services: servers added to and removed (by URL) from the pool
+	AddServers    []ServerConfig `json:"add_servers,omitempty"`
+	RemoveServers []string       `json:"remove_servers,omitempty"`
+	// loadBalancer services: server URL -> weight
+	ServerWeights map[string]int     `json:"server_weights,omitempty"`
+	HealthCheck   *HealthCheckConfig `json:"health_check,omitempty"`
+	// weighted services: child service name -> weight
+	ServiceWeights map[string]int `json:"service_weights,omitempty"`
+	// Sticky replaces the sticky sessions of loadBalancer and weighted services
+	Sticky    *StickyConfig `json:"sticky,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Normalize trims names and URLs. Pangolin services show up as <name>@http in
+// Traefik, so the provider suffix is dropped.
+func (o *ServiceOverride) Normalize() {
+	o.ServiceName = strings.TrimSuffix(strings.TrimSpace(o.ServiceName), "@http")
+	for i := range o.AddServers {
+		o.AddServers[i].URL = strings.TrimSpace(o.AddServers[i].URL)
+	}
+	for i, server := range o.RemoveServers {
+		o.RemoveServers[i] = strings.TrimSpace(server)
+	}
+	o.ServerWeights = trimWeightKeys(o.ServerWeights)
+	o.ServiceWeights = trimWeightKeys(o.ServiceWeights)
+}
+
+func trimWeightKeys(weights map[string]int) map[string]int {
+	if len(weights) == 0 {
+		return nil
+	}
+	trimmed := make(map[string]int, len(weights))
+	for key, weight := range weights {
+		trimmed[strings.TrimSpace(key)] = weight
+	}
+	return trimmed
+}
+
+// Validate checks the override changes something and its servers and weights
+func (o *ServiceOverride) Validate() error {
+	if o.ServiceName == "" {
+		return fmt.Errorf("service_name is required")
+	}
+	if len(o.AddServers) == 0 && len(o.RemoveServers) == 0 && len(o.ServerWeights) == 0 &&
+		o.HealthCheck == nil && len(o.ServiceWeights) == 0 && o.Sticky == nil {
+		return fmt.Errorf("override of %s changes nothing", o.ServiceName)
+	}
+
+	removed := make(map[string]bool, len(o.RemoveServers))
+	for _, server := range o.RemoveServers {
+		if server == "" {
+			return fmt.Errorf("remove_servers must not contain empty URLs")
+		}
+		removed[server] = true
+	}
+	for _, server := range o.AddServers {
+		u, err := url.Parse(server.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "h2c") || u.Host == "" {
+			return fmt.Errorf("invalid server url %q: expected http(s)://host[:port]", server.URL)
+		}
+		if removed[server.URL] {
+			return fmt.Errorf("%s is both added and removed", server.URL)
+		}
+		if server.Weight != nil && *server.Weight < 0 {
+			return fmt.Errorf("weight of %s must not be negative", server.URL)
+		}
+	}
+	for name, weights := range map[string]map[string]int{"server_weights": o.ServerWeights, "service_weights": o.ServiceWeights} {
+		for key, weight := range weights {
+			if key == "" {
+				return fmt.Errorf("%s must name a target", name)
+			}
+			if weight < 0 {
+				return fmt.Errorf("weight of %s must not be negative", key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import "testing"
+
+func TestServiceOverride_NormalizeAndValidate(t *testing.T) {
+	weight := 2
+	override := ServiceOverride{
+		ServiceName:   " app-service@http ",
+		AddServers:    []ServerConfig{{URL: " http://10.0.0.7:8080 ", Weight: &weight}},
+		ServerWeights: map[string]int{" http://10.0.0.6:8080 ": 3},
+	}
+	override.Normalize()
+	if err := override.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if override.ServiceName != "app-service" || override.AddServers[0].URL != "http://10.0.0.7:8080" ||
+		override.ServerWeights["http://10.0.0.6:8080"] != 3 {
+		t.Errorf("unexpected override %+v", override)
+	}
+
+	negative := -1
+	for _, invalid := range []ServiceOverride{
+		{ServiceName: "app"},
+		{ServiceName: "", Sticky: &StickyConfig{}},
+		{ServiceName: "app", AddServers: []ServerConfig{{URL: "10.0.0.7:8080"}}},
+		{ServiceName: "app", AddServers: []ServerConfig{{URL: "http://a", Weight: &negative}}},
+		{ServiceName: "app", AddServers: []ServerConfig{{URL: "http://a"}}, RemoveServers: []string{"http://a"}},
+		{ServiceName: "app", ServiceWeights: map[string]int{"blue": -1}},
+	} {
+		invalid.Normalize()
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
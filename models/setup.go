@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// Deployment types offered by the setup wizard
+const (
+	DeploymentPangolin   = "pangolin"
+	DeploymentStandalone = "standalone"
+)
+
+// Setup wizard steps, in order
+const (
+	SetupStepDetect     = "detect"
+	SetupStepDataSource = "data_source"
+	SetupStepProvider   = "provider_check"
+	SetupStepComplete   = "complete"
+)
+
+// SetupProgress is the first-run wizard state persisted in config.json
+type SetupProgress struct {
+	DeploymentType     string     `json:"deployment_type,omitempty"`
+	DetectedAt         *time.Time `json:"detected_at,omitempty"`
+	DataSourceAt       *time.Time `json:"data_source_at,omitempty"`
+	ProviderVerifiedAt *time.Time `json:"provider_verified_at,omitempty"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	// Skipped is set when an install from before the wizard was upgraded
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// SetupStep is one step of the wizard as shown to the UI
+type SetupStep struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// SetupState is returned by GET /api/setup
+type SetupState struct {
+	Completed        bool                        `json:"completed"`
+	NextStep         string                      `json:"next_step,omitempty"`
+	Progress         SetupProgress               `json:"progress"`
+	Steps            []SetupStep                 `json:"steps"`
+	ActiveDataSource string                      `json:"active_data_source"`
+	DataSources      map[string]DataSourceConfig `json:"data_sources"`
+}
+
+// SetupProbe is the result of probing one candidate URL
+type SetupProbe struct {
+	Type      DataSourceType `json:"type"`
+	URL       string         `json:"url"`
+	Reachable bool           `json:"reachable"`
+	Error     string         `json:"error,omitempty"`
+	LatencyMs float64        `json:"latency_ms"`
+}
+
+// SetupDetectRequest adds candidate URLs to the built-in Docker network guesses
+type SetupDetectRequest struct {
+	PangolinURLs []string `json:"pangolin_urls"`
+	TraefikURLs  []string `json:"traefik_urls"`
+}
+
+// SetupDetection is the outcome of probing. DeploymentType is empty when
+// neither Pangolin nor Traefik answered.
+type SetupDetection struct {
+	DeploymentType string       `json:"deployment_type"`
+	PangolinURL    string       `json:"pangolin_url,omitempty"`
+	TraefikURL     string       `json:"traefik_url,omitempty"`
+	Probes         []SetupProbe `json:"probes"`
+}
+
+// SetupDataSourceRequest configures the data sources for a deployment type.
+// Pangolin deployments need pangolin_url; standalone ones need traefik_url.
+type SetupDataSourceRequest struct {
+	DeploymentType string `json:"deployment_type" binding:"required,oneof=pangolin standalone"`
+	PangolinURL    string `json:"pangolin_url"`
+	TraefikURL     string `json:"traefik_url"`
+	BasicAuth      struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"basic_auth"`
+	// Force saves the data source even when it is not reachable yet
+	Force bool `json:"force"`
+}
+
+// SetupProviderCheck reports whether Traefik loads the config served by
+// Middleware Manager through its HTTP provider
+type SetupProviderCheck struct {
+	OK      bool              `json:"ok"`
+	Checks  []DiagnosticCheck `json:"checks"`
+	Snippet string            `json:"snippet,omitempty"`
+}
@@ -0,0 +1,200 @@
+package models
+
+// TLS hardening profiles a resource can use
+const (
+	TLSProfileHardened = "hardened"
+	TLSProfileCompat   = "compat"
+)
+
+// TLSProfileOptionsName returns the name of the Traefik TLS options generated
+// for a hardening profile
+func TLSProfileOptionsName(profile string) string {
+	if profile == TLSProfileCompat {
+		return "tls-compat"
+	}
+	return "tls-hardened"
+}
+
+// ValidTLSProfile reports whether profile names a known hardening profile
+func ValidTLSProfile(profile string) bool {
+	return profile == TLSProfileHardened || profile == TLSProfileCompat
+}
+
+// TLSCompatOptions returns TLS options that keep TLS 1.2 as the floor but add
+// the CBC and RSA key-exchange suites and the P-256 curve that older clients
+// need. It trades some strength for reach and should only be used where the
+// compatibility report shows hardened would lock clients out.
+func TLSCompatOptions() map[string]interface{} {
+	return map[string]interface{}{
+		"minVersion": "VersionTLS12",
+		"maxVersion": "VersionTLS13",
+		"sniStrict":  true,
+		"cipherSuites": []string{
+			"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+			"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+			"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+			"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+			"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+			"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+			"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+			"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+			"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+			"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+			"TLS_RSA_WITH_AES_128_GCM_SHA256",
+			"TLS_RSA_WITH_AES_256_GCM_SHA384",
+			"TLS_RSA_WITH_AES_128_CBC_SHA",
+			"TLS_RSA_WITH_AES_256_CBC_SHA",
+		},
+		"curvePreferences": []string{
+			"X25519",
+			"CurveP256",
+			"CurveP384",
+			"CurveP521",
+		},
+	}
+}
+
+// TLSClientProfile describes what a class of clients offers in its handshake.
+// The data is a simplified snapshot of public handshake simulations and only
+// covers the settings MM controls (protocol floor, cipher suites and curves).
+type TLSClientProfile struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Category     string   `json:"category"`
+	MaxVersion   string   `json:"max_version"`
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+	Curves       []string `json:"curves,omitempty"`
+}
+
+// TLS client profile categories
+const (
+	TLSClientModern     = "modern"
+	TLSClientMobile     = "legacy-mobile"
+	TLSClientDesktop    = "legacy-desktop"
+	TLSClientSmartTV    = "smart-tv"
+	TLSClientMonitoring = "monitoring"
+)
+
+// TLS 1.3 clients negotiate their own suites, so only the groups matter
+var tls13Curves = []string{"X25519", "CurveP256", "CurveP384"}
+
+// TLSClientProfiles lists the client profiles the compatibility report checks
+func TLSClientProfiles() []TLSClientProfile {
+	return []TLSClientProfile{
+		{ID: "chrome-current", Name: "Chrome / Edge (current)", Category: TLSClientModern, MaxVersion: "VersionTLS13", Curves: tls13Curves},
+		{ID: "firefox-current", Name: "Firefox (current)", Category: TLSClientModern, MaxVersion: "VersionTLS13", Curves: tls13Curves},
+		{ID: "safari-current", Name: "Safari / iOS 13+", Category: TLSClientModern, MaxVersion: "VersionTLS13", Curves: tls13Curves},
+		{
+			ID: "android-4.0", Name: "Android 4.0-4.3", Category: TLSClientMobile, MaxVersion: "VersionTLS10",
+			CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA", "TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_CBC_SHA"},
+			Curves:       []string{"CurveP256", "CurveP384", "CurveP521"},
+		},
+		{
+			ID: "android-4.4", Name: "Android 4.4", Category: TLSClientMobile, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+				"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+				"TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_CBC_SHA",
+			},
+			Curves: []string{"CurveP256", "CurveP384", "CurveP521"},
+		},
+		{
+			ID: "android-6", Name: "Android 5-6", Category: TLSClientMobile, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+				"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+				"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA",
+			},
+			Curves: []string{"CurveP256", "CurveP384"},
+		},
+		{
+			ID: "ios-8", Name: "Safari 8 / iOS 8", Category: TLSClientMobile, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+				"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+				"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+				"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_CBC_SHA",
+			},
+			Curves: []string{"CurveP256", "CurveP384", "CurveP521"},
+		},
+		{
+			ID: "ie11-win7", Name: "IE 11 / Windows 7", Category: TLSClientDesktop, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+				"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+				"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+				"TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_256_GCM_SHA384", "TLS_RSA_WITH_AES_128_CBC_SHA",
+			},
+			Curves: []string{"CurveP256", "CurveP384"},
+		},
+		{
+			ID: "ie8-winxp", Name: "IE 8 / Windows XP", Category: TLSClientDesktop, MaxVersion: "VersionTLS10",
+			CipherSuites: []string{"TLS_RSA_WITH_AES_128_CBC_SHA"},
+		},
+		{
+			ID: "smart-tv-2016", Name: "Smart TV / streaming stick (2015-2018 firmware)", Category: TLSClientSmartTV, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{
+				"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+				"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_CBC_SHA",
+			},
+			Curves: []string{"CurveP256"},
+		},
+		{
+			ID: "embedded-p256", Name: "Embedded TLS stacks (mbedTLS, older firmware)", Category: TLSClientSmartTV, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			Curves:       []string{"CurveP256"},
+		},
+		{
+			ID: "java-8", Name: "Java 8 (monitoring agents, JVM tools)", Category: TLSClientMonitoring, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+				"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+				"TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA",
+			},
+			Curves: []string{"CurveP256", "CurveP384", "CurveP521"},
+		},
+		{
+			ID: "java-7", Name: "Java 7 (legacy monitoring agents)", Category: TLSClientMonitoring, MaxVersion: "VersionTLS10",
+			CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_128_CBC_SHA"},
+			Curves:       []string{"CurveP256", "CurveP384", "CurveP521"},
+		},
+		{
+			ID: "openssl-1.0.1", Name: "curl / OpenSSL 1.0.1 (older Linux hosts)", Category: TLSClientMonitoring, MaxVersion: "VersionTLS12",
+			CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+				"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+				"TLS_RSA_WITH_AES_256_GCM_SHA384", "TLS_RSA_WITH_AES_128_CBC_SHA",
+			},
+			Curves: []string{"CurveP256", "CurveP384", "CurveP521"},
+		},
+		{ID: "go-current", Name: "Go clients (Prometheus, blackbox exporter, Uptime Kuma)", Category: TLSClientMonitoring, MaxVersion: "VersionTLS13", Curves: tls13Curves},
+	}
+}
+
+// TLSCompatResult is the simulated handshake of one client profile
+type TLSCompatResult struct {
+	Client      TLSClientProfile `json:"client"`
+	Compatible  bool             `json:"compatible"`
+	Protocol    string           `json:"protocol,omitempty"`
+	CipherSuite string           `json:"cipher_suite,omitempty"`
+	Curve       string           `json:"curve,omitempty"`
+	Reason      string           `json:"reason,omitempty"`
+}
+
+// TLSCompatReport summarizes which clients a TLS profile would lock out
+type TLSCompatReport struct {
+	Profile       string            `json:"profile"`
+	OptionsName   string            `json:"options_name"`
+	KeyType       string            `json:"key_type"`
+	Compatible    int               `json:"compatible"`
+	Incompatible  int               `json:"incompatible"`
+	Results       []TLSCompatResult `json:"results"`
+	Warnings      []string          `json:"warnings"`
+	CompatProfile *TLSCompatReport  `json:"compat_profile,omitempty"`
+}
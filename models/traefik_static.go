@@ -0,0 +1,46 @@
+package models
+
+// Static config lint severities
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+// StaticConfigLintFinding is one problem found in the Traefik static config
+type StaticConfigLintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	// Path is the dotted key the finding refers to, e.g. providers.http.pollInterval
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// StaticConfigLintReport is the result of linting a Traefik static config.
+// Parsed is false when the YAML could not be read; the parse error is then the
+// only finding.
+type StaticConfigLintReport struct {
+	Path     string                    `json:"path,omitempty"`
+	Parsed   bool                      `json:"parsed"`
+	Errors   int                       `json:"errors"`
+	Warnings int                       `json:"warnings"`
+	Findings []StaticConfigLintFinding `json:"findings"`
+}
+
+// LintStaticConfigRequest lints unsaved static config content instead of the
+// file on disk
+type LintStaticConfigRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// Add appends a finding and updates the severity counts
+func (r *StaticConfigLintReport) Add(finding StaticConfigLintFinding) {
+	switch finding.Severity {
+	case LintSeverityError:
+		r.Errors++
+	case LintSeverityWarning:
+		r.Warnings++
+	}
+	r.Findings = append(r.Findings, finding)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxUpstreamMiddlewareRemovals caps how many upstream middlewares a resource
+// can remove or replace
+const MaxUpstreamMiddlewareRemovals = 20
+
+// UpstreamMiddlewareRemoval drops a middleware the upstream (e.g. Pangolin)
+// attached to a resource's router during the merge, or swaps it for
+// ReplaceWith at the same position
+type UpstreamMiddlewareRemoval struct {
+	Name        string `json:"name"`
+	ReplaceWith string `json:"replace_with,omitempty"`
+}
+
+// UpdateUpstreamMiddlewaresRequest replaces the upstream middleware removals
+// of a resource
+type UpdateUpstreamMiddlewaresRequest struct {
+	Removals []UpstreamMiddlewareRemoval `json:"removals"`
+}
+
+// NormalizeUpstreamMiddlewareRemovals trims and validates removals; a name
+// may only be listed once
+func NormalizeUpstreamMiddlewareRemovals(removals []UpstreamMiddlewareRemoval) ([]UpstreamMiddlewareRemoval, error) {
+	if len(removals) > MaxUpstreamMiddlewareRemovals {
+		return nil, fmt.Errorf("at most %d upstream middlewares can be removed", MaxUpstreamMiddlewareRemovals)
+	}
+	normalized := make([]UpstreamMiddlewareRemoval, 0, len(removals))
+	seen := make(map[string]bool, len(removals))
+	for _, removal := range removals {
+		removal.Name = strings.TrimSpace(removal.Name)
+		removal.ReplaceWith = strings.TrimSpace(removal.ReplaceWith)
+		if removal.Name == "" {
+			return nil, fmt.Errorf("upstream middleware name is required")
+		}
+		if removal.ReplaceWith == removal.Name {
+			return nil, fmt.Errorf("%s cannot be replaced with itself", removal.Name)
+		}
+		if seen[removal.Name] {
+			return nil, fmt.Errorf("%s is listed more than once", removal.Name)
+		}
+		seen[removal.Name] = true
+		normalized = append(normalized, removal)
+	}
+	return normalized, nil
+}
+
+// UpstreamRemovalWarnings flags removals that take protection off a resource,
+// such as Pangolin's badger authentication middleware
+func UpstreamRemovalWarnings(removals []UpstreamMiddlewareRemoval) []string {
+	warnings := []string{}
+	for _, removal := range removals {
+		if removal.ReplaceWith == "" && strings.Contains(strings.ToLower(removal.Name), "badger") {
+			warnings = append(warnings, fmt.Sprintf(
+				"removing %s turns off Pangolin authentication for this resource; assign another auth middleware or replace it", removal.Name))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestNormalizeUpstreamMiddlewareRemovals(t *testing.T) {
+	removals, err := NormalizeUpstreamMiddlewareRemovals([]UpstreamMiddlewareRemoval{
+		{Name: " badger@http "},
+		{Name: "ratelimit@file", ReplaceWith: " res-1-ratelimit "},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeUpstreamMiddlewareRemovals: %v", err)
+	}
+	if removals[0].Name != "badger@http" || removals[1].ReplaceWith != "res-1-ratelimit" {
+		t.Errorf("unexpected removals %+v", removals)
+	}
+
+	for _, invalid := range [][]UpstreamMiddlewareRemoval{
+		{{Name: " "}},
+		{{Name: "badger@http", ReplaceWith: "badger@http"}},
+		{{Name: "badger@http"}, {Name: "badger@http", ReplaceWith: "auth"}},
+	} {
+		if _, err := NormalizeUpstreamMiddlewareRemovals(invalid); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestUpstreamRemovalWarnings(t *testing.T) {
+	warnings := UpstreamRemovalWarnings([]UpstreamMiddlewareRemoval{
+		{Name: "badger@http"},
+		{Name: "Badger-2@http", ReplaceWith: "auth"},
+		{Name: "ratelimit@file"},
+	})
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %v, want one for badger@http", warnings)
+	}
+}
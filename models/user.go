@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// User roles, from least to most privileged
+const (
+	// RoleViewer may read everything but change nothing
+	RoleViewer = "viewer"
+	// RoleEditor may also manage resources, middlewares and services
+	RoleEditor = "editor"
+	// RoleAdmin may do everything, including managing users and tokens
+	RoleAdmin = "admin"
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// usernamePattern keeps usernames readable in logs and URLs
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._@-]{0,63}$`)
+
+// ValidRole reports whether role is one of the user roles
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAllows reports whether role grants at least the rights of required
+func RoleAllows(role, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+// User is an operator of the management API. Users sign in with the API
+// tokens issued to them; a token acts with its user's role.
+type User struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize lowercases the username and role and fills in the viewer default
+func (u *User) Normalize() {
+	u.Username = strings.ToLower(strings.TrimSpace(u.Username))
+	u.Role = strings.ToLower(strings.TrimSpace(u.Role))
+	if u.Role == "" {
+		u.Role = RoleViewer
+	}
+}
+
+// Validate checks the username and role
+func (u *User) Validate() error {
+	if !usernamePattern.MatchString(u.Username) {
+		return fmt.Errorf("invalid username %q: use up to 64 lowercase letters, digits and . _ @ -", u.Username)
+	}
+	if !ValidRole(u.Role) {
+		return fmt.Errorf("invalid role %q: expected %s, %s or %s", u.Role, RoleViewer, RoleEditor, RoleAdmin)
+	}
+	return nil
+}
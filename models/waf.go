@@ -0,0 +1,164 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Coraza plugin defaults. The plugin key is the name used both in the static
+// config (experimental.plugins) and in the generated plugin middleware.
+const (
+	WAFPluginKey           = "coraza"
+	DefaultWAFPluginModule = "github.com/jcchavezs/coraza-http-wasm-traefik"
+	DefaultWAFPluginVer    = "v0.3.0"
+	DefaultCRSVersion      = "4"
+)
+
+// WAFConfig is the global WAF configuration (singleton)
+type WAFConfig struct {
+	PluginModule    string    `json:"plugin_module"`
+	PluginVersion   string    `json:"plugin_version"`
+	CRSVersion      string    `json:"crs_version"`
+	DefaultParanoia int       `json:"default_paranoia"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// WAFExclusion disables CRS rules, either everywhere or only for requests
+// whose path starts with one of Paths
+type WAFExclusion struct {
+	RuleIDs []int    `json:"rule_ids"`
+	Paths   []string `json:"paths,omitempty"`
+	Comment string   `json:"comment,omitempty"`
+}
+
+// ResourceWAF holds the WAF settings of one resource. A paranoia level of 0
+// inherits the global default.
+type ResourceWAF struct {
+	ResourceID    string         `json:"resource_id"`
+	Enabled       bool           `json:"enabled"`
+	ParanoiaLevel int            `json:"paranoia_level"`
+	LogOnly       bool           `json:"log_only"`
+	Exclusions    []WAFExclusion `json:"exclusions"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// DefaultWAFConfig returns the global WAF defaults
+func DefaultWAFConfig() WAFConfig {
+	return WAFConfig{
+		PluginModule:    DefaultWAFPluginModule,
+		PluginVersion:   DefaultWAFPluginVer,
+		CRSVersion:      DefaultCRSVersion,
+		DefaultParanoia: 1,
+	}
+}
+
+// Normalize trims whitespace and strips a leading "v" from the CRS version
+func (c *WAFConfig) Normalize() {
+	c.PluginModule = strings.TrimSpace(c.PluginModule)
+	c.PluginVersion = strings.TrimSpace(c.PluginVersion)
+	c.CRSVersion = strings.TrimPrefix(strings.TrimSpace(c.CRSVersion), "v")
+}
+
+// Validate checks the global WAF configuration
+func (c *WAFConfig) Validate() error {
+	if c.PluginModule == "" {
+		return fmt.Errorf("plugin_module is required")
+	}
+	if c.PluginVersion == "" {
+		return fmt.Errorf("plugin_version is required")
+	}
+	if c.CRSVersion != "3" && c.CRSVersion != "4" {
+		return fmt.Errorf("crs_version must be 3 or 4")
+	}
+	if c.DefaultParanoia < 1 || c.DefaultParanoia > 4 {
+		return fmt.Errorf("default_paranoia must be between 1 and 4")
+	}
+	return nil
+}
+
+// Validate checks the paranoia level and exclusions of a resource
+func (w *ResourceWAF) Validate() error {
+	if w.ParanoiaLevel < 0 || w.ParanoiaLevel > 4 {
+		return fmt.Errorf("paranoia_level must be between 1 and 4, or 0 to inherit")
+	}
+	for i, ex := range w.Exclusions {
+		if len(ex.RuleIDs) == 0 {
+			return fmt.Errorf("exclusion %d: at least one rule id is required", i)
+		}
+		for _, id := range ex.RuleIDs {
+			if id <= 0 {
+				return fmt.Errorf("exclusion %d: invalid rule id %d", i, id)
+			}
+		}
+		for _, path := range ex.Paths {
+			if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, "\"' \t\r\n") {
+				return fmt.Errorf("exclusion %d: invalid path %q", i, path)
+			}
+		}
+	}
+	return nil
+}
+
+// Directives renders the Coraza SecLang directives for the resource: the
+// recommended engine config, the CRS at the effective paranoia level and the
+// rule exclusions. Path-scoped exclusions must run before the CRS rules and
+// global removals after them, so the order matters.
+func (w *ResourceWAF) Directives(cfg WAFConfig) []string {
+	paranoia := w.ParanoiaLevel
+	if paranoia == 0 {
+		paranoia = cfg.DefaultParanoia
+	}
+	// CRS 4 split the paranoia level into blocking and detection levels
+	paranoiaVar := "tx.blocking_paranoia_level"
+	if cfg.CRSVersion == "3" {
+		paranoiaVar = "tx.paranoia_level"
+	}
+	engine := "SecRuleEngine On"
+	if w.LogOnly {
+		engine = "SecRuleEngine DetectionOnly"
+	}
+
+	directives := []string{
+		"Include @coraza.conf-recommended",
+		engine,
+		"Include @crs-setup.conf.example",
+		fmt.Sprintf(`SecAction "id:900000,phase:1,pass,t:none,nolog,setvar:%s=%d"`, paranoiaVar, paranoia),
+	}
+
+	ruleID := 10000
+	var removals []string
+	for _, ex := range w.Exclusions {
+		ids := make([]string, len(ex.RuleIDs))
+		for i, id := range ex.RuleIDs {
+			ids[i] = fmt.Sprintf("%d", id)
+		}
+		if len(ex.Paths) == 0 {
+			removals = append(removals, "SecRuleRemoveById "+strings.Join(ids, " "))
+			continue
+		}
+		ctl := make([]string, len(ids))
+		for i, id := range ids {
+			ctl[i] = "ctl:ruleRemoveById=" + id
+		}
+		for _, path := range ex.Paths {
+			ruleID++
+			directives = append(directives, fmt.Sprintf(`SecRule REQUEST_FILENAME "@beginsWith %s" "id:%d,phase:1,pass,nolog,%s"`,
+				path, ruleID, strings.Join(ctl, ",")))
+		}
+	}
+
+	directives = append(directives, "Include @owasp_crs/*.conf")
+	return append(directives, removals...)
+}
+
+// MiddlewareConfig returns the Traefik plugin middleware for the resource
+func (w *ResourceWAF) MiddlewareConfig(cfg WAFConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"plugin": map[string]interface{}{
+			WAFPluginKey: map[string]interface{}{
+				"directives": w.Directives(cfg),
+			},
+		},
+	}
+}
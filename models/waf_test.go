@@ -0,0 +1,74 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResourceWAFDirectives(t *testing.T) {
+	cfg := DefaultWAFConfig()
+	settings := ResourceWAF{
+		Enabled:       true,
+		ParanoiaLevel: 2,
+		LogOnly:       true,
+		Exclusions: []WAFExclusion{
+			{RuleIDs: []int{942100}},
+			{RuleIDs: []int{920420, 921110}, Paths: []string{"/api/upload", "/webdav"}},
+		},
+	}
+
+	want := []string{
+		"Include @coraza.conf-recommended",
+		"SecRuleEngine DetectionOnly",
+		"Include @crs-setup.conf.example",
+		`SecAction "id:900000,phase:1,pass,t:none,nolog,setvar:tx.blocking_paranoia_level=2"`,
+		`SecRule REQUEST_FILENAME "@beginsWith /api/upload" "id:10001,phase:1,pass,nolog,ctl:ruleRemoveById=920420,ctl:ruleRemoveById=921110"`,
+		`SecRule REQUEST_FILENAME "@beginsWith /webdav" "id:10002,phase:1,pass,nolog,ctl:ruleRemoveById=920420,ctl:ruleRemoveById=921110"`,
+		"Include @owasp_crs/*.conf",
+		"SecRuleRemoveById 942100",
+	}
+	if got := settings.Directives(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Directives() =\n%v\nwant\n%v", got, want)
+	}
+
+	// Paranoia 0 inherits the default; CRS 3 uses the older variable name
+	cfg.CRSVersion = "3"
+	cfg.DefaultParanoia = 3
+	got := (&ResourceWAF{Enabled: true}).Directives(cfg)
+	if got[1] != "SecRuleEngine On" {
+		t.Errorf("expected blocking mode, got %q", got[1])
+	}
+	if got[3] != `SecAction "id:900000,phase:1,pass,t:none,nolog,setvar:tx.paranoia_level=3"` {
+		t.Errorf("unexpected paranoia directive %q", got[3])
+	}
+}
+
+func TestResourceWAFValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings ResourceWAF
+		wantErr  bool
+	}{
+		{"defaults", ResourceWAF{}, false},
+		{"paranoia too high", ResourceWAF{ParanoiaLevel: 5}, true},
+		{"exclusion without rules", ResourceWAF{Exclusions: []WAFExclusion{{Paths: []string{"/a"}}}}, true},
+		{"relative path", ResourceWAF{Exclusions: []WAFExclusion{{RuleIDs: []int{1}, Paths: []string{"api"}}}}, true},
+		{"path injection", ResourceWAF{Exclusions: []WAFExclusion{{RuleIDs: []int{1}, Paths: []string{`/a" "id:1`}}}}, true},
+		{"valid exclusion", ResourceWAF{ParanoiaLevel: 4, Exclusions: []WAFExclusion{{RuleIDs: []int{942100}, Paths: []string{"/api"}}}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.settings.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	cfg := WAFConfig{PluginModule: " github.com/x/y ", PluginVersion: "v1.0.0", CRSVersion: "v4", DefaultParanoia: 1}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil || cfg.CRSVersion != "4" || cfg.PluginModule != "github.com/x/y" {
+		t.Errorf("unexpected normalized config %+v (err %v)", cfg, err)
+	}
+	cfg.CRSVersion = "5"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("expected an error for CRS version 5")
+	}
+}
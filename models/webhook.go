@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook events
+const (
+	// EventResourceDiscovered is sent when the resource watcher creates a resource
+	EventResourceDiscovered = "resource.discovered"
+	// EventResourceDisabled is sent when a resource disappears from the data source
+	EventResourceDisabled = "resource.disabled"
+	// EventMiddlewareAssigned is sent when a middleware is assigned to a resource
+	EventMiddlewareAssigned = "middleware.assigned"
+	// EventMiddlewareRemoved is sent when a middleware is removed from a resource
+	EventMiddlewareRemoved = "middleware.removed"
+	// EventConfigFailed is sent when generating or merging the Traefik config fails
+	EventConfigFailed = "config.generation_failed"
+	// EventWebhookTest is sent by the test endpoint, whatever the event filter
+	EventWebhookTest = "webhook.test"
+)
+
+// WebhookEvents lists the events a webhook can subscribe to
+var WebhookEvents = []string{
+	EventResourceDiscovered,
+	EventResourceDisabled,
+	EventMiddlewareAssigned,
+	EventMiddlewareRemoved,
+	EventConfigFailed,
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook posts events to an external URL. Deliveries are signed like the
+// config webhook when Secret is set.
+type Webhook struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	// Events filters the events sent; empty sends every event
+	Events    []string  `json:"events"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize trims the fields and drops duplicate events
+func (w *Webhook) Normalize() {
+	w.Name = strings.TrimSpace(w.Name)
+	w.URL = strings.TrimSpace(w.URL)
+	seen := map[string]bool{}
+	events := []string{}
+	for _, event := range w.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event != "" && !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	w.Events = events
+}
+
+// Validate checks the name, URL and events
+func (w *Webhook) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q: expected an http or https URL", w.URL)
+	}
+	for _, event := range w.Events {
+		if !validWebhookEvent(event) {
+			return fmt.Errorf("unknown event %q: expected one of %s", event, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// Wants reports whether the webhook subscribes to event
+func (w *Webhook) Wants(event string) bool {
+	if len(w.Events) == 0 || event == EventWebhookTest {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecret replaces the secret for display; sending the mask back on
+// update keeps the stored secret
+func (w *Webhook) MaskSecret() {
+	if w.Secret != "" {
+		w.Secret = MaskedSecret
+	}
+}
+
+func validWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event posted, or to be posted, to a webhook
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      string     `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrBundleDecrypt is returned when an encrypted backup cannot be opened,
+// because the passphrase is wrong or the backup was modified or truncated
+var ErrBundleDecrypt = errors.New("cannot decrypt bundle: wrong passphrase or corrupted data")
+
+// DecryptBackup opens a backup Middleware Manager encrypted with
+// BACKUP_ENCRYPTION_PASSPHRASE (objects ending in ".enc"), e.g. to inspect a
+// snapshot offline. Data that is not encrypted is returned unchanged.
+func DecryptBackup(passphrase string, data []byte) ([]byte, error) {
+	const (
+		magic      = "MMENC"
+		saltSize   = 16
+		nonceSize  = 12
+		headerSize = len(magic) + 1 + 4 + saltSize + nonceSize
+	)
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return data, nil
+	}
+	if len(data) < headerSize || data[len(magic)] != 1 {
+		return nil, ErrBundleDecrypt
+	}
+	iterations := binary.BigEndian.Uint32(data[len(magic)+1:])
+	if iterations == 0 || iterations > 10000000 {
+		return nil, ErrBundleDecrypt
+	}
+	header := data[:headerSize]
+	salt := header[len(magic)+5 : len(magic)+5+saltSize]
+	nonce := header[len(magic)+5+saltSize:]
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, int(iterations), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrBundleDecrypt
+	}
+	return plaintext, nil
+}
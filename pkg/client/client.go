@@ -0,0 +1,390 @@
+// Package client is a Go client for the Middleware Manager HTTP API.
+//
+// Every endpoint served under /api has a typed method. Requests that fail with
+// a transport error or a retryable status (429, 502, 503, 504) are retried with
+// exponential backoff; POST requests carry an Idempotency-Key that is reused
+// across attempts, so a retried create cannot run twice.
+//
+//	c, err := client.New("http://localhost:3456")
+//	if err != nil {
+//		return err
+//	}
+//	resources, err := c.ListResources(ctx, nil)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// idempotencyKeyHeader matches the header the API's replay cache reads
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values
+	// below 1 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry; it doubles per attempt
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, including Retry-After hints
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy retries up to three times over roughly two seconds
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  250 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+// Client talks to one Middleware Manager instance. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retry      RetryPolicy
+	headers    http.Header
+	userAgent  string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.httpClient = hc
+		}
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithHeader adds a header to every request, e.g. Authorization when the API
+// sits behind an authenticating proxy
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithToken authenticates every request with an API token created under
+// /api/tokens
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.headers.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithUserAgent sets the User-Agent sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client for the instance at baseURL, e.g. http://localhost:3456
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(strings.TrimSpace(baseURL), "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry:      DefaultRetryPolicy,
+		headers:    http.Header{},
+		userAgent:  "middleware-manager-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is an error response from the API. Branch on Code rather than Message,
+// which may be reworded or translated.
+type Error struct {
+	Status  int          `json:"status"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Field   string       `json:"field,omitempty"`
+	Hint    string       `json:"hint,omitempty"`
+	Details string       `json:"details,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+	// Current holds the entity's current state on a version conflict
+	Current json.RawMessage `json:"current,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("middleware-manager: %d", e.Status)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Field != "" {
+		msg += " (field " + e.Field + ")"
+	}
+	return msg
+}
+
+// StatusCode returns the HTTP status of an API error, or 0 for other errors
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Status
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsConflict reports whether err is a 409 from the API, including version conflicts
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+// request describes one API call
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	header http.Header
+	// once sends the request a single time: the API does not replay requests
+	// that return a secret, so a retry could create a second credential
+	once bool
+}
+
+// do sends the request, retrying per the client's policy, and decodes a
+// successful JSON response into out (which may be nil)
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	data, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw = data
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// send performs the request with retries and returns the response body
+func (c *Client) send(ctx context.Context, req request) ([]byte, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		payload, err = json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+	}
+
+	target := *c.baseURL
+	target.Path = c.baseURL.Path + req.path
+	if len(req.query) > 0 {
+		target.RawQuery = req.query.Encode()
+	}
+
+	// One key for all attempts so the server replays instead of re-running
+	var idempotencyKey string
+	if req.method == http.MethodPost {
+		idempotencyKey = uuid.NewString()
+	}
+
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 || req.once {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return nil, err
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for key, values := range c.headers {
+			httpReq.Header[key] = append([]string(nil), values...)
+		}
+		for key, values := range req.header {
+			httpReq.Header[key] = append([]string(nil), values...)
+		}
+		httpReq.Header.Set("Accept", "application/json")
+		if c.userAgent != "" {
+			httpReq.Header.Set("User-Agent", c.userAgent)
+		}
+		if payload != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		if idempotencyKey != "" {
+			httpReq.Header.Set(idempotencyKeyHeader, idempotencyKey)
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("%s %s: %w", req.method, req.path, err)
+			continue
+		}
+
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			lastErr = fmt.Errorf("%s %s: failed to read response: %w", req.method, req.path, readErr)
+			continue
+		}
+
+		if resp.StatusCode < 300 {
+			return body, nil
+		}
+
+		apiErr := decodeError(resp, body)
+		if !retryableStatus(resp.StatusCode) {
+			return nil, apiErr
+		}
+		lastErr = &retryAfterError{err: apiErr, after: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	var retryErr *retryAfterError
+	if errors.As(lastErr, &retryErr) {
+		return nil, retryErr.err
+	}
+	return nil, lastErr
+}
+
+// backoff returns the delay before the given retry: exponential with jitter,
+// or the server's Retry-After hint when it sent one
+func (c *Client) backoff(attempt int, lastErr error) time.Duration {
+	var retryErr *retryAfterError
+	if errors.As(lastErr, &retryErr) && retryErr.after > 0 {
+		return minDuration(retryErr.after, c.retry.MaxBackoff)
+	}
+	delay := c.retry.MinBackoff << (attempt - 1)
+	if delay <= 0 || (c.retry.MaxBackoff > 0 && delay > c.retry.MaxBackoff) {
+		delay = c.retry.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Up to 20% jitter keeps many clients from retrying in lockstep
+	return delay - time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// retryAfterError carries a retryable API error and the server's Retry-After hint
+type retryAfterError struct {
+	err   *Error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// decodeError turns an error response into an *Error, falling back to the
+// status text for bodies that are not API errors (e.g. from a proxy)
+func decodeError(resp *http.Response, body []byte) *Error {
+	apiErr := &Error{}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr = &Error{Message: strings.TrimSpace(string(body))}
+		if apiErr.Message == "" || len(apiErr.Message) > 512 {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	apiErr.Status = resp.StatusCode
+	return apiErr
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if b > 0 && b < a {
+		return b
+	}
+	return a
+}
+
+// escape encodes a path segment
+func escape(segment string) string {
+	return url.PathEscape(segment)
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/services"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestNewRejectsInvalidURL(t *testing.T) {
+	for _, bad := range []string{"", "localhost:3456", "ftp://example.com"} {
+		if _, err := New(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRetriesUnavailableThenSucceeds(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Middleware{ID: "mw-1", Name: "auth"})
+	})
+
+	mw, err := c.GetMiddleware(context.Background(), "mw-1")
+	if err != nil {
+		t.Fatalf("GetMiddleware() error = %v", err)
+	}
+	if mw.Name != "auth" || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("got %+v after %d calls", mw, calls)
+	}
+}
+
+func TestPostReusesIdempotencyKeyAcrossRetries(t *testing.T) {
+	var keys []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(Middleware{ID: "new"})
+	})
+
+	if _, err := c.CreateMiddleware(context.Background(), MiddlewareInput{Name: "n", Type: "headers", Config: map[string]interface{}{}}); err != nil {
+		t.Fatalf("CreateMiddleware() error = %v", err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected one key reused across attempts, got %q", keys)
+	}
+}
+
+func TestSecretRequestsAreNotRetried(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if _, err := c.CreateAPIToken(context.Background(), models.APITokenRequest{Name: "ci"}); StatusCode(err) != http.StatusBadGateway {
+		t.Fatalf("expected the 502 to be returned, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestAPIErrorIsDecodedAndNotRetried(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if got := r.Header.Get("If-Match"); got != `"3"` {
+			t.Errorf("If-Match = %q, want %q", got, `"3"`)
+		}
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"status":409,"code":"version_conflict","message":"Resource was changed by someone else","current":{"id":"r1"}}`))
+	})
+
+	_, err := c.UpdateRouterPriority(context.Background(), "r1", 3, 10)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if apiErr.Code != "version_conflict" || !IsConflict(err) || len(apiErr.Current) == 0 {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+	if calls != 1 {
+		t.Errorf("expected no retries for a 409, got %d calls", calls)
+	}
+}
+
+func TestNonJSONErrorFallsBackToBody(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream exploded", http.StatusInternalServerError)
+	})
+
+	err := c.Health(context.Background())
+	if StatusCode(err) != http.StatusInternalServerError {
+		t.Fatalf("expected a 500 error, got %v", err)
+	}
+	if apiErr := err.(*Error); apiErr.Message != "upstream exploded" {
+		t.Errorf("message = %q", apiErr.Message)
+	}
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	err := c.Health(context.Background())
+	if StatusCode(err) != http.StatusTooManyRequests {
+		t.Fatalf("expected the last 429 to be returned, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestContextCancelStopsRetries(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c.retry = RetryPolicy{MaxAttempts: 5, MinBackoff: time.Second, MaxBackoff: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Health(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestQueryAndPathEncoding(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/traefik-config/changes":
+			if r.URL.Query().Get("since") != "7" {
+				t.Errorf("since = %q", r.URL.Query().Get("since"))
+			}
+			_, _ = w.Write([]byte(`{"revision":9,"since":7,"routers":{"added":{},"modified":{},"removed":["a"]},"middlewares":{"added":{},"modified":{},"removed":[]}}`))
+		case "/api/resources":
+			if r.URL.Query().Get("status") != "all" || r.URL.Query().Get("page") != "" {
+				t.Errorf("unexpected query %q", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`[{"id":"r1","host":"app.example.com","tcp_enabled":true}]`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.EscapedPath())
+		}
+	})
+
+	changes, err := c.GetTraefikConfigChanges(context.Background(), 7)
+	if err != nil || changes.Revision != 9 || len(changes.Routers.Removed) != 1 {
+		t.Errorf("GetTraefikConfigChanges() = %+v, %v", changes, err)
+	}
+
+	resources, err := c.ListResources(context.Background(), &ResourceListOptions{Status: "all"})
+	if err != nil || len(resources) != 1 || !resources[0].TCPEnabled {
+		t.Errorf("ListResources() = %+v, %v", resources, err)
+	}
+}
+
+func TestVerifyConfigWebhook(t *testing.T) {
+	body := []byte(`{"event":"config.changed","revision":3}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	// Signature computed as the server does: HMAC-SHA256 of "<timestamp>.<body>"
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	header := http.Header{}
+	header.Set("X-MM-Timestamp", timestamp)
+	header.Set("X-MM-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	if !VerifyConfigWebhook("s3cret", header, body, time.Minute) {
+		t.Error("expected a valid signature to verify")
+	}
+	if VerifyConfigWebhook("other", header, body, time.Minute) {
+		t.Error("expected a wrong secret to fail")
+	}
+	if VerifyConfigWebhook("s3cret", header, []byte(`{"revision":4}`), time.Minute) {
+		t.Error("expected a modified body to fail")
+	}
+
+	header.Set("X-MM-Timestamp", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	if VerifyConfigWebhook("s3cret", header, body, time.Minute) {
+		t.Error("expected a stale timestamp to fail")
+	}
+}
+
+func TestDecryptBackup(t *testing.T) {
+	sealed, err := services.EncryptBundle("passphrase", []byte("snapshot"))
+	if err != nil {
+		t.Fatalf("EncryptBundle: %v", err)
+	}
+	if plain, err := DecryptBackup("passphrase", sealed); err != nil || string(plain) != "snapshot" {
+		t.Fatalf("DecryptBackup = %q, %v", plain, err)
+	}
+	if _, err := DecryptBackup("wrong", sealed); !errors.Is(err, ErrBundleDecrypt) {
+		t.Errorf("expected ErrBundleDecrypt, got %v", err)
+	}
+	if plain, err := DecryptBackup("passphrase", []byte("plain")); err != nil || string(plain) != "plain" {
+		t.Errorf("unencrypted data should pass through, got %q, %v", plain, err)
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// ListMiddlewares returns all middlewares
+func (c *Client) ListMiddlewares(ctx context.Context) ([]Middleware, error) {
+	var out []Middleware
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/middlewares"}, &out)
+	return out, err
+}
+
+// ListMiddlewaresPage returns one page of middlewares
+func (c *Client) ListMiddlewaresPage(ctx context.Context, opts ListOptions) (*Page[Middleware], error) {
+	if opts.Page == 0 {
+		opts.Page = 1
+	}
+	out := &Page[Middleware]{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/middlewares", query: opts.values()}, out)
+	return out, err
+}
+
+// GetMiddleware returns one middleware
+func (c *Client) GetMiddleware(ctx context.Context, id string) (*Middleware, error) {
+	out := &Middleware{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/middlewares/" + escape(id)}, out)
+	return out, err
+}
+
+// CreateMiddleware creates a middleware and returns it with its new ID
+func (c *Client) CreateMiddleware(ctx context.Context, input MiddlewareInput) (*Middleware, error) {
+	out := &Middleware{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/middlewares", body: input, query: forceQuery(input.Force)}, out)
+	return out, err
+}
+
+// UpdateMiddleware replaces a middleware. Set input.Version to the version last
+// read to fail with a conflict instead of overwriting someone else's change.
+func (c *Client) UpdateMiddleware(ctx context.Context, id string, input MiddlewareInput) (*Middleware, error) {
+	out := &Middleware{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/middlewares/" + escape(id), body: input,
+		query: forceQuery(input.Force)}, out)
+	return out, err
+}
+
+// ValidateMiddleware checks a middleware config against the Traefik schema of
+// its type without saving it
+func (c *Client) ValidateMiddleware(ctx context.Context, typ string, config map[string]interface{}) (*models.MiddlewareValidation, error) {
+	out := &models.MiddlewareValidation{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/middlewares/validate", body: struct {
+		Type   string                 `json:"type"`
+		Config map[string]interface{} `json:"config"`
+	}{typ, config}}, out)
+	return out, err
+}
+
+func forceQuery(force bool) url.Values {
+	if !force {
+		return nil
+	}
+	return url.Values{"force": {"true"}}
+}
+
+// DeleteMiddleware deletes a middleware
+func (c *Client) DeleteMiddleware(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/middlewares/" + escape(id)}, nil)
+}
+
+// UpdateMiddlewareMetadata sets the notes, owner and contact of a middleware
+func (c *Client) UpdateMiddlewareMetadata(ctx context.Context, id string, meta models.OwnershipMetadata) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/middlewares/" + escape(id) + "/metadata", body: meta}, &out)
+	return out, err
+}
+
+// ConvertMiddlewares converts middlewares to another type, e.g. plugin
+// middlewares to their built-in equivalent. It is a dry run unless DryRun is false.
+func (c *Client) ConvertMiddlewares(ctx context.Context, input ConvertMiddlewaresRequest) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/middlewares/convert", body: input}, &out)
+	return out, err
+}
+
+// ListEntrypointMiddlewares returns the middlewares attached to entrypoints
+func (c *Client) ListEntrypointMiddlewares(ctx context.Context) ([]models.EntrypointMiddleware, error) {
+	var out []models.EntrypointMiddleware
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/entrypoints/middlewares"}, &out)
+	return out, err
+}
+
+// AssignEntrypointMiddleware attaches a middleware to every HTTP router on an
+// entrypoint; assignment.ExpiresAt is ignored
+func (c *Client) AssignEntrypointMiddleware(ctx context.Context, entryPoint string, assignment MiddlewareAssignment) (*models.EntrypointMiddleware, error) {
+	out := &models.EntrypointMiddleware{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/entrypoints/" + escape(entryPoint) + "/middlewares", body: assignment}, out)
+	return out, err
+}
+
+// RemoveEntrypointMiddleware detaches a middleware from an entrypoint
+func (c *Client) RemoveEntrypointMiddleware(ctx context.Context, entryPoint, middlewareID string) error {
+	return c.do(ctx, request{method: http.MethodDelete,
+		path: "/api/entrypoints/" + escape(entryPoint) + "/middlewares/" + escape(middlewareID)}, nil)
+}
+
+// GetDefaultChain returns the global default middleware chain
+func (c *Client) GetDefaultChain(ctx context.Context) (*DefaultChain, error) {
+	out := &DefaultChain{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/default-chain"}, out)
+	return out, err
+}
+
+// UpdateDefaultChain replaces the middlewares of the global default chain; an
+// empty list turns it off. Priority and MiddlewareID are used, MiddlewareName is ignored
+func (c *Client) UpdateDefaultChain(ctx context.Context, middlewares []models.DefaultChainMiddleware) (*DefaultChain, error) {
+	out := &DefaultChain{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/default-chain", body: struct {
+		Middlewares []models.DefaultChainMiddleware `json:"middlewares"`
+	}{middlewares}}, out)
+	return out, err
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// GetMTLSConfig returns the global mTLS settings, without the CA certificate
+func (c *Client) GetMTLSConfig(ctx context.Context) (*models.MTLSConfigResponse, error) {
+	out := &models.MTLSConfigResponse{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/config"}, out)
+	return out, err
+}
+
+// SetMTLS enables or disables mTLS globally
+func (c *Client) SetMTLS(ctx context.Context, enabled bool) (Object, error) {
+	path := "/api/mtls/disable"
+	if enabled {
+		path = "/api/mtls/enable"
+	}
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: path}, &out)
+	return out, err
+}
+
+// CreateCA generates the certificate authority client certificates are signed with
+func (c *Client) CreateCA(ctx context.Context, input models.CreateCARequest) (*models.MTLSConfig, error) {
+	out := &models.MTLSConfig{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/mtls/ca", body: input}, out)
+	return out, err
+}
+
+// DeleteCA deletes the CA and every client certificate
+func (c *Client) DeleteCA(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/mtls/ca"}, nil)
+}
+
+// UpdateCertsBasePath sets where Traefik reads the CA and CRL from
+func (c *Client) UpdateCertsBasePath(ctx context.Context, path string) (Object, error) {
+	var out Object
+	body := struct {
+		CertsBasePath string `json:"certs_base_path"`
+	}{path}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/mtls/config/path", body: body}, &out)
+	return out, err
+}
+
+// ListMTLSClients returns all client certificates
+func (c *Client) ListMTLSClients(ctx context.Context) ([]models.MTLSClient, error) {
+	var out []models.MTLSClient
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/clients"}, &out)
+	return out, err
+}
+
+// GetMTLSClient returns one client certificate
+func (c *Client) GetMTLSClient(ctx context.Context, id string) (*models.MTLSClient, error) {
+	out := &models.MTLSClient{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/clients/" + escape(id)}, out)
+	return out, err
+}
+
+// CreateMTLSClient issues a client certificate
+func (c *Client) CreateMTLSClient(ctx context.Context, input models.CreateClientRequest) (*models.MTLSClient, error) {
+	out := &models.MTLSClient{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/mtls/clients", body: input}, out)
+	return out, err
+}
+
+// ImportMTLSClients imports existing client certificates; each result reports
+// whether that certificate was imported, skipped as already stored or failed
+func (c *Client) ImportMTLSClients(ctx context.Context, clients []models.MTLSClientImport) (*models.MTLSClientImportResponse, error) {
+	out := &models.MTLSClientImportResponse{}
+	body := models.ImportClientsRequest{Clients: clients}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/mtls/clients/import", body: body}, out)
+	return out, err
+}
+
+// ExportMTLSClients returns the certificates of every client that is not revoked
+func (c *Client) ExportMTLSClients(ctx context.Context) ([]models.MTLSClientCert, error) {
+	var out []models.MTLSClientCert
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/clients/export"}, &out)
+	return out, err
+}
+
+// ExportMTLSClientsPEM returns the certificates of ExportMTLSClients as one PEM file
+func (c *Client) ExportMTLSClientsPEM(ctx context.Context) ([]byte, error) {
+	req := request{method: http.MethodGet, path: "/api/mtls/clients/export", query: map[string][]string{"format": {"pem"}}}
+	var out []byte
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DownloadMTLSClientP12 returns the PKCS#12 bundle of a client certificate
+func (c *Client) DownloadMTLSClientP12(ctx context.Context, id string) ([]byte, error) {
+	var out []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/clients/" + escape(id) + "/download"}, &out)
+	return out, err
+}
+
+// RevokeMTLSClient revokes a client certificate and adds it to the CRL
+func (c *Client) RevokeMTLSClient(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodPut, path: "/api/mtls/clients/" + escape(id) + "/revoke"}, nil)
+}
+
+// DeleteMTLSClient deletes a client certificate
+func (c *Client) DeleteMTLSClient(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/mtls/clients/" + escape(id)}, nil)
+}
+
+// CheckMTLSPlugin reports whether the mTLS plugin is installed in Traefik
+func (c *Client) CheckMTLSPlugin(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/plugin/check"}, &out)
+	return out, err
+}
+
+// MTLSPreflight checks whether enabling mTLS on a resource would work
+func (c *Client) MTLSPreflight(ctx context.Context, resourceID string) (*models.MTLSPreflightResult, error) {
+	out := &models.MTLSPreflightResult{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/preflight/" + escape(resourceID)}, out)
+	return out, err
+}
+
+// GetMTLSMiddlewareConfig returns the global mTLS plugin settings
+func (c *Client) GetMTLSMiddlewareConfig(ctx context.Context) (*models.MTLSMiddlewareConfig, error) {
+	out := &models.MTLSMiddlewareConfig{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/middleware/config"}, out)
+	return out, err
+}
+
+// UpdateMTLSMiddlewareConfig replaces the global mTLS plugin settings
+func (c *Client) UpdateMTLSMiddlewareConfig(ctx context.Context, config models.MTLSMiddlewareConfig) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/mtls/middleware/config", body: config}, &out)
+	return out, err
+}
+
+// ExportMTLS returns the CA, CRL, clients and per-resource policies as a manifest
+func (c *Client) ExportMTLS(ctx context.Context) (*models.MTLSExport, error) {
+	out := &models.MTLSExport{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/export"}, out)
+	return out, err
+}
+
+// ExportMTLSPEMBundle returns the CA certificate followed by the current CRL
+func (c *Client) ExportMTLSPEMBundle(ctx context.Context) ([]byte, error) {
+	var out []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/export/bundle.pem"}, &out)
+	return out, err
+}
+
+// ExportMTLSCRL returns the current certificate revocation list as PEM
+func (c *Client) ExportMTLSCRL(ctx context.Context) ([]byte, error) {
+	var out []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mtls/export/crl.pem"}, &out)
+	return out, err
+}
+
+// SetPublicPKI enables or disables the unauthenticated /pki endpoints
+func (c *Client) SetPublicPKI(ctx context.Context, enabled bool) (Object, error) {
+	var out Object
+	body := struct {
+		Enabled bool `json:"enabled"`
+	}{enabled}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/mtls/public-pki", body: body}, &out)
+	return out, err
+}
+
+// PublicCACert returns the CA certificate from the public PKI endpoint, as PEM
+// or, with der set, DER
+func (c *Client) PublicCACert(ctx context.Context, der bool) ([]byte, error) {
+	req := request{method: http.MethodGet, path: "/pki/ca.crt"}
+	if der {
+		req.query = map[string][]string{"format": {"der"}}
+	}
+	var out []byte
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PublicPKIInstructions returns the HTML page explaining how to trust the CA
+func (c *Client) PublicPKIInstructions(ctx context.Context) ([]byte, error) {
+	var out []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: "/pki/instructions"}, &out)
+	return out, err
+}
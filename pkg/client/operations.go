@@ -0,0 +1,524 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Health checks that the server is up
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/health"}, nil)
+}
+
+// GetDiagnostics runs the server's environment self-checks
+func (c *Client) GetDiagnostics(ctx context.Context) (*models.DiagnosticsReport, error) {
+	out := &models.DiagnosticsReport{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/system/diagnostics"}, out)
+	return out, err
+}
+
+// GetRuntime returns the server's goroutine, memory, GC, database and cache
+// figures
+func (c *Client) GetRuntime(ctx context.Context) (*RuntimeStats, error) {
+	out := &RuntimeStats{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/system/runtime"}, out)
+	return out, err
+}
+
+// ReloadSettings makes the server re-read its env file, environment and
+// config file without a restart
+func (c *Client) ReloadSettings(ctx context.Context) (*ReloadReport, error) {
+	out := &ReloadReport{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/system/reload"}, out)
+	return out, err
+}
+
+// ListResourceWatcherRuns returns the latest resource watcher run summaries,
+// newest first; limit 0 uses the server default
+func (c *Client) ListResourceWatcherRuns(ctx context.Context, limit int) ([]ResourceRun, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out struct {
+		Runs []ResourceRun `json:"runs"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/system/watchers/resource/runs", query: q}, &out)
+	return out.Runs, err
+}
+
+// GetScope returns the management scope and which resources it matches
+func (c *Client) GetScope(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/scope"}, &out)
+	return out, err
+}
+
+// UpdateScope replaces the include/exclude host patterns
+func (c *Client) UpdateScope(ctx context.Context, scope models.ManagementScope) (*models.ManagementScope, error) {
+	out := &models.ManagementScope{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/scope", body: scope}, out)
+	return out, err
+}
+
+// ListProtectedNames returns the protected names of a kind (middleware or
+// service); empty returns every kind
+func (c *Client) ListProtectedNames(ctx context.Context, kind string) ([]ProtectedName, error) {
+	q := url.Values{}
+	if kind != "" {
+		q.Set("kind", kind)
+	}
+	var out []ProtectedName
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/protected", query: q}, &out)
+	return out, err
+}
+
+// AddProtectedName stops Middleware Manager from changing a named object
+func (c *Client) AddProtectedName(ctx context.Context, name ProtectedName) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/protected", body: name}, nil)
+}
+
+// RemoveProtectedName lifts the protection of a named object
+func (c *Client) RemoveProtectedName(ctx context.Context, kind, name string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/protected/" + escape(kind) + "/" + escape(name)}, nil)
+}
+
+// RunCleanup runs maintenance cleanup and returns its report. Without
+// DryRun set to false nothing is changed.
+func (c *Client) RunCleanup(ctx context.Context, input CleanupRequest) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/maintenance/cleanup", body: input}, &out)
+	return out, err
+}
+
+// ListCleanupRuns returns stored cleanup reports, newest first; limit 0 uses
+// the server default
+func (c *Client) ListCleanupRuns(ctx context.Context, limit int) (json.RawMessage, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/maintenance/cleanup/runs", query: q}, &out)
+	return out, err
+}
+
+// GetCleanupRun returns one stored cleanup report
+func (c *Client) GetCleanupRun(ctx context.Context, runID string) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/maintenance/cleanup/runs/" + escape(runID)}, &out)
+	return out, err
+}
+
+// UndoCleanupRun restores what a cleanup run deleted or changed
+func (c *Client) UndoCleanupRun(ctx context.Context, runID string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/maintenance/undo/" + escape(runID)}, &out)
+	return out, err
+}
+
+// MigrateResourceIDs moves resources keyed by their Pangolin router ID to
+// internal UUIDs and returns the old-to-new mapping; a dry run only reports it
+func (c *Client) MigrateResourceIDs(ctx context.Context, dryRun bool) (json.RawMessage, error) {
+	var out json.RawMessage
+	body := map[string]bool{"dry_run": dryRun}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/maintenance/migrate-resource-ids", body: body}, &out)
+	return out, err
+}
+
+// MigrateToPostgres copies the SQLite database into PostgreSQL and verifies
+// the copy with row counts and checksums
+func (c *Client) MigrateToPostgres(ctx context.Context, input PostgresMigrationRequest) (*PostgresMigration, error) {
+	out := &PostgresMigration{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/maintenance/migrate-postgres", body: input}, out)
+	return out, err
+}
+
+// GetRedirects returns the HTTP→HTTPS redirect settings and per-resource status
+func (c *Client) GetRedirects(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/redirects"}, &out)
+	return out, err
+}
+
+// UpdateRedirects configures the global HTTP→HTTPS redirect
+func (c *Client) UpdateRedirects(ctx context.Context, input RedirectsRequest) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/redirects", body: input}, &out)
+	return out, err
+}
+
+// UpdateEntrypointRedirect writes an entrypoint redirect to the Traefik static
+// config; Traefik must be restarted to apply it
+func (c *Client) UpdateEntrypointRedirect(ctx context.Context, input EntrypointRedirectRequest) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/redirects/entrypoint", body: input}, &out)
+	return out, err
+}
+
+// GetWAFConfig returns the global WAF settings and plugin status
+func (c *Client) GetWAFConfig(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/waf"}, &out)
+	return out, err
+}
+
+// UpdateWAFConfig replaces the global WAF settings
+func (c *Client) UpdateWAFConfig(ctx context.Context, config models.WAFConfig) (*models.WAFConfig, error) {
+	out := &models.WAFConfig{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/waf", body: config}, out)
+	return out, err
+}
+
+// InstallWAFPlugin adds the WAF plugin to the Traefik static config
+func (c *Client) InstallWAFPlugin(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/waf/install"}, &out)
+	return out, err
+}
+
+// ListCaptures returns recent traffic captures
+func (c *Client) ListCaptures(ctx context.Context) ([]models.TrafficCapture, error) {
+	var out []models.TrafficCapture
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/captures"}, &out)
+	return out, err
+}
+
+// GetCapture returns a traffic capture with the lines recorded so far
+func (c *Client) GetCapture(ctx context.Context, captureID string) (*models.TrafficCapture, error) {
+	out := &models.TrafficCapture{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/captures/" + escape(captureID)}, out)
+	return out, err
+}
+
+// StopCapture stops a running traffic capture
+func (c *Client) StopCapture(ctx context.Context, captureID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/captures/" + escape(captureID)}, nil)
+}
+
+// ListMirrors returns every resource mirror
+func (c *Client) ListMirrors(ctx context.Context) ([]models.ResourceMirror, error) {
+	var out []models.ResourceMirror
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/mirrors"}, &out)
+	return out, err
+}
+
+// ListFailovers returns every resource failover
+func (c *Client) ListFailovers(ctx context.Context) ([]models.ResourceFailover, error) {
+	var out []models.ResourceFailover
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/failovers"}, &out)
+	return out, err
+}
+
+// ListDeployments returns every blue/green deployment
+func (c *Client) ListDeployments(ctx context.Context) ([]models.ResourceDeployment, error) {
+	var out []models.ResourceDeployment
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/deployments"}, &out)
+	return out, err
+}
+
+// ListAPITokens returns the API tokens without their secrets
+func (c *Client) ListAPITokens(ctx context.Context) ([]models.APIToken, error) {
+	var out []models.APIToken
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/tokens"}, &out)
+	return out, err
+}
+
+// CreateAPIToken issues an API token; the secret is only returned here
+func (c *Client) CreateAPIToken(ctx context.Context, req models.APITokenRequest) (*models.CreatedAPIToken, error) {
+	out := &models.CreatedAPIToken{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/tokens", body: req, once: true}, out)
+	return out, err
+}
+
+// DeleteAPIToken revokes an API token
+func (c *Client) DeleteAPIToken(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/tokens/" + escape(id)}, nil)
+}
+
+// ListUsers returns the users of the management API
+func (c *Client) ListUsers(ctx context.Context) ([]models.User, error) {
+	var out []models.User
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/users"}, &out)
+	return out, err
+}
+
+// CreateUser adds a user with a viewer, editor or admin role
+func (c *Client) CreateUser(ctx context.Context, username, role string) (*models.User, error) {
+	out := &models.User{}
+	body := models.User{Username: username, Role: role}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/users", body: body}, out)
+	return out, err
+}
+
+// UpdateUserRole changes a user's role
+func (c *Client) UpdateUserRole(ctx context.Context, id, role string) (*models.User, error) {
+	out := &models.User{}
+	body := map[string]string{"role": role}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/users/" + escape(id), body: body}, out)
+	return out, err
+}
+
+// DeleteUser removes a user and revokes their API tokens
+func (c *Client) DeleteUser(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/users/" + escape(id)}, nil)
+}
+
+// ListWebhooks returns the webhooks events are posted to
+func (c *Client) ListWebhooks(ctx context.Context) (*WebhookList, error) {
+	out := &WebhookList{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/webhooks"}, out)
+	return out, err
+}
+
+// CreateWebhook adds a webhook; an empty Events sends every event
+func (c *Client) CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	out := &models.Webhook{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/webhooks", body: webhook}, out)
+	return out, err
+}
+
+// UpdateWebhook replaces a webhook's settings; a masked Secret keeps the stored one
+func (c *Client) UpdateWebhook(ctx context.Context, id string, webhook models.Webhook) (*models.Webhook, error) {
+	out := &models.Webhook{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/webhooks/" + escape(id), body: webhook}, out)
+	return out, err
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/webhooks/" + escape(id)}, nil)
+}
+
+// TestWebhook queues a webhook.test delivery and returns it
+func (c *Client) TestWebhook(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	out := &models.WebhookDelivery{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/webhooks/" + escape(id) + "/test"}, out)
+	return out, err
+}
+
+// ListWebhookDeliveries returns the latest deliveries, newest first, of one
+// webhook or, with an empty id, of every webhook; limit 0 uses the server default
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id string, limit int) ([]models.WebhookDelivery, error) {
+	path := "/api/webhooks/deliveries"
+	if id != "" {
+		path = "/api/webhooks/" + escape(id) + "/deliveries"
+	}
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out []models.WebhookDelivery
+	err := c.do(ctx, request{method: http.MethodGet, path: path, query: q}, &out)
+	return out, err
+}
+
+// ListEnvironments returns the environments with their tagged counts
+func (c *Client) ListEnvironments(ctx context.Context) ([]models.Environment, error) {
+	var out []models.Environment
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/environments"}, &out)
+	return out, err
+}
+
+// CreateEnvironment adds an environment; IsDefault serves it at the plain config endpoint
+func (c *Client) CreateEnvironment(ctx context.Context, env models.Environment) (*models.Environment, error) {
+	out := &models.Environment{}
+	body := map[string]interface{}{"name": env.Name, "description": env.Description, "is_default": env.IsDefault}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/environments", body: body}, out)
+	return out, err
+}
+
+// UpdateEnvironment replaces an environment's description and default flag
+func (c *Client) UpdateEnvironment(ctx context.Context, name, description string, isDefault bool) (*models.Environment, error) {
+	out := &models.Environment{}
+	body := map[string]interface{}{"description": description, "is_default": isDefault}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/environments/" + escape(name), body: body}, out)
+	return out, err
+}
+
+// DeleteEnvironment removes an environment and its tags
+func (c *Client) DeleteEnvironment(ctx context.Context, name string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/environments/" + escape(name)}, nil)
+}
+
+// ListEnvironmentTags returns the environments of every tagged resource and middleware
+func (c *Client) ListEnvironmentTags(ctx context.Context) ([]models.EnvironmentTags, error) {
+	var out []models.EnvironmentTags
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/environments/tags"}, &out)
+	return out, err
+}
+
+// GetResourceEnvironments returns the environments a resource is limited to
+func (c *Client) GetResourceEnvironments(ctx context.Context, id string) (*models.EnvironmentTags, error) {
+	return c.environmentTags(ctx, http.MethodGet, "/api/resources/"+escape(id)+"/environments", nil)
+}
+
+// SetResourceEnvironments limits a resource to environments; none applies it everywhere
+func (c *Client) SetResourceEnvironments(ctx context.Context, id string, version int64, environments []string) (*models.EnvironmentTags, error) {
+	if environments == nil {
+		environments = []string{}
+	}
+	out := &models.EnvironmentTags{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/resources/" + escape(id) + "/environments",
+		body: map[string][]string{"environments": environments}, header: ifMatch(version)}, out)
+	return out, err
+}
+
+// GetMiddlewareEnvironments returns the environments a middleware is limited to
+func (c *Client) GetMiddlewareEnvironments(ctx context.Context, id string) (*models.EnvironmentTags, error) {
+	return c.environmentTags(ctx, http.MethodGet, "/api/middlewares/"+escape(id)+"/environments", nil)
+}
+
+// SetMiddlewareEnvironments limits a middleware to environments; none applies it everywhere
+func (c *Client) SetMiddlewareEnvironments(ctx context.Context, id string, environments []string) (*models.EnvironmentTags, error) {
+	return c.environmentTags(ctx, http.MethodPut, "/api/middlewares/"+escape(id)+"/environments", environments)
+}
+
+func (c *Client) environmentTags(ctx context.Context, method, path string, environments []string) (*models.EnvironmentTags, error) {
+	out := &models.EnvironmentTags{}
+	req := request{method: method, path: path}
+	if method == http.MethodPut {
+		if environments == nil {
+			environments = []string{}
+		}
+		req.body = map[string][]string{"environments": environments}
+	}
+	err := c.do(ctx, req, out)
+	return out, err
+}
+
+// SearchMetadata finds resources and middlewares whose notes, owner or contact
+// contain query, optionally limited to an owner
+func (c *Client) SearchMetadata(ctx context.Context, query, owner string) (Object, error) {
+	q := url.Values{}
+	if query != "" {
+		q.Set("q", query)
+	}
+	if owner != "" {
+		q.Set("owner", owner)
+	}
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/metadata/search", query: q}, &out)
+	return out, err
+}
+
+// ListAssignmentExpirations returns recently expired temporary middleware assignments
+func (c *Client) ListAssignmentExpirations(ctx context.Context) ([]models.AssignmentExpiration, error) {
+	var out []models.AssignmentExpiration
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/assignments/expirations"}, &out)
+	return out, err
+}
+
+// ExpireAssignments removes temporary assignments that are past due now
+// instead of waiting for the next sweep
+func (c *Client) ExpireAssignments(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/assignments/expire"}, &out)
+	return out, err
+}
+
+// RotateSecret replaces a credential stored in a middleware, keeping the old
+// one valid for the grace period
+func (c *Client) RotateSecret(ctx context.Context, input SecretRotationRequest) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/secrets/rotate", body: input, once: true}, &out)
+	return out, err
+}
+
+// ListSecretRotations returns pending and completed secret rotations
+func (c *Client) ListSecretRotations(ctx context.Context) ([]Object, error) {
+	var out []Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/secrets/rotations"}, &out)
+	return out, err
+}
+
+// CompleteSecretRotation drops the old credential of a rotation before its grace period ends
+func (c *Client) CompleteSecretRotation(ctx context.Context, rotationID string) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/secrets/rotations/" + escape(rotationID) + "/complete"}, nil)
+}
+
+// GetAccessMatrix returns every exposed host with its auth mechanisms, IP
+// restrictions and TLS posture, for access reviews
+func (c *Client) GetAccessMatrix(ctx context.Context) (*AccessMatrix, error) {
+	out := &AccessMatrix{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/reports/access-matrix"}, out)
+	return out, err
+}
+
+// ExportAccessMatrixCSV returns the access matrix as CSV
+func (c *Client) ExportAccessMatrixCSV(ctx context.Context) ([]byte, error) {
+	var out []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/reports/access-matrix",
+		query: url.Values{"format": []string{"csv"}}}, &out)
+	return out, err
+}
+
+// GetInventory returns the plugins of the Traefik static config and every
+// middleware type in use with counts
+func (c *Client) GetInventory(ctx context.Context) (*Inventory, error) {
+	out := &Inventory{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/reports/inventory"}, out)
+	return out, err
+}
+
+// ListBackups returns the database snapshots and config exports in the local
+// backup directory with the state of the last run
+func (c *Client) ListBackups(ctx context.Context) (*LocalBackups, error) {
+	out := &LocalBackups{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/backups"}, out)
+	return out, err
+}
+
+// CreateBackup writes a database snapshot and a config export to the local
+// backup directory now
+func (c *Client) CreateBackup(ctx context.Context) ([]BackupObject, error) {
+	var out struct {
+		Objects []BackupObject `json:"objects"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/backup"}, &out)
+	return out.Objects, err
+}
+
+// RestoreBackup replaces the database with the local snapshot id, the ID of
+// a BackupObject under db/. An encrypted snapshot is opened with passphrase,
+// or with the server's BACKUP_ENCRYPTION_PASSPHRASE when it is empty.
+func (c *Client) RestoreBackup(ctx context.Context, id, passphrase string) error {
+	var body interface{}
+	if passphrase != "" {
+		body = map[string]string{"passphrase": passphrase}
+	}
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/backups/" + escape(id) + "/restore", body: body}, nil)
+}
+
+// ListS3Backups returns the database snapshots and config exports in the S3
+// backup bucket with the state of the last run
+func (c *Client) ListS3Backups(ctx context.Context) (*S3Backups, error) {
+	out := &S3Backups{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/backups/s3"}, out)
+	return out, err
+}
+
+// RunS3Backup uploads a database snapshot and a config export now
+func (c *Client) RunS3Backup(ctx context.Context) ([]BackupObject, error) {
+	var out struct {
+		Objects []BackupObject `json:"objects"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/backups/s3/run"}, &out)
+	return out.Objects, err
+}
+
+// RestoreS3Backup replaces the database with the snapshot stored under key.
+// An encrypted snapshot is opened with passphrase, or with the server's
+// BACKUP_ENCRYPTION_PASSPHRASE when it is empty.
+func (c *Client) RestoreS3Backup(ctx context.Context, key, passphrase string) error {
+	body := map[string]string{"key": key}
+	if passphrase != "" {
+		body["passphrase"] = passphrase
+	}
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/backups/s3/restore", body: body}, nil)
+}
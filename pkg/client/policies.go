@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// ListHeaderPolicies returns all header policies
+func (c *Client) ListHeaderPolicies(ctx context.Context) ([]models.HeaderPolicy, error) {
+	var out []models.HeaderPolicy
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/header-policies"}, &out)
+	return out, err
+}
+
+// GetHeaderPolicy returns one header policy
+func (c *Client) GetHeaderPolicy(ctx context.Context, id string) (*models.HeaderPolicy, error) {
+	out := &models.HeaderPolicy{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/header-policies/" + escape(id)}, out)
+	return out, err
+}
+
+// CreateHeaderPolicy creates a header policy
+func (c *Client) CreateHeaderPolicy(ctx context.Context, input HeaderPolicyInput) (*models.HeaderPolicy, error) {
+	out := &models.HeaderPolicy{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/header-policies", body: input}, out)
+	return out, err
+}
+
+// UpdateHeaderPolicy replaces a header policy
+func (c *Client) UpdateHeaderPolicy(ctx context.Context, id string, input HeaderPolicyInput) (*models.HeaderPolicy, error) {
+	out := &models.HeaderPolicy{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/header-policies/" + escape(id), body: input}, out)
+	return out, err
+}
+
+// DeleteHeaderPolicy deletes a header policy
+func (c *Client) DeleteHeaderPolicy(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/header-policies/" + escape(id)}, nil)
+}
+
+// ListCORSPolicies returns all CORS policies
+func (c *Client) ListCORSPolicies(ctx context.Context) ([]models.CORSPolicy, error) {
+	var out []models.CORSPolicy
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/cors-policies"}, &out)
+	return out, err
+}
+
+// GetCORSPolicy returns one CORS policy with the resources using it
+func (c *Client) GetCORSPolicy(ctx context.Context, id string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/cors-policies/" + escape(id)}, &out)
+	return out, err
+}
+
+// CreateCORSPolicy creates a CORS policy
+func (c *Client) CreateCORSPolicy(ctx context.Context, policy models.CORSPolicy) (*models.CORSPolicy, error) {
+	out := &models.CORSPolicy{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/cors-policies", body: policy}, out)
+	return out, err
+}
+
+// UpdateCORSPolicy replaces a CORS policy
+func (c *Client) UpdateCORSPolicy(ctx context.Context, id string, policy models.CORSPolicy) (*models.CORSPolicy, error) {
+	out := &models.CORSPolicy{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/cors-policies/" + escape(id), body: policy}, out)
+	return out, err
+}
+
+// DeleteCORSPolicy deletes a CORS policy
+func (c *Client) DeleteCORSPolicy(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/cors-policies/" + escape(id)}, nil)
+}
+
+// ListResourceGroups returns all resource groups
+func (c *Client) ListResourceGroups(ctx context.Context) ([]models.ResourceGroup, error) {
+	var out []models.ResourceGroup
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/resource-groups"}, &out)
+	return out, err
+}
+
+// GetResourceGroup returns a resource group with its members and policies
+func (c *Client) GetResourceGroup(ctx context.Context, id string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/resource-groups/" + escape(id)}, &out)
+	return out, err
+}
+
+// CreateResourceGroup creates a resource group
+func (c *Client) CreateResourceGroup(ctx context.Context, name, description string) (*models.ResourceGroup, error) {
+	out := &models.ResourceGroup{}
+	body := struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	}{name, description}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/resource-groups", body: body}, out)
+	return out, err
+}
+
+// DeleteResourceGroup deletes a resource group
+func (c *Client) DeleteResourceGroup(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/resource-groups/" + escape(id)}, nil)
+}
+
+// AddGroupResource adds a resource to a group
+func (c *Client) AddGroupResource(ctx context.Context, groupID, resourceID string) error {
+	body := struct {
+		ResourceID string `json:"resource_id"`
+	}{resourceID}
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/resource-groups/" + escape(groupID) + "/resources", body: body}, nil)
+}
+
+// RemoveGroupResource removes a resource from a group
+func (c *Client) RemoveGroupResource(ctx context.Context, groupID, resourceID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/resource-groups/" + escape(groupID) + "/resources/" + escape(resourceID)}, nil)
+}
+
+// AssignGroupHeaderPolicy applies a header policy to every resource in a group
+func (c *Client) AssignGroupHeaderPolicy(ctx context.Context, groupID string, assignment PolicyAssignment) (*models.HeaderPolicyAssignment, error) {
+	out := &models.HeaderPolicyAssignment{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/resource-groups/" + escape(groupID) + "/header-policies", body: assignment}, out)
+	return out, err
+}
+
+// RemoveGroupHeaderPolicy removes a header policy from a group
+func (c *Client) RemoveGroupHeaderPolicy(ctx context.Context, groupID, policyID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/resource-groups/" + escape(groupID) + "/header-policies/" + escape(policyID)}, nil)
+}
+
+// BotListWithResources is a bot list and the resources it is applied to
+type BotListWithResources struct {
+	List        models.BotList `json:"list"`
+	ResourceIDs []string       `json:"resource_ids"`
+	// Regex is the compiled User-Agent pattern; only GetBotList returns it
+	Regex string `json:"regex,omitempty"`
+}
+
+// ListBotLists returns all bot lists
+func (c *Client) ListBotLists(ctx context.Context) ([]BotListWithResources, error) {
+	var out []BotListWithResources
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/bot-lists"}, &out)
+	return out, err
+}
+
+// GetBotList returns one bot list
+func (c *Client) GetBotList(ctx context.Context, id string) (*BotListWithResources, error) {
+	out := &BotListWithResources{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/bot-lists/" + escape(id)}, out)
+	return out, err
+}
+
+// CreateBotList creates a bot list
+func (c *Client) CreateBotList(ctx context.Context, list models.BotList) (*models.BotList, error) {
+	out := &models.BotList{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/bot-lists", body: list}, out)
+	return out, err
+}
+
+// UpdateBotList replaces a bot list
+func (c *Client) UpdateBotList(ctx context.Context, id string, list models.BotList) (*models.BotList, error) {
+	out := &models.BotList{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/bot-lists/" + escape(id), body: list}, out)
+	return out, err
+}
+
+// DeleteBotList deletes a bot list
+func (c *Client) DeleteBotList(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/bot-lists/" + escape(id)}, nil)
+}
+
+// RefreshBotList downloads a bot list from its source URL again
+func (c *Client) RefreshBotList(ctx context.Context, id string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/bot-lists/" + escape(id) + "/refresh"}, &out)
+	return out, err
+}
+
+// SetBotListResources replaces the resources a bot list is applied to
+func (c *Client) SetBotListResources(ctx context.Context, id string, resourceIDs []string) (Object, error) {
+	var out Object
+	body := struct {
+		ResourceIDs []string `json:"resource_ids"`
+	}{resourceIDs}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/bot-lists/" + escape(id) + "/resources", body: body}, &out)
+	return out, err
+}
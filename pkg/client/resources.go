@@ -0,0 +1,462 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func resourcePath(id string, parts ...string) string {
+	path := "/api/resources/" + escape(id)
+	for _, part := range parts {
+		path += "/" + part
+	}
+	return path
+}
+
+func (o *ResourceListOptions) values() url.Values {
+	if o == nil {
+		return url.Values{}
+	}
+	q := o.ListOptions.values()
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	if o.SourceType != "" {
+		q.Set("source_type", o.SourceType)
+	}
+	return q
+}
+
+// ListResources returns resources matching opts; nil returns all active resources
+func (c *Client) ListResources(ctx context.Context, opts *ResourceListOptions) ([]Resource, error) {
+	q := opts.values()
+	q.Del("page")
+	q.Del("page_size")
+	var out []Resource
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/resources", query: q}, &out)
+	return out, err
+}
+
+// ListResourcesPage returns one page of resources
+func (c *Client) ListResourcesPage(ctx context.Context, opts ResourceListOptions) (*Page[Resource], error) {
+	if opts.Page == 0 {
+		opts.Page = 1
+	}
+	out := &Page[Resource]{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/resources", query: opts.values()}, out)
+	return out, err
+}
+
+// GetResource returns one resource with its full configuration
+func (c *Client) GetResource(ctx context.Context, id string) (*Resource, error) {
+	out := &Resource{}
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(id)}, out)
+	return out, err
+}
+
+// DeleteResource deletes a disabled resource
+func (c *Client) DeleteResource(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(id)}, nil)
+}
+
+// DeleteDisabledResources deletes several disabled resources at once
+func (c *Client) DeleteDisabledResources(ctx context.Context, ids []string) (Object, error) {
+	var out Object
+	body := struct {
+		IDs []string `json:"ids"`
+	}{IDs: ids}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/resources/bulk-delete-disabled", body: body}, &out)
+	return out, err
+}
+
+// PendingChanges is the review queue and the review mode settings
+type PendingChanges struct {
+	Review  models.ResourceReviewConfig `json:"review"`
+	Changes []PendingChange             `json:"changes"`
+}
+
+// ListPendingChanges returns the resource changes held back in review mode
+func (c *Client) ListPendingChanges(ctx context.Context) (*PendingChanges, error) {
+	out := &PendingChanges{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/resources/pending"}, out)
+	return out, err
+}
+
+// ApprovePendingChanges applies the queued changes with the given IDs, or
+// all of them when ids is empty, and returns how many were applied
+func (c *Client) ApprovePendingChanges(ctx context.Context, ids []string) (int, error) {
+	var out struct {
+		Applied int `json:"applied"`
+	}
+	body := struct {
+		IDs []string `json:"ids,omitempty"`
+	}{IDs: ids}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/resources/pending/approve", body: body}, &out)
+	return out.Applied, err
+}
+
+// SetResourceReview turns review mode on or off
+func (c *Client) SetResourceReview(ctx context.Context, config models.ResourceReviewConfig) error {
+	return c.do(ctx, request{method: http.MethodPut, path: "/api/resources/review", body: config}, nil)
+}
+
+// SyncResources runs the resource watcher now and returns the run summary
+func (c *Client) SyncResources(ctx context.Context) (*ResourceRun, error) {
+	out := &ResourceRun{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/resources/sync"}, out)
+	return out, err
+}
+
+// ResyncResource refreshes one resource from the data source
+func (c *Client) ResyncResource(ctx context.Context, id string) (*ResourceRun, error) {
+	out := &ResourceRun{}
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(id, "resync")}, out)
+	return out, err
+}
+
+// AssignMiddleware attaches a middleware to a resource
+func (c *Client) AssignMiddleware(ctx context.Context, resourceID string, assignment MiddlewareAssignment) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "middlewares"), body: assignment}, &out)
+	return out, err
+}
+
+// AssignMiddlewares attaches several middlewares to a resource in one request
+func (c *Client) AssignMiddlewares(ctx context.Context, resourceID string, assignments []MiddlewareAssignment) (Object, error) {
+	var out Object
+	body := struct {
+		Middlewares []MiddlewareAssignment `json:"middlewares"`
+	}{Middlewares: assignments}
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "middlewares", "bulk"), body: body}, &out)
+	return out, err
+}
+
+// RemoveMiddleware detaches a middleware from a resource
+func (c *Client) RemoveMiddleware(ctx context.Context, resourceID, middlewareID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "middlewares", escape(middlewareID))}, nil)
+}
+
+// ListExternalMiddlewares returns the external middlewares attached to a resource
+func (c *Client) ListExternalMiddlewares(ctx context.Context, resourceID string) ([]Object, error) {
+	var out []Object
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "external-middlewares")}, &out)
+	return out, err
+}
+
+// AssignExternalMiddleware attaches an external middleware to a resource
+func (c *Client) AssignExternalMiddleware(ctx context.Context, resourceID string, assignment ExternalMiddlewareAssignment) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "external-middlewares"), body: assignment}, &out)
+	return out, err
+}
+
+// RemoveExternalMiddleware detaches an external middleware from a resource
+func (c *Client) RemoveExternalMiddleware(ctx context.Context, resourceID, name string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "external-middlewares", escape(name))}, nil)
+}
+
+// GetResourceService returns the custom service assigned to a resource
+func (c *Client) GetResourceService(ctx context.Context, resourceID string) (*ResourceService, error) {
+	out := &ResourceService{}
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "service")}, out)
+	return out, err
+}
+
+// AssignService replaces the service of a resource with a custom one
+func (c *Client) AssignService(ctx context.Context, resourceID, serviceID string) (Object, error) {
+	var out Object
+	body := struct {
+		ServiceID string `json:"service_id"`
+	}{ServiceID: serviceID}
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "service"), body: body}, &out)
+	return out, err
+}
+
+// RemoveService restores the original service of a resource
+func (c *Client) RemoveService(ctx context.Context, resourceID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "service")}, nil)
+}
+
+// ifMatch sends the Version of a resource as last read; the API answers 409
+// when the resource was changed since
+func ifMatch(version int64) http.Header {
+	return http.Header{"If-Match": {strconv.Quote(strconv.FormatInt(version, 10))}}
+}
+
+// putResourceConfig sends a PUT to /api/resources/:id/config/<section>
+func (c *Client) putResourceConfig(ctx context.Context, resourceID string, version int64, section string, body interface{}) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: resourcePath(resourceID, "config", section), body: body,
+		header: ifMatch(version)}, &out)
+	return out, err
+}
+
+// UpdateHTTPConfig sets the comma-separated HTTP entrypoints of a resource
+func (c *Client) UpdateHTTPConfig(ctx context.Context, resourceID string, version int64, entrypoints string) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "http", struct {
+		Entrypoints string `json:"entrypoints"`
+	}{entrypoints})
+}
+
+// UpdateTLSConfig sets the comma-separated certificate domains of a resource
+func (c *Client) UpdateTLSConfig(ctx context.Context, resourceID string, version int64, tlsDomains string) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "tls", struct {
+		TLSDomains string `json:"tls_domains"`
+	}{tlsDomains})
+}
+
+// UpdateTCPConfig sets the TCP SNI routing of a resource
+func (c *Client) UpdateTCPConfig(ctx context.Context, resourceID string, version int64, config TCPConfig) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "tcp", config)
+}
+
+// UpdateHeadersConfig sets the custom request headers of a resource
+func (c *Client) UpdateHeadersConfig(ctx context.Context, resourceID string, version int64, headers map[string]string) (Object, error) {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	return c.putResourceConfig(ctx, resourceID, version, "headers", struct {
+		CustomHeaders map[string]string `json:"custom_headers"`
+	}{headers})
+}
+
+// UpdateRouterPriority sets the router priority of a resource
+func (c *Client) UpdateRouterPriority(ctx context.Context, resourceID string, version int64, priority int) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "priority", struct {
+		RouterPriority int `json:"router_priority"`
+	}{priority})
+}
+
+// UpdateMTLSConfig enables or disables mTLS on a resource
+func (c *Client) UpdateMTLSConfig(ctx context.Context, resourceID string, version int64, enabled bool) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "mtls", struct {
+		MTLSEnabled bool `json:"mtls_enabled"`
+	}{enabled})
+}
+
+// UpdateMTLSWhitelistConfig sets the mTLS plugin rules of a resource
+func (c *Client) UpdateMTLSWhitelistConfig(ctx context.Context, resourceID string, version int64, config MTLSWhitelistConfig) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "mtlswhitelist", config)
+}
+
+// UpdateMTLSExemptions sets the paths of a resource reachable without a client certificate
+func (c *Client) UpdateMTLSExemptions(ctx context.Context, resourceID string, version int64, input models.UpdateMTLSExemptionsRequest) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "mtls/exemptions", input)
+}
+
+// UpdateResourceTLSHardening enables TLS hardening on a resource with a profile
+func (c *Client) UpdateResourceTLSHardening(ctx context.Context, resourceID string, version int64, input models.UpdateResourceTLSHardeningRequest) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "tls-hardening", input)
+}
+
+// UpdateResourceSecureHeaders enables secure headers on a resource, optionally
+// with a preset or in report-only mode
+func (c *Client) UpdateResourceSecureHeaders(ctx context.Context, resourceID string, version int64, input models.UpdateResourceSecureHeadersRequest) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "secure-headers", input)
+}
+
+// UpdateResourceCORS assigns a CORS policy to a resource; an empty ID removes it
+func (c *Client) UpdateResourceCORS(ctx context.Context, resourceID string, version int64, corsPolicyID string) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "cors", struct {
+		CORSPolicyID string `json:"cors_policy_id"`
+	}{corsPolicyID})
+}
+
+// UpdateResourceForwardAuth enables or disables the built-in forward auth on a resource
+func (c *Client) UpdateResourceForwardAuth(ctx context.Context, resourceID string, version int64, enabled bool) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "forward-auth", struct {
+		Enabled bool `json:"enabled"`
+	}{enabled})
+}
+
+// UpdateResourceDefaultChain excludes a resource from the global default
+// middleware chain, or includes it again
+func (c *Client) UpdateResourceDefaultChain(ctx context.Context, resourceID string, version int64, excluded bool) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "default-chain", struct {
+		Excluded bool `json:"excluded"`
+	}{excluded})
+}
+
+// UpdateResourceMiddlewareOrder sets where MM's middlewares go relative to the
+// router's upstream middlewares
+func (c *Client) UpdateResourceMiddlewareOrder(ctx context.Context, resourceID string, version int64, order models.MiddlewareOrder) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "middleware-order", order)
+}
+
+// UpdateResourceUpstreamMiddlewares sets which of the router's upstream
+// middlewares are removed or replaced; the response carries warnings, e.g.
+// when badger authentication is removed
+func (c *Client) UpdateResourceUpstreamMiddlewares(ctx context.Context, resourceID string, version int64, removals []models.UpstreamMiddlewareRemoval) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "upstream-middlewares", models.UpdateUpstreamMiddlewaresRequest{Removals: removals})
+}
+
+// UpdateResourceRedirect sets the HTTP→HTTPS redirect mode of a resource
+func (c *Client) UpdateResourceRedirect(ctx context.Context, resourceID string, version int64, mode string) (Object, error) {
+	return c.putResourceConfig(ctx, resourceID, version, "https-redirect", struct {
+		Mode string `json:"mode"`
+	}{mode})
+}
+
+// GetResourceWAF returns the WAF settings of a resource
+func (c *Client) GetResourceWAF(ctx context.Context, resourceID string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "waf")}, &out)
+	return out, err
+}
+
+// UpdateResourceWAF sets the WAF settings of a resource
+func (c *Client) UpdateResourceWAF(ctx context.Context, resourceID string, version int64, settings models.ResourceWAF) (*models.ResourceWAF, error) {
+	out := &models.ResourceWAF{}
+	err := c.do(ctx, request{method: http.MethodPut, path: resourcePath(resourceID, "config", "waf"), body: settings,
+		header: ifMatch(version)}, out)
+	return out, err
+}
+
+// GetResourceMirror returns the traffic mirror of a resource
+func (c *Client) GetResourceMirror(ctx context.Context, resourceID string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "mirror")}, &out)
+	return out, err
+}
+
+// SetResourceMirror mirrors a share of a resource's traffic to another service
+func (c *Client) SetResourceMirror(ctx context.Context, resourceID string, version int64, mirror models.ResourceMirror) (*models.ResourceMirror, error) {
+	out := &models.ResourceMirror{}
+	err := c.do(ctx, request{method: http.MethodPut, path: resourcePath(resourceID, "mirror"), body: mirror,
+		header: ifMatch(version)}, out)
+	return out, err
+}
+
+// DeleteResourceMirror stops mirroring a resource's traffic
+func (c *Client) DeleteResourceMirror(ctx context.Context, resourceID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "mirror")}, nil)
+}
+
+// GetResourceFailover returns the failover of a resource and the services it generates
+func (c *Client) GetResourceFailover(ctx context.Context, resourceID string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "failover")}, &out)
+	return out, err
+}
+
+// SetResourceFailover wraps a resource's service in a failover service with the given fallback
+func (c *Client) SetResourceFailover(ctx context.Context, resourceID string, version int64, failover models.ResourceFailover) (*models.ResourceFailover, error) {
+	out := &models.ResourceFailover{}
+	err := c.do(ctx, request{method: http.MethodPut, path: resourcePath(resourceID, "failover"), body: failover,
+		header: ifMatch(version)}, out)
+	return out, err
+}
+
+// DeleteResourceFailover gives a resource's router its original service back
+func (c *Client) DeleteResourceFailover(ctx context.Context, resourceID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "failover")}, nil)
+}
+
+// GetResourceDeployment returns the blue/green deployment of a resource and the services it generates
+func (c *Client) GetResourceDeployment(ctx context.Context, resourceID string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "deployment")}, &out)
+	return out, err
+}
+
+// StartDeployment stages a new green backend for a resource
+func (c *Client) StartDeployment(ctx context.Context, resourceID string, deployment models.ResourceDeployment) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "deployment"), body: deployment}, &out)
+	return out, err
+}
+
+// SwitchDeployment points a resource's router at its staged green backend
+func (c *Client) SwitchDeployment(ctx context.Context, resourceID string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "deployment/switch")}, &out)
+	return out, err
+}
+
+// RollbackDeployment returns a resource's router to blue
+func (c *Client) RollbackDeployment(ctx context.Context, resourceID string) error {
+	return c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "deployment/rollback")}, nil)
+}
+
+// DeleteDeployment forgets a resource's deployment; its router uses the upstream service again
+func (c *Client) DeleteDeployment(ctx context.Context, resourceID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "deployment")}, nil)
+}
+
+// StartCapture starts recording access-log lines for a resource
+func (c *Client) StartCapture(ctx context.Context, resourceID string, input CaptureRequest) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "capture"), body: input}, &out)
+	return out, err
+}
+
+// UpdateResourceMetadata sets the notes, owner and contact of a resource
+func (c *Client) UpdateResourceMetadata(ctx context.Context, resourceID string, version int64, meta models.OwnershipMetadata) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: resourcePath(resourceID, "metadata"), body: meta, header: ifMatch(version)}, &out)
+	return out, err
+}
+
+// SetResourcePinned pins or unpins a resource against watcher changes
+func (c *Client) SetResourcePinned(ctx context.Context, resourceID string, version int64, pinned bool) (Object, error) {
+	var out Object
+	body := struct {
+		Pinned bool `json:"pinned"`
+	}{pinned}
+	err := c.do(ctx, request{method: http.MethodPut, path: resourcePath(resourceID, "pin"), body: body, header: ifMatch(version)}, &out)
+	return out, err
+}
+
+// ListForwardAuthTokens returns the forward-auth credentials of a resource, without secrets
+func (c *Client) ListForwardAuthTokens(ctx context.Context, resourceID string) ([]Object, error) {
+	var out []Object
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "forward-auth", "tokens")}, &out)
+	return out, err
+}
+
+// CreateForwardAuthToken creates a forward-auth credential. The secret is only
+// present in this response.
+func (c *Client) CreateForwardAuthToken(ctx context.Context, resourceID string, input ForwardAuthTokenRequest) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "forward-auth", "tokens"), body: input, once: true}, &out)
+	return out, err
+}
+
+// DeleteForwardAuthToken revokes a forward-auth credential
+func (c *Client) DeleteForwardAuthToken(ctx context.Context, resourceID, tokenID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "forward-auth", "tokens", escape(tokenID))}, nil)
+}
+
+// RotateForwardAuthToken issues a new secret for a credential; the old one
+// keeps working for gracePeriodSeconds
+func (c *Client) RotateForwardAuthToken(ctx context.Context, resourceID, tokenID string, gracePeriodSeconds int) (Object, error) {
+	var out Object
+	body := struct {
+		GracePeriodSeconds int `json:"grace_period_seconds"`
+	}{gracePeriodSeconds}
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "forward-auth", "tokens", escape(tokenID), "rotate"), body: body, once: true}, &out)
+	return out, err
+}
+
+// ListResourceHeaderPolicies returns the header policies applied to a resource
+func (c *Client) ListResourceHeaderPolicies(ctx context.Context, resourceID string) ([]Object, error) {
+	var out []Object
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(resourceID, "header-policies")}, &out)
+	return out, err
+}
+
+// AssignResourceHeaderPolicy applies a header policy to a resource
+func (c *Client) AssignResourceHeaderPolicy(ctx context.Context, resourceID string, assignment PolicyAssignment) (*models.HeaderPolicyAssignment, error) {
+	out := &models.HeaderPolicyAssignment{}
+	err := c.do(ctx, request{method: http.MethodPost, path: resourcePath(resourceID, "header-policies"), body: assignment}, out)
+	return out, err
+}
+
+// RemoveResourceHeaderPolicy removes a header policy from a resource
+func (c *Client) RemoveResourceHeaderPolicy(ctx context.Context, resourceID, policyID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resourcePath(resourceID, "header-policies", escape(policyID))}, nil)
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// GetSecurityConfig returns the global TLS hardening and secure headers settings
+func (c *Client) GetSecurityConfig(ctx context.Context) (*models.SecurityConfig, error) {
+	out := &models.SecurityConfig{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/security/config"}, out)
+	return out, err
+}
+
+// GetTLSCompatibility reports which common clients fail a TLS hardening
+// profile ("hardened" or "compat") with an "rsa" or "ecdsa" certificate.
+// Empty arguments use the server defaults.
+func (c *Client) GetTLSCompatibility(ctx context.Context, profile, keyType string) (*models.TLSCompatReport, error) {
+	q := url.Values{}
+	if profile != "" {
+		q.Set("profile", profile)
+	}
+	if keyType != "" {
+		q.Set("key_type", keyType)
+	}
+	out := &models.TLSCompatReport{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/security/tls-hardening/compatibility", query: q}, out)
+	return out, err
+}
+
+// SetTLSHardening enables or disables TLS hardening globally
+func (c *Client) SetTLSHardening(ctx context.Context, enabled bool) (Object, error) {
+	path := "/api/security/tls-hardening/disable"
+	if enabled {
+		path = "/api/security/tls-hardening/enable"
+	}
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: path}, &out)
+	return out, err
+}
+
+// SetTLSHardeningMode sets how resources inherit TLS hardening: force_on,
+// default_on or per_resource
+func (c *Client) SetTLSHardeningMode(ctx context.Context, mode string) (Object, error) {
+	var out Object
+	body := models.UpdateTLSHardeningModeRequest{Mode: mode}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/security/tls-hardening/mode", body: body}, &out)
+	return out, err
+}
+
+// SetSecureHeaders enables or disables secure headers globally
+func (c *Client) SetSecureHeaders(ctx context.Context, enabled bool) (Object, error) {
+	path := "/api/security/secure-headers/disable"
+	if enabled {
+		path = "/api/security/secure-headers/enable"
+	}
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: path}, &out)
+	return out, err
+}
+
+// UpdateSecureHeadersConfig replaces the global secure headers
+func (c *Client) UpdateSecureHeadersConfig(ctx context.Context, headers models.SecureHeadersConfig) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/security/secure-headers/config", body: headers}, &out)
+	return out, err
+}
+
+// ListSecureHeadersPresets returns the built-in and custom secure headers presets
+func (c *Client) ListSecureHeadersPresets(ctx context.Context) ([]models.SecureHeadersPreset, error) {
+	var out []models.SecureHeadersPreset
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/security/secure-headers/presets"}, &out)
+	return out, err
+}
+
+// CreateSecureHeadersPreset creates a custom secure headers preset
+func (c *Client) CreateSecureHeadersPreset(ctx context.Context, input models.SecureHeadersPresetRequest) (*models.SecureHeadersPreset, error) {
+	out := &models.SecureHeadersPreset{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/security/secure-headers/presets", body: input}, out)
+	return out, err
+}
+
+// UpdateSecureHeadersPreset replaces a custom preset; built-in presets are read-only
+func (c *Client) UpdateSecureHeadersPreset(ctx context.Context, id string, input models.SecureHeadersPresetRequest) (*models.SecureHeadersPreset, error) {
+	out := &models.SecureHeadersPreset{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/security/secure-headers/presets/" + escape(id), body: input}, out)
+	return out, err
+}
+
+// DeleteSecureHeadersPreset deletes a custom preset no resource uses
+func (c *Client) DeleteSecureHeadersPreset(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/security/secure-headers/presets/" + escape(id)}, nil)
+}
+
+// ApplySecureHeadersPreset copies a preset into the global secure headers
+func (c *Client) ApplySecureHeadersPreset(ctx context.Context, id string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/security/secure-headers/presets/" + escape(id) + "/apply"}, &out)
+	return out, err
+}
+
+// CheckMiddlewareDuplicates looks for middlewares in other providers that
+// duplicate the named one
+func (c *Client) CheckMiddlewareDuplicates(ctx context.Context, input models.DuplicateCheckRequest) (*models.DuplicateCheckResult, error) {
+	out := &models.DuplicateCheckResult{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/security/check-duplicates", body: input}, out)
+	return out, err
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// ListServices returns services with the given status; empty means active,
+// "all" returns every service
+func (c *Client) ListServices(ctx context.Context, status string) ([]Service, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	var out []Service
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/services", query: q}, &out)
+	return out, err
+}
+
+// ListServicesPage returns one page of active services
+func (c *Client) ListServicesPage(ctx context.Context, opts ListOptions) (*Page[Service], error) {
+	if opts.Page == 0 {
+		opts.Page = 1
+	}
+	out := &Page[Service]{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/services", query: opts.values()}, out)
+	return out, err
+}
+
+// GetService returns one service
+func (c *Client) GetService(ctx context.Context, id string) (*Service, error) {
+	out := &Service{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/services/" + escape(id)}, out)
+	return out, err
+}
+
+// CreateService creates a service and returns it with its new ID
+func (c *Client) CreateService(ctx context.Context, input ServiceInput) (*Service, error) {
+	out := &Service{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/services", body: input}, out)
+	return out, err
+}
+
+// UpdateService replaces a service
+func (c *Client) UpdateService(ctx context.Context, id string, input ServiceInput) (*Service, error) {
+	out := &Service{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/services/" + escape(id), body: input}, out)
+	return out, err
+}
+
+// DeleteService deletes a service that no resource uses
+func (c *Client) DeleteService(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/services/" + escape(id)}, nil)
+}
+
+// ListServiceOverrides returns the overrides of discovered services
+func (c *Client) ListServiceOverrides(ctx context.Context) ([]models.ServiceOverride, error) {
+	var out []models.ServiceOverride
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/service-overrides"}, &out)
+	return out, err
+}
+
+// SetServiceOverride creates or replaces the override of a discovered service
+func (c *Client) SetServiceOverride(ctx context.Context, serviceName string, override models.ServiceOverride) (*models.ServiceOverride, error) {
+	out := &models.ServiceOverride{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/service-overrides/" + escape(serviceName), body: override}, out)
+	return out, err
+}
+
+// DeleteServiceOverride serves the discovered service unchanged again
+func (c *Client) DeleteServiceOverride(ctx context.Context, serviceName string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/service-overrides/" + escape(serviceName)}, nil)
+}
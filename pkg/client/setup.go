@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// GetSetupState returns the first-run wizard progress
+func (c *Client) GetSetupState(ctx context.Context) (*models.SetupState, error) {
+	out := &models.SetupState{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/setup"}, out)
+	return out, err
+}
+
+// DetectDeployment probes for Pangolin and Traefik; extra candidate URLs are
+// tried before the built-in Docker network guesses
+func (c *Client) DetectDeployment(ctx context.Context, req models.SetupDetectRequest) (*models.SetupDetection, error) {
+	out := &models.SetupDetection{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/setup/detect", body: req}, out)
+	return out, err
+}
+
+// ConfigureSetupDataSource saves and activates the data source for a deployment type
+func (c *Client) ConfigureSetupDataSource(ctx context.Context, req models.SetupDataSourceRequest) (*models.SetupState, error) {
+	out := &models.SetupState{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/setup/datasource", body: req}, out)
+	return out, err
+}
+
+// CheckSetupProvider checks that Traefik loads the config served by the server
+func (c *Client) CheckSetupProvider(ctx context.Context) (*models.SetupProviderCheck, error) {
+	out := &models.SetupProviderCheck{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/setup/provider-check"}, out)
+	return out, err
+}
+
+// CompleteSetup marks the first-run wizard finished
+func (c *Client) CompleteSetup(ctx context.Context) (*models.SetupState, error) {
+	out := &models.SetupState{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/setup/complete"}, out)
+	return out, err
+}
@@ -0,0 +1,384 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// traefikQuery filters Traefik API lists by protocol: http, tcp or udp; empty
+// returns every protocol
+func traefikQuery(protocol string) url.Values {
+	q := url.Values{}
+	if protocol != "" {
+		q.Set("type", protocol)
+	}
+	return q
+}
+
+// GetTraefikOverview returns the overview reported by the Traefik API
+func (c *Client) GetTraefikOverview(ctx context.Context) (*models.TraefikOverview, error) {
+	out := &models.TraefikOverview{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/overview"}, out)
+	return out, err
+}
+
+// GetTraefikVersion returns the version of the connected Traefik
+func (c *Client) GetTraefikVersion(ctx context.Context) (*models.TraefikVersion, error) {
+	out := &models.TraefikVersion{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/version"}, out)
+	return out, err
+}
+
+// ListTraefikEntrypoints returns the entrypoints of the connected Traefik
+func (c *Client) ListTraefikEntrypoints(ctx context.Context) ([]models.TraefikEntrypoint, error) {
+	var out []models.TraefikEntrypoint
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/entrypoints"}, &out)
+	return out, err
+}
+
+// ListTraefikRouters returns the routers Traefik is running. The shape depends
+// on protocol, so the raw JSON is returned.
+func (c *Client) ListTraefikRouters(ctx context.Context, protocol string) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/routers", query: traefikQuery(protocol)}, &out)
+	return out, err
+}
+
+// ListTraefikServices returns the services Traefik is running, as raw JSON
+func (c *Client) ListTraefikServices(ctx context.Context, protocol string) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/services", query: traefikQuery(protocol)}, &out)
+	return out, err
+}
+
+// ListTraefikMiddlewares returns the middlewares Traefik is running, as raw JSON
+func (c *Client) ListTraefikMiddlewares(ctx context.Context, protocol string) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/middlewares", query: traefikQuery(protocol)}, &out)
+	return out, err
+}
+
+// GetTraefikData returns everything the Traefik API reports in one call
+func (c *Client) GetTraefikData(ctx context.Context) (*models.FullTraefikData, error) {
+	out := &models.FullTraefikData{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/data"}, out)
+	return out, err
+}
+
+// LintStaticConfig lints the Traefik static config file the server manages
+func (c *Client) LintStaticConfig(ctx context.Context) (*models.StaticConfigLintReport, error) {
+	out := &models.StaticConfigLintReport{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/static-config/lint"}, out)
+	return out, err
+}
+
+// LintStaticConfigContent lints static config content without saving it
+func (c *Client) LintStaticConfigContent(ctx context.Context, content string) (*models.StaticConfigLintReport, error) {
+	out := &models.StaticConfigLintReport{}
+	body := models.LintStaticConfigRequest{Content: content}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/traefik/static-config/lint", body: body}, out)
+	return out, err
+}
+
+// GetTraefikConfig returns the merged dynamic config served to Traefik's HTTP provider
+func (c *Client) GetTraefikConfig(ctx context.Context) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config"}, &out)
+	return out, err
+}
+
+// InvalidateTraefikConfig makes the next config request rebuild the merged config
+func (c *Client) InvalidateTraefikConfig(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/traefik-config/invalidate"}, nil)
+}
+
+// GetTraefikConfigStatus returns the health, validation and cache state of the config proxy
+func (c *Client) GetTraefikConfigStatus(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/status"}, &out)
+	return out, err
+}
+
+// GetTraefikConfigChanges returns the routers and middlewares changed since a
+// revision. Pass 0, or a revision the server no longer knows, to get everything
+// with Reset set.
+func (c *Client) GetTraefikConfigChanges(ctx context.Context, since uint64) (*ConfigChanges, error) {
+	q := url.Values{}
+	if since > 0 {
+		q.Set("since", strconv.FormatUint(since, 10))
+	}
+	out := &ConfigChanges{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/changes", query: q}, out)
+	return out, err
+}
+
+// GetTransformLog returns what the latest merge changed in the upstream
+// config. The server answers 503 unless PROXY_TRANSFORM_LOG is set.
+func (c *Client) GetTransformLog(ctx context.Context) (*TransformLog, error) {
+	out := &TransformLog{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/transforms"}, out)
+	return out, err
+}
+
+// PreviewConfigDiff merges the config the server would serve now, without
+// serving it, and diffs it against the config Traefik last got
+func (c *Client) PreviewConfigDiff(ctx context.Context) (*ConfigDiff, error) {
+	out := &ConfigDiff{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/diff"}, out)
+	return out, err
+}
+
+// DiffConfigSnapshot is PreviewConfigDiff against snapshot, such as a saved
+// response of GetTraefikConfig
+func (c *Client) DiffConfigSnapshot(ctx context.Context, snapshot json.RawMessage) (*ConfigDiff, error) {
+	out := &ConfigDiff{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/traefik-config/diff", body: snapshot}, out)
+	return out, err
+}
+
+// GetChaos reports whether chaos mode is on and the faults injected into
+// the config endpoint
+func (c *Client) GetChaos(ctx context.Context) (*ChaosStatus, error) {
+	out := &ChaosStatus{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/chaos"}, out)
+	return out, err
+}
+
+// SetChaos injects faults into the config endpoint until they expire. The
+// server answers 503 unless PROXY_CHAOS is set.
+func (c *Client) SetChaos(ctx context.Context, faults ChaosFaults) (*ChaosFaults, error) {
+	out := &ChaosFaults{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/traefik-config/chaos", body: faults}, out)
+	return out, err
+}
+
+// ClearChaos stops injecting faults into the config endpoint
+func (c *Client) ClearChaos(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/traefik-config/chaos"}, nil)
+}
+
+// GetProviderHealth reports whether a client fetched the merged config within
+// maxAge (zero uses the server default of 60s). When none did, the server
+// answers 503 and the error is an *Error whose Message says why.
+func (c *Client) GetProviderHealth(ctx context.Context, maxAge time.Duration) (*ProviderHealth, error) {
+	q := url.Values{}
+	if maxAge > 0 {
+		q.Set("max_age", strconv.Itoa(int(maxAge.Seconds())))
+	}
+	out := &ProviderHealth{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/health", query: q}, out)
+	return out, err
+}
+
+// GetProviderConsumers lists who polled the merged config and flags setups
+// with no poller or several within window (zero uses the server default of 5m)
+func (c *Client) GetProviderConsumers(ctx context.Context, window time.Duration) (*ProviderConsumers, error) {
+	q := url.Values{}
+	if window > 0 {
+		q.Set("window", strconv.Itoa(int(window.Seconds())))
+	}
+	out := &ProviderConsumers{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/consumers", query: q}, out)
+	return out, err
+}
+
+// DataSources is the configured data sources and the active one. Passwords are masked.
+type DataSources struct {
+	ActiveSource string                             `json:"active_source"`
+	Sources      map[string]models.DataSourceConfig `json:"sources"`
+}
+
+// ListDataSources returns the configured data sources
+func (c *Client) ListDataSources(ctx context.Context) (*DataSources, error) {
+	out := &DataSources{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/datasource"}, out)
+	return out, err
+}
+
+// ActiveDataSource is the data source resources are currently read from
+type ActiveDataSource struct {
+	Name   string                  `json:"name"`
+	Config models.DataSourceConfig `json:"config"`
+}
+
+// GetActiveDataSource returns the active data source
+func (c *Client) GetActiveDataSource(ctx context.Context) (*ActiveDataSource, error) {
+	out := &ActiveDataSource{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/datasource/active"}, out)
+	return out, err
+}
+
+// SetActiveDataSource switches the data source resources are read from
+func (c *Client) SetActiveDataSource(ctx context.Context, name string) (Object, error) {
+	var out Object
+	body := struct {
+		Name string `json:"name"`
+	}{name}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/datasource/active", body: body}, &out)
+	return out, err
+}
+
+// FailoverStatus is the data source failover config and which source
+// resource discovery currently reads from
+type FailoverStatus struct {
+	Config           models.DataSourceFailover `json:"config"`
+	Primary          string                    `json:"primary"`
+	Serving          string                    `json:"serving"`
+	PrimaryDownSince *time.Time                `json:"primary_down_since,omitempty"`
+	LastError        string                    `json:"last_error,omitempty"`
+	SwitchedAt       *time.Time                `json:"switched_at,omitempty"`
+}
+
+// GetFailover returns the failover config and status
+func (c *Client) GetFailover(ctx context.Context) (*FailoverStatus, error) {
+	out := &FailoverStatus{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/datasource/failover"}, out)
+	return out, err
+}
+
+// SetFailover configures falling back to a secondary data source while the
+// active one is unreachable
+func (c *Client) SetFailover(ctx context.Context, config models.DataSourceFailover) (*FailoverStatus, error) {
+	out := &FailoverStatus{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/datasource/failover", body: config}, out)
+	return out, err
+}
+
+// UpdateDataSource replaces the configuration of a data source
+func (c *Client) UpdateDataSource(ctx context.Context, name string, config models.DataSourceConfig) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/datasource/" + escape(name), body: config}, &out)
+	return out, err
+}
+
+// TestDataSource checks that a data source is reachable. A nil config tests
+// the stored configuration.
+func (c *Client) TestDataSource(ctx context.Context, name string, config *models.DataSourceConfig) error {
+	req := request{method: http.MethodPost, path: "/api/datasource/" + escape(name) + "/test"}
+	if config != nil {
+		req.body = config
+	}
+	return c.do(ctx, req, nil)
+}
+
+// ListPlugins returns the Traefik plugins installed in the static config
+func (c *Client) ListPlugins(ctx context.Context) ([]models.PluginResponse, error) {
+	var out []models.PluginResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/plugins"}, &out)
+	return out, err
+}
+
+// GetPluginCatalogue returns the plugins published on plugins.traefik.io
+func (c *Client) GetPluginCatalogue(ctx context.Context) ([]Object, error) {
+	var out []Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/plugins/catalogue"}, &out)
+	return out, err
+}
+
+// GetPluginUsage returns which middlewares use a plugin
+func (c *Client) GetPluginUsage(ctx context.Context, name string) (Object, error) {
+	var out Object
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/plugins/" + escape(name) + "/usage"}, &out)
+	return out, err
+}
+
+// InstallPlugin adds a plugin to the Traefik static config; Traefik must be
+// restarted to load it
+func (c *Client) InstallPlugin(ctx context.Context, moduleName, version string) (Object, error) {
+	var out Object
+	body := struct {
+		ModuleName string `json:"moduleName"`
+		Version    string `json:"version,omitempty"`
+	}{moduleName, version}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/plugins/install", body: body}, &out)
+	return out, err
+}
+
+// TestPluginSandbox runs a plugin in the server's Yaegi sandbox without
+// installing it. Plugin failures are reported in the result, not as an error.
+func (c *Client) TestPluginSandbox(ctx context.Context, req models.PluginSandboxRequest) (*models.PluginSandboxResult, error) {
+	var out models.PluginSandboxResult
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/api/plugins/sandbox", body: req}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemovePlugin removes a plugin from the Traefik static config
+func (c *Client) RemovePlugin(ctx context.Context, moduleName string) (Object, error) {
+	var out Object
+	body := struct {
+		ModuleName string `json:"moduleName"`
+	}{moduleName}
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/api/plugins/remove", body: body}, &out)
+	return out, err
+}
+
+// GetTraefikStaticConfigPath returns the path of the Traefik static config file
+func (c *Client) GetTraefikStaticConfigPath(ctx context.Context) (string, error) {
+	var out struct {
+		Path string `json:"path"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/plugins/configpath"}, &out)
+	return out.Path, err
+}
+
+// SetTraefikStaticConfigPath sets the path of the Traefik static config file
+func (c *Client) SetTraefikStaticConfigPath(ctx context.Context, path string) (Object, error) {
+	var out Object
+	body := struct {
+		Path string `json:"path"`
+	}{path}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/plugins/configpath", body: body}, &out)
+	return out, err
+}
+
+// SimulateRoute reports which merged router Traefik would pick for a request,
+// evaluated by Middleware Manager without asking Traefik
+func (c *Client) SimulateRoute(ctx context.Context, query RouteQuery) (*RouteSimulation, error) {
+	q := url.Values{}
+	q.Set("url", query.URL)
+	if query.Method != "" {
+		q.Set("method", query.Method)
+	}
+	for name, value := range query.Headers {
+		q.Add("header", name+": "+value)
+	}
+	if query.ClientIP != "" {
+		q.Set("client_ip", query.ClientIP)
+	}
+	if query.EntryPoint != "" {
+		q.Set("entrypoint", query.EntryPoint)
+	}
+	out := &RouteSimulation{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/tools/route", query: q}, out)
+	return out, err
+}
+
+// GetDeprecations scans stored middlewares and services for types and options
+// the target Traefik version renamed, removed or deprecated
+func (c *Client) GetDeprecations(ctx context.Context) (*DeprecationScan, error) {
+	out := &DeprecationScan{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik/deprecations"}, out)
+	return out, err
+}
+
+// FixDeprecations rewrites the fixable deprecations and returns those left
+func (c *Client) FixDeprecations(ctx context.Context) (*DeprecationScan, error) {
+	out := &DeprecationScan{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/traefik/deprecations/fix"}, out)
+	return out, err
+}
+
+// GetEnvironmentConfig returns the merged dynamic config of one environment
+func (c *Client) GetEnvironmentConfig(ctx context.Context, environment string) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/traefik-config/env/" + escape(environment)}, &out)
+	return out, err
+}
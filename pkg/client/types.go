@@ -0,0 +1,681 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Object is a JSON object returned by endpoints without a fixed response shape
+type Object = map[string]interface{}
+
+// Message is the {"message": ...} acknowledgement most write endpoints return
+type Message struct {
+	Message string `json:"message"`
+}
+
+// ListOptions requests one page of a list endpoint. A nil *ListOptions returns
+// the full list.
+type ListOptions struct {
+	Page     int
+	PageSize int
+}
+
+func (o *ListOptions) values() url.Values {
+	q := url.Values{}
+	if o == nil {
+		return q
+	}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PageSize > 0 {
+		q.Set("page_size", strconv.Itoa(o.PageSize))
+	}
+	return q
+}
+
+// Page is one page of a paginated list
+type Page[T any] struct {
+	Data       []T `json:"data"`
+	Total      int `json:"total"`
+	Page       int `json:"page"`
+	PageSize   int `json:"page_size"`
+	TotalPages int `json:"total_pages"`
+}
+
+// Middleware is a middleware as returned by the middlewares endpoints
+type Middleware struct {
+	ID      string                 `json:"id"`
+	Name    string                 `json:"name"`
+	Type    string                 `json:"type"`
+	Config  map[string]interface{} `json:"config"`
+	Version int64                  `json:"version"`
+	Notes   string                 `json:"notes,omitempty"`
+	Owner   string                 `json:"owner,omitempty"`
+	Contact string                 `json:"contact,omitempty"`
+}
+
+// MiddlewareInput creates or replaces a middleware. Version, when set on an
+// update, makes the request fail with a conflict if someone else changed it.
+// Force saves a config that does not match the Traefik schema of its type.
+type MiddlewareInput struct {
+	Name    string                 `json:"name"`
+	Type    string                 `json:"type"`
+	Config  map[string]interface{} `json:"config"`
+	Version *int64                 `json:"version,omitempty"`
+	Force   bool                   `json:"-"`
+}
+
+// Service is a service as returned by the services endpoints
+type Service struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Config     map[string]interface{} `json:"config"`
+	Status     string                 `json:"status,omitempty"`
+	SourceType string                 `json:"source_type,omitempty"`
+}
+
+// ServiceInput creates or replaces a service
+type ServiceInput struct {
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// ResourceService is the service assigned to a resource
+type ResourceService struct {
+	ResourceID string  `json:"resource_id"`
+	Service    Service `json:"service"`
+}
+
+// Resource is a resource as returned by the resources endpoints. Fields only
+// served by the single-resource endpoint are zero in lists.
+type Resource struct {
+	ID                      string   `json:"id"`
+	PangolinRouterID        string   `json:"pangolin_router_id"`
+	Host                    string   `json:"host"`
+	ServiceID               string   `json:"service_id"`
+	OrgID                   string   `json:"org_id"`
+	SiteID                  string   `json:"site_id"`
+	Status                  string   `json:"status"`
+	Entrypoints             string   `json:"entrypoints"`
+	TLSDomains              string   `json:"tls_domains"`
+	TCPEnabled              bool     `json:"tcp_enabled"`
+	TCPEntrypoints          string   `json:"tcp_entrypoints"`
+	TCPSNIRule              string   `json:"tcp_sni_rule"`
+	CustomHeaders           string   `json:"custom_headers"`
+	MTLSEnabled             bool     `json:"mtls_enabled"`
+	MTLSExemptPaths         []string `json:"mtls_exempt_paths,omitempty"`
+	RouterPriority          int      `json:"router_priority"`
+	SourceType              string   `json:"source_type"`
+	TLSHardeningEnabled     bool     `json:"tls_hardening_enabled"`
+	TLSHardeningProfile     string   `json:"tls_hardening_profile,omitempty"`
+	TLSHardeningOptOut      bool     `json:"tls_hardening_opt_out,omitempty"`
+	SecureHeadersEnabled    bool     `json:"secure_headers_enabled"`
+	SecureHeadersPreset     string   `json:"secure_headers_preset,omitempty"`
+	SecureHeadersReportOnly bool     `json:"secure_headers_report_only,omitempty"`
+	CORSPolicyID            string   `json:"cors_policy_id,omitempty"`
+	ForwardAuthEnabled      bool     `json:"forward_auth_enabled,omitempty"`
+	HTTPSRedirect           string   `json:"https_redirect,omitempty"`
+	// Middlewares is "id:name:priority" entries joined by commas
+	Middlewares string `json:"middlewares"`
+	// ExternalMiddlewares is "name:priority:provider" entries joined by commas
+	ExternalMiddlewares string `json:"external_middlewares,omitempty"`
+	Version             int64  `json:"version"`
+	Notes               string `json:"notes,omitempty"`
+	Owner               string `json:"owner,omitempty"`
+	Contact             string `json:"contact,omitempty"`
+	Pinned              bool   `json:"pinned,omitempty"`
+	// DefaultChainExcluded is set when the resource opted out of the default chain
+	DefaultChainExcluded bool `json:"default_chain_excluded,omitempty"`
+	// MiddlewareOrder is only filled in by GetResource
+	MiddlewareOrder *models.MiddlewareOrder `json:"middleware_order,omitempty"`
+	// UpstreamMiddlewareRemovals is only filled in by GetResource
+	UpstreamMiddlewareRemovals []models.UpstreamMiddlewareRemoval `json:"upstream_middleware_removals,omitempty"`
+	// RouterChain is only filled in by GetResource, when the upstream router
+	// of the resource was found
+	RouterChain *RouterChain `json:"router_chain,omitempty"`
+	// RouterChainError is why RouterChain could not be determined
+	RouterChainError string `json:"router_chain_error,omitempty"`
+	// SharedHost is set when other active resources have the same host
+	SharedHost bool `json:"shared_host,omitempty"`
+	// PinDivergence is only filled in by GetResource
+	PinDivergence []models.PinDivergence `json:"pin_divergence,omitempty"`
+}
+
+// RouterChain is the middleware chain of a resource's router, as the upstream
+// attached it and as served to Traefik after the merge
+type RouterChain struct {
+	Router    string   `json:"router"`
+	Upstream  []string `json:"upstream"`
+	Effective []string `json:"effective"`
+}
+
+// ResourceListOptions filters and pages the resource list
+type ResourceListOptions struct {
+	ListOptions
+	// Status filters by status; empty means the server default (active).
+	// Use "all" for every resource.
+	Status string
+	// SourceType filters by data source, e.g. "pangolin" or "traefik"
+	SourceType string
+}
+
+// MiddlewareAssignment attaches a middleware to a resource. A set ExpiresAt
+// makes the assignment temporary.
+type MiddlewareAssignment struct {
+	MiddlewareID string     `json:"middleware_id"`
+	Priority     int        `json:"priority"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// DefaultChain is the global default middleware chain and the resources
+// excluded from it
+type DefaultChain struct {
+	Middlewares       []models.DefaultChainMiddleware `json:"middlewares"`
+	ExcludedResources []string                        `json:"excluded_resources"`
+}
+
+// ExternalMiddlewareAssignment attaches a middleware defined outside Middleware
+// Manager (e.g. a Traefik file-provider middleware) to a resource
+type ExternalMiddlewareAssignment struct {
+	MiddlewareName string `json:"middleware_name"`
+	Priority       int    `json:"priority"`
+	Provider       string `json:"provider,omitempty"`
+}
+
+// TCPConfig is the TCP SNI routing configuration of a resource
+type TCPConfig struct {
+	TCPEnabled     bool   `json:"tcp_enabled"`
+	TCPEntrypoints string `json:"tcp_entrypoints"`
+	TCPSNIRule     string `json:"tcp_sni_rule"`
+}
+
+// MTLSWhitelistConfig is the per-resource mTLS plugin configuration
+type MTLSWhitelistConfig struct {
+	Rules           []interface{}          `json:"rules,omitempty"`
+	RequestHeaders  map[string]string      `json:"request_headers,omitempty"`
+	RejectMessage   string                 `json:"reject_message,omitempty"`
+	RejectCode      *int                   `json:"reject_code,omitempty"`
+	RefreshInterval string                 `json:"refresh_interval,omitempty"`
+	ExternalData    map[string]interface{} `json:"external_data,omitempty"`
+}
+
+// ConvertMiddlewaresRequest converts middlewares to another type. DryRun
+// defaults to true on the server.
+type ConvertMiddlewaresRequest struct {
+	Target string   `json:"target"`
+	IDs    []string `json:"ids,omitempty"`
+	DryRun *bool    `json:"dry_run,omitempty"`
+}
+
+// HeaderPolicyInput creates or replaces a header policy
+type HeaderPolicyInput struct {
+	Name        string                 `json:"name"`
+	Type        string                 `json:"type,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Config      map[string]interface{} `json:"config"`
+}
+
+// PolicyAssignment attaches a header policy to a resource or group
+type PolicyAssignment struct {
+	PolicyID string `json:"policy_id"`
+	Priority *int   `json:"priority,omitempty"`
+}
+
+// ForwardAuthTokenRequest creates a forward-auth credential; Type is "bearer"
+// (the default) or "totp"
+type ForwardAuthTokenRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// CleanupRequest runs maintenance cleanup. Runs are dry runs unless DryRun is
+// set to false.
+type CleanupRequest struct {
+	DryRun           *bool `json:"dry_run,omitempty"`
+	ReapDisabled     bool  `json:"reap_disabled,omitempty"`
+	RecoverCorrupted *bool `json:"recover_corrupted,omitempty"`
+}
+
+// PostgresMigrationRequest copies the SQLite database into the PostgreSQL
+// database at DSN. Runs are dry runs unless DryRun is set to false; cutting
+// over is only done from the command line with MM stopped.
+type PostgresMigrationRequest struct {
+	DSN       string `json:"dsn"`
+	DryRun    *bool  `json:"dry_run,omitempty"`
+	Overwrite bool   `json:"overwrite,omitempty"`
+}
+
+// TableMigration is the row count and checksum of one table on both sides
+type TableMigration struct {
+	Table          string `json:"table"`
+	SourceRows     int64  `json:"source_rows"`
+	TargetRows     int64  `json:"target_rows"`
+	SourceChecksum string `json:"source_checksum,omitempty"`
+	TargetChecksum string `json:"target_checksum,omitempty"`
+	Verified       bool   `json:"verified"`
+}
+
+// PostgresMigration reports a SQLite to PostgreSQL migration
+type PostgresMigration struct {
+	Target     string           `json:"target"`
+	DryRun     bool             `json:"dry_run"`
+	Tables     []TableMigration `json:"tables"`
+	Rows       int64            `json:"rows"`
+	Verified   bool             `json:"verified"`
+	CutOver    bool             `json:"cut_over"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// RedirectsRequest configures the global HTTP→HTTPS redirect
+type RedirectsRequest struct {
+	Enabled    bool   `json:"enabled"`
+	EntryPoint string `json:"entrypoint,omitempty"`
+	Permanent  *bool  `json:"permanent,omitempty"`
+}
+
+// EntrypointRedirectRequest configures an entrypoint-level redirect in the
+// Traefik static config
+type EntrypointRedirectRequest struct {
+	Enabled    bool   `json:"enabled"`
+	EntryPoint string `json:"entrypoint,omitempty"`
+	To         string `json:"to,omitempty"`
+	Permanent  *bool  `json:"permanent,omitempty"`
+}
+
+// SecretRotationRequest rotates a credential stored in a middleware
+type SecretRotationRequest struct {
+	MiddlewareID       string `json:"middleware_id"`
+	Path               string `json:"path,omitempty"`
+	Username           string `json:"username,omitempty"`
+	NewValue           string `json:"new_value,omitempty"`
+	GracePeriodSeconds int    `json:"grace_period_seconds,omitempty"`
+}
+
+// CaptureRequest starts a traffic capture on a resource
+type CaptureRequest struct {
+	Lines      int `json:"lines,omitempty"`
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+// ProtectedName marks a middleware or service name as untouchable
+type ProtectedName struct {
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ConfigSectionChanges lists what changed in one config section
+type ConfigSectionChanges struct {
+	Added    map[string]interface{} `json:"added"`
+	Modified map[string]interface{} `json:"modified"`
+	Removed  []string               `json:"removed"`
+}
+
+// ProviderPollClient is one client of the config endpoint, by IP and User-Agent
+type ProviderPollClient struct {
+	ClientIP    string     `json:"client_ip"`
+	UserAgent   string     `json:"user_agent"`
+	FirstSeen   time.Time  `json:"first_seen"`
+	LastSeen    time.Time  `json:"last_seen"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Requests    int64      `json:"requests"`
+	Failures    int64      `json:"failures"`
+	Revision    uint64     `json:"revision"`
+	// Endpoints counts requests per path
+	Endpoints map[string]int64 `json:"endpoints"`
+	// IntervalSeconds approximates the client's poll interval
+	IntervalSeconds float64 `json:"interval_seconds,omitempty"`
+}
+
+// ProviderConsumers lists the clients of the config endpoint, with warnings
+// when none or several polled within the window
+type ProviderConsumers struct {
+	WindowSeconds float64              `json:"window_seconds"`
+	Active        int                  `json:"active"`
+	Consumers     []ProviderPollClient `json:"consumers"`
+	Warnings      []string             `json:"warnings"`
+}
+
+// TransformChange is one change the latest merge made to the upstream
+// config. Action is added, removed, modified, middleware_added,
+// middleware_removed or middlewares_reordered.
+type TransformChange struct {
+	Section string      `json:"section"`
+	Name    string      `json:"name"`
+	Action  string      `json:"action"`
+	Field   string      `json:"field,omitempty"`
+	Before  interface{} `json:"before,omitempty"`
+	After   interface{} `json:"after,omitempty"`
+	Detail  string      `json:"detail"`
+}
+
+// TransformLog lists what the latest merge changed in the upstream config
+type TransformLog struct {
+	MergedAt  time.Time         `json:"merged_at"`
+	Served    bool              `json:"served"`
+	Changes   []TransformChange `json:"changes"`
+	Truncated bool              `json:"truncated"`
+}
+
+// ConfigDiff compares the config a fresh merge would serve with the one
+// Traefik last got (Base served, or cache before any poll), a supplied
+// snapshot (snapshot) or nothing (none)
+type ConfigDiff struct {
+	Base             string            `json:"base"`
+	BaseAt           *time.Time        `json:"base_at,omitempty"`
+	GeneratedAt      time.Time         `json:"generated_at"`
+	Identical        bool              `json:"identical"`
+	WouldServe       bool              `json:"would_serve"`
+	ValidationErrors []string          `json:"validation_errors,omitempty"`
+	Changes          []TransformChange `json:"changes"`
+	Truncated        bool              `json:"truncated"`
+	Unified          string            `json:"unified"`
+}
+
+// ChaosFaults are the faults injected into the config endpoint in chaos
+// mode. Zero Probability and DurationSeconds use the server defaults (1 and
+// 600); SetAt and ExpiresAt are filled in by the server.
+type ChaosFaults struct {
+	DelayMS         int       `json:"delay_ms"`
+	Stale           bool      `json:"stale"`
+	Truncate        bool      `json:"truncate"`
+	Probability     float64   `json:"probability"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	SetAt           time.Time `json:"set_at,omitempty"`
+	ExpiresAt       time.Time `json:"expires_at,omitempty"`
+}
+
+// ChaosStatus reports whether chaos mode is on and the active faults
+type ChaosStatus struct {
+	Enabled  bool         `json:"enabled"`
+	Faults   *ChaosFaults `json:"faults,omitempty"`
+	Injected int64        `json:"injected"`
+}
+
+// PendingChange is a resource creation, disable or service change waiting
+// for approval
+type PendingChange struct {
+	ID               string     `json:"id"`
+	Kind             string     `json:"kind"`
+	ResourceID       string     `json:"resource_id,omitempty"`
+	PangolinRouterID string     `json:"pangolin_router_id,omitempty"`
+	Host             string     `json:"host"`
+	OldServiceID     string     `json:"old_service_id,omitempty"`
+	NewServiceID     string     `json:"new_service_id,omitempty"`
+	DetectedAt       time.Time  `json:"detected_at"`
+	AutoApproveAt    *time.Time `json:"auto_approve_at,omitempty"`
+}
+
+// ResourceChange is a change a resource sync made: action is "created",
+// "updated", "disabled" or, in review mode, "queued"
+type ResourceChange struct {
+	ResourceID string `json:"resource_id,omitempty"`
+	Host       string `json:"host"`
+	Action     string `json:"action"`
+}
+
+// ResourceRun summarizes one resource watcher run; trigger is "scheduled",
+// "manual", "resync", "reload" or "restore"
+type ResourceRun struct {
+	ID         int64             `json:"id,omitempty"`
+	Trigger    string            `json:"trigger"`
+	Source     string            `json:"source"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMS int64             `json:"duration_ms"`
+	Fetched    int               `json:"fetched"`
+	Created    int               `json:"created"`
+	Updated    int               `json:"updated"`
+	Disabled   int               `json:"disabled"`
+	Queued     int               `json:"queued"`
+	Skipped    []SkippedResource `json:"skipped"`
+	Changes    []ResourceChange  `json:"changes"`
+	// SharedHosts lists hosts several routers serve, with their router IDs
+	SharedHosts []SharedHost `json:"shared_hosts,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// SharedHost is a host served by more than one router
+type SharedHost struct {
+	Host    string   `json:"host"`
+	Routers []string `json:"routers"`
+}
+
+// SkippedResource is a resource a watcher run left alone, with the reason
+type SkippedResource struct {
+	ResourceID string `json:"resource_id,omitempty"`
+	Host       string `json:"host"`
+	Reason     string `json:"reason"`
+}
+
+// ProviderHealth reports whether Traefik is polling the merged config
+type ProviderHealth struct {
+	Healthy       bool                 `json:"healthy"`
+	Message       string               `json:"message"`
+	MaxAgeSeconds float64              `json:"max_age_seconds"`
+	LastServedAt  *time.Time           `json:"last_served_at,omitempty"`
+	Revision      uint64               `json:"revision"`
+	Clients       []ProviderPollClient `json:"clients"`
+}
+
+// ConfigChanges is the delta between two revisions of the Traefik config.
+// Reset means the requested revision is unknown and everything is in Added.
+type ConfigChanges struct {
+	Revision    uint64               `json:"revision"`
+	Since       uint64               `json:"since"`
+	Reset       bool                 `json:"reset"`
+	Routers     ConfigSectionChanges `json:"routers"`
+	Middlewares ConfigSectionChanges `json:"middlewares"`
+}
+
+// RouteQuery is a request to simulate Traefik's router selection for. Only
+// URL is required; Headers are sent as "Name: value" pairs.
+type RouteQuery struct {
+	URL        string
+	Method     string
+	Headers    map[string]string
+	ClientIP   string
+	EntryPoint string
+}
+
+// RouteSimulation is the router Traefik would pick for a RouteQuery, with its
+// service and middleware chain. Candidates lists the matching routers, winner
+// first, and routers whose rule could not be evaluated.
+type RouteSimulation struct {
+	URL         string           `json:"url"`
+	Method      string           `json:"method"`
+	Matched     bool             `json:"matched"`
+	Router      string           `json:"router,omitempty"`
+	Rule        string           `json:"rule,omitempty"`
+	Priority    int              `json:"priority,omitempty"`
+	Service     string           `json:"service,omitempty"`
+	EntryPoints []string         `json:"entry_points,omitempty"`
+	Middlewares []string         `json:"middlewares,omitempty"`
+	Chain       []string         `json:"chain,omitempty"`
+	Candidates  []RouteCandidate `json:"candidates"`
+}
+
+// RouteCandidate is a router evaluated by a route simulation
+type RouteCandidate struct {
+	Router   string `json:"router"`
+	Rule     string `json:"rule"`
+	Priority int    `json:"priority"`
+	Matched  bool   `json:"matched"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DeprecationScan lists stored middlewares and services using types or
+// options the target Traefik version renamed, removed or deprecated
+type DeprecationScan struct {
+	TraefikVersion string               `json:"traefik_version"`
+	Major          int                  `json:"major"`
+	Findings       []DeprecationFinding `json:"findings"`
+	Fixed          int                  `json:"fixed,omitempty"`
+}
+
+// DeprecationFinding is one deprecated type or option; Fixable findings are
+// rewritten by FixDeprecations
+type DeprecationFinding struct {
+	Kind        string `json:"kind"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Option      string `json:"option"`
+	Replacement string `json:"replacement,omitempty"`
+	Message     string `json:"message"`
+	Hint        string `json:"hint,omitempty"`
+	Fixable     bool   `json:"fixable"`
+}
+
+// AccessMatrix is the access review of every exposed host
+type AccessMatrix struct {
+	GeneratedAt time.Time           `json:"generated_at"`
+	Hosts       []AccessMatrixEntry `json:"hosts"`
+}
+
+// AccessMatrixEntry is one host of the access review. Auth lists forwardAuth,
+// basicAuth, digestAuth and mTLS, or "none"; TLS is "mtls",
+// "hardened:<profile>" or "default".
+type AccessMatrixEntry struct {
+	Host          string   `json:"host"`
+	ResourceID    string   `json:"resource_id"`
+	EntryPoints   []string `json:"entry_points"`
+	Auth          []string `json:"auth"`
+	IPAllowList   []string `json:"ip_allow_list"`
+	TLS           string   `json:"tls"`
+	TLSDomains    []string `json:"tls_domains"`
+	HTTPSRedirect string   `json:"https_redirect"`
+	Middlewares   []string `json:"middlewares"`
+}
+
+// Inventory lists the plugins Traefik loads and the middleware types in use
+type Inventory struct {
+	GeneratedAt       time.Time                      `json:"generated_at"`
+	StaticConfigPath  string                         `json:"static_config_path"`
+	StaticConfigError string                         `json:"static_config_error,omitempty"`
+	Plugins           []PluginInventoryEntry         `json:"plugins"`
+	MiddlewareTypes   []MiddlewareTypeInventoryEntry `json:"middleware_types"`
+}
+
+// PluginInventoryEntry is one plugin of the static config or referenced by an
+// MM plugin middleware; Installed is false when the static config lacks it
+type PluginInventoryEntry struct {
+	Key         string `json:"key"`
+	ModuleName  string `json:"module_name"`
+	Version     string `json:"version"`
+	Installed   bool   `json:"installed"`
+	Middlewares int    `json:"middlewares"`
+}
+
+// MiddlewareTypeInventoryEntry counts the middlewares of one type and the
+// resources they are assigned to
+type MiddlewareTypeInventoryEntry struct {
+	Type        string `json:"type"`
+	Middlewares int    `json:"middlewares"`
+	Resources   int    `json:"resources"`
+	Deprecated  bool   `json:"deprecated"`
+	ReplacedBy  string `json:"replaced_by,omitempty"`
+}
+
+// BackupObject is a database snapshot (key under db/) or config export (key
+// under config/) in a backup target. ID is the file name of the key.
+type BackupObject struct {
+	ID           string    `json:"id"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// BackupStatus reports the last scheduled or manual backup run
+type BackupStatus struct {
+	Target      string         `json:"target"`
+	Interval    string         `json:"interval,omitempty"`
+	Keep        int            `json:"keep,omitempty"`
+	LastRunAt   *time.Time     `json:"last_run_at,omitempty"`
+	LastError   string         `json:"last_error,omitempty"`
+	LastObjects []BackupObject `json:"last_objects,omitempty"`
+}
+
+// S3Backups lists the backups in the S3 bucket
+type S3Backups struct {
+	Status  BackupStatus   `json:"status"`
+	Objects []BackupObject `json:"objects"`
+}
+
+// LocalBackups lists the backups in the local backup directory
+type LocalBackups struct {
+	Status  BackupStatus   `json:"status"`
+	Objects []BackupObject `json:"objects"`
+}
+
+// ReloadReport is the outcome of POST /api/system/reload
+type ReloadReport struct {
+	Trigger    string    `json:"trigger"`
+	ReloadedAt time.Time `json:"reloaded_at"`
+	Applied    []string  `json:"applied"`
+	// RestartRequired lists changed variables that take effect after a restart
+	RestartRequired []string `json:"restart_required"`
+}
+
+// RuntimeStats is the server's process report from /api/system/runtime
+type RuntimeStats struct {
+	CollectedAt   time.Time      `json:"collected_at"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Version       string         `json:"version"`
+	GoVersion     string         `json:"go_version"`
+	NumCPU        int            `json:"num_cpu"`
+	GOMAXPROCS    int            `json:"gomaxprocs"`
+	Goroutines    int            `json:"goroutines"`
+	OpenFDs       *int           `json:"open_fds,omitempty"`
+	Memory        RuntimeMemory  `json:"memory"`
+	GC            RuntimeGC      `json:"gc"`
+	Database      RuntimeDB      `json:"database"`
+	Caches        map[string]int `json:"caches"`
+}
+
+// RuntimeMemory are the Go heap and total memory figures in bytes
+type RuntimeMemory struct {
+	HeapAlloc   uint64 `json:"heap_alloc_bytes"`
+	HeapInuse   uint64 `json:"heap_inuse_bytes"`
+	HeapIdle    uint64 `json:"heap_idle_bytes"`
+	HeapObjects uint64 `json:"heap_objects"`
+	StackInuse  uint64 `json:"stack_inuse_bytes"`
+	Sys         uint64 `json:"sys_bytes"`
+	TotalAlloc  uint64 `json:"total_alloc_bytes"`
+	NextGC      uint64 `json:"next_gc_bytes"`
+}
+
+// RuntimeGC summarizes garbage collection; RecentPausesMs is newest first
+type RuntimeGC struct {
+	NumGC          uint32     `json:"num_gc"`
+	PauseTotalMs   float64    `json:"pause_total_ms"`
+	RecentPausesMs []float64  `json:"recent_pauses_ms"`
+	LastGCAt       *time.Time `json:"last_gc_at,omitempty"`
+	CPUFraction    float64    `json:"cpu_fraction"`
+}
+
+// RuntimeDB reports the database file and connection pool
+type RuntimeDB struct {
+	Path       string `json:"path,omitempty"`
+	SizeBytes  int64  `json:"size_bytes"`
+	FreeBytes  int64  `json:"free_bytes"`
+	WALBytes   int64  `json:"wal_bytes"`
+	OpenConns  int    `json:"open_connections"`
+	InUseConns int    `json:"in_use_connections"`
+	IdleConns  int    `json:"idle_connections"`
+	WaitCount  int64  `json:"wait_count"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WebhookList is the webhooks, secrets masked, and the events they can subscribe to
+type WebhookList struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+	Events   []string         `json:"events"`
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ConfigWebhookPayload is the body Middleware Manager POSTs to
+// CONFIG_WEBHOOK_URL when the served config changes. Config is set in full
+// mode and Changes in delta mode.
+type ConfigWebhookPayload struct {
+	Event     string          `json:"event"`
+	Revision  uint64          `json:"revision"`
+	Timestamp time.Time       `json:"timestamp"`
+	Config    json.RawMessage `json:"config,omitempty"`
+	Changes   *ConfigChanges  `json:"changes,omitempty"`
+}
+
+// VerifyConfigWebhook checks the X-MM-Signature of a config webhook delivery
+// against the shared secret and rejects deliveries whose X-MM-Timestamp is
+// more than maxAge away from now, so captured deliveries cannot be replayed
+func VerifyConfigWebhook(secret string, header http.Header, body []byte, maxAge time.Duration) bool {
+	timestamp := header.Get("X-MM-Timestamp")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > maxAge || age < -maxAge {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(header.Get("X-MM-Signature")))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/api"
+	"github.com/hhftechnology/middleware-manager/services"
+	"github.com/hhftechnology/middleware-manager/util"
+)
+
+// reloader re-reads the settings on SIGHUP or POST /api/system/reload
+// without restarting the process, so Traefik's provider requests in flight
+// are not dropped
+type reloader struct {
+	mu    sync.Mutex
+	debug bool
+	// running is the configuration the process was started with; settings
+	// that need a restart are compared against it
+	running         Configuration
+	envFile         *util.EnvFile
+	logOutput       *util.LogOutput
+	configManager   *services.ConfigManager
+	resourceWatcher *services.ResourceWatcher
+	serviceWatcher  *services.ServiceWatcher
+	server          *api.Server
+}
+
+// Reload re-reads ENV_FILE, the environment and config.json and applies
+// what can change at runtime. An unreadable env or config file or an
+// invalid variable stops the reload before the server settings change.
+func (r *reloader) Reload(trigger string) (*services.ReloadReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &services.ReloadReport{Trigger: trigger, ReloadedAt: time.Now().UTC()}
+	if r.envFile != nil {
+		if err := r.envFile.Load(); err != nil {
+			return nil, err
+		}
+		report.Applied = append(report.Applied, "env_file")
+	}
+
+	next, err := loadConfiguration(r.debug)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.configManager.Reload(); err != nil {
+		return nil, err
+	}
+	if err := configureOutboundProxy(next, true); err != nil {
+		return nil, err
+	}
+	report.Applied = append(report.Applied, "config_file", "outbound_proxy")
+
+	// Reopen the log file even when its path is unchanged, after logrotate moved it
+	if next.LogFile != "" || r.logOutput.Path() != "" {
+		if err := r.logOutput.Open(next.LogFile); err != nil {
+			log.Printf("Warning: %v; logging to %q as before", err, r.logOutput.Path())
+		} else {
+			report.Applied = append(report.Applied, "log_file")
+		}
+	}
+
+	services.ConfigureUserAgent(next.OutboundUserAgent)
+	services.ConfigureRetry(next.UpstreamRetry)
+	report.Applied = append(report.Applied, "user_agent", "upstream_retry")
+
+	if r.server != nil {
+		r.server.Reload(newServerConfig(next, r.resourceWatcher))
+		report.Applied = append(report.Applied, "api_auth", "proxy_validation", "proxy_limits",
+			"merge_sections", "transform_log", "proxy_chaos", "traefik_version", "dns_discovery_server")
+	}
+
+	// Rebuild the fetchers from the re-read data sources right away
+	r.resourceWatcher.SetShrinkGuard(next.ShrinkPercent, next.ShrinkConfirmations)
+	go func() {
+		if _, err := r.resourceWatcher.Reload(); err != nil {
+			log.Printf("Resource check after reload failed: %v", err)
+		}
+	}()
+	if r.serviceWatcher != nil {
+		r.serviceWatcher.Reload()
+	}
+	report.Applied = append(report.Applied, "shrink_guard", "data_source_fetchers")
+
+	report.RestartRequired = restartRequired(r.running, next)
+	if len(report.RestartRequired) > 0 {
+		log.Printf("Settings reloaded (%s); changes to %v take effect after a restart", trigger, report.RestartRequired)
+	} else {
+		log.Printf("Settings reloaded (%s)", trigger)
+	}
+	return report, nil
+}
+
+// restartRequired lists the variables whose change only takes effect after
+// a restart: listeners, storage, intervals and the long-running workers
+func restartRequired(running, next Configuration) []string {
+	changed := []string{}
+	check := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, name)
+		}
+	}
+	check("PORT", running.Port, next.Port)
+	check("API_SOCKET_PATH", running.SocketPath, next.SocketPath)
+	check("API_SOCKET_MODE", running.SocketMode, next.SocketMode)
+	check("API_SOCKET_ONLY", running.SocketOnly, next.SocketOnly)
+	check("DB_PATH", running.DBPath, next.DBPath)
+	check("SQLITE_DRIVER", running.SQLiteDriver, next.SQLiteDriver)
+	check("DB_DSN", running.DBDSN, next.DBDSN)
+	check("UI_PATH", running.UIPath, next.UIPath)
+	check("CONFIG_DIR", running.ConfigDir, next.ConfigDir)
+	check("TRAEFIK_CONF_DIR", running.TraefikConfDir, next.TraefikConfDir)
+	check("TRAEFIK_STATIC_CONFIG_PATH", running.TraefikStaticConfigPath, next.TraefikStaticConfigPath)
+	check("TRAEFIK_ACCESS_LOG_PATH", running.TraefikAccessLogPath, next.TraefikAccessLogPath)
+	check("PANGOLIN_API_URL", running.PangolinAPIURL, next.PangolinAPIURL)
+	// An unset TRAEFIK_API_URL was auto-discovered at startup
+	if os.Getenv("TRAEFIK_API_URL") != "" {
+		check("TRAEFIK_API_URL", running.TraefikAPIURL, next.TraefikAPIURL)
+	}
+	check("FORWARD_AUTH_URL", running.ForwardAuthURL, next.ForwardAuthURL)
+	check("CHECK_INTERVAL_SECONDS", running.CheckInterval, next.CheckInterval)
+	check("GENERATE_INTERVAL_SECONDS", running.GenerateInterval, next.GenerateInterval)
+	check("SERVICE_INTERVAL_SECONDS", running.ServiceInterval, next.ServiceInterval)
+	check("ENABLE_FILE_CONFIG", running.FileConfig, next.FileConfig)
+	check("DEBUG", running.Debug, next.Debug)
+	check("ALLOW_CORS", running.AllowCORS, next.AllowCORS)
+	check("CORS_ORIGIN", running.CORSOrigin, next.CORSOrigin)
+	check("CONFIG_WEBHOOK_*", running.ConfigWebhook, next.ConfigWebhook)
+	check("PANGOLIN_BREAKER_*", fmt.Sprint(running.BreakerThreshold, running.BreakerCooldown),
+		fmt.Sprint(next.BreakerThreshold, next.BreakerCooldown))
+	check("MTLS_SIGNER", running.CertSigner, next.CertSigner)
+	check("BACKUP_DIR", running.BackupDir, next.BackupDir)
+	check("BACKUP_INTERVAL_HOURS", running.BackupInterval, next.BackupInterval)
+	check("BACKUP_KEEP", running.BackupKeep, next.BackupKeep)
+	check("BACKUP_S3_*", running.S3Backup, next.S3Backup)
+	check("BACKUP_S3_INTERVAL_HOURS", running.S3BackupInterval, next.S3BackupInterval)
+	check("BACKUP_ENCRYPTION_PASSPHRASE", running.BackupPassphrase, next.BackupPassphrase)
+	check("ENABLE_PPROF", running.Pprof, next.Pprof)
+	check("MIGRATE_LEGACY_RESOURCE_IDS", running.MigrateResourceIDs, next.MigrateResourceIDs)
+	return changed
+}
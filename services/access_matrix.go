@@ -0,0 +1,257 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// AccessMatrixEntry is one exposed host in the access review: how requests
+// are authenticated, where they may come from and how TLS is set up
+type AccessMatrixEntry struct {
+	Host        string   `json:"host"`
+	ResourceID  string   `json:"resource_id"`
+	EntryPoints []string `json:"entry_points"`
+	// Auth lists forwardAuth, basicAuth, digestAuth and mTLS, or "none"
+	Auth        []string `json:"auth"`
+	IPAllowList []string `json:"ip_allow_list"`
+	// TLS is "mtls", "hardened:<profile>" or "default"
+	TLS           string   `json:"tls"`
+	TLSDomains    []string `json:"tls_domains"`
+	HTTPSRedirect string   `json:"https_redirect"`
+	// Middlewares are the names of the MM middlewares the host runs through,
+	// from the resource, its entrypoints and the default chain
+	Middlewares []string `json:"middlewares"`
+}
+
+// accessMiddleware is a middleware as far as the access review cares
+type accessMiddleware struct {
+	name   string
+	typ    string
+	config string
+}
+
+// authMiddlewareTypes are the middleware types that authenticate requests
+var authMiddlewareTypes = map[string]bool{
+	"forwardAuth": true,
+	"basicAuth":   true,
+	"digestAuth":  true,
+}
+
+// BuildAccessMatrix lists every active resource with its auth mechanisms, IP
+// restrictions and TLS posture from Middleware Manager's own data, sorted by
+// host. Middlewares defined outside MM are not known and not listed.
+func BuildAccessMatrix(db *sql.DB) ([]AccessMatrixEntry, error) {
+	var mtlsEnabled int
+	if err := db.QueryRow("SELECT COALESCE(enabled, 0) FROM mtls_config WHERE id = 1").Scan(&mtlsEnabled); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	hardeningMode := models.TLSHardeningPerResource
+	if err := db.QueryRow("SELECT COALESCE(tls_hardening_mode, 'per_resource') FROM security_config WHERE id = 1").Scan(&hardeningMode); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	resourceMiddlewares, err := loadAccessMiddlewares(db, `
+		SELECT rm.resource_id, m.name, m.type, m.config
+		FROM resource_middlewares rm
+		JOIN middlewares m ON m.id = rm.middleware_id
+		WHERE rm.expires_at IS NULL OR rm.expires_at > ?
+		ORDER BY rm.resource_id, rm.priority DESC, m.name
+	`, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	entrypointMiddlewares, err := loadAccessMiddlewares(db, `
+		SELECT em.entrypoint, m.name, m.type, m.config
+		FROM entrypoint_middlewares em
+		JOIN middlewares m ON m.id = em.middleware_id
+		ORDER BY em.entrypoint, em.priority DESC, m.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defaultChain, err := loadAccessMiddlewares(db, `
+		SELECT '', m.name, m.type, m.config
+		FROM default_chain_middlewares dc
+		JOIN middlewares m ON m.id = dc.middleware_id
+		ORDER BY dc.priority DESC, m.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, host, COALESCE(entrypoints, ''), COALESCE(tls_domains, ''), COALESCE(mtls_enabled, 0),
+		       COALESCE(tls_hardening_enabled, 0), COALESCE(tls_hardening_profile, 'hardened'),
+		       COALESCE(tls_hardening_opt_out, 0), COALESCE(forward_auth_enabled, 0),
+		       COALESCE(https_redirect, 'inherit'), COALESCE(default_chain_excluded, 0)
+		FROM resources
+		WHERE status = 'active'
+		ORDER BY host, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AccessMatrixEntry{}
+	for rows.Next() {
+		var id, host, entrypoints, tlsDomains, profile, httpsRedirect string
+		var resourceMTLS, hardeningEnabled, hardeningOptOut, forwardAuth, chainExcluded int
+		if err := rows.Scan(&id, &host, &entrypoints, &tlsDomains, &resourceMTLS,
+			&hardeningEnabled, &profile, &hardeningOptOut, &forwardAuth,
+			&httpsRedirect, &chainExcluded); err != nil {
+			return nil, err
+		}
+
+		entry := AccessMatrixEntry{
+			Host:          host,
+			ResourceID:    id,
+			EntryPoints:   splitList(entrypoints),
+			Auth:          []string{},
+			IPAllowList:   []string{},
+			TLS:           "default",
+			TLSDomains:    splitList(tlsDomains),
+			HTTPSRedirect: httpsRedirect,
+			Middlewares:   []string{},
+		}
+		if len(entry.EntryPoints) == 0 {
+			entry.EntryPoints = []string{"websecure"}
+		}
+
+		var middlewares []accessMiddleware
+		for _, ep := range entry.EntryPoints {
+			middlewares = append(middlewares, entrypointMiddlewares[ep]...)
+		}
+		if chainExcluded == 0 {
+			middlewares = append(middlewares, defaultChain[""]...)
+		}
+		middlewares = append(middlewares, resourceMiddlewares[id]...)
+
+		auth := make(map[string]bool)
+		if forwardAuth == 1 {
+			auth["forwardAuth"] = true
+		}
+		mtls := resourceMTLS == 1 && mtlsEnabled == 1
+		if mtls {
+			auth["mTLS"] = true
+			entry.TLS = "mtls"
+		} else if models.EffectiveTLSHardening(hardeningMode, hardeningEnabled == 1, hardeningOptOut == 1) {
+			entry.TLS = "hardened:" + profile
+		}
+
+		seen := make(map[string]bool)
+		for _, mw := range middlewares {
+			if seen[mw.name] {
+				continue
+			}
+			seen[mw.name] = true
+			entry.Middlewares = append(entry.Middlewares, mw.name)
+			if authMiddlewareTypes[mw.typ] {
+				auth[mw.typ] = true
+			}
+			if mw.typ == "ipAllowList" || mw.typ == "ipWhiteList" {
+				entry.IPAllowList = append(entry.IPAllowList, sourceRanges(mw)...)
+			}
+		}
+
+		for mechanism := range auth {
+			entry.Auth = append(entry.Auth, mechanism)
+		}
+		sort.Strings(entry.Auth)
+		if len(entry.Auth) == 0 {
+			entry.Auth = []string{"none"}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// loadAccessMiddlewares groups the middlewares a query returns by its first
+// column
+func loadAccessMiddlewares(db *sql.DB, query string, args ...interface{}) (map[string][]accessMiddleware, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grouped := make(map[string][]accessMiddleware)
+	for rows.Next() {
+		var key string
+		var mw accessMiddleware
+		if err := rows.Scan(&key, &mw.name, &mw.typ, &mw.config); err != nil {
+			return nil, err
+		}
+		grouped[key] = append(grouped[key], mw)
+	}
+	return grouped, rows.Err()
+}
+
+// sourceRanges returns the source ranges of an IP allowlist middleware
+func sourceRanges(mw accessMiddleware) []string {
+	var config struct {
+		SourceRange []string `json:"sourceRange"`
+	}
+	if err := json.Unmarshal([]byte(mw.config), &config); err != nil {
+		log.Printf("Failed to parse IP allowlist middleware %s: %v", mw.name, err)
+		return nil
+	}
+	return config.SourceRange
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// accessMatrixCSVHeader are the columns of the CSV access review
+var accessMatrixCSVHeader = []string{
+	"host", "resource_id", "entry_points", "auth", "ip_allow_list",
+	"tls", "tls_domains", "https_redirect", "middlewares",
+}
+
+// WriteAccessMatrixCSV writes the access review as CSV; list cells are
+// joined with semicolons
+func WriteAccessMatrixCSV(w io.Writer, entries []AccessMatrixEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(accessMatrixCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			e.Host, e.ResourceID, strings.Join(e.EntryPoints, ";"), strings.Join(e.Auth, ";"),
+			strings.Join(e.IPAllowList, ";"), e.TLS, strings.Join(e.TLSDomains, ";"),
+			e.HTTPSRedirect, strings.Join(e.Middlewares, ";"),
+		}
+		for i, cell := range record {
+			record[i] = csvCell(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell keeps spreadsheets from evaluating a cell that starts like a formula
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildAccessMatrix(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, stmt := range []string{
+		`UPDATE mtls_config SET enabled = 1 WHERE id = 1`,
+		`INSERT INTO middlewares (id, name, type, config) VALUES
+			('mw-basic', 'office-login', 'basicAuth', '{"users": ["u:p"]}'),
+			('mw-ip', 'office-only', 'ipAllowList', '{"sourceRange": ["10.0.0.0/8", "192.168.1.0/24"]}'),
+			('mw-gzip', 'gzip', 'compress', '{}')`,
+		`INSERT INTO default_chain_middlewares (middleware_id) VALUES ('mw-gzip')`,
+		`INSERT INTO entrypoint_middlewares (entrypoint, middleware_id) VALUES ('internal', 'mw-ip')`,
+		`INSERT INTO resources (id, host, service_id, org_id, site_id, status, entrypoints, tls_domains) VALUES
+			('res-open', 'open.example.com', 's', 'o', 'site', 'active', 'websecure', ''),
+			('res-basic', 'admin.example.com', 's', 'o', 'site', 'active', 'internal', 'example.com,*.example.com'),
+			('res-gone', 'gone.example.com', 's', 'o', 'site', 'disabled', 'websecure', '')`,
+		`INSERT INTO resources (id, host, service_id, org_id, site_id, status, entrypoints, mtls_enabled, forward_auth_enabled, default_chain_excluded)
+			VALUES ('res-mtls', 'secure.example.com', 's', 'o', 'site', 'active', 'websecure', 1, 1, 1)`,
+		`INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES ('res-basic', 'mw-basic', 200)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	entries, err := BuildAccessMatrix(db.DB)
+	if err != nil {
+		t.Fatalf("BuildAccessMatrix: %v", err)
+	}
+	var hosts []string
+	for _, e := range entries {
+		hosts = append(hosts, e.Host)
+	}
+	if want := []string{"admin.example.com", "open.example.com", "secure.example.com"}; !reflect.DeepEqual(hosts, want) {
+		t.Fatalf("hosts = %v, want %v", hosts, want)
+	}
+
+	admin, open, secure := entries[0], entries[1], entries[2]
+	if !reflect.DeepEqual(admin.Auth, []string{"basicAuth"}) ||
+		!reflect.DeepEqual(admin.IPAllowList, []string{"10.0.0.0/8", "192.168.1.0/24"}) ||
+		!reflect.DeepEqual(admin.Middlewares, []string{"office-only", "gzip", "office-login"}) ||
+		!reflect.DeepEqual(admin.TLSDomains, []string{"example.com", "*.example.com"}) {
+		t.Errorf("admin entry = %+v", admin)
+	}
+	if !reflect.DeepEqual(open.Auth, []string{"none"}) || open.TLS != "default" || len(open.IPAllowList) != 0 {
+		t.Errorf("open entry = %+v", open)
+	}
+	if !reflect.DeepEqual(secure.Auth, []string{"forwardAuth", "mTLS"}) || secure.TLS != "mtls" || len(secure.Middlewares) != 0 {
+		t.Errorf("secure entry = %+v", secure)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAccessMatrixCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteAccessMatrixCSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != strings.Join(accessMatrixCSVHeader, ",") {
+		t.Fatalf("csv = %q", buf.String())
+	}
+	if want := "admin.example.com,res-basic,internal,basicAuth,10.0.0.0/8;192.168.1.0/24,default,example.com;*.example.com,inherit,office-only;gzip;office-login"; lines[1] != want {
+		t.Errorf("csv row = %q, want %q", lines[1], want)
+	}
+}
+
+func TestCSVCell_EscapesFormulas(t *testing.T) {
+	for value, want := range map[string]string{"=cmd()": "'=cmd()", "@x": "'@x", "app.example.com": "app.example.com", "": ""} {
+		if got := csvCell(value); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// apiTokenPrefix marks MM API token secrets so they are recognisable in
+// configs and secret scanners
+const apiTokenPrefix = "mmt_"
+
+// apiTokenLastUsedInterval limits how often verifying a token writes its
+// last_used_at, so read-heavy clients do not cause a write per request
+const apiTokenLastUsedInterval = time.Minute
+
+// AdminTokenID is the ID reported for the token configured with SetAdminToken
+const AdminTokenID = "env-admin"
+
+// APITokenService issues and verifies bearer tokens for the management API.
+// Secrets are stored as SHA-256 hashes.
+type APITokenService struct {
+	db  *database.DB
+	now func() time.Time
+
+	mu         sync.RWMutex
+	adminToken string
+}
+
+// NewAPITokenService creates a new API token service
+func NewAPITokenService(db *database.DB) *APITokenService {
+	return &APITokenService{db: db, now: time.Now}
+}
+
+// SetAdminToken sets a fixed admin token that is accepted without being
+// stored, to create the first tokens and for automation (empty disables it)
+func (s *APITokenService) SetAdminToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.adminToken = strings.TrimSpace(token)
+}
+
+// HasAdminToken reports whether a fixed admin token is configured
+func (s *APITokenService) HasAdminToken() bool {
+	return s.currentAdminToken() != ""
+}
+
+func (s *APITokenService) currentAdminToken() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.adminToken
+}
+
+// ErrUnknownUser is returned when a token is issued to a user that does not exist
+var ErrUnknownUser = errors.New("user not found")
+
+const apiTokenColumns = `t.id, t.name, t.scope, t.user_id, COALESCE(u.role, ''), t.prefix,
+	t.expires_at, t.last_used_at, t.created_at`
+
+// apiTokenFrom joins the role of each token's user
+const apiTokenFrom = " FROM api_tokens t LEFT JOIN users u ON u.id = t.user_id"
+
+// Create stores a new token; the returned secret cannot be read again.
+// It returns ErrUnknownUser when req.UserID is not a user.
+func (s *APITokenService) Create(req models.APITokenRequest) (*models.CreatedAPIToken, error) {
+	var userRole string
+	if req.UserID != "" {
+		err := s.db.QueryRow("SELECT role FROM users WHERE id = ?", req.UserID).Scan(&userRole)
+		if err == sql.ErrNoRows {
+			return nil, ErrUnknownUser
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := apiTokenPrefix + hex.EncodeToString(raw)
+
+	now := s.now().UTC()
+	token := models.APIToken{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		Scope:     req.Scope,
+		UserID:    req.UserID,
+		Prefix:    secret[:len(apiTokenPrefix)+8],
+		CreatedAt: now,
+	}
+	token.ResolveRole(userRole)
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			return nil, err
+		}
+		expires := now.Add(d)
+		token.ExpiresAt = &expires
+	}
+
+	var expiresAt interface{}
+	if token.ExpiresAt != nil {
+		expiresAt = *token.ExpiresAt
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO api_tokens (id, name, scope, user_id, prefix, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, token.ID, token.Name, token.Scope, token.UserID, token.Prefix, hashAPIToken(secret), expiresAt, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return &models.CreatedAPIToken{APIToken: token, Secret: secret}, nil
+}
+
+// List returns every stored token, newest first
+func (s *APITokenService) List() ([]models.APIToken, error) {
+	rows, err := s.db.Query("SELECT " + apiTokenColumns + apiTokenFrom + " ORDER BY t.created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke deletes a token, or returns sql.ErrNoRows
+func (s *APITokenService) Revoke(id string) error {
+	result, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Verify returns the token a secret belongs to, or nil when the secret is
+// unknown or expired
+func (s *APITokenService) Verify(secret string) (*models.APIToken, error) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return nil, nil
+	}
+	hash := hashAPIToken(secret)
+
+	if admin := s.currentAdminToken(); admin != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(hashAPIToken(admin))) == 1 {
+		return &models.APIToken{ID: AdminTokenID, Name: "API_ADMIN_TOKEN", Scope: models.APITokenScopeAdmin, Role: models.RoleAdmin}, nil
+	}
+
+	token, err := scanAPIToken(s.db.QueryRow("SELECT "+apiTokenColumns+apiTokenFrom+" WHERE t.token_hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return nil, nil
+	}
+	// A token whose user was deleted has no role left
+	if token.Role == "" {
+		return nil, nil
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenLastUsedInterval {
+		if _, err := s.db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", now, token.ID); err == nil {
+			token.LastUsedAt = &now
+		}
+	}
+	return token, nil
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*models.APIToken, error) {
+	var token models.APIToken
+	var userRole string
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&token.ID, &token.Name, &token.Scope, &token.UserID, &userRole, &token.Prefix,
+		&expiresAt, &lastUsedAt, &token.CreatedAt); err != nil {
+		return nil, err
+	}
+	token.ResolveRole(userRole)
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestAPITokenServiceLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tokens := NewAPITokenService(db)
+	tokens.now = func() time.Time { return now }
+
+	created, err := tokens.Create(models.APITokenRequest{Name: "ci", Scope: models.APITokenScopeRead, ExpiresIn: "1h"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(created.Secret, created.Prefix) || created.Secret == created.Prefix {
+		t.Fatalf("expected the prefix to start the secret, got %q and %q", created.Prefix, created.Secret)
+	}
+
+	var stored string
+	if err := db.QueryRow("SELECT token_hash FROM api_tokens WHERE id = ?", created.ID).Scan(&stored); err != nil {
+		t.Fatalf("read hash: %v", err)
+	}
+	if stored == created.Secret || strings.Contains(stored, created.Secret) {
+		t.Fatalf("expected only a hash of the secret to be stored")
+	}
+
+	token, err := tokens.Verify(created.Secret)
+	if err != nil || token == nil || token.ID != created.ID || token.Role != models.RoleViewer {
+		t.Fatalf("Verify = %+v, %v", token, err)
+	}
+	if token.LastUsedAt == nil {
+		t.Errorf("expected last_used_at to be recorded")
+	}
+	if token, err := tokens.Verify(created.Secret + "x"); err != nil || token != nil {
+		t.Errorf("expected an unknown secret to be rejected, got %+v, %v", token, err)
+	}
+
+	now = now.Add(time.Hour)
+	if token, err := tokens.Verify(created.Secret); err != nil || token != nil {
+		t.Errorf("expected an expired token to be rejected, got %+v, %v", token, err)
+	}
+
+	if err := tokens.Revoke(created.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := tokens.Revoke(created.ID); err != sql.ErrNoRows {
+		t.Errorf("expected revoking twice to return sql.ErrNoRows, got %v", err)
+	}
+	if list, err := tokens.List(); err != nil || len(list) != 0 {
+		t.Errorf("List after revoke = %v, %v", list, err)
+	}
+}
+
+func TestAPITokenServiceAdminToken(t *testing.T) {
+	tokens := NewAPITokenService(newTestDB(t))
+	if token, _ := tokens.Verify("bootstrap"); token != nil {
+		t.Fatalf("expected no admin token before it is set")
+	}
+
+	tokens.SetAdminToken("bootstrap")
+	token, err := tokens.Verify("bootstrap")
+	if err != nil || token == nil || token.ID != AdminTokenID || token.Role != models.RoleAdmin {
+		t.Fatalf("Verify admin token = %+v, %v", token, err)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// AssignmentExpirer removes middleware assignments whose expires_at has passed
+// and records who owns the affected resource so they can be notified
+type AssignmentExpirer struct {
+	db       *database.DB
+	now      func() time.Time
+	stopChan chan struct{}
+	stopOnce sync.Once
+	// webhooks is told about each removed assignment
+	webhooks *WebhookService
+}
+
+// NewAssignmentExpirer creates a new assignment expirer
+func NewAssignmentExpirer(db *database.DB) *AssignmentExpirer {
+	return &AssignmentExpirer{
+		db:       db,
+		now:      time.Now,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// SetWebhooks sets the service told about removed assignments; call it
+// before Start
+func (e *AssignmentExpirer) SetWebhooks(webhooks *WebhookService) {
+	e.webhooks = webhooks
+}
+
+// Start periodically removes expired assignments until Stop is called
+func (e *AssignmentExpirer) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := e.ExpireAssignments(); err != nil {
+				log.Printf("Error expiring middleware assignments: %v", err)
+			}
+		case <-e.stopChan:
+			return
+		}
+	}
+}
+
+// Stop stops the background expiry loop
+func (e *AssignmentExpirer) Stop() {
+	e.stopOnce.Do(func() { close(e.stopChan) })
+}
+
+// ExpireAssignments deletes every assignment that has expired and returns the
+// expiration records written for them
+func (e *AssignmentExpirer) ExpireAssignments() ([]models.AssignmentExpiration, error) {
+	now := e.now().UTC()
+	rows, err := e.db.Query(`
+		SELECT rm.resource_id, COALESCE(r.host, ''), rm.middleware_id, COALESCE(m.name, ''),
+		       COALESCE(r.owner, ''), COALESCE(r.contact, ''),
+		       COALESCE(m.owner, ''), COALESCE(m.contact, ''), rm.expires_at
+		FROM resource_middlewares rm
+		LEFT JOIN resources r ON rm.resource_id = r.id
+		LEFT JOIN middlewares m ON rm.middleware_id = m.id
+		WHERE rm.expires_at IS NOT NULL AND rm.expires_at <= ?
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired assignments: %w", err)
+	}
+
+	var expired []models.AssignmentExpiration
+	for rows.Next() {
+		var exp models.AssignmentExpiration
+		var mwOwner, mwContact string
+		if err := rows.Scan(&exp.ResourceID, &exp.ResourceHost, &exp.MiddlewareID, &exp.MiddlewareName,
+			&exp.Owner, &exp.Contact, &mwOwner, &mwContact, &exp.ExpiresAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan expired assignment: %w", err)
+		}
+		if exp.Owner == "" {
+			exp.Owner, exp.Contact = mwOwner, mwContact
+		}
+		exp.ID = uuid.New().String()
+		exp.RemovedAt = now
+		expired = append(expired, exp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	var removed []models.AssignmentExpiration
+	err = e.db.WithTransaction(func(tx *sql.Tx) error {
+		for _, exp := range expired {
+			// Re-check the expiry so an assignment renewed meanwhile is kept
+			result, err := tx.Exec(
+				"DELETE FROM resource_middlewares WHERE resource_id = ? AND middleware_id = ? AND expires_at <= ?",
+				exp.ResourceID, exp.MiddlewareID, now,
+			)
+			if err != nil {
+				return err
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				continue
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO assignment_expirations
+				    (id, resource_id, resource_host, middleware_id, middleware_name, owner, contact, expires_at, removed_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, exp.ID, exp.ResourceID, exp.ResourceHost, exp.MiddlewareID, exp.MiddlewareName,
+				exp.Owner, exp.Contact, exp.ExpiresAt, exp.RemovedAt); err != nil {
+				return err
+			}
+			removed = append(removed, exp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove expired assignments: %w", err)
+	}
+
+	for _, exp := range removed {
+		notify := "no owner recorded"
+		if exp.Owner != "" || exp.Contact != "" {
+			notify = fmt.Sprintf("notify owner %q <%s>", exp.Owner, exp.Contact)
+		}
+		log.Printf("Removed expired middleware %s (%s) from resource %s (%s); %s",
+			exp.MiddlewareName, exp.MiddlewareID, exp.ResourceHost, exp.ResourceID, notify)
+		e.webhooks.Emit(models.EventMiddlewareRemoved, exp)
+	}
+	return removed, nil
+}
+
+// ListExpirations returns the most recent expiration records
+func (e *AssignmentExpirer) ListExpirations(limit int) ([]models.AssignmentExpiration, error) {
+	rows, err := e.db.Query(`
+		SELECT id, resource_id, resource_host, middleware_id, middleware_name, owner, contact, expires_at, removed_at
+		FROM assignment_expirations ORDER BY removed_at DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expirations := []models.AssignmentExpiration{}
+	for rows.Next() {
+		var exp models.AssignmentExpiration
+		if err := rows.Scan(&exp.ID, &exp.ResourceID, &exp.ResourceHost, &exp.MiddlewareID, &exp.MiddlewareName,
+			&exp.Owner, &exp.Contact, &exp.ExpiresAt, &exp.RemovedAt); err != nil {
+			return nil, err
+		}
+		expirations = append(expirations, exp)
+	}
+	return expirations, rows.Err()
+}
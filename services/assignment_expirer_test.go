@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignmentExpirerRemovesExpiredAssignments(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status, owner, contact)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active', 'team-a', 'a@example.com')
+	`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO middlewares (id, name, type, config) VALUES
+		('mw-temp', 'incident-allowlist', 'ipAllowList', '{}'),
+		('mw-later', 'later', 'headers', '{}'),
+		('mw-perm', 'permanent', 'headers', '{}')
+	`); err != nil {
+		t.Fatalf("insert middlewares: %v", err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, row := range []struct {
+		id      string
+		expires interface{}
+	}{
+		{"mw-temp", now.Add(-time.Minute)},
+		{"mw-later", now.Add(time.Hour)},
+		{"mw-perm", nil},
+	} {
+		if _, err := db.Exec(
+			"INSERT INTO resource_middlewares (resource_id, middleware_id, priority, expires_at) VALUES ('res-1', ?, 100, ?)",
+			row.id, row.expires,
+		); err != nil {
+			t.Fatalf("insert assignment %s: %v", row.id, err)
+		}
+	}
+
+	expirer := NewAssignmentExpirer(db)
+	expirer.now = func() time.Time { return now }
+
+	expired, err := expirer.ExpireAssignments()
+	if err != nil {
+		t.Fatalf("ExpireAssignments: %v", err)
+	}
+	if len(expired) != 1 || expired[0].MiddlewareID != "mw-temp" {
+		t.Fatalf("expected only mw-temp to expire, got %+v", expired)
+	}
+	if expired[0].Owner != "team-a" || expired[0].Contact != "a@example.com" {
+		t.Errorf("expected resource owner to be recorded, got %q <%s>", expired[0].Owner, expired[0].Contact)
+	}
+
+	var remaining int
+	if err := db.QueryRow("SELECT COUNT(*) FROM resource_middlewares WHERE resource_id = 'res-1'").Scan(&remaining); err != nil {
+		t.Fatalf("count assignments: %v", err)
+	}
+	if remaining != 2 {
+		t.Errorf("expected 2 remaining assignments, got %d", remaining)
+	}
+
+	list, err := expirer.ListExpirations(10)
+	if err != nil {
+		t.Fatalf("ListExpirations: %v", err)
+	}
+	if len(list) != 1 || list[0].MiddlewareName != "incident-allowlist" || list[0].ResourceHost != "app.example.com" {
+		t.Errorf("unexpected expiration records: %+v", list)
+	}
+
+	// A second run has nothing left to do
+	if expired, err := expirer.ExpireAssignments(); err != nil || len(expired) != 0 {
+		t.Errorf("expected no further expirations, got %d (err %v)", len(expired), err)
+	}
+}
+
+func TestAssignmentExpirerFallsBackToMiddlewareOwner(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')
+	`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO middlewares (id, name, type, config, owner, contact)
+		VALUES ('mw-temp', 'incident-allowlist', 'ipAllowList', '{}', 'sec-team', '#security')
+	`); err != nil {
+		t.Fatalf("insert middleware: %v", err)
+	}
+	if _, err := db.Exec(
+		"INSERT INTO resource_middlewares (resource_id, middleware_id, priority, expires_at) VALUES ('res-1', 'mw-temp', 100, ?)",
+		time.Now().UTC().Add(-time.Second),
+	); err != nil {
+		t.Fatalf("insert assignment: %v", err)
+	}
+
+	expired, err := NewAssignmentExpirer(db).ExpireAssignments()
+	if err != nil {
+		t.Fatalf("ExpireAssignments: %v", err)
+	}
+	if len(expired) != 1 || expired[0].Owner != "sec-team" || expired[0].Contact != "#security" {
+		t.Fatalf("expected middleware owner to be recorded, got %+v", expired)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultBackupKeep is how many local snapshots are kept when BACKUP_KEEP is unset
+const DefaultBackupKeep = 7
+
+// LocalTarget stores backups in a directory on the local disk, by default
+// next to the database
+type LocalTarget struct {
+	dir string
+}
+
+// NewLocalTarget creates a local backup target, creating dir if needed
+func NewLocalTarget(dir string) (*LocalTarget, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("backup directory is required")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return &LocalTarget{dir: dir}, nil
+}
+
+// Name describes the target for logs and status
+func (t *LocalTarget) Name() string {
+	return "file://" + filepath.ToSlash(t.dir)
+}
+
+// path maps a key to a file below the backup directory
+func (t *LocalTarget) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid backup key %q", key)
+	}
+	return filepath.Join(t.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes an object through a temporary file, so a crash never leaves a
+// partial snapshot under its final name
+func (t *LocalTarget) Put(_ context.Context, key string, data []byte) error {
+	path, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Get reads an object; a missing one returns an error matching os.ErrNotExist
+func (t *LocalTarget) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := t.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// List returns the objects under prefix, oldest first
+func (t *LocalTarget) List(_ context.Context, prefix string) ([]BackupObject, error) {
+	path, err := t.path(prefix)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []BackupObject
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, BackupObject{
+			Key:          prefix + entry.Name(),
+			Size:         info.Size(),
+			LastModified: info.ModTime().UTC(),
+		})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete removes an object
+func (t *LocalTarget) Delete(_ context.Context, key string) error {
+	path, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackups_LocalRotationAndRestore(t *testing.T) {
+	db := newTestDB(t)
+	dir := filepath.Join(t.TempDir(), "backups")
+	target, err := NewLocalTarget(dir)
+	if err != nil {
+		t.Fatalf("NewLocalTarget: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES ('mw1', 'before', 'headers', '{}')`); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := false
+	backups := NewBackups(db, target, func() (*ProxiedTraefikConfig, error) {
+		return &ProxiedTraefikConfig{}, nil
+	}, func() { restored = true })
+	backups.SetRetention(2)
+
+	var last []BackupObject
+	for i := 0; i < 3; i++ {
+		if last, err = backups.Run(context.Background()); err != nil {
+			t.Fatalf("Run %d: %v", i, err)
+		}
+	}
+
+	objects, err := backups.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 4 {
+		t.Fatalf("expected 2 snapshots and 2 exports after rotation, got %+v", objects)
+	}
+	snapshots, _ := os.ReadDir(filepath.Join(dir, "db"))
+	if len(snapshots) != 2 {
+		t.Errorf("expected 2 snapshot files, got %d", len(snapshots))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "db", last[0].ID)); err != nil {
+		t.Errorf("the newest snapshot was rotated out: %v", err)
+	}
+	if status := backups.Status(); status.Keep != 2 || !strings.HasPrefix(status.Target, "file://") {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	if _, err := db.Exec(`UPDATE middlewares SET name = 'after' WHERE id = 'mw1'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := backups.RestoreID(context.Background(), "../"+last[0].ID, ""); err != ErrNotSnapshot {
+		t.Errorf("expected ErrNotSnapshot for a path, got %v", err)
+	}
+	if err := backups.RestoreID(context.Background(), "missing.db", ""); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-exist error for an unknown ID, got %v", err)
+	}
+	if err := backups.RestoreID(context.Background(), last[0].ID, ""); err != nil {
+		t.Fatalf("RestoreID: %v", err)
+	}
+	var name string
+	if err := db.QueryRow(`SELECT name FROM middlewares WHERE id = 'mw1'`).Scan(&name); err != nil || name != "before" {
+		t.Errorf("name after restore = %q, %v", name, err)
+	}
+	if !restored {
+		t.Error("afterRestore was not called")
+	}
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Settings configures an S3-compatible bucket (AWS S3, MinIO, R2, ...) as a
+// backup target
+type S3Settings struct {
+	// Endpoint is the base URL, e.g. https://s3.eu-west-1.amazonaws.com or
+	// http://minio:9000
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to every object key, e.g. "mm/prod/"
+	Prefix string
+	// PathStyle addresses the bucket as Endpoint/Bucket instead of
+	// Bucket.Endpoint; MinIO and most self-hosted stores need it
+	PathStyle bool
+	// SSE requests server-side encryption: "AES256" or "aws:kms"
+	SSE string
+	// SSEKMSKeyID selects the KMS key when SSE is "aws:kms"
+	SSEKMSKeyID string
+}
+
+// S3Target stores backups in an S3-compatible bucket, signing requests with
+// AWS Signature Version 4
+type S3Target struct {
+	settings S3Settings
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Target creates an S3 backup target
+func NewS3Target(settings S3Settings) (*S3Target, error) {
+	if settings.Endpoint == "" || settings.Bucket == "" {
+		return nil, fmt.Errorf("S3 endpoint and bucket are required")
+	}
+	if settings.AccessKeyID == "" || settings.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access key ID and secret access key are required")
+	}
+	if _, err := url.Parse(settings.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	switch settings.SSE {
+	case "", "AES256", "aws:kms":
+	default:
+		return nil, fmt.Errorf("unsupported S3 server-side encryption %q (use AES256 or aws:kms)", settings.SSE)
+	}
+	if settings.Region == "" {
+		settings.Region = "us-east-1"
+	}
+	return &S3Target{
+		settings: settings,
+		client:   HTTPClientWithTimeout(5 * time.Minute),
+		now:      time.Now,
+	}, nil
+}
+
+// Name describes the target for logs and status
+func (t *S3Target) Name() string {
+	return "s3://" + t.settings.Bucket + "/" + t.settings.Prefix
+}
+
+// Put uploads an object, encrypted server-side when SSE is configured
+func (t *S3Target) Put(ctx context.Context, key string, data []byte) error {
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	if t.settings.SSE != "" {
+		header.Set("X-Amz-Server-Side-Encryption", t.settings.SSE)
+		if t.settings.SSE == "aws:kms" && t.settings.SSEKMSKeyID != "" {
+			header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", t.settings.SSEKMSKeyID)
+		}
+	}
+	resp, err := t.do(ctx, http.MethodPut, t.settings.Prefix+key, nil, header, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads an object
+func (t *S3Target) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := t.do(ctx, http.MethodGet, t.settings.Prefix+key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// s3ListResult is the part of a ListObjectsV2 response the target reads
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the objects under prefix, oldest first, with keys relative to
+// the configured prefix
+func (t *S3Target) List(ctx context.Context, prefix string) ([]BackupObject, error) {
+	var objects []BackupObject
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", t.settings.Prefix+prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := t.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse S3 listing: %w", err)
+		}
+		for _, item := range result.Contents {
+			objects = append(objects, BackupObject{
+				Key:          strings.TrimPrefix(item.Key, t.settings.Prefix),
+				Size:         item.Size,
+				LastModified: item.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// s3Error is the error document S3 returns
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do sends a signed request for an object key, or for the bucket when key is
+// empty, and turns non-2xx responses into errors
+func (t *S3Target) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	endpoint, _ := url.Parse(t.settings.Endpoint)
+	u := *endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if t.settings.PathStyle {
+		path += "/" + t.settings.Bucket
+	} else {
+		u.Host = t.settings.Bucket + "." + u.Host
+	}
+	if key != "" {
+		path += "/" + key
+	}
+	if path == "" {
+		path = "/"
+	}
+	u.Path = path
+	u.RawPath = s3EscapePath(path)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	t.sign(req, body)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var s3err s3Error
+	if xml.Unmarshal(data, &s3err) == nil && s3err.Code != "" {
+		return nil, fmt.Errorf("S3 %s %s: %s: %s", method, key, s3err.Code, s3err.Message)
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s", method, key, resp.Status)
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (t *S3Target) sign(req *http.Request, body []byte) {
+	signAWSRequest(req, body, t.now(), awsCredentials{
+		AccessKeyID:     t.settings.AccessKeyID,
+		SecretAccessKey: t.settings.SecretAccessKey,
+	}, t.settings.Region, "s3")
+}
+
+// awsCredentials sign requests to AWS APIs
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+}
+
+// signAWSRequest adds AWS Signature Version 4 headers for service in region
+// to req. Host, Range and the x-amz-* headers are signed.
+func signAWSRequest(req *http.Request, body []byte, now time.Time, creds awsCredentials, region, service string) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes everything but the unreserved characters, as
+// Signature Version 4 requires
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3EscapePath escapes each segment of an object path
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery encodes a query sorted by key, as Signature Version 4
+// requires
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
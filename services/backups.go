@@ -0,0 +1,340 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+)
+
+// BackupObject is a stored backup
+type BackupObject struct {
+	// ID is the file name of the key, which restores address snapshots by
+	ID           string    `json:"id"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// BackupTarget stores backup objects under keys relative to its own prefix
+type BackupTarget interface {
+	Name() string
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string) ([]BackupObject, error)
+}
+
+// BackupDeleter is implemented by targets that can delete objects, so old
+// backups are rotated out. Other targets rely on their own expiry, such as
+// bucket lifecycle rules.
+type BackupDeleter interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// Backup object key prefixes. Every run writes new keys that sort by time and
+// are never overwritten, so bucket lifecycle rules can expire each kind by
+// prefix and versioned buckets keep one version per object.
+const (
+	backupDBPrefix     = "db/"
+	backupConfigPrefix = "config/"
+)
+
+// BackupStatus reports the scheduled backups
+type BackupStatus struct {
+	Target      string         `json:"target"`
+	Interval    string         `json:"interval,omitempty"`
+	Keep        int            `json:"keep,omitempty"`
+	LastRunAt   *time.Time     `json:"last_run_at,omitempty"`
+	LastError   string         `json:"last_error,omitempty"`
+	LastObjects []BackupObject `json:"last_objects,omitempty"`
+}
+
+// Backups snapshots the database and exports the served config to a backup
+// target, on a schedule or on demand, and restores database snapshots
+type Backups struct {
+	db     *database.DB
+	target BackupTarget
+	// exportConfig returns the served config; nil skips config exports
+	exportConfig func() (*ProxiedTraefikConfig, error)
+	// afterRestore runs after a snapshot was restored, e.g. to drop caches
+	afterRestore func()
+	// passphrase encrypts uploaded objects; empty uploads them in the clear
+	passphrase string
+	// keep is how many runs are kept on targets that can delete; 0 keeps all
+	keep int
+
+	mu     sync.Mutex
+	status BackupStatus
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBackups creates backups for target
+func NewBackups(db *database.DB, target BackupTarget, exportConfig func() (*ProxiedTraefikConfig, error), afterRestore func()) *Backups {
+	return &Backups{
+		db:           db,
+		target:       target,
+		exportConfig: exportConfig,
+		afterRestore: afterRestore,
+		status:       BackupStatus{Target: target.Name()},
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// SetPassphrase encrypts the objects of later runs with passphrase, since
+// snapshots hold CA keys and middleware secrets. Encrypted objects get the
+// ".enc" suffix and are decrypted with it on restore.
+func (b *Backups) SetPassphrase(passphrase string) {
+	b.passphrase = passphrase
+}
+
+// SetRetention keeps the objects of the last keep runs after each run and
+// deletes older ones, when the target can delete objects. 0 keeps all.
+func (b *Backups) SetRetention(keep int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.keep = keep
+	b.status.Keep = keep
+}
+
+// Start runs a backup every interval until Stop is called
+func (b *Backups) Start(interval time.Duration) {
+	b.mu.Lock()
+	b.status.Interval = interval.String()
+	b.mu.Unlock()
+	log.Printf("Backing up to %s every %s", b.target.Name(), interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := b.Run(context.Background()); err != nil {
+				log.Printf("Scheduled backup to %s failed: %v", b.target.Name(), err)
+			}
+		case <-b.stopChan:
+			return
+		}
+	}
+}
+
+// Stop stops scheduled backups
+func (b *Backups) Stop() {
+	b.stopOnce.Do(func() { close(b.stopChan) })
+}
+
+// Status returns the state of the last backup run
+func (b *Backups) Status() BackupStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := b.status
+	status.LastObjects = append([]BackupObject{}, b.status.LastObjects...)
+	return status
+}
+
+// Run uploads a database snapshot and, when available, an export of the
+// served config. Both objects of a run share a timestamp and suffix.
+func (b *Backups) Run(ctx context.Context) ([]BackupObject, error) {
+	objects, err := b.run(ctx)
+	if err == nil {
+		b.prune(ctx)
+	}
+
+	now := time.Now()
+	b.mu.Lock()
+	b.status.LastRunAt = &now
+	b.status.LastObjects = objects
+	b.status.LastError = ""
+	if err != nil {
+		b.status.LastError = err.Error()
+	}
+	b.mu.Unlock()
+	return objects, err
+}
+
+func (b *Backups) run(ctx context.Context) ([]BackupObject, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	stamp := now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+
+	dir, err := os.MkdirTemp("", "mm-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	if err := b.db.Snapshot(ctx, snapshotPath); err != nil {
+		return nil, err
+	}
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []BackupObject
+	key, snapshot, err := b.seal(backupDBPrefix+"middleware-manager-"+stamp+".db", snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.target.Put(ctx, key, snapshot); err != nil {
+		return nil, fmt.Errorf("uploading database snapshot: %w", err)
+	}
+	objects = append(objects, BackupObject{ID: backupID(key), Key: key, Size: int64(len(snapshot)), LastModified: now})
+
+	if b.exportConfig == nil {
+		return objects, nil
+	}
+	config, err := b.exportConfig()
+	if err != nil {
+		return objects, fmt.Errorf("exporting config: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := config.WriteJSON(&buf); err != nil {
+		return objects, fmt.Errorf("exporting config: %w", err)
+	}
+	key, export, err := b.seal(backupConfigPrefix+"traefik-config-"+stamp+".json", buf.Bytes())
+	if err != nil {
+		return objects, err
+	}
+	if err := b.target.Put(ctx, key, export); err != nil {
+		return objects, fmt.Errorf("uploading config export: %w", err)
+	}
+	objects = append(objects, BackupObject{ID: backupID(key), Key: key, Size: int64(len(export)), LastModified: now})
+	return objects, nil
+}
+
+// prune deletes the snapshots and config exports beyond the retention,
+// oldest first
+func (b *Backups) prune(ctx context.Context) {
+	b.mu.Lock()
+	keep := b.keep
+	b.mu.Unlock()
+	deleter, ok := b.target.(BackupDeleter)
+	if !ok || keep <= 0 {
+		return
+	}
+
+	for _, prefix := range []string{backupDBPrefix, backupConfigPrefix} {
+		objects, err := b.target.List(ctx, prefix)
+		if err != nil {
+			log.Printf("Listing backups to rotate in %s failed: %v", b.target.Name(), err)
+			return
+		}
+		sort.Slice(objects, func(i, j int) bool {
+			if !objects[i].LastModified.Equal(objects[j].LastModified) {
+				return objects[i].LastModified.Before(objects[j].LastModified)
+			}
+			return objects[i].Key < objects[j].Key
+		})
+		for i := 0; i < len(objects)-keep; i++ {
+			if err := deleter.Delete(ctx, objects[i].Key); err != nil {
+				log.Printf("Deleting old backup %s failed: %v", objects[i].Key, err)
+			}
+		}
+	}
+}
+
+// backupID returns the file name of a key
+func backupID(key string) string {
+	return path.Base(key)
+}
+
+// seal encrypts an object when a passphrase is set, adding the suffix
+func (b *Backups) seal(key string, data []byte) (string, []byte, error) {
+	if b.passphrase == "" {
+		return key, data, nil
+	}
+	sealed, err := EncryptBundle(b.passphrase, data)
+	if err != nil {
+		return "", nil, fmt.Errorf("encrypting %s: %w", key, err)
+	}
+	return key + EncryptedBundleSuffix, sealed, nil
+}
+
+// List returns the stored database snapshots and config exports
+func (b *Backups) List(ctx context.Context) ([]BackupObject, error) {
+	snapshots, err := b.target.List(ctx, backupDBPrefix)
+	if err != nil {
+		return nil, err
+	}
+	exports, err := b.target.List(ctx, backupConfigPrefix)
+	if err != nil {
+		return nil, err
+	}
+	objects := append(snapshots, exports...)
+	for i := range objects {
+		objects[i].ID = backupID(objects[i].Key)
+	}
+	return objects, nil
+}
+
+// ErrNotSnapshot is returned when restoring a key that is not a database
+// snapshot; config exports are derived from the database and not restored
+var ErrNotSnapshot = errors.New("only database snapshots under db/ can be restored")
+
+// Restore downloads a database snapshot and restores it into the live
+// database. Encrypted snapshots are decrypted with passphrase, or with the
+// configured one when it is empty; decryption authenticates the whole
+// snapshot, so nothing is applied unless it is intact.
+func (b *Backups) Restore(ctx context.Context, key, passphrase string) error {
+	if !strings.HasPrefix(key, backupDBPrefix) || strings.Contains(key, "..") {
+		return ErrNotSnapshot
+	}
+	data, err := b.target.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", key, err)
+	}
+	if IsEncryptedBundle(data) {
+		if passphrase == "" {
+			passphrase = b.passphrase
+		}
+		if data, err = DecryptBundle(passphrase, data); err != nil {
+			return err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "mm-restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	if err := os.WriteFile(snapshotPath, data, 0600); err != nil {
+		return err
+	}
+
+	if err := b.db.RestoreSnapshot(ctx, snapshotPath); err != nil {
+		return err
+	}
+	log.Printf("Restored database snapshot %s from %s", key, b.target.Name())
+	if b.afterRestore != nil {
+		b.afterRestore()
+	}
+	return nil
+}
+
+// RestoreID restores the database snapshot with the given ID, the file name
+// of its key
+func (b *Backups) RestoreID(ctx context.Context, id, passphrase string) error {
+	if id == "" || strings.ContainsAny(id, "/\\") {
+		return ErrNotSnapshot
+	}
+	return b.Restore(ctx, backupDBPrefix+id, passphrase)
+}
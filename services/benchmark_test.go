@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// benchSizes are the router counts every benchmark runs at
+var benchSizes = []int{100, 1000, 5000}
+
+// quietLogs silences the per-resource logging for the duration of a benchmark
+func quietLogs(b *testing.B) {
+	b.Helper()
+	previous := log.Writer()
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(previous) })
+}
+
+// genPangolinConfig builds a Pangolin-style provider payload with n routers,
+// each with its own service and redirect middleware
+func genPangolinConfig(n int) map[string]interface{} {
+	routers := make(map[string]interface{}, n)
+	services := make(map[string]interface{}, n)
+	middlewares := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("%d-router", i)
+		service := fmt.Sprintf("%d-service", i)
+		routers[name] = map[string]interface{}{
+			"entryPoints": []interface{}{"websecure"},
+			"middlewares": []interface{}{"redirect-to-https"},
+			"service":     service,
+			"rule":        fmt.Sprintf("Host(`app%d.example.com`)", i),
+			"priority":    float64(100),
+			"tls":         map[string]interface{}{"certResolver": "letsencrypt"},
+		}
+		services[service] = map[string]interface{}{
+			"loadBalancer": map[string]interface{}{
+				"servers": []interface{}{map[string]interface{}{"url": fmt.Sprintf("http://10.0.%d.%d:80", i/250, i%250)}},
+			},
+		}
+	}
+	middlewares["redirect-to-https"] = map[string]interface{}{
+		"redirectScheme": map[string]interface{}{"scheme": "https"},
+	}
+	return map[string]interface{}{
+		"http": map[string]interface{}{
+			"routers":     routers,
+			"services":    services,
+			"middlewares": middlewares,
+		},
+	}
+}
+
+// genProxiedConfig decodes a generated payload into the proxy's config type
+func genProxiedConfig(b *testing.B, n int) *ProxiedTraefikConfig {
+	b.Helper()
+	data, err := json.Marshal(genPangolinConfig(n))
+	if err != nil {
+		b.Fatalf("marshal generated config: %v", err)
+	}
+	var config ProxiedTraefikConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		b.Fatalf("unmarshal generated config: %v", err)
+	}
+	return &config
+}
+
+// seedBenchResources inserts n active resources matching genPangolinConfig's
+// routers, each with a middleware assignment
+func seedBenchResources(b *testing.B, db *database.DB, n int) {
+	b.Helper()
+	err := db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES ('bench-headers', 'bench-headers', 'headers', '{"customRequestHeaders":{"X-Bench":"1"}}')`); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			id := fmt.Sprintf("res-%d", i)
+			if _, err := tx.Exec(
+				`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status) VALUES (?, ?, ?, ?, 'org', 'site', 'active')`,
+				id, fmt.Sprintf("%d-router", i), fmt.Sprintf("app%d.example.com", i), fmt.Sprintf("%d-service", i),
+			); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES (?, 'bench-headers', 100)`, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatalf("seed resources: %v", err)
+	}
+}
+
+func BenchmarkGetMergedConfig(b *testing.B) {
+	quietLogs(b)
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("routers=%d", n), func(b *testing.B) {
+			payload, err := json.Marshal(genPangolinConfig(n))
+			if err != nil {
+				b.Fatalf("marshal payload: %v", err)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write(payload)
+			}))
+			defer server.Close()
+
+			db := newTestDB(b)
+			seedBenchResources(b, db, n)
+			cp := NewConfigProxy(db, newTestConfigManager(b), server.URL)
+			cp.httpClient = server.Client()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cp.InvalidateCache()
+				if _, err := cp.GetMergedConfig(); err != nil {
+					b.Fatalf("GetMergedConfig() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFetchResourceData(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("resources=%d", n), func(b *testing.B) {
+			db := newTestDB(b)
+			seedBenchResources(b, db, n)
+			cp := NewConfigProxy(db, newTestConfigManager(b), "")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resources, err := cp.fetchResourceData()
+				if err != nil {
+					b.Fatalf("fetchResourceData() error = %v", err)
+				}
+				if len(resources) != n {
+					b.Fatalf("expected %d resources, got %d", n, len(resources))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkResourceWatcherSync(b *testing.B) {
+	quietLogs(b)
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("resources=%d", n), func(b *testing.B) {
+			collection := &models.ResourceCollection{Resources: make([]models.Resource, n)}
+			for i := range collection.Resources {
+				collection.Resources[i] = models.Resource{
+					ID:          fmt.Sprintf("%d-router", i),
+					Host:        fmt.Sprintf("app%d.example.com", i),
+					ServiceID:   fmt.Sprintf("%d-service", i),
+					OrgID:       "org",
+					SiteID:      "site",
+					Status:      "active",
+					Entrypoints: "websecure",
+				}
+			}
+
+			rw := &ResourceWatcher{
+				db:            newTestDB(b),
+				fetcher:       &mockResourceFetcher{resources: collection},
+				configManager: newTestConfigManager(b),
+				stopChan:      make(chan struct{}),
+				httpClient:    GetHTTPClient(),
+			}
+			// The first sync creates every resource; measure the steady state
+			if _, err := rw.checkResources(); err != nil {
+				b.Fatalf("initial sync error = %v", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := rw.checkResources(); err != nil {
+					b.Fatalf("checkResources() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeProxiedConfig(b *testing.B) {
+	cp := &ConfigProxy{}
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("routers=%d", n), func(b *testing.B) {
+			payload, err := json.Marshal(genPangolinConfig(n))
+			if err != nil {
+				b.Fatalf("marshal payload: %v", err)
+			}
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(payload)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := cp.decodeProxiedConfig(bytes.NewReader(payload)); err != nil {
+					b.Fatalf("decodeProxiedConfig() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNormalizeMiddlewareOrder(b *testing.B) {
+	cp := &ConfigProxy{}
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("middlewares=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				config := genProxiedConfig(b, 0)
+				for j := 0; j < n; j++ {
+					config.HTTP.Middlewares[fmt.Sprintf("%d-headers", j)] = map[string]interface{}{
+						"headers": map[string]interface{}{"customRequestHeaders": map[string]interface{}{"X-Id": fmt.Sprint(j)}},
+					}
+				}
+				b.StartTimer()
+				cp.normalizeMiddlewareOrder(config)
+			}
+		})
+	}
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	cp := &ConfigProxy{}
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("routers=%d", n), func(b *testing.B) {
+			config := genProxiedConfig(b, n)
+			cp.normalizeMiddlewareOrder(config)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := config.WriteJSON(io.Discard); err != nil {
+					b.Fatalf("WriteJSON() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeRouterAllocations guards the per-router allocation cost of decoding
+// the provider payload so a regression shows up in a plain `go test` run
+func TestDecodeRouterAllocations(t *testing.T) {
+	const routers = 1000
+	cp := &ConfigProxy{}
+	payload, err := json.Marshal(genPangolinConfig(routers))
+	if err != nil {
+		t.Fatalf("marshal generated config: %v", err)
+	}
+
+	allocs := testing.AllocsPerRun(5, func() {
+		if _, err := cp.decodeProxiedConfig(bytes.NewReader(payload)); err != nil {
+			t.Fatalf("decodeProxiedConfig() error = %v", err)
+		}
+	})
+	if perRouter := allocs / routers; perRouter > 80 {
+		t.Fatalf("decoding allocates %.1f objects per router, want <= 80", perRouter)
+	}
+	t.Logf("decoding allocates %.1f objects per router", allocs/routers)
+}
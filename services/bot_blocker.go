@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// botBlockMiddleware denies every request routed through it; it is attached to
+// the generated routers that match blocked user agents
+const botBlockMiddleware = "mm-bot-block"
+
+// maxBotListSize caps downloaded lists so a bad source URL cannot exhaust memory
+const maxBotListSize = 2 << 20
+
+// BotListUpdater refreshes bot lists that have a source URL and an update interval
+type BotListUpdater struct {
+	db       *database.DB
+	client   *http.Client
+	now      func() time.Time
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBotListUpdater creates a new bot list updater
+func NewBotListUpdater(db *database.DB) *BotListUpdater {
+	return &BotListUpdater{
+		db:       db,
+		client:   HTTPClientWithTimeout(30 * time.Second),
+		now:      time.Now,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start periodically refreshes the lists that are due
+func (u *BotListUpdater) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			u.RefreshDue(context.Background())
+		case <-u.stopChan:
+			return
+		}
+	}
+}
+
+// Stop stops the background refresh loop
+func (u *BotListUpdater) Stop() {
+	u.stopOnce.Do(func() { close(u.stopChan) })
+}
+
+// RefreshDue refreshes every list whose update interval has elapsed and returns
+// how many were updated
+func (u *BotListUpdater) RefreshDue(ctx context.Context) int {
+	rows, err := u.db.Query(`
+		SELECT id, update_interval, last_updated_at FROM bot_lists
+		WHERE source_url != '' AND update_interval > 0
+	`)
+	if err != nil {
+		log.Printf("Error fetching bot lists to refresh: %v", err)
+		return 0
+	}
+
+	var due []string
+	now := u.now()
+	for rows.Next() {
+		var id string
+		var interval int
+		var lastUpdated *time.Time
+		if err := rows.Scan(&id, &interval, &lastUpdated); err != nil {
+			log.Printf("Failed to scan bot list: %v", err)
+			continue
+		}
+		if lastUpdated == nil || !now.Before(lastUpdated.Add(time.Duration(interval)*time.Minute)) {
+			due = append(due, id)
+		}
+	}
+	rows.Close()
+
+	updated := 0
+	for _, id := range due {
+		if _, err := u.Refresh(ctx, id); err != nil {
+			log.Printf("Error refreshing bot list %s: %v", id, err)
+			continue
+		}
+		updated++
+	}
+	return updated
+}
+
+// Refresh downloads a list from its source URL and replaces its patterns. The
+// error is also recorded on the list so it shows up in the API.
+func (u *BotListUpdater) Refresh(ctx context.Context, id string) (int, error) {
+	var sourceURL string
+	if err := u.db.QueryRow("SELECT source_url FROM bot_lists WHERE id = ?", id).Scan(&sourceURL); err != nil {
+		return 0, err
+	}
+	if sourceURL == "" {
+		return 0, fmt.Errorf("bot list %s has no source URL", id)
+	}
+
+	patterns, err := u.download(ctx, sourceURL)
+	if err != nil {
+		if _, dbErr := u.db.Exec("UPDATE bot_lists SET last_error = ? WHERE id = ?", err.Error(), id); dbErr != nil {
+			log.Printf("Error recording bot list failure: %v", dbErr)
+		}
+		return 0, err
+	}
+
+	patternsJSON, err := json.Marshal(patterns)
+	if err != nil {
+		return 0, err
+	}
+	now := u.now()
+	if _, err := u.db.Exec(
+		"UPDATE bot_lists SET patterns = ?, last_updated_at = ?, last_error = '', updated_at = ? WHERE id = ?",
+		string(patternsJSON), now, now, id,
+	); err != nil {
+		return 0, fmt.Errorf("failed to store bot list: %w", err)
+	}
+
+	log.Printf("Refreshed bot list %s from %s (%d patterns)", id, sourceURL, len(patterns))
+	return len(patterns), nil
+}
+
+// download fetches and parses a list, rejecting empty results so a broken
+// source does not silently unblock everything
+func (u *BotListUpdater) download(ctx context.Context, sourceURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list download returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBotListSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read list: %w", err)
+	}
+	if len(data) > maxBotListSize {
+		return nil, fmt.Errorf("list exceeds %d bytes", maxBotListSize)
+	}
+
+	patterns := models.ParseBotPatterns(data)
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("list contains no patterns")
+	}
+	return patterns, nil
+}
+
+// loadBotLists attaches the user agents blocked on each resource
+func (cp *ConfigProxy) loadBotLists(resourceMap map[string]*resourceData) error {
+	rows, err := cp.db.Query(`
+		SELECT rb.resource_id, b.patterns
+		FROM resource_bot_lists rb
+		JOIN bot_lists b ON b.id = rb.list_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var resID, patternsJSON string
+		if err := rows.Scan(&resID, &patternsJSON); err != nil {
+			log.Printf("Failed to scan bot list: %v", err)
+			continue
+		}
+		data, ok := resourceMap[resID]
+		if !ok {
+			continue
+		}
+		var patterns []string
+		if err := json.Unmarshal([]byte(patternsJSON), &patterns); err != nil {
+			log.Printf("Skipping invalid bot list for resource %s: %v", resID, err)
+			continue
+		}
+		data.BlockedUserAgents = append(data.BlockedUserAgents, patterns...)
+	}
+
+	return rows.Err()
+}
+
+// applyBotBlocking adds, for each resource with blocked user agents, a router
+// matching the same rule plus the User-Agent header. It outranks the resource's
+// router and sends matching requests through a deny-all middleware.
+func (cp *ConfigProxy) applyBotBlocking(config *ProxiedTraefikConfig, resources []*resourceData) {
+	major := cp.TraefikCompatibility().Major
+	// Traefik v3 renamed HeadersRegexp to HeaderRegexp
+	matcher := "HeaderRegexp"
+	denyType := "ipAllowList"
+	if major == 2 {
+		matcher = "HeadersRegexp"
+		denyType = traefikMiddlewareType(denyType, major)
+	}
+
+	for _, resource := range resources {
+		regex := models.UserAgentRegex(resource.BlockedUserAgents)
+		if regex == "" {
+			continue
+		}
+
+		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, router = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+		if routerKey == "" || router.Rule == "" {
+			continue
+		}
+
+		blockRouter := &OrderedRouter{
+			EntryPoints: append([]string{}, router.EntryPoints...),
+			Middlewares: []string{botBlockMiddleware},
+			Service:     router.Service,
+			Rule:        fmt.Sprintf("(%s) && %s(`User-Agent`, `%s`)", router.Rule, matcher, regex),
+		}
+		// Without an explicit priority Traefik ranks by rule length, which the longer rule already wins
+		if router.Priority > 0 {
+			blockRouter.Priority = router.Priority + 1
+		}
+		if router.TLS != nil {
+			tls := *router.TLS
+			blockRouter.TLS = &tls
+		}
+		config.HTTP.Routers[routerKey+"-mm-botblock"] = blockRouter
+
+		// 255.255.255.255 is never a client address, so every request is rejected with 403
+		config.HTTP.Middlewares[botBlockMiddleware] = map[string]interface{}{
+			denyType: map[string]interface{}{
+				"sourceRange": []interface{}{"255.255.255.255/32"},
+			},
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBotListUpdaterRefresh(t *testing.T) {
+	db := newTestDB(t)
+
+	var status = http.StatusOK
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("# scanners\nsqlmap\nnuclei\n"))
+	}))
+	defer source.Close()
+
+	if _, err := db.Exec(`INSERT INTO bot_lists (id, name, category, patterns, source_url, update_interval)
+		VALUES ('remote', 'Remote', 'scanners', '["old"]', ?, 60)`, source.URL); err != nil {
+		t.Fatalf("insert bot list: %v", err)
+	}
+
+	updater := NewBotListUpdater(db)
+	updater.client = source.Client()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	updater.now = func() time.Time { return now }
+
+	if n := updater.RefreshDue(context.Background()); n != 1 {
+		t.Fatalf("expected the never-updated list to be refreshed, got %d", n)
+	}
+	var patterns string
+	if err := db.QueryRow("SELECT patterns FROM bot_lists WHERE id = 'remote'").Scan(&patterns); err != nil {
+		t.Fatalf("query patterns: %v", err)
+	}
+	if patterns != `["sqlmap","nuclei"]` {
+		t.Fatalf("patterns = %s", patterns)
+	}
+
+	// Not due again until the interval has elapsed
+	now = now.Add(30 * time.Minute)
+	if n := updater.RefreshDue(context.Background()); n != 0 {
+		t.Fatalf("expected no refresh within the interval, got %d", n)
+	}
+
+	// A failing source keeps the previous patterns and records the error
+	status = http.StatusInternalServerError
+	now = now.Add(time.Hour)
+	if n := updater.RefreshDue(context.Background()); n != 0 {
+		t.Fatalf("expected the failed refresh not to count, got %d", n)
+	}
+	var lastError string
+	if err := db.QueryRow("SELECT patterns, last_error FROM bot_lists WHERE id = 'remote'").Scan(&patterns, &lastError); err != nil {
+		t.Fatalf("query list: %v", err)
+	}
+	if patterns != `["sqlmap","nuclei"]` || !strings.Contains(lastError, "500") {
+		t.Fatalf("unexpected state after failure: patterns=%s last_error=%q", patterns, lastError)
+	}
+}
+
+func TestApplyBotBlocking(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	newConfig := func() *ProxiedTraefikConfig {
+		return &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"app-router": {
+					EntryPoints: []string{"websecure"},
+					Rule:        "Host(`app.example.com`)",
+					Service:     "app-service",
+					Priority:    100,
+					TLS:         &OrderedTLSConfig{CertResolver: "letsencrypt"},
+				},
+			},
+			Middlewares: map[string]interface{}{},
+		}}
+	}
+	resources := []*resourceData{
+		{ID: "res-1", PangolinRouterID: "app-router", Host: "app.example.com", BlockedUserAgents: []string{"GPTBot", "CCBot"}},
+		{ID: "res-2", PangolinRouterID: "other-router", Host: "other.example.com"},
+	}
+
+	config := newConfig()
+	cp.applyBotBlocking(config, resources)
+
+	block := config.HTTP.Routers["app-router-mm-botblock"]
+	if block == nil {
+		t.Fatalf("block router not added: %v", config.HTTP.Routers)
+	}
+	wantRule := "(Host(`app.example.com`)) && HeaderRegexp(`User-Agent`, `(?i)(CCBot|GPTBot)`)"
+	if block.Rule != wantRule || block.Priority != 101 || block.Service != "app-service" ||
+		block.TLS == nil || block.TLS.CertResolver != "letsencrypt" || block.Middlewares[0] != botBlockMiddleware {
+		t.Errorf("unexpected block router: %+v", block)
+	}
+	if _, ok := config.HTTP.Middlewares[botBlockMiddleware].(map[string]interface{})["ipAllowList"]; !ok {
+		t.Errorf("deny middleware missing: %v", config.HTTP.Middlewares)
+	}
+	if len(config.HTTP.Routers) != 2 {
+		t.Errorf("expected only one block router, got %v", config.HTTP.Routers)
+	}
+
+	// Traefik v2 uses the older matcher and middleware names
+	cp.SetTraefikVersion("v2.11")
+	config = newConfig()
+	cp.applyBotBlocking(config, resources)
+	if rule := config.HTTP.Routers["app-router-mm-botblock"].Rule; !strings.Contains(rule, "HeadersRegexp(`User-Agent`") {
+		t.Errorf("expected the v2 matcher, got %s", rule)
+	}
+	if _, ok := config.HTTP.Middlewares[botBlockMiddleware].(map[string]interface{})["ipWhiteList"]; !ok {
+		t.Errorf("expected ipWhiteList for v2: %v", config.HTTP.Middlewares)
+	}
+}
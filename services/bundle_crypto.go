@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Encrypted bundles are backups and exports sealed with a passphrase. The
+// layout is
+//
+//	magic "MMENC" | version 1 | PBKDF2 iterations (uint32 BE) | salt (16) | nonce (12) | ciphertext
+//
+// The key is PBKDF2-HMAC-SHA256 of the passphrase, and the ciphertext is
+// AES-256-GCM with the header as additional data, so a wrong passphrase and
+// any change to the header or ciphertext fail to open.
+const (
+	bundleMagic      = "MMENC"
+	bundleVersion    = 1
+	bundleSaltSize   = 16
+	bundleNonceSize  = 12
+	bundleHeaderSize = len(bundleMagic) + 1 + 4 + bundleSaltSize + bundleNonceSize
+	// BundleIterations is the PBKDF2 work factor of new bundles
+	BundleIterations = 600000
+	// bundleMaxIterations bounds the work a crafted header can demand
+	bundleMaxIterations = 10000000
+)
+
+// EncryptedBundleSuffix is appended to the names of encrypted backup objects
+const EncryptedBundleSuffix = ".enc"
+
+// ErrBundleDecrypt is returned when a bundle cannot be opened, because the
+// passphrase is wrong or the bundle was modified or truncated
+var ErrBundleDecrypt = errors.New("cannot decrypt bundle: wrong passphrase or corrupted data")
+
+// ErrBundlePassphrase is returned when an encrypted bundle is opened without
+// a passphrase
+var ErrBundlePassphrase = errors.New("bundle is encrypted and no passphrase is configured")
+
+// IsEncryptedBundle reports whether data starts like an encrypted bundle
+func IsEncryptedBundle(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(bundleMagic))
+}
+
+// EncryptBundle seals plaintext with passphrase
+func EncryptBundle(passphrase string, plaintext []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is required")
+	}
+	header := make([]byte, bundleHeaderSize)
+	n := copy(header, bundleMagic)
+	header[n] = bundleVersion
+	binary.BigEndian.PutUint32(header[n+1:], BundleIterations)
+	if _, err := rand.Read(header[n+5:]); err != nil {
+		return nil, err
+	}
+	salt := header[n+5 : n+5+bundleSaltSize]
+	nonce := header[n+5+bundleSaltSize:]
+
+	aead, err := bundleAEAD(passphrase, salt, BundleIterations)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// DecryptBundle opens a bundle sealed by EncryptBundle
+func DecryptBundle(passphrase string, data []byte) ([]byte, error) {
+	if !IsEncryptedBundle(data) {
+		return nil, fmt.Errorf("not an encrypted bundle")
+	}
+	if passphrase == "" {
+		return nil, ErrBundlePassphrase
+	}
+	if len(data) < bundleHeaderSize {
+		return nil, ErrBundleDecrypt
+	}
+	n := len(bundleMagic)
+	if data[n] != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", data[n])
+	}
+	iterations := binary.BigEndian.Uint32(data[n+1:])
+	if iterations == 0 || iterations > bundleMaxIterations {
+		return nil, ErrBundleDecrypt
+	}
+	header := data[:bundleHeaderSize]
+	salt := header[n+5 : n+5+bundleSaltSize]
+	nonce := header[n+5+bundleSaltSize:]
+
+	aead, err := bundleAEAD(passphrase, salt, int(iterations))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[bundleHeaderSize:], header)
+	if err != nil {
+		return nil, ErrBundleDecrypt
+	}
+	return plaintext, nil
+}
+
+func bundleAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -1,36 +1,52 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"database/sql"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
-	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
 	"software.sslmate.com/src/go-pkcs12"
 )
 
 // CertGenerator handles certificate generation and management
 type CertGenerator struct {
 	db *sql.DB
+	fs util.FileSystem
+	// signer issues client certificates from an external CA; nil signs them
+	// with the CA in the database
+	signer CertSigner
 }
 
 // NewCertGenerator creates a new certificate generator
 func NewCertGenerator(db *sql.DB) *CertGenerator {
-	return &CertGenerator{db: db}
+	return &CertGenerator{db: db, fs: util.OS}
+}
+
+// SetSigner issues client certificates from an external CA. Creating the CA
+// then adopts the signer's CA certificate instead of generating a key pair.
+func (cg *CertGenerator) SetSigner(signer CertSigner) {
+	cg.signer = signer
 }
 
 // GenerateCA creates a new Certificate Authority
 func (cg *CertGenerator) GenerateCA(req models.CreateCARequest, basePath string) (*models.MTLSConfig, error) {
+	if cg.signer != nil {
+		return cg.adoptExternalCA(basePath)
+	}
+
 	// Set defaults
 	if req.ValidityDays <= 0 {
 		req.ValidityDays = 1825 // 5 years
@@ -145,44 +161,32 @@ func (cg *CertGenerator) GenerateCA(req models.CreateCARequest, basePath string)
 	return config, nil
 }
 
-// GenerateClientCert creates a new client certificate signed by the CA
+// GenerateClientCert creates a new client certificate signed by the CA, or
+// issued by the external signer when one is set
 func (cg *CertGenerator) GenerateClientCert(req models.CreateClientRequest) (*models.MTLSClient, error) {
 	// Set defaults
 	if req.ValidityDays <= 0 {
 		req.ValidityDays = 730 // 2 years
 	}
 
-	// Get CA from database
-	var caCertPEM, caKeyPEM, caSubject string
-	err := cg.db.QueryRow(`
-		SELECT ca_cert, ca_key, ca_subject FROM mtls_config WHERE id = 1
-	`).Scan(&caCertPEM, &caKeyPEM, &caSubject)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get CA from database: %w", err)
-	}
-
-	if caCertPEM == "" || caKeyPEM == "" {
-		return nil, fmt.Errorf("CA not configured - please create a CA first")
-	}
-
-	// Parse CA certificate
-	caCertBlock, _ := pem.Decode([]byte(caCertPEM))
-	if caCertBlock == nil {
-		return nil, fmt.Errorf("failed to decode CA certificate PEM")
-	}
-	caCert, err := x509.ParseCertificate(caCertBlock.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
-	}
-
-	// Parse CA private key
-	caKeyBlock, _ := pem.Decode([]byte(caKeyPEM))
-	if caKeyBlock == nil {
-		return nil, fmt.Errorf("failed to decode CA private key PEM")
+	// Get CA from database; an external signer only needs its certificate
+	var caCert *x509.Certificate
+	var caKey *rsa.PrivateKey
+	var err error
+	if cg.signer != nil {
+		var caKeyPEM string
+		caCert, _, caKeyPEM, err = cg.loadCACert()
+		if err == nil && caKeyPEM != "" {
+			err = fmt.Errorf("the stored CA is MM's own; create the CA again to adopt the %s CA", cg.signer.Name())
+		}
+	} else {
+		caCert, caKey, _, err = cg.loadCA()
+		if errors.Is(err, ErrExternalCA) {
+			err = fmt.Errorf("%w and no certificate signer is configured; set MTLS_SIGNER or create a new CA", err)
+		}
 	}
-	caKey, err := x509.ParsePKCS1PrivateKey(caKeyBlock.Bytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse CA private key: %w", err)
+		return nil, err
 	}
 
 	// Generate client private key
@@ -191,12 +195,6 @@ func (cg *CertGenerator) GenerateClientCert(req models.CreateClientRequest) (*mo
 		return nil, fmt.Errorf("failed to generate client private key: %w", err)
 	}
 
-	// Generate serial number
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate serial number: %w", err)
-	}
-
 	// Build client subject based on CA subject but with client name
 	clientSubject := pkix.Name{
 		CommonName: req.Name + "." + caCert.Subject.CommonName,
@@ -211,26 +209,46 @@ func (cg *CertGenerator) GenerateClientCert(req models.CreateClientRequest) (*mo
 	notBefore := time.Now()
 	notAfter := notBefore.AddDate(0, 0, req.ValidityDays)
 
-	// Create client certificate template
-	clientTemplate := x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject:      clientSubject,
-		NotBefore:    notBefore,
-		NotAfter:     notAfter,
-		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-	}
+	var clientCert *x509.Certificate
+	var chain []*x509.Certificate
+	if cg.signer != nil {
+		if clientCert, chain, err = cg.signClientCert(clientKey, clientSubject, notAfter, caCert); err != nil {
+			return nil, err
+		}
+	} else {
+		// Generate serial number
+		serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate serial number: %w", err)
+		}
 
-	// Sign client certificate with CA
-	clientCertDER, err := x509.CreateCertificate(rand.Reader, &clientTemplate, caCert, &clientKey.PublicKey, caKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create client certificate: %w", err)
+		// Create client certificate template
+		clientTemplate := x509.Certificate{
+			SerialNumber: serialNumber,
+			Subject:      clientSubject,
+			NotBefore:    notBefore,
+			NotAfter:     notAfter,
+			KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+
+		// Sign client certificate with CA
+		clientCertDER, err := x509.CreateCertificate(rand.Reader, &clientTemplate, caCert, &clientKey.PublicKey, caKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client certificate: %w", err)
+		}
+
+		// Parse the client certificate for PKCS#12
+		if clientCert, err = x509.ParseCertificate(clientCertDER); err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		chain = []*x509.Certificate{caCert}
 	}
 
 	// Encode client certificate to PEM
 	clientCertPEM := pem.EncodeToMemory(&pem.Block{
 		Type:  "CERTIFICATE",
-		Bytes: clientCertDER,
+		Bytes: clientCert.Raw,
 	})
 
 	// Encode client private key to PEM
@@ -239,39 +257,21 @@ func (cg *CertGenerator) GenerateClientCert(req models.CreateClientRequest) (*mo
 		Bytes: x509.MarshalPKCS1PrivateKey(clientKey),
 	})
 
-	// Parse the client certificate for PKCS#12
-	clientCert, err := x509.ParseCertificate(clientCertDER)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse client certificate: %w", err)
-	}
-
 	// Generate PKCS#12 (.p12) file
 	encoder := pkcs12.Modern
 	if req.LegacyP12 {
 		encoder = pkcs12.Legacy
 	}
-	p12Data, err := encoder.Encode(clientKey, clientCert, []*x509.Certificate{caCert}, req.P12Password)
+	p12Data, err := encoder.Encode(clientKey, clientCert, chain, req.P12Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate PKCS#12: %w", err)
 	}
 
-	// Build subject string for display
-	subjectStr := fmt.Sprintf("CN=%s", clientSubject.CommonName)
-	if len(clientSubject.Organization) > 0 {
-		subjectStr += fmt.Sprintf(", O=%s", clientSubject.Organization[0])
-	}
-	if len(clientSubject.Country) > 0 {
-		subjectStr += fmt.Sprintf(", C=%s", clientSubject.Country[0])
-	}
-
 	// Generate unique ID
 	clientID := uuid.New().String()
 
-	// Create password hint (first and last character)
-	passwordHint := ""
-	if len(req.P12Password) >= 2 {
-		passwordHint = string(req.P12Password[0]) + "***" + string(req.P12Password[len(req.P12Password)-1])
-	}
+	// An external CA may shorten the validity, so the expiry is the issued one
+	expiry := clientCert.NotAfter
 
 	// Create client record
 	client := &models.MTLSClient{
@@ -280,18 +280,21 @@ func (cg *CertGenerator) GenerateClientCert(req models.CreateClientRequest) (*mo
 		Cert:            string(clientCertPEM),
 		Key:             string(clientKeyPEM),
 		P12:             p12Data,
-		P12PasswordHint: passwordHint,
-		Subject:         subjectStr,
-		Expiry:          &notAfter,
+		P12PasswordHint: passwordHint(req.P12Password),
+		Subject:         formatClientSubject(clientCert.Subject),
+		Expiry:          &expiry,
 		Revoked:         false,
 		CreatedAt:       time.Now(),
 	}
+	if cg.signer != nil {
+		client.Signer = cg.signer.Name()
+	}
 
 	// Save to database
 	_, err = cg.db.Exec(`
-		INSERT INTO mtls_clients (id, name, cert, key, p12, p12_password_hint, subject, expiry, revoked, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, client.ID, client.Name, client.Cert, client.Key, client.P12, client.P12PasswordHint, client.Subject, client.Expiry, 0, client.CreatedAt)
+		INSERT INTO mtls_clients (id, name, cert, key, p12, p12_password_hint, subject, expiry, revoked, signer, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, client.ID, client.Name, client.Cert, client.Key, client.P12, client.P12PasswordHint, client.Subject, client.Expiry, 0, client.Signer, client.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save client to database: %w", err)
 	}
@@ -299,22 +302,101 @@ func (cg *CertGenerator) GenerateClientCert(req models.CreateClientRequest) (*mo
 	return client, nil
 }
 
+// signClientCert has the external signer issue a certificate for clientKey
+// and checks it chains to the stored CA certificate Traefik trusts
+func (cg *CertGenerator) signClientCert(clientKey *rsa.PrivateKey, subject pkix.Name, notAfter time.Time, caCert *x509.Certificate) (*x509.Certificate, []*x509.Certificate, error) {
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: subject}, clientKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), signerTimeout)
+	defer cancel()
+	cert, chain, err := cg.signer.Sign(ctx, csr, notAfter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s failed to issue the certificate: %w", cg.signer.Name(), err)
+	}
+	if err := verifyIssued(cert, chain, caCert); err != nil {
+		return nil, nil, fmt.Errorf("the certificate %s issued does not chain to the stored CA; create the CA again to adopt the current one: %w",
+			cg.signer.Name(), err)
+	}
+	return cert, chain, nil
+}
+
+// adoptExternalCA stores the external signer's CA certificate, without a
+// private key, and writes it out for Traefik
+func (cg *CertGenerator) adoptExternalCA(basePath string) (*models.MTLSConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), signerTimeout)
+	defer cancel()
+	caCert, err := cg.signer.CACertificate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s CA certificate: %w", cg.signer.Name(), err)
+	}
+	caCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})
+
+	notAfter := caCert.NotAfter
+	now := time.Now()
+	config := &models.MTLSConfig{
+		ID:            1,
+		CACert:        string(caCertPEM),
+		CACertPath:    filepath.Join(basePath, "ca", "ca.crt"),
+		CASubject:     formatClientSubject(caCert.Subject),
+		CAExpiry:      &notAfter,
+		CertsBasePath: basePath,
+		HasCA:         true,
+		Signer:        cg.signer.Name(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	_, err = cg.db.Exec(`
+		UPDATE mtls_config SET
+			ca_cert = ?,
+			ca_key = '',
+			ca_cert_path = ?,
+			ca_subject = ?,
+			ca_expiry = ?,
+			certs_base_path = ?,
+			updated_at = ?
+		WHERE id = 1
+	`, config.CACert, config.CACertPath, config.CASubject, config.CAExpiry, config.CertsBasePath, config.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save CA to database: %w", err)
+	}
+
+	if err := cg.WriteCACertToFilesystem(basePath, caCertPEM); err != nil {
+		log.Printf("Warning: Failed to write CA cert to filesystem: %v", err)
+	}
+	log.Printf("Adopted the %s CA %s for mTLS client certificates", cg.signer.Name(), config.CASubject)
+
+	return config, nil
+}
+
 // GetConfig retrieves the current mTLS configuration
 func (cg *CertGenerator) GetConfig() (*models.MTLSConfig, error) {
 	var config models.MTLSConfig
 	var caExpiry sql.NullTime
-	var enabled int
+	var enabled, publicPKI int
 
 	err := cg.db.QueryRow(`
-		SELECT id, enabled, ca_cert, ca_cert_path, ca_subject, ca_expiry, certs_base_path, created_at, updated_at
+		SELECT id, enabled, ca_cert, ca_cert_path, ca_subject, ca_expiry, certs_base_path,
+		       COALESCE(public_pki_enabled, 0), created_at, updated_at
 		FROM mtls_config WHERE id = 1
-	`).Scan(&config.ID, &enabled, &config.CACert, &config.CACertPath, &config.CASubject, &caExpiry, &config.CertsBasePath, &config.CreatedAt, &config.UpdatedAt)
+	`).Scan(&config.ID, &enabled, &config.CACert, &config.CACertPath, &config.CASubject, &caExpiry, &config.CertsBasePath, &publicPKI, &config.CreatedAt, &config.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get mTLS config: %w", err)
 	}
 
 	config.Enabled = enabled == 1
+	config.PublicPKI = publicPKI == 1
 	config.HasCA = config.CACert != ""
+	if cg.signer != nil {
+		config.Signer = cg.signer.Name()
+	}
 	if caExpiry.Valid {
 		config.CAExpiry = &caExpiry.Time
 	}
@@ -355,6 +437,29 @@ func (cg *CertGenerator) DisableMTLS() error {
 	return nil
 }
 
+// SetPublicPKI enables or disables serving the CA certificate on the public /pki/ endpoints
+func (cg *CertGenerator) SetPublicPKI(enabled bool) error {
+	if enabled {
+		config, err := cg.GetConfig()
+		if err != nil {
+			return err
+		}
+		if !config.HasCA {
+			return fmt.Errorf("cannot publish CA: CA not configured")
+		}
+	}
+
+	value := 0
+	if enabled {
+		value = 1
+	}
+	_, err := cg.db.Exec(`UPDATE mtls_config SET public_pki_enabled = ?, updated_at = ? WHERE id = 1`, value, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update public PKI setting: %w", err)
+	}
+	return nil
+}
+
 // DeleteCA removes the CA and all client certificates
 func (cg *CertGenerator) DeleteCA() error {
 	tx, err := cg.db.Begin()
@@ -373,6 +478,7 @@ func (cg *CertGenerator) DeleteCA() error {
 	_, err = tx.Exec(`
 		UPDATE mtls_config SET
 			enabled = 0,
+			public_pki_enabled = 0,
 			ca_cert = '',
 			ca_key = '',
 			ca_cert_path = '',
@@ -395,7 +501,7 @@ func (cg *CertGenerator) DeleteCA() error {
 // GetClients retrieves all client certificates
 func (cg *CertGenerator) GetClients() ([]models.MTLSClient, error) {
 	rows, err := cg.db.Query(`
-		SELECT id, name, cert, p12_password_hint, subject, expiry, revoked, revoked_at, created_at
+		SELECT id, name, cert, p12_password_hint, subject, expiry, revoked, revoked_at, signer, created_at
 		FROM mtls_clients
 		ORDER BY created_at DESC
 	`)
@@ -410,7 +516,7 @@ func (cg *CertGenerator) GetClients() ([]models.MTLSClient, error) {
 		var expiry, revokedAt sql.NullTime
 		var revoked int
 
-		err := rows.Scan(&client.ID, &client.Name, &client.Cert, &client.P12PasswordHint, &client.Subject, &expiry, &revoked, &revokedAt, &client.CreatedAt)
+		err := rows.Scan(&client.ID, &client.Name, &client.Cert, &client.P12PasswordHint, &client.Subject, &expiry, &revoked, &revokedAt, &client.Signer, &client.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan client row: %w", err)
 		}
@@ -436,9 +542,9 @@ func (cg *CertGenerator) GetClient(id string) (*models.MTLSClient, error) {
 	var revoked int
 
 	err := cg.db.QueryRow(`
-		SELECT id, name, cert, p12_password_hint, subject, expiry, revoked, revoked_at, created_at
+		SELECT id, name, cert, p12_password_hint, subject, expiry, revoked, revoked_at, signer, created_at
 		FROM mtls_clients WHERE id = ?
-	`, id).Scan(&client.ID, &client.Name, &client.Cert, &client.P12PasswordHint, &client.Subject, &expiry, &revoked, &revokedAt, &client.CreatedAt)
+	`, id).Scan(&client.ID, &client.Name, &client.Cert, &client.P12PasswordHint, &client.Subject, &expiry, &revoked, &revokedAt, &client.Signer, &client.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client: %w", err)
 	}
@@ -467,8 +573,31 @@ func (cg *CertGenerator) GetClientP12(id string) ([]byte, string, error) {
 	return p12Data, name, nil
 }
 
-// RevokeClient marks a client certificate as revoked
+// RevokeClient marks a client certificate as revoked. Certificates the
+// configured external signer issued are revoked at its CA first.
 func (cg *CertGenerator) RevokeClient(id string) error {
+	if cg.signer != nil {
+		var certPEM, signer string
+		err := cg.db.QueryRow(`SELECT cert, signer FROM mtls_clients WHERE id = ?`, id).Scan(&certPEM, &signer)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("client not found: %s", id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get client: %w", err)
+		}
+		if signer == cg.signer.Name() {
+			cert, err := parseCertPEM(certPEM)
+			if err != nil {
+				return fmt.Errorf("failed to parse client certificate: %w", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), signerTimeout)
+			defer cancel()
+			if err := cg.signer.Revoke(ctx, cert); err != nil {
+				return fmt.Errorf("%s failed to revoke the certificate: %w", signer, err)
+			}
+		}
+	}
+
 	result, err := cg.db.Exec(`
 		UPDATE mtls_clients SET revoked = 1, revoked_at = ? WHERE id = ?
 	`, time.Now(), id)
@@ -503,18 +632,18 @@ func (cg *CertGenerator) DeleteClient(id string) error {
 func (cg *CertGenerator) WriteCACertToFilesystem(basePath string, caCertPEM []byte) error {
 	// Create directories
 	caDir := filepath.Join(basePath, "ca")
-	if err := os.MkdirAll(caDir, 0755); err != nil {
+	if err := cg.fs.MkdirAll(caDir, 0755); err != nil {
 		return fmt.Errorf("failed to create CA directory: %w", err)
 	}
 
 	clientsDir := filepath.Join(basePath, "clients")
-	if err := os.MkdirAll(clientsDir, 0755); err != nil {
+	if err := cg.fs.MkdirAll(clientsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create clients directory: %w", err)
 	}
 
 	// Write CA certificate
 	certPath := filepath.Join(caDir, "ca.crt")
-	if err := os.WriteFile(certPath, caCertPEM, 0644); err != nil {
+	if err := cg.fs.WriteFile(certPath, caCertPEM, 0644); err != nil {
 		return fmt.Errorf("failed to write CA certificate: %w", err)
 	}
 
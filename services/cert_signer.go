@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Client certificate signers
+const (
+	SignerBuiltin = "builtin"
+	SignerStepCA  = "step-ca"
+	SignerVault   = "vault"
+	SignerAWSPCA  = "aws-pca"
+)
+
+// signerTimeout bounds one issuance or revocation at an external CA
+const signerTimeout = time.Minute
+
+// ErrExternalCA is returned by operations that need the CA private key, such
+// as signing a CRL, when the CA is held by an external signer
+var ErrExternalCA = errors.New("the CA private key is held by an external CA")
+
+// ErrCANotConfigured is returned by operations that need a CA before one was created
+var ErrCANotConfigured = errors.New("CA not configured")
+
+// CertSigner issues client certificates from an external CA. MM keeps the
+// inventory, PKCS#12 files and revocation state; the signer only holds the
+// CA key, for organisations that do not allow it in an application database.
+type CertSigner interface {
+	// Name identifies the signer in the config and on issued clients
+	Name() string
+	// CACertificate returns the certificate clients are issued under, which
+	// Traefik must trust
+	CACertificate(ctx context.Context) (*x509.Certificate, error)
+	// Sign issues a client certificate for csr valid until notAfter and
+	// returns it with the chain up to the CA
+	Sign(ctx context.Context, csr *x509.CertificateRequest, notAfter time.Time) (*x509.Certificate, []*x509.Certificate, error)
+	// Revoke revokes an issued certificate at the CA, so its CRL and OCSP
+	// responder list it too
+	Revoke(ctx context.Context, cert *x509.Certificate) error
+}
+
+// CertSignerSettings selects the CA client certificates are issued by
+type CertSignerSettings struct {
+	// Type is step-ca, vault or aws-pca; empty or builtin signs with the CA
+	// in the database
+	Type   string
+	StepCA StepCASettings
+	Vault  VaultPKISettings
+	AWSPCA AWSPCASettings
+}
+
+// NewCertSigner creates the signer settings select, or nil for the built-in CA
+func NewCertSigner(settings CertSignerSettings) (CertSigner, error) {
+	var signer CertSigner
+	var err error
+	// Each case assigns through err, so a failed constructor never leaves a
+	// typed nil pointer in the interface
+	switch strings.ToLower(settings.Type) {
+	case "", SignerBuiltin:
+		return nil, nil
+	case SignerStepCA:
+		var s *StepCASigner
+		if s, err = NewStepCASigner(settings.StepCA); err == nil {
+			signer = s
+		}
+	case SignerVault:
+		var s *VaultPKISigner
+		if s, err = NewVaultPKISigner(settings.Vault); err == nil {
+			signer = s
+		}
+	case SignerAWSPCA:
+		var s *AWSPCASigner
+		if s, err = NewAWSPCASigner(settings.AWSPCA); err == nil {
+			signer = s
+		}
+	default:
+		err = fmt.Errorf("unknown certificate signer %q (use builtin, step-ca, vault or aws-pca)", settings.Type)
+	}
+	return signer, err
+}
+
+// signerHTTPClient returns the client external CAs are called with, trusting
+// the PEM certificates in caFile besides the system roots when it is set
+func signerHTTPClient(caFile string) (*http.Client, error) {
+	return DataSourceHTTPClient(HTTPClientWithTimeout(signerTimeout), models.DataSourceConfig{CAFile: caFile})
+}
+
+// readSignerError reads the body of a failed response for the error message
+func readSignerError(resp *http.Response) string {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if message := strings.TrimSpace(string(data)); message != "" {
+		return resp.Status + ": " + message
+	}
+	return resp.Status
+}
+
+// parseCertChainPEM parses every certificate of a PEM bundle
+func parseCertChainPEM(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return certs, nil
+}
+
+// encodeCSR returns the PEM encoding of a certificate request
+func encodeCSR(csr *x509.CertificateRequest) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw}))
+}
+
+// verifyIssued checks that an issued certificate chains to the stored CA
+// certificate Traefik trusts, through the intermediates the signer returned
+func verifyIssued(cert *x509.Certificate, chain []*x509.Certificate, caCert *x509.Certificate) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	intermediates := x509.NewCertPool()
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultPCATemplateARN issues end-entity certificates for client authentication
+const defaultPCATemplateARN = "arn:aws:acm-pca:::template/EndEntityClientAuthCertificate/V1"
+
+// AWSPCASettings configures an AWS Private CA
+type AWSPCASettings struct {
+	// CAARN is the ARN of the private CA
+	CAARN string
+	// Region is the CA's region; empty takes it from the ARN
+	Region string
+	// Endpoint overrides https://acm-pca.<region>.amazonaws.com, e.g. for a
+	// VPC endpoint
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+	// SigningAlgorithm must match the CA's key, e.g. SHA256WITHRSA or
+	// SHA256WITHECDSA (default SHA256WITHRSA)
+	SigningAlgorithm string
+	// TemplateARN selects the certificate template (default
+	// EndEntityClientAuthCertificate/V1)
+	TemplateARN string
+}
+
+// AWSPCASigner issues client certificates through the ACM Private CA API
+type AWSPCASigner struct {
+	settings AWSPCASettings
+	client   *http.Client
+	now      func() time.Time
+	// pollInterval is the wait between GetCertificate calls while AWS issues
+	pollInterval time.Duration
+}
+
+// NewAWSPCASigner creates an AWS Private CA signer
+func NewAWSPCASigner(settings AWSPCASettings) (*AWSPCASigner, error) {
+	if settings.CAARN == "" {
+		return nil, fmt.Errorf("AWS Private CA ARN is required")
+	}
+	if settings.AccessKeyID == "" || settings.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS access key ID and secret access key are required")
+	}
+	if settings.Region == "" {
+		// arn:aws:acm-pca:<region>:<account>:certificate-authority/<id>
+		if parts := strings.Split(settings.CAARN, ":"); len(parts) > 3 {
+			settings.Region = parts[3]
+		}
+		if settings.Region == "" {
+			return nil, fmt.Errorf("AWS region is required")
+		}
+	}
+	if settings.Endpoint == "" {
+		settings.Endpoint = "https://acm-pca." + settings.Region + ".amazonaws.com"
+	}
+	if settings.SigningAlgorithm == "" {
+		settings.SigningAlgorithm = "SHA256WITHRSA"
+	}
+	if settings.TemplateARN == "" {
+		settings.TemplateARN = defaultPCATemplateARN
+	}
+	return &AWSPCASigner{
+		settings:     settings,
+		client:       HTTPClientWithTimeout(signerTimeout),
+		now:          time.Now,
+		pollInterval: time.Second,
+	}, nil
+}
+
+// Name identifies the signer
+func (s *AWSPCASigner) Name() string {
+	return SignerAWSPCA
+}
+
+// CACertificate returns the private CA's certificate
+func (s *AWSPCASigner) CACertificate(ctx context.Context) (*x509.Certificate, error) {
+	var out struct {
+		Certificate string `json:"Certificate"`
+	}
+	if err := s.call(ctx, "GetCertificateAuthorityCertificate", map[string]interface{}{
+		"CertificateAuthorityArn": s.settings.CAARN,
+	}, &out); err != nil {
+		return nil, err
+	}
+	certs, err := parseCertChainPEM(out.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("AWS Private CA certificate: %w", err)
+	}
+	return certs[0], nil
+}
+
+// Sign issues a certificate for csr and waits until AWS has issued it
+func (s *AWSPCASigner) Sign(ctx context.Context, csr *x509.CertificateRequest, notAfter time.Time) (*x509.Certificate, []*x509.Certificate, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, nil, err
+	}
+	var issued struct {
+		CertificateArn string `json:"CertificateArn"`
+	}
+	err := s.call(ctx, "IssueCertificate", map[string]interface{}{
+		"CertificateAuthorityArn": s.settings.CAARN,
+		// Blobs are base64 in the JSON protocol; encoding/json does that for []byte
+		"Csr":              []byte(encodeCSR(csr)),
+		"SigningAlgorithm": s.settings.SigningAlgorithm,
+		"TemplateArn":      s.settings.TemplateARN,
+		"Validity":         map[string]interface{}{"Type": "ABSOLUTE", "Value": notAfter.Unix()},
+		"IdempotencyToken": hex.EncodeToString(token),
+	}, &issued)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out struct {
+		Certificate      string `json:"Certificate"`
+		CertificateChain string `json:"CertificateChain"`
+	}
+	for {
+		err := s.call(ctx, "GetCertificate", map[string]interface{}{
+			"CertificateAuthorityArn": s.settings.CAARN,
+			"CertificateArn":          issued.CertificateArn,
+		}, &out)
+		if err == nil {
+			break
+		}
+		if !strings.Contains(err.Error(), "RequestInProgressException") {
+			return nil, nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("AWS Private CA did not issue %s in time: %w", issued.CertificateArn, ctx.Err())
+		case <-time.After(s.pollInterval):
+		}
+	}
+
+	certs, err := parseCertChainPEM(out.Certificate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("AWS Private CA certificate: %w", err)
+	}
+	chain, err := parseCertChainPEM(out.CertificateChain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("AWS Private CA chain: %w", err)
+	}
+	return certs[0], chain, nil
+}
+
+// Revoke revokes a certificate, adding it to the CA's CRL and OCSP responses
+func (s *AWSPCASigner) Revoke(ctx context.Context, cert *x509.Certificate) error {
+	return s.call(ctx, "RevokeCertificate", map[string]interface{}{
+		"CertificateAuthorityArn": s.settings.CAARN,
+		"CertificateSerial":       hex.EncodeToString(cert.SerialNumber.Bytes()),
+		"RevocationReason":        "UNSPECIFIED",
+	}, nil)
+}
+
+// call invokes an ACMPrivateCA action with the signed JSON protocol
+func (s *AWSPCASigner) call(ctx context.Context, action string, input, out interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.settings.Endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "ACMPrivateCA."+action)
+	signAWSRequest(req, body, s.now(), awsCredentials{
+		AccessKeyID:     s.settings.AccessKeyID,
+		SecretAccessKey: s.settings.SecretAccessKey,
+		SessionToken:    s.settings.SessionToken,
+	}, s.settings.Region, "acm-pca")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("AWS Private CA %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &awsErr) == nil && awsErr.Type != "" {
+			// __type may be prefixed with a namespace, e.g. com.amazonaws...#Name
+			errType := awsErr.Type[strings.LastIndex(awsErr.Type, "#")+1:]
+			return fmt.Errorf("AWS Private CA %s: %s: %s", action, errType, awsErr.Message)
+		}
+		return fmt.Errorf("AWS Private CA %s: %s", action, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("AWS Private CA %s: invalid response: %w", action, err)
+	}
+	return nil
+}
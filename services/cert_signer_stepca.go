@@ -0,0 +1,258 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StepCASettings configures a smallstep step-ca server with a JWK provisioner
+type StepCASettings struct {
+	// URL is the CA's base URL, e.g. https://ca.internal:9000
+	URL string
+	// Provisioner is the name of the JWK provisioner
+	Provisioner string
+	// ProvisionerKeyFile holds the provisioner's decrypted private JWK, e.g.
+	// from `step crypto jwe decrypt`
+	ProvisionerKeyFile string
+	// RootFile is the CA's root certificate, trusted for the connection
+	RootFile string
+}
+
+// StepCASigner issues client certificates through step-ca's sign API,
+// authorising each request with a one-time token from the provisioner key
+type StepCASigner struct {
+	settings StepCASettings
+	key      *ecdsa.PrivateKey
+	kid      string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewStepCASigner creates a step-ca signer
+func NewStepCASigner(settings StepCASettings) (*StepCASigner, error) {
+	if settings.URL == "" || settings.Provisioner == "" || settings.ProvisionerKeyFile == "" {
+		return nil, fmt.Errorf("step-ca URL, provisioner and provisioner key file are required")
+	}
+	data, err := os.ReadFile(filepath.Clean(settings.ProvisionerKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read step-ca provisioner key: %w", err)
+	}
+	key, kid, err := parseProvisionerJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	client, err := signerHTTPClient(settings.RootFile)
+	if err != nil {
+		return nil, err
+	}
+	settings.URL = strings.TrimSuffix(settings.URL, "/")
+	return &StepCASigner{settings: settings, key: key, kid: kid, client: client, now: time.Now}, nil
+}
+
+// Name identifies the signer
+func (s *StepCASigner) Name() string {
+	return SignerStepCA
+}
+
+// CACertificate returns the CA's root certificate
+func (s *StepCASigner) CACertificate(ctx context.Context) (*x509.Certificate, error) {
+	var out struct {
+		Certificates []string `json:"crts"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/roots", nil, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Certificates) == 0 {
+		return nil, fmt.Errorf("step-ca returned no root certificate")
+	}
+	certs, err := parseCertChainPEM(out.Certificates[0])
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// Sign issues a certificate for csr
+func (s *StepCASigner) Sign(ctx context.Context, csr *x509.CertificateRequest, notAfter time.Time) (*x509.Certificate, []*x509.Certificate, error) {
+	cn := csr.Subject.CommonName
+	token, err := s.token(s.settings.URL+"/1.0/sign", cn, []string{cn})
+	if err != nil {
+		return nil, nil, err
+	}
+	body := map[string]string{
+		"csr":      encodeCSR(csr),
+		"ott":      token,
+		"notAfter": notAfter.UTC().Format(time.RFC3339),
+	}
+	var out struct {
+		Certificate string   `json:"crt"`
+		CA          string   `json:"ca"`
+		Chain       []string `json:"certChain"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/1.0/sign", body, &out); err != nil {
+		return nil, nil, err
+	}
+
+	certs, err := parseCertChainPEM(out.Certificate)
+	if err != nil {
+		return nil, nil, err
+	}
+	chainPEM := out.CA
+	if len(out.Chain) > 1 {
+		chainPEM = strings.Join(out.Chain[1:], "\n")
+	}
+	chain, err := parseCertChainPEM(chainPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("step-ca chain: %w", err)
+	}
+	return certs[0], chain, nil
+}
+
+// Revoke revokes a certificate passively, i.e. in step-ca's CRL and database
+func (s *StepCASigner) Revoke(ctx context.Context, cert *x509.Certificate) error {
+	serial := cert.SerialNumber.String()
+	token, err := s.token(s.settings.URL+"/1.0/revoke", serial, nil)
+	if err != nil {
+		return err
+	}
+	body := map[string]interface{}{
+		"serial":     serial,
+		"ott":        token,
+		"reasonCode": 0,
+		"passive":    true,
+	}
+	return s.do(ctx, http.MethodPost, "/1.0/revoke", body, nil)
+}
+
+func (s *StepCASigner) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.settings.URL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("step-ca %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("step-ca %s: %s", path, readSignerError(resp))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("step-ca %s: invalid response: %w", path, err)
+	}
+	return nil
+}
+
+// token creates the provisioner's one-time token for audience, an ES256 JWT
+func (s *StepCASigner) token(audience, subject string, sans []string) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := s.now()
+	claims := map[string]interface{}{
+		"iss": s.settings.Provisioner,
+		"aud": audience,
+		"sub": subject,
+		"iat": now.Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"jti": hex.EncodeToString(jti),
+	}
+	if len(sans) > 0 {
+		claims["sans"] = sans
+	}
+	header := map[string]string{"alg": "ES256", "typ": "JWT", "kid": s.kid}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseProvisionerJWK reads a P-256 private JWK and its key ID, which is the
+// RFC 7638 thumbprint when the JWK has none
+func parseProvisionerJWK(data []byte) (*ecdsa.PrivateKey, string, error) {
+	var jwk struct {
+		Kty string `json:"kty"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		D   string `json:"d"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, "", fmt.Errorf("step-ca provisioner key is not a JWK (decrypt it with `step crypto jwe decrypt`): %w", err)
+	}
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" {
+		return nil, "", fmt.Errorf("step-ca provisioner key must be an EC P-256 JWK, got %s %s", jwk.Kty, jwk.Crv)
+	}
+	if jwk.D == "" {
+		return nil, "", fmt.Errorf("step-ca provisioner key has no private part")
+	}
+
+	var coords [3]*big.Int
+	for i, value := range []string{jwk.X, jwk.Y, jwk.D} {
+		raw, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid step-ca provisioner key: %w", err)
+		}
+		coords[i] = new(big.Int).SetBytes(raw)
+	}
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: coords[0], Y: coords[1]},
+		D:         coords[2],
+	}
+	if _, err := key.ECDH(); err != nil {
+		return nil, "", fmt.Errorf("invalid step-ca provisioner key: %w", err)
+	}
+
+	kid := jwk.Kid
+	if kid == "" {
+		thumbprint := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, jwk.X, jwk.Y)))
+		kid = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+	}
+	return key, kid, nil
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// testSigningCA stands in for an external CA: a root, and an intermediate
+// that signs CSRs
+type testSigningCA struct {
+	root, intermediate *x509.Certificate
+	rootPEM, interPEM  string
+	key                *ecdsa.PrivateKey
+}
+
+func newTestSigningCA(t *testing.T) *testSigningCA {
+	t.Helper()
+	rootKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "External Root", Organization: []string{"Example"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("create root: %v", err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	interTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "External Issuing CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(5 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	interDER, err := x509.CreateCertificate(rand.Reader, interTemplate, root, &key.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("create intermediate: %v", err)
+	}
+	intermediate, _ := x509.ParseCertificate(interDER)
+
+	return &testSigningCA{
+		root:         root,
+		intermediate: intermediate,
+		rootPEM:      string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER})),
+		interPEM:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: interDER})),
+		key:          key,
+	}
+}
+
+// sign issues a client certificate for a PEM CSR, valid until notAfter
+func (ca *testSigningCA) sign(t *testing.T, csrPEM string, notAfter time.Time) (string, *big.Int) {
+	t.Helper()
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		t.Fatalf("expected a PEM CSR, got %q", csrPEM)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil || csr.CheckSignature() != nil {
+		t.Fatalf("invalid CSR: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.intermediate, csr.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("sign CSR: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), serial
+}
+
+func TestCertGenerator_VaultSigner(t *testing.T) {
+	ca := newTestSigningCA(t)
+	var mu sync.Mutex
+	var revoked []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/pki_int/ca/pem":
+			fmt.Fprint(w, ca.rootPEM)
+		case "/v1/pki_int/sign/clients":
+			var body struct {
+				CSR        string `json:"csr"`
+				CommonName string `json:"common_name"`
+				TTL        string `json:"ttl"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			ttl, err := time.ParseDuration(body.TTL)
+			if err != nil || !strings.HasPrefix(body.CommonName, "laptop.") {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"errors":["bad request %s %s"]}`, body.CommonName, body.TTL)
+				return
+			}
+			cert, _ := ca.sign(t, body.CSR, time.Now().Add(ttl))
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"certificate": cert,
+					"issuing_ca":  ca.interPEM,
+					"ca_chain":    []string{ca.interPEM, ca.rootPEM},
+				},
+			})
+		case "/v1/pki_int/revoke":
+			var body struct {
+				Serial string `json:"serial_number"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			revoked = append(revoked, body.Serial)
+			mu.Unlock()
+			fmt.Fprint(w, `{"data":{}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	signer, err := NewCertSigner(CertSignerSettings{Type: SignerVault, Vault: VaultPKISettings{
+		Addr: server.URL, Token: "s.token", Mount: "/pki_int/", Role: "clients",
+	}})
+	if err != nil {
+		t.Fatalf("NewCertSigner() error = %v", err)
+	}
+
+	db := newTestSQLDB(t)
+	cg := NewCertGenerator(db)
+
+	// MM's own CA is refused once a signer is set, until the external CA is adopted
+	if _, err := cg.GenerateCA(models.CreateCARequest{CommonName: "Local CA"}, t.TempDir()); err != nil {
+		t.Fatalf("GenerateCA() error = %v", err)
+	}
+	cg.SetSigner(signer)
+	if _, err := cg.GenerateClientCert(models.CreateClientRequest{Name: "laptop", P12Password: "password123"}); err == nil {
+		t.Fatal("expected an error while the stored CA is MM's own")
+	}
+
+	basePath := t.TempDir()
+	config, err := cg.GenerateCA(models.CreateCARequest{CommonName: "ignored"}, basePath)
+	if err != nil {
+		t.Fatalf("adopting the Vault CA: %v", err)
+	}
+	if config.Signer != SignerVault || config.CASubject != "CN=External Root, O=Example" {
+		t.Errorf("unexpected adopted config %+v", config)
+	}
+	if written, err := os.ReadFile(filepath.Join(basePath, "ca", "ca.crt")); err != nil || string(written) != ca.rootPEM {
+		t.Errorf("CA certificate for Traefik = %q, %v", written, err)
+	}
+	if stored, _ := cg.GetConfig(); !stored.HasCA || stored.Signer != SignerVault {
+		t.Errorf("stored config = %+v", stored)
+	}
+
+	client, err := cg.GenerateClientCert(models.CreateClientRequest{Name: "laptop", P12Password: "password123", ValidityDays: 30})
+	if err != nil {
+		t.Fatalf("GenerateClientCert() error = %v", err)
+	}
+	if client.Signer != SignerVault || client.Subject != "CN=laptop.External Root, O=Example" || len(client.P12) == 0 {
+		t.Errorf("unexpected client %+v", client)
+	}
+	if client.Expiry.Before(time.Now().Add(29*24*time.Hour)) || client.Expiry.After(time.Now().Add(31*24*time.Hour)) {
+		t.Errorf("expiry %s is not about 30 days out", client.Expiry)
+	}
+
+	if _, _, err := cg.GenerateCRL(); !errors.Is(err, ErrExternalCA) {
+		t.Errorf("GenerateCRL() error = %v, want ErrExternalCA", err)
+	}
+	export, err := cg.ExportConfig()
+	if err != nil || export.CABundlePEM != ca.rootPEM || export.CRLPEM != "" || len(export.Clients) != 1 {
+		t.Errorf("ExportConfig() = %+v, %v", export, err)
+	}
+
+	if err := cg.RevokeClient(client.ID); err != nil {
+		t.Fatalf("RevokeClient() error = %v", err)
+	}
+	cert, _ := parseCertPEM(client.Cert)
+	if len(revoked) != 1 || revoked[0] != strings.ToLower(formatSerial(cert.SerialNumber)) {
+		t.Errorf("Vault revocations = %v", revoked)
+	}
+	if stored, _ := cg.GetClient(client.ID); !stored.Revoked || stored.Signer != SignerVault {
+		t.Errorf("stored client = %+v", stored)
+	}
+}
+
+func TestStepCASigner(t *testing.T) {
+	ca := newTestSigningCA(t)
+	provisionerKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwk := map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(provisionerKey.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(provisionerKey.Y.FillBytes(make([]byte, 32))),
+		"d":   base64.RawURLEncoding.EncodeToString(provisionerKey.D.FillBytes(make([]byte, 32))),
+	}
+	jwkJSON, _ := json.Marshal(jwk)
+	keyFile := filepath.Join(t.TempDir(), "provisioner.json")
+	if err := os.WriteFile(keyFile, jwkJSON, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// verifyToken checks the one-time token as step-ca does and returns its claims
+	verifyToken := func(token, audience string) (map[string]interface{}, error) {
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("malformed token")
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if len(sig) != 64 || !ecdsa.Verify(&provisionerKey.PublicKey, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return nil, fmt.Errorf("bad signature")
+		}
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims map[string]interface{}
+		json.Unmarshal(claimsJSON, &claims)
+		if claims["aud"] != audience || claims["iss"] != "mm" {
+			return nil, fmt.Errorf("unexpected claims %v", claims)
+		}
+		return claims, nil
+	}
+
+	var server *httptest.Server
+	var revokedSerial string
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/roots":
+			json.NewEncoder(w).Encode(map[string]interface{}{"crts": []string{ca.rootPEM}})
+		case "/1.0/sign":
+			var body struct {
+				CSR      string `json:"csr"`
+				OTT      string `json:"ott"`
+				NotAfter string `json:"notAfter"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			claims, err := verifyToken(body.OTT, server.URL+"/1.0/sign")
+			notAfter, _ := time.Parse(time.RFC3339, body.NotAfter)
+			if err != nil || claims["sub"] != "phone.External Root" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprintf(w, `{"message":"%v"}`, err)
+				return
+			}
+			cert, _ := ca.sign(t, body.CSR, notAfter)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"crt": cert, "ca": ca.interPEM, "certChain": []string{cert, ca.interPEM},
+			})
+		case "/1.0/revoke":
+			var body struct {
+				Serial  string `json:"serial"`
+				OTT     string `json:"ott"`
+				Passive bool   `json:"passive"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if claims, err := verifyToken(body.OTT, server.URL+"/1.0/revoke"); err != nil || claims["sub"] != body.Serial || !body.Passive {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			revokedSerial = body.Serial
+			fmt.Fprint(w, `{"status":"ok"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	signer, err := NewStepCASigner(StepCASettings{URL: server.URL + "/", Provisioner: "mm", ProvisionerKeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewStepCASigner() error = %v", err)
+	}
+	thumbprint := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, jwk["x"], jwk["y"])))
+	if signer.kid != base64.RawURLEncoding.EncodeToString(thumbprint[:]) {
+		t.Errorf("kid = %s, want the JWK thumbprint", signer.kid)
+	}
+
+	db := newTestSQLDB(t)
+	cg := NewCertGenerator(db)
+	cg.SetSigner(signer)
+	if _, err := cg.GenerateCA(models.CreateCARequest{CommonName: "ignored"}, t.TempDir()); err != nil {
+		t.Fatalf("adopting the step-ca root: %v", err)
+	}
+	client, err := cg.GenerateClientCert(models.CreateClientRequest{Name: "phone", P12Password: "password123"})
+	if err != nil {
+		t.Fatalf("GenerateClientCert() error = %v", err)
+	}
+	if err := cg.RevokeClient(client.ID); err != nil {
+		t.Fatalf("RevokeClient() error = %v", err)
+	}
+	cert, _ := parseCertPEM(client.Cert)
+	if revokedSerial != cert.SerialNumber.String() {
+		t.Errorf("step-ca revoked %q, want %s", revokedSerial, cert.SerialNumber)
+	}
+
+	if _, err := NewStepCASigner(StepCASettings{URL: server.URL, Provisioner: "mm", ProvisionerKeyFile: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected an error for a missing provisioner key")
+	}
+}
+
+func TestAWSPCASigner(t *testing.T) {
+	ca := newTestSigningCA(t)
+	const arn = "arn:aws:acm-pca:eu-west-1:123456789012:certificate-authority/abc"
+	var issued string
+	var polls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/acm-pca/aws4_request") ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"__type":"AccessDeniedException","message":"bad signature"}`)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["CertificateAuthorityArn"] != arn {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "ACMPrivateCA.GetCertificateAuthorityCertificate":
+			json.NewEncoder(w).Encode(map[string]string{"Certificate": ca.rootPEM})
+		case "ACMPrivateCA.IssueCertificate":
+			csr, _ := base64.StdEncoding.DecodeString(body["Csr"].(string))
+			validity := body["Validity"].(map[string]interface{})
+			if body["TemplateArn"] != defaultPCATemplateARN || validity["Type"] != "ABSOLUTE" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			issued, _ = ca.sign(t, string(csr), time.Unix(int64(validity["Value"].(float64)), 0))
+			json.NewEncoder(w).Encode(map[string]string{"CertificateArn": arn + "/certificate/1"})
+		case "ACMPrivateCA.GetCertificate":
+			if polls++; polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"com.amazonaws.acmpca#RequestInProgressException","message":"pending"}`)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"Certificate": issued, "CertificateChain": ca.interPEM + ca.rootPEM})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	signer, err := NewAWSPCASigner(AWSPCASettings{
+		CAARN: arn, Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session",
+	})
+	if err != nil {
+		t.Fatalf("NewAWSPCASigner() error = %v", err)
+	}
+	if signer.settings.Region != "eu-west-1" {
+		t.Errorf("region = %q, want it taken from the ARN", signer.settings.Region)
+	}
+	signer.pollInterval = time.Millisecond
+
+	db := newTestSQLDB(t)
+	cg := NewCertGenerator(db)
+	cg.SetSigner(signer)
+	if _, err := cg.GenerateCA(models.CreateCARequest{CommonName: "ignored"}, t.TempDir()); err != nil {
+		t.Fatalf("adopting the private CA: %v", err)
+	}
+	client, err := cg.GenerateClientCert(models.CreateClientRequest{Name: "server", P12Password: "password123"})
+	if err != nil {
+		t.Fatalf("GenerateClientCert() error = %v", err)
+	}
+	if client.Signer != SignerAWSPCA || polls != 2 {
+		t.Errorf("client signer %q after %d polls", client.Signer, polls)
+	}
+
+	for _, settings := range []CertSignerSettings{
+		{Type: "openssl"},
+		{Type: SignerAWSPCA, AWSPCA: AWSPCASettings{CAARN: arn}},
+		{Type: SignerVault, Vault: VaultPKISettings{Addr: server.URL}},
+	} {
+		if signer, err := NewCertSigner(settings); err == nil || signer != nil {
+			t.Errorf("expected %+v to be rejected, got %v, %v", settings, signer, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VaultPKISettings configures a HashiCorp Vault (or OpenBao) PKI secrets engine
+type VaultPKISettings struct {
+	// Addr is Vault's address, e.g. https://vault.internal:8200
+	Addr string
+	// Token authenticates MM; its policy needs update on <mount>/sign/<role>
+	// and <mount>/revoke
+	Token string
+	// Mount is the PKI engine's mount path (default pki)
+	Mount string
+	// Role is the role client certificates are signed with; it must allow
+	// the <client>.<CA common name> names MM requests
+	Role string
+	// Namespace is the Vault Enterprise namespace (empty for the root)
+	Namespace string
+	// CAFile is trusted for the connection besides the system roots
+	CAFile string
+}
+
+// VaultPKISigner issues client certificates through Vault's PKI sign endpoint
+type VaultPKISigner struct {
+	settings VaultPKISettings
+	client   *http.Client
+}
+
+// NewVaultPKISigner creates a Vault PKI signer
+func NewVaultPKISigner(settings VaultPKISettings) (*VaultPKISigner, error) {
+	if settings.Addr == "" || settings.Token == "" || settings.Role == "" {
+		return nil, fmt.Errorf("Vault address, token and PKI role are required")
+	}
+	if settings.Mount == "" {
+		settings.Mount = "pki"
+	}
+	settings.Addr = strings.TrimSuffix(settings.Addr, "/")
+	settings.Mount = strings.Trim(settings.Mount, "/")
+	client, err := signerHTTPClient(settings.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &VaultPKISigner{settings: settings, client: client}, nil
+}
+
+// Name identifies the signer
+func (s *VaultPKISigner) Name() string {
+	return SignerVault
+}
+
+// CACertificate returns the certificate of the mount's default issuer
+func (s *VaultPKISigner) CACertificate(ctx context.Context) (*x509.Certificate, error) {
+	resp, err := s.do(ctx, http.MethodGet, "ca/pem", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCertChainPEM(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Vault CA: %w", err)
+	}
+	return certs[0], nil
+}
+
+// Sign issues a certificate for csr with the configured role
+func (s *VaultPKISigner) Sign(ctx context.Context, csr *x509.CertificateRequest, notAfter time.Time) (*x509.Certificate, []*x509.Certificate, error) {
+	ttl := time.Until(notAfter).Round(time.Second)
+	body := map[string]string{
+		"csr":         encodeCSR(csr),
+		"common_name": csr.Subject.CommonName,
+		"ttl":         strconv.FormatInt(int64(ttl/time.Second), 10) + "s",
+	}
+	resp, err := s.do(ctx, http.MethodPost, "sign/"+s.settings.Role, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Data struct {
+			Certificate string   `json:"certificate"`
+			IssuingCA   string   `json:"issuing_ca"`
+			CAChain     []string `json:"ca_chain"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, nil, fmt.Errorf("Vault sign: invalid response: %w", err)
+	}
+	certs, err := parseCertChainPEM(out.Data.Certificate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Vault sign: %w", err)
+	}
+	chainPEM := out.Data.IssuingCA
+	if len(out.Data.CAChain) > 0 {
+		chainPEM = strings.Join(out.Data.CAChain, "\n")
+	}
+	chain, err := parseCertChainPEM(chainPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Vault sign chain: %w", err)
+	}
+	return certs[0], chain, nil
+}
+
+// Revoke revokes a certificate by serial number, adding it to Vault's CRL
+func (s *VaultPKISigner) Revoke(ctx context.Context, cert *x509.Certificate) error {
+	body := map[string]string{"serial_number": strings.ToLower(formatSerial(cert.SerialNumber))}
+	resp, err := s.do(ctx, http.MethodPost, "revoke", body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a request to path below the PKI mount and turns non-2xx
+// responses into errors carrying Vault's error messages
+func (s *VaultPKISigner) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	url := s.settings.Addr + "/v1/" + s.settings.Mount + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.settings.Token)
+	if s.settings.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.settings.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Vault %s: %w", path, err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var vaultErr struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(data, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+		return nil, fmt.Errorf("Vault %s: %s: %s", path, resp.Status, strings.Join(vaultErr.Errors, "; "))
+	}
+	return nil, fmt.Errorf("Vault %s: %s", path, resp.Status)
+}
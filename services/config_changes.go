@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// maxConfigRevisions bounds how far back consumers can ask for a delta;
+// older revisions get a full reset instead
+const maxConfigRevisions = 64
+
+// ConfigSectionChanges lists what changed in one section between revisions
+type ConfigSectionChanges struct {
+	Added    map[string]json.RawMessage `json:"added"`
+	Modified map[string]json.RawMessage `json:"modified"`
+	Removed  []string                   `json:"removed"`
+}
+
+// ConfigChanges is the delta between a consumer's revision and the current one.
+// Reset means the requested revision is unknown and every current item is
+// reported as added, so the consumer should rebuild from scratch.
+type ConfigChanges struct {
+	Revision    uint64               `json:"revision"`
+	Since       uint64               `json:"since"`
+	Reset       bool                 `json:"reset"`
+	Routers     ConfigSectionChanges `json:"routers"`
+	Middlewares ConfigSectionChanges `json:"middlewares"`
+}
+
+// configSnapshot holds the encoded routers and middlewares of one revision
+type configSnapshot struct {
+	revision    uint64
+	routers     map[string]json.RawMessage
+	middlewares map[string]json.RawMessage
+}
+
+// configChangeLog records a snapshot whenever the served config changes
+type configChangeLog struct {
+	mu        sync.Mutex
+	last      *ProxiedTraefikConfig
+	snapshots []*configSnapshot // oldest first
+	next      uint64
+}
+
+// record snapshots the served config, bumping the revision only when a router
+// or middleware actually changed, and reports whether it did
+func (l *configChangeLog) record(config *ProxiedTraefikConfig) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if config == nil || config == l.last {
+		return false
+	}
+	l.last = config
+
+	snapshot := &configSnapshot{
+		routers:     map[string]json.RawMessage{},
+		middlewares: map[string]json.RawMessage{},
+	}
+	if config.HTTP != nil {
+		encodeSection(snapshot.routers, config.HTTP.Routers)
+		encodeSection(snapshot.middlewares, config.HTTP.Middlewares)
+	}
+
+	if n := len(l.snapshots); n > 0 {
+		latest := l.snapshots[n-1]
+		if sameSection(latest.routers, snapshot.routers) && sameSection(latest.middlewares, snapshot.middlewares) {
+			return false
+		}
+	}
+
+	l.next++
+	snapshot.revision = l.next
+	l.snapshots = append(l.snapshots, snapshot)
+	if len(l.snapshots) > maxConfigRevisions {
+		l.snapshots = l.snapshots[len(l.snapshots)-maxConfigRevisions:]
+	}
+	return true
+}
+
+// changes diffs the current snapshot against the one at revision since
+func (l *configChangeLog) changes(since uint64) *ConfigChanges {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := &ConfigChanges{Since: since}
+	if len(l.snapshots) == 0 {
+		result.Reset = true
+		result.Routers = diffSection(nil, nil)
+		result.Middlewares = diffSection(nil, nil)
+		return result
+	}
+
+	current := l.snapshots[len(l.snapshots)-1]
+	result.Revision = current.revision
+
+	var base *configSnapshot
+	for _, snapshot := range l.snapshots {
+		if snapshot.revision == since {
+			base = snapshot
+			break
+		}
+	}
+	if base == nil {
+		result.Reset = true
+		base = &configSnapshot{}
+	}
+
+	result.Routers = diffSection(base.routers, current.routers)
+	result.Middlewares = diffSection(base.middlewares, current.middlewares)
+	return result
+}
+
+func encodeSection[V any](dst map[string]json.RawMessage, section map[string]V) {
+	for name, item := range section {
+		data, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		dst[name] = data
+	}
+}
+
+func sameSection(a, b map[string]json.RawMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, data := range a {
+		other, ok := b[name]
+		if !ok || !bytes.Equal(data, other) {
+			return false
+		}
+	}
+	return true
+}
+
+func diffSection(before, after map[string]json.RawMessage) ConfigSectionChanges {
+	changes := ConfigSectionChanges{
+		Added:    map[string]json.RawMessage{},
+		Modified: map[string]json.RawMessage{},
+		Removed:  []string{},
+	}
+	for name, data := range after {
+		previous, ok := before[name]
+		switch {
+		case !ok:
+			changes.Added[name] = data
+		case !bytes.Equal(previous, data):
+			changes.Modified[name] = data
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// ConfigChanges returns the routers and middlewares added, modified or removed
+// since the given revision of the served config
+func (cp *ConfigProxy) ConfigChanges(since uint64) *ConfigChanges {
+	return cp.changeLog.changes(since)
+}
+
+// ConfigRevision returns the revision of the most recently served config
+func (cp *ConfigProxy) ConfigRevision() uint64 {
+	cp.changeLog.mu.Lock()
+	defer cp.changeLog.mu.Unlock()
+	return cp.changeLog.next
+}
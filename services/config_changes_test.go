@@ -0,0 +1,83 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func changesTestConfig(routers map[string]string, middlewares map[string]string) *ProxiedTraefikConfig {
+	config := &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+		Routers:     map[string]*OrderedRouter{},
+		Middlewares: map[string]interface{}{},
+	}}
+	for name, rule := range routers {
+		config.HTTP.Routers[name] = &OrderedRouter{Rule: rule, Service: name + "-service"}
+	}
+	for name, prefix := range middlewares {
+		config.HTTP.Middlewares[name] = map[string]interface{}{
+			"stripPrefix": map[string]interface{}{"prefixes": []interface{}{prefix}},
+		}
+	}
+	return config
+}
+
+func TestConfigChangeLogDiffsRevisions(t *testing.T) {
+	var log configChangeLog
+
+	log.record(changesTestConfig(
+		map[string]string{"a": "Host(`a.example.com`)", "b": "Host(`b.example.com`)"},
+		map[string]string{"strip": "/api"},
+	))
+	// Same content under a new pointer must not bump the revision
+	log.record(changesTestConfig(
+		map[string]string{"a": "Host(`a.example.com`)", "b": "Host(`b.example.com`)"},
+		map[string]string{"strip": "/api"},
+	))
+	if log.next != 1 {
+		t.Fatalf("expected revision 1 after identical configs, got %d", log.next)
+	}
+
+	log.record(changesTestConfig(
+		map[string]string{"a": "Host(`a.example.org`)", "c": "Host(`c.example.com`)"},
+		map[string]string{"strip": "/api"},
+	))
+
+	changes := log.changes(1)
+	if changes.Revision != 2 || changes.Reset {
+		t.Fatalf("expected a delta to revision 2, got revision %d reset %v", changes.Revision, changes.Reset)
+	}
+	if _, ok := changes.Routers.Added["c"]; !ok || len(changes.Routers.Added) != 1 {
+		t.Errorf("expected router c added, got %v", changes.Routers.Added)
+	}
+	if _, ok := changes.Routers.Modified["a"]; !ok || len(changes.Routers.Modified) != 1 {
+		t.Errorf("expected router a modified, got %v", changes.Routers.Modified)
+	}
+	if !reflect.DeepEqual(changes.Routers.Removed, []string{"b"}) {
+		t.Errorf("expected router b removed, got %v", changes.Routers.Removed)
+	}
+	if len(changes.Middlewares.Added)+len(changes.Middlewares.Modified)+len(changes.Middlewares.Removed) != 0 {
+		t.Errorf("expected no middleware changes, got %+v", changes.Middlewares)
+	}
+
+	if current := log.changes(2); len(current.Routers.Added)+len(current.Routers.Modified)+len(current.Routers.Removed) != 0 {
+		t.Errorf("expected an empty delta at the current revision, got %+v", current.Routers)
+	}
+}
+
+func TestConfigChangeLogResetsUnknownRevision(t *testing.T) {
+	var log configChangeLog
+	for i := 0; i < maxConfigRevisions+2; i++ {
+		log.record(changesTestConfig(map[string]string{"a": string(rune('a'+i%26)) + "-rule"}, nil))
+	}
+	// Consecutive configs alternate content, so every record bumps the revision
+	changes := log.changes(1)
+	if !changes.Reset {
+		t.Fatal("expected an evicted revision to reset")
+	}
+	if len(changes.Routers.Added) != 1 || len(changes.Routers.Removed) != 0 {
+		t.Errorf("expected the full router set as added, got %+v", changes.Routers)
+	}
+	if fresh := log.changes(0); !fresh.Reset {
+		t.Error("expected since=0 to reset")
+	}
+}
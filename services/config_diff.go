@@ -0,0 +1,266 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Config diff bases
+const (
+	DiffBaseServed   = "served"
+	DiffBaseCache    = "cache"
+	DiffBaseSnapshot = "snapshot"
+	DiffBaseNone     = "none"
+)
+
+// diffContext is the number of unchanged lines around each hunk
+const diffContext = 3
+
+// maxLineDiffCells bounds the line diff of one item; larger items are shown
+// as removed and re-added whole
+const maxLineDiffCells = 4 << 20
+
+// ConfigDiff compares the config a fresh merge would serve with the one
+// Traefik last got, or with a supplied snapshot
+type ConfigDiff struct {
+	// Base is served (last served to a provider), cache (nothing served yet),
+	// snapshot (supplied by the caller) or none (everything is new)
+	Base        string     `json:"base"`
+	BaseAt      *time.Time `json:"base_at,omitempty"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Identical   bool       `json:"identical"`
+	// WouldServe is false when validation would fall back to the last
+	// known-good config instead of the preview
+	WouldServe       bool              `json:"would_serve"`
+	ValidationErrors []string          `json:"validation_errors,omitempty"`
+	Changes          []TransformChange `json:"changes"`
+	Truncated        bool              `json:"truncated"`
+	// Unified is a unified diff of the changed routers, middlewares,
+	// services and TLS options
+	Unified string `json:"unified"`
+}
+
+// servedConfig is the config last written to a provider
+type servedConfig struct {
+	config *ProxiedTraefikConfig
+	at     time.Time
+}
+
+// MarkServed records the config a provider was sent, as the base of diff
+// previews
+func (cp *ConfigProxy) MarkServed(config *ProxiedTraefikConfig) {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.lastServed = servedConfig{config: config, at: time.Now().UTC()}
+}
+
+// PreviewConfigDiff merges a fresh config without serving or caching it and
+// diffs it against base, or against the config last served to a provider
+// when base is nil
+func (cp *ConfigProxy) PreviewConfigDiff(base *ProxiedTraefikConfig) (*ConfigDiff, error) {
+	cp.cacheMutex.RLock()
+	sections, limits := cp.sections, cp.limits
+	errorBudget, hasFallback := cp.errorBudget, cp.lastKnownGood != nil
+	lastServed, cached := cp.lastServed, cp.cache
+	cp.cacheMutex.RUnlock()
+
+	diff := &ConfigDiff{Base: DiffBaseSnapshot, GeneratedAt: time.Now().UTC()}
+	if base == nil {
+		switch {
+		case lastServed.config != nil:
+			diff.Base, base = DiffBaseServed, lastServed.config
+			at := lastServed.at
+			diff.BaseAt = &at
+		case cached != nil:
+			diff.Base, base = DiffBaseCache, cached
+		default:
+			diff.Base = DiffBaseNone
+		}
+	}
+
+	config, err := cp.fetchPangolinConfigGuarded()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Pangolin config: %w", err)
+	}
+	merge, err := cp.mergeConfig(config, sections, limits, false, "")
+	if err != nil {
+		return nil, err
+	}
+	diff.ValidationErrors = merge.validationErrors
+	diff.WouldServe = len(merge.validationErrors) <= errorBudget || !hasFallback
+
+	var before map[string]interface{}
+	if base != nil {
+		before = configTree(base)
+	}
+	after := configTree(merge.config)
+	diff.Changes, diff.Truncated = diffTransforms(before, after)
+	diff.Unified = unifiedConfigDiff(before, after)
+	diff.Identical = len(diff.Changes) == 0 && diff.Unified == ""
+	return diff, nil
+}
+
+// unifiedConfigDiff renders a unified diff of each changed item of the
+// compared sections, as indented JSON
+func unifiedConfigDiff(before, after map[string]interface{}) string {
+	var out strings.Builder
+	for _, section := range transformSections {
+		name := section[0] + "." + section[1]
+		prev, next := treeMap(before, section[0], section[1]), treeMap(after, section[0], section[1])
+		keys := make(map[string]interface{}, len(next))
+		for key := range prev {
+			keys[key] = nil
+		}
+		for key := range next {
+			keys[key] = nil
+		}
+		for _, key := range sortedKeys(keys) {
+			a, inPrev := prev[key]
+			b, inNext := next[key]
+			if inPrev && inNext && reflect.DeepEqual(a, b) {
+				continue
+			}
+			path := name + "/" + key
+			fromFile, toFile := "a/"+path, "b/"+path
+			if !inPrev {
+				fromFile = "/dev/null"
+			}
+			if !inNext {
+				toFile = "/dev/null"
+			}
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromFile, toFile)
+			writeHunks(&out, lineDiff(itemLines(a, inPrev), itemLines(b, inNext)))
+		}
+	}
+	return out.String()
+}
+
+func itemLines(item interface{}, present bool) []string {
+	if !present {
+		return nil
+	}
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return []string{fmt.Sprintf("%v", item)}
+	}
+	return strings.Split(string(data), "\n")
+}
+
+// diffLine is one line of a line diff; op is ' ', '-' or '+'
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// lineDiff aligns a and b on their longest common subsequence
+func lineDiff(a, b []string) []diffLine {
+	if len(a)*len(b) > maxLineDiffCells {
+		lines := make([]diffLine, 0, len(a)+len(b))
+		for _, line := range a {
+			lines = append(lines, diffLine{'-', line})
+		}
+		for _, line := range b {
+			lines = append(lines, diffLine{'+', line})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// writeHunks writes the changed lines with diffContext lines around them,
+// joining hunks whose context overlaps
+func writeHunks(out *strings.Builder, lines []diffLine) {
+	// Lines of a and b before each diff line, for the hunk headers
+	aPos, bPos := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for k, line := range lines {
+		aPos[k+1], bPos[k+1] = aPos[k], bPos[k]
+		if line.op != '+' {
+			aPos[k+1]++
+		}
+		if line.op != '-' {
+			bPos[k+1]++
+		}
+	}
+
+	for k := 0; k < len(lines); {
+		if lines[k].op == ' ' {
+			k++
+			continue
+		}
+		start := max(k-diffContext, 0)
+		end := k
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			// Keep going when the next change is within twice the context
+			next := end
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+			if next == len(lines) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		end = min(end+diffContext, len(lines))
+
+		aCount, bCount := aPos[end]-aPos[start], bPos[end]-bPos[start]
+		aStart, bStart := aPos[start], bPos[start]
+		if aCount > 0 {
+			aStart++
+		}
+		if bCount > 0 {
+			bStart++
+		}
+		fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, line := range lines[start:end] {
+			out.WriteByte(line.op)
+			out.WriteString(line.text)
+			out.WriteByte('\n')
+		}
+		k = end
+	}
+}
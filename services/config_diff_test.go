@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedConfigDiff(t *testing.T) {
+	before := map[string]interface{}{
+		"http": map[string]interface{}{
+			"routers": map[string]interface{}{
+				"app": map[string]interface{}{"rule": "Host(`app.example.com`)", "priority": 100.0, "service": "app"},
+				"old": map[string]interface{}{"rule": "Host(`old.example.com`)"},
+			},
+		},
+	}
+	after := map[string]interface{}{
+		"http": map[string]interface{}{
+			"routers": map[string]interface{}{
+				"app": map[string]interface{}{"rule": "Host(`app.example.com`)", "priority": 200.0, "service": "app"},
+			},
+		},
+	}
+
+	want := strings.Join([]string{
+		"--- a/http.routers/app",
+		"+++ b/http.routers/app",
+		"@@ -1,5 +1,5 @@",
+		" {",
+		`-  "priority": 100,`,
+		`+  "priority": 200,`,
+		`   "rule": "Host(` + "`app.example.com`" + `)",`,
+		`   "service": "app"`,
+		" }",
+		"--- a/http.routers/old",
+		"+++ /dev/null",
+		"@@ -1,3 +0,0 @@",
+		"-{",
+		`-  "rule": "Host(` + "`old.example.com`" + `)"`,
+		"-}",
+		"",
+	}, "\n")
+	if got := unifiedConfigDiff(before, after); got != want {
+		t.Errorf("unifiedConfigDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedConfigDiff(after, after); got != "" {
+		t.Errorf("expected no diff for identical configs, got\n%s", got)
+	}
+}
+
+func TestConfigProxyPreviewConfigDiff(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"tool-router": map[string]interface{}{"rule": "Host(`tool.example.com`)", "service": "tool-service"},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+	cp.SetForwardAuthURL("http://middleware-manager:3456")
+
+	diff, err := cp.PreviewConfigDiff(nil)
+	if err != nil {
+		t.Fatalf("PreviewConfigDiff() error = %v", err)
+	}
+	if diff.Base != DiffBaseNone || diff.Identical || !diff.WouldServe {
+		t.Errorf("expected everything to be new before anything was served, got %+v", diff)
+	}
+
+	served, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	cp.MarkServed(served)
+	if diff, _ := cp.PreviewConfigDiff(nil); diff.Base != DiffBaseServed || !diff.Identical || diff.BaseAt == nil {
+		t.Fatalf("expected no changes against the served config, got %+v", diff)
+	}
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-1', 'tool-router', 'tool.example.com', 'tool-service', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	diff, err = cp.PreviewConfigDiff(nil)
+	if err != nil {
+		t.Fatalf("PreviewConfigDiff() error = %v", err)
+	}
+	var middlewareAdded bool
+	for _, change := range diff.Changes {
+		if change.Section == "http.middlewares" && change.Name == "res-1-forwardauth" && change.Action == TransformAdded {
+			middlewareAdded = true
+		}
+	}
+	if diff.Identical || !middlewareAdded || !strings.Contains(diff.Unified, "+++ b/http.middlewares/res-1-forwardauth") {
+		t.Errorf("expected the forwardAuth middleware in the preview, got %+v", diff)
+	}
+
+	// The preview is neither cached nor served
+	if cached, _ := cp.GetMergedConfig(); cached != served {
+		t.Errorf("expected the preview to leave the cache alone")
+	}
+
+	// Against a supplied snapshot
+	diff, err = cp.PreviewConfigDiff(&ProxiedTraefikConfig{})
+	if err != nil || diff.Base != DiffBaseSnapshot || !strings.Contains(diff.Unified, "+++ b/http.routers/tool-router") {
+		t.Errorf("expected every router to be new against an empty snapshot, got %+v, %v", diff, err)
+	}
+}
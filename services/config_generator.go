@@ -16,6 +16,7 @@ import (
 
 	"github.com/hhftechnology/middleware-manager/database"
 	"github.com/hhftechnology/middleware-manager/models" // Correct import for your models
+	"github.com/hhftechnology/middleware-manager/util"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,10 +25,13 @@ type ConfigGenerator struct {
 	db            *database.DB
 	confDir       string
 	configManager *ConfigManager
+	fs            util.FileSystem
 	stopChan      chan struct{}
 	isRunning     bool
 	mutex         sync.Mutex
 	lastConfig    []byte
+	// failures tells the webhooks when writing starts failing
+	failures failureNotifier
 }
 
 // TraefikConfig represents the structure of the Traefik configuration
@@ -69,6 +73,7 @@ func NewConfigGenerator(db *database.DB, confDir string, configManager *ConfigMa
 		db:            db,
 		confDir:       confDir,
 		configManager: configManager,
+		fs:            util.OS,
 		stopChan:      make(chan struct{}),
 		isRunning:     false,
 		lastConfig:    nil,
@@ -94,7 +99,7 @@ func (cg *ConfigGenerator) Start(interval time.Duration) {
 
 	log.Printf("Config generator started, checking every %v", interval)
 
-	if err := os.MkdirAll(cg.confDir, 0755); err != nil {
+	if err := cg.fs.MkdirAll(cg.confDir, 0755); err != nil {
 		log.Printf("Failed to create conf directory: %v", err)
 		return
 	}
@@ -102,16 +107,20 @@ func (cg *ConfigGenerator) Start(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	if err := cg.generateConfig(); err != nil {
+	err := cg.generateConfig()
+	if err != nil {
 		log.Printf("Initial config generation failed: %v", err)
 	}
+	cg.failures.report("file", err)
 
 	for {
 		select {
 		case <-ticker.C:
-			if err := cg.generateConfigWithRetry(); err != nil { // Use retry version
+			err := cg.generateConfigWithRetry() // Use retry version
+			if err != nil {
 				log.Printf("Config generation failed: %v", err)
 			}
+			cg.failures.report("file", err)
 		case <-cg.stopChan:
 			log.Println("Config generator stopped")
 			return
@@ -152,7 +161,7 @@ func (cg *ConfigGenerator) generateConfigWithRetry() error {
 		}
 
 		// Check if it's a database locked error
-		if strings.Contains(strings.ToLower(err.Error()), "database is locked") {
+		if database.IsBusy(err) {
 			if attempt < maxRetries-1 {
 				delay := baseDelay * time.Duration(1<<attempt) // Exponential backoff
 				log.Printf("⚠️  Database locked on attempt %d, retrying in %v", attempt+1, delay)
@@ -334,12 +343,19 @@ func (cg *ConfigGenerator) processResourcesWithServices(config *TraefikConfig) e
                rs.service_id as custom_service_id
         FROM resources r
         LEFT JOIN resource_middlewares rm ON r.id = rm.resource_id
+             AND (rm.expires_at IS NULL OR rm.expires_at > ?)
         LEFT JOIN middlewares m ON rm.middleware_id = m.id
         LEFT JOIN resource_services rs ON r.id = rs.resource_id
         WHERE r.status = 'active'
         ORDER BY r.id, rm.priority DESC
     `
-	rows, err := cg.db.Query(query)
+	scope, err := LoadManagementScope(cg.db.DB)
+	if err != nil {
+		log.Printf("Warning: failed to load management scope, managing all resources: %v", err)
+		scope = nil
+	}
+
+	rows, err := cg.db.Query(query, time.Now().UTC())
 	if err != nil {
 		return fmt.Errorf("failed to fetch resources for HTTP routers: %w", err)
 	}
@@ -370,6 +386,9 @@ func (cg *ConfigGenerator) processResourcesWithServices(config *TraefikConfig) e
 			log.Printf("Failed to scan resource data for HTTP router: %v", err)
 			continue
 		}
+		if !scope.Matches(host_db) {
+			continue
+		}
 
 		data, exists := resourceDataMap[rID_db]
 		if !exists {
@@ -429,15 +448,21 @@ func (cg *ConfigGenerator) processResourcesWithServices(config *TraefikConfig) e
 		if info.CustomHeaders != "" && info.CustomHeaders != "{}" && info.CustomHeaders != "null" {
 			var headersMap map[string]string
 			if err := json.Unmarshal([]byte(info.CustomHeaders), &headersMap); err == nil && len(headersMap) > 0 {
-				middlewareName := fmt.Sprintf("%s-customheaders", info.ID)
 				customRequestHeadersMap := make(map[string]string)
 				for k, v := range headersMap {
 					customRequestHeadersMap[k] = v
 				}
-				config.HTTP.Middlewares[middlewareName] = map[string]interface{}{
+				headers := map[string]interface{}{
 					"headers": map[string]interface{}{"customRequestHeaders": customRequestHeadersMap},
 				}
-				customHeadersMiddlewareID = fmt.Sprintf("%s@file", middlewareName)
+				// Resources with the same custom headers share one middleware
+				middlewareName, err := contentHashedName(customHeadersMiddlewarePrefix, headers)
+				if err != nil {
+					log.Printf("Failed to render custom headers for resource %s: %v", info.ID, err)
+				} else {
+					config.HTTP.Middlewares[middlewareName] = headers
+					customHeadersMiddlewareID = fmt.Sprintf("%s@file", middlewareName)
+				}
 			} else if err != nil {
 				log.Printf("Failed to parse custom headers for resource %s: %v. Headers: %s", info.ID, err, info.CustomHeaders)
 			}
@@ -685,6 +710,12 @@ func (cg *ConfigGenerator) processTCPRouters(config *TraefikConfig) error {
         LEFT JOIN resource_services rs ON r.id = rs.resource_id
         WHERE r.status = 'active' AND r.tcp_enabled = 1
     `
+	scope, err := LoadManagementScope(cg.db.DB)
+	if err != nil {
+		log.Printf("Warning: failed to load management scope, managing all resources: %v", err)
+		scope = nil
+	}
+
 	rows, err := cg.db.Query(query)
 	if err != nil {
 		return fmt.Errorf("failed to fetch TCP resources: %w", err)
@@ -699,6 +730,9 @@ func (cg *ConfigGenerator) processTCPRouters(config *TraefikConfig) error {
 			log.Printf("Failed to scan TCP resource: %v", err)
 			continue
 		}
+		if !scope.Matches(host) {
+			continue
+		}
 
 		priority := 100
 		if routerPriority.Valid {
@@ -770,11 +804,10 @@ func (cg *ConfigGenerator) hasConfigurationChanged(newConfig []byte) bool {
 
 func (cg *ConfigGenerator) writeConfigToFile(yamlData []byte) error {
 	configFile := filepath.Join(cg.confDir, "resource-overrides.yml")
-	tempFile := configFile + ".tmp"
-	if err := os.WriteFile(tempFile, yamlData, 0644); err != nil {
-		return fmt.Errorf("failed to write temp config file: %w", err)
+	if err := cg.fs.WriteFile(configFile, yamlData, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
 	}
-	return os.Rename(tempFile, configFile)
+	return nil
 }
 
 // MiddlewareWithPriority represents a middleware with its priority value
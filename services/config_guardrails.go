@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ConfigLimits bounds the size and complexity of the merged config. Traefik
+// accepts pathological configs but slows down without saying why, so going
+// over a limit is reported as a warning; the config is still served. Zero
+// turns a limit off.
+type ConfigLimits struct {
+	MaxMiddlewaresPerRouter int `json:"max_middlewares_per_router"`
+	MaxConfigBytes          int `json:"max_config_bytes"`
+	MaxRuleRegexLength      int `json:"max_rule_regex_length"`
+}
+
+// DefaultConfigLimits are the limits used unless configured otherwise
+var DefaultConfigLimits = ConfigLimits{
+	MaxMiddlewaresPerRouter: 20,
+	MaxConfigBytes:          5 << 20,
+	MaxRuleRegexLength:      256,
+}
+
+// Names of the limits in warnings
+const (
+	limitMiddlewaresPerRouter = "max_middlewares_per_router"
+	limitConfigBytes          = "max_config_bytes"
+	limitRuleRegexLength      = "max_rule_regex_length"
+)
+
+// ConfigLimitWarning is a limit the merged config goes over
+type ConfigLimitWarning struct {
+	Limit   string `json:"limit"`
+	Subject string `json:"subject"`
+	Value   int    `json:"value"`
+	Max     int    `json:"max"`
+}
+
+// String describes the warning for logs
+func (w ConfigLimitWarning) String() string {
+	return fmt.Sprintf("%s: %d exceeds %s %d", w.Subject, w.Value, w.Limit, w.Max)
+}
+
+// regexpMatcherPattern finds the arguments of regexp matchers in router
+// rules, such as HostRegexp(`...`) and PathRegexp(`...`)
+var regexpMatcherPattern = regexp.MustCompile("\\w+Regexp\\(((?:\\s*(?:`[^`]*`|\"[^\"]*\")\\s*,?)+)\\)")
+
+// quotedArgPattern finds the quoted arguments of a matcher
+var quotedArgPattern = regexp.MustCompile("`[^`]*`|\"[^\"]*\"")
+
+// SetConfigLimits sets the size and complexity limits the merged config is
+// checked against; negative values are treated as zero
+func (cp *ConfigProxy) SetConfigLimits(limits ConfigLimits) {
+	limits.MaxMiddlewaresPerRouter = max(limits.MaxMiddlewaresPerRouter, 0)
+	limits.MaxConfigBytes = max(limits.MaxConfigBytes, 0)
+	limits.MaxRuleRegexLength = max(limits.MaxRuleRegexLength, 0)
+
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.limits = limits
+}
+
+// checkConfigLimits returns the limits config goes over, sorted by subject
+func checkConfigLimits(config *ProxiedTraefikConfig, limits ConfigLimits) []ConfigLimitWarning {
+	var warnings []ConfigLimitWarning
+	if config == nil {
+		return warnings
+	}
+
+	if config.HTTP != nil {
+		for name, router := range config.HTTP.Routers {
+			if router == nil {
+				continue
+			}
+			subject := fmt.Sprintf("http router %q", name)
+			if limits.MaxMiddlewaresPerRouter > 0 && len(router.Middlewares) > limits.MaxMiddlewaresPerRouter {
+				warnings = append(warnings, ConfigLimitWarning{
+					Limit:   limitMiddlewaresPerRouter,
+					Subject: subject,
+					Value:   len(router.Middlewares),
+					Max:     limits.MaxMiddlewaresPerRouter,
+				})
+			}
+			if limits.MaxRuleRegexLength > 0 {
+				if longest := longestRuleRegexp(router.Rule); longest > limits.MaxRuleRegexLength {
+					warnings = append(warnings, ConfigLimitWarning{
+						Limit:   limitRuleRegexLength,
+						Subject: subject,
+						Value:   longest,
+						Max:     limits.MaxRuleRegexLength,
+					})
+				}
+			}
+		}
+	}
+
+	if limits.MaxConfigBytes > 0 {
+		if encoded, err := json.Marshal(config); err == nil && len(encoded) > limits.MaxConfigBytes {
+			warnings = append(warnings, ConfigLimitWarning{
+				Limit:   limitConfigBytes,
+				Subject: "merged config",
+				Value:   len(encoded),
+				Max:     limits.MaxConfigBytes,
+			})
+		}
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Subject != warnings[j].Subject {
+			return warnings[i].Subject < warnings[j].Subject
+		}
+		return warnings[i].Limit < warnings[j].Limit
+	})
+	return warnings
+}
+
+// longestRuleRegexp returns the length of the longest regular expression
+// passed to a regexp matcher in rule
+func longestRuleRegexp(rule string) int {
+	longest := 0
+	for _, match := range regexpMatcherPattern.FindAllStringSubmatch(rule, -1) {
+		for _, arg := range quotedArgPattern.FindAllString(match[1], -1) {
+			longest = max(longest, len(arg)-2)
+		}
+	}
+	return longest
+}
+
+// recordLimitWarnings stores the limit warnings of a fresh config, logging
+// them when they differ from the previous check. Callers must hold cacheMutex.
+func (cp *ConfigProxy) recordLimitWarnings(warnings []ConfigLimitWarning) {
+	if !sameLimitWarnings(cp.validation.LimitWarnings, warnings) && len(warnings) > 0 {
+		messages := make([]string, 0, len(warnings))
+		for _, w := range warnings {
+			messages = append(messages, w.String())
+		}
+		log.Printf("Warning: merged Traefik config exceeds %d limit(s): %s", len(warnings), strings.Join(messages, "; "))
+	}
+	cp.validation.LimitWarnings = warnings
+}
+
+// sameLimitWarnings reports whether two sorted warning lists name the same
+// limits and subjects, ignoring values that drift between merges
+func sameLimitWarnings(a, b []ConfigLimitWarning) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Limit != b[i].Limit || a[i].Subject != b[i].Subject {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckConfigLimits(t *testing.T) {
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"busy": {
+					Rule:        "Host(`busy.example.com`)",
+					Service:     "s",
+					Middlewares: []string{"a", "b", "c", "d"},
+				},
+				"regex": {
+					Rule:    "Host(`regex.example.com`) && PathRegexp(`^/(api|v1|v2|internal)/.*$`)",
+					Service: "s",
+				},
+				"plain": {Rule: "Host(`plain.example.com`)", Service: "s", Middlewares: []string{"a"}},
+			},
+		},
+	}
+
+	warnings := checkConfigLimits(config, ConfigLimits{MaxMiddlewaresPerRouter: 3, MaxConfigBytes: 100, MaxRuleRegexLength: 20})
+	want := []ConfigLimitWarning{
+		{Limit: limitMiddlewaresPerRouter, Subject: `http router "busy"`, Value: 4, Max: 3},
+		{Limit: limitRuleRegexLength, Subject: `http router "regex"`, Value: 26, Max: 20},
+	}
+	if len(warnings) != 3 || warnings[2].Limit != limitConfigBytes || warnings[2].Value <= 100 {
+		t.Fatalf("warnings = %+v, want router warnings and a config size warning", warnings)
+	}
+	if !reflect.DeepEqual(warnings[:2], want) {
+		t.Errorf("router warnings = %+v, want %+v", warnings[:2], want)
+	}
+
+	if warnings := checkConfigLimits(config, ConfigLimits{}); len(warnings) != 0 {
+		t.Errorf("disabled limits produced warnings %+v", warnings)
+	}
+}
+
+func TestLongestRuleRegexp(t *testing.T) {
+	tests := map[string]int{
+		"Host(`app.example.com`)":                              0,
+		"HostRegexp(`^.+\\.example\\.com$`)":                   18,
+		"HeaderRegexp(`X-Id`, `^[a-f0-9]{32}$`) || Path(`/x`)": 14,
+		"PathRegexp(\"^/a\") && QueryRegexp(`k`, `^.{1,64}$`)": 9,
+	}
+	for rule, want := range tests {
+		if got := longestRuleRegexp(rule); got != want {
+			t.Errorf("longestRuleRegexp(%q) = %d, want %d", rule, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Keys each config section models; anything else Pangolin sends (new Traefik
+// sections such as tcp.middlewares or tls.certificates) is kept in Extra and
+// written back unchanged.
+var (
+	proxiedConfigFields = []string{"http", "tcp", "udp", "tls"}
+	httpConfigFields    = []string{"middlewares", "routers", "services", "serversTransports"}
+	tcpConfigFields     = []string{"routers", "services"}
+	udpConfigFields     = []string{"routers", "services"}
+	tlsConfigFields     = []string{"options"}
+)
+
+// UnmarshalJSON decodes the known sections and keeps unknown top-level keys
+func (config *ProxiedTraefikConfig) UnmarshalJSON(data []byte) error {
+	type plain ProxiedTraefikConfig
+	if err := decodeKeepingNumbers(data, (*plain)(config)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, proxiedConfigFields)
+	config.Extra = extra
+	return err
+}
+
+// MarshalJSON writes the known sections followed by preserved top-level keys
+func (config ProxiedTraefikConfig) MarshalJSON() ([]byte, error) {
+	type plain ProxiedTraefikConfig
+	encoded, err := json.Marshal(plain(config))
+	if err != nil {
+		return nil, err
+	}
+	return appendUnknownFields(encoded, config.Extra), nil
+}
+
+func (c *HTTPConfig) UnmarshalJSON(data []byte) error {
+	type plain HTTPConfig
+	if err := decodeKeepingNumbers(data, (*plain)(c)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, httpConfigFields)
+	c.Extra = extra
+	return err
+}
+
+func (c HTTPConfig) MarshalJSON() ([]byte, error) {
+	type plain HTTPConfig
+	encoded, err := json.Marshal(plain(c))
+	if err != nil {
+		return nil, err
+	}
+	return appendUnknownFields(encoded, c.Extra), nil
+}
+
+func (c *TCPConfig) UnmarshalJSON(data []byte) error {
+	type plain TCPConfig
+	if err := decodeKeepingNumbers(data, (*plain)(c)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, tcpConfigFields)
+	c.Extra = extra
+	return err
+}
+
+func (c TCPConfig) MarshalJSON() ([]byte, error) {
+	type plain TCPConfig
+	encoded, err := json.Marshal(plain(c))
+	if err != nil {
+		return nil, err
+	}
+	return appendUnknownFields(encoded, c.Extra), nil
+}
+
+func (c *UDPConfig) UnmarshalJSON(data []byte) error {
+	type plain UDPConfig
+	if err := decodeKeepingNumbers(data, (*plain)(c)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, udpConfigFields)
+	c.Extra = extra
+	return err
+}
+
+func (c UDPConfig) MarshalJSON() ([]byte, error) {
+	type plain UDPConfig
+	encoded, err := json.Marshal(plain(c))
+	if err != nil {
+		return nil, err
+	}
+	return appendUnknownFields(encoded, c.Extra), nil
+}
+
+func (c *TLSConfig) UnmarshalJSON(data []byte) error {
+	type plain TLSConfig
+	if err := decodeKeepingNumbers(data, (*plain)(c)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, tlsConfigFields)
+	c.Extra = extra
+	return err
+}
+
+func (c TLSConfig) MarshalJSON() ([]byte, error) {
+	type plain TLSConfig
+	encoded, err := json.Marshal(plain(c))
+	if err != nil {
+		return nil, err
+	}
+	return appendUnknownFields(encoded, c.Extra), nil
+}
+
+// decodeKeepingNumbers decodes with json.Number so numbers MM passes through are
+// written back as Pangolin sent them (1.50 stays 1.50, large integers stay exact)
+func decodeKeepingNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// unknownFields returns the members of a JSON object that are not in known,
+// or nil when there are none
+func unknownFields(data []byte, known []string) (map[string]json.RawMessage, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for _, key := range known {
+		delete(raw, key)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return raw, nil
+}
+
+// appendUnknownFields adds preserved members, sorted by key, to an encoded object
+func appendUnknownFields(encoded []byte, extra map[string]json.RawMessage) []byte {
+	if len(extra) == 0 {
+		return encoded
+	}
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(encoded[:len(encoded)-1])
+	needComma := len(encoded) > 2
+	for _, key := range keys {
+		if needComma {
+			buf.WriteByte(',')
+		}
+		needComma = true
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
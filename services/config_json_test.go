@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pangolinPayloadWithUnknownFields uses Traefik options MM does not model at
+// every level of the document
+const pangolinPayloadWithUnknownFields = `{
+	"http": {
+		"routers": {
+			"app-router": {
+				"entryPoints": ["websecure"],
+				"service": "app-service",
+				"rule": "Host(` + "`app.example.com`" + `)",
+				"priority": 100,
+				"tls": {"certResolver": "letsencrypt", "options": "modern"},
+				"observability": {"accessLogs": false, "tracing": true, "metrics": true},
+				"ruleSyntax": "v2"
+			}
+		},
+		"services": {
+			"app-service": {
+				"loadBalancer": {
+					"servers": [{"url": "http://10.0.0.1:80", "weight": 3}],
+					"healthCheck": {"path": "/health", "interval": "10s", "timeout": "3s"},
+					"passHostHeader": true,
+					"responseForwarding": {"flushInterval": "100ms"}
+				}
+			},
+			"weighted-service": {
+				"weighted": {"services": [{"name": "app-service", "weight": 1.50}]}
+			}
+		},
+		"middlewares": {},
+		"serversTransports": {},
+		"futureHTTPSection": {"enabled": true}
+	},
+	"tcp": {
+		"routers": {"db-router": {"rule": "HostSNI(` + "`*`" + `)", "service": "db-service", "entryPoints": ["postgres"]}},
+		"services": {"db-service": {"loadBalancer": {"servers": [{"address": "10.0.0.2:5432"}]}}},
+		"middlewares": {"db-allow": {"ipAllowList": {"sourceRange": ["10.0.0.0/8"]}}},
+		"serversTransports": {"db-transport": {"dialTimeout": "5s"}}
+	},
+	"udp": {
+		"routers": {"dns-router": {"service": "dns-service", "entryPoints": ["dns"]}},
+		"services": {"dns-service": {"loadBalancer": {"servers": [{"address": "10.0.0.3:53"}]}}}
+	},
+	"tls": {
+		"options": {"modern": {"minVersion": "VersionTLS13"}},
+		"certificates": [{"certFile": "/certs/app.crt", "keyFile": "/certs/app.key", "stores": ["default"]}],
+		"stores": {"default": {"defaultGeneratedCert": {"resolver": "letsencrypt", "domain": {"main": "example.com"}}}}
+	},
+	"experimental": {"newTopLevelOption": 12345678901234567890}
+}`
+
+// mergeUnknownFieldsPayload serves the payload through the proxy and returns
+// both the streamed and the json.Marshal output
+func mergeUnknownFieldsPayload(t *testing.T, cp *ConfigProxy) (streamed, marshalled []byte) {
+	t.Helper()
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := config.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	marshalled, err = json.Marshal(config)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return buf.Bytes(), marshalled
+}
+
+func decodeWithNumbers(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return v
+}
+
+func newUnknownFieldsProxy(t *testing.T) *ConfigProxy {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(pangolinPayloadWithUnknownFields))
+	}))
+	t.Cleanup(server.Close)
+
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), server.URL)
+	cp.httpClient = server.Client()
+	return cp
+}
+
+func TestConfigProxyRoundTripsUnknownFields(t *testing.T) {
+	cp := newUnknownFieldsProxy(t)
+	streamed, marshalled := mergeUnknownFieldsPayload(t, cp)
+
+	if !bytes.Equal(streamed, marshalled) {
+		t.Fatalf("WriteJSON() and json.Marshal differ\n got: %s\nwant: %s", streamed, marshalled)
+	}
+
+	// Nothing is managed, so the served config must equal Pangolin's, except
+	// for the empty sections Traefik would reject
+	want := decodeWithNumbers(t, []byte(pangolinPayloadWithUnknownFields)).(map[string]interface{})
+	httpSection := want["http"].(map[string]interface{})
+	delete(httpSection, "middlewares")
+	delete(httpSection, "serversTransports")
+	if got := decodeWithNumbers(t, streamed); !reflect.DeepEqual(got, want) {
+		t.Fatalf("served config lost or changed fields\n got: %s\nwant: %s", streamed, pangolinPayloadWithUnknownFields)
+	}
+
+	// Values MM does not model are written exactly as Pangolin sent them
+	for _, fragment := range []string{
+		`"observability":{"accessLogs":false,"tracing":true,"metrics":true}`,
+		`"ruleSyntax":"v2"`,
+		`"weight":1.50`,
+		`"newTopLevelOption":12345678901234567890`,
+	} {
+		if !strings.Contains(string(streamed), fragment) {
+			t.Errorf("served config is missing %s\n%s", fragment, streamed)
+		}
+	}
+}
+
+func TestConfigProxyKeepsUnknownRouterFieldsOnManagedRouters(t *testing.T) {
+	cp := newUnknownFieldsProxy(t)
+	if _, err := cp.db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, router_priority)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'app-service', 'org', 'site', 'active', 250)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	streamed, _ := mergeUnknownFieldsPayload(t, cp)
+	served := decodeWithNumbers(t, streamed).(map[string]interface{})
+	router := served["http"].(map[string]interface{})["routers"].(map[string]interface{})["app-router"].(map[string]interface{})
+
+	if router["priority"] != json.Number("250") {
+		t.Fatalf("priority = %v, want the managed override 250", router["priority"])
+	}
+	if router["ruleSyntax"] != "v2" {
+		t.Errorf("ruleSyntax = %v, want v2", router["ruleSyntax"])
+	}
+	if _, ok := router["observability"].(map[string]interface{}); !ok {
+		t.Errorf("observability was dropped from the managed router: %v", router)
+	}
+	if tls, _ := router["tls"].(map[string]interface{}); tls["options"] != "modern" {
+		t.Errorf("tls = %v, want options preserved", router["tls"])
+	}
+}
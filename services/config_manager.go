@@ -13,19 +13,25 @@ import (
 	"time"
 
 	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
 )
 
 // ConfigManager manages system configuration
 type ConfigManager struct {
 	configPath string
+	fs         util.FileSystem
 	config     models.SystemConfig
 	mu         sync.RWMutex
+
+	// failover tracks outages of the active source (see datasource_failover.go)
+	failover failoverState
 }
 
 // NewConfigManager creates a new config manager
 func NewConfigManager(configPath string) (*ConfigManager, error) {
 	cm := &ConfigManager{
 		configPath: configPath,
+		fs:         util.OS,
 	}
 
 	if err := cm.loadConfig(); err != nil {
@@ -41,7 +47,7 @@ func (cm *ConfigManager) loadConfig() error {
 	defer cm.mu.Unlock()
 
 	// Check if config file exists
-	if _, err := os.Stat(cm.configPath); os.IsNotExist(err) {
+	if _, err := cm.fs.Stat(cm.configPath); os.IsNotExist(err) {
 		// Create default config
 		cm.config = models.SystemConfig{
 			ActiveDataSource: "pangolin",
@@ -55,24 +61,59 @@ func (cm *ConfigManager) loadConfig() error {
 					URL:  "http://host.docker.internal:8080",
 				},
 			},
+			// A fresh install starts the setup wizard
+			Setup: &models.SetupProgress{},
 		}
 
 		// Save default config
 		return cm.saveConfig()
 	}
 
+	config, err := cm.readConfig()
+	if err != nil {
+		return err
+	}
+	cm.config = config
+	return nil
+}
+
+// Reload re-reads the config file, e.g. after it was edited on disk. The
+// current config is kept when the file is missing or invalid.
+func (cm *ConfigManager) Reload() error {
+	config, err := cm.readConfig()
+	if err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.config = config
+	return nil
+}
+
+// readConfig reads and parses the config file
+func (cm *ConfigManager) readConfig() (models.SystemConfig, error) {
+	var config models.SystemConfig
+
 	// Read config file
-	data, err := os.ReadFile(cm.configPath)
+	data, err := cm.fs.ReadFile(cm.configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return config, fmt.Errorf("failed to read config file: %w", err)
 	}
 
 	// Parse config
-	if err := json.Unmarshal(data, &cm.config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	return nil
+	// Configs written before the setup wizard existed belong to working
+	// installs; do not send them through first-run setup
+	if config.Setup == nil {
+		now := time.Now().UTC()
+		config.Setup = &models.SetupProgress{Skipped: true, CompletedAt: &now}
+	}
+
+	return config, nil
 }
 
 // EnsureDefaultDataSources ensures default data sources are configured
@@ -155,7 +196,7 @@ func (cm *ConfigManager) EnsureDefaultDataSources(pangolinURL, traefikURL string
 func (cm *ConfigManager) saveConfig() error {
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(cm.configPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := cm.fs.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
@@ -166,22 +207,49 @@ func (cm *ConfigManager) saveConfig() error {
 	}
 
 	// Write config file
-	if err := os.WriteFile(cm.configPath, data, 0644); err != nil {
+	if err := cm.fs.WriteFile(cm.configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
+// GetSetupProgress returns a copy of the first-run wizard state
+func (cm *ConfigManager) GetSetupProgress() models.SetupProgress {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.Setup == nil {
+		return models.SetupProgress{}
+	}
+	return *cm.config.Setup
+}
+
+// UpdateSetupProgress applies fn to the wizard state and saves it
+func (cm *ConfigManager) UpdateSetupProgress(fn func(*models.SetupProgress)) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.config.Setup == nil {
+		cm.config.Setup = &models.SetupProgress{}
+	}
+	fn(cm.config.Setup)
+	return cm.saveConfig()
+}
+
 // GetActiveDataSourceConfig returns the active data source configuration
 func (cm *ConfigManager) GetActiveDataSourceConfig() (models.DataSourceConfig, error) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
-	dsName := cm.config.ActiveDataSource
+	return cm.dataSource(cm.config.ActiveDataSource)
+}
+
+// dataSource looks up a data source by name; callers hold cm.mu
+func (cm *ConfigManager) dataSource(dsName string) (models.DataSourceConfig, error) {
 	ds, ok := cm.config.DataSources[dsName]
 	if !ok {
-		return models.DataSourceConfig{}, fmt.Errorf("active data source not found: %s", dsName)
+		return models.DataSourceConfig{}, fmt.Errorf("data source not found: %s", dsName)
 	}
 
 	// Fallback: infer Type from name if empty (for old configs)
@@ -226,6 +294,7 @@ func (cm *ConfigManager) SetActiveDataSource(name string) error {
 
 	// Update active source
 	cm.config.ActiveDataSource = name
+	cm.failover.reset()
 
 	// Log the change
 	log.Printf("Changed active data source from %s to %s", oldSource, name)
@@ -254,6 +323,7 @@ func (cm *ConfigManager) UpdateDataSource(name string, config models.DataSourceC
 
 	// Create a copy to avoid reference issues
 	newConfig := config
+	newConfig.Headers = preserveMaskedHeaders(config, cm.config.DataSources[name])
 
 	// Ensure URL doesn't end with a slash
 	if newConfig.URL != "" && strings.HasSuffix(newConfig.URL, "/") {
@@ -280,10 +350,42 @@ func (cm *ConfigManager) UpdateDataSource(name string, config models.DataSourceC
 	return cm.saveConfig()
 }
 
+// PersistTraefikURL replaces previous with working as the URL of every
+// Traefik data source that still points at previous and allows auto-persist.
+// The fetcher calls it after a fallback URL answered, so there is no probe.
+func (cm *ConfigManager) PersistTraefikURL(previous, working string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	working = strings.TrimSuffix(working, "/")
+	changed := false
+	for name, source := range cm.config.DataSources {
+		if source.Type != models.TraefikAPI || source.DisableAutoPersist {
+			continue
+		}
+		if strings.TrimSuffix(source.URL, "/") != strings.TrimSuffix(previous, "/") || source.URL == working {
+			continue
+		}
+		source.URL = working
+		cm.config.DataSources[name] = source
+		changed = true
+		log.Printf("Saved working Traefik API URL %s for data source '%s'", working, name)
+	}
+	if !changed {
+		return nil
+	}
+	return cm.saveConfig()
+}
+
 // testDataSourceConnection tests the connection to a data source
 func (cm *ConfigManager) testDataSourceConnection(ctx context.Context, config models.DataSourceConfig) error {
-	client := HTTPClientWithTimeout(5 * time.Second)
+	_, err := probeDataSource(ctx, HTTPClientWithTimeout(5*time.Second), config)
+	return err
+}
 
+// probeDataSource requests a data source's health-check endpoint and returns
+// the response headers
+func probeDataSource(ctx context.Context, client *http.Client, config models.DataSourceConfig) (http.Header, error) {
 	var url string
 	switch config.Type {
 	case models.PangolinAPI:
@@ -292,30 +394,33 @@ func (cm *ConfigManager) testDataSourceConnection(ctx context.Context, config mo
 	case models.TraefikAPI:
 		url = config.URL + "/api/version"
 	default:
-		return fmt.Errorf("unsupported data source type: %s", config.Type)
+		return nil, fmt.Errorf("unsupported data source type: %s", config.Type)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	client, err := DataSourceHTTPClient(client, config)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("connection test failed: %w", err)
 	}
 
-	// Add basic auth if configured
-	if config.BasicAuth.Username != "" {
-		req.SetBasicAuth(config.BasicAuth.Username, config.BasicAuth.Password)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Add basic auth and custom headers if configured
+	AuthorizeDataSourceRequest(req, config)
+
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("connection test failed: %w", err)
+		return nil, fmt.Errorf("connection test failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("connection test failed with status code: %d", resp.StatusCode)
+		return resp.Header, fmt.Errorf("connection test failed with status code: %d", resp.StatusCode)
 	}
 
-	return nil
+	return resp.Header, nil
 }
 
 // TestDataSourceConnection is a public method to test a connection
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"os"
 	"testing"
 
 	"github.com/hhftechnology/middleware-manager/models"
@@ -41,3 +42,32 @@ func TestConfigManagerSetActiveDataSource(t *testing.T) {
 		t.Fatalf("expected error for unknown data source")
 	}
 }
+
+func TestConfigManagerReload(t *testing.T) {
+	cm := newTestConfigManager(t)
+
+	edited := `{"active_data_source":"traefik","data_sources":{"traefik":{"type":"traefik","url":"http://traefik.edited:8080"}}}`
+	if err := os.WriteFile(cm.configPath, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if cm.GetActiveSourceName() != "traefik" {
+		t.Fatalf("expected the edited active source, got %s", cm.GetActiveSourceName())
+	}
+	if _, ok := cm.GetDataSources()["pangolin"]; ok {
+		t.Fatalf("expected sources removed from the file to be gone after reload")
+	}
+
+	// A broken file keeps the current config
+	if err := os.WriteFile(cm.configPath, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Reload(); err == nil {
+		t.Fatalf("expected an invalid config file to fail the reload")
+	}
+	if cm.GetActiveSourceName() != "traefik" {
+		t.Fatalf("expected the config to be kept after a failed reload")
+	}
+}
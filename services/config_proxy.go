@@ -3,10 +3,12 @@ package services
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"sort"
@@ -16,57 +18,73 @@ import (
 
 	"github.com/hhftechnology/middleware-manager/database"
 	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
+	"golang.org/x/sync/singleflight"
 )
 
-// ProxiedTraefikConfig represents the full Traefik config structure (JSON format)
+// ProxiedTraefikConfig represents the full Traefik config structure (JSON format).
+// Each section keeps keys MM does not model in Extra (see config_json.go).
 type ProxiedTraefikConfig struct {
-	HTTP *HTTPConfig `json:"http,omitempty"`
-	TCP  *TCPConfig  `json:"tcp,omitempty"`
-	UDP  *UDPConfig  `json:"udp,omitempty"`
-	TLS  *TLSConfig  `json:"tls,omitempty"`
+	HTTP  *HTTPConfig                `json:"http,omitempty"`
+	TCP   *TCPConfig                 `json:"tcp,omitempty"`
+	UDP   *UDPConfig                 `json:"udp,omitempty"`
+	TLS   *TLSConfig                 `json:"tls,omitempty"`
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // HTTPConfig represents HTTP configuration section
 type HTTPConfig struct {
-	Middlewares       map[string]interface{} `json:"middlewares,omitempty"`
-	Routers           map[string]interface{} `json:"routers,omitempty"`
-	Services          map[string]interface{} `json:"services,omitempty"`
-	ServersTransports map[string]interface{} `json:"serversTransports,omitempty"`
+	Middlewares       map[string]interface{}     `json:"middlewares,omitempty"`
+	Routers           map[string]*OrderedRouter  `json:"routers,omitempty"`
+	Services          map[string]interface{}     `json:"services,omitempty"`
+	ServersTransports map[string]interface{}     `json:"serversTransports,omitempty"`
+	Extra             map[string]json.RawMessage `json:"-"`
 }
 
 // TCPConfig represents TCP configuration section
 type TCPConfig struct {
-	Routers  map[string]interface{} `json:"routers,omitempty"`
-	Services map[string]interface{} `json:"services,omitempty"`
+	Routers  map[string]interface{}     `json:"routers,omitempty"`
+	Services map[string]interface{}     `json:"services,omitempty"`
+	Extra    map[string]json.RawMessage `json:"-"`
 }
 
 // UDPConfig represents UDP configuration section
 type UDPConfig struct {
-	Routers  map[string]interface{} `json:"routers,omitempty"`
-	Services map[string]interface{} `json:"services,omitempty"`
+	Routers  map[string]interface{}     `json:"routers,omitempty"`
+	Services map[string]interface{}     `json:"services,omitempty"`
+	Extra    map[string]json.RawMessage `json:"-"`
 }
 
 // TLSConfig represents TLS configuration section
 type TLSConfig struct {
-	Options map[string]interface{} `json:"options,omitempty"`
+	Options map[string]interface{}     `json:"options,omitempty"`
+	Extra   map[string]json.RawMessage `json:"-"`
 }
 
-// OrderedRouter represents a Traefik HTTP router with fields in Pangolin's order.
-// The JSON field order matches Pangolin API output for consistency.
+// OrderedRouter is a typed Traefik HTTP router. It marshals with fields in
+// Pangolin's order; fields MM does not model are kept in Extra (see router_json.go).
 type OrderedRouter struct {
-	EntryPoints []string          `json:"entryPoints,omitempty"`
-	Middlewares []string          `json:"middlewares,omitempty"`
-	Service     string            `json:"service,omitempty"`
-	Rule        string            `json:"rule,omitempty"`
-	Priority    int               `json:"priority,omitempty"`
-	TLS         *OrderedTLSConfig `json:"tls,omitempty"`
+	EntryPoints []string
+	Middlewares []string
+	Service     string
+	Rule        string
+	Priority    int
+	TLS         *OrderedTLSConfig
+	Extra       map[string]json.RawMessage
 }
 
-// OrderedTLSConfig represents TLS config for a router with Pangolin's field order.
+// OrderedTLSConfig is a router's TLS section, typed the same way as OrderedRouter.
 type OrderedTLSConfig struct {
-	CertResolver string   `json:"certResolver,omitempty"`
-	Domains      []string `json:"domains,omitempty"`
-	Options      string   `json:"options,omitempty"`
+	CertResolver string
+	Domains      []RouterTLSDomain
+	Options      string
+	Extra        map[string]json.RawMessage
+}
+
+// RouterTLSDomain is a certificate domain requested by a router
+type RouterTLSDomain struct {
+	Main string   `json:"main,omitempty"`
+	SANs []string `json:"sans,omitempty"`
 }
 
 // OrderedMiddleware represents a middleware with Pangolin's field order.
@@ -113,18 +131,51 @@ type resourceData struct {
 	MTLSRejectCode       sql.NullInt64
 	MTLSRefresh          sql.NullString
 	MTLSExternal         sql.NullString
+	MTLSExemptPaths      []string
 	TLSHardeningEnabled  bool
+	TLSHardeningProfile  string
+	TLSHardeningOptOut   bool
 	SecureHeadersEnabled bool
-	Middlewares          []middlewareWithPriority
-	ExternalMiddlewares  []externalMiddlewareRef
-	CustomServiceID      sql.NullString
+	SecureHeadersPreset  string
+	// Emit CSP as report-only and a soft HSTS while a policy is trialed
+	SecureHeadersReportOnly bool
+	ForwardAuthEnabled      bool
+	HTTPSRedirect           string
+	Middlewares             []middlewareWithPriority
+	ExternalMiddlewares     []externalMiddlewareRef
+	CustomServiceID         sql.NullString
+	// Header policy configs in ascending precedence (lowest priority first)
+	GroupHeaderPolicies    []map[string]interface{}
+	ResourceHeaderPolicies []map[string]interface{}
+	CORSHeaders            map[string]interface{}
+	// Rendered Coraza plugin middleware (nil when the WAF is off for the resource)
+	WAFMiddleware map[string]interface{}
+	// User-agent substrings from the bot lists assigned to the resource
+	BlockedUserAgents []string
+	// Request mirror to a test environment (nil when not mirrored)
+	Mirror *models.ResourceMirror
+	// Failover to a fallback while the service is unhealthy (nil when not configured)
+	Failover *models.ResourceFailover
+	// Blue/green deployment moving the resource to a new backend (nil when none)
+	Deployment *models.ResourceDeployment
+	// ID of the active traffic capture (empty when none)
+	CaptureID string
+	// Leave the global default middleware chain off this resource's router
+	DefaultChainExcluded bool
+	// Where MM's middlewares go relative to the router's existing ones
+	MiddlewareOrder models.MiddlewareOrder
+	// Upstream middlewares dropped from or replaced on the router
+	UpstreamRemovals []models.UpstreamMiddlewareRemoval
 }
 
 // securityConfigData holds global security settings from the database
 type securityConfigData struct {
 	TLSHardeningEnabled  bool
+	TLSHardeningMode     string
 	SecureHeadersEnabled bool
 	SecureHeaders        models.SecureHeadersConfig
+	// Custom secure headers presets by ID; built-in presets are resolved from models
+	SecureHeadersPresets map[string]models.SecureHeadersConfig
 }
 
 // ConfigProxy fetches config from Pangolin and merges MW-manager additions
@@ -134,11 +185,75 @@ type ConfigProxy struct {
 	pangolinURL   string
 	httpClient    *http.Client
 
+	// Base URL Traefik uses to reach the built-in forwardAuth endpoint (empty disables it)
+	forwardAuthURL string
+
 	// Caching
 	cache         *ProxiedTraefikConfig
 	cacheExpiry   time.Time
 	cacheDuration time.Duration
 	cacheMutex    sync.RWMutex
+	// Coalesces the refreshes of concurrent polls after the cache expired
+	refreshGroup singleflight.Group
+
+	// Parsed Pangolin sections keyed on the response hash (see pangolin_cache.go)
+	pangolinCache pangolinCache
+
+	// Stops fetching from a failing Pangolin and counts connection reuse (see pangolin_breaker.go)
+	pangolinBreaker *circuitBreaker
+	pangolinPool    connectionPoolStats
+
+	// Protocol sections MM leaves as upstream sent them (see merge_sections.go)
+	sections MergeSections
+
+	// Revisions of the served config for delta consumers (see config_changes.go)
+	changeLog configChangeLog
+
+	// Posts served config changes to an external consumer (see config_webhook.go)
+	webhook *configWebhook
+
+	// Clients of the config endpoint, for the provider health check (see provider_polls.go)
+	providerPolls providerPollLog
+
+	// Validation: configs with more errors than errorBudget are replaced by lastKnownGood
+	errorBudget   int
+	lastKnownGood *ProxiedTraefikConfig
+	validation    ConfigValidationStatus
+
+	// Size and complexity limits checked on every merge (see config_guardrails.go)
+	limits ConfigLimits
+
+	// Traefik version generated options are adapted to (see traefik_compat.go)
+	traefikVersion traefikVersionState
+
+	// HTTP→HTTPS redirects: static config checked for entrypoint redirections and
+	// the per-resource outcome of the last merge (see https_redirect.go)
+	staticConfigPath string
+	redirectStatus   []ResourceRedirectStatus
+
+	// Outcome of the per-resource upstream middleware removals (see upstream_removals.go)
+	upstreamRemovals []UpstreamRemovalStatus
+	// Outcome of the service overrides (see service_overrides.go)
+	serviceOverrides []ServiceOverrideStatus
+
+	// Servers of dnsDiscovery services, cached for their TTL (see dns_discovery.go)
+	dnsDiscovery *DNSDiscovery
+
+	// What the latest merge changed in the upstream config, when recorded (see transform_log.go)
+	transformLog   bool
+	lastTransforms *TransformLog
+
+	// Tells the webhooks when merging starts failing (see webhook_events.go)
+	mergeFailures failureNotifier
+
+	// Faults injected into the config endpoint when PROXY_CHAOS is on (see proxy_chaos.go)
+	chaos chaosState
+
+	// The config last written to a provider, the base of diff previews (see config_diff.go)
+	lastServed servedConfig
+
+	// Merged configs of the named environments other than the default (see environments.go)
+	environments map[string]cachedEnvironmentConfig
 }
 
 // NewConfigProxy creates a new config proxy instance
@@ -149,6 +264,10 @@ func NewConfigProxy(db *database.DB, configManager *ConfigManager, pangolinURL s
 		pangolinURL:   pangolinURL,
 		httpClient:    HTTPClientWithTimeout(10 * time.Second),
 		cacheDuration: 5 * time.Second, // Match typical Traefik poll interval
+		limits:        DefaultConfigLimits,
+
+		pangolinBreaker: newCircuitBreaker(DefaultPangolinBreakerThreshold, DefaultPangolinBreakerCooldown),
+		dnsDiscovery:    NewDNSDiscovery(),
 	}
 }
 
@@ -160,41 +279,122 @@ func (cp *ConfigProxy) GetMergedConfig() (*ProxiedTraefikConfig, error) {
 		defer cp.cacheMutex.RUnlock()
 		return cp.cache, nil
 	}
+	cp.cacheMutex.RUnlock()
+
+	// Replicas polling right after expiry share a single upstream fetch
+	result, err, _ := cp.refreshGroup.Do("merged-config", func() (interface{}, error) {
+		return cp.refreshMergedConfig()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*ProxiedTraefikConfig), nil
+}
+
+// refreshMergedConfig fetches and merges a fresh config and swaps the cache
+func (cp *ConfigProxy) refreshMergedConfig() (*ProxiedTraefikConfig, error) {
+	// A refresh that finished while this call waited for the group may
+	// already have filled the cache
+	cp.cacheMutex.RLock()
+	if cp.cache != nil && time.Now().Before(cp.cacheExpiry) {
+		defer cp.cacheMutex.RUnlock()
+		return cp.cache, nil
+	}
 	staleCache := cp.cache
+	limits := cp.limits
+	sections := cp.sections
+	recordTransforms := cp.transformLog
 	cp.cacheMutex.RUnlock()
 
 	// Fetch fresh config OUTSIDE the lock to avoid blocking readers
-	config, err := cp.fetchPangolinConfig()
+	config, err := cp.fetchPangolinConfigGuarded()
 	if err != nil {
-		// Return stale cache on error if available
+		// Return stale cache on error if available; the breaker already
+		// logged why it is open
 		if staleCache != nil {
-			log.Printf("Warning: Pangolin fetch failed, using stale cache: %v", err)
+			if !errors.Is(err, ErrPangolinCircuitOpen) {
+				log.Printf("Warning: Pangolin fetch failed, using stale cache: %v", err)
+			}
 			return staleCache, nil
 		}
 		return nil, fmt.Errorf("failed to fetch Pangolin config: %w", err)
 	}
 
-	// Merge MW-manager additions (no lock needed, operates on local config)
-	if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+	merge, err := cp.mergeConfig(config, sections, limits, recordTransforms, "")
+	if err != nil {
+		cp.mergeFailures.report("merge", err)
+		return nil, err
+	}
+	cp.mergeFailures.report("merge", nil)
+
+	// Lock only to swap the cache
+	cp.cacheMutex.Lock()
+	cp.recordLimitWarnings(merge.limitWarnings)
+	served := cp.selectServedConfig(merge.config, merge.validationErrors)
+	if merge.transforms != nil && cp.transformLog {
+		merge.transforms.Served = served == merge.config
+		cp.lastTransforms = merge.transforms
+	}
+	cp.cache = served
+	cp.cacheExpiry = time.Now().Add(cp.cacheDuration)
+	cp.cacheMutex.Unlock()
+
+	if cp.changeLog.record(served) && cp.webhook != nil {
+		cp.webhook.notify(served, cp.ConfigRevision())
+	}
+
+	return served, nil
+}
+
+// configMerge is a merged config before the error budget decides whether
+// it is served
+type configMerge struct {
+	config           *ProxiedTraefikConfig
+	validationErrors []string
+	limitWarnings    []ConfigLimitWarning
+	transforms       *TransformLog
+}
+
+// mergeConfig applies MM's changes for an environment ("" for the default
+// one) to a fetched upstream config, then validates and normalizes it
+func (cp *ConfigProxy) mergeConfig(config *ProxiedTraefikConfig, sections MergeSections, limits ConfigLimits, recordTransforms bool, environment string) (*configMerge, error) {
+	// Keep the upstream config to log what the merge changes
+	var upstream map[string]interface{}
+	if recordTransforms {
+		upstream = configTree(config)
+	}
+
+	// Merge MW-manager additions (no lock needed, operates on local config);
+	// disabled protocol sections are kept out of reach and served as fetched
+	held := holdUpstreamSections(config, sections)
+	if err := cp.mergeEnvironmentConfig(config, environment); err != nil {
 		return nil, fmt.Errorf("failed to merge MW-manager config: %w", err)
 	}
 
+	// Rename or drop middleware options the connected Traefik version does not accept
+	cp.applyTraefikCompat(config)
+	held.restore(config)
+
 	// Remove empty protocol sections so Traefik doesn't reject blank configs
 	cp.pruneEmptySections(config)
 
-	// Normalize router field ordering to match Pangolin's JSON format
-	cp.normalizeRouterOrder(config)
+	merge := &configMerge{config: config}
+	if upstream != nil {
+		if merged := configTree(config); merged != nil {
+			merge.transforms = &TransformLog{MergedAt: time.Now().UTC()}
+			merge.transforms.Changes, merge.transforms.Truncated = diffTransforms(upstream, merged)
+		}
+	}
+
+	// Validate before middlewares are converted to ordered structs
+	merge.validationErrors = cp.validateConfig(config)
 
 	// Normalize middleware field ordering to match Pangolin's JSON format
 	cp.normalizeMiddlewareOrder(config)
 
-	// Lock only to swap the cache
-	cp.cacheMutex.Lock()
-	cp.cache = config
-	cp.cacheExpiry = time.Now().Add(cp.cacheDuration)
-	cp.cacheMutex.Unlock()
-
-	return config, nil
+	// Warn about configs that degrade Traefik without failing validation
+	merge.limitWarnings = checkConfigLimits(config, limits)
+	return merge, nil
 }
 
 // InvalidateCache forces the next GetMergedConfig call to fetch fresh data
@@ -202,6 +402,9 @@ func (cp *ConfigProxy) InvalidateCache() {
 	cp.cacheMutex.Lock()
 	defer cp.cacheMutex.Unlock()
 	cp.cacheExpiry = time.Now().Add(-1 * time.Second) // Expire immediately
+	// Do not hand a refresh started before the invalidation to later callers
+	cp.refreshGroup.Forget("merged-config")
+	cp.expireEnvironments()
 }
 
 // fetchPangolinConfig fetches the Traefik configuration from Pangolin API
@@ -231,7 +434,14 @@ func (cp *ConfigProxy) fetchPangolinConfig() (*ProxiedTraefikConfig, error) {
 		log.Printf("Fetching Pangolin config from: %s", url)
 	}
 
-	resp, err := cp.httpClient.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req, done := cp.pangolinPool.trace(req)
+	defer done()
+
+	resp, err := doWithRetry(cp.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -242,8 +452,19 @@ func (cp *ConfigProxy) fetchPangolinConfig() (*ProxiedTraefikConfig, error) {
 		return nil, fmt.Errorf("Pangolin returned status %d: %s", resp.StatusCode, string(body))
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Pangolin response: %w", err)
+	}
+
+	// Unchanged responses reuse the parsed sections; only the merge reruns
+	return cp.pangolinCache.load(body, cp.decodeProxiedBody)
+}
+
+// decodeProxiedConfig parses a provider payload and initializes its nil maps
+func (cp *ConfigProxy) decodeProxiedConfig(r io.Reader) (*ProxiedTraefikConfig, error) {
 	var config ProxiedTraefikConfig
-	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
 		return nil, fmt.Errorf("failed to decode Pangolin response: %w", err)
 	}
 
@@ -262,7 +483,7 @@ func (cp *ConfigProxy) initializeConfigMaps(config *ProxiedTraefikConfig) {
 		config.HTTP.Middlewares = make(map[string]interface{})
 	}
 	if config.HTTP.Routers == nil {
-		config.HTTP.Routers = make(map[string]interface{})
+		config.HTTP.Routers = make(map[string]*OrderedRouter)
 	}
 	if config.HTTP.Services == nil {
 		config.HTTP.Services = make(map[string]interface{})
@@ -307,33 +528,55 @@ func (cp *ConfigProxy) pruneEmptySections(config *ProxiedTraefikConfig) {
 	}
 
 	if config.TCP != nil {
-		if len(config.TCP.Routers) == 0 && len(config.TCP.Services) == 0 {
+		if len(config.TCP.Routers) == 0 && len(config.TCP.Services) == 0 && len(config.TCP.Extra) == 0 {
 			config.TCP = nil
 		}
 	}
 
 	if config.UDP != nil {
-		if len(config.UDP.Routers) == 0 && len(config.UDP.Services) == 0 {
+		if len(config.UDP.Routers) == 0 && len(config.UDP.Services) == 0 && len(config.UDP.Extra) == 0 {
 			config.UDP = nil
 		}
 	}
 
 	if config.TLS != nil {
-		if len(config.TLS.Options) == 0 {
+		if len(config.TLS.Options) == 0 && len(config.TLS.Extra) == 0 {
 			config.TLS = nil
 		}
 	}
 }
 
 // mergeMiddlewareManagerConfig merges MW-manager middlewares into the config
-// NOTE: Routers and services come from Pangolin API and are NOT modified here.
+// of the default environment
 func (cp *ConfigProxy) mergeMiddlewareManagerConfig(config *ProxiedTraefikConfig) error {
+	return cp.mergeEnvironmentConfig(config, "")
+}
+
+// mergeEnvironmentConfig merges the MW-manager middlewares of an environment
+// ("" for the default one) into the config
+// NOTE: Routers and services come from Pangolin API and are NOT modified here.
+func (cp *ConfigProxy) mergeEnvironmentConfig(config *ProxiedTraefikConfig, environment string) error {
 	// Load resources and their middleware assignments
 	resources, err := cp.fetchResourceData()
 	if err != nil {
 		return fmt.Errorf("failed to fetch resources: %w", err)
 	}
 
+	// Routers for resources outside the management scope pass through untouched
+	resources = cp.filterByScope(resources)
+
+	// So do routers of resources tagged with other environments; middlewares
+	// tagged with other environments are not rendered
+	envFilter, err := cp.loadEnvironmentFilter(environment)
+	if err != nil {
+		log.Printf("Warning: failed to load environment tags, applying every resource and middleware: %v", err)
+	}
+	resources = envFilter.resources(resources)
+
+	// Drop generated middlewares left in the upstream config; the features
+	// still active render theirs again below
+	collected := collectGeneratedMiddlewares(config, resources)
+
 	// Load global security config
 	securityCfg, err := cp.loadSecurityConfig()
 	if err != nil {
@@ -343,21 +586,52 @@ func (cp *ConfigProxy) mergeMiddlewareManagerConfig(config *ProxiedTraefikConfig
 
 	assignedMiddlewareIDs := make(map[string]struct{})
 	hasMTLSResources := false
-	hasTLSHardeningResources := false
+	tlsProfiles := make(map[string]struct{})
 
 	for _, res := range resources {
 		if res.MTLSEnabled {
 			hasMTLSResources = true
 		}
-		if res.TLSHardeningEnabled && !res.MTLSEnabled {
-			hasTLSHardeningResources = true
+		if tlsHardeningActive(res, securityCfg) {
+			tlsProfiles[res.TLSHardeningProfile] = struct{}{}
 		}
 		for _, mw := range res.Middlewares {
 			assignedMiddlewareIDs[mw.ID] = struct{}{}
 		}
 	}
 
+	// Middlewares attached to whole entrypoints are rendered like assigned ones
+	entrypointMiddlewares, err := cp.loadEntrypointMiddlewares()
+	if err != nil {
+		log.Printf("Warning: failed to load entrypoint middlewares: %v", err)
+	}
+	for entrypoint, attached := range entrypointMiddlewares {
+		attached = envFilter.middlewares(attached)
+		entrypointMiddlewares[entrypoint] = attached
+		for _, mw := range attached {
+			assignedMiddlewareIDs[mw.ID] = struct{}{}
+		}
+	}
+	defaultChain, err := cp.loadDefaultChain()
+	if err != nil {
+		log.Printf("Warning: failed to load default middleware chain: %v", err)
+	}
+	defaultChain = envFilter.middlewares(defaultChain)
+	for _, mw := range defaultChain {
+		assignedMiddlewareIDs[mw.ID] = struct{}{}
+	}
+
 	var mtlsCfg *mtlsConfigData
+	// Without the TLS section routers could not reference the mTLS and
+	// hardening options, so neither is applied
+	if cp.mergeSections().DisableTLS {
+		if hasMTLSResources || len(tlsProfiles) > 0 {
+			log.Printf("TLS section merging is disabled; skipping mTLS and TLS hardening options")
+		}
+		hasMTLSResources = false
+		clear(tlsProfiles)
+	}
+
 	if hasMTLSResources {
 		cfg, err := cp.loadGlobalMTLSConfig()
 		if err != nil {
@@ -369,9 +643,9 @@ func (cp *ConfigProxy) mergeMiddlewareManagerConfig(config *ProxiedTraefikConfig
 		}
 	}
 
-	// Apply TLS hardening options if any resource has it enabled (and not mTLS)
-	if hasTLSHardeningResources {
-		cp.applyTLSHardeningOptions(config)
+	// Apply TLS hardening options for each profile a resource uses (and not mTLS)
+	if len(tlsProfiles) > 0 {
+		cp.applyTLSHardeningOptions(config, tlsProfiles)
 	}
 
 	// Only add MW-manager middlewares that are assigned to resources/routers
@@ -381,6 +655,12 @@ func (cp *ConfigProxy) mergeMiddlewareManagerConfig(config *ProxiedTraefikConfig
 		}
 	}
 
+	// Patch discovered services with their stored overrides
+	cp.applyServiceOverrides(config)
+
+	// Serve dnsDiscovery services with the servers their records resolve to
+	cp.applyDNSServices(config)
+
 	// Apply resource-specific overrides (middleware attachments, priorities, headers, mtls, security)
 	if len(resources) > 0 {
 		if err := cp.applyResourceOverrides(config, resources, mtlsCfg, securityCfg); err != nil {
@@ -388,12 +668,93 @@ func (cp *ConfigProxy) mergeMiddlewareManagerConfig(config *ProxiedTraefikConfig
 		}
 	}
 
+	// Put the global default chain in front of every router before the
+	// helper routers below copy router middlewares
+	cp.applyDefaultChain(config, resources, defaultChain)
+
+	// Mark requests of resources with an active traffic capture
+	cp.applyTrafficCapture(config, resources)
+
+	// Add web→websecure redirect routers where requested and not already present
+	cp.applyHTTPSRedirects(config, resources)
+
+	// Point routers of blue/green deployments at the active color; failover
+	// and mirroring below then wrap it
+	cp.applyDeployments(config, resources)
+
+	// Send requests of unhealthy services to their fallbacks; mirroring below
+	// then wraps the failover service
+	cp.applyFailovers(config, resources)
+
+	// Copy a share of mirrored resources' requests to their test targets
+	cp.applyMirroring(config, resources)
+
+	// Route mTLS-exempt paths through copies of the resource routers without the mTLS middleware
+	cp.applyMTLSExemptions(config, resources)
+
+	// Reject requests from blocked user agents ahead of the resource routers
+	cp.applyBotBlocking(config, resources)
+
+	// Put entrypoint middlewares in front of every router on those entrypoints
+	cp.applyEntrypointMiddlewares(config, entrypointMiddlewares)
+
+	// Remove references to generated middlewares whose feature is no longer active
+	pruneStaleGeneratedMiddlewares(config, collected)
+
 	// Sanitize mtlswhitelist requestHeaders to ensure map type (Traefik plugin is strict)
 	cp.sanitizeMTLSWhitelist(config)
 
 	return nil
 }
 
+// filterByScope drops resources whose host is outside the management scope
+func (cp *ConfigProxy) filterByScope(resources []*resourceData) []*resourceData {
+	scope, err := LoadManagementScope(cp.db.DB)
+	if err != nil {
+		log.Printf("Warning: failed to load management scope, managing all resources: %v", err)
+		return resources
+	}
+	if scope.IsUnrestricted() {
+		return resources
+	}
+
+	inScope := resources[:0]
+	for _, res := range resources {
+		if scope.Matches(res.Host) {
+			inScope = append(inScope, res)
+		} else if shouldLog() {
+			log.Printf("Resource %s (%s) is outside the management scope; leaving its router untouched", res.ID, res.Host)
+		}
+	}
+	return inScope
+}
+
+// protectedNames loads names that must never be overridden; on error nothing is protected
+func (cp *ConfigProxy) protectedNames(kind string) database.ProtectedSet {
+	set, err := cp.db.ProtectedNames(kind)
+	if err != nil {
+		log.Printf("Warning: failed to load protected %s names: %v", kind, err)
+		return nil
+	}
+	return set
+}
+
+// serviceExists reports whether the upstream config already defines a service
+func (cp *ConfigProxy) serviceExists(config *ProxiedTraefikConfig, protocol, id string) bool {
+	switch protocol {
+	case "http":
+		_, ok := config.HTTP.Services[id]
+		return ok
+	case "tcp":
+		_, ok := config.TCP.Services[id]
+		return ok
+	case "udp":
+		_, ok := config.UDP.Services[id]
+		return ok
+	}
+	return false
+}
+
 // applyMiddlewares adds custom middlewares from the database
 func (cp *ConfigProxy) applyMiddlewares(config *ProxiedTraefikConfig, allowedIDs map[string]struct{}) error {
 	rows, err := cp.db.Query("SELECT id, name, type, config FROM middlewares")
@@ -402,6 +763,8 @@ func (cp *ConfigProxy) applyMiddlewares(config *ProxiedTraefikConfig, allowedIDs
 	}
 	defer rows.Close()
 
+	protected := cp.protectedNames(database.ProtectedKindMiddleware)
+
 	for rows.Next() {
 		var id, name, typ, configStr string
 		if err := rows.Scan(&id, &name, &typ, &configStr); err != nil {
@@ -425,6 +788,12 @@ func (cp *ConfigProxy) applyMiddlewares(config *ProxiedTraefikConfig, allowedIDs
 		// Use the centralized processing logic from models package
 		middlewareConfig = models.ProcessMiddlewareConfig(typ, middlewareConfig)
 
+		// Protected upstream middlewares keep their upstream definition
+		if _, exists := config.HTTP.Middlewares[name]; exists && protected.Contains(name) {
+			log.Printf("Middleware %s is protected; keeping upstream definition", name)
+			continue
+		}
+
 		// Add middleware using its name as the key (so chain references by name work)
 		config.HTTP.Middlewares[name] = map[string]interface{}{
 			typ: middlewareConfig,
@@ -446,6 +815,8 @@ func (cp *ConfigProxy) applyServices(config *ProxiedTraefikConfig) error {
 	}
 	defer rows.Close()
 
+	protected := cp.protectedNames(database.ProtectedKindService)
+
 	for rows.Next() {
 		var id, name, typ, configStr string
 		if err := rows.Scan(&id, &name, &typ, &configStr); err != nil {
@@ -467,6 +838,11 @@ func (cp *ConfigProxy) applyServices(config *ProxiedTraefikConfig) error {
 
 		serviceEntry := map[string]interface{}{typ: serviceConfig}
 
+		if protected.Contains(id) && cp.serviceExists(config, protocol, id) {
+			log.Printf("Service %s is protected; keeping upstream definition", id)
+			continue
+		}
+
 		switch protocol {
 		case "http":
 			config.HTTP.Services[id] = serviceEntry
@@ -486,6 +862,16 @@ func (cp *ConfigProxy) applyServices(config *ProxiedTraefikConfig) error {
 
 // applyResourceOverrides applies middleware assignments and other overrides to routers
 func (cp *ConfigProxy) applyResourceOverrides(config *ProxiedTraefikConfig, resources []*resourceData, mtlsCfg *mtlsConfigData, securityCfg *securityConfigData) error {
+	protectedServices := cp.protectedNames(database.ProtectedKindService)
+	tlsMerged := !cp.mergeSections().DisableTLS
+
+	removals := []UpstreamRemovalStatus{}
+	defer func() {
+		cp.cacheMutex.Lock()
+		cp.upstreamRemovals = removals
+		cp.cacheMutex.Unlock()
+	}()
+
 	for _, resource := range resources {
 		// First try to find router by pangolin_router_id (direct match)
 		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
@@ -501,10 +887,20 @@ func (cp *ConfigProxy) applyResourceOverrides(config *ProxiedTraefikConfig, reso
 				log.Printf("No matching router found for resource %s (pangolin: %s, host: %s)",
 					resource.ID, resource.PangolinRouterID, resource.Host)
 			}
+			for _, removal := range resource.UpstreamRemovals {
+				removals = append(removals, UpstreamRemovalStatus{
+					ResourceID: resource.ID, Name: removal.Name, ReplaceWith: removal.ReplaceWith, State: UpstreamRemovalNoRouter,
+				})
+			}
 			continue
 		}
 
-		// Build middleware list (mTLS first, then secure headers, then custom headers, then assigned)
+		// Drop or replace the upstream middlewares the resource opted out of
+		if len(resource.UpstreamRemovals) > 0 {
+			removals = append(removals, removeUpstreamMiddlewares(routerKey, router, resource)...)
+		}
+
+		// Build middleware list (mTLS first, then WAF, forward auth, merged headers, then assigned)
 		var newMiddlewares []string
 
 		if resource.MTLSEnabled && mtlsCfg != nil {
@@ -515,50 +911,40 @@ func (cp *ConfigProxy) applyResourceOverrides(config *ProxiedTraefikConfig, reso
 				newMiddlewares = append(newMiddlewares, mtlsMiddlewareName)
 
 				// Add mTLS TLS options on the router
-				if tlsConfig, ok := router["tls"].(map[string]interface{}); ok {
-					if _, ok := tlsConfig["options"]; !ok {
-						tlsConfig["options"] = "mtls-verify"
-					}
-				} else {
-					router["tls"] = map[string]interface{}{
-						"options": "mtls-verify",
-					}
+				if router.TLS == nil {
+					router.TLS = &OrderedTLSConfig{}
+				}
+				if router.TLS.Options == "" {
+					router.TLS.Options = "mtls-verify"
 				}
 			}
 		}
 
-		// Apply TLS hardening if enabled for this resource AND mTLS is NOT enabled
-		// (mTLS already includes TLS hardening via mtls-verify options)
-		if resource.TLSHardeningEnabled && !resource.MTLSEnabled {
-			if tlsConfig, ok := router["tls"].(map[string]interface{}); ok {
-				tlsConfig["options"] = "tls-hardened"
-			} else {
-				router["tls"] = map[string]interface{}{
-					"options": "tls-hardened",
-				}
+		// Apply TLS hardening if the inheritance mode resolves to on for this resource
+		if tlsMerged && tlsHardeningActive(resource, securityCfg) {
+			if router.TLS == nil {
+				router.TLS = &OrderedTLSConfig{}
 			}
+			router.TLS.Options = models.TLSProfileOptionsName(resource.TLSHardeningProfile)
 		}
 
-		// Add secure headers middleware if enabled for this resource
-		if resource.SecureHeadersEnabled && securityCfg != nil && securityCfg.SecureHeadersEnabled {
-			secureHeadersMiddlewareName := cp.ensureSecureHeadersMiddleware(config, resource, securityCfg)
-			if secureHeadersMiddlewareName != "" {
-				newMiddlewares = append(newMiddlewares, secureHeadersMiddlewareName)
-			}
+		// Inspect requests with the WAF before they reach auth or the backend
+		if resource.WAFMiddleware != nil {
+			newMiddlewares = append(newMiddlewares, cp.ensureWAFMiddleware(config, resource))
 		}
 
-		// Add custom headers middleware if configured
-		if resource.CustomHeaders != "" && resource.CustomHeaders != "{}" && resource.CustomHeaders != "null" {
-			var headersMap map[string]string
-			if err := json.Unmarshal([]byte(resource.CustomHeaders), &headersMap); err == nil && len(headersMap) > 0 {
-				middlewareName := fmt.Sprintf("%s-customheaders", resource.ID)
-				config.HTTP.Middlewares[middlewareName] = map[string]interface{}{
-					"headers": map[string]interface{}{"customRequestHeaders": headersMap},
-				}
-				newMiddlewares = append(newMiddlewares, middlewareName)
+		// Add the built-in forward auth middleware if enabled for this resource
+		if resource.ForwardAuthEnabled {
+			if forwardAuthMiddlewareName := cp.ensureForwardAuthMiddleware(config, resource); forwardAuthMiddlewareName != "" {
+				newMiddlewares = append(newMiddlewares, forwardAuthMiddlewareName)
 			}
 		}
 
+		// Add the merged headers middleware (secure headers, header policies, custom headers)
+		if headersMiddlewareName := cp.ensureHeadersMiddleware(config, resource, securityCfg); headersMiddlewareName != "" {
+			newMiddlewares = append(newMiddlewares, headersMiddlewareName)
+		}
+
 		// Build a combined list of internal + external middlewares sorted by priority
 		type middlewareEntry struct {
 			Name     string
@@ -581,42 +967,29 @@ func (cp *ConfigProxy) applyResourceOverrides(config *ProxiedTraefikConfig, reso
 			newMiddlewares = append(newMiddlewares, entry.Name)
 		}
 
-		// Get existing middlewares from router
-		existingMiddlewares := cp.getRouterMiddlewares(router)
-
-		// Merge middlewares (MW-manager additions first, then existing)
-		finalMiddlewares := newMiddlewares
-		for _, em := range existingMiddlewares {
-			// Avoid duplicates
-			found := false
-			for _, nm := range newMiddlewares {
-				if em == nm {
-					found = true
-					break
-				}
-			}
-			if !found {
-				finalMiddlewares = append(finalMiddlewares, em)
-			}
-		}
+		// Merge with the router's existing middlewares following the resource's
+		// ordering policy (MW-manager additions first by default)
+		finalMiddlewares := orderRouterMiddlewares(resource.MiddlewareOrder, newMiddlewares, router.Middlewares)
 
 		// Update router
 		if len(finalMiddlewares) > 0 {
-			router["middlewares"] = finalMiddlewares
+			router.Middlewares = finalMiddlewares
 		}
 
 		// Update priority if customized
 		if resource.RouterPriority != 100 {
-			router["priority"] = resource.RouterPriority
+			router.Priority = resource.RouterPriority
 		}
 
-		// Update custom service if configured
+		// Update custom service if configured (routers pointing at a protected service keep it)
 		if resource.CustomServiceID.Valid && resource.CustomServiceID.String != "" {
-			router["service"] = resource.CustomServiceID.String
+			if current := router.Service; current != "" && protectedServices.Contains(current) {
+				log.Printf("Router %s uses protected service %s; ignoring custom service override", routerKey, current)
+			} else {
+				router.Service = resource.CustomServiceID.String
+			}
 		}
 
-		config.HTTP.Routers[routerKey] = router
-
 		if shouldLog() {
 			log.Printf("Applied overrides to router %s (resource: %s)", routerKey, resource.ID)
 		}
@@ -724,18 +1097,27 @@ func (cp *ConfigProxy) fetchResourceData() ([]*resourceData, error) {
 		SELECT r.id, COALESCE(r.pangolin_router_id, r.id), r.host, r.service_id, r.entrypoints, r.tls_domains,
 		       r.custom_headers, r.router_priority, r.source_type, r.mtls_enabled,
 		       r.mtls_rules, r.mtls_request_headers, r.mtls_reject_message, r.mtls_reject_code,
-		       r.mtls_refresh_interval, r.mtls_external_data,
-		       COALESCE(r.tls_hardening_enabled, 0), COALESCE(r.secure_headers_enabled, 0),
+		       r.mtls_refresh_interval, r.mtls_external_data, COALESCE(r.mtls_exempt_paths, '[]'),
+		       COALESCE(r.tls_hardening_enabled, 0), COALESCE(r.tls_hardening_profile, 'hardened'),
+		       COALESCE(r.tls_hardening_opt_out, 0),
+		       COALESCE(r.secure_headers_enabled, 0), COALESCE(r.secure_headers_preset, ''),
+		       COALESCE(r.secure_headers_report_only, 0),
+		       COALESCE(r.forward_auth_enabled, 0), COALESCE(r.https_redirect, 'inherit'),
+		       COALESCE(r.default_chain_excluded, 0),
+		       COALESCE(r.middleware_order, 'prepend'), COALESCE(r.middleware_positions, '{}'),
+		       COALESCE(r.upstream_middleware_removals, '[]'),
 		       rm.middleware_id, rm.priority, m.name as middleware_name,
 		       rs.service_id as custom_service_id
 		FROM resources r
 		LEFT JOIN resource_middlewares rm ON r.id = rm.resource_id
+		     AND (rm.expires_at IS NULL OR rm.expires_at > ?)
 		LEFT JOIN middlewares m ON rm.middleware_id = m.id
 		LEFT JOIN resource_services rs ON r.id = rs.resource_id
 		WHERE r.status = 'active'
 		ORDER BY r.id, rm.priority DESC
 	`
-	rows, err := cp.db.Query(query)
+	// Expired temporary assignments are left out even before the expirer removes them
+	rows, err := cp.db.Query(query, time.Now().UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -744,22 +1126,29 @@ func (cp *ConfigProxy) fetchResourceData() ([]*resourceData, error) {
 	resourceMap := make(map[string]*resourceData)
 
 	for rows.Next() {
-		var rID, pangolinRouterID, host, serviceID, entrypoints, tlsDomains, customHeaders, sourceType string
+		var rID, pangolinRouterID, host, serviceID, entrypoints, tlsDomains, customHeaders, sourceType, httpsRedirect string
+		var tlsHardeningProfile, secureHeadersPreset string
 		var routerPriority sql.NullInt64
-		var mtlsEnabled, tlsHardeningEnabled, secureHeadersEnabled int
+		var mtlsEnabled, tlsHardeningEnabled, tlsHardeningOptOut, secureHeadersEnabled, secureHeadersReportOnly, forwardAuthEnabled int
+		var defaultChainExcluded int
+		var middlewareOrder, middlewarePositions, upstreamRemovals string
 		var middlewareID sql.NullString
 		var middlewarePriority sql.NullInt64
 		var middlewareName sql.NullString
 		var customServiceID sql.NullString
 		var mtlsRules, mtlsRequestHeaders, mtlsRejectMessage, mtlsRefreshInterval, mtlsExternalData sql.NullString
 		var mtlsRejectCode sql.NullInt64
+		var mtlsExemptPaths string
 
 		err := rows.Scan(
 			&rID, &pangolinRouterID, &host, &serviceID, &entrypoints, &tlsDomains,
 			&customHeaders, &routerPriority, &sourceType, &mtlsEnabled,
 			&mtlsRules, &mtlsRequestHeaders, &mtlsRejectMessage, &mtlsRejectCode,
-			&mtlsRefreshInterval, &mtlsExternalData,
-			&tlsHardeningEnabled, &secureHeadersEnabled,
+			&mtlsRefreshInterval, &mtlsExternalData, &mtlsExemptPaths,
+			&tlsHardeningEnabled, &tlsHardeningProfile, &tlsHardeningOptOut, &secureHeadersEnabled, &secureHeadersPreset, &secureHeadersReportOnly,
+			&forwardAuthEnabled, &httpsRedirect,
+			&defaultChainExcluded,
+			&middlewareOrder, &middlewarePositions, &upstreamRemovals,
 			&middlewareID, &middlewarePriority, &middlewareName, &customServiceID,
 		)
 		if err != nil {
@@ -774,25 +1163,37 @@ func (cp *ConfigProxy) fetchResourceData() ([]*resourceData, error) {
 				priority = int(routerPriority.Int64)
 			}
 			data = &resourceData{
-				ID:                   rID,
-				PangolinRouterID:     pangolinRouterID,
-				Host:                 host,
-				ServiceID:            serviceID,
-				Entrypoints:          entrypoints,
-				TLSDomains:           tlsDomains,
-				CustomHeaders:        customHeaders,
-				RouterPriority:       priority,
-				SourceType:           sourceType,
-				MTLSEnabled:          mtlsEnabled == 1,
-				TLSHardeningEnabled:  tlsHardeningEnabled == 1,
-				SecureHeadersEnabled: secureHeadersEnabled == 1,
-				CustomServiceID:      customServiceID,
-				MTLSRules:            mtlsRules,
-				MTLSRequestHdrs:      mtlsRequestHeaders,
-				MTLSRejectMsg:        mtlsRejectMessage,
-				MTLSRejectCode:       mtlsRejectCode,
-				MTLSRefresh:          mtlsRefreshInterval,
-				MTLSExternal:         mtlsExternalData,
+				ID:                      rID,
+				PangolinRouterID:        pangolinRouterID,
+				Host:                    host,
+				ServiceID:               serviceID,
+				Entrypoints:             entrypoints,
+				TLSDomains:              tlsDomains,
+				CustomHeaders:           customHeaders,
+				RouterPriority:          priority,
+				SourceType:              sourceType,
+				MTLSEnabled:             mtlsEnabled == 1,
+				TLSHardeningEnabled:     tlsHardeningEnabled == 1,
+				TLSHardeningProfile:     tlsHardeningProfile,
+				TLSHardeningOptOut:      tlsHardeningOptOut == 1,
+				SecureHeadersEnabled:    secureHeadersEnabled == 1,
+				SecureHeadersPreset:     secureHeadersPreset,
+				SecureHeadersReportOnly: secureHeadersReportOnly == 1,
+				ForwardAuthEnabled:      forwardAuthEnabled == 1,
+				HTTPSRedirect:           httpsRedirect,
+				DefaultChainExcluded:    defaultChainExcluded == 1,
+				MiddlewareOrder:         parseMiddlewareOrder(rID, middlewareOrder, middlewarePositions),
+				UpstreamRemovals:        parseUpstreamRemovals(rID, upstreamRemovals),
+				CustomServiceID:         customServiceID,
+				MTLSRules:               mtlsRules,
+				MTLSRequestHdrs:         mtlsRequestHeaders,
+				MTLSRejectMsg:           mtlsRejectMessage,
+				MTLSRejectCode:          mtlsRejectCode,
+				MTLSRefresh:             mtlsRefreshInterval,
+				MTLSExternal:            mtlsExternalData,
+			}
+			if err := json.Unmarshal([]byte(mtlsExemptPaths), &data.MTLSExemptPaths); err != nil {
+				log.Printf("Failed to parse mtls_exempt_paths for resource %s: %v", rID, err)
 			}
 			resourceMap[rID] = data
 		}
@@ -842,6 +1243,31 @@ func (cp *ConfigProxy) fetchResourceData() ([]*resourceData, error) {
 		}
 	}
 
+	if err := cp.loadHeaderPolicies(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch header policies: %v", err)
+	}
+	if err := cp.loadCORSPolicies(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch CORS policies: %v", err)
+	}
+	if err := cp.loadWAFSettings(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch WAF settings: %v", err)
+	}
+	if err := cp.loadBotLists(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch bot lists: %v", err)
+	}
+	if err := cp.loadMirrors(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch resource mirrors: %v", err)
+	}
+	if err := cp.loadFailovers(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch resource failovers: %v", err)
+	}
+	if err := cp.loadDeployments(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch blue/green deployments: %v", err)
+	}
+	if err := cp.loadCaptures(resourceMap); err != nil {
+		log.Printf("Warning: failed to fetch traffic captures: %v", err)
+	}
+
 	resources := make([]*resourceData, 0, len(resourceMap))
 	for _, r := range resourceMap {
 		resources = append(resources, r)
@@ -936,18 +1362,16 @@ func (cp *ConfigProxy) applyTLSOptions(config *ProxiedTraefikConfig, mtlsCfg *mt
 
 // findRouterByPangolinID finds a router by its Pangolin router ID (direct name match).
 // Prefers the main websecure router over redirect routers (-redirect suffix).
-func (cp *ConfigProxy) findRouterByPangolinID(routers map[string]interface{}, pangolinRouterID string) (string, map[string]interface{}) {
+func (cp *ConfigProxy) findRouterByPangolinID(routers map[string]*OrderedRouter, pangolinRouterID string) (string, *OrderedRouter) {
 	if pangolinRouterID == "" {
 		return "", nil
 	}
 
 	// Try direct match first
-	if routerConfig, ok := routers[pangolinRouterID]; ok {
-		if router, ok := routerConfig.(map[string]interface{}); ok {
-			// Verify it's not a redirect router - prefer websecure
-			if !strings.HasSuffix(pangolinRouterID, "-redirect") {
-				return pangolinRouterID, router
-			}
+	if router, ok := routers[pangolinRouterID]; ok && router != nil {
+		// Verify it's not a redirect router - prefer websecure
+		if !strings.HasSuffix(pangolinRouterID, "-redirect") {
+			return pangolinRouterID, router
 		}
 	}
 
@@ -955,62 +1379,54 @@ func (cp *ConfigProxy) findRouterByPangolinID(routers map[string]interface{}, pa
 	// If pangolinRouterID ends with "-redirect", try the base name
 	baseName := strings.TrimSuffix(pangolinRouterID, "-redirect")
 	if baseName != pangolinRouterID {
-		if routerConfig, ok := routers[baseName]; ok {
-			if router, ok := routerConfig.(map[string]interface{}); ok {
-				return baseName, router
-			}
+		if router, ok := routers[baseName]; ok && router != nil {
+			return baseName, router
 		}
 	}
 
 	// Try the -redirect version if we have the base name
 	redirectName := pangolinRouterID + "-redirect"
-	if routerConfig, ok := routers[redirectName]; ok {
-		if router, ok := routerConfig.(map[string]interface{}); ok {
-			// But only return redirect router if we can't find the main one
-			if _, ok := routers[pangolinRouterID]; !ok {
-				return redirectName, router
-			}
+	if router, ok := routers[redirectName]; ok && router != nil {
+		// But only return redirect router if we can't find the main one
+		if _, ok := routers[pangolinRouterID]; !ok {
+			return redirectName, router
 		}
 	}
 
 	// Return direct match even if it's a redirect router (better than nothing)
-	if routerConfig, ok := routers[pangolinRouterID]; ok {
-		if router, ok := routerConfig.(map[string]interface{}); ok {
-			return pangolinRouterID, router
-		}
+	if router, ok := routers[pangolinRouterID]; ok && router != nil {
+		return pangolinRouterID, router
 	}
 
 	return "", nil
 }
 
 // findMatchingRouter finds a router that matches the given host.
+// Hosts are compared in normalized form, so case, a trailing dot or an
+// internationalized spelling do not prevent a match. When several routers
+// serve the host it warns and returns no router rather than guess.
 // Prefers the main websecure router over redirect routers (-redirect suffix).
 // This ensures middlewares are applied to the HTTPS router, not the HTTP->HTTPS redirect router.
-func (cp *ConfigProxy) findMatchingRouter(routers map[string]interface{}, host string) (string, map[string]interface{}) {
+func (cp *ConfigProxy) findMatchingRouter(routers map[string]*OrderedRouter, host string) (string, *OrderedRouter) {
 	// Host matching regex
 	hostRegex := regexp.MustCompile(`Host\(\x60([^` + "`" + `]+)\x60\)`)
+	host = util.NormalizeHost(host)
 
 	// Collect all matching routers first
 	type matchedRouter struct {
 		name   string
-		router map[string]interface{}
+		router *OrderedRouter
 	}
 	var matches []matchedRouter
 
-	for routerName, routerConfig := range routers {
-		router, ok := routerConfig.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		rule, ok := router["rule"].(string)
-		if !ok {
+	for routerName, router := range routers {
+		if router == nil || router.Rule == "" {
 			continue
 		}
 
 		// Extract host from rule
-		hostMatches := hostRegex.FindStringSubmatch(rule)
-		if len(hostMatches) > 1 && hostMatches[1] == host {
+		hostMatches := hostRegex.FindStringSubmatch(router.Rule)
+		if len(hostMatches) > 1 && util.NormalizeHost(hostMatches[1]) == host {
 			matches = append(matches, matchedRouter{name: routerName, router: router})
 		}
 	}
@@ -1018,72 +1434,43 @@ func (cp *ConfigProxy) findMatchingRouter(routers map[string]interface{}, host s
 	if len(matches) == 0 {
 		return "", nil
 	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].name < matches[j].name })
 
-	// Prefer the main router (websecure) over redirect routers
+	// Prefer the main routers over redirect routers
 	// Main routers don't have the "-redirect" suffix
+	var mainRouters, secureRouters []matchedRouter
 	for _, m := range matches {
-		if !strings.HasSuffix(m.name, "-redirect") {
-			// Also verify it has websecure entrypoint for extra safety
-			if eps := cp.getRouterEntryPoints(m.router); len(eps) > 0 {
-				for _, ep := range eps {
-					if ep == "websecure" {
-						return m.name, m.router
-					}
-				}
-			}
-			// Even without websecure check, prefer non-redirect routers
-			return m.name, m.router
+		if strings.HasSuffix(m.name, "-redirect") {
+			continue
 		}
-	}
-
-	// Fallback to first match if no non-redirect router found
-	return matches[0].name, matches[0].router
-}
-
-// getRouterEntryPoints extracts the entryPoints list from a router config
-func (cp *ConfigProxy) getRouterEntryPoints(router map[string]interface{}) []string {
-	entryPoints, ok := router["entryPoints"]
-	if !ok {
-		return nil
-	}
-
-	switch v := entryPoints.(type) {
-	case []interface{}:
-		result := make([]string, 0, len(v))
-		for _, ep := range v {
-			if s, ok := ep.(string); ok {
-				result = append(result, s)
+		mainRouters = append(mainRouters, m)
+		for _, ep := range m.router.EntryPoints {
+			if ep == "websecure" {
+				secureRouters = append(secureRouters, m)
+				break
 			}
 		}
-		return result
-	case []string:
-		return v
-	default:
-		return nil
 	}
-}
 
-// getRouterMiddlewares extracts the middleware list from a router config
-func (cp *ConfigProxy) getRouterMiddlewares(router map[string]interface{}) []string {
-	middlewares, ok := router["middlewares"]
-	if !ok {
-		return nil
+	switch {
+	case len(mainRouters) == 0:
+		// Fallback to first match if no non-redirect router found
+		return matches[0].name, matches[0].router
+	case len(mainRouters) == 1:
+		return mainRouters[0].name, mainRouters[0].router
+	case len(secureRouters) == 1:
+		return secureRouters[0].name, secureRouters[0].router
 	}
 
-	switch v := middlewares.(type) {
-	case []interface{}:
-		result := make([]string, 0, len(v))
-		for _, m := range v {
-			if s, ok := m.(string); ok {
-				result = append(result, s)
-			}
-		}
-		return result
-	case []string:
-		return v
-	default:
-		return nil
+	// Several routers serve the host, e.g. split by path. Picking one would
+	// apply the overrides to an arbitrary router, so match none of them.
+	names := make([]string, len(mainRouters))
+	for i, m := range mainRouters {
+		names[i] = m.name
 	}
+	log.Printf("Warning: host %s matches %d routers (%s); not applying overrides by host, assign them to each router's resource",
+		host, len(names), strings.Join(names, ", "))
+	return "", nil
 }
 
 // determineServiceProtocol determines which protocol section a service belongs to
@@ -1158,6 +1545,11 @@ func (cp *ConfigProxy) SetPangolinURL(url string) {
 	cp.InvalidateCache()
 }
 
+// SetForwardAuthURL sets the base URL Traefik uses to reach the built-in forwardAuth endpoint
+func (cp *ConfigProxy) SetForwardAuthURL(url string) {
+	cp.forwardAuthURL = strings.TrimSuffix(url, "/")
+}
+
 // SetCacheDuration updates the cache duration
 func (cp *ConfigProxy) SetCacheDuration(duration time.Duration) {
 	cp.cacheMutex.Lock()
@@ -1165,120 +1557,6 @@ func (cp *ConfigProxy) SetCacheDuration(duration time.Duration) {
 	cp.cacheDuration = duration
 }
 
-// normalizeRouterOrder converts all HTTP routers to OrderedRouter structs
-// to ensure consistent JSON field ordering matching Pangolin's output.
-func (cp *ConfigProxy) normalizeRouterOrder(config *ProxiedTraefikConfig) {
-	if config == nil || config.HTTP == nil || config.HTTP.Routers == nil {
-		return
-	}
-
-	for routerKey, routerVal := range config.HTTP.Routers {
-		router, ok := routerVal.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		ordered := cp.mapToOrderedRouter(router)
-		config.HTTP.Routers[routerKey] = ordered
-	}
-}
-
-// mapToOrderedRouter converts a map[string]interface{} router to OrderedRouter
-func (cp *ConfigProxy) mapToOrderedRouter(router map[string]interface{}) *OrderedRouter {
-	ordered := &OrderedRouter{}
-
-	// EntryPoints
-	if eps, ok := router["entryPoints"]; ok {
-		switch v := eps.(type) {
-		case []interface{}:
-			for _, ep := range v {
-				if s, ok := ep.(string); ok {
-					ordered.EntryPoints = append(ordered.EntryPoints, s)
-				}
-			}
-		case []string:
-			ordered.EntryPoints = v
-		}
-	}
-
-	// Middlewares
-	if mws, ok := router["middlewares"]; ok {
-		switch v := mws.(type) {
-		case []interface{}:
-			for _, mw := range v {
-				if s, ok := mw.(string); ok {
-					ordered.Middlewares = append(ordered.Middlewares, s)
-				}
-			}
-		case []string:
-			ordered.Middlewares = v
-		}
-	}
-
-	// Service
-	if svc, ok := router["service"].(string); ok {
-		ordered.Service = svc
-	}
-
-	// Rule
-	if rule, ok := router["rule"].(string); ok {
-		ordered.Rule = rule
-	}
-
-	// Priority
-	if priority, ok := router["priority"]; ok {
-		switch v := priority.(type) {
-		case int:
-			ordered.Priority = v
-		case float64:
-			ordered.Priority = int(v)
-		case int64:
-			ordered.Priority = int(v)
-		}
-	}
-
-	// TLS
-	if tlsVal, ok := router["tls"]; ok {
-		switch tls := tlsVal.(type) {
-		case map[string]interface{}:
-			ordered.TLS = cp.mapToOrderedTLS(tls)
-		}
-	}
-
-	return ordered
-}
-
-// mapToOrderedTLS converts a map[string]interface{} TLS config to OrderedTLSConfig
-func (cp *ConfigProxy) mapToOrderedTLS(tls map[string]interface{}) *OrderedTLSConfig {
-	ordered := &OrderedTLSConfig{}
-
-	// CertResolver
-	if cr, ok := tls["certResolver"].(string); ok {
-		ordered.CertResolver = cr
-	}
-
-	// Domains
-	if domains, ok := tls["domains"]; ok {
-		switch v := domains.(type) {
-		case []interface{}:
-			for _, d := range v {
-				if s, ok := d.(string); ok {
-					ordered.Domains = append(ordered.Domains, s)
-				}
-			}
-		case []string:
-			ordered.Domains = v
-		}
-	}
-
-	// Options
-	if opts, ok := tls["options"].(string); ok {
-		ordered.Options = opts
-	}
-
-	return ordered
-}
-
 // normalizeMiddlewareOrder converts HTTP middlewares to OrderedMiddleware structs
 // to ensure consistent JSON field ordering matching Pangolin's output.
 // Only converts middlewares with known field structures (redirectScheme, plugin, headers).
@@ -1345,21 +1623,27 @@ func (cp *ConfigProxy) mapToOrderedMiddleware(mw map[string]interface{}) *Ordere
 // loadSecurityConfig loads global security configuration from the database
 func (cp *ConfigProxy) loadSecurityConfig() (*securityConfigData, error) {
 	var tlsHardeningEnabled, secureHeadersEnabled int
+	var tlsHardeningMode string
 	var xContentTypeOptions, xFrameOptions, xXSSProtection, hsts, referrerPolicy, csp, permissionsPolicy string
+	var directivesJSON, coop, coep, corp string
 
 	err := cp.db.QueryRow(`
-		SELECT tls_hardening_enabled, secure_headers_enabled,
+		SELECT tls_hardening_enabled, COALESCE(tls_hardening_mode, 'per_resource'), secure_headers_enabled,
 		       secure_headers_x_content_type_options, secure_headers_x_frame_options,
 		       secure_headers_x_xss_protection, secure_headers_hsts,
 		       secure_headers_referrer_policy, secure_headers_csp,
-		       secure_headers_permissions_policy
+		       secure_headers_permissions_policy,
+		       COALESCE(secure_headers_permissions_policy_directives, '[]'),
+		       COALESCE(secure_headers_coop, ''), COALESCE(secure_headers_coep, ''),
+		       COALESCE(secure_headers_corp, '')
 		FROM security_config WHERE id = 1
 	`).Scan(
-		&tlsHardeningEnabled, &secureHeadersEnabled,
+		&tlsHardeningEnabled, &tlsHardeningMode, &secureHeadersEnabled,
 		&xContentTypeOptions, &xFrameOptions,
 		&xXSSProtection, &hsts,
 		&referrerPolicy, &csp,
-		&permissionsPolicy,
+		&permissionsPolicy, &directivesJSON,
+		&coop, &coep, &corp,
 	)
 
 	if err != nil {
@@ -1367,6 +1651,7 @@ func (cp *ConfigProxy) loadSecurityConfig() (*securityConfigData, error) {
 			// Return defaults
 			return &securityConfigData{
 				TLSHardeningEnabled:  false,
+				TLSHardeningMode:     models.TLSHardeningPerResource,
 				SecureHeadersEnabled: false,
 				SecureHeaders:        models.DefaultSecureHeaders(),
 			}, nil
@@ -1374,8 +1659,19 @@ func (cp *ConfigProxy) loadSecurityConfig() (*securityConfigData, error) {
 		return nil, fmt.Errorf("failed to load security config: %w", err)
 	}
 
+	presets, err := cp.loadSecureHeadersPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	var directives []models.PermissionsPolicyDirective
+	if err := json.Unmarshal([]byte(directivesJSON), &directives); err != nil {
+		log.Printf("Warning: ignoring invalid Permissions-Policy directives: %v", err)
+	}
+
 	return &securityConfigData{
 		TLSHardeningEnabled:  tlsHardeningEnabled == 1,
+		TLSHardeningMode:     tlsHardeningMode,
 		SecureHeadersEnabled: secureHeadersEnabled == 1,
 		SecureHeaders: models.SecureHeadersConfig{
 			XContentTypeOptions: xContentTypeOptions,
@@ -1385,57 +1681,304 @@ func (cp *ConfigProxy) loadSecurityConfig() (*securityConfigData, error) {
 			ReferrerPolicy:      referrerPolicy,
 			CSP:                 csp,
 			PermissionsPolicy:   permissionsPolicy,
+
+			PermissionsPolicyDirectives: directives,
+			CrossOriginOpenerPolicy:     coop,
+			CrossOriginEmbedderPolicy:   coep,
+			CrossOriginResourcePolicy:   corp,
 		},
+		SecureHeadersPresets: presets,
 	}, nil
 }
 
-// applyTLSHardeningOptions adds TLS options for hardened security (without client auth)
-func (cp *ConfigProxy) applyTLSHardeningOptions(config *ProxiedTraefikConfig) {
-	config.TLS.Options["tls-hardened"] = models.TLSHardeningOptions()
+// tlsHardeningActive resolves a resource's TLS hardening under the global
+// inheritance mode. mTLS resources are skipped because mtls-verify already
+// includes the hardened options. Without a security config only the
+// resource's own flag counts.
+func tlsHardeningActive(resource *resourceData, securityCfg *securityConfigData) bool {
+	if resource.MTLSEnabled {
+		return false
+	}
+	mode := models.TLSHardeningPerResource
+	if securityCfg != nil {
+		mode = securityCfg.TLSHardeningMode
+	}
+	return models.EffectiveTLSHardening(mode, resource.TLSHardeningEnabled, resource.TLSHardeningOptOut)
 }
 
-// ensureSecureHeadersMiddleware creates and registers a secure headers middleware for a resource
-func (cp *ConfigProxy) ensureSecureHeadersMiddleware(config *ProxiedTraefikConfig, resource *resourceData, securityCfg *securityConfigData) string {
-	if securityCfg == nil {
-		return ""
+// applyTLSHardeningOptions adds TLS options for each hardening profile in use (without client auth)
+func (cp *ConfigProxy) applyTLSHardeningOptions(config *ProxiedTraefikConfig, profiles map[string]struct{}) {
+	for profile := range profiles {
+		config.TLS.Options[models.TLSProfileOptionsName(profile)] = TLSProfileOptions(profile)
+	}
+}
+
+// loadSecureHeadersPresets loads the custom secure headers presets
+func (cp *ConfigProxy) loadSecureHeadersPresets() (map[string]models.SecureHeadersConfig, error) {
+	rows, err := cp.db.Query("SELECT id, headers FROM secure_header_presets")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secure headers presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := make(map[string]models.SecureHeadersConfig)
+	for rows.Next() {
+		var id, headersJSON string
+		if err := rows.Scan(&id, &headersJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan secure headers preset: %w", err)
+		}
+		var headers models.SecureHeadersConfig
+		if err := json.Unmarshal([]byte(headersJSON), &headers); err != nil {
+			log.Printf("Warning: skipping secure headers preset %s: %v", id, err)
+			continue
+		}
+		presets[id] = headers
 	}
+	return presets, rows.Err()
+}
 
-	customResponseHeaders := make(map[string]string)
+// resourceSecureHeaders returns the secure header values for a resource: its
+// selected preset, or the global values when it has none. A preset that no
+// longer exists falls back to the global values.
+func resourceSecureHeaders(resource *resourceData, securityCfg *securityConfigData) models.SecureHeadersConfig {
+	if resource.SecureHeadersPreset != "" {
+		if preset, ok := models.BuiltInSecureHeadersPreset(resource.SecureHeadersPreset); ok {
+			return preset.Headers
+		}
+		if headers, ok := securityCfg.SecureHeadersPresets[resource.SecureHeadersPreset]; ok {
+			return headers
+		}
+		log.Printf("Warning: resource %s uses unknown secure headers preset %s; using global values",
+			resource.ID, resource.SecureHeadersPreset)
+	}
+	return securityCfg.SecureHeaders
+}
+
+// secureHeadersLayer converts secure headers settings into a headers middleware config.
+// In report-only mode CSP, COOP and COEP use their -Report-Only headers so
+// browsers only report violations, and HSTS is softened with models.SoftHSTS.
+func secureHeadersLayer(headers models.SecureHeadersConfig, reportOnly bool) map[string]interface{} {
+	customResponseHeaders := make(map[string]interface{})
 
 	// Only add headers that have values configured
-	if securityCfg.SecureHeaders.XContentTypeOptions != "" {
-		customResponseHeaders["X-Content-Type-Options"] = securityCfg.SecureHeaders.XContentTypeOptions
+	if headers.XContentTypeOptions != "" {
+		customResponseHeaders["X-Content-Type-Options"] = headers.XContentTypeOptions
+	}
+	if headers.XFrameOptions != "" {
+		customResponseHeaders["X-Frame-Options"] = headers.XFrameOptions
+	}
+	if headers.XXSSProtection != "" {
+		customResponseHeaders["X-XSS-Protection"] = headers.XXSSProtection
 	}
-	if securityCfg.SecureHeaders.XFrameOptions != "" {
-		customResponseHeaders["X-Frame-Options"] = securityCfg.SecureHeaders.XFrameOptions
+	if headers.HSTS != "" {
+		hsts := headers.HSTS
+		if reportOnly {
+			hsts = models.SoftHSTS(hsts)
+		}
+		customResponseHeaders["Strict-Transport-Security"] = hsts
+	}
+	if headers.ReferrerPolicy != "" {
+		customResponseHeaders["Referrer-Policy"] = headers.ReferrerPolicy
 	}
-	if securityCfg.SecureHeaders.XXSSProtection != "" {
-		customResponseHeaders["X-XSS-Protection"] = securityCfg.SecureHeaders.XXSSProtection
+	if headers.CSP != "" {
+		customResponseHeaders[reportOnlyHeader("Content-Security-Policy", reportOnly)] = headers.CSP
 	}
-	if securityCfg.SecureHeaders.HSTS != "" {
-		customResponseHeaders["Strict-Transport-Security"] = securityCfg.SecureHeaders.HSTS
+	if permissionsPolicy := headers.PermissionsPolicyValue(); permissionsPolicy != "" {
+		customResponseHeaders["Permissions-Policy"] = permissionsPolicy
 	}
-	if securityCfg.SecureHeaders.ReferrerPolicy != "" {
-		customResponseHeaders["Referrer-Policy"] = securityCfg.SecureHeaders.ReferrerPolicy
+	// COOP and COEP have report-only variants; CORP does not
+	if headers.CrossOriginOpenerPolicy != "" {
+		customResponseHeaders[reportOnlyHeader("Cross-Origin-Opener-Policy", reportOnly)] = headers.CrossOriginOpenerPolicy
 	}
-	if securityCfg.SecureHeaders.CSP != "" {
-		customResponseHeaders["Content-Security-Policy"] = securityCfg.SecureHeaders.CSP
+	if headers.CrossOriginEmbedderPolicy != "" {
+		customResponseHeaders[reportOnlyHeader("Cross-Origin-Embedder-Policy", reportOnly)] = headers.CrossOriginEmbedderPolicy
 	}
-	if securityCfg.SecureHeaders.PermissionsPolicy != "" {
-		customResponseHeaders["Permissions-Policy"] = securityCfg.SecureHeaders.PermissionsPolicy
+	if headers.CrossOriginResourcePolicy != "" {
+		customResponseHeaders["Cross-Origin-Resource-Policy"] = headers.CrossOriginResourcePolicy
 	}
 
-	// Skip if no headers configured
 	if len(customResponseHeaders) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"customResponseHeaders": customResponseHeaders,
+	}
+}
+
+// reportOnlyHeader returns the -Report-Only variant of name in report-only mode
+func reportOnlyHeader(name string, reportOnly bool) string {
+	if reportOnly {
+		return name + "-Report-Only"
+	}
+	return name
+}
+
+// ensureHeadersMiddleware renders a single headers middleware for a resource.
+// Precedence, lowest to highest: global secure headers, group header policies,
+// resource header policies, the resource's CORS policy, then its own custom headers.
+// Resources whose merged headers are identical share one middleware.
+func (cp *ConfigProxy) ensureHeadersMiddleware(config *ProxiedTraefikConfig, resource *resourceData, securityCfg *securityConfigData) string {
+	var layers []map[string]interface{}
+
+	if resource.SecureHeadersEnabled && securityCfg != nil && securityCfg.SecureHeadersEnabled {
+		if layer := secureHeadersLayer(resourceSecureHeaders(resource, securityCfg), resource.SecureHeadersReportOnly); layer != nil {
+			layers = append(layers, layer)
+		}
+	}
+
+	layers = append(layers, resource.GroupHeaderPolicies...)
+	layers = append(layers, resource.ResourceHeaderPolicies...)
+	if resource.CORSHeaders != nil {
+		layers = append(layers, resource.CORSHeaders)
+	}
+
+	if resource.CustomHeaders != "" && resource.CustomHeaders != "{}" && resource.CustomHeaders != "null" {
+		var headersMap map[string]string
+		if err := json.Unmarshal([]byte(resource.CustomHeaders), &headersMap); err == nil && len(headersMap) > 0 {
+			layers = append(layers, map[string]interface{}{"customRequestHeaders": headersMap})
+		}
+	}
+
+	merged := models.MergeHeaderConfigs(layers...)
+	if len(merged) == 0 {
 		return ""
 	}
 
-	middlewareName := fmt.Sprintf("%s-secureheaders", resource.ID)
-	config.HTTP.Middlewares[middlewareName] = map[string]interface{}{
-		"headers": map[string]interface{}{
-			"customResponseHeaders": customResponseHeaders,
+	headers := map[string]interface{}{"headers": merged}
+	middlewareName, err := contentHashedName(headersMiddlewarePrefix, headers)
+	if err != nil {
+		log.Printf("Failed to render headers middleware for resource %s: %v", resource.ID, err)
+		return ""
+	}
+	config.HTTP.Middlewares[middlewareName] = headers
+
+	return middlewareName
+}
+
+// loadHeaderPolicies attaches group and resource header policies to the loaded resources
+func (cp *ConfigProxy) loadHeaderPolicies(resourceMap map[string]*resourceData) error {
+	queries := []struct {
+		query   string
+		isGroup bool
+	}{
+		{
+			query: `
+				SELECT gm.resource_id, p.name, p.config
+				FROM header_policy_assignments a
+				JOIN header_policies p ON p.id = a.policy_id
+				JOIN resource_group_members gm ON gm.group_id = a.target_id
+				WHERE a.target_type = 'group'
+				ORDER BY gm.resource_id, a.priority ASC, p.name
+			`,
+			isGroup: true,
+		},
+		{
+			query: `
+				SELECT a.target_id, p.name, p.config
+				FROM header_policy_assignments a
+				JOIN header_policies p ON p.id = a.policy_id
+				WHERE a.target_type = 'resource'
+				ORDER BY a.target_id, a.priority ASC, p.name
+			`,
 		},
 	}
 
+	for _, q := range queries {
+		rows, err := cp.db.Query(q.query)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var resID, name, configStr string
+			if err := rows.Scan(&resID, &name, &configStr); err != nil {
+				log.Printf("Failed to scan header policy: %v", err)
+				continue
+			}
+			data, ok := resourceMap[resID]
+			if !ok {
+				continue
+			}
+			var policyConfig map[string]interface{}
+			if err := json.Unmarshal([]byte(configStr), &policyConfig); err != nil {
+				log.Printf("Failed to parse header policy %s: %v", name, err)
+				continue
+			}
+			if q.isGroup {
+				data.GroupHeaderPolicies = append(data.GroupHeaderPolicies, policyConfig)
+			} else {
+				data.ResourceHeaderPolicies = append(data.ResourceHeaderPolicies, policyConfig)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadCORSPolicies attaches the CORS policy referenced by each resource
+func (cp *ConfigProxy) loadCORSPolicies(resourceMap map[string]*resourceData) error {
+	rows, err := cp.db.Query(`
+		SELECT r.id, c.name, c.allow_origins, c.allow_methods, c.allow_headers, c.expose_headers,
+		       c.allow_credentials, c.max_age
+		FROM resources r
+		JOIN cors_policies c ON c.id = r.cors_policy_id
+		WHERE r.status = 'active'
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var resID, origins, methods, headers, expose string
+		var credentials int
+		policy := models.CORSPolicy{}
+		if err := rows.Scan(&resID, &policy.Name, &origins, &methods, &headers, &expose, &credentials, &policy.MaxAge); err != nil {
+			log.Printf("Failed to scan CORS policy: %v", err)
+			continue
+		}
+		data, ok := resourceMap[resID]
+		if !ok {
+			continue
+		}
+		_ = json.Unmarshal([]byte(origins), &policy.AllowOrigins)
+		_ = json.Unmarshal([]byte(methods), &policy.AllowMethods)
+		_ = json.Unmarshal([]byte(headers), &policy.AllowHeaders)
+		_ = json.Unmarshal([]byte(expose), &policy.ExposeHeaders)
+		policy.AllowCredentials = credentials == 1
+
+		// Policies are validated on save; skip anything that slipped through rather than emit unsafe headers
+		if err := policy.Validate(); err != nil {
+			log.Printf("Skipping invalid CORS policy %s for resource %s: %v", policy.Name, resID, err)
+			continue
+		}
+		data.CORSHeaders = policy.HeadersConfig()
+	}
+
+	return rows.Err()
+}
+
+// ensureForwardAuthMiddleware registers a forwardAuth middleware pointing at the built-in verify endpoint
+func (cp *ConfigProxy) ensureForwardAuthMiddleware(config *ProxiedTraefikConfig, resource *resourceData) string {
+	if cp.forwardAuthURL == "" {
+		log.Printf("Forward auth enabled for resource %s but FORWARD_AUTH_URL is not set; skipping", resource.ID)
+		return ""
+	}
+
+	middlewareName := fmt.Sprintf("%s-forwardauth", resource.ID)
+	config.HTTP.Middlewares[middlewareName] = map[string]interface{}{
+		"forwardAuth": map[string]interface{}{
+			"address":            fmt.Sprintf("%s/api/forward-auth/verify?resource=%s", cp.forwardAuthURL, url.QueryEscape(resource.ID)),
+			"trustForwardHeader": false,
+			// Passes the session cookie issued after a TOTP code to the client
+			"addAuthCookiesToResponse": []string{ForwardAuthSessionCookie},
+		},
+	}
 	return middlewareName
 }
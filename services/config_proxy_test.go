@@ -6,8 +6,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
 )
 
 func TestConfigProxyCachesAndInvalidates(t *testing.T) {
@@ -55,6 +60,49 @@ func TestConfigProxyCachesAndInvalidates(t *testing.T) {
 	}
 }
 
+func TestConfigProxyCoalescesConcurrentRefreshes(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"http":{"middlewares":{},"routers":{},"services":{}}}`))
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), server.URL)
+	cp.httpClient = server.Client()
+
+	const pollers = 20
+	var wg sync.WaitGroup
+	configs := make([]*ProxiedTraefikConfig, pollers)
+	for i := range pollers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config, err := cp.GetMergedConfig()
+			if err != nil {
+				t.Errorf("poller %d: %v", i, err)
+			}
+			configs[i] = config
+		}()
+	}
+	// Give every poller time to miss the cache before Pangolin answers
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if hits.Load() != 1 {
+		t.Fatalf("expected concurrent polls to share one Pangolin fetch, got %d", hits.Load())
+	}
+	for i, config := range configs {
+		if config != configs[0] {
+			t.Fatalf("poller %d got a different config", i)
+		}
+	}
+}
+
 func TestConfigProxyPreservesServersTransports(t *testing.T) {
 	db := newTestDB(t)
 	cm := newTestConfigManager(t)
@@ -158,3 +206,407 @@ func TestConfigGeneratorWritesConfigFile(t *testing.T) {
 		t.Fatalf("second generateConfig failed: %v", err)
 	}
 }
+
+func TestConfigProxyMergesHeaderPoliciesWithPrecedence(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	mustExec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("exec failed: %v", err)
+		}
+	}
+
+	mustExec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, custom_headers)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'app-service', 'org', 'site', 'active', '{"X-Request":"resource"}')`)
+	mustExec(`UPDATE resources SET secure_headers_enabled = 1 WHERE id = 'res-1'`)
+	mustExec(`UPDATE security_config SET secure_headers_enabled = 1, secure_headers_x_frame_options = 'SAMEORIGIN' WHERE id = 1`)
+
+	mustExec(`INSERT INTO header_policies (id, name, type, config) VALUES
+		('p-group', 'group-frame', 'security', '{"customResponseHeaders":{"X-Frame-Options":"DENY","Cache-Control":"no-cache"}}'),
+		('p-low', 'resource-low', 'caching', '{"customResponseHeaders":{"Cache-Control":"private"}}'),
+		('p-high', 'resource-high', 'caching', '{"customResponseHeaders":{"Cache-Control":"no-store"}}')`)
+	mustExec(`INSERT INTO resource_groups (id, name) VALUES ('g-1', 'apps')`)
+	mustExec(`INSERT INTO resource_group_members (group_id, resource_id) VALUES ('g-1', 'res-1')`)
+	mustExec(`INSERT INTO header_policy_assignments (policy_id, target_type, target_id, priority) VALUES
+		('p-group', 'group', 'g-1', 500),
+		('p-low', 'resource', 'res-1', 10),
+		('p-high', 'resource', 'res-1', 200)`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"app-router": map[string]interface{}{
+						"rule":        "Host(`app.example.com`)",
+						"service":     "app-service",
+						"entryPoints": []string{"websecure"},
+					},
+				},
+				"services": map[string]interface{}{},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+
+	for _, legacy := range []string{"res-1-secureheaders", "res-1-customheaders"} {
+		if _, exists := config.HTTP.Middlewares[legacy]; exists {
+			t.Errorf("unexpected legacy middleware %q", legacy)
+		}
+	}
+
+	routerMiddlewares := config.HTTP.Routers["app-router"].Middlewares
+	if len(routerMiddlewares) != 1 || !strings.HasPrefix(routerMiddlewares[0], headersMiddlewarePrefix) {
+		t.Fatalf("router middlewares = %v, want a single merged headers middleware", routerMiddlewares)
+	}
+	raw, exists := config.HTTP.Middlewares[routerMiddlewares[0]]
+	if !exists {
+		t.Fatalf("merged headers middleware not found; middlewares = %v", config.HTTP.Middlewares)
+	}
+	encoded, _ := json.Marshal(raw)
+	var mw struct {
+		Headers struct {
+			CustomRequestHeaders  map[string]string `json:"customRequestHeaders"`
+			CustomResponseHeaders map[string]string `json:"customResponseHeaders"`
+		} `json:"headers"`
+	}
+	if err := json.Unmarshal(encoded, &mw); err != nil {
+		t.Fatalf("failed to decode merged middleware: %v", err)
+	}
+
+	if got := mw.Headers.CustomResponseHeaders["X-Frame-Options"]; got != "DENY" {
+		t.Errorf("X-Frame-Options = %q, want group policy to override global secure headers", got)
+	}
+	if got := mw.Headers.CustomResponseHeaders["Cache-Control"]; got != "no-store" {
+		t.Errorf("Cache-Control = %q, want highest-priority resource policy", got)
+	}
+	if got := mw.Headers.CustomResponseHeaders["X-Content-Type-Options"]; got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want inherited global value", got)
+	}
+	if got := mw.Headers.CustomRequestHeaders["X-Request"]; got != "resource" {
+		t.Errorf("X-Request = %q, want resource custom header", got)
+	}
+}
+
+func TestConfigProxyRendersCORSPolicy(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	if _, err := db.Exec(`INSERT INTO cors_policies (id, name, allow_origins, allow_methods, allow_credentials, max_age)
+		VALUES ('cors-1', 'spa', '["https://spa.example.com"]', '["GET","POST"]', 1, 600)`); err != nil {
+		t.Fatalf("insert cors policy: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, cors_policy_id)
+		VALUES ('res-1', 'api-router', 'api.example.com', 'api-service', 'org', 'site', 'active', 'cors-1')`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"api-router": map[string]interface{}{
+						"rule":    "Host(`api.example.com`)",
+						"service": "api-service",
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+
+	routerMiddlewares := config.HTTP.Routers["api-router"].Middlewares
+	if len(routerMiddlewares) != 1 {
+		t.Fatalf("router middlewares = %v, want the headers middleware", routerMiddlewares)
+	}
+	encoded, _ := json.Marshal(config.HTTP.Middlewares[routerMiddlewares[0]])
+	var mw struct {
+		Headers struct {
+			AllowOriginList  []string `json:"accessControlAllowOriginList"`
+			AllowCredentials bool     `json:"accessControlAllowCredentials"`
+			MaxAge           int      `json:"accessControlMaxAge"`
+			AddVaryHeader    bool     `json:"addVaryHeader"`
+		} `json:"headers"`
+	}
+	if err := json.Unmarshal(encoded, &mw); err != nil {
+		t.Fatalf("failed to decode middleware: %v", err)
+	}
+	if len(mw.Headers.AllowOriginList) != 1 || mw.Headers.AllowOriginList[0] != "https://spa.example.com" {
+		t.Errorf("accessControlAllowOriginList = %v", mw.Headers.AllowOriginList)
+	}
+	if !mw.Headers.AllowCredentials || mw.Headers.MaxAge != 600 || !mw.Headers.AddVaryHeader {
+		t.Errorf("unexpected CORS headers: %+v", mw.Headers)
+	}
+}
+
+func TestConfigProxyAddsForwardAuthMiddleware(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-1', 'tool-router', 'tool.example.com', 'tool-service', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"tool-router": map[string]interface{}{
+						"rule":    "Host(`tool.example.com`)",
+						"service": "tool-service",
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	// Without a forward auth URL the middleware is skipped
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if _, exists := config.HTTP.Middlewares["res-1-forwardauth"]; exists {
+		t.Fatal("forwardauth middleware should not be generated without FORWARD_AUTH_URL")
+	}
+
+	cp.SetForwardAuthURL("http://middleware-manager:3456/")
+	cp.InvalidateCache()
+	config, err = cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+
+	mw, ok := config.HTTP.Middlewares["res-1-forwardauth"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("forwardauth middleware missing: %v", config.HTTP.Middlewares)
+	}
+	fa := mw["forwardAuth"].(map[string]interface{})
+	if want := "http://middleware-manager:3456/api/forward-auth/verify?resource=res-1"; fa["address"] != want {
+		t.Errorf("address = %v, want %s", fa["address"], want)
+	}
+	if cookies, _ := fa["addAuthCookiesToResponse"].([]string); len(cookies) != 1 || cookies[0] != ForwardAuthSessionCookie {
+		t.Errorf("addAuthCookiesToResponse = %v", fa["addAuthCookiesToResponse"])
+	}
+}
+
+func TestConfigProxyRespectsManagementScope(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-in', 'in-router', 'app.example.com', 'svc', 'org', 'site', 'active', 1),
+		       ('res-out', 'out-router', 'legacy.example.com', 'svc', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resources: %v", err)
+	}
+	scope := &models.ManagementScope{Include: []string{"*.example.com"}, Exclude: []string{"legacy.*"}}
+	if err := SaveManagementScope(db.DB, scope); err != nil {
+		t.Fatalf("SaveManagementScope() error = %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"in-router":  map[string]interface{}{"rule": "Host(`app.example.com`)", "service": "svc"},
+					"out-router": map[string]interface{}{"rule": "Host(`legacy.example.com`)", "service": "svc", "middlewares": []string{"hand-made"}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+	cp.SetForwardAuthURL("http://mm:3456")
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if _, ok := config.HTTP.Middlewares["res-in-forwardauth"]; !ok {
+		t.Error("in-scope resource should get its forwardauth middleware")
+	}
+	if _, ok := config.HTTP.Middlewares["res-out-forwardauth"]; ok {
+		t.Error("out-of-scope resource must not get generated middlewares")
+	}
+
+	outJSON, _ := json.Marshal(config.HTTP.Routers["out-router"])
+	var out map[string]interface{}
+	_ = json.Unmarshal(outJSON, &out)
+	mws, _ := out["middlewares"].([]interface{})
+	if len(mws) != 1 || mws[0] != "hand-made" {
+		t.Errorf("out-of-scope router was modified: %s", outJSON)
+	}
+}
+
+func TestConfigProxyKeepsProtectedUpstreamDefinitions(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	stmts := []string{
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+			VALUES ('res-1', 'app-router', 'app.example.com', 'app-service', 'org', 'site', 'active')`,
+		`INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'shared-auth', 'headers', '{"customRequestHeaders":{"X-MM":"1"}}')`,
+		`INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES ('res-1', 'mw-1', 100)`,
+		`INSERT INTO resource_services (resource_id, service_id) VALUES ('res-1', 'mm-service')`,
+		`INSERT INTO protected_names (kind, name) VALUES ('middleware', 'shared-auth'), ('service', 'app-service')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"app-router": map[string]interface{}{"rule": "Host(`app.example.com`)", "service": "app-service"},
+				},
+				"middlewares": map[string]interface{}{
+					"shared-auth": map[string]interface{}{"basicAuth": map[string]interface{}{"users": []string{"u:hash"}}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+
+	mwJSON, _ := json.Marshal(config.HTTP.Middlewares["shared-auth"])
+	if !strings.Contains(string(mwJSON), "basicAuth") || strings.Contains(string(mwJSON), "X-MM") {
+		t.Errorf("protected middleware was overridden: %s", mwJSON)
+	}
+
+	routerJSON, _ := json.Marshal(config.HTTP.Routers["app-router"])
+	var router map[string]interface{}
+	_ = json.Unmarshal(routerJSON, &router)
+	if router["service"] != "app-service" {
+		t.Errorf("router service = %v; protected service must not be overridden", router["service"])
+	}
+}
+
+func TestConfigProxyFallsBackToLastKnownGood(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	broken := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router := map[string]interface{}{
+			"rule":    "Host(`app.example.com`)",
+			"service": "app-service",
+		}
+		if broken {
+			router["service"] = "missing-service"
+			router["middlewares"] = []string{"missing-mw"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{"app-router": router},
+				"services": map[string]interface{}{
+					"app-service": map[string]interface{}{
+						"loadBalancer": map[string]interface{}{
+							"servers": []map[string]interface{}{{"url": "http://10.0.0.1:80"}},
+						},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	good, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if status := cp.ValidationStatus(); len(status.Errors) != 0 || status.ServingFallback {
+		t.Fatalf("expected clean validation, got %+v", status)
+	}
+
+	broken = true
+	cp.InvalidateCache()
+	served, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if served != good {
+		t.Fatal("expected last known-good config to be served")
+	}
+	status := cp.ValidationStatus()
+	if !status.ServingFallback || len(status.Errors) != 2 {
+		t.Fatalf("expected fallback with 2 errors, got %+v", status)
+	}
+
+	// Within budget the fresh config is served despite its errors
+	cp.SetErrorBudget(2)
+	cp.InvalidateCache()
+	served, err = cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if served == good {
+		t.Fatal("expected fresh config within error budget")
+	}
+	if status := cp.ValidationStatus(); status.ServingFallback {
+		t.Fatalf("expected fallback to clear, got %+v", status)
+	}
+}
+
+func TestFindMatchingRouterNormalizesHosts(t *testing.T) {
+	cp := &ConfigProxy{}
+	routers := map[string]*OrderedRouter{
+		"cafe-router-redirect": {Rule: "Host(`Café.Example.com`)", EntryPoints: []string{"web"}},
+		"cafe-router":          {Rule: "Host(`CAFÉ.example.com.`)", EntryPoints: []string{"websecure"}},
+		"other-router":         {Rule: "Host(`other.example.com`)"},
+	}
+
+	name, router := cp.findMatchingRouter(routers, "xn--caf-dma.example.com")
+	if router == nil || name != "cafe-router" {
+		t.Errorf("findMatchingRouter() = %q, want cafe-router", name)
+	}
+	if name, _ := cp.findMatchingRouter(routers, "missing.example.com"); name != "" {
+		t.Errorf("findMatchingRouter() = %q for an unknown host", name)
+	}
+	// Routers splitting a host by path are ambiguous
+	routers["cafe-api-router"] = &OrderedRouter{Rule: "Host(`café.example.com`) && PathPrefix(`/api`)", EntryPoints: []string{"websecure"}}
+	if name, _ := cp.findMatchingRouter(routers, "xn--caf-dma.example.com"); name != "" {
+		t.Errorf("findMatchingRouter() = %q for a host served by two routers, want none", name)
+	}
+}
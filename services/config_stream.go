@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// streamWriterPool reuses the buffered writers used to stream configs to Traefik
+var streamWriterPool = sync.Pool{
+	New: func() interface{} {
+		return bufio.NewWriterSize(nil, 32*1024)
+	},
+}
+
+// WriteJSON streams the config as JSON, encoding one router, service or
+// middleware at a time instead of marshalling the whole document in memory.
+// The output is identical to json.Marshal.
+func (config *ProxiedTraefikConfig) WriteJSON(w io.Writer) error {
+	bw := streamWriterPool.Get().(*bufio.Writer)
+	bw.Reset(w)
+	defer func() {
+		bw.Reset(nil)
+		streamWriterPool.Put(bw)
+	}()
+
+	s := &jsonStream{w: bw}
+	s.openObject()
+	if config.HTTP != nil {
+		s.key("http")
+		s.openObject()
+		writeMapField(s, "middlewares", config.HTTP.Middlewares)
+		writeMapField(s, "routers", config.HTTP.Routers)
+		writeMapField(s, "services", config.HTTP.Services)
+		writeMapField(s, "serversTransports", config.HTTP.ServersTransports)
+		writeUnknownFields(s, config.HTTP.Extra)
+		s.closeObject()
+	}
+	if config.TCP != nil {
+		s.key("tcp")
+		s.openObject()
+		writeMapField(s, "routers", config.TCP.Routers)
+		writeMapField(s, "services", config.TCP.Services)
+		writeUnknownFields(s, config.TCP.Extra)
+		s.closeObject()
+	}
+	if config.UDP != nil {
+		s.key("udp")
+		s.openObject()
+		writeMapField(s, "routers", config.UDP.Routers)
+		writeMapField(s, "services", config.UDP.Services)
+		writeUnknownFields(s, config.UDP.Extra)
+		s.closeObject()
+	}
+	if config.TLS != nil {
+		s.key("tls")
+		s.openObject()
+		writeMapField(s, "options", config.TLS.Options)
+		writeUnknownFields(s, config.TLS.Extra)
+		s.closeObject()
+	}
+	writeUnknownFields(s, config.Extra)
+	s.closeObject()
+
+	if s.err != nil {
+		return s.err
+	}
+	return bw.Flush()
+}
+
+// jsonStream writes JSON objects incrementally, remembering the first error
+type jsonStream struct {
+	w *bufio.Writer
+	// needComma tracks, per open object, whether a member has been written
+	needComma []bool
+	err       error
+}
+
+func (s *jsonStream) write(data []byte) {
+	if s.err == nil {
+		_, s.err = s.w.Write(data)
+	}
+}
+
+func (s *jsonStream) openObject() {
+	s.write([]byte{'{'})
+	s.needComma = append(s.needComma, false)
+}
+
+func (s *jsonStream) closeObject() {
+	s.write([]byte{'}'})
+	s.needComma = s.needComma[:len(s.needComma)-1]
+}
+
+// key writes a member name (with a leading comma when needed) and the colon
+func (s *jsonStream) key(name string) {
+	top := len(s.needComma) - 1
+	if s.needComma[top] {
+		s.write([]byte{','})
+	}
+	s.needComma[top] = true
+
+	encoded, err := json.Marshal(name)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	s.write(encoded)
+	s.write([]byte{':'})
+}
+
+func (s *jsonStream) value(v interface{}) {
+	if s.err != nil {
+		return
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.write(encoded)
+}
+
+// writeMapField writes a map member with sorted keys, omitting it when empty to
+// match the omitempty tags on the config structs
+func writeMapField[V any](s *jsonStream, name string, m map[string]V) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s.key(name)
+	s.openObject()
+	for _, k := range keys {
+		s.key(k)
+		s.value(m[k])
+	}
+	s.closeObject()
+}
+
+// writeUnknownFields writes a section's preserved members sorted by key, the
+// same way appendUnknownFields does for json.Marshal
+func writeUnknownFields(s *jsonStream, extra map[string]json.RawMessage) {
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.key(k)
+		s.value(extra[k])
+	}
+}
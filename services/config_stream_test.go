@@ -0,0 +1,50 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestProxiedTraefikConfigWriteJSONMatchesMarshal(t *testing.T) {
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{
+			Middlewares: map[string]interface{}{
+				"b-headers": &OrderedMiddleware{Headers: map[string]interface{}{"customRequestHeaders": map[string]interface{}{"X-A": "<&>"}}},
+				"a-limit":   map[string]interface{}{"rateLimit": map[string]interface{}{"average": 10}},
+			},
+			Routers: map[string]*OrderedRouter{
+				"r2": &OrderedRouter{EntryPoints: []string{"websecure"}, Service: "svc", Rule: "Host(`b.example.com`)"},
+				"r1": &OrderedRouter{Middlewares: []string{"a-limit"}, Service: "svc", Rule: "Host(`a.example.com`)", Priority: 100,
+					TLS: &OrderedTLSConfig{CertResolver: "letsencrypt", Extra: map[string]json.RawMessage{"passthrough": json.RawMessage(`false`)}}},
+			},
+			Services: map[string]interface{}{
+				"svc": map[string]interface{}{"loadBalancer": map[string]interface{}{"servers": []interface{}{map[string]interface{}{"url": "http://10.0.0.1"}}}},
+			},
+		},
+		TCP: &TCPConfig{
+			Routers: map[string]interface{}{"tcp-r": map[string]interface{}{"rule": "HostSNI(`*`)", "service": "tcp-svc"}},
+		},
+		UDP: &UDPConfig{},
+		TLS: &TLSConfig{Options: map[string]interface{}{"default": map[string]interface{}{"minVersion": "VersionTLS12"}}},
+	}
+
+	want, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := config.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("WriteJSON() output differs from json.Marshal\n got: %s\nwant: %s", buf.String(), want)
+	}
+
+	// An empty config is still a valid document
+	buf.Reset()
+	if err := (&ProxiedTraefikConfig{}).WriteJSON(&buf); err != nil || buf.String() != "{}" {
+		t.Fatalf("empty config = %q, %v", buf.String(), err)
+	}
+}
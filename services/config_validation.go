@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigValidationStatus describes the most recent validation of the merged config
+type ConfigValidationStatus struct {
+	CheckedAt       *time.Time `json:"checked_at,omitempty"`
+	Errors          []string   `json:"errors"`
+	ErrorBudget     int        `json:"error_budget"`
+	ServingFallback bool       `json:"serving_last_known_good"`
+	FallbackSince   *time.Time `json:"fallback_since,omitempty"`
+	LastKnownGoodAt *time.Time `json:"last_known_good_at,omitempty"`
+	// Size and complexity limits and the ones the latest config goes over
+	Limits        ConfigLimits         `json:"limits"`
+	LimitWarnings []ConfigLimitWarning `json:"limit_warnings"`
+}
+
+// SetErrorBudget sets how many validation errors a freshly merged config may
+// contain before the last known-good config is served instead
+func (cp *ConfigProxy) SetErrorBudget(budget int) {
+	if budget < 0 {
+		budget = 0
+	}
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.errorBudget = budget
+}
+
+// ValidationStatus returns a copy of the latest validation result
+func (cp *ConfigProxy) ValidationStatus() ConfigValidationStatus {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+
+	status := cp.validation
+	status.ErrorBudget = cp.errorBudget
+	status.Errors = append([]string{}, cp.validation.Errors...)
+	status.Limits = cp.limits
+	status.LimitWarnings = append([]ConfigLimitWarning{}, cp.validation.LimitWarnings...)
+	return status
+}
+
+// selectServedConfig records the validation result for a fresh config and
+// returns the config Traefik should get. Over budget it falls back to the last
+// known-good config, if there is one. Callers must hold cacheMutex.
+func (cp *ConfigProxy) selectServedConfig(config *ProxiedTraefikConfig, errs []string) *ProxiedTraefikConfig {
+	now := time.Now()
+	cp.validation.CheckedAt = &now
+	cp.validation.Errors = errs
+
+	if len(errs) <= cp.errorBudget {
+		if len(errs) > 0 {
+			log.Printf("Warning: merged Traefik config has %d validation error(s) within budget %d: %s",
+				len(errs), cp.errorBudget, strings.Join(errs, "; "))
+		}
+		if cp.validation.ServingFallback {
+			log.Printf("Merged Traefik config is valid again; no longer serving last known-good config")
+		}
+		cp.lastKnownGood = config
+		cp.validation.LastKnownGoodAt = &now
+		cp.validation.ServingFallback = false
+		cp.validation.FallbackSince = nil
+		return config
+	}
+
+	if cp.lastKnownGood == nil {
+		log.Printf("Warning: merged Traefik config has %d validation error(s) and no known-good config exists; serving it anyway: %s",
+			len(errs), strings.Join(errs, "; "))
+		return config
+	}
+
+	if !cp.validation.ServingFallback {
+		log.Printf("ALERT: merged Traefik config has %d validation error(s) (budget %d); serving last known-good config from %s: %s",
+			len(errs), cp.errorBudget, cp.validation.LastKnownGoodAt.Format(time.RFC3339), strings.Join(errs, "; "))
+		cp.validation.ServingFallback = true
+		cp.validation.FallbackSince = &now
+	}
+	return cp.lastKnownGood
+}
+
+// validateConfig reports problems Traefik would reject the provider payload for:
+// malformed middlewares or services and references that do not resolve.
+// References to other providers (name@provider) cannot be checked and are skipped.
+func (cp *ConfigProxy) validateConfig(config *ProxiedTraefikConfig) []string {
+	var errs []string
+	if config == nil {
+		return errs
+	}
+
+	if config.HTTP != nil {
+		for name, value := range config.HTTP.Middlewares {
+			if msg := checkSingleTypeObject(value); msg != "" {
+				errs = append(errs, fmt.Sprintf("http middleware %q is malformed: %s", name, msg))
+				continue
+			}
+			// Chains reference other middlewares
+			if mw, ok := value.(map[string]interface{}); ok {
+				if chain, ok := mw["chain"].(map[string]interface{}); ok {
+					for _, ref := range stringList(chain["middlewares"]) {
+						if !referenceResolves(config.HTTP.Middlewares, ref) {
+							errs = append(errs, fmt.Sprintf("http middleware %q chains unknown middleware %q", name, ref))
+						}
+					}
+				}
+			}
+		}
+
+		for name, value := range config.HTTP.Services {
+			if msg := checkSingleTypeObject(value); msg != "" {
+				errs = append(errs, fmt.Sprintf("http service %q is malformed: %s", name, msg))
+			}
+		}
+
+		for name, router := range config.HTTP.Routers {
+			if router == nil {
+				errs = append(errs, fmt.Sprintf("http router %q is empty", name))
+				continue
+			}
+			for _, field := range router.InvalidFields() {
+				errs = append(errs, fmt.Sprintf("http router %q has an invalid %s", name, field))
+			}
+			if strings.TrimSpace(router.Rule) == "" {
+				errs = append(errs, fmt.Sprintf("http router %q has no rule", name))
+			}
+			if router.Service == "" {
+				errs = append(errs, fmt.Sprintf("http router %q has no service", name))
+			} else if !referenceResolves(config.HTTP.Services, router.Service) {
+				errs = append(errs, fmt.Sprintf("http router %q references unknown service %q", name, router.Service))
+			}
+			for _, ref := range router.Middlewares {
+				if !referenceResolves(config.HTTP.Middlewares, ref) {
+					errs = append(errs, fmt.Sprintf("http router %q references unknown middleware %q", name, ref))
+				}
+			}
+		}
+	}
+
+	if config.TCP != nil {
+		errs = append(errs, validateRouterServices("tcp", config.TCP.Routers, config.TCP.Services)...)
+	}
+	if config.UDP != nil {
+		errs = append(errs, validateRouterServices("udp", config.UDP.Routers, config.UDP.Services)...)
+	}
+
+	sort.Strings(errs)
+	return errs
+}
+
+// validateRouterServices checks that TCP/UDP routers point at a defined service
+func validateRouterServices(protocol string, routers, services map[string]interface{}) []string {
+	var errs []string
+	for name, value := range routers {
+		router, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		service, _ := router["service"].(string)
+		if service == "" {
+			errs = append(errs, fmt.Sprintf("%s router %q has no service", protocol, name))
+		} else if !referenceResolves(services, service) {
+			errs = append(errs, fmt.Sprintf("%s router %q references unknown service %q", protocol, name, service))
+		}
+	}
+	return errs
+}
+
+// checkSingleTypeObject verifies a middleware or service definition has exactly
+// one type key whose value is an object; it returns a description of the problem
+func checkSingleTypeObject(value interface{}) string {
+	def, ok := value.(map[string]interface{})
+	if !ok {
+		if _, ordered := value.(*OrderedMiddleware); ordered {
+			return ""
+		}
+		return fmt.Sprintf("expected an object, got %T", value)
+	}
+	if len(def) != 1 {
+		return fmt.Sprintf("expected exactly one type, got %d", len(def))
+	}
+	for typ, body := range def {
+		if _, ok := body.(map[string]interface{}); !ok {
+			return fmt.Sprintf("%s config must be an object", typ)
+		}
+	}
+	return ""
+}
+
+// referenceResolves reports whether a reference names a definition in this payload.
+// The http provider's own suffix is stripped; other providers are assumed to resolve.
+func referenceResolves(defs map[string]interface{}, ref string) bool {
+	if idx := strings.LastIndex(ref, "@"); idx >= 0 {
+		if ref[idx+1:] != "http" {
+			return true
+		}
+		ref = ref[:idx]
+	}
+	_, ok := defs[ref]
+	return ok
+}
+
+// stringList converts a decoded JSON array (or []string) to a string slice
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config webhook modes
+const (
+	ConfigWebhookModeFull  = "full"
+	ConfigWebhookModeDelta = "delta"
+)
+
+// ConfigWebhookEvent is the event name of config webhook deliveries
+const ConfigWebhookEvent = "config.changed"
+
+// Headers of config webhook deliveries. The signature is the hex HMAC-SHA256
+// of "<timestamp>.<body>" with the webhook secret, prefixed with "sha256=".
+const (
+	configWebhookEventHeader     = "X-MM-Event"
+	configWebhookRevisionHeader  = "X-MM-Revision"
+	configWebhookTimestampHeader = "X-MM-Timestamp"
+	configWebhookSignatureHeader = "X-MM-Signature"
+)
+
+// ConfigWebhookSettings configures posting the served config to an external
+// URL whenever its routers or middlewares change. An empty URL disables it.
+type ConfigWebhookSettings struct {
+	URL string
+	// Secret signs deliveries; empty sends them unsigned
+	Secret string
+	// Mode is "full" for the whole config or "delta" for the routers and
+	// middlewares changed since the last successful delivery
+	Mode string
+	// MaxAttempts bounds the deliveries of one change, retried with backoff
+	MaxAttempts int
+}
+
+// DefaultConfigWebhookMaxAttempts is used when MaxAttempts is not set
+const DefaultConfigWebhookMaxAttempts = 5
+
+// ConfigWebhookStatus reports the deliveries of the config webhook
+type ConfigWebhookStatus struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	// DeliveredRevision is the last config revision the consumer accepted
+	DeliveredRevision uint64     `json:"delivered_revision"`
+	Deliveries        int        `json:"deliveries"`
+	Failures          int        `json:"failures"`
+	LastAttemptAt     *time.Time `json:"last_attempt_at,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+}
+
+// configWebhookPayload is the body of a delivery; Config is set in full mode
+// and Changes in delta mode
+type configWebhookPayload struct {
+	Event     string                `json:"event"`
+	Revision  uint64                `json:"revision"`
+	Timestamp time.Time             `json:"timestamp"`
+	Config    *ProxiedTraefikConfig `json:"config,omitempty"`
+	Changes   *ConfigChanges        `json:"changes,omitempty"`
+}
+
+// configWebhook delivers served config changes in the background. Changes
+// that arrive while a delivery is retried are coalesced into the next one.
+type configWebhook struct {
+	settings ConfigWebhookSettings
+	client   *http.Client
+	changes  func(since uint64) *ConfigChanges
+	// backoff returns the wait before retry attempt n (1-based)
+	backoff func(attempt int) time.Duration
+
+	mu       sync.Mutex
+	latest   *ProxiedTraefikConfig
+	revision uint64
+	status   ConfigWebhookStatus
+
+	pending  chan struct{}
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// newConfigWebhook creates a webhook; changes returns the delta since a
+// revision of the served config
+func newConfigWebhook(settings ConfigWebhookSettings, changes func(since uint64) *ConfigChanges) *configWebhook {
+	if settings.Mode != ConfigWebhookModeDelta {
+		settings.Mode = ConfigWebhookModeFull
+	}
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = DefaultConfigWebhookMaxAttempts
+	}
+	return &configWebhook{
+		settings: settings,
+		client:   HTTPClientWithTimeout(10 * time.Second),
+		changes:  changes,
+		backoff: func(attempt int) time.Duration {
+			return min(time.Second<<(attempt-1), 30*time.Second)
+		},
+		status: ConfigWebhookStatus{
+			Enabled: true,
+			URL:     settings.URL,
+			Mode:    settings.Mode,
+		},
+		pending:  make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// SetConfigWebhook posts the served config to settings.URL whenever its
+// routers or middlewares change. Call StartConfigWebhook to run deliveries.
+func (cp *ConfigProxy) SetConfigWebhook(settings ConfigWebhookSettings) {
+	if settings.URL == "" {
+		cp.webhook = nil
+		return
+	}
+	cp.webhook = newConfigWebhook(settings, cp.ConfigChanges)
+}
+
+// StartConfigWebhook delivers config changes until StopConfigWebhook is
+// called; it returns at once when no webhook is configured
+func (cp *ConfigProxy) StartConfigWebhook() {
+	if cp.webhook != nil {
+		cp.webhook.run()
+	}
+}
+
+// StopConfigWebhook stops delivering config changes
+func (cp *ConfigProxy) StopConfigWebhook() {
+	if cp.webhook != nil {
+		cp.webhook.stop()
+	}
+}
+
+// ConfigWebhookStatus returns the state of the config webhook deliveries
+func (cp *ConfigProxy) ConfigWebhookStatus() ConfigWebhookStatus {
+	if cp.webhook == nil {
+		return ConfigWebhookStatus{}
+	}
+	cp.webhook.mu.Lock()
+	defer cp.webhook.mu.Unlock()
+	return cp.webhook.status
+}
+
+// notify queues a delivery of a new served config revision without blocking
+func (w *configWebhook) notify(config *ProxiedTraefikConfig, revision uint64) {
+	w.mu.Lock()
+	w.latest = config
+	w.revision = revision
+	w.mu.Unlock()
+
+	select {
+	case w.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (w *configWebhook) run() {
+	for {
+		select {
+		case <-w.pending:
+			w.deliver()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+func (w *configWebhook) stop() {
+	w.stopOnce.Do(func() { close(w.stopChan) })
+}
+
+// deliver posts the latest revision, retrying with exponential backoff. Each
+// attempt sends the latest revision, so changes made meanwhile are included.
+func (w *configWebhook) deliver() {
+	for attempt := 1; attempt <= w.settings.MaxAttempts; attempt++ {
+		revision, retry, err := w.attempt()
+		now := time.Now()
+
+		w.mu.Lock()
+		w.status.LastAttemptAt = &now
+		if err == nil {
+			w.status.Deliveries++
+			w.status.DeliveredRevision = revision
+			w.status.LastError = ""
+		} else {
+			w.status.Failures++
+			w.status.LastError = err.Error()
+		}
+		w.mu.Unlock()
+
+		if err == nil {
+			return
+		}
+		if !retry || attempt == w.settings.MaxAttempts {
+			log.Printf("Config webhook delivery of revision %d failed after %d attempt(s): %v", revision, attempt, err)
+			return
+		}
+		select {
+		case <-time.After(w.backoff(attempt)):
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// attempt posts the latest revision once and reports whether a failure is
+// worth retrying
+func (w *configWebhook) attempt() (uint64, bool, error) {
+	w.mu.Lock()
+	payload := configWebhookPayload{
+		Event:     ConfigWebhookEvent,
+		Revision:  w.revision,
+		Timestamp: time.Now().UTC(),
+	}
+	if w.settings.Mode == ConfigWebhookModeDelta {
+		payload.Changes = w.changes(w.status.DeliveredRevision)
+		payload.Revision = payload.Changes.Revision
+	} else {
+		payload.Config = w.latest
+	}
+	w.mu.Unlock()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return payload.Revision, false, fmt.Errorf("encoding payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return payload.Revision, false, err
+	}
+	timestamp := strconv.FormatInt(payload.Timestamp.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(configWebhookEventHeader, ConfigWebhookEvent)
+	req.Header.Set(configWebhookRevisionHeader, strconv.FormatUint(payload.Revision, 10))
+	req.Header.Set(configWebhookTimestampHeader, timestamp)
+	if w.settings.Secret != "" {
+		req.Header.Set(configWebhookSignatureHeader, signConfigWebhook(w.settings.Secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return payload.Revision, true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return payload.Revision, false, nil
+	}
+	// Other client errors will not change on retry
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return payload.Revision, retry, fmt.Errorf("consumer returned %s", resp.Status)
+}
+
+// signConfigWebhook returns the signature header value of a delivery
+func signConfigWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
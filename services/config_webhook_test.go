@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookConsumer records deliveries and answers with the queued statuses,
+// then 200
+type webhookConsumer struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (c *webhookConsumer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, r)
+	c.bodies = append(c.bodies, body)
+	if len(c.statuses) > 0 {
+		w.WriteHeader(c.statuses[0])
+		c.statuses = c.statuses[1:]
+	}
+}
+
+func newTestConfigWebhook(t *testing.T, consumer *webhookConsumer, settings ConfigWebhookSettings, log *configChangeLog) *configWebhook {
+	t.Helper()
+	server := httptest.NewServer(consumer)
+	t.Cleanup(server.Close)
+	settings.URL = server.URL
+	w := newConfigWebhook(settings, log.changes)
+	w.backoff = func(int) time.Duration { return 0 }
+	return w
+}
+
+func TestConfigWebhook_FullSigned(t *testing.T) {
+	var changeLog configChangeLog
+	consumer := &webhookConsumer{}
+	w := newTestConfigWebhook(t, consumer, ConfigWebhookSettings{Secret: "s3cret"}, &changeLog)
+
+	config := changesTestConfig(map[string]string{"app": "Host(`app.example.com`)"}, nil)
+	changeLog.record(config)
+	w.notify(config, 1)
+	w.deliver()
+
+	if len(consumer.requests) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(consumer.requests))
+	}
+	req, body := consumer.requests[0], consumer.bodies[0]
+	if req.Header.Get(configWebhookEventHeader) != ConfigWebhookEvent || req.Header.Get(configWebhookRevisionHeader) != "1" {
+		t.Errorf("unexpected headers %v", req.Header)
+	}
+	want := signConfigWebhook("s3cret", req.Header.Get(configWebhookTimestampHeader), body)
+	if req.Header.Get(configWebhookSignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get(configWebhookSignatureHeader), want)
+	}
+
+	var payload configWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("parse payload: %v", err)
+	}
+	if payload.Config == nil || payload.Config.HTTP.Routers["app"] == nil || payload.Changes != nil {
+		t.Errorf("expected the full config, got %s", body)
+	}
+	if status := w.status; status.Deliveries != 1 || status.DeliveredRevision != 1 || status.LastError != "" {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestConfigWebhook_DeltaSinceLastDelivery(t *testing.T) {
+	var changeLog configChangeLog
+	consumer := &webhookConsumer{}
+	w := newTestConfigWebhook(t, consumer, ConfigWebhookSettings{Mode: ConfigWebhookModeDelta}, &changeLog)
+
+	first := changesTestConfig(map[string]string{"a": "Host(`a.example.com`)"}, nil)
+	changeLog.record(first)
+	w.notify(first, 1)
+	w.deliver()
+
+	second := changesTestConfig(map[string]string{"a": "Host(`a.example.com`)", "b": "Host(`b.example.com`)"}, nil)
+	changeLog.record(second)
+	w.notify(second, 2)
+	w.deliver()
+
+	if len(consumer.bodies) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(consumer.bodies))
+	}
+	if consumer.requests[0].Header.Get(configWebhookSignatureHeader) != "" {
+		t.Error("deliveries without a secret should be unsigned")
+	}
+	var payload configWebhookPayload
+	if err := json.Unmarshal(consumer.bodies[1], &payload); err != nil {
+		t.Fatalf("parse payload: %v", err)
+	}
+	if payload.Config != nil || payload.Changes == nil || payload.Changes.Reset || payload.Changes.Since != 1 {
+		t.Fatalf("expected a delta since revision 1, got %s", consumer.bodies[1])
+	}
+	if _, added := payload.Changes.Routers.Added["b"]; !added || len(payload.Changes.Routers.Added) != 1 {
+		t.Errorf("expected only router b added, got %+v", payload.Changes.Routers)
+	}
+}
+
+func TestConfigWebhook_Retries(t *testing.T) {
+	var changeLog configChangeLog
+	config := changesTestConfig(map[string]string{"a": "Host(`a.example.com`)"}, nil)
+	changeLog.record(config)
+
+	consumer := &webhookConsumer{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	w := newTestConfigWebhook(t, consumer, ConfigWebhookSettings{}, &changeLog)
+	w.notify(config, 1)
+	w.deliver()
+	if len(consumer.requests) != 3 || w.status.Deliveries != 1 || w.status.Failures != 2 {
+		t.Errorf("retryable failures: %d requests, status %+v", len(consumer.requests), w.status)
+	}
+
+	consumer = &webhookConsumer{statuses: []int{http.StatusBadRequest}}
+	w = newTestConfigWebhook(t, consumer, ConfigWebhookSettings{}, &changeLog)
+	w.notify(config, 1)
+	w.deliver()
+	if len(consumer.requests) != 1 || w.status.Deliveries != 0 || w.status.LastError == "" {
+		t.Errorf("client error: %d requests, status %+v", len(consumer.requests), w.status)
+	}
+
+	consumer = &webhookConsumer{statuses: []int{500, 500, 500}}
+	w = newTestConfigWebhook(t, consumer, ConfigWebhookSettings{MaxAttempts: 2}, &changeLog)
+	w.notify(config, 1)
+	w.deliver()
+	if len(consumer.requests) != 2 {
+		t.Errorf("expected delivery to stop after 2 attempts, got %d", len(consumer.requests))
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// DefaultFailoverAfter is how long the active data source must fail before
+// resource discovery switches to the secondary
+const DefaultFailoverAfter = 5 * time.Minute
+
+// FailoverStatus is the failover config plus which source discovery is
+// currently reading from
+type FailoverStatus struct {
+	Config  models.DataSourceFailover `json:"config"`
+	Primary string                    `json:"primary"`
+	// Serving is the data source the resource watcher last used
+	Serving          string     `json:"serving"`
+	PrimaryDownSince *time.Time `json:"primary_down_since,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	SwitchedAt       *time.Time `json:"switched_at,omitempty"`
+}
+
+// failoverState tracks the active source's outage. It is runtime only: a
+// restart starts on the primary again.
+type failoverState struct {
+	mu             sync.Mutex
+	downSince      time.Time
+	lastError      string
+	usingSecondary bool
+	switchedAt     time.Time
+}
+
+// primaryFailed notes a failed fetch from the primary. use reports whether
+// the secondary should serve this round; switched is true on the round the
+// outage first exceeds after.
+func (s *failoverState) primaryFailed(err error, after time.Duration, now time.Time) (use, switched bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.downSince.IsZero() {
+		s.downSince = now
+	}
+	s.lastError = err.Error()
+	if !s.usingSecondary && now.Sub(s.downSince) >= after {
+		s.usingSecondary = true
+		s.switchedAt = now
+		switched = true
+	}
+	return s.usingSecondary, switched
+}
+
+// primaryRecovered clears the outage and reports whether discovery was on
+// the secondary until now
+func (s *failoverState) primaryRecovered() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wasSecondary := s.usingSecondary
+	s.downSince, s.lastError = time.Time{}, ""
+	s.usingSecondary, s.switchedAt = false, time.Time{}
+	return wasSecondary
+}
+
+func (s *failoverState) reset() {
+	s.primaryRecovered()
+}
+
+func failoverAfter(cfg models.DataSourceFailover) time.Duration {
+	if cfg.AfterMinutes <= 0 {
+		return DefaultFailoverAfter
+	}
+	return time.Duration(cfg.AfterMinutes) * time.Minute
+}
+
+// GetFailoverConfig returns the failover settings; the zero value when
+// failover was never configured
+func (cm *ConfigManager) GetFailoverConfig() models.DataSourceFailover {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.Failover == nil {
+		return models.DataSourceFailover{}
+	}
+	return *cm.config.Failover
+}
+
+// SetFailoverConfig validates and saves the failover settings. The
+// secondary must be a configured source other than the active one.
+func (cm *ConfigManager) SetFailoverConfig(cfg models.DataSourceFailover) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cfg.AfterMinutes < 0 {
+		return fmt.Errorf("after_minutes must not be negative")
+	}
+	if cfg.Enabled {
+		if cfg.Secondary == "" {
+			return fmt.Errorf("secondary data source is required when failover is enabled")
+		}
+		if _, ok := cm.config.DataSources[cfg.Secondary]; !ok {
+			return fmt.Errorf("data source not found: %s", cfg.Secondary)
+		}
+		if cfg.Secondary == cm.config.ActiveDataSource {
+			return fmt.Errorf("secondary data source must differ from the active one (%s)", cfg.Secondary)
+		}
+	}
+
+	cm.config.Failover = &cfg
+	cm.failover.reset()
+	return cm.saveConfig()
+}
+
+// failoverSource returns the secondary data source and how long the primary
+// must fail before using it; ok is false when failover is off or the
+// secondary has since become the active source
+func (cm *ConfigManager) failoverSource() (config models.DataSourceConfig, after time.Duration, ok bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	fo := cm.config.Failover
+	if fo == nil || !fo.Enabled || fo.Secondary == cm.config.ActiveDataSource {
+		return models.DataSourceConfig{}, 0, false
+	}
+	config, err := cm.dataSource(fo.Secondary)
+	if err != nil {
+		return models.DataSourceConfig{}, 0, false
+	}
+	return config, failoverAfter(*fo), true
+}
+
+// FailoverStatus reports the failover settings and whether resource
+// discovery is currently reading from the secondary
+func (cm *ConfigManager) FailoverStatus() FailoverStatus {
+	cfg := cm.GetFailoverConfig()
+	status := FailoverStatus{
+		Config:  cfg,
+		Primary: cm.GetActiveSourceName(),
+	}
+	status.Serving = status.Primary
+
+	cm.failover.mu.Lock()
+	defer cm.failover.mu.Unlock()
+	if !cm.failover.downSince.IsZero() {
+		downSince := cm.failover.downSince
+		status.PrimaryDownSince = &downSince
+		status.LastError = cm.failover.lastError
+	}
+	if cm.failover.usingSecondary {
+		status.Serving = cfg.Secondary
+		switchedAt := cm.failover.switchedAt
+		status.SwitchedAt = &switchedAt
+	}
+	return status
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestFailoverState(t *testing.T) {
+	var s failoverState
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	down := errors.New("connection refused")
+
+	if use, switched := s.primaryFailed(down, 5*time.Minute, start); use || switched {
+		t.Fatalf("first failure: use=%v switched=%v, want neither", use, switched)
+	}
+	if use, _ := s.primaryFailed(down, 5*time.Minute, start.Add(4*time.Minute)); use {
+		t.Fatal("failed over before the outage reached 5m")
+	}
+	if use, switched := s.primaryFailed(down, 5*time.Minute, start.Add(5*time.Minute)); !use || !switched {
+		t.Fatalf("at 5m: use=%v switched=%v, want both", use, switched)
+	}
+	if use, switched := s.primaryFailed(down, 5*time.Minute, start.Add(6*time.Minute)); !use || switched {
+		t.Fatalf("after switching: use=%v switched=%v, want use only", use, switched)
+	}
+
+	if !s.primaryRecovered() {
+		t.Error("primaryRecovered() = false, want true after failing over")
+	}
+	if s.primaryRecovered() {
+		t.Error("primaryRecovered() = true on a healthy primary")
+	}
+	if use, _ := s.primaryFailed(down, 5*time.Minute, start.Add(10*time.Minute)); use {
+		t.Error("a new outage failed over immediately; the timer should restart")
+	}
+}
+
+func TestResourceWatcher_FailoverAndBack(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+	if err := cm.SetFailoverConfig(models.DataSourceFailover{Enabled: true, Secondary: "traefik"}); err != nil {
+		t.Fatalf("SetFailoverConfig() error = %v", err)
+	}
+
+	primary := &mockResourceFetcher{err: errors.New("pangolin unreachable")}
+	secondary := &mockResourceFetcher{resources: &models.ResourceCollection{
+		Resources: []models.Resource{{ID: "new-app@file", Host: "new.example.com", ServiceID: "new-svc"}},
+	}}
+	watcher := &ResourceWatcher{
+		db:            db,
+		fetcher:       primary,
+		configManager: cm,
+		secondary:     secondary,
+		failoverAfter: 0,
+	}
+
+	resources, err := watcher.fetchResources(context.Background())
+	if err != nil {
+		t.Fatalf("fetchResources() during outage error = %v", err)
+	}
+	if len(resources.Resources) != 1 || resources.Resources[0].Host != "new.example.com" {
+		t.Errorf("fetchResources() = %+v, want the secondary's resources", resources)
+	}
+	if status := cm.FailoverStatus(); status.Serving != "traefik" || status.LastError == "" {
+		t.Errorf("FailoverStatus() = %+v, want serving traefik with the primary's error", status)
+	}
+
+	// The primary is retried every round and takes over once it answers
+	primary.err = nil
+	primary.resources = &models.ResourceCollection{}
+	resources, err = watcher.fetchResources(context.Background())
+	if err != nil {
+		t.Fatalf("fetchResources() after recovery error = %v", err)
+	}
+	if len(resources.Resources) != 0 {
+		t.Errorf("fetchResources() = %+v, want the primary's resources", resources)
+	}
+	if status := cm.FailoverStatus(); status.Serving != "pangolin" || status.PrimaryDownSince != nil {
+		t.Errorf("FailoverStatus() = %+v, want back on pangolin", status)
+	}
+}
+
+func TestResourceWatcher_FailoverBothDown(t *testing.T) {
+	cm := newTestConfigManager(t)
+	if err := cm.SetFailoverConfig(models.DataSourceFailover{Enabled: true, Secondary: "traefik"}); err != nil {
+		t.Fatalf("SetFailoverConfig() error = %v", err)
+	}
+	watcher := &ResourceWatcher{
+		fetcher:       &mockResourceFetcher{err: errors.New("pangolin unreachable")},
+		configManager: cm,
+		secondary:     &mockResourceFetcher{err: errors.New("traefik unreachable")},
+	}
+
+	_, err := watcher.fetchResources(context.Background())
+	if err == nil {
+		t.Fatal("fetchResources() error = nil, want both failures")
+	}
+	if got := err.Error(); got != "pangolin unreachable; failover source traefik also failed: traefik unreachable" {
+		t.Errorf("error = %q", got)
+	}
+}
+
+func TestConfigManager_FailoverSource(t *testing.T) {
+	cm := newTestConfigManager(t)
+	if _, _, ok := cm.failoverSource(); ok {
+		t.Fatal("failoverSource() ok with failover unset")
+	}
+
+	if err := cm.SetFailoverConfig(models.DataSourceFailover{Enabled: true, Secondary: "traefik", AfterMinutes: 2}); err != nil {
+		t.Fatalf("SetFailoverConfig() error = %v", err)
+	}
+	config, after, ok := cm.failoverSource()
+	if !ok || config.Type != models.TraefikAPI || after != 2*time.Minute {
+		t.Errorf("failoverSource() = %v, %v, %v; want traefik after 2m", config.Type, after, ok)
+	}
+
+	// Making the secondary the active source leaves nothing to fail over to
+	if err := cm.SetActiveDataSource("traefik"); err != nil {
+		t.Fatalf("SetActiveDataSource() error = %v", err)
+	}
+	if _, _, ok := cm.failoverSource(); ok {
+		t.Error("failoverSource() ok when the secondary is active")
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// dataSourceTLSConfig builds the TLS settings for a data source: optional
+// skip-verify, an extra CA and a client certificate
+func dataSourceTLSConfig(config models.DataSourceConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.SkipTLSVerify,
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(config.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no PEM certificates", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+			return nil, fmt.Errorf("client_cert_file and client_key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(filepath.Clean(config.ClientCertFile), filepath.Clean(config.ClientKeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// needsOwnTransport reports whether config changes TLS or proxy settings,
+// which the shared connection pool cannot carry
+func needsOwnTransport(config models.DataSourceConfig) bool {
+	return config.SkipTLSVerify || config.CAFile != "" || config.ClientCertFile != "" || config.ClientKeyFile != "" ||
+		config.ProxyURL != ""
+}
+
+// DataSourceHTTPClient returns base when the data source uses default TLS
+// and proxy settings, otherwise a client with the same timeout on a
+// transport that carries the data source's CA, client certificate and proxy
+func DataSourceHTTPClient(base *http.Client, config models.DataSourceConfig) (*http.Client, error) {
+	if !needsOwnTransport(config) {
+		return base, nil
+	}
+	tlsConfig, err := dataSourceTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := dataSourceProxy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport *http.Transport
+	if t, ok := baseTransport(base.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+
+	return &http.Client{
+		Transport: withUserAgent(transport),
+		Timeout:   base.Timeout,
+	}, nil
+}
+
+// AuthorizeDataSourceRequest adds the data source's basic auth and custom
+// headers to req. A Host header overrides the request host, since
+// net/http ignores it in the header map.
+func AuthorizeDataSourceRequest(req *http.Request, config models.DataSourceConfig) {
+	if config.BasicAuth.Username != "" {
+		req.SetBasicAuth(config.BasicAuth.Username, config.BasicAuth.Password)
+	}
+	for name, value := range config.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+}
+
+// preserveMaskedHeaders keeps the stored value of every header the client
+// sent back masked, so saving a config read from the API does not replace
+// secrets with the mask
+func preserveMaskedHeaders(updated, existing models.DataSourceConfig) map[string]string {
+	if len(updated.Headers) == 0 {
+		return updated.Headers
+	}
+	headers := make(map[string]string, len(updated.Headers))
+	for name, value := range updated.Headers {
+		if value == models.MaskedSecret {
+			value = existing.Headers[name]
+		}
+		headers[name] = value
+	}
+	return headers
+}
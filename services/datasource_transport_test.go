@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// testPKI is a CA with one server and one client certificate, written as
+// PEM files to a temp dir
+type testPKI struct {
+	pool                  *x509.CertPool
+	serverCert            tls.Certificate
+	caFile                string
+	clientCert, clientKey string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "test"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	pki := &testPKI{pool: x509.NewCertPool()}
+	pki.pool.AddCert(caCert)
+	serverPEM, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	if pki.serverCert, err = tls.X509KeyPair(serverPEM, serverKey); err != nil {
+		t.Fatal(err)
+	}
+	clientPEM, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pki.caFile = write("ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	pki.clientCert = write("client.pem", clientPEM)
+	pki.clientKey = write("client-key.pem", clientKey)
+	return pki
+}
+
+func TestDataSourceHTTPClient_ClientCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pki.serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	base := HTTPClientWithTimeout(5 * time.Second)
+	config := models.DataSourceConfig{Type: models.TraefikAPI, URL: server.URL, CAFile: pki.caFile}
+
+	// Trusting the CA is not enough: the server wants a client certificate
+	if _, err := probeDataSource(context.Background(), base, config); err == nil {
+		t.Fatal("probeDataSource() without client certificate succeeded, want handshake error")
+	}
+
+	config.ClientCertFile, config.ClientKeyFile = pki.clientCert, pki.clientKey
+	if _, err := probeDataSource(context.Background(), base, config); err != nil {
+		t.Fatalf("probeDataSource() with client certificate error = %v", err)
+	}
+
+	client, err := DataSourceHTTPClient(base, config)
+	if err != nil {
+		t.Fatalf("DataSourceHTTPClient() error = %v", err)
+	}
+	if client.Timeout != base.Timeout {
+		t.Errorf("Timeout = %v, want %v", client.Timeout, base.Timeout)
+	}
+	if shared, _ := baseTransport(base.Transport); shared.TLSClientConfig != nil && len(shared.TLSClientConfig.Certificates) > 0 {
+		t.Error("shared transport was modified")
+	}
+}
+
+func TestDataSourceHTTPClient_Errors(t *testing.T) {
+	pki := newTestPKI(t)
+	base := HTTPClientWithTimeout(time.Second)
+
+	if client, err := DataSourceHTTPClient(base, models.DataSourceConfig{}); err != nil || client != base {
+		t.Errorf("DataSourceHTTPClient() without TLS settings = %v, %v; want the base client", client, err)
+	}
+
+	tests := []struct {
+		name   string
+		config models.DataSourceConfig
+	}{
+		{"missing CA file", models.DataSourceConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"CA file without PEM", models.DataSourceConfig{CAFile: pki.clientKey}},
+		{"cert without key", models.DataSourceConfig{ClientCertFile: pki.clientCert}},
+		{"mismatched key", models.DataSourceConfig{ClientCertFile: pki.caFile, ClientKeyFile: pki.clientKey}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DataSourceHTTPClient(base, tt.config); err == nil {
+				t.Error("DataSourceHTTPClient() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestAuthorizeDataSourceRequest(t *testing.T) {
+	config := models.DataSourceConfig{
+		Headers: map[string]string{
+			"CF-Access-Client-Id":     "id.access",
+			"CF-Access-Client-Secret": "secret",
+			"Host":                    "traefik.internal",
+		},
+	}
+	config.BasicAuth.Username = "admin"
+	config.BasicAuth.Password = "pw"
+
+	req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:8080/api/version", nil)
+	AuthorizeDataSourceRequest(req, config)
+
+	if got := req.Header.Get("CF-Access-Client-Secret"); got != "secret" {
+		t.Errorf("CF-Access-Client-Secret = %q, want secret", got)
+	}
+	if req.Host != "traefik.internal" {
+		t.Errorf("Host = %q, want traefik.internal", req.Host)
+	}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "admin" || pass != "pw" {
+		t.Errorf("BasicAuth() = %q, %q, %v", user, pass, ok)
+	}
+}
+
+func TestPangolinFetcher_SendsCustomHeaders(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("CF-Access-Client-Secret")
+		w.Write([]byte(`{"http":{"routers":{}}}`))
+	}))
+	defer server.Close()
+
+	fetcher := NewPangolinFetcher(models.DataSourceConfig{
+		Type:    models.PangolinAPI,
+		URL:     server.URL,
+		Headers: map[string]string{"CF-Access-Client-Secret": "secret"},
+	})
+	if _, err := fetcher.fetchTraefikConfig(context.Background()); err != nil {
+		t.Fatalf("fetchTraefikConfig() error = %v", err)
+	}
+	if got != "secret" {
+		t.Errorf("CF-Access-Client-Secret = %q, want secret", got)
+	}
+}
+
+func TestConfigManager_UpdateDataSourceKeepsMaskedHeaders(t *testing.T) {
+	cm := newTestConfigManager(t)
+	config := models.DataSourceConfig{
+		Type:    models.TraefikAPI,
+		URL:     "http://127.0.0.1:1",
+		Headers: map[string]string{"CF-Access-Client-Secret": "secret"},
+	}
+	if err := cm.UpdateDataSource("traefik", config); err != nil {
+		t.Fatalf("UpdateDataSource() error = %v", err)
+	}
+
+	// Save what the API returned, plus a new header
+	masked := cm.GetDataSources()["traefik"]
+	masked.FormatBasicAuth()
+	masked.Headers["X-Env"] = "prod"
+	if err := cm.UpdateDataSource("traefik", masked); err != nil {
+		t.Fatalf("UpdateDataSource() error = %v", err)
+	}
+
+	headers := cm.GetDataSources()["traefik"].Headers
+	if headers["CF-Access-Client-Secret"] != "secret" || headers["X-Env"] != "prod" {
+		t.Errorf("Headers = %v, want the stored secret kept and X-Env added", headers)
+	}
+}
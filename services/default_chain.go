@@ -0,0 +1,79 @@
+package services
+
+import "log"
+
+// defaultChainMiddleware is the chain middleware holding the global default
+// middlewares; routers reference it instead of N identical copies
+const defaultChainMiddleware = "mm-default-chain"
+
+// loadDefaultChain returns the middlewares of the global default chain,
+// highest priority first
+func (cp *ConfigProxy) loadDefaultChain() ([]middlewareWithPriority, error) {
+	rows, err := cp.db.Query(`
+		SELECT m.id, m.name, dc.priority
+		FROM default_chain_middlewares dc
+		JOIN middlewares m ON m.id = dc.middleware_id
+		ORDER BY dc.priority DESC, m.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chain []middlewareWithPriority
+	for rows.Next() {
+		var mw middlewareWithPriority
+		if err := rows.Scan(&mw.ID, &mw.Name, &mw.Priority); err != nil {
+			return nil, err
+		}
+		chain = append(chain, mw)
+	}
+	return chain, rows.Err()
+}
+
+// applyDefaultChain renders the default chain once as a Traefik chain
+// middleware and puts it in front of the middlewares of every HTTP router,
+// except the routers of resources that opted out and routers outside the
+// management scope
+func (cp *ConfigProxy) applyDefaultChain(config *ProxiedTraefikConfig, resources []*resourceData, chain []middlewareWithPriority) {
+	if len(chain) == 0 || config.HTTP == nil {
+		return
+	}
+
+	names := make([]string, 0, len(chain))
+	for _, mw := range chain {
+		names = append(names, mw.Name)
+	}
+	config.HTTP.Middlewares[defaultChainMiddleware] = map[string]interface{}{
+		"chain": map[string]interface{}{"middlewares": names},
+	}
+
+	excluded := make(map[string]bool)
+	for _, resource := range resources {
+		if !resource.DefaultChainExcluded {
+			continue
+		}
+		routerKey, _ := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, _ = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+		if routerKey != "" {
+			excluded[routerKey] = true
+		}
+	}
+
+	scope := cp.routerScope()
+	for routerName, router := range config.HTTP.Routers {
+		if router == nil || excluded[routerName] || !routerInScope(scope, router) {
+			continue
+		}
+		if stringSliceContains(router.Middlewares, defaultChainMiddleware) {
+			continue
+		}
+		router.Middlewares = append([]string{defaultChainMiddleware}, router.Middlewares...)
+	}
+
+	if shouldLog() {
+		log.Printf("Applied default middleware chain (%d middlewares) with %d excluded routers", len(names), len(excluded))
+	}
+}
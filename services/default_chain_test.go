@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestConfigProxyAppliesDefaultChain(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES
+		('mw-headers', 'secure-headers', 'headers', '{"frameDeny": true}'),
+		('mw-gzip', 'gzip', 'compress', '{}')`); err != nil {
+		t.Fatalf("insert middlewares: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO default_chain_middlewares (middleware_id, priority) VALUES
+		('mw-gzip', 100),
+		('mw-headers', 300)`); err != nil {
+		t.Fatalf("insert default chain: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, default_chain_excluded) VALUES
+		('res-1', 'app-router', 'app.example.com', 'svc', 'org', 'site', 'active', 0),
+		('res-2', 'legacy-router', 'legacy.example.com', 'svc', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resources: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"app-router":    map[string]interface{}{"rule": "Host(`app.example.com`)", "service": "svc", "entryPoints": []string{"websecure"}},
+					"legacy-router": map[string]interface{}{"rule": "Host(`legacy.example.com`)", "service": "svc", "entryPoints": []string{"websecure"}},
+					"other-router":  map[string]interface{}{"rule": "Host(`other.example.com`)", "service": "svc", "entryPoints": []string{"web"}, "middlewares": []string{"upstream"}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+
+	chain, ok := config.HTTP.Middlewares[defaultChainMiddleware].(map[string]interface{})
+	if !ok {
+		t.Fatalf("default chain middleware was not rendered")
+	}
+	wantChain := map[string]interface{}{"middlewares": []string{"secure-headers", "gzip"}}
+	if !reflect.DeepEqual(chain["chain"], wantChain) {
+		t.Errorf("default chain = %v, want %v", chain["chain"], wantChain)
+	}
+	for _, name := range []string{"secure-headers", "gzip"} {
+		if _, ok := config.HTTP.Middlewares[name]; !ok {
+			t.Errorf("default chain middleware %s was not rendered", name)
+		}
+	}
+
+	tests := map[string][]string{
+		"app-router":    {defaultChainMiddleware},
+		"legacy-router": nil,
+		"other-router":  {defaultChainMiddleware, "upstream"},
+	}
+	for router, want := range tests {
+		if got := config.HTTP.Routers[router].Middlewares; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s middlewares = %v, want %v", router, got, want)
+		}
+	}
+}
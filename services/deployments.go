@@ -0,0 +1,357 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// ErrDeploymentStage is returned when a deployment is not in a stage that
+// allows the requested step
+var ErrDeploymentStage = errors.New("deployment is not in a stage that allows this step")
+
+// BlueGreenDeployer moves resources to new backends: it stages the green
+// service, switches the router to it and archives blue once the soak period
+// ends. Until then one rollback call restores blue.
+type BlueGreenDeployer struct {
+	db       *database.DB
+	now      func() time.Time
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBlueGreenDeployer creates a new blue/green deployer
+func NewBlueGreenDeployer(db *database.DB) *BlueGreenDeployer {
+	return &BlueGreenDeployer{
+		db:       db,
+		now:      time.Now,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start periodically completes deployments whose soak period ended until Stop is called
+func (d *BlueGreenDeployer) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := d.CompleteSoaked(); err != nil {
+				log.Printf("Error completing blue/green deployments: %v", err)
+			}
+		case <-d.stopChan:
+			return
+		}
+	}
+}
+
+// Stop stops the background soak loop
+func (d *BlueGreenDeployer) Stop() {
+	d.stopOnce.Do(func() { close(d.stopChan) })
+}
+
+const deploymentColumns = `resource_id, green_url, blue_url, mirror_percent, soak_period, stage,
+	switched_at, soak_until, completed_at, created_at, updated_at`
+
+// Get returns the deployment of a resource, or sql.ErrNoRows
+func (d *BlueGreenDeployer) Get(resourceID string) (*models.ResourceDeployment, error) {
+	return scanDeployment(d.db.QueryRow("SELECT "+deploymentColumns+" FROM resource_deployments WHERE resource_id = ?", resourceID))
+}
+
+// List returns every deployment, most recently updated first
+func (d *BlueGreenDeployer) List() ([]models.ResourceDeployment, error) {
+	rows, err := d.db.Query("SELECT " + deploymentColumns + " FROM resource_deployments ORDER BY updated_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deployments := []models.ResourceDeployment{}
+	for rows.Next() {
+		deployment, err := scanDeployment(rows)
+		if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, *deployment)
+	}
+	return deployments, rows.Err()
+}
+
+// Begin stages a new green backend for a resource. A completed deployment's
+// green becomes the new blue; a deployment still in progress must be
+// switched and soaked or rolled back first.
+func (d *BlueGreenDeployer) Begin(deployment models.ResourceDeployment) (*models.ResourceDeployment, error) {
+	now := d.now()
+	deployment.Stage = models.DeploymentStaged
+	deployment.BlueURL = ""
+	deployment.SwitchedAt, deployment.SoakUntil, deployment.CompletedAt = nil, nil, nil
+	deployment.CreatedAt, deployment.UpdatedAt = now, now
+
+	err := d.db.WithTransaction(func(tx *sql.Tx) error {
+		current, err := scanDeployment(tx.QueryRow(
+			"SELECT "+deploymentColumns+" FROM resource_deployments WHERE resource_id = ?", deployment.ResourceID))
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return err
+		case current.Stage != models.DeploymentCompleted:
+			return fmt.Errorf("%w: a deployment to %s is %s", ErrDeploymentStage, current.GreenURL, current.Stage)
+		default:
+			deployment.BlueURL = current.GreenURL
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO resource_deployments (resource_id, green_url, blue_url, mirror_percent, soak_period, stage,
+				switched_at, soak_until, completed_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, NULL, NULL, NULL, ?, ?)
+			ON CONFLICT(resource_id) DO UPDATE SET green_url = excluded.green_url, blue_url = excluded.blue_url,
+				mirror_percent = excluded.mirror_percent, soak_period = excluded.soak_period, stage = excluded.stage,
+				switched_at = NULL, soak_until = NULL, completed_at = NULL,
+				created_at = excluded.created_at, updated_at = excluded.updated_at
+		`, deployment.ResourceID, deployment.GreenURL, deployment.BlueURL, deployment.MirrorPercent,
+			deployment.SoakPeriod, deployment.Stage, deployment.CreatedAt, deployment.UpdatedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Staged blue/green deployment of resource %s to %s", deployment.ResourceID, deployment.GreenURL)
+	return &deployment, nil
+}
+
+// Switch points the resource's router at green and starts the soak period
+func (d *BlueGreenDeployer) Switch(resourceID string) (*models.ResourceDeployment, error) {
+	deployment, err := d.Get(resourceID)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Stage != models.DeploymentStaged {
+		return nil, fmt.Errorf("%w: deployment is %s", ErrDeploymentStage, deployment.Stage)
+	}
+	soak, err := time.ParseDuration(deployment.SoakPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid soak period %q: %w", deployment.SoakPeriod, err)
+	}
+
+	now := d.now()
+	soakUntil := now.Add(soak)
+	result, err := d.db.Exec(`
+		UPDATE resource_deployments SET stage = ?, switched_at = ?, soak_until = ?, updated_at = ?
+		WHERE resource_id = ? AND stage = ?
+	`, models.DeploymentSwitched, now, soakUntil, now, resourceID, models.DeploymentStaged)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("%w: deployment changed concurrently", ErrDeploymentStage)
+	}
+
+	deployment.Stage = models.DeploymentSwitched
+	deployment.SwitchedAt, deployment.SoakUntil = &now, &soakUntil
+	deployment.UpdatedAt = now
+	log.Printf("Switched resource %s to green %s; blue is kept until %s",
+		resourceID, deployment.GreenURL, soakUntil.Format(time.RFC3339))
+	return deployment, nil
+}
+
+// Rollback returns a staged or switched deployment's router to blue. Blue is
+// the upstream service again, or the previous deployment's backend, which is
+// restored as a completed deployment.
+func (d *BlueGreenDeployer) Rollback(resourceID string) error {
+	deployment, err := d.Get(resourceID)
+	if err != nil {
+		return err
+	}
+	if deployment.Stage == models.DeploymentCompleted {
+		return fmt.Errorf("%w: the soak period ended and blue was archived", ErrDeploymentStage)
+	}
+
+	var result sql.Result
+	if deployment.BlueURL == "" {
+		result, err = d.db.Exec("DELETE FROM resource_deployments WHERE resource_id = ? AND stage = ?",
+			resourceID, deployment.Stage)
+	} else {
+		now := d.now()
+		result, err = d.db.Exec(`
+			UPDATE resource_deployments SET green_url = blue_url, blue_url = '', mirror_percent = 0, stage = ?,
+				switched_at = NULL, soak_until = NULL, completed_at = ?, updated_at = ?
+			WHERE resource_id = ? AND stage = ?
+		`, models.DeploymentCompleted, now, now, resourceID, deployment.Stage)
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: deployment changed concurrently", ErrDeploymentStage)
+	}
+
+	log.Printf("Rolled back blue/green deployment of resource %s from %s", resourceID, deployment.GreenURL)
+	return nil
+}
+
+// Remove deletes a resource's deployment in any stage; its router uses the
+// upstream service again
+func (d *BlueGreenDeployer) Remove(resourceID string) error {
+	result, err := d.db.Exec("DELETE FROM resource_deployments WHERE resource_id = ?", resourceID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	log.Printf("Removed blue/green deployment of resource %s", resourceID)
+	return nil
+}
+
+// CompleteSoaked archives blue for every switched deployment whose soak
+// period ended and returns the affected resource IDs
+func (d *BlueGreenDeployer) CompleteSoaked() ([]string, error) {
+	now := d.now()
+	rows, err := d.db.Query(
+		"SELECT resource_id FROM resource_deployments WHERE stage = ? AND soak_until <= ?",
+		models.DeploymentSwitched, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query soaked deployments: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var completed []string
+	for _, id := range ids {
+		// Re-check the stage so a rollback meanwhile wins
+		result, err := d.db.Exec(`
+			UPDATE resource_deployments SET stage = ?, completed_at = ?, updated_at = ?
+			WHERE resource_id = ? AND stage = ? AND soak_until <= ?
+		`, models.DeploymentCompleted, now, now, id, models.DeploymentSwitched, now)
+		if err != nil {
+			return completed, fmt.Errorf("failed to complete deployment of resource %s: %w", id, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			log.Printf("Soak period of resource %s ended; archived blue", id)
+			completed = append(completed, id)
+		}
+	}
+	return completed, nil
+}
+
+func scanDeployment(row interface{ Scan(...interface{}) error }) (*models.ResourceDeployment, error) {
+	var deployment models.ResourceDeployment
+	var switchedAt, soakUntil, completedAt sql.NullTime
+	if err := row.Scan(&deployment.ResourceID, &deployment.GreenURL, &deployment.BlueURL, &deployment.MirrorPercent,
+		&deployment.SoakPeriod, &deployment.Stage, &switchedAt, &soakUntil, &completedAt,
+		&deployment.CreatedAt, &deployment.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if switchedAt.Valid {
+		deployment.SwitchedAt = &switchedAt.Time
+	}
+	if soakUntil.Valid {
+		deployment.SoakUntil = &soakUntil.Time
+	}
+	if completedAt.Valid {
+		deployment.CompletedAt = &completedAt.Time
+	}
+	return &deployment, nil
+}
+
+// loadDeployments attaches the blue/green deployment of each resource
+func (cp *ConfigProxy) loadDeployments(resourceMap map[string]*resourceData) error {
+	rows, err := cp.db.Query("SELECT " + deploymentColumns + " FROM resource_deployments")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		deployment, err := scanDeployment(rows)
+		if err != nil {
+			log.Printf("Failed to scan blue/green deployment: %v", err)
+			continue
+		}
+		if data, ok := resourceMap[deployment.ResourceID]; ok {
+			data.Deployment = deployment
+		}
+	}
+
+	return rows.Err()
+}
+
+// applyDeployments points the router of each resource with a blue/green
+// deployment at the active color. The green service copies blue's
+// loadBalancer settings when blue is a loadBalancer of this provider. While
+// staged, a share of the requests can be mirrored to green.
+func (cp *ConfigProxy) applyDeployments(config *ProxiedTraefikConfig, resources []*resourceData) {
+	for _, resource := range resources {
+		deployment := resource.Deployment
+		if deployment == nil {
+			continue
+		}
+
+		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, router = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+		if routerKey == "" || router.Service == "" {
+			log.Printf("Blue/green deployment configured for resource %s but no router with a service matched; skipping", resource.ID)
+			continue
+		}
+
+		greenName, blueName, mirroringName := models.DeploymentServiceNames(resource.ID)
+		servers := func(url string) []interface{} {
+			return []interface{}{map[string]interface{}{"url": url}}
+		}
+		green := map[string]interface{}{"servers": servers(deployment.GreenURL)}
+		if upstream, ok := config.HTTP.Services[strings.TrimSuffix(router.Service, "@http")].(map[string]interface{}); ok {
+			if lb, ok := upstream["loadBalancer"].(map[string]interface{}); ok {
+				green = cloneValueMap(lb)
+				green["servers"] = servers(deployment.GreenURL)
+			}
+		}
+		config.HTTP.Services[greenName] = map[string]interface{}{"loadBalancer": green}
+
+		if deployment.Stage != models.DeploymentStaged {
+			router.Service = greenName
+			continue
+		}
+
+		blue := router.Service
+		if deployment.BlueURL != "" {
+			blueLB := cloneValueMap(green)
+			blueLB["servers"] = servers(deployment.BlueURL)
+			config.HTTP.Services[blueName] = map[string]interface{}{"loadBalancer": blueLB}
+			blue = blueName
+		}
+		if deployment.MirrorPercent > 0 {
+			config.HTTP.Services[mirroringName] = map[string]interface{}{
+				"mirroring": map[string]interface{}{
+					"service": blue,
+					"mirrors": []interface{}{
+						map[string]interface{}{"name": greenName, "percent": deployment.MirrorPercent},
+					},
+				},
+			}
+			blue = mirroringName
+		}
+		router.Service = blue
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestBlueGreenDeployerLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')
+	`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	deployer := NewBlueGreenDeployer(db)
+	deployer.now = func() time.Time { return now }
+
+	begin := func(url string) (*models.ResourceDeployment, error) {
+		deployment := models.ResourceDeployment{ResourceID: "res-1", GreenURL: url, SoakPeriod: "30m"}
+		deployment.Normalize()
+		return deployer.Begin(deployment)
+	}
+
+	if _, err := begin("http://green-1:80"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := begin("http://green-2:80"); !errors.Is(err, ErrDeploymentStage) {
+		t.Fatalf("expected a second deployment to be rejected while staged, got %v", err)
+	}
+
+	switched, err := deployer.Switch("res-1")
+	if err != nil {
+		t.Fatalf("Switch: %v", err)
+	}
+	if switched.Stage != models.DeploymentSwitched || !switched.SoakUntil.Equal(now.Add(30*time.Minute)) {
+		t.Fatalf("unexpected switched deployment %+v", switched)
+	}
+	if _, err := deployer.Switch("res-1"); !errors.Is(err, ErrDeploymentStage) {
+		t.Errorf("expected switching twice to be rejected, got %v", err)
+	}
+
+	// Nothing is archived before the soak period ends
+	if completed, err := deployer.CompleteSoaked(); err != nil || len(completed) != 0 {
+		t.Fatalf("CompleteSoaked before soak end = %v, %v", completed, err)
+	}
+	now = now.Add(31 * time.Minute)
+	if completed, err := deployer.CompleteSoaked(); err != nil || len(completed) != 1 {
+		t.Fatalf("CompleteSoaked after soak end = %v, %v", completed, err)
+	}
+	if err := deployer.Rollback("res-1"); !errors.Is(err, ErrDeploymentStage) {
+		t.Errorf("expected rollback of a completed deployment to be rejected, got %v", err)
+	}
+
+	// The next deployment uses the completed green as blue; rolling it back restores it
+	next, err := begin("http://green-2:80")
+	if err != nil {
+		t.Fatalf("Begin after completion: %v", err)
+	}
+	if next.BlueURL != "http://green-1:80" {
+		t.Fatalf("blue_url = %q, want the previous green", next.BlueURL)
+	}
+	if err := deployer.Rollback("res-1"); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	restored, err := deployer.Get("res-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if restored.GreenURL != "http://green-1:80" || restored.BlueURL != "" || restored.Stage != models.DeploymentCompleted {
+		t.Errorf("unexpected deployment after rollback %+v", restored)
+	}
+
+	if err := deployer.Remove("res-1"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := deployer.Get("res-1"); err == nil {
+		t.Errorf("expected the deployment to be removed")
+	}
+}
+
+func TestApplyDeployments(t *testing.T) {
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), "")
+
+	newConfig := func() *ProxiedTraefikConfig {
+		return &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"app-router": {Rule: "Host(`app.example.com`)", Service: "app-service"},
+			},
+			Services: map[string]interface{}{
+				"app-service": map[string]interface{}{"loadBalancer": map[string]interface{}{
+					"servers":        []interface{}{map[string]interface{}{"url": "http://blue:80"}},
+					"passHostHeader": false,
+				}},
+			},
+		}}
+	}
+	apply := func(deployment models.ResourceDeployment) *ProxiedTraefikConfig {
+		config := newConfig()
+		deployment.ResourceID = "res-1"
+		cp.applyDeployments(config, []*resourceData{
+			{ID: "res-1", PangolinRouterID: "app-router", Host: "app.example.com", Deployment: &deployment},
+		})
+		return config
+	}
+
+	// Staged without mirroring leaves the router on blue
+	config := apply(models.ResourceDeployment{GreenURL: "http://green:80", Stage: models.DeploymentStaged})
+	if got := config.HTTP.Routers["app-router"].Service; got != "app-service" {
+		t.Errorf("staged router service = %s, want app-service", got)
+	}
+	green := config.HTTP.Services["res-1-green"].(map[string]interface{})["loadBalancer"].(map[string]interface{})
+	if green["passHostHeader"] != false {
+		t.Errorf("green should inherit blue's loadBalancer settings: %v", green)
+	}
+
+	// Staged with mirroring copies requests to green
+	config = apply(models.ResourceDeployment{GreenURL: "http://green:80", Stage: models.DeploymentStaged, MirrorPercent: 25})
+	if got := config.HTTP.Routers["app-router"].Service; got != "res-1-green-mirroring" {
+		t.Fatalf("mirrored router service = %s, want res-1-green-mirroring", got)
+	}
+	mirroring := config.HTTP.Services["res-1-green-mirroring"].(map[string]interface{})["mirroring"].(map[string]interface{})
+	if mirroring["service"] != "app-service" {
+		t.Errorf("mirroring main service = %v, want app-service", mirroring["service"])
+	}
+
+	// A previous deployment's backend is served as the generated blue service
+	config = apply(models.ResourceDeployment{GreenURL: "http://green:80", BlueURL: "http://old-green:80", Stage: models.DeploymentStaged})
+	if got := config.HTTP.Routers["app-router"].Service; got != "res-1-blue" {
+		t.Errorf("router service = %s, want res-1-blue", got)
+	}
+
+	for _, stage := range []string{models.DeploymentSwitched, models.DeploymentCompleted} {
+		config = apply(models.ResourceDeployment{GreenURL: "http://green:80", BlueURL: "http://old-green:80", Stage: stage})
+		if got := config.HTTP.Routers["app-router"].Service; got != "res-1-green" {
+			t.Errorf("%s router service = %s, want res-1-green", stage, got)
+		}
+		if _, ok := config.HTTP.Services["res-1-blue"]; ok {
+			t.Errorf("%s: blue service should no longer be served", stage)
+		}
+	}
+}
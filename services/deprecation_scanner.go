@@ -0,0 +1,248 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DeprecationFinding is a stored middleware or service using a type or option
+// the target Traefik version renamed, removed or deprecated
+type DeprecationFinding struct {
+	Kind string `json:"kind"` // "middleware" or "service"
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Option is the type, or type.option, as stored in MM
+	Option      string `json:"option"`
+	Replacement string `json:"replacement,omitempty"`
+	Message     string `json:"message"`
+	Hint        string `json:"hint,omitempty"`
+	// Fixable findings are rewritten by FixDeprecations
+	Fixable bool `json:"fixable"`
+}
+
+// DeprecationScan is the result of scanning stored configs against the
+// target Traefik version
+type DeprecationScan struct {
+	TraefikVersion string               `json:"traefik_version"`
+	Major          int                  `json:"major"`
+	Findings       []DeprecationFinding `json:"findings"`
+	// Fixed counts the middlewares FixDeprecations rewrote
+	Fixed int `json:"fixed,omitempty"`
+}
+
+// removedOptionHints suggest what to use instead of middleware options
+// Traefik v3 removed without a direct replacement
+var removedOptionHints = map[string]string{
+	"headers.sslRedirect":          "Use a redirectScheme middleware or the HTTPS redirect setting",
+	"headers.sslTemporaryRedirect": "Use a redirectScheme middleware or the HTTPS redirect setting",
+	"headers.sslHost":              "Use a redirectRegex middleware",
+	"headers.sslForceHost":         "Use a redirectRegex middleware",
+}
+
+// serviceOptionDeprecation is a service option Traefik deprecated in favor of
+// a setting MM cannot write for the service, so it is only reported
+type serviceOptionDeprecation struct {
+	Protocol string
+	Option   string
+	Since    string
+	Hint     string
+}
+
+var serviceOptionDeprecations = []serviceOptionDeprecation{
+	{Protocol: "tcp", Option: "terminationDelay", Since: "v3.2", Hint: "Set terminationDelay on a TCP serversTransport instead"},
+	{Protocol: "tcp", Option: "proxyProtocol", Since: "v3.2", Hint: "Set proxyProtocol on a TCP serversTransport instead"},
+}
+
+// ScanDeprecations lists the middlewares and services MM stores that use
+// types or options the target Traefik version renamed, removed or deprecated.
+// With an unknown Traefik version nothing is reported.
+func (cp *ConfigProxy) ScanDeprecations() (*DeprecationScan, error) {
+	status := cp.TraefikCompatibility()
+	scan := &DeprecationScan{
+		TraefikVersion: status.Version,
+		Major:          status.Major,
+		Findings:       []DeprecationFinding{},
+	}
+	if status.Major == 0 {
+		return scan, nil
+	}
+
+	middlewares, err := scanMiddlewareDeprecations(cp.db.DB, status.Major)
+	if err != nil {
+		return nil, err
+	}
+	services, err := scanServiceDeprecations(cp.db.DB, status.Major)
+	if err != nil {
+		return nil, err
+	}
+	scan.Findings = append(middlewares, services...)
+	return scan, nil
+}
+
+// FixDeprecations rewrites the stored middlewares with fixable findings for
+// the target Traefik version, renaming types and options and dropping removed
+// options, and returns the findings left afterwards
+func (cp *ConfigProxy) FixDeprecations() (*DeprecationScan, error) {
+	status := cp.TraefikCompatibility()
+	if status.Major == 0 {
+		return cp.ScanDeprecations()
+	}
+
+	fixed := 0
+	err := cp.db.WithTransaction(func(tx *sql.Tx) error {
+		middlewares, err := loadStoredConfigs(tx, "SELECT id, name, type, config FROM middlewares")
+		if err != nil {
+			return err
+		}
+		for _, mw := range middlewares {
+			target, changes := planMiddlewareCompat(mw.typ, mw.options, status.Major)
+			if target == mw.typ && len(changes) == 0 {
+				continue
+			}
+			applyMiddlewareCompat(mw.options, changes)
+			configJSON, err := json.Marshal(mw.options)
+			if err != nil {
+				return fmt.Errorf("encoding middleware %s: %w", mw.id, err)
+			}
+			if _, err := tx.Exec(
+				"UPDATE middlewares SET type = ?, config = ?, updated_at = ?, version = version + 1 WHERE id = ?",
+				target, string(configJSON), time.Now(), mw.id,
+			); err != nil {
+				return fmt.Errorf("updating middleware %s: %w", mw.id, err)
+			}
+			log.Printf("Fixed deprecated options of middleware %s for Traefik v%d", mw.name, status.Major)
+			fixed++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fixed > 0 {
+		cp.InvalidateCache()
+	}
+
+	scan, err := cp.ScanDeprecations()
+	if err != nil {
+		return nil, err
+	}
+	scan.Fixed = fixed
+	return scan, nil
+}
+
+// storedConfig is a middleware or service row with its decoded options
+type storedConfig struct {
+	id      string
+	name    string
+	typ     string
+	options map[string]interface{}
+}
+
+// queryer is the part of *sql.DB and *sql.Tx the scanner reads with
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadStoredConfigs reads id, name, type and config rows, skipping configs
+// that are not JSON objects
+func loadStoredConfigs(q queryer, query string) ([]storedConfig, error) {
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []storedConfig
+	for rows.Next() {
+		var row storedConfig
+		var config string
+		if err := rows.Scan(&row.id, &row.name, &row.typ, &config); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(config), &row.options); err != nil || row.options == nil {
+			continue
+		}
+		configs = append(configs, row)
+	}
+	return configs, rows.Err()
+}
+
+// scanMiddlewareDeprecations reports stored middlewares whose type or options
+// differ in the given Traefik major version
+func scanMiddlewareDeprecations(db *sql.DB, major int) ([]DeprecationFinding, error) {
+	middlewares, err := loadStoredConfigs(db, "SELECT id, name, type, config FROM middlewares ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []DeprecationFinding{}
+	for _, mw := range middlewares {
+		target, changes := planMiddlewareCompat(mw.typ, mw.options, major)
+		if target != mw.typ {
+			findings = append(findings, DeprecationFinding{
+				Kind:        "middleware",
+				ID:          mw.id,
+				Name:        mw.name,
+				Option:      mw.typ,
+				Replacement: target,
+				Message:     fmt.Sprintf("%s is named %s in Traefik v%d", mw.typ, target, major),
+				Fixable:     true,
+			})
+		}
+		for _, change := range changes {
+			finding := DeprecationFinding{
+				Kind:    "middleware",
+				ID:      mw.id,
+				Name:    mw.name,
+				Option:  mw.typ + "." + change.From,
+				Fixable: true,
+			}
+			if change.To == "" {
+				finding.Message = fmt.Sprintf("%s.%s is not supported by Traefik v%d and is dropped", target, change.From, major)
+				finding.Hint = removedOptionHints[target+"."+change.From]
+			} else {
+				finding.Replacement = target + "." + change.To
+				finding.Message = fmt.Sprintf("%s.%s is named %s in Traefik v%d", target, change.From, change.To, major)
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// scanServiceDeprecations reports stored load balancer services using options
+// Traefik deprecated; these need a serversTransport and are not fixable
+func scanServiceDeprecations(db *sql.DB, major int) ([]DeprecationFinding, error) {
+	findings := []DeprecationFinding{}
+	if major < 3 {
+		return findings, nil
+	}
+
+	services, err := loadStoredConfigs(db, "SELECT id, name, type, config FROM services ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		protocol := determineServiceProtocol(svc.typ, svc.options)
+		for _, deprecation := range serviceOptionDeprecations {
+			if deprecation.Protocol != protocol {
+				continue
+			}
+			if _, exists := svc.options[deprecation.Option]; !exists {
+				continue
+			}
+			findings = append(findings, DeprecationFinding{
+				Kind:    "service",
+				ID:      svc.id,
+				Name:    svc.name,
+				Option:  svc.typ + "." + deprecation.Option,
+				Message: fmt.Sprintf("%s %s.%s is deprecated since Traefik %s", protocol, svc.typ, deprecation.Option, deprecation.Since),
+				Hint:    deprecation.Hint,
+			})
+		}
+	}
+	return findings, nil
+}
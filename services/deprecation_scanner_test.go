@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestScanDeprecations(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "http://pangolin.invalid")
+
+	for _, stmt := range []string{
+		`INSERT INTO middlewares (id, name, type, config) VALUES
+			('mw-ip', 'office-only', 'ipWhiteList', '{"sourceRange": ["10.0.0.0/8"]}'),
+			('mw-headers', 'secure', 'headers', '{"sslRedirect": true, "featurePolicy": "camera none", "frameDeny": true}'),
+			('mw-ok', 'gzip', 'compress', '{}')`,
+		`INSERT INTO services (id, name, type, config) VALUES
+			('svc-tcp', 'db', 'loadBalancer', '{"servers": [{"address": "10.0.0.5:5432"}], "terminationDelay": 100}'),
+			('svc-http', 'web', 'loadBalancer', '{"servers": [{"url": "http://10.0.0.6"}], "terminationDelay": 100}')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	scan, err := cp.ScanDeprecations()
+	if err != nil {
+		t.Fatalf("ScanDeprecations() error = %v", err)
+	}
+	if len(scan.Findings) != 0 {
+		t.Errorf("expected no findings with an unknown Traefik version, got %+v", scan.Findings)
+	}
+
+	cp.SetTraefikVersion("v3.2.0")
+	scan, err = cp.ScanDeprecations()
+	if err != nil {
+		t.Fatalf("ScanDeprecations() error = %v", err)
+	}
+	var options []string
+	for _, finding := range scan.Findings {
+		options = append(options, finding.Kind+":"+finding.Name+":"+finding.Option)
+	}
+	want := []string{
+		"middleware:office-only:ipWhiteList",
+		"middleware:secure:headers.featurePolicy",
+		"middleware:secure:headers.sslRedirect",
+		"service:db:loadBalancer.terminationDelay",
+	}
+	if !reflect.DeepEqual(options, want) {
+		t.Fatalf("findings = %v, want %v", options, want)
+	}
+	if scan.Findings[2].Hint == "" || scan.Findings[2].Replacement != "" {
+		t.Errorf("removed option should have a hint and no replacement: %+v", scan.Findings[2])
+	}
+	if scan.Findings[3].Fixable {
+		t.Errorf("service deprecations should not be fixable: %+v", scan.Findings[3])
+	}
+
+	scan, err = cp.FixDeprecations()
+	if err != nil {
+		t.Fatalf("FixDeprecations() error = %v", err)
+	}
+	if scan.Fixed != 2 || len(scan.Findings) != 1 || scan.Findings[0].Kind != "service" {
+		t.Fatalf("after fix: fixed = %d, findings = %+v", scan.Fixed, scan.Findings)
+	}
+
+	var typ, config string
+	var version int
+	if err := db.QueryRow("SELECT type, config, version FROM middlewares WHERE id = 'mw-headers'").Scan(&typ, &config, &version); err != nil {
+		t.Fatalf("load middleware: %v", err)
+	}
+	var fixedConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(config), &fixedConfig); err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	wantConfig := map[string]interface{}{"permissionsPolicy": "camera none", "frameDeny": true}
+	if typ != "headers" || !reflect.DeepEqual(fixedConfig, wantConfig) || version != 2 {
+		t.Errorf("fixed middleware = %s %v (version %d), want headers %v (version 2)", typ, fixedConfig, version, wantConfig)
+	}
+	if err := db.QueryRow("SELECT type FROM middlewares WHERE id = 'mw-ip'").Scan(&typ); err != nil || typ != "ipAllowList" {
+		t.Errorf("renamed middleware type = %q (%v), want ipAllowList", typ, err)
+	}
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+const (
+	// clockSkewWarn and clockSkewFail bound the difference between the local
+	// clock and the Date header of Pangolin or Traefik. Certificates, token
+	// expiry and assignment expiry all depend on the local clock.
+	clockSkewWarn = 30 * time.Second
+	clockSkewFail = 5 * time.Minute
+	// diagnosticsProbeTimeout bounds each reachability probe
+	diagnosticsProbeTimeout = 5 * time.Second
+)
+
+// minPlausibleTime is earlier than any release of this project; a clock
+// before it was never set
+var minPlausibleTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DiagnosticsOptions describes the environment the checks inspect
+type DiagnosticsOptions struct {
+	TraefikConfDir    string
+	FileConfigEnabled bool
+	// StaticConfigPath returns the current static config path, which can be
+	// changed at runtime from the plugin hub
+	StaticConfigPath func() string
+}
+
+// Diagnostics runs the environment self-checks behind /api/system/diagnostics.
+// Most first-run problems are one of these: a read-only volume, a wrong
+// Pangolin or Traefik URL, or a static config path that is not mounted.
+type Diagnostics struct {
+	db            *sql.DB
+	configManager *ConfigManager
+	opts          DiagnosticsOptions
+	client        *http.Client
+	now           func() time.Time
+}
+
+// NewDiagnostics creates a diagnostics runner
+func NewDiagnostics(db *sql.DB, configManager *ConfigManager, opts DiagnosticsOptions) *Diagnostics {
+	return &Diagnostics{
+		db:            db,
+		configManager: configManager,
+		opts:          opts,
+		client:        HTTPClientWithTimeout(diagnosticsProbeTimeout),
+		now:           time.Now,
+	}
+}
+
+// Run executes every check. The reachability probes run concurrently; the
+// clock check compares against the Date headers they collect.
+func (d *Diagnostics) Run(ctx context.Context) *models.DiagnosticsReport {
+	report := &models.DiagnosticsReport{OK: true, CheckedAt: d.now().UTC()}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		pangolin models.DiagnosticCheck
+		traefik  models.DiagnosticCheck
+		dates    []time.Time
+	)
+	probe := func(out *models.DiagnosticCheck, name string, sourceType models.DataSourceType) {
+		defer wg.Done()
+		check, date := d.checkDataSource(ctx, name, sourceType)
+		mu.Lock()
+		defer mu.Unlock()
+		*out = check
+		if !date.IsZero() {
+			dates = append(dates, date)
+		}
+	}
+	wg.Add(2)
+	go probe(&pangolin, "pangolin_reachable", models.PangolinAPI)
+	go probe(&traefik, "traefik_api_reachable", models.TraefikAPI)
+
+	report.Checks = append(report.Checks,
+		timedCheck(d.checkDatabase),
+		timedCheck(d.checkTraefikConfDir),
+		timedCheck(d.checkStaticConfig),
+		timedCheck(d.checkCertsPath),
+	)
+	wg.Wait()
+	report.Checks = append(report.Checks, pangolin, traefik, d.checkClock(dates))
+
+	for _, check := range report.Checks {
+		if check.Status == models.DiagnosticFail {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+func timedCheck(fn func() models.DiagnosticCheck) models.DiagnosticCheck {
+	start := time.Now()
+	check := fn()
+	check.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return check
+}
+
+// checkDatabase opens a write transaction and rolls it back. SQLite takes the
+// write lock on the first write, so a read-only file or volume fails here.
+func (d *Diagnostics) checkDatabase() models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: "database_writable"}
+	if d.db == nil {
+		check.Status = models.DiagnosticFail
+		check.Message = "No database connection"
+		return check
+	}
+
+	tx, err := d.db.Begin()
+	if err == nil {
+		_, err = tx.Exec("CREATE TABLE diagnostics_write_probe (id INTEGER)")
+		tx.Rollback()
+	}
+	if err != nil {
+		check.Status = models.DiagnosticFail
+		check.Message = fmt.Sprintf("Database is not writable: %v", err)
+		check.Remediation = "Mount the directory holding DB_PATH read-write and make sure the container user owns it"
+		if database.IsPostgres() {
+			check.Remediation = "Grant the DB_DSN user CREATE and write privileges on its schema"
+		}
+		return check
+	}
+	check.Status = models.DiagnosticPass
+	check.Message = "Database accepts writes"
+	return check
+}
+
+func (d *Diagnostics) checkTraefikConfDir() models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: "traefik_conf_dir_writable"}
+	if !d.opts.FileConfigEnabled {
+		check.Status = models.DiagnosticSkip
+		check.Message = "File config generation is disabled; Traefik reads the config from the API"
+		return check
+	}
+	if err := probeWritableDir(d.opts.TraefikConfDir); err != nil {
+		check.Status = models.DiagnosticFail
+		check.Message = fmt.Sprintf("TRAEFIK_CONF_DIR %q is not writable: %v", d.opts.TraefikConfDir, err)
+		check.Remediation = "Mount the directory Traefik's file provider watches read-write at TRAEFIK_CONF_DIR"
+		return check
+	}
+	check.Status = models.DiagnosticPass
+	check.Message = fmt.Sprintf("TRAEFIK_CONF_DIR %q is writable", d.opts.TraefikConfDir)
+	return check
+}
+
+func (d *Diagnostics) checkStaticConfig() models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: "static_config_readable"}
+	path := ""
+	if d.opts.StaticConfigPath != nil {
+		path = d.opts.StaticConfigPath()
+	}
+	if path == "" {
+		check.Status = models.DiagnosticWarn
+		check.Message = "Traefik static config path is not set"
+		check.Remediation = "Set TRAEFIK_STATIC_CONFIG_PATH to enable plugin, WAF and entrypoint redirect management"
+		return check
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		check.Status = models.DiagnosticFail
+		check.Message = fmt.Sprintf("Cannot read Traefik static config %q: %v", path, err)
+		check.Remediation = "Mount Traefik's traefik.yml into this container and point TRAEFIK_STATIC_CONFIG_PATH at it"
+		return check
+	}
+	if lint := LintTraefikStaticConfig(data); !lint.Parsed {
+		check.Status = models.DiagnosticFail
+		check.Message = lint.Findings[0].Message
+		check.Remediation = "Fix the YAML syntax error; Traefik will not start with this file either"
+		return check
+	}
+	check.Status = models.DiagnosticPass
+	check.Message = fmt.Sprintf("Traefik static config %q is readable", path)
+	return check
+}
+
+func (d *Diagnostics) checkCertsPath() models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: "certs_path_writable"}
+	var enabled bool
+	var basePath string
+	err := d.db.QueryRow("SELECT enabled, COALESCE(certs_base_path, '') FROM mtls_config WHERE id = 1").Scan(&enabled, &basePath)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("Could not read the mTLS configuration: %v", err)
+		return check
+	}
+	if basePath == "" {
+		check.Status = models.DiagnosticSkip
+		check.Message = "No certificate base path configured"
+		return check
+	}
+
+	status := models.DiagnosticFail
+	if !enabled {
+		// Only matters once mTLS is turned on
+		status = models.DiagnosticWarn
+	}
+
+	dir := basePath
+	if _, statErr := os.Stat(dir); os.IsNotExist(statErr) {
+		// The CA is written with MkdirAll, so a writable parent is enough
+		dir = filepath.Dir(dir)
+	}
+	if err := probeWritableDir(dir); err != nil {
+		check.Status = status
+		check.Message = fmt.Sprintf("Certificate base path %q is not writable: %v", basePath, err)
+		check.Remediation = "Mount the certificate directory read-write; Traefik must see the same files at the same path"
+		return check
+	}
+	check.Status = models.DiagnosticPass
+	check.Message = fmt.Sprintf("Certificate base path %q is writable", basePath)
+	return check
+}
+
+// checkDataSource probes the configured data source of the given type and
+// returns the Date header of its response, if any
+func (d *Diagnostics) checkDataSource(ctx context.Context, name string, sourceType models.DataSourceType) (models.DiagnosticCheck, time.Time) {
+	start := time.Now()
+	check := models.DiagnosticCheck{Name: name}
+
+	label, envVar := "Pangolin", "PANGOLIN_API_URL"
+	if sourceType == models.TraefikAPI {
+		label, envVar = "Traefik API", "TRAEFIK_API_URL"
+	}
+
+	// Prefer the active source; only it has to be reachable
+	status := models.DiagnosticWarn
+	config, found := models.DataSourceConfig{}, false
+	if d.configManager != nil {
+		if active, err := d.configManager.GetActiveDataSourceConfig(); err == nil && active.Type == sourceType && active.URL != "" {
+			config, found, status = active, true, models.DiagnosticFail
+		} else {
+			sources := d.configManager.GetDataSources()
+			names := make([]string, 0, len(sources))
+			for name := range sources {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if source := sources[name]; source.Type == sourceType && source.URL != "" {
+					config, found = source, true
+					break
+				}
+			}
+		}
+	}
+	if !found {
+		check.Status = models.DiagnosticSkip
+		check.Message = fmt.Sprintf("No %s data source configured", label)
+		return check, time.Time{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsProbeTimeout)
+	defer cancel()
+	header, err := probeDataSource(ctx, d.client, config)
+	var date time.Time
+	if header != nil {
+		date, _ = http.ParseTime(header.Get("Date"))
+	}
+	if err != nil {
+		check.Status = status
+		check.Message = fmt.Sprintf("%s at %s is not reachable: %v", label, config.URL, err)
+		check.Remediation = fmt.Sprintf("Check %s and that this container shares a Docker network with %s", envVar, label)
+		check.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		return check, date
+	}
+	check.Status = models.DiagnosticPass
+	check.Message = fmt.Sprintf("%s at %s is reachable", label, config.URL)
+	check.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return check, date
+}
+
+// checkClock flags a clock that was never set or drifts from the servers
+// this instance talks to
+func (d *Diagnostics) checkClock(peerDates []time.Time) models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: "clock_sane"}
+	now := d.now()
+	if now.Before(minPlausibleTime) {
+		check.Status = models.DiagnosticFail
+		check.Message = fmt.Sprintf("System clock reads %s", now.UTC().Format(time.RFC3339))
+		check.Remediation = "Sync the host clock with NTP; certificates and expiring assignments depend on it"
+		return check
+	}
+
+	var worst time.Duration
+	for _, date := range peerDates {
+		skew := now.Sub(date)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > worst {
+			worst = skew
+		}
+	}
+	// Date headers have one-second resolution
+	worst = worst.Truncate(time.Second)
+
+	switch {
+	case len(peerDates) == 0:
+		check.Status = models.DiagnosticPass
+		check.Message = "System clock is plausible; no peer to compare against"
+	case worst > clockSkewFail:
+		check.Status = models.DiagnosticFail
+		check.Message = fmt.Sprintf("System clock differs from Pangolin/Traefik by %s", worst)
+		check.Remediation = "Sync the host clock with NTP; certificates and expiring assignments depend on it"
+	case worst > clockSkewWarn:
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("System clock differs from Pangolin/Traefik by %s", worst)
+		check.Remediation = "Sync the host clock with NTP"
+	default:
+		check.Status = models.DiagnosticPass
+		check.Message = "System clock agrees with Pangolin/Traefik"
+	}
+	return check
+}
+
+// probeWritableDir creates and removes a temporary file in dir
+func probeWritableDir(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("path is empty")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+	f, err := os.CreateTemp(dir, ".mm-write-probe-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func diagnosticsByName(report *models.DiagnosticsReport) map[string]models.DiagnosticCheck {
+	checks := make(map[string]models.DiagnosticCheck)
+	for _, check := range report.Checks {
+		checks[check.Name] = check
+	}
+	return checks
+}
+
+// newDiagnosticsFixture points both data sources at a server whose Date header
+// is offset from the local clock by skew
+func newDiagnosticsFixture(t *testing.T, skew time.Duration, opts DiagnosticsOptions) *Diagnostics {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	cm := newTestConfigManager(t)
+	if err := cm.UpdateDataSource("pangolin", models.DataSourceConfig{Type: models.PangolinAPI, URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.UpdateDataSource("traefik", models.DataSourceConfig{Type: models.TraefikAPI, URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	return NewDiagnostics(newTestDB(t).DB, cm, opts)
+}
+
+func TestDiagnosticsAllPass(t *testing.T) {
+	confDir := t.TempDir()
+	staticPath := filepath.Join(t.TempDir(), "traefik.yml")
+	if err := os.WriteFile(staticPath, []byte("entryPoints:\n  web:\n    address: \":80\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newDiagnosticsFixture(t, 0, DiagnosticsOptions{
+		TraefikConfDir:    confDir,
+		FileConfigEnabled: true,
+		StaticConfigPath:  func() string { return staticPath },
+	})
+	certsDir := filepath.Join(t.TempDir(), "certs")
+	if _, err := d.db.Exec("UPDATE mtls_config SET certs_base_path = ? WHERE id = 1", certsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	report := d.Run(context.Background())
+	if !report.OK {
+		t.Fatalf("expected all checks to pass: %+v", report.Checks)
+	}
+	checks := diagnosticsByName(report)
+	for _, name := range []string{
+		"database_writable", "traefik_conf_dir_writable", "static_config_readable",
+		"certs_path_writable", "pangolin_reachable", "traefik_api_reachable", "clock_sane",
+	} {
+		if checks[name].Status != models.DiagnosticPass {
+			t.Errorf("%s = %s: %s", name, checks[name].Status, checks[name].Message)
+		}
+	}
+
+	var probes int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'diagnostics_write_probe'").Scan(&probes); err != nil || probes != 0 {
+		t.Errorf("database probe left a table behind (count %d, err %v)", probes, err)
+	}
+}
+
+func TestDiagnosticsReportsFailures(t *testing.T) {
+	d := newDiagnosticsFixture(t, 10*time.Minute, DiagnosticsOptions{
+		TraefikConfDir:    filepath.Join(t.TempDir(), "missing"),
+		FileConfigEnabled: true,
+		StaticConfigPath:  func() string { return filepath.Join(t.TempDir(), "traefik.yml") },
+	})
+
+	report := d.Run(context.Background())
+	if report.OK {
+		t.Fatal("expected the report to fail")
+	}
+	checks := diagnosticsByName(report)
+	for _, name := range []string{"traefik_conf_dir_writable", "static_config_readable", "clock_sane"} {
+		if checks[name].Status != models.DiagnosticFail || checks[name].Remediation == "" {
+			t.Errorf("%s = %+v, want a failure with remediation", name, checks[name])
+		}
+	}
+}
+
+func TestDiagnosticsUnreachableSources(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	cm := newTestConfigManager(t)
+	cm.UpdateDataSource("pangolin", models.DataSourceConfig{Type: models.PangolinAPI, URL: server.URL})
+	cm.UpdateDataSource("traefik", models.DataSourceConfig{Type: models.TraefikAPI, URL: server.URL})
+	if err := cm.SetActiveDataSource("pangolin"); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDiagnostics(newTestDB(t).DB, cm, DiagnosticsOptions{})
+
+	checks := diagnosticsByName(d.Run(context.Background()))
+	if checks["pangolin_reachable"].Status != models.DiagnosticFail {
+		t.Errorf("active source should fail: %+v", checks["pangolin_reachable"])
+	}
+	if checks["traefik_api_reachable"].Status != models.DiagnosticWarn {
+		t.Errorf("inactive source should only warn: %+v", checks["traefik_api_reachable"])
+	}
+	if checks["traefik_conf_dir_writable"].Status != models.DiagnosticSkip {
+		t.Errorf("conf dir check should be skipped without file config: %+v", checks["traefik_conf_dir_writable"])
+	}
+	if checks["clock_sane"].Status != models.DiagnosticPass {
+		t.Errorf("clock without peers should pass: %+v", checks["clock_sane"])
+	}
+}
+
+func TestDiagnosticsClockNeverSet(t *testing.T) {
+	d := NewDiagnostics(nil, nil, DiagnosticsOptions{})
+	d.now = func() time.Time { return time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC) }
+	if check := d.checkClock(nil); check.Status != models.DiagnosticFail {
+		t.Errorf("unset clock = %+v, want fail", check)
+	}
+}
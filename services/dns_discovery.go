@@ -0,0 +1,469 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Record TTLs are clamped so a zero TTL does not query DNS on every merge and
+// a long one still notices changes; failed lookups are retried after the minimum
+const (
+	dnsDiscoveryMinTTL  = 5 * time.Second
+	dnsDiscoveryMaxTTL  = 5 * time.Minute
+	dnsDiscoveryTimeout = 2 * time.Second
+)
+
+// dnsTarget is one server resolved for a dnsDiscovery service
+type dnsTarget struct {
+	Host   string
+	Port   int
+	Weight int
+}
+
+// dnsLookupFunc resolves a record into targets and the lowest TTL of the answer
+type dnsLookupFunc func(ctx context.Context, recordType, name string) ([]dnsTarget, time.Duration, error)
+
+// DNSDiscovery resolves the servers of dnsDiscovery services and caches each
+// answer for its TTL
+type DNSDiscovery struct {
+	mu      sync.Mutex
+	server  string
+	lookup  dnsLookupFunc
+	entries map[string]*dnsDiscoveryEntry
+}
+
+type dnsDiscoveryEntry struct {
+	record     string
+	targets    []dnsTarget
+	resolvedAt time.Time
+	expires    time.Time
+	changedAt  time.Time
+	err        string
+}
+
+// DNSDiscoveryStatus reports the last resolution of a dnsDiscovery service
+type DNSDiscoveryStatus struct {
+	ServiceID  string    `json:"service_id"`
+	Record     string    `json:"record"`
+	Servers    []string  `json:"servers"`
+	ResolvedAt time.Time `json:"resolved_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ChangedAt  time.Time `json:"changed_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// NewDNSDiscovery creates a resolver that queries the nameserver of
+// /etc/resolv.conf until SetServer picks another
+func NewDNSDiscovery() *DNSDiscovery {
+	d := &DNSDiscovery{entries: make(map[string]*dnsDiscoveryEntry)}
+	d.lookup = d.queryRecords
+	return d
+}
+
+// SetServer sets the nameserver (host or host:port) queried for records, e.g.
+// Consul's DNS interface on port 8600; empty uses /etc/resolv.conf
+func (d *DNSDiscovery) SetServer(server string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.server = strings.TrimSpace(server)
+	clear(d.entries)
+}
+
+// Status returns the last resolution of every dnsDiscovery service
+func (d *DNSDiscovery) Status() []DNSDiscoveryStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	statuses := make([]DNSDiscoveryStatus, 0, len(d.entries))
+	for id, entry := range d.entries {
+		servers := make([]string, 0, len(entry.targets))
+		for _, target := range entry.targets {
+			servers = append(servers, net.JoinHostPort(target.Host, strconv.Itoa(target.Port)))
+		}
+		statuses = append(statuses, DNSDiscoveryStatus{
+			ServiceID:  id,
+			Record:     entry.record,
+			Servers:    servers,
+			ResolvedAt: entry.resolvedAt,
+			ExpiresAt:  entry.expires,
+			ChangedAt:  entry.changedAt,
+			Error:      entry.err,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ServiceID < statuses[j].ServiceID })
+	return statuses
+}
+
+// resolve returns the targets of a service, querying DNS once the cached
+// answer expired. When a query fails the last answer keeps being served.
+func (d *DNSDiscovery) resolve(serviceID string, cfg models.DNSDiscoveryConfig) ([]dnsTarget, error) {
+	record := cfg.RecordType + " " + cfg.Name
+	now := time.Now()
+
+	d.mu.Lock()
+	entry := d.entries[serviceID]
+	if entry != nil && entry.record == record && now.Before(entry.expires) {
+		d.mu.Unlock()
+		if len(entry.targets) == 0 {
+			return nil, fmt.Errorf("%s", entry.err)
+		}
+		return entry.targets, nil
+	}
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsDiscoveryTimeout)
+	defer cancel()
+	targets, ttl, err := d.lookup(ctx, cfg.RecordType, cfg.Name)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if entry == nil || entry.record != record {
+		entry = &dnsDiscoveryEntry{record: record}
+		d.entries[serviceID] = entry
+	}
+	entry.resolvedAt = now
+	if err == nil && len(targets) == 0 {
+		err = fmt.Errorf("%s has no records", record)
+	}
+	if err != nil {
+		entry.err = err.Error()
+		entry.expires = now.Add(dnsDiscoveryMinTTL)
+		if len(entry.targets) == 0 {
+			return nil, err
+		}
+		log.Printf("Warning: DNS discovery for service %s failed, keeping %d servers: %v", serviceID, len(entry.targets), err)
+		return entry.targets, nil
+	}
+
+	// A records carry no port
+	for i := range targets {
+		if targets[i].Port == 0 {
+			targets[i].Port = cfg.Port
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Host != targets[j].Host {
+			return targets[i].Host < targets[j].Host
+		}
+		return targets[i].Port < targets[j].Port
+	})
+	if !slices.Equal(targets, entry.targets) {
+		if entry.targets != nil {
+			log.Printf("DNS discovery for service %s: %s now resolves to %d servers", serviceID, record, len(targets))
+		}
+		entry.changedAt = now
+	}
+	entry.targets = targets
+	entry.err = ""
+	entry.expires = now.Add(min(max(ttl, dnsDiscoveryMinTTL), dnsDiscoveryMaxTTL))
+	return targets, nil
+}
+
+// retain forgets services that no longer exist
+func (d *DNSDiscovery) retain(ids map[string]struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for id := range d.entries {
+		if _, ok := ids[id]; !ok {
+			delete(d.entries, id)
+		}
+	}
+}
+
+// DNSDiscoveryStatus returns the last resolution of every dnsDiscovery service
+func (cp *ConfigProxy) DNSDiscoveryStatus() []DNSDiscoveryStatus {
+	return cp.dnsDiscovery.Status()
+}
+
+// SetDNSDiscoveryServer sets the nameserver dnsDiscovery services are resolved with
+func (cp *ConfigProxy) SetDNSDiscoveryServer(server string) {
+	cp.dnsDiscovery.SetServer(server)
+}
+
+// applyDNSServices serves each active dnsDiscovery service as a loadBalancer
+// service with the servers its record currently resolves to, so resources
+// can use it as their custom service
+func (cp *ConfigProxy) applyDNSServices(config *ProxiedTraefikConfig) {
+	rows, err := cp.db.Query("SELECT id, config FROM services WHERE type = ? AND status = 'active'", string(models.DNSDiscoveryType))
+	if err != nil {
+		log.Printf("Warning: failed to load DNS discovery services: %v", err)
+		return
+	}
+	configs := make(map[string]models.DNSDiscoveryConfig)
+	for rows.Next() {
+		var id, configStr string
+		if err := rows.Scan(&id, &configStr); err != nil {
+			log.Printf("Failed to scan service: %v", err)
+			continue
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(configStr), &raw); err != nil {
+			log.Printf("Failed to parse service config for %s: %v", id, err)
+			continue
+		}
+		cfg, err := models.ParseDNSDiscoveryConfig(raw)
+		if err != nil {
+			log.Printf("Skipping DNS discovery service %s: %v", id, err)
+			continue
+		}
+		configs[id] = cfg
+	}
+	rows.Close()
+
+	// Queries run after the rows are closed so slow DNS does not hold the connection
+	protected := cp.protectedNames(database.ProtectedKindService)
+	seen := make(map[string]struct{}, len(configs))
+	for id, cfg := range configs {
+		seen[id] = struct{}{}
+		if protected.Contains(id) && cp.serviceExists(config, "http", id) {
+			log.Printf("Service %s is protected; keeping upstream definition", id)
+			continue
+		}
+		targets, err := cp.dnsDiscovery.resolve(id, cfg)
+		if err != nil {
+			log.Printf("Warning: DNS discovery for service %s: %v; not serving it", id, err)
+			continue
+		}
+		config.HTTP.Services[id] = dnsLoadBalancer(cfg, targets)
+	}
+	cp.dnsDiscovery.retain(seen)
+}
+
+// dnsLoadBalancer renders the loadBalancer service of resolved targets
+func dnsLoadBalancer(cfg models.DNSDiscoveryConfig, targets []dnsTarget) map[string]interface{} {
+	servers := make([]interface{}, 0, len(targets))
+	for _, target := range targets {
+		server := map[string]interface{}{
+			"url": cfg.Scheme + "://" + net.JoinHostPort(target.Host, strconv.Itoa(target.Port)),
+		}
+		if target.Weight > 0 {
+			server["weight"] = target.Weight
+		}
+		servers = append(servers, server)
+	}
+	lb := map[string]interface{}{"servers": servers}
+	if cfg.PassHostHeader != nil {
+		lb["passHostHeader"] = *cfg.PassHostHeader
+	}
+	if cfg.HealthCheck != nil {
+		lb["healthCheck"] = overrideValue(cfg.HealthCheck)
+	}
+	if cfg.Sticky != nil {
+		lb["sticky"] = overrideValue(cfg.Sticky)
+	}
+	return map[string]interface{}{"loadBalancer": lb}
+}
+
+// queryRecords resolves SRV or A records against the configured nameserver.
+// Only the SRV targets with the lowest priority are used; the others are
+// backups Traefik's loadBalancer has no notion of.
+func (d *DNSDiscovery) queryRecords(ctx context.Context, recordType, name string) ([]dnsTarget, time.Duration, error) {
+	d.mu.Lock()
+	server := d.server
+	d.mu.Unlock()
+	server = dnsNameserver(server)
+
+	if recordType == models.DNSRecordA {
+		addrs, ttl, err := queryA(ctx, server, name+".")
+		if err != nil {
+			return nil, 0, err
+		}
+		targets := make([]dnsTarget, 0, len(addrs))
+		for _, addr := range addrs {
+			targets = append(targets, dnsTarget{Host: addr})
+		}
+		return targets, ttl, nil
+	}
+
+	msg, err := exchangeDNS(ctx, server, name+".", dnsmessage.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := dnsDiscoveryMaxTTL
+	additional := make(map[string]string)
+	for _, rr := range msg.Additionals {
+		if a, ok := rr.Body.(*dnsmessage.AResource); ok {
+			if _, seen := additional[rr.Header.Name.String()]; !seen {
+				additional[rr.Header.Name.String()] = net.IP(a.A[:]).String()
+			}
+		}
+	}
+	var records []*dnsmessage.SRVResource
+	lowest := uint16(0)
+	for _, rr := range msg.Answers {
+		srv, ok := rr.Body.(*dnsmessage.SRVResource)
+		if !ok {
+			continue
+		}
+		ttl = min(ttl, time.Duration(rr.Header.TTL)*time.Second)
+		if len(records) == 0 || srv.Priority < lowest {
+			records, lowest = records[:0], srv.Priority
+		}
+		if srv.Priority == lowest {
+			records = append(records, srv)
+		}
+	}
+
+	targets := make([]dnsTarget, 0, len(records))
+	for _, srv := range records {
+		target := srv.Target.String()
+		host, ok := additional[target]
+		if !ok {
+			// Without glue records resolve the target here too, since it may only
+			// be known to this nameserver (e.g. Consul); else let Traefik resolve it
+			host = strings.TrimSuffix(target, ".")
+			if addrs, addrTTL, err := queryA(ctx, server, target); err == nil && len(addrs) > 0 {
+				host = addrs[0]
+				ttl = min(ttl, addrTTL)
+			}
+		}
+		targets = append(targets, dnsTarget{Host: host, Port: int(srv.Port), Weight: int(srv.Weight)})
+	}
+	return targets, ttl, nil
+}
+
+// queryA returns the IPv4 addresses of name, following CNAMEs in the answer
+func queryA(ctx context.Context, server, name string) ([]string, time.Duration, error) {
+	msg, err := exchangeDNS(ctx, server, name, dnsmessage.TypeA)
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := dnsDiscoveryMaxTTL
+	var addrs []string
+	for _, rr := range msg.Answers {
+		if a, ok := rr.Body.(*dnsmessage.AResource); ok {
+			addrs = append(addrs, net.IP(a.A[:]).String())
+			ttl = min(ttl, time.Duration(rr.Header.TTL)*time.Second)
+		}
+	}
+	return addrs, ttl, nil
+}
+
+// exchangeDNS sends one query over UDP, retrying over TCP when the answer was truncated
+func exchangeDNS(ctx context.Context, server, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS name %q: %w", name, err)
+	}
+	id := uint16(rand.N(1 << 16))
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	// Advertise a larger UDP payload so typical SRV answers are not truncated
+	if err := builder.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(4096, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+	if err := builder.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, err
+	}
+	query, err := builder.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := exchangeDNSOver(ctx, "udp", server, query, id)
+	if err == nil && msg.Header.Truncated {
+		msg, err = exchangeDNSOver(ctx, "tcp", server, query, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s lookup of %s via %s: %w", qtype, name, server, err)
+	}
+	if msg.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("%s lookup of %s via %s: %s", qtype, name, server, msg.Header.RCode)
+	}
+	return msg, nil
+}
+
+func exchangeDNSOver(ctx context.Context, network, server string, query []byte, id uint16) (*dnsmessage.Message, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var response []byte
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+		if _, err := conn.Write(append(framed, query...)); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		response = make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, response); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		response = make([]byte, 4096)
+		n, err := conn.Read(response)
+		if err != nil {
+			return nil, err
+		}
+		response = response[:n]
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(response); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if msg.Header.ID != id {
+		return nil, fmt.Errorf("response ID mismatch")
+	}
+	return &msg, nil
+}
+
+// dnsNameserver returns server with a port, or the first nameserver of
+// /etc/resolv.conf when server is empty
+func dnsNameserver(server string) string {
+	if server == "" {
+		server = "127.0.0.1"
+		if data, err := os.ReadFile("/etc/resolv.conf"); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && fields[0] == "nameserver" {
+					server = fields[1]
+					break
+				}
+			}
+		}
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return server
+}
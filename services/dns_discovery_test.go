@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestDNSDiscoveryResolveCachesForTTL(t *testing.T) {
+	d := NewDNSDiscovery()
+	calls := 0
+	var lookupErr error
+	d.lookup = func(ctx context.Context, recordType, name string) ([]dnsTarget, time.Duration, error) {
+		calls++
+		return []dnsTarget{{Host: "10.0.0.2", Port: 80}, {Host: "10.0.0.1", Port: 80}}, time.Minute, lookupErr
+	}
+	cfg := models.DNSDiscoveryConfig{Name: "_http._tcp.api.internal", RecordType: models.DNSRecordSRV}
+
+	want := []dnsTarget{{Host: "10.0.0.1", Port: 80}, {Host: "10.0.0.2", Port: 80}}
+	for range 2 {
+		targets, err := d.resolve("svc", cfg)
+		if err != nil || !reflect.DeepEqual(targets, want) {
+			t.Fatalf("resolve() = %v, %v; want %v", targets, err, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("lookups = %d, want 1 within the TTL", calls)
+	}
+
+	// Once expired, a failing lookup keeps serving the last answer
+	d.entries["svc"].expires = time.Now().Add(-time.Second)
+	lookupErr = errors.New("timeout")
+	if targets, err := d.resolve("svc", cfg); err != nil || !reflect.DeepEqual(targets, want) {
+		t.Fatalf("resolve() after failure = %v, %v; want the last answer", targets, err)
+	}
+	status := d.Status()
+	if len(status) != 1 || status[0].Error != "timeout" || len(status[0].Servers) != 2 {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestDNSDiscoveryQueriesSRVRecords(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp not available: %v", err)
+	}
+	defer conn.Close()
+	go serveTestDNS(t, conn)
+
+	d := NewDNSDiscovery()
+	d.SetServer(conn.LocalAddr().String())
+	targets, ttl, err := d.queryRecords(context.Background(), models.DNSRecordSRV, "_http._tcp.api.internal")
+	if err != nil {
+		t.Fatalf("queryRecords: %v", err)
+	}
+	want := []dnsTarget{
+		{Host: "10.0.0.1", Port: 8080, Weight: 5},
+		{Host: "10.0.0.2", Port: 8081, Weight: 1},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("targets = %+v, want %+v", targets, want)
+	}
+	if ttl != 30*time.Second {
+		t.Errorf("ttl = %v, want the lowest TTL 30s", ttl)
+	}
+}
+
+// serveTestDNS answers the SRV query with two priority-10 targets (one with a
+// glue record) and a priority-20 backup, and A queries for the second target
+func serveTestDNS(t *testing.T, conn net.PacketConn) {
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf[:n]); err != nil {
+			t.Errorf("unpack query: %v", err)
+			return
+		}
+		q := query.Questions[0]
+		resp := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.Header.ID, Response: true},
+			Questions: query.Questions,
+		}
+		header := func(name string, typ dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+			return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET, TTL: ttl}
+		}
+		srv := func(priority, weight, port uint16, target string) dnsmessage.Resource {
+			return dnsmessage.Resource{
+				Header: header(q.Name.String(), dnsmessage.TypeSRV, 60),
+				Body:   &dnsmessage.SRVResource{Priority: priority, Weight: weight, Port: port, Target: dnsmessage.MustNewName(target)},
+			}
+		}
+		switch q.Type {
+		case dnsmessage.TypeSRV:
+			resp.Answers = []dnsmessage.Resource{
+				srv(10, 5, 8080, "a.api.internal."),
+				srv(10, 1, 8081, "b.api.internal."),
+				srv(20, 1, 8082, "c.api.internal."),
+			}
+			resp.Additionals = []dnsmessage.Resource{{
+				Header: header("a.api.internal.", dnsmessage.TypeA, 60),
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+			}}
+		case dnsmessage.TypeA:
+			resp.Answers = []dnsmessage.Resource{{
+				Header: header(q.Name.String(), dnsmessage.TypeA, 30),
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}},
+			}}
+		}
+		packed, err := resp.Pack()
+		if err != nil {
+			t.Errorf("pack response: %v", err)
+			return
+		}
+		_, _ = conn.WriteTo(packed, addr)
+	}
+}
+
+func TestApplyDNSServices(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+	cp.dnsDiscovery.lookup = func(ctx context.Context, recordType, name string) ([]dnsTarget, time.Duration, error) {
+		return []dnsTarget{{Host: "10.0.0.1"}}, time.Minute, nil
+	}
+	if _, err := db.Exec(`INSERT INTO services (id, name, type, config) VALUES
+		('api-dns', 'api', 'dnsDiscovery', '{"name":"api.internal","recordType":"A","port":9000}')`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	config := &ProxiedTraefikConfig{HTTP: &HTTPConfig{Services: map[string]interface{}{}}}
+	cp.applyDNSServices(config)
+
+	want := map[string]interface{}{"loadBalancer": map[string]interface{}{
+		"servers": []interface{}{map[string]interface{}{"url": "http://10.0.0.1:9000"}},
+	}}
+	if got := config.HTTP.Services["api-dns"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("service = %v, want %v", got, want)
+	}
+}
@@ -39,7 +39,9 @@ func (d *DuplicateDetector) getTraefikFetcher() *TraefikFetcher {
 		traefikConfig = activeConfig
 	}
 
-	return NewTraefikFetcher(traefikConfig)
+	fetcher := NewTraefikFetcher(traefikConfig)
+	fetcher.SetURLPersister(d.configManager.PersistTraefikURL)
+	return fetcher
 }
 
 // CheckDuplicates checks if a middleware name already exists in Traefik
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"log"
+	"sort"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// loadEntrypointMiddlewares returns the middlewares attached to each
+// entrypoint, highest priority first
+func (cp *ConfigProxy) loadEntrypointMiddlewares() (map[string][]middlewareWithPriority, error) {
+	rows, err := cp.db.Query(`
+		SELECT em.entrypoint, m.id, m.name, em.priority
+		FROM entrypoint_middlewares em
+		JOIN middlewares m ON m.id = em.middleware_id
+		ORDER BY em.entrypoint, em.priority DESC, m.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attached := make(map[string][]middlewareWithPriority)
+	for rows.Next() {
+		var entryPoint string
+		var mw middlewareWithPriority
+		if err := rows.Scan(&entryPoint, &mw.ID, &mw.Name, &mw.Priority); err != nil {
+			return nil, err
+		}
+		attached[entryPoint] = append(attached[entryPoint], mw)
+	}
+	return attached, rows.Err()
+}
+
+// applyEntrypointMiddlewares puts the middlewares attached to an entrypoint in
+// front of the middlewares of every HTTP router on it, like an entrypoint-level
+// middleware in Traefik's static config but without a restart. A router on
+// several entrypoints gets all of their middlewares, ordered by priority.
+// Routers outside the management scope are left alone.
+func (cp *ConfigProxy) applyEntrypointMiddlewares(config *ProxiedTraefikConfig, attached map[string][]middlewareWithPriority) {
+	if len(attached) == 0 || config.HTTP == nil {
+		return
+	}
+
+	scope := cp.routerScope()
+	for routerName, router := range config.HTTP.Routers {
+		if router == nil {
+			continue
+		}
+
+		var chain []middlewareWithPriority
+		for _, ep := range router.EntryPoints {
+			chain = append(chain, attached[ep]...)
+		}
+		if len(chain) == 0 {
+			continue
+		}
+		if !routerInScope(scope, router) {
+			continue
+		}
+		sort.SliceStable(chain, func(i, j int) bool {
+			return chain[i].Priority > chain[j].Priority
+		})
+
+		seen := make(map[string]bool, len(chain)+len(router.Middlewares))
+		var middlewares []string
+		for _, mw := range chain {
+			if !seen[mw.Name] {
+				seen[mw.Name] = true
+				middlewares = append(middlewares, mw.Name)
+			}
+		}
+		for _, name := range router.Middlewares {
+			if !seen[name] {
+				seen[name] = true
+				middlewares = append(middlewares, name)
+			}
+		}
+		router.Middlewares = middlewares
+
+		if shouldLog() {
+			log.Printf("Applied entrypoint middlewares to router %s", routerName)
+		}
+	}
+}
+
+// routerScope loads the management scope for router-wide changes; on error
+// every router is managed
+func (cp *ConfigProxy) routerScope() *models.ManagementScope {
+	scope, err := LoadManagementScope(cp.db.DB)
+	if err != nil {
+		log.Printf("Warning: failed to load management scope, managing all routers: %v", err)
+		return &models.ManagementScope{}
+	}
+	return scope
+}
+
+// routerInScope reports whether the host of a router's rule is inside the scope
+func routerInScope(scope *models.ManagementScope, router *OrderedRouter) bool {
+	return scope.IsUnrestricted() || scope.Matches(extractHostFromRule(router.Rule))
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestConfigProxyAppliesEntrypointMiddlewares(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES
+		('mw-headers', 'secure-headers', 'headers', '{"frameDeny": true}'),
+		('mw-gzip', 'gzip', 'compress', '{}'),
+		('mw-auth', 'auth', 'basicAuth', '{"users": ["u:p"]}')`); err != nil {
+		t.Fatalf("insert middlewares: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO entrypoint_middlewares (entrypoint, middleware_id, priority) VALUES
+		('websecure', 'mw-gzip', 100),
+		('websecure', 'mw-headers', 300)`); err != nil {
+		t.Fatalf("insert entrypoint middlewares: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'svc', 'org', 'site', 'active')`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES ('res-1', 'mw-auth', 200)`); err != nil {
+		t.Fatalf("assign middleware: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"app-router":   map[string]interface{}{"rule": "Host(`app.example.com`)", "service": "svc", "entryPoints": []string{"websecure"}},
+					"other-router": map[string]interface{}{"rule": "Host(`other.example.com`)", "service": "svc", "entryPoints": []string{"websecure"}, "middlewares": []string{"gzip", "upstream"}},
+					"plain-router": map[string]interface{}{"rule": "Host(`plain.example.com`)", "service": "svc", "entryPoints": []string{"web"}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	for _, name := range []string{"secure-headers", "gzip"} {
+		if _, ok := config.HTTP.Middlewares[name]; !ok {
+			t.Errorf("entrypoint middleware %s was not rendered", name)
+		}
+	}
+
+	tests := map[string][]string{
+		"app-router":   {"secure-headers", "gzip", "auth"},
+		"other-router": {"secure-headers", "gzip", "upstream"},
+		"plain-router": nil,
+	}
+	for router, want := range tests {
+		if got := config.HTTP.Routers[router].Middlewares; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s middlewares = %v, want %v", router, got, want)
+		}
+	}
+}
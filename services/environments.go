@@ -0,0 +1,457 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// EnvironmentHeader selects the environment of a config endpoint request
+// when the path does not name one
+const EnvironmentHeader = "X-MM-Environment"
+
+var (
+	// ErrUnknownEnvironment is returned for an environment that was not created
+	ErrUnknownEnvironment = errors.New("unknown environment")
+	// ErrEnvironmentExists is returned when creating an environment twice
+	ErrEnvironmentExists = errors.New("environment already exists")
+	// ErrResourceVersionConflict is returned when a resource is no longer at
+	// the version an edit is based on
+	ErrResourceVersionConflict = errors.New("resource was changed by someone else")
+)
+
+// EnvironmentService stores environments and the environment tags of
+// resources and middlewares
+type EnvironmentService struct {
+	db  *database.DB
+	now func() time.Time
+}
+
+// NewEnvironmentService creates a new environment service
+func NewEnvironmentService(db *database.DB) *EnvironmentService {
+	return &EnvironmentService{db: db, now: time.Now}
+}
+
+const environmentColumns = `e.name, e.description, e.is_default, e.created_at,
+	(SELECT COUNT(*) FROM environment_tags t WHERE t.environment = e.name AND t.target_type = 'resource'),
+	(SELECT COUNT(*) FROM environment_tags t WHERE t.environment = e.name AND t.target_type = 'middleware')`
+
+// List returns every environment by name with the number of tagged
+// resources and middlewares
+func (s *EnvironmentService) List() ([]models.Environment, error) {
+	rows, err := s.db.Query("SELECT " + environmentColumns + " FROM environments e ORDER BY e.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	environments := []models.Environment{}
+	for rows.Next() {
+		env, err := scanEnvironment(rows)
+		if err != nil {
+			return nil, err
+		}
+		environments = append(environments, *env)
+	}
+	return environments, rows.Err()
+}
+
+// Get returns an environment, or sql.ErrNoRows
+func (s *EnvironmentService) Get(name string) (*models.Environment, error) {
+	return scanEnvironment(s.db.QueryRow("SELECT "+environmentColumns+" FROM environments e WHERE e.name = ?", name))
+}
+
+// Create stores a normalized, validated environment; making it the default
+// takes that from the previous default
+func (s *EnvironmentService) Create(env models.Environment) (*models.Environment, error) {
+	if _, err := s.Get(env.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentExists, env.Name)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		if env.IsDefault {
+			if _, err := tx.Exec("UPDATE environments SET is_default = 0"); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("INSERT INTO environments (name, description, is_default, created_at) VALUES (?, ?, ?, ?)",
+			env.Name, env.Description, env.IsDefault, s.now().UTC())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(env.Name)
+}
+
+// Update replaces the description and default flag of an environment, or
+// returns sql.ErrNoRows
+func (s *EnvironmentService) Update(name string, env models.Environment) (*models.Environment, error) {
+	if _, err := s.Get(name); err != nil {
+		return nil, err
+	}
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		if env.IsDefault {
+			if _, err := tx.Exec("UPDATE environments SET is_default = 0 WHERE name != ?", name); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("UPDATE environments SET description = ?, is_default = ? WHERE name = ?",
+			env.Description, env.IsDefault, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(name)
+}
+
+// Delete removes an environment and its tags, or returns sql.ErrNoRows.
+// Targets tagged only with it are then limited to no environment.
+func (s *EnvironmentService) Delete(name string) error {
+	return s.db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM environment_tags WHERE environment = ?", name); err != nil {
+			return err
+		}
+		result, err := tx.Exec("DELETE FROM environments WHERE name = ?", name)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// Tags returns the environments a resource or middleware is limited to
+func (s *EnvironmentService) Tags(targetType, targetID string) (*models.EnvironmentTags, error) {
+	if err := s.targetExists(targetType, targetID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT environment FROM environment_tags
+		WHERE target_type = ? AND target_id = ?
+		ORDER BY environment
+	`, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := &models.EnvironmentTags{TargetType: targetType, TargetID: targetID, Environments: []string{}}
+	for rows.Next() {
+		var env string
+		if err := rows.Scan(&env); err != nil {
+			return nil, err
+		}
+		tags.Environments = append(tags.Environments, env)
+	}
+	return tags, rows.Err()
+}
+
+// SetTags limits a resource or middleware to environments; none applies it
+// in every environment. It returns sql.ErrNoRows for a missing target and
+// ErrUnknownEnvironment for an environment that was not created.
+func (s *EnvironmentService) SetTags(targetType, targetID string, environments []string) (*models.EnvironmentTags, error) {
+	return s.setTags(targetType, targetID, environments, nil)
+}
+
+// SetResourceTags limits a resource to environments like SetTags, as an edit
+// of the resource at version. It moves the resource on to its next version and
+// returns ErrResourceVersionConflict when the resource is at another one.
+func (s *EnvironmentService) SetResourceTags(resourceID string, version int64, environments []string) (*models.EnvironmentTags, error) {
+	return s.setTags(models.EnvironmentTargetResource, resourceID, environments, &version)
+}
+
+func (s *EnvironmentService) setTags(targetType, targetID string, environments []string, version *int64) (*models.EnvironmentTags, error) {
+	if err := s.targetExists(targetType, targetID); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, name := range environments {
+		name = models.NormalizeEnvironmentName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, err := s.Get(name); errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEnvironment, name)
+		} else if err != nil {
+			return nil, err
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		if version != nil {
+			result, err := tx.Exec("UPDATE resources SET updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
+				s.now(), targetID, *version)
+			if err != nil {
+				return err
+			}
+			if rows, err := result.RowsAffected(); err != nil {
+				return err
+			} else if rows == 0 {
+				return ErrResourceVersionConflict
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM environment_tags WHERE target_type = ? AND target_id = ?", targetType, targetID); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, err := tx.Exec("INSERT INTO environment_tags (environment, target_type, target_id) VALUES (?, ?, ?)",
+				name, targetType, targetID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.EnvironmentTags{TargetType: targetType, TargetID: targetID, Environments: names}, nil
+}
+
+// AllTags returns the tags of every tagged resource and middleware
+func (s *EnvironmentService) AllTags() ([]models.EnvironmentTags, error) {
+	rows, err := s.db.Query(`
+		SELECT target_type, target_id, environment FROM environment_tags
+		ORDER BY target_type, target_id, environment
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.EnvironmentTags{}
+	for rows.Next() {
+		var targetType, targetID, env string
+		if err := rows.Scan(&targetType, &targetID, &env); err != nil {
+			return nil, err
+		}
+		if n := len(tags); n > 0 && tags[n-1].TargetType == targetType && tags[n-1].TargetID == targetID {
+			tags[n-1].Environments = append(tags[n-1].Environments, env)
+			continue
+		}
+		tags = append(tags, models.EnvironmentTags{TargetType: targetType, TargetID: targetID, Environments: []string{env}})
+	}
+	return tags, rows.Err()
+}
+
+func (s *EnvironmentService) targetExists(targetType, targetID string) error {
+	var table string
+	switch targetType {
+	case models.EnvironmentTargetResource:
+		table = "resources"
+	case models.EnvironmentTargetMiddleware:
+		table = "middlewares"
+	default:
+		return fmt.Errorf("unknown environment target type %q", targetType)
+	}
+	var exists int
+	return s.db.QueryRow("SELECT 1 FROM "+table+" WHERE id = ?", targetID).Scan(&exists)
+}
+
+func scanEnvironment(row interface{ Scan(...interface{}) error }) (*models.Environment, error) {
+	var env models.Environment
+	if err := row.Scan(&env.Name, &env.Description, &env.IsDefault, &env.CreatedAt,
+		&env.Resources, &env.Middlewares); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// environmentFilter limits a merge to the resources and middlewares of one
+// environment
+type environmentFilter struct {
+	name string
+	// tagged holds the environments of each tagged target by type and ID
+	tagged map[string]map[string]map[string]bool
+}
+
+// loadEnvironmentFilter loads the tags for a merge of environment, or of the
+// default environment when it is empty. It returns nil when nothing is
+// tagged, so merges of installs without environments are not filtered.
+func (cp *ConfigProxy) loadEnvironmentFilter(environment string) (*environmentFilter, error) {
+	if environment == "" {
+		err := cp.db.QueryRow("SELECT name FROM environments WHERE is_default = 1 LIMIT 1").Scan(&environment)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	rows, err := cp.db.Query("SELECT environment, target_type, target_id FROM environment_tags")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filter := &environmentFilter{name: environment, tagged: map[string]map[string]map[string]bool{}}
+	for rows.Next() {
+		var env, targetType, targetID string
+		if err := rows.Scan(&env, &targetType, &targetID); err != nil {
+			return nil, err
+		}
+		if filter.tagged[targetType] == nil {
+			filter.tagged[targetType] = map[string]map[string]bool{}
+		}
+		if filter.tagged[targetType][targetID] == nil {
+			filter.tagged[targetType][targetID] = map[string]bool{}
+		}
+		filter.tagged[targetType][targetID][env] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(filter.tagged) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// includes reports whether a target applies in the environment: untagged
+// targets apply everywhere
+func (f *environmentFilter) includes(targetType, id string) bool {
+	if f == nil {
+		return true
+	}
+	envs, tagged := f.tagged[targetType][id]
+	return !tagged || envs[f.name]
+}
+
+// resources drops the resources of other environments, leaving their routers
+// untouched, and the middlewares of other environments from the rest
+func (f *environmentFilter) resources(resources []*resourceData) []*resourceData {
+	if f == nil {
+		return resources
+	}
+	kept := resources[:0]
+	for _, res := range resources {
+		if !f.includes(models.EnvironmentTargetResource, res.ID) {
+			if shouldLog() {
+				log.Printf("Resource %s (%s) is not in environment %q; leaving its router untouched", res.ID, res.Host, f.name)
+			}
+			continue
+		}
+		res.Middlewares = f.middlewares(res.Middlewares)
+		kept = append(kept, res)
+	}
+	return kept
+}
+
+// middlewares drops the middlewares of other environments
+func (f *environmentFilter) middlewares(middlewares []middlewareWithPriority) []middlewareWithPriority {
+	if f == nil {
+		return middlewares
+	}
+	var kept []middlewareWithPriority
+	for _, mw := range middlewares {
+		if f.includes(models.EnvironmentTargetMiddleware, mw.ID) {
+			kept = append(kept, mw)
+		}
+	}
+	return kept
+}
+
+// cachedEnvironmentConfig is the merged config of a named environment
+type cachedEnvironmentConfig struct {
+	config *ProxiedTraefikConfig
+	expiry time.Time
+}
+
+// GetEnvironmentConfig returns the merged config of an environment. The
+// default environment shares the plain config endpoint's cache, validation
+// fallback and change log; the others are merged and cached on their own.
+func (cp *ConfigProxy) GetEnvironmentConfig(name string) (*ProxiedTraefikConfig, error) {
+	name = models.NormalizeEnvironmentName(name)
+	var isDefault bool
+	err := cp.db.QueryRow("SELECT is_default FROM environments WHERE name = ?", name).Scan(&isDefault)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEnvironment, name)
+	} else if err != nil {
+		return nil, err
+	}
+	if isDefault {
+		return cp.GetMergedConfig()
+	}
+
+	cp.cacheMutex.RLock()
+	cached := cp.environments[name]
+	cp.cacheMutex.RUnlock()
+	if cached.config != nil && time.Now().Before(cached.expiry) {
+		return cached.config, nil
+	}
+
+	result, err, _ := cp.refreshGroup.Do(environmentRefreshKey(name), func() (interface{}, error) {
+		return cp.refreshEnvironmentConfig(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*ProxiedTraefikConfig), nil
+}
+
+// refreshEnvironmentConfig merges a fresh config for a named environment.
+// Over the error budget it keeps serving the environment's previous config.
+func (cp *ConfigProxy) refreshEnvironmentConfig(name string) (*ProxiedTraefikConfig, error) {
+	cp.cacheMutex.RLock()
+	cached := cp.environments[name]
+	sections, limits, errorBudget := cp.sections, cp.limits, cp.errorBudget
+	cp.cacheMutex.RUnlock()
+	if cached.config != nil && time.Now().Before(cached.expiry) {
+		return cached.config, nil
+	}
+
+	config, err := cp.fetchPangolinConfigGuarded()
+	if err != nil {
+		if cached.config != nil {
+			if !errors.Is(err, ErrPangolinCircuitOpen) {
+				log.Printf("Warning: Pangolin fetch failed, using stale config of environment %s: %v", name, err)
+			}
+			return cached.config, nil
+		}
+		return nil, fmt.Errorf("failed to fetch Pangolin config: %w", err)
+	}
+
+	merge, err := cp.mergeConfig(config, sections, limits, false, name)
+	if err != nil {
+		return nil, err
+	}
+	served := merge.config
+	if len(merge.validationErrors) > errorBudget && cached.config != nil {
+		log.Printf("ALERT: merged config of environment %s has %d validation error(s) (budget %d); serving its previous config",
+			name, len(merge.validationErrors), errorBudget)
+		served = cached.config
+	}
+
+	cp.cacheMutex.Lock()
+	if cp.environments == nil {
+		cp.environments = map[string]cachedEnvironmentConfig{}
+	}
+	cp.environments[name] = cachedEnvironmentConfig{config: served, expiry: time.Now().Add(cp.cacheDuration)}
+	cp.cacheMutex.Unlock()
+	return served, nil
+}
+
+// expireEnvironments makes the next poll of every named environment merge
+// afresh; callers hold cacheMutex
+func (cp *ConfigProxy) expireEnvironments() {
+	for name := range cp.environments {
+		cp.refreshGroup.Forget(environmentRefreshKey(name))
+	}
+	clear(cp.environments)
+}
+
+func environmentRefreshKey(name string) string {
+	return "environment:" + name
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func routerUses(config *ProxiedTraefikConfig, router, middleware string) bool {
+	r, ok := config.HTTP.Routers[router]
+	if !ok {
+		return false
+	}
+	for _, mw := range r.Middlewares {
+		if strings.TrimSuffix(mw, "@file") == middleware {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConfigProxyServesEnvironments(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+	envs := NewEnvironmentService(db)
+
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES
+		('mw-strict', 'rl-strict', 'rateLimit', '{"average": 10}'),
+		('mw-relaxed', 'rl-relaxed', 'rateLimit', '{"average": 1000}'),
+		('mw-gzip', 'gzip', 'compress', '{}')`); err != nil {
+		t.Fatalf("insert middlewares: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status) VALUES
+		('res-1', 'app-router', 'app.example.com', 'svc', 'org', 'site', 'active'),
+		('res-2', 'beta-router', 'beta.example.com', 'svc', 'org', 'site', 'active')`); err != nil {
+		t.Fatalf("insert resources: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES
+		('res-1', 'mw-strict', 100), ('res-1', 'mw-relaxed', 100), ('res-1', 'mw-gzip', 50),
+		('res-2', 'mw-gzip', 50)`); err != nil {
+		t.Fatalf("insert assignments: %v", err)
+	}
+
+	for _, env := range []models.Environment{{Name: "prod", IsDefault: true}, {Name: "staging"}} {
+		if _, err := envs.Create(env); err != nil {
+			t.Fatalf("create environment %s: %v", env.Name, err)
+		}
+	}
+	if _, err := envs.Create(models.Environment{Name: "prod"}); !errors.Is(err, ErrEnvironmentExists) {
+		t.Fatalf("expected ErrEnvironmentExists, got %v", err)
+	}
+	tag := func(targetType, id string, names ...string) {
+		t.Helper()
+		if _, err := envs.SetTags(targetType, id, names); err != nil {
+			t.Fatalf("tag %s %s: %v", targetType, id, err)
+		}
+	}
+	tag(models.EnvironmentTargetMiddleware, "mw-strict", "prod")
+	tag(models.EnvironmentTargetMiddleware, "mw-relaxed", "staging")
+	tag(models.EnvironmentTargetResource, "res-2", "staging")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"app-router":  map[string]interface{}{"rule": "Host(`app.example.com`)", "service": "svc"},
+					"beta-router": map[string]interface{}{"rule": "Host(`beta.example.com`)", "service": "svc"},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	prod, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if !routerUses(prod, "app-router", "rl-strict") || routerUses(prod, "app-router", "rl-relaxed") ||
+		!routerUses(prod, "app-router", "gzip") {
+		t.Errorf("prod app-router middlewares = %v", prod.HTTP.Routers["app-router"].Middlewares)
+	}
+	if _, ok := prod.HTTP.Middlewares["rl-relaxed"]; ok {
+		t.Errorf("staging middleware rendered in prod")
+	}
+	if len(prod.HTTP.Routers["beta-router"].Middlewares) != 0 {
+		t.Errorf("staging resource changed in prod: %v", prod.HTTP.Routers["beta-router"].Middlewares)
+	}
+
+	// The default environment shares the plain endpoint's config
+	byName, err := cp.GetEnvironmentConfig("PROD")
+	if err != nil || byName != prod {
+		t.Fatalf("GetEnvironmentConfig(prod) = %p, %v; want the default config %p", byName, err, prod)
+	}
+
+	staging, err := cp.GetEnvironmentConfig("staging")
+	if err != nil {
+		t.Fatalf("GetEnvironmentConfig(staging) error = %v", err)
+	}
+	if routerUses(staging, "app-router", "rl-strict") || !routerUses(staging, "app-router", "rl-relaxed") {
+		t.Errorf("staging app-router middlewares = %v", staging.HTTP.Routers["app-router"].Middlewares)
+	}
+	if !routerUses(staging, "beta-router", "gzip") {
+		t.Errorf("staging beta-router middlewares = %v", staging.HTTP.Routers["beta-router"].Middlewares)
+	}
+	if cached, _ := cp.GetEnvironmentConfig("staging"); cached != staging {
+		t.Errorf("expected the staging config to be cached")
+	}
+
+	if _, err := cp.GetEnvironmentConfig("qa"); !errors.Is(err, ErrUnknownEnvironment) {
+		t.Fatalf("expected ErrUnknownEnvironment, got %v", err)
+	}
+}
+
+func TestEnvironmentServiceTags(t *testing.T) {
+	db := newTestDB(t)
+	envs := NewEnvironmentService(db)
+
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'gzip', 'compress', '{}')`); err != nil {
+		t.Fatalf("insert middleware: %v", err)
+	}
+	for _, name := range []string{"dev", "prod"} {
+		if _, err := envs.Create(models.Environment{Name: name, IsDefault: true}); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+	if dev, _ := envs.Get("dev"); dev.IsDefault {
+		t.Errorf("creating prod as default should clear dev's default flag")
+	}
+
+	if _, err := envs.SetTags(models.EnvironmentTargetMiddleware, "mw-1", []string{"qa"}); !errors.Is(err, ErrUnknownEnvironment) {
+		t.Fatalf("expected ErrUnknownEnvironment, got %v", err)
+	}
+	if _, err := envs.SetTags(models.EnvironmentTargetMiddleware, "missing", nil); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows for a missing middleware, got %v", err)
+	}
+
+	tags, err := envs.SetTags(models.EnvironmentTargetMiddleware, "mw-1", []string{" Prod", "dev", "prod"})
+	if err != nil {
+		t.Fatalf("SetTags() error = %v", err)
+	}
+	if strings.Join(tags.Environments, ",") != "dev,prod" {
+		t.Errorf("tags = %v, want dev,prod", tags.Environments)
+	}
+	if prod, _ := envs.Get("prod"); prod.Middlewares != 1 {
+		t.Errorf("prod middlewares = %d, want 1", prod.Middlewares)
+	}
+
+	if err := envs.Delete("dev"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	all, err := envs.AllTags()
+	if err != nil || len(all) != 1 || strings.Join(all[0].Environments, ",") != "prod" {
+		t.Fatalf("AllTags() = %+v, %v; want mw-1 in prod only", all, err)
+	}
+}
+
+func TestEnvironmentServiceResourceTagsCheckVersion(t *testing.T) {
+	db := newTestDB(t)
+	envs := NewEnvironmentService(db)
+
+	if _, err := db.Exec(`INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app.example.com', 'svc', 'org', 'site', 'active')`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	if _, err := envs.Create(models.Environment{Name: "prod"}); err != nil {
+		t.Fatalf("create prod: %v", err)
+	}
+
+	if _, err := envs.SetResourceTags("res-1", 2, []string{"prod"}); !errors.Is(err, ErrResourceVersionConflict) {
+		t.Fatalf("expected ErrResourceVersionConflict for a stale version, got %v", err)
+	}
+	if tags, _ := envs.Tags(models.EnvironmentTargetResource, "res-1"); len(tags.Environments) != 0 {
+		t.Fatalf("a stale edit must not tag the resource, got %v", tags.Environments)
+	}
+
+	if _, err := envs.SetResourceTags("res-1", 1, []string{"prod"}); err != nil {
+		t.Fatalf("SetResourceTags() error = %v", err)
+	}
+	var version int
+	if err := db.QueryRow("SELECT version FROM resources WHERE id = 'res-1'").Scan(&version); err != nil || version != 2 {
+		t.Errorf("version = %d (%v), want 2", version, err)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"log"
+	"strings"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// loadFailovers attaches the failover configured for each resource
+func (cp *ConfigProxy) loadFailovers(resourceMap map[string]*resourceData) error {
+	rows, err := cp.db.Query(`
+		SELECT resource_id, fallback_url, fallback_service, health_check_path, health_check_interval, health_check_timeout
+		FROM resource_failovers
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var failover models.ResourceFailover
+		if err := rows.Scan(&failover.ResourceID, &failover.FallbackURL, &failover.FallbackService,
+			&failover.HealthCheckPath, &failover.HealthCheckInterval, &failover.HealthCheckTimeout); err != nil {
+			log.Printf("Failed to scan resource failover: %v", err)
+			continue
+		}
+		data, ok := resourceMap[failover.ResourceID]
+		if !ok {
+			continue
+		}
+		failover.Normalize()
+		if err := failover.Validate(); err != nil {
+			log.Printf("Skipping invalid failover for resource %s: %v", failover.ResourceID, err)
+			continue
+		}
+		data.Failover = &failover
+	}
+
+	return rows.Err()
+}
+
+// applyFailovers points the router of each resource with a failover at a
+// failover service. The primary is a copy of the router's loadBalancer
+// service with a health check, since Traefik only fails over on failing
+// health checks; the original service is left untouched, so removing the
+// failover restores it.
+func (cp *ConfigProxy) applyFailovers(config *ProxiedTraefikConfig, resources []*resourceData) {
+	for _, resource := range resources {
+		if resource.Failover == nil {
+			continue
+		}
+
+		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, router = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+		if routerKey == "" || router.Service == "" {
+			log.Printf("Failover configured for resource %s but no router with a service matched; skipping", resource.ID)
+			continue
+		}
+		service, _ := config.HTTP.Services[strings.TrimSuffix(router.Service, "@http")].(map[string]interface{})
+		if _, ok := service["loadBalancer"].(map[string]interface{}); !ok {
+			log.Printf("Failover configured for resource %s but router %s does not use a loadBalancer service of this provider; skipping",
+				resource.ID, routerKey)
+			continue
+		}
+
+		failoverName, primaryName, fallbackName := models.FailoverServiceNames(resource.ID)
+		primary := cloneValueMap(service)
+		lb := primary["loadBalancer"].(map[string]interface{})
+		if _, ok := lb["healthCheck"]; !ok {
+			lb["healthCheck"] = map[string]interface{}{
+				"path":     resource.Failover.HealthCheckPath,
+				"interval": resource.Failover.HealthCheckInterval,
+				"timeout":  resource.Failover.HealthCheckTimeout,
+			}
+		}
+		config.HTTP.Services[primaryName] = primary
+
+		fallback := resource.Failover.FallbackService
+		if resource.Failover.FallbackURL != "" {
+			fallback = fallbackName
+			config.HTTP.Services[fallbackName] = map[string]interface{}{
+				"loadBalancer": map[string]interface{}{
+					"servers": []interface{}{
+						map[string]interface{}{"url": resource.Failover.FallbackURL},
+					},
+				},
+			}
+		}
+		config.HTTP.Services[failoverName] = map[string]interface{}{
+			"failover": map[string]interface{}{
+				"service":  primaryName,
+				"fallback": fallback,
+			},
+		}
+		router.Service = failoverName
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestApplyFailovers(t *testing.T) {
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), "")
+
+	appService := map[string]interface{}{"loadBalancer": map[string]interface{}{
+		"servers": []interface{}{map[string]interface{}{"url": "http://app:80"}},
+	}}
+	config := &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+		Routers: map[string]*OrderedRouter{
+			"app-router":  {Rule: "Host(`app.example.com`)", Service: "app-service"},
+			"docs-router": {Rule: "Host(`docs.example.com`)", Service: "docs-service@http"},
+		},
+		Services: map[string]interface{}{
+			"app-service":  appService,
+			"docs-service": map[string]interface{}{"loadBalancer": map[string]interface{}{"healthCheck": map[string]interface{}{"path": "/ready"}}},
+		},
+	}}
+	failover := func(f models.ResourceFailover) *models.ResourceFailover {
+		f.Normalize()
+		return &f
+	}
+	resources := []*resourceData{
+		{ID: "res-1", PangolinRouterID: "app-router", Host: "app.example.com",
+			Failover: failover(models.ResourceFailover{FallbackURL: "http://standby:8080", HealthCheckPath: "/health"})},
+		{ID: "res-2", PangolinRouterID: "docs-router", Host: "docs.example.com",
+			Failover: failover(models.ResourceFailover{FallbackService: "maintenance@file"})},
+	}
+
+	cp.applyFailovers(config, resources)
+
+	if got := config.HTTP.Routers["app-router"].Service; got != "res-1-failover" {
+		t.Fatalf("router service = %s, want res-1-failover", got)
+	}
+	if _, ok := appService["loadBalancer"].(map[string]interface{})["healthCheck"]; ok {
+		t.Errorf("original service must not be modified")
+	}
+	primary := config.HTTP.Services["res-1-failover-primary"].(map[string]interface{})["loadBalancer"].(map[string]interface{})
+	wantCheck := map[string]interface{}{"path": "/health", "interval": "10s", "timeout": "3s"}
+	if !reflect.DeepEqual(primary["healthCheck"], wantCheck) {
+		t.Errorf("primary health check = %v, want %v", primary["healthCheck"], wantCheck)
+	}
+	wantFailover := map[string]interface{}{"failover": map[string]interface{}{
+		"service": "res-1-failover-primary", "fallback": "res-1-failover-fallback",
+	}}
+	if got := config.HTTP.Services["res-1-failover"]; !reflect.DeepEqual(got, wantFailover) {
+		t.Errorf("failover service = %v, want %v", got, wantFailover)
+	}
+
+	// An upstream health check is kept and an existing fallback service used as is
+	docsPrimary := config.HTTP.Services["res-2-failover-primary"].(map[string]interface{})["loadBalancer"].(map[string]interface{})
+	if docsPrimary["healthCheck"].(map[string]interface{})["path"] != "/ready" {
+		t.Errorf("upstream health check replaced: %v", docsPrimary["healthCheck"])
+	}
+	if got := config.HTTP.Services["res-2-failover"].(map[string]interface{})["failover"].(map[string]interface{})["fallback"]; got != "maintenance@file" {
+		t.Errorf("fallback = %v, want maintenance@file", got)
+	}
+	if _, ok := config.HTTP.Services["res-2-failover-fallback"]; ok {
+		t.Errorf("no fallback service should be generated for fallback_service")
+	}
+}
@@ -0,0 +1,461 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+)
+
+// Forward-auth token types
+const (
+	ForwardAuthTokenBearer = "bearer"
+	ForwardAuthTokenTOTP   = "totp"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is the number of periods accepted either side of now to tolerate clock drift
+	totpSkew = 1
+)
+
+// ForwardAuthSessionCookie holds the session issued after a TOTP code is
+// accepted, so the code is not needed on every request
+const ForwardAuthSessionCookie = "mm_forward_auth"
+
+// Failed TOTP codes are limited per client IP and per resource within a window
+const (
+	forwardAuthSessionTTL       = time.Hour
+	forwardAuthFailureWindow    = 5 * time.Minute
+	forwardAuthFailuresPerIP    = 5
+	forwardAuthFailuresResource = 20
+)
+
+// ErrForwardAuthThrottled is returned while too many TOTP codes failed
+var ErrForwardAuthThrottled = errors.New("too many failed TOTP codes")
+
+// ForwardAuthService issues and verifies credentials for the built-in forwardAuth endpoint.
+// Bearer tokens are stored as SHA-256 hashes; TOTP secrets are stored as base32.
+type ForwardAuthService struct {
+	db  *database.DB
+	now func() time.Time
+
+	// sessionKey signs session cookies; it is generated per process, so a
+	// restart ends every session
+	sessionKey []byte
+	byIP       *failureLimiter
+	byResource *failureLimiter
+}
+
+// ForwardAuthResult is the outcome of a forward-auth check
+type ForwardAuthResult struct {
+	OK bool
+	// Session is set when a TOTP code was accepted and holds the value of
+	// the session cookie to issue
+	Session        string
+	SessionExpires time.Time
+}
+
+// ForwardAuthCredential is returned once when a token is created
+type ForwardAuthCredential struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Token      string `json:"token,omitempty"`
+	TOTPSecret string `json:"totp_secret,omitempty"`
+	TOTPURI    string `json:"totp_uri,omitempty"`
+}
+
+// NewForwardAuthService creates a new forward-auth service
+func NewForwardAuthService(db *database.DB) *ForwardAuthService {
+	key, err := randomBytes(32)
+	if err != nil {
+		// Sessions are then never issued; codes keep working
+		log.Printf("Failed to generate the forward-auth session key: %v", err)
+	}
+	return &ForwardAuthService{
+		db:         db,
+		now:        time.Now,
+		sessionKey: key,
+		byIP:       newFailureLimiter(forwardAuthFailuresPerIP, forwardAuthFailureWindow),
+		byResource: newFailureLimiter(forwardAuthFailuresResource, forwardAuthFailureWindow),
+	}
+}
+
+// CreateToken creates a bearer token or TOTP secret for a resource
+func (s *ForwardAuthService) CreateToken(id, resourceID, host, name, tokenType string) (*ForwardAuthCredential, error) {
+	cred, tokenHash, totpSecret, err := newForwardAuthCredential(id, host, name, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO forward_auth_tokens (id, resource_id, name, type, token_hash, totp_secret)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, resourceID, name, tokenType, tokenHash, totpSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return cred, nil
+}
+
+// RotateToken replaces a token's secret. When grace is positive the previous
+// secret keeps working until the grace period ends.
+func (s *ForwardAuthService) RotateToken(resourceID, tokenID, host string, grace time.Duration) (*ForwardAuthCredential, error) {
+	var name, tokenType, tokenHash, totpSecret string
+	err := s.db.QueryRow(
+		"SELECT name, type, token_hash, totp_secret FROM forward_auth_tokens WHERE id = ? AND resource_id = ?",
+		tokenID, resourceID,
+	).Scan(&name, &tokenType, &tokenHash, &totpSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, newHash, newSecret, err := newForwardAuthCredential(tokenID, host, name, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	var previousHash, previousSecret string
+	var previousExpires interface{}
+	if grace > 0 {
+		previousHash, previousSecret = tokenHash, totpSecret
+		previousExpires = s.now().Add(grace)
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE forward_auth_tokens
+		SET token_hash = ?, totp_secret = ?,
+		    previous_token_hash = ?, previous_totp_secret = ?, previous_expires_at = ?
+		WHERE id = ?
+	`, newHash, newSecret, previousHash, previousSecret, previousExpires, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate token: %w", err)
+	}
+
+	return cred, nil
+}
+
+// Verify reports whether credential is valid for the resource. The credential is
+// either a bearer token or a current TOTP code for one of the resource's secrets.
+// A TOTP code is accepted once; the result then carries a session to issue.
+// Failed codes from clientIP or for the resource return ErrForwardAuthThrottled
+// once they pass the limits.
+func (s *ForwardAuthService) Verify(resourceID, credential, clientIP string) (*ForwardAuthResult, error) {
+	credential = strings.TrimSpace(credential)
+	if resourceID == "" || credential == "" {
+		return &ForwardAuthResult{}, nil
+	}
+
+	now := s.now()
+	isCode := isTOTPCode(credential)
+	if isCode && (s.byIP.blocked(clientIP, now) || s.byResource.blocked(resourceID, now)) {
+		return nil, ErrForwardAuthThrottled
+	}
+
+	if enabled, err := s.forwardAuthEnabled(resourceID); err != nil || !enabled {
+		return &ForwardAuthResult{}, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, type, token_hash, totp_secret,
+		       COALESCE(previous_token_hash, ''), COALESCE(previous_totp_secret, ''), previous_expires_at
+		FROM forward_auth_tokens WHERE resource_id = ?
+	`, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidateHash := hashToken(credential)
+	// matches returns the TOTP step a code matched, or 0 for a bearer token
+	matches := func(typ, tokenHash, totpSecret string) (int64, bool) {
+		switch typ {
+		case ForwardAuthTokenBearer:
+			return 0, tokenHash != "" && subtle.ConstantTimeCompare([]byte(candidateHash), []byte(tokenHash)) == 1
+		case ForwardAuthTokenTOTP:
+			if totpSecret == "" {
+				return 0, false
+			}
+			return totpStep(totpSecret, credential, now)
+		}
+		return 0, false
+	}
+
+	matchedID, matchedType, matchedSecret := "", "", ""
+	var matchedStep int64
+	for rows.Next() {
+		var id, typ, tokenHash, totpSecret, previousHash, previousSecret string
+		var previousExpires sql.NullTime
+		if err := rows.Scan(&id, &typ, &tokenHash, &totpSecret, &previousHash, &previousSecret, &previousExpires); err != nil {
+			log.Printf("Failed to scan forward-auth token: %v", err)
+			continue
+		}
+		if step, ok := matches(typ, tokenHash, totpSecret); ok {
+			matchedID, matchedType, matchedSecret, matchedStep = id, typ, totpSecret, step
+			break
+		}
+		// Previous secret stays valid during a rotation grace period
+		if previousExpires.Valid && now.Before(previousExpires.Time) {
+			if step, ok := matches(typ, previousHash, previousSecret); ok {
+				matchedID, matchedType, matchedSecret, matchedStep = id, typ, totpSecret, step
+				break
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	result := &ForwardAuthResult{}
+	if matchedType == ForwardAuthTokenTOTP {
+		// Steps only move forward, so a code, or an older one, is accepted once
+		res, err := s.db.Exec(`
+			UPDATE forward_auth_tokens SET totp_last_step = ?, last_used_at = ?
+			WHERE id = ? AND COALESCE(totp_last_step, -1) < ?
+		`, matchedStep, now, matchedID, matchedStep)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			log.Printf("Rejected a reused TOTP code for resource %s", resourceID)
+			matchedID = ""
+		} else if s.sessionKey != nil {
+			result.SessionExpires = now.Add(forwardAuthSessionTTL)
+			result.Session = s.signSession(resourceID, matchedID, matchedSecret, result.SessionExpires)
+		}
+	} else if matchedID != "" {
+		if _, err := s.db.Exec("UPDATE forward_auth_tokens SET last_used_at = ? WHERE id = ?", now, matchedID); err != nil {
+			log.Printf("Failed to record forward-auth token use: %v", err)
+		}
+	}
+
+	if matchedID == "" {
+		if isCode {
+			s.byIP.fail(clientIP, now)
+			s.byResource.fail(resourceID, now)
+		}
+		return result, nil
+	}
+	result.OK = true
+	return result, nil
+}
+
+// VerifySession reports whether a session cookie issued by Verify is valid
+// for the resource. Rotating or deleting the TOTP secret it was issued for
+// ends the session.
+func (s *ForwardAuthService) VerifySession(resourceID, session string) (bool, error) {
+	parts := strings.Split(session, ".")
+	if s.sessionKey == nil || resourceID == "" || len(parts) != 3 {
+		return false, nil
+	}
+	tokenID := parts[0]
+	expiresUnix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	expires := time.Unix(expiresUnix, 0)
+	if !s.now().Before(expires) {
+		return false, nil
+	}
+
+	if enabled, err := s.forwardAuthEnabled(resourceID); err != nil || !enabled {
+		return false, err
+	}
+	var totpSecret string
+	err = s.db.QueryRow(
+		"SELECT totp_secret FROM forward_auth_tokens WHERE id = ? AND resource_id = ? AND type = ?",
+		tokenID, resourceID, ForwardAuthTokenTOTP,
+	).Scan(&totpSecret)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	expected := s.signSession(resourceID, tokenID, totpSecret, expires)
+	return hmac.Equal([]byte(expected), []byte(session)), nil
+}
+
+// forwardAuthEnabled reports whether the resource is active with forward auth on
+func (s *ForwardAuthService) forwardAuthEnabled(resourceID string) (bool, error) {
+	var enabled int
+	err := s.db.QueryRow(
+		"SELECT COALESCE(forward_auth_enabled, 0) FROM resources WHERE id = ? AND status = 'active'", resourceID,
+	).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return enabled != 0, nil
+}
+
+// signSession returns a session cookie value: the token ID, the expiry and an
+// HMAC over both, the resource and the token's current TOTP secret
+func (s *ForwardAuthService) signSession(resourceID, tokenID, totpSecret string, expires time.Time) string {
+	payload := tokenID + "." + strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, s.sessionKey)
+	mac.Write([]byte(resourceID + "\x00" + payload + "\x00" + totpSecret))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newForwardAuthCredential generates a new secret, returning the credential shown
+// to the user along with the values to persist
+func newForwardAuthCredential(id, host, name, tokenType string) (*ForwardAuthCredential, string, string, error) {
+	cred := &ForwardAuthCredential{ID: id, Name: name, Type: tokenType}
+
+	switch tokenType {
+	case ForwardAuthTokenBearer:
+		raw, err := randomBytes(32)
+		if err != nil {
+			return nil, "", "", err
+		}
+		cred.Token = "mmfa_" + hex.EncodeToString(raw)
+		return cred, hashToken(cred.Token), "", nil
+	case ForwardAuthTokenTOTP:
+		raw, err := randomBytes(20)
+		if err != nil {
+			return nil, "", "", err
+		}
+		secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+		cred.TOTPSecret = secret
+		cred.TOTPURI = fmt.Sprintf("otpauth://totp/%s?secret=%s&issuer=%s&period=%d&digits=%d",
+			url.PathEscape("Middleware Manager:"+host+" ("+name+")"), secret,
+			url.QueryEscape("Middleware Manager"), totpPeriod, totpDigits)
+		return cred, "", secret, nil
+	}
+	return nil, "", "", fmt.Errorf("unsupported token type: %s", tokenType)
+}
+
+// ValidateTOTP checks an RFC 6238 code (SHA-1, 30s period, 6 digits) against a base32 secret
+func ValidateTOTP(secret, code string, at time.Time) bool {
+	_, ok := totpStep(secret, code, at)
+	return ok
+}
+
+// totpStep returns the time step a valid code belongs to
+func totpStep(secret, code string, at time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return 0, false
+	}
+
+	counter := at.Unix() / totpPeriod
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, uint64(counter+offset), totpDigits)), []byte(code)) == 1 {
+			return counter + offset, true
+		}
+	}
+	return 0, false
+}
+
+// isTOTPCode reports whether a credential has the form of a TOTP code
+func isTOTPCode(credential string) bool {
+	if len(credential) != totpDigits {
+		return false
+	}
+	for _, r := range credential {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// totpCode computes the HOTP value for a counter (RFC 4226)
+func totpCode(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// failureLimiter counts failures per key in fixed windows
+type failureLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	failures map[string]*failureCount
+}
+
+type failureCount struct {
+	count int
+	start time.Time
+}
+
+func newFailureLimiter(limit int, window time.Duration) *failureLimiter {
+	return &failureLimiter{limit: limit, window: window, failures: make(map[string]*failureCount)}
+}
+
+// blocked reports whether key reached the limit in the current window
+func (l *failureLimiter) blocked(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.failures[key]
+	return ok && now.Sub(entry.start) < l.window && entry.count >= l.limit
+}
+
+// fail records a failure for key
+func (l *failureLimiter) fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.failures[key]
+	if !ok || now.Sub(entry.start) >= l.window {
+		// Drop expired windows once many keys are held, so the map stays bounded
+		if len(l.failures) >= 1024 {
+			for k, e := range l.failures {
+				if now.Sub(e.start) >= l.window {
+					delete(l.failures, k)
+				}
+			}
+		}
+		entry = &failureCount{start: now}
+		l.failures[key] = entry
+	}
+	entry.count++
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// verify calls Verify from a fixed client IP
+func verify(svc *ForwardAuthService, resourceID, credential string) (bool, error) {
+	result, err := svc.Verify(resourceID, credential, "192.0.2.1")
+	if err != nil {
+		return false, err
+	}
+	return result.OK, nil
+}
+
+func TestTOTPCodeRFC6238Vector(t *testing.T) {
+	// RFC 6238 Appendix B, SHA-1 key "12345678901234567890", T = 59s => 94287082
+	key := []byte("12345678901234567890")
+	if got := totpCode(key, 59/30, 8); got != "94287082" {
+		t.Fatalf("totpCode() = %s, want 94287082", got)
+	}
+
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	at := time.Unix(59, 0)
+	if !ValidateTOTP(secret, "287082", at) {
+		t.Error("ValidateTOTP() rejected the current code")
+	}
+	if !ValidateTOTP(secret, "287082", at.Add(30*time.Second)) {
+		t.Error("ValidateTOTP() should accept the previous period to tolerate drift")
+	}
+	if ValidateTOTP(secret, "287082", at.Add(5*time.Minute)) {
+		t.Error("ValidateTOTP() accepted a stale code")
+	}
+	if ValidateTOTP(secret, "12345", at) {
+		t.Error("ValidateTOTP() accepted a short code")
+	}
+}
+
+func TestForwardAuthServiceVerify(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`INSERT INTO resources (id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-1', 'tool.example.com', 'svc', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	svc := NewForwardAuthService(db)
+	bearer, err := svc.CreateToken("tok-1", "res-1", "tool.example.com", "ci", ForwardAuthTokenBearer)
+	if err != nil {
+		t.Fatalf("CreateToken(bearer) error = %v", err)
+	}
+	totp, err := svc.CreateToken("tok-2", "res-1", "tool.example.com", "phone", ForwardAuthTokenTOTP)
+	if err != nil {
+		t.Fatalf("CreateToken(totp) error = %v", err)
+	}
+
+	var stored string
+	if err := db.QueryRow("SELECT token_hash FROM forward_auth_tokens WHERE id = 'tok-1'").Scan(&stored); err != nil {
+		t.Fatalf("query token: %v", err)
+	}
+	if stored == bearer.Token {
+		t.Fatal("bearer token must not be stored in plaintext")
+	}
+
+	if ok, err := verify(svc, "res-1", bearer.Token); err != nil || !ok {
+		t.Fatalf("Verify(bearer) = %v, %v; want true", ok, err)
+	}
+	if ok, _ := verify(svc, "res-1", "mmfa_wrong"); ok {
+		t.Fatal("Verify() accepted an unknown token")
+	}
+	if ok, _ := verify(svc, "other", bearer.Token); ok {
+		t.Fatal("Verify() accepted a token for a different resource")
+	}
+
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(totp.TOTPSecret)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	code := totpCode(key, uint64(now.Unix()/totpPeriod), totpDigits)
+	if ok, err := verify(svc, "res-1", code); err != nil || !ok {
+		t.Fatalf("Verify(totp) = %v, %v; want true", ok, err)
+	}
+
+	if _, err := db.Exec("UPDATE resources SET forward_auth_enabled = 0 WHERE id = 'res-1'"); err != nil {
+		t.Fatalf("disable forward auth: %v", err)
+	}
+	if ok, _ := verify(svc, "res-1", bearer.Token); ok {
+		t.Fatal("Verify() accepted a token while forward auth is disabled")
+	}
+}
+
+func TestForwardAuthRotateTokenGracePeriod(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`INSERT INTO resources (id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-1', 'tool.example.com', 'svc', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	svc := NewForwardAuthService(db)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	old, err := svc.CreateToken("tok-1", "res-1", "tool.example.com", "ci", ForwardAuthTokenBearer)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	rotated, err := svc.RotateToken("res-1", "tok-1", "tool.example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	if rotated.Token == old.Token {
+		t.Fatal("RotateToken() returned the old token")
+	}
+
+	if ok, _ := verify(svc, "res-1", rotated.Token); !ok {
+		t.Fatal("Verify() rejected the rotated token")
+	}
+	if ok, _ := verify(svc, "res-1", old.Token); !ok {
+		t.Fatal("Verify() rejected the old token during the grace period")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if ok, _ := verify(svc, "res-1", old.Token); ok {
+		t.Fatal("Verify() accepted the old token after the grace period")
+	}
+
+	// Rotating without grace invalidates the previous token immediately
+	again, err := svc.RotateToken("res-1", "tok-1", "tool.example.com", 0)
+	if err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	if ok, _ := verify(svc, "res-1", rotated.Token); ok {
+		t.Fatal("Verify() accepted a token rotated without grace")
+	}
+	if ok, _ := verify(svc, "res-1", again.Token); !ok {
+		t.Fatal("Verify() rejected the newest token")
+	}
+}
+
+func TestForwardAuthTOTPReplayAndSession(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`INSERT INTO resources (id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-1', 'tool.example.com', 'svc', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	svc := NewForwardAuthService(db)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	totp, err := svc.CreateToken("tok-1", "res-1", "tool.example.com", "phone", ForwardAuthTokenTOTP)
+	if err != nil {
+		t.Fatalf("CreateToken(totp) error = %v", err)
+	}
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(totp.TOTPSecret)
+	step := now.Unix() / totpPeriod
+
+	result, err := svc.Verify("res-1", totpCode(key, uint64(step), totpDigits), "192.0.2.1")
+	if err != nil || !result.OK || result.Session == "" {
+		t.Fatalf("Verify(totp) = %+v, %v; want a session", result, err)
+	}
+	if result, _ := svc.Verify("res-1", totpCode(key, uint64(step), totpDigits), "192.0.2.2"); result.OK {
+		t.Fatal("Verify() accepted a code twice")
+	}
+	if result, _ := svc.Verify("res-1", totpCode(key, uint64(step-1), totpDigits), "192.0.2.2"); result.OK {
+		t.Fatal("Verify() accepted a code older than the last one used")
+	}
+
+	if ok, err := svc.VerifySession("res-1", result.Session); err != nil || !ok {
+		t.Fatalf("VerifySession() = %v, %v; want true", ok, err)
+	}
+	if ok, _ := svc.VerifySession("res-2", result.Session); ok {
+		t.Fatal("VerifySession() accepted a session for another resource")
+	}
+	if ok, _ := svc.VerifySession("res-1", result.Session+"0"); ok {
+		t.Fatal("VerifySession() accepted a tampered session")
+	}
+	now = now.Add(forwardAuthSessionTTL)
+	if ok, _ := svc.VerifySession("res-1", result.Session); ok {
+		t.Fatal("VerifySession() accepted an expired session")
+	}
+	now = now.Add(-time.Minute)
+	if _, err := svc.RotateToken("res-1", "tok-1", "tool.example.com", 0); err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	if ok, _ := svc.VerifySession("res-1", result.Session); ok {
+		t.Fatal("VerifySession() accepted a session after the secret was rotated")
+	}
+}
+
+func TestForwardAuthTOTPThrottling(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`INSERT INTO resources (id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-1', 'tool.example.com', 'svc', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	svc := NewForwardAuthService(db)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	bearer, err := svc.CreateToken("tok-1", "res-1", "tool.example.com", "ci", ForwardAuthTokenBearer)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	totp, err := svc.CreateToken("tok-2", "res-1", "tool.example.com", "phone", ForwardAuthTokenTOTP)
+	if err != nil {
+		t.Fatalf("CreateToken(totp) error = %v", err)
+	}
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(totp.TOTPSecret)
+	valid := totpCode(key, uint64(now.Unix()/totpPeriod), totpDigits)
+	wrong := "000000"
+	if valid == wrong {
+		wrong = "000001"
+	}
+
+	for i := 0; i < forwardAuthFailuresPerIP; i++ {
+		if _, err := svc.Verify("res-1", wrong, "192.0.2.1"); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	if _, err := svc.Verify("res-1", valid, "192.0.2.1"); !errors.Is(err, ErrForwardAuthThrottled) {
+		t.Fatalf("Verify() after %d failures = %v, want ErrForwardAuthThrottled", forwardAuthFailuresPerIP, err)
+	}
+	if result, err := svc.Verify("res-1", bearer.Token, "192.0.2.1"); err != nil || !result.OK {
+		t.Fatalf("bearer tokens must not be throttled: %+v, %v", result, err)
+	}
+
+	// Failures from many addresses add up per resource
+	for i := 0; i < forwardAuthFailuresResource; i++ {
+		svc.Verify("res-1", wrong, fmt.Sprintf("198.51.100.%d", i))
+	}
+	if _, err := svc.Verify("res-1", valid, "203.0.113.1"); !errors.Is(err, ErrForwardAuthThrottled) {
+		t.Fatalf("Verify() after %d failures for the resource = %v, want ErrForwardAuthThrottled", forwardAuthFailuresResource, err)
+	}
+
+	now = now.Add(forwardAuthFailureWindow)
+	valid = totpCode(key, uint64(now.Unix()/totpPeriod), totpDigits)
+	if result, err := svc.Verify("res-1", valid, "192.0.2.1"); err != nil || !result.OK {
+		t.Fatalf("Verify() after the window = %+v, %v; want true", result, err)
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Fuzz targets: malformed upstream payloads must never panic the proxy.
+// Run one with e.g. `go test ./services -run '^$' -fuzz FuzzProxiedConfigDecoder`.
+
+func FuzzOrderedRouterJSON(f *testing.F) {
+	f.Add([]byte(`{"entryPoints":["websecure"],"middlewares":["a","b"],"service":"svc","rule":"Host(` + "`a.example.com`" + `)","priority":100,"tls":{"certResolver":"le","domains":[{"main":"a"}],"options":"x"}}`))
+	f.Add([]byte(`{"entryPoints":"websecure","middlewares":{"a":1},"priority":"high","tls":true}`))
+	f.Add([]byte(`{"entryPoints":[1,null,{}],"tls":{"domains":["a"]},"observability":{"accessLogs":false}}`))
+	f.Add([]byte(`{}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var router OrderedRouter
+		if err := json.Unmarshal(data, &router); err != nil {
+			return
+		}
+		first, err := json.Marshal(&router)
+		if err != nil {
+			t.Fatalf("decoded router does not marshal: %v", err)
+		}
+
+		// Re-decoding the output must be stable
+		var again OrderedRouter
+		if err := json.Unmarshal(first, &again); err != nil {
+			t.Fatalf("marshalled router does not decode: %v\n%s", err, first)
+		}
+		second, err := json.Marshal(&again)
+		if err != nil {
+			t.Fatalf("re-decoded router does not marshal: %v", err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Fatalf("router round trip is unstable:\n%s\n%s", first, second)
+		}
+	})
+}
+
+func FuzzSanitizeMTLSWhitelist(f *testing.F) {
+	f.Add([]byte(`{"http":{"middlewares":{"m":{"plugin":{"mtlswhitelist":{"requestHeaders":"X-Cert"}}}}}}`))
+	f.Add([]byte(`{"http":{"middlewares":{"m":{"plugin":{"mtlswhitelist":{"requestHeaders":{}}}}}}}`))
+	f.Add([]byte(`{"http":{"middlewares":{"m":{"plugin":{"mtlswhitelist":{"requestHeaders":{"X":"[[.Cert]]"},"rules":[]}}}}}}`))
+	f.Add([]byte(`{"http":{"middlewares":{"m":{"plugin":"mtlswhitelist"},"n":null,"o":[1]}}}`))
+
+	cp := &ConfigProxy{}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var config ProxiedTraefikConfig
+		if err := json.Unmarshal(data, &config); err != nil {
+			return
+		}
+		cp.sanitizeMTLSWhitelist(&config)
+
+		// requestHeaders must never survive as anything but a non-empty map
+		if config.HTTP != nil {
+			for name, mw := range config.HTTP.Middlewares {
+				mwMap, _ := mw.(map[string]interface{})
+				plugin, _ := mwMap["plugin"].(map[string]interface{})
+				mtls, _ := plugin["mtlswhitelist"].(map[string]interface{})
+				if rh, exists := mtls["requestHeaders"]; exists {
+					if headers, ok := rh.(map[string]interface{}); !ok || len(headers) == 0 {
+						t.Fatalf("middleware %s kept invalid requestHeaders %#v", name, rh)
+					}
+				}
+			}
+		}
+		if _, err := json.Marshal(&config); err != nil {
+			t.Fatalf("sanitized config does not marshal: %v", err)
+		}
+	})
+}
+
+func FuzzDecodeArrayOrMap(f *testing.F) {
+	f.Add([]byte(`[{"name":"a","provider":"file"}]`))
+	f.Add([]byte(`{"a":{"provider":"file"},"b":{}}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`"string"`))
+	f.Add([]byte(`{"a":null}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		items, err := DecodeArrayOrMap[models.TraefikRouter](data, func(r *models.TraefikRouter, name string) {
+			r.Name = name
+		})
+		if err != nil && items != nil {
+			t.Fatalf("expected no items alongside error %v", err)
+		}
+	})
+}
+
+func FuzzProxiedConfigDecoder(f *testing.F) {
+	f.Add([]byte(`{"http":{"routers":{"r":{"rule":"Host(` + "`a.example.com`" + `)","service":"s","middlewares":["m"]}},"services":{"s":{"loadBalancer":{"servers":[{"url":"http://x"}]}}},"middlewares":{"m":{"headers":{}}}}}`))
+	f.Add([]byte(`{"http":{"routers":{"r":"not-a-router"},"services":[],"middlewares":{"m":{"a":{},"b":{}}}},"tcp":{"routers":{"t":{}}},"udp":null,"tls":{"options":{}}}`))
+	f.Add([]byte(`{"http":{"middlewares":{"c":{"chain":{"middlewares":[1,"missing@http","other@docker"]}}}}}`))
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`[]`))
+
+	cp := &ConfigProxy{}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config, err := cp.decodeProxiedConfig(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		// Everything GetMergedConfig does after fetching, minus the database merge
+		cp.sanitizeMTLSWhitelist(config)
+		cp.pruneEmptySections(config)
+		_ = cp.validateConfig(config)
+		cp.normalizeMiddlewareOrder(config)
+
+		var streamed bytes.Buffer
+		if err := config.WriteJSON(&streamed); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+		if !json.Valid(streamed.Bytes()) {
+			t.Fatalf("WriteJSON() produced invalid JSON: %s", streamed.String())
+		}
+	})
+}
+
+func FuzzPangolinFetcherConvert(f *testing.F) {
+	f.Add([]byte(`{"http":{"routers":{"1-router":{"rule":"Host(` + "`a.example.com`" + `)","service":"s","entryPoints":["websecure"]}}}}`))
+	f.Add([]byte(`{"http":{"routers":{"x-redirect":{"rule":"Host(` + "`" + `"}}}}`))
+	f.Add([]byte(`{"http":{"routers":{"r":{"rule":"Host(` + "``" + `) || PathPrefix(` + "`/`" + `)"}}}}`))
+
+	fetcher := NewPangolinFetcher(models.DataSourceConfig{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var config models.PangolinTraefikConfig
+		if err := json.NewDecoder(io.LimitReader(bytes.NewReader(data), 1<<20)).Decode(&config); err != nil {
+			return
+		}
+		collection := fetcher.convertConfigToResources(&config)
+		for _, resource := range collection.Resources {
+			if resource.Host == "" {
+				t.Fatalf("converted resource %s has no host", resource.ID)
+			}
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"strings"
+)
+
+// generatedMiddlewarePrefix names global middlewares the merge generates,
+// such as the bot blocker, the HTTPS redirect and the shared headers
+const generatedMiddlewarePrefix = "mm-"
+
+// generatedResourceMiddlewares is the ownership registry of per-resource
+// middlewares the merge generates, named "<resource ID><suffix>", with the
+// feature that owns each. Legacy names are kept so leftovers of older
+// releases are collected too.
+var generatedResourceMiddlewares = []struct {
+	suffix  string
+	feature string
+}{
+	{"-mtlsauth", "mtls"},
+	{"-forwardauth", "forward auth"},
+	{"-waf", "waf"},
+	{"-capture", "traffic capture"},
+	{"-headers", "headers (legacy)"},
+	{"-secureheaders", "secure headers (legacy)"},
+	{"-customheaders", "custom headers (legacy)"},
+}
+
+// generatedMiddlewareOwner returns the feature owning a generated middleware
+// name, or "" when the name is not one the merge generates
+func generatedMiddlewareOwner(name string, resourceIDs map[string]bool) string {
+	if strings.HasPrefix(name, generatedMiddlewarePrefix) {
+		return "global"
+	}
+	for _, owned := range generatedResourceMiddlewares {
+		if id := strings.TrimSuffix(name, owned.suffix); id != name && resourceIDs[id] {
+			return owned.feature
+		}
+	}
+	return ""
+}
+
+// collectGeneratedMiddlewares removes generated middlewares from the
+// upstream config before the merge, so only those of features that are
+// still active are rendered again. Stale copies reach the upstream config
+// when a data source reads back Traefik's merged runtime config. It returns
+// the removed names with their owning feature.
+func collectGeneratedMiddlewares(config *ProxiedTraefikConfig, resources []*resourceData) map[string]string {
+	if config.HTTP == nil || len(config.HTTP.Middlewares) == 0 {
+		return nil
+	}
+
+	resourceIDs := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		resourceIDs[resource.ID] = true
+	}
+
+	removed := make(map[string]string)
+	for name := range config.HTTP.Middlewares {
+		if owner := generatedMiddlewareOwner(name, resourceIDs); owner != "" {
+			removed[name] = owner
+			delete(config.HTTP.Middlewares, name)
+		}
+	}
+	return removed
+}
+
+// pruneStaleGeneratedMiddlewares drops references to collected middlewares
+// the merge did not render again, from routers and chain middlewares, and
+// returns the stale names
+func pruneStaleGeneratedMiddlewares(config *ProxiedTraefikConfig, collected map[string]string) []string {
+	if config.HTTP == nil || len(collected) == 0 {
+		return nil
+	}
+
+	stale := make(map[string]bool)
+	for name := range collected {
+		if _, rendered := config.HTTP.Middlewares[name]; !rendered {
+			stale[name] = true
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	isStale := func(ref string) bool {
+		return stale[strings.TrimSuffix(ref, "@http")]
+	}
+	for _, router := range config.HTTP.Routers {
+		if router != nil {
+			router.Middlewares = withoutReferences(router.Middlewares, isStale)
+		}
+	}
+	for _, value := range config.HTTP.Middlewares {
+		mw, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		chain, ok := mw["chain"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		refs, ok := chain["middlewares"].([]interface{})
+		if !ok {
+			continue
+		}
+		kept := make([]interface{}, 0, len(refs))
+		for _, ref := range refs {
+			if name, ok := ref.(string); ok && isStale(name) {
+				continue
+			}
+			kept = append(kept, ref)
+		}
+		chain["middlewares"] = kept
+	}
+
+	names := make([]string, 0, len(stale))
+	for name := range stale {
+		names = append(names, name)
+		log.Printf("Removed stale generated middleware %s (%s)", name, collected[name])
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withoutReferences returns refs without the ones drop matches, keeping nil
+// slices nil
+func withoutReferences(refs []string, drop func(string) bool) []string {
+	if len(refs) == 0 {
+		return refs
+	}
+	kept := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !drop(ref) {
+			kept = append(kept, ref)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
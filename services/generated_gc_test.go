@@ -0,0 +1,92 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeConfig_CollectsStaleGeneratedMiddlewares(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, custom_headers)
+		VALUES ('res-1', 'app-router', 'app.example.com', 's', 'org', 'site', 'active', '{"X-Team":"web"}')`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	// An upstream config read back from Traefik still carries the output of
+	// an earlier merge, when mTLS and bot blocking were on
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"app-router": {
+					Rule:        "Host(`app.example.com`)",
+					Service:     "s",
+					Middlewares: []string{"res-1-mtlsauth", "res-1-secureheaders@http", "mm-bot-block", "badger"},
+				},
+			},
+			Middlewares: map[string]interface{}{
+				"res-1-mtlsauth":      map[string]interface{}{"plugin": map[string]interface{}{}},
+				"res-1-secureheaders": map[string]interface{}{"headers": map[string]interface{}{}},
+				"mm-bot-block":        map[string]interface{}{"plugin": map[string]interface{}{}},
+				"badger":              map[string]interface{}{"plugin": map[string]interface{}{}},
+				"other-headers":       map[string]interface{}{"headers": map[string]interface{}{}},
+				"auth-chain": map[string]interface{}{
+					"chain": map[string]interface{}{"middlewares": []interface{}{"res-1-mtlsauth", "badger"}},
+				},
+			},
+		},
+	}
+
+	if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+		t.Fatalf("mergeMiddlewareManagerConfig: %v", err)
+	}
+
+	for _, name := range []string{"res-1-mtlsauth", "res-1-secureheaders", "mm-bot-block"} {
+		if _, exists := config.HTTP.Middlewares[name]; exists {
+			t.Errorf("stale generated middleware %s was kept", name)
+		}
+	}
+	// Names that only look generated are left alone when no resource owns them
+	for _, name := range []string{"badger", "other-headers"} {
+		if _, exists := config.HTTP.Middlewares[name]; !exists {
+			t.Errorf("upstream middleware %s was removed", name)
+		}
+	}
+
+	for _, ref := range config.HTTP.Routers["app-router"].Middlewares {
+		if ref == "res-1-mtlsauth" || ref == "res-1-secureheaders@http" || ref == "mm-bot-block" {
+			t.Errorf("router still references stale middleware %s: %v", ref, config.HTTP.Routers["app-router"].Middlewares)
+		}
+	}
+	hasHeaders := false
+	for _, ref := range config.HTTP.Routers["app-router"].Middlewares {
+		hasHeaders = hasHeaders || strings.HasPrefix(ref, headersMiddlewarePrefix)
+	}
+	if !hasHeaders {
+		t.Errorf("active headers middleware missing from router: %v", config.HTTP.Routers["app-router"].Middlewares)
+	}
+
+	chain := config.HTTP.Middlewares["auth-chain"].(map[string]interface{})["chain"].(map[string]interface{})
+	if got, want := chain["middlewares"], []interface{}{"badger"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("chain middlewares = %v, want %v", got, want)
+	}
+}
+
+func TestGeneratedMiddlewareOwner(t *testing.T) {
+	resourceIDs := map[string]bool{"res-1": true}
+	tests := map[string]string{
+		"res-1-mtlsauth":        "mtls",
+		"res-1-waf":             "waf",
+		"mm-headers-0123456789": "global",
+		"res-2-mtlsauth":        "",
+		"res-1":                 "",
+		"badger":                "",
+	}
+	for name, want := range tests {
+		if got := generatedMiddlewareOwner(name, resourceIDs); got != want {
+			t.Errorf("generatedMiddlewareOwner(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Prefixes of generated headers middlewares. The rest of the name is a hash
+// of the middleware's content, so resources with identical headers share a
+// single middleware instead of one copy each.
+const (
+	headersMiddlewarePrefix       = "mm-headers-"
+	customHeadersMiddlewarePrefix = "mm-customheaders-"
+)
+
+// contentHashedName returns prefix followed by a short hash of the JSON
+// encoding of content. encoding/json sorts map keys, so equal content always
+// gets the same name.
+func contentHashedName(prefix string, content interface{}) (string, error) {
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return prefix + hex.EncodeToString(sum[:6]), nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeConfig_DeduplicatesHeadersMiddlewares(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	for _, stmt := range []string{
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, custom_headers)
+		 VALUES ('a', 'a', 'a.example.com', 's', 'org', 'site', 'active', '{"X-Team":"web"}')`,
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, custom_headers)
+		 VALUES ('b', 'b', 'b.example.com', 's', 'org', 'site', 'active', '{"X-Team":"web"}')`,
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, custom_headers)
+		 VALUES ('c', 'c', 'c.example.com', 's', 'org', 'site', 'active', '{"X-Team":"ops"}')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{Routers: map[string]*OrderedRouter{}, Middlewares: map[string]interface{}{}},
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		config.HTTP.Routers[id] = &OrderedRouter{Rule: "Host(`" + id + ".example.com`)", Service: "s"}
+	}
+
+	if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+		t.Fatalf("mergeMiddlewareManagerConfig: %v", err)
+	}
+
+	headersMiddleware := func(router string) string {
+		for _, name := range config.HTTP.Routers[router].Middlewares {
+			if strings.HasPrefix(name, headersMiddlewarePrefix) {
+				return name
+			}
+		}
+		t.Fatalf("router %s has no headers middleware: %v", router, config.HTTP.Routers[router].Middlewares)
+		return ""
+	}
+
+	a, b, c := headersMiddleware("a"), headersMiddleware("b"), headersMiddleware("c")
+	if a != b {
+		t.Errorf("identical headers rendered as %s and %s, want one shared middleware", a, b)
+	}
+	if a == c {
+		t.Errorf("different headers share middleware %s", a)
+	}
+
+	rendered := 0
+	for name := range config.HTTP.Middlewares {
+		if strings.HasPrefix(name, headersMiddlewarePrefix) {
+			rendered++
+		}
+	}
+	if rendered != 2 {
+		t.Errorf("rendered %d headers middlewares, want 2", rendered)
+	}
+}
+
+func TestContentHashedName_StableForEqualContent(t *testing.T) {
+	first, err := contentHashedName(headersMiddlewarePrefix, map[string]interface{}{"a": 1, "b": map[string]string{"x": "1", "y": "2"}})
+	if err != nil {
+		t.Fatalf("contentHashedName: %v", err)
+	}
+	second, _ := contentHashedName(headersMiddlewarePrefix, map[string]interface{}{"b": map[string]string{"y": "2", "x": "1"}, "a": 1})
+	if first != second {
+		t.Errorf("names differ for equal content: %s, %s", first, second)
+	}
+	if !strings.HasPrefix(first, headersMiddlewarePrefix) || len(first) != len(headersMiddlewarePrefix)+12 {
+		t.Errorf("unexpected name %s", first)
+	}
+}
@@ -45,7 +45,7 @@ func GetHTTPClient() *http.Client {
 // NewHTTPClient creates a new HTTP client with the given configuration
 func NewHTTPClient(config HTTPClientConfig) *http.Client {
 	transport := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: OutboundProxy,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
@@ -60,7 +60,7 @@ func NewHTTPClient(config HTTPClientConfig) *http.Client {
 	}
 
 	return &http.Client{
-		Transport: transport,
+		Transport: withUserAgent(transport),
 		Timeout:   config.Timeout,
 	}
 }
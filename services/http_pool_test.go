@@ -52,7 +52,7 @@ func TestNewHTTPClient(t *testing.T) {
 		t.Errorf("client.Timeout = %v, want %v", client.Timeout, config.Timeout)
 	}
 
-	transport, ok := client.Transport.(*http.Transport)
+	transport, ok := baseTransport(client.Transport)
 	if !ok {
 		t.Fatal("client.Transport is not *http.Transport")
 	}
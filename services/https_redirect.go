@@ -0,0 +1,245 @@
+package services
+
+import (
+	"database/sql"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/hhftechnology/middleware-manager/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Per-resource HTTP→HTTPS redirect modes
+const (
+	HTTPSRedirectInherit  = "inherit"
+	HTTPSRedirectEnabled  = "enabled"
+	HTTPSRedirectDisabled = "disabled"
+)
+
+// Redirect states reported for each resource after a merge
+const (
+	RedirectStateOff        = "off"        // redirect not requested
+	RedirectStateAdded      = "added"      // MM generated a redirect router
+	RedirectStateUpstream   = "upstream"   // an upstream router already redirects this host
+	RedirectStateEntrypoint = "entrypoint" // the entrypoint redirects in Traefik's static config
+	RedirectStateNoTLS      = "no_tls"     // the router does not serve HTTPS, so redirecting would break it
+	RedirectStateNoRouter   = "no_router"  // no router matched the resource
+)
+
+// httpsRedirectMiddleware is the redirectScheme middleware shared by generated redirect routers
+const httpsRedirectMiddleware = "mm-https-redirect"
+
+// IsValidHTTPSRedirectMode reports whether mode is a known per-resource redirect mode
+func IsValidHTTPSRedirectMode(mode string) bool {
+	switch mode {
+	case HTTPSRedirectInherit, HTTPSRedirectEnabled, HTTPSRedirectDisabled:
+		return true
+	}
+	return false
+}
+
+// redirectConfigData holds the global redirect settings from the database
+type redirectConfigData struct {
+	Enabled    bool
+	EntryPoint string
+	Permanent  bool
+}
+
+// ResourceRedirectStatus reports how the redirect for one resource was resolved
+type ResourceRedirectStatus struct {
+	ResourceID     string `json:"resource_id"`
+	Host           string `json:"host"`
+	Mode           string `json:"mode"`
+	State          string `json:"state"`
+	Router         string `json:"router,omitempty"`
+	RedirectRouter string `json:"redirect_router,omitempty"`
+}
+
+// SetTraefikStaticConfigPath sets the static config checked for entrypoint-level redirections
+func (cp *ConfigProxy) SetTraefikStaticConfigPath(path string) {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.staticConfigPath = path
+}
+
+// RedirectStatus returns the per-resource redirect states from the last merge
+func (cp *ConfigProxy) RedirectStatus() []ResourceRedirectStatus {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+	return append([]ResourceRedirectStatus{}, cp.redirectStatus...)
+}
+
+// loadRedirectConfig reads the global redirect settings, defaulting to disabled
+func (cp *ConfigProxy) loadRedirectConfig() (*redirectConfigData, error) {
+	var enabled, permanent int
+	var entryPoint string
+	err := cp.db.QueryRow(
+		"SELECT enabled, COALESCE(entrypoint, 'web'), permanent FROM redirect_config WHERE id = 1",
+	).Scan(&enabled, &entryPoint, &permanent)
+	if err == sql.ErrNoRows {
+		return &redirectConfigData{EntryPoint: "web", Permanent: true}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if entryPoint == "" {
+		entryPoint = "web"
+	}
+	return &redirectConfigData{Enabled: enabled == 1, EntryPoint: entryPoint, Permanent: permanent == 1}, nil
+}
+
+// applyHTTPSRedirects adds a router on the plain-HTTP entrypoint that redirects
+// to HTTPS for every resource that wants one. Hosts that are already redirected
+// upstream or by the entrypoint itself are left alone to avoid double redirects,
+// and routers without TLS are skipped since redirecting them would loop or 404.
+func (cp *ConfigProxy) applyHTTPSRedirects(config *ProxiedTraefikConfig, resources []*resourceData) {
+	var statuses []ResourceRedirectStatus
+	defer func() {
+		cp.cacheMutex.Lock()
+		cp.redirectStatus = statuses
+		cp.cacheMutex.Unlock()
+	}()
+
+	redirectCfg, err := cp.loadRedirectConfig()
+	if err != nil {
+		log.Printf("Warning: failed to load redirect config: %v", err)
+		return
+	}
+
+	cp.cacheMutex.RLock()
+	staticConfigPath := cp.staticConfigPath
+	cp.cacheMutex.RUnlock()
+	entrypointTarget := EntrypointRedirectTarget(staticConfigPath, redirectCfg.EntryPoint)
+
+	for _, resource := range resources {
+		status := ResourceRedirectStatus{ResourceID: resource.ID, Host: resource.Host, Mode: resource.HTTPSRedirect}
+		if status.Mode == "" {
+			status.Mode = HTTPSRedirectInherit
+		}
+		wanted := status.Mode == HTTPSRedirectEnabled || (status.Mode == HTTPSRedirectInherit && redirectCfg.Enabled)
+
+		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, router = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+
+		switch {
+		case !wanted:
+			status.State = RedirectStateOff
+		case routerKey == "":
+			status.State = RedirectStateNoRouter
+		case entrypointTarget != "":
+			status.State = RedirectStateEntrypoint
+		case router.TLS == nil:
+			status.State = RedirectStateNoTLS
+		default:
+			if existing := findRedirectRouter(config, routerKey, router, redirectCfg.EntryPoint); existing != "" {
+				status.State = RedirectStateUpstream
+				status.RedirectRouter = existing
+				break
+			}
+
+			redirectKey := routerKey + "-mm-redirect"
+			config.HTTP.Routers[redirectKey] = &OrderedRouter{
+				EntryPoints: []string{redirectCfg.EntryPoint},
+				Middlewares: []string{httpsRedirectMiddleware},
+				Service:     router.Service,
+				Rule:        router.Rule,
+				Priority:    router.Priority,
+			}
+			config.HTTP.Middlewares[httpsRedirectMiddleware] = map[string]interface{}{
+				"redirectScheme": map[string]interface{}{
+					"scheme":    "https",
+					"permanent": redirectCfg.Permanent,
+				},
+			}
+			status.State = RedirectStateAdded
+			status.RedirectRouter = redirectKey
+		}
+		if status.State != RedirectStateOff {
+			status.Router = routerKey
+		}
+		statuses = append(statuses, status)
+	}
+}
+
+// findRedirectRouter returns another router with the same rule on the given
+// entrypoint that already redirects to HTTPS, or ""
+func findRedirectRouter(config *ProxiedTraefikConfig, routerKey string, router *OrderedRouter, entryPoint string) string {
+	for name, candidate := range config.HTTP.Routers {
+		if name == routerKey || candidate == nil || candidate.Rule != router.Rule {
+			continue
+		}
+		onEntryPoint := false
+		for _, ep := range candidate.EntryPoints {
+			if ep == entryPoint {
+				onEntryPoint = true
+				break
+			}
+		}
+		if !onEntryPoint {
+			continue
+		}
+		for _, ref := range candidate.Middlewares {
+			if middlewareRedirectsToHTTPS(config.HTTP.Middlewares, ref) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// middlewareRedirectsToHTTPS reports whether a middleware reference is a
+// redirectScheme to https. Middlewares from other providers cannot be inspected,
+// so their name is used as a hint.
+func middlewareRedirectsToHTTPS(middlewares map[string]interface{}, ref string) bool {
+	name := ref
+	if idx := strings.LastIndex(ref, "@"); idx >= 0 {
+		if ref[idx+1:] != "http" {
+			lower := strings.ToLower(ref)
+			return strings.Contains(lower, "redirect-to-https") || strings.Contains(lower, "https-redirect")
+		}
+		name = ref[:idx]
+	}
+
+	var scheme interface{}
+	switch mw := middlewares[name].(type) {
+	case map[string]interface{}:
+		if rs, ok := mw["redirectScheme"].(map[string]interface{}); ok {
+			scheme = rs["scheme"]
+		}
+	case *OrderedMiddleware:
+		scheme = mw.RedirectScheme["scheme"]
+	}
+	return scheme == "https"
+}
+
+// EntrypointRedirectTarget returns the entrypoint that the given entrypoint
+// redirects to in Traefik's YAML static config, or "" when it does not redirect
+// or the file cannot be read
+func EntrypointRedirectTarget(staticConfigPath, entryPoint string) string {
+	if staticConfigPath == "" {
+		return ""
+	}
+	data, err := util.OS.ReadFile(filepath.Clean(staticConfigPath))
+	if err != nil {
+		return ""
+	}
+
+	var static struct {
+		EntryPoints map[string]struct {
+			HTTP struct {
+				Redirections struct {
+					EntryPoint struct {
+						To string `yaml:"to"`
+					} `yaml:"entryPoint"`
+				} `yaml:"redirections"`
+			} `yaml:"http"`
+		} `yaml:"entryPoints"`
+	}
+	if err := yaml.Unmarshal(data, &static); err != nil {
+		log.Printf("Warning: could not parse Traefik static config %s: %v", staticConfigPath, err)
+		return ""
+	}
+	return static.EntryPoints[entryPoint].HTTP.Redirections.EntryPoint.To
+}
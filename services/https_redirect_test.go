@@ -0,0 +1,111 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyHTTPSRedirects(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	if _, err := db.Exec("UPDATE redirect_config SET enabled = 1, entrypoint = 'web', permanent = 1 WHERE id = 1"); err != nil {
+		t.Fatalf("enable redirects: %v", err)
+	}
+
+	newConfig := func() *ProxiedTraefikConfig {
+		return &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"app-router": {
+					EntryPoints: []string{"websecure"},
+					Rule:        "Host(`app.example.com`)",
+					Service:     "app-service",
+					Priority:    100,
+					TLS:         &OrderedTLSConfig{CertResolver: "letsencrypt"},
+				},
+				"legacy-router": {
+					EntryPoints: []string{"websecure"},
+					Rule:        "Host(`legacy.example.com`)",
+					Service:     "legacy-service",
+					TLS:         &OrderedTLSConfig{},
+				},
+				"legacy-router-redirect": {
+					EntryPoints: []string{"web"},
+					Rule:        "Host(`legacy.example.com`)",
+					Service:     "legacy-service",
+					Middlewares: []string{"redirect-to-https"},
+				},
+				"plain-router": {
+					EntryPoints: []string{"web"},
+					Rule:        "Host(`plain.example.com`)",
+					Service:     "plain-service",
+				},
+			},
+			Middlewares: map[string]interface{}{
+				"redirect-to-https": map[string]interface{}{
+					"redirectScheme": map[string]interface{}{"scheme": "https"},
+				},
+			},
+		}}
+	}
+	resources := []*resourceData{
+		{ID: "res-app", PangolinRouterID: "app-router", Host: "app.example.com", HTTPSRedirect: HTTPSRedirectInherit},
+		{ID: "res-legacy", PangolinRouterID: "legacy-router", Host: "legacy.example.com", HTTPSRedirect: HTTPSRedirectEnabled},
+		{ID: "res-plain", PangolinRouterID: "plain-router", Host: "plain.example.com"},
+		{ID: "res-off", PangolinRouterID: "app-router", Host: "app.example.com", HTTPSRedirect: HTTPSRedirectDisabled},
+		{ID: "res-gone", PangolinRouterID: "gone-router", Host: "gone.example.com", HTTPSRedirect: HTTPSRedirectEnabled},
+	}
+
+	config := newConfig()
+	cp.applyHTTPSRedirects(config, resources)
+
+	redirect := config.HTTP.Routers["app-router-mm-redirect"]
+	if redirect == nil {
+		t.Fatalf("redirect router was not added: %v", config.HTTP.Routers)
+	}
+	if redirect.Rule != "Host(`app.example.com`)" || redirect.Service != "app-service" || redirect.Priority != 100 ||
+		len(redirect.EntryPoints) != 1 || redirect.EntryPoints[0] != "web" || redirect.TLS != nil {
+		t.Errorf("unexpected redirect router: %+v", redirect)
+	}
+	if _, ok := config.HTTP.Middlewares[httpsRedirectMiddleware]; !ok {
+		t.Errorf("redirectScheme middleware was not added")
+	}
+	if _, ok := config.HTTP.Routers["legacy-router-mm-redirect"]; ok {
+		t.Errorf("a second redirect was added for a host already redirected upstream")
+	}
+
+	want := map[string]string{
+		"res-app":    RedirectStateAdded,
+		"res-legacy": RedirectStateUpstream,
+		"res-plain":  RedirectStateNoTLS,
+		"res-off":    RedirectStateOff,
+		"res-gone":   RedirectStateNoRouter,
+	}
+	statuses := cp.RedirectStatus()
+	if len(statuses) != len(want) {
+		t.Fatalf("expected %d statuses, got %+v", len(want), statuses)
+	}
+	for _, status := range statuses {
+		if status.State != want[status.ResourceID] {
+			t.Errorf("%s: state = %s, want %s", status.ResourceID, status.State, want[status.ResourceID])
+		}
+	}
+
+	// An entrypoint-level redirection in the static config makes router redirects redundant
+	staticPath := filepath.Join(t.TempDir(), "traefik.yml")
+	static := "entryPoints:\n  web:\n    address: \":80\"\n    http:\n      redirections:\n        entryPoint:\n          to: websecure\n          scheme: https\n  websecure:\n    address: \":443\"\n"
+	if err := os.WriteFile(staticPath, []byte(static), 0644); err != nil {
+		t.Fatalf("write static config: %v", err)
+	}
+	cp.SetTraefikStaticConfigPath(staticPath)
+
+	config = newConfig()
+	cp.applyHTTPSRedirects(config, resources[:1])
+	if _, ok := config.HTTP.Routers["app-router-mm-redirect"]; ok {
+		t.Errorf("redirect router added although the entrypoint already redirects")
+	}
+	if statuses := cp.RedirectStatus(); len(statuses) != 1 || statuses[0].State != RedirectStateEntrypoint {
+		t.Errorf("unexpected statuses with entrypoint redirection: %+v", statuses)
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory lists the plugins Traefik loads and the middleware types in use,
+// for planning upgrades
+type Inventory struct {
+	Plugins         []PluginInventoryEntry         `json:"plugins"`
+	MiddlewareTypes []MiddlewareTypeInventoryEntry `json:"middleware_types"`
+	// StaticConfigError is set when the static config could not be read, in
+	// which case plugins only lists those MM middlewares reference
+	StaticConfigError string `json:"static_config_error,omitempty"`
+}
+
+// PluginInventoryEntry is one plugin, from experimental.plugins of the static
+// config or referenced by an MM plugin middleware
+type PluginInventoryEntry struct {
+	Key        string `json:"key"`
+	ModuleName string `json:"module_name"`
+	Version    string `json:"version"`
+	// Installed is false for plugins MM middlewares use but the static config
+	// does not load
+	Installed   bool `json:"installed"`
+	Middlewares int  `json:"middlewares"`
+}
+
+// MiddlewareTypeInventoryEntry counts the MM middlewares of one type and the
+// resources they are assigned to
+type MiddlewareTypeInventoryEntry struct {
+	Type        string `json:"type"`
+	Middlewares int    `json:"middlewares"`
+	Resources   int    `json:"resources"`
+	Deprecated  bool   `json:"deprecated"`
+	ReplacedBy  string `json:"replaced_by,omitempty"`
+}
+
+// BuildInventory lists the plugins of the Traefik static config and every
+// middleware type MM defines with usage counts. staticConfig is the static
+// config file content, or nil when it is not available.
+func BuildInventory(db *sql.DB, staticConfig []byte) (*Inventory, error) {
+	inventory := &Inventory{
+		Plugins:         []PluginInventoryEntry{},
+		MiddlewareTypes: []MiddlewareTypeInventoryEntry{},
+	}
+
+	plugins := make(map[string]*PluginInventoryEntry)
+	if staticConfig != nil {
+		var config map[string]interface{}
+		if err := yaml.Unmarshal(staticConfig, &config); err != nil {
+			inventory.StaticConfigError = fmt.Sprintf("static config is not valid YAML: %v", err)
+		}
+		for key, value := range staticSection(staticSection(config, "experimental"), "plugins") {
+			plugin, _ := value.(map[string]interface{})
+			entry := &PluginInventoryEntry{Key: key, Installed: true}
+			if moduleName := staticValue(plugin, "moduleName"); moduleName != nil {
+				entry.ModuleName = fmt.Sprint(moduleName)
+			}
+			if version := staticValue(plugin, "version"); version != nil {
+				entry.Version = fmt.Sprint(version)
+			}
+			plugins[key] = entry
+		}
+	}
+
+	resourcesByType, err := resourcesPerMiddlewareType(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT type, config FROM middlewares")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]*MiddlewareTypeInventoryEntry)
+	for rows.Next() {
+		var typ, config string
+		if err := rows.Scan(&typ, &config); err != nil {
+			return nil, err
+		}
+
+		entry, ok := types[typ]
+		if !ok {
+			entry = &MiddlewareTypeInventoryEntry{Type: typ, Resources: resourcesByType[typ]}
+			if renamed, deprecated := middlewareTypeRenames[typ]; deprecated {
+				entry.Deprecated = true
+				entry.ReplacedBy = renamed
+			}
+			types[typ] = entry
+		}
+		entry.Middlewares++
+
+		if typ != "plugin" {
+			continue
+		}
+		var pluginConfig map[string]interface{}
+		if err := json.Unmarshal([]byte(config), &pluginConfig); err != nil {
+			log.Printf("Failed to parse plugin middleware config for inventory: %v", err)
+			continue
+		}
+		for key := range pluginConfig {
+			plugin, ok := plugins[key]
+			if !ok {
+				plugin = &PluginInventoryEntry{Key: key}
+				plugins[key] = plugin
+			}
+			plugin.Middlewares++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, plugin := range plugins {
+		inventory.Plugins = append(inventory.Plugins, *plugin)
+	}
+	sort.Slice(inventory.Plugins, func(i, j int) bool {
+		return inventory.Plugins[i].Key < inventory.Plugins[j].Key
+	})
+	for _, entry := range types {
+		inventory.MiddlewareTypes = append(inventory.MiddlewareTypes, *entry)
+	}
+	sort.Slice(inventory.MiddlewareTypes, func(i, j int) bool {
+		return inventory.MiddlewareTypes[i].Type < inventory.MiddlewareTypes[j].Type
+	})
+	return inventory, nil
+}
+
+// resourcesPerMiddlewareType counts the resources each middleware type is
+// assigned to
+func resourcesPerMiddlewareType(db *sql.DB) (map[string]int, error) {
+	rows, err := db.Query(`
+		SELECT m.type, COUNT(DISTINCT rm.resource_id)
+		FROM resource_middlewares rm
+		JOIN middlewares m ON m.id = rm.middleware_id
+		GROUP BY m.type
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var typ string
+		var resources int
+		if err := rows.Scan(&typ, &resources); err != nil {
+			return nil, err
+		}
+		counts[typ] = resources
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildInventory(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, stmt := range []string{
+		`INSERT INTO middlewares (id, name, type, config) VALUES
+			('mw-ip', 'office-only', 'ipWhiteList', '{"sourceRange": ["10.0.0.0/8"]}'),
+			('mw-ip2', 'vpn-only', 'ipWhiteList', '{"sourceRange": ["100.64.0.0/10"]}'),
+			('mw-crowdsec', 'crowdsec', 'plugin', '{"crowdsec": {"enabled": true}}'),
+			('mw-geo', 'geoblock', 'plugin', '{"geoblock": {"countries": ["US"]}}')`,
+		`INSERT INTO resources (id, host, service_id, org_id, site_id, status) VALUES
+			('res-1', 'a.example.com', 's', 'o', 'site', 'active'),
+			('res-2', 'b.example.com', 's', 'o', 'site', 'active')`,
+		`INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES
+			('res-1', 'mw-ip', 100), ('res-1', 'mw-ip2', 100), ('res-2', 'mw-ip', 100), ('res-1', 'mw-crowdsec', 100)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	static := []byte(`
+experimental:
+  plugins:
+    crowdsec:
+      moduleName: github.com/maxlerebourg/crowdsec-bouncer-traefik-plugin
+      version: v1.3.5
+    unused:
+      moduleName: github.com/example/unused
+      version: v0.1.0
+`)
+	inventory, err := BuildInventory(db.DB, static)
+	if err != nil {
+		t.Fatalf("BuildInventory: %v", err)
+	}
+
+	wantPlugins := []PluginInventoryEntry{
+		{Key: "crowdsec", ModuleName: "github.com/maxlerebourg/crowdsec-bouncer-traefik-plugin", Version: "v1.3.5", Installed: true, Middlewares: 1},
+		{Key: "geoblock", Middlewares: 1},
+		{Key: "unused", ModuleName: "github.com/example/unused", Version: "v0.1.0", Installed: true},
+	}
+	if !reflect.DeepEqual(inventory.Plugins, wantPlugins) {
+		t.Errorf("plugins = %+v, want %+v", inventory.Plugins, wantPlugins)
+	}
+
+	wantTypes := []MiddlewareTypeInventoryEntry{
+		{Type: "ipWhiteList", Middlewares: 2, Resources: 2, Deprecated: true, ReplacedBy: "ipAllowList"},
+		{Type: "plugin", Middlewares: 2, Resources: 1},
+	}
+	if !reflect.DeepEqual(inventory.MiddlewareTypes, wantTypes) {
+		t.Errorf("middleware types = %+v, want %+v", inventory.MiddlewareTypes, wantTypes)
+	}
+}
+
+func TestBuildInventory_InvalidStaticConfig(t *testing.T) {
+	db := newTestDB(t)
+
+	inventory, err := BuildInventory(db.DB, []byte("experimental: [unclosed"))
+	if err != nil {
+		t.Fatalf("BuildInventory: %v", err)
+	}
+	if inventory.StaticConfigError == "" {
+		t.Error("expected a static config error")
+	}
+	if len(inventory.Plugins) != 0 || len(inventory.MiddlewareTypes) != 0 {
+		t.Errorf("expected an empty inventory, got %+v", inventory)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// LoadManagementScope reads the management scope; a missing row means unrestricted
+func LoadManagementScope(db *sql.DB) (*models.ManagementScope, error) {
+	var include, exclude string
+	var updatedAt sql.NullTime
+	err := db.QueryRow(
+		"SELECT include_patterns, exclude_patterns, updated_at FROM management_scope WHERE id = 1",
+	).Scan(&include, &exclude, &updatedAt)
+	if err == sql.ErrNoRows {
+		return &models.ManagementScope{Include: []string{}, Exclude: []string{}}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load management scope: %w", err)
+	}
+
+	scope := &models.ManagementScope{}
+	if err := json.Unmarshal([]byte(include), &scope.Include); err != nil {
+		return nil, fmt.Errorf("failed to parse include patterns: %w", err)
+	}
+	if err := json.Unmarshal([]byte(exclude), &scope.Exclude); err != nil {
+		return nil, fmt.Errorf("failed to parse exclude patterns: %w", err)
+	}
+	if updatedAt.Valid {
+		scope.UpdatedAt = updatedAt.Time
+	}
+	scope.Normalize()
+	return scope, nil
+}
+
+// SaveManagementScope validates and stores the management scope
+func SaveManagementScope(db *sql.DB, scope *models.ManagementScope) error {
+	scope.Normalize()
+	if err := scope.Validate(); err != nil {
+		return err
+	}
+
+	include, _ := json.Marshal(scope.Include)
+	exclude, _ := json.Marshal(scope.Exclude)
+	scope.UpdatedAt = time.Now()
+
+	_, err := db.Exec(`
+		INSERT INTO management_scope (id, include_patterns, exclude_patterns, updated_at)
+		VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			include_patterns = excluded.include_patterns,
+			exclude_patterns = excluded.exclude_patterns,
+			updated_at = excluded.updated_at
+	`, string(include), string(exclude), scope.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save management scope: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeSections turns off MM's changes to whole protocol sections of the
+// served config. A disabled section is served exactly as upstream sent it,
+// without compatibility rewrites and, for TLS, without the mTLS and hardening
+// options, so conservative deployments can limit MM to the HTTP section.
+// The zero value merges every section.
+type MergeSections struct {
+	DisableTCP bool
+	DisableUDP bool
+	DisableTLS bool
+}
+
+// MergeSectionsStatus reports which sections MM merges into
+type MergeSectionsStatus struct {
+	TCP bool `json:"tcp"`
+	UDP bool `json:"udp"`
+	TLS bool `json:"tls"`
+}
+
+// ParseDisabledSections reads a comma-separated list of sections MM must not
+// touch, e.g. "tcp,udp"
+func ParseDisabledSections(value string) (MergeSections, error) {
+	var sections MergeSections
+	for _, name := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "tcp":
+			sections.DisableTCP = true
+		case "udp":
+			sections.DisableUDP = true
+		case "tls":
+			sections.DisableTLS = true
+		default:
+			return MergeSections{}, fmt.Errorf("unknown config section %q (expected tcp, udp or tls)", strings.TrimSpace(name))
+		}
+	}
+	return sections, nil
+}
+
+// SetMergeSections selects the protocol sections MM merges into
+func (cp *ConfigProxy) SetMergeSections(sections MergeSections) {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.sections = sections
+}
+
+// mergeSections returns the current section settings
+func (cp *ConfigProxy) mergeSections() MergeSections {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+	return cp.sections
+}
+
+// MergeSectionsStatus returns the sections MM merges into
+func (cp *ConfigProxy) MergeSectionsStatus() MergeSectionsStatus {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+	return MergeSectionsStatus{
+		TCP: !cp.sections.DisableTCP,
+		UDP: !cp.sections.DisableUDP,
+		TLS: !cp.sections.DisableTLS,
+	}
+}
+
+// upstreamSections holds the disabled sections of a fetched config while the
+// merge runs
+type upstreamSections struct {
+	sections MergeSections
+	tcp      *TCPConfig
+	udp      *UDPConfig
+	tls      *TLSConfig
+}
+
+// holdUpstreamSections detaches the disabled sections of config so the merge
+// cannot modify them; restore puts them back
+func holdUpstreamSections(config *ProxiedTraefikConfig, sections MergeSections) *upstreamSections {
+	held := &upstreamSections{sections: sections}
+	if sections.DisableTCP {
+		held.tcp, config.TCP = config.TCP, nil
+	}
+	if sections.DisableUDP {
+		held.udp, config.UDP = config.UDP, nil
+	}
+	if sections.DisableTLS {
+		held.tls, config.TLS = config.TLS, nil
+	}
+	return held
+}
+
+// restore replaces whatever the merge put into the disabled sections with the
+// upstream ones
+func (h *upstreamSections) restore(config *ProxiedTraefikConfig) {
+	if h.sections.DisableTCP {
+		config.TCP = h.tcp
+	}
+	if h.sections.DisableUDP {
+		config.UDP = h.udp
+	}
+	if h.sections.DisableTLS {
+		config.TLS = h.tls
+	}
+}
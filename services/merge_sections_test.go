@@ -0,0 +1,91 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseDisabledSections(t *testing.T) {
+	sections, err := ParseDisabledSections(" TCP, tls ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sections.DisableTCP || sections.DisableUDP || !sections.DisableTLS {
+		t.Errorf("unexpected sections %+v", sections)
+	}
+	if _, err := ParseDisabledSections("tcp,http"); err == nil {
+		t.Error("expected an unknown section to be rejected")
+	}
+}
+
+func TestConfigProxy_DisabledSectionsServedAsUpstream(t *testing.T) {
+	upstream := map[string]interface{}{
+		"http": map[string]interface{}{
+			"routers": map[string]interface{}{
+				"app-router": map[string]interface{}{"rule": "Host(`app.example.com`)", "service": "app", "tls": map[string]interface{}{}},
+			},
+			"services": map[string]interface{}{"app": map[string]interface{}{}},
+		},
+		"tcp": map[string]interface{}{
+			"routers":  map[string]interface{}{"db": map[string]interface{}{"rule": "HostSNI(`*`)", "service": "db"}},
+			"services": map[string]interface{}{"db": map[string]interface{}{"loadBalancer": map[string]interface{}{}}},
+		},
+		"tls": map[string]interface{}{
+			"options": map[string]interface{}{"pangolin": map[string]interface{}{"minVersion": "VersionTLS12"}},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(upstream)
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name     string
+		sections MergeSections
+		merged   bool
+	}{
+		{"merged", MergeSections{}, true},
+		{"disabled", MergeSections{DisableTCP: true, DisableUDP: true, DisableTLS: true}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDB(t)
+			for _, stmt := range []string{
+				`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, tls_hardening_enabled)
+				 VALUES ('app', 'app-router', 'app.example.com', 'app', 'org', 'site', 'active', 1)`,
+			} {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatalf("seed: %v", err)
+				}
+			}
+			cp := NewConfigProxy(db, newTestConfigManager(t), server.URL)
+			cp.httpClient = server.Client()
+			cp.SetMergeSections(tc.sections)
+
+			config, err := cp.GetMergedConfig()
+			if err != nil {
+				t.Fatalf("GetMergedConfig() error = %v", err)
+			}
+
+			_, hasHardening := config.TLS.Options["tls-hardened"]
+			routerOptions := config.HTTP.Routers["app-router"].TLS.Options
+			if hasHardening != tc.merged || (routerOptions == "tls-hardened") != tc.merged {
+				t.Errorf("merged = %v: hardening options %v, router options %q", tc.merged, hasHardening, routerOptions)
+			}
+			if !tc.merged {
+				want := map[string]interface{}{"pangolin": map[string]interface{}{"minVersion": "VersionTLS12"}}
+				if !reflect.DeepEqual(config.TLS.Options, want) {
+					t.Errorf("TLS options changed: %v", config.TLS.Options)
+				}
+				if len(config.TCP.Services) != 1 || len(config.TCP.Routers) != 1 {
+					t.Errorf("TCP section changed: %+v", config.TCP)
+				}
+			}
+			if got := cp.MergeSectionsStatus(); got.TCP != tc.merged || got.UDP != tc.merged || got.TLS != tc.merged {
+				t.Errorf("unexpected status %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// MiddlewareConversion describes how a stored middleware changes when converted
+// to another Traefik major version
+type MiddlewareConversion struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	FromType string                 `json:"from_type"`
+	ToType   string                 `json:"to_type"`
+	Config   map[string]interface{} `json:"config"`
+	Changes  []string               `json:"changes"`
+}
+
+// ConvertMiddlewareConfig converts one middleware's type and options to the
+// naming of the given Traefik major version. The config is modified in place.
+func ConvertMiddlewareConfig(name, typ string, config map[string]interface{}, major int) (string, map[string]interface{}, []string) {
+	mw := map[string]interface{}{typ: config}
+	changes := adaptMiddlewareForTraefik(name, mw, major)
+	for newType, body := range mw {
+		converted, _ := body.(map[string]interface{})
+		return newType, converted, changes
+	}
+	return typ, config, changes
+}
+
+// ConvertStoredMiddlewares converts stored middlewares (all of them, or only ids)
+// to the given Traefik major version and returns the ones that change. Nothing
+// is written unless apply is true; all updates share one transaction.
+func ConvertStoredMiddlewares(db *sql.DB, major int, ids []string, apply bool) ([]MiddlewareConversion, error) {
+	if major != 2 && major != 3 {
+		return nil, fmt.Errorf("unsupported Traefik major version %d", major)
+	}
+
+	query := "SELECT id, name, type, config FROM middlewares"
+	args := make([]interface{}, 0, len(ids))
+	if len(ids) > 0 {
+		query += " WHERE id IN (?" + strings.Repeat(",?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY name"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch middlewares: %w", err)
+	}
+	defer rows.Close()
+
+	conversions := []MiddlewareConversion{}
+	for rows.Next() {
+		var id, name, typ, configStr string
+		if err := rows.Scan(&id, &name, &typ, &configStr); err != nil {
+			return nil, fmt.Errorf("failed to scan middleware: %w", err)
+		}
+
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(configStr), &config); err != nil {
+			log.Printf("Skipping middleware %s during conversion: invalid config: %v", name, err)
+			continue
+		}
+
+		newType, newConfig, changes := ConvertMiddlewareConfig(name, typ, config, major)
+		if len(changes) == 0 {
+			continue
+		}
+		conversions = append(conversions, MiddlewareConversion{
+			ID:       id,
+			Name:     name,
+			FromType: typ,
+			ToType:   newType,
+			Config:   newConfig,
+			Changes:  changes,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !apply || len(conversions) == 0 {
+		return conversions, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, conv := range conversions {
+		configJSON, err := json.Marshal(conv.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode config for %s: %w", conv.Name, err)
+		}
+		if _, err := tx.Exec(
+			"UPDATE middlewares SET type = ?, config = ?, updated_at = ?, version = version + 1 WHERE id = ?",
+			conv.ToType, string(configJSON), now, conv.ID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to update middleware %s: %w", conv.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit conversion: %w", err)
+	}
+
+	log.Printf("Converted %d middleware(s) to Traefik v%d naming", len(conversions), major)
+	return conversions, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"log"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// parseMiddlewareOrder reads a resource's ordering policy, falling back to
+// prepend on invalid data
+func parseMiddlewareOrder(resourceID, policy, positions string) models.MiddlewareOrder {
+	order, err := models.ParseMiddlewareOrder(policy, positions)
+	if err != nil {
+		log.Printf("Warning: resource %s: %v; prepending its middlewares", resourceID, err)
+		return models.MiddlewareOrder{Policy: models.MiddlewareOrderPrepend}
+	}
+	return order
+}
+
+// orderRouterMiddlewares combines MM's middlewares with the ones already on
+// the router according to order. Upstream middlewares MM also adds are kept
+// only at MM's position, and interleave positions index the remaining
+// upstream chain.
+func orderRouterMiddlewares(order models.MiddlewareOrder, mm, existing []string) []string {
+	added := make(map[string]struct{}, len(mm))
+	for _, name := range mm {
+		added[name] = struct{}{}
+	}
+	upstream := make([]string, 0, len(existing))
+	for _, name := range existing {
+		if _, dup := added[name]; !dup {
+			upstream = append(upstream, name)
+		}
+	}
+
+	final := make([]string, 0, len(mm)+len(upstream))
+	switch order.Policy {
+	case models.MiddlewareOrderAppend:
+		final = append(append(final, upstream...), mm...)
+	case models.MiddlewareOrderInterleave:
+		at := make(map[int][]string)
+		for _, name := range mm {
+			index, ok := order.Positions[name]
+			if !ok {
+				final = append(final, name)
+				continue
+			}
+			index = min(index, len(upstream))
+			at[index] = append(at[index], name)
+		}
+		for i, name := range upstream {
+			final = append(final, at[i]...)
+			final = append(final, name)
+		}
+		final = append(final, at[len(upstream)]...)
+	default:
+		final = append(append(final, mm...), upstream...)
+	}
+	return final
+}
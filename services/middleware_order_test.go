@@ -0,0 +1,63 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestOrderRouterMiddlewares(t *testing.T) {
+	mm := []string{"waf", "auth"}
+	upstream := []string{"badger", "auth", "rewrite"}
+	for _, tc := range []struct {
+		name  string
+		order models.MiddlewareOrder
+		want  []string
+	}{
+		{"prepend", models.MiddlewareOrder{Policy: models.MiddlewareOrderPrepend}, []string{"waf", "auth", "badger", "rewrite"}},
+		{"append", models.MiddlewareOrder{Policy: models.MiddlewareOrderAppend}, []string{"badger", "rewrite", "waf", "auth"}},
+		{"interleave", models.MiddlewareOrder{
+			Policy:    models.MiddlewareOrderInterleave,
+			Positions: map[string]int{"auth": 1},
+		}, []string{"waf", "badger", "auth", "rewrite"}},
+		{"interleave past the end", models.MiddlewareOrder{
+			Policy:    models.MiddlewareOrderInterleave,
+			Positions: map[string]int{"waf": 0, "auth": 9},
+		}, []string{"waf", "badger", "rewrite", "auth"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := orderRouterMiddlewares(tc.order, mm, upstream); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("orderRouterMiddlewares() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeConfig_AppendsAfterUpstreamMiddlewares(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, forward_auth_enabled, middleware_order)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'app', 'org', 'site', 'active', 1, 'append')`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	cp.SetForwardAuthURL("http://mm:3456/api/forward-auth")
+
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"app-router": {Rule: "Host(`app.example.com`)", Service: "app", Middlewares: []string{"badger@http"}},
+			},
+			Middlewares: map[string]interface{}{},
+		},
+	}
+	if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+		t.Fatalf("mergeMiddlewareManagerConfig: %v", err)
+	}
+
+	want := []string{"badger@http", "res-1-forwardauth"}
+	if got := config.HTTP.Routers["app-router"].Middlewares; !reflect.DeepEqual(got, want) {
+		t.Errorf("router middlewares = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"log"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// loadMirrors attaches the request mirror configured for each resource
+func (cp *ConfigProxy) loadMirrors(resourceMap map[string]*resourceData) error {
+	rows, err := cp.db.Query(`
+		SELECT resource_id, target_url, percent, mirror_body, max_body_size
+		FROM resource_mirrors
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mirror models.ResourceMirror
+		var mirrorBody int
+		if err := rows.Scan(&mirror.ResourceID, &mirror.TargetURL, &mirror.Percent, &mirrorBody, &mirror.MaxBodySize); err != nil {
+			log.Printf("Failed to scan resource mirror: %v", err)
+			continue
+		}
+		data, ok := resourceMap[mirror.ResourceID]
+		if !ok {
+			continue
+		}
+		mirror.MirrorBody = mirrorBody == 1
+		if err := mirror.Validate(); err != nil {
+			log.Printf("Skipping invalid mirror for resource %s: %v", mirror.ResourceID, err)
+			continue
+		}
+		data.Mirror = &mirror
+	}
+
+	return rows.Err()
+}
+
+// applyMirroring wraps the service of each mirrored resource's router in a
+// mirroring service that copies a share of the requests to the mirror target.
+// The original service is left untouched, so removing the mirror restores it.
+func (cp *ConfigProxy) applyMirroring(config *ProxiedTraefikConfig, resources []*resourceData) {
+	for _, resource := range resources {
+		if resource.Mirror == nil {
+			continue
+		}
+
+		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, router = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+		if routerKey == "" || router.Service == "" {
+			log.Printf("Mirror configured for resource %s but no router with a service matched; skipping", resource.ID)
+			continue
+		}
+
+		mirroringName, targetName := models.MirrorServiceNames(resource.ID)
+		config.HTTP.Services[targetName] = map[string]interface{}{
+			"loadBalancer": map[string]interface{}{
+				"servers": []interface{}{
+					map[string]interface{}{"url": resource.Mirror.TargetURL},
+				},
+			},
+		}
+		mirroring := map[string]interface{}{
+			"service": router.Service,
+			"mirrors": []interface{}{
+				map[string]interface{}{"name": targetName, "percent": resource.Mirror.Percent},
+			},
+			"mirrorBody": resource.Mirror.MirrorBody,
+		}
+		if resource.Mirror.MaxBodySize > 0 {
+			mirroring["maxBodySize"] = resource.Mirror.MaxBodySize
+		}
+		config.HTTP.Services[mirroringName] = map[string]interface{}{"mirroring": mirroring}
+		router.Service = mirroringName
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestApplyMirroring(t *testing.T) {
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), "")
+
+	config := &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+		Routers: map[string]*OrderedRouter{
+			"app-router":   {Rule: "Host(`app.example.com`)", Service: "app-service"},
+			"other-router": {Rule: "Host(`other.example.com`)", Service: "other-service"},
+		},
+		Services: map[string]interface{}{
+			"app-service": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+		},
+	}}
+	resources := []*resourceData{
+		{ID: "res-1", PangolinRouterID: "app-router", Host: "app.example.com", Mirror: &models.ResourceMirror{
+			TargetURL: "http://staging:8080", Percent: 25, MirrorBody: false, MaxBodySize: 1024,
+		}},
+		{ID: "res-2", PangolinRouterID: "other-router", Host: "other.example.com"},
+	}
+
+	cp.applyMirroring(config, resources)
+
+	if got := config.HTTP.Routers["app-router"].Service; got != "res-1-mirroring" {
+		t.Fatalf("router service = %s, want res-1-mirroring", got)
+	}
+	if got := config.HTTP.Routers["other-router"].Service; got != "other-service" {
+		t.Errorf("unmirrored router changed to %s", got)
+	}
+	if _, ok := config.HTTP.Services["app-service"]; !ok {
+		t.Errorf("original service must be kept")
+	}
+
+	wantMirroring := map[string]interface{}{
+		"mirroring": map[string]interface{}{
+			"service":     "app-service",
+			"mirrors":     []interface{}{map[string]interface{}{"name": "res-1-mirror-target", "percent": 25}},
+			"mirrorBody":  false,
+			"maxBodySize": int64(1024),
+		},
+	}
+	if got := config.HTTP.Services["res-1-mirroring"]; !reflect.DeepEqual(got, wantMirroring) {
+		t.Errorf("mirroring service = %v, want %v", got, wantMirroring)
+	}
+	target := config.HTTP.Services["res-1-mirror-target"].(map[string]interface{})["loadBalancer"].(map[string]interface{})
+	if servers := target["servers"].([]interface{}); servers[0].(map[string]interface{})["url"] != "http://staging:8080" {
+		t.Errorf("unexpected mirror target: %v", target)
+	}
+}
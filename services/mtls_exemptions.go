@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// applyMTLSExemptions adds a higher-priority router for each mTLS resource
+// with exempt paths. The copy matches the same rule narrowed to the exempt
+// path prefixes and carries every middleware except the mtlswhitelist one.
+//
+// The router keeps its TLS section: TLS options are chosen per host, so a
+// different set on the copy would make Traefik fall back to its defaults for
+// the whole host. mtls-verify only requests a certificate (the middleware is
+// what enforces it), so clients without one still reach the exempt paths.
+func (cp *ConfigProxy) applyMTLSExemptions(config *ProxiedTraefikConfig, resources []*resourceData) {
+	for _, resource := range resources {
+		if !resource.MTLSEnabled || len(resource.MTLSExemptPaths) == 0 {
+			continue
+		}
+
+		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, router = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+		if routerKey == "" || router.Rule == "" {
+			continue
+		}
+
+		mtlsMiddleware := fmt.Sprintf("%s-mtlsauth", resource.ID)
+		if _, ok := config.HTTP.Middlewares[mtlsMiddleware]; !ok {
+			// mTLS could not be applied to this resource, so there is nothing to exempt from
+			continue
+		}
+
+		var middlewares []string
+		for _, mw := range router.Middlewares {
+			if mw != mtlsMiddleware {
+				middlewares = append(middlewares, mw)
+			}
+		}
+
+		matchers := make([]string, 0, len(resource.MTLSExemptPaths))
+		for _, path := range resource.MTLSExemptPaths {
+			matchers = append(matchers, fmt.Sprintf("PathPrefix(`%s`)", path))
+		}
+
+		exemptRouter := &OrderedRouter{
+			EntryPoints: append([]string{}, router.EntryPoints...),
+			Middlewares: middlewares,
+			Service:     router.Service,
+			Rule:        fmt.Sprintf("(%s) && (%s)", router.Rule, strings.Join(matchers, " || ")),
+		}
+		// Without an explicit priority Traefik ranks by rule length, which the longer rule already wins
+		if router.Priority > 0 {
+			exemptRouter.Priority = router.Priority + 1
+		}
+		if router.TLS != nil {
+			tls := *router.TLS
+			exemptRouter.TLS = &tls
+		}
+		config.HTTP.Routers[routerKey+"-mm-mtls-exempt"] = exemptRouter
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import "testing"
+
+func TestApplyMTLSExemptions(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	config := &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+		Routers: map[string]*OrderedRouter{
+			"app-router": {
+				EntryPoints: []string{"websecure"},
+				Middlewares: []string{"res-1-mtlsauth", "res-1-headers", "badger@http"},
+				Rule:        "Host(`app.example.com`)",
+				Service:     "app-service",
+				Priority:    100,
+				TLS:         &OrderedTLSConfig{CertResolver: "letsencrypt", Options: "mtls-verify"},
+			},
+			"other-router": {
+				EntryPoints: []string{"websecure"},
+				Rule:        "Host(`other.example.com`)",
+				Service:     "other-service",
+			},
+		},
+		Middlewares: map[string]interface{}{
+			"res-1-mtlsauth": map[string]interface{}{},
+		},
+	}}
+	resources := []*resourceData{
+		{ID: "res-1", PangolinRouterID: "app-router", Host: "app.example.com", MTLSEnabled: true,
+			MTLSExemptPaths: []string{"/api/webhook", "/healthz"}},
+		// Exempt paths are ignored while mTLS is off
+		{ID: "res-2", PangolinRouterID: "other-router", Host: "other.example.com",
+			MTLSExemptPaths: []string{"/api/webhook"}},
+	}
+
+	cp.applyMTLSExemptions(config, resources)
+
+	exempt := config.HTTP.Routers["app-router-mm-mtls-exempt"]
+	if exempt == nil {
+		t.Fatalf("exempt router not added: %v", config.HTTP.Routers)
+	}
+	wantRule := "(Host(`app.example.com`)) && (PathPrefix(`/api/webhook`) || PathPrefix(`/healthz`))"
+	if exempt.Rule != wantRule {
+		t.Errorf("rule = %s, want %s", exempt.Rule, wantRule)
+	}
+	if exempt.Priority != 101 || exempt.Service != "app-service" {
+		t.Errorf("unexpected exempt router: %+v", exempt)
+	}
+	if len(exempt.Middlewares) != 2 || exempt.Middlewares[0] != "res-1-headers" || exempt.Middlewares[1] != "badger@http" {
+		t.Errorf("expected every middleware except mTLS, got %v", exempt.Middlewares)
+	}
+	if exempt.TLS == nil || exempt.TLS.Options != "mtls-verify" || exempt.TLS == config.HTTP.Routers["app-router"].TLS {
+		t.Errorf("expected a copy of the router TLS section, got %+v", exempt.TLS)
+	}
+	if _, ok := config.HTTP.Routers["other-router-mm-mtls-exempt"]; ok {
+		t.Error("no exempt router expected for a resource without mTLS")
+	}
+	if len(config.HTTP.Routers["app-router"].Middlewares) != 3 {
+		t.Error("the main router must keep the mTLS middleware")
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// crlValidity is how long an exported CRL is valid before consumers should refresh it
+const crlValidity = 7 * 24 * time.Hour
+
+// ExportVersion is the manifest format version for mTLS exports
+const ExportVersion = 1
+
+// loadCA parses the stored CA certificate and private key
+func (cg *CertGenerator) loadCA() (*x509.Certificate, *rsa.PrivateKey, string, error) {
+	caCert, caCertPEM, caKeyPEM, err := cg.loadCACert()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if caKeyPEM == "" {
+		return nil, nil, "", ErrExternalCA
+	}
+
+	keyBlock, _ := pem.Decode([]byte(caKeyPEM))
+	if keyBlock == nil {
+		return nil, nil, "", fmt.Errorf("failed to decode CA private key PEM")
+	}
+	caKey, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to parse CA private key: %w", err)
+	}
+
+	return caCert, caKey, caCertPEM, nil
+}
+
+// loadCACert parses the stored CA certificate and returns it with its PEM and
+// the PEM of the private key, which is empty for an external CA
+func (cg *CertGenerator) loadCACert() (*x509.Certificate, string, string, error) {
+	var caCertPEM, caKeyPEM string
+	err := cg.db.QueryRow("SELECT ca_cert, ca_key FROM mtls_config WHERE id = 1").Scan(&caCertPEM, &caKeyPEM)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to get CA from database: %w", err)
+	}
+	if caCertPEM == "" {
+		return nil, "", "", fmt.Errorf("%w - please create a CA first", ErrCANotConfigured)
+	}
+
+	certBlock, _ := pem.Decode([]byte(caCertPEM))
+	if certBlock == nil {
+		return nil, "", "", fmt.Errorf("failed to decode CA certificate PEM")
+	}
+	caCert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	return caCert, caCertPEM, caKeyPEM, nil
+}
+
+// GenerateCRL builds a PEM-encoded CRL, signed by the CA, listing every revoked client
+func (cg *CertGenerator) GenerateCRL() ([]byte, *x509.RevocationList, error) {
+	caCert, caKey, _, err := cg.loadCA()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clients, err := cg.GetClients()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var revoked []x509.RevocationListEntry
+	for _, client := range clients {
+		if !client.Revoked {
+			continue
+		}
+		cert, err := parseCertPEM(client.Cert)
+		if err != nil {
+			log.Printf("Warning: skipping unparseable certificate for client %s in CRL: %v", client.Name, err)
+			continue
+		}
+		revokedAt := client.CreatedAt
+		if client.RevokedAt != nil {
+			revokedAt = *client.RevokedAt
+		}
+		revoked = append(revoked, x509.RevocationListEntry{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: revokedAt.UTC(),
+		})
+	}
+
+	now := time.Now().UTC()
+	template := &x509.RevocationList{
+		// CRL numbers must increase monotonically; the issue time satisfies that
+		Number:                    big.NewInt(now.Unix()),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(crlValidity),
+		RevokedCertificateEntries: revoked,
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, caCert, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CRL: %w", err)
+	}
+
+	crlPEM := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	return crlPEM, template, nil
+}
+
+// ExportConfig builds a proxy-neutral export of the CA bundle, CRL and the
+// verification policy of every mTLS-enabled resource
+func (cg *CertGenerator) ExportConfig() (*models.MTLSExport, error) {
+	caCert, caCertPEM, _, err := cg.loadCACert()
+	if err != nil {
+		return nil, err
+	}
+
+	// An external CA publishes its own CRL, which the export then leaves out
+	crlPEM, crl, err := cg.GenerateCRL()
+	if errors.Is(err, ErrExternalCA) {
+		crl = &x509.RevocationList{Number: big.NewInt(0)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	fingerprint := sha256.Sum256(caCert.Raw)
+	notAfter := caCert.NotAfter
+	export := &models.MTLSExport{
+		Version:     ExportVersion,
+		GeneratedAt: time.Now().UTC(),
+		CA: models.MTLSExportCA{
+			Subject:           caCert.Subject.String(),
+			SerialNumber:      formatSerial(caCert.SerialNumber),
+			FingerprintSHA256: strings.ToUpper(hex.EncodeToString(fingerprint[:])),
+			NotAfter:          &notAfter,
+		},
+		CRL: models.MTLSExportCRL{
+			Number:       crl.Number.String(),
+			ThisUpdate:   crl.ThisUpdate,
+			NextUpdate:   crl.NextUpdate,
+			RevokedCount: len(crl.RevokedCertificateEntries),
+		},
+		Clients:     []models.MTLSExportClient{},
+		Resources:   []models.MTLSExportResourcePolicy{},
+		CABundlePEM: caCertPEM,
+		CRLPEM:      string(crlPEM),
+	}
+
+	clients, err := cg.GetClients()
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		entry := models.MTLSExportClient{
+			Name:      client.Name,
+			Subject:   client.Subject,
+			NotAfter:  client.Expiry,
+			Revoked:   client.Revoked,
+			RevokedAt: client.RevokedAt,
+		}
+		if cert, err := parseCertPEM(client.Cert); err == nil {
+			entry.SerialNumber = formatSerial(cert.SerialNumber)
+		}
+		export.Clients = append(export.Clients, entry)
+	}
+
+	policies, err := cg.resourcePolicies()
+	if err != nil {
+		return nil, err
+	}
+	export.Resources = policies
+
+	return export, nil
+}
+
+// resourcePolicies returns the verification policy for every active mTLS-enabled resource
+func (cg *CertGenerator) resourcePolicies() ([]models.MTLSExportResourcePolicy, error) {
+	rows, err := cg.db.Query(`
+		SELECT id, host, COALESCE(mtls_rules, ''), COALESCE(mtls_request_headers, ''),
+		       COALESCE(mtls_reject_message, ''), COALESCE(mtls_reject_code, 403)
+		FROM resources
+		WHERE mtls_enabled = 1 AND status = 'active'
+		ORDER BY host
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mTLS resources: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.MTLSExportResourcePolicy{}
+	for rows.Next() {
+		var policy models.MTLSExportResourcePolicy
+		var rules, headers string
+		if err := rows.Scan(&policy.ResourceID, &policy.Host, &rules, &headers, &policy.RejectMessage, &policy.RejectCode); err != nil {
+			return nil, fmt.Errorf("failed to scan mTLS resource row: %w", err)
+		}
+		// Traefik's mtls-verify options use RequireAndVerifyClientCert
+		policy.VerifyClient = "require"
+		policy.Rules = rawJSONOrNil(rules)
+		policy.RequestHeaders = rawJSONOrNil(headers)
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// ExportPEMBundle returns the CA certificate followed by the current CRL, or
+// the CA certificate alone for an external CA
+func (cg *CertGenerator) ExportPEMBundle() ([]byte, error) {
+	_, caCertPEM, _, err := cg.loadCACert()
+	if err != nil {
+		return nil, err
+	}
+	crlPEM, _, err := cg.GenerateCRL()
+	if err != nil && !errors.Is(err, ErrExternalCA) {
+		return nil, err
+	}
+
+	bundle := strings.TrimRight(caCertPEM, "\n") + "\n"
+	return append([]byte(bundle), crlPEM...), nil
+}
+
+func parseCertPEM(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode certificate PEM")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// formatSerial renders a serial number as colon-separated hex, as openssl does
+func formatSerial(serial *big.Int) string {
+	raw := hex.EncodeToString(serial.Bytes())
+	if len(raw)%2 == 1 {
+		raw = "0" + raw
+	}
+	parts := make([]string, 0, len(raw)/2)
+	for i := 0; i < len(raw); i += 2 {
+		parts = append(parts, strings.ToUpper(raw[i:i+2]))
+	}
+	return strings.Join(parts, ":")
+}
+
+// rawJSONOrNil passes valid stored JSON through unchanged and drops anything else
+func rawJSONOrNil(value string) json.RawMessage {
+	value = strings.TrimSpace(value)
+	if value == "" || !json.Valid([]byte(value)) {
+		return nil
+	}
+	return json.RawMessage(value)
+}
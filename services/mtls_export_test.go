@@ -0,0 +1,91 @@
+package services
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestCertGenerator_ExportConfig(t *testing.T) {
+	db := newTestSQLDB(t)
+	cg := NewCertGenerator(db)
+
+	if _, err := cg.ExportConfig(); err == nil {
+		t.Fatal("ExportConfig() should fail without a CA")
+	}
+
+	if _, err := cg.GenerateCA(models.CreateCARequest{CommonName: "Export CA"}, t.TempDir()); err != nil {
+		t.Fatalf("GenerateCA() error = %v", err)
+	}
+	kept, err := cg.GenerateClientCert(models.CreateClientRequest{Name: "kept", P12Password: "password123"})
+	if err != nil {
+		t.Fatalf("GenerateClientCert() error = %v", err)
+	}
+	revoked, err := cg.GenerateClientCert(models.CreateClientRequest{Name: "revoked", P12Password: "password123"})
+	if err != nil {
+		t.Fatalf("GenerateClientCert() error = %v", err)
+	}
+	if err := cg.RevokeClient(revoked.ID); err != nil {
+		t.Fatalf("RevokeClient() error = %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO resources (id, host, service_id, org_id, site_id, status, mtls_enabled, mtls_rules)
+		VALUES ('res-1', 'secure.example.com', 'svc', 'org', 'site', 'active', 1, '[{"type":"header"}]')`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resources (id, host, service_id, org_id, site_id, status)
+		VALUES ('res-2', 'open.example.com', 'svc', 'org', 'site', 'active')`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	export, err := cg.ExportConfig()
+	if err != nil {
+		t.Fatalf("ExportConfig() error = %v", err)
+	}
+	if export.CA.FingerprintSHA256 == "" || !strings.Contains(export.CABundlePEM, "BEGIN CERTIFICATE") {
+		t.Fatalf("export is missing CA details: %+v", export.CA)
+	}
+	if len(export.Clients) != 2 {
+		t.Errorf("expected 2 clients, got %d", len(export.Clients))
+	}
+	if len(export.Resources) != 1 || export.Resources[0].Host != "secure.example.com" {
+		t.Fatalf("expected only the mTLS resource in the policy list, got %+v", export.Resources)
+	}
+	if string(export.Resources[0].Rules) != `[{"type":"header"}]` {
+		t.Errorf("rules should pass through as JSON, got %s", export.Resources[0].Rules)
+	}
+
+	block, _ := pem.Decode([]byte(export.CRLPEM))
+	if block == nil || block.Type != "X509 CRL" {
+		t.Fatalf("CRL PEM is invalid: %q", export.CRLPEM)
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseRevocationList() error = %v", err)
+	}
+	caCert, _ := parseCertPEM(export.CABundlePEM)
+	if err := crl.CheckSignatureFrom(caCert); err != nil {
+		t.Fatalf("CRL is not signed by the CA: %v", err)
+	}
+
+	revokedCert, _ := parseCertPEM(revoked.Cert)
+	keptCert, _ := parseCertPEM(kept.Cert)
+	if len(crl.RevokedCertificateEntries) != 1 {
+		t.Fatalf("expected 1 revoked entry, got %d", len(crl.RevokedCertificateEntries))
+	}
+	serial := crl.RevokedCertificateEntries[0].SerialNumber
+	if serial.Cmp(revokedCert.SerialNumber) != 0 || serial.Cmp(keptCert.SerialNumber) == 0 {
+		t.Error("CRL should list only the revoked client's serial")
+	}
+
+	bundle, err := cg.ExportPEMBundle()
+	if err != nil {
+		t.Fatalf("ExportPEMBundle() error = %v", err)
+	}
+	if !strings.Contains(string(bundle), "BEGIN CERTIFICATE") || !strings.Contains(string(bundle), "BEGIN X509 CRL") {
+		t.Error("PEM bundle should contain the CA certificate and the CRL")
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/models"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// MaxClientImport caps the number of certificates in one import request
+const MaxClientImport = 500
+
+// ImportClients stores existing client certificates so devices migrated from
+// another CA keep their certificates. Each entry is imported on its own:
+// certificates already stored are skipped, unusable ones fail.
+func (cg *CertGenerator) ImportClients(entries []models.MTLSClientImport) (*models.MTLSClientImportResponse, error) {
+	// Without a CA, imported certificates are simply not issued by it
+	caCert, _, _, err := cg.loadCACert()
+	if err != nil {
+		caCert = nil
+	}
+
+	names := make(map[string]bool)
+	fingerprints := make(map[[32]byte]string)
+	rows, err := cg.db.Query(`SELECT name, cert FROM mtls_clients`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query clients: %w", err)
+	}
+	for rows.Next() {
+		var name, certPEM string
+		if err := rows.Scan(&name, &certPEM); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan client row: %w", err)
+		}
+		names[name] = true
+		if cert, err := parseCertPEM(certPEM); err == nil {
+			fingerprints[sha256.Sum256(cert.Raw)] = name
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query clients: %w", err)
+	}
+
+	response := &models.MTLSClientImportResponse{Results: []models.MTLSClientImportResult{}}
+	for i, entry := range entries {
+		result := cg.importClient(entry, caCert, names, fingerprints)
+		result.Index = i
+		switch result.Status {
+		case models.ImportStatusImported:
+			response.Imported++
+		case models.ImportStatusSkipped:
+			response.Skipped++
+		default:
+			response.Failed++
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response, nil
+}
+
+func (cg *CertGenerator) importClient(entry models.MTLSClientImport, caCert *x509.Certificate,
+	names map[string]bool, fingerprints map[[32]byte]string) models.MTLSClientImportResult {
+	result := models.MTLSClientImportResult{Name: strings.TrimSpace(entry.Name), Status: models.ImportStatusFailed}
+
+	cert, certPEM, key, keyPEM, err := parseClientPEM(entry.Cert, entry.Key)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if result.Name == "" {
+		result.Name = cert.Subject.CommonName
+	}
+	result.IssuedByCA = caCert != nil && cert.CheckSignatureFrom(caCert) == nil
+
+	if existing, ok := fingerprints[sha256.Sum256(cert.Raw)]; ok {
+		result.Status = models.ImportStatusSkipped
+		result.Message = fmt.Sprintf("certificate already stored as %s", existing)
+		return result
+	}
+	if err := checkClientCert(cert); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if result.Name == "" {
+		result.Message = "name is required when the certificate has no common name"
+		return result
+	}
+	if names[result.Name] {
+		result.Message = fmt.Sprintf("a client named %s already exists", result.Name)
+		return result
+	}
+
+	var p12Data []byte
+	if entry.P12Password != "" {
+		if key == nil {
+			result.Message = "a PKCS#12 password needs the private key"
+			return result
+		}
+		var chain []*x509.Certificate
+		if result.IssuedByCA {
+			chain = append(chain, caCert)
+		}
+		encoder := pkcs12.Modern
+		if entry.LegacyP12 {
+			encoder = pkcs12.Legacy
+		}
+		if p12Data, err = encoder.Encode(key, cert, chain, entry.P12Password); err != nil {
+			result.Message = fmt.Sprintf("failed to generate PKCS#12: %v", err)
+			return result
+		}
+	}
+
+	id := uuid.New().String()
+	expiry := cert.NotAfter
+	_, err = cg.db.Exec(`
+		INSERT INTO mtls_clients (id, name, cert, key, p12, p12_password_hint, subject, expiry, revoked, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, result.Name, certPEM, keyPEM, p12Data, passwordHint(entry.P12Password), formatClientSubject(cert.Subject), &expiry, 0, time.Now())
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to save client: %v", err)
+		return result
+	}
+
+	names[result.Name] = true
+	fingerprints[sha256.Sum256(cert.Raw)] = result.Name
+	result.ID = id
+	result.Status = models.ImportStatusImported
+	if !result.IssuedByCA {
+		result.Message = "not issued by the configured CA; Traefik must also trust its issuer"
+	}
+	return result
+}
+
+// parseClientPEM reads the first certificate and private key from the PEM
+// blocks of cert and key, and checks the key belongs to the certificate
+func parseClientPEM(certText, keyText string) (*x509.Certificate, string, crypto.Signer, string, error) {
+	var cert *x509.Certificate
+	var certPEM, keyPEM string
+	var key crypto.Signer
+
+	rest := []byte(certText + "\n" + keyText)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE" && cert == nil:
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, "", nil, "", fmt.Errorf("failed to parse certificate: %w", err)
+			}
+			cert, certPEM = parsed, string(pem.EncodeToMemory(block))
+		case strings.HasSuffix(block.Type, "PRIVATE KEY") && key == nil:
+			parsed, err := parsePrivateKey(block)
+			if err != nil {
+				return nil, "", nil, "", err
+			}
+			key, keyPEM = parsed, string(pem.EncodeToMemory(block))
+		}
+	}
+
+	if cert == nil {
+		return nil, "", nil, "", fmt.Errorf("no PEM certificate found")
+	}
+	if key != nil {
+		public, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+		if !ok || !public.Equal(key.Public()) {
+			return nil, "", nil, "", fmt.Errorf("private key does not match the certificate")
+		}
+	}
+	return cert, certPEM, key, keyPEM, nil
+}
+
+// parsePrivateKey accepts PKCS#1, SEC 1 and PKCS#8 keys
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+		return nil, fmt.Errorf("encrypted private keys are not supported; decrypt the key first")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// checkClientCert rejects CA, expired and server-only certificates
+func checkClientCert(cert *x509.Certificate) error {
+	if cert.IsCA {
+		return fmt.Errorf("%s is a CA certificate, not a client certificate", cert.Subject.CommonName)
+	}
+	if time.Now().After(cert.NotAfter) {
+		return fmt.Errorf("certificate expired on %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if len(cert.ExtKeyUsage) == 0 {
+		return nil
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageAny {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate is not valid for client authentication")
+}
+
+// formatClientSubject renders a subject the way issued clients show it
+func formatClientSubject(subject pkix.Name) string {
+	parts := []string{"CN=" + subject.CommonName}
+	if len(subject.Organization) > 0 {
+		parts = append(parts, "O="+subject.Organization[0])
+	}
+	if len(subject.Country) > 0 {
+		parts = append(parts, "C="+subject.Country[0])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// passwordHint keeps the first and last character of a PKCS#12 password
+func passwordHint(password string) string {
+	if len(password) < 2 {
+		return ""
+	}
+	return string(password[0]) + "***" + string(password[len(password)-1])
+}
+
+// ExportClientCerts returns the certificate of every client that is not revoked
+func (cg *CertGenerator) ExportClientCerts() ([]models.MTLSClientCert, error) {
+	clients, err := cg.GetClients()
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []models.MTLSClientCert{}
+	for _, client := range clients {
+		if client.Revoked {
+			continue
+		}
+		entry := models.MTLSClientCert{
+			ID:       client.ID,
+			Name:     client.Name,
+			Subject:  client.Subject,
+			NotAfter: client.Expiry,
+			Cert:     client.Cert,
+		}
+		if cert, err := parseCertPEM(client.Cert); err == nil {
+			entry.SerialNumber = formatSerial(cert.SerialNumber)
+		}
+		certs = append(certs, entry)
+	}
+	return certs, nil
+}
+
+// ExportClientCertsPEM returns the certificates of ExportClientCerts as one PEM
+// file, each preceded by a line naming the client
+func (cg *CertGenerator) ExportClientCertsPEM() ([]byte, error) {
+	certs, err := cg.ExportClientCerts()
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	for _, cert := range certs {
+		fmt.Fprintf(&b, "# %s (%s)\n", cert.Name, cert.Subject)
+		b.WriteString(strings.TrimRight(cert.Cert, "\n") + "\n")
+	}
+	return []byte(b.String()), nil
+}
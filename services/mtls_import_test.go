@@ -0,0 +1,148 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// foreignClientPEM issues a client certificate from a throwaway CA, as easy-rsa
+// or step-ca would, and returns the certificate and PKCS#8 key as PEM
+func foreignClientPEM(t *testing.T, cn string, notAfter time.Time) (string, string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Old CA"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create client certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestCertGenerator_ImportClients(t *testing.T) {
+	db := newTestSQLDB(t)
+	cg := NewCertGenerator(db)
+
+	if _, err := cg.GenerateCA(models.CreateCARequest{CommonName: "Import CA"}, t.TempDir()); err != nil {
+		t.Fatalf("GenerateCA() error = %v", err)
+	}
+	issued, err := cg.GenerateClientCert(models.CreateClientRequest{Name: "issued", P12Password: "password123"})
+	if err != nil {
+		t.Fatalf("GenerateClientCert() error = %v", err)
+	}
+
+	laptopCert, laptopKey := foreignClientPEM(t, "laptop", time.Now().Add(90*24*time.Hour))
+	phoneCert, _ := foreignClientPEM(t, "phone", time.Now().Add(90*24*time.Hour))
+	_, otherKey := foreignClientPEM(t, "other", time.Now().Add(90*24*time.Hour))
+	expiredCert, _ := foreignClientPEM(t, "old", time.Now().Add(-time.Hour))
+
+	response, err := cg.ImportClients([]models.MTLSClientImport{
+		{Cert: laptopCert + laptopKey, P12Password: "secret99"},
+		{Name: "phone-1", Cert: phoneCert},
+		{Name: "issued", Cert: issued.Cert},
+		{Name: "mismatch", Cert: phoneCert, Key: otherKey},
+		{Cert: expiredCert},
+		{Name: "issued", Cert: laptopCert},
+		{Cert: "not a certificate"},
+	})
+	if err != nil {
+		t.Fatalf("ImportClients() error = %v", err)
+	}
+
+	want := []string{
+		models.ImportStatusImported, models.ImportStatusImported, models.ImportStatusSkipped,
+		models.ImportStatusFailed, models.ImportStatusFailed, models.ImportStatusSkipped, models.ImportStatusFailed,
+	}
+	for i, result := range response.Results {
+		if result.Status != want[i] {
+			t.Errorf("result %d (%s) = %s (%s), want %s", i, result.Name, result.Status, result.Message, want[i])
+		}
+	}
+	if response.Imported != 2 || response.Skipped != 2 || response.Failed != 3 {
+		t.Errorf("counts = %d/%d/%d, want 2/2/3", response.Imported, response.Skipped, response.Failed)
+	}
+
+	laptop := response.Results[0]
+	if laptop.Name != "laptop" || laptop.IssuedByCA {
+		t.Errorf("laptop result = %+v, want the common name and a foreign issuer", laptop)
+	}
+	if !response.Results[2].IssuedByCA {
+		t.Errorf("the re-imported MM certificate should be issued by the CA")
+	}
+	if p12, _, err := cg.GetClientP12(laptop.ID); err != nil || len(p12) == 0 {
+		t.Errorf("expected a PKCS#12 for the imported laptop, got %d bytes (%v)", len(p12), err)
+	}
+	if p12, _, _ := cg.GetClientP12(response.Results[1].ID); len(p12) != 0 {
+		t.Errorf("phone-1 was imported without a key and should have no PKCS#12")
+	}
+	stored, err := cg.GetClient(laptop.ID)
+	if err != nil || stored.Subject != "CN=laptop, O=Example" || stored.Expiry == nil {
+		t.Fatalf("stored laptop = %+v, %v", stored, err)
+	}
+
+	if err := cg.RevokeClient(response.Results[1].ID); err != nil {
+		t.Fatalf("RevokeClient() error = %v", err)
+	}
+	certs, err := cg.ExportClientCerts()
+	if err != nil {
+		t.Fatalf("ExportClientCerts() error = %v", err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("exported %d certificates, want issued and laptop: %+v", len(certs), certs)
+	}
+	for _, cert := range certs {
+		if cert.Name == "phone-1" || cert.SerialNumber == "" || !strings.Contains(cert.Cert, "BEGIN CERTIFICATE") {
+			t.Errorf("unexpected export entry %+v", cert)
+		}
+	}
+
+	bundle, err := cg.ExportClientCertsPEM()
+	if err != nil {
+		t.Fatalf("ExportClientCertsPEM() error = %v", err)
+	}
+	if strings.Count(string(bundle), "BEGIN CERTIFICATE") != 2 || !strings.Contains(string(bundle), "# laptop (CN=laptop, O=Example)") {
+		t.Errorf("unexpected PEM bundle:\n%s", bundle)
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// Pangolin circuit breaker defaults: open after 5 consecutive failed fetches
+// and probe again after 30 seconds
+const (
+	DefaultPangolinBreakerThreshold = 5
+	DefaultPangolinBreakerCooldown  = 30 * time.Second
+)
+
+// ErrPangolinCircuitOpen is returned instead of fetching while the breaker is
+// open; GetMergedConfig then serves the stale config if it has one
+var ErrPangolinCircuitOpen = errors.New("Pangolin circuit breaker is open")
+
+// CircuitBreakerStatus reports the Pangolin circuit breaker
+type CircuitBreakerStatus struct {
+	Enabled             bool       `json:"enabled"`
+	State               string     `json:"state"`
+	Threshold           int        `json:"threshold"`
+	CooldownSeconds     float64    `json:"cooldown_seconds"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	// RetryAt is when the next probe is let through while open
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	// Trips counts how often the breaker opened from closed
+	Trips int `json:"trips"`
+	// Rejected counts the fetches skipped while open
+	Rejected int64 `json:"rejected"`
+}
+
+// circuitBreaker opens after threshold consecutive failures, rejects calls
+// for cooldown, then lets a single probe through (half-open): its success
+// closes the breaker and its failure opens it for another cooldown
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int // 0 disables the breaker
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+	lastErr  string
+	trips    int
+	rejected int64
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now, state: BreakerClosed}
+}
+
+// allow reports whether a call may proceed, moving an open breaker whose
+// cooldown elapsed to half-open for one probe
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 {
+		return true
+	}
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			b.rejected++
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			b.rejected++
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record updates the breaker with the outcome of an allowed call
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 {
+		return
+	}
+	b.probing = false
+	if err == nil {
+		if b.state != BreakerClosed {
+			log.Printf("Pangolin circuit breaker closed: fetch succeeded after %d failure(s)", b.failures)
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		b.lastErr = ""
+		return
+	}
+
+	b.failures++
+	b.lastErr = err.Error()
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		if b.state == BreakerClosed {
+			b.trips++
+		}
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		log.Printf("Pangolin circuit breaker open after %d consecutive failure(s), next probe in %s: %v",
+			b.failures, b.cooldown, err)
+	}
+}
+
+func (b *circuitBreaker) status() CircuitBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := CircuitBreakerStatus{
+		Enabled:             b.threshold > 0,
+		State:               b.state,
+		Threshold:           b.threshold,
+		CooldownSeconds:     b.cooldown.Seconds(),
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastErr,
+		Trips:               b.trips,
+		Rejected:            b.rejected,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.cooldown)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// ConnectionPoolStats reports how Pangolin fetches used the HTTP connection
+// pool. Reused connections skip the TCP and TLS handshakes; a pool that only
+// opens new ones points at keep-alives being refused.
+type ConnectionPoolStats struct {
+	Requests          int64   `json:"requests"`
+	InFlight          int64   `json:"in_flight"`
+	ConnectionsOpened int64   `json:"connections_opened"`
+	ConnectionsReused int64   `json:"connections_reused"`
+	LastLatencyMs     float64 `json:"last_latency_ms"`
+}
+
+// connectionPoolStats counts connection use through an httptrace hook
+type connectionPoolStats struct {
+	mu    sync.Mutex
+	stats ConnectionPoolStats
+}
+
+// trace returns a request with a GotConn hook and a func to call once the
+// response was read
+func (p *connectionPoolStats) trace(req *http.Request) (*http.Request, func()) {
+	start := time.Now()
+	p.mu.Lock()
+	p.stats.Requests++
+	p.stats.InFlight++
+	p.mu.Unlock()
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			if info.Reused {
+				p.stats.ConnectionsReused++
+			} else {
+				p.stats.ConnectionsOpened++
+			}
+			p.mu.Unlock()
+		},
+	}
+	done := func() {
+		p.mu.Lock()
+		p.stats.InFlight--
+		p.stats.LastLatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		p.mu.Unlock()
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), done
+}
+
+func (p *connectionPoolStats) snapshot() ConnectionPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// SetPangolinCircuitBreaker configures the breaker around Pangolin fetches;
+// a threshold of 0 disables it
+func (cp *ConfigProxy) SetPangolinCircuitBreaker(threshold int, cooldown time.Duration) {
+	if cooldown <= 0 {
+		cooldown = DefaultPangolinBreakerCooldown
+	}
+	cp.pangolinBreaker = newCircuitBreaker(threshold, cooldown)
+}
+
+// PangolinBreakerStatus returns the state of the Pangolin circuit breaker
+func (cp *ConfigProxy) PangolinBreakerStatus() CircuitBreakerStatus {
+	return cp.pangolinBreaker.status()
+}
+
+// PangolinPoolStats returns the connection pool use of Pangolin fetches
+func (cp *ConfigProxy) PangolinPoolStats() ConnectionPoolStats {
+	return cp.pangolinPool.snapshot()
+}
+
+// fetchPangolinConfigGuarded fetches through the circuit breaker, so a crashed
+// Pangolin is probed once per cooldown instead of on every Traefik poll
+func (cp *ConfigProxy) fetchPangolinConfigGuarded() (*ProxiedTraefikConfig, error) {
+	if !cp.pangolinBreaker.allow() {
+		status := cp.pangolinBreaker.status()
+		return nil, fmt.Errorf("%w (last error: %s)", ErrPangolinCircuitOpen, status.LastError)
+	}
+	config, err := cp.fetchPangolinConfig()
+	cp.pangolinBreaker.record(err)
+	return config, err
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(2, 30*time.Second)
+	b.now = func() time.Time { return now }
+	fail := errors.New("connection refused")
+
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("closed breaker rejected call %d", i)
+		}
+		b.record(fail)
+	}
+	if s := b.status(); s.State != BreakerOpen || s.Trips != 1 {
+		t.Fatalf("expected open after 2 failures, got %+v", s)
+	}
+	if b.allow() {
+		t.Fatal("open breaker allowed a call before the cooldown")
+	}
+
+	now = now.Add(31 * time.Second)
+	if !b.allow() {
+		t.Fatal("expected a half-open probe after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("only one probe may run while half-open")
+	}
+	b.record(fail)
+	if s := b.status(); s.State != BreakerOpen || s.Trips != 1 || s.Rejected != 2 {
+		t.Fatalf("failed probe should reopen the breaker, got %+v", s)
+	}
+
+	now = now.Add(31 * time.Second)
+	if !b.allow() {
+		t.Fatal("expected a second probe")
+	}
+	b.record(nil)
+	if s := b.status(); s.State != BreakerClosed || s.ConsecutiveFailures != 0 || s.OpenedAt != nil {
+		t.Fatalf("successful probe should close the breaker, got %+v", s)
+	}
+}
+
+func TestConfigProxy_PangolinBreakerStopsFetches(t *testing.T) {
+	var hits atomic.Int32
+	var healthy atomic.Bool
+	pangolin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"http":{"routers":{},"services":{},"middlewares":{}}}`))
+	}))
+	defer pangolin.Close()
+	previous := CurrentRetryPolicy()
+	ConfigureRetry(RetryPolicy{Attempts: 1})
+	t.Cleanup(func() { ConfigureRetry(previous) })
+
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), pangolin.URL)
+	cp.SetPangolinCircuitBreaker(3, time.Minute)
+	now := time.Now()
+	cp.pangolinBreaker.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		cp.InvalidateCache()
+		if _, err := cp.GetMergedConfig(); err == nil {
+			t.Fatal("expected an error while Pangolin fails and nothing is cached")
+		}
+	}
+	if hits.Load() != 3 {
+		t.Errorf("expected 3 fetches before the breaker opened, got %d", hits.Load())
+	}
+	if status := cp.PangolinBreakerStatus(); status.State != BreakerOpen || status.Rejected != 7 {
+		t.Errorf("unexpected breaker status %+v", status)
+	}
+
+	healthy.Store(true)
+	now = now.Add(2 * time.Minute)
+	if _, err := cp.GetMergedConfig(); err != nil {
+		t.Fatalf("probe after the cooldown should succeed: %v", err)
+	}
+	if status := cp.PangolinBreakerStatus(); status.State != BreakerClosed {
+		t.Errorf("expected the breaker to close, got %+v", status)
+	}
+	if pool := cp.PangolinPoolStats(); pool.Requests != 4 || pool.InFlight != 0 ||
+		pool.ConnectionsOpened+pool.ConnectionsReused != 4 {
+		t.Errorf("unexpected pool stats %+v", pool)
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// pangolinCache keeps the last parsed Pangolin config keyed on the hash of
+// the response body. When Pangolin serves the same bytes again the parse is
+// skipped and only MM's override pass runs on a copy of the cached sections.
+type pangolinCache struct {
+	mu        sync.Mutex
+	hash      string
+	config    *ProxiedTraefikConfig // pristine; never handed out without cloning
+	changedAt time.Time
+	parseTime time.Duration
+	hits      uint64
+	misses    uint64
+}
+
+// PangolinCacheStats reports how often the parsed Pangolin sections were reused
+type PangolinCacheStats struct {
+	Hits            uint64     `json:"hits"`
+	Misses          uint64     `json:"misses"`
+	Hash            string     `json:"hash,omitempty"`
+	ChangedAt       *time.Time `json:"changed_at,omitempty"`
+	LastParseMs     float64    `json:"last_parse_ms"`
+	HTTPRouters     int        `json:"http_routers"`
+	HTTPServices    int        `json:"http_services"`
+	TCPRouters      int        `json:"tcp_routers"`
+	UDPRouters      int        `json:"udp_routers"`
+	HTTPMiddlewares int        `json:"http_middlewares"`
+}
+
+// load returns a private copy of the parsed body, parsing only when the body
+// differs from the cached one
+func (pc *pangolinCache) load(body []byte, decode func([]byte) (*ProxiedTraefikConfig, error)) (*ProxiedTraefikConfig, error) {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	pc.mu.Lock()
+	if pc.config != nil && pc.hash == hash {
+		pc.hits++
+		cached := pc.config
+		pc.mu.Unlock()
+		return cloneProxiedConfig(cached), nil
+	}
+	pc.mu.Unlock()
+
+	start := time.Now()
+	config, err := decode(body)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+
+	pc.mu.Lock()
+	pc.misses++
+	pc.hash = hash
+	pc.config = config
+	pc.changedAt = time.Now()
+	pc.parseTime = elapsed
+	pc.mu.Unlock()
+
+	return cloneProxiedConfig(config), nil
+}
+
+func (pc *pangolinCache) stats() PangolinCacheStats {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	stats := PangolinCacheStats{
+		Hits:        pc.hits,
+		Misses:      pc.misses,
+		Hash:        pc.hash,
+		LastParseMs: float64(pc.parseTime.Microseconds()) / 1000,
+	}
+	if !pc.changedAt.IsZero() {
+		changedAt := pc.changedAt
+		stats.ChangedAt = &changedAt
+	}
+	if pc.config != nil {
+		if pc.config.HTTP != nil {
+			stats.HTTPRouters = len(pc.config.HTTP.Routers)
+			stats.HTTPServices = len(pc.config.HTTP.Services)
+			stats.HTTPMiddlewares = len(pc.config.HTTP.Middlewares)
+		}
+		if pc.config.TCP != nil {
+			stats.TCPRouters = len(pc.config.TCP.Routers)
+		}
+		if pc.config.UDP != nil {
+			stats.UDPRouters = len(pc.config.UDP.Routers)
+		}
+	}
+	return stats
+}
+
+// PangolinCacheStats returns hit/miss counters for the parsed Pangolin sections
+func (cp *ConfigProxy) PangolinCacheStats() PangolinCacheStats {
+	return cp.pangolinCache.stats()
+}
+
+// decodeProxiedBody parses a buffered provider payload
+func (cp *ConfigProxy) decodeProxiedBody(body []byte) (*ProxiedTraefikConfig, error) {
+	return cp.decodeProxiedConfig(bytes.NewReader(body))
+}
+
+// cloneProxiedConfig deep-copies a config so the merge can mutate it freely.
+// json.RawMessage values are shared; MM only ever replaces them.
+func cloneProxiedConfig(config *ProxiedTraefikConfig) *ProxiedTraefikConfig {
+	if config == nil {
+		return nil
+	}
+	clone := &ProxiedTraefikConfig{Extra: cloneRawMap(config.Extra)}
+	if config.HTTP != nil {
+		clone.HTTP = &HTTPConfig{
+			Middlewares:       cloneValueMap(config.HTTP.Middlewares),
+			Services:          cloneValueMap(config.HTTP.Services),
+			ServersTransports: cloneValueMap(config.HTTP.ServersTransports),
+			Extra:             cloneRawMap(config.HTTP.Extra),
+		}
+		if config.HTTP.Routers != nil {
+			clone.HTTP.Routers = make(map[string]*OrderedRouter, len(config.HTTP.Routers))
+			for name, router := range config.HTTP.Routers {
+				clone.HTTP.Routers[name] = cloneRouter(router)
+			}
+		}
+	}
+	if config.TCP != nil {
+		clone.TCP = &TCPConfig{
+			Routers:  cloneValueMap(config.TCP.Routers),
+			Services: cloneValueMap(config.TCP.Services),
+			Extra:    cloneRawMap(config.TCP.Extra),
+		}
+	}
+	if config.UDP != nil {
+		clone.UDP = &UDPConfig{
+			Routers:  cloneValueMap(config.UDP.Routers),
+			Services: cloneValueMap(config.UDP.Services),
+			Extra:    cloneRawMap(config.UDP.Extra),
+		}
+	}
+	if config.TLS != nil {
+		clone.TLS = &TLSConfig{
+			Options: cloneValueMap(config.TLS.Options),
+			Extra:   cloneRawMap(config.TLS.Extra),
+		}
+	}
+	return clone
+}
+
+func cloneRouter(router *OrderedRouter) *OrderedRouter {
+	if router == nil {
+		return nil
+	}
+	clone := *router
+	clone.EntryPoints = append([]string(nil), router.EntryPoints...)
+	clone.Middlewares = append([]string(nil), router.Middlewares...)
+	clone.Extra = cloneRawMap(router.Extra)
+	if router.TLS != nil {
+		tls := *router.TLS
+		tls.Extra = cloneRawMap(router.TLS.Extra)
+		tls.Domains = make([]RouterTLSDomain, len(router.TLS.Domains))
+		for i, domain := range router.TLS.Domains {
+			tls.Domains[i] = RouterTLSDomain{Main: domain.Main, SANs: append([]string(nil), domain.SANs...)}
+		}
+		if router.TLS.Domains == nil {
+			tls.Domains = nil
+		}
+		clone.TLS = &tls
+	}
+	return &clone
+}
+
+func cloneRawMap(m map[string]json.RawMessage) map[string]json.RawMessage {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]json.RawMessage, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+func cloneValueMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		clone[k] = cloneValue(v)
+	}
+	return clone
+}
+
+// cloneValue copies the map and slice types produced by JSON decoding
+func cloneValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return cloneValueMap(value)
+	case []interface{}:
+		clone := make([]interface{}, len(value))
+		for i, item := range value {
+			clone[i] = cloneValue(item)
+		}
+		return clone
+	case []string:
+		return append([]string(nil), value...)
+	default:
+		return v
+	}
+}
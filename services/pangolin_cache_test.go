@@ -0,0 +1,61 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPangolinCacheReusesParsedSections(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	var payload atomic.Value
+	payload.Store(`{"http":{"routers":{"app-router":{"rule":"Host(` + "`app.example.com`" + `)","service":"app-service","entryPoints":["websecure"],"middlewares":["upstream"]}},"services":{"app-service":{"loadBalancer":{"servers":[{"url":"http://10.0.0.1"}]}}},"middlewares":{}}}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(payload.Load().(string)))
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+
+	first, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+	// Mutating the merged result must not leak into the cached sections
+	first.HTTP.Routers["app-router"].Middlewares = append(first.HTTP.Routers["app-router"].Middlewares, "mutated")
+	first.HTTP.Services["app-service"].(map[string]interface{})["loadBalancer"] = nil
+
+	cp.InvalidateCache()
+	second, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("second fetch failed: %v", err)
+	}
+	stats := cp.PangolinCacheStats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("expected 1 hit and 1 miss, got %+v", stats)
+	}
+	if stats.HTTPRouters != 1 || stats.HTTPServices != 1 || stats.Hash == "" {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if got := second.HTTP.Routers["app-router"].Middlewares; len(got) != 1 || got[0] != "upstream" {
+		t.Errorf("cached router was mutated: %v", got)
+	}
+	if lb := second.HTTP.Services["app-service"].(map[string]interface{})["loadBalancer"]; lb == nil {
+		t.Error("cached service was mutated")
+	}
+
+	payload.Store(`{"http":{"routers":{},"services":{},"middlewares":{}}}`)
+	cp.InvalidateCache()
+	if _, err := cp.GetMergedConfig(); err != nil {
+		t.Fatalf("third fetch failed: %v", err)
+	}
+	stats = cp.PangolinCacheStats()
+	if stats.Misses != 2 || stats.HTTPRouters != 0 {
+		t.Errorf("expected a changed payload to be reparsed, got %+v", stats)
+	}
+}
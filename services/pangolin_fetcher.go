@@ -35,8 +35,13 @@ type PangolinFetcher struct {
 
 // NewPangolinFetcher creates a new Pangolin API fetcher with connection pooling
 func NewPangolinFetcher(config models.DataSourceConfig) *PangolinFetcher {
-	// Use the shared HTTP client pool for better connection reuse
-	httpClient := GetHTTPClient()
+	// Use the shared HTTP client pool for better connection reuse, unless the
+	// data source brings its own TLS settings
+	httpClient, err := DataSourceHTTPClient(GetHTTPClient(), config)
+	if err != nil {
+		log.Printf("Warning: invalid TLS settings for Pangolin API, using defaults: %v", err)
+		httpClient = GetHTTPClient()
+	}
 
 	return &PangolinFetcher{
 		config:      config,
@@ -111,12 +116,10 @@ func (f *PangolinFetcher) fetchTraefikConfig(ctx context.Context) (*models.Pango
 
 	req.Header.Set("Content-Type", "application/json")
 
-	// Add basic auth if configured
-	if f.config.BasicAuth.Username != "" {
-		req.SetBasicAuth(f.config.BasicAuth.Username, f.config.BasicAuth.Password)
-	}
+	// Add basic auth and custom headers if configured
+	AuthorizeDataSourceRequest(req, f.config)
 
-	resp, err := f.httpClient.Do(req)
+	resp, err := doWithRetry(f.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -429,11 +429,13 @@ func FetchPluginCatalogue(ctx context.Context) ([]CataloguePlugin, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("User-Agent", "MiddlewareManager/1.0")
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := client.Do(req)
 	if err != nil {
+		if !OutboundProxyConfigured() {
+			return nil, fmt.Errorf("failed to fetch plugin catalogue (set OUTBOUND_PROXY if egress needs a proxy): %w", err)
+		}
 		return nil, fmt.Errorf("failed to fetch plugin catalogue: %w", err)
 	}
 	defer resp.Body.Close()
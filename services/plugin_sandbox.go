@@ -0,0 +1,702 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+	"gopkg.in/yaml.v3"
+)
+
+// Sandbox stages, in the order they run
+const (
+	SandboxStageDownload = "download"
+	SandboxStageManifest = "manifest"
+	SandboxStageLoad     = "load"
+	SandboxStageConfig   = "config"
+	SandboxStageInit     = "init"
+	SandboxStageRequest  = "request"
+)
+
+const (
+	// maxPluginArchiveSize bounds the module zip downloaded from the proxy
+	maxPluginArchiveSize = 50 * 1024 * 1024
+	// maxSandboxBody bounds the response body reported back
+	maxSandboxBody = 4 * 1024
+	// defaultSandboxTimeout bounds a whole sandbox run
+	defaultSandboxTimeout = 20 * time.Second
+)
+
+// sandboxPackages are the standard library packages plugins can import, by
+// their yaegi export path. Packages that read or write files by name, dial,
+// listen, start processes or reach into the runtime are left out; os/exec,
+// syscall and unsafe are never loaded.
+var sandboxPackages = map[string]bool{
+	".": true, // interface wrappers of the packages below
+
+	"archive/tar/tar": true, "archive/zip/zip": true, "bufio/bufio": true, "bytes/bytes": true,
+	"cmp/cmp": true, "compress/bzip2/bzip2": true, "compress/flate/flate": true,
+	"compress/gzip/gzip": true, "compress/lzw/lzw": true, "compress/zlib/zlib": true,
+	"container/heap/heap": true, "container/list/list": true, "container/ring/ring": true,
+	"context/context": true,
+
+	"crypto/aes/aes": true, "crypto/cipher/cipher": true, "crypto/crypto": true, "crypto/des/des": true,
+	"crypto/dsa/dsa": true, "crypto/ecdh/ecdh": true, "crypto/ecdsa/ecdsa": true,
+	"crypto/ed25519/ed25519": true, "crypto/elliptic/elliptic": true, "crypto/hmac/hmac": true,
+	"crypto/md5/md5": true, "crypto/rand/rand": true, "crypto/rc4/rc4": true, "crypto/rsa/rsa": true,
+	"crypto/sha1/sha1": true, "crypto/sha256/sha256": true, "crypto/sha512/sha512": true,
+	"crypto/subtle/subtle": true, "crypto/tls/tls": true, "crypto/x509/pkix/pkix": true,
+	"crypto/x509/x509": true,
+
+	"encoding/ascii85/ascii85": true, "encoding/asn1/asn1": true, "encoding/base32/base32": true,
+	"encoding/base64/base64": true, "encoding/binary/binary": true, "encoding/csv/csv": true,
+	"encoding/encoding": true, "encoding/gob/gob": true, "encoding/hex/hex": true,
+	"encoding/json/json": true, "encoding/pem/pem": true, "encoding/xml/xml": true,
+
+	"errors/errors": true, "flag/flag": true, "fmt/fmt": true, "hash/adler32/adler32": true,
+	"hash/crc32/crc32": true, "hash/crc64/crc64": true, "hash/fnv/fnv": true, "hash/hash": true,
+	"hash/maphash/maphash": true, "html/html": true, "io/fs/fs": true, "io/io": true,
+	"io/ioutil/ioutil": true, "log/log": true, "log/slog/slog": true, "maps/maps": true,
+	"math/big/big": true, "math/bits/bits": true, "math/cmplx/cmplx": true, "math/math": true,
+	"math/rand/rand": true, "math/rand/v2/rand": true, "mime/mime": true,
+	"mime/multipart/multipart": true, "mime/quotedprintable/quotedprintable": true,
+
+	"net/http/cookiejar/cookiejar": true, "net/http/http": true, "net/http/httptrace/httptrace": true,
+	"net/http/httputil/httputil": true, "net/mail/mail": true, "net/net": true,
+	"net/netip/netip": true, "net/textproto/textproto": true, "net/url/url": true,
+
+	"os/os": true, "path/filepath/filepath": true, "path/path": true, "reflect/reflect": true,
+	"regexp/regexp": true, "regexp/syntax/syntax": true, "slices/slices": true, "sort/sort": true,
+	"strconv/strconv": true, "strings/strings": true, "sync/atomic/atomic": true, "sync/sync": true,
+	"text/scanner/scanner": true, "text/tabwriter/tabwriter": true, "time/time": true,
+	"unicode/unicode": true, "unicode/utf16/utf16": true, "unicode/utf8/utf8": true,
+}
+
+// sandboxDeniedSymbols are left out of allowed packages: whatever opens
+// connections or reads or writes files by name. Plugins keep the net/http
+// types a middleware is made of, but no client, transport or server.
+var sandboxDeniedSymbols = map[string][]string{
+	"archive/zip/zip":         {"OpenReader"},
+	"crypto/tls/tls":          {"Dial", "DialWithDialer", "Dialer", "Listen", "LoadX509KeyPair", "NewListener"},
+	"io/ioutil/ioutil":        {"ReadDir", "ReadFile", "TempDir", "TempFile", "WriteFile"},
+	"net/textproto/textproto": {"Dial"},
+	"net/http/http": {
+		"Client", "DefaultClient", "DefaultTransport", "Dir", "FS", "FileServer", "FileServerFS",
+		"Get", "Head", "ListenAndServe", "ListenAndServeTLS", "NewFileTransport", "NewFileTransportFS",
+		"Post", "PostForm", "Serve", "ServeFile", "ServeFileFS", "ServeTLS", "Server", "Transport",
+	},
+	"net/http/httputil/httputil": {"NewSingleHostReverseProxy", "ReverseProxy"},
+	"path/filepath/filepath":     {"EvalSymlinks", "Glob", "Walk", "WalkDir"},
+}
+
+// sandboxOnlySymbols restricts packages to the listed symbols: of net,
+// addresses and their parsing; of os, the (virtual) environment, standard
+// streams and error values, without opening, reading or listing files
+var sandboxOnlySymbols = map[string]map[string]bool{
+	"net/net": {
+		"Addr": true, "AddrError": true, "CIDRMask": true, "Conn": true, "Error": true,
+		"HardwareAddr": true, "IP": true, "IPAddr": true, "IPMask": true, "IPNet": true,
+		"IPv4": true, "IPv4Mask": true, "IPv4len": true, "IPv6len": true, "InvalidAddrError": true,
+		"JoinHostPort": true, "ParseCIDR": true, "ParseError": true, "ParseIP": true,
+		"ParseMAC": true, "SplitHostPort": true, "TCPAddr": true, "UDPAddr": true,
+	},
+	"os/os": {
+		"Args": true, "Clearenv": true, "DevNull": true, "Environ": true, "ErrClosed": true,
+		"ErrDeadlineExceeded": true, "ErrExist": true, "ErrInvalid": true, "ErrNoDeadline": true,
+		"ErrNotExist": true, "ErrPermission": true, "ErrProcessDone": true, "Exit": true,
+		"Expand": true, "ExpandEnv": true, "FileInfo": true, "FileMode": true, "Getenv": true,
+		"Getpagesize": true, "Getpid": true, "Interrupt": true, "IsExist": true, "IsNotExist": true,
+		"IsPermission": true, "IsTimeout": true, "Kill": true, "LinkError": true, "LookupEnv": true,
+		"ModeDir": true, "ModePerm": true, "PathError": true, "PathListSeparator": true,
+		"PathSeparator": true, "Setenv": true, "Signal": true, "Stderr": true, "Stdin": true,
+		"Stdout": true, "SyscallError": true, "Unsetenv": true,
+	},
+}
+
+// sandboxSymbols returns the standard library symbols plugins are loaded with
+func sandboxSymbols() interp.Exports {
+	symbols := make(interp.Exports, len(sandboxPackages))
+	for pkg, values := range stdlib.Symbols {
+		if !sandboxPackages[pkg] {
+			continue
+		}
+		denied := map[string]bool{}
+		for _, name := range sandboxDeniedSymbols[pkg] {
+			denied[name] = true
+		}
+		only := sandboxOnlySymbols[pkg]
+		allowed := make(map[string]reflect.Value, len(values))
+		for name, value := range values {
+			// Interface wrappers are named after their interface with a leading _
+			base := strings.TrimPrefix(name, "_")
+			if denied[base] || (only != nil && !only[base]) {
+				continue
+			}
+			allowed[name] = value
+		}
+		symbols[pkg] = allowed
+	}
+	return symbols
+}
+
+// PluginSandbox loads a Traefik middleware plugin into Yaegi, the interpreter
+// Traefik itself runs plugins with, and sends a synthetic request through it.
+// Problems that would otherwise only show up when Traefik restarts (missing
+// imports, config that does not decode, panics in New or ServeHTTP) are
+// reported per stage instead.
+//
+// Plugins get an allow-listed part of the standard library: they cannot open,
+// read or write files by name, open connections or start processes, so they
+// cannot read MM's database or send anything out. Each run happens in a child
+// process with a scrubbed environment, which is killed when it outlives the
+// timeout.
+type PluginSandbox struct {
+	// ProxyURL is the Go module proxy plugin sources are downloaded from
+	ProxyURL string
+	Timeout  time.Duration
+	client   *http.Client
+	// fetch writes the module source below dir and returns the resolved version
+	fetch func(ctx context.Context, module, version, dir string) (string, error)
+	// spawn creates the child process a run happens in; nil runs plugins in
+	// this process
+	spawn func(ctx context.Context, dir string) (*exec.Cmd, error)
+}
+
+// NewPluginSandbox creates a sandbox downloading plugins from proxy.golang.org
+func NewPluginSandbox() *PluginSandbox {
+	s := &PluginSandbox{
+		ProxyURL: "https://proxy.golang.org",
+		Timeout:  defaultSandboxTimeout,
+		client:   HTTPClientWithTimeout(60 * time.Second),
+	}
+	s.fetch = s.fetchFromProxy
+	s.spawn = spawnSandboxChild
+	return s
+}
+
+// pluginManifest is the part of a plugin's .traefik.yml the sandbox needs
+type pluginManifest struct {
+	Type     string                 `yaml:"type"`
+	Import   string                 `yaml:"import"`
+	TestData map[string]interface{} `yaml:"testData"`
+}
+
+// Run downloads, loads and exercises a plugin. Failures are reported in the
+// result; the error is only set when the sandbox itself could not run.
+func (s *PluginSandbox) Run(ctx context.Context, req models.PluginSandboxRequest) (*models.PluginSandboxResult, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultSandboxTimeout
+	}
+	if s.spawn != nil {
+		return s.runInChild(ctx, req, timeout)
+	}
+	return s.runInProcess(ctx, req, timeout, nil)
+}
+
+// runInProcess runs the stages in this process. A plugin that never returns is
+// abandoned after the timeout; its goroutine cannot be stopped. onStage, when
+// set, is called as each stage starts with the result so far.
+func (s *PluginSandbox) runInProcess(ctx context.Context, req models.PluginSandboxRequest, timeout time.Duration,
+	onStage func(string, *models.PluginSandboxResult)) (*models.PluginSandboxResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	goPath, err := os.MkdirTemp("", "mm-plugin-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+
+	run := newSandboxRun(req)
+	run.onStage = onStage
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.execute(ctx, run, req, goPath)
+	}()
+
+	select {
+	case <-done:
+		os.RemoveAll(goPath)
+	case <-ctx.Done():
+		// The interpreter may still be running; leave its sources in place and
+		// clean up once it returns
+		go func() {
+			<-done
+			os.RemoveAll(goPath)
+		}()
+		run.fail(run.current(), fmt.Sprintf("timed out after %s", timeout), 0)
+	}
+
+	return run.snapshot(), nil
+}
+
+// execute runs every stage, stopping at the first failure
+func (s *PluginSandbox) execute(ctx context.Context, run *sandboxRun, req models.PluginSandboxRequest, goPath string) {
+	module := strings.TrimSpace(req.ModuleName)
+
+	var version string
+	if !run.stage(SandboxStageDownload, func() error {
+		var err error
+		version, err = s.fetch(ctx, module, strings.TrimSpace(req.Version), goPath)
+		return err
+	}) {
+		return
+	}
+	run.update(func(r *models.PluginSandboxResult) { r.Version = version })
+
+	moduleDir := filepath.Join(goPath, "src", filepath.FromSlash(module))
+	var manifest pluginManifest
+	if !run.stage(SandboxStageManifest, func() error {
+		var err error
+		manifest, err = readPluginManifest(moduleDir, module)
+		return err
+	}) {
+		return
+	}
+
+	config := req.Config
+	usedTestData := len(config) == 0
+	if usedTestData {
+		config = manifest.TestData
+	}
+	run.update(func(r *models.PluginSandboxResult) {
+		r.Import = manifest.Import
+		r.UsedTestData = usedTestData
+		r.Config = config
+	})
+
+	basePkg := strings.ReplaceAll(path.Base(manifest.Import), "-", "_")
+	// Plugin output goes to stderr; a child reports to the parent on stdout
+	i := interp.New(interp.Options{GoPath: goPath, Stdout: os.Stderr})
+
+	if !run.stage(SandboxStageLoad, func() error {
+		if err := i.Use(sandboxSymbols()); err != nil {
+			return fmt.Errorf("failed to load the standard library: %w", err)
+		}
+		_, err := i.EvalWithContext(ctx, fmt.Sprintf("import %q", manifest.Import))
+		return err
+	}) {
+		return
+	}
+
+	var vConfig reflect.Value
+	if !run.stage(SandboxStageConfig, func() error {
+		var err error
+		vConfig, err = i.EvalWithContext(ctx, basePkg+".CreateConfig()")
+		if err != nil {
+			return fmt.Errorf("CreateConfig: %w", err)
+		}
+		if len(config) == 0 {
+			return nil
+		}
+		data, err := json.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("config cannot be encoded: %w", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(vConfig.Interface()); err != nil {
+			return fmt.Errorf("config does not match the plugin's Config: %w", err)
+		}
+		return nil
+	}) {
+		return
+	}
+
+	upstream := &sandboxUpstream{}
+	var handler http.Handler
+	if !run.stage(SandboxStageInit, func() error {
+		fnNew, err := i.EvalWithContext(ctx, basePkg+".New")
+		if err != nil {
+			return fmt.Errorf("New: %w", err)
+		}
+		args := []reflect.Value{
+			reflect.ValueOf(ctx),
+			reflect.ValueOf(http.Handler(upstream)),
+			vConfig,
+			reflect.ValueOf("sandbox"),
+		}
+		if fnNew.Kind() != reflect.Func || fnNew.Type().NumIn() != len(args) || fnNew.Type().NumOut() != 2 {
+			return fmt.Errorf("New has signature %s, want func(context.Context, http.Handler, *Config, string) (http.Handler, error)", fnNew.Type())
+		}
+		results := fnNew.Call(args)
+		if errValue, ok := results[1].Interface().(error); ok && errValue != nil {
+			return fmt.Errorf("New returned an error: %w", errValue)
+		}
+		h, ok := results[0].Interface().(http.Handler)
+		if !ok || h == nil {
+			return fmt.Errorf("New did not return an http.Handler")
+		}
+		handler = h
+		return nil
+	}) {
+		return
+	}
+
+	run.stage(SandboxStageRequest, func() error {
+		httpReq, err := buildSandboxRequest(ctx, req.Request)
+		if err != nil {
+			return err
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httpReq)
+
+		response := &models.PluginSandboxResponse{
+			Status:          rec.Code,
+			Headers:         flattenHeaders(rec.Header()),
+			Body:            truncateBody(rec.Body.Bytes()),
+			CalledNext:      upstream.called,
+			UpstreamHeaders: flattenHeaders(upstream.headers),
+		}
+		run.update(func(r *models.PluginSandboxResult) { r.Response = response })
+		return nil
+	})
+}
+
+// sandboxRun collects stage results; the handler goroutine and the timeout
+// path both write to it
+type sandboxRun struct {
+	mu      sync.Mutex
+	result  *models.PluginSandboxResult
+	running string
+	onStage func(string, *models.PluginSandboxResult)
+}
+
+func newSandboxRun(req models.PluginSandboxRequest) *sandboxRun {
+	return &sandboxRun{
+		result: &models.PluginSandboxResult{
+			ModuleName: req.ModuleName,
+			Version:    req.Version,
+			Stages:     []models.PluginSandboxStage{},
+		},
+	}
+}
+
+// stage runs fn as the named stage, recording its outcome and any panic.
+// It returns false when the stage failed or the run already timed out.
+func (r *sandboxRun) stage(name string, fn func() error) (ok bool) {
+	r.mu.Lock()
+	if r.result.FailedStage != "" {
+		r.mu.Unlock()
+		return false
+	}
+	r.running = name
+	r.mu.Unlock()
+	if r.onStage != nil {
+		r.onStage(name, r.snapshot())
+	}
+
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			r.mu.Lock()
+			r.result.Panic = fmt.Sprintf("%v\n%s", p, debug.Stack())
+			r.mu.Unlock()
+			r.fail(name, fmt.Sprintf("panic: %v", p), time.Since(start))
+			ok = false
+		}
+	}()
+
+	if err := fn(); err != nil {
+		r.fail(name, err.Error(), time.Since(start))
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.result.FailedStage != "" {
+		return false
+	}
+	r.result.Stages = append(r.result.Stages, models.PluginSandboxStage{
+		Name:       name,
+		OK:         true,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	})
+	if name == SandboxStageRequest {
+		r.result.OK = true
+	}
+	return true
+}
+
+// fail records a failed stage unless one was already recorded
+func (r *sandboxRun) fail(name, message string, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.result.FailedStage != "" {
+		return
+	}
+	if name == "" {
+		name = SandboxStageDownload
+	}
+	r.result.OK = false
+	r.result.FailedStage = name
+	r.result.Stages = append(r.result.Stages, models.PluginSandboxStage{
+		Name:       name,
+		Error:      message,
+		DurationMs: float64(elapsed.Microseconds()) / 1000,
+	})
+}
+
+func (r *sandboxRun) current() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+func (r *sandboxRun) update(fn func(*models.PluginSandboxResult)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(r.result)
+}
+
+// snapshot copies the result so a still-running plugin cannot change it
+func (r *sandboxRun) snapshot() *models.PluginSandboxResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := *r.result
+	result.Stages = append([]models.PluginSandboxStage(nil), r.result.Stages...)
+	return &result
+}
+
+// sandboxUpstream stands in for the service behind the middleware
+type sandboxUpstream struct {
+	called  bool
+	headers http.Header
+}
+
+func (u *sandboxUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.called = true
+	u.headers = r.Header.Clone()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, "sandbox upstream")
+}
+
+// buildSandboxRequest creates the synthetic request, defaulting to GET /
+func buildSandboxRequest(ctx context.Context, spec *models.PluginSandboxHTTPRequest) (*http.Request, error) {
+	method, target, body := http.MethodGet, "http://sandbox.local/", ""
+	var headers map[string]string
+	if spec != nil {
+		if spec.Method != "" {
+			method = strings.ToUpper(spec.Method)
+		}
+		if spec.URL != "" {
+			target = spec.URL
+			if strings.HasPrefix(target, "/") {
+				target = "http://sandbox.local" + target
+			}
+		}
+		body = spec.Body
+		headers = spec.Headers
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox request: %w", err)
+	}
+	req.RemoteAddr = "192.0.2.1:12345"
+	for key, value := range headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+// readPluginManifest parses .traefik.yml from the module root
+func readPluginManifest(moduleDir, module string) (pluginManifest, error) {
+	var manifest pluginManifest
+	data, err := os.ReadFile(filepath.Join(moduleDir, ".traefik.yml"))
+	if err != nil {
+		return manifest, fmt.Errorf("module has no .traefik.yml manifest: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid .traefik.yml: %w", err)
+	}
+	if manifest.Type != "" && manifest.Type != "middleware" {
+		return manifest, fmt.Errorf("plugin type is %q; only middleware plugins can be tested", manifest.Type)
+	}
+	if manifest.Import == "" {
+		manifest.Import = module
+	}
+	if manifest.Import != module && !strings.HasPrefix(manifest.Import, module+"/") {
+		return manifest, fmt.Errorf("manifest import %q is outside module %q", manifest.Import, module)
+	}
+	return manifest, nil
+}
+
+// fetchFromProxy downloads the module zip from the Go module proxy and unpacks
+// it into dir laid out as a GOPATH
+func (s *PluginSandbox) fetchFromProxy(ctx context.Context, module, version, dir string) (string, error) {
+	escaped, err := escapeModulePath(module)
+	if err != nil {
+		return "", err
+	}
+	base := strings.TrimRight(s.ProxyURL, "/") + "/" + escaped
+
+	if version == "" {
+		var latest struct {
+			Version string `json:"Version"`
+		}
+		data, err := s.get(ctx, base+"/@latest", 64*1024)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve latest version: %w", err)
+		}
+		if err := json.Unmarshal(data, &latest); err != nil || latest.Version == "" {
+			return "", fmt.Errorf("module proxy returned no version for %s", module)
+		}
+		version = latest.Version
+	}
+
+	escapedVersion, err := escapeModulePath(version)
+	if err != nil {
+		return "", err
+	}
+	archive, err := s.get(ctx, base+"/@v/"+escapedVersion+".zip", maxPluginArchiveSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s@%s: %w", module, version, err)
+	}
+
+	if err := unpackModuleZip(archive, module+"@"+version, filepath.Join(dir, "src", filepath.FromSlash(module))); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+func (s *PluginSandbox) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("module proxy returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+// unpackModuleZip extracts a module zip, whose entries are all prefixed with
+// module@version/, into dest
+func unpackModuleZip(archive []byte, prefix, dest string) error {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return fmt.Errorf("invalid module archive: %w", err)
+	}
+
+	var total uint64
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, prefix+"/")
+		if name == f.Name || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		clean := filepath.Clean(filepath.FromSlash(name))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("module archive contains unsafe path %q", f.Name)
+		}
+		total += f.UncompressedSize64
+		if total > maxPluginArchiveSize*4 {
+			return fmt.Errorf("module archive expands beyond %d bytes", maxPluginArchiveSize*4)
+		}
+
+		target := filepath.Join(dest, clean)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.LimitReader(rc, int64(f.UncompressedSize64)+1)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// escapeModulePath applies the module proxy's case encoding: upper-case
+// letters become '!' followed by the lower-case letter
+func escapeModulePath(p string) (string, error) {
+	if p == "" || strings.Contains(p, "..") || strings.ContainsAny(p, " \\?#%") {
+		return "", fmt.Errorf("invalid module path or version %q", p)
+	}
+	var b strings.Builder
+	for _, r := range p {
+		if r == '!' || r > unicode.MaxASCII {
+			return "", fmt.Errorf("invalid module path or version %q", p)
+		}
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
+
+func flattenHeaders(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	flat := make(map[string]string, len(h))
+	for key, values := range h {
+		flat[key] = strings.Join(values, ", ")
+	}
+	return flat
+}
+
+func truncateBody(body []byte) string {
+	if len(body) > maxSandboxBody {
+		return string(body[:maxSandboxBody]) + "…"
+	}
+	return string(body)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// pluginSandboxChildEnv marks a process started to run one sandbox request
+const pluginSandboxChildEnv = "MM_PLUGIN_SANDBOX_CHILD"
+
+// sandboxChildGrace is how long a child may outlive the run timeout before it
+// is killed; the child stops the run itself and reports it first
+const sandboxChildGrace = 5 * time.Second
+
+// sandboxChildEnvKeys are the only variables a child inherits, so it can reach
+// the module proxy while its environment holds no tokens, passwords or DSNs.
+// The files those are kept in are guarded by sandboxSymbols, not by the child.
+var sandboxChildEnvKeys = []string{
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	"SSL_CERT_FILE", "SSL_CERT_DIR",
+}
+
+// sandboxChildInput is what the parent writes to the child's stdin
+type sandboxChildInput struct {
+	Request  models.PluginSandboxRequest `json:"request"`
+	ProxyURL string                      `json:"proxy_url"`
+	Timeout  time.Duration               `json:"timeout"`
+}
+
+// sandboxChildEvent is one line the child writes to stdout: the stage that
+// started with the result so far, or the final result
+type sandboxChildEvent struct {
+	Running string                      `json:"running,omitempty"`
+	Done    bool                        `json:"done,omitempty"`
+	Result  *models.PluginSandboxResult `json:"result"`
+}
+
+// IsPluginSandboxChild reports whether this process was started by the plugin
+// sandbox to run a plugin
+func IsPluginSandboxChild() bool {
+	return os.Getenv(pluginSandboxChildEnv) == "1"
+}
+
+// RunPluginSandboxChild runs the sandbox request on stdin in this process and
+// reports on stdout. main calls it in the child and exits with its result.
+func RunPluginSandboxChild() int {
+	s := NewPluginSandbox()
+	s.spawn = nil
+	if err := s.serveChild(os.Stdin, os.Stdout); err != nil {
+		log.Printf("Plugin sandbox child failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// serveChild is the child's side of runInChild
+func (s *PluginSandbox) serveChild(r io.Reader, w io.Writer) error {
+	var input sandboxChildInput
+	if err := json.NewDecoder(r).Decode(&input); err != nil {
+		return fmt.Errorf("failed to read the sandbox request: %w", err)
+	}
+	if input.ProxyURL != "" {
+		s.ProxyURL = input.ProxyURL
+	}
+
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	emit := func(event sandboxChildEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = encoder.Encode(event)
+	}
+
+	result, err := s.runInProcess(context.Background(), input.Request, input.Timeout, func(stage string, result *models.PluginSandboxResult) {
+		emit(sandboxChildEvent{Running: stage, Result: result})
+	})
+	if err != nil {
+		return err
+	}
+	emit(sandboxChildEvent{Done: true, Result: result})
+	return nil
+}
+
+// spawnSandboxChild re-runs this binary as a sandbox child
+func spawnSandboxChild(ctx context.Context, dir string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the MM binary: %w", err)
+	}
+	return sandboxChildCommand(ctx, dir, executable), nil
+}
+
+// sandboxChildCommand runs name in dir with only the sandbox environment
+func sandboxChildCommand(ctx context.Context, dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = sandboxChildEnv(dir)
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+func sandboxChildEnv(dir string) []string {
+	env := []string{pluginSandboxChildEnv + "=1", "HOME=" + dir, "TMPDIR=" + dir}
+	for _, key := range sandboxChildEnvKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// runInChild runs the stages in a child process. When the child dies or is
+// killed before it reports, the run fails in the stage it was in.
+func (s *PluginSandbox) runInChild(ctx context.Context, req models.PluginSandboxRequest, timeout time.Duration) (*models.PluginSandboxResult, error) {
+	dir, err := os.MkdirTemp("", "mm-plugin-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout+sandboxChildGrace)
+	defer cancel()
+	cmd, err := s.spawn(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	input, err := json.Marshal(sandboxChildInput{Request: req, ProxyURL: s.ProxyURL, Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	stderr := &tailBuffer{limit: maxSandboxBody}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start the sandbox process: %w", err)
+	}
+
+	last := sandboxChildEvent{Result: newSandboxRun(req).snapshot()}
+	done := false
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event sandboxChildEvent
+		// Anything else on stdout is not from the sandbox
+		if json.Unmarshal(scanner.Bytes(), &event) != nil || event.Result == nil {
+			continue
+		}
+		last = event
+		if event.Done {
+			done = true
+			break
+		}
+	}
+	waitErr := cmd.Wait()
+	if done {
+		return last.Result, nil
+	}
+
+	run := &sandboxRun{result: last.Result}
+	if run.result.Stages == nil {
+		run.result.Stages = []models.PluginSandboxStage{}
+	}
+	message := fmt.Sprintf("sandbox process exited: %v", waitErr)
+	if ctx.Err() != nil {
+		message = fmt.Sprintf("timed out after %s; the sandbox process was killed", timeout)
+	} else if output := strings.TrimSpace(stderr.String()); output != "" {
+		message += ": " + output
+	}
+	run.fail(last.Running, message, 0)
+	return run.snapshot(), nil
+}
+
+// tailBuffer keeps the last limit bytes written to it
+type tailBuffer struct {
+	limit int
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.limit {
+		b.buf = b.buf[len(b.buf)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
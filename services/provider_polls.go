@@ -0,0 +1,238 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxProviderPollClients bounds how many distinct pollers are remembered;
+// the least recently seen one is dropped first
+const maxProviderPollClients = 32
+
+// DefaultProviderPollMaxAge is how recent a successful poll must be for the
+// provider to count as healthy. Traefik polls every 5s by default.
+const DefaultProviderPollMaxAge = 60 * time.Second
+
+// DefaultConsumerWindow is how recently a client must have polled to count
+// as an active consumer
+const DefaultConsumerWindow = 5 * time.Minute
+
+// pollIntervalWeight is the weight of the newest gap in the moving average
+const pollIntervalWeight = 0.2
+
+// ProviderPollClient is what is known about one client of /traefik-config,
+// identified by remote IP and User-Agent
+type ProviderPollClient struct {
+	ClientIP    string     `json:"client_ip"`
+	UserAgent   string     `json:"user_agent"`
+	FirstSeen   time.Time  `json:"first_seen"`
+	LastSeen    time.Time  `json:"last_seen"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Requests    int64      `json:"requests"`
+	Failures    int64      `json:"failures"`
+	// Revision is the config revision last served to this client
+	Revision uint64 `json:"revision"`
+	// Endpoints counts requests per path: /api/v1/traefik-config or
+	// /api/traefik-config
+	Endpoints map[string]int64 `json:"endpoints"`
+	// IntervalSeconds is a moving average of the gap between requests,
+	// roughly the client's poll interval
+	IntervalSeconds float64 `json:"interval_seconds,omitempty"`
+}
+
+// ProviderConsumers lists the clients polling the config endpoint, with
+// warnings when none or several are active
+type ProviderConsumers struct {
+	WindowSeconds float64              `json:"window_seconds"`
+	Active        int                  `json:"active"`
+	Consumers     []ProviderPollClient `json:"consumers"`
+	Warnings      []string             `json:"warnings"`
+}
+
+// ProviderHealth reports whether Traefik is polling the merged config
+type ProviderHealth struct {
+	Healthy       bool                 `json:"healthy"`
+	Message       string               `json:"message"`
+	MaxAgeSeconds float64              `json:"max_age_seconds"`
+	LastServedAt  *time.Time           `json:"last_served_at,omitempty"`
+	Revision      uint64               `json:"revision"`
+	Clients       []ProviderPollClient `json:"clients"`
+}
+
+// providerPollLog tracks requests to the config endpoint per client
+type providerPollLog struct {
+	mu      sync.Mutex
+	clients map[string]*ProviderPollClient
+}
+
+// record notes one request to endpoint; err is the reason the config was
+// not served
+func (l *providerPollLog) record(clientIP, userAgent, endpoint string, revision uint64, err error, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.clients == nil {
+		l.clients = make(map[string]*ProviderPollClient)
+	}
+	key := clientIP + "\x00" + userAgent
+	client, ok := l.clients[key]
+	if !ok {
+		if len(l.clients) >= maxProviderPollClients {
+			l.evictOldest()
+		}
+		client = &ProviderPollClient{ClientIP: clientIP, UserAgent: userAgent, FirstSeen: now, Endpoints: map[string]int64{}}
+		l.clients[key] = client
+	}
+
+	if client.Requests > 0 {
+		gap := now.Sub(client.LastSeen).Seconds()
+		if client.IntervalSeconds == 0 {
+			client.IntervalSeconds = gap
+		} else {
+			client.IntervalSeconds += pollIntervalWeight * (gap - client.IntervalSeconds)
+		}
+	}
+	client.LastSeen = now
+	client.Requests++
+	client.Endpoints[endpoint]++
+	if err != nil {
+		client.Failures++
+		client.LastError = err.Error()
+		return
+	}
+	served := now
+	client.LastSuccess = &served
+	client.LastError = ""
+	client.Revision = revision
+}
+
+func (l *providerPollLog) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, client := range l.clients {
+		if oldestKey == "" || client.LastSeen.Before(oldest) {
+			oldestKey, oldest = key, client.LastSeen
+		}
+	}
+	delete(l.clients, oldestKey)
+}
+
+// snapshot copies the clients, most recently seen first
+func (l *providerPollLog) snapshot() []ProviderPollClient {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clients := make([]ProviderPollClient, 0, len(l.clients))
+	for _, client := range l.clients {
+		c := *client
+		c.Endpoints = make(map[string]int64, len(client.Endpoints))
+		for endpoint, n := range client.Endpoints {
+			c.Endpoints[endpoint] = n
+		}
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].LastSeen.After(clients[j].LastSeen)
+	})
+	return clients
+}
+
+// health summarises the log: healthy when some client got the config within
+// maxAge and its latest request did not fail
+func (l *providerPollLog) health(maxAge time.Duration, revision uint64, now time.Time) ProviderHealth {
+	health := ProviderHealth{
+		MaxAgeSeconds: maxAge.Seconds(),
+		Revision:      revision,
+		Clients:       l.snapshot(),
+	}
+	for _, client := range health.Clients {
+		if client.LastSuccess == nil {
+			continue
+		}
+		if health.LastServedAt == nil || client.LastSuccess.After(*health.LastServedAt) {
+			served := *client.LastSuccess
+			health.LastServedAt = &served
+		}
+		if client.LastError == "" && now.Sub(*client.LastSuccess) <= maxAge {
+			health.Healthy = true
+		}
+	}
+
+	switch {
+	case health.Healthy:
+		health.Message = "Traefik is polling the merged config"
+	case len(health.Clients) == 0:
+		health.Message = "Nothing has fetched the config since startup; check that Traefik's providers.http.endpoint points at this instance and not at Pangolin"
+	case health.LastServedAt == nil:
+		health.Message = "The config endpoint has only answered with errors"
+	default:
+		health.Message = "No successful poll within max_age; Traefik may have stopped polling or been pointed elsewhere"
+	}
+	return health
+}
+
+// consumers lists clients seen within window and flags the setups that
+// usually mean a misconfiguration: nobody polling, or several pollers
+func (l *providerPollLog) consumers(window time.Duration, now time.Time) ProviderConsumers {
+	result := ProviderConsumers{
+		WindowSeconds: window.Seconds(),
+		Consumers:     l.snapshot(),
+		Warnings:      []string{},
+	}
+
+	var active []string
+	for _, client := range result.Consumers {
+		if now.Sub(client.LastSeen) > window {
+			continue
+		}
+		result.Active++
+		active = append(active, describePollClient(client))
+		if client.LastError != "" {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("%s got an error on its last poll: %s", describePollClient(client), client.LastError))
+		}
+	}
+
+	switch {
+	case result.Active == 0 && len(result.Consumers) == 0:
+		result.Warnings = append(result.Warnings,
+			"Nothing has polled the config since startup; Traefik's providers.http.endpoint may still point at Pangolin")
+	case result.Active == 0:
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("Nothing polled the config in the last %s; Traefik may be down or pointed elsewhere", window))
+	case result.Active > 1:
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("%d clients polled the config in the last %s (%s); check for a second or stale Traefik instance",
+				result.Active, window, strings.Join(active, ", ")))
+	}
+	return result
+}
+
+func describePollClient(client ProviderPollClient) string {
+	if client.UserAgent == "" {
+		return client.ClientIP
+	}
+	return fmt.Sprintf("%s (%s)", client.ClientIP, client.UserAgent)
+}
+
+// RecordProviderPoll notes a request to the config endpoint and whether the
+// merged config was served
+func (cp *ConfigProxy) RecordProviderPoll(clientIP, userAgent, endpoint string, err error) {
+	cp.providerPolls.record(clientIP, userAgent, endpoint, cp.ConfigRevision(), err, time.Now().UTC())
+}
+
+// ProviderConsumers lists the clients of the config endpoint seen within
+// window, with misconfiguration warnings
+func (cp *ConfigProxy) ProviderConsumers(window time.Duration) ProviderConsumers {
+	return cp.providerPolls.consumers(window, time.Now().UTC())
+}
+
+// ProviderHealth reports who polled the config endpoint and whether any
+// poll succeeded within maxAge
+func (cp *ConfigProxy) ProviderHealth(maxAge time.Duration) ProviderHealth {
+	return cp.providerPolls.health(maxAge, cp.ConfigRevision(), time.Now().UTC())
+}
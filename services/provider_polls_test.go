@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProviderPollLog_Health(t *testing.T) {
+	var l providerPollLog
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	health := l.health(time.Minute, 0, now)
+	if health.Healthy || len(health.Clients) != 0 {
+		t.Fatalf("empty log health = %+v, want unhealthy with no clients", health)
+	}
+
+	l.record("172.18.0.5", "Go-http-client/1.1", "/api/v1/traefik-config", 3, nil, now.Add(-10*time.Second))
+	l.record("172.18.0.9", "curl/8.0", "/api/traefik-config", 3, errors.New("pangolin down"), now.Add(-5*time.Second))
+
+	health = l.health(time.Minute, 3, now)
+	if !health.Healthy {
+		t.Fatalf("health = %+v, want healthy", health)
+	}
+	if len(health.Clients) != 2 || health.Clients[0].ClientIP != "172.18.0.9" {
+		t.Errorf("clients = %+v, want both, most recent first", health.Clients)
+	}
+	if health.Clients[0].Failures != 1 || health.Clients[0].LastError != "pangolin down" {
+		t.Errorf("failed client = %+v, want the failure recorded", health.Clients[0])
+	}
+	if health.LastServedAt == nil || !health.LastServedAt.Equal(now.Add(-10*time.Second)) {
+		t.Errorf("LastServedAt = %v, want the successful poll", health.LastServedAt)
+	}
+
+	// Stale once the last success is older than max age
+	if health := l.health(5*time.Second, 3, now); health.Healthy {
+		t.Errorf("health with 5s max age = %+v, want unhealthy", health)
+	}
+
+	// A failure after the success makes that client unhealthy too
+	l.record("172.18.0.5", "Go-http-client/1.1", "/api/v1/traefik-config", 3, errors.New("merge failed"), now)
+	if health := l.health(time.Minute, 3, now); health.Healthy {
+		t.Errorf("health after failure = %+v, want unhealthy", health)
+	}
+}
+
+func TestProviderPollLog_EvictsOldestClient(t *testing.T) {
+	var l providerPollLog
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= maxProviderPollClients; i++ {
+		l.record("10.0.0.1", string(rune('a'+i)), "/api/v1/traefik-config", 1, nil, start.Add(time.Duration(i)*time.Second))
+	}
+
+	health := l.health(time.Hour, 1, start)
+	if len(health.Clients) != maxProviderPollClients {
+		t.Fatalf("clients = %d, want %d", len(health.Clients), maxProviderPollClients)
+	}
+	for _, client := range health.Clients {
+		if client.UserAgent == "a" {
+			t.Error("oldest client was kept")
+		}
+	}
+}
+
+func TestProviderPollLog_Consumers(t *testing.T) {
+	var l providerPollLog
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := l.consumers(time.Minute, now); got.Active != 0 || len(got.Warnings) != 1 {
+		t.Fatalf("empty log consumers = %+v, want one warning", got)
+	}
+
+	// One Traefik polling every 5s on the v1 path
+	for i := 4; i >= 0; i-- {
+		l.record("172.18.0.5", "Go-http-client/1.1", "/api/v1/traefik-config", 1, nil, now.Add(-time.Duration(i)*5*time.Second))
+	}
+	got := l.consumers(time.Minute, now)
+	if got.Active != 1 || len(got.Warnings) != 0 {
+		t.Fatalf("single consumer = %+v, want one active and no warnings", got)
+	}
+	consumer := got.Consumers[0]
+	if consumer.Endpoints["/api/v1/traefik-config"] != 5 {
+		t.Errorf("Endpoints = %v, want 5 requests to /api/v1/traefik-config", consumer.Endpoints)
+	}
+	if consumer.IntervalSeconds < 4.9 || consumer.IntervalSeconds > 5.1 {
+		t.Errorf("IntervalSeconds = %v, want about 5", consumer.IntervalSeconds)
+	}
+
+	// A second instance on the legacy path is flagged
+	l.record("172.18.0.7", "Go-http-client/1.1", "/api/traefik-config", 1, nil, now)
+	got = l.consumers(time.Minute, now)
+	if got.Active != 2 || len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "172.18.0.7") {
+		t.Errorf("two consumers = %+v, want a warning naming both", got)
+	}
+
+	// Nobody within the window
+	if got := l.consumers(time.Minute, now.Add(time.Hour)); got.Active != 0 || len(got.Warnings) != 1 {
+		t.Errorf("stale consumers = %+v, want one warning", got)
+	}
+}
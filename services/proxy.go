@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/hhftechnology/middleware-manager/models"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyDirect as a data source proxy_url bypasses the outbound proxy
+const ProxyDirect = "direct"
+
+// ProxyConfig is the outbound proxy used by the catalogue, Pangolin and
+// Traefik fetchers. Proxy URLs may use http, https, socks5 or socks5h.
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	// NoProxy uses the NO_PROXY syntax: hosts, domains, IPs and CIDRs,
+	// comma separated
+	NoProxy string
+}
+
+var (
+	outboundProxyMu sync.RWMutex
+	outboundProxy   func(*url.URL) (*url.URL, error)
+	outboundProxyOn bool
+)
+
+func init() {
+	// Until ConfigureOutboundProxy runs, honour the standard variables
+	env := httpproxy.FromEnvironment()
+	setOutboundProxy(ProxyConfig{HTTPProxy: env.HTTPProxy, HTTPSProxy: env.HTTPSProxy, NoProxy: env.NoProxy})
+}
+
+// ConfigureOutboundProxy replaces the outbound proxy for every HTTP client
+// built by this package, including ones created earlier
+func ConfigureOutboundProxy(cfg ProxyConfig) error {
+	for _, raw := range []string{cfg.HTTPProxy, cfg.HTTPSProxy} {
+		if raw == "" {
+			continue
+		}
+		if _, err := parseProxyURL(raw); err != nil {
+			return err
+		}
+	}
+	setOutboundProxy(cfg)
+	return nil
+}
+
+func setOutboundProxy(cfg ProxyConfig) {
+	fn := (&httpproxy.Config{
+		HTTPProxy:  cfg.HTTPProxy,
+		HTTPSProxy: cfg.HTTPSProxy,
+		NoProxy:    cfg.NoProxy,
+	}).ProxyFunc()
+
+	outboundProxyMu.Lock()
+	outboundProxy = fn
+	outboundProxyOn = cfg.HTTPProxy != "" || cfg.HTTPSProxy != ""
+	outboundProxyMu.Unlock()
+}
+
+// OutboundProxyConfigured reports whether requests may go through a proxy
+func OutboundProxyConfigured() bool {
+	outboundProxyMu.RLock()
+	defer outboundProxyMu.RUnlock()
+	return outboundProxyOn
+}
+
+// OutboundProxy is the Proxy func of every transport in this package. Docker
+// service names (hosts without a dot) are always dialled directly: a remote
+// proxy cannot resolve them.
+func OutboundProxy(req *http.Request) (*url.URL, error) {
+	if isDockerServiceHost(req.URL.Hostname()) {
+		return nil, nil
+	}
+	outboundProxyMu.RLock()
+	fn := outboundProxy
+	outboundProxyMu.RUnlock()
+	return fn(req.URL)
+}
+
+// dataSourceProxy returns the Proxy func for a data source: its own
+// proxy_url when set, "direct" for none, otherwise the outbound proxy
+func dataSourceProxy(config models.DataSourceConfig) (func(*http.Request) (*url.URL, error), error) {
+	switch strings.TrimSpace(config.ProxyURL) {
+	case "":
+		return OutboundProxy, nil
+	case ProxyDirect:
+		return nil, nil
+	}
+	proxyURL, err := parseProxyURL(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https, socks5 or socks5h", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+	return u, nil
+}
+
+func isDockerServiceHost(host string) bool {
+	return host != "" && !strings.Contains(host, ".") && net.ParseIP(host) == nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Limits of injected faults; delays stay under the API server's write timeout
+const (
+	maxChaosDelay        = 10 * time.Second
+	defaultChaosDuration = 10 * time.Minute
+	maxChaosDuration     = 24 * time.Hour
+)
+
+// ChaosHeader names the faults injected into a config endpoint response
+const ChaosHeader = "X-MM-Chaos"
+
+// ErrChaosDisabled is returned when faults are set while PROXY_CHAOS is off
+var ErrChaosDisabled = errors.New("chaos mode is disabled")
+
+// ChaosFaults are the faults injected into responses of the config endpoint
+// Traefik polls, to rehearse how it copes with a slow, stale or broken
+// provider. The management endpoints are never affected.
+type ChaosFaults struct {
+	// DelayMS holds each response back this long
+	DelayMS int `json:"delay_ms"`
+	// Stale keeps serving the config merged when the faults were set
+	Stale bool `json:"stale"`
+	// Truncate cuts the JSON body in half
+	Truncate bool `json:"truncate"`
+	// Probability is the share of polls affected, from 0 to 1 (0 means 1)
+	Probability float64 `json:"probability"`
+	// DurationSeconds is how long the faults last (default 600)
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	SetAt           time.Time `json:"set_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// Validate checks the faults and fills in the defaults
+func (f *ChaosFaults) Validate() error {
+	if f.DelayMS < 0 || time.Duration(f.DelayMS)*time.Millisecond > maxChaosDelay {
+		return fmt.Errorf("delay_ms must be between 0 and %d", maxChaosDelay.Milliseconds())
+	}
+	if f.DelayMS == 0 && !f.Stale && !f.Truncate {
+		return fmt.Errorf("set at least one of delay_ms, stale or truncate")
+	}
+	if f.Probability < 0 || f.Probability > 1 {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	if f.Probability == 0 {
+		f.Probability = 1
+	}
+	if f.DurationSeconds < 0 || time.Duration(f.DurationSeconds)*time.Second > maxChaosDuration {
+		return fmt.Errorf("duration_seconds must be between 0 and %d", int(maxChaosDuration.Seconds()))
+	}
+	if f.DurationSeconds == 0 {
+		f.DurationSeconds = int(defaultChaosDuration.Seconds())
+	}
+	return nil
+}
+
+// ChaosStatus reports whether chaos mode is on and the active faults
+type ChaosStatus struct {
+	Enabled bool         `json:"enabled"`
+	Faults  *ChaosFaults `json:"faults,omitempty"`
+	// Injected counts the responses faults were injected into since they were set
+	Injected int64 `json:"injected"`
+}
+
+// ChaosPlan is what to do to one response of the config endpoint
+type ChaosPlan struct {
+	Delay time.Duration
+	// Config is the stale config to serve instead of a fresh merge (nil serves a fresh one)
+	Config   *ProxiedTraefikConfig
+	Truncate bool
+}
+
+// chaosState is the chaos mode of the config proxy
+type chaosState struct {
+	enabled  bool
+	faults   *ChaosFaults
+	stale    *ProxiedTraefikConfig
+	injected int64
+}
+
+// SetChaosMode allows or forbids injecting faults; turning it off clears them
+func (cp *ConfigProxy) SetChaosMode(enabled bool) {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	if enabled && !cp.chaos.enabled {
+		log.Printf("WARNING: chaos mode is on; faults can be injected into the Traefik config endpoint. Do not use in production.")
+	}
+	cp.chaos.enabled = enabled
+	if !enabled {
+		cp.chaos = chaosState{}
+	}
+}
+
+// ChaosStatus returns whether chaos mode is on and the active faults
+func (cp *ConfigProxy) ChaosStatus() ChaosStatus {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.expireChaos(time.Now())
+	status := ChaosStatus{Enabled: cp.chaos.enabled, Injected: cp.chaos.injected}
+	if cp.chaos.faults != nil {
+		faults := *cp.chaos.faults
+		status.Faults = &faults
+	}
+	return status
+}
+
+// SetChaosFaults replaces the injected faults. A stale fault captures the
+// config served now.
+func (cp *ConfigProxy) SetChaosFaults(faults ChaosFaults) (*ChaosFaults, error) {
+	if !cp.chaosEnabled() {
+		return nil, ErrChaosDisabled
+	}
+	if err := faults.Validate(); err != nil {
+		return nil, err
+	}
+
+	var stale *ProxiedTraefikConfig
+	if faults.Stale {
+		config, err := cp.GetMergedConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture the config to serve stale: %w", err)
+		}
+		stale = config
+	}
+
+	now := time.Now().UTC()
+	faults.SetAt = now
+	faults.ExpiresAt = now.Add(time.Duration(faults.DurationSeconds) * time.Second)
+
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	if !cp.chaos.enabled {
+		return nil, ErrChaosDisabled
+	}
+	cp.chaos.faults = &faults
+	cp.chaos.stale = stale
+	cp.chaos.injected = 0
+	log.Printf("WARNING: chaos faults set on the Traefik config endpoint until %s: %s",
+		faults.ExpiresAt.Format(time.RFC3339), describeChaos(faults.DelayMS, faults.Stale, faults.Truncate))
+	return &faults, nil
+}
+
+// ClearChaosFaults stops injecting faults
+func (cp *ConfigProxy) ClearChaosFaults() {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	if cp.chaos.faults != nil {
+		log.Printf("Chaos faults cleared after %d injected responses", cp.chaos.injected)
+	}
+	cp.chaos.faults = nil
+	cp.chaos.stale = nil
+}
+
+// PlanChaos decides the faults of one config endpoint response; nil
+// serves it normally
+func (cp *ConfigProxy) PlanChaos() *ChaosPlan {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.expireChaos(time.Now())
+	faults := cp.chaos.faults
+	if faults == nil || rand.Float64() >= faults.Probability {
+		return nil
+	}
+	cp.chaos.injected++
+	return &ChaosPlan{
+		Delay:    time.Duration(faults.DelayMS) * time.Millisecond,
+		Config:   cp.chaos.stale,
+		Truncate: faults.Truncate,
+	}
+}
+
+func (cp *ConfigProxy) chaosEnabled() bool {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+	return cp.chaos.enabled
+}
+
+// expireChaos drops faults past their expiry; callers hold cacheMutex
+func (cp *ConfigProxy) expireChaos(now time.Time) {
+	if cp.chaos.faults != nil && !now.Before(cp.chaos.faults.ExpiresAt) {
+		log.Printf("Chaos faults expired after %d injected responses", cp.chaos.injected)
+		cp.chaos.faults = nil
+		cp.chaos.stale = nil
+	}
+}
+
+// Describe lists the faults of the plan for the ChaosHeader
+func (p *ChaosPlan) Describe() string {
+	return describeChaos(int(p.Delay.Milliseconds()), p.Config != nil, p.Truncate)
+}
+
+// Write encodes config, cut in half when the plan truncates it
+func (p *ChaosPlan) Write(w io.Writer, config *ProxiedTraefikConfig) error {
+	if !p.Truncate {
+		return config.WriteJSON(w)
+	}
+	var buf bytes.Buffer
+	if err := config.WriteJSON(&buf); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes()[:buf.Len()/2])
+	return err
+}
+
+func describeChaos(delayMS int, stale, truncate bool) string {
+	var faults []string
+	if delayMS > 0 {
+		faults = append(faults, fmt.Sprintf("delay=%dms", delayMS))
+	}
+	if stale {
+		faults = append(faults, "stale")
+	}
+	if truncate {
+		faults = append(faults, "truncate")
+	}
+	return strings.Join(faults, ",")
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConfigProxyChaosFaults(t *testing.T) {
+	var router atomic.Value
+	router.Store("first")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		name := router.Load().(string)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					name: map[string]interface{}{"rule": "Host(`" + name + ".example.com`)", "service": "svc"},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), server.URL)
+	cp.httpClient = server.Client()
+
+	if _, err := cp.SetChaosFaults(ChaosFaults{Truncate: true}); !errors.Is(err, ErrChaosDisabled) {
+		t.Fatalf("expected ErrChaosDisabled while chaos mode is off, got %v", err)
+	}
+	if cp.PlanChaos() != nil {
+		t.Fatalf("expected no faults while chaos mode is off")
+	}
+
+	cp.SetChaosMode(true)
+	if _, err := cp.SetChaosFaults(ChaosFaults{Probability: 2, Truncate: true}); err == nil {
+		t.Fatalf("expected a probability above 1 to be rejected")
+	}
+	faults, err := cp.SetChaosFaults(ChaosFaults{DelayMS: 5, Stale: true, Truncate: true})
+	if err != nil {
+		t.Fatalf("SetChaosFaults() error = %v", err)
+	}
+	if faults.Probability != 1 || faults.ExpiresAt.Sub(faults.SetAt) != defaultChaosDuration {
+		t.Errorf("expected the defaults to be filled in, got %+v", faults)
+	}
+
+	// The stale config is the one captured when the faults were set
+	router.Store("second")
+	cp.InvalidateCache()
+	plan := cp.PlanChaos()
+	if plan == nil || plan.Delay != 5*time.Millisecond || !plan.Truncate || plan.Config == nil {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if plan.Config.HTTP.Routers["first"] == nil {
+		t.Errorf("expected the stale config to hold the first router")
+	}
+	if plan.Describe() != "delay=5ms,stale,truncate" {
+		t.Errorf("Describe() = %q", plan.Describe())
+	}
+
+	var full, truncated bytes.Buffer
+	if err := plan.Config.WriteJSON(&full); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if err := plan.Write(&truncated, plan.Config); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if truncated.Len() != full.Len()/2 || json.Valid(truncated.Bytes()) {
+		t.Errorf("expected half of the %d byte body as invalid JSON, got %d bytes", full.Len(), truncated.Len())
+	}
+	if status := cp.ChaosStatus(); !status.Enabled || status.Faults == nil || status.Injected != 1 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	// Expired faults are dropped
+	cp.cacheMutex.Lock()
+	cp.chaos.faults.ExpiresAt = time.Now().Add(-time.Second)
+	cp.cacheMutex.Unlock()
+	if cp.PlanChaos() != nil || cp.ChaosStatus().Faults != nil {
+		t.Errorf("expected expired faults to be dropped")
+	}
+
+	if _, err := cp.SetChaosFaults(ChaosFaults{Truncate: true}); err != nil {
+		t.Fatalf("SetChaosFaults() error = %v", err)
+	}
+	cp.SetChaosMode(false)
+	if status := cp.ChaosStatus(); status.Enabled || status.Faults != nil {
+		t.Errorf("expected turning chaos mode off to clear the faults, got %+v", status)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// withOutboundProxy configures cfg for the test and restores a proxy-less
+// setup afterwards
+func withOutboundProxy(t *testing.T, cfg ProxyConfig) {
+	t.Helper()
+	if err := ConfigureOutboundProxy(cfg); err != nil {
+		t.Fatalf("ConfigureOutboundProxy() error = %v", err)
+	}
+	t.Cleanup(func() { setOutboundProxy(ProxyConfig{}) })
+}
+
+// newRecordingProxy is a plain HTTP proxy that answers every request itself
+// and records the absolute URLs it was asked for
+func newRecordingProxy(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.String())
+		mu.Unlock()
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestOutboundProxy(t *testing.T) {
+	proxy, _ := newRecordingProxy(t)
+	withOutboundProxy(t, ProxyConfig{HTTPProxy: proxy.URL, HTTPSProxy: proxy.URL, NoProxy: "internal.example.com,10.0.0.0/8"})
+
+	tests := []struct {
+		target    string
+		wantProxy bool
+	}{
+		{"https://plugins.traefik.io/api/services/plugins", true},
+		{"http://pangolin.example.org/api/v1/traefik-config", true},
+		{"http://api.internal.example.com/api/version", false},
+		{"http://10.1.2.3:8080/api/version", false},
+		{"http://traefik:8080/api/version", false},
+		{"http://localhost:8080/api/version", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		got, err := OutboundProxy(req)
+		if err != nil {
+			t.Fatalf("OutboundProxy(%s) error = %v", tt.target, err)
+		}
+		if (got != nil) != tt.wantProxy {
+			t.Errorf("OutboundProxy(%s) = %v, want proxied %v", tt.target, got, tt.wantProxy)
+		}
+	}
+	if !OutboundProxyConfigured() {
+		t.Error("OutboundProxyConfigured() = false, want true")
+	}
+}
+
+func TestOutboundProxy_SharedClientUsesProxy(t *testing.T) {
+	proxy, seen := newRecordingProxy(t)
+	withOutboundProxy(t, ProxyConfig{HTTPProxy: proxy.URL})
+
+	// The transport reads the proxy on each request, so a client built
+	// before the proxy was configured picks it up too
+	client := HTTPClientWithTimeout(5 * time.Second)
+	resp, err := client.Get("http://catalogue.example.org/plugins")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if got := seen(); len(got) != 1 || got[0] != "http://catalogue.example.org/plugins" {
+		t.Errorf("proxy saw %v, want the catalogue request", got)
+	}
+}
+
+func TestConfigureOutboundProxy_Invalid(t *testing.T) {
+	t.Cleanup(func() { setOutboundProxy(ProxyConfig{}) })
+	for _, raw := range []string{"ftp://proxy:21", "socks5://", "://bad"} {
+		if err := ConfigureOutboundProxy(ProxyConfig{HTTPSProxy: raw}); err == nil {
+			t.Errorf("ConfigureOutboundProxy(%q) error = nil, want error", raw)
+		}
+	}
+}
+
+func TestDataSourceProxy(t *testing.T) {
+	withOutboundProxy(t, ProxyConfig{HTTPSProxy: "http://corp-proxy.example.com:3128"})
+	req := httptest.NewRequest(http.MethodGet, "https://pangolin.example.org/api/v1", nil)
+
+	tests := []struct {
+		name     string
+		proxyURL string
+		want     string
+	}{
+		{"outbound proxy", "", "http://corp-proxy.example.com:3128"},
+		{"direct", ProxyDirect, ""},
+		{"own socks proxy", "socks5h://tunnel:1080", "socks5h://tunnel:1080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, err := dataSourceProxy(models.DataSourceConfig{ProxyURL: tt.proxyURL})
+			if err != nil {
+				t.Fatalf("dataSourceProxy() error = %v", err)
+			}
+			var got *url.URL
+			if proxy != nil {
+				if got, err = proxy(req); err != nil {
+					t.Fatalf("proxy() error = %v", err)
+				}
+			}
+			if (got == nil && tt.want != "") || (got != nil && got.String() != tt.want) {
+				t.Errorf("proxy = %v, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := dataSourceProxy(models.DataSourceConfig{ProxyURL: "gopher://x"}); err == nil {
+		t.Error("dataSourceProxy() with bad scheme error = nil, want error")
+	}
+}
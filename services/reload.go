@@ -0,0 +1,19 @@
+package services
+
+import "time"
+
+// Triggers of a settings reload
+const (
+	ReloadSignal = "signal"
+	ReloadAPI    = "api"
+)
+
+// ReloadReport is the outcome of re-reading the settings without a restart
+type ReloadReport struct {
+	Trigger    string    `json:"trigger"`
+	ReloadedAt time.Time `json:"reloaded_at"`
+	// Applied lists what was re-read and took effect
+	Applied []string `json:"applied"`
+	// RestartRequired lists changed settings that only take effect after a restart
+	RestartRequired []string `json:"restart_required"`
+}
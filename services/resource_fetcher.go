@@ -65,6 +65,14 @@ func NewFullDataFetcher(config models.DataSourceConfig) (FullDataFetcher, error)
 	}
 }
 
+// persistFetcherURL lets a Traefik fetcher save a fallback URL that answered
+// through the config manager; other fetchers are left alone
+func persistFetcherURL(fetcher ResourceFetcher, configManager *ConfigManager) {
+	if tf, ok := fetcher.(*TraefikFetcher); ok && configManager != nil {
+		tf.SetURLPersister(configManager.PersistTraefikURL)
+	}
+}
+
 // traefikFullFetcher wraps TraefikFetcher to implement FullDataFetcher
 type traefikFullFetcher struct {
 	*TraefikFetcher
@@ -72,7 +80,7 @@ type traefikFullFetcher struct {
 
 // GetTraefikRouters returns routers from Traefik API
 func (f *traefikFullFetcher) GetTraefikRouters(ctx context.Context) ([]models.TraefikRouter, error) {
-	apiResponse, err := f.fetchAllEndpointsConcurrently(ctx, f.config.URL)
+	apiResponse, err := f.fetchAllEndpointsConcurrently(ctx, f.baseURL())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// pinnedResource is the stored state of a pinned resource the watcher compares
+// against the data source
+type pinnedResource struct {
+	host           string
+	serviceID      string
+	status         string
+	routerPriority int
+	priorityManual bool
+	divergence     string
+}
+
+// loadPinned returns the stored state of a resource when it is pinned
+func (rw *ResourceWatcher) loadPinned(internalID string) (*pinnedResource, bool, error) {
+	var p pinnedResource
+	var pinned, priorityManual int
+	err := rw.db.QueryRow(`
+        SELECT pinned, host, service_id, status, COALESCE(router_priority, 0),
+               COALESCE(router_priority_manual, 0), pin_divergence
+        FROM resources WHERE id = ?
+    `, internalID).Scan(&pinned, &p.host, &p.serviceID, &p.status, &p.routerPriority,
+		&priorityManual, &p.divergence)
+	if err != nil {
+		return nil, false, err
+	}
+	if pinned == 0 {
+		return nil, false, nil
+	}
+	p.priorityManual = priorityManual > 0
+	return &p, true, nil
+}
+
+// pinDivergence lists the watcher-owned fields where a fetched resource
+// differs from the pinned one
+func pinDivergence(p *pinnedResource, resource models.Resource) []models.PinDivergence {
+	diffs := []models.PinDivergence{}
+	if p.host != resource.Host {
+		diffs = append(diffs, models.PinDivergence{Field: "host", Pinned: p.host, Upstream: resource.Host})
+	}
+	if p.serviceID != resource.ServiceID {
+		diffs = append(diffs, models.PinDivergence{Field: "service_id", Pinned: p.serviceID, Upstream: resource.ServiceID})
+	}
+	if resource.RouterPriority > 0 && !p.priorityManual && p.routerPriority != resource.RouterPriority {
+		diffs = append(diffs, models.PinDivergence{
+			Field:    "router_priority",
+			Pinned:   strconv.Itoa(p.routerPriority),
+			Upstream: strconv.Itoa(resource.RouterPriority),
+		})
+	}
+	if p.status != "active" {
+		diffs = append(diffs, models.PinDivergence{Field: "status", Pinned: p.status, Upstream: "active"})
+	}
+	return diffs
+}
+
+// recordPinDivergence stores the divergence of a pinned resource, logging
+// only when it changes so every check does not repeat the same report
+func (rw *ResourceWatcher) recordPinDivergence(internalID string, p *pinnedResource, diffs []models.PinDivergence) error {
+	if len(diffs) > 0 {
+		fields := make([]string, len(diffs))
+		for i, d := range diffs {
+			fields[i] = d.Field
+		}
+		rw.skip(internalID, p.host, "pinned; data source differs on "+strings.Join(fields, ", "))
+	}
+
+	data, err := json.Marshal(diffs)
+	if err != nil {
+		return fmt.Errorf("failed to encode pin divergence: %w", err)
+	}
+	if string(data) == p.divergence {
+		return nil
+	}
+
+	if len(diffs) == 0 {
+		log.Printf("Pinned resource %s (%s) matches the data source again", internalID, p.host)
+	} else {
+		for _, d := range diffs {
+			log.Printf("Pinned resource %s (%s): data source has %s=%q, keeping %q",
+				internalID, p.host, d.Field, d.Upstream, d.Pinned)
+		}
+	}
+
+	_, err = rw.db.Exec("UPDATE resources SET pin_divergence = ? WHERE id = ?", string(data), internalID)
+	if err != nil {
+		return fmt.Errorf("failed to record pin divergence for %s: %w", internalID, err)
+	}
+	return nil
+}
+
+// updatePinnedResource syncs the fields a pin does not protect and reports
+// divergence on host, service, priority and status instead of applying it
+func (rw *ResourceWatcher) updatePinnedResource(internalID, pangolinRouterID string, p *pinnedResource, resource models.Resource) error {
+	if err := rw.recordPinDivergence(internalID, p, pinDivergence(p, resource)); err != nil {
+		return err
+	}
+
+	result, err := rw.db.Exec(`
+        UPDATE resources
+        SET pangolin_router_id = ?, source_type = ?, entrypoints = ?, tls_domains = ?, tcp_enabled = ?, updated_at = ?
+        WHERE id = ? AND (COALESCE(pangolin_router_id, '') != ? OR COALESCE(source_type, '') != ?
+                          OR COALESCE(entrypoints, '') != ?)
+    `, pangolinRouterID, resource.SourceType, resource.Entrypoints, resource.TLSDomains, resource.TCPEnabled,
+		time.Now(), internalID, pangolinRouterID, resource.SourceType, resource.Entrypoints)
+	if err != nil {
+		return fmt.Errorf("failed to update pinned resource %s: %w", internalID, err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		rw.record(ResourceUpdated, internalID, p.host)
+	}
+	return nil
+}
+
+// disableMissingResource disables a resource the data source no longer
+// returns. Pinned resources stay active and record the divergence instead.
+func (rw *ResourceWatcher) disableMissingResource(resourceID string) error {
+	p, pinned, err := rw.loadPinned(resourceID)
+	if err != nil {
+		return fmt.Errorf("failed to read resource %s: %w", resourceID, err)
+	}
+	if pinned {
+		diffs := []models.PinDivergence{{Field: "status", Pinned: p.status, Upstream: "disabled"}}
+		return rw.recordPinDivergence(resourceID, p, diffs)
+	}
+
+	log.Printf("Resource %s no longer exists, marking as disabled", resourceID)
+	result, err := rw.db.Exec(
+		"UPDATE resources SET status = 'disabled', updated_at = ? WHERE id = ? AND pinned = 0",
+		time.Now(), resourceID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark resource %s as disabled: %w", resourceID, err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		var host string
+		if err := rw.db.QueryRow("SELECT host FROM resources WHERE id = ?", resourceID).Scan(&host); err != nil {
+			log.Printf("Error reading host of resource %s: %v", resourceID, err)
+		}
+		rw.record(ResourceDisabled, resourceID, host)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestResourceWatcher_PinnedResourceOnlyReportsDivergence(t *testing.T) {
+	db := newTestDB(t)
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc"},
+		{ID: "api", Host: "api.example.com", ServiceID: "api-svc"},
+	}}}
+	rw := &ResourceWatcher{db: db, fetcher: fetcher, configManager: newTestConfigManager(t)}
+
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if _, err := db.Exec("UPDATE resources SET pinned = 1 WHERE host IN ('app.example.com', 'api.example.com')"); err != nil {
+		t.Fatalf("failed to pin resources: %v", err)
+	}
+
+	// Pangolin now points app at another service and drops api entirely
+	fetcher.resources = &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "wrong-svc", Entrypoints: "web"},
+	}}
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+
+	var serviceID, entrypoints, raw string
+	if err := db.QueryRow("SELECT service_id, entrypoints, pin_divergence FROM resources WHERE host = 'app.example.com'").
+		Scan(&serviceID, &entrypoints, &raw); err != nil {
+		t.Fatalf("failed to read app: %v", err)
+	}
+	if serviceID != "app-svc" {
+		t.Errorf("pinned service_id = %q, want app-svc", serviceID)
+	}
+	if entrypoints != "web" {
+		t.Errorf("entrypoints = %q, want web: fields outside the pin should still sync", entrypoints)
+	}
+	var diffs []models.PinDivergence
+	if err := json.Unmarshal([]byte(raw), &diffs); err != nil {
+		t.Fatalf("invalid pin_divergence %q: %v", raw, err)
+	}
+	if len(diffs) != 1 || diffs[0].Field != "service_id" || diffs[0].Upstream != "wrong-svc" {
+		t.Errorf("unexpected divergence %+v", diffs)
+	}
+
+	if got := resourceStatus(t, rw, "api.example.com"); got != "active" {
+		t.Errorf("pinned resource missing upstream has status %q, want active", got)
+	}
+	if err := db.QueryRow("SELECT pin_divergence FROM resources WHERE host = 'api.example.com'").Scan(&raw); err != nil {
+		t.Fatalf("failed to read api: %v", err)
+	}
+	if raw != `[{"field":"status","pinned":"active","upstream":"disabled"}]` {
+		t.Errorf("unexpected divergence for a missing resource: %s", raw)
+	}
+
+	// Once Pangolin agrees again the divergence is cleared
+	fetcher.resources = &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc", Entrypoints: "web"},
+		{ID: "api", Host: "api.example.com", ServiceID: "api-svc"},
+	}}
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if err := db.QueryRow("SELECT pin_divergence FROM resources WHERE host = 'app.example.com'").Scan(&raw); err != nil {
+		t.Fatalf("failed to read app: %v", err)
+	}
+	if raw != "[]" {
+		t.Errorf("divergence after recovery = %s, want []", raw)
+	}
+}
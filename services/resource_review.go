@@ -0,0 +1,241 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Kinds of pending resource changes
+const (
+	PendingCreate        = "create"
+	PendingDisable       = "disable"
+	PendingServiceChange = "service_change"
+)
+
+// PendingChange is a resource change the watcher found in review mode
+type PendingChange struct {
+	ID               string    `json:"id"`
+	Kind             string    `json:"kind"`
+	ResourceID       string    `json:"resource_id,omitempty"`
+	PangolinRouterID string    `json:"pangolin_router_id,omitempty"`
+	Host             string    `json:"host"`
+	OldServiceID     string    `json:"old_service_id,omitempty"`
+	NewServiceID     string    `json:"new_service_id,omitempty"`
+	DetectedAt       time.Time `json:"detected_at"`
+	// AutoApproveAt is when the change applies unless approved earlier
+	AutoApproveAt *time.Time `json:"auto_approve_at,omitempty"`
+
+	// resource is the fetched resource for creations and service changes
+	resource models.Resource
+}
+
+func (c PendingChange) key() string {
+	if c.Kind == PendingCreate {
+		return c.Kind + ":" + c.PangolinRouterID
+	}
+	return c.Kind + ":" + c.ResourceID
+}
+
+// ResourceReview keeps the queue of resource changes waiting for approval
+// and applies them
+type ResourceReview struct {
+	db            *database.DB
+	configManager *ConfigManager
+	now           func() time.Time
+
+	// resources applies approved changes with the watcher's own queries;
+	// it is never started
+	resources *ResourceWatcher
+}
+
+// NewResourceReview creates a new resource review queue
+func NewResourceReview(db *database.DB, configManager *ConfigManager) *ResourceReview {
+	return &ResourceReview{
+		db:            db,
+		configManager: configManager,
+		now:           time.Now,
+		resources:     &ResourceWatcher{db: db, configManager: configManager},
+	}
+}
+
+// GetResourceReviewConfig returns the review mode settings
+func (cm *ConfigManager) GetResourceReviewConfig() models.ResourceReviewConfig {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.ResourceReview == nil {
+		return models.ResourceReviewConfig{}
+	}
+	return *cm.config.ResourceReview
+}
+
+// SetResourceReviewConfig saves the review mode settings
+func (cm *ConfigManager) SetResourceReviewConfig(cfg models.ResourceReviewConfig) error {
+	if cfg.AutoApproveMinutes < 0 {
+		return fmt.Errorf("auto_approve_minutes must not be negative")
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.config.ResourceReview = &cfg
+	return cm.saveConfig()
+}
+
+// Enabled reports whether the watcher should queue changes
+func (r *ResourceReview) Enabled() bool {
+	return r.configManager.GetResourceReviewConfig().Enabled
+}
+
+// sync replaces the queue with the changes found by the latest watcher run.
+// Changes still present keep their detected_at; changes that went away,
+// such as disables caused by a briefly empty config, are dropped.
+func (r *ResourceReview) sync(changes []PendingChange) error {
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		keys := make([]interface{}, 0, len(changes))
+		for _, change := range changes {
+			data, err := json.Marshal(change.resource)
+			if err != nil {
+				return fmt.Errorf("failed to encode pending change: %w", err)
+			}
+			_, err = tx.Exec(`
+				INSERT INTO pending_changes (
+					id, change_key, kind, resource_id, pangolin_router_id, host,
+					old_service_id, new_service_id, data, detected_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(change_key) DO UPDATE SET
+					host = excluded.host, old_service_id = excluded.old_service_id,
+					new_service_id = excluded.new_service_id, data = excluded.data
+			`, uuid.New().String(), change.key(), change.Kind, change.ResourceID, change.PangolinRouterID,
+				change.Host, change.OldServiceID, change.NewServiceID, string(data), r.now().UTC())
+			if err != nil {
+				return fmt.Errorf("failed to queue %s change for %s: %w", change.Kind, change.Host, err)
+			}
+			keys = append(keys, change.key())
+		}
+
+		query := "DELETE FROM pending_changes"
+		if len(keys) > 0 {
+			query += " WHERE change_key NOT IN (?" + strings.Repeat(", ?", len(keys)-1) + ")"
+		}
+		if _, err := tx.Exec(query, keys...); err != nil {
+			return fmt.Errorf("failed to drop resolved pending changes: %w", err)
+		}
+		return nil
+	})
+}
+
+// Pending lists the queued changes, oldest first
+func (r *ResourceReview) Pending() ([]PendingChange, error) {
+	rows, err := r.db.Query(`
+		SELECT id, kind, resource_id, pangolin_router_id, host, old_service_id, new_service_id, data, detected_at
+		FROM pending_changes ORDER BY detected_at, host
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending changes: %w", err)
+	}
+	defer rows.Close()
+
+	autoApprove := time.Duration(r.configManager.GetResourceReviewConfig().AutoApproveMinutes) * time.Minute
+	changes := []PendingChange{}
+	for rows.Next() {
+		var change PendingChange
+		var data string
+		if err := rows.Scan(&change.ID, &change.Kind, &change.ResourceID, &change.PangolinRouterID, &change.Host,
+			&change.OldServiceID, &change.NewServiceID, &data, &change.DetectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending change: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &change.resource); err != nil {
+			return nil, fmt.Errorf("failed to decode pending change %s: %w", change.ID, err)
+		}
+		if autoApprove > 0 {
+			at := change.DetectedAt.Add(autoApprove)
+			change.AutoApproveAt = &at
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// Approve applies the queued changes with the given IDs, or all of them when
+// ids is empty, and returns how many were applied. A change that fails
+// stays queued.
+func (r *ResourceReview) Approve(ids []string) (int, error) {
+	changes, err := r.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	applied := 0
+	var failed []string
+	for _, change := range changes {
+		if len(ids) > 0 && !wanted[change.ID] {
+			continue
+		}
+		if err := r.apply(change); err != nil {
+			log.Printf("Failed to apply %s change for %s: %v", change.Kind, change.Host, err)
+			failed = append(failed, fmt.Sprintf("%s %s: %v", change.Kind, change.Host, err))
+			continue
+		}
+		if _, err := r.db.Exec("DELETE FROM pending_changes WHERE id = ?", change.ID); err != nil {
+			return applied, fmt.Errorf("failed to dequeue change %s: %w", change.ID, err)
+		}
+		applied++
+	}
+
+	if len(failed) > 0 {
+		return applied, fmt.Errorf("%d changes failed: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return applied, nil
+}
+
+// approveDue applies changes that have waited out the auto-approve delay
+func (r *ResourceReview) approveDue() (int, error) {
+	changes, err := r.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	now := r.now()
+	var due []string
+	for _, change := range changes {
+		if change.AutoApproveAt != nil && !now.Before(*change.AutoApproveAt) {
+			due = append(due, change.ID)
+		}
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+	return r.Approve(due)
+}
+
+func (r *ResourceReview) apply(change PendingChange) error {
+	switch change.Kind {
+	case PendingCreate:
+		_, err := r.resources.updateOrCreateResource(change.resource)
+		return err
+	case PendingServiceChange:
+		return r.resources.updateExistingResourceByInternalID(change.ResourceID, change.PangolinRouterID, change.resource)
+	case PendingDisable:
+		_, err := r.db.Exec(
+			"UPDATE resources SET status = 'disabled', updated_at = ? WHERE id = ? AND status = 'active' AND pinned = 0",
+			time.Now(), change.ResourceID,
+		)
+		return err
+	default:
+		return fmt.Errorf("unknown change kind %q", change.Kind)
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// newReviewWatcher returns a watcher in review mode reading from fetcher
+func newReviewWatcher(t *testing.T, fetcher ResourceFetcher, autoApproveMinutes int) *ResourceWatcher {
+	t.Helper()
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+	if err := cm.SetResourceReviewConfig(models.ResourceReviewConfig{Enabled: true, AutoApproveMinutes: autoApproveMinutes}); err != nil {
+		t.Fatalf("SetResourceReviewConfig() error = %v", err)
+	}
+	return &ResourceWatcher{
+		db:            db,
+		fetcher:       fetcher,
+		configManager: cm,
+		review:        NewResourceReview(db, cm),
+	}
+}
+
+func resourceStatus(t *testing.T, rw *ResourceWatcher, host string) string {
+	t.Helper()
+	var status string
+	if err := rw.db.QueryRow("SELECT status FROM resources WHERE host = ?", host).Scan(&status); err != nil {
+		return ""
+	}
+	return status
+}
+
+func TestResourceReview_EmptyConfigIsHeldBack(t *testing.T) {
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc"},
+	}}}
+	rw := newReviewWatcher(t, fetcher, 0)
+
+	// Seed the resource as if review mode had been off
+	if _, err := rw.updateOrCreateResource(fetcher.resources.Resources[0]); err != nil {
+		t.Fatalf("updateOrCreateResource() error = %v", err)
+	}
+
+	// Pangolin briefly returns nothing: the disable is queued, not applied
+	fetcher.resources = &models.ResourceCollection{}
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if got := resourceStatus(t, rw, "app.example.com"); got != "active" {
+		t.Errorf("status = %q, want active while the disable is pending", got)
+	}
+	pending, err := rw.review.Pending()
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].Kind != PendingDisable || pending[0].Host != "app.example.com" {
+		t.Fatalf("Pending() = %+v, want one disable for app.example.com", pending)
+	}
+
+	// The resource is back on the next run and the disable is dropped
+	fetcher.resources = &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc"},
+	}}
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if pending, _ := rw.review.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %+v, want none after the resource came back", pending)
+	}
+}
+
+func TestResourceReview_Approve(t *testing.T) {
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc"},
+		{ID: "new", Host: "new.example.com", ServiceID: "new-svc"},
+	}}}
+	rw := newReviewWatcher(t, fetcher, 0)
+	if _, err := rw.updateOrCreateResource(models.Resource{ID: "app", Host: "app.example.com", ServiceID: "old-svc"}); err != nil {
+		t.Fatalf("updateOrCreateResource() error = %v", err)
+	}
+
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	pending, err := rw.review.Pending()
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	kinds := map[string]PendingChange{}
+	for _, change := range pending {
+		kinds[change.Kind] = change
+	}
+	if kinds[PendingCreate].Host != "new.example.com" {
+		t.Errorf("missing create for new.example.com in %+v", pending)
+	}
+	if change := kinds[PendingServiceChange]; change.OldServiceID != "old-svc" || change.NewServiceID != "app-svc" {
+		t.Errorf("service change = %+v, want old-svc -> app-svc", change)
+	}
+	if resourceStatus(t, rw, "new.example.com") != "" {
+		t.Error("new resource was created before approval")
+	}
+
+	// Approving only the creation leaves the service change queued
+	applied, err := rw.review.Approve([]string{kinds[PendingCreate].ID})
+	if err != nil || applied != 1 {
+		t.Fatalf("Approve(create) = %d, %v; want 1, nil", applied, err)
+	}
+	if got := resourceStatus(t, rw, "new.example.com"); got != "active" {
+		t.Errorf("new resource status = %q, want active", got)
+	}
+
+	applied, err = rw.review.Approve(nil)
+	if err != nil || applied != 1 {
+		t.Fatalf("Approve(all) = %d, %v; want 1, nil", applied, err)
+	}
+	var serviceID string
+	rw.db.QueryRow("SELECT service_id FROM resources WHERE host = 'app.example.com'").Scan(&serviceID)
+	if serviceID != "app-svc" {
+		t.Errorf("service_id = %q, want app-svc", serviceID)
+	}
+	if pending, _ := rw.review.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %+v, want empty", pending)
+	}
+}
+
+func TestResourceReview_AutoApprove(t *testing.T) {
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "new", Host: "new.example.com", ServiceID: "new-svc"},
+	}}}
+	rw := newReviewWatcher(t, fetcher, 10)
+	now := time.Now().UTC()
+	rw.review.now = func() time.Time { return now }
+
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	pending, _ := rw.review.Pending()
+	if len(pending) != 1 || pending[0].AutoApproveAt == nil {
+		t.Fatalf("Pending() = %+v, want one change with an auto-approve time", pending)
+	}
+
+	// Later runs keep the original detection time, so the delay elapses
+	now = now.Add(11 * time.Minute)
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if got := resourceStatus(t, rw, "new.example.com"); got != "active" {
+		t.Errorf("status = %q, want active after auto-approve", got)
+	}
+}
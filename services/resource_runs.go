@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Triggers of a resource watcher run
+const (
+	RunScheduled = "scheduled"
+	RunManual    = "manual"
+	RunResync    = "resync"
+	RunReload    = "reload"
+	RunRestore   = "restore"
+)
+
+// resourceRunHistory is how many runs the run log keeps
+const resourceRunHistory = 500
+
+// ResourceRun summarizes one resource watcher run
+type ResourceRun struct {
+	ID         int64             `json:"id,omitempty"`
+	Trigger    string            `json:"trigger"`
+	Source     string            `json:"source"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMS int64             `json:"duration_ms"`
+	Fetched    int               `json:"fetched"`
+	Created    int               `json:"created"`
+	Updated    int               `json:"updated"`
+	Disabled   int               `json:"disabled"`
+	Queued     int               `json:"queued"`
+	Skipped    []SkippedResource `json:"skipped"`
+	Changes    []ResourceChange  `json:"changes"`
+	// SharedHosts are hosts several fetched routers serve
+	SharedHosts []SharedHost `json:"shared_hosts,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// SkippedResource is a resource a run left alone, with the reason
+type SkippedResource struct {
+	ResourceID string `json:"resource_id,omitempty"`
+	Host       string `json:"host"`
+	Reason     string `json:"reason"`
+}
+
+// beginRun starts collecting the changes of a run
+func (rw *ResourceWatcher) beginRun() *ResourceRun {
+	run := &ResourceRun{
+		StartedAt: time.Now().UTC(),
+		Skipped:   []SkippedResource{},
+		Changes:   []ResourceChange{},
+	}
+	if rw.configManager != nil {
+		run.Source = rw.configManager.GetActiveSourceName()
+	}
+	rw.run = run
+	return run
+}
+
+// finishRun stops collecting and fills in the totals
+func (rw *ResourceWatcher) finishRun(run *ResourceRun, err error) {
+	rw.run = nil
+	run.DurationMS = time.Since(run.StartedAt).Milliseconds()
+	if err != nil {
+		run.Error = err.Error()
+	}
+	for _, change := range run.Changes {
+		switch change.Action {
+		case ResourceCreated:
+			run.Created++
+		case ResourceUpdated:
+			run.Updated++
+		case ResourceDisabled:
+			run.Disabled++
+		case ResourceQueued:
+			run.Queued++
+		}
+	}
+}
+
+// record notes a change of the run in progress; outside a run, such as
+// when the review queue applies approvals, nothing is collected. Created
+// and disabled resources are sent to the webhooks either way.
+func (rw *ResourceWatcher) record(action, resourceID, host string) {
+	switch action {
+	case ResourceCreated:
+		rw.webhooks.Emit(models.EventResourceDiscovered, ResourceChange{ResourceID: resourceID, Host: host, Action: action})
+	case ResourceDisabled:
+		rw.webhooks.Emit(models.EventResourceDisabled, ResourceChange{ResourceID: resourceID, Host: host, Action: action})
+	}
+	if rw.run == nil {
+		return
+	}
+	rw.run.Changes = append(rw.run.Changes, ResourceChange{ResourceID: resourceID, Host: host, Action: action})
+}
+
+// skip notes a resource the run in progress left alone
+func (rw *ResourceWatcher) skip(resourceID, host, reason string) {
+	if rw.run == nil {
+		return
+	}
+	rw.run.Skipped = append(rw.run.Skipped, SkippedResource{ResourceID: resourceID, Host: host, Reason: reason})
+}
+
+// ResourceRunLog stores the latest resource watcher runs
+type ResourceRunLog struct {
+	db *database.DB
+}
+
+// NewResourceRunLog creates a new resource watcher run log
+func NewResourceRunLog(db *database.DB) *ResourceRunLog {
+	return &ResourceRunLog{db: db}
+}
+
+// Save stores a run and drops the oldest beyond the retained history
+func (l *ResourceRunLog) Save(run *ResourceRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode resource run: %w", err)
+	}
+
+	err = l.db.QueryRow(
+		"INSERT INTO resource_watcher_runs (trigger_type, started_at, data) VALUES (?, ?, ?) RETURNING id",
+		run.Trigger, run.StartedAt, string(data),
+	).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save resource run: %w", err)
+	}
+
+	_, err = l.db.Exec(`
+        DELETE FROM resource_watcher_runs
+        WHERE id <= (SELECT id FROM resource_watcher_runs ORDER BY id DESC LIMIT 1 OFFSET ?)
+    `, resourceRunHistory)
+	if err != nil {
+		log.Printf("Warning: failed to prune resource watcher runs: %v", err)
+	}
+	return nil
+}
+
+// List returns up to limit runs, newest first
+func (l *ResourceRunLog) List(limit int) ([]ResourceRun, error) {
+	rows, err := l.db.Query(
+		"SELECT id, data FROM resource_watcher_runs ORDER BY id DESC LIMIT ?", limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query resource runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []ResourceRun{}
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan resource run: %w", err)
+		}
+		var run ResourceRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			log.Printf("Skipping unreadable resource run %d: %v", id, err)
+			continue
+		}
+		run.ID = id
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
+)
+
+// SharedHost is a host served by more than one router, for example when the
+// data source splits it by path. Each router is tracked as its own resource.
+type SharedHost struct {
+	Host    string   `json:"host"`
+	Routers []string `json:"routers"`
+}
+
+// findSharedHosts returns the hosts more than one valid fetched router
+// serves, sorted by host
+func findSharedHosts(resources []models.Resource) []SharedHost {
+	routers := make(map[string][]string)
+	for _, resource := range resources {
+		if resource.Host == "" || resource.ServiceID == "" {
+			continue
+		}
+		routers[resource.Host] = append(routers[resource.Host], util.NormalizeID(resource.ID))
+	}
+
+	var shared []SharedHost
+	for host, ids := range routers {
+		if len(ids) > 1 {
+			sort.Strings(ids)
+			shared = append(shared, SharedHost{Host: host, Routers: ids})
+		}
+	}
+	sort.Slice(shared, func(i, j int) bool { return shared[i].Host < shared[j].Host })
+	return shared
+}
+
+// noteSharedHosts remembers the shared hosts of a fetch so resources on them
+// are matched by router only, never by host, and warns about hosts that
+// became shared since the previous check
+func (rw *ResourceWatcher) noteSharedHosts(run *ResourceRun, resources []models.Resource) {
+	shared := findSharedHosts(resources)
+	hosts := make(map[string]bool, len(shared))
+	for _, s := range shared {
+		hosts[s.Host] = true
+		if !rw.sharedHosts[s.Host] {
+			log.Printf("Warning: host %s is served by %d routers (%s); each is tracked as its own resource, assign middlewares per router",
+				s.Host, len(s.Routers), strings.Join(s.Routers, ", "))
+		}
+	}
+	rw.sharedHosts = hosts
+	run.SharedHosts = shared
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestResourceWatcher_SharedHostKeepsOneResourcePerRouter(t *testing.T) {
+	db := newTestDB(t)
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app-web", Host: "app.example.com", ServiceID: "web-svc", Entrypoints: "websecure"},
+		{ID: "app-api", Host: "app.example.com", ServiceID: "api-svc", Entrypoints: "websecure"},
+	}}}
+	rw := &ResourceWatcher{db: db, fetcher: fetcher, configManager: newTestConfigManager(t)}
+
+	run, err := rw.checkResources()
+	if err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if run.Created != 2 {
+		t.Fatalf("expected a resource per router, got %+v", run.Changes)
+	}
+	if len(run.SharedHosts) != 1 || run.SharedHosts[0].Host != "app.example.com" || len(run.SharedHosts[0].Routers) != 2 {
+		t.Errorf("unexpected shared hosts %+v", run.SharedHosts)
+	}
+
+	// Later checks keep each router on its own resource instead of swapping them
+	for i := 0; i < 2; i++ {
+		run, err = rw.checkResources()
+		if err != nil {
+			t.Fatalf("checkResources() error = %v", err)
+		}
+		if len(run.Changes) != 0 {
+			t.Errorf("check %d changed %+v", i, run.Changes)
+		}
+	}
+
+	var services int
+	if err := db.QueryRow(`SELECT COUNT(DISTINCT service_id) FROM resources
+		WHERE host = 'app.example.com' AND status = 'active'`).Scan(&services); err != nil {
+		t.Fatalf("failed to count resources: %v", err)
+	}
+	if services != 2 {
+		t.Errorf("expected both routers' services to be kept, got %d", services)
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import "log"
+
+// Defaults for the guard against a data source suddenly dropping resources
+const (
+	// DefaultShrinkPercent is the share of active resources a fetch must
+	// return; fewer counts as a suspicious drop
+	DefaultShrinkPercent = 50
+	// DefaultShrinkConfirmations is how many consecutive checks must see the
+	// drop before missing resources are disabled
+	DefaultShrinkConfirmations = 3
+)
+
+// shrinkGuard holds back disables when a fetch returns far fewer resources
+// than are active, e.g. a Pangolin 200 with an empty config during a restart.
+// The zero value never holds anything back.
+type shrinkGuard struct {
+	percent       int
+	confirmations int
+	streak        int
+}
+
+// hold reports whether this check should skip disabling missing resources
+func (g *shrinkGuard) hold(fetched, active int) bool {
+	if g.confirmations <= 1 {
+		return false
+	}
+
+	shrunk := active > 0 && (fetched == 0 || fetched*100 < active*g.percent)
+	if !shrunk {
+		if g.streak > 0 {
+			log.Printf("Data source returned %d resources again (%d active); the drop after %d held checks was transient",
+				fetched, active, g.streak)
+		}
+		g.streak = 0
+		return false
+	}
+
+	g.streak++
+	if g.streak >= g.confirmations {
+		log.Printf("ALERT: data source returned %d of %d active resources for %d consecutive checks; disabling the missing ones",
+			fetched, active, g.streak)
+		g.streak = 0
+		return false
+	}
+
+	log.Printf("ALERT: data source returned %d resources but %d are active; not disabling any until %d consecutive checks agree (%d so far)",
+		fetched, active, g.confirmations, g.streak)
+	return true
+}
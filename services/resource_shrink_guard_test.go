@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestShrinkGuard(t *testing.T) {
+	g := shrinkGuard{percent: 50, confirmations: 3}
+
+	tests := []struct {
+		name            string
+		fetched, active int
+		want            bool
+	}{
+		{"normal", 10, 10, false},
+		{"empty once", 0, 10, true},
+		{"empty twice", 0, 10, true},
+		{"recovered resets the streak", 10, 10, false},
+		{"below threshold", 4, 10, true},
+		{"at threshold", 5, 10, false},
+		{"nothing active", 0, 0, false},
+		{"drop 1", 2, 10, true},
+		{"drop 2", 2, 10, true},
+		{"drop confirmed", 2, 10, false},
+		{"streak starts over", 2, 10, true},
+	}
+	for _, tt := range tests {
+		if got := g.hold(tt.fetched, tt.active); got != tt.want {
+			t.Errorf("%s: hold(%d, %d) = %v, want %v", tt.name, tt.fetched, tt.active, got, tt.want)
+		}
+	}
+
+	var off shrinkGuard
+	if off.hold(0, 10) {
+		t.Error("zero-value guard held back disables")
+	}
+}
+
+func TestResourceWatcher_ShrinkGuardHoldsEmptyResponse(t *testing.T) {
+	db := newTestDB(t)
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc"},
+		{ID: "api", Host: "api.example.com", ServiceID: "api-svc"},
+	}}}
+	rw := &ResourceWatcher{db: db, fetcher: fetcher, configManager: newTestConfigManager(t)}
+	rw.SetShrinkGuard(50, 2)
+
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+
+	// A single empty response disables nothing
+	fetcher.resources = &models.ResourceCollection{}
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if got := resourceStatus(t, rw, "app.example.com"); got != "active" {
+		t.Fatalf("status after one empty response = %q, want active", got)
+	}
+
+	// The second consecutive one confirms the drop
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if got := resourceStatus(t, rw, "app.example.com"); got != "disabled" {
+		t.Errorf("status after confirmed drop = %q, want disabled", got)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
+)
+
+// Actions of the resource changes a watcher check reports
+const (
+	ResourceCreated  = "created"
+	ResourceUpdated  = "updated"
+	ResourceDisabled = "disabled"
+	// ResourceQueued is a change held back for approval in review mode
+	ResourceQueued = "queued"
+)
+
+// ResourceChange is one change a watcher check applied or queued
+type ResourceChange struct {
+	ResourceID string `json:"resource_id,omitempty"`
+	Host       string `json:"host"`
+	Action     string `json:"action"`
+}
+
+// Sync runs a full check right away with the current data source config and
+// returns its summary
+func (rw *ResourceWatcher) Sync() (*ResourceRun, error) {
+	return rw.runCheck(RunManual)
+}
+
+// Reload runs a full check right away after the settings were reloaded, so
+// the fetchers are rebuilt from the re-read data source config
+func (rw *ResourceWatcher) Reload() (*ResourceRun, error) {
+	return rw.runCheck(RunReload)
+}
+
+// Restored runs a full check right away after a database backup was
+// restored, so the restored resources are reconciled with the data source
+func (rw *ResourceWatcher) Restored() (*ResourceRun, error) {
+	return rw.runCheck(RunRestore)
+}
+
+// runCheck runs a full check, logs and stores its summary. Scheduled checks
+// and manual syncs both run through it, so they never overlap.
+func (rw *ResourceWatcher) runCheck(trigger string) (*ResourceRun, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	// Check if data source config has changed
+	if err := rw.refreshFetcher(); err != nil {
+		log.Printf("Failed to refresh resource fetcher: %v", err)
+	}
+
+	run, err := rw.checkResources()
+	run.Trigger = trigger
+	rw.saveRun(run)
+	return run, err
+}
+
+// saveRun logs a run that changed or skipped something and adds it to the run log
+func (rw *ResourceWatcher) saveRun(run *ResourceRun) {
+	if len(run.Changes) > 0 || len(run.Skipped) > 0 {
+		log.Printf("Resource %s run: %d created, %d updated, %d disabled, %d queued, %d skipped in %dms",
+			run.Trigger, run.Created, run.Updated, run.Disabled, run.Queued, len(run.Skipped), run.DurationMS)
+	}
+	if rw.runs == nil {
+		return
+	}
+	if err := rw.runs.Save(run); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// ResyncResource refreshes a single resource from the data source. The
+// operator asked for it, so the change is applied even in review mode, and
+// a resource the data source no longer returns is disabled unless pinned.
+// It returns sql.ErrNoRows when the resource does not exist.
+func (rw *ResourceWatcher) ResyncResource(id string) (*ResourceRun, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	var pangolinRouterID, host string
+	err := rw.db.QueryRow(
+		"SELECT COALESCE(pangolin_router_id, ''), host FROM resources WHERE id = ?", id,
+	).Scan(&pangolinRouterID, &host)
+	if err != nil {
+		return nil, err
+	}
+
+	run := rw.beginRun()
+	run.Trigger = RunResync
+	err = rw.resync(run, id, pangolinRouterID, host)
+	rw.finishRun(run, err)
+	rw.saveRun(run)
+	return run, err
+}
+
+// resync applies the data source's definition of one resource
+func (rw *ResourceWatcher) resync(run *ResourceRun, id, pangolinRouterID, host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resources, err := rw.fetchResources(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch resources: %w", err)
+	}
+	run.Fetched = len(resources.Resources)
+	rw.noteSharedHosts(run, resources.Resources)
+
+	// Prefer the resource's own router; fall back to its host unless other
+	// routers serve that host too
+	host = util.NormalizeHost(host)
+	var byHost *models.Resource
+	for i, resource := range resources.Resources {
+		if resource.Host == "" || resource.ServiceID == "" {
+			continue
+		}
+		routerID := util.NormalizeID(resource.ID)
+		if routerID == pangolinRouterID {
+			return rw.updateExistingResourceByInternalID(id, routerID, resource)
+		}
+		if resource.Host == host && !rw.sharedHosts[host] && byHost == nil {
+			byHost = &resources.Resources[i]
+		}
+	}
+	if byHost != nil {
+		return rw.updateExistingResourceByInternalID(id, util.NormalizeID(byHost.ID), *byHost)
+	}
+
+	return rw.disableMissingResource(id)
+}
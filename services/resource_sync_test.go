@@ -0,0 +1,115 @@
+package services
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestResourceWatcher_CheckReportsChanges(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc"},
+		{ID: "api", Host: "api.example.com", ServiceID: "api-svc"},
+		{ID: "broken", Host: "broken.example.com"},
+	}}}
+	rw := &ResourceWatcher{db: db, fetcher: fetcher, configManager: cm}
+
+	run, err := rw.checkResources()
+	if err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if run.Fetched != 3 || run.Created != 2 || len(run.Changes) != 2 {
+		t.Fatalf("expected two creations out of three fetched, got %+v", run)
+	}
+	if len(run.Skipped) != 1 || run.Skipped[0].Host != "broken.example.com" {
+		t.Errorf("expected the resource without a service to be skipped, got %+v", run.Skipped)
+	}
+	appID, apiID := run.Changes[0].ResourceID, run.Changes[1].ResourceID
+
+	fetcher.resources = &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc-2"},
+	}}
+	run, err = rw.checkResources()
+	if err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	want := []ResourceChange{
+		{ResourceID: appID, Host: "app.example.com", Action: ResourceUpdated},
+		{ResourceID: apiID, Host: "api.example.com", Action: ResourceDisabled},
+	}
+	if !reflect.DeepEqual(run.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", run.Changes, want)
+	}
+	if run.Updated != 1 || run.Disabled != 1 {
+		t.Errorf("counts = %d updated, %d disabled, want 1 and 1", run.Updated, run.Disabled)
+	}
+
+	// A check without changes reports none
+	run, err = rw.checkResources()
+	if err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if len(run.Changes) != 0 {
+		t.Errorf("expected no changes, got %+v", run.Changes)
+	}
+}
+
+func TestResourceWatcher_ResyncResource(t *testing.T) {
+	db := newTestDB(t)
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc"},
+		{ID: "api", Host: "api.example.com", ServiceID: "api-svc"},
+	}}}
+	rw := &ResourceWatcher{db: db, fetcher: fetcher, configManager: newTestConfigManager(t), runs: NewResourceRunLog(db)}
+	run, err := rw.checkResources()
+	if err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	appID, apiID := run.Changes[0].ResourceID, run.Changes[1].ResourceID
+
+	fetcher.resources = &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "app", Host: "app.example.com", ServiceID: "app-svc-2"},
+		{ID: "api", Host: "api.example.com", ServiceID: "api-svc-2"},
+	}}
+	run, err = rw.ResyncResource(appID)
+	if err != nil {
+		t.Fatalf("ResyncResource() error = %v", err)
+	}
+	if run.Trigger != RunResync || len(run.Changes) != 1 || run.Changes[0].ResourceID != appID || run.Changes[0].Action != ResourceUpdated {
+		t.Errorf("unexpected run %+v", run)
+	}
+
+	// Only the requested resource is refreshed
+	var serviceID string
+	if err := db.QueryRow("SELECT service_id FROM resources WHERE id = ?", apiID).Scan(&serviceID); err != nil {
+		t.Fatalf("failed to read api: %v", err)
+	}
+	if serviceID != "api-svc" {
+		t.Errorf("api service_id = %q, want it untouched", serviceID)
+	}
+
+	fetcher.resources = &models.ResourceCollection{}
+	run, err = rw.ResyncResource(apiID)
+	if err != nil {
+		t.Fatalf("ResyncResource() error = %v", err)
+	}
+	if run.Disabled != 1 {
+		t.Errorf("expected the missing resource to be disabled, got %+v", run.Changes)
+	}
+
+	if _, err := rw.ResyncResource("missing"); err != sql.ErrNoRows {
+		t.Errorf("ResyncResource(missing) error = %v, want sql.ErrNoRows", err)
+	}
+
+	runs, err := rw.runs.List(10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].Disabled != 1 || runs[1].Updated != 1 {
+		t.Errorf("expected both resyncs in the run log, newest first, got %+v", runs)
+	}
+}
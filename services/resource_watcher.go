@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -26,6 +27,26 @@ type ResourceWatcher struct {
     stopChan        chan struct{}
     isRunning       atomic.Bool
     httpClient      *http.Client
+
+    // secondary is the failover fetcher; nil when failover is off
+    secondary       ResourceFetcher
+    failoverAfter   time.Duration
+
+    // review queues changes for approval when review mode is on
+    review          *ResourceReview
+    // shrink holds back disables after a sudden drop in resources
+    shrink          shrinkGuard
+
+    // mu serializes scheduled checks with manual syncs; run collects the
+    // changes of the check in progress
+    mu              sync.Mutex
+    run             *ResourceRun
+    runs            *ResourceRunLog
+    // sharedHosts are the hosts several fetched routers serve; resources
+    // on them are only ever matched by router
+    sharedHosts     map[string]bool
+    // webhooks is told about created and disabled resources
+    webhooks        *WebhookService
 }
 
 // NewResourceWatcher creates a new resource watcher
@@ -41,17 +62,48 @@ func NewResourceWatcher(db *database.DB, configManager *ConfigManager) (*Resourc
     if err != nil {
         return nil, fmt.Errorf("failed to create resource fetcher: %w", err)
     }
+    persistFetcherURL(fetcher, configManager)
 
     // Use the shared HTTP client pool for better connection reuse
     httpClient := GetHTTPClient()
 
-    return &ResourceWatcher{
+    rw := &ResourceWatcher{
         db:             db,
         fetcher:        fetcher,
         configManager:  configManager,
         stopChan:       make(chan struct{}),
         httpClient:     httpClient,
-    }, nil
+        review:         NewResourceReview(db, configManager),
+        runs:           NewResourceRunLog(db),
+        shrink: shrinkGuard{
+            percent:       DefaultShrinkPercent,
+            confirmations: DefaultShrinkConfirmations,
+        },
+    }
+    if err := rw.refreshSecondary(); err != nil {
+        log.Printf("Failover disabled: %v", err)
+    }
+    return rw, nil
+}
+
+// SetShrinkGuard configures how a sudden drop in fetched resources is
+// handled: a fetch returning fewer than percent of the active resources
+// disables nothing until confirmations consecutive checks agree. A
+// confirmations of 1 or less turns the guard off.
+func (rw *ResourceWatcher) SetShrinkGuard(percent, confirmations int) {
+    rw.mu.Lock()
+    defer rw.mu.Unlock()
+    if rw.shrink.percent == percent && rw.shrink.confirmations == confirmations {
+        return
+    }
+    rw.shrink = shrinkGuard{percent: percent, confirmations: confirmations}
+}
+
+// SetWebhooks sets the service told about created and disabled resources
+func (rw *ResourceWatcher) SetWebhooks(webhooks *WebhookService) {
+    rw.mu.Lock()
+    defer rw.mu.Unlock()
+    rw.webhooks = webhooks
 }
 
 // Start begins watching for resources
@@ -65,19 +117,14 @@ func (rw *ResourceWatcher) Start(interval time.Duration) {
     defer ticker.Stop()
 
     // Do an initial check
-    if err := rw.checkResources(); err != nil {
+    if _, err := rw.runCheck(RunScheduled); err != nil {
         log.Printf("Initial resource check failed: %v", err)
     }
 
     for {
         select {
         case <-ticker.C:
-            // Check if data source config has changed
-            if err := rw.refreshFetcher(); err != nil {
-                log.Printf("Failed to refresh resource fetcher: %v", err)
-            }
-            
-            if err := rw.checkResources(); err != nil {
+            if _, err := rw.runCheck(RunScheduled); err != nil {
                 log.Printf("Resource check failed: %v", err)
             }
         case <-rw.stopChan:
@@ -99,12 +146,87 @@ func (rw *ResourceWatcher) refreshFetcher() error {
     if err != nil {
         return fmt.Errorf("failed to create resource fetcher: %w", err)
     }
+    persistFetcherURL(fetcher, rw.configManager)
     
     // Update the fetcher
     rw.fetcher = fetcher
+    return rw.refreshSecondary()
+}
+
+// refreshSecondary rebuilds the failover fetcher from the failover config
+func (rw *ResourceWatcher) refreshSecondary() error {
+    dsConfig, after, ok := rw.configManager.failoverSource()
+    if !ok {
+        rw.secondary = nil
+        return nil
+    }
+
+    fetcher, err := NewResourceFetcher(dsConfig)
+    if err != nil {
+        rw.secondary = nil
+        return fmt.Errorf("failed to create failover fetcher: %w", err)
+    }
+    rw.secondary = fetcher
+    rw.failoverAfter = after
     return nil
 }
 
+// fetchResources reads from the data source and normalizes the hosts, so a
+// resource matches its stored row however the source spells the host
+func (rw *ResourceWatcher) fetchResources(ctx context.Context) (*models.ResourceCollection, error) {
+    resources, err := rw.fetchFromSources(ctx)
+    if err != nil {
+        return nil, err
+    }
+    // Fetchers may share the collection with their cache, so normalize a copy
+    normalized := &models.ResourceCollection{Resources: make([]models.Resource, len(resources.Resources))}
+    for i, resource := range resources.Resources {
+        resource.Host = util.NormalizeHost(resource.Host)
+        resource.TLSDomains = util.NormalizeHostList(resource.TLSDomains)
+        normalized.Resources[i] = resource
+    }
+    return normalized, nil
+}
+
+// fetchFromSources reads from the active data source. With failover set up,
+// an outage longer than failoverAfter switches reads to the secondary until
+// the active source answers again.
+func (rw *ResourceWatcher) fetchFromSources(ctx context.Context) (*models.ResourceCollection, error) {
+    if rw.secondary == nil {
+        return rw.fetcher.FetchResources(ctx)
+    }
+
+    // Leave the secondary time to answer when the primary hangs
+    primaryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    resources, err := rw.fetcher.FetchResources(primaryCtx)
+    cancel()
+
+    state := &rw.configManager.failover
+    if err == nil {
+        if state.primaryRecovered() {
+            log.Printf("Data source %s is reachable again, switching resource discovery back to it",
+                rw.configManager.GetActiveSourceName())
+        }
+        return resources, nil
+    }
+
+    use, switched := state.primaryFailed(err, rw.failoverAfter, time.Now().UTC())
+    if !use {
+        return nil, err
+    }
+    failover := rw.configManager.GetFailoverConfig().Secondary
+    if switched {
+        log.Printf("Data source %s unreachable for %v (%v), failing over to %s",
+            rw.configManager.GetActiveSourceName(), rw.failoverAfter, err, failover)
+    }
+
+    resources, secondaryErr := rw.secondary.FetchResources(ctx)
+    if secondaryErr != nil {
+        return nil, fmt.Errorf("%w; failover source %s also failed: %v", err, failover, secondaryErr)
+    }
+    return resources, nil
+}
+
 // Stop stops the resource watcher
 func (rw *ResourceWatcher) Stop() {
     if !rw.isRunning.CompareAndSwap(true, false) {
@@ -114,18 +236,28 @@ func (rw *ResourceWatcher) Stop() {
     close(rw.stopChan)
 }
 
-// checkResources fetches resources from the configured data source and updates the database
-func (rw *ResourceWatcher) checkResources() error {
-    
+// checkResources fetches resources from the configured data source, updates
+// the database and returns a summary of the run
+func (rw *ResourceWatcher) checkResources() (*ResourceRun, error) {
+    run := rw.beginRun()
+    err := rw.reconcile(run)
+    rw.finishRun(run, err)
+    return run, err
+}
+
+// reconcile applies the data source's resources to the database
+func (rw *ResourceWatcher) reconcile(run *ResourceRun) error {
     // Create a context with timeout for the operation
     ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
     defer cancel()
     
-    // Fetch resources using the configured fetcher
-    resources, err := rw.fetcher.FetchResources(ctx)
+    // Fetch resources using the configured fetcher, or the failover one
+    resources, err := rw.fetchResources(ctx)
     if err != nil {
         return fmt.Errorf("failed to fetch resources: %w", err)
     }
+    run.Fetched = len(resources.Resources)
+    rw.noteSharedHosts(run, resources.Resources)
 
     // Get all existing resources from the database
     var existingResources []string
@@ -143,6 +275,13 @@ func (rw *ResourceWatcher) checkResources() error {
         existingResources = append(existingResources, id)
     }
     rows.Close()
+
+    // A sudden drop is more often a data source hiccup than real deletions
+    holdDisables := rw.shrink.hold(len(resources.Resources), len(existingResources))
+
+    if rw.review != nil && rw.review.Enabled() {
+        return rw.queueChanges(resources, existingResources, holdDisables)
+    }
     
     // Keep track of resources we find (by internal ID)
     foundInternalIDs := make(map[string]bool)
@@ -150,14 +289,13 @@ func (rw *ResourceWatcher) checkResources() error {
     // Check if there are any resources
     if len(resources.Resources) == 0 {
         log.Println("No resources found in data source")
+        if holdDisables {
+            rw.skipHeldDisables(existingResources)
+            return nil
+        }
         // Mark all existing resources as disabled since there are no active resources
         for _, resourceID := range existingResources {
-            log.Printf("No active resources, marking resource %s as disabled", resourceID)
-            _, err := rw.db.Exec(
-                "UPDATE resources SET status = 'disabled', updated_at = ? WHERE id = ?",
-                time.Now(), resourceID,
-            )
-            if err != nil {
+            if err := rw.disableMissingResource(resourceID); err != nil {
                 log.Printf("Error marking resource as disabled: %v", err)
             }
         }
@@ -168,6 +306,7 @@ func (rw *ResourceWatcher) checkResources() error {
     for _, resource := range resources.Resources {
         // Skip invalid resources
         if resource.Host == "" || resource.ServiceID == "" {
+            rw.skip("", resource.Host, "missing host or service in data source")
             continue
         }
 
@@ -175,6 +314,7 @@ func (rw *ResourceWatcher) checkResources() error {
         internalID, err := rw.updateOrCreateResource(resource)
         if err != nil {
             log.Printf("Error processing resource %s: %v", resource.ID, err)
+            rw.skip("", resource.Host, err.Error())
             // Continue processing other resources even if one fails
             continue
         }
@@ -183,16 +323,22 @@ func (rw *ResourceWatcher) checkResources() error {
         foundInternalIDs[internalID] = true
     }
     
+    if holdDisables {
+        var missing []string
+        for _, resourceID := range existingResources {
+            if !foundInternalIDs[resourceID] {
+                missing = append(missing, resourceID)
+            }
+        }
+        rw.skipHeldDisables(missing)
+        return nil
+    }
+
     // Mark resources as disabled if they no longer exist in the data source
     // Now we compare internal UUIDs, which is correct
     for _, resourceID := range existingResources {
         if !foundInternalIDs[resourceID] {
-            log.Printf("Resource %s no longer exists, marking as disabled", resourceID)
-            _, err := rw.db.Exec(
-                "UPDATE resources SET status = 'disabled', updated_at = ? WHERE id = ?",
-                time.Now(), resourceID,
-            )
-            if err != nil {
+            if err := rw.disableMissingResource(resourceID); err != nil {
                 log.Printf("Error marking resource as disabled: %v", err)
             }
         }
@@ -201,22 +347,148 @@ func (rw *ResourceWatcher) checkResources() error {
     return nil
 }
 
+// skipHeldDisables notes the resources the shrink guard kept from being disabled
+func (rw *ResourceWatcher) skipHeldDisables(resourceIDs []string) {
+    for _, resourceID := range resourceIDs {
+        var host string
+        if err := rw.db.QueryRow("SELECT host FROM resources WHERE id = ?", resourceID).Scan(&host); err != nil {
+            log.Printf("Error reading resource %s: %v", resourceID, err)
+        }
+        rw.skip(resourceID, host, "disable held back after a sudden drop in fetched resources")
+    }
+}
+
+// queueChanges is checkResources in review mode: updates to known resources
+// apply at once, while creations, service changes and disables wait in the
+// review queue. No disables are queued while holdDisables is set.
+func (rw *ResourceWatcher) queueChanges(resources *models.ResourceCollection, existingResources []string, holdDisables bool) error {
+    var changes []PendingChange
+    found := make(map[string]bool)
+
+    for _, resource := range resources.Resources {
+        if resource.Host == "" || resource.ServiceID == "" {
+            rw.skip("", resource.Host, "missing host or service in data source")
+            continue
+        }
+        pangolinRouterID := util.NormalizeID(resource.ID)
+
+        internalID, err := rw.findExistingResource(pangolinRouterID, resource.Host)
+        if err == sql.ErrNoRows {
+            changes = append(changes, PendingChange{
+                Kind:             PendingCreate,
+                PangolinRouterID: pangolinRouterID,
+                Host:             resource.Host,
+                NewServiceID:     resource.ServiceID,
+                resource:         resource,
+            })
+            continue
+        }
+        if err != nil {
+            log.Printf("Error looking up resource %s: %v", resource.ID, err)
+            continue
+        }
+        found[internalID] = true
+
+        var serviceID string
+        var pinned bool
+        if err := rw.db.QueryRow("SELECT service_id, pinned FROM resources WHERE id = ?", internalID).Scan(&serviceID, &pinned); err != nil {
+            log.Printf("Error reading service of resource %s: %v", internalID, err)
+            continue
+        }
+        // Pinned resources only report a service change, so there is nothing to approve
+        if serviceID != resource.ServiceID && !pinned {
+            changes = append(changes, PendingChange{
+                Kind:             PendingServiceChange,
+                ResourceID:       internalID,
+                PangolinRouterID: pangolinRouterID,
+                Host:             resource.Host,
+                OldServiceID:     serviceID,
+                NewServiceID:     resource.ServiceID,
+                resource:         resource,
+            })
+            continue
+        }
+
+        if err := rw.updateExistingResourceByInternalID(internalID, pangolinRouterID, resource); err != nil {
+            log.Printf("Error processing resource %s: %v", resource.ID, err)
+        }
+    }
+
+    for _, resourceID := range existingResources {
+        if found[resourceID] {
+            continue
+        }
+        if holdDisables {
+            rw.skipHeldDisables([]string{resourceID})
+            continue
+        }
+        var host string
+        var pinned bool
+        if err := rw.db.QueryRow("SELECT host, pinned FROM resources WHERE id = ?", resourceID).Scan(&host, &pinned); err != nil {
+            log.Printf("Error reading resource %s: %v", resourceID, err)
+        }
+        if pinned {
+            if err := rw.disableMissingResource(resourceID); err != nil {
+                log.Printf("Error recording divergence of pinned resource %s: %v", resourceID, err)
+            }
+            continue
+        }
+        changes = append(changes, PendingChange{Kind: PendingDisable, ResourceID: resourceID, Host: host})
+    }
+
+    for _, change := range changes {
+        rw.record(ResourceQueued, change.ResourceID, change.Host)
+    }
+    if err := rw.review.sync(changes); err != nil {
+        return err
+    }
+    if len(changes) > 0 {
+        log.Printf("Review mode: %d resource changes waiting for approval", len(changes))
+    }
+
+    applied, err := rw.review.approveDue()
+    if applied > 0 {
+        log.Printf("Review mode: auto-approved %d resource changes", applied)
+    }
+    return err
+}
+
 // updateOrCreateResource updates an existing resource or creates a new one
 // Uses internal UUID for stable tracking, pangolin_router_id for Pangolin reference
 // Returns the internal UUID of the resource
 func (rw *ResourceWatcher) updateOrCreateResource(resource models.Resource) (string, error) {
     pangolinRouterID := util.NormalizeID(resource.ID)
 
+    internalID, err := rw.findExistingResource(pangolinRouterID, resource.Host)
+    if err == nil {
+        // Found - update it (only if changed)
+        if err := rw.updateExistingResourceByInternalID(internalID, pangolinRouterID, resource); err != nil {
+            return "", err
+        }
+        return internalID, nil
+    }
+
+    // No existing resource found, create a new one with UUID
+    return rw.createNewResourceWithUUID(resource, pangolinRouterID)
+}
+
+// findExistingResource returns the internal UUID of the resource a fetched
+// router maps to, or sql.ErrNoRows when it would be created
+func (rw *ResourceWatcher) findExistingResource(pangolinRouterID, host string) (string, error) {
     // Step 1: Try to find existing resource by pangolin_router_id
     var internalID, status string
     err := rw.db.QueryRow(`
         SELECT id, status FROM resources
         WHERE pangolin_router_id = ? AND status = 'active'
     `, pangolinRouterID).Scan(&internalID, &status)
-
     if err == nil {
-        // Found by pangolin_router_id - update it (only if changed)
-        if err := rw.updateExistingResourceByInternalID(internalID, pangolinRouterID, resource); err != nil {
+        return internalID, nil
+    }
+
+    // A router of a shared host must not take over another router's resource
+    if rw.sharedHosts[host] {
+        err = rw.db.QueryRow("SELECT id, status FROM resources WHERE id = ?", pangolinRouterID).Scan(&internalID, &status)
+        if err != nil {
             return "", err
         }
         return internalID, nil
@@ -226,13 +498,8 @@ func (rw *ResourceWatcher) updateOrCreateResource(resource models.Resource) (str
     err = rw.db.QueryRow(`
         SELECT id, status FROM resources
         WHERE host = ? AND status = 'active'
-    `, resource.Host).Scan(&internalID, &status)
-
+    `, host).Scan(&internalID, &status)
     if err == nil {
-        // Found by host - Pangolin changed the router ID, update pangolin_router_id (only if changed)
-        if err := rw.updateExistingResourceByInternalID(internalID, pangolinRouterID, resource); err != nil {
-            return "", err
-        }
         return internalID, nil
     }
 
@@ -240,29 +507,31 @@ func (rw *ResourceWatcher) updateOrCreateResource(resource models.Resource) (str
     err = rw.db.QueryRow(`
         SELECT id, status FROM resources
         WHERE id = ? OR pangolin_router_id IS NULL AND host = ?
-    `, pangolinRouterID, resource.Host).Scan(&internalID, &status)
-
-    if err == nil {
-        // Found legacy resource - update it (only if changed)
-        if err := rw.updateExistingResourceByInternalID(internalID, pangolinRouterID, resource); err != nil {
-            return "", err
-        }
-        return internalID, nil
+    `, pangolinRouterID, host).Scan(&internalID, &status)
+    if err != nil {
+        return "", err
     }
-
-    // Step 4: No existing resource found, create a new one with UUID
-    return rw.createNewResourceWithUUID(resource, pangolinRouterID)
+    return internalID, nil
 }
 
 // updateExistingResourceByInternalID updates an existing resource using its internal UUID
 // Only performs update if the data has actually changed
 func (rw *ResourceWatcher) updateExistingResourceByInternalID(internalID, pangolinRouterID string, resource models.Resource) error {
+    // Pinned resources keep their host, service, priority and status
+    p, pinned, err := rw.loadPinned(internalID)
+    if err != nil && err != sql.ErrNoRows {
+        log.Printf("Warning: Could not check whether resource %s is pinned: %v", internalID, err)
+    }
+    if pinned {
+        return rw.updatePinnedResource(internalID, pangolinRouterID, p, resource)
+    }
+
     // First, check if any data has actually changed
     var existingPangolinRouterID, existingHost, existingServiceID, existingSourceType, existingEntrypoints string
     var existingRouterPriority int
     var routerPriorityManual int
 
-    err := rw.db.QueryRow(`
+    err = rw.db.QueryRow(`
         SELECT COALESCE(pangolin_router_id, ''), host, service_id, COALESCE(source_type, ''),
                COALESCE(entrypoints, ''), COALESCE(router_priority, 0), COALESCE(router_priority_manual, 0)
         FROM resources WHERE id = ?
@@ -309,6 +578,7 @@ func (rw *ResourceWatcher) updateExistingResourceByInternalID(internalID, pangol
         if err != nil {
             return fmt.Errorf("failed to update resource %s: %w", internalID, err)
         }
+        rw.record(ResourceUpdated, internalID, resource.Host)
 
         // Update router_priority from Pangolin only if not manually overridden
         if resource.RouterPriority > 0 {
@@ -389,6 +659,7 @@ func (rw *ResourceWatcher) createNewResourceWithUUID(resource models.Resource, p
     if err != nil {
         return "", err
     }
+    rw.record(ResourceCreated, internalID, resource.Host)
 
     return internalID, nil
 }
@@ -72,7 +72,7 @@ func TestNewResourceWatcher(t *testing.T) {
 	if watcher.configManager == nil {
 		t.Error("watcher.configManager is nil")
 	}
-	if watcher.isRunning {
+	if watcher.isRunning.Load() {
 		t.Error("watcher.isRunning should be false initially")
 	}
 	if watcher.httpClient == nil {
@@ -100,7 +100,7 @@ func TestResourceWatcher_Stop(t *testing.T) {
 	// Should not panic when stopping a non-running watcher
 	watcher.Stop()
 
-	if watcher.isRunning {
+	if watcher.isRunning.Load() {
 		t.Error("watcher.isRunning should be false after Stop()")
 	}
 }
@@ -152,7 +152,7 @@ func TestResourceWatcher_StartStop(t *testing.T) {
 	// Wait a bit for it to start
 	time.Sleep(50 * time.Millisecond)
 
-	if !watcher.isRunning {
+	if !watcher.isRunning.Load() {
 		t.Error("watcher should be running after Start()")
 	}
 
@@ -162,7 +162,7 @@ func TestResourceWatcher_StartStop(t *testing.T) {
 	// Wait for stop to complete
 	time.Sleep(50 * time.Millisecond)
 
-	if watcher.isRunning {
+	if watcher.isRunning.Load() {
 		t.Error("watcher should not be running after Stop()")
 	}
 }
@@ -204,7 +204,7 @@ func TestResourceWatcher_CheckResources(t *testing.T) {
 	}
 
 	// Manually call checkResources
-	err = watcher.checkResources()
+	_, err = watcher.checkResources()
 	if err != nil {
 		t.Fatalf("checkResources() error = %v", err)
 	}
@@ -249,7 +249,7 @@ func TestResourceWatcher_CheckResources_EmptyResult(t *testing.T) {
 	}
 
 	// Should not error on empty result
-	err = watcher.checkResources()
+	_, err = watcher.checkResources()
 	if err != nil {
 		t.Errorf("checkResources() should not error on empty result: %v", err)
 	}
@@ -484,7 +484,7 @@ func TestResourceWatcher_DisablesRemovedResources(t *testing.T) {
 	}
 
 	// Check resources (old-resource should be marked disabled)
-	err = watcher.checkResources()
+	_, err = watcher.checkResources()
 	if err != nil {
 		t.Fatalf("checkResources() error = %v", err)
 	}
@@ -682,3 +682,35 @@ func TestResourceWatcher_CreateWithDefaults(t *testing.T) {
 		t.Errorf("expected router_priority 100, got %d", priority)
 	}
 }
+
+// TestResourceWatcher_NormalizesHosts tests that differently spelled hosts map to one resource
+func TestResourceWatcher_NormalizesHosts(t *testing.T) {
+	db := newTestDB(t)
+	fetcher := &mockResourceFetcher{resources: &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "cafe", Host: "Café.Example.com.", ServiceID: "cafe-svc", TLSDomains: "Café.Example.com"},
+	}}}
+	rw := &ResourceWatcher{db: db, fetcher: fetcher, configManager: newTestConfigManager(t)}
+
+	if _, err := rw.checkResources(); err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	var host, tlsDomains string
+	if err := db.QueryRow("SELECT host, tls_domains FROM resources").Scan(&host, &tlsDomains); err != nil {
+		t.Fatalf("failed to read resource: %v", err)
+	}
+	if host != "xn--caf-dma.example.com" || tlsDomains != "xn--caf-dma.example.com" {
+		t.Errorf("stored host %q and tls_domains %q, want punycode", host, tlsDomains)
+	}
+
+	// A new router ID with the punycode spelling still finds the resource by host
+	fetcher.resources = &models.ResourceCollection{Resources: []models.Resource{
+		{ID: "cafe-renamed", Host: "XN--CAF-DMA.example.com", ServiceID: "cafe-svc"},
+	}}
+	run, err := rw.checkResources()
+	if err != nil {
+		t.Fatalf("checkResources() error = %v", err)
+	}
+	if run.Created != 0 || run.Disabled != 0 {
+		t.Errorf("expected the existing resource to be matched, got %+v", run.Changes)
+	}
+}
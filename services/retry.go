@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy bounds the retries of upstream fetches
+type RetryPolicy struct {
+	// Attempts is the total number of tries; 1 disables retries
+	Attempts int
+	// BaseDelay is the wait before the first retry, doubled for each next one
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between tries
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy rides out the few seconds an upstream container needs to
+// restart without holding a Traefik poll much longer than its timeout
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, BaseDelay: 250 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+var (
+	retryPolicyMu sync.RWMutex
+	retryPolicy   = DefaultRetryPolicy
+)
+
+// ConfigureRetry replaces the retry policy of the Pangolin and Traefik
+// fetchers; zero fields keep their defaults
+func ConfigureRetry(policy RetryPolicy) {
+	if policy.Attempts <= 0 {
+		policy.Attempts = DefaultRetryPolicy.Attempts
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = max(DefaultRetryPolicy.MaxDelay, policy.BaseDelay)
+	}
+	retryPolicyMu.Lock()
+	retryPolicy = policy
+	retryPolicyMu.Unlock()
+}
+
+// CurrentRetryPolicy returns the policy set by ConfigureRetry
+func CurrentRetryPolicy() RetryPolicy {
+	retryPolicyMu.RLock()
+	defer retryPolicyMu.RUnlock()
+	return retryPolicy
+}
+
+// delay returns the wait before retry n (1-based): exponential backoff with
+// jitter over its upper half, so instances restarted together spread out
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxDelay)
+	if half := d / 2; half > 0 {
+		d = half + rand.N(half+1)
+	}
+	return d
+}
+
+// retryableStatus reports whether a response may succeed when retried: server
+// errors, timeouts and rate limiting. Other client errors will not change.
+func retryableStatus(code int) bool {
+	return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// doWithRetry sends req with the current retry policy. Network errors and
+// retryable statuses are tried again until the attempts run out or the
+// request's context would expire during the wait. The last response is
+// returned as is, so callers keep reporting its status.
+func doWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
+	return doWithRetryPolicy(client, req, CurrentRetryPolicy())
+}
+
+func doWithRetryPolicy(client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		try, err := retryRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(try)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= policy.Attempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		wait := policy.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+		if err != nil {
+			log.Printf("%s %s failed (attempt %d/%d), retrying in %s: %v",
+				req.Method, req.URL.Redacted(), attempt, policy.Attempts, wait.Round(time.Millisecond), err)
+		} else {
+			log.Printf("%s %s returned %d (attempt %d/%d), retrying in %s",
+				req.Method, req.URL.Redacted(), resp.StatusCode, attempt, policy.Attempts, wait.Round(time.Millisecond))
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, fmt.Errorf("retry of %s aborted: %w", req.URL.Redacted(), err)
+		}
+	}
+}
+
+// retryRequest returns the request for an attempt, rewinding its body
+func retryRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed for a retry")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	try := req.Clone(req.Context())
+	try.Body = body
+	return try, nil
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoWithRetry_RetriesTransientFailures(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := doWithRetryPolicy(server.Client(), req, policy)
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits.Load() != 3 {
+		t.Fatalf("expected 200 after 3 attempts, got %d after %d", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestDoWithRetry_ReturnsLastResponse(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		hits   int32
+	}{
+		{http.StatusBadGateway, 2},
+		{http.StatusTooManyRequests, 2},
+		{http.StatusNotFound, 1},
+		{http.StatusUnauthorized, 1},
+	} {
+		var hits atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.WriteHeader(tc.status)
+		}))
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := doWithRetryPolicy(server.Client(), req, RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+		server.Close()
+		if err != nil {
+			t.Fatalf("status %d: unexpected error %v", tc.status, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status || hits.Load() != tc.hits {
+			t.Errorf("status %d: expected %d attempt(s), got %d (status %d)", tc.status, tc.hits, hits.Load(), resp.StatusCode)
+		}
+	}
+}
+
+func TestDoWithRetry_ReplaysBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	resp, err := doWithRetryPolicy(server.Client(), req, RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Fatalf("expected the body to be sent again, got %q", bodies)
+	}
+}
+
+func TestDoWithRetry_HonorsDeadline(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	start := time.Now()
+	resp, err := doWithRetryPolicy(server.Client(), req, RetryPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expected no retry past the deadline, got %d attempts in %s", hits.Load(), time.Since(start))
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Attempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for _, tc := range []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 300 * time.Millisecond},
+		{6, 300 * time.Millisecond},
+	} {
+		for range 20 {
+			d := policy.delay(tc.attempt)
+			if d < tc.max/2 || d > tc.max {
+				t.Fatalf("delay(%d) = %s, want within [%s, %s]", tc.attempt, d, tc.max/2, tc.max)
+			}
+		}
+	}
+}
+
+func TestConfigureRetryDefaults(t *testing.T) {
+	previous := CurrentRetryPolicy()
+	t.Cleanup(func() { ConfigureRetry(previous) })
+
+	ConfigureRetry(RetryPolicy{Attempts: 4})
+	got := CurrentRetryPolicy()
+	if got.Attempts != 4 || got.BaseDelay != DefaultRetryPolicy.BaseDelay || got.MaxDelay != DefaultRetryPolicy.MaxDelay {
+		t.Fatalf("unexpected policy %+v", got)
+	}
+}
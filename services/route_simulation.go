@@ -0,0 +1,432 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// RouteRequest is a request to simulate Traefik's router selection for
+type RouteRequest struct {
+	URL        *url.URL
+	Method     string
+	Headers    http.Header
+	ClientIP   string
+	EntryPoint string // Only routers on this entrypoint are considered; empty means all
+}
+
+// RouteCandidate is a router whose rule was evaluated for a simulated request
+type RouteCandidate struct {
+	Router   string `json:"router"`
+	Rule     string `json:"rule"`
+	Priority int    `json:"priority"`
+	Matched  bool   `json:"matched"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RouteSimulation is the outcome of a simulated request. Candidates lists the
+// matching routers, winner first, and routers whose rule could not be
+// evaluated.
+type RouteSimulation struct {
+	URL         string           `json:"url"`
+	Method      string           `json:"method"`
+	Matched     bool             `json:"matched"`
+	Router      string           `json:"router,omitempty"`
+	Rule        string           `json:"rule,omitempty"`
+	Priority    int              `json:"priority,omitempty"`
+	Service     string           `json:"service,omitempty"`
+	EntryPoints []string         `json:"entry_points,omitempty"`
+	Middlewares []string         `json:"middlewares,omitempty"`
+	Chain       []string         `json:"chain,omitempty"`
+	Candidates  []RouteCandidate `json:"candidates"`
+}
+
+// SimulateRoute evaluates the rules of the merged HTTP routers against a
+// request and reports the router Traefik would pick, without asking Traefik
+func (cp *ConfigProxy) SimulateRoute(req RouteRequest) (*RouteSimulation, error) {
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		return nil, err
+	}
+	return SimulateRoute(config, req), nil
+}
+
+// SimulateRoute picks the router of config that serves req the way Traefik
+// does: among the routers on the entrypoint whose TLS setting fits the scheme
+// and whose rule matches, the highest priority wins, where the default
+// priority is the rule length. Ties go to the router name that sorts first.
+func SimulateRoute(config *ProxiedTraefikConfig, req RouteRequest) *RouteSimulation {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	result := &RouteSimulation{URL: req.URL.String(), Method: method, Candidates: []RouteCandidate{}}
+	if config == nil || config.HTTP == nil {
+		return result
+	}
+
+	ctx := &ruleContext{
+		host:     strings.ToLower(req.URL.Hostname()),
+		path:     req.URL.Path,
+		method:   method,
+		headers:  req.Headers,
+		query:    req.URL.Query(),
+		clientIP: net.ParseIP(req.ClientIP),
+	}
+	if ctx.path == "" {
+		ctx.path = "/"
+	}
+	secure := strings.EqualFold(req.URL.Scheme, "https")
+
+	for name, router := range config.HTTP.Routers {
+		if router == nil || (router.TLS != nil) != secure {
+			continue
+		}
+		if req.EntryPoint != "" && len(router.EntryPoints) > 0 && !stringSliceContains(router.EntryPoints, req.EntryPoint) {
+			continue
+		}
+
+		candidate := RouteCandidate{Router: name, Rule: router.Rule, Priority: router.Priority}
+		if candidate.Priority <= 0 {
+			candidate.Priority = len(router.Rule)
+		}
+		matched, err := evaluateRule(router.Rule, ctx)
+		if err != nil {
+			candidate.Error = err.Error()
+		} else if !matched {
+			continue
+		}
+		candidate.Matched = matched
+		result.Candidates = append(result.Candidates, candidate)
+	}
+
+	sort.Slice(result.Candidates, func(i, j int) bool {
+		a, b := result.Candidates[i], result.Candidates[j]
+		if a.Matched != b.Matched {
+			return a.Matched
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.Router < b.Router
+	})
+
+	if len(result.Candidates) == 0 || !result.Candidates[0].Matched {
+		return result
+	}
+	winner := result.Candidates[0]
+	router := config.HTTP.Routers[winner.Router]
+	result.Matched = true
+	result.Router = winner.Router
+	result.Rule = winner.Rule
+	result.Priority = winner.Priority
+	result.Service = router.Service
+	result.EntryPoints = router.EntryPoints
+	result.Middlewares = router.Middlewares
+	result.Chain = expandMiddlewareChain(config.HTTP.Middlewares, router.Middlewares, map[string]bool{})
+	return result
+}
+
+// expandMiddlewareChain replaces chain middlewares by the middlewares they
+// chain, in order, so the result is what a request actually runs through
+func expandMiddlewareChain(defs map[string]interface{}, refs []string, visiting map[string]bool) []string {
+	var expanded []string
+	for _, ref := range refs {
+		name := strings.TrimSuffix(ref, "@http")
+		mw, _ := defs[name].(map[string]interface{})
+		chain, isChain := mw["chain"].(map[string]interface{})
+		if !isChain || visiting[name] {
+			expanded = append(expanded, ref)
+			continue
+		}
+		visiting[name] = true
+		expanded = append(expanded, expandMiddlewareChain(defs, stringList(chain["middlewares"]), visiting)...)
+		delete(visiting, name)
+	}
+	return expanded
+}
+
+// ruleContext is the request a rule is evaluated against
+type ruleContext struct {
+	host     string
+	path     string
+	method   string
+	headers  http.Header
+	query    url.Values
+	clientIP net.IP
+}
+
+// evaluateRule reports whether a Traefik v3 router rule matches ctx
+func evaluateRule(rule string, ctx *ruleContext) (bool, error) {
+	tokens, err := tokenizeRule(rule)
+	if err != nil {
+		return false, err
+	}
+	p := &ruleParser{tokens: tokens, ctx: ctx}
+	matched, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected %q in rule", p.tokens[p.pos].value)
+	}
+	return matched, nil
+}
+
+type ruleTokenKind int
+
+const (
+	ruleIdent ruleTokenKind = iota
+	ruleString
+	rulePunct
+)
+
+type ruleToken struct {
+	kind  ruleTokenKind
+	value string
+}
+
+// tokenizeRule splits a rule into matcher names, quoted arguments and the
+// operators && || ! ( ) ,
+func tokenizeRule(rule string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	for i := 0; i < len(rule); {
+		ch := rule[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '`' || ch == '"':
+			end := strings.IndexByte(rule[i+1:], ch)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string in rule")
+			}
+			tokens = append(tokens, ruleToken{ruleString, rule[i+1 : i+1+end]})
+			i += end + 2
+		case strings.HasPrefix(rule[i:], "&&") || strings.HasPrefix(rule[i:], "||"):
+			tokens = append(tokens, ruleToken{rulePunct, rule[i : i+2]})
+			i += 2
+		case ch == '!' || ch == '(' || ch == ')' || ch == ',':
+			tokens = append(tokens, ruleToken{rulePunct, string(ch)})
+			i++
+		case unicode.IsLetter(rune(ch)):
+			start := i
+			for i < len(rule) && (unicode.IsLetter(rune(rule[i])) || unicode.IsDigit(rune(rule[i]))) {
+				i++
+			}
+			tokens = append(tokens, ruleToken{ruleIdent, rule[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected %q in rule", ch)
+		}
+	}
+	return tokens, nil
+}
+
+// ruleParser evaluates tokens while parsing them; both sides of && and || are
+// always parsed so syntax errors surface regardless of the request
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+	ctx    *ruleContext
+}
+
+func (p *ruleParser) peek(value string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == rulePunct && p.tokens[p.pos].value == value
+}
+
+func (p *ruleParser) expect(value string) error {
+	if !p.peek(value) {
+		return fmt.Errorf("expected %q in rule", value)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *ruleParser) parseOr() (bool, error) {
+	matched, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		matched = matched || right
+	}
+	return matched, nil
+}
+
+func (p *ruleParser) parseAnd() (bool, error) {
+	matched, err := p.parseUnary()
+	if err != nil {
+		return false, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return false, err
+		}
+		matched = matched && right
+	}
+	return matched, nil
+}
+
+func (p *ruleParser) parseUnary() (bool, error) {
+	if p.peek("!") {
+		p.pos++
+		matched, err := p.parseUnary()
+		return !matched, err
+	}
+	if p.peek("(") {
+		p.pos++
+		matched, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		return matched, p.expect(")")
+	}
+	return p.parseMatcher()
+}
+
+func (p *ruleParser) parseMatcher() (bool, error) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != ruleIdent {
+		return false, fmt.Errorf("expected a matcher in rule")
+	}
+	name := p.tokens[p.pos].value
+	p.pos++
+	if err := p.expect("("); err != nil {
+		return false, err
+	}
+
+	var args []string
+	for !p.peek(")") {
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != ruleString {
+			return false, fmt.Errorf("matcher %s takes quoted arguments", name)
+		}
+		args = append(args, p.tokens[p.pos].value)
+		p.pos++
+		if !p.peek(",") {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(")"); err != nil {
+		return false, err
+	}
+	return p.ctx.match(name, args)
+}
+
+// match evaluates one matcher against the request
+func (ctx *ruleContext) match(name string, args []string) (bool, error) {
+	arity := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("matcher %s takes %d argument(s), got %d", name, n, len(args))
+		}
+		return nil
+	}
+
+	switch name {
+	case "Host":
+		if err := arity(1); err != nil {
+			return false, err
+		}
+		return strings.EqualFold(strings.TrimSuffix(args[0], "."), ctx.host), nil
+	case "HostRegexp":
+		if err := arity(1); err != nil {
+			return false, err
+		}
+		return matchRegexp(args[0], ctx.host)
+	case "Path":
+		if err := arity(1); err != nil {
+			return false, err
+		}
+		return ctx.path == args[0], nil
+	case "PathPrefix":
+		if err := arity(1); err != nil {
+			return false, err
+		}
+		return strings.HasPrefix(ctx.path, args[0]), nil
+	case "PathRegexp":
+		if err := arity(1); err != nil {
+			return false, err
+		}
+		return matchRegexp(args[0], ctx.path)
+	case "Method":
+		if err := arity(1); err != nil {
+			return false, err
+		}
+		return strings.EqualFold(args[0], ctx.method), nil
+	case "Header":
+		if err := arity(2); err != nil {
+			return false, err
+		}
+		return stringSliceContains(ctx.headers.Values(args[0]), args[1]), nil
+	case "HeaderRegexp":
+		if err := arity(2); err != nil {
+			return false, err
+		}
+		return matchAnyRegexp(args[1], ctx.headers.Values(args[0]))
+	case "Query":
+		if len(args) != 1 && len(args) != 2 {
+			return false, fmt.Errorf("matcher Query takes 1 or 2 arguments, got %d", len(args))
+		}
+		values, present := ctx.query[args[0]]
+		if len(args) == 1 {
+			return present, nil
+		}
+		return stringSliceContains(values, args[1]), nil
+	case "QueryRegexp":
+		if err := arity(2); err != nil {
+			return false, err
+		}
+		return matchAnyRegexp(args[1], ctx.query[args[0]])
+	case "ClientIP":
+		if err := arity(1); err != nil {
+			return false, err
+		}
+		if ctx.clientIP == nil {
+			return false, nil
+		}
+		if !strings.Contains(args[0], "/") {
+			ip := net.ParseIP(args[0])
+			return ip != nil && ip.Equal(ctx.clientIP), nil
+		}
+		_, network, err := net.ParseCIDR(args[0])
+		if err != nil {
+			return false, fmt.Errorf("matcher ClientIP: %v", err)
+		}
+		return network.Contains(ctx.clientIP), nil
+	default:
+		return false, fmt.Errorf("matcher %s is not supported", name)
+	}
+}
+
+// matchRegexp matches value against a Traefik v3 regexp, which is not anchored
+func matchRegexp(pattern, value string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid regexp %q: %v", pattern, err)
+	}
+	return re.MatchString(value), nil
+}
+
+// matchAnyRegexp reports whether any of values matches pattern
+func matchAnyRegexp(pattern string, values []string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid regexp %q: %v", pattern, err)
+	}
+	for _, value := range values {
+		if re.MatchString(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateRule(t *testing.T) {
+	ctx := &ruleContext{
+		host:    "app.example.com",
+		path:    "/api/v1/users",
+		method:  http.MethodPost,
+		headers: http.Header{"X-Env": []string{"prod"}},
+		query:   url.Values{"debug": []string{"1"}},
+	}
+
+	tests := []struct {
+		rule    string
+		matched bool
+		wantErr bool
+	}{
+		{"Host(`app.example.com`)", true, false},
+		{"Host(`APP.example.com`) && PathPrefix(`/api`)", true, false},
+		{"Host(`other.example.com`) || Path(`/api/v1/users`)", true, false},
+		{"Host(`app.example.com`) && !Method(`POST`)", false, false},
+		{"HostRegexp(`^[a-z]+\\.example\\.com$`) && (Method(`GET`) || Method(`POST`))", true, false},
+		{"PathRegexp(`/users$`) && Header(`X-Env`, `prod`)", true, false},
+		{"HeaderRegexp(`X-Env`, `^stag`)", false, false},
+		{"Query(`debug`) && Query(`debug`, `1`) && QueryRegexp(`debug`, `^\\d$`)", true, false},
+		{"ClientIP(`10.0.0.0/8`)", false, false},
+		{"Host(`app.example.com`", false, true},
+		{"Bogus(`x`)", false, true},
+		{"Host(`a`, `b`)", false, true},
+	}
+	for _, tt := range tests {
+		matched, err := evaluateRule(tt.rule, ctx)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("evaluateRule(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			continue
+		}
+		if matched != tt.matched {
+			t.Errorf("evaluateRule(%q) = %v, want %v", tt.rule, matched, tt.matched)
+		}
+	}
+}
+
+func TestSimulateRoute(t *testing.T) {
+	tls := &OrderedTLSConfig{}
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"app":      {Rule: "Host(`app.example.com`)", Service: "app-svc", EntryPoints: []string{"websecure"}, TLS: tls, Middlewares: []string{"auth"}},
+				"app-api":  {Rule: "Host(`app.example.com`) && PathPrefix(`/api`)", Service: "api-svc", EntryPoints: []string{"websecure"}, TLS: tls, Middlewares: []string{"stack", "gzip"}},
+				"app-low":  {Rule: "Host(`app.example.com`) && PathPrefix(`/api/v1`)", Service: "low-svc", Priority: 1, TLS: tls},
+				"app-http": {Rule: "Host(`app.example.com`)", Service: "redirect", EntryPoints: []string{"web"}},
+				"broken":   {Rule: "Host(`app.example.com`", Service: "x", TLS: tls},
+			},
+			Middlewares: map[string]interface{}{
+				"stack": map[string]interface{}{"chain": map[string]interface{}{"middlewares": []interface{}{"headers", "auth"}}},
+			},
+		},
+	}
+
+	target, _ := url.Parse("https://App.Example.com/api/v1/users")
+	result := SimulateRoute(config, RouteRequest{URL: target})
+	if !result.Matched || result.Router != "app-api" || result.Service != "api-svc" {
+		t.Fatalf("winner = %q (%q), want app-api", result.Router, result.Service)
+	}
+	if want := []string{"headers", "auth", "gzip"}; !reflect.DeepEqual(result.Chain, want) {
+		t.Errorf("chain = %v, want %v", result.Chain, want)
+	}
+	var order []string
+	for _, candidate := range result.Candidates {
+		order = append(order, candidate.Router)
+	}
+	if want := []string{"app-api", "app", "app-low", "broken"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("candidates = %v, want %v", order, want)
+	}
+	if result.Candidates[3].Error == "" || result.Candidates[3].Matched {
+		t.Errorf("broken router candidate = %+v, want an evaluation error", result.Candidates[3])
+	}
+
+	plain, _ := url.Parse("http://app.example.com/")
+	if result := SimulateRoute(config, RouteRequest{URL: plain}); result.Router != "app-http" {
+		t.Errorf("plain HTTP winner = %q, want app-http", result.Router)
+	}
+	if result := SimulateRoute(config, RouteRequest{URL: target, EntryPoint: "web"}); result.Router != "app-low" {
+		t.Errorf("winner on entrypoint web = %q, want app-low which has no entrypoints", result.Router)
+	}
+}
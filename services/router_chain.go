@@ -0,0 +1,45 @@
+package services
+
+// RouterChain is the middleware chain of a resource's router: Upstream as
+// Pangolin attached it and Effective as served to Traefik after the merge
+type RouterChain struct {
+	Router    string   `json:"router"`
+	Upstream  []string `json:"upstream"`
+	Effective []string `json:"effective"`
+}
+
+// ResourceRouterChain finds the router of a resource in the upstream config
+// the way the merge does, by Pangolin router ID and then by host, and returns
+// its chain. It returns nil when no upstream router matches.
+func (cp *ConfigProxy) ResourceRouterChain(pangolinRouterID, host string) (*RouterChain, error) {
+	// The served config is cached, so this only fetches after expiry
+	served, err := cp.GetMergedConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cp.pangolinCache.mu.Lock()
+	upstream := cp.pangolinCache.config
+	var chain *RouterChain
+	if upstream != nil && upstream.HTTP != nil {
+		key, router := cp.findRouterByPangolinID(upstream.HTTP.Routers, pangolinRouterID)
+		if key == "" {
+			key, router = cp.findMatchingRouter(upstream.HTTP.Routers, host)
+		}
+		if key != "" {
+			chain = &RouterChain{Router: key, Upstream: append([]string{}, router.Middlewares...)}
+		}
+	}
+	cp.pangolinCache.mu.Unlock()
+
+	if chain == nil {
+		return nil, nil
+	}
+	chain.Effective = []string{}
+	if served.HTTP != nil {
+		if router := served.HTTP.Routers[chain.Router]; router != nil {
+			chain.Effective = append(chain.Effective, router.Middlewares...)
+		}
+	}
+	return chain, nil
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Known router and router TLS keys, in the order Pangolin writes them
+var (
+	routerFieldOrder    = []string{"entryPoints", "middlewares", "service", "rule", "priority", "tls"}
+	routerTLSFieldOrder = []string{"certResolver", "domains", "options"}
+)
+
+// UnmarshalJSON decodes a router leniently: known fields are typed, anything
+// else (including a known field with an unexpected type) is kept verbatim in
+// Extra so it is never lost and validation can report it.
+func (r *OrderedRouter) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = OrderedRouter{}
+	for key, value := range raw {
+		var err error
+		switch key {
+		case "entryPoints":
+			err = json.Unmarshal(value, &r.EntryPoints)
+		case "middlewares":
+			err = json.Unmarshal(value, &r.Middlewares)
+		case "service":
+			err = json.Unmarshal(value, &r.Service)
+		case "rule":
+			err = json.Unmarshal(value, &r.Rule)
+		case "priority":
+			err = json.Unmarshal(value, &r.Priority)
+		case "tls":
+			if !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+				r.TLS = &OrderedTLSConfig{}
+				if err = json.Unmarshal(value, r.TLS); err != nil {
+					r.TLS = nil
+				}
+			}
+		default:
+			r.setExtra(key, value)
+			continue
+		}
+		if err != nil {
+			r.setExtra(key, value)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes known fields in Pangolin's order followed by preserved extras
+func (r OrderedRouter) MarshalJSON() ([]byte, error) {
+	return marshalOrderedObject(routerFieldOrder, r.knownFields(), r.Extra)
+}
+
+// knownFields returns the typed fields that are set, keyed by JSON name
+func (r *OrderedRouter) knownFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	if len(r.EntryPoints) > 0 {
+		fields["entryPoints"] = r.EntryPoints
+	}
+	if len(r.Middlewares) > 0 {
+		fields["middlewares"] = r.Middlewares
+	}
+	if r.Service != "" {
+		fields["service"] = r.Service
+	}
+	if r.Rule != "" {
+		fields["rule"] = r.Rule
+	}
+	if r.Priority != 0 {
+		fields["priority"] = r.Priority
+	}
+	if r.TLS != nil {
+		fields["tls"] = r.TLS
+	}
+	return fields
+}
+
+func (r *OrderedRouter) setExtra(key string, value json.RawMessage) {
+	if r.Extra == nil {
+		r.Extra = make(map[string]json.RawMessage)
+	}
+	r.Extra[key] = value
+}
+
+// InvalidFields lists known router fields that were present with an unexpected
+// type and have not been set since, i.e. the ones that would be written as-is
+func (r *OrderedRouter) InvalidFields() []string {
+	invalid := shadowedExtras(routerFieldOrder, r.knownFields(), r.Extra, "")
+	if r.TLS != nil {
+		invalid = append(invalid, shadowedExtras(routerTLSFieldOrder, r.TLS.knownFields(), r.TLS.Extra, "tls.")...)
+	}
+	return invalid
+}
+
+// UnmarshalJSON decodes router TLS settings with the same leniency as routers
+func (t *OrderedTLSConfig) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*t = OrderedTLSConfig{}
+	for key, value := range raw {
+		var err error
+		switch key {
+		case "certResolver":
+			err = json.Unmarshal(value, &t.CertResolver)
+		case "domains":
+			err = json.Unmarshal(value, &t.Domains)
+		case "options":
+			err = json.Unmarshal(value, &t.Options)
+		default:
+			t.setExtra(key, value)
+			continue
+		}
+		if err != nil {
+			t.setExtra(key, value)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes known TLS fields in Pangolin's order followed by preserved extras
+func (t OrderedTLSConfig) MarshalJSON() ([]byte, error) {
+	return marshalOrderedObject(routerTLSFieldOrder, t.knownFields(), t.Extra)
+}
+
+func (t *OrderedTLSConfig) knownFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	if t.CertResolver != "" {
+		fields["certResolver"] = t.CertResolver
+	}
+	if len(t.Domains) > 0 {
+		fields["domains"] = t.Domains
+	}
+	if t.Options != "" {
+		fields["options"] = t.Options
+	}
+	return fields
+}
+
+func (t *OrderedTLSConfig) setExtra(key string, value json.RawMessage) {
+	if t.Extra == nil {
+		t.Extra = make(map[string]json.RawMessage)
+	}
+	t.Extra[key] = value
+}
+
+// shadowedExtras returns the known keys whose raw extra value is still in effect
+func shadowedExtras(order []string, fields map[string]interface{}, extra map[string]json.RawMessage, prefix string) []string {
+	var keys []string
+	for _, key := range order {
+		if _, set := fields[key]; set {
+			continue
+		}
+		if _, ok := extra[key]; ok {
+			keys = append(keys, prefix+key)
+		}
+	}
+	return keys
+}
+
+// marshalOrderedObject writes the known fields in order, then the extras sorted
+// by key. An extra that shadows a known field is only written while the typed
+// field is unset, so code that sets the field replaces the invalid original.
+func marshalOrderedObject(order []string, fields map[string]interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	writeMember := func(key string, value []byte) {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	known := make(map[string]struct{}, len(order))
+	for _, key := range order {
+		known[key] = struct{}{}
+		if value, ok := fields[key]; ok {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			writeMember(key, encoded)
+		} else if raw, ok := extra[key]; ok {
+			writeMember(key, raw)
+		}
+	}
+
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		if _, ok := known[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeMember(key, extra[key])
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
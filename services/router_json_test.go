@@ -0,0 +1,73 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOrderedRouterDecodesTypedFields(t *testing.T) {
+	data := []byte(`{
+		"rule": "Host(` + "`app.example.com`" + `)",
+		"service": "app-service",
+		"entryPoints": ["websecure"],
+		"priority": 150,
+		"tls": {"certResolver": "letsencrypt", "domains": [{"main": "example.com", "sans": ["*.example.com"]}]},
+		"ruleSyntax": "v3"
+	}`)
+
+	var router OrderedRouter
+	if err := json.Unmarshal(data, &router); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if router.Service != "app-service" || router.Priority != 150 || !reflect.DeepEqual(router.EntryPoints, []string{"websecure"}) {
+		t.Fatalf("unexpected typed fields: %+v", router)
+	}
+	if router.TLS == nil || len(router.TLS.Domains) != 1 || router.TLS.Domains[0].SANs[0] != "*.example.com" {
+		t.Fatalf("unexpected TLS: %+v", router.TLS)
+	}
+	if len(router.InvalidFields()) != 0 {
+		t.Fatalf("unexpected invalid fields: %v", router.InvalidFields())
+	}
+
+	out, err := json.Marshal(&router)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"entryPoints":["websecure"],"service":"app-service","rule":"Host(` + "`app.example.com`" + `)","priority":150,` +
+		`"tls":{"certResolver":"letsencrypt","domains":[{"main":"example.com","sans":["*.example.com"]}]},"ruleSyntax":"v3"}`
+	if string(out) != want {
+		t.Fatalf("Marshal() =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestOrderedRouterKeepsMistypedFields(t *testing.T) {
+	var router OrderedRouter
+	if err := json.Unmarshal([]byte(`{"rule":"Host(`+"`a`"+`)","service":"s","middlewares":"auth","priority":"high"}`), &router); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got := router.InvalidFields(); !reflect.DeepEqual(got, []string{"middlewares", "priority"}) {
+		t.Fatalf("InvalidFields() = %v", got)
+	}
+
+	// The original values are passed through until MM sets the field itself
+	out, _ := json.Marshal(&router)
+	if want := `{"middlewares":"auth","service":"s","rule":"Host(` + "`a`" + `)","priority":"high"}`; string(out) != want {
+		t.Fatalf("Marshal() = %s, want %s", out, want)
+	}
+	router.Middlewares = []string{"headers"}
+	out, _ = json.Marshal(&router)
+	if want := `{"middlewares":["headers"],"service":"s","rule":"Host(` + "`a`" + `)","priority":"high"}`; string(out) != want {
+		t.Fatalf("Marshal() = %s, want %s", out, want)
+	}
+
+	cp := &ConfigProxy{}
+	errs := cp.validateConfig(&ProxiedTraefikConfig{HTTP: &HTTPConfig{
+		Routers:  map[string]*OrderedRouter{"r": &router},
+		Services: map[string]interface{}{"s": map[string]interface{}{"loadBalancer": map[string]interface{}{}}},
+	}})
+	want := []string{`http router "r" has an invalid priority`, `http router "r" references unknown middleware "headers"`}
+	if !reflect.DeepEqual(errs, want) {
+		t.Fatalf("validateConfig() = %v, want %v", errs, want)
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"database/sql"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/database"
+)
+
+// processStart approximates the process start time for the uptime report
+var processStart = time.Now()
+
+// recentGCPauses is how many of the latest GC pauses are reported
+const recentGCPauses = 10
+
+// RuntimeStats is a point-in-time view of the process, for diagnosing memory
+// growth and leaks on long-running instances
+type RuntimeStats struct {
+	CollectedAt   time.Time `json:"collected_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Version       string    `json:"version"`
+	GoVersion     string    `json:"go_version"`
+	NumCPU        int       `json:"num_cpu"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	Goroutines    int       `json:"goroutines"`
+	// OpenFDs is the number of open file descriptors; nil where the platform
+	// does not expose them
+	OpenFDs  *int               `json:"open_fds,omitempty"`
+	Memory   RuntimeMemoryStats `json:"memory"`
+	GC       RuntimeGCStats     `json:"gc"`
+	Database RuntimeDBStats     `json:"database"`
+	Caches   map[string]int     `json:"caches"`
+}
+
+// RuntimeMemoryStats are the Go heap and total memory figures in bytes
+type RuntimeMemoryStats struct {
+	HeapAlloc   uint64 `json:"heap_alloc_bytes"`
+	HeapInuse   uint64 `json:"heap_inuse_bytes"`
+	HeapIdle    uint64 `json:"heap_idle_bytes"`
+	HeapObjects uint64 `json:"heap_objects"`
+	StackInuse  uint64 `json:"stack_inuse_bytes"`
+	Sys         uint64 `json:"sys_bytes"`
+	TotalAlloc  uint64 `json:"total_alloc_bytes"`
+	NextGC      uint64 `json:"next_gc_bytes"`
+}
+
+// RuntimeGCStats summarizes garbage collection; RecentPausesMs is newest first
+type RuntimeGCStats struct {
+	NumGC          uint32     `json:"num_gc"`
+	PauseTotalMs   float64    `json:"pause_total_ms"`
+	RecentPausesMs []float64  `json:"recent_pauses_ms"`
+	LastGCAt       *time.Time `json:"last_gc_at,omitempty"`
+	CPUFraction    float64    `json:"cpu_fraction"`
+}
+
+// RuntimeDBStats reports the database file and connection pool
+type RuntimeDBStats struct {
+	Path       string `json:"path,omitempty"`
+	Driver     string `json:"driver"`
+	SizeBytes  int64  `json:"size_bytes"`
+	FreeBytes  int64  `json:"free_bytes"`
+	WALBytes   int64  `json:"wal_bytes"`
+	OpenConns  int    `json:"open_connections"`
+	InUseConns int    `json:"in_use_connections"`
+	IdleConns  int    `json:"idle_connections"`
+	WaitCount  int64  `json:"wait_count"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CollectRuntimeStats gathers runtime, database and cache figures. caches maps
+// a cache name to the number of entries it holds. Reading memory statistics
+// stops the world briefly, so this is meant for on-demand diagnostics.
+func CollectRuntimeStats(db *sql.DB, caches map[string]int) RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		CollectedAt:   time.Now().UTC(),
+		UptimeSeconds: int64(time.Since(processStart).Seconds()),
+		Version:       AppVersion(),
+		GoVersion:     runtime.Version(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Goroutines:    runtime.NumGoroutine(),
+		OpenFDs:       openFDs(),
+		Memory: RuntimeMemoryStats{
+			HeapAlloc:   mem.HeapAlloc,
+			HeapInuse:   mem.HeapInuse,
+			HeapIdle:    mem.HeapIdle,
+			HeapObjects: mem.HeapObjects,
+			StackInuse:  mem.StackInuse,
+			Sys:         mem.Sys,
+			TotalAlloc:  mem.TotalAlloc,
+			NextGC:      mem.NextGC,
+		},
+		GC: RuntimeGCStats{
+			NumGC:          mem.NumGC,
+			PauseTotalMs:   float64(mem.PauseTotalNs) / 1e6,
+			RecentPausesMs: []float64{},
+			CPUFraction:    mem.GCCPUFraction,
+		},
+		Caches: caches,
+	}
+	// PauseNs is a circular buffer whose latest entry is at (NumGC+255)%256
+	for i := uint32(0); i < min(mem.NumGC, recentGCPauses); i++ {
+		pause := mem.PauseNs[(mem.NumGC-1-i)%uint32(len(mem.PauseNs))]
+		stats.GC.RecentPausesMs = append(stats.GC.RecentPausesMs, float64(pause)/1e6)
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		stats.GC.LastGCAt = &lastGC
+	}
+	if stats.Caches == nil {
+		stats.Caches = map[string]int{}
+	}
+	if db != nil {
+		stats.Database = collectDBStats(db)
+	}
+	return stats
+}
+
+// collectDBStats sizes the database from its page counts, which works for
+// any path, and stats the write-ahead log next to the file. PostgreSQL
+// reports the size of the whole database instead.
+func collectDBStats(db *sql.DB) RuntimeDBStats {
+	pool := db.Stats()
+	stats := RuntimeDBStats{
+		Driver:     database.DriverName(),
+		OpenConns:  pool.OpenConnections,
+		InUseConns: pool.InUse,
+		IdleConns:  pool.Idle,
+		WaitCount:  pool.WaitCount,
+	}
+
+	if database.IsPostgres() {
+		if err := db.QueryRow("SELECT pg_database_size(current_database())").Scan(&stats.SizeBytes); err != nil {
+			stats.Error = err.Error()
+		}
+		return stats
+	}
+
+	var pageCount, pageSize, freePages int64
+	err := db.QueryRow("PRAGMA page_count").Scan(&pageCount)
+	if err == nil {
+		err = db.QueryRow("PRAGMA page_size").Scan(&pageSize)
+	}
+	if err == nil {
+		err = db.QueryRow("PRAGMA freelist_count").Scan(&freePages)
+	}
+	if err != nil {
+		stats.Error = err.Error()
+		return stats
+	}
+	stats.SizeBytes = pageCount * pageSize
+	stats.FreeBytes = freePages * pageSize
+
+	var path string
+	if err := db.QueryRow("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&path); err == nil && path != "" {
+		stats.Path = path
+		if info, err := os.Stat(path + "-wal"); err == nil {
+			stats.WALBytes = info.Size()
+		}
+	}
+	return stats
+}
+
+// openFDs counts the entries of /proc/self/fd, which Linux containers expose
+func openFDs() *int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return nil
+	}
+	n := len(entries)
+	return &n
+}
+
+// CacheSizes returns the entries held by the config proxy's caches: the
+// routers, services and middlewares of the served and parsed Pangolin
+// configs, and the revisions the change log keeps
+func (cp *ConfigProxy) CacheSizes() map[string]int {
+	cp.cacheMutex.RLock()
+	served := configEntries(cp.cache)
+	cp.cacheMutex.RUnlock()
+
+	cp.pangolinCache.mu.Lock()
+	pangolin := configEntries(cp.pangolinCache.config)
+	cp.pangolinCache.mu.Unlock()
+
+	cp.changeLog.mu.Lock()
+	revisions := len(cp.changeLog.snapshots)
+	cp.changeLog.mu.Unlock()
+
+	return map[string]int{
+		"served_config":    served,
+		"pangolin_config":  pangolin,
+		"config_revisions": revisions,
+	}
+}
+
+// configEntries counts the routers, services and middlewares of a config
+func configEntries(config *ProxiedTraefikConfig) int {
+	if config == nil {
+		return 0
+	}
+	n := 0
+	if config.HTTP != nil {
+		n += len(config.HTTP.Routers) + len(config.HTTP.Services) + len(config.HTTP.Middlewares)
+	}
+	if config.TCP != nil {
+		n += len(config.TCP.Routers) + len(config.TCP.Services)
+	}
+	if config.UDP != nil {
+		n += len(config.UDP.Routers) + len(config.UDP.Services)
+	}
+	return n
+}
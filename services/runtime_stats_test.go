@@ -0,0 +1,38 @@
+package services
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCollectRuntimeStats(t *testing.T) {
+	db := newTestDB(t)
+	runtime.GC()
+
+	stats := CollectRuntimeStats(db.DB, map[string]int{"test": 3})
+	if stats.Goroutines < 1 || stats.Memory.HeapAlloc == 0 || stats.GC.NumGC == 0 {
+		t.Errorf("missing runtime figures: %+v", stats)
+	}
+	if len(stats.GC.RecentPausesMs) == 0 || stats.GC.LastGCAt == nil {
+		t.Errorf("missing GC pauses: %+v", stats.GC)
+	}
+	if stats.Database.Error != "" || stats.Database.SizeBytes == 0 || stats.Database.Path == "" {
+		t.Errorf("missing database figures: %+v", stats.Database)
+	}
+	if stats.Caches["test"] != 3 {
+		t.Errorf("caches = %v", stats.Caches)
+	}
+}
+
+func TestConfigProxyCacheSizes(t *testing.T) {
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), "")
+	cp.cache = &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+		Routers:     map[string]*OrderedRouter{"a": {}, "b": {}},
+		Middlewares: map[string]interface{}{"m": nil},
+	}}
+
+	sizes := cp.CacheSizes()
+	if sizes["served_config"] != 3 || sizes["pangolin_config"] != 0 || sizes["config_revisions"] != 0 {
+		t.Errorf("CacheSizes() = %v", sizes)
+	}
+}
@@ -0,0 +1,496 @@
+package services
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Secret rotation kinds
+const (
+	SecretKindBasicAuth  = "basicAuth"
+	SecretKindDigestAuth = "digestAuth"
+	SecretKindValue      = "value"
+)
+
+// RotateSecretRequest describes a middleware secret to rotate.
+// For basicAuth/digestAuth middlewares Username selects the entry in "users";
+// for everything else Path is a dot-separated path into the middleware config
+// (for plugins this starts with the plugin name).
+type RotateSecretRequest struct {
+	MiddlewareID string        `json:"middleware_id"`
+	Path         string        `json:"path"`
+	Username     string        `json:"username"`
+	NewValue     string        `json:"new_value"`
+	GracePeriod  time.Duration `json:"-"`
+}
+
+// RotateSecretResult reports what a rotation changed. NewValue is only returned once.
+type RotateSecretResult struct {
+	RotationID         string     `json:"rotation_id"`
+	Kind               string     `json:"kind"`
+	NewValue           string     `json:"new_value"`
+	UpdatedMiddlewares []string   `json:"updated_middlewares"`
+	GraceApplied       bool       `json:"grace_applied"`
+	GraceExpiresAt     *time.Time `json:"grace_expires_at,omitempty"`
+	Note               string     `json:"note,omitempty"`
+}
+
+// SecretRotator rotates secrets stored in middleware configs and removes
+// superseded values once their grace period ends
+type SecretRotator struct {
+	db       *database.DB
+	now      func() time.Time
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSecretRotator creates a new secret rotator
+func NewSecretRotator(db *database.DB) *SecretRotator {
+	return &SecretRotator{
+		db:       db,
+		now:      time.Now,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start periodically finalizes rotations whose grace period has ended
+func (r *SecretRotator) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if n, err := r.ExpireRotations(); err != nil {
+				log.Printf("Error finalizing secret rotations: %v", err)
+			} else if n > 0 {
+				log.Printf("Finalized %d secret rotation(s)", n)
+			}
+		case <-r.stopChan:
+			return
+		}
+	}
+}
+
+// Stop stops the background expiry loop
+func (r *SecretRotator) Stop() {
+	r.stopOnce.Do(func() { close(r.stopChan) })
+}
+
+// Rotate generates (or accepts) a new secret and updates every middleware that
+// references the old value in a single transaction
+func (r *SecretRotator) Rotate(req RotateSecretRequest) (*RotateSecretResult, error) {
+	var typ, configStr string
+	err := r.db.QueryRow("SELECT type, config FROM middlewares WHERE id = ?", req.MiddlewareID).Scan(&typ, &configStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(configStr), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse middleware config: %w", err)
+	}
+
+	result := &RotateSecretResult{RotationID: uuid.New().String(), UpdatedMiddlewares: []string{}}
+
+	// replace is applied to every middleware config; it reports whether it changed anything
+	var replace func(typ string, config map[string]interface{}) bool
+	var oldValue string
+
+	switch typ {
+	case "basicAuth", "digestAuth":
+		if req.Username == "" {
+			return nil, fmt.Errorf("username is required to rotate %s credentials", typ)
+		}
+		oldEntry, realm, err := findUserEntry(config, req.Username)
+		if err != nil {
+			return nil, err
+		}
+		password := req.NewValue
+		if password == "" {
+			if password, err = randomSecret(24); err != nil {
+				return nil, err
+			}
+		}
+		newEntry, err := buildUserEntry(typ, req.Username, realm, password)
+		if err != nil {
+			return nil, err
+		}
+		result.Kind = typ
+		result.NewValue = password
+		result.Note = "Traefik matches each user once, so the previous password stops working immediately"
+		replace = func(t string, cfg map[string]interface{}) bool {
+			if t != typ {
+				return false
+			}
+			return replaceInList(cfg, "users", oldEntry, newEntry, false)
+		}
+
+	default:
+		if req.Path == "" {
+			return nil, fmt.Errorf("path is required to rotate a %s secret", typ)
+		}
+		current, ok := lookupPath(config, req.Path)
+		if !ok {
+			return nil, fmt.Errorf("path %q not found in middleware config", req.Path)
+		}
+		newValue := req.NewValue
+		if newValue == "" {
+			if newValue, err = randomSecret(32); err != nil {
+				return nil, err
+			}
+		}
+		result.Kind = SecretKindValue
+		result.NewValue = newValue
+
+		switch v := current.(type) {
+		case string:
+			if v == "" {
+				return nil, fmt.Errorf("path %q holds an empty value", req.Path)
+			}
+			oldValue = v
+			result.Note = "Field holds a single value, so the previous secret stops working immediately"
+			replace = func(_ string, cfg map[string]interface{}) bool {
+				return replaceString(cfg, oldValue, newValue)
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("path %q holds an empty list", req.Path)
+			}
+			last, ok := v[len(v)-1].(string)
+			if !ok {
+				return nil, fmt.Errorf("path %q does not hold a list of strings", req.Path)
+			}
+			// Lists accept several secrets at once, so the old value can stay during the grace period
+			oldValue = last
+			keepOld := req.GracePeriod > 0
+			result.GraceApplied = keepOld
+			replace = func(_ string, cfg map[string]interface{}) bool {
+				return replaceInAnyList(cfg, oldValue, newValue, keepOld)
+			}
+		default:
+			return nil, fmt.Errorf("path %q does not hold a string or list of strings", req.Path)
+		}
+	}
+
+	if req.GracePeriod > 0 && !result.GraceApplied && result.Note != "" {
+		result.Note += "; grace period ignored"
+	}
+
+	err = r.db.WithTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id, type, config FROM middlewares")
+		if err != nil {
+			return err
+		}
+		type update struct{ id, config string }
+		var updates []update
+		for rows.Next() {
+			var id, t, cfgStr string
+			if err := rows.Scan(&id, &t, &cfgStr); err != nil {
+				rows.Close()
+				return err
+			}
+			var cfg map[string]interface{}
+			if err := json.Unmarshal([]byte(cfgStr), &cfg); err != nil {
+				continue
+			}
+			if !replace(t, cfg) {
+				continue
+			}
+			encoded, err := json.Marshal(cfg)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			updates = append(updates, update{id: id, config: string(encoded)})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, u := range updates {
+			if _, err := tx.Exec("UPDATE middlewares SET config = ?, updated_at = ?, version = version + 1 WHERE id = ?", u.config, r.now(), u.id); err != nil {
+				return fmt.Errorf("failed to update middleware %s: %w", u.id, err)
+			}
+			result.UpdatedMiddlewares = append(result.UpdatedMiddlewares, u.id)
+		}
+		if len(updates) == 0 {
+			return fmt.Errorf("no middleware references the secret")
+		}
+
+		idsJSON, _ := json.Marshal(result.UpdatedMiddlewares)
+		var expiresAt interface{}
+		storedOld := ""
+		if result.GraceApplied {
+			expires := r.now().Add(req.GracePeriod)
+			result.GraceExpiresAt = &expires
+			expiresAt = expires
+			storedOld = oldValue
+		}
+		var completedAt interface{}
+		if !result.GraceApplied {
+			completedAt = r.now()
+		}
+		_, err = tx.Exec(`
+			INSERT INTO secret_rotations (id, kind, middleware_ids, path, old_value, grace_applied, expires_at, completed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, result.RotationID, result.Kind, string(idsJSON), req.Path, storedOld, boolToInt(result.GraceApplied), expiresAt, completedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Rotated %s secret for middleware %s (%d middleware(s) updated, grace=%v)",
+		result.Kind, req.MiddlewareID, len(result.UpdatedMiddlewares), result.GraceApplied)
+	return result, nil
+}
+
+// ExpireRotations removes old values for rotations whose grace period has ended
+func (r *SecretRotator) ExpireRotations() (int, error) {
+	return r.finalize("expires_at <= ?", r.now())
+}
+
+// CompleteRotation ends a rotation's grace period immediately
+func (r *SecretRotator) CompleteRotation(id string) error {
+	n, err := r.finalize("id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SecretRotator) finalize(condition string, arg interface{}) (int, error) {
+	rows, err := r.db.Query(
+		"SELECT id, middleware_ids, old_value FROM secret_rotations WHERE completed_at IS NULL AND grace_applied = 1 AND "+condition,
+		arg,
+	)
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		id       string
+		ids      []string
+		oldValue string
+	}
+	var items []pending
+	for rows.Next() {
+		var p pending
+		var idsJSON string
+		if err := rows.Scan(&p.id, &idsJSON, &p.oldValue); err != nil {
+			continue
+		}
+		_ = json.Unmarshal([]byte(idsJSON), &p.ids)
+		items = append(items, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range items {
+		err := r.db.WithTransaction(func(tx *sql.Tx) error {
+			for _, mwID := range p.ids {
+				var cfgStr string
+				if err := tx.QueryRow("SELECT config FROM middlewares WHERE id = ?", mwID).Scan(&cfgStr); err != nil {
+					if err == sql.ErrNoRows {
+						continue
+					}
+					return err
+				}
+				var cfg map[string]interface{}
+				if err := json.Unmarshal([]byte(cfgStr), &cfg); err != nil {
+					continue
+				}
+				if !removeFromAnyList(cfg, p.oldValue) {
+					continue
+				}
+				encoded, err := json.Marshal(cfg)
+				if err != nil {
+					return err
+				}
+				if _, err := tx.Exec("UPDATE middlewares SET config = ?, updated_at = ?, version = version + 1 WHERE id = ?", string(encoded), r.now(), mwID); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec("UPDATE secret_rotations SET completed_at = ?, old_value = '' WHERE id = ?", r.now(), p.id)
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to finalize rotation %s: %w", p.id, err)
+		}
+	}
+
+	return len(items), nil
+}
+
+// findUserEntry returns the users entry for username and, for digestAuth, its realm
+func findUserEntry(config map[string]interface{}, username string) (string, string, error) {
+	users, _ := config["users"].([]interface{})
+	for _, u := range users {
+		entry, ok := u.(string)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) >= 2 && parts[0] == username {
+			realm := ""
+			if len(parts) == 3 {
+				realm = parts[1]
+			}
+			return entry, realm, nil
+		}
+	}
+	return "", "", fmt.Errorf("user %q not found", username)
+}
+
+// buildUserEntry produces a Traefik users entry: bcrypt for basicAuth, HA1 for digestAuth
+func buildUserEntry(typ, username, realm, password string) (string, error) {
+	if typ == "digestAuth" {
+		sum := md5.Sum([]byte(username + ":" + realm + ":" + password))
+		return fmt.Sprintf("%s:%s:%s", username, realm, hex.EncodeToString(sum[:])), nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return username + ":" + string(hash), nil
+}
+
+// lookupPath follows a dot-separated path through nested maps
+func lookupPath(config map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = config
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// replaceInList swaps oldValue for newValue in config[key]; keepOld appends instead
+func replaceInList(config map[string]interface{}, key, oldValue, newValue string, keepOld bool) bool {
+	list, ok := config[key].([]interface{})
+	if !ok {
+		return false
+	}
+	updated, changed := swapListValue(list, oldValue, newValue, keepOld)
+	if changed {
+		config[key] = updated
+	}
+	return changed
+}
+
+func swapListValue(list []interface{}, oldValue, newValue string, keepOld bool) ([]interface{}, bool) {
+	out := make([]interface{}, 0, len(list)+1)
+	changed := false
+	for _, item := range list {
+		if s, ok := item.(string); ok && s == oldValue {
+			changed = true
+			if keepOld {
+				out = append(out, s)
+			}
+			out = append(out, newValue)
+			continue
+		}
+		out = append(out, item)
+	}
+	return out, changed
+}
+
+// replaceString replaces every string equal to oldValue anywhere in the config tree
+func replaceString(node map[string]interface{}, oldValue, newValue string) bool {
+	changed := false
+	for k, v := range node {
+		switch val := v.(type) {
+		case string:
+			if val == oldValue {
+				node[k] = newValue
+				changed = true
+			}
+		case map[string]interface{}:
+			if replaceString(val, oldValue, newValue) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// replaceInAnyList swaps oldValue in every string list anywhere in the config tree
+func replaceInAnyList(node map[string]interface{}, oldValue, newValue string, keepOld bool) bool {
+	changed := false
+	for k, v := range node {
+		switch val := v.(type) {
+		case []interface{}:
+			if updated, ok := swapListValue(val, oldValue, newValue, keepOld); ok {
+				node[k] = updated
+				changed = true
+			}
+		case map[string]interface{}:
+			if replaceInAnyList(val, oldValue, newValue, keepOld) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// removeFromAnyList drops oldValue from every string list anywhere in the config tree
+func removeFromAnyList(node map[string]interface{}, oldValue string) bool {
+	changed := false
+	for k, v := range node {
+		switch val := v.(type) {
+		case []interface{}:
+			out := make([]interface{}, 0, len(val))
+			for _, item := range val {
+				if s, ok := item.(string); ok && s == oldValue {
+					changed = true
+					continue
+				}
+				out = append(out, item)
+			}
+			node[k] = out
+		case map[string]interface{}:
+			if removeFromAnyList(val, oldValue) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func randomSecret(n int) (string, error) {
+	raw, err := randomBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func middlewareConfig(t *testing.T, rotator *SecretRotator, id string) map[string]interface{} {
+	t.Helper()
+	var raw string
+	if err := rotator.db.QueryRow("SELECT config FROM middlewares WHERE id = ?", id).Scan(&raw); err != nil {
+		t.Fatalf("query middleware %s: %v", id, err)
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatalf("decode middleware %s: %v", id, err)
+	}
+	return cfg
+}
+
+func TestSecretRotatorBasicAuthUpdatesEveryReference(t *testing.T) {
+	db := newTestDB(t)
+	oldEntry := "alice:$apr1$old$hash"
+	for _, id := range []string{"auth-a", "auth-b"} {
+		cfg := `{"users":["` + oldEntry + `","bob:$apr1$bob$hash"]}`
+		if _, err := db.Exec("INSERT INTO middlewares (id, name, type, config) VALUES (?, ?, 'basicAuth', ?)", id, id, cfg); err != nil {
+			t.Fatalf("insert middleware: %v", err)
+		}
+	}
+
+	rotator := NewSecretRotator(db)
+	result, err := rotator.Rotate(RotateSecretRequest{MiddlewareID: "auth-a", Username: "alice", GracePeriod: time.Hour})
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if result.NewValue == "" || result.GraceApplied {
+		t.Fatalf("Rotate() = %+v; want a new password without grace", result)
+	}
+	if len(result.UpdatedMiddlewares) != 2 {
+		t.Fatalf("UpdatedMiddlewares = %v; want both middlewares", result.UpdatedMiddlewares)
+	}
+
+	for _, id := range []string{"auth-a", "auth-b"} {
+		users := middlewareConfig(t, rotator, id)["users"].([]interface{})
+		if len(users) != 2 || users[1] != "bob:$apr1$bob$hash" {
+			t.Fatalf("%s users = %v; other users must be untouched", id, users)
+		}
+		entry := users[0].(string)
+		if !strings.HasPrefix(entry, "alice:") {
+			t.Fatalf("%s users[0] = %q; want alice entry", id, entry)
+		}
+		hash := strings.TrimPrefix(entry, "alice:")
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(result.NewValue)); err != nil {
+			t.Fatalf("%s: new hash does not match returned password: %v", id, err)
+		}
+	}
+}
+
+func TestSecretRotatorListGracePeriod(t *testing.T) {
+	db := newTestDB(t)
+	cfg := `{"my-plugin":{"apiKeys":["key-old"],"endpoint":"https://example.com"}}`
+	if _, err := db.Exec("INSERT INTO middlewares (id, name, type, config) VALUES ('plug', 'plug', 'plugin', ?)", cfg); err != nil {
+		t.Fatalf("insert middleware: %v", err)
+	}
+
+	rotator := NewSecretRotator(db)
+	now := time.Now()
+	rotator.now = func() time.Time { return now }
+
+	result, err := rotator.Rotate(RotateSecretRequest{
+		MiddlewareID: "plug",
+		Path:         "my-plugin.apiKeys",
+		NewValue:     "key-new",
+		GracePeriod:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if !result.GraceApplied || result.GraceExpiresAt == nil {
+		t.Fatalf("Rotate() = %+v; want grace applied", result)
+	}
+
+	keys := func() []interface{} {
+		return middlewareConfig(t, rotator, "plug")["my-plugin"].(map[string]interface{})["apiKeys"].([]interface{})
+	}
+	if got := keys(); len(got) != 2 || got[0] != "key-old" || got[1] != "key-new" {
+		t.Fatalf("apiKeys during grace = %v; want both keys", got)
+	}
+
+	if n, err := rotator.ExpireRotations(); err != nil || n != 0 {
+		t.Fatalf("ExpireRotations() before expiry = %d, %v", n, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if n, err := rotator.ExpireRotations(); err != nil || n != 1 {
+		t.Fatalf("ExpireRotations() = %d, %v; want 1", n, err)
+	}
+	if got := keys(); len(got) != 1 || got[0] != "key-new" {
+		t.Fatalf("apiKeys after grace = %v; want only the new key", got)
+	}
+
+	var oldValue string
+	if err := db.QueryRow("SELECT old_value FROM secret_rotations WHERE id = ?", result.RotationID).Scan(&oldValue); err != nil {
+		t.Fatalf("query rotation: %v", err)
+	}
+	if oldValue != "" {
+		t.Fatal("old secret should be cleared once the rotation completes")
+	}
+}
+
+func TestSecretRotatorStringValue(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES ('p1', 'p1', 'plugin', '{"geo":{"apiKey":"abc"}}')`); err != nil {
+		t.Fatalf("insert middleware: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES ('p2', 'p2', 'plugin', '{"other":{"token":"abc"}}')`); err != nil {
+		t.Fatalf("insert middleware: %v", err)
+	}
+
+	rotator := NewSecretRotator(db)
+	result, err := rotator.Rotate(RotateSecretRequest{MiddlewareID: "p1", Path: "geo.apiKey"})
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if len(result.UpdatedMiddlewares) != 2 {
+		t.Fatalf("UpdatedMiddlewares = %v; want every middleware referencing the value", result.UpdatedMiddlewares)
+	}
+	got := middlewareConfig(t, rotator, "p2")["other"].(map[string]interface{})["token"]
+	if got != result.NewValue {
+		t.Fatalf("p2 token = %v; want %s", got, result.NewValue)
+	}
+
+	if _, err := rotator.Rotate(RotateSecretRequest{MiddlewareID: "p1", Path: "geo.missing"}); err == nil {
+		t.Fatal("Rotate() accepted an unknown path")
+	}
+}
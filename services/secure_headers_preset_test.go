@@ -0,0 +1,128 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestMergeConfig_SecureHeadersPresets(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	for _, stmt := range []string{
+		`UPDATE security_config SET secure_headers_enabled = 1, secure_headers_x_frame_options = 'SAMEORIGIN' WHERE id = 1`,
+		`INSERT INTO secure_header_presets (id, name, headers) VALUES ('custom-1', 'kiosk', '{"x_frame_options":"ALLOW-FROM https://kiosk.example.com"}')`,
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, secure_headers_enabled)
+		 VALUES ('global', 'global', 'global.example.com', 's', 'org', 'site', 'active', 1)`,
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, secure_headers_enabled, secure_headers_preset)
+		 VALUES ('strict', 'strict', 'strict.example.com', 's', 'org', 'site', 'active', 1, 'strict')`,
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, secure_headers_enabled, secure_headers_preset)
+		 VALUES ('custom', 'custom', 'custom.example.com', 's', 'org', 'site', 'active', 1, 'custom-1')`,
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, secure_headers_enabled, secure_headers_preset)
+		 VALUES ('gone', 'gone', 'gone.example.com', 's', 'org', 'site', 'active', 1, 'deleted-preset')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{Routers: map[string]*OrderedRouter{}, Middlewares: map[string]interface{}{}},
+		TLS:  &TLSConfig{Options: map[string]interface{}{}},
+	}
+	for _, id := range []string{"global", "strict", "custom", "gone"} {
+		config.HTTP.Routers[id] = &OrderedRouter{Rule: "Host(`" + id + ".example.com`)", Service: "s"}
+	}
+
+	if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+		t.Fatalf("mergeMiddlewareManagerConfig: %v", err)
+	}
+
+	frameOptions := func(resourceID string) interface{} {
+		var mw map[string]interface{}
+		ok := false
+		for _, name := range config.HTTP.Routers[resourceID].Middlewares {
+			if strings.HasPrefix(name, headersMiddlewarePrefix) {
+				mw, ok = config.HTTP.Middlewares[name].(map[string]interface{})
+			}
+		}
+		if !ok {
+			t.Fatalf("no headers middleware for %s", resourceID)
+		}
+		headers := mw["headers"].(map[string]interface{})
+		return headers["customResponseHeaders"].(map[string]interface{})["X-Frame-Options"]
+	}
+
+	strict, _ := models.BuiltInSecureHeadersPreset(models.SecureHeadersPresetStrict)
+	tests := map[string]interface{}{
+		"global": "SAMEORIGIN",
+		"strict": strict.Headers.XFrameOptions,
+		"custom": "ALLOW-FROM https://kiosk.example.com",
+		"gone":   "SAMEORIGIN",
+	}
+	for id, want := range tests {
+		if got := frameOptions(id); got != want {
+			t.Errorf("%s: X-Frame-Options = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestSecureHeadersLayer_ReportOnly(t *testing.T) {
+	headers := models.SecureHeadersConfig{
+		XFrameOptions: "DENY",
+		HSTS:          "max-age=63072000; includeSubDomains; preload",
+		CSP:           "default-src 'self'",
+	}
+
+	enforced := secureHeadersLayer(headers, false)["customResponseHeaders"].(map[string]interface{})
+	if enforced["Content-Security-Policy"] != headers.CSP || enforced["Strict-Transport-Security"] != headers.HSTS {
+		t.Errorf("unexpected enforced headers: %v", enforced)
+	}
+
+	trial := secureHeadersLayer(headers, true)["customResponseHeaders"].(map[string]interface{})
+	if _, ok := trial["Content-Security-Policy"]; ok {
+		t.Error("report-only mode must not enforce the CSP")
+	}
+	if trial["Content-Security-Policy-Report-Only"] != headers.CSP {
+		t.Errorf("expected the CSP as report-only, got %v", trial)
+	}
+	if trial["Strict-Transport-Security"] != "max-age=300; includeSubDomains" {
+		t.Errorf("expected a soft HSTS, got %v", trial["Strict-Transport-Security"])
+	}
+	if trial["X-Frame-Options"] != "DENY" {
+		t.Error("other headers are unaffected by report-only mode")
+	}
+}
+
+func TestSecureHeadersLayer_CrossOriginPolicies(t *testing.T) {
+	headers := models.SecureHeadersConfig{
+		PermissionsPolicy:           "camera=()",
+		PermissionsPolicyDirectives: []models.PermissionsPolicyDirective{{Feature: "geolocation", Allowlist: []string{"self"}}},
+		CrossOriginOpenerPolicy:     "same-origin",
+		CrossOriginEmbedderPolicy:   "require-corp",
+		CrossOriginResourcePolicy:   "same-origin",
+	}
+
+	enforced := secureHeadersLayer(headers, false)["customResponseHeaders"].(map[string]interface{})
+	want := map[string]interface{}{
+		"Permissions-Policy":           "geolocation=(self)",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "require-corp",
+		"Cross-Origin-Resource-Policy": "same-origin",
+	}
+	for name, value := range want {
+		if enforced[name] != value {
+			t.Errorf("%s = %v, want %v", name, enforced[name], value)
+		}
+	}
+
+	trial := secureHeadersLayer(headers, true)["customResponseHeaders"].(map[string]interface{})
+	if trial["Cross-Origin-Opener-Policy-Report-Only"] != "same-origin" || trial["Cross-Origin-Embedder-Policy-Report-Only"] != "require-corp" {
+		t.Errorf("expected report-only COOP/COEP, got %v", trial)
+	}
+	if trial["Cross-Origin-Resource-Policy"] != "same-origin" {
+		t.Error("CORP has no report-only variant and stays enforced")
+	}
+}
@@ -58,7 +58,7 @@ func (f *PangolinServiceFetcher) FetchServices(ctx context.Context) (*models.Ser
 	}
 
 	// Execute request
-	resp, err := f.httpClient.Do(req)
+	resp, err := doWithRetry(f.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -301,7 +301,7 @@ func (f *TraefikServiceFetcher) fetchHTTPServices(ctx context.Context, baseURL s
 	}
 
 	// Execute request
-	resp, err := f.httpClient.Do(req)
+	resp, err := doWithRetry(f.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -380,7 +380,7 @@ func (f *TraefikServiceFetcher) fetchTCPServices(ctx context.Context, baseURL st
 	}
 
 	// Execute request
-	resp, err := f.httpClient.Do(req)
+	resp, err := doWithRetry(f.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -514,7 +514,7 @@ func (f *TraefikServiceFetcher) fetchUDPServices(ctx context.Context, baseURL st
 	}
 
 	// Execute request
-	resp, err := f.httpClient.Do(req)
+	resp, err := doWithRetry(f.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
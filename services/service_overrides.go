@@ -0,0 +1,211 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Outcomes of a service override
+const (
+	ServiceOverrideApplied  = "applied"
+	ServiceOverrideNotFound = "not_found"
+	// ServiceOverrideUnsupported means the service is neither a loadBalancer
+	// nor a weighted service
+	ServiceOverrideUnsupported = "unsupported"
+)
+
+// ServiceOverrideStatus reports how one service override was applied in the
+// last merge; Warnings lists the parts that did not match the service
+type ServiceOverrideStatus struct {
+	ServiceName string   `json:"service_name"`
+	State       string   `json:"state"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// ServiceOverrideStatus returns the service overrides of the last merge
+func (cp *ConfigProxy) ServiceOverrideStatus() []ServiceOverrideStatus {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+	return append([]ServiceOverrideStatus{}, cp.serviceOverrides...)
+}
+
+// loadServiceOverrides reads the stored overrides, skipping invalid ones
+func (cp *ConfigProxy) loadServiceOverrides() ([]models.ServiceOverride, error) {
+	rows, err := cp.db.Query("SELECT service_name, data FROM service_overrides ORDER BY service_name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []models.ServiceOverride
+	for rows.Next() {
+		var name, data string
+		if err := rows.Scan(&name, &data); err != nil {
+			log.Printf("Failed to scan service override: %v", err)
+			continue
+		}
+		var override models.ServiceOverride
+		if err := json.Unmarshal([]byte(data), &override); err != nil {
+			log.Printf("Skipping invalid override of service %s: %v", name, err)
+			continue
+		}
+		override.ServiceName = name
+		overrides = append(overrides, override)
+	}
+	return overrides, rows.Err()
+}
+
+// applyServiceOverrides patches the HTTP services the upstream sent with the
+// stored deltas. Only the served copy changes, so deleting an override
+// restores the upstream service on the next merge.
+func (cp *ConfigProxy) applyServiceOverrides(config *ProxiedTraefikConfig) {
+	overrides, err := cp.loadServiceOverrides()
+	if err != nil {
+		log.Printf("Warning: failed to load service overrides: %v", err)
+	}
+
+	statuses := make([]ServiceOverrideStatus, 0, len(overrides))
+	for _, override := range overrides {
+		status := ServiceOverrideStatus{ServiceName: override.ServiceName, State: ServiceOverrideNotFound}
+		if service, ok := config.HTTP.Services[override.ServiceName].(map[string]interface{}); ok {
+			status.State, status.Warnings = applyServiceOverride(service, override)
+		}
+		if status.State != ServiceOverrideApplied || len(status.Warnings) > 0 {
+			if shouldLog() {
+				log.Printf("Service override of %s: %s %v", override.ServiceName, status.State, status.Warnings)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	cp.cacheMutex.Lock()
+	cp.serviceOverrides = statuses
+	cp.cacheMutex.Unlock()
+}
+
+// applyServiceOverride patches one service in place
+func applyServiceOverride(service map[string]interface{}, override models.ServiceOverride) (string, []string) {
+	var warnings []string
+	if lb, ok := service["loadBalancer"].(map[string]interface{}); ok {
+		warnings = overrideLoadBalancer(lb, override)
+		if len(override.ServiceWeights) > 0 {
+			warnings = append(warnings, "service_weights ignored: not a weighted service")
+		}
+		return ServiceOverrideApplied, warnings
+	}
+	if weighted, ok := service["weighted"].(map[string]interface{}); ok {
+		warnings = overrideWeighted(weighted, override)
+		if len(override.AddServers) > 0 || len(override.RemoveServers) > 0 || len(override.ServerWeights) > 0 || override.HealthCheck != nil {
+			warnings = append(warnings, "server changes and health_check ignored: not a loadBalancer service")
+		}
+		return ServiceOverrideApplied, warnings
+	}
+	return ServiceOverrideUnsupported, nil
+}
+
+// overrideLoadBalancer removes, adds and reweights servers, then replaces the
+// health check and sticky sessions
+func overrideLoadBalancer(lb map[string]interface{}, override models.ServiceOverride) []string {
+	var warnings []string
+	servers, _ := lb["servers"].([]interface{})
+
+	if len(override.RemoveServers) > 0 {
+		remove := make(map[string]bool, len(override.RemoveServers))
+		for _, server := range override.RemoveServers {
+			remove[server] = true
+		}
+		kept := make([]interface{}, 0, len(servers))
+		for _, server := range servers {
+			if url := serverURL(server); remove[url] {
+				delete(remove, url)
+				continue
+			}
+			kept = append(kept, server)
+		}
+		for _, server := range override.RemoveServers {
+			if remove[server] {
+				warnings = append(warnings, fmt.Sprintf("server %s to remove not found", server))
+			}
+		}
+		servers = kept
+	}
+
+	for _, server := range override.AddServers {
+		entry := map[string]interface{}{"url": server.URL}
+		if server.Weight != nil {
+			entry["weight"] = *server.Weight
+		}
+		servers = append(servers, entry)
+	}
+
+	for url, weight := range override.ServerWeights {
+		found := false
+		for _, server := range servers {
+			if entry, ok := server.(map[string]interface{}); ok && serverURL(entry) == url {
+				entry["weight"] = weight
+				found = true
+			}
+		}
+		if !found {
+			warnings = append(warnings, fmt.Sprintf("server %s to reweight not found", url))
+		}
+	}
+
+	if len(servers) == 0 {
+		warnings = append(warnings, "no servers left in the pool")
+	}
+	lb["servers"] = servers
+
+	if override.HealthCheck != nil {
+		lb["healthCheck"] = overrideValue(override.HealthCheck)
+	}
+	if override.Sticky != nil {
+		lb["sticky"] = overrideValue(override.Sticky)
+	}
+	return warnings
+}
+
+// overrideWeighted reweights the child services and replaces sticky sessions
+func overrideWeighted(weighted map[string]interface{}, override models.ServiceOverride) []string {
+	var warnings []string
+	children, _ := weighted["services"].([]interface{})
+	for name, weight := range override.ServiceWeights {
+		found := false
+		for _, child := range children {
+			if entry, ok := child.(map[string]interface{}); ok && entry["name"] == name {
+				entry["weight"] = weight
+				found = true
+			}
+		}
+		if !found {
+			warnings = append(warnings, fmt.Sprintf("service %s to reweight not found", name))
+		}
+	}
+	if override.Sticky != nil {
+		weighted["sticky"] = overrideValue(override.Sticky)
+	}
+	return warnings
+}
+
+func serverURL(server interface{}) string {
+	entry, _ := server.(map[string]interface{})
+	url, _ := entry["url"].(string)
+	return url
+}
+
+// overrideValue converts a typed config block to the JSON map form the rest
+// of the served config uses
+func overrideValue(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	value := map[string]interface{}{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return map[string]interface{}{}
+	}
+	return value
+}
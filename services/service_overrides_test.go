@@ -0,0 +1,63 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyServiceOverrides(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	for _, row := range [][2]string{
+		{"app-service", `{"add_servers":[{"url":"http://10.0.0.7:8080","weight":1}],"remove_servers":["http://10.0.0.5:8080"],
+			"server_weights":{"http://10.0.0.6:8080":3,"http://10.0.0.9:8080":1},"health_check":{"path":"/healthz"}}`},
+		{"split", `{"service_weights":{"blue":1,"green":9},"sticky":{"cookie":{"name":"lb"}}}`},
+		{"gone", `{"server_weights":{"http://10.0.0.1":1}}`},
+	} {
+		if _, err := db.Exec("INSERT INTO service_overrides (service_name, data) VALUES (?, ?)", row[0], row[1]); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	config := &ProxiedTraefikConfig{HTTP: &HTTPConfig{Services: map[string]interface{}{
+		"app-service": map[string]interface{}{"loadBalancer": map[string]interface{}{"servers": []interface{}{
+			map[string]interface{}{"url": "http://10.0.0.5:8080"},
+			map[string]interface{}{"url": "http://10.0.0.6:8080"},
+		}}},
+		"split": map[string]interface{}{"weighted": map[string]interface{}{"services": []interface{}{
+			map[string]interface{}{"name": "blue", "weight": 5.0},
+			map[string]interface{}{"name": "green", "weight": 5.0},
+		}}},
+	}}}
+	cp.applyServiceOverrides(config)
+
+	lb := config.HTTP.Services["app-service"].(map[string]interface{})["loadBalancer"].(map[string]interface{})
+	wantServers := []interface{}{
+		map[string]interface{}{"url": "http://10.0.0.6:8080", "weight": 3},
+		map[string]interface{}{"url": "http://10.0.0.7:8080", "weight": 1},
+	}
+	if !reflect.DeepEqual(lb["servers"], wantServers) {
+		t.Errorf("servers = %v, want %v", lb["servers"], wantServers)
+	}
+	if !reflect.DeepEqual(lb["healthCheck"], map[string]interface{}{"path": "/healthz"}) {
+		t.Errorf("healthCheck = %v", lb["healthCheck"])
+	}
+
+	weighted := config.HTTP.Services["split"].(map[string]interface{})["weighted"].(map[string]interface{})
+	children := weighted["services"].([]interface{})
+	if children[1].(map[string]interface{})["weight"] != 9 || weighted["sticky"] == nil {
+		t.Errorf("weighted = %v", weighted)
+	}
+
+	states := map[string]ServiceOverrideStatus{}
+	for _, status := range cp.ServiceOverrideStatus() {
+		states[status.ServiceName] = status
+	}
+	if states["app-service"].State != ServiceOverrideApplied || len(states["app-service"].Warnings) != 1 {
+		t.Errorf("app-service status = %+v, want applied with a warning for the unknown server", states["app-service"])
+	}
+	if states["gone"].State != ServiceOverrideNotFound {
+		t.Errorf("gone status = %+v, want not_found", states["gone"])
+	}
+}
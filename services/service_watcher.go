@@ -21,7 +21,9 @@ type ServiceWatcher struct {
 	fetcher       ServiceFetcher
 	configManager *ConfigManager
 	stopChan      chan struct{}
-	isRunning     atomic.Bool
+	// reload asks the watch loop for an immediate refresh and check
+	reload    chan struct{}
+	isRunning atomic.Bool
 }
 
 // NewServiceWatcher creates a new service watcher
@@ -43,6 +45,7 @@ func NewServiceWatcher(db *database.DB, configManager *ConfigManager) (*ServiceW
 		fetcher:       fetcher,
 		configManager: configManager,
 		stopChan:      make(chan struct{}),
+		reload:        make(chan struct{}, 1),
 	}, nil
 }
 
@@ -66,14 +69,9 @@ func (sw *ServiceWatcher) Start(interval time.Duration) {
 	for {
 		select {
 		case <-ticker.C:
-			// Check if data source config has changed
-			if err := sw.refreshFetcher(); err != nil {
-				log.Printf("Failed to refresh service fetcher: %v", err)
-			}
-
-			if err := sw.checkServices(); err != nil {
-				log.Printf("Service check failed: %v", err)
-			}
+			sw.refreshAndCheck()
+		case <-sw.reload:
+			sw.refreshAndCheck()
 		case <-sw.stopChan:
 			log.Println("Service watcher stopped")
 			return
@@ -81,6 +79,26 @@ func (sw *ServiceWatcher) Start(interval time.Duration) {
 	}
 }
 
+// Reload asks the running watcher to rebuild its fetcher and check services
+// right away, e.g. after the settings were reloaded. It does not wait for the check.
+func (sw *ServiceWatcher) Reload() {
+	select {
+	case sw.reload <- struct{}{}:
+	default:
+	}
+}
+
+func (sw *ServiceWatcher) refreshAndCheck() {
+	// Check if data source config has changed
+	if err := sw.refreshFetcher(); err != nil {
+		log.Printf("Failed to refresh service fetcher: %v", err)
+	}
+
+	if err := sw.checkServices(); err != nil {
+		log.Printf("Service check failed: %v", err)
+	}
+}
+
 // refreshFetcher updates the fetcher if the data source config has changed
 func (sw *ServiceWatcher) refreshFetcher() error {
 	dsConfig, err := sw.configManager.GetActiveDataSourceConfig()
@@ -193,6 +211,16 @@ func (sw *ServiceWatcher) updateOrCreateService(service models.Service) error {
 	normalizedID := util.NormalizeID(service.ID)
 	originalID := service.ID
 
+	// Protected services keep the locally stored definition
+	if protected, err := sw.db.ProtectedNames(database.ProtectedKindService); err != nil {
+		log.Printf("Warning: failed to load protected services: %v", err)
+	} else if protected.Contains(originalID) || protected.Contains(normalizedID) {
+		var exists int
+		if err := sw.db.QueryRow("SELECT 1 FROM services WHERE id LIKE ?", normalizedID+"%").Scan(&exists); err == nil {
+			return nil
+		}
+	}
+
 	// Check if service already exists using normalized ID
 	var exists int
 	var existingType, existingConfig string
@@ -454,7 +482,7 @@ func (sw *ServiceWatcher) createService(service models.Service) error {
 
 		if err != nil {
 			// Check if it's a duplicate key error
-			if strings.Contains(err.Error(), "UNIQUE constraint") {
+			if database.IsUniqueViolation(err) {
 				// Log but don't return error to continue processing other services
 				log.Printf("Service %s already exists, skipping", service.ID)
 				return nil
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+	"github.com/hhftechnology/middleware-manager/util"
+	"gopkg.in/yaml.v3"
+)
+
+// setupProbeTimeout bounds each candidate probe; unreachable Docker hostnames
+// usually fail at DNS well before it
+const setupProbeTimeout = 3 * time.Second
+
+// Candidate URLs probed during detection, matching the service names used by
+// the Pangolin and Traefik compose examples
+var (
+	defaultPangolinCandidates = []string{
+		"http://pangolin:3001/api/v1",
+	}
+	defaultTraefikCandidates = []string{
+		"http://traefik:8080",
+		"http://host.docker.internal:8080",
+		"http://localhost:8080",
+		"http://127.0.0.1:8080",
+	}
+)
+
+// providerSnippet is the static config Traefik needs to load the config this
+// instance serves
+const providerSnippet = `providers:
+  http:
+    endpoint: "http://middleware-manager:3456/api/v1/traefik-config"
+    pollInterval: "5s"
+`
+
+// SetupWizard backs the first-run /api/setup flow: it guesses the deployment
+// type by probing the Docker network, saves the data sources and checks that
+// Traefik actually loads the config served here
+type SetupWizard struct {
+	configManager *ConfigManager
+	client        *http.Client
+	now           func() time.Time
+}
+
+// NewSetupWizard creates a setup wizard
+func NewSetupWizard(configManager *ConfigManager) *SetupWizard {
+	return &SetupWizard{
+		configManager: configManager,
+		client:        HTTPClientWithTimeout(setupProbeTimeout),
+		now:           time.Now,
+	}
+}
+
+// State returns the wizard progress with data source passwords masked
+func (w *SetupWizard) State() *models.SetupState {
+	progress := w.configManager.GetSetupProgress()
+	sources := w.configManager.GetDataSources()
+	for name, source := range sources {
+		source.FormatBasicAuth()
+		sources[name] = source
+	}
+
+	steps := []models.SetupStep{
+		{ID: models.SetupStepDetect, Title: "Detect deployment", Done: progress.DetectedAt != nil || progress.DataSourceAt != nil},
+		{ID: models.SetupStepDataSource, Title: "Configure data source", Done: progress.DataSourceAt != nil},
+		{ID: models.SetupStepProvider, Title: "Verify Traefik provider", Done: progress.ProviderVerifiedAt != nil},
+		{ID: models.SetupStepComplete, Title: "Finish", Done: progress.CompletedAt != nil},
+	}
+
+	state := &models.SetupState{
+		Completed:        progress.CompletedAt != nil,
+		Progress:         progress,
+		Steps:            steps,
+		ActiveDataSource: w.configManager.GetActiveSourceName(),
+		DataSources:      sources,
+	}
+	if !state.Completed {
+		for _, step := range steps {
+			if !step.Done {
+				state.NextStep = step.ID
+				break
+			}
+		}
+	}
+	return state
+}
+
+// Detect probes the candidate URLs concurrently and picks a deployment type:
+// Pangolin when any Pangolin candidate answers, standalone when only Traefik does
+func (w *SetupWizard) Detect(ctx context.Context, req models.SetupDetectRequest) (*models.SetupDetection, error) {
+	sources := w.configManager.GetDataSources()
+	var configuredPangolin, configuredTraefik []string
+	for _, source := range sources {
+		switch source.Type {
+		case models.PangolinAPI:
+			configuredPangolin = append(configuredPangolin, source.URL)
+		case models.TraefikAPI:
+			configuredTraefik = append(configuredTraefik, source.URL)
+		}
+	}
+
+	// User-supplied URLs first, then what is configured, then the guesses
+	pangolin := setupCandidates(req.PangolinURLs, configuredPangolin, defaultPangolinCandidates)
+	traefik := setupCandidates(req.TraefikURLs, configuredTraefik, defaultTraefikCandidates)
+
+	probes := make([]models.SetupProbe, len(pangolin)+len(traefik))
+	var wg sync.WaitGroup
+	for i, url := range pangolin {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			probes[i] = w.probe(ctx, models.PangolinAPI, url)
+		}(i, url)
+	}
+	for i, url := range traefik {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			probes[len(pangolin)+i] = w.probe(ctx, models.TraefikAPI, url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	detection := &models.SetupDetection{Probes: probes}
+	for _, probe := range probes {
+		if !probe.Reachable {
+			continue
+		}
+		if probe.Type == models.PangolinAPI && detection.PangolinURL == "" {
+			detection.PangolinURL = probe.URL
+		}
+		if probe.Type == models.TraefikAPI && detection.TraefikURL == "" {
+			detection.TraefikURL = probe.URL
+		}
+	}
+	switch {
+	case detection.PangolinURL != "":
+		detection.DeploymentType = models.DeploymentPangolin
+	case detection.TraefikURL != "":
+		detection.DeploymentType = models.DeploymentStandalone
+	}
+
+	if detection.DeploymentType != "" {
+		now := w.now().UTC()
+		err := w.configManager.UpdateSetupProgress(func(p *models.SetupProgress) {
+			p.DetectedAt = &now
+			if p.DeploymentType == "" {
+				p.DeploymentType = detection.DeploymentType
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return detection, nil
+}
+
+func (w *SetupWizard) probe(ctx context.Context, sourceType models.DataSourceType, url string) models.SetupProbe {
+	ctx, cancel := context.WithTimeout(ctx, setupProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := probeDataSource(ctx, w.client, models.DataSourceConfig{Type: sourceType, URL: url})
+	probe := models.SetupProbe{
+		Type:      sourceType,
+		URL:       url,
+		Reachable: err == nil,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		probe.Error = err.Error()
+	}
+	return probe
+}
+
+// setupCandidates merges candidate lists in priority order without duplicates
+func setupCandidates(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, list := range lists {
+		for _, url := range list {
+			url = strings.TrimRight(strings.TrimSpace(url), "/")
+			if url == "" || seen[url] {
+				continue
+			}
+			seen[url] = true
+			out = append(out, url)
+		}
+	}
+	return out
+}
+
+// SetupFieldError is returned by ConfigureDataSource for invalid or
+// unreachable input; Field names the request field at fault
+type SetupFieldError struct {
+	Field   string
+	Message string
+	// Unreachable is set when the URL is valid but did not answer
+	Unreachable bool
+}
+
+func (e *SetupFieldError) Error() string { return e.Message }
+
+// ConfigureDataSource saves the data sources for the chosen deployment type
+// and makes the matching one active. The active URL must answer unless
+// req.Force is set.
+func (w *SetupWizard) ConfigureDataSource(ctx context.Context, req models.SetupDataSourceRequest) error {
+	name, field, url := "traefik", "traefik_url", strings.TrimSpace(req.TraefikURL)
+	sourceType := models.TraefikAPI
+	if req.DeploymentType == models.DeploymentPangolin {
+		name, field, url = "pangolin", "pangolin_url", strings.TrimSpace(req.PangolinURL)
+		sourceType = models.PangolinAPI
+	}
+	if url == "" {
+		return &SetupFieldError{Field: field, Message: fmt.Sprintf("%s is required for a %s deployment", field, req.DeploymentType)}
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return &SetupFieldError{Field: field, Message: fmt.Sprintf("%s must start with http:// or https://", field)}
+	}
+
+	active := models.DataSourceConfig{Type: sourceType, URL: strings.TrimRight(url, "/")}
+	active.BasicAuth.Username = req.BasicAuth.Username
+	active.BasicAuth.Password = req.BasicAuth.Password
+
+	if !req.Force {
+		probeCtx, cancel := context.WithTimeout(ctx, setupProbeTimeout)
+		_, err := probeDataSource(probeCtx, w.client, active)
+		cancel()
+		if err != nil {
+			return &SetupFieldError{Field: field, Message: fmt.Sprintf("%s is not reachable: %v", url, err), Unreachable: true}
+		}
+	}
+
+	if err := w.configManager.UpdateDataSource(name, active); err != nil {
+		return err
+	}
+	// A Pangolin deployment still reads Traefik's API for the dashboard
+	if req.DeploymentType == models.DeploymentPangolin && strings.TrimSpace(req.TraefikURL) != "" {
+		traefik := models.DataSourceConfig{Type: models.TraefikAPI, URL: strings.TrimRight(strings.TrimSpace(req.TraefikURL), "/")}
+		if err := w.configManager.UpdateDataSource("traefik", traefik); err != nil {
+			return err
+		}
+	}
+	if err := w.configManager.SetActiveDataSource(name); err != nil {
+		return err
+	}
+
+	now := w.now().UTC()
+	return w.configManager.UpdateSetupProgress(func(p *models.SetupProgress) {
+		p.DeploymentType = req.DeploymentType
+		p.DataSourceAt = &now
+	})
+}
+
+// CheckProvider verifies from Traefik's side that it loads the config served
+// here: the static config must declare the HTTP provider, its endpoint must
+// answer, and Traefik's API must list the HTTP provider as loaded
+func (w *SetupWizard) CheckProvider(ctx context.Context, staticConfigPath string) (*models.SetupProviderCheck, error) {
+	result := &models.SetupProviderCheck{OK: true}
+
+	endpoint, declared := w.checkProviderDeclared(staticConfigPath)
+	result.Checks = append(result.Checks, declared)
+	if declared.Status == models.DiagnosticFail {
+		result.Snippet = providerSnippet
+	}
+	if endpoint != "" {
+		result.Checks = append(result.Checks, w.checkProviderEndpoint(ctx, endpoint))
+	}
+	result.Checks = append(result.Checks, w.checkProviderLoaded(ctx))
+
+	for _, check := range result.Checks {
+		if check.Status == models.DiagnosticFail {
+			result.OK = false
+		}
+	}
+	if result.OK {
+		now := w.now().UTC()
+		if err := w.configManager.UpdateSetupProgress(func(p *models.SetupProgress) { p.ProviderVerifiedAt = &now }); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (w *SetupWizard) checkProviderDeclared(staticConfigPath string) (string, models.DiagnosticCheck) {
+	check := models.DiagnosticCheck{Name: "http_provider_declared"}
+	if staticConfigPath == "" {
+		check.Status = models.DiagnosticWarn
+		check.Message = "Traefik static config path is not set, so the HTTP provider cannot be checked"
+		check.Remediation = "Set TRAEFIK_STATIC_CONFIG_PATH to the traefik.yml Traefik uses"
+		return "", check
+	}
+	data, err := util.OS.ReadFile(filepath.Clean(staticConfigPath))
+	if err != nil {
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("Cannot read %s: %v", staticConfigPath, err)
+		check.Remediation = "Mount Traefik's traefik.yml into this container to let setup check it"
+		return "", check
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		check.Status = models.DiagnosticFail
+		check.Message = fmt.Sprintf("Traefik static config is not valid YAML: %v", err)
+		return "", check
+	}
+
+	provider := staticSection(staticSection(config, "providers"), "http")
+	endpoint, _ := staticValue(provider, "endpoint").(string)
+	if endpoint == "" {
+		check.Status = models.DiagnosticFail
+		check.Message = "Traefik's static config has no HTTP provider endpoint"
+		check.Remediation = "Add the HTTP provider shown in the snippet and restart Traefik"
+		return "", check
+	}
+	if !strings.Contains(endpoint, "/traefik-config") {
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("HTTP provider endpoint %s does not point at /api/v1/traefik-config", endpoint)
+		check.Remediation = "Point providers.http.endpoint at this instance's /api/v1/traefik-config"
+		return endpoint, check
+	}
+	check.Status = models.DiagnosticPass
+	check.Message = fmt.Sprintf("HTTP provider endpoint is %s", endpoint)
+	return endpoint, check
+}
+
+// checkProviderEndpoint fetches the endpoint Traefik polls. Both containers
+// usually share a Docker network, so the hostname resolves here as well.
+func (w *SetupWizard) checkProviderEndpoint(ctx context.Context, endpoint string) models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: "http_provider_endpoint"}
+	ctx, cancel := context.WithTimeout(ctx, setupProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = w.client.Do(req)
+		if err == nil {
+			defer resp.Body.Close()
+			var body map[string]json.RawMessage
+			switch {
+			case resp.StatusCode != http.StatusOK:
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			case json.NewDecoder(io.LimitReader(resp.Body, 32<<20)).Decode(&body) != nil:
+				err = fmt.Errorf("response is not a JSON config")
+			}
+		}
+	}
+	if err != nil {
+		// Traefik may still reach it under a name this container cannot resolve
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("Could not fetch %s from here: %v", endpoint, err)
+		check.Remediation = "Make sure the hostname in providers.http.endpoint resolves on the network Traefik and Middleware Manager share"
+		return check
+	}
+	check.Status = models.DiagnosticPass
+	check.Message = fmt.Sprintf("%s serves a config", endpoint)
+	return check
+}
+
+// checkProviderLoaded asks Traefik's API whether the HTTP provider is active
+func (w *SetupWizard) checkProviderLoaded(ctx context.Context) models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: "http_provider_loaded"}
+	sources := w.configManager.GetDataSources()
+	traefik := sources["traefik"]
+	if traefik.Type != models.TraefikAPI {
+		traefik = models.DataSourceConfig{}
+		for _, source := range sources {
+			if source.Type == models.TraefikAPI && source.URL != "" {
+				traefik = source
+				break
+			}
+		}
+	}
+	if traefik.URL == "" {
+		check.Status = models.DiagnosticWarn
+		check.Message = "No Traefik API configured, so Traefik's providers cannot be checked"
+		check.Remediation = "Configure the Traefik API data source"
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, setupProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, traefik.URL+"/api/overview", nil)
+	if err != nil {
+		check.Status = models.DiagnosticWarn
+		check.Message = err.Error()
+		return check
+	}
+	AuthorizeDataSourceRequest(req, traefik)
+	client, err := DataSourceHTTPClient(w.client, traefik)
+	if err != nil {
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("Traefik API TLS settings are invalid: %v", err)
+		return check
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("Traefik API at %s is not reachable: %v", traefik.URL, err)
+		check.Remediation = "Enable Traefik's API and check the Traefik API URL"
+		return check
+	}
+	defer resp.Body.Close()
+
+	var overview struct {
+		Providers []string `json:"providers"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&overview) != nil {
+		check.Status = models.DiagnosticWarn
+		check.Message = fmt.Sprintf("Traefik API at %s returned an unexpected overview (status %d)", traefik.URL, resp.StatusCode)
+		return check
+	}
+	for _, provider := range overview.Providers {
+		if strings.EqualFold(provider, "http") {
+			check.Status = models.DiagnosticPass
+			check.Message = "Traefik has the HTTP provider loaded"
+			return check
+		}
+	}
+	check.Status = models.DiagnosticFail
+	check.Message = fmt.Sprintf("Traefik reports providers %v but not HTTP", overview.Providers)
+	check.Remediation = "Add the HTTP provider to Traefik's static config and restart Traefik; static config changes are not hot-reloaded"
+	return check
+}
+
+// Complete marks the wizard finished; it can also be used to skip it
+func (w *SetupWizard) Complete() error {
+	now := w.now().UTC()
+	return w.configManager.UpdateSetupProgress(func(p *models.SetupProgress) { p.CompletedAt = &now })
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// withoutDefaultCandidates keeps detection from probing real Docker hostnames
+func withoutDefaultCandidates(t *testing.T) {
+	t.Helper()
+	pangolin, traefik := defaultPangolinCandidates, defaultTraefikCandidates
+	defaultPangolinCandidates, defaultTraefikCandidates = nil, nil
+	t.Cleanup(func() {
+		defaultPangolinCandidates, defaultTraefikCandidates = pangolin, traefik
+	})
+}
+
+// newSetupUpstream answers like Pangolin and Traefik; providers is what
+// Traefik's overview lists
+func newSetupUpstream(t *testing.T, providers string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/traefik-config", "/traefik-config":
+			w.Write([]byte(`{"http":{"routers":{}}}`))
+		case "/api/version":
+			w.Write([]byte(`{"Version":"3.1.0"}`))
+		case "/api/overview":
+			w.Write([]byte(`{"providers":` + providers + `}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSetupWizardFreshInstallState(t *testing.T) {
+	wizard := NewSetupWizard(newTestConfigManager(t))
+	state := wizard.State()
+	if state.Completed || state.NextStep != models.SetupStepDetect {
+		t.Fatalf("fresh install should start at detection: %+v", state)
+	}
+}
+
+func TestSetupWizardSkipsLegacyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	legacy := `{"active_data_source":"pangolin","data_sources":{"pangolin":{"type":"pangolin","url":"http://pangolin:3001/api/v1"}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := NewConfigManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := cm.GetSetupProgress()
+	if !progress.Skipped || progress.CompletedAt == nil {
+		t.Fatalf("pre-wizard config should count as set up: %+v", progress)
+	}
+}
+
+func TestSetupWizardDetect(t *testing.T) {
+	withoutDefaultCandidates(t)
+	upstream := newSetupUpstream(t, `["HTTP"]`)
+	cm := newTestConfigManager(t)
+	wizard := NewSetupWizard(cm)
+
+	detection, err := wizard.Detect(context.Background(), models.SetupDetectRequest{
+		PangolinURLs: []string{upstream.URL, "http://127.0.0.1:1/api/v1"},
+		TraefikURLs:  []string{upstream.URL + "/"},
+	})
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if detection.DeploymentType != models.DeploymentPangolin || detection.PangolinURL != upstream.URL || detection.TraefikURL != upstream.URL {
+		t.Fatalf("unexpected detection: %+v", detection)
+	}
+
+	progress := cm.GetSetupProgress()
+	if progress.DetectedAt == nil || progress.DeploymentType != models.DeploymentPangolin {
+		t.Errorf("detection not recorded: %+v", progress)
+	}
+	if next := wizard.State().NextStep; next != models.SetupStepDataSource {
+		t.Errorf("next step = %q, want %q", next, models.SetupStepDataSource)
+	}
+}
+
+func TestSetupWizardDetectStandalone(t *testing.T) {
+	withoutDefaultCandidates(t)
+	upstream := newSetupUpstream(t, `[]`)
+	wizard := NewSetupWizard(newTestConfigManager(t))
+
+	detection, err := wizard.Detect(context.Background(), models.SetupDetectRequest{
+		PangolinURLs: []string{"http://127.0.0.1:1/api/v1"},
+		TraefikURLs:  []string{upstream.URL},
+	})
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if detection.DeploymentType != models.DeploymentStandalone {
+		t.Fatalf("expected a standalone deployment: %+v", detection)
+	}
+}
+
+func TestSetupWizardConfigureDataSource(t *testing.T) {
+	upstream := newSetupUpstream(t, `["HTTP"]`)
+	cm := newTestConfigManager(t)
+	wizard := NewSetupWizard(cm)
+
+	err := wizard.ConfigureDataSource(context.Background(), models.SetupDataSourceRequest{
+		DeploymentType: models.DeploymentStandalone,
+	})
+	var fieldErr *SetupFieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "traefik_url" {
+		t.Fatalf("expected a missing traefik_url error, got %v", err)
+	}
+
+	err = wizard.ConfigureDataSource(context.Background(), models.SetupDataSourceRequest{
+		DeploymentType: models.DeploymentStandalone,
+		TraefikURL:     "http://127.0.0.1:1",
+	})
+	if !errors.As(err, &fieldErr) || !fieldErr.Unreachable {
+		t.Fatalf("expected an unreachable error, got %v", err)
+	}
+
+	err = wizard.ConfigureDataSource(context.Background(), models.SetupDataSourceRequest{
+		DeploymentType: models.DeploymentStandalone,
+		TraefikURL:     upstream.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("ConfigureDataSource: %v", err)
+	}
+	if cm.GetActiveSourceName() != "traefik" || cm.GetDataSources()["traefik"].URL != upstream.URL {
+		t.Errorf("traefik source not saved and activated: %+v", cm.GetDataSources())
+	}
+	if progress := cm.GetSetupProgress(); progress.DataSourceAt == nil || progress.DeploymentType != models.DeploymentStandalone {
+		t.Errorf("data source step not recorded: %+v", progress)
+	}
+}
+
+func TestSetupWizardCheckProvider(t *testing.T) {
+	upstream := newSetupUpstream(t, `["Docker","HTTP"]`)
+	cm := newTestConfigManager(t)
+	if err := cm.UpdateDataSource("traefik", models.DataSourceConfig{Type: models.TraefikAPI, URL: upstream.URL}); err != nil {
+		t.Fatal(err)
+	}
+	wizard := NewSetupWizard(cm)
+
+	staticPath := filepath.Join(t.TempDir(), "traefik.yml")
+	static := "providers:\n  http:\n    endpoint: " + upstream.URL + "/api/v1/traefik-config\n"
+	if err := os.WriteFile(staticPath, []byte(static), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := wizard.CheckProvider(context.Background(), staticPath)
+	if err != nil {
+		t.Fatalf("CheckProvider: %v", err)
+	}
+	if !result.OK || len(result.Checks) != 3 || result.Snippet != "" {
+		t.Fatalf("expected all provider checks to pass: %+v", result)
+	}
+	if cm.GetSetupProgress().ProviderVerifiedAt == nil {
+		t.Error("provider verification not recorded")
+	}
+}
+
+func TestSetupWizardCheckProviderMissing(t *testing.T) {
+	upstream := newSetupUpstream(t, `["Docker"]`)
+	cm := newTestConfigManager(t)
+	cm.UpdateDataSource("traefik", models.DataSourceConfig{Type: models.TraefikAPI, URL: upstream.URL})
+	wizard := NewSetupWizard(cm)
+
+	staticPath := filepath.Join(t.TempDir(), "traefik.yml")
+	if err := os.WriteFile(staticPath, []byte("entryPoints:\n  web:\n    address: \":80\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := wizard.CheckProvider(context.Background(), staticPath)
+	if err != nil {
+		t.Fatalf("CheckProvider: %v", err)
+	}
+	if result.OK || result.Snippet == "" {
+		t.Fatalf("expected a failure with a snippet: %+v", result)
+	}
+	for _, check := range result.Checks {
+		if check.Status != models.DiagnosticFail {
+			t.Errorf("%s = %s, want fail", check.Name, check.Status)
+		}
+	}
+	if cm.GetSetupProgress().ProviderVerifiedAt != nil {
+		t.Error("a failed check must not be recorded as verified")
+	}
+}
+
+func TestSetupWizardComplete(t *testing.T) {
+	wizard := NewSetupWizard(newTestConfigManager(t))
+	if err := wizard.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if state := wizard.State(); !state.Completed || state.NextStep != "" {
+		t.Fatalf("expected a completed wizard: %+v", state)
+	}
+}
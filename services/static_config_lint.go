@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+	"gopkg.in/yaml.v3"
+)
+
+// HTTP provider poll interval bounds. Traefik polls every 5s by default; much
+// faster hammers the config endpoint, much slower delays every change made here.
+const (
+	minHTTPPollInterval = time.Second
+	maxHTTPPollInterval = time.Minute
+)
+
+// pinnedPluginVersion matches the tagged versions Traefik can download
+var pinnedPluginVersion = regexp.MustCompile(`^v\d+\.\d+\.\d+([-+][0-9A-Za-z.\-+]+)?$`)
+
+// LintTraefikStaticConfig parses a Traefik static config and reports common
+// problems. It never fails; a YAML error is reported as a finding.
+func LintTraefikStaticConfig(data []byte) *models.StaticConfigLintReport {
+	report := &models.StaticConfigLintReport{Findings: []models.StaticConfigLintFinding{}}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "parse",
+			Severity: models.LintSeverityError,
+			Message:  fmt.Sprintf("Static config is not valid YAML: %v", err),
+		})
+		return report
+	}
+	report.Parsed = true
+
+	lintAPI(report, staticSection(config, "api"))
+	lintFileProvider(report, staticSection(staticSection(config, "providers"), "file"))
+	lintHTTPProvider(report, staticSection(staticSection(config, "providers"), "http"))
+	lintPlugins(report, staticSection(staticSection(config, "experimental"), "plugins"))
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if lintSeverityRank(a.Severity) != lintSeverityRank(b.Severity) {
+			return lintSeverityRank(a.Severity) < lintSeverityRank(b.Severity)
+		}
+		return a.Path < b.Path
+	})
+	return report
+}
+
+func lintAPI(report *models.StaticConfigLintReport, api map[string]interface{}) {
+	if api == nil {
+		return
+	}
+	if staticBool(api, "insecure") {
+		message := "api.insecure serves the API without authentication on the traefik entrypoint (:8080 by default)"
+		if staticBool(api, "dashboard") {
+			message = "api.insecure exposes the dashboard and API without authentication on the traefik entrypoint (:8080 by default)"
+		}
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "api-insecure",
+			Severity: models.LintSeverityError,
+			Path:     "api.insecure",
+			Message:  message,
+			Hint:     "Remove api.insecure and route api@internal through a router with an auth middleware",
+		})
+	}
+	if staticBool(api, "debug") {
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "api-debug",
+			Severity: models.LintSeverityWarning,
+			Path:     "api.debug",
+			Message:  "api.debug exposes /debug/vars and pprof endpoints",
+			Hint:     "Only enable api.debug while troubleshooting",
+		})
+	}
+}
+
+func lintFileProvider(report *models.StaticConfigLintReport, file map[string]interface{}) {
+	if file == nil {
+		return
+	}
+	if staticValue(file, "filename") == nil && staticValue(file, "directory") == nil {
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "file-provider-source",
+			Severity: models.LintSeverityError,
+			Path:     "providers.file",
+			Message:  "File provider has neither filename nor directory; Traefik will not start",
+		})
+	}
+	if watch, ok := staticValue(file, "watch").(bool); ok && !watch {
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "file-provider-watch",
+			Severity: models.LintSeverityWarning,
+			Path:     "providers.file.watch",
+			Message:  "File provider watch is disabled, so changes to dynamic config files need a Traefik restart",
+			Hint:     "Set providers.file.watch to true",
+		})
+	}
+}
+
+func lintHTTPProvider(report *models.StaticConfigLintReport, provider map[string]interface{}) {
+	if provider == nil {
+		return
+	}
+	if endpoint, _ := staticValue(provider, "endpoint").(string); strings.TrimSpace(endpoint) == "" {
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "http-provider-endpoint",
+			Severity: models.LintSeverityError,
+			Path:     "providers.http.endpoint",
+			Message:  "HTTP provider has no endpoint",
+			Hint:     "Point it at Middleware Manager's /api/v1/traefik-config",
+		})
+	}
+
+	raw := staticValue(provider, "pollInterval")
+	if raw == nil {
+		return
+	}
+	interval, err := parseStaticDuration(raw)
+	if err != nil {
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "http-provider-poll-interval",
+			Severity: models.LintSeverityError,
+			Path:     "providers.http.pollInterval",
+			Message:  fmt.Sprintf("pollInterval %v is not a valid duration", raw),
+			Hint:     `Use a Go duration such as "5s"`,
+		})
+		return
+	}
+	switch {
+	case interval < minHTTPPollInterval:
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "http-provider-poll-interval",
+			Severity: models.LintSeverityWarning,
+			Path:     "providers.http.pollInterval",
+			Message:  fmt.Sprintf("pollInterval %s is below %s and will load the config endpoint heavily", interval, minHTTPPollInterval),
+			Hint:     `The Traefik default of "5s" suits most setups`,
+		})
+	case interval > maxHTTPPollInterval:
+		report.Add(models.StaticConfigLintFinding{
+			Rule:     "http-provider-poll-interval",
+			Severity: models.LintSeverityWarning,
+			Path:     "providers.http.pollInterval",
+			Message:  fmt.Sprintf("pollInterval %s is above %s, so changes take that long to reach Traefik", interval, maxHTTPPollInterval),
+			Hint:     `The Traefik default of "5s" suits most setups`,
+		})
+	}
+}
+
+func lintPlugins(report *models.StaticConfigLintReport, plugins map[string]interface{}) {
+	keys := make([]string, 0, len(plugins))
+	for key := range plugins {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := "experimental.plugins." + key
+		plugin, _ := plugins[key].(map[string]interface{})
+		if moduleName, _ := staticValue(plugin, "moduleName").(string); moduleName == "" {
+			report.Add(models.StaticConfigLintFinding{
+				Rule:     "plugin-module",
+				Severity: models.LintSeverityError,
+				Path:     path + ".moduleName",
+				Message:  fmt.Sprintf("Plugin %q has no moduleName", key),
+			})
+		}
+
+		version := fmt.Sprint(staticValue(plugin, "version"))
+		if staticValue(plugin, "version") == nil || strings.TrimSpace(version) == "" {
+			report.Add(models.StaticConfigLintFinding{
+				Rule:     "plugin-version",
+				Severity: models.LintSeverityError,
+				Path:     path + ".version",
+				Message:  fmt.Sprintf("Plugin %q has no version; Traefik refuses to load unpinned plugins", key),
+				Hint:     "Pin a released tag such as v1.2.3",
+			})
+		} else if !pinnedPluginVersion.MatchString(version) {
+			report.Add(models.StaticConfigLintFinding{
+				Rule:     "plugin-version",
+				Severity: models.LintSeverityWarning,
+				Path:     path + ".version",
+				Message:  fmt.Sprintf("Plugin %q version %q is not a release tag, so restarts may load different code", key, version),
+				Hint:     "Pin a released tag such as v1.2.3",
+			})
+		}
+	}
+}
+
+// parseStaticDuration accepts the forms Traefik does: a Go duration string or
+// a bare number of seconds
+func parseStaticDuration(raw interface{}) (time.Duration, error) {
+	switch v := raw.(type) {
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+		return time.ParseDuration(v)
+	}
+	return 0, fmt.Errorf("unsupported duration %v", raw)
+}
+
+// staticValue looks up a key case-insensitively, as Traefik does
+func staticValue(section map[string]interface{}, key string) interface{} {
+	if v, ok := section[key]; ok {
+		return v
+	}
+	for k, v := range section {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+func staticSection(section map[string]interface{}, key string) map[string]interface{} {
+	v := staticValue(section, key)
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	// An empty section such as "api:" still enables the feature
+	if v == nil {
+		if _, present := lookupStaticKey(section, key); present {
+			return map[string]interface{}{}
+		}
+	}
+	return nil
+}
+
+func lookupStaticKey(section map[string]interface{}, key string) (string, bool) {
+	for k := range section {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func staticBool(section map[string]interface{}, key string) bool {
+	b, _ := staticValue(section, key).(bool)
+	return b
+}
+
+func lintSeverityRank(severity string) int {
+	switch severity {
+	case models.LintSeverityError:
+		return 0
+	case models.LintSeverityWarning:
+		return 1
+	}
+	return 2
+}
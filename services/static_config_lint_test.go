@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// lintRules maps each rule to its most severe finding
+func lintRules(report *models.StaticConfigLintReport) map[string]string {
+	rules := make(map[string]string)
+	for _, f := range report.Findings {
+		if _, seen := rules[f.Rule]; !seen {
+			rules[f.Rule] = f.Severity
+		}
+	}
+	return rules
+}
+
+func TestLintTraefikStaticConfigFindsProblems(t *testing.T) {
+	report := LintTraefikStaticConfig([]byte(`
+api:
+  dashboard: true
+  insecure: true
+providers:
+  file:
+    directory: /rules
+    watch: false
+  http:
+    endpoint: http://mm:3456/api/v1/traefik-config
+    pollInterval: 500ms
+experimental:
+  plugins:
+    unpinned:
+      moduleName: github.com/example/unpinned
+    floating:
+      moduleName: github.com/example/floating
+      version: main
+    pinned:
+      moduleName: github.com/example/pinned
+      version: v1.2.3
+`))
+
+	if !report.Parsed {
+		t.Fatalf("expected the config to parse: %+v", report.Findings)
+	}
+	rules := lintRules(report)
+	want := map[string]string{
+		"api-insecure":                models.LintSeverityError,
+		"file-provider-watch":         models.LintSeverityWarning,
+		"http-provider-poll-interval": models.LintSeverityWarning,
+		"plugin-version":              models.LintSeverityError,
+	}
+	for rule, severity := range want {
+		if rules[rule] != severity {
+			t.Errorf("rule %s = %q, want %q (findings %+v)", rule, rules[rule], severity, report.Findings)
+		}
+	}
+	if report.Errors != 2 || report.Warnings != 3 {
+		t.Errorf("errors/warnings = %d/%d, want 2/3: %+v", report.Errors, report.Warnings, report.Findings)
+	}
+	if report.Findings[0].Severity != models.LintSeverityError {
+		t.Errorf("expected errors to sort first, got %+v", report.Findings[0])
+	}
+	for _, f := range report.Findings {
+		if f.Path == "experimental.plugins.pinned.version" {
+			t.Errorf("pinned plugin should not be flagged: %+v", f)
+		}
+	}
+}
+
+func TestLintTraefikStaticConfigClean(t *testing.T) {
+	report := LintTraefikStaticConfig([]byte(`
+api:
+  dashboard: true
+providers:
+  file:
+    filename: /etc/traefik/dynamic.yml
+  http:
+    endpoint: http://mm:3456/api/v1/traefik-config
+    pollInterval: "5s"
+experimental:
+  plugins:
+    crowdsec:
+      moduleName: github.com/maxlerebourg/crowdsec-bouncer-traefik-plugin
+      version: v1.4.2
+`))
+	if !report.Parsed || len(report.Findings) != 0 {
+		t.Fatalf("expected no findings, got %+v", report.Findings)
+	}
+}
+
+func TestLintTraefikStaticConfigPollInterval(t *testing.T) {
+	cases := map[string]string{
+		`pollInterval: 5`:      "",
+		`pollInterval: "10m"`:  models.LintSeverityWarning,
+		`pollInterval: "soon"`: models.LintSeverityError,
+	}
+	for line, severity := range cases {
+		report := LintTraefikStaticConfig([]byte("providers:\n  http:\n    endpoint: http://mm\n    " + line + "\n"))
+		if got := lintRules(report)["http-provider-poll-interval"]; got != severity {
+			t.Errorf("%s: severity = %q, want %q", line, got, severity)
+		}
+	}
+}
+
+func TestLintTraefikStaticConfigInvalidYAML(t *testing.T) {
+	report := LintTraefikStaticConfig([]byte("api: [unterminated"))
+	if report.Parsed || report.Errors != 1 || report.Findings[0].Rule != "parse" {
+		t.Fatalf("expected a single parse error, got %+v", report)
+	}
+}
@@ -2,13 +2,21 @@ package services
 
 import (
 	"database/sql"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hhftechnology/middleware-manager/database"
 )
 
-func newTestDB(t *testing.T) *database.DB {
+func TestMain(m *testing.M) {
+	// Keep retrying upstream fetches, but without real backoff waits
+	ConfigureRetry(RetryPolicy{Attempts: DefaultRetryPolicy.Attempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	os.Exit(m.Run())
+}
+
+func newTestDB(t testing.TB) *database.DB {
 	t.Helper()
 	dbPath := filepath.Join(t.TempDir(), "test.db")
 	db, err := database.InitDB(dbPath)
@@ -27,7 +35,7 @@ func newTestSQLDB(t *testing.T) *sql.DB {
 	return newTestDB(t).DB
 }
 
-func newTestConfigManager(t *testing.T) *ConfigManager {
+func newTestConfigManager(t testing.TB) *ConfigManager {
 	t.Helper()
 	cfgPath := filepath.Join(t.TempDir(), "config.json")
 	cm, err := NewConfigManager(cfgPath)
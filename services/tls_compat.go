@@ -0,0 +1,161 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Certificate key types the compatibility report can assume
+const (
+	TLSKeyTypeRSA   = "rsa"
+	TLSKeyTypeECDSA = "ecdsa"
+)
+
+var tlsVersionRank = map[string]int{
+	"VersionTLS10": 10,
+	"VersionTLS11": 11,
+	"VersionTLS12": 12,
+	"VersionTLS13": 13,
+}
+
+var tlsVersionLabel = map[int]string{10: "TLS 1.0", 11: "TLS 1.1", 12: "TLS 1.2", 13: "TLS 1.3"}
+
+// TLSProfileOptions returns the Traefik TLS options for a hardening profile
+func TLSProfileOptions(profile string) map[string]interface{} {
+	if profile == models.TLSProfileCompat {
+		return models.TLSCompatOptions()
+	}
+	return models.TLSHardeningOptions()
+}
+
+// AnalyzeTLSCompatibility simulates the handshake of each known client
+// profile against a hardening profile. keyType is the certificate key type
+// Traefik serves, which decides whether ECDHE_RSA or ECDHE_ECDSA suites apply.
+// When hardened locks clients out, the report also covers the compat profile.
+func AnalyzeTLSCompatibility(profile, keyType string) models.TLSCompatReport {
+	report := analyzeTLSProfile(profile, keyType)
+	if profile != models.TLSProfileCompat && report.Incompatible > 0 {
+		compat := analyzeTLSProfile(models.TLSProfileCompat, keyType)
+		report.CompatProfile = &compat
+	}
+	return report
+}
+
+func analyzeTLSProfile(profile, keyType string) models.TLSCompatReport {
+	options := TLSProfileOptions(profile)
+	minVersion := tlsVersionRank[fmt.Sprint(options["minVersion"])]
+	maxVersion := tlsVersionRank[fmt.Sprint(options["maxVersion"])]
+	suites, _ := options["cipherSuites"].([]string)
+	curves, _ := options["curvePreferences"].([]string)
+
+	report := models.TLSCompatReport{
+		Profile:     profile,
+		OptionsName: models.TLSProfileOptionsName(profile),
+		KeyType:     keyType,
+		Results:     []models.TLSCompatResult{},
+		Warnings:    []string{},
+	}
+
+	brokenByCategory := make(map[string][]string)
+	for _, client := range models.TLSClientProfiles() {
+		result := simulateHandshake(client, minVersion, maxVersion, suites, curves, keyType)
+		if result.Compatible {
+			report.Compatible++
+		} else {
+			report.Incompatible++
+			brokenByCategory[client.Category] = append(brokenByCategory[client.Category], client.Name)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	for _, category := range []string{models.TLSClientMobile, models.TLSClientDesktop, models.TLSClientSmartTV, models.TLSClientMonitoring} {
+		if names := brokenByCategory[category]; len(names) > 0 {
+			report.Warnings = append(report.Warnings,
+				fmt.Sprintf("%s clients would fail to connect: %s", category, strings.Join(names, ", ")))
+		}
+	}
+	return report
+}
+
+// simulateHandshake picks the protocol, suite and curve a server with the
+// given options would negotiate with a client, in server preference order
+func simulateHandshake(client models.TLSClientProfile, minVersion, maxVersion int, suites, curves []string, keyType string) models.TLSCompatResult {
+	result := models.TLSCompatResult{Client: client}
+
+	version := tlsVersionRank[client.MaxVersion]
+	if version > maxVersion {
+		version = maxVersion
+	}
+	if version < minVersion {
+		result.Reason = fmt.Sprintf("client supports at most %s but %s is required",
+			tlsVersionLabel[tlsVersionRank[client.MaxVersion]], tlsVersionLabel[minVersion])
+		return result
+	}
+
+	if version == 13 {
+		// TLS 1.3 suites are not configurable; only the key exchange group can fail
+		curve := firstCommon(curves, client.Curves)
+		if curve == "" {
+			result.Reason = "no TLS 1.3 key exchange group in common"
+			return result
+		}
+		result.Compatible = true
+		result.Protocol = tlsVersionLabel[version]
+		result.Curve = curve
+		return result
+	}
+
+	curve := firstCommon(curves, client.Curves)
+	sawSuite := false
+	for _, suite := range suites {
+		if !containsString(client.CipherSuites, suite) || !suiteMatchesKey(suite, keyType) {
+			continue
+		}
+		sawSuite = true
+		if strings.HasPrefix(suite, "TLS_ECDHE_") {
+			if curve == "" {
+				continue
+			}
+			result.Curve = curve
+		}
+		result.Compatible = true
+		result.Protocol = tlsVersionLabel[version]
+		result.CipherSuite = suite
+		return result
+	}
+
+	if sawSuite {
+		result.Reason = fmt.Sprintf("no elliptic curve in common (client offers %s)", strings.Join(client.Curves, ", "))
+	} else {
+		result.Reason = fmt.Sprintf("no cipher suite in common for an %s certificate", strings.ToUpper(keyType))
+	}
+	return result
+}
+
+// suiteMatchesKey reports whether a TLS 1.2 suite can be used with the certificate key type
+func suiteMatchesKey(suite, keyType string) bool {
+	if strings.Contains(suite, "_ECDSA_") {
+		return keyType == TLSKeyTypeECDSA
+	}
+	return keyType != TLSKeyTypeECDSA
+}
+
+func firstCommon(preferred, offered []string) string {
+	for _, item := range preferred {
+		if containsString(offered, item) {
+			return item
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func compatResults(report models.TLSCompatReport) map[string]models.TLSCompatResult {
+	results := make(map[string]models.TLSCompatResult, len(report.Results))
+	for _, r := range report.Results {
+		results[r.Client.ID] = r
+	}
+	return results
+}
+
+func TestAnalyzeTLSCompatibility_Hardened(t *testing.T) {
+	report := AnalyzeTLSCompatibility(models.TLSProfileHardened, TLSKeyTypeRSA)
+	if report.OptionsName != "tls-hardened" {
+		t.Errorf("options name = %s", report.OptionsName)
+	}
+	results := compatResults(report)
+
+	if r := results["chrome-current"]; !r.Compatible || r.Protocol != "TLS 1.3" {
+		t.Errorf("modern browsers should negotiate TLS 1.3: %+v", r)
+	}
+	if r := results["android-4.4"]; !r.Compatible || r.CipherSuite != "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" || r.Curve != "CurveP384" {
+		t.Errorf("unexpected Android 4.4 result: %+v", r)
+	}
+	for _, id := range []string{"android-4.0", "ie11-win7", "smart-tv-2016", "embedded-p256", "java-7"} {
+		if r := results[id]; r.Compatible || r.Reason == "" {
+			t.Errorf("%s should break with a reason: %+v", id, r)
+		}
+	}
+	if report.Compatible+report.Incompatible != len(models.TLSClientProfiles()) {
+		t.Errorf("counts do not cover every client: %+v", report)
+	}
+	if len(report.Warnings) == 0 {
+		t.Error("expected warnings for broken clients")
+	}
+	if report.CompatProfile == nil {
+		t.Fatal("expected the compat profile to be analyzed when clients break")
+	}
+
+	compat := compatResults(*report.CompatProfile)
+	for _, id := range []string{"ie11-win7", "smart-tv-2016", "embedded-p256", "android-4.4"} {
+		if !compat[id].Compatible {
+			t.Errorf("%s should connect with the compat profile: %+v", id, compat[id])
+		}
+	}
+	// compat keeps TLS 1.2 as the floor
+	if compat["android-4.0"].Compatible {
+		t.Error("TLS 1.0-only clients must still fail with the compat profile")
+	}
+	if report.CompatProfile.CompatProfile != nil {
+		t.Error("the compat report should not nest another compat report")
+	}
+}
+
+func TestAnalyzeTLSCompatibility_KeyType(t *testing.T) {
+	rsa := compatResults(AnalyzeTLSCompatibility(models.TLSProfileHardened, TLSKeyTypeRSA))
+	ecdsa := compatResults(AnalyzeTLSCompatibility(models.TLSProfileHardened, TLSKeyTypeECDSA))
+
+	// IE 11 on Windows 7 only offers GCM with ECDSA certificates
+	if rsa["ie11-win7"].Compatible {
+		t.Error("IE 11 should fail with an RSA certificate")
+	}
+	if r := ecdsa["ie11-win7"]; !r.Compatible || r.CipherSuite != "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256" {
+		t.Errorf("IE 11 should connect with an ECDSA certificate: %+v", r)
+	}
+}
+
+func TestMergeConfig_TLSHardeningProfile(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	for _, stmt := range []string{
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, tls_hardening_enabled)
+		 VALUES ('a', 'hardened-router', 'a.example.com', 'a', 'org', 'site', 'active', 1)`,
+		`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, tls_hardening_enabled, tls_hardening_profile)
+		 VALUES ('b', 'compat-router', 'b.example.com', 'b', 'org', 'site', 'active', 1, 'compat')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"hardened-router": {Rule: "Host(`a.example.com`)", Service: "a", TLS: &OrderedTLSConfig{}},
+				"compat-router":   {Rule: "Host(`b.example.com`)", Service: "b", TLS: &OrderedTLSConfig{}},
+			},
+			Middlewares: map[string]interface{}{},
+		},
+		TLS: &TLSConfig{Options: map[string]interface{}{}},
+	}
+
+	if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+		t.Fatalf("mergeMiddlewareManagerConfig: %v", err)
+	}
+
+	if got := config.HTTP.Routers["hardened-router"].TLS.Options; got != "tls-hardened" {
+		t.Errorf("hardened router options = %q", got)
+	}
+	if got := config.HTTP.Routers["compat-router"].TLS.Options; got != "tls-compat" {
+		t.Errorf("compat router options = %q", got)
+	}
+	for _, name := range []string{"tls-hardened", "tls-compat"} {
+		if _, ok := config.TLS.Options[name]; !ok {
+			t.Errorf("expected TLS options %s to be generated", name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestMergeConfig_TLSHardeningModes(t *testing.T) {
+	tests := []struct {
+		mode string
+		want map[string]bool
+	}{
+		// per-resource only: just the resource that enabled it
+		{models.TLSHardeningPerResource, map[string]bool{"on": true, "untouched": false, "opted-out": false, "mtls": false}},
+		// default-on: everything except the explicit opt-out
+		{models.TLSHardeningDefaultOn, map[string]bool{"on": true, "untouched": true, "opted-out": false, "mtls": false}},
+		// force-on: opt-outs are ignored, mTLS still uses its own options
+		{models.TLSHardeningForceOn, map[string]bool{"on": true, "untouched": true, "opted-out": true, "mtls": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			db := newTestDB(t)
+			cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+			for _, stmt := range []string{
+				`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, tls_hardening_enabled)
+				 VALUES ('on', 'on', 'on.example.com', 's', 'org', 'site', 'active', 1)`,
+				`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+				 VALUES ('untouched', 'untouched', 'untouched.example.com', 's', 'org', 'site', 'active')`,
+				`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, tls_hardening_opt_out)
+				 VALUES ('opted-out', 'opted-out', 'out.example.com', 's', 'org', 'site', 'active', 1)`,
+				`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, mtls_enabled)
+				 VALUES ('mtls', 'mtls', 'mtls.example.com', 's', 'org', 'site', 'active', 1)`,
+			} {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatalf("seed: %v", err)
+				}
+			}
+			if _, err := db.Exec("UPDATE security_config SET tls_hardening_mode = ? WHERE id = 1", tt.mode); err != nil {
+				t.Fatalf("set mode: %v", err)
+			}
+
+			config := &ProxiedTraefikConfig{
+				HTTP: &HTTPConfig{Routers: map[string]*OrderedRouter{}, Middlewares: map[string]interface{}{}},
+				TLS:  &TLSConfig{Options: map[string]interface{}{}},
+			}
+			for id := range tt.want {
+				config.HTTP.Routers[id] = &OrderedRouter{Rule: "Host(`" + id + ".example.com`)", Service: "s", TLS: &OrderedTLSConfig{}}
+			}
+
+			if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+				t.Fatalf("mergeMiddlewareManagerConfig: %v", err)
+			}
+
+			for id, want := range tt.want {
+				got := config.HTTP.Routers[id].TLS.Options == "tls-hardened"
+				if got != want {
+					t.Errorf("router %s hardened = %v, want %v (options %q)", id, got, want, config.HTTP.Routers[id].TLS.Options)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraefikCompatStatus describes the Traefik version the merged config is adapted to
+type TraefikCompatStatus struct {
+	Version    string     `json:"version"`
+	Major      int        `json:"major"`
+	Source     string     `json:"source"` // "override", "detected" or "unknown"
+	DetectedAt *time.Time `json:"detected_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	Warnings   []string   `json:"warnings"`
+}
+
+// traefikVersionState holds the detected or configured Traefik version
+type traefikVersionState struct {
+	mu       sync.RWMutex
+	override string
+	status   TraefikCompatStatus
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// middlewareTypeRenames maps middleware types Traefik v3 renamed (v2 name -> v3 name)
+var middlewareTypeRenames = map[string]string{
+	"ipWhiteList": "ipAllowList",
+}
+
+// middlewareOptionChange is one row of the v2/v3 option compatibility matrix.
+// V2 is empty for options added in v3; V3 is empty for options v3 removed.
+type middlewareOptionChange struct {
+	Type string
+	V2   string
+	V3   string
+}
+
+var middlewareOptionChanges = []middlewareOptionChange{
+	{Type: "headers", V2: "featurePolicy", V3: "permissionsPolicy"},
+	{Type: "headers", V2: "sslRedirect"},
+	{Type: "headers", V2: "sslTemporaryRedirect"},
+	{Type: "headers", V2: "sslHost"},
+	{Type: "headers", V2: "sslForceHost"},
+	{Type: "headers", V2: "sslProxyHeaders"},
+	{Type: "stripPrefix", V2: "forceSlash"},
+	{Type: "contentType", V2: "autoDetect"},
+	{Type: "ipAllowList", V3: "rejectStatusCode"},
+	{Type: "forwardAuth", V3: "addAuthCookiesToResponse"},
+}
+
+// ParseTraefikMajor returns the major version of a Traefik version string such
+// as "v3.1.2" or "2.11", or 0 when it cannot be determined (e.g. "dev")
+func ParseTraefikMajor(version string) int {
+	v := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+	if idx := strings.IndexAny(v, ".-+"); idx >= 0 {
+		v = v[:idx]
+	}
+	major, err := strconv.Atoi(v)
+	if err != nil || major <= 0 {
+		return 0
+	}
+	return major
+}
+
+// SetTraefikVersion pins the Traefik version generated config targets. An empty
+// version clears the pin and falls back to detection.
+func (cp *ConfigProxy) SetTraefikVersion(version string) {
+	cp.traefikVersion.mu.Lock()
+	defer cp.traefikVersion.mu.Unlock()
+
+	cp.traefikVersion.override = strings.TrimSpace(version)
+	if cp.traefikVersion.override != "" {
+		cp.traefikVersion.status.Version = cp.traefikVersion.override
+		cp.traefikVersion.status.Major = ParseTraefikMajor(cp.traefikVersion.override)
+		cp.traefikVersion.status.Source = "override"
+	} else if cp.traefikVersion.status.Source == "override" {
+		cp.traefikVersion.status = TraefikCompatStatus{}
+	}
+}
+
+// TraefikCompatibility returns the target Traefik version and the warnings from
+// the last merge
+func (cp *ConfigProxy) TraefikCompatibility() TraefikCompatStatus {
+	cp.traefikVersion.mu.RLock()
+	defer cp.traefikVersion.mu.RUnlock()
+
+	status := cp.traefikVersion.status
+	if status.Source == "" {
+		status.Source = "unknown"
+	}
+	status.Warnings = append([]string{}, cp.traefikVersion.status.Warnings...)
+	return status
+}
+
+// StartVersionDetection polls the Traefik API for its version until
+// StopVersionDetection is called. A pinned version skips the polling.
+func (cp *ConfigProxy) StartVersionDetection(interval time.Duration) {
+	cp.traefikVersion.mu.Lock()
+	if cp.traefikVersion.stopChan == nil {
+		cp.traefikVersion.stopChan = make(chan struct{})
+	}
+	stopChan := cp.traefikVersion.stopChan
+	cp.traefikVersion.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cp.refreshTraefikVersion()
+		select {
+		case <-ticker.C:
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// StopVersionDetection stops the background version polling
+func (cp *ConfigProxy) StopVersionDetection() {
+	cp.traefikVersion.mu.Lock()
+	if cp.traefikVersion.stopChan == nil {
+		cp.traefikVersion.stopChan = make(chan struct{})
+	}
+	stopChan := cp.traefikVersion.stopChan
+	cp.traefikVersion.mu.Unlock()
+
+	cp.traefikVersion.stopOnce.Do(func() { close(stopChan) })
+}
+
+// refreshTraefikVersion asks the configured Traefik API for its version. On
+// failure the previously detected version is kept.
+func (cp *ConfigProxy) refreshTraefikVersion() {
+	cp.traefikVersion.mu.RLock()
+	pinned := cp.traefikVersion.override != ""
+	cp.traefikVersion.mu.RUnlock()
+	if pinned || cp.configManager == nil {
+		return
+	}
+
+	traefikConfig, ok := cp.configManager.GetDataSources()["traefik"]
+	if !ok || traefikConfig.URL == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	version, err := NewTraefikFetcher(traefikConfig).FetchVersion(ctx)
+
+	cp.traefikVersion.mu.Lock()
+	defer cp.traefikVersion.mu.Unlock()
+	if cp.traefikVersion.override != "" {
+		return
+	}
+	if err != nil {
+		cp.traefikVersion.status.LastError = err.Error()
+		if shouldLog() {
+			log.Printf("Could not detect Traefik version: %v", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	if version.Version != cp.traefikVersion.status.Version {
+		log.Printf("Detected Traefik version %s", version.Version)
+	}
+	cp.traefikVersion.status.Version = version.Version
+	cp.traefikVersion.status.Major = ParseTraefikMajor(version.Version)
+	cp.traefikVersion.status.Source = "detected"
+	cp.traefikVersion.status.DetectedAt = &now
+	cp.traefikVersion.status.LastError = ""
+}
+
+// applyTraefikCompat adapts middleware names and options to the target Traefik
+// version and records a warning for every change it had to make. With an
+// unknown version the config is left as is.
+func (cp *ConfigProxy) applyTraefikCompat(config *ProxiedTraefikConfig) {
+	cp.traefikVersion.mu.RLock()
+	major := cp.traefikVersion.status.Major
+	previous := cp.traefikVersion.status.Warnings
+	cp.traefikVersion.mu.RUnlock()
+
+	var warnings []string
+	if major > 0 && config.HTTP != nil {
+		warnings = adaptMiddlewaresForTraefik(config.HTTP.Middlewares, major)
+	}
+
+	if len(warnings) > 0 && strings.Join(warnings, "\n") != strings.Join(previous, "\n") {
+		log.Printf("Warning: adapted %d middleware option(s) for Traefik v%d: %s",
+			len(warnings), major, strings.Join(warnings, "; "))
+	}
+
+	cp.traefikVersion.mu.Lock()
+	cp.traefikVersion.status.Warnings = warnings
+	cp.traefikVersion.mu.Unlock()
+}
+
+// adaptMiddlewaresForTraefik rewrites middleware types and options in place for
+// the given Traefik major version using the compatibility matrix above
+func adaptMiddlewaresForTraefik(middlewares map[string]interface{}, major int) []string {
+	names := make([]string, 0, len(middlewares))
+	for name := range middlewares {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var warnings []string
+	for _, name := range names {
+		if mw, ok := middlewares[name].(map[string]interface{}); ok {
+			warnings = append(warnings, adaptMiddlewareForTraefik(name, mw, major)...)
+		}
+	}
+	return warnings
+}
+
+// adaptMiddlewareForTraefik rewrites a single {type: options} middleware in place
+func adaptMiddlewareForTraefik(name string, mw map[string]interface{}, major int) []string {
+	if len(mw) != 1 {
+		return nil
+	}
+	var typ string
+	for t := range mw {
+		typ = t
+	}
+	body := mw[typ]
+
+	var warnings []string
+	target := traefikMiddlewareType(typ, major)
+	if target != typ {
+		delete(mw, typ)
+		mw[target] = body
+		warnings = append(warnings, fmt.Sprintf("middleware %q: %s renamed to %s for Traefik v%d", name, typ, target, major))
+	}
+
+	options, ok := body.(map[string]interface{})
+	if !ok {
+		return warnings
+	}
+	_, changes := planMiddlewareCompat(typ, options, major)
+	applyMiddlewareCompat(options, changes)
+	for _, change := range changes {
+		if change.To == "" {
+			warnings = append(warnings, fmt.Sprintf("middleware %q: %s.%s is not supported by Traefik v%d and was dropped", name, target, change.From, major))
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("middleware %q: %s.%s renamed to %s for Traefik v%d", name, target, change.From, change.To, major))
+	}
+	return warnings
+}
+
+// middlewareCompatChange is an option a middleware needs changed for a
+// Traefik version; an empty To drops the option
+type middlewareCompatChange struct {
+	From string
+	To   string
+}
+
+// planMiddlewareCompat returns the type a middleware has in the given Traefik
+// major version and the option changes it needs, without modifying options
+func planMiddlewareCompat(typ string, options map[string]interface{}, major int) (string, []middlewareCompatChange) {
+	target := traefikMiddlewareType(typ, major)
+	var changes []middlewareCompatChange
+	for _, change := range middlewareOptionChanges {
+		if traefikMiddlewareType(change.Type, major) != target {
+			continue
+		}
+		// v3 renames and removals apply going to v3; going to v2 only
+		// options v3 introduced need dropping
+		var from, to string
+		if major >= 3 {
+			from, to = change.V2, change.V3
+		} else if change.V2 == "" {
+			from = change.V3
+		}
+		if _, exists := options[from]; from == "" || !exists {
+			continue
+		}
+		if _, taken := options[to]; taken {
+			to = ""
+		}
+		changes = append(changes, middlewareCompatChange{From: from, To: to})
+	}
+	return target, changes
+}
+
+// applyMiddlewareCompat applies planned option changes to options in place
+func applyMiddlewareCompat(options map[string]interface{}, changes []middlewareCompatChange) {
+	for _, change := range changes {
+		value := options[change.From]
+		delete(options, change.From)
+		if change.To != "" {
+			options[change.To] = value
+		}
+	}
+}
+
+// traefikMiddlewareType returns the name Traefik of the given major version uses
+// for a middleware type
+func traefikMiddlewareType(typ string, major int) string {
+	for v2, v3 := range middlewareTypeRenames {
+		if major >= 3 && typ == v2 {
+			return v3
+		}
+		if major < 3 && typ == v3 {
+			return v2
+		}
+	}
+	return typ
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestParseTraefikMajor(t *testing.T) {
+	tests := map[string]int{
+		"v3.1.2":      3,
+		"3.0.0-rc1":   3,
+		"2.11":        2,
+		" V2.10.7 ":   2,
+		"dev":         0,
+		"":            0,
+		"v0.1.0":      0,
+		"4+metadata":  4,
+		"not-a-thing": 0,
+	}
+	for version, want := range tests {
+		if got := ParseTraefikMajor(version); got != want {
+			t.Errorf("ParseTraefikMajor(%q) = %d, want %d", version, got, want)
+		}
+	}
+}
+
+func TestAdaptMiddlewaresForTraefik(t *testing.T) {
+	newMiddlewares := func() map[string]interface{} {
+		return map[string]interface{}{
+			"allow": map[string]interface{}{
+				"ipWhiteList": map[string]interface{}{"sourceRange": []interface{}{"10.0.0.0/8"}},
+			},
+			"allow-v3": map[string]interface{}{
+				"ipAllowList": map[string]interface{}{"sourceRange": []interface{}{"10.0.0.0/8"}, "rejectStatusCode": 404},
+			},
+			"secure": map[string]interface{}{
+				"headers": map[string]interface{}{"featurePolicy": "camera 'none'", "sslRedirect": true, "stsSeconds": 31536000},
+			},
+		}
+	}
+
+	v3 := newMiddlewares()
+	warnings := adaptMiddlewaresForTraefik(v3, 3)
+	if _, ok := v3["allow"].(map[string]interface{})["ipAllowList"]; !ok {
+		t.Errorf("ipWhiteList was not renamed for v3: %v", v3["allow"])
+	}
+	wantHeaders := map[string]interface{}{"permissionsPolicy": "camera 'none'", "stsSeconds": 31536000}
+	if got := v3["secure"].(map[string]interface{})["headers"]; !reflect.DeepEqual(got, wantHeaders) {
+		t.Errorf("headers for v3 = %v, want %v", got, wantHeaders)
+	}
+	if len(warnings) != 3 {
+		t.Errorf("expected 3 warnings for v3, got %v", warnings)
+	}
+
+	v2 := newMiddlewares()
+	warnings = adaptMiddlewaresForTraefik(v2, 2)
+	allow := v2["allow-v3"].(map[string]interface{})
+	body, ok := allow["ipWhiteList"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("ipAllowList was not renamed for v2: %v", allow)
+	}
+	if _, exists := body["rejectStatusCode"]; exists {
+		t.Errorf("rejectStatusCode should be dropped for v2: %v", body)
+	}
+	// v2 understands both featurePolicy and permissionsPolicy, so headers are untouched
+	if got := v2["secure"].(map[string]interface{})["headers"].(map[string]interface{}); got["featurePolicy"] == nil || got["sslRedirect"] == nil {
+		t.Errorf("headers for v2 should be unchanged: %v", got)
+	}
+	want := []string{
+		`middleware "allow-v3": ipAllowList renamed to ipWhiteList for Traefik v2`,
+		`middleware "allow-v3": ipWhiteList.rejectStatusCode is not supported by Traefik v2 and was dropped`,
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("v2 warnings = %v, want %v", warnings, want)
+	}
+}
+
+func TestConfigProxyAdaptsMiddlewaresToDetectedTraefik(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	traefik := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/version" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(models.TraefikVersion{Version: "2.11.3", Codename: "mimolette"})
+	}))
+	defer traefik.Close()
+	if err := cm.UpdateDataSource("traefik", models.DataSourceConfig{Type: models.TraefikAPI, URL: traefik.URL}); err != nil {
+		t.Fatalf("UpdateDataSource() error = %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO middlewares (id, name, type, config) VALUES ('mw-1', 'office-only', 'ipAllowList', '{"sourceRange":["192.168.0.0/16"]}')`); err != nil {
+		t.Fatalf("insert middleware: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'app-service', 'org', 'site', 'active')`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resource_middlewares (resource_id, middleware_id, priority) VALUES ('res-1', 'mw-1', 100)`); err != nil {
+		t.Fatalf("insert assignment: %v", err)
+	}
+
+	pangolin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"app-router": map[string]interface{}{"rule": "Host(`app.example.com`)", "service": "app-service"},
+				},
+				"services": map[string]interface{}{
+					"app-service": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+				},
+			},
+		})
+	}))
+	defer pangolin.Close()
+
+	cp := NewConfigProxy(db, cm, pangolin.URL)
+	cp.httpClient = pangolin.Client()
+	cp.refreshTraefikVersion()
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	mw, ok := config.HTTP.Middlewares["office-only"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("middleware missing: %v", config.HTTP.Middlewares)
+	}
+	if _, ok := mw["ipWhiteList"]; !ok {
+		t.Fatalf("expected ipWhiteList for Traefik v2, got %v", mw)
+	}
+
+	status := cp.TraefikCompatibility()
+	if status.Source != "detected" || status.Major != 2 || len(status.Warnings) != 1 {
+		t.Fatalf("unexpected compatibility status: %+v", status)
+	}
+
+	// A pinned version wins over detection
+	cp.SetTraefikVersion("v3.2.0")
+	cp.refreshTraefikVersion()
+	cp.InvalidateCache()
+	config, err = cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if _, ok := config.HTTP.Middlewares["office-only"].(map[string]interface{})["ipAllowList"]; !ok {
+		t.Fatalf("expected ipAllowList for pinned Traefik v3, got %v", config.HTTP.Middlewares["office-only"])
+	}
+	if status := cp.TraefikCompatibility(); status.Source != "override" || len(status.Warnings) != 0 {
+		t.Fatalf("unexpected compatibility status after pinning: %+v", status)
+	}
+}
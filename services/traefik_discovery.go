@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// traefikDiscoveryTimeout bounds all hostname lookups of one discovery pass
+const traefikDiscoveryTimeout = 2 * time.Second
+
+// traefikDiscoveryPort is Traefik's default API entrypoint port
+const traefikDiscoveryPort = "8080"
+
+var (
+	// defaultTraefikFallbackURLs are tried when the configured URL fails and
+	// the data source does not list its own fallbacks
+	defaultTraefikFallbackURLs = []string{
+		"http://traefik:8080",
+		"http://localhost:8080",
+		"http://127.0.0.1:8080",
+		"http://host.docker.internal:8080",
+	}
+
+	// defaultTraefikDiscoveryHosts are the service and container names
+	// Traefik usually gets from Compose and Swarm. In Pangolin's stack
+	// Traefik shares gerbil's network namespace, so its API answers there.
+	defaultTraefikDiscoveryHosts = []string{
+		"traefik",
+		"traefik_traefik",
+		"traefik-traefik-1",
+		"traefik_traefik_1",
+		"gerbil",
+	}
+
+	// lookupHost is swapped out in tests
+	lookupHost = net.DefaultResolver.LookupHost
+)
+
+// TraefikCandidateURLs returns the URLs to try for a Traefik data source in
+// order: the configured URL, its fallbacks, then discovered Docker services.
+// Only discovery hosts that resolve on this network are included.
+func TraefikCandidateURLs(ctx context.Context, config models.DataSourceConfig) []string {
+	fallbacks := config.FallbackURLs
+	if len(fallbacks) == 0 {
+		fallbacks = defaultTraefikFallbackURLs
+	}
+	hosts := config.DiscoveryHosts
+	if len(hosts) == 0 {
+		hosts = defaultTraefikDiscoveryHosts
+	}
+	return setupCandidates([]string{config.URL}, fallbacks, discoverTraefikHosts(ctx, hosts))
+}
+
+// discoverTraefikHosts resolves hosts concurrently and returns API URLs for
+// the ones that exist, keeping the order of hosts
+func discoverTraefikHosts(ctx context.Context, hosts []string) []string {
+	ctx, cancel := context.WithTimeout(ctx, traefikDiscoveryTimeout)
+	defer cancel()
+
+	resolved := make([]bool, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			addrs, err := lookupHost(ctx, host)
+			resolved[i] = err == nil && len(addrs) > 0
+		}(i, host)
+	}
+	wg.Wait()
+
+	var urls []string
+	for i, host := range hosts {
+		if resolved[i] {
+			urls = append(urls, (&url.URL{Scheme: "http", Host: net.JoinHostPort(host, traefikDiscoveryPort)}).String())
+		}
+	}
+	return urls
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// stubLookupHost makes only the given hosts resolve
+func stubLookupHost(t *testing.T, resolvable ...string) {
+	t.Helper()
+	orig := lookupHost
+	t.Cleanup(func() { lookupHost = orig })
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		for _, h := range resolvable {
+			if h == host {
+				return []string{"172.18.0.2"}, nil
+			}
+		}
+		return nil, errors.New("no such host")
+	}
+}
+
+func newMinimalTraefikServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/overview", "/api/version":
+			w.Write([]byte("{}"))
+		default:
+			w.Write([]byte("[]"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTraefikCandidateURLs(t *testing.T) {
+	stubLookupHost(t, "traefik_traefik", "gerbil")
+
+	got := TraefikCandidateURLs(context.Background(), models.DataSourceConfig{
+		URL:          "http://traefik:8080/",
+		FallbackURLs: []string{"http://traefik:8080", "http://10.0.0.5:8080"},
+	})
+	want := []string{
+		"http://traefik:8080",
+		"http://10.0.0.5:8080",
+		"http://traefik_traefik:8080",
+		"http://gerbil:8080",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TraefikCandidateURLs() = %v, want %v", got, want)
+	}
+
+	got = TraefikCandidateURLs(context.Background(), models.DataSourceConfig{
+		DiscoveryHosts: []string{"edge-proxy", "gerbil"},
+		FallbackURLs:   []string{"http://10.0.0.5:8080"},
+	})
+	want = []string{"http://10.0.0.5:8080", "http://gerbil:8080"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TraefikCandidateURLs() with discovery hosts = %v, want %v", got, want)
+	}
+}
+
+func TestTraefikFetcher_FallbackPersistsURL(t *testing.T) {
+	stubLookupHost(t)
+	server := newMinimalTraefikServer(t)
+
+	tests := []struct {
+		name        string
+		disable     bool
+		wantPersist bool
+	}{
+		{"auto persist", false, true},
+		{"auto persist disabled", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := NewTraefikFetcher(models.DataSourceConfig{
+				Type:               models.TraefikAPI,
+				URL:                "http://127.0.0.1:1",
+				FallbackURLs:       []string{server.URL},
+				DisableAutoPersist: tt.disable,
+			})
+			var persisted [][2]string
+			fetcher.SetURLPersister(func(previous, working string) error {
+				persisted = append(persisted, [2]string{previous, working})
+				return nil
+			})
+
+			if _, err := fetcher.FetchFullData(context.Background()); err != nil {
+				t.Fatalf("FetchFullData() error = %v", err)
+			}
+			if got := fetcher.baseURL(); got != server.URL {
+				t.Errorf("baseURL() = %q, want %q", got, server.URL)
+			}
+			if tt.wantPersist {
+				want := [][2]string{{"http://127.0.0.1:1", server.URL}}
+				if !reflect.DeepEqual(persisted, want) {
+					t.Errorf("persisted = %v, want %v", persisted, want)
+				}
+			} else if len(persisted) != 0 {
+				t.Errorf("persisted = %v, want none", persisted)
+			}
+		})
+	}
+}
+
+func TestConfigManager_PersistTraefikURL(t *testing.T) {
+	cm := newTestConfigManager(t)
+	if err := cm.UpdateDataSource("traefik", models.DataSourceConfig{Type: models.TraefikAPI, URL: "http://127.0.0.1:1"}); err != nil {
+		t.Fatalf("UpdateDataSource() error = %v", err)
+	}
+	if err := cm.UpdateDataSource("pinned", models.DataSourceConfig{Type: models.TraefikAPI, URL: "http://127.0.0.1:1", DisableAutoPersist: true}); err != nil {
+		t.Fatalf("UpdateDataSource() error = %v", err)
+	}
+
+	if err := cm.PersistTraefikURL("http://127.0.0.1:1", "http://traefik_traefik:8080/"); err != nil {
+		t.Fatalf("PersistTraefikURL() error = %v", err)
+	}
+
+	reloaded, err := NewConfigManager(cm.configPath)
+	if err != nil {
+		t.Fatalf("NewConfigManager() error = %v", err)
+	}
+	sources := reloaded.GetDataSources()
+	if got := sources["traefik"].URL; got != "http://traefik_traefik:8080" {
+		t.Errorf("traefik URL = %q, want the working URL", got)
+	}
+	if got := sources["pinned"].URL; got != "http://127.0.0.1:1" {
+		t.Errorf("pinned URL = %q, want it unchanged", got)
+	}
+}
@@ -31,6 +31,11 @@ type TraefikFetcher struct {
 	lastFetchMu  sync.RWMutex
 	minInterval  time.Duration
 
+	// url starts as config.URL and moves to the fallback that last answered
+	url        string
+	urlMu      sync.RWMutex
+	persistURL func(previous, working string) error
+
 	// Cached data from last fetch
 	cachedData   *models.FullTraefikData
 	cachedDataMu sync.RWMutex
@@ -50,6 +55,7 @@ func NewTraefikFetcher(config models.DataSourceConfig) *TraefikFetcher {
 
 	return &TraefikFetcher{
 		config:      config,
+		url:         config.URL,
 		httpClient:  httpClient,
 		minInterval: 5 * time.Second, // Rate limit: minimum 5 seconds between fetches
 	}
@@ -58,17 +64,28 @@ func NewTraefikFetcher(config models.DataSourceConfig) *TraefikFetcher {
 // createTraefikHTTPClient creates an HTTP client with proper TLS settings
 // Following Mantrae's pattern: 5-second timeout, connection pooling (100 max idle, 10 per-host)
 func createTraefikHTTPClient(config models.DataSourceConfig) *http.Client {
+	tlsConfig, err := dataSourceTLSConfig(config)
+	if err != nil {
+		// Requests still go out and fail the TLS handshake, which the
+		// connection test reports together with this error
+		log.Printf("Warning: invalid TLS settings for Traefik API: %v", err)
+		tlsConfig = &tls.Config{InsecureSkipVerify: config.SkipTLSVerify}
+	}
+	proxy, err := dataSourceProxy(config)
+	if err != nil {
+		log.Printf("Warning: invalid proxy for Traefik API, using the outbound proxy: %v", err)
+		proxy = OutboundProxy
+	}
 	transport := &http.Transport{
+		Proxy:               proxy,
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 10,
 		IdleConnTimeout:     10 * time.Second,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: config.SkipTLSVerify,
-		},
+		TLSClientConfig:     tlsConfig,
 	}
 
 	return &http.Client{
-		Transport: transport,
+		Transport: withUserAgent(transport),
 		Timeout:   5 * time.Second, // Mantrae uses 5 seconds
 	}
 }
@@ -117,49 +134,50 @@ func (f *TraefikFetcher) fetchResourcesInternal(ctx context.Context) (*models.Re
 
 	log.Println("Fetching resources from Traefik API...")
 
-	// Try the configured URL first
-	resources, err := f.fetchResourcesFromURL(ctx, f.config.URL)
-	if err == nil {
-		f.updateLastFetch()
-		log.Printf("Successfully fetched resources from %s", f.config.URL)
-		return resources, nil
+	var resources *models.ResourceCollection
+	err := f.fetchWithFallback(ctx, func(baseURL string) error {
+		var err error
+		resources, err = f.fetchResourcesFromURL(ctx, baseURL)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	f.updateLastFetch()
+	return resources, nil
+}
 
-	// Log the initial error
-	log.Printf("Failed to connect to primary Traefik API URL %s: %v", f.config.URL, err)
-
-	// Try common fallback URLs
-	fallbackURLs := []string{
-		"http://traefik:8080",
-		"http://localhost:8080",
-		"http://127.0.0.1:8080",
-		"http://host.docker.internal:8080",
+// fetchWithFallback calls fetch with the current URL and, when that fails,
+// with each candidate URL in turn. The first candidate that answers becomes
+// the current URL.
+func (f *TraefikFetcher) fetchWithFallback(ctx context.Context, fetch func(baseURL string) error) error {
+	primary := f.baseURL()
+	err := fetch(primary)
+	if err == nil {
+		log.Printf("Successfully fetched from %s", primary)
+		return nil
 	}
+	log.Printf("Failed to connect to primary Traefik API URL %s: %v", primary, err)
 
-	// Don't try the same URL twice
-	if f.config.URL != "" {
-		for i := len(fallbackURLs) - 1; i >= 0; i-- {
-			if fallbackURLs[i] == f.config.URL {
-				fallbackURLs = append(fallbackURLs[:i], fallbackURLs[i+1:]...)
-			}
+	lastErr := err
+	for _, url := range TraefikCandidateURLs(ctx, f.config) {
+		if url == primary {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
 		}
-	}
-
-	// Try each fallback URL
-	var lastErr error
-	for _, url := range fallbackURLs {
 		log.Printf("Trying fallback Traefik API URL: %s", url)
-		resources, err := f.fetchResourcesFromURL(ctx, url)
-		if err == nil {
-			f.updateLastFetch()
-			f.suggestURLUpdate(url)
-			return resources, nil
+		if err := fetch(url); err != nil {
+			lastErr = err
+			log.Printf("Fallback URL %s failed: %v", url, err)
+			continue
 		}
-		lastErr = err
-		log.Printf("Fallback URL %s failed: %v", url, err)
+		f.adoptURL(url)
+		return nil
 	}
 
-	return nil, fmt.Errorf("all Traefik API connection attempts failed, last error: %w", lastErr)
+	return fmt.Errorf("all Traefik API connection attempts failed, last error: %w", lastErr)
 }
 
 // fetchFullDataInternal fetches all Traefik data with caching
@@ -181,7 +199,12 @@ func (f *TraefikFetcher) fetchFullDataInternal(ctx context.Context) (*models.Ful
 	log.Println("Fetching full data from Traefik API...")
 
 	// Fetch all endpoints concurrently
-	data, err := f.fetchAllEndpointsConcurrently(ctx, f.config.URL)
+	var data *models.FullTraefikData
+	err := f.fetchWithFallback(ctx, func(baseURL string) error {
+		var err error
+		data, err = f.fetchAllEndpointsConcurrently(ctx, baseURL)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -464,12 +487,10 @@ func (f *TraefikFetcher) fetch(ctx context.Context, url string) ([]byte, error)
 
 	req.Header.Set("Content-Type", "application/json")
 
-	// Add basic auth if configured
-	if f.config.BasicAuth.Username != "" {
-		req.SetBasicAuth(f.config.BasicAuth.Username, f.config.BasicAuth.Password)
-	}
+	// Add basic auth and custom headers if configured
+	AuthorizeDataSourceRequest(req, f.config)
 
-	resp, err := f.httpClient.Do(req)
+	resp, err := doWithRetry(f.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -645,6 +666,20 @@ func (f *TraefikFetcher) GetVersion(ctx context.Context) (*models.TraefikVersion
 	return data.Version, nil
 }
 
+// FetchVersion queries only /api/version, for callers that need the version
+// without the cost of a full data fetch
+func (f *TraefikFetcher) FetchVersion(ctx context.Context) (*models.TraefikVersion, error) {
+	body, err := f.fetch(ctx, strings.TrimSuffix(f.baseURL(), "/")+"/api/version")
+	if err != nil {
+		return nil, err
+	}
+	var version models.TraefikVersion
+	if err := json.Unmarshal(body, &version); err != nil {
+		return nil, fmt.Errorf("failed to decode version: %w", err)
+	}
+	return &version, nil
+}
+
 // GetEntrypoints returns the Traefik entrypoints
 func (f *TraefikFetcher) GetEntrypoints(ctx context.Context) ([]models.TraefikEntrypoint, error) {
 	data, err := f.FetchFullData(ctx)
@@ -654,9 +689,35 @@ func (f *TraefikFetcher) GetEntrypoints(ctx context.Context) ([]models.TraefikEn
 	return data.Entrypoints, nil
 }
 
-// suggestURLUpdate logs a message suggesting the URL should be updated
-func (f *TraefikFetcher) suggestURLUpdate(workingURL string) {
-	log.Printf("IMPORTANT: Consider updating the Traefik API URL to %s in the settings", workingURL)
+// SetURLPersister sets the function used to save a fallback URL that
+// answered back to the data source config
+func (f *TraefikFetcher) SetURLPersister(persist func(previous, working string) error) {
+	f.persistURL = persist
+}
+
+// baseURL returns the URL requests currently go to
+func (f *TraefikFetcher) baseURL() string {
+	f.urlMu.RLock()
+	defer f.urlMu.RUnlock()
+	return f.url
+}
+
+// adoptURL switches to a fallback URL that answered and, unless the data
+// source disables it, saves it in place of the configured URL
+func (f *TraefikFetcher) adoptURL(workingURL string) {
+	f.urlMu.Lock()
+	f.url = workingURL
+	f.urlMu.Unlock()
+
+	if f.config.DisableAutoPersist || f.persistURL == nil {
+		log.Printf("IMPORTANT: Consider updating the Traefik API URL to %s in the settings", workingURL)
+		return
+	}
+	if err := f.persistURL(f.config.URL, workingURL); err != nil {
+		log.Printf("Failed to save Traefik API URL %s: %v", workingURL, err)
+		return
+	}
+	log.Printf("Traefik API URL updated from %s to %s", f.config.URL, workingURL)
 }
 
 // shouldIncludeNonTLSRouters returns whether non-TLS routers should be included
@@ -61,7 +61,7 @@ func TestCreateTraefikHTTPClient(t *testing.T) {
 				t.Errorf("client.Timeout = %v, want %v", client.Timeout, 5*time.Second)
 			}
 
-			transport, ok := client.Transport.(*http.Transport)
+			transport, ok := baseTransport(client.Transport)
 			if !ok {
 				t.Fatal("client.Transport is not *http.Transport")
 			}
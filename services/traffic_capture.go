@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// maxCaptureRead caps how much of the access log one poll reads, so a large
+// backlog is worked through over several polls
+const maxCaptureRead = 4 << 20
+
+// TrafficCapturer follows Traefik's JSON access log and collects the lines of
+// requests marked by an active capture
+type TrafficCapturer struct {
+	db      *database.DB
+	logPath string
+	now     func() time.Time
+
+	mu     sync.Mutex
+	offset int64
+	primed bool
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTrafficCapturer creates a capturer reading the given access log; an empty
+// path disables capturing
+func NewTrafficCapturer(db *database.DB, accessLogPath string) *TrafficCapturer {
+	return &TrafficCapturer{
+		db:       db,
+		logPath:  accessLogPath,
+		now:      time.Now,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Enabled reports whether an access log path is configured
+func (tc *TrafficCapturer) Enabled() bool {
+	return tc.logPath != ""
+}
+
+// Start polls the access log until Stop is called
+func (tc *TrafficCapturer) Start(interval time.Duration) {
+	if !tc.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := tc.Poll(); err != nil {
+				log.Printf("Error collecting captured traffic: %v", err)
+			}
+		case <-tc.stopChan:
+			return
+		}
+	}
+}
+
+// Stop stops the polling loop
+func (tc *TrafficCapturer) Stop() {
+	tc.stopOnce.Do(func() { close(tc.stopChan) })
+}
+
+// StartCapture begins capturing up to maxLines access-log lines for a resource
+func (tc *TrafficCapturer) StartCapture(resourceID string, maxLines int, ttl time.Duration) (*models.TrafficCapture, error) {
+	// Lines logged before the capture started cannot match, but skip them anyway
+	if err := tc.prime(); err != nil {
+		return nil, err
+	}
+
+	now := tc.now()
+	capture := &models.TrafficCapture{
+		ID:         uuid.New().String(),
+		ResourceID: resourceID,
+		Status:     models.CaptureActive,
+		MaxLines:   maxLines,
+		Lines:      []json.RawMessage{},
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if _, err := tc.db.Exec(`
+		INSERT INTO traffic_captures (id, resource_id, status, max_lines, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, capture.ID, resourceID, capture.Status, maxLines, capture.CreatedAt, capture.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to store capture: %w", err)
+	}
+
+	log.Printf("Started traffic capture %s for resource %s (%d lines, expires %s)",
+		capture.ID, resourceID, maxLines, capture.ExpiresAt.Format(time.RFC3339))
+	return capture, nil
+}
+
+// GetCapture returns a capture and the lines collected so far
+func (tc *TrafficCapturer) GetCapture(id string) (*models.TrafficCapture, error) {
+	return scanCapture(tc.db.QueryRow(`
+		SELECT id, resource_id, status, max_lines, lines, created_at, expires_at, completed_at
+		FROM traffic_captures WHERE id = ?
+	`, id))
+}
+
+// ListCaptures returns the most recent captures without their lines
+func (tc *TrafficCapturer) ListCaptures(limit int) ([]models.TrafficCapture, error) {
+	rows, err := tc.db.Query(`
+		SELECT id, resource_id, status, max_lines, '[]', created_at, expires_at, completed_at
+		FROM traffic_captures ORDER BY created_at DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	captures := []models.TrafficCapture{}
+	for rows.Next() {
+		capture, err := scanCapture(rows)
+		if err != nil {
+			return nil, err
+		}
+		captures = append(captures, *capture)
+	}
+	return captures, rows.Err()
+}
+
+// StopCapture ends an active capture early, keeping the lines collected so far
+func (tc *TrafficCapturer) StopCapture(id string) error {
+	result, err := tc.db.Exec(
+		"UPDATE traffic_captures SET status = ?, completed_at = ? WHERE id = ? AND status = ?",
+		models.CaptureStopped, tc.now(), id, models.CaptureActive,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Poll expires old captures and collects new access-log lines for active ones
+func (tc *TrafficCapturer) Poll() error {
+	now := tc.now()
+	if _, err := tc.db.Exec(
+		"UPDATE traffic_captures SET status = ?, completed_at = ? WHERE status = ? AND expires_at <= ?",
+		models.CaptureExpired, now, models.CaptureActive, now,
+	); err != nil {
+		return fmt.Errorf("failed to expire captures: %w", err)
+	}
+
+	active, err := tc.activeCaptures()
+	if err != nil {
+		return err
+	}
+	if len(active) == 0 {
+		// Nothing to collect; keep following the end of the log
+		tc.mu.Lock()
+		tc.primed = false
+		tc.mu.Unlock()
+		return nil
+	}
+
+	lines, err := tc.readNewLines()
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string]bool)
+	for _, line := range lines {
+		id := captureMarker(line)
+		capture, ok := active[id]
+		if !ok || len(capture.Lines) >= capture.MaxLines {
+			continue
+		}
+		capture.Lines = append(capture.Lines, line)
+		changed[id] = true
+	}
+
+	for id := range changed {
+		capture := active[id]
+		linesJSON, err := json.Marshal(capture.Lines)
+		if err != nil {
+			return err
+		}
+		if len(capture.Lines) >= capture.MaxLines {
+			_, err = tc.db.Exec("UPDATE traffic_captures SET lines = ?, status = ?, completed_at = ? WHERE id = ?",
+				string(linesJSON), models.CaptureComplete, now, id)
+			log.Printf("Traffic capture %s complete (%d lines)", id, len(capture.Lines))
+		} else {
+			_, err = tc.db.Exec("UPDATE traffic_captures SET lines = ? WHERE id = ?", string(linesJSON), id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store captured lines: %w", err)
+		}
+	}
+	return nil
+}
+
+// activeCaptures returns the active captures keyed by ID
+func (tc *TrafficCapturer) activeCaptures() (map[string]*models.TrafficCapture, error) {
+	rows, err := tc.db.Query(`
+		SELECT id, resource_id, status, max_lines, lines, created_at, expires_at, completed_at
+		FROM traffic_captures WHERE status = ?
+	`, models.CaptureActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	active := make(map[string]*models.TrafficCapture)
+	for rows.Next() {
+		capture, err := scanCapture(rows)
+		if err != nil {
+			return nil, err
+		}
+		active[capture.ID] = capture
+	}
+	return active, rows.Err()
+}
+
+// prime moves the read offset to the end of the log unless a capture is already following it
+func (tc *TrafficCapturer) prime() error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.primed {
+		return nil
+	}
+	info, err := os.Stat(filepath.Clean(tc.logPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read access log: %w", err)
+	}
+	tc.offset = 0
+	if info != nil {
+		tc.offset = info.Size()
+	}
+	tc.primed = true
+	return nil
+}
+
+// readNewLines returns the complete lines appended since the last read. A log
+// that shrank was rotated or truncated and is read from the start.
+func (tc *TrafficCapturer) readNewLines() ([]json.RawMessage, error) {
+	if err := tc.prime(); err != nil {
+		return nil, err
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	f, err := os.Open(filepath.Clean(tc.logPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < tc.offset {
+		tc.offset = 0
+	}
+	if _, err := f.Seek(tc.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxCaptureRead))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+
+	// Leave a partially written last line for the next poll
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		if len(data) == maxCaptureRead {
+			tc.offset += int64(len(data))
+		}
+		return nil, nil
+	}
+	tc.offset += int64(end + 1)
+
+	var lines []json.RawMessage
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		lines = append(lines, json.RawMessage(append([]byte{}, line...)))
+	}
+	return lines, nil
+}
+
+// captureMarker returns the capture ID a JSON access-log line was marked with.
+// Traefik logs kept request headers as request_<Canonical-Header-Name>.
+func captureMarker(line json.RawMessage) string {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return ""
+	}
+	for key, value := range entry {
+		if strings.EqualFold(key, "request_"+models.CaptureHeader) {
+			marker, _ := value.(string)
+			return marker
+		}
+	}
+	return ""
+}
+
+func scanCapture(row interface{ Scan(...interface{}) error }) (*models.TrafficCapture, error) {
+	var capture models.TrafficCapture
+	var lines string
+	var completedAt sql.NullTime
+	if err := row.Scan(&capture.ID, &capture.ResourceID, &capture.Status, &capture.MaxLines, &lines,
+		&capture.CreatedAt, &capture.ExpiresAt, &completedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(lines), &capture.Lines); err != nil || capture.Lines == nil {
+		capture.Lines = []json.RawMessage{}
+	}
+	if completedAt.Valid {
+		capture.CompletedAt = &completedAt.Time
+	}
+	return &capture, nil
+}
+
+// loadCaptures attaches each resource's active capture
+func (cp *ConfigProxy) loadCaptures(resourceMap map[string]*resourceData) error {
+	rows, err := cp.db.Query(
+		"SELECT id, resource_id FROM traffic_captures WHERE status = ? AND expires_at > ? ORDER BY created_at",
+		models.CaptureActive, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, resID string
+		if err := rows.Scan(&id, &resID); err != nil {
+			log.Printf("Failed to scan traffic capture: %v", err)
+			continue
+		}
+		if data, ok := resourceMap[resID]; ok {
+			data.CaptureID = id
+		}
+	}
+	return rows.Err()
+}
+
+// applyTrafficCapture marks requests of resources with an active capture so
+// their access-log lines can be collected. The marker middleware goes first
+// so requests rejected by later middlewares are captured too.
+func (cp *ConfigProxy) applyTrafficCapture(config *ProxiedTraefikConfig, resources []*resourceData) {
+	for _, resource := range resources {
+		if resource.CaptureID == "" {
+			continue
+		}
+
+		routerKey, router := cp.findRouterByPangolinID(config.HTTP.Routers, resource.PangolinRouterID)
+		if routerKey == "" {
+			routerKey, router = cp.findMatchingRouter(config.HTTP.Routers, resource.Host)
+		}
+		if routerKey == "" {
+			continue
+		}
+
+		middlewareName := fmt.Sprintf("%s-capture", resource.ID)
+		config.HTTP.Middlewares[middlewareName] = map[string]interface{}{
+			"headers": map[string]interface{}{
+				"customRequestHeaders": map[string]interface{}{
+					models.CaptureHeader: resource.CaptureID,
+				},
+			},
+		}
+		router.Middlewares = append([]string{middlewareName}, router.Middlewares...)
+	}
+}
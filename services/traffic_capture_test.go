@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func appendLog(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer f.Close()
+	for _, line := range lines {
+		if _, err := f.WriteString(line + "\n"); err != nil {
+			t.Fatalf("write log: %v", err)
+		}
+	}
+}
+
+func TestTrafficCapturer_CollectsMarkedLines(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "access.log")
+	appendLog(t, logPath, `{"RequestHost":"before.example.com","request_X-Mm-Capture":"stale"}`)
+
+	tc := NewTrafficCapturer(newTestDB(t), logPath)
+	capture, err := tc.StartCapture("res-1", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+
+	marked := func(n int) string {
+		return fmt.Sprintf(`{"RequestPath":"/%d","request_X-Mm-Capture":%q}`, n, capture.ID)
+	}
+	appendLog(t, logPath, marked(1), `{"RequestPath":"/other"}`, "not json")
+	if err := tc.Poll(); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	got, err := tc.GetCapture(capture.ID)
+	if err != nil {
+		t.Fatalf("GetCapture: %v", err)
+	}
+	if got.Status != models.CaptureActive || len(got.Lines) != 1 {
+		t.Fatalf("after first poll: status=%s lines=%d", got.Status, len(got.Lines))
+	}
+
+	appendLog(t, logPath, marked(2), marked(3))
+	if err := tc.Poll(); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	got, _ = tc.GetCapture(capture.ID)
+	if got.Status != models.CaptureComplete || len(got.Lines) != 2 || got.CompletedAt == nil {
+		t.Fatalf("expected complete capture with 2 lines, got status=%s lines=%d", got.Status, len(got.Lines))
+	}
+	if string(got.Lines[1]) != marked(2) {
+		t.Errorf("unexpected second line: %s", got.Lines[1])
+	}
+}
+
+func TestTrafficCapturer_ExpiresAndStops(t *testing.T) {
+	tc := NewTrafficCapturer(newTestDB(t), filepath.Join(t.TempDir(), "access.log"))
+	now := time.Now()
+	tc.now = func() time.Time { return now }
+
+	expiring, err := tc.StartCapture("res-1", 10, time.Minute)
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+	stopped, err := tc.StartCapture("res-2", 10, time.Hour)
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := tc.Poll(); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if got, _ := tc.GetCapture(expiring.ID); got.Status != models.CaptureExpired {
+		t.Errorf("expected expired capture, got %s", got.Status)
+	}
+
+	if err := tc.StopCapture(stopped.ID); err != nil {
+		t.Fatalf("StopCapture: %v", err)
+	}
+	if err := tc.StopCapture(stopped.ID); err == nil {
+		t.Errorf("stopping an inactive capture should fail")
+	}
+}
+
+func TestApplyTrafficCapture(t *testing.T) {
+	cp := NewConfigProxy(newTestDB(t), newTestConfigManager(t), "")
+
+	config := &ProxiedTraefikConfig{HTTP: &HTTPConfig{
+		Routers: map[string]*OrderedRouter{
+			"app-router": {Rule: "Host(`app.example.com`)", Service: "app-service", Middlewares: []string{"auth"}},
+		},
+		Middlewares: map[string]interface{}{},
+	}}
+	resources := []*resourceData{
+		{ID: "res-1", PangolinRouterID: "app-router", Host: "app.example.com", CaptureID: "cap-1"},
+	}
+
+	cp.applyTrafficCapture(config, resources)
+
+	router := config.HTTP.Routers["app-router"]
+	if len(router.Middlewares) != 2 || router.Middlewares[0] != "res-1-capture" {
+		t.Fatalf("capture middleware must be first, got %v", router.Middlewares)
+	}
+	headers := config.HTTP.Middlewares["res-1-capture"].(map[string]interface{})["headers"].(map[string]interface{})
+	if headers["customRequestHeaders"].(map[string]interface{})[models.CaptureHeader] != "cap-1" {
+		t.Errorf("unexpected capture middleware: %v", headers)
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTransformChanges bounds the transform log of one merge; a first merge
+// against a large upstream config can touch thousands of fields
+const maxTransformChanges = 2000
+
+// Transform actions
+const (
+	TransformAdded             = "added"
+	TransformRemoved           = "removed"
+	TransformModified          = "modified"
+	TransformMiddlewareAdded   = "middleware_added"
+	TransformMiddlewareRemoved = "middleware_removed"
+	TransformMiddlewaresMoved  = "middlewares_reordered"
+)
+
+// TransformChange is one mutation MM made to the upstream config: an item it
+// added or removed, or a field of an upstream item it changed
+type TransformChange struct {
+	// Section is e.g. http.routers or tls.options
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+	// Field is the dotted path of a modified field, e.g. priority or
+	// plugin.mtlswhitelist.requestHeaders
+	Field  string      `json:"field,omitempty"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+	Detail string      `json:"detail"`
+}
+
+// TransformLog lists what the latest merge changed in the upstream config.
+// Served is false when validation replaced the merged config with the last
+// known good one.
+type TransformLog struct {
+	MergedAt  time.Time         `json:"merged_at"`
+	Served    bool              `json:"served"`
+	Changes   []TransformChange `json:"changes"`
+	Truncated bool              `json:"truncated"`
+}
+
+// transformSections are the parts of the config compared item by item
+var transformSections = [][2]string{
+	{"http", "routers"},
+	{"http", "middlewares"},
+	{"http", "services"},
+	{"http", "serversTransports"},
+	{"tcp", "routers"},
+	{"tcp", "services"},
+	{"udp", "routers"},
+	{"udp", "services"},
+	{"tls", "options"},
+}
+
+// SetTransformLog turns recording the transform log of each merge on or off
+func (cp *ConfigProxy) SetTransformLog(enabled bool) {
+	cp.cacheMutex.Lock()
+	defer cp.cacheMutex.Unlock()
+	cp.transformLog = enabled
+	if !enabled {
+		cp.lastTransforms = nil
+	}
+}
+
+// TransformLog returns the transform log of the latest merge (nil before
+// the first one) and whether recording is on
+func (cp *ConfigProxy) TransformLog() (*TransformLog, bool) {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+	return cp.lastTransforms, cp.transformLog
+}
+
+// configTree encodes a config into plain maps and slices to compare it
+func configTree(config *ProxiedTraefikConfig) map[string]interface{} {
+	data, err := json.Marshal(config)
+	if err != nil {
+		log.Printf("Warning: failed to encode config for the transform log: %v", err)
+		return nil
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		log.Printf("Warning: failed to decode config for the transform log: %v", err)
+		return nil
+	}
+	return tree
+}
+
+// diffTransforms lists the changes between the upstream and the merged config
+func diffTransforms(upstream, merged map[string]interface{}) ([]TransformChange, bool) {
+	d := &transformDiff{changes: []TransformChange{}}
+	for _, section := range transformSections {
+		name := section[0] + "." + section[1]
+		d.section(name, treeMap(upstream, section[0], section[1]), treeMap(merged, section[0], section[1]))
+	}
+	return d.changes, d.truncated
+}
+
+type transformDiff struct {
+	changes   []TransformChange
+	truncated bool
+}
+
+func (d *transformDiff) add(change TransformChange) {
+	if len(d.changes) >= maxTransformChanges {
+		d.truncated = true
+		return
+	}
+	d.changes = append(d.changes, change)
+}
+
+func (d *transformDiff) section(section string, before, after map[string]interface{}) {
+	kind := strings.TrimSuffix(strings.SplitN(section, ".", 2)[1], "s")
+	for _, name := range sortedKeys(after) {
+		previous, ok := before[name]
+		if !ok {
+			d.add(TransformChange{Section: section, Name: name, Action: TransformAdded, After: after[name],
+				Detail: fmt.Sprintf("%s %s added", kind, name)})
+			continue
+		}
+		if reflect.DeepEqual(previous, after[name]) {
+			continue
+		}
+		prevItem, _ := previous.(map[string]interface{})
+		item, _ := after[name].(map[string]interface{})
+		if prevItem == nil || item == nil {
+			d.add(TransformChange{Section: section, Name: name, Action: TransformModified, Before: previous, After: after[name],
+				Detail: fmt.Sprintf("%s %s changed", kind, name)})
+			continue
+		}
+		if strings.HasSuffix(section, ".routers") {
+			d.routerMiddlewares(section, name, prevItem["middlewares"], item["middlewares"])
+			prevItem, item = withoutKey(prevItem, "middlewares"), withoutKey(item, "middlewares")
+		}
+		d.fields(section, name, kind, "", prevItem, item)
+	}
+	for _, name := range sortedKeys(before) {
+		if _, ok := after[name]; !ok {
+			d.add(TransformChange{Section: section, Name: name, Action: TransformRemoved, Before: before[name],
+				Detail: fmt.Sprintf("%s %s removed", kind, name)})
+		}
+	}
+}
+
+// routerMiddlewares reports middlewares attached to or detached from a
+// router, and a changed order of the ones it kept
+func (d *transformDiff) routerMiddlewares(section, router string, before, after interface{}) {
+	prev, next := stringList(before), stringList(after)
+	inPrev, inNext := toSet(prev), toSet(next)
+	for _, mw := range next {
+		if !inPrev[mw] {
+			d.add(TransformChange{Section: section, Name: router, Action: TransformMiddlewareAdded, Field: "middlewares", After: mw,
+				Detail: fmt.Sprintf("middleware %s added to router %s", mw, router)})
+		}
+	}
+	for _, mw := range prev {
+		if !inNext[mw] {
+			d.add(TransformChange{Section: section, Name: router, Action: TransformMiddlewareRemoved, Field: "middlewares", Before: mw,
+				Detail: fmt.Sprintf("middleware %s removed from router %s", mw, router)})
+		}
+	}
+	if kept(prev, inNext) != kept(next, inPrev) {
+		d.add(TransformChange{Section: section, Name: router, Action: TransformMiddlewaresMoved, Field: "middlewares", Before: prev, After: next,
+			Detail: fmt.Sprintf("middlewares of router %s reordered", router)})
+	}
+}
+
+// fields reports the changed leaves of an item; lists count as one leaf
+func (d *transformDiff) fields(section, name, kind, prefix string, before, after map[string]interface{}) {
+	keys := sortedKeys(after)
+	for _, key := range sortedKeys(before) {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		prev, next := before[key], after[key]
+		if reflect.DeepEqual(prev, next) {
+			continue
+		}
+		prevMap, prevIsMap := prev.(map[string]interface{})
+		nextMap, nextIsMap := next.(map[string]interface{})
+		if prevIsMap && nextIsMap {
+			d.fields(section, name, kind, path, prevMap, nextMap)
+			continue
+		}
+		detail := fmt.Sprintf("%s %s: %s changed", kind, name, path)
+		switch {
+		case prev == nil:
+			detail = fmt.Sprintf("%s %s: %s set", kind, name, path)
+		case next == nil:
+			detail = fmt.Sprintf("%s %s: %s removed", kind, name, path)
+		}
+		d.add(TransformChange{Section: section, Name: name, Action: TransformModified, Field: path, Before: prev, After: next, Detail: detail})
+	}
+}
+
+func treeMap(tree map[string]interface{}, keys ...string) map[string]interface{} {
+	current := tree
+	for _, key := range keys {
+		next, _ := current[key].(map[string]interface{})
+		if next == nil {
+			return map[string]interface{}{}
+		}
+		current = next
+	}
+	return current
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func withoutKey(m map[string]interface{}, key string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if k != key {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// kept joins the items that are also in other, to compare their order
+func kept(items []string, other map[string]bool) string {
+	var out []string
+	for _, item := range items {
+		if other[item] {
+			out = append(out, item)
+		}
+	}
+	return strings.Join(out, "\x00")
+}
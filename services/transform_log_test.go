@@ -0,0 +1,117 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigProxyRecordsTransformLog(t *testing.T) {
+	db := newTestDB(t)
+	cm := newTestConfigManager(t)
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, forward_auth_enabled)
+		VALUES ('res-1', 'tool-router', 'tool.example.com', 'tool-service', 'org', 'site', 'active', 1)`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"tool-router": map[string]interface{}{
+						"rule":        "Host(`tool.example.com`)",
+						"service":     "tool-service",
+						"middlewares": []string{"upstream-auth"},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cp := NewConfigProxy(db, cm, server.URL)
+	cp.httpClient = server.Client()
+	cp.SetForwardAuthURL("http://middleware-manager:3456")
+
+	if _, err := cp.GetMergedConfig(); err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if transforms, enabled := cp.TransformLog(); enabled || transforms != nil {
+		t.Fatalf("expected no transform log while recording is off")
+	}
+
+	cp.SetTransformLog(true)
+	cp.InvalidateCache()
+	if _, err := cp.GetMergedConfig(); err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	transforms, _ := cp.TransformLog()
+	if transforms == nil || !transforms.Served {
+		t.Fatalf("expected a served transform log, got %+v", transforms)
+	}
+
+	var middlewareAdded, attached bool
+	for _, change := range transforms.Changes {
+		switch {
+		case change.Section == "http.middlewares" && change.Name == "res-1-forwardauth" && change.Action == TransformAdded:
+			middlewareAdded = true
+		case change.Section == "http.routers" && change.Name == "tool-router" &&
+			change.Action == TransformMiddlewareAdded && change.After == "res-1-forwardauth":
+			attached = true
+		case change.Action == TransformMiddlewareRemoved:
+			t.Errorf("unexpected removal: %s", change.Detail)
+		}
+	}
+	if !middlewareAdded || !attached {
+		t.Fatalf("expected the forwardauth middleware to be logged as added and attached, got %+v", transforms.Changes)
+	}
+}
+
+func TestDiffTransformsReportsFieldChanges(t *testing.T) {
+	upstream := map[string]interface{}{"http": map[string]interface{}{
+		"routers": map[string]interface{}{
+			"r": map[string]interface{}{"priority": float64(10), "middlewares": []interface{}{"a", "b"}},
+		},
+		"middlewares": map[string]interface{}{
+			"mtls": map[string]interface{}{"plugin": map[string]interface{}{
+				"mtlswhitelist": map[string]interface{}{"requestHeaders": []interface{}{"X-Cert"}},
+			}},
+			"old": map[string]interface{}{"headers": map[string]interface{}{}},
+		},
+	}}
+	merged := map[string]interface{}{"http": map[string]interface{}{
+		"routers": map[string]interface{}{
+			"r": map[string]interface{}{"priority": float64(200), "middlewares": []interface{}{"b", "a"}},
+		},
+		"middlewares": map[string]interface{}{
+			"mtls": map[string]interface{}{"plugin": map[string]interface{}{
+				"mtlswhitelist": map[string]interface{}{"requestHeaders": map[string]interface{}{"X-Cert": "[[.Cert]]"}},
+			}},
+		},
+	}}
+
+	changes, truncated := diffTransforms(upstream, merged)
+	if truncated {
+		t.Fatalf("unexpected truncation")
+	}
+	want := map[string]bool{
+		"middleware mtls: plugin.mtlswhitelist.requestHeaders changed": false,
+		"middleware old removed":            false,
+		"middlewares of router r reordered": false,
+		"router r: priority changed":        false,
+	}
+	for _, change := range changes {
+		if _, ok := want[change.Detail]; !ok {
+			t.Errorf("unexpected change %q", change.Detail)
+		}
+		want[change.Detail] = true
+	}
+	for detail, seen := range want {
+		if !seen {
+			t.Errorf("missing change %q", detail)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// Outcomes of an upstream middleware removal
+const (
+	UpstreamRemovalRemoved  = "removed"
+	UpstreamRemovalReplaced = "replaced"
+	// UpstreamRemovalNotFound means the router no longer carries the middleware,
+	// e.g. after it was renamed upstream
+	UpstreamRemovalNotFound = "not_found"
+	UpstreamRemovalNoRouter = "no_router"
+)
+
+// UpstreamRemovalStatus reports how one removal was applied in the last merge
+type UpstreamRemovalStatus struct {
+	ResourceID  string `json:"resource_id"`
+	Router      string `json:"router,omitempty"`
+	Name        string `json:"name"`
+	ReplaceWith string `json:"replace_with,omitempty"`
+	State       string `json:"state"`
+}
+
+// UpstreamRemovalStatus returns the upstream middleware removals of the last merge
+func (cp *ConfigProxy) UpstreamRemovalStatus() []UpstreamRemovalStatus {
+	cp.cacheMutex.RLock()
+	defer cp.cacheMutex.RUnlock()
+	return append([]UpstreamRemovalStatus{}, cp.upstreamRemovals...)
+}
+
+// parseUpstreamRemovals reads a resource's removals, ignoring invalid data
+func parseUpstreamRemovals(resourceID, raw string) []models.UpstreamMiddlewareRemoval {
+	var removals []models.UpstreamMiddlewareRemoval
+	if err := json.Unmarshal([]byte(raw), &removals); err != nil {
+		log.Printf("Warning: invalid upstream middleware removals of resource %s: %v", resourceID, err)
+		return nil
+	}
+	return removals
+}
+
+// removeUpstreamMiddlewares drops or replaces the resource's listed
+// middlewares on the router before MM's own are merged in
+func removeUpstreamMiddlewares(routerKey string, router *OrderedRouter, resource *resourceData) []UpstreamRemovalStatus {
+	statuses := make([]UpstreamRemovalStatus, 0, len(resource.UpstreamRemovals))
+	for _, removal := range resource.UpstreamRemovals {
+		status := UpstreamRemovalStatus{
+			ResourceID:  resource.ID,
+			Router:      routerKey,
+			Name:        removal.Name,
+			ReplaceWith: removal.ReplaceWith,
+			State:       UpstreamRemovalNotFound,
+		}
+		kept := router.Middlewares[:0]
+		for _, name := range router.Middlewares {
+			switch {
+			case name != removal.Name:
+				kept = append(kept, name)
+			case removal.ReplaceWith != "":
+				kept = append(kept, removal.ReplaceWith)
+				status.State = UpstreamRemovalReplaced
+			default:
+				status.State = UpstreamRemovalRemoved
+			}
+		}
+		router.Middlewares = kept
+
+		// Repeated on every merge, so only logged verbosely; the status endpoint
+		// lists the outcome
+		if shouldLog() {
+			log.Printf("Upstream middleware %s of resource %s on router %s: %s", removal.Name, resource.ID, routerKey, status.State)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
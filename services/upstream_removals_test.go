@@ -0,0 +1,47 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeConfig_RemovesAndReplacesUpstreamMiddlewares(t *testing.T) {
+	db := newTestDB(t)
+	cp := NewConfigProxy(db, newTestConfigManager(t), "")
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status, upstream_middleware_removals)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'app', 'org', 'site', 'active',
+		'[{"name":"badger@http"},{"name":"ratelimit@file","replace_with":"strict@file"},{"name":"gone@file"}]')`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	config := &ProxiedTraefikConfig{
+		HTTP: &HTTPConfig{
+			Routers: map[string]*OrderedRouter{
+				"app-router": {Rule: "Host(`app.example.com`)", Service: "app", Middlewares: []string{"badger@http", "ratelimit@file", "compress@file"}},
+			},
+			Middlewares: map[string]interface{}{},
+		},
+	}
+	if err := cp.mergeMiddlewareManagerConfig(config); err != nil {
+		t.Fatalf("mergeMiddlewareManagerConfig: %v", err)
+	}
+
+	want := []string{"strict@file", "compress@file"}
+	if got := config.HTTP.Routers["app-router"].Middlewares; !reflect.DeepEqual(got, want) {
+		t.Errorf("router middlewares = %v, want %v", got, want)
+	}
+
+	states := map[string]string{}
+	for _, status := range cp.UpstreamRemovalStatus() {
+		states[status.Name] = status.State
+	}
+	wantStates := map[string]string{
+		"badger@http":    UpstreamRemovalRemoved,
+		"ratelimit@file": UpstreamRemovalReplaced,
+		"gone@file":      UpstreamRemovalNotFound,
+	}
+	if !reflect.DeepEqual(states, wantStates) {
+		t.Errorf("removal states = %v, want %v", states, wantStates)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Version is the release of this build, set with
+// -ldflags "-X github.com/hhftechnology/middleware-manager/services.Version=v1.2.3"
+var Version = "dev"
+
+// AppVersion returns Version, or the module version recorded by go install
+// when no version was linked in
+func AppVersion() string {
+	if Version != "dev" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return Version
+}
+
+// DefaultUserAgent identifies MM in upstream access logs and WAFs
+func DefaultUserAgent() string {
+	return "middleware-manager/" + strings.TrimPrefix(AppVersion(), "v") + " (+https://github.com/hhftechnology/middleware-manager)"
+}
+
+var (
+	userAgentMu sync.RWMutex
+	userAgent   string
+)
+
+// ConfigureUserAgent replaces the User-Agent of every HTTP client built by
+// this package, including ones created earlier; empty restores the default
+func ConfigureUserAgent(value string) {
+	userAgentMu.Lock()
+	userAgent = strings.TrimSpace(value)
+	userAgentMu.Unlock()
+}
+
+// UserAgent returns the User-Agent sent on outbound requests
+func UserAgent() string {
+	userAgentMu.RLock()
+	defer userAgentMu.RUnlock()
+	if userAgent != "" {
+		return userAgent
+	}
+	return DefaultUserAgent()
+}
+
+// userAgentTransport sets UserAgent on requests that do not carry their own,
+// so a User-Agent among a data source's custom headers still wins
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+// withUserAgent wraps a transport so its requests identify MM
+func withUserAgent(base http.RoundTripper) http.RoundTripper {
+	if _, ok := base.(*userAgentTransport); ok {
+		return base
+	}
+	return &userAgentTransport{base: base}
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", UserAgent())
+	}
+	return t.base.RoundTrip(req)
+}
+
+// baseTransport returns the *http.Transport below the User-Agent wrapper
+func baseTransport(rt http.RoundTripper) (*http.Transport, bool) {
+	if wrapped, ok := rt.(*userAgentTransport); ok {
+		rt = wrapped.base
+	}
+	t, ok := rt.(*http.Transport)
+	return t, ok
+}
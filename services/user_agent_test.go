@@ -0,0 +1,53 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestUserAgentOnOutboundRequests(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.UserAgent())
+	}))
+	defer server.Close()
+	t.Cleanup(func() { ConfigureUserAgent("") })
+
+	get := func(client *http.Client, header string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		if header != "" {
+			req.Header.Set("User-Agent", header)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	get(HTTPClientWithTimeout(time.Second), "")
+	ConfigureUserAgent("acme-mm/1")
+	get(createTraefikHTTPClient(models.DataSourceConfig{}), "")
+	dsClient, err := DataSourceHTTPClient(HTTPClientWithTimeout(time.Second), models.DataSourceConfig{SkipTLSVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get(dsClient, "")
+	get(dsClient, "custom/2")
+
+	if !strings.HasPrefix(got[0], "middleware-manager/") {
+		t.Errorf("default User-Agent = %q", got[0])
+	}
+	if got[1] != "acme-mm/1" || got[2] != "acme-mm/1" {
+		t.Errorf("configured User-Agent not sent: %v", got)
+	}
+	if got[3] != "custom/2" {
+		t.Errorf("request User-Agent should win, got %q", got[3])
+	}
+}
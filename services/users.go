@@ -0,0 +1,100 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+const userColumns = "id, username, role, created_at, updated_at"
+
+// UserService stores the operators of the management API and their roles
+type UserService struct {
+	db  *database.DB
+	now func() time.Time
+}
+
+// NewUserService creates a new user service
+func NewUserService(db *database.DB) *UserService {
+	return &UserService{db: db, now: time.Now}
+}
+
+// List returns every user by username
+func (s *UserService) List() ([]models.User, error) {
+	rows, err := s.db.Query("SELECT " + userColumns + " FROM users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// Get returns a user, or sql.ErrNoRows
+func (s *UserService) Get(id string) (*models.User, error) {
+	return scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+// Create stores a normalized, validated user. A taken username is reported
+// by database.IsUniqueViolation.
+func (s *UserService) Create(user models.User) (*models.User, error) {
+	now := s.now().UTC()
+	user.ID = uuid.New().String()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	_, err := s.db.Exec(`
+		INSERT INTO users (id, username, role, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, user.ID, user.Username, user.Role, now, now)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateRole changes a user's role, which their tokens use from the next
+// request on, or returns sql.ErrNoRows
+func (s *UserService) UpdateRole(id, role string) (*models.User, error) {
+	result, err := s.db.Exec("UPDATE users SET role = ?, updated_at = ? WHERE id = ?", role, s.now().UTC(), id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return s.Get(id)
+}
+
+// Delete removes a user and revokes their tokens, or returns sql.ErrNoRows
+func (s *UserService) Delete(id string) error {
+	return s.db.WithTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM users WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return sql.ErrNoRows
+		}
+		_, err = tx.Exec("DELETE FROM api_tokens WHERE user_id = ?", id)
+		return err
+	})
+}
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
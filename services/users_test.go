@@ -0,0 +1,48 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func TestUserServiceRolesFollowTokens(t *testing.T) {
+	db := newTestDB(t)
+	users := NewUserService(db)
+	tokens := NewAPITokenService(db)
+
+	user, err := users.Create(models.User{Username: "alice", Role: models.RoleViewer})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := users.Create(models.User{Username: "alice", Role: models.RoleAdmin}); !database.IsUniqueViolation(err) {
+		t.Errorf("expected a duplicate username to be a unique violation, got %v", err)
+	}
+
+	created, err := tokens.Create(models.APITokenRequest{Name: "cli", Scope: models.APITokenScopeAdmin, UserID: user.ID})
+	if err != nil || created.Role != models.RoleViewer {
+		t.Fatalf("Create token = %+v, %v", created, err)
+	}
+	if _, err := tokens.Create(models.APITokenRequest{Name: "cli", Scope: models.APITokenScopeAdmin, UserID: "missing"}); err != ErrUnknownUser {
+		t.Errorf("expected ErrUnknownUser, got %v", err)
+	}
+
+	if _, err := users.UpdateRole(user.ID, models.RoleEditor); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	if token, err := tokens.Verify(created.Secret); err != nil || token == nil || token.Role != models.RoleEditor {
+		t.Fatalf("expected the token to take the new role, got %+v, %v", token, err)
+	}
+
+	if err := users.Delete(user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := users.Delete(user.ID); err != sql.ErrNoRows {
+		t.Errorf("expected deleting twice to return sql.ErrNoRows, got %v", err)
+	}
+	if token, err := tokens.Verify(created.Secret); err != nil || token != nil {
+		t.Errorf("expected the user's tokens to be revoked, got %+v, %v", token, err)
+	}
+}
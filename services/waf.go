@@ -0,0 +1,84 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// LoadWAFConfig reads the global WAF settings, falling back to the defaults
+func LoadWAFConfig(db *sql.DB) (models.WAFConfig, error) {
+	cfg := models.DefaultWAFConfig()
+	var updatedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT plugin_module, plugin_version, crs_version, default_paranoia, updated_at
+		FROM waf_config WHERE id = 1
+	`).Scan(&cfg.PluginModule, &cfg.PluginVersion, &cfg.CRSVersion, &cfg.DefaultParanoia, &updatedAt)
+	if err == sql.ErrNoRows {
+		return models.DefaultWAFConfig(), nil
+	} else if err != nil {
+		return cfg, fmt.Errorf("failed to load WAF config: %w", err)
+	}
+	if updatedAt.Valid {
+		cfg.UpdatedAt = updatedAt.Time
+	}
+	return cfg, nil
+}
+
+// loadWAFSettings renders the WAF plugin middleware for each resource that has the WAF enabled
+func (cp *ConfigProxy) loadWAFSettings(resourceMap map[string]*resourceData) error {
+	rows, err := cp.db.Query(`
+		SELECT resource_id, paranoia_level, log_only, exclusions
+		FROM resource_waf WHERE enabled = 1
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var wafCfg *models.WAFConfig
+	for rows.Next() {
+		var resID, exclusions string
+		var logOnly int
+		settings := models.ResourceWAF{Enabled: true}
+		if err := rows.Scan(&resID, &settings.ParanoiaLevel, &logOnly, &exclusions); err != nil {
+			log.Printf("Failed to scan WAF settings: %v", err)
+			continue
+		}
+		data, ok := resourceMap[resID]
+		if !ok {
+			continue
+		}
+		settings.ResourceID = resID
+		settings.LogOnly = logOnly == 1
+		if err := json.Unmarshal([]byte(exclusions), &settings.Exclusions); err != nil {
+			log.Printf("Skipping WAF for resource %s: invalid exclusions: %v", resID, err)
+			continue
+		}
+		if err := settings.Validate(); err != nil {
+			log.Printf("Skipping invalid WAF settings for resource %s: %v", resID, err)
+			continue
+		}
+
+		if wafCfg == nil {
+			cfg, err := LoadWAFConfig(cp.db.DB)
+			if err != nil {
+				return err
+			}
+			wafCfg = &cfg
+		}
+		data.WAFMiddleware = settings.MiddlewareConfig(*wafCfg)
+	}
+
+	return rows.Err()
+}
+
+// ensureWAFMiddleware registers the resource's Coraza plugin middleware
+func (cp *ConfigProxy) ensureWAFMiddleware(config *ProxiedTraefikConfig, resource *resourceData) string {
+	middlewareName := fmt.Sprintf("%s-waf", resource.ID)
+	config.HTTP.Middlewares[middlewareName] = resource.WAFMiddleware
+	return middlewareName
+}
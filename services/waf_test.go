@@ -0,0 +1,72 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigProxyAddsWAFMiddleware(t *testing.T) {
+	db := newTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO resources (id, pangolin_router_id, host, service_id, org_id, site_id, status)
+		VALUES ('res-1', 'app-router', 'app.example.com', 'app-service', 'org', 'site', 'active')`); err != nil {
+		t.Fatalf("insert resource: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO resource_waf (resource_id, enabled, paranoia_level, log_only, exclusions)
+		VALUES ('res-1', 1, 0, 1, '[{"rule_ids":[942100]}]')`); err != nil {
+		t.Fatalf("insert WAF settings: %v", err)
+	}
+
+	pangolin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"http": map[string]interface{}{
+				"routers": map[string]interface{}{
+					"app-router": map[string]interface{}{
+						"rule": "Host(`app.example.com`)", "service": "app-service", "middlewares": []string{"badger@file"},
+					},
+				},
+				"services": map[string]interface{}{
+					"app-service": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+				},
+			},
+		})
+	}))
+	defer pangolin.Close()
+
+	cp := NewConfigProxy(db, newTestConfigManager(t), pangolin.URL)
+	cp.httpClient = pangolin.Client()
+
+	config, err := cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+
+	router := config.HTTP.Routers["app-router"]
+	if router == nil || len(router.Middlewares) != 2 || router.Middlewares[0] != "res-1-waf" || router.Middlewares[1] != "badger@file" {
+		t.Fatalf("expected the WAF middleware ahead of upstream middlewares, got %+v", router)
+	}
+	mw, ok := config.HTTP.Middlewares["res-1-waf"].(*OrderedMiddleware)
+	if !ok {
+		t.Fatalf("WAF middleware missing: %v", config.HTTP.Middlewares)
+	}
+	coraza, _ := mw.Plugin["coraza"].(map[string]interface{})
+	directives, _ := coraza["directives"].([]string)
+	if len(directives) == 0 || directives[1] != "SecRuleEngine DetectionOnly" || directives[len(directives)-1] != "SecRuleRemoveById 942100" {
+		t.Fatalf("unexpected directives: %v", directives)
+	}
+
+	// Disabling the WAF removes the middleware on the next merge
+	if _, err := db.Exec("UPDATE resource_waf SET enabled = 0 WHERE resource_id = 'res-1'"); err != nil {
+		t.Fatalf("disable WAF: %v", err)
+	}
+	cp.InvalidateCache()
+	config, err = cp.GetMergedConfig()
+	if err != nil {
+		t.Fatalf("GetMergedConfig() error = %v", err)
+	}
+	if _, ok := config.HTTP.Middlewares["res-1-waf"]; ok {
+		t.Fatalf("WAF middleware still present after disabling")
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// ConfigFailure is the data of a config.generation_failed event
+type ConfigFailure struct {
+	// Source is merge for the served config or file for resource-overrides.yml
+	Source   string    `json:"source"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// failureNotifier sends config.generation_failed when a config stops
+// generating, once per run of failures rather than on every poll
+type failureNotifier struct {
+	mu       sync.Mutex
+	webhooks *WebhookService
+	failing  bool
+}
+
+func (n *failureNotifier) setWebhooks(webhooks *WebhookService) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.webhooks = webhooks
+}
+
+// report records the outcome of a generation; err is nil on success
+func (n *failureNotifier) report(source string, err error) {
+	n.mu.Lock()
+	wasFailing := n.failing
+	n.failing = err != nil
+	webhooks := n.webhooks
+	n.mu.Unlock()
+
+	if err != nil && !wasFailing {
+		webhooks.Emit(models.EventConfigFailed, ConfigFailure{Source: source, Error: err.Error(), FailedAt: time.Now().UTC()})
+	}
+}
+
+// SetWebhooks sets the service told when merging the served config fails
+func (cp *ConfigProxy) SetWebhooks(webhooks *WebhookService) {
+	cp.mergeFailures.setWebhooks(webhooks)
+}
+
+// SetWebhooks sets the service told when writing resource-overrides.yml fails
+func (cg *ConfigGenerator) SetWebhooks(webhooks *WebhookService) {
+	cg.failures.setWebhooks(webhooks)
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/middleware-manager/database"
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+// DefaultWebhookMaxAttempts bounds the attempts of one delivery
+const DefaultWebhookMaxAttempts = 5
+
+// webhookDeliveryHistory is how many finished deliveries the delivery log keeps
+const webhookDeliveryHistory = 1000
+
+// webhookBatch is how many due deliveries one pass sends
+const webhookBatch = 50
+
+// webhookDeliveryHeader carries the delivery ID, so consumers can drop
+// deliveries they already processed
+const webhookDeliveryHeader = "X-MM-Delivery"
+
+// WebhookPayload is the body of an event delivery
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookService stores webhooks and delivers their events in the
+// background. Deliveries are queued in the database, so retries survive a
+// restart. A nil *WebhookService drops events.
+type WebhookService struct {
+	db          *database.DB
+	client      *http.Client
+	now         func() time.Time
+	maxAttempts int
+	// backoff returns the wait before retry attempt n (1-based)
+	backoff func(attempt int) time.Duration
+
+	wake     chan struct{}
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(db *database.DB) *WebhookService {
+	return &WebhookService{
+		db:          db,
+		client:      HTTPClientWithTimeout(10 * time.Second),
+		now:         time.Now,
+		maxAttempts: DefaultWebhookMaxAttempts,
+		backoff: func(attempt int) time.Duration {
+			return min(30*time.Second<<(attempt-1), 30*time.Minute)
+		},
+		wake:     make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+	}
+}
+
+const webhookColumns = "id, name, url, secret, events, enabled, created_at, updated_at"
+
+// List returns every webhook by name
+func (s *WebhookService) List() ([]models.Webhook, error) {
+	rows, err := s.db.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// Get returns a webhook, or sql.ErrNoRows
+func (s *WebhookService) Get(id string) (*models.Webhook, error) {
+	return scanWebhook(s.db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+}
+
+// Create stores a normalized, validated webhook
+func (s *WebhookService) Create(webhook models.Webhook) (*models.Webhook, error) {
+	now := s.now().UTC()
+	webhook.ID = uuid.New().String()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	_, err := s.db.Exec(`
+		INSERT INTO webhooks (id, name, url, secret, events, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, webhook.ID, webhook.Name, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","),
+		webhook.Enabled, now, now)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Update replaces a webhook's settings, or returns sql.ErrNoRows. A secret
+// of models.MaskedSecret keeps the stored one.
+func (s *WebhookService) Update(id string, webhook models.Webhook) (*models.Webhook, error) {
+	existing, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.Secret == models.MaskedSecret {
+		webhook.Secret = existing.Secret
+	}
+	_, err = s.db.Exec(`
+		UPDATE webhooks SET name = ?, url = ?, secret = ?, events = ?, enabled = ?, updated_at = ?
+		WHERE id = ?
+	`, webhook.Name, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Enabled,
+		s.now().UTC(), id)
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// Delete removes a webhook and its deliveries, or returns sql.ErrNoRows
+func (s *WebhookService) Delete(id string) error {
+	return s.db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+			return err
+		}
+		result, err := tx.Exec("DELETE FROM webhooks WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// Emit queues event for every enabled webhook subscribed to it. It does not
+// block on delivery; failures to queue are logged.
+func (s *WebhookService) Emit(event string, data interface{}) {
+	if s == nil {
+		return
+	}
+	webhooks, err := s.List()
+	if err != nil {
+		log.Printf("Error loading webhooks for %s: %v", event, err)
+		return
+	}
+	queued := false
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Enabled || !webhook.Wants(event) {
+			continue
+		}
+		if _, err := s.enqueue(webhook.ID, event, data); err != nil {
+			log.Printf("Error queuing %s for webhook %s: %v", event, webhook.Name, err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		s.notify()
+	}
+}
+
+// Test queues a webhook.test event for one webhook, even a disabled one
+func (s *WebhookService) Test(id string) (*models.WebhookDelivery, error) {
+	webhook, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	deliveryID, err := s.enqueue(webhook.ID, models.EventWebhookTest, map[string]string{"webhook": webhook.Name})
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return s.delivery(deliveryID)
+}
+
+// Deliveries returns the latest deliveries, newest first; an empty
+// webhookID returns those of every webhook
+func (s *WebhookService) Deliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 || limit > webhookDeliveryHistory {
+		limit = 100
+	}
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries"
+	args := []interface{}{}
+	if webhookID != "" {
+		query += " WHERE webhook_id = ?"
+		args = append(args, webhookID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Start sends due deliveries every interval, and right away when an event
+// is queued, until Stop is called
+func (s *WebhookService) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.stopChan:
+			return
+		}
+		if _, err := s.DeliverDue(); err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		}
+	}
+}
+
+// Stop stops the delivery loop
+func (s *WebhookService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+}
+
+// DeliverDue sends the pending deliveries whose next attempt is due and
+// returns how many it attempted
+func (s *WebhookService) DeliverDue() (int, error) {
+	now := s.now().UTC()
+	rows, err := s.db.Query(`
+		SELECT d.id, d.event, d.payload, d.attempts, w.url, w.secret
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= ?
+		ORDER BY d.id LIMIT ?
+	`, models.DeliveryPending, now, webhookBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load due deliveries: %w", err)
+	}
+
+	type due struct {
+		id                int64
+		event, payload    string
+		attempts          int
+		targetURL, secret string
+	}
+	var batch []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.event, &d.payload, &d.attempts, &d.targetURL, &d.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, d := range batch {
+		status, retry, err := s.post(d.id, d.event, d.payload, d.targetURL, d.secret)
+		attempts := d.attempts + 1
+		finished := s.now().UTC()
+
+		switch {
+		case err == nil:
+			_, err = s.db.Exec(`
+				UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, last_error = '',
+				    next_attempt_at = NULL, delivered_at = ?
+				WHERE id = ?
+			`, models.DeliveryDelivered, attempts, status, finished, d.id)
+		case retry && attempts < s.maxAttempts:
+			_, err = s.db.Exec(`
+				UPDATE webhook_deliveries SET attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ?
+				WHERE id = ?
+			`, attempts, status, err.Error(), finished.Add(s.backoff(attempts)), d.id)
+		default:
+			log.Printf("Webhook delivery %d (%s) to %s failed after %d attempt(s): %v", d.id, d.event, d.targetURL, attempts, err)
+			_, err = s.db.Exec(`
+				UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, last_error = ?,
+				    next_attempt_at = NULL
+				WHERE id = ?
+			`, models.DeliveryFailed, attempts, status, err.Error(), d.id)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to record delivery %d: %w", d.id, err)
+		}
+	}
+
+	if len(batch) > 0 {
+		s.prune()
+	}
+	return len(batch), nil
+}
+
+// enqueue stores a pending delivery that is due right away
+func (s *WebhookService) enqueue(webhookID, event string, data interface{}) (int64, error) {
+	now := s.now().UTC()
+	payload, err := json.Marshal(WebhookPayload{Event: event, Timestamp: now, Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	var id int64
+	err = s.db.QueryRow(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, webhookID, event, string(payload), models.DeliveryPending, now, now).Scan(&id)
+	return id, err
+}
+
+// notify wakes the delivery loop without blocking
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// post sends one delivery and reports whether a failure is worth retrying
+func (s *WebhookService) post(id int64, event, payload, targetURL, secret string) (int, bool, error) {
+	body := []byte(payload)
+	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(configWebhookEventHeader, event)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(id, 10))
+	req.Header.Set(configWebhookTimestampHeader, timestamp)
+	if secret != "" {
+		req.Header.Set(configWebhookSignatureHeader, signConfigWebhook(secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	// Other client errors will not change on retry
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return resp.StatusCode, retry, fmt.Errorf("consumer returned %s", resp.Status)
+}
+
+// prune drops the oldest finished deliveries beyond webhookDeliveryHistory
+func (s *WebhookService) prune() {
+	_, err := s.db.Exec(`
+		DELETE FROM webhook_deliveries
+		WHERE status != ? AND id <= (SELECT id FROM webhook_deliveries ORDER BY id DESC LIMIT 1 OFFSET ?)
+	`, models.DeliveryPending, webhookDeliveryHistory)
+	if err != nil {
+		log.Printf("Warning: failed to prune webhook deliveries: %v", err)
+	}
+}
+
+func (s *WebhookService) delivery(id int64) (*models.WebhookDelivery, error) {
+	return scanWebhookDelivery(s.db.QueryRow("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = ?", id))
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, response_status, last_error,
+	next_attempt_at, created_at, delivered_at`
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	if err := row.Scan(&webhook.ID, &webhook.Name, &webhook.URL, &webhook.Secret, &events, &webhook.Enabled,
+		&webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return nil, err
+	}
+	webhook.Events = []string{}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+	return &webhook, nil
+}
+
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var nextAttemptAt, deliveredAt sql.NullTime
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError, &nextAttemptAt,
+		&delivery.CreatedAt, &deliveredAt); err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/middleware-manager/models"
+)
+
+func newTestWebhookService(t *testing.T) (*WebhookService, *time.Time) {
+	t.Helper()
+	s := NewWebhookService(newTestDB(t))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func createTestWebhook(t *testing.T, s *WebhookService, consumer *webhookConsumer, webhook models.Webhook) *models.Webhook {
+	t.Helper()
+	server := httptest.NewServer(consumer)
+	t.Cleanup(server.Close)
+	webhook.URL = server.URL
+	webhook.Normalize()
+	if err := webhook.Validate(); err != nil {
+		t.Fatalf("validate webhook: %v", err)
+	}
+	created, err := s.Create(webhook)
+	if err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	return created
+}
+
+func TestWebhookService_EmitFiltersAndSigns(t *testing.T) {
+	s, _ := newTestWebhookService(t)
+	consumer := &webhookConsumer{}
+	subscribed := createTestWebhook(t, s, consumer, models.Webhook{
+		Name: "chat", Secret: "s3cret", Enabled: true, Events: []string{models.EventMiddlewareAssigned},
+	})
+	createTestWebhook(t, s, consumer, models.Webhook{Name: "off", Enabled: false})
+
+	s.Emit(models.EventResourceDiscovered, map[string]string{"host": "app.example.com"})
+	s.Emit(models.EventMiddlewareAssigned, map[string]string{"resource_id": "r1", "middleware_id": "m1"})
+	if n, err := s.DeliverDue(); err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v; want 1 delivery", n, err)
+	}
+
+	if len(consumer.requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(consumer.requests))
+	}
+	req, body := consumer.requests[0], consumer.bodies[0]
+	if req.Header.Get(configWebhookEventHeader) != models.EventMiddlewareAssigned || req.Header.Get(webhookDeliveryHeader) == "" {
+		t.Errorf("unexpected headers %v", req.Header)
+	}
+	want := signConfigWebhook("s3cret", req.Header.Get(configWebhookTimestampHeader), body)
+	if req.Header.Get(configWebhookSignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get(configWebhookSignatureHeader), want)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("parse payload: %v", err)
+	}
+	if payload.Event != models.EventMiddlewareAssigned || payload.Data.(map[string]interface{})["middleware_id"] != "m1" {
+		t.Errorf("unexpected payload %s", body)
+	}
+
+	deliveries, err := s.Deliveries(subscribed.ID, 0)
+	if err != nil {
+		t.Fatalf("Deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryDelivered || deliveries[0].Attempts != 1 ||
+		deliveries[0].ResponseStatus != http.StatusOK || deliveries[0].DeliveredAt == nil {
+		t.Errorf("unexpected delivery log %+v", deliveries)
+	}
+}
+
+func TestWebhookService_RetriesWithBackoff(t *testing.T) {
+	s, now := newTestWebhookService(t)
+	s.maxAttempts = 3
+	consumer := &webhookConsumer{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	webhook := createTestWebhook(t, s, consumer, models.Webhook{Name: "ops", Enabled: true})
+
+	s.Emit(models.EventConfigFailed, ConfigFailure{Source: "merge", Error: "boom"})
+	if _, err := s.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	deliveries, _ := s.Deliveries(webhook.ID, 0)
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryPending || deliveries[0].Attempts != 1 ||
+		deliveries[0].NextAttemptAt == nil || !deliveries[0].NextAttemptAt.Equal(now.Add(s.backoff(1))) {
+		t.Fatalf("expected a retry after the backoff, got %+v", deliveries)
+	}
+
+	// Not due yet
+	if n, _ := s.DeliverDue(); n != 0 {
+		t.Fatalf("expected no delivery before the backoff, got %d", n)
+	}
+
+	*now = now.Add(s.backoff(1))
+	s.DeliverDue()
+	*now = now.Add(s.backoff(2))
+	s.DeliverDue()
+
+	deliveries, _ = s.Deliveries(webhook.ID, 0)
+	if len(consumer.requests) != 3 || deliveries[0].Status != models.DeliveryDelivered || deliveries[0].Attempts != 3 {
+		t.Errorf("expected delivery on the third attempt, got %d requests and %+v", len(consumer.requests), deliveries)
+	}
+}
+
+func TestWebhookService_GivesUp(t *testing.T) {
+	s, _ := newTestWebhookService(t)
+	consumer := &webhookConsumer{statuses: []int{http.StatusNotFound}}
+	webhook := createTestWebhook(t, s, consumer, models.Webhook{Name: "gone", Enabled: true})
+
+	if _, err := s.Test(webhook.ID); err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	s.DeliverDue()
+
+	deliveries, _ := s.Deliveries(webhook.ID, 0)
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryFailed || deliveries[0].Attempts != 1 ||
+		deliveries[0].ResponseStatus != http.StatusNotFound || deliveries[0].LastError == "" {
+		t.Errorf("expected a 404 not to be retried, got %+v", deliveries)
+	}
+}
+
+func TestWebhookService_UpdateKeepsMaskedSecret(t *testing.T) {
+	s, _ := newTestWebhookService(t)
+	webhook := createTestWebhook(t, s, &webhookConsumer{}, models.Webhook{Name: "chat", Secret: "s3cret", Enabled: true})
+
+	webhook.MaskSecret()
+	webhook.Name = "chat-ops"
+	updated, err := s.Update(webhook.ID, *webhook)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Secret != "s3cret" || updated.Name != "chat-ops" {
+		t.Errorf("unexpected webhook %+v", updated)
+	}
+}
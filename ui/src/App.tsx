@@ -1,6 +1,7 @@
 import { useAppStore } from '@/stores/appStore'
 import { Header } from '@/components/common/Header'
 import { Footer } from '@/components/common/Footer'
+import { ApiTokenDialog } from '@/components/common/ApiTokenDialog'
 import { Dashboard } from '@/components/dashboard/Dashboard'
 import { ResourcesList } from '@/components/resources/ResourcesList'
 import { ResourceDetail } from '@/components/resources/ResourceDetail'
@@ -53,6 +54,7 @@ function AppContent() {
       </main>
       <Footer />
       {showSettings && <DataSourceSettings />}
+      <ApiTokenDialog />
       <Toaster />
     </div>
   )
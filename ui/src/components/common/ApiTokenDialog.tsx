@@ -0,0 +1,85 @@
+import { useEffect, useRef, useState } from 'react'
+import { setTokenPrompt } from '@/services/api'
+import {
+  Dialog,
+  DialogContent,
+  DialogDescription,
+  DialogFooter,
+  DialogHeader,
+  DialogTitle,
+} from '@/components/ui/dialog'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { KeyRound } from 'lucide-react'
+
+// ApiTokenDialog asks for an API token when a request is answered with 401.
+// The token is saved in the browser and the request is sent again with it.
+export function ApiTokenDialog() {
+  const [open, setOpen] = useState(false)
+  const [reason, setReason] = useState('')
+  const [token, setToken] = useState('')
+  const resolveRef = useRef<((token: string | null) => void) | null>(null)
+
+  useEffect(() => {
+    setTokenPrompt(
+      (message) =>
+        new Promise((resolve) => {
+          resolveRef.current = resolve
+          setReason(message)
+          setToken('')
+          setOpen(true)
+        })
+    )
+    return () => setTokenPrompt(null)
+  }, [])
+
+  const finish = (value: string | null) => {
+    resolveRef.current?.(value)
+    resolveRef.current = null
+    setOpen(false)
+  }
+
+  const handleSubmit = (e: React.FormEvent) => {
+    e.preventDefault()
+    if (token.trim()) finish(token.trim())
+  }
+
+  return (
+    <Dialog open={open} onOpenChange={(isOpen) => !isOpen && finish(null)}>
+      <DialogContent className="sm:max-w-[450px]">
+        <form onSubmit={handleSubmit}>
+          <DialogHeader>
+            <DialogTitle className="flex items-center gap-2">
+              <KeyRound className="h-5 w-5" />
+              API token required
+            </DialogTitle>
+            <DialogDescription>
+              {reason} Enter an API token; it is saved in this browser and sent with every request.
+            </DialogDescription>
+          </DialogHeader>
+          <div className="space-y-2 py-4">
+            <Label htmlFor="api-token">API token</Label>
+            <Input
+              id="api-token"
+              type="password"
+              autoComplete="off"
+              value={token}
+              onChange={(e) => setToken(e.target.value)}
+              placeholder="mmt_..."
+              autoFocus
+            />
+          </div>
+          <DialogFooter>
+            <Button type="button" variant="outline" onClick={() => finish(null)}>
+              Cancel
+            </Button>
+            <Button type="submit" disabled={!token.trim()}>
+              Save and retry
+            </Button>
+          </DialogFooter>
+        </form>
+      </DialogContent>
+    </Dialog>
+  )
+}
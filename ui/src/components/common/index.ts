@@ -5,3 +5,4 @@ export { ErrorMessage, InlineError } from './ErrorMessage'
 export { ConfirmationModal, useConfirmation } from './ConfirmationModal'
 export { LoadingSpinner, PageLoader, Spinner, LoadingOverlay, LoadingCard } from './LoadingSpinner'
 export { EmptyState } from './EmptyState'
+export { ApiTokenDialog } from './ApiTokenDialog'
@@ -17,6 +17,7 @@ import {
   RouterTabs,
   ServiceTabs,
   MiddlewareTabs,
+  ProviderConsumers,
 } from '@/components/traefik'
 import { Globe, Layers, Server, Plus, ArrowRight, Activity, Network } from 'lucide-react'
 
@@ -168,6 +169,8 @@ export function Dashboard() {
             <EntrypointList />
           </div>
 
+          <ProviderConsumers />
+
           <RouterTabs />
           <ServiceTabs />
           <MiddlewareTabs />
@@ -281,6 +281,7 @@ export function MiddlewareForm() {
         name,
         type,
         config,
+        version: selectedMiddleware?.version,
       })
       if (success) {
         navigateTo('middlewares')
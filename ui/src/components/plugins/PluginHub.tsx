@@ -36,6 +36,7 @@ import {
   Download,
   ChevronLeft,
   ChevronRight,
+  ListChecks,
 } from 'lucide-react'
 import type { Plugin, CataloguePlugin } from '@/types'
 
@@ -49,12 +50,14 @@ export function PluginHub() {
     plugins,
     cataloguePlugins,
     configPath,
+    staticConfigLint,
     selectedPlugin,
     selectedCataloguePlugin,
     loading,
     loadingCatalogue,
     installing,
     removing,
+    linting,
     error,
     showRestartWarning,
     lastInstalledPlugin,
@@ -64,6 +67,7 @@ export function PluginHub() {
     installPlugin,
     removePlugin,
     updateConfigPath,
+    lintStaticConfig,
     selectPlugin,
     selectCataloguePlugin,
     clearError,
@@ -350,7 +354,39 @@ export function PluginHub() {
                 <Save className="h-4 w-4" />
               )}
             </Button>
+            <Button
+              variant="outline"
+              onClick={() => lintStaticConfig()}
+              disabled={linting || newConfigPath !== configPath}
+              title="Check the static config for common problems"
+            >
+              {linting ? (
+                <Loader2 className="h-4 w-4 animate-spin" />
+              ) : (
+                <ListChecks className="h-4 w-4" />
+              )}
+            </Button>
           </div>
+          {staticConfigLint && (
+            <div className="mt-4 space-y-2">
+              {staticConfigLint.findings.length === 0 ? (
+                <p className="text-sm text-muted-foreground">No problems found in the static config.</p>
+              ) : (
+                staticConfigLint.findings.map((finding, index) => (
+                  <div key={`${finding.rule}-${finding.path}-${index}`} className="rounded-md border p-3 text-sm">
+                    <div className="flex items-center gap-2">
+                      <Badge variant={finding.severity === 'error' ? 'destructive' : finding.severity === 'warning' ? 'warning' : 'secondary'}>
+                        {finding.severity}
+                      </Badge>
+                      {finding.path && <code className="text-xs text-muted-foreground">{finding.path}</code>}
+                    </div>
+                    <p className="mt-1">{finding.message}</p>
+                    {finding.hint && <p className="mt-1 text-xs text-muted-foreground">{finding.hint}</p>}
+                  </div>
+                ))
+              )}
+            </div>
+          )}
         </CardContent>
       </Card>
 
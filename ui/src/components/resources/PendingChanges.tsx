@@ -0,0 +1,125 @@
+import { useCallback, useEffect, useState } from 'react'
+import { ClipboardCheck, Loader2 } from 'lucide-react'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+import { Button } from '@/components/ui/button'
+import { Badge } from '@/components/ui/badge'
+import { Alert, AlertDescription } from '@/components/ui/alert'
+import { resourceApi } from '@/services/api'
+import type { PendingChange, PendingChangeKind } from '@/types'
+
+const KIND_LABELS: Record<PendingChangeKind, string> = {
+  create: 'New',
+  disable: 'Disable',
+  service_change: 'Service change',
+}
+
+interface PendingChangesProps {
+  // Called after changes were applied so the resource list can reload
+  onApplied?: () => void
+}
+
+export function PendingChanges({ onApplied }: PendingChangesProps) {
+  const [changes, setChanges] = useState<PendingChange[]>([])
+  const [approving, setApproving] = useState(false)
+  const [error, setError] = useState<string | null>(null)
+
+  const load = useCallback(async () => {
+    try {
+      const result = await resourceApi.getPendingChanges()
+      setChanges(result.changes)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to load pending changes')
+    }
+  }, [])
+
+  useEffect(() => {
+    load()
+  }, [load])
+
+  const approve = async (ids?: string[]) => {
+    setApproving(true)
+    setError(null)
+    try {
+      await resourceApi.approvePendingChanges(ids)
+      onApplied?.()
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to approve changes')
+    } finally {
+      setApproving(false)
+      load()
+    }
+  }
+
+  if (changes.length === 0 && !error) {
+    return null
+  }
+
+  const disables = changes.filter((change) => change.kind === 'disable').length
+
+  return (
+    <Card>
+      <CardHeader>
+        <div className="flex items-center justify-between">
+          <div>
+            <CardTitle className="flex items-center gap-2">
+              <ClipboardCheck className="h-5 w-5" />
+              Pending Changes
+            </CardTitle>
+            <CardDescription>
+              Review mode is holding back {changes.length} change{changes.length === 1 ? '' : 's'} from the data source
+            </CardDescription>
+          </div>
+          <Button onClick={() => approve()} disabled={approving || changes.length === 0}>
+            {approving && <Loader2 className="h-4 w-4 mr-2 animate-spin" />}
+            Approve all
+          </Button>
+        </div>
+      </CardHeader>
+      <CardContent className="space-y-3">
+        {error && (
+          <Alert variant="destructive">
+            <AlertDescription>{error}</AlertDescription>
+          </Alert>
+        )}
+        {disables > 0 && disables === changes.length && (
+          <Alert variant="warning">
+            <AlertDescription>
+              Every pending change disables a resource. If the data source briefly returned an empty
+              config, wait for the next check instead of approving.
+            </AlertDescription>
+          </Alert>
+        )}
+        {changes.map((change) => (
+          <div
+            key={change.id}
+            className="flex items-center justify-between rounded-lg border p-3"
+          >
+            <div className="flex items-center gap-3">
+              <Badge variant={change.kind === 'disable' ? 'destructive' : 'secondary'}>
+                {KIND_LABELS[change.kind]}
+              </Badge>
+              <div>
+                <div className="font-medium">{change.host}</div>
+                <div className="text-sm text-muted-foreground">
+                  {change.kind === 'service_change'
+                    ? `${change.old_service_id} → ${change.new_service_id}`
+                    : change.new_service_id}
+                  {change.auto_approve_at &&
+                    ` · auto-approves ${new Date(change.auto_approve_at).toLocaleString()}`}
+                </div>
+              </div>
+            </div>
+            <Button
+              variant="outline"
+              size="sm"
+              onClick={() => approve([change.id])}
+              disabled={approving}
+            >
+              Approve
+            </Button>
+          </div>
+        ))}
+      </CardContent>
+    </Card>
+  )
+}
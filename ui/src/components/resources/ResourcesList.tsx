@@ -17,6 +17,7 @@ import { PageLoader } from '@/components/common/LoadingSpinner'
 import { ErrorMessage } from '@/components/common/ErrorMessage'
 import { EmptyState } from '@/components/common/EmptyState'
 import { ConfirmationModal } from '@/components/common/ConfirmationModal'
+import { PendingChanges } from './PendingChanges'
 import { Search, ExternalLink, Trash2, Globe, RefreshCw, CheckSquare, Square } from 'lucide-react'
 import { truncate } from '@/lib/utils'
 import type { Resource } from '@/types'
@@ -131,6 +132,8 @@ export function ResourcesList() {
         />
       )}
 
+      <PendingChanges onApplied={fetchResources} />
+
       <Card>
         <CardHeader>
           <div className="flex items-center justify-between">
@@ -1,2 +1,3 @@
 export { ResourcesList } from './ResourcesList'
 export { ResourceDetail } from './ResourceDetail'
+export { PendingChanges } from './PendingChanges'
@@ -38,6 +38,11 @@ const DEFAULT_CONFIGS: Record<ServiceType, object> = {
     service: 'main-service',
     fallback: 'fallback-service',
   },
+  dnsDiscovery: {
+    name: '_http._tcp.api.service.consul',
+    recordType: 'SRV',
+    scheme: 'http',
+  },
 }
 
 export function ServiceForm() {
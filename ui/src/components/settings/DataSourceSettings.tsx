@@ -13,6 +13,7 @@ import { Button } from '@/components/ui/button'
 import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
 import { Badge } from '@/components/ui/badge'
+import { Switch } from '@/components/ui/switch'
 import { Alert, AlertDescription } from '@/components/ui/alert'
 import { Spinner } from '@/components/ui/spinner'
 import {
@@ -40,18 +41,39 @@ export function DataSourceSettings() {
     updateDataSource,
     testConnection,
     clearTestResult,
+    failover,
+    fetchFailover,
+    setFailover,
   } = useDataSourceStore()
 
   const [editingSource, setEditingSource] = useState<string | null>(null)
   const [editUrl, setEditUrl] = useState('')
   const [editUsername, setEditUsername] = useState('')
   const [editPassword, setEditPassword] = useState('')
+  const [failoverMinutes, setFailoverMinutes] = useState(5)
 
   useEffect(() => {
     if (showSettings) {
       fetchDataSources()
+      fetchFailover()
     }
-  }, [showSettings, fetchDataSources])
+  }, [showSettings, fetchDataSources, fetchFailover])
+
+  useEffect(() => {
+    setFailoverMinutes(failover?.config.after_minutes || 5)
+  }, [failover])
+
+  // Failover goes to the first source that is not active
+  const secondarySource = (Array.isArray(dataSources) ? dataSources : []).find((ds) => !ds.isActive)
+
+  const handleFailoverChange = async (enabled: boolean, minutes = failoverMinutes) => {
+    if (!secondarySource) return
+    await setFailover({
+      enabled,
+      secondary: secondarySource.name,
+      after_minutes: minutes,
+    })
+  }
 
   const handleEdit = (name: string) => {
     const source = dataSources.find((ds) => ds.name === name)
@@ -225,6 +247,51 @@ export function DataSourceSettings() {
                   )}
                 </div>
               ))}
+
+              {secondarySource && (
+                <div className="border rounded-lg p-4 space-y-3">
+                  <div className="flex items-center justify-between">
+                    <div>
+                      <h3 className="font-medium">Failover</h3>
+                      <p className="text-sm text-muted-foreground">
+                        Discover resources from{' '}
+                        {DATA_SOURCE_TYPE_LABELS[secondarySource.type as DataSourceType] || secondarySource.name}{' '}
+                        while the active source is unreachable
+                      </p>
+                    </div>
+                    <Switch
+                      checked={failover?.config.enabled ?? false}
+                      onCheckedChange={(enabled) => handleFailoverChange(enabled)}
+                    />
+                  </div>
+                  <div className="flex items-center gap-2 text-sm">
+                    <Label htmlFor="failover-minutes">Switch after</Label>
+                    <Input
+                      id="failover-minutes"
+                      type="number"
+                      min={1}
+                      className="w-20"
+                      value={failoverMinutes}
+                      onChange={(e) => setFailoverMinutes(Math.max(1, Number(e.target.value) || 1))}
+                      onBlur={() => {
+                        if (failover?.config.enabled) {
+                          handleFailoverChange(true, failoverMinutes)
+                        }
+                      }}
+                    />
+                    <span className="text-muted-foreground">minutes</span>
+                  </div>
+                  {failover && failover.serving !== failover.primary && (
+                    <Alert variant="warning">
+                      <AlertDescription>
+                        {failover.primary} is unreachable; resources are being discovered from{' '}
+                        {failover.serving}
+                        {failover.last_error ? ` (${failover.last_error})` : ''}
+                      </AlertDescription>
+                    </Alert>
+                  )}
+                </div>
+              )}
             </>
           )}
         </div>
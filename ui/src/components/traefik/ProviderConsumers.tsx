@@ -0,0 +1,110 @@
+import { useEffect, useState } from 'react'
+import { Radio } from 'lucide-react'
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
+import { Badge } from '@/components/ui/badge'
+import { Skeleton } from '@/components/ui/skeleton'
+import { Alert, AlertDescription } from '@/components/ui/alert'
+import { traefikApi } from '@/services/api'
+import type { ProviderConsumers as ProviderConsumersData } from '@/types'
+
+const REFRESH_INTERVAL_MS = 30000
+
+export function ProviderConsumers() {
+  const [data, setData] = useState<ProviderConsumersData | null>(null)
+  const [error, setError] = useState<string | null>(null)
+
+  useEffect(() => {
+    let cancelled = false
+    const load = () => {
+      traefikApi
+        .getProviderConsumers()
+        .then((result) => {
+          if (!cancelled) {
+            setData(result)
+            setError(null)
+          }
+        })
+        .catch((err: Error) => {
+          if (!cancelled) setError(err.message)
+        })
+    }
+    load()
+    const timer = setInterval(load, REFRESH_INTERVAL_MS)
+    return () => {
+      cancelled = true
+      clearInterval(timer)
+    }
+  }, [])
+
+  if (!data && !error) {
+    return (
+      <Card>
+        <CardHeader className="pb-2">
+          <Skeleton className="h-4 w-32" />
+        </CardHeader>
+        <CardContent>
+          <Skeleton className="h-12 w-full" />
+        </CardContent>
+      </Card>
+    )
+  }
+
+  return (
+    <Card>
+      <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+        <CardTitle className="text-lg">Config Consumers</CardTitle>
+        <Radio className="h-4 w-4 text-muted-foreground" />
+      </CardHeader>
+      <CardContent className="space-y-3">
+        {error && (
+          <Alert variant="destructive">
+            <AlertDescription>{error}</AlertDescription>
+          </Alert>
+        )}
+        {data?.warnings.map((warning) => (
+          <Alert key={warning} variant="warning">
+            <AlertDescription>{warning}</AlertDescription>
+          </Alert>
+        ))}
+        {data?.consumers.map((client) => {
+          const active =
+            Date.now() - new Date(client.last_seen).getTime() <= data.window_seconds * 1000
+
+          return (
+            <div
+              key={`${client.client_ip}|${client.user_agent}`}
+              className="flex items-center justify-between rounded-lg border p-3"
+            >
+              <div>
+                <div className="font-medium">{client.client_ip}</div>
+                <div className="text-sm text-muted-foreground">
+                  {client.user_agent || 'No User-Agent'} · last seen{' '}
+                  {new Date(client.last_seen).toLocaleTimeString()}
+                </div>
+              </div>
+              <div className="flex flex-wrap items-center justify-end gap-2">
+                {Object.entries(client.endpoints).map(([endpoint, count]) => (
+                  <Badge key={endpoint} variant="secondary">
+                    {endpoint} × {count}
+                  </Badge>
+                ))}
+                {client.interval_seconds !== undefined && (
+                  <Badge variant="outline">every ~{Math.round(client.interval_seconds)}s</Badge>
+                )}
+                {client.last_error ? (
+                  <Badge variant="destructive">Failing</Badge>
+                ) : (
+                  !active && (
+                    <Badge variant="outline" className="text-muted-foreground">
+                      Inactive
+                    </Badge>
+                  )
+                )}
+              </div>
+            </div>
+          )
+        })}
+      </CardContent>
+    </Card>
+  )
+}
@@ -4,3 +4,4 @@ export { VersionInfo, VersionBadge } from './VersionInfo'
 export { RouterTabs } from './RouterTabs'
 export { ServiceTabs } from './ServiceTabs'
 export { MiddlewareTabs } from './MiddlewareTabs'
+export { ProviderConsumers } from './ProviderConsumers'
@@ -1,5 +1,12 @@
 import type {
   Resource,
+  MiddlewareOrder,
+  UpstreamMiddlewareRemoval,
+  UpdateUpstreamMiddlewaresResponse,
+  ResourceFailover,
+  ResourceFailoverResponse,
+  ResourceDeployment,
+  ResourceDeploymentResponse,
   Middleware,
   Service,
   DataSourceConfig,
@@ -8,8 +15,13 @@ import type {
   CataloguePlugin,
   CreateMiddlewareRequest,
   UpdateMiddlewareRequest,
+  MiddlewareType,
+  MiddlewareValidation,
+  EntrypointMiddleware,
+  DefaultChain,
   CreateServiceRequest,
   UpdateServiceRequest,
+  ServiceOverride,
   AssignMiddlewareRequest,
   AssignExternalMiddlewareRequest,
   ExternalMiddleware,
@@ -19,12 +31,32 @@ import type {
   TCPConfig,
   HeadersConfig,
   MTLSWhitelistConfigRequest,
+  PendingChangesResponse,
+  ResourceReviewConfig,
+  ResourceRun,
   TestConnectionResponse,
+  DataSourceFailover,
+  FailoverStatus,
+  SetupState,
+  SetupDetection,
+  SetupDataSourceRequest,
+  SetupProviderCheck,
   PluginInstallRequest,
+  PluginSandboxRequest,
+  PluginSandboxResult,
+  StaticConfigLintReport,
   PluginRemoveRequest,
   TraefikOverview,
   TraefikVersion,
   TraefikEntrypoint,
+  ProviderConsumers,
+  TransformLog,
+  ConfigDiff,
+  ChaosFaults,
+  ChaosStatus,
+  RouteSimulation,
+  RouteQuery,
+  DeprecationScan,
   HTTPRouter,
   TCPRouter,
   UDPRouter,
@@ -42,45 +74,154 @@ import type {
   MTLSClient,
   CreateCARequest,
   CreateClientRequest,
+  MTLSClientImport,
+  MTLSClientImportResponse,
+  MTLSClientCert,
   PluginCheckResponse,
+  MTLSPreflightResult,
   MTLSMiddlewareConfig,
   SecurityConfig,
   SecureHeadersConfig,
   DuplicateCheckResult,
   DuplicateCheckRequest,
-  UpdateResourceSecurityRequest,
+  UpdateResourceTLSHardeningRequest,
+  UpdateResourceSecureHeadersRequest,
+  SecureHeadersPreset,
+  SecureHeadersPresetRequest,
+  TLSCompatReport,
+  TLSHardeningProfile,
+  TLSHardeningMode,
+  AccessMatrix,
+  Inventory,
+  BackupObject,
+  S3Backups,
+  LocalBackups,
+  RuntimeStats,
+  ReloadReport,
+  APIToken,
+  CreateAPITokenRequest,
+  CreatedAPIToken,
+  User,
+  UserRole,
+  Webhook,
+  WebhookList,
+  SaveWebhookRequest,
+  WebhookDelivery,
+  Environment,
+  SaveEnvironmentRequest,
+  EnvironmentTags,
 } from '@/types'
 
 const API_BASE = '/api'
 
+// localStorage key of the API token sent when API authentication is on
+const API_TOKEN_STORAGE_KEY = 'mm_api_token'
+
+export function getApiToken(): string | null {
+  return localStorage.getItem(API_TOKEN_STORAGE_KEY)
+}
+
+// setApiToken stores the token sent with every request; null forgets it
+export function setApiToken(token: string | null) {
+  if (token) {
+    localStorage.setItem(API_TOKEN_STORAGE_KEY, token)
+  } else {
+    localStorage.removeItem(API_TOKEN_STORAGE_KEY)
+  }
+}
+
+// tokenPrompt asks the user for an API token after a 401 and resolves to
+// the token, or null when the user cancels
+type TokenPrompt = (reason: string) => Promise<string | null>
+
+let tokenPrompt: TokenPrompt | null = null
+// pendingTokenPrompt is shared by the requests that fail while it is open
+let pendingTokenPrompt: Promise<string | null> | null = null
+
+// setTokenPrompt registers the dialog shown when a request needs a token
+export function setTokenPrompt(prompt: TokenPrompt | null) {
+  tokenPrompt = prompt
+}
+
+function promptForToken(reason: string): Promise<string | null> {
+  if (!tokenPrompt) return Promise.resolve(null)
+  if (!pendingTokenPrompt) {
+    pendingTokenPrompt = tokenPrompt(reason).finally(() => {
+      pendingTokenPrompt = null
+    })
+  }
+  return pendingTokenPrompt
+}
+
+// Per-field validation error returned with 422 responses
+export interface ApiFieldError {
+  field: string
+  code: string
+  message: string
+}
+
 // Custom API Error class
 export class ApiError extends Error {
   status: number
   details?: unknown
-
-  constructor(message: string, status: number, details?: unknown) {
+  code?: string
+  field?: string
+  hint?: string
+  errors?: ApiFieldError[]
+
+  constructor(
+    message: string,
+    status: number,
+    details?: unknown,
+    extra?: { code?: string; field?: string; hint?: string; errors?: ApiFieldError[] }
+  ) {
     super(message)
     this.name = 'ApiError'
     this.status = status
     this.details = details
+    this.code = extra?.code
+    this.field = extra?.field
+    this.hint = extra?.hint
+    this.errors = extra?.errors
   }
 }
 
-// Generic request function with error handling
+// Generic request function with error handling. A 401 asks for an API
+// token and retries the request once with it.
 async function request<T>(
   url: string,
-  options?: RequestInit
+  options?: RequestInit,
+  retried = false
 ): Promise<T> {
+  const token = getApiToken()
   const response = await fetch(url, {
     ...options,
     headers: {
       'Content-Type': 'application/json',
+      ...(token ? { Authorization: `Bearer ${token}` } : {}),
       ...options?.headers,
     },
   })
 
+  if (response.status === 401 && !retried) {
+    const reason = token ? 'The saved API token was rejected.' : 'The API requires a token.'
+    const entered = await promptForToken(reason)
+    if (entered) {
+      setApiToken(entered)
+      return request<T>(url, options, true)
+    }
+  }
+
   if (!response.ok) {
-    let errorData: { message?: string; error?: string; details?: unknown } = {}
+    let errorData: {
+      message?: string
+      error?: string
+      details?: unknown
+      code?: string
+      field?: string
+      hint?: string
+      errors?: ApiFieldError[]
+    } = {}
     try {
       errorData = await response.json()
     } catch {
@@ -89,7 +230,13 @@ async function request<T>(
     throw new ApiError(
       errorData.message || errorData.error || `Request failed: ${response.statusText}`,
       response.status,
-      errorData.details
+      errorData.details,
+      {
+        code: errorData.code,
+        field: errorData.field,
+        hint: errorData.hint,
+        errors: errorData.errors,
+      }
     )
   }
 
@@ -107,11 +254,35 @@ async function request<T>(
   return {} as T
 }
 
+// resourceVersions holds the version of each resource as last read
+const resourceVersions = new Map<string, number>()
+
+function rememberVersions<T extends Resource | Resource[]>(data: T): T {
+  for (const resource of Array.isArray(data) ? data : [data]) {
+    if (resource?.version !== undefined) resourceVersions.set(resource.id, resource.version)
+  }
+  return data
+}
+
+// updateResource sends an edit of a resource with the version last read as
+// If-Match; the API answers 409 when someone else changed it since
+async function updateResource<T>(resourceId: string, path: string, body: unknown): Promise<T> {
+  const version = resourceVersions.get(resourceId)
+  const result = await request<T>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/${path}`, {
+    method: 'PUT',
+    headers: version !== undefined ? { 'If-Match': `"${version}"` } : undefined,
+    body: JSON.stringify(body),
+  })
+  if (version !== undefined) resourceVersions.set(resourceId, version + 1)
+  return result
+}
+
 // Resource API
 export const resourceApi = {
-  getAll: () => request<Resource[]>(`${API_BASE}/resources`),
+  getAll: () => request<Resource[]>(`${API_BASE}/resources`).then(rememberVersions),
 
-  getById: (id: string) => request<Resource>(`${API_BASE}/resources/${encodeURIComponent(id)}`),
+  getById: (id: string) =>
+    request<Resource>(`${API_BASE}/resources/${encodeURIComponent(id)}`).then(rememberVersions),
 
   delete: (id: string) =>
     request<void>(`${API_BASE}/resources/${encodeURIComponent(id)}`, {
@@ -124,6 +295,36 @@ export const resourceApi = {
       body: JSON.stringify({ ids }),
     }),
 
+  // Review mode
+  getPendingChanges: () => request<PendingChangesResponse>(`${API_BASE}/resources/pending`),
+
+  approvePendingChanges: (ids?: string[]) =>
+    request<{ applied: number }>(`${API_BASE}/resources/pending/approve`, {
+      method: 'POST',
+      body: JSON.stringify({ ids }),
+    }),
+
+  setReviewConfig: (config: ResourceReviewConfig) =>
+    request<ResourceReviewConfig>(`${API_BASE}/resources/review`, {
+      method: 'PUT',
+      body: JSON.stringify(config),
+    }),
+
+  setPinned: (resourceId: string, pinned: boolean) =>
+    updateResource<{ id: string; pinned: boolean }>(resourceId, 'pin', { pinned }),
+
+  // Manual sync with the data source
+  sync: () => request<ResourceRun>(`${API_BASE}/resources/sync`, { method: 'POST' }),
+
+  resync: (id: string) =>
+    request<ResourceRun>(`${API_BASE}/resources/${encodeURIComponent(id)}/resync`, { method: 'POST' }),
+
+  // Resource watcher run history, newest first
+  getRuns: (limit?: number) =>
+    request<{ runs: ResourceRun[] }>(
+      `${API_BASE}/system/watchers/resource/runs${limit ? `?limit=${limit}` : ''}`
+    ),
+
   // Middleware assignment
   assignMiddleware: (resourceId: string, data: AssignMiddlewareRequest) =>
     request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/middlewares`, {
@@ -178,57 +379,81 @@ export const resourceApi = {
 
   // Configuration updates
   updateHTTPConfig: (resourceId: string, config: HTTPConfig) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/http`, {
-      method: 'PUT',
-      body: JSON.stringify(config),
-    }),
+    updateResource<void>(resourceId, 'config/http', config),
 
   updateTLSConfig: (resourceId: string, config: TLSConfig) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/tls`, {
-      method: 'PUT',
-      body: JSON.stringify(config),
-    }),
+    updateResource<void>(resourceId, 'config/tls', config),
 
   updateTCPConfig: (resourceId: string, config: TCPConfig) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/tcp`, {
-      method: 'PUT',
-      body: JSON.stringify(config),
-    }),
+    updateResource<void>(resourceId, 'config/tcp', config),
 
   updateHeadersConfig: (resourceId: string, config: HeadersConfig) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/headers`, {
-      method: 'PUT',
-      body: JSON.stringify(config),
-    }),
+    updateResource<void>(resourceId, 'config/headers', config),
 
   updateRouterPriority: (resourceId: string, priority: number) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/priority`, {
-      method: 'PUT',
-      body: JSON.stringify({ router_priority: priority }),
-    }),
+    updateResource<void>(resourceId, 'config/priority', { router_priority: priority }),
 
   updateMTLSConfig: (resourceId: string, mtlsEnabled: boolean) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/mtls`, {
-      method: 'PUT',
-      body: JSON.stringify({ mtls_enabled: mtlsEnabled }),
-    }),
+    updateResource<void>(resourceId, 'config/mtls', { mtls_enabled: mtlsEnabled }),
 
   updateMTLSWhitelistConfig: (resourceId: string, config: MTLSWhitelistConfigRequest) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/mtlswhitelist`, {
-      method: 'PUT',
-      body: JSON.stringify(config),
+    updateResource<void>(resourceId, 'config/mtlswhitelist', config),
+
+  updateMTLSExemptions: (resourceId: string, paths: string[]) =>
+    updateResource<{ id: string; mtls_exempt_paths: string[] }>(resourceId, 'config/mtls/exemptions', { paths }),
+
+  updateTLSHardeningConfig: (resourceId: string, enabled: boolean, profile?: TLSHardeningProfile) =>
+    updateResource<void>(resourceId, 'config/tls-hardening', { enabled, profile } as UpdateResourceTLSHardeningRequest),
+
+  updateSecureHeadersConfig: (
+    resourceId: string,
+    enabled: boolean,
+    options: Omit<UpdateResourceSecureHeadersRequest, 'enabled'> = {}
+  ) =>
+    updateResource<void>(resourceId, 'config/secure-headers', { enabled, ...options } as UpdateResourceSecureHeadersRequest),
+
+  updateDefaultChainConfig: (resourceId: string, excluded: boolean) =>
+    updateResource<void>(resourceId, 'config/default-chain', { excluded }),
+
+  updateMiddlewareOrder: (resourceId: string, order: MiddlewareOrder) =>
+    updateResource<void>(resourceId, 'config/middleware-order', order),
+
+  updateUpstreamMiddlewares: (resourceId: string, removals: UpstreamMiddlewareRemoval[]) =>
+    updateResource<UpdateUpstreamMiddlewaresResponse>(resourceId, 'config/upstream-middlewares', { removals }),
+
+  getFailover: (resourceId: string) =>
+    request<ResourceFailoverResponse>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/failover`),
+
+  setFailover: (resourceId: string, failover: ResourceFailover) =>
+    updateResource<ResourceFailover>(resourceId, 'failover', failover),
+
+  removeFailover: (resourceId: string) =>
+    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/failover`, {
+      method: 'DELETE',
     }),
 
-  updateTLSHardeningConfig: (resourceId: string, enabled: boolean) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/tls-hardening`, {
-      method: 'PUT',
-      body: JSON.stringify({ enabled } as UpdateResourceSecurityRequest),
+  getDeployment: (resourceId: string) =>
+    request<ResourceDeploymentResponse>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/deployment`),
+
+  startDeployment: (resourceId: string, deployment: ResourceDeployment) =>
+    request<ResourceDeploymentResponse>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/deployment`, {
+      method: 'POST',
+      body: JSON.stringify(deployment),
     }),
 
-  updateSecureHeadersConfig: (resourceId: string, enabled: boolean) =>
-    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/config/secure-headers`, {
-      method: 'PUT',
-      body: JSON.stringify({ enabled } as UpdateResourceSecurityRequest),
+  switchDeployment: (resourceId: string) =>
+    request<ResourceDeploymentResponse>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/deployment/switch`, {
+      method: 'POST',
+    }),
+
+  rollbackDeployment: (resourceId: string) =>
+    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/deployment/rollback`, {
+      method: 'POST',
+    }),
+
+  removeDeployment: (resourceId: string) =>
+    request<void>(`${API_BASE}/resources/${encodeURIComponent(resourceId)}/deployment`, {
+      method: 'DELETE',
     }),
 }
 
@@ -238,22 +463,53 @@ export const middlewareApi = {
 
   getById: (id: string) => request<Middleware>(`${API_BASE}/middlewares/${encodeURIComponent(id)}`),
 
-  create: (data: CreateMiddlewareRequest) =>
-    request<Middleware>(`${API_BASE}/middlewares`, {
+  // force saves a config that does not match the Traefik schema of its type
+  create: (data: CreateMiddlewareRequest, force = false) =>
+    request<Middleware>(`${API_BASE}/middlewares${force ? '?force=true' : ''}`, {
       method: 'POST',
       body: JSON.stringify(data),
     }),
 
-  update: (id: string, data: UpdateMiddlewareRequest) =>
-    request<Middleware>(`${API_BASE}/middlewares/${encodeURIComponent(id)}`, {
+  update: (id: string, data: UpdateMiddlewareRequest, force = false) =>
+    request<Middleware>(`${API_BASE}/middlewares/${encodeURIComponent(id)}${force ? '?force=true' : ''}`, {
       method: 'PUT',
       body: JSON.stringify(data),
     }),
 
+  validate: (type: MiddlewareType, config: Record<string, unknown>) =>
+    request<MiddlewareValidation>(`${API_BASE}/middlewares/validate`, {
+      method: 'POST',
+      body: JSON.stringify({ type, config }),
+    }),
+
   delete: (id: string) =>
     request<void>(`${API_BASE}/middlewares/${encodeURIComponent(id)}`, {
       method: 'DELETE',
     }),
+
+  // Middlewares attached to every router on an entrypoint
+  getEntrypointMiddlewares: () => request<EntrypointMiddleware[]>(`${API_BASE}/entrypoints/middlewares`),
+
+  assignToEntrypoint: (entrypoint: string, middlewareId: string, priority?: number) =>
+    request<EntrypointMiddleware>(`${API_BASE}/entrypoints/${encodeURIComponent(entrypoint)}/middlewares`, {
+      method: 'POST',
+      body: JSON.stringify({ middleware_id: middlewareId, priority }),
+    }),
+
+  removeFromEntrypoint: (entrypoint: string, middlewareId: string) =>
+    request<void>(
+      `${API_BASE}/entrypoints/${encodeURIComponent(entrypoint)}/middlewares/${encodeURIComponent(middlewareId)}`,
+      { method: 'DELETE' }
+    ),
+
+  // Global default chain put in front of every router not excluded
+  getDefaultChain: () => request<DefaultChain>(`${API_BASE}/default-chain`),
+
+  updateDefaultChain: (middlewares: { middleware_id: string; priority?: number }[]) =>
+    request<DefaultChain>(`${API_BASE}/default-chain`, {
+      method: 'PUT',
+      body: JSON.stringify({ middlewares }),
+    }),
 }
 
 // Service API
@@ -278,6 +534,19 @@ export const serviceApi = {
     request<void>(`${API_BASE}/services/${encodeURIComponent(id)}`, {
       method: 'DELETE',
     }),
+
+  getOverrides: () => request<ServiceOverride[]>(`${API_BASE}/service-overrides`),
+
+  setOverride: (serviceName: string, override: Omit<ServiceOverride, 'service_name'>) =>
+    request<ServiceOverride>(`${API_BASE}/service-overrides/${encodeURIComponent(serviceName)}`, {
+      method: 'PUT',
+      body: JSON.stringify(override),
+    }),
+
+  deleteOverride: (serviceName: string) =>
+    request<void>(`${API_BASE}/service-overrides/${encodeURIComponent(serviceName)}`, {
+      method: 'DELETE',
+    }),
 }
 
 // Data Source API response types (backend format)
@@ -320,6 +589,36 @@ export const dataSourceApi = {
     request<TestConnectionResponse>(`${API_BASE}/datasource/${encodeURIComponent(name)}/test`, {
       method: 'POST',
     }),
+
+  getFailover: () => request<FailoverStatus>(`${API_BASE}/datasource/failover`),
+
+  setFailover: (config: DataSourceFailover) =>
+    request<FailoverStatus>(`${API_BASE}/datasource/failover`, {
+      method: 'PUT',
+      body: JSON.stringify(config),
+    }),
+}
+
+// Setup API - first-run wizard
+export const setupApi = {
+  getState: () => request<SetupState>(`${API_BASE}/setup`),
+
+  detect: (urls?: { pangolin_urls?: string[]; traefik_urls?: string[] }) =>
+    request<SetupDetection>(`${API_BASE}/setup/detect`, {
+      method: 'POST',
+      body: JSON.stringify(urls ?? {}),
+    }),
+
+  configureDataSource: (data: SetupDataSourceRequest) =>
+    request<SetupState>(`${API_BASE}/setup/datasource`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    }),
+
+  checkProvider: () =>
+    request<SetupProviderCheck>(`${API_BASE}/setup/provider-check`, { method: 'POST' }),
+
+  complete: () => request<SetupState>(`${API_BASE}/setup/complete`, { method: 'POST' }),
 }
 
 // Plugin API - fetches plugins from Traefik API
@@ -343,6 +642,13 @@ export const pluginApi = {
       }
     ),
 
+  // Load the plugin in a sandbox and run a synthetic request through it
+  sandbox: (data: PluginSandboxRequest) =>
+    request<PluginSandboxResult>(`${API_BASE}/plugins/sandbox`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    }),
+
   remove: (data: PluginRemoveRequest) =>
     request<{ message: string; pluginKey: string; moduleName: string }>(
       `${API_BASE}/plugins/remove`,
@@ -359,6 +665,15 @@ export const pluginApi = {
       method: 'PUT',
       body: JSON.stringify({ path }),
     }),
+
+  // Lint the static config file, or unsaved content when given
+  lintStaticConfig: (content?: string) =>
+    content === undefined
+      ? request<StaticConfigLintReport>(`${API_BASE}/traefik/static-config/lint`)
+      : request<StaticConfigLintReport>(`${API_BASE}/traefik/static-config/lint`, {
+          method: 'POST',
+          body: JSON.stringify({ content }),
+        }),
 }
 
 // Traefik API - Direct access to Traefik data following Mantrae patterns
@@ -372,6 +687,36 @@ export const traefikApi = {
   // Get Traefik entrypoints
   getEntrypoints: () => request<TraefikEntrypoint[]>(`${API_BASE}/traefik/entrypoints`),
 
+  // Get the clients polling the merged config endpoint
+  getProviderConsumers: () =>
+    request<ProviderConsumers>(`${API_BASE}/traefik-config/consumers`),
+
+  // Get what the latest merge changed in the upstream config
+  getTransformLog: () => request<TransformLog>(`${API_BASE}/traefik-config/transforms`),
+
+  // Preview the config the next poll would get against the last served one, or a saved snapshot
+  getConfigDiff: () => request<ConfigDiff>(`${API_BASE}/traefik-config/diff`),
+
+  diffConfigSnapshot: (snapshot: unknown) =>
+    request<ConfigDiff>(`${API_BASE}/traefik-config/diff`, {
+      method: 'POST',
+      body: JSON.stringify(snapshot),
+    }),
+
+  // Chaos mode: faults injected into the config endpoint on test instances
+  getChaos: () => request<ChaosStatus>(`${API_BASE}/traefik-config/chaos`),
+
+  setChaos: (faults: Pick<ChaosFaults, 'delay_ms' | 'stale' | 'truncate' | 'probability' | 'duration_seconds'>) =>
+    request<ChaosFaults>(`${API_BASE}/traefik-config/chaos`, {
+      method: 'PUT',
+      body: JSON.stringify(faults),
+    }),
+
+  clearChaos: () =>
+    request<{ message: string }>(`${API_BASE}/traefik-config/chaos`, {
+      method: 'DELETE',
+    }),
+
   // Get routers with optional protocol filter
   getRouters: (type?: ProtocolType) => {
     const params = type ? `?type=${type}` : ''
@@ -431,6 +776,21 @@ export const traefikApi = {
 
   // Get full Traefik data in one request
   getFullData: () => request<FullTraefikData>(`${API_BASE}/traefik/data`),
+
+  // Simulate which merged router serves a URL, without asking Traefik
+  simulateRoute: (query: RouteQuery) => {
+    const params = new URLSearchParams({ url: query.url })
+    if (query.method) params.set('method', query.method)
+    Object.entries(query.headers ?? {}).forEach(([name, value]) => params.append('header', `${name}: ${value}`))
+    if (query.client_ip) params.set('client_ip', query.client_ip)
+    if (query.entrypoint) params.set('entrypoint', query.entrypoint)
+    return request<RouteSimulation>(`${API_BASE}/tools/route?${params}`)
+  },
+
+  getDeprecations: () => request<DeprecationScan>(`${API_BASE}/traefik/deprecations`),
+
+  fixDeprecations: () =>
+    request<DeprecationScan>(`${API_BASE}/traefik/deprecations/fix`, { method: 'POST' }),
 }
 
 // mTLS API - Certificate Authority and client certificate management
@@ -480,6 +840,19 @@ export const mtlsApi = {
       body: JSON.stringify(data),
     }),
 
+  // Import existing client certificates (PEM) without reissuing them
+  importClients: (clients: MTLSClientImport[]) =>
+    request<MTLSClientImportResponse>(`${API_BASE}/mtls/clients/import`, {
+      method: 'POST',
+      body: JSON.stringify({ clients }),
+    }),
+
+  // Export the certificates of every client that is not revoked
+  exportClients: () => request<MTLSClientCert[]>(`${API_BASE}/mtls/clients/export`),
+
+  // Get download URL for the non-revoked client certificates as one PEM file
+  getClientsPEMUrl: () => `${API_BASE}/mtls/clients/export?format=pem`,
+
   // Get a specific client certificate
   getClient: (id: string) =>
     request<MTLSClient>(`${API_BASE}/mtls/clients/${encodeURIComponent(id)}`),
@@ -503,6 +876,10 @@ export const mtlsApi = {
   // Check if mtlswhitelist plugin is installed
   checkPlugin: () => request<PluginCheckResponse>(`${API_BASE}/mtls/plugin/check`),
 
+  // Check whether mTLS can be enabled on a resource without breaking its handshake
+  preflight: (resourceId: string) =>
+    request<MTLSPreflightResult>(`${API_BASE}/mtls/preflight/${encodeURIComponent(resourceId)}`),
+
   // Get middleware plugin configuration
   getMiddlewareConfig: () => request<MTLSMiddlewareConfig>(`${API_BASE}/mtls/middleware/config`),
 
@@ -530,6 +907,18 @@ export const securityApi = {
       method: 'PUT',
     }),
 
+  setTLSHardeningMode: (mode: TLSHardeningMode) =>
+    request<{ message: string; mode: TLSHardeningMode }>(`${API_BASE}/security/tls-hardening/mode`, {
+      method: 'PUT',
+      body: JSON.stringify({ mode }),
+    }),
+
+  // Which clients a hardening profile would lock out
+  getTLSCompatibility: (profile: TLSHardeningProfile = 'hardened', keyType: 'rsa' | 'ecdsa' = 'rsa') =>
+    request<TLSCompatReport>(
+      `${API_BASE}/security/tls-hardening/compatibility?profile=${profile}&key_type=${keyType}`
+    ),
+
   // Secure Headers
   enableSecureHeaders: () =>
     request<{ message: string; enabled: boolean }>(`${API_BASE}/security/secure-headers/enable`, {
@@ -547,6 +936,33 @@ export const securityApi = {
       body: JSON.stringify(config),
     }),
 
+  // Secure headers presets
+  getSecureHeadersPresets: () =>
+    request<SecureHeadersPreset[]>(`${API_BASE}/security/secure-headers/presets`),
+
+  createSecureHeadersPreset: (data: SecureHeadersPresetRequest) =>
+    request<SecureHeadersPreset>(`${API_BASE}/security/secure-headers/presets`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    }),
+
+  updateSecureHeadersPreset: (id: string, data: SecureHeadersPresetRequest) =>
+    request<SecureHeadersPreset>(`${API_BASE}/security/secure-headers/presets/${encodeURIComponent(id)}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    }),
+
+  deleteSecureHeadersPreset: (id: string) =>
+    request<{ message: string }>(`${API_BASE}/security/secure-headers/presets/${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+    }),
+
+  applySecureHeadersPreset: (id: string) =>
+    request<{ message: string; secure_headers: SecureHeadersConfig }>(
+      `${API_BASE}/security/secure-headers/presets/${encodeURIComponent(id)}/apply`,
+      { method: 'POST' }
+    ),
+
   // Duplicate Detection
   checkDuplicates: (req: DuplicateCheckRequest) =>
     request<DuplicateCheckResult>(`${API_BASE}/security/check-duplicates`, {
@@ -555,6 +971,157 @@ export const securityApi = {
     }),
 }
 
+// Reports
+export const reportApi = {
+  getAccessMatrix: () => request<AccessMatrix>(`${API_BASE}/reports/access-matrix`),
+
+  accessMatrixCSVUrl: () => `${API_BASE}/reports/access-matrix?format=csv`,
+
+  getInventory: () => request<Inventory>(`${API_BASE}/reports/inventory`),
+}
+
+export const backupApi = {
+  getBackups: () => request<LocalBackups>(`${API_BASE}/backups`),
+
+  createBackup: () =>
+    request<{ objects: BackupObject[] }>(`${API_BASE}/backup`, { method: 'POST' }),
+
+  restoreBackup: (id: string, passphrase?: string) =>
+    request<{ message: string; id: string }>(
+      `${API_BASE}/backups/${encodeURIComponent(id)}/restore`,
+      {
+        method: 'POST',
+        body: JSON.stringify({ passphrase }),
+      }
+    ),
+
+  getS3Backups: () => request<S3Backups>(`${API_BASE}/backups/s3`),
+
+  runS3Backup: () =>
+    request<{ objects: BackupObject[] }>(`${API_BASE}/backups/s3/run`, { method: 'POST' }),
+
+  restoreS3Backup: (key: string, passphrase?: string) =>
+    request<{ message: string; key: string }>(`${API_BASE}/backups/s3/restore`, {
+      method: 'POST',
+      body: JSON.stringify({ key, passphrase }),
+    }),
+}
+
+export const systemApi = {
+  getRuntime: () => request<RuntimeStats>(`${API_BASE}/system/runtime`),
+
+  reload: () => request<ReloadReport>(`${API_BASE}/system/reload`, { method: 'POST' }),
+}
+
+export const tokenApi = {
+  getTokens: () => request<APIToken[]>(`${API_BASE}/tokens`),
+
+  createToken: (data: CreateAPITokenRequest) =>
+    request<CreatedAPIToken>(`${API_BASE}/tokens`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    }),
+
+  deleteToken: (id: string) =>
+    request<{ message: string }>(`${API_BASE}/tokens/${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+    }),
+}
+
+// Users (admin only once API auth is on)
+export const userApi = {
+  getUsers: () => request<User[]>(`${API_BASE}/users`),
+
+  createUser: (data: { username: string; role: UserRole }) =>
+    request<User>(`${API_BASE}/users`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    }),
+
+  updateRole: (id: string, role: UserRole) =>
+    request<User>(`${API_BASE}/users/${encodeURIComponent(id)}`, {
+      method: 'PUT',
+      body: JSON.stringify({ role }),
+    }),
+
+  deleteUser: (id: string) =>
+    request<{ message: string }>(`${API_BASE}/users/${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+    }),
+}
+
+// Webhook API
+export const webhookApi = {
+  getWebhooks: () => request<WebhookList>(`${API_BASE}/webhooks`),
+
+  createWebhook: (data: SaveWebhookRequest) =>
+    request<Webhook>(`${API_BASE}/webhooks`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    }),
+
+  updateWebhook: (id: string, data: SaveWebhookRequest) =>
+    request<Webhook>(`${API_BASE}/webhooks/${encodeURIComponent(id)}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    }),
+
+  deleteWebhook: (id: string) =>
+    request<{ message: string }>(`${API_BASE}/webhooks/${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+    }),
+
+  testWebhook: (id: string) =>
+    request<WebhookDelivery>(`${API_BASE}/webhooks/${encodeURIComponent(id)}/test`, {
+      method: 'POST',
+    }),
+
+  getDeliveries: (id?: string, limit?: number) => {
+    const path = id ? `/webhooks/${encodeURIComponent(id)}/deliveries` : '/webhooks/deliveries'
+    const query = limit ? `?limit=${limit}` : ''
+    return request<WebhookDelivery[]>(`${API_BASE}${path}${query}`)
+  },
+}
+
+// Environment API
+export const environmentApi = {
+  getEnvironments: () => request<Environment[]>(`${API_BASE}/environments`),
+
+  createEnvironment: (data: SaveEnvironmentRequest) =>
+    request<Environment>(`${API_BASE}/environments`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    }),
+
+  updateEnvironment: (name: string, data: Omit<SaveEnvironmentRequest, 'name'>) =>
+    request<Environment>(`${API_BASE}/environments/${encodeURIComponent(name)}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    }),
+
+  deleteEnvironment: (name: string) =>
+    request<{ message: string }>(`${API_BASE}/environments/${encodeURIComponent(name)}`, {
+      method: 'DELETE',
+    }),
+
+  getTags: () => request<EnvironmentTags[]>(`${API_BASE}/environments/tags`),
+
+  getResourceEnvironments: (id: string) =>
+    request<EnvironmentTags>(`${API_BASE}/resources/${encodeURIComponent(id)}/environments`),
+
+  setResourceEnvironments: (id: string, environments: string[]) =>
+    updateResource<EnvironmentTags>(id, 'environments', { environments }),
+
+  getMiddlewareEnvironments: (id: string) =>
+    request<EnvironmentTags>(`${API_BASE}/middlewares/${encodeURIComponent(id)}/environments`),
+
+  setMiddlewareEnvironments: (id: string, environments: string[]) =>
+    request<EnvironmentTags>(`${API_BASE}/middlewares/${encodeURIComponent(id)}/environments`, {
+      method: 'PUT',
+      body: JSON.stringify({ environments }),
+    }),
+}
+
 // Health check
 export const healthApi = {
   check: () => request<{ status: string }>('/health'),
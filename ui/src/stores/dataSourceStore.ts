@@ -1,11 +1,12 @@
 import { create } from 'zustand'
 import { dataSourceApi } from '@/services/api'
-import type { DataSourceInfo, DataSourceConfig } from '@/types'
+import type { DataSourceInfo, DataSourceConfig, DataSourceFailover, FailoverStatus } from '@/types'
 
 interface DataSourceState {
   // Data
   dataSources: DataSourceInfo[]
   activeDataSource: DataSourceConfig | null
+  failover: FailoverStatus | null
 
   // Loading states
   loading: boolean
@@ -23,6 +24,8 @@ interface DataSourceState {
   setActiveDataSource: (name: string) => Promise<boolean>
   updateDataSource: (name: string, config: Partial<DataSourceConfig>) => Promise<boolean>
   testConnection: (name: string) => Promise<boolean>
+  fetchFailover: () => Promise<void>
+  setFailover: (config: DataSourceFailover) => Promise<boolean>
   clearError: () => void
   clearTestResult: () => void
 }
@@ -31,6 +34,7 @@ export const useDataSourceStore = create<DataSourceState>((set) => ({
   // Initial state
   dataSources: [],
   activeDataSource: null,
+  failover: null,
   loading: false,
   testing: false,
   error: null,
@@ -139,6 +143,29 @@ export const useDataSourceStore = create<DataSourceState>((set) => ({
     }
   },
 
+  // Fetch failover config and whether discovery is on the secondary
+  fetchFailover: async () => {
+    try {
+      const failover = await dataSourceApi.getFailover()
+      set({ failover })
+    } catch (err) {
+      set({ error: err instanceof Error ? err.message : 'Failed to load failover settings' })
+    }
+  },
+
+  // Update failover config
+  setFailover: async (config) => {
+    set({ error: null })
+    try {
+      const failover = await dataSourceApi.setFailover(config)
+      set({ failover })
+      return true
+    } catch (err) {
+      set({ error: err instanceof Error ? err.message : 'Failed to update failover settings' })
+      return false
+    }
+  },
+
   // Clear error
   clearError: () => set({ error: null }),
 
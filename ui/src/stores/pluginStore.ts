@@ -1,6 +1,6 @@
 import { create } from 'zustand'
 import { pluginApi } from '@/services/api'
-import type { Plugin, PluginUsage, CataloguePlugin } from '@/types'
+import type { Plugin, PluginUsage, CataloguePlugin, StaticConfigLintReport } from '@/types'
 
 function derivePluginKey(input?: string): string {
   if (!input) return ''
@@ -59,6 +59,7 @@ interface PluginState {
   plugins: Plugin[]
   cataloguePlugins: CataloguePlugin[]
   configPath: string
+  staticConfigLint: StaticConfigLintReport | null
   selectedPlugin: Plugin | null
   selectedCataloguePlugin: CataloguePlugin | null
 
@@ -67,6 +68,7 @@ interface PluginState {
   loadingCatalogue: boolean
   installing: boolean
   removing: boolean
+  linting: boolean
 
   // Error state
   error: string | null
@@ -83,6 +85,7 @@ interface PluginState {
   installPlugin: (moduleName: string, version?: string) => Promise<boolean>
   removePlugin: (moduleName: string) => Promise<boolean>
   updateConfigPath: (path: string) => Promise<boolean>
+  lintStaticConfig: () => Promise<void>
   selectPlugin: (plugin: Plugin | null) => void
   selectCataloguePlugin: (plugin: CataloguePlugin | null) => void
   clearError: () => void
@@ -94,12 +97,14 @@ export const usePluginStore = create<PluginState>((set, get) => ({
   plugins: [],
   cataloguePlugins: [],
   configPath: '/etc/traefik/traefik.yml',
+  staticConfigLint: null,
   selectedPlugin: null,
   selectedCataloguePlugin: null,
   loading: false,
   loadingCatalogue: false,
   installing: false,
   removing: false,
+  linting: false,
   error: null,
   showRestartWarning: false,
   lastInstalledPlugin: null,
@@ -256,7 +261,7 @@ export const usePluginStore = create<PluginState>((set, get) => ({
     set({ error: null })
     try {
       await pluginApi.updateConfigPath(path)
-      set({ configPath: path })
+      set({ configPath: path, staticConfigLint: null })
       return true
     } catch (err) {
       set({
@@ -266,6 +271,21 @@ export const usePluginStore = create<PluginState>((set, get) => ({
     }
   },
 
+  // Lint the static config file at the configured path
+  lintStaticConfig: async () => {
+    set({ linting: true, error: null })
+    try {
+      const staticConfigLint = await pluginApi.lintStaticConfig()
+      set({ staticConfigLint, linting: false })
+    } catch (err) {
+      set({
+        error: err instanceof Error ? err.message : 'Failed to lint static config',
+        staticConfigLint: null,
+        linting: false,
+      })
+    }
+  },
+
   // Select a plugin for viewing details
   selectPlugin: (plugin) => {
     set({ selectedPlugin: plugin })
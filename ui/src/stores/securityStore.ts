@@ -45,6 +45,7 @@ export const useSecurityStore = create<SecurityStore>((set, get) => ({
         config: {
           id: 1,
           tls_hardening_enabled: false,
+          tls_hardening_mode: 'per_resource',
           secure_headers_enabled: false,
           secure_headers: defaultSecureHeaders,
         },
@@ -9,6 +9,14 @@ export interface DataSourceConfig {
     password: string
   }
   isActive?: boolean
+  fallback_urls?: string[]
+  discovery_hosts?: string[]
+  disable_auto_persist?: boolean
+  headers?: Record<string, string>
+  ca_file?: string
+  client_cert_file?: string
+  client_key_file?: string
+  proxy_url?: string
 }
 
 export interface DataSourceInfo {
@@ -38,8 +46,265 @@ export interface TestConnectionResponse {
   error?: string
 }
 
+// Resource discovery falls back to the secondary while the active source is unreachable
+export interface DataSourceFailover {
+  enabled: boolean
+  secondary: string
+  after_minutes?: number
+}
+
+export interface FailoverStatus {
+  config: DataSourceFailover
+  primary: string
+  serving: string
+  primary_down_since?: string
+  last_error?: string
+  switched_at?: string
+}
+
 // Data source type display names
 export const DATA_SOURCE_TYPE_LABELS: Record<DataSourceType, string> = {
   pangolin: 'Pangolin',
   traefik: 'Traefik API',
 }
+
+// First-run setup wizard
+export type DeploymentType = 'pangolin' | 'standalone'
+
+export type SetupStepId = 'detect' | 'data_source' | 'provider_check' | 'complete'
+
+export interface SetupState {
+  completed: boolean
+  next_step?: SetupStepId
+  progress: {
+    deployment_type?: DeploymentType
+    detected_at?: string
+    data_source_at?: string
+    provider_verified_at?: string
+    completed_at?: string
+    skipped?: boolean
+  }
+  steps: { id: SetupStepId; title: string; done: boolean }[]
+  active_data_source: string
+  data_sources: Record<string, { type: DataSourceType; url: string }>
+}
+
+export interface SetupProbe {
+  type: DataSourceType
+  url: string
+  reachable: boolean
+  error?: string
+  latency_ms: number
+}
+
+export interface SetupDetection {
+  deployment_type: DeploymentType | ''
+  pangolin_url?: string
+  traefik_url?: string
+  probes: SetupProbe[]
+}
+
+export interface SetupDataSourceRequest {
+  deployment_type: DeploymentType
+  pangolin_url?: string
+  traefik_url?: string
+  basic_auth?: {
+    username: string
+    password: string
+  }
+  force?: boolean
+}
+
+export interface DiagnosticCheck {
+  name: string
+  status: 'pass' | 'warn' | 'fail' | 'skip'
+  message: string
+  remediation?: string
+  duration_ms: number
+}
+
+export interface SetupProviderCheck {
+  ok: boolean
+  checks: DiagnosticCheck[]
+  snippet?: string
+}
+
+// Database snapshots and config exports in the backup directory or an S3-compatible bucket
+export interface BackupObject {
+  id: string
+  key: string
+  size: number
+  last_modified: string
+}
+
+export interface BackupStatus {
+  target: string
+  interval?: string
+  keep?: number
+  last_run_at?: string
+  last_error?: string
+  last_objects?: BackupObject[]
+}
+
+export interface S3Backups {
+  status: BackupStatus
+  objects: BackupObject[]
+}
+
+export interface LocalBackups {
+  status: BackupStatus
+  objects: BackupObject[]
+}
+
+// Process figures from /api/system/runtime
+export interface RuntimeStats {
+  collected_at: string
+  uptime_seconds: number
+  version: string
+  go_version: string
+  num_cpu: number
+  gomaxprocs: number
+  goroutines: number
+  open_fds?: number
+  memory: {
+    heap_alloc_bytes: number
+    heap_inuse_bytes: number
+    heap_idle_bytes: number
+    heap_objects: number
+    stack_inuse_bytes: number
+    sys_bytes: number
+    total_alloc_bytes: number
+    next_gc_bytes: number
+  }
+  gc: {
+    num_gc: number
+    pause_total_ms: number
+    recent_pauses_ms: number[]
+    last_gc_at?: string
+    cpu_fraction: number
+  }
+  database: {
+    path?: string
+    size_bytes: number
+    free_bytes: number
+    wal_bytes: number
+    open_connections: number
+    in_use_connections: number
+    idle_connections: number
+    wait_count: number
+    error?: string
+  }
+  caches: Record<string, number>
+}
+
+// Outcome of POST /api/system/reload
+export interface ReloadReport {
+  trigger: string
+  reloaded_at: string
+  applied: string[]
+  restart_required: string[]
+}
+
+// Bearer tokens for the management API
+export type APITokenScope = 'read' | 'admin'
+
+export type UserRole = 'viewer' | 'editor' | 'admin'
+
+export interface APIToken {
+  id: string
+  name: string
+  scope: APITokenScope
+  // The user the token acts for; empty for service tokens
+  user_id?: string
+  role: UserRole
+  prefix: string
+  expires_at?: string
+  last_used_at?: string
+  created_at: string
+}
+
+export interface CreateAPITokenRequest {
+  name: string
+  scope: APITokenScope
+  user_id?: string
+  expires_in?: string
+}
+
+// Returned once on creation; the secret cannot be read again
+export interface CreatedAPIToken extends APIToken {
+  secret: string
+}
+
+// Operators of the management API; their tokens act with their role
+export interface User {
+  id: string
+  username: string
+  role: UserRole
+  created_at: string
+  updated_at: string
+}
+
+// Webhooks post resource, middleware and config events to external URLs
+export type WebhookEvent =
+  | 'resource.discovered'
+  | 'resource.disabled'
+  | 'middleware.assigned'
+  | 'middleware.removed'
+  | 'config.generation_failed'
+
+export interface Webhook {
+  id: string
+  name: string
+  url: string
+  // Masked in responses; send the mask back to keep the stored secret
+  secret?: string
+  // Empty sends every event
+  events: WebhookEvent[]
+  enabled: boolean
+  created_at: string
+  updated_at: string
+}
+
+export interface WebhookList {
+  webhooks: Webhook[]
+  events: WebhookEvent[]
+}
+
+export type SaveWebhookRequest = Pick<Webhook, 'name' | 'url' | 'secret' | 'events' | 'enabled'>
+
+export type WebhookDeliveryStatus = 'pending' | 'delivered' | 'failed'
+
+export interface WebhookDelivery {
+  id: number
+  webhook_id: string
+  event: WebhookEvent | 'webhook.test'
+  payload: string
+  status: WebhookDeliveryStatus
+  attempts: number
+  response_status?: number
+  last_error?: string
+  next_attempt_at?: string
+  created_at: string
+  delivered_at?: string
+}
+
+export type EnvironmentTargetType = 'resource' | 'middleware'
+
+export interface Environment {
+  name: string
+  description: string
+  // Served at the plain /api/traefik-config endpoint
+  is_default: boolean
+  resources: number
+  middlewares: number
+  created_at: string
+}
+
+export type SaveEnvironmentRequest = Pick<Environment, 'name' | 'description' | 'is_default'>
+
+// Empty environments applies the target everywhere
+export interface EnvironmentTags {
+  target_type: EnvironmentTargetType
+  target_id: string
+  environments: string[]
+}
@@ -13,6 +13,24 @@ export type {
   AssignServiceRequest,
   MTLSWhitelistConfigRequest,
   MTLSWhitelistExternalData,
+  PendingChangeKind,
+  PendingChange,
+  ResourceReviewConfig,
+  PendingChangesResponse,
+  PinDivergence,
+  MiddlewareOrderPolicy,
+  MiddlewareOrder,
+  UpstreamMiddlewareRemoval,
+  UpdateUpstreamMiddlewaresResponse,
+  ResourceFailover,
+  ResourceFailoverResponse,
+  ResourceDeployment,
+  ResourceDeploymentResponse,
+  RouterChain,
+  ResourceChange,
+  SkippedResource,
+  ResourceRun,
+  SharedHost,
 } from './resource'
 
 // Middleware types
@@ -22,6 +40,11 @@ export type {
   MiddlewareTemplate,
   CreateMiddlewareRequest,
   UpdateMiddlewareRequest,
+  MiddlewareConfigIssue,
+  MiddlewareValidation,
+  EntrypointMiddleware,
+  DefaultChainMiddleware,
+  DefaultChain,
 } from './middleware'
 export { MIDDLEWARE_TYPE_LABELS } from './middleware'
 
@@ -33,8 +56,10 @@ export type {
   WeightedConfig,
   MirroringConfig,
   FailoverConfig,
+  DNSDiscoveryConfig,
   CreateServiceRequest,
   UpdateServiceRequest,
+  ServiceOverride,
 } from './service'
 export { SERVICE_TYPE_LABELS } from './service'
 
@@ -46,6 +71,37 @@ export type {
   SetActiveDataSourceRequest,
   UpdateDataSourceRequest,
   TestConnectionResponse,
+  DataSourceFailover,
+  FailoverStatus,
+  DeploymentType,
+  SetupState,
+  SetupProbe,
+  SetupDetection,
+  SetupDataSourceRequest,
+  DiagnosticCheck,
+  SetupProviderCheck,
+  BackupObject,
+  BackupStatus,
+  S3Backups,
+  LocalBackups,
+  RuntimeStats,
+  ReloadReport,
+  APIToken,
+  APITokenScope,
+  CreateAPITokenRequest,
+  CreatedAPIToken,
+  User,
+  UserRole,
+  WebhookEvent,
+  Webhook,
+  WebhookList,
+  SaveWebhookRequest,
+  WebhookDeliveryStatus,
+  WebhookDelivery,
+  EnvironmentTargetType,
+  Environment,
+  SaveEnvironmentRequest,
+  EnvironmentTags,
 } from './datasource'
 export { DATA_SOURCE_TYPE_LABELS } from './datasource'
 
@@ -54,6 +110,11 @@ export type {
   Plugin,
   PluginUsage,
   PluginInstallRequest,
+  PluginSandboxRequest,
+  PluginSandboxResult,
+  PluginSandboxStage,
+  StaticConfigLintFinding,
+  StaticConfigLintReport,
   PluginRemoveRequest,
   PluginConfigPathResponse,
   PluginConfigPathRequest,
@@ -66,8 +127,15 @@ export type {
   MTLSClient,
   CreateCARequest,
   CreateClientRequest,
+  MTLSClientImport,
+  MTLSClientImportStatus,
+  MTLSClientImportResult,
+  MTLSClientImportResponse,
+  MTLSClientCert,
   MTLSConfigRequest,
   PluginCheckResponse,
+  MTLSPreflightCheck,
+  MTLSPreflightResult,
   MTLSMiddlewareConfig,
 } from './mtls'
 
@@ -75,10 +143,25 @@ export type {
 export type {
   SecurityConfig,
   SecureHeadersConfig,
+  PermissionsPolicyDirective,
   DuplicateCheckResult,
   Duplicate,
   DuplicateCheckRequest,
   UpdateResourceSecurityRequest,
+  UpdateResourceTLSHardeningRequest,
+  UpdateResourceSecureHeadersRequest,
+  SecureHeadersPreset,
+  SecureHeadersPresetRequest,
+  TLSHardeningProfile,
+  TLSHardeningMode,
+  TLSClientProfile,
+  TLSCompatResult,
+  TLSCompatReport,
+  AccessMatrixEntry,
+  AccessMatrix,
+  PluginInventoryEntry,
+  MiddlewareTypeInventoryEntry,
+  Inventory,
 } from './security'
 export { defaultSecureHeaders } from './security'
 
@@ -103,6 +186,18 @@ export type {
   AllServicesResponse,
   AllMiddlewaresResponse,
   ProtocolType,
+  ProviderPollClient,
+  ProviderConsumers,
+  TransformChange,
+  TransformLog,
+  ConfigDiff,
+  ChaosFaults,
+  ChaosStatus,
+  RouteCandidate,
+  RouteSimulation,
+  RouteQuery,
+  DeprecationFinding,
+  DeprecationScan,
 } from './traefik'
 
 // Common types
@@ -31,6 +31,10 @@ export interface Middleware {
   config: Record<string, unknown>
   created_at?: string
   updated_at?: string
+  version?: number
+  notes?: string
+  owner?: string
+  contact?: string
 }
 
 export interface MiddlewareTemplate {
@@ -50,6 +54,46 @@ export interface UpdateMiddlewareRequest {
   name?: string
   type?: MiddlewareType
   config?: Record<string, unknown>
+  // Version from the last fetch; a stale version is rejected with 409
+  version?: number
+}
+
+// A problem found in a middleware config; field is a dotted path such as
+// sourceCriterion.ipStrategy.depth
+export interface MiddlewareConfigIssue {
+  field: string
+  code: 'unknown_field' | 'invalid_type' | 'invalid_value' | 'required' | 'deprecated'
+  message: string
+}
+
+// Result of checking a middleware config against the Traefik schema; errors
+// block saving unless forced, warnings do not
+export interface MiddlewareValidation {
+  valid: boolean
+  errors: MiddlewareConfigIssue[]
+  warnings: MiddlewareConfigIssue[]
+}
+
+// A middleware attached to every HTTP router on an entrypoint
+export interface EntrypointMiddleware {
+  entrypoint: string
+  middleware_id: string
+  middleware_name: string
+  priority: number
+  created_at: string
+}
+
+// A middleware of the global default chain
+export interface DefaultChainMiddleware {
+  middleware_id: string
+  middleware_name?: string
+  priority: number
+}
+
+// The global default chain and the resources opted out of it
+export interface DefaultChain {
+  middlewares: DefaultChainMiddleware[]
+  excluded_resources?: string[]
 }
 
 // Middleware type display names
@@ -10,6 +10,9 @@ export interface MTLSConfig {
   certs_base_path: string
   has_ca: boolean
   client_count: number
+  // Set when client certificates are issued by an external CA (step-ca,
+  // vault or aws-pca) that holds the CA private key
+  signer?: string
   created_at: string
   updated_at: string
 }
@@ -23,6 +26,8 @@ export interface MTLSClient {
   expiry: string | null
   revoked: boolean
   revoked_at: string | null
+  // External CA that issued the certificate; empty for the built-in CA
+  signer?: string
   created_at: string
 }
 
@@ -40,6 +45,45 @@ export interface CreateClientRequest {
   legacy_p12?: boolean
 }
 
+export interface MTLSClientImport {
+  // Defaults to the certificate's common name
+  name?: string
+  // PEM certificate, optionally followed by its PEM private key
+  cert: string
+  key?: string
+  // Needs the private key; builds a PKCS#12 download
+  p12_password?: string
+  legacy_p12?: boolean
+}
+
+export type MTLSClientImportStatus = 'imported' | 'skipped' | 'failed'
+
+export interface MTLSClientImportResult {
+  index: number
+  name: string
+  id?: string
+  status: MTLSClientImportStatus
+  message?: string
+  // False when Traefik must also trust the certificate's issuer
+  issued_by_ca: boolean
+}
+
+export interface MTLSClientImportResponse {
+  imported: number
+  skipped: number
+  failed: number
+  results: MTLSClientImportResult[]
+}
+
+export interface MTLSClientCert {
+  id: string
+  name: string
+  subject: string
+  serial_number: string
+  not_after?: string
+  cert: string
+}
+
 export interface MTLSConfigRequest {
   mtls_enabled: boolean
 }
@@ -51,6 +95,19 @@ export interface PluginCheckResponse {
   recommended_version?: string // Latest version from plugin catalogue
 }
 
+export interface MTLSPreflightCheck {
+  name: 'plugin' | 'ca_cert' | 'router_tls'
+  status: 'pass' | 'warn' | 'fail'
+  message: string
+  hint?: string
+}
+
+export interface MTLSPreflightResult {
+  resource_id: string
+  ready: boolean
+  checks: MTLSPreflightCheck[]
+}
+
 export interface MTLSMiddlewareConfig {
   rules: string
   request_headers: string
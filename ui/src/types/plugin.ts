@@ -68,6 +68,66 @@ export interface PluginConfigPathRequest {
   path: string
 }
 
+// Static config lint - findings for the Traefik static config file
+export type StaticConfigLintSeverity = 'error' | 'warning' | 'info'
+
+export interface StaticConfigLintFinding {
+  rule: string
+  severity: StaticConfigLintSeverity
+  path: string
+  message: string
+  hint?: string
+}
+
+export interface StaticConfigLintReport {
+  path?: string
+  parsed: boolean
+  errors: number
+  warnings: number
+  findings: StaticConfigLintFinding[]
+}
+
+// Plugin sandbox - dry-runs a plugin in Yaegi before it is installed
+export interface PluginSandboxRequest {
+  moduleName: string
+  version?: string
+  config?: Record<string, unknown>
+  request?: {
+    method?: string
+    url?: string
+    headers?: Record<string, string>
+    body?: string
+  }
+}
+
+export type PluginSandboxStageName = 'download' | 'manifest' | 'load' | 'config' | 'init' | 'request'
+
+export interface PluginSandboxStage {
+  name: PluginSandboxStageName
+  ok: boolean
+  error?: string
+  durationMs: number
+}
+
+export interface PluginSandboxResult {
+  moduleName: string
+  version: string
+  import?: string
+  ok: boolean
+  failedStage?: PluginSandboxStageName
+  panic?: string
+  usedTestData: boolean
+  config?: Record<string, unknown>
+  stages: PluginSandboxStage[]
+  response?: {
+    status: number
+    headers?: Record<string, string>
+    body?: string
+    calledNext: boolean
+    upstreamHeaders?: Record<string, string>
+  }
+}
+
 // CataloguePlugin represents a plugin from the Traefik plugin catalogue (plugins.traefik.io)
 export interface CataloguePlugin {
   id: string
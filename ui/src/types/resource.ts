@@ -20,14 +20,117 @@ export interface Resource {
   mtls_reject_code?: number
   mtls_refresh_interval?: string
   mtls_external_data?: string
+  mtls_exempt_paths?: string[]
   tls_hardening_enabled: boolean
+  tls_hardening_profile?: 'hardened' | 'compat'
+  tls_hardening_opt_out?: boolean
   secure_headers_enabled: boolean
+  secure_headers_preset?: string
+  secure_headers_report_only?: boolean
   middlewares: string
   external_middlewares: string
+  version?: number
+  notes?: string
+  owner?: string
+  contact?: string
+  pinned?: boolean
+  // Opted out of the global default chain
+  default_chain_excluded?: boolean
+  // Other active resources have the same host, e.g. a path-split router
+  shared_host?: boolean
+  pin_divergence?: PinDivergence[]
+  // Where MM's middlewares go relative to the router's upstream ones
+  middleware_order?: MiddlewareOrder
+  // Upstream middlewares removed from or replaced on the router (single resource only)
+  upstream_middleware_removals?: UpstreamMiddlewareRemoval[]
+  // Upstream and merged middlewares of the router (single resource only)
+  router_chain?: RouterChain
+  router_chain_error?: string
   created_at?: string
   updated_at?: string
 }
 
+export interface RouterChain {
+  router: string
+  // As Pangolin attached them
+  upstream: string[]
+  // As served to Traefik after the merge
+  effective: string[]
+}
+
+export type MiddlewareOrderPolicy = 'prepend' | 'append' | 'interleave'
+
+export interface MiddlewareOrder {
+  policy: MiddlewareOrderPolicy
+  // interleave: MM middleware name -> index of the upstream middleware it runs before
+  positions?: Record<string, number>
+}
+
+export interface UpstreamMiddlewareRemoval {
+  name: string
+  // Swapped in at the same position; empty removes the middleware
+  replace_with?: string
+}
+
+export interface UpdateUpstreamMiddlewaresResponse {
+  id: string
+  upstream_middleware_removals: UpstreamMiddlewareRemoval[]
+  warnings: string[]
+}
+
+// Wraps the resource's service in a failover service; set exactly one fallback
+export interface ResourceFailover {
+  resource_id?: string
+  // Backend MM generates a fallback service for
+  fallback_url?: string
+  // Existing service, e.g. a static maintenance page
+  fallback_service?: string
+  // Health check added to MM's copy of the primary service (defaults /, 10s, 3s)
+  health_check_path?: string
+  health_check_interval?: string
+  health_check_timeout?: string
+  created_at?: string
+  updated_at?: string
+}
+
+export interface ResourceFailoverResponse {
+  failover: ResourceFailover
+  failover_service: string
+  primary_service: string
+  fallback_service: string
+}
+
+// Blue/green deployment of a resource to a new backend URL
+export interface ResourceDeployment {
+  resource_id?: string
+  green_url: string
+  // Previous deployment's backend; empty when blue is the upstream service
+  blue_url?: string
+  // Share of requests mirrored to green while staged (0 disables mirroring)
+  mirror_percent?: number
+  // How long blue is kept for rollback after the switch (default 1h)
+  soak_period?: string
+  stage?: 'staged' | 'switched' | 'completed'
+  switched_at?: string
+  soak_until?: string
+  completed_at?: string
+  created_at?: string
+  updated_at?: string
+}
+
+export interface ResourceDeploymentResponse {
+  deployment: ResourceDeployment
+  green_service: string
+  blue_service?: string
+  mirroring_service?: string
+}
+
+export interface PinDivergence {
+  field: 'host' | 'service_id' | 'router_priority' | 'status'
+  pinned: string
+  upstream: string
+}
+
 export interface ResourceMiddleware {
   resource_id: string
   middleware_id: string
@@ -79,6 +182,8 @@ export interface MTLSWhitelistConfigRequest {
 export interface AssignMiddlewareRequest {
   middleware_id: string
   priority: number
+  /** RFC3339 timestamp after which the assignment is removed automatically */
+  expires_at?: string
 }
 
 export interface AssignExternalMiddlewareRequest {
@@ -98,3 +203,66 @@ export interface ExternalMiddleware {
 export interface AssignServiceRequest {
   service_id: string
 }
+
+// Review mode: resource changes found by the watcher waiting for approval
+export type PendingChangeKind = 'create' | 'disable' | 'service_change'
+
+export interface PendingChange {
+  id: string
+  kind: PendingChangeKind
+  resource_id?: string
+  pangolin_router_id?: string
+  host: string
+  old_service_id?: string
+  new_service_id?: string
+  detected_at: string
+  auto_approve_at?: string
+}
+
+export interface ResourceReviewConfig {
+  enabled: boolean
+  auto_approve_minutes?: number
+}
+
+export interface PendingChangesResponse {
+  review: ResourceReviewConfig
+  changes: PendingChange[]
+}
+
+// Changes made by a resource watcher run
+export interface ResourceChange {
+  resource_id?: string
+  host: string
+  action: 'created' | 'updated' | 'disabled' | 'queued'
+}
+
+// A resource a watcher run left alone
+export interface SkippedResource {
+  resource_id?: string
+  host: string
+  reason: string
+}
+
+// Summary of one resource watcher run
+export interface ResourceRun {
+  id?: number
+  trigger: 'scheduled' | 'manual' | 'resync' | 'reload' | 'restore'
+  source: string
+  started_at: string
+  duration_ms: number
+  fetched: number
+  created: number
+  updated: number
+  disabled: number
+  queued: number
+  skipped: SkippedResource[]
+  changes: ResourceChange[]
+  shared_hosts?: SharedHost[]
+  error?: string
+}
+
+// A host served by more than one router
+export interface SharedHost {
+  host: string
+  routers: string[]
+}
@@ -8,11 +8,25 @@ export interface SecureHeadersConfig {
   referrer_policy: string
   csp: string
   permissions_policy: string
+  // Structured Permissions-Policy; replaces permissions_policy when set
+  permissions_policy_directives?: PermissionsPolicyDirective[]
+  cross_origin_opener_policy?: '' | 'unsafe-none' | 'same-origin-allow-popups' | 'same-origin' | 'noopener-allow-popups'
+  cross_origin_embedder_policy?: '' | 'unsafe-none' | 'require-corp' | 'credentialless'
+  cross_origin_resource_policy?: '' | 'same-site' | 'same-origin' | 'cross-origin'
 }
 
+// allowlist entries are '*', 'self', 'src' or an origin; empty disables the feature
+export interface PermissionsPolicyDirective {
+  feature: string
+  allowlist: string[]
+}
+
+export type TLSHardeningMode = 'force_on' | 'default_on' | 'per_resource'
+
 export interface SecurityConfig {
   id: number
   tls_hardening_enabled: boolean
+  tls_hardening_mode: TLSHardeningMode
   secure_headers_enabled: boolean
   secure_headers: SecureHeadersConfig
   created_at?: string
@@ -41,6 +55,109 @@ export interface UpdateResourceSecurityRequest {
   enabled: boolean
 }
 
+export interface SecureHeadersPreset {
+  id: string
+  name: string
+  description: string
+  built_in: boolean
+  headers: SecureHeadersConfig
+  created_at?: string
+  updated_at?: string
+}
+
+export interface SecureHeadersPresetRequest {
+  name: string
+  description?: string
+  headers: SecureHeadersConfig
+}
+
+// preset: undefined keeps the current selection, '' uses the global values.
+// report_only sends the CSP as Content-Security-Policy-Report-Only with a soft HSTS.
+export interface UpdateResourceSecureHeadersRequest {
+  enabled: boolean
+  preset?: string
+  report_only?: boolean
+}
+
+export type TLSHardeningProfile = 'hardened' | 'compat'
+
+export interface UpdateResourceTLSHardeningRequest {
+  enabled: boolean
+  profile?: TLSHardeningProfile
+}
+
+export interface TLSClientProfile {
+  id: string
+  name: string
+  category: string
+  max_version: string
+  cipher_suites?: string[]
+  curves?: string[]
+}
+
+export interface TLSCompatResult {
+  client: TLSClientProfile
+  compatible: boolean
+  protocol?: string
+  cipher_suite?: string
+  curve?: string
+  reason?: string
+}
+
+export interface TLSCompatReport {
+  profile: TLSHardeningProfile
+  options_name: string
+  key_type: 'rsa' | 'ecdsa'
+  compatible: number
+  incompatible: number
+  results: TLSCompatResult[]
+  warnings: string[]
+  compat_profile?: TLSCompatReport
+}
+
+// Access review of every exposed host
+export interface AccessMatrixEntry {
+  host: string
+  resource_id: string
+  entry_points: string[]
+  auth: string[]
+  ip_allow_list: string[]
+  tls: string
+  tls_domains: string[]
+  https_redirect: string
+  middlewares: string[]
+}
+
+export interface AccessMatrix {
+  generated_at: string
+  hosts: AccessMatrixEntry[]
+}
+
+// Plugins and middleware types in use, for upgrade planning
+export interface PluginInventoryEntry {
+  key: string
+  module_name: string
+  version: string
+  installed: boolean
+  middlewares: number
+}
+
+export interface MiddlewareTypeInventoryEntry {
+  type: string
+  middlewares: number
+  resources: number
+  deprecated: boolean
+  replaced_by?: string
+}
+
+export interface Inventory {
+  generated_at: string
+  static_config_path: string
+  static_config_error?: string
+  plugins: PluginInventoryEntry[]
+  middleware_types: MiddlewareTypeInventoryEntry[]
+}
+
 // Default secure headers configuration
 export const defaultSecureHeaders: SecureHeadersConfig = {
   x_content_type_options: 'nosniff',
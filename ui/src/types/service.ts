@@ -1,4 +1,4 @@
-export type ServiceType = 'loadBalancer' | 'weighted' | 'mirroring' | 'failover'
+export type ServiceType = 'loadBalancer' | 'weighted' | 'mirroring' | 'failover' | 'dnsDiscovery'
 
 export interface Service {
   id: string
@@ -60,6 +60,19 @@ export interface FailoverConfig {
   }
 }
 
+// Served as a loadBalancer whose servers MM resolves from DNS, honouring TTLs
+export interface DNSDiscoveryConfig {
+  // Fully qualified record, e.g. _http._tcp.api.service.consul
+  name: string
+  recordType?: 'SRV' | 'A'
+  scheme?: 'http' | 'https' | 'h2c'
+  // Required for A records; SRV records carry their own ports
+  port?: number
+  passHostHeader?: boolean
+  healthCheck?: LoadBalancerConfig['healthCheck']
+  sticky?: LoadBalancerConfig['sticky']
+}
+
 export interface CreateServiceRequest {
   name: string
   type: ServiceType
@@ -72,10 +85,30 @@ export interface UpdateServiceRequest {
   config?: Record<string, unknown>
 }
 
+// Delta applied to a service discovered upstream (e.g. Pangolin) during the merge
+export interface ServiceOverride {
+  service_name: string
+  // loadBalancer services
+  add_servers?: Array<{
+    url: string
+    weight?: number
+  }>
+  remove_servers?: string[]
+  // Server URL -> weight
+  server_weights?: Record<string, number>
+  health_check?: LoadBalancerConfig['healthCheck']
+  // weighted services: child service name -> weight
+  service_weights?: Record<string, number>
+  sticky?: LoadBalancerConfig['sticky']
+  created_at?: string
+  updated_at?: string
+}
+
 // Service type display names
 export const SERVICE_TYPE_LABELS: Record<ServiceType, string> = {
   loadBalancer: 'Load Balancer',
   weighted: 'Weighted',
   mirroring: 'Mirroring',
   failover: 'Failover',
+  dnsDiscovery: 'DNS Discovery',
 }
@@ -290,3 +290,130 @@ export interface AllMiddlewaresResponse {
 
 // Protocol type filter
 export type ProtocolType = 'http' | 'tcp' | 'udp' | 'all'
+
+// A client of /traefik-config, identified by IP and User-Agent
+export interface ProviderPollClient {
+  client_ip: string
+  user_agent: string
+  first_seen: string
+  last_seen: string
+  last_success?: string
+  last_error?: string
+  requests: number
+  failures: number
+  revision: number
+  endpoints: Record<string, number>
+  interval_seconds?: number
+}
+
+// Who is polling the merged config, with misconfiguration warnings
+export interface ProviderConsumers {
+  window_seconds: number
+  active: number
+  consumers: ProviderPollClient[]
+  warnings: string[]
+}
+
+// One change the latest merge made to the upstream config
+export interface TransformChange {
+  section: string
+  name: string
+  action: 'added' | 'removed' | 'modified' | 'middleware_added' | 'middleware_removed' | 'middlewares_reordered'
+  field?: string
+  before?: unknown
+  after?: unknown
+  detail: string
+}
+
+// What the latest merge changed; recorded when PROXY_TRANSFORM_LOG is set
+export interface TransformLog {
+  merged_at: string
+  served: boolean
+  changes: TransformChange[]
+  truncated: boolean
+}
+
+// The config a fresh merge would serve, compared with what Traefik last got
+export interface ConfigDiff {
+  base: 'served' | 'cache' | 'snapshot' | 'none'
+  base_at?: string
+  generated_at: string
+  identical: boolean
+  // False when validation would fall back to the last known-good config
+  would_serve: boolean
+  validation_errors?: string[]
+  changes: TransformChange[]
+  truncated: boolean
+  unified: string
+}
+
+// Faults injected into the config endpoint; only allowed when PROXY_CHAOS is set
+export interface ChaosFaults {
+  delay_ms: number
+  stale: boolean
+  truncate: boolean
+  // Share of polls affected, 0 to 1 (0 means every poll)
+  probability: number
+  duration_seconds?: number
+  set_at?: string
+  expires_at?: string
+}
+
+export interface ChaosStatus {
+  enabled: boolean
+  faults?: ChaosFaults
+  injected: number
+}
+
+// A router evaluated by the route simulation
+export interface RouteCandidate {
+  router: string
+  rule: string
+  priority: number
+  matched: boolean
+  error?: string
+}
+
+// The merged router Traefik would pick for a URL, evaluated by MM
+export interface RouteSimulation {
+  url: string
+  method: string
+  matched: boolean
+  router?: string
+  rule?: string
+  priority?: number
+  service?: string
+  entry_points?: string[]
+  middlewares?: string[]
+  chain?: string[]
+  candidates: RouteCandidate[]
+}
+
+// A stored middleware or service using a type or option the target Traefik
+// version renamed, removed or deprecated
+export interface DeprecationFinding {
+  kind: 'middleware' | 'service'
+  id: string
+  name: string
+  option: string
+  replacement?: string
+  message: string
+  hint?: string
+  fixable: boolean
+}
+
+export interface DeprecationScan {
+  traefik_version: string
+  major: number
+  findings: DeprecationFinding[]
+  fixed?: number
+}
+
+// Request to simulate; headers are "Name: value" pairs
+export interface RouteQuery {
+  url: string
+  method?: string
+  headers?: Record<string, string>
+  client_ip?: string
+  entrypoint?: string
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvFile applies the KEY=VALUE lines of a file to the process environment,
+// so settings can be edited and re-read without recreating the container.
+// Load can be called again to pick up edits; a key removed from the file
+// gets back the value it had before the file was first applied.
+type EnvFile struct {
+	path string
+	fs   FileSystem
+	// original holds the values keys had before the file set them; nil
+	// means the key was unset
+	original map[string]*string
+}
+
+// NewEnvFile creates an env file reader for path
+func NewEnvFile(path string) *EnvFile {
+	return &EnvFile{path: path, fs: OS, original: make(map[string]*string)}
+}
+
+// Load reads the file and applies it. Nothing is changed when the file
+// cannot be read or has a malformed line.
+func (e *EnvFile) Load() error {
+	data, err := e.fs.ReadFile(e.path)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+	values, err := ParseEnv(data)
+	if err != nil {
+		return fmt.Errorf("invalid env file %s: %w", e.path, err)
+	}
+
+	for key, original := range e.original {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if original == nil {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, *original)
+		}
+		delete(e.original, key)
+	}
+	for key, value := range values {
+		if _, ok := e.original[key]; !ok {
+			if current, set := os.LookupEnv(key); set {
+				e.original[key] = &current
+			} else {
+				e.original[key] = nil
+			}
+		}
+		os.Setenv(key, value)
+	}
+	return nil
+}
+
+// ParseEnv parses KEY=VALUE lines. Blank lines and lines starting with #
+// are skipped, an "export " prefix is allowed and values may be wrapped in
+// single or double quotes.
+func ParseEnv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
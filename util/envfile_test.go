@@ -0,0 +1,60 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	values, err := ParseEnv([]byte("# settings\n\nexport A=1\nB = \"two words\"\nC='x=y'\nD=\n"))
+	if err != nil {
+		t.Fatalf("ParseEnv: %v", err)
+	}
+	want := map[string]string{"A": "1", "B": "two words", "C": "x=y", "D": ""}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %q, want %q", key, values[key], value)
+		}
+	}
+	if _, err := ParseEnv([]byte("A=1\nnot a setting\n")); err == nil {
+		t.Errorf("expected a line without = to be rejected")
+	}
+}
+
+func TestEnvFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mm.env")
+	t.Setenv("MM_ENVFILE_KEPT", "original")
+	os.Unsetenv("MM_ENVFILE_ADDED")
+	t.Cleanup(func() { os.Unsetenv("MM_ENVFILE_ADDED") })
+
+	if err := os.WriteFile(path, []byte("MM_ENVFILE_KEPT=file\nMM_ENVFILE_ADDED=1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := NewEnvFile(path)
+	if err := env.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if os.Getenv("MM_ENVFILE_KEPT") != "file" || os.Getenv("MM_ENVFILE_ADDED") != "1" {
+		t.Fatalf("expected the file to override the environment")
+	}
+
+	// Keys removed from the file get their previous values back
+	if err := os.WriteFile(path, []byte("MM_ENVFILE_ADDED=2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if os.Getenv("MM_ENVFILE_KEPT") != "original" || os.Getenv("MM_ENVFILE_ADDED") != "2" {
+		t.Fatalf("got KEPT=%q ADDED=%q", os.Getenv("MM_ENVFILE_KEPT"), os.Getenv("MM_ENVFILE_ADDED"))
+	}
+
+	// A malformed file changes nothing
+	if err := os.WriteFile(path, []byte("broken\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Load(); err == nil || os.Getenv("MM_ENVFILE_ADDED") != "2" {
+		t.Fatalf("expected a malformed file to be rejected without changes, got %v", err)
+	}
+}
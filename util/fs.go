@@ -0,0 +1,89 @@
+package util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem is the file access MM uses for the files it shares with
+// Traefik: the generated config in the conf dir, the static config it edits
+// and the certificates it writes. WriteFile replaces a file atomically, so
+// Traefik's file watcher never reads a half-written file.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	Stat(name string) (fs.FileInfo, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Remove(name string) error
+}
+
+// OS is the FileSystem of the host
+var OS FileSystem = osFS{}
+
+type osFS struct{}
+
+func (osFS) ReadFile(name string) ([]byte, error)         { return os.ReadFile(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+
+// WriteFile writes data to a temporary file next to name and moves it into
+// place
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := replaceFile(tmpName, name); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+	return nil
+}
+
+// IsRootPath reports whether path is a filesystem root such as / or C:\
+func IsRootPath(path string) bool {
+	clean := filepath.Clean(path)
+	return clean == filepath.VolumeName(clean)+string(filepath.Separator)
+}
+
+// FindFile returns the first of the candidate paths that exists, or "".
+// Relative candidates are tried against the working directory and then
+// against the directory of the executable, so a release archive works from
+// wherever it is started, e.g. as a Windows service.
+func FindFile(candidates ...string) string {
+	var exeDir string
+	if exe, err := os.Executable(); err == nil {
+		exeDir = filepath.Dir(exe)
+	}
+	for _, candidate := range candidates {
+		path := filepath.FromSlash(candidate)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if exeDir != "" && !filepath.IsAbs(path) {
+			if _, err := os.Stat(filepath.Join(exeDir, path)); err == nil {
+				return filepath.Join(exeDir, path)
+			}
+		}
+	}
+	return ""
+}
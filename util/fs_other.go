@@ -0,0 +1,10 @@
+//go:build !windows
+
+package util
+
+import "os"
+
+// replaceFile moves src over dst; rename is atomic on POSIX filesystems
+func replaceFile(src, dst string) error {
+	return os.Rename(src, dst)
+}
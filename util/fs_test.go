@@ -0,0 +1,71 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOSWriteFileReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "resource-overrides.yml")
+
+	for _, content := range []string{"http: {}\n", "http:\n  routers: {}\n"} {
+		if err := OS.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		data, err := OS.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("content = %q, want %q", data, content)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+	if runtime.GOOS != "windows" {
+		info, err := OS.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if info.Mode().Perm() != 0644 {
+			t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+		}
+	}
+}
+
+func TestIsRootPath(t *testing.T) {
+	root := string(filepath.Separator)
+	if runtime.GOOS == "windows" {
+		root = `C:\`
+	}
+	if !IsRootPath(root) {
+		t.Errorf("expected %q to be a root path", root)
+	}
+	if IsRootPath(filepath.Join(root, "etc", "traefik", "traefik.yml")) {
+		t.Errorf("expected a file path not to be a root path")
+	}
+}
+
+func TestFindFile(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "migrations.sql")
+	if err := os.WriteFile(existing, []byte("--"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := FindFile(filepath.Join(dir, "missing.sql"), existing); got != existing {
+		t.Errorf("FindFile = %q, want %q", got, existing)
+	}
+	if got := FindFile(filepath.Join(dir, "missing.sql")); got != "" {
+		t.Errorf("FindFile = %q, want no match", got)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+)
+
+// errorSharingViolation is ERROR_SHARING_VIOLATION: another process, such as
+// Traefik reloading the file, has it open
+const errorSharingViolation = syscall.Errno(32)
+
+// replaceFile moves src over dst. Windows refuses to replace a file another
+// process has open, so the move is retried briefly.
+func replaceFile(src, dst string) error {
+	var err error
+	for attempt := 1; attempt <= 5; attempt++ {
+		if err = os.Rename(src, dst); err == nil {
+			return nil
+		}
+		if !errors.Is(err, fs.ErrPermission) && !errors.Is(err, errorSharingViolation) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+	}
+	return err
+}
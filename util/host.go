@@ -0,0 +1,41 @@
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// hostProfile maps hosts the way a browser would look them up
+var hostProfile = idna.New(idna.MapForLookup(), idna.Transitional(false))
+
+// NormalizeHost returns the canonical form of a host: trimmed, lowercase,
+// without a trailing dot and with internationalized labels in punycode, so
+// "Café.Example.com." and "xn--caf-dma.example.com" compare equal
+func NormalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" {
+		return ""
+	}
+	if ascii, err := hostProfile.ToASCII(host); err == nil {
+		return ascii
+	}
+	// Lookup rules reject wildcards and underscores; encode those labels
+	// without validating them
+	if ascii, err := idna.Punycode.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// NormalizeHostList normalizes a comma-separated list of hosts, dropping
+// empty entries
+func NormalizeHostList(hosts string) string {
+	var result []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host = NormalizeHost(host); host != "" {
+			result = append(result, host)
+		}
+	}
+	return strings.Join(result, ",")
+}
@@ -0,0 +1,34 @@
+package util
+
+import "testing"
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"app.example.com", "app.example.com"},
+		{" App.Example.COM. ", "app.example.com"},
+		{"Café.example.com", "xn--caf-dma.example.com"},
+		{"xn--caf-dma.example.com", "xn--caf-dma.example.com"},
+		{"*.Bücher.example", "*.xn--bcher-kva.example"},
+		{"_acme.example.com", "_acme.example.com"},
+		{"", ""},
+		{".", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := NormalizeHost(tt.input); got != tt.expected {
+				t.Errorf("NormalizeHost(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeHostList(t *testing.T) {
+	got := NormalizeHostList("Example.com., ,Café.example.com")
+	if want := "example.com,xn--caf-dma.example.com"; got != want {
+		t.Errorf("NormalizeHostList() = %q, want %q", got, want)
+	}
+}
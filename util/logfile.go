@@ -0,0 +1,59 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LogOutput sends the standard logger to stderr and, when a path is set, to
+// a file as well. Calling Open again with the same path reopens the file, so
+// a file moved away by logrotate is replaced by a new one.
+type LogOutput struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// Open points the logger at path in addition to stderr; empty logs to
+// stderr only. The previous file is closed once the new one is in use.
+func (l *LogOutput) Open(path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var file *os.File
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		file = f
+		log.SetOutput(io.MultiWriter(os.Stderr, file))
+	} else {
+		log.SetOutput(os.Stderr)
+	}
+
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.path, l.file = path, file
+	return nil
+}
+
+// Path returns the file currently logged to, or ""
+func (l *LogOutput) Path() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.path
+}
+
+// Close stops logging to the file
+func (l *LogOutput) Close() error {
+	return l.Open("")
+}